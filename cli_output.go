@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the commands that report results (--output)
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// parseOutputFormat checks an --output value
+func parseOutputFormat(format string) (string, error) {
+	switch format {
+	case "", outputTable:
+		return outputTable, nil
+	case outputJSON, outputYAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown output format '%s'; use table, json or yaml", format)
+	}
+}
+
+// writeOutput prints a result as JSON or YAML. YAML keeps the JSON field
+// names and order, so scripts can switch between the two.
+func writeOutput(format string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	if format == outputJSON {
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	}
+
+	// JSON is YAML, and a node keeps the order of the keys
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	clearNodeStyle(&node)
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return encoder.Close()
+}
+
+// clearNodeStyle drops the flow style and quotes the nodes took from JSON
+func clearNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearNodeStyle(child)
+	}
+}
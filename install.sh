@@ -97,16 +97,27 @@ Description=Vertex Service Manager
 Documentation=https://github.com/zechtz/vertex
 After=network.target
 Wants=network.target
+StartLimitIntervalSec=300
+StartLimitBurst=5
 
 [Service]
-Type=simple
+Type=notify
+NotifyAccess=main
 WorkingDirectory=$DATA_DIR
 Environment=VERTEX_DATA_DIR=$DATA_DIR
 ExecStart=$INSTALL_DIR/$BINARY_NAME -port 8080
-Restart=always
+Restart=on-failure
 RestartSec=5
+WatchdogSec=30
+TimeoutStartSec=60
+TimeoutStopSec=60
+KillMode=mixed
+LimitNOFILE=65536
+TasksMax=8192
+UMask=0022
 StandardOutput=journal
 StandardError=journal
+SyslogIdentifier=vertex
 
 [Install]
 WantedBy=default.target
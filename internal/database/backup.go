@@ -0,0 +1,221 @@
+// Package database - Backups of the database and restoring them
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	backupTimeLayout = "20060102-150405"
+	restoreSuffix    = ".restore" // Next to the database, replaces it at the next start
+)
+
+// vertex-<kind>-<UTC time>.db, the names of the files in the backups directory
+var backupNamePattern = regexp.MustCompile(`^vertex-(daily|manual|pre-restore)-(\d{8}-\d{6})\.db$`)
+
+// BackupsDir is where backups of the database are kept, next to it in the data directory
+func (db *Database) BackupsDir() string {
+	return filepath.Join(filepath.Dir(db.path), "backups")
+}
+
+// parseBackupName returns the kind and time of a backup from its file name
+func parseBackupName(name string) (string, time.Time, bool) {
+	match := backupNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return "", time.Time{}, false
+	}
+	createdAt, err := time.Parse(backupTimeLayout, match[2])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return match[1], createdAt, true
+}
+
+// backupInto writes a consistent copy of an open database to a new file.
+// VACUUM INTO reads a snapshot, so writers carry on while it runs.
+func backupInto(conn *sql.DB, dir, kind string, now time.Time) (*models.DatabaseBackup, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	now = now.UTC().Truncate(time.Second)
+	name := fmt.Sprintf("vertex-%s-%s.db", kind, now.Format(backupTimeLayout))
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("backup %s already exists", name)
+	}
+
+	if _, err := conn.Exec("VACUUM INTO ?", path); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to back up the database: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up the database: %w", err)
+	}
+	return &models.DatabaseBackup{Name: name, Kind: kind, CreatedAt: now, SizeBytes: info.Size()}, nil
+}
+
+// CreateBackup copies the database into the backups directory
+func (db *Database) CreateBackup(kind string) (*models.DatabaseBackup, error) {
+	return backupInto(db.DB, db.BackupsDir(), kind, time.Now())
+}
+
+// ListBackups returns the backups in the backups directory, newest first
+func (db *Database) ListBackups() ([]models.DatabaseBackup, error) {
+	entries, err := os.ReadDir(db.BackupsDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return []models.DatabaseBackup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	backups := []models.DatabaseBackup{}
+	for _, entry := range entries {
+		kind, createdAt, ok := parseBackupName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, models.DatabaseBackup{Name: entry.Name(), Kind: kind, CreatedAt: createdAt, SizeBytes: info.Size()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// backupPath returns the file of a backup, refusing names that are not backups
+func (db *Database) backupPath(name string) (string, error) {
+	if _, _, ok := parseBackupName(name); !ok {
+		return "", fmt.Errorf("backup %s not found", name)
+	}
+	path := filepath.Join(db.BackupsDir(), name)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("backup %s not found", name)
+	}
+	return path, nil
+}
+
+// DeleteBackup removes a backup
+func (db *Database) DeleteBackup(name string) error {
+	path, err := db.backupPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to delete backup %s: %w", name, err)
+	}
+	return nil
+}
+
+// StageRestore copies a backup next to the database, where it replaces the
+// database the next time Vertex starts. The open database cannot be swapped
+// out from under its connections.
+func (db *Database) StageRestore(name string) error {
+	path, err := db.backupPath(name)
+	if err != nil {
+		return err
+	}
+
+	// Check the copy is a database before it can replace this one
+	check, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup %s: %w", name, err)
+	}
+	var result string
+	err = check.QueryRow("PRAGMA quick_check").Scan(&result)
+	check.Close()
+	if err != nil || result != "ok" {
+		return fmt.Errorf("backup %s is damaged and cannot be restored", name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup %s: %w", name, err)
+	}
+	staged := db.path + restoreSuffix
+	if err := os.WriteFile(staged+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to stage backup %s: %w", name, err)
+	}
+	if err := os.Rename(staged+".tmp", staged); err != nil {
+		return fmt.Errorf("failed to stage backup %s: %w", name, err)
+	}
+	return os.WriteFile(staged+".name", []byte(name), 0644)
+}
+
+// PendingRestore returns the backup staged to replace the database at the
+// next start, or "" when there is none
+func (db *Database) PendingRestore() string {
+	if _, err := os.Stat(db.path + restoreSuffix); err != nil {
+		return ""
+	}
+	name, err := os.ReadFile(db.path + restoreSuffix + ".name")
+	if err != nil {
+		return "unknown backup"
+	}
+	return string(name)
+}
+
+// CancelRestore drops the backup staged to replace the database
+func (db *Database) CancelRestore() error {
+	if db.PendingRestore() == "" {
+		return fmt.Errorf("no restore is pending")
+	}
+	os.Remove(db.path + restoreSuffix + ".name")
+	if err := os.Remove(db.path + restoreSuffix); err != nil {
+		return fmt.Errorf("failed to cancel restore: %w", err)
+	}
+	return nil
+}
+
+// applyPendingRestore replaces the database at path with a staged backup
+// before it is opened, keeping the replaced database as a pre-restore backup
+func applyPendingRestore(path string) error {
+	staged := path + restoreSuffix
+	if _, err := os.Stat(staged); err != nil {
+		return nil
+	}
+	name, _ := os.ReadFile(staged + ".name")
+
+	if _, err := os.Stat(path); err == nil {
+		current, err := sql.Open("sqlite3", sqliteDSN(path))
+		if err != nil {
+			return fmt.Errorf("failed to open database at %s: %w", path, err)
+		}
+		backup, err := backupInto(current, filepath.Join(filepath.Dir(path), "backups"), models.BackupKindPreRestore, time.Now())
+		current.Close()
+		if err != nil {
+			return fmt.Errorf("failed to keep the database before restoring %s: %w", name, err)
+		}
+		log.Printf("[INFO] Kept the database before the restore as backup %s", backup.Name)
+	}
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path+suffix, err)
+		}
+	}
+	if err := os.Rename(staged, path); err != nil {
+		return fmt.Errorf("failed to restore backup %s: %w", name, err)
+	}
+	os.Remove(staged + ".name")
+	log.Printf("[INFO] Restored the database from backup %s", name)
+	return nil
+}
@@ -2,12 +2,15 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
@@ -16,6 +19,11 @@ import (
 
 type Database struct {
 	*sql.DB
+	path     string
+	logQueue *logWriteQueue // Batches log inserts; see QueueLogEntry
+
+	// maintenanceMutex is held by VACUUM; the log writer waits for it
+	maintenanceMutex sync.RWMutex
 }
 
 func NewDatabase() (*Database, error) {
@@ -58,12 +66,18 @@ func NewDatabaseWithPath(dbPath string) (*Database, error) {
 		}
 	}
 
-	db, err := sql.Open("sqlite3", finalPath)
+	// Swap in a backup staged for restore while nothing has the database open
+	if err := applyPendingRestore(finalPath); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", sqliteDSN(finalPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database at %s: %w", finalPath, err)
 	}
 
-	database := &Database{DB: db}
+	database := &Database{DB: db, path: finalPath}
+	database.tuneConnections()
 	if err := database.initTables(); err != nil {
 		return nil, fmt.Errorf("failed to initialize database tables: %w", err)
 	}
@@ -72,10 +86,23 @@ func NewDatabaseWithPath(dbPath string) (*Database, error) {
 	if err := database.InitializeLogTables(); err != nil {
 		return nil, fmt.Errorf("failed to initialize log tables: %w", err)
 	}
+	database.startLogWriter()
 
 	return database, nil
 }
 
+// GetDataDir returns the directory used for application data, honouring
+// VERTEX_DATA_DIR before the platform default and finally the current directory
+func GetDataDir() string {
+	if dataDir := os.Getenv("VERTEX_DATA_DIR"); dataDir != "" {
+		return dataDir
+	}
+	if defaultDataDir := getDefaultDataDir(); defaultDataDir != "" {
+		return defaultDataDir
+	}
+	return "."
+}
+
 // getDefaultDataDir returns the platform-specific default data directory
 func getDefaultDataDir() string {
 	switch runtime.GOOS {
@@ -243,6 +270,10 @@ func (db *Database) initTables() error {
 		java_home_override TEXT DEFAULT '',
 		is_default BOOLEAN DEFAULT FALSE,
 		is_active BOOLEAN DEFAULT FALSE,
+		memory_budget_mb INTEGER DEFAULT 0,
+		memory_budget_mode TEXT DEFAULT 'warn',
+		port_pool_start INTEGER DEFAULT 0,
+		port_pool_end INTEGER DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
@@ -315,6 +346,539 @@ func (db *Database) initTables() error {
 		FOREIGN KEY (profile_id) REFERENCES service_profiles(id) ON DELETE CASCADE
 	);`
 
+	// Create service lifecycle hooks table
+	createServiceHooksTable := `
+	CREATE TABLE IF NOT EXISTS service_hooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		phase TEXT NOT NULL,
+		hook_type TEXT NOT NULL DEFAULT 'command',
+		command TEXT,
+		url TEXT,
+		method TEXT,
+		body TEXT,
+		timeout_seconds INTEGER DEFAULT 60,
+		abort_on_failure BOOLEAN DEFAULT FALSE,
+		is_enabled BOOLEAN DEFAULT TRUE,
+		hook_order INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create service tags table
+	createServiceTagsTable := `
+	CREATE TABLE IF NOT EXISTS service_tags (
+		service_id TEXT NOT NULL,
+		tag_key TEXT NOT NULL,
+		tag_value TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (service_id, tag_key),
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create external dependencies table (databases, brokers, URLs checked before start)
+	createExternalDependenciesTable := `
+	CREATE TABLE IF NOT EXISTS service_external_dependencies (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		dependency_type TEXT NOT NULL DEFAULT 'tcp',
+		target TEXT NOT NULL,
+		timeout_seconds INTEGER DEFAULT 5,
+		is_required BOOLEAN DEFAULT TRUE,
+		is_enabled BOOLEAN DEFAULT TRUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create service event timeline table
+	createServiceEventsTable := `
+	CREATE TABLE IF NOT EXISTS service_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		service_name TEXT NOT NULL,
+		event_type TEXT NOT NULL,
+		message TEXT,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_service_events_service_time ON service_events(service_id, created_at);`
+
+	// Create the restarts and crashes of services, counted per time window
+	createServiceRestartsTable := `
+	CREATE TABLE IF NOT EXISTS service_restarts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		restarted_at DATETIME NOT NULL,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_service_restarts_time ON service_restarts(restarted_at);`
+
+	// Create external log sink table (Loki/Elasticsearch shipping per profile)
+	createProfileLogSinksTable := `
+	CREATE TABLE IF NOT EXISTS profile_log_sinks (
+		profile_id TEXT PRIMARY KEY,
+		sink_type TEXT NOT NULL,
+		url TEXT NOT NULL,
+		index_name TEXT,
+		labels_json TEXT,
+		username TEXT,
+		password TEXT,
+		batch_size INTEGER DEFAULT 500,
+		flush_interval_seconds INTEGER DEFAULT 5,
+		buffer_size INTEGER DEFAULT 10000,
+		disable_sqlite BOOLEAN DEFAULT FALSE,
+		is_enabled BOOLEAN DEFAULT TRUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (profile_id) REFERENCES service_profiles(id) ON DELETE CASCADE
+	);`
+
+	// Create test run history table
+	createServiceTestRunsTable := `
+	CREATE TABLE IF NOT EXISTS service_test_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		service_name TEXT NOT NULL,
+		status TEXT NOT NULL,
+		command TEXT,
+		filter TEXT,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME,
+		duration_ms INTEGER DEFAULT 0,
+		total INTEGER DEFAULT 0,
+		failures INTEGER DEFAULT 0,
+		errors INTEGER DEFAULT 0,
+		skipped INTEGER DEFAULT 0,
+		suites_json TEXT DEFAULT '[]',
+		output TEXT,
+		error_message TEXT,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_service_test_runs_service ON service_test_runs(service_id, started_at);`
+
+	// Create build history table; the output is the build phase of a service start
+	createServiceBuildsTable := `
+	CREATE TABLE IF NOT EXISTS service_builds (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		service_name TEXT NOT NULL,
+		status TEXT NOT NULL,
+		build_system TEXT,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME,
+		duration_ms INTEGER DEFAULT 0,
+		artifact_path TEXT,
+		artifact_size INTEGER DEFAULT 0,
+		lines INTEGER DEFAULT 0,
+		output TEXT,
+		error_message TEXT,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_service_builds_service ON service_builds(service_id, started_at);`
+
+	// Create artifact repository credentials table (secrets are encrypted by the caller)
+	createRepositoryCredentialsTable := `
+	CREATE TABLE IF NOT EXISTS profile_repository_credentials (
+		profile_id TEXT NOT NULL,
+		server_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		url TEXT,
+		username TEXT,
+		secret TEXT,
+		token_header TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (profile_id, server_id),
+		FOREIGN KEY (profile_id) REFERENCES service_profiles(id) ON DELETE CASCADE
+	);`
+
+	// Create per-service rotating log file settings table
+	createServiceLogFilesTable := `
+	CREATE TABLE IF NOT EXISTS service_log_files (
+		service_id TEXT PRIMARY KEY,
+		is_enabled BOOLEAN DEFAULT FALSE,
+		max_size_mb INTEGER DEFAULT 10,
+		max_files INTEGER DEFAULT 5,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create per-service log level reclassification rules table
+	createServiceLogLevelRulesTable := `
+	CREATE TABLE IF NOT EXISTS service_log_level_rules (
+		service_id TEXT PRIMARY KEY,
+		use_defaults BOOLEAN DEFAULT TRUE,
+		rules_json TEXT DEFAULT '[]',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create per-service health check client settings table
+	createServiceHealthChecksTable := `
+	CREATE TABLE IF NOT EXISTS service_health_checks (
+		service_id TEXT PRIMARY KEY,
+		headers_json TEXT DEFAULT '{}',
+		auth_type TEXT DEFAULT '',
+		username_env TEXT DEFAULT '',
+		password_env TEXT DEFAULT '',
+		token_env TEXT DEFAULT '',
+		timeout_seconds INTEGER DEFAULT 0,
+		insecure_skip_verify BOOLEAN DEFAULT FALSE,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create the health ingestion tokens of services and the last report each pushed
+	createServiceHealthPushTable := `
+	CREATE TABLE IF NOT EXISTS service_health_push (
+		service_id TEXT PRIMARY KEY,
+		token_hash TEXT NOT NULL UNIQUE,
+		created_at DATETIME NOT NULL,
+		health_status TEXT DEFAULT '',
+		report_json TEXT DEFAULT '',
+		pushed_at DATETIME,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create per-profile Maven/Gradle build settings table
+	createProfileBuildSettingsTable := `
+	CREATE TABLE IF NOT EXISTS profile_build_settings (
+		profile_id TEXT PRIMARY KEY,
+		offline BOOLEAN DEFAULT FALSE,
+		mirror_url TEXT DEFAULT '',
+		mirror_of TEXT DEFAULT '',
+		maven_opts TEXT DEFAULT '',
+		gradle_opts TEXT DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (profile_id) REFERENCES service_profiles(id) ON DELETE CASCADE
+	);`
+
+	// Create per-service notes table
+	createServiceNotesTable := `
+	CREATE TABLE IF NOT EXISTS service_notes (
+		service_id TEXT PRIMARY KEY,
+		notes TEXT DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create per-service nginx location settings table
+	createServiceNginxLocationsTable := `
+	CREATE TABLE IF NOT EXISTS service_nginx_locations (
+		service_id TEXT PRIMARY KEY,
+		is_enabled BOOLEAN DEFAULT FALSE,
+		path_prefix TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create per-profile local hostnames table; a hostname belongs to one profile
+	createProfileHostnamesTable := `
+	CREATE TABLE IF NOT EXISTS profile_hostnames (
+		hostname TEXT PRIMARY KEY,
+		profile_id TEXT NOT NULL,
+		service_id TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (profile_id) REFERENCES service_profiles(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_profile_hostnames_profile ON profile_hostnames(profile_id);`
+
+	// Create named JVM option presets table
+	createJVMPresetsTable := `
+	CREATE TABLE IF NOT EXISTS jvm_presets (
+		name TEXT PRIMARY KEY,
+		description TEXT,
+		xms TEXT,
+		xmx TEXT,
+		gc TEXT,
+		extra_opts TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Create alert routing settings table (a single row)
+	createAlertSettingsTable := `
+	CREATE TABLE IF NOT EXISTS alert_settings (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		webhook_url TEXT NOT NULL DEFAULT '',
+		team_webhooks TEXT NOT NULL DEFAULT '{}',
+		slack_token TEXT NOT NULL DEFAULT '',
+		cooldown_seconds INTEGER NOT NULL DEFAULT 300,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Create the access settings table (a single row)
+	createAccessSettingsTable := `
+	CREATE TABLE IF NOT EXISTS access_settings (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		anonymous_read_only BOOLEAN NOT NULL DEFAULT 0,
+		require_two_factor BOOLEAN NOT NULL DEFAULT 0,
+		updated_by TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Create the TOTP secrets of users with two-factor authentication
+	createUserTwoFactorTable := `
+	CREATE TABLE IF NOT EXISTS user_two_factor (
+		user_id TEXT PRIMARY KEY,
+		secret TEXT NOT NULL,
+		is_enabled BOOLEAN NOT NULL DEFAULT 0,
+		recovery_codes TEXT NOT NULL DEFAULT '[]',
+		last_used_step INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		enabled_at DATETIME,
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+	);`
+
+	// Create the backup settings table (a single row)
+	createBackupSettingsTable := `
+	CREATE TABLE IF NOT EXISTS backup_settings (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		is_enabled BOOLEAN NOT NULL DEFAULT 1,
+		keep_daily INTEGER NOT NULL DEFAULT 7,
+		keep_weekly INTEGER NOT NULL DEFAULT 4,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Create the per-service nginx subdomains
+	createServiceDomainsTable := `
+	CREATE TABLE IF NOT EXISTS service_domains (
+		service_id TEXT PRIMARY KEY,
+		is_enabled BOOLEAN DEFAULT FALSE,
+		subdomain TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create the per-service log ingestion filters
+	createServiceLogIngestionTable := `
+	CREATE TABLE IF NOT EXISTS service_log_ingestion (
+		service_id TEXT PRIMARY KEY,
+		drop_banner BOOLEAN NOT NULL DEFAULT 0,
+		drop_patterns TEXT NOT NULL DEFAULT '[]',
+		debug_sample_percent INTEGER NOT NULL DEFAULT 0,
+		max_lines_per_second INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create the database maintenance schedule (a single row)
+	createMaintenanceSettingsTable := `
+	CREATE TABLE IF NOT EXISTS maintenance_settings (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		is_enabled BOOLEAN NOT NULL DEFAULT 1,
+		interval_hours INTEGER NOT NULL DEFAULT 168,
+		tasks TEXT NOT NULL DEFAULT '["vacuum","analyze"]',
+		vacuum_min_free_percent INTEGER NOT NULL DEFAULT 20,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Create the history of database maintenance runs
+	createMaintenanceRunsTable := `
+	CREATE TABLE IF NOT EXISTS maintenance_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tasks TEXT NOT NULL,
+		trigger_type TEXT NOT NULL,
+		status TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME,
+		size_before_bytes INTEGER NOT NULL DEFAULT 0,
+		size_after_bytes INTEGER NOT NULL DEFAULT 0,
+		problems TEXT NOT NULL DEFAULT '[]',
+		error TEXT NOT NULL DEFAULT ''
+	);`
+
+	// Create the per-service ulimits and memory hints
+	createServiceResourceLimitsTable := `
+	CREATE TABLE IF NOT EXISTS service_resource_limits (
+		service_id TEXT PRIMARY KEY,
+		nofile INTEGER NOT NULL DEFAULT 0,
+		nproc INTEGER NOT NULL DEFAULT 0,
+		memory_limit_mb INTEGER NOT NULL DEFAULT 0,
+		heap_percent INTEGER NOT NULL DEFAULT 0,
+		cpus INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);`
+
+	// Create email notification channels, the users' subscriptions to them and
+	// the notifications waiting for an hourly digest
+	createNotificationChannelsTable := `
+	CREATE TABLE IF NOT EXISTS notification_channels (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT 'email',
+		smtp_host TEXT NOT NULL,
+		smtp_port INTEGER NOT NULL,
+		smtp_username TEXT NOT NULL DEFAULT '',
+		smtp_password TEXT NOT NULL DEFAULT '',
+		from_address TEXT NOT NULL,
+		implicit_tls BOOLEAN NOT NULL DEFAULT 0,
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	createNotificationSubscriptionsTable := `
+	CREATE TABLE IF NOT EXISTS notification_subscriptions (
+		channel_id INTEGER NOT NULL,
+		user_id TEXT NOT NULL,
+		email TEXT NOT NULL,
+		events TEXT NOT NULL DEFAULT '[]',
+		mode TEXT NOT NULL DEFAULT 'immediate',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (channel_id, user_id)
+	);`
+
+	createPendingNotificationsTable := `
+	CREATE TABLE IF NOT EXISTS pending_notifications (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		channel_id INTEGER NOT NULL,
+		user_id TEXT NOT NULL,
+		service_id TEXT NOT NULL,
+		service_name TEXT NOT NULL,
+		event TEXT NOT NULL,
+		message TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);`
+
+	// Create the audit trail of faults injected by chaos testing
+	createChaosFaultsTable := `
+	CREATE TABLE IF NOT EXISTS chaos_faults (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		type TEXT NOT NULL,
+		service_id TEXT NOT NULL,
+		service_name TEXT NOT NULL,
+		profile_id TEXT NOT NULL DEFAULT '',
+		detail TEXT NOT NULL DEFAULT '',
+		trigger TEXT NOT NULL DEFAULT 'manual',
+		status TEXT NOT NULL,
+		error_message TEXT NOT NULL DEFAULT '',
+		started_at DATETIME NOT NULL,
+		ended_at DATETIME
+	);`
+
+	// Create dependency license and vulnerability scan history table
+	createDependencyScansTable := `
+	CREATE TABLE IF NOT EXISTS dependency_scans (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		service_name TEXT NOT NULL,
+		scanner TEXT NOT NULL,
+		status TEXT NOT NULL,
+		command TEXT,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME,
+		duration_ms INTEGER DEFAULT 0,
+		dependencies INTEGER DEFAULT 0,
+		critical INTEGER DEFAULT 0,
+		high INTEGER DEFAULT 0,
+		medium INTEGER DEFAULT 0,
+		low INTEGER DEFAULT 0,
+		unknown INTEGER DEFAULT 0,
+		licenses_json TEXT DEFAULT '{}',
+		findings_json TEXT DEFAULT '[]',
+		output TEXT,
+		error_message TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_dependency_scans_service ON dependency_scans(service_id, started_at);`
+
+	// Create database migration run history table
+	createMigrationRunsTable := `
+	CREATE TABLE IF NOT EXISTS migration_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		service_name TEXT NOT NULL,
+		tool TEXT NOT NULL,
+		runner TEXT NOT NULL,
+		action TEXT NOT NULL,
+		status TEXT NOT NULL,
+		command TEXT,
+		started_at DATETIME NOT NULL,
+		finished_at DATETIME,
+		duration_ms INTEGER DEFAULT 0,
+		applied INTEGER DEFAULT 0,
+		pending INTEGER DEFAULT 0,
+		migrations_json TEXT DEFAULT '[]',
+		output TEXT,
+		error_message TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_migration_runs_service ON migration_runs(service_id, started_at);`
+
+	// Create profile variants of service files and the record of those written
+	// into service directories, with the files they replaced
+	createProfileFileOverlaysTable := `
+	CREATE TABLE IF NOT EXISTS profile_file_overlays (
+		profile_id TEXT NOT NULL,
+		service_id TEXT NOT NULL,
+		path TEXT NOT NULL,
+		content TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (profile_id, service_id, path),
+		FOREIGN KEY (profile_id) REFERENCES service_profiles(id) ON DELETE CASCADE,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE
+	);
+	CREATE TABLE IF NOT EXISTS materialized_file_overlays (
+		file_path TEXT PRIMARY KEY,
+		profile_id TEXT NOT NULL,
+		service_id TEXT NOT NULL,
+		path TEXT NOT NULL,
+		original_existed BOOLEAN NOT NULL,
+		original_content TEXT,
+		written_content TEXT NOT NULL,
+		materialized_at DATETIME NOT NULL
+	);`
+
+	// Create synthetic probe definitions and their results
+	createServiceProbesTable := `
+	CREATE TABLE IF NOT EXISTS service_probes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		service_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		url TEXT NOT NULL,
+		method TEXT NOT NULL DEFAULT 'GET',
+		interval_seconds INTEGER NOT NULL,
+		expected_status INTEGER DEFAULT 0,
+		max_latency_ms INTEGER DEFAULT 0,
+		target_success_rate REAL NOT NULL,
+		window_minutes INTEGER NOT NULL,
+		enabled BOOLEAN DEFAULT TRUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE,
+		UNIQUE(service_id, name)
+	);
+	CREATE TABLE IF NOT EXISTS service_probe_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		probe_id INTEGER NOT NULL,
+		success BOOLEAN NOT NULL,
+		status_code INTEGER DEFAULT 0,
+		latency_ms INTEGER NOT NULL,
+		error TEXT,
+		created_at DATETIME NOT NULL,
+		FOREIGN KEY (probe_id) REFERENCES service_probes(id) ON DELETE CASCADE
+	);
+	CREATE INDEX IF NOT EXISTS idx_service_probe_results_probe_time ON service_probe_results(probe_id, created_at);`
+
+	// Create the audit trail of values promoted between profiles
+	createProfilePromotionsTable := `
+	CREATE TABLE IF NOT EXISTS profile_promotions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id TEXT NOT NULL,
+		source_profile_id TEXT NOT NULL,
+		source_profile_name TEXT NOT NULL,
+		target_profile_id TEXT NOT NULL,
+		target_profile_name TEXT NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		changes_json TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);`
+
 	tables := []string{
 		createServicesTable,
 		createEnvVarsTable,
@@ -330,6 +894,42 @@ func (db *Database) initTables() error {
 		createProfileServiceConfigsTable,
 		createProfileDependenciesTable,
 		createDockerConfigTable,
+		createServiceHooksTable,
+		createServiceTagsTable,
+		createExternalDependenciesTable,
+		createServiceEventsTable,
+		createProfileLogSinksTable,
+		createServiceTestRunsTable,
+		createServiceBuildsTable,
+		createRepositoryCredentialsTable,
+		createServiceLogFilesTable,
+		createServiceLogLevelRulesTable,
+		createServiceNginxLocationsTable,
+		createProfileHostnamesTable,
+		createJVMPresetsTable,
+		createAlertSettingsTable,
+		createNotificationChannelsTable,
+		createNotificationSubscriptionsTable,
+		createPendingNotificationsTable,
+		createChaosFaultsTable,
+		createDependencyScansTable,
+		createMigrationRunsTable,
+		createServiceHealthChecksTable,
+		createServiceHealthPushTable,
+		createProfileBuildSettingsTable,
+		createServiceNotesTable,
+		createProfileFileOverlaysTable,
+		createServiceProbesTable,
+		createProfilePromotionsTable,
+		createAccessSettingsTable,
+		createServiceDomainsTable,
+		createBackupSettingsTable,
+		createServiceResourceLimitsTable,
+		createServiceLogIngestionTable,
+		createUserTwoFactorTable,
+		createMaintenanceSettingsTable,
+		createMaintenanceRunsTable,
+		createServiceRestartsTable,
 	}
 
 	for _, table := range tables {
@@ -354,6 +954,96 @@ func (db *Database) initTables() error {
 		return fmt.Errorf("failed to add verbose_logging column: %w", err)
 	}
 
+	// Add idle_timeout_minutes column for idle auto-suspend
+	if err := db.migrateAddIdleTimeoutColumn(); err != nil {
+		return fmt.Errorf("failed to add idle_timeout_minutes column: %w", err)
+	}
+
+	// Add health_interval_seconds column for per-service health check cadence
+	if err := db.migrateAddHealthIntervalColumn(); err != nil {
+		return fmt.Errorf("failed to add health_interval_seconds column: %w", err)
+	}
+
+	// Add memory budget columns for profile admission control
+	if err := db.migrateAddProfileMemoryBudgetColumns(); err != nil {
+		return fmt.Errorf("failed to add memory budget columns: %w", err)
+	}
+
+	// Add port pool columns for automatic port assignment
+	if err := db.migrateAddProfilePortPoolColumns(); err != nil {
+		return fmt.Errorf("failed to add port pool columns: %w", err)
+	}
+
+	// Add java_opts_preset column and the built-in JVM presets
+	if err := db.migrateAddJavaOptsPresetColumn(); err != nil {
+		return fmt.Errorf("failed to add java_opts_preset column: %w", err)
+	}
+
+	// Add skip_discovery column for opting out of service discovery variables
+	if err := db.migrateAddSkipDiscoveryColumn(); err != nil {
+		return fmt.Errorf("failed to add skip_discovery column: %w", err)
+	}
+
+	// Add owner columns for routing a service's alerts
+	if err := db.migrateAddOwnerColumns(); err != nil {
+		return fmt.Errorf("failed to add owner columns: %w", err)
+	}
+
+	// Add run_as_user column for starting services as another OS user
+	if err := db.migrateAddRunAsUserColumn(); err != nil {
+		return fmt.Errorf("failed to add run_as_user column: %w", err)
+	}
+
+	// Add readiness column for HTTP readiness probes of service dependencies
+	if err := db.migrateAddDependencyReadinessColumn(); err != nil {
+		return fmt.Errorf("failed to add readiness column: %w", err)
+	}
+
+	// Add archived_at column for archiving services without deleting them
+	if err := db.migrateAddArchivedAtColumn(); err != nil {
+		return fmt.Errorf("failed to add archived_at column: %w", err)
+	}
+
+	// Add execution_mode column for running services from their packaged jar
+	if err := db.migrateAddExecutionModeColumn(); err != nil {
+		return fmt.Errorf("failed to add execution_mode column: %w", err)
+	}
+
+	// Add working_dir, start_command and build_command columns for per-service command overrides
+	if err := db.migrateAddCommandOverrideColumns(); err != nil {
+		return fmt.Errorf("failed to add command override columns: %w", err)
+	}
+
+	// Add auto_migrate column for applying database migrations before start
+	if err := db.migrateAddAutoMigrateColumn(); err != nil {
+		return fmt.Errorf("failed to add auto_migrate column: %w", err)
+	}
+
+	// Add infra_type and infra_runtime columns for infrastructure services run by Vertex
+	if err := db.migrateAddInfrastructureColumns(); err != nil {
+		return fmt.Errorf("failed to add infrastructure columns: %w", err)
+	}
+
+	// Add require_two_factor column for the two-factor authentication policy
+	if err := db.migrateAddRequireTwoFactorColumn(); err != nil {
+		return fmt.Errorf("failed to add require_two_factor column: %w", err)
+	}
+
+	// Add interactive column for services whose stdin stays open
+	if err := db.migrateAddInteractiveColumn(); err != nil {
+		return fmt.Errorf("failed to add interactive column: %w", err)
+	}
+
+	// Add reload_on_sighup column for services that re-read their configuration on SIGHUP
+	if err := db.migrateAddReloadOnSighupColumn(); err != nil {
+		return fmt.Errorf("failed to add reload_on_sighup column: %w", err)
+	}
+
+	// Add value_type and value_pattern columns for env var validation schemas
+	if err := db.migrateAddEnvVarSchemaColumns(); err != nil {
+		return fmt.Errorf("failed to add env var schema columns: %w", err)
+	}
+
 	return nil
 }
 
@@ -596,15 +1286,25 @@ func (db *Database) SaveServiceDependencies(serviceUUID string, dependencies []a
 				dependencyType = "hard"
 			}
 
+			// The HTTP readiness probe is kept as JSON
+			readiness := ""
+			if probe, ok := depMap["readiness"].(map[string]any); ok && len(probe) > 0 {
+				encoded, err := json.Marshal(probe)
+				if err != nil {
+					return fmt.Errorf("failed to encode readiness probe of %s -> %s: %w", serviceUUID, dependencyServiceUUID, err)
+				}
+				readiness = string(encoded)
+			}
+
 			_, err = tx.Exec(`
 				INSERT INTO service_dependencies (
 					service_id, dependency_service_id, dependency_type, 
 					health_check, timeout_seconds, retry_interval_seconds, 
-					is_required, description, updated_at
-				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+					is_required, description, readiness, updated_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
 				serviceUUID, dependencyServiceUUID, dependencyType,
 				healthCheck, timeoutSeconds, retryIntervalSeconds,
-				isRequired, description)
+				isRequired, description, readiness)
 			if err != nil {
 				return fmt.Errorf("failed to insert dependency %s -> %s: %w", serviceUUID, dependencyServiceUUID, err)
 			}
@@ -618,7 +1318,8 @@ func (db *Database) SaveServiceDependencies(serviceUUID string, dependencies []a
 func (db *Database) LoadServiceDependencies(serviceUUID string) ([]map[string]any, error) {
 	rows, err := db.Query(`
 		SELECT dependency_service_id, dependency_type, health_check, 
-		       timeout_seconds, retry_interval_seconds, is_required, description
+		       timeout_seconds, retry_interval_seconds, is_required, description,
+		       COALESCE(readiness, '')
 		FROM service_dependencies 
 		WHERE service_id = ?
 		ORDER BY dependency_service_id`, serviceUUID)
@@ -629,17 +1330,17 @@ func (db *Database) LoadServiceDependencies(serviceUUID string) ([]map[string]an
 
 	var dependencies []map[string]any
 	for rows.Next() {
-		var dependencyServiceUUID, dependencyType, description string
+		var dependencyServiceUUID, dependencyType, description, readiness string
 		var healthCheck, isRequired bool
 		var timeoutSeconds, retryIntervalSeconds int
 
 		err := rows.Scan(&dependencyServiceUUID, &dependencyType, &healthCheck,
-			&timeoutSeconds, &retryIntervalSeconds, &isRequired, &description)
+			&timeoutSeconds, &retryIntervalSeconds, &isRequired, &description, &readiness)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan dependency: %w", err)
 		}
 
-		dependencies = append(dependencies, map[string]any{
+		dependency := map[string]any{
 			"serviceId":            dependencyServiceUUID,
 			"type":                 dependencyType,
 			"healthCheck":          healthCheck,
@@ -647,7 +1348,9 @@ func (db *Database) LoadServiceDependencies(serviceUUID string) ([]map[string]an
 			"retryIntervalSeconds": retryIntervalSeconds,
 			"required":             isRequired,
 			"description":          description,
-		})
+		}
+		addDependencyReadiness(dependency, readiness)
+		dependencies = append(dependencies, dependency)
 	}
 
 	return dependencies, rows.Err()
@@ -657,7 +1360,8 @@ func (db *Database) LoadServiceDependencies(serviceUUID string) ([]map[string]an
 func (db *Database) GetAllServiceDependencies() (map[string][]map[string]any, error) {
 	rows, err := db.Query(`
 		SELECT service_id, dependency_service_id, dependency_type, health_check, 
-		       timeout_seconds, retry_interval_seconds, is_required, description
+		       timeout_seconds, retry_interval_seconds, is_required, description,
+		       COALESCE(readiness, '')
 		FROM service_dependencies 
 		ORDER BY service_id, dependency_service_id`)
 	if err != nil {
@@ -667,12 +1371,12 @@ func (db *Database) GetAllServiceDependencies() (map[string][]map[string]any, er
 
 	allDependencies := make(map[string][]map[string]any)
 	for rows.Next() {
-		var serviceUUID, dependencyServiceUUID, dependencyType, description string
+		var serviceUUID, dependencyServiceUUID, dependencyType, description, readiness string
 		var healthCheck, isRequired bool
 		var timeoutSeconds, retryIntervalSeconds int
 
 		err := rows.Scan(&serviceUUID, &dependencyServiceUUID, &dependencyType, &healthCheck,
-			&timeoutSeconds, &retryIntervalSeconds, &isRequired, &description)
+			&timeoutSeconds, &retryIntervalSeconds, &isRequired, &description, &readiness)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan dependency: %w", err)
 		}
@@ -681,7 +1385,7 @@ func (db *Database) GetAllServiceDependencies() (map[string][]map[string]any, er
 			allDependencies[serviceUUID] = []map[string]any{}
 		}
 
-		allDependencies[serviceUUID] = append(allDependencies[serviceUUID], map[string]any{
+		dependency := map[string]any{
 			"serviceId":            dependencyServiceUUID,
 			"type":                 dependencyType,
 			"healthCheck":          healthCheck,
@@ -689,78 +1393,509 @@ func (db *Database) GetAllServiceDependencies() (map[string][]map[string]any, er
 			"retryIntervalSeconds": retryIntervalSeconds,
 			"required":             isRequired,
 			"description":          description,
-		})
+		}
+		addDependencyReadiness(dependency, readiness)
+		allDependencies[serviceUUID] = append(allDependencies[serviceUUID], dependency)
 	}
 
 	return allDependencies, rows.Err()
 }
 
-// Profile-scoped environment variable methods
+// addDependencyReadiness adds a stored readiness probe to a dependency as
+// "readiness", in the same shape SaveServiceDependencies accepts
+func addDependencyReadiness(dependency map[string]any, readiness string) {
+	if readiness == "" {
+		return
+	}
+	var probe map[string]any
+	if err := json.Unmarshal([]byte(readiness), &probe); err != nil {
+		log.Printf("[WARN] Ignoring invalid readiness probe of dependency %v: %v", dependency["serviceId"], err)
+		return
+	}
+	dependency["readiness"] = probe
+}
 
-// GetProfileEnvVars retrieves all environment variables for a specific profile
-func (db *Database) GetProfileEnvVars(profileID string) (map[string]string, error) {
-	query := `SELECT var_name, var_value FROM profile_env_vars WHERE profile_id = ? ORDER BY var_name`
-	rows, err := db.Query(query, profileID)
+// GetServiceHooks returns the lifecycle hooks configured for a service in execution order
+func (db *Database) GetServiceHooks(serviceUUID string) ([]models.ServiceHook, error) {
+	rows, err := db.Query(`
+		SELECT id, service_id, phase, hook_type, COALESCE(command, ''), COALESCE(url, ''), COALESCE(method, ''),
+		       COALESCE(body, ''), timeout_seconds, abort_on_failure, is_enabled
+		FROM service_hooks
+		WHERE service_id = ?
+		ORDER BY hook_order, id`, serviceUUID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query profile env vars: %w", err)
+		return nil, fmt.Errorf("failed to query hooks for UUID %s: %w", serviceUUID, err)
 	}
 	defer rows.Close()
 
-	envVars := make(map[string]string)
+	hooks := []models.ServiceHook{}
 	for rows.Next() {
-		var name, value string
-		if err := rows.Scan(&name, &value); err != nil {
-			return nil, fmt.Errorf("failed to scan profile env var: %w", err)
+		var hook models.ServiceHook
+		if err := rows.Scan(&hook.ID, &hook.ServiceID, &hook.Phase, &hook.Type, &hook.Command, &hook.URL, &hook.Method,
+			&hook.Body, &hook.TimeoutSeconds, &hook.AbortOnFailure, &hook.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan hook: %w", err)
 		}
-		envVars[name] = value
+		hooks = append(hooks, hook)
 	}
 
-	return envVars, rows.Err()
+	return hooks, rows.Err()
 }
 
-// SetProfileEnvVar sets an environment variable for a specific profile
-func (db *Database) SetProfileEnvVar(profileID, name, value, description string, isRequired bool) error {
-	query := `INSERT OR REPLACE INTO profile_env_vars (profile_id, var_name, var_value, description, is_required, updated_at) 
-			  VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
-	_, err := db.Exec(query, profileID, name, value, description, isRequired)
+// SaveServiceHooks replaces all lifecycle hooks for a service
+func (db *Database) SaveServiceHooks(serviceUUID string, hooks []models.ServiceHook) error {
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to set profile env var %s: %w", name, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	return nil
-}
+	defer tx.Rollback()
 
-// DeleteProfileEnvVar deletes an environment variable for a specific profile
-func (db *Database) DeleteProfileEnvVar(profileID, name string) error {
-	query := `DELETE FROM profile_env_vars WHERE profile_id = ? AND var_name = ?`
-	_, err := db.Exec(query, profileID, name)
-	if err != nil {
-		return fmt.Errorf("failed to delete profile env var %s: %w", name, err)
+	if _, err := tx.Exec("DELETE FROM service_hooks WHERE service_id = ?", serviceUUID); err != nil {
+		return fmt.Errorf("failed to clear existing hooks for UUID %s: %w", serviceUUID, err)
 	}
-	return nil
-}
 
-// Profile-scoped service configuration methods
+	for i, hook := range hooks {
+		_, err = tx.Exec(`
+			INSERT INTO service_hooks (
+				service_id, phase, hook_type, command, url, method, body,
+				timeout_seconds, abort_on_failure, is_enabled, hook_order, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+			serviceUUID, hook.Phase, hook.Type, hook.Command, hook.URL, hook.Method, hook.Body,
+			hook.TimeoutSeconds, hook.AbortOnFailure, hook.Enabled, i)
+		if err != nil {
+			return fmt.Errorf("failed to insert %s hook for UUID %s: %w", hook.Phase, serviceUUID, err)
+		}
+	}
 
-// GetProfileServiceConfig retrieves service configuration overrides for a specific profile
-func (db *Database) GetProfileServiceConfig(profileID, serviceUUID string) (map[string]string, error) {
-	query := `SELECT config_key, config_value FROM profile_service_configs 
-			  WHERE profile_id = ? AND service_id = ? ORDER BY config_key`
-	rows, err := db.Query(query, profileID, serviceUUID)
+	return tx.Commit()
+}
+
+// GetExternalDependencies returns the external dependencies configured for a service
+func (db *Database) GetExternalDependencies(serviceUUID string) ([]models.ExternalDependency, error) {
+	rows, err := db.Query(`
+		SELECT id, service_id, name, dependency_type, target, timeout_seconds, is_required, is_enabled
+		FROM service_external_dependencies
+		WHERE service_id = ?
+		ORDER BY id`, serviceUUID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query profile service config for UUID %s: %w", serviceUUID, err)
+		return nil, fmt.Errorf("failed to query external dependencies for UUID %s: %w", serviceUUID, err)
 	}
 	defer rows.Close()
 
-	config := make(map[string]string)
+	dependencies := []models.ExternalDependency{}
 	for rows.Next() {
-		var key, value string
-		if err := rows.Scan(&key, &value); err != nil {
-			return nil, fmt.Errorf("failed to scan profile service config: %w", err)
+		var dep models.ExternalDependency
+		if err := rows.Scan(&dep.ID, &dep.ServiceID, &dep.Name, &dep.Type, &dep.Target,
+			&dep.TimeoutSeconds, &dep.Required, &dep.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan external dependency: %w", err)
 		}
-		config[key] = value
+		dependencies = append(dependencies, dep)
 	}
 
-	return config, rows.Err()
+	return dependencies, rows.Err()
+}
+
+// SaveExternalDependencies replaces all external dependencies for a service
+func (db *Database) SaveExternalDependencies(serviceUUID string, dependencies []models.ExternalDependency) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM service_external_dependencies WHERE service_id = ?", serviceUUID); err != nil {
+		return fmt.Errorf("failed to clear external dependencies for UUID %s: %w", serviceUUID, err)
+	}
+
+	for _, dep := range dependencies {
+		_, err = tx.Exec(`
+			INSERT INTO service_external_dependencies (
+				service_id, name, dependency_type, target, timeout_seconds, is_required, is_enabled, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+			serviceUUID, dep.Name, dep.Type, dep.Target, dep.TimeoutSeconds, dep.Required, dep.Enabled)
+		if err != nil {
+			return fmt.Errorf("failed to insert external dependency %s for UUID %s: %w", dep.Name, serviceUUID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLogSinkConfigs returns the external log sink configuration of every profile
+func (db *Database) GetLogSinkConfigs() ([]models.LogSinkConfig, error) {
+	rows, err := db.Query(`
+		SELECT profile_id, sink_type, url, COALESCE(index_name, ''), COALESCE(labels_json, ''),
+			COALESCE(username, ''), COALESCE(password, ''), batch_size, flush_interval_seconds,
+			buffer_size, disable_sqlite, is_enabled
+		FROM profile_log_sinks`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query log sinks: %w", err)
+	}
+	defer rows.Close()
+
+	configs := []models.LogSinkConfig{}
+	for rows.Next() {
+		var config models.LogSinkConfig
+		var labelsJSON string
+		if err := rows.Scan(&config.ProfileID, &config.Type, &config.URL, &config.Index, &labelsJSON,
+			&config.Username, &config.Password, &config.BatchSize, &config.FlushIntervalSeconds,
+			&config.BufferSize, &config.DisableSQLite, &config.Enabled); err != nil {
+			return nil, fmt.Errorf("failed to scan log sink: %w", err)
+		}
+		if labelsJSON != "" {
+			if err := json.Unmarshal([]byte(labelsJSON), &config.Labels); err != nil {
+				log.Printf("[WARN] Invalid log sink labels for profile %s: %v", config.ProfileID, err)
+			}
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+// SaveLogSinkConfig creates or replaces the external log sink of a profile
+func (db *Database) SaveLogSinkConfig(config models.LogSinkConfig) error {
+	labelsJSON, err := json.Marshal(config.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to encode log sink labels: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT OR REPLACE INTO profile_log_sinks (
+			profile_id, sink_type, url, index_name, labels_json, username, password, batch_size,
+			flush_interval_seconds, buffer_size, disable_sqlite, is_enabled, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+		config.ProfileID, config.Type, config.URL, config.Index, string(labelsJSON), config.Username,
+		config.Password, config.BatchSize, config.FlushIntervalSeconds, config.BufferSize,
+		config.DisableSQLite, config.Enabled)
+	if err != nil {
+		return fmt.Errorf("failed to save log sink for profile %s: %w", config.ProfileID, err)
+	}
+	return nil
+}
+
+// DeleteLogSinkConfig removes the external log sink of a profile
+func (db *Database) DeleteLogSinkConfig(profileID string) error {
+	if _, err := db.Exec("DELETE FROM profile_log_sinks WHERE profile_id = ?", profileID); err != nil {
+		return fmt.Errorf("failed to delete log sink for profile %s: %w", profileID, err)
+	}
+	return nil
+}
+
+// InsertServiceEvent appends an event to a service's timeline
+func (db *Database) InsertServiceEvent(event models.ServiceEvent) error {
+	_, err := db.Exec(`INSERT INTO service_events (service_id, service_name, event_type, message, created_at) VALUES (?, ?, ?, ?, ?)`,
+		event.ServiceID, event.ServiceName, event.Type, event.Message, event.Timestamp.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert %s event for UUID %s: %w", event.Type, event.ServiceID, err)
+	}
+	return nil
+}
+
+// GetServiceEvents returns the events of the given services newer than since,
+// newest first
+func (db *Database) GetServiceEvents(serviceUUIDs []string, since time.Time, limit int) ([]models.ServiceEvent, error) {
+	events := []models.ServiceEvent{}
+	if len(serviceUUIDs) == 0 {
+		return events, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(serviceUUIDs)), ",")
+	args := make([]interface{}, 0, len(serviceUUIDs)+2)
+	for _, id := range serviceUUIDs {
+		args = append(args, id)
+	}
+	args = append(args, since.UTC(), limit)
+
+	rows, err := db.Query(`
+		SELECT id, service_id, service_name, event_type, COALESCE(message, ''), created_at
+		FROM service_events
+		WHERE service_id IN (`+placeholders+`) AND created_at >= ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.ServiceEvent
+		if err := rows.Scan(&event.ID, &event.ServiceID, &event.ServiceName, &event.Type, &event.Message, &event.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan service event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// CleanupServiceEvents removes events older than the cutoff
+func (db *Database) CleanupServiceEvents(before time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM service_events WHERE created_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up service events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// InsertServiceRestart records a restart or crash of a service and drops
+// those recorded before keepSince
+func (db *Database) InsertServiceRestart(serviceUUID, reason string, at, keepSince time.Time) error {
+	_, err := db.Exec(`INSERT INTO service_restarts (service_id, reason, restarted_at) VALUES (?, ?, ?)`, serviceUUID, reason, at.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert %s restart for UUID %s: %w", reason, serviceUUID, err)
+	}
+	if _, err := db.Exec(`DELETE FROM service_restarts WHERE restarted_at < ?`, keepSince.UTC()); err != nil {
+		return fmt.Errorf("failed to prune service restarts: %w", err)
+	}
+	return nil
+}
+
+// GetServiceRestarts returns the restarts of all services since a point in
+// time, oldest first
+func (db *Database) GetServiceRestarts(since time.Time) ([]models.ServiceRestart, error) {
+	rows, err := db.Query(`SELECT service_id, reason, restarted_at FROM service_restarts WHERE restarted_at >= ? ORDER BY restarted_at, id`, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service restarts: %w", err)
+	}
+	defer rows.Close()
+
+	restarts := []models.ServiceRestart{}
+	for rows.Next() {
+		var restart models.ServiceRestart
+		if err := rows.Scan(&restart.ServiceID, &restart.Reason, &restart.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan service restart: %w", err)
+		}
+		restarts = append(restarts, restart)
+	}
+	return restarts, rows.Err()
+}
+
+// InsertTestRun records a new test run and returns its ID
+func (db *Database) InsertTestRun(run *models.TestRun) (int64, error) {
+	result, err := db.Exec(`INSERT INTO service_test_runs (service_id, service_name, status, command, filter, started_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		run.ServiceID, run.ServiceName, run.Status, run.Command, run.Filter, run.StartedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert test run for UUID %s: %w", run.ServiceID, err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateTestRun stores the outcome of a finished test run
+func (db *Database) UpdateTestRun(run *models.TestRun) error {
+	suitesJSON, err := json.Marshal(run.Suites)
+	if err != nil {
+		return fmt.Errorf("failed to marshal test suites: %w", err)
+	}
+
+	var finishedAt interface{}
+	if run.FinishedAt != nil {
+		finishedAt = run.FinishedAt.UTC()
+	}
+
+	_, err = db.Exec(`
+		UPDATE service_test_runs
+		SET status = ?, finished_at = ?, duration_ms = ?, total = ?, failures = ?, errors = ?, skipped = ?,
+			suites_json = ?, output = ?, error_message = ?
+		WHERE id = ?`,
+		run.Status, finishedAt, run.DurationMs, run.Total, run.Failures, run.Errors, run.Skipped,
+		string(suitesJSON), run.Output, run.Error, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update test run %d: %w", run.ID, err)
+	}
+	return nil
+}
+
+// GetTestRuns returns the most recent test runs of a service, newest first,
+// without suite details or output
+func (db *Database) GetTestRuns(serviceUUID string, limit int) ([]models.TestRun, error) {
+	rows, err := db.Query(`
+		SELECT id, service_id, service_name, status, COALESCE(command, ''), COALESCE(filter, ''), started_at, finished_at,
+			duration_ms, total, failures, errors, skipped, COALESCE(error_message, '')
+		FROM service_test_runs
+		WHERE service_id = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?`, serviceUUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query test runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.TestRun{}
+	for rows.Next() {
+		var run models.TestRun
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&run.ID, &run.ServiceID, &run.ServiceName, &run.Status, &run.Command, &run.Filter, &run.StartedAt, &finishedAt,
+			&run.DurationMs, &run.Total, &run.Failures, &run.Errors, &run.Skipped, &run.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan test run: %w", err)
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetTestRun returns a single test run of a service including its suites and output
+func (db *Database) GetTestRun(serviceUUID string, runID int64) (*models.TestRun, error) {
+	var run models.TestRun
+	var finishedAt sql.NullTime
+	var suitesJSON string
+	err := db.QueryRow(`
+		SELECT id, service_id, service_name, status, COALESCE(command, ''), COALESCE(filter, ''), started_at, finished_at,
+			duration_ms, total, failures, errors, skipped, COALESCE(suites_json, '[]'), COALESCE(output, ''), COALESCE(error_message, '')
+		FROM service_test_runs
+		WHERE id = ? AND service_id = ?`, runID, serviceUUID).
+		Scan(&run.ID, &run.ServiceID, &run.ServiceName, &run.Status, &run.Command, &run.Filter, &run.StartedAt, &finishedAt,
+			&run.DurationMs, &run.Total, &run.Failures, &run.Errors, &run.Skipped, &suitesJSON, &run.Output, &run.Error)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("test run %d not found", runID)
+		}
+		return nil, fmt.Errorf("failed to load test run %d: %w", runID, err)
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	if err := json.Unmarshal([]byte(suitesJSON), &run.Suites); err != nil {
+		return nil, fmt.Errorf("failed to parse test suites of run %d: %w", runID, err)
+	}
+	return &run, nil
+}
+
+// PruneTestRuns keeps only the newest runs of a service
+func (db *Database) PruneTestRuns(serviceUUID string, keep int) error {
+	_, err := db.Exec(`
+		DELETE FROM service_test_runs
+		WHERE service_id = ? AND id NOT IN (
+			SELECT id FROM service_test_runs WHERE service_id = ? ORDER BY started_at DESC, id DESC LIMIT ?
+		)`, serviceUUID, serviceUUID, keep)
+	if err != nil {
+		return fmt.Errorf("failed to prune test runs for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// FailInterruptedTestRuns marks runs left running by a previous process as errored
+func (db *Database) FailInterruptedTestRuns() error {
+	_, err := db.Exec(`UPDATE service_test_runs SET status = 'error', error_message = 'Interrupted by a Vertex restart' WHERE status = 'running'`)
+	if err != nil {
+		return fmt.Errorf("failed to close interrupted test runs: %w", err)
+	}
+	return nil
+}
+
+// GetAllServiceTags returns the tags of every service keyed by service UUID
+func (db *Database) GetAllServiceTags() (map[string]map[string]string, error) {
+	rows, err := db.Query("SELECT service_id, tag_key, tag_value FROM service_tags ORDER BY service_id, tag_key")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[string]map[string]string)
+	for rows.Next() {
+		var serviceID, key, value string
+		if err := rows.Scan(&serviceID, &key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan service tag: %w", err)
+		}
+		if tags[serviceID] == nil {
+			tags[serviceID] = make(map[string]string)
+		}
+		tags[serviceID][key] = value
+	}
+
+	return tags, rows.Err()
+}
+
+// SaveServiceTags replaces all tags for a service
+func (db *Database) SaveServiceTags(serviceUUID string, tags map[string]string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM service_tags WHERE service_id = ?", serviceUUID); err != nil {
+		return fmt.Errorf("failed to clear existing tags for UUID %s: %w", serviceUUID, err)
+	}
+
+	for key, value := range tags {
+		if _, err := tx.Exec("INSERT INTO service_tags (service_id, tag_key, tag_value) VALUES (?, ?, ?)", serviceUUID, key, value); err != nil {
+			return fmt.Errorf("failed to insert tag %s for UUID %s: %w", key, serviceUUID, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Profile-scoped environment variable methods
+
+// GetProfileEnvVars retrieves all environment variables for a specific profile
+func (db *Database) GetProfileEnvVars(profileID string) (map[string]string, error) {
+	query := `SELECT var_name, var_value FROM profile_env_vars WHERE profile_id = ? ORDER BY var_name`
+	rows, err := db.Query(query, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile env vars: %w", err)
+	}
+	defer rows.Close()
+
+	envVars := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan profile env var: %w", err)
+		}
+		envVars[name] = value
+	}
+
+	return envVars, rows.Err()
+}
+
+// SetProfileEnvVar sets an environment variable for a specific profile
+func (db *Database) SetProfileEnvVar(profileID, name, value, description string, isRequired bool) error {
+	query := `INSERT OR REPLACE INTO profile_env_vars (profile_id, var_name, var_value, description, is_required, updated_at) 
+			  VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	_, err := db.Exec(query, profileID, name, value, description, isRequired)
+	if err != nil {
+		return fmt.Errorf("failed to set profile env var %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteProfileEnvVar deletes an environment variable for a specific profile
+func (db *Database) DeleteProfileEnvVar(profileID, name string) error {
+	query := `DELETE FROM profile_env_vars WHERE profile_id = ? AND var_name = ?`
+	_, err := db.Exec(query, profileID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete profile env var %s: %w", name, err)
+	}
+	return nil
+}
+
+// Profile-scoped service configuration methods
+
+// GetProfileServiceConfig retrieves service configuration overrides for a specific profile
+func (db *Database) GetProfileServiceConfig(profileID, serviceUUID string) (map[string]string, error) {
+	query := `SELECT config_key, config_value FROM profile_service_configs 
+			  WHERE profile_id = ? AND service_id = ? ORDER BY config_key`
+	rows, err := db.Query(query, profileID, serviceUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile service config for UUID %s: %w", serviceUUID, err)
+	}
+	defer rows.Close()
+
+	config := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan profile service config: %w", err)
+		}
+		config[key] = value
+	}
+
+	return config, rows.Err()
 }
 
 // SetProfileServiceConfig sets a service configuration override for a specific profile
@@ -775,6 +1910,53 @@ func (db *Database) SetProfileServiceConfig(profileID, serviceUUID, key, value,
 	return nil
 }
 
+// GetProfileServiceConfigByType retrieves the service configuration overrides of one config type
+func (db *Database) GetProfileServiceConfigByType(profileID, serviceUUID, configType string) (map[string]string, error) {
+	query := `SELECT config_key, config_value FROM profile_service_configs
+			  WHERE profile_id = ? AND service_id = ? AND config_type = ? ORDER BY config_key`
+	rows, err := db.Query(query, profileID, serviceUUID, configType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s configs for UUID %s: %w", configType, serviceUUID, err)
+	}
+	defer rows.Close()
+
+	config := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan profile service config: %w", err)
+		}
+		config[key] = value
+	}
+
+	return config, rows.Err()
+}
+
+// ReplaceProfileServiceConfigsByType replaces all service configuration overrides of one config type
+func (db *Database) ReplaceProfileServiceConfigsByType(profileID, serviceUUID, configType string, config map[string]string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM profile_service_configs WHERE profile_id = ? AND service_id = ? AND config_type = ?`,
+		profileID, serviceUUID, configType); err != nil {
+		return fmt.Errorf("failed to clear %s configs for UUID %s: %w", configType, serviceUUID, err)
+	}
+
+	for key, value := range config {
+		_, err := tx.Exec(`INSERT OR REPLACE INTO profile_service_configs
+			(profile_id, service_id, config_key, config_value, config_type, updated_at)
+			VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`, profileID, serviceUUID, key, value, configType)
+		if err != nil {
+			return fmt.Errorf("failed to set profile service config %s.%s: %w", serviceUUID, key, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 // DeleteProfileServiceConfig deletes a service configuration override for a specific profile
 func (db *Database) DeleteProfileServiceConfig(profileID, serviceUUID, key string) error {
 	query := `DELETE FROM profile_service_configs WHERE profile_id = ? AND service_id = ? AND config_key = ?`
@@ -977,3 +2159,2258 @@ func (db *Database) migrateAddVerboseLoggingColumn() error {
 	log.Println("[INFO] Successfully added 'verbose_logging' column to services table")
 	return nil
 }
+
+// migrateAddIdleTimeoutColumn adds the idle_timeout_minutes column to the services table
+func (db *Database) migrateAddIdleTimeoutColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "idle_timeout_minutes") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'idle_timeout_minutes' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN idle_timeout_minutes INTEGER DEFAULT 0`)
+	if err != nil {
+		return fmt.Errorf("failed to add idle_timeout_minutes column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddHealthIntervalColumn adds the health_interval_seconds column to the services table
+func (db *Database) migrateAddHealthIntervalColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "health_interval_seconds") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'health_interval_seconds' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN health_interval_seconds INTEGER DEFAULT 0`)
+	if err != nil {
+		return fmt.Errorf("failed to add health_interval_seconds column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddProfileMemoryBudgetColumns adds the memory budget columns to the service_profiles table
+func (db *Database) migrateAddProfileMemoryBudgetColumns() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='service_profiles'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query service_profiles table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "memory_budget_mb") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding memory budget columns to service_profiles table")
+
+	if _, err := db.Exec(`ALTER TABLE service_profiles ADD COLUMN memory_budget_mb INTEGER DEFAULT 0`); err != nil {
+		return fmt.Errorf("failed to add memory_budget_mb column: %w", err)
+	}
+	if _, err := db.Exec(`ALTER TABLE service_profiles ADD COLUMN memory_budget_mode TEXT DEFAULT 'warn'`); err != nil {
+		return fmt.Errorf("failed to add memory_budget_mode column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddProfilePortPoolColumns adds the port pool columns to the service_profiles table
+func (db *Database) migrateAddProfilePortPoolColumns() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='service_profiles'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query service_profiles table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "port_pool_start") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding port pool columns to service_profiles table")
+
+	if _, err := db.Exec(`ALTER TABLE service_profiles ADD COLUMN port_pool_start INTEGER DEFAULT 0`); err != nil {
+		return fmt.Errorf("failed to add port_pool_start column: %w", err)
+	}
+	if _, err := db.Exec(`ALTER TABLE service_profiles ADD COLUMN port_pool_end INTEGER DEFAULT 0`); err != nil {
+		return fmt.Errorf("failed to add port_pool_end column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddJavaOptsPresetColumn adds the java_opts_preset column to the
+// services table and, on the same one-time upgrade, seeds the built-in
+// presets so that deleting one later sticks
+func (db *Database) migrateAddJavaOptsPresetColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "java_opts_preset") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'java_opts_preset' column to services table")
+
+	if _, err := db.Exec(`ALTER TABLE services ADD COLUMN java_opts_preset TEXT DEFAULT ''`); err != nil {
+		return fmt.Errorf("failed to add java_opts_preset column: %w", err)
+	}
+
+	defaults := []models.JVMPreset{
+		{Name: "small", Description: "Lightweight services such as config servers and gateways", Xms: "128m", Xmx: "256m", GC: "serial"},
+		{Name: "medium", Description: "Typical business services", Xms: "256m", Xmx: "512m", GC: "g1"},
+		{Name: "large", Description: "Memory-hungry services such as search or reporting", Xms: "512m", Xmx: "1g", GC: "g1"},
+	}
+	for _, preset := range defaults {
+		if _, err := db.Exec(`INSERT OR IGNORE INTO jvm_presets (name, description, xms, xmx, gc, extra_opts) VALUES (?, ?, ?, ?, ?, '')`,
+			preset.Name, preset.Description, preset.Xms, preset.Xmx, preset.GC); err != nil {
+			return fmt.Errorf("failed to seed JVM preset %s: %w", preset.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateAddSkipDiscoveryColumn adds the skip_discovery column to the services table
+func (db *Database) migrateAddSkipDiscoveryColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "skip_discovery") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'skip_discovery' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN skip_discovery BOOLEAN DEFAULT FALSE`)
+	if err != nil {
+		return fmt.Errorf("failed to add skip_discovery column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddOwnerColumns adds the owner_team, owner_slack_channel and
+// owner_email columns to the services table
+func (db *Database) migrateAddOwnerColumns() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	for _, column := range []string{"owner_team", "owner_slack_channel", "owner_email"} {
+		if strings.Contains(sql, column) {
+			continue
+		}
+
+		log.Printf("[INFO] Adding '%s' column to services table", column)
+
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE services ADD COLUMN %s TEXT DEFAULT ''", column)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateAddRunAsUserColumn adds the run_as_user column to the services table
+func (db *Database) migrateAddRunAsUserColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "run_as_user") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'run_as_user' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN run_as_user TEXT DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add run_as_user column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddExecutionModeColumn adds the execution_mode column to the services table
+func (db *Database) migrateAddExecutionModeColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "execution_mode") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'execution_mode' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN execution_mode TEXT DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add execution_mode column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddCommandOverrideColumns adds the working_dir, start_command and
+// build_command columns to the services table
+func (db *Database) migrateAddCommandOverrideColumns() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	for _, column := range []string{"working_dir", "start_command", "build_command"} {
+		if strings.Contains(sql, column) {
+			continue
+		}
+
+		log.Printf("[INFO] Adding '%s' column to services table", column)
+
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE services ADD COLUMN %s TEXT DEFAULT ''`, column)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateAddEnvVarSchemaColumns adds the value_type and value_pattern columns
+// to the service_env_vars table
+func (db *Database) migrateAddEnvVarSchemaColumns() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='service_env_vars'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query service_env_vars table schema: %w", err)
+	}
+
+	for _, column := range []string{"value_type", "value_pattern"} {
+		if strings.Contains(sql, column) {
+			continue
+		}
+
+		log.Printf("[INFO] Adding '%s' column to service_env_vars table", column)
+
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE service_env_vars ADD COLUMN %s TEXT DEFAULT ''`, column)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateAddAutoMigrateColumn adds the auto_migrate column to the services table
+func (db *Database) migrateAddAutoMigrateColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "auto_migrate") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'auto_migrate' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN auto_migrate BOOLEAN DEFAULT FALSE`)
+	if err != nil {
+		return fmt.Errorf("failed to add auto_migrate column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddInteractiveColumn adds the interactive column to the services table
+func (db *Database) migrateAddInteractiveColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "interactive") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'interactive' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN interactive BOOLEAN DEFAULT FALSE`)
+	if err != nil {
+		return fmt.Errorf("failed to add interactive column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddReloadOnSighupColumn adds the reload_on_sighup column to the services table
+func (db *Database) migrateAddReloadOnSighupColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "reload_on_sighup") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'reload_on_sighup' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN reload_on_sighup BOOLEAN DEFAULT FALSE`)
+	if err != nil {
+		return fmt.Errorf("failed to add reload_on_sighup column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddRequireTwoFactorColumn adds the require_two_factor column to the access_settings table
+func (db *Database) migrateAddRequireTwoFactorColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='access_settings'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query access_settings table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "require_two_factor") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'require_two_factor' column to access_settings table")
+
+	_, err = db.Exec(`ALTER TABLE access_settings ADD COLUMN require_two_factor BOOLEAN NOT NULL DEFAULT 0`)
+	if err != nil {
+		return fmt.Errorf("failed to add require_two_factor column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddInfrastructureColumns adds the infra_type and infra_runtime columns to the services table
+func (db *Database) migrateAddInfrastructureColumns() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	for _, column := range []string{"infra_type", "infra_runtime"} {
+		if strings.Contains(sql, column) {
+			continue
+		}
+
+		log.Printf("[INFO] Adding '%s' column to services table", column)
+
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE services ADD COLUMN %s TEXT DEFAULT ''`, column)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", column, err)
+		}
+	}
+
+	return nil
+}
+
+// migrateAddDependencyReadinessColumn adds the readiness column to the service_dependencies table
+func (db *Database) migrateAddDependencyReadinessColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='service_dependencies'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query service_dependencies table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "readiness") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'readiness' column to service_dependencies table")
+
+	_, err = db.Exec(`ALTER TABLE service_dependencies ADD COLUMN readiness TEXT DEFAULT ''`)
+	if err != nil {
+		return fmt.Errorf("failed to add readiness column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateAddArchivedAtColumn adds the archived_at column to the services table
+func (db *Database) migrateAddArchivedAtColumn() error {
+	var sql string
+	err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type='table' AND name='services'").Scan(&sql)
+	if err != nil {
+		return fmt.Errorf("failed to query services table schema: %w", err)
+	}
+
+	if strings.Contains(sql, "archived_at") {
+		return nil
+	}
+
+	log.Println("[INFO] Adding 'archived_at' column to services table")
+
+	_, err = db.Exec(`ALTER TABLE services ADD COLUMN archived_at DATETIME`)
+	if err != nil {
+		return fmt.Errorf("failed to add archived_at column: %w", err)
+	}
+
+	return nil
+}
+
+// GetRepositoryCredentials returns the artifact repository credentials of a
+// profile with their secrets as stored
+func (db *Database) GetRepositoryCredentials(profileID string) ([]models.RepositoryCredential, error) {
+	rows, err := db.Query(`
+		SELECT profile_id, server_id, kind, COALESCE(url, ''), COALESCE(username, ''), COALESCE(secret, ''),
+			COALESCE(token_header, ''), updated_at
+		FROM profile_repository_credentials
+		WHERE profile_id = ?
+		ORDER BY server_id`, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query repository credentials: %w", err)
+	}
+	defer rows.Close()
+
+	credentials := []models.RepositoryCredential{}
+	for rows.Next() {
+		var credential models.RepositoryCredential
+		if err := rows.Scan(&credential.ProfileID, &credential.ServerID, &credential.Kind, &credential.URL,
+			&credential.Username, &credential.Secret, &credential.TokenHeader, &credential.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan repository credential: %w", err)
+		}
+		credential.HasSecret = credential.Secret != ""
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+// SaveRepositoryCredential creates or replaces a repository credential of a profile
+func (db *Database) SaveRepositoryCredential(credential models.RepositoryCredential) error {
+	_, err := db.Exec(`
+		INSERT INTO profile_repository_credentials (profile_id, server_id, kind, url, username, secret, token_header)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(profile_id, server_id) DO UPDATE SET
+			kind = excluded.kind, url = excluded.url, username = excluded.username, secret = excluded.secret,
+			token_header = excluded.token_header, updated_at = CURRENT_TIMESTAMP`,
+		credential.ProfileID, credential.ServerID, credential.Kind, credential.URL, credential.Username,
+		credential.Secret, credential.TokenHeader)
+	if err != nil {
+		return fmt.Errorf("failed to save repository credential %s: %w", credential.ServerID, err)
+	}
+	return nil
+}
+
+// DeleteRepositoryCredential removes a repository credential of a profile
+func (db *Database) DeleteRepositoryCredential(profileID, serverID string) error {
+	result, err := db.Exec("DELETE FROM profile_repository_credentials WHERE profile_id = ? AND server_id = ?", profileID, serverID)
+	if err != nil {
+		return fmt.Errorf("failed to delete repository credential %s: %w", serverID, err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("repository credential %s not found", serverID)
+	}
+	return nil
+}
+
+// GetLogFileConfigs returns the log file settings of every service that has them
+func (db *Database) GetLogFileConfigs() ([]models.LogFileConfig, error) {
+	rows, err := db.Query("SELECT service_id, is_enabled, max_size_mb, max_files FROM service_log_files")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query log file settings: %w", err)
+	}
+	defer rows.Close()
+
+	configs := []models.LogFileConfig{}
+	for rows.Next() {
+		var config models.LogFileConfig
+		if err := rows.Scan(&config.ServiceID, &config.Enabled, &config.MaxSizeMB, &config.MaxFiles); err != nil {
+			return nil, fmt.Errorf("failed to scan log file settings: %w", err)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+// SaveLogFileConfig creates or replaces the log file settings of a service
+func (db *Database) SaveLogFileConfig(config models.LogFileConfig) error {
+	_, err := db.Exec(`
+		INSERT INTO service_log_files (service_id, is_enabled, max_size_mb, max_files)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET
+			is_enabled = excluded.is_enabled, max_size_mb = excluded.max_size_mb,
+			max_files = excluded.max_files, updated_at = CURRENT_TIMESTAMP`,
+		config.ServiceID, config.Enabled, config.MaxSizeMB, config.MaxFiles)
+	if err != nil {
+		return fmt.Errorf("failed to save log file settings for UUID %s: %w", config.ServiceID, err)
+	}
+	return nil
+}
+
+// GetLogLevelRules returns the log level rules of every service that has them
+func (db *Database) GetLogLevelRules() ([]models.LogLevelRules, error) {
+	rows, err := db.Query("SELECT service_id, use_defaults, rules_json FROM service_log_level_rules")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query log level rules: %w", err)
+	}
+	defer rows.Close()
+
+	ruleSets := []models.LogLevelRules{}
+	for rows.Next() {
+		var rules models.LogLevelRules
+		var rulesJSON string
+		if err := rows.Scan(&rules.ServiceID, &rules.UseDefaults, &rulesJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan log level rules: %w", err)
+		}
+		if err := json.Unmarshal([]byte(rulesJSON), &rules.Rules); err != nil {
+			log.Printf("[WARN] Ignoring unreadable log level rules of service UUID %s: %v", rules.ServiceID, err)
+			continue
+		}
+		ruleSets = append(ruleSets, rules)
+	}
+
+	return ruleSets, rows.Err()
+}
+
+// SaveLogLevelRules creates or replaces the log level rules of a service
+func (db *Database) SaveLogLevelRules(rules models.LogLevelRules) error {
+	rulesJSON, err := json.Marshal(rules.Rules)
+	if err != nil {
+		return fmt.Errorf("failed to encode log level rules: %w", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO service_log_level_rules (service_id, use_defaults, rules_json)
+		VALUES (?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET
+			use_defaults = excluded.use_defaults, rules_json = excluded.rules_json,
+			updated_at = CURRENT_TIMESTAMP`,
+		rules.ServiceID, rules.UseDefaults, string(rulesJSON))
+	if err != nil {
+		return fmt.Errorf("failed to save log level rules for UUID %s: %w", rules.ServiceID, err)
+	}
+	return nil
+}
+
+// GetLogIngestionConfigs returns the log ingestion settings of every service that has them
+func (db *Database) GetLogIngestionConfigs() ([]models.LogIngestionConfig, error) {
+	rows, err := db.Query("SELECT service_id, drop_banner, drop_patterns, debug_sample_percent, max_lines_per_second FROM service_log_ingestion")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query log ingestion settings: %w", err)
+	}
+	defer rows.Close()
+
+	configs := []models.LogIngestionConfig{}
+	for rows.Next() {
+		var config models.LogIngestionConfig
+		var patternsJSON string
+		if err := rows.Scan(&config.ServiceID, &config.DropBanner, &patternsJSON, &config.DebugSamplePercent, &config.MaxLinesPerSecond); err != nil {
+			return nil, fmt.Errorf("failed to scan log ingestion settings: %w", err)
+		}
+		if err := json.Unmarshal([]byte(patternsJSON), &config.DropPatterns); err != nil {
+			return nil, fmt.Errorf("failed to parse drop patterns for UUID %s: %w", config.ServiceID, err)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+// SaveLogIngestionConfig creates or replaces the log ingestion settings of a service
+func (db *Database) SaveLogIngestionConfig(config models.LogIngestionConfig) error {
+	patternsJSON, err := json.Marshal(config.DropPatterns)
+	if err != nil {
+		return fmt.Errorf("failed to marshal drop patterns: %w", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO service_log_ingestion (service_id, drop_banner, drop_patterns, debug_sample_percent, max_lines_per_second)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET
+			drop_banner = excluded.drop_banner, drop_patterns = excluded.drop_patterns,
+			debug_sample_percent = excluded.debug_sample_percent, max_lines_per_second = excluded.max_lines_per_second,
+			updated_at = CURRENT_TIMESTAMP`,
+		config.ServiceID, config.DropBanner, string(patternsJSON), config.DebugSamplePercent, config.MaxLinesPerSecond)
+	if err != nil {
+		return fmt.Errorf("failed to save log ingestion settings for UUID %s: %w", config.ServiceID, err)
+	}
+	return nil
+}
+
+// GetNginxLocationConfigs returns the nginx location settings of every service that has them
+func (db *Database) GetNginxLocationConfigs() ([]models.NginxLocationConfig, error) {
+	rows, err := db.Query("SELECT service_id, is_enabled, path_prefix FROM service_nginx_locations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query nginx location settings: %w", err)
+	}
+	defer rows.Close()
+
+	configs := []models.NginxLocationConfig{}
+	for rows.Next() {
+		var config models.NginxLocationConfig
+		if err := rows.Scan(&config.ServiceID, &config.Enabled, &config.Path); err != nil {
+			return nil, fmt.Errorf("failed to scan nginx location settings: %w", err)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+// SaveNginxLocationConfig creates or replaces the nginx location settings of a service
+func (db *Database) SaveNginxLocationConfig(config models.NginxLocationConfig) error {
+	_, err := db.Exec(`
+		INSERT INTO service_nginx_locations (service_id, is_enabled, path_prefix)
+		VALUES (?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET
+			is_enabled = excluded.is_enabled, path_prefix = excluded.path_prefix,
+			updated_at = CURRENT_TIMESTAMP`,
+		config.ServiceID, config.Enabled, config.Path)
+	if err != nil {
+		return fmt.Errorf("failed to save nginx location settings for UUID %s: %w", config.ServiceID, err)
+	}
+	return nil
+}
+
+// GetServiceDomainConfigs returns the subdomain settings of every service that has them
+func (db *Database) GetServiceDomainConfigs() ([]models.ServiceDomainConfig, error) {
+	rows, err := db.Query("SELECT service_id, is_enabled, subdomain FROM service_domains")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service domain settings: %w", err)
+	}
+	defer rows.Close()
+
+	configs := []models.ServiceDomainConfig{}
+	for rows.Next() {
+		var config models.ServiceDomainConfig
+		if err := rows.Scan(&config.ServiceID, &config.Enabled, &config.Subdomain); err != nil {
+			return nil, fmt.Errorf("failed to scan service domain settings: %w", err)
+		}
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+// SaveServiceDomainConfig creates or replaces the subdomain settings of a service
+func (db *Database) SaveServiceDomainConfig(config models.ServiceDomainConfig) error {
+	_, err := db.Exec(`
+		INSERT INTO service_domains (service_id, is_enabled, subdomain)
+		VALUES (?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET
+			is_enabled = excluded.is_enabled, subdomain = excluded.subdomain,
+			updated_at = CURRENT_TIMESTAMP`,
+		config.ServiceID, config.Enabled, config.Subdomain)
+	if err != nil {
+		return fmt.Errorf("failed to save service domain settings for UUID %s: %w", config.ServiceID, err)
+	}
+	return nil
+}
+
+// DeleteServiceDomainConfig removes the subdomain settings of a service
+func (db *Database) DeleteServiceDomainConfig(serviceID string) error {
+	if _, err := db.Exec("DELETE FROM service_domains WHERE service_id = ?", serviceID); err != nil {
+		return fmt.Errorf("failed to delete service domain settings for UUID %s: %w", serviceID, err)
+	}
+	return nil
+}
+
+// GetJVMPresets returns all JVM presets ordered by name
+func (db *Database) GetJVMPresets() ([]models.JVMPreset, error) {
+	rows, err := db.Query(`
+		SELECT name, COALESCE(description, ''), COALESCE(xms, ''), COALESCE(xmx, ''), COALESCE(gc, ''),
+			COALESCE(extra_opts, ''), updated_at
+		FROM jvm_presets
+		ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query JVM presets: %w", err)
+	}
+	defer rows.Close()
+
+	presets := []models.JVMPreset{}
+	for rows.Next() {
+		var preset models.JVMPreset
+		if err := rows.Scan(&preset.Name, &preset.Description, &preset.Xms, &preset.Xmx, &preset.GC,
+			&preset.ExtraOpts, &preset.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan JVM preset: %w", err)
+		}
+		presets = append(presets, preset)
+	}
+
+	return presets, rows.Err()
+}
+
+// SaveJVMPreset creates or replaces a JVM preset
+func (db *Database) SaveJVMPreset(preset models.JVMPreset) error {
+	_, err := db.Exec(`
+		INSERT INTO jvm_presets (name, description, xms, xmx, gc, extra_opts)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			description = excluded.description, xms = excluded.xms, xmx = excluded.xmx,
+			gc = excluded.gc, extra_opts = excluded.extra_opts, updated_at = CURRENT_TIMESTAMP`,
+		preset.Name, preset.Description, preset.Xms, preset.Xmx, preset.GC, preset.ExtraOpts)
+	if err != nil {
+		return fmt.Errorf("failed to save JVM preset %s: %w", preset.Name, err)
+	}
+	return nil
+}
+
+// DeleteJVMPreset deletes a JVM preset
+func (db *Database) DeleteJVMPreset(name string) error {
+	if _, err := db.Exec("DELETE FROM jvm_presets WHERE name = ?", name); err != nil {
+		return fmt.Errorf("failed to delete JVM preset %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetProfileConfigsByType returns one config value of the given type for every
+// service of a profile that has it, keyed by service UUID
+func (db *Database) GetProfileConfigsByType(profileID, configType, key string) (map[string]string, error) {
+	rows, err := db.Query(`SELECT service_id, config_value FROM profile_service_configs
+			  WHERE profile_id = ? AND config_type = ? AND config_key = ?`, profileID, configType, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s configs for profile %s: %w", configType, profileID, err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]string)
+	for rows.Next() {
+		var serviceUUID, value string
+		if err := rows.Scan(&serviceUUID, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan profile service config: %w", err)
+		}
+		values[serviceUUID] = value
+	}
+
+	return values, rows.Err()
+}
+
+// GetAlertSettings returns the alert routing settings with the Slack token as stored
+func (db *Database) GetAlertSettings() (*models.AlertSettings, error) {
+	settings := &models.AlertSettings{TeamWebhooks: map[string]string{}, Cooldown: 300}
+	var teamWebhooks string
+	var updatedAt sql.NullTime
+	err := db.QueryRow("SELECT webhook_url, team_webhooks, slack_token, cooldown_seconds, updated_at FROM alert_settings WHERE id = 1").
+		Scan(&settings.WebhookURL, &teamWebhooks, &settings.SlackToken, &settings.Cooldown, &updatedAt)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert settings: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(teamWebhooks), &settings.TeamWebhooks); err != nil {
+		return nil, fmt.Errorf("failed to parse team webhooks: %w", err)
+	}
+	if settings.TeamWebhooks == nil {
+		settings.TeamWebhooks = map[string]string{}
+	}
+	if updatedAt.Valid {
+		settings.UpdatedAt = updatedAt.Time
+	}
+	return settings, nil
+}
+
+// SaveAlertSettings creates or replaces the alert routing settings
+func (db *Database) SaveAlertSettings(settings models.AlertSettings) error {
+	teamWebhooks, err := json.Marshal(settings.TeamWebhooks)
+	if err != nil {
+		return fmt.Errorf("failed to encode team webhooks: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO alert_settings (id, webhook_url, team_webhooks, slack_token, cooldown_seconds)
+		VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			webhook_url = excluded.webhook_url, team_webhooks = excluded.team_webhooks,
+			slack_token = excluded.slack_token, cooldown_seconds = excluded.cooldown_seconds,
+			updated_at = CURRENT_TIMESTAMP`,
+		settings.WebhookURL, string(teamWebhooks), settings.SlackToken, settings.Cooldown)
+	if err != nil {
+		return fmt.Errorf("failed to save alert settings: %w", err)
+	}
+	return nil
+}
+
+// GetAccessSettings returns the access settings, all off when never saved
+func (db *Database) GetAccessSettings() (*models.AccessSettings, error) {
+	settings := &models.AccessSettings{}
+	var updatedAt sql.NullTime
+	err := db.QueryRow("SELECT anonymous_read_only, require_two_factor, updated_by, updated_at FROM access_settings WHERE id = 1").
+		Scan(&settings.AnonymousReadOnly, &settings.RequireTwoFactor, &settings.UpdatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query access settings: %w", err)
+	}
+	if updatedAt.Valid {
+		settings.UpdatedAt = updatedAt.Time
+	}
+	return settings, nil
+}
+
+// SaveAccessSettings creates or replaces the access settings
+func (db *Database) SaveAccessSettings(settings models.AccessSettings) error {
+	_, err := db.Exec(`
+		INSERT INTO access_settings (id, anonymous_read_only, require_two_factor, updated_by)
+		VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			anonymous_read_only = excluded.anonymous_read_only, require_two_factor = excluded.require_two_factor,
+			updated_by = excluded.updated_by, updated_at = CURRENT_TIMESTAMP`,
+		settings.AnonymousReadOnly, settings.RequireTwoFactor, settings.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to save access settings: %w", err)
+	}
+	return nil
+}
+
+// GetUserTwoFactor returns a user's TOTP secret, as stored (encrypted), and
+// recovery codes, or nil when the user never enrolled
+func (db *Database) GetUserTwoFactor(userID string) (*models.UserTwoFactor, error) {
+	tf := &models.UserTwoFactor{UserID: userID}
+	var recoveryCodes string
+	var createdAt, enabledAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT secret, is_enabled, recovery_codes, last_used_step, created_at, enabled_at
+		FROM user_two_factor WHERE user_id = ?`, userID).
+		Scan(&tf.Secret, &tf.Enabled, &recoveryCodes, &tf.LastUsedStep, &createdAt, &enabledAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query two-factor settings: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(recoveryCodes), &tf.RecoveryCodes); err != nil {
+		return nil, fmt.Errorf("failed to parse recovery codes: %w", err)
+	}
+	if createdAt.Valid {
+		tf.CreatedAt = createdAt.Time
+	}
+	if enabledAt.Valid {
+		tf.EnabledAt = &enabledAt.Time
+	}
+	return tf, nil
+}
+
+// SaveUserTwoFactor creates or replaces a user's TOTP secret, which the caller
+// encrypts, and recovery codes
+func (db *Database) SaveUserTwoFactor(tf models.UserTwoFactor) error {
+	recoveryCodes, err := json.Marshal(tf.RecoveryCodes)
+	if err != nil {
+		return fmt.Errorf("failed to encode recovery codes: %w", err)
+	}
+	if tf.RecoveryCodes == nil {
+		recoveryCodes = []byte("[]")
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO user_two_factor (user_id, secret, is_enabled, recovery_codes, last_used_step, enabled_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET
+			secret = excluded.secret, is_enabled = excluded.is_enabled,
+			recovery_codes = excluded.recovery_codes, last_used_step = excluded.last_used_step,
+			enabled_at = excluded.enabled_at`,
+		tf.UserID, tf.Secret, tf.Enabled, string(recoveryCodes), tf.LastUsedStep, tf.EnabledAt)
+	if err != nil {
+		return fmt.Errorf("failed to save two-factor settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserTwoFactor turns two-factor authentication off for a user
+func (db *Database) DeleteUserTwoFactor(userID string) error {
+	if _, err := db.Exec("DELETE FROM user_two_factor WHERE user_id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete two-factor settings: %w", err)
+	}
+	return nil
+}
+
+// GetBackupSettings returns the automatic backup settings, defaulting to
+// daily backups that keep 7 days and 4 weeks
+func (db *Database) GetBackupSettings() (*models.BackupSettings, error) {
+	settings := &models.BackupSettings{Enabled: true, KeepDaily: 7, KeepWeekly: 4}
+	var updatedAt sql.NullTime
+	err := db.QueryRow("SELECT is_enabled, keep_daily, keep_weekly, updated_at FROM backup_settings WHERE id = 1").
+		Scan(&settings.Enabled, &settings.KeepDaily, &settings.KeepWeekly, &updatedAt)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backup settings: %w", err)
+	}
+	if updatedAt.Valid {
+		settings.UpdatedAt = updatedAt.Time
+	}
+	return settings, nil
+}
+
+// SaveBackupSettings creates or replaces the automatic backup settings
+func (db *Database) SaveBackupSettings(settings models.BackupSettings) error {
+	_, err := db.Exec(`
+		INSERT INTO backup_settings (id, is_enabled, keep_daily, keep_weekly)
+		VALUES (1, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			is_enabled = excluded.is_enabled, keep_daily = excluded.keep_daily,
+			keep_weekly = excluded.keep_weekly, updated_at = CURRENT_TIMESTAMP`,
+		settings.Enabled, settings.KeepDaily, settings.KeepWeekly)
+	if err != nil {
+		return fmt.Errorf("failed to save backup settings: %w", err)
+	}
+	return nil
+}
+
+// InsertServiceBuild records a new build and returns its ID
+func (db *Database) InsertServiceBuild(build *models.ServiceBuild) (int64, error) {
+	result, err := db.Exec(`INSERT INTO service_builds (service_id, service_name, status, build_system, started_at) VALUES (?, ?, ?, ?, ?)`,
+		build.ServiceID, build.ServiceName, build.Status, build.BuildSystem, build.StartedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert build for UUID %s: %w", build.ServiceID, err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateServiceBuild stores the outcome of a finished build
+func (db *Database) UpdateServiceBuild(build *models.ServiceBuild) error {
+	var finishedAt interface{}
+	if build.FinishedAt != nil {
+		finishedAt = build.FinishedAt.UTC()
+	}
+
+	_, err := db.Exec(`
+		UPDATE service_builds
+		SET status = ?, finished_at = ?, duration_ms = ?, artifact_path = ?, artifact_size = ?, lines = ?, output = ?, error_message = ?
+		WHERE id = ?`,
+		build.Status, finishedAt, build.DurationMs, build.ArtifactPath, build.ArtifactSize, build.Lines, build.Output, build.Error, build.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update build %d: %w", build.ID, err)
+	}
+	return nil
+}
+
+// GetServiceBuilds returns the most recent builds of a service, newest first,
+// without their output
+func (db *Database) GetServiceBuilds(serviceUUID string, limit int) ([]models.ServiceBuild, error) {
+	rows, err := db.Query(`
+		SELECT id, service_id, service_name, status, COALESCE(build_system, ''), started_at, finished_at,
+			duration_ms, COALESCE(artifact_path, ''), artifact_size, lines, COALESCE(error_message, '')
+		FROM service_builds
+		WHERE service_id = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?`, serviceUUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query builds: %w", err)
+	}
+	defer rows.Close()
+
+	builds := []models.ServiceBuild{}
+	for rows.Next() {
+		var build models.ServiceBuild
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&build.ID, &build.ServiceID, &build.ServiceName, &build.Status, &build.BuildSystem, &build.StartedAt, &finishedAt,
+			&build.DurationMs, &build.ArtifactPath, &build.ArtifactSize, &build.Lines, &build.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan build: %w", err)
+		}
+		if finishedAt.Valid {
+			build.FinishedAt = &finishedAt.Time
+		}
+		builds = append(builds, build)
+	}
+
+	return builds, rows.Err()
+}
+
+// GetServiceBuild returns a single build of a service including its output
+func (db *Database) GetServiceBuild(serviceUUID string, buildID int64) (*models.ServiceBuild, error) {
+	var build models.ServiceBuild
+	var finishedAt sql.NullTime
+	err := db.QueryRow(`
+		SELECT id, service_id, service_name, status, COALESCE(build_system, ''), started_at, finished_at,
+			duration_ms, COALESCE(artifact_path, ''), artifact_size, lines, COALESCE(output, ''), COALESCE(error_message, '')
+		FROM service_builds
+		WHERE id = ? AND service_id = ?`, buildID, serviceUUID).
+		Scan(&build.ID, &build.ServiceID, &build.ServiceName, &build.Status, &build.BuildSystem, &build.StartedAt, &finishedAt,
+			&build.DurationMs, &build.ArtifactPath, &build.ArtifactSize, &build.Lines, &build.Output, &build.Error)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("build %d not found", buildID)
+		}
+		return nil, fmt.Errorf("failed to load build %d: %w", buildID, err)
+	}
+	if finishedAt.Valid {
+		build.FinishedAt = &finishedAt.Time
+	}
+	return &build, nil
+}
+
+// PruneServiceBuilds keeps only the newest builds of a service
+func (db *Database) PruneServiceBuilds(serviceUUID string, keep int) error {
+	_, err := db.Exec(`
+		DELETE FROM service_builds
+		WHERE service_id = ? AND id NOT IN (
+			SELECT id FROM service_builds WHERE service_id = ? ORDER BY started_at DESC, id DESC LIMIT ?
+		)`, serviceUUID, serviceUUID, keep)
+	if err != nil {
+		return fmt.Errorf("failed to prune builds for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// FailInterruptedBuilds marks builds left running by a previous process as failed
+func (db *Database) FailInterruptedBuilds() error {
+	_, err := db.Exec(`UPDATE service_builds SET status = 'failed', error_message = 'Interrupted by a Vertex restart' WHERE status = 'running'`)
+	if err != nil {
+		return fmt.Errorf("failed to close interrupted builds: %w", err)
+	}
+	return nil
+}
+
+// GetProfileHostnames returns the local hostnames of a profile, or of every
+// profile when profileID is empty, ordered by hostname
+func (db *Database) GetProfileHostnames(profileID string) ([]models.ProfileHostname, error) {
+	query := "SELECT hostname, profile_id, service_id FROM profile_hostnames"
+	args := []interface{}{}
+	if profileID != "" {
+		query += " WHERE profile_id = ?"
+		args = append(args, profileID)
+	}
+	rows, err := db.Query(query+" ORDER BY hostname", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile hostnames: %w", err)
+	}
+	defer rows.Close()
+
+	hostnames := []models.ProfileHostname{}
+	for rows.Next() {
+		var hostname models.ProfileHostname
+		if err := rows.Scan(&hostname.Hostname, &hostname.ProfileID, &hostname.ServiceID); err != nil {
+			return nil, fmt.Errorf("failed to scan profile hostname: %w", err)
+		}
+		hostnames = append(hostnames, hostname)
+	}
+
+	return hostnames, rows.Err()
+}
+
+// ReplaceProfileHostnames replaces all local hostnames of a profile
+func (db *Database) ReplaceProfileHostnames(profileID string, hostnames []models.ProfileHostname) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM profile_hostnames WHERE profile_id = ?", profileID); err != nil {
+		return fmt.Errorf("failed to delete hostnames of profile %s: %w", profileID, err)
+	}
+	for _, hostname := range hostnames {
+		if _, err := tx.Exec("INSERT INTO profile_hostnames (hostname, profile_id, service_id) VALUES (?, ?, ?)",
+			hostname.Hostname, profileID, hostname.ServiceID); err != nil {
+			return fmt.Errorf("failed to save hostname %s: %w", hostname.Hostname, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit profile hostnames: %w", err)
+	}
+	return nil
+}
+
+// DeleteProfileHostnames removes the local hostnames of a profile and returns how many there were
+func (db *Database) DeleteProfileHostnames(profileID string) (int64, error) {
+	result, err := db.Exec("DELETE FROM profile_hostnames WHERE profile_id = ?", profileID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete hostnames of profile %s: %w", profileID, err)
+	}
+	return result.RowsAffected()
+}
+
+// GetNotificationChannels returns the notification channels with their SMTP
+// passwords as stored, ordered by name
+func (db *Database) GetNotificationChannels() ([]models.NotificationChannel, error) {
+	rows, err := db.Query(`SELECT id, name, type, smtp_host, smtp_port, smtp_username, smtp_password, from_address, implicit_tls, enabled, created_at, updated_at
+		FROM notification_channels ORDER BY name, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification channels: %w", err)
+	}
+	defer rows.Close()
+
+	channels := []models.NotificationChannel{}
+	for rows.Next() {
+		var channel models.NotificationChannel
+		if err := rows.Scan(&channel.ID, &channel.Name, &channel.Type, &channel.SMTPHost, &channel.SMTPPort, &channel.SMTPUsername,
+			&channel.SMTPPassword, &channel.FromAddress, &channel.ImplicitTLS, &channel.Enabled, &channel.CreatedAt, &channel.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification channel: %w", err)
+		}
+		channels = append(channels, channel)
+	}
+
+	return channels, rows.Err()
+}
+
+// GetNotificationChannel returns a notification channel with its SMTP password as stored
+func (db *Database) GetNotificationChannel(id int64) (*models.NotificationChannel, error) {
+	var channel models.NotificationChannel
+	err := db.QueryRow(`SELECT id, name, type, smtp_host, smtp_port, smtp_username, smtp_password, from_address, implicit_tls, enabled, created_at, updated_at
+		FROM notification_channels WHERE id = ?`, id).
+		Scan(&channel.ID, &channel.Name, &channel.Type, &channel.SMTPHost, &channel.SMTPPort, &channel.SMTPUsername,
+			&channel.SMTPPassword, &channel.FromAddress, &channel.ImplicitTLS, &channel.Enabled, &channel.CreatedAt, &channel.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification channel %d not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification channel %d: %w", id, err)
+	}
+	return &channel, nil
+}
+
+// CreateNotificationChannel stores a new notification channel and returns its ID
+func (db *Database) CreateNotificationChannel(channel models.NotificationChannel) (int64, error) {
+	result, err := db.Exec(`INSERT INTO notification_channels (name, type, smtp_host, smtp_port, smtp_username, smtp_password, from_address, implicit_tls, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		channel.Name, channel.Type, channel.SMTPHost, channel.SMTPPort, channel.SMTPUsername, channel.SMTPPassword,
+		channel.FromAddress, channel.ImplicitTLS, channel.Enabled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create notification channel: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateNotificationChannel replaces the settings of a notification channel
+func (db *Database) UpdateNotificationChannel(channel models.NotificationChannel) error {
+	result, err := db.Exec(`UPDATE notification_channels SET name = ?, type = ?, smtp_host = ?, smtp_port = ?, smtp_username = ?, smtp_password = ?,
+		from_address = ?, implicit_tls = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		channel.Name, channel.Type, channel.SMTPHost, channel.SMTPPort, channel.SMTPUsername, channel.SMTPPassword,
+		channel.FromAddress, channel.ImplicitTLS, channel.Enabled, channel.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update notification channel %d: %w", channel.ID, err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("notification channel %d not found", channel.ID)
+	}
+	return nil
+}
+
+// DeleteNotificationChannel removes a notification channel with its
+// subscriptions and pending digest notifications
+func (db *Database) DeleteNotificationChannel(id int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM notification_channels WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel %d: %w", id, err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("notification channel %d not found", id)
+	}
+	if _, err := tx.Exec("DELETE FROM notification_subscriptions WHERE channel_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete subscriptions of notification channel %d: %w", id, err)
+	}
+	if _, err := tx.Exec("DELETE FROM pending_notifications WHERE channel_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete pending notifications of channel %d: %w", id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit notification channel deletion: %w", err)
+	}
+	return nil
+}
+
+// GetNotificationSubscriptions returns the subscriptions of a channel, or of
+// every channel when channelID is 0, optionally only those of one user
+func (db *Database) GetNotificationSubscriptions(channelID int64, userID string) ([]models.NotificationSubscription, error) {
+	query := "SELECT channel_id, user_id, email, events, mode, updated_at FROM notification_subscriptions WHERE 1 = 1"
+	args := []interface{}{}
+	if channelID != 0 {
+		query += " AND channel_id = ?"
+		args = append(args, channelID)
+	}
+	if userID != "" {
+		query += " AND user_id = ?"
+		args = append(args, userID)
+	}
+	rows, err := db.Query(query+" ORDER BY channel_id, user_id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.NotificationSubscription{}
+	for rows.Next() {
+		var subscription models.NotificationSubscription
+		var events string
+		if err := rows.Scan(&subscription.ChannelID, &subscription.UserID, &subscription.Email, &events, &subscription.Mode, &subscription.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification subscription: %w", err)
+		}
+		if err := json.Unmarshal([]byte(events), &subscription.Events); err != nil {
+			return nil, fmt.Errorf("failed to parse subscription events: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// SaveNotificationSubscription creates or replaces a user's subscription to a channel
+func (db *Database) SaveNotificationSubscription(subscription models.NotificationSubscription) error {
+	events, err := json.Marshal(subscription.Events)
+	if err != nil {
+		return fmt.Errorf("failed to encode subscription events: %w", err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO notification_subscriptions (channel_id, user_id, email, events, mode)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(channel_id, user_id) DO UPDATE SET
+			email = excluded.email, events = excluded.events, mode = excluded.mode,
+			updated_at = CURRENT_TIMESTAMP`,
+		subscription.ChannelID, subscription.UserID, subscription.Email, string(events), subscription.Mode)
+	if err != nil {
+		return fmt.Errorf("failed to save notification subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteNotificationSubscription removes a user's subscription to a channel
+// with the notifications waiting for its digest
+func (db *Database) DeleteNotificationSubscription(channelID int64, userID string) (int64, error) {
+	result, err := db.Exec("DELETE FROM notification_subscriptions WHERE channel_id = ? AND user_id = ?", channelID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notification subscription: %w", err)
+	}
+	if _, err := db.Exec("DELETE FROM pending_notifications WHERE channel_id = ? AND user_id = ?", channelID, userID); err != nil {
+		return 0, fmt.Errorf("failed to delete pending notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// InsertPendingNotification queues a notification for the next digest of a subscription
+func (db *Database) InsertPendingNotification(notification models.PendingNotification) error {
+	_, err := db.Exec(`INSERT INTO pending_notifications (channel_id, user_id, service_id, service_name, event, message, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		notification.ChannelID, notification.UserID, notification.ServiceID, notification.ServiceName,
+		notification.Event, notification.Message, notification.Timestamp.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to queue notification for service %s: %w", notification.ServiceName, err)
+	}
+	return nil
+}
+
+// GetPendingNotifications returns the notifications waiting for a digest,
+// grouped by channel and user, oldest first
+func (db *Database) GetPendingNotifications() ([]models.PendingNotification, error) {
+	rows, err := db.Query(`SELECT id, channel_id, user_id, service_id, service_name, event, message, created_at
+		FROM pending_notifications ORDER BY channel_id, user_id, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.PendingNotification{}
+	for rows.Next() {
+		var notification models.PendingNotification
+		if err := rows.Scan(&notification.ID, &notification.ChannelID, &notification.UserID, &notification.ServiceID,
+			&notification.ServiceName, &notification.Event, &notification.Message, &notification.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan pending notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// DeletePendingNotifications removes the notifications of a subscription's
+// digest up to and including the given ID
+func (db *Database) DeletePendingNotifications(channelID int64, userID string, throughID int64) error {
+	if _, err := db.Exec("DELETE FROM pending_notifications WHERE channel_id = ? AND user_id = ? AND id <= ?", channelID, userID, throughID); err != nil {
+		return fmt.Errorf("failed to delete sent notifications: %w", err)
+	}
+	return nil
+}
+
+// InsertChaosFault records an injected fault and returns its ID
+func (db *Database) InsertChaosFault(fault models.ChaosFault) (int64, error) {
+	var endedAt interface{}
+	if fault.EndedAt != nil {
+		endedAt = fault.EndedAt.UTC()
+	}
+	result, err := db.Exec(`INSERT INTO chaos_faults (type, service_id, service_name, profile_id, detail, trigger, status, error_message, started_at, ended_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		fault.Type, fault.ServiceID, fault.ServiceName, fault.ProfileID, fault.Detail, fault.Trigger, fault.Status, fault.Error,
+		fault.StartedAt.UTC(), endedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record chaos fault: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// EndChaosFault records how an active fault ended
+func (db *Database) EndChaosFault(id int64, status, errorMessage string, endedAt time.Time) error {
+	_, err := db.Exec(`UPDATE chaos_faults SET status = ?, error_message = ?, ended_at = ? WHERE id = ?`,
+		status, errorMessage, endedAt.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update chaos fault %d: %w", id, err)
+	}
+	return nil
+}
+
+// GetChaosFaults returns the newest injected faults first
+func (db *Database) GetChaosFaults(limit int) ([]models.ChaosFault, error) {
+	rows, err := db.Query(`SELECT id, type, service_id, service_name, profile_id, detail, trigger, status, error_message, started_at, ended_at
+		FROM chaos_faults ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chaos faults: %w", err)
+	}
+	defer rows.Close()
+
+	faults := []models.ChaosFault{}
+	for rows.Next() {
+		var fault models.ChaosFault
+		var endedAt sql.NullTime
+		if err := rows.Scan(&fault.ID, &fault.Type, &fault.ServiceID, &fault.ServiceName, &fault.ProfileID, &fault.Detail,
+			&fault.Trigger, &fault.Status, &fault.Error, &fault.StartedAt, &endedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chaos fault: %w", err)
+		}
+		if endedAt.Valid {
+			fault.EndedAt = &endedAt.Time
+		}
+		faults = append(faults, fault)
+	}
+
+	return faults, rows.Err()
+}
+
+// CloseInterruptedChaosFaults marks faults that were active when Vertex
+// stopped; their proxies and timers did not survive the restart
+func (db *Database) CloseInterruptedChaosFaults() error {
+	_, err := db.Exec(`UPDATE chaos_faults SET status = 'interrupted', ended_at = CURRENT_TIMESTAMP WHERE status = 'active'`)
+	if err != nil {
+		return fmt.Errorf("failed to close interrupted chaos faults: %w", err)
+	}
+	return nil
+}
+
+// InsertDependencyScan records a new dependency scan and returns its ID
+func (db *Database) InsertDependencyScan(scan *models.DependencyScan) (int64, error) {
+	result, err := db.Exec(`INSERT INTO dependency_scans (service_id, service_name, scanner, status, command, started_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		scan.ServiceID, scan.ServiceName, scan.Scanner, scan.Status, scan.Command, scan.StartedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert dependency scan for UUID %s: %w", scan.ServiceID, err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateDependencyScan stores the outcome of a finished dependency scan
+func (db *Database) UpdateDependencyScan(scan *models.DependencyScan) error {
+	licensesJSON, err := json.Marshal(scan.Licenses)
+	if err != nil {
+		return fmt.Errorf("failed to marshal licenses: %w", err)
+	}
+	findingsJSON, err := json.Marshal(scan.Findings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal findings: %w", err)
+	}
+
+	var finishedAt interface{}
+	if scan.FinishedAt != nil {
+		finishedAt = scan.FinishedAt.UTC()
+	}
+
+	_, err = db.Exec(`
+		UPDATE dependency_scans
+		SET status = ?, finished_at = ?, duration_ms = ?, dependencies = ?, critical = ?, high = ?, medium = ?, low = ?, unknown = ?,
+			licenses_json = ?, findings_json = ?, output = ?, error_message = ?
+		WHERE id = ?`,
+		scan.Status, finishedAt, scan.DurationMs, scan.Dependencies, scan.Summary.Critical, scan.Summary.High, scan.Summary.Medium,
+		scan.Summary.Low, scan.Summary.Unknown, string(licensesJSON), string(findingsJSON), scan.Output, scan.Error, scan.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update dependency scan %d: %w", scan.ID, err)
+	}
+	return nil
+}
+
+const dependencyScanColumns = `id, service_id, service_name, scanner, status, COALESCE(command, ''), started_at, finished_at, duration_ms,
+	dependencies, critical, high, medium, low, unknown, COALESCE(licenses_json, '{}'), COALESCE(error_message, '')`
+
+// scanDependencyScan reads a row selected with dependencyScanColumns
+func scanDependencyScan(row interface{ Scan(...any) error }, extra ...any) (*models.DependencyScan, error) {
+	var scan models.DependencyScan
+	var finishedAt sql.NullTime
+	var licensesJSON string
+	dest := []any{&scan.ID, &scan.ServiceID, &scan.ServiceName, &scan.Scanner, &scan.Status, &scan.Command, &scan.StartedAt, &finishedAt,
+		&scan.DurationMs, &scan.Dependencies, &scan.Summary.Critical, &scan.Summary.High, &scan.Summary.Medium, &scan.Summary.Low,
+		&scan.Summary.Unknown, &licensesJSON, &scan.Error}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		scan.FinishedAt = &finishedAt.Time
+	}
+	scan.Summary.Total = scan.Summary.Critical + scan.Summary.High + scan.Summary.Medium + scan.Summary.Low + scan.Summary.Unknown
+	scan.Licenses = map[string]int{}
+	if err := json.Unmarshal([]byte(licensesJSON), &scan.Licenses); err != nil {
+		return nil, fmt.Errorf("failed to parse licenses of scan %d: %w", scan.ID, err)
+	}
+	return &scan, nil
+}
+
+// GetDependencyScans returns the most recent dependency scans of a service,
+// newest first, without findings or output
+func (db *Database) GetDependencyScans(serviceUUID string, limit int) ([]models.DependencyScan, error) {
+	rows, err := db.Query(`SELECT `+dependencyScanColumns+`
+		FROM dependency_scans
+		WHERE service_id = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?`, serviceUUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dependency scans: %w", err)
+	}
+	defer rows.Close()
+
+	scans := []models.DependencyScan{}
+	for rows.Next() {
+		scan, err := scanDependencyScan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dependency scan: %w", err)
+		}
+		scans = append(scans, *scan)
+	}
+
+	return scans, rows.Err()
+}
+
+// GetDependencyScan returns a single dependency scan of a service including
+// its findings and output
+func (db *Database) GetDependencyScan(serviceUUID string, scanID int64) (*models.DependencyScan, error) {
+	var findingsJSON, output string
+	row := db.QueryRow(`SELECT `+dependencyScanColumns+`, COALESCE(findings_json, '[]'), COALESCE(output, '')
+		FROM dependency_scans
+		WHERE id = ? AND service_id = ?`, scanID, serviceUUID)
+	scan, err := scanDependencyScan(row, &findingsJSON, &output)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("dependency scan %d not found", scanID)
+		}
+		return nil, fmt.Errorf("failed to load dependency scan %d: %w", scanID, err)
+	}
+	scan.Output = output
+	if err := json.Unmarshal([]byte(findingsJSON), &scan.Findings); err != nil {
+		return nil, fmt.Errorf("failed to parse findings of scan %d: %w", scanID, err)
+	}
+	return scan, nil
+}
+
+// GetLatestDependencyScan returns the newest completed scan of a service
+// without findings, or nil when it was never scanned
+func (db *Database) GetLatestDependencyScan(serviceUUID string) (*models.DependencyScan, error) {
+	row := db.QueryRow(`SELECT `+dependencyScanColumns+`
+		FROM dependency_scans
+		WHERE service_id = ? AND status = 'completed'
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1`, serviceUUID)
+	scan, err := scanDependencyScan(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest dependency scan for UUID %s: %w", serviceUUID, err)
+	}
+	return scan, nil
+}
+
+// PruneDependencyScans keeps only the newest scans of a service
+func (db *Database) PruneDependencyScans(serviceUUID string, keep int) error {
+	_, err := db.Exec(`
+		DELETE FROM dependency_scans
+		WHERE service_id = ? AND id NOT IN (
+			SELECT id FROM dependency_scans WHERE service_id = ? ORDER BY started_at DESC, id DESC LIMIT ?
+		)`, serviceUUID, serviceUUID, keep)
+	if err != nil {
+		return fmt.Errorf("failed to prune dependency scans for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// FailInterruptedDependencyScans marks scans left running by a previous
+// process as errored
+func (db *Database) FailInterruptedDependencyScans() error {
+	_, err := db.Exec(`UPDATE dependency_scans SET status = 'error', error_message = 'Interrupted by a Vertex restart' WHERE status = 'running'`)
+	if err != nil {
+		return fmt.Errorf("failed to close interrupted dependency scans: %w", err)
+	}
+	return nil
+}
+
+// InsertMigrationRun records a new migration run and returns its ID
+func (db *Database) InsertMigrationRun(run *models.MigrationRun) (int64, error) {
+	result, err := db.Exec(`INSERT INTO migration_runs (service_id, service_name, tool, runner, action, status, command, started_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.ServiceID, run.ServiceName, run.Tool, run.Runner, run.Action, run.Status, run.Command, run.StartedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert migration run for UUID %s: %w", run.ServiceID, err)
+	}
+	return result.LastInsertId()
+}
+
+// UpdateMigrationRun stores the outcome of a finished migration run
+func (db *Database) UpdateMigrationRun(run *models.MigrationRun) error {
+	migrationsJSON, err := json.Marshal(run.Migrations)
+	if err != nil {
+		return fmt.Errorf("failed to marshal migrations: %w", err)
+	}
+
+	var finishedAt interface{}
+	if run.FinishedAt != nil {
+		finishedAt = run.FinishedAt.UTC()
+	}
+
+	_, err = db.Exec(`
+		UPDATE migration_runs
+		SET status = ?, finished_at = ?, duration_ms = ?, applied = ?, pending = ?, migrations_json = ?, output = ?, error_message = ?
+		WHERE id = ?`,
+		run.Status, finishedAt, run.DurationMs, run.Applied, run.Pending, string(migrationsJSON), run.Output, run.Error, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update migration run %d: %w", run.ID, err)
+	}
+	return nil
+}
+
+const migrationRunColumns = `id, service_id, service_name, tool, runner, action, status, COALESCE(command, ''), started_at, finished_at,
+	duration_ms, applied, pending, COALESCE(error_message, '')`
+
+// scanMigrationRun reads a row selected with migrationRunColumns
+func scanMigrationRun(row interface{ Scan(...any) error }, extra ...any) (*models.MigrationRun, error) {
+	var run models.MigrationRun
+	var finishedAt sql.NullTime
+	dest := []any{&run.ID, &run.ServiceID, &run.ServiceName, &run.Tool, &run.Runner, &run.Action, &run.Status, &run.Command,
+		&run.StartedAt, &finishedAt, &run.DurationMs, &run.Applied, &run.Pending, &run.Error}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	return &run, nil
+}
+
+// GetMigrationRuns returns the most recent migration runs of a service,
+// newest first, without migrations or output
+func (db *Database) GetMigrationRuns(serviceUUID string, limit int) ([]models.MigrationRun, error) {
+	rows, err := db.Query(`SELECT `+migrationRunColumns+`
+		FROM migration_runs
+		WHERE service_id = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT ?`, serviceUUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query migration runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.MigrationRun{}
+	for rows.Next() {
+		run, err := scanMigrationRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan migration run: %w", err)
+		}
+		runs = append(runs, *run)
+	}
+
+	return runs, rows.Err()
+}
+
+// GetMigrationRun returns a single migration run of a service including its
+// migrations and output
+func (db *Database) GetMigrationRun(serviceUUID string, runID int64) (*models.MigrationRun, error) {
+	var migrationsJSON, output string
+	row := db.QueryRow(`SELECT `+migrationRunColumns+`, COALESCE(migrations_json, '[]'), COALESCE(output, '')
+		FROM migration_runs
+		WHERE id = ? AND service_id = ?`, runID, serviceUUID)
+	run, err := scanMigrationRun(row, &migrationsJSON, &output)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("migration run %d not found", runID)
+		}
+		return nil, fmt.Errorf("failed to load migration run %d: %w", runID, err)
+	}
+	run.Output = output
+	if err := json.Unmarshal([]byte(migrationsJSON), &run.Migrations); err != nil {
+		return nil, fmt.Errorf("failed to parse migrations of run %d: %w", runID, err)
+	}
+	return run, nil
+}
+
+// GetLatestMigrationRun returns the newest completed run of a service with
+// its migrations, or nil when none completed
+func (db *Database) GetLatestMigrationRun(serviceUUID string) (*models.MigrationRun, error) {
+	var runID int64
+	err := db.QueryRow(`SELECT id FROM migration_runs WHERE service_id = ? AND status = 'completed' ORDER BY started_at DESC, id DESC LIMIT 1`,
+		serviceUUID).Scan(&runID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest migration run for UUID %s: %w", serviceUUID, err)
+	}
+	return db.GetMigrationRun(serviceUUID, runID)
+}
+
+// PruneMigrationRuns keeps only the newest runs of a service
+func (db *Database) PruneMigrationRuns(serviceUUID string, keep int) error {
+	_, err := db.Exec(`
+		DELETE FROM migration_runs
+		WHERE service_id = ? AND id NOT IN (
+			SELECT id FROM migration_runs WHERE service_id = ? ORDER BY started_at DESC, id DESC LIMIT ?
+		)`, serviceUUID, serviceUUID, keep)
+	if err != nil {
+		return fmt.Errorf("failed to prune migration runs for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// FailInterruptedMigrationRuns marks runs left running by a previous process
+// as errored
+func (db *Database) FailInterruptedMigrationRuns() error {
+	_, err := db.Exec(`UPDATE migration_runs SET status = 'error', error_message = 'Interrupted by a Vertex restart' WHERE status = 'running'`)
+	if err != nil {
+		return fmt.Errorf("failed to close interrupted migration runs: %w", err)
+	}
+	return nil
+}
+
+// GetHealthCheckConfig returns the health check settings of a service, or
+// nil when it has none
+func (db *Database) GetHealthCheckConfig(serviceUUID string) (*models.HealthCheckConfig, error) {
+	config := models.HealthCheckConfig{ServiceID: serviceUUID}
+	var headersJSON string
+	err := db.QueryRow(`
+		SELECT COALESCE(headers_json, '{}'), COALESCE(auth_type, ''), COALESCE(username_env, ''), COALESCE(password_env, ''),
+			COALESCE(token_env, ''), timeout_seconds, insecure_skip_verify
+		FROM service_health_checks WHERE service_id = ?`, serviceUUID).
+		Scan(&headersJSON, &config.AuthType, &config.UsernameEnv, &config.PasswordEnv, &config.TokenEnv,
+			&config.TimeoutSeconds, &config.InsecureSkipVerify)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load health check settings for UUID %s: %w", serviceUUID, err)
+	}
+	if err := json.Unmarshal([]byte(headersJSON), &config.Headers); err != nil {
+		return nil, fmt.Errorf("failed to parse health check headers for UUID %s: %w", serviceUUID, err)
+	}
+	return &config, nil
+}
+
+// SaveHealthCheckConfig creates or replaces the health check settings of a service
+func (db *Database) SaveHealthCheckConfig(config models.HealthCheckConfig) error {
+	headersJSON, err := json.Marshal(config.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal health check headers: %w", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO service_health_checks (service_id, headers_json, auth_type, username_env, password_env, token_env, timeout_seconds, insecure_skip_verify)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET
+			headers_json = excluded.headers_json, auth_type = excluded.auth_type, username_env = excluded.username_env,
+			password_env = excluded.password_env, token_env = excluded.token_env, timeout_seconds = excluded.timeout_seconds,
+			insecure_skip_verify = excluded.insecure_skip_verify, updated_at = CURRENT_TIMESTAMP`,
+		config.ServiceID, string(headersJSON), config.AuthType, config.UsernameEnv, config.PasswordEnv, config.TokenEnv,
+		config.TimeoutSeconds, config.InsecureSkipVerify)
+	if err != nil {
+		return fmt.Errorf("failed to save health check settings for UUID %s: %w", config.ServiceID, err)
+	}
+	return nil
+}
+
+// GetServiceHealthPush returns the health ingestion token and last pushed
+// report of a service, or nil when it has no token
+func (db *Database) GetServiceHealthPush(serviceUUID string) (*models.HealthPush, error) {
+	push := models.HealthPush{ServiceID: serviceUUID, Enabled: true}
+	var createdAt time.Time
+	var reportJSON string
+	var pushedAt sql.NullTime
+	err := db.QueryRow(`SELECT created_at, health_status, report_json, pushed_at FROM service_health_push WHERE service_id = ?`, serviceUUID).
+		Scan(&createdAt, &push.HealthStatus, &reportJSON, &pushedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load health push for UUID %s: %w", serviceUUID, err)
+	}
+
+	push.CreatedAt = &createdAt
+	if pushedAt.Valid {
+		push.LastPushedAt = &pushedAt.Time
+	}
+	if reportJSON != "" {
+		var report models.HealthPushReport
+		if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
+			return nil, fmt.Errorf("failed to parse pushed health report for UUID %s: %w", serviceUUID, err)
+		}
+		push.LastReport = &report
+	}
+	return &push, nil
+}
+
+// GetServiceByHealthPushToken returns the UUID of the service a health
+// ingestion token hash belongs to, or "" when none does
+func (db *Database) GetServiceByHealthPushToken(tokenHash string) (string, error) {
+	var serviceUUID string
+	err := db.QueryRow(`SELECT service_id FROM service_health_push WHERE token_hash = ?`, tokenHash).Scan(&serviceUUID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up health push token: %w", err)
+	}
+	return serviceUUID, nil
+}
+
+// SaveServiceHealthPushToken gives a service a new health ingestion token
+// hash, forgetting its previous token and report
+func (db *Database) SaveServiceHealthPushToken(serviceUUID, tokenHash string, createdAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO service_health_push (service_id, token_hash, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET
+			token_hash = excluded.token_hash, created_at = excluded.created_at,
+			health_status = '', report_json = '', pushed_at = NULL`,
+		serviceUUID, tokenHash, createdAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save health push token for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// SaveServiceHealthPushReport stores the last report a service pushed
+func (db *Database) SaveServiceHealthPushReport(serviceUUID, healthStatus string, report models.HealthPushReport, pushedAt time.Time) error {
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pushed health report: %w", err)
+	}
+	_, err = db.Exec(`UPDATE service_health_push SET health_status = ?, report_json = ?, pushed_at = ? WHERE service_id = ?`,
+		healthStatus, string(reportJSON), pushedAt.UTC(), serviceUUID)
+	if err != nil {
+		return fmt.Errorf("failed to save pushed health report for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// DeleteServiceHealthPush drops the health ingestion token of a service
+func (db *Database) DeleteServiceHealthPush(serviceUUID string) error {
+	if _, err := db.Exec("DELETE FROM service_health_push WHERE service_id = ?", serviceUUID); err != nil {
+		return fmt.Errorf("failed to delete health push for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// GetServiceResourceLimits returns the ulimits and memory hints of a
+// service, or nil when it has none
+func (db *Database) GetServiceResourceLimits(serviceUUID string) (*models.ServiceResourceLimits, error) {
+	limits := models.ServiceResourceLimits{ServiceID: serviceUUID}
+	err := db.QueryRow(`
+		SELECT nofile, nproc, memory_limit_mb, heap_percent, cpus
+		FROM service_resource_limits WHERE service_id = ?`, serviceUUID).
+		Scan(&limits.NoFile, &limits.NProc, &limits.MemoryLimitMB, &limits.HeapPercent, &limits.CPUs)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load resource limits for UUID %s: %w", serviceUUID, err)
+	}
+	return &limits, nil
+}
+
+// SaveServiceResourceLimits creates or replaces the ulimits and memory hints of a service
+func (db *Database) SaveServiceResourceLimits(limits models.ServiceResourceLimits) error {
+	_, err := db.Exec(`
+		INSERT INTO service_resource_limits (service_id, nofile, nproc, memory_limit_mb, heap_percent, cpus)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET
+			nofile = excluded.nofile, nproc = excluded.nproc, memory_limit_mb = excluded.memory_limit_mb,
+			heap_percent = excluded.heap_percent, cpus = excluded.cpus, updated_at = CURRENT_TIMESTAMP`,
+		limits.ServiceID, limits.NoFile, limits.NProc, limits.MemoryLimitMB, limits.HeapPercent, limits.CPUs)
+	if err != nil {
+		return fmt.Errorf("failed to save resource limits for UUID %s: %w", limits.ServiceID, err)
+	}
+	return nil
+}
+
+// DeleteServiceResourceLimits drops the ulimits and memory hints of a service
+func (db *Database) DeleteServiceResourceLimits(serviceUUID string) error {
+	if _, err := db.Exec("DELETE FROM service_resource_limits WHERE service_id = ?", serviceUUID); err != nil {
+		return fmt.Errorf("failed to delete resource limits for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// GetRecordedServicePIDs returns the PIDs saved for services, which outlive
+// a crash of Vertex; service UUIDs map to PIDs
+func (db *Database) GetRecordedServicePIDs() (map[string]int, error) {
+	rows, err := db.Query(`SELECT id, pid FROM services WHERE pid > 0`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service PIDs: %w", err)
+	}
+	defer rows.Close()
+
+	pids := make(map[string]int)
+	for rows.Next() {
+		var serviceUUID string
+		var pid int
+		if err := rows.Scan(&serviceUUID, &pid); err != nil {
+			return nil, fmt.Errorf("failed to scan service PID: %w", err)
+		}
+		pids[serviceUUID] = pid
+	}
+	return pids, rows.Err()
+}
+
+// GetProfileBuildSettings returns the build settings of a profile, or nil
+// when it has none
+func (db *Database) GetProfileBuildSettings(profileID string) (*models.ProfileBuildSettings, error) {
+	settings := models.ProfileBuildSettings{ProfileID: profileID}
+	err := db.QueryRow(`
+		SELECT offline, COALESCE(mirror_url, ''), COALESCE(mirror_of, ''), COALESCE(maven_opts, ''),
+			COALESCE(gradle_opts, ''), updated_at
+		FROM profile_build_settings WHERE profile_id = ?`, profileID).
+		Scan(&settings.Offline, &settings.MirrorURL, &settings.MirrorOf, &settings.MavenOpts,
+			&settings.GradleOpts, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load build settings for profile %s: %w", profileID, err)
+	}
+	return &settings, nil
+}
+
+// SaveProfileBuildSettings creates or replaces the build settings of a profile
+func (db *Database) SaveProfileBuildSettings(settings models.ProfileBuildSettings) error {
+	_, err := db.Exec(`
+		INSERT INTO profile_build_settings (profile_id, offline, mirror_url, mirror_of, maven_opts, gradle_opts)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(profile_id) DO UPDATE SET
+			offline = excluded.offline, mirror_url = excluded.mirror_url, mirror_of = excluded.mirror_of,
+			maven_opts = excluded.maven_opts, gradle_opts = excluded.gradle_opts, updated_at = CURRENT_TIMESTAMP`,
+		settings.ProfileID, settings.Offline, settings.MirrorURL, settings.MirrorOf, settings.MavenOpts, settings.GradleOpts)
+	if err != nil {
+		return fmt.Errorf("failed to save build settings for profile %s: %w", settings.ProfileID, err)
+	}
+	return nil
+}
+
+// GetServiceNotes returns the notes of a service, or nil when it has none
+func (db *Database) GetServiceNotes(serviceUUID string) (*models.ServiceNotes, error) {
+	notes := models.ServiceNotes{ServiceID: serviceUUID}
+	var updatedAt time.Time
+	err := db.QueryRow(`SELECT COALESCE(notes, ''), updated_at FROM service_notes WHERE service_id = ?`, serviceUUID).
+		Scan(&notes.Notes, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notes for UUID %s: %w", serviceUUID, err)
+	}
+	notes.UpdatedAt = &updatedAt
+	return &notes, nil
+}
+
+// SaveServiceNotes creates or replaces the notes of a service
+func (db *Database) SaveServiceNotes(serviceUUID, notes string) error {
+	_, err := db.Exec(`
+		INSERT INTO service_notes (service_id, notes) VALUES (?, ?)
+		ON CONFLICT(service_id) DO UPDATE SET notes = excluded.notes, updated_at = CURRENT_TIMESTAMP`,
+		serviceUUID, notes)
+	if err != nil {
+		return fmt.Errorf("failed to save notes for UUID %s: %w", serviceUUID, err)
+	}
+	return nil
+}
+
+// GetProfileFileOverlays returns the file overlays of a profile, or of every
+// profile when profileID is empty, ordered by service and path
+func (db *Database) GetProfileFileOverlays(profileID string) ([]models.ProfileFileOverlay, error) {
+	query := "SELECT profile_id, service_id, path, content, updated_at FROM profile_file_overlays"
+	args := []interface{}{}
+	if profileID != "" {
+		query += " WHERE profile_id = ?"
+		args = append(args, profileID)
+	}
+	rows, err := db.Query(query+" ORDER BY service_id, path", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file overlays: %w", err)
+	}
+	defer rows.Close()
+
+	overlays := []models.ProfileFileOverlay{}
+	for rows.Next() {
+		var overlay models.ProfileFileOverlay
+		if err := rows.Scan(&overlay.ProfileID, &overlay.ServiceID, &overlay.Path, &overlay.Content, &overlay.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan file overlay: %w", err)
+		}
+		overlays = append(overlays, overlay)
+	}
+
+	return overlays, rows.Err()
+}
+
+// SaveProfileFileOverlay creates or replaces a file overlay of a profile
+func (db *Database) SaveProfileFileOverlay(overlay *models.ProfileFileOverlay) error {
+	_, err := db.Exec(`
+		INSERT INTO profile_file_overlays (profile_id, service_id, path, content, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(profile_id, service_id, path) DO UPDATE SET content = excluded.content, updated_at = CURRENT_TIMESTAMP`,
+		overlay.ProfileID, overlay.ServiceID, overlay.Path, overlay.Content)
+	if err != nil {
+		return fmt.Errorf("failed to save file overlay %s: %w", overlay.Path, err)
+	}
+	return nil
+}
+
+// DeleteProfileFileOverlay removes a file overlay of a profile and returns how many were removed
+func (db *Database) DeleteProfileFileOverlay(profileID, serviceUUID, path string) (int64, error) {
+	result, err := db.Exec("DELETE FROM profile_file_overlays WHERE profile_id = ? AND service_id = ? AND path = ?",
+		profileID, serviceUUID, path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete file overlay %s: %w", path, err)
+	}
+	return result.RowsAffected()
+}
+
+// GetMaterializedFileOverlays returns the overlays currently written into service directories
+func (db *Database) GetMaterializedFileOverlays() ([]models.MaterializedFileOverlay, error) {
+	rows, err := db.Query(`SELECT file_path, profile_id, service_id, path, original_existed, COALESCE(original_content, ''), written_content, materialized_at
+		FROM materialized_file_overlays ORDER BY file_path`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query materialized file overlays: %w", err)
+	}
+	defer rows.Close()
+
+	materialized := []models.MaterializedFileOverlay{}
+	for rows.Next() {
+		var m models.MaterializedFileOverlay
+		if err := rows.Scan(&m.FilePath, &m.ProfileID, &m.ServiceID, &m.Path, &m.OriginalExisted, &m.OriginalContent, &m.WrittenContent, &m.MaterializedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan materialized file overlay: %w", err)
+		}
+		materialized = append(materialized, m)
+	}
+
+	return materialized, rows.Err()
+}
+
+// SaveMaterializedFileOverlay records an overlay written into a service
+// directory. Rewriting the same file keeps the original it first replaced.
+func (db *Database) SaveMaterializedFileOverlay(m *models.MaterializedFileOverlay) error {
+	_, err := db.Exec(`
+		INSERT INTO materialized_file_overlays (file_path, profile_id, service_id, path, original_existed, original_content, written_content, materialized_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(file_path) DO UPDATE SET profile_id = excluded.profile_id, service_id = excluded.service_id, path = excluded.path,
+			written_content = excluded.written_content, materialized_at = excluded.materialized_at`,
+		m.FilePath, m.ProfileID, m.ServiceID, m.Path, m.OriginalExisted, m.OriginalContent, m.WrittenContent, m.MaterializedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record materialized file %s: %w", m.FilePath, err)
+	}
+	return nil
+}
+
+// DeleteMaterializedFileOverlay forgets a written overlay once its original is restored
+func (db *Database) DeleteMaterializedFileOverlay(filePath string) error {
+	if _, err := db.Exec("DELETE FROM materialized_file_overlays WHERE file_path = ?", filePath); err != nil {
+		return fmt.Errorf("failed to forget materialized file %s: %w", filePath, err)
+	}
+	return nil
+}
+
+const serviceProbeColumns = `id, service_id, name, url, method, interval_seconds, expected_status, max_latency_ms,
+	target_success_rate, window_minutes, enabled, created_at, updated_at`
+
+func scanServiceProbe(scanner interface{ Scan(...interface{}) error }) (models.ServiceProbe, error) {
+	var probe models.ServiceProbe
+	err := scanner.Scan(&probe.ID, &probe.ServiceID, &probe.Name, &probe.URL, &probe.Method, &probe.IntervalSeconds,
+		&probe.ExpectedStatus, &probe.MaxLatencyMs, &probe.TargetSuccessRate, &probe.WindowMinutes, &probe.Enabled,
+		&probe.CreatedAt, &probe.UpdatedAt)
+	return probe, err
+}
+
+// GetServiceProbes returns the probes of a service, or of every service when
+// serviceUUID is empty, ordered by name
+func (db *Database) GetServiceProbes(serviceUUID string) ([]models.ServiceProbe, error) {
+	query := "SELECT " + serviceProbeColumns + " FROM service_probes"
+	args := []interface{}{}
+	if serviceUUID != "" {
+		query += " WHERE service_id = ?"
+		args = append(args, serviceUUID)
+	}
+	rows, err := db.Query(query+" ORDER BY service_id, name", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service probes: %w", err)
+	}
+	defer rows.Close()
+
+	probes := []models.ServiceProbe{}
+	for rows.Next() {
+		probe, err := scanServiceProbe(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan service probe: %w", err)
+		}
+		probes = append(probes, probe)
+	}
+	return probes, rows.Err()
+}
+
+// GetServiceProbe returns a probe of a service, or nil when it has no such probe
+func (db *Database) GetServiceProbe(serviceUUID string, probeID int64) (*models.ServiceProbe, error) {
+	probe, err := scanServiceProbe(db.QueryRow("SELECT "+serviceProbeColumns+" FROM service_probes WHERE id = ? AND service_id = ?", probeID, serviceUUID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load probe %d: %w", probeID, err)
+	}
+	return &probe, nil
+}
+
+// SaveServiceProbe inserts a probe when its ID is 0 and updates it otherwise
+func (db *Database) SaveServiceProbe(probe *models.ServiceProbe) error {
+	if probe.ID == 0 {
+		result, err := db.Exec(`
+			INSERT INTO service_probes (service_id, name, url, method, interval_seconds, expected_status, max_latency_ms,
+				target_success_rate, window_minutes, enabled)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			probe.ServiceID, probe.Name, probe.URL, probe.Method, probe.IntervalSeconds, probe.ExpectedStatus,
+			probe.MaxLatencyMs, probe.TargetSuccessRate, probe.WindowMinutes, probe.Enabled)
+		if err != nil {
+			return fmt.Errorf("failed to create probe %s: %w", probe.Name, err)
+		}
+		probe.ID, err = result.LastInsertId()
+		return err
+	}
+
+	_, err := db.Exec(`
+		UPDATE service_probes SET name = ?, url = ?, method = ?, interval_seconds = ?, expected_status = ?, max_latency_ms = ?,
+			target_success_rate = ?, window_minutes = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND service_id = ?`,
+		probe.Name, probe.URL, probe.Method, probe.IntervalSeconds, probe.ExpectedStatus, probe.MaxLatencyMs,
+		probe.TargetSuccessRate, probe.WindowMinutes, probe.Enabled, probe.ID, probe.ServiceID)
+	if err != nil {
+		return fmt.Errorf("failed to update probe %s: %w", probe.Name, err)
+	}
+	return nil
+}
+
+// DeleteServiceProbe removes a probe of a service along with its results and
+// returns how many probes were removed
+func (db *Database) DeleteServiceProbe(serviceUUID string, probeID int64) (int64, error) {
+	if _, err := db.Exec("DELETE FROM service_probe_results WHERE probe_id IN (SELECT id FROM service_probes WHERE id = ? AND service_id = ?)", probeID, serviceUUID); err != nil {
+		return 0, fmt.Errorf("failed to delete results of probe %d: %w", probeID, err)
+	}
+	result, err := db.Exec("DELETE FROM service_probes WHERE id = ? AND service_id = ?", probeID, serviceUUID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete probe %d: %w", probeID, err)
+	}
+	return result.RowsAffected()
+}
+
+// InsertProbeResult records one request of a probe
+func (db *Database) InsertProbeResult(result models.ProbeResult) error {
+	_, err := db.Exec(`INSERT INTO service_probe_results (probe_id, success, status_code, latency_ms, error, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		result.ProbeID, result.Success, result.StatusCode, result.LatencyMs, result.Error, result.Timestamp.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to insert result of probe %d: %w", result.ProbeID, err)
+	}
+	return nil
+}
+
+// GetProbeResults returns the results of a probe newer than since, oldest
+// first, keeping the newest limit when limit is positive
+func (db *Database) GetProbeResults(probeID int64, since time.Time, limit int) ([]models.ProbeResult, error) {
+	query := `SELECT probe_id, success, status_code, latency_ms, COALESCE(error, ''), created_at FROM service_probe_results
+		WHERE probe_id = ? AND created_at >= ? ORDER BY created_at DESC, id DESC`
+	args := []interface{}{probeID, since.UTC()}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query results of probe %d: %w", probeID, err)
+	}
+	defer rows.Close()
+
+	results := []models.ProbeResult{}
+	for rows.Next() {
+		var result models.ProbeResult
+		if err := rows.Scan(&result.ProbeID, &result.Success, &result.StatusCode, &result.LatencyMs, &result.Error, &result.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan probe result: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+	return results, nil
+}
+
+// CleanupProbeResults drops probe results recorded before the given time
+func (db *Database) CleanupProbeResults(before time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM service_probe_results WHERE created_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean up probe results: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// serviceDataKinds are the records kept per service that deleting it loses
+var serviceDataKinds = []struct{ kind, label, table string }{
+	{"logs", "Log lines", "service_logs"},
+	{"envVars", "Environment variables", "service_env_vars"},
+	{"events", "Timeline events", "service_events"},
+	{"builds", "Builds", "service_builds"},
+	{"testRuns", "Test runs", "service_test_runs"},
+	{"dependencyScans", "Dependency scans", "dependency_scans"},
+	{"migrationRuns", "Migration runs", "migration_runs"},
+	{"probes", "Request probes", "service_probes"},
+	{"hooks", "Lifecycle hooks", "service_hooks"},
+	{"externalDependencies", "External dependencies", "service_external_dependencies"},
+	{"tags", "Tags", "service_tags"},
+	{"notes", "Notes", "service_notes"},
+	{"profileConfigs", "Profile configuration values", "profile_service_configs"},
+	{"fileOverlays", "Profile file overlays", "profile_file_overlays"},
+}
+
+// CountServiceData returns how many records of each kind a service has,
+// leaving out kinds it has none of
+func (db *Database) CountServiceData(serviceUUID string) ([]models.StoredDataCount, error) {
+	counts := []models.StoredDataCount{}
+	for _, kind := range serviceDataKinds {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM "+kind.table+" WHERE service_id = ?", serviceUUID).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s of UUID %s: %w", kind.kind, serviceUUID, err)
+		}
+		if count > 0 {
+			counts = append(counts, models.StoredDataCount{Kind: kind.kind, Label: kind.label, Count: count})
+		}
+	}
+	return counts, nil
+}
+
+// GetProfilesWithService returns the profiles whose service list includes a service
+func (db *Database) GetProfilesWithService(serviceUUID string) ([]models.ImpactedProfile, error) {
+	rows, err := db.Query("SELECT id, name, user_id, services_json FROM service_profiles WHERE services_json LIKE ? ORDER BY name",
+		"%\""+serviceUUID+"\"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profiles of UUID %s: %w", serviceUUID, err)
+	}
+	defer rows.Close()
+
+	profiles := []models.ImpactedProfile{}
+	for rows.Next() {
+		var profile models.ImpactedProfile
+		var servicesJSON string
+		if err := rows.Scan(&profile.ID, &profile.Name, &profile.UserID, &servicesJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan profile: %w", err)
+		}
+		var services []string
+		if err := json.Unmarshal([]byte(servicesJSON), &services); err != nil {
+			continue
+		}
+		for _, id := range services {
+			if id == serviceUUID {
+				profiles = append(profiles, profile)
+				break
+			}
+		}
+	}
+	return profiles, rows.Err()
+}
+
+// GetProfileDependents returns the profile-scoped dependencies on a service
+func (db *Database) GetProfileDependents(serviceUUID string) ([]models.ImpactedDependent, error) {
+	rows, err := db.Query(`SELECT profile_id, service_id, dependency_type, is_required FROM profile_dependencies
+		WHERE dependency_service_id = ? ORDER BY profile_id, service_id`, serviceUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile dependents of UUID %s: %w", serviceUUID, err)
+	}
+	defer rows.Close()
+
+	dependents := []models.ImpactedDependent{}
+	for rows.Next() {
+		var dependent models.ImpactedDependent
+		if err := rows.Scan(&dependent.ProfileID, &dependent.ServiceID, &dependent.Type, &dependent.Required); err != nil {
+			return nil, fmt.Errorf("failed to scan profile dependent: %w", err)
+		}
+		dependents = append(dependents, dependent)
+	}
+	return dependents, rows.Err()
+}
+
+// InsertProfilePromotion records a promotion between profiles and returns its ID
+func (db *Database) InsertProfilePromotion(promotion models.ProfilePromotion) (int64, error) {
+	changesJSON, err := json.Marshal(promotion.Changes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal promotion changes: %w", err)
+	}
+	result, err := db.Exec(`INSERT INTO profile_promotions (user_id, source_profile_id, source_profile_name, target_profile_id,
+		target_profile_name, note, changes_json, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		promotion.UserID, promotion.SourceProfileID, promotion.SourceProfileName, promotion.TargetProfileID,
+		promotion.TargetProfileName, promotion.Note, string(changesJSON), promotion.CreatedAt.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to record profile promotion: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// GetProfilePromotions returns the newest promotions from or to a profile first
+func (db *Database) GetProfilePromotions(profileID string, limit int) ([]models.ProfilePromotion, error) {
+	rows, err := db.Query(`SELECT id, user_id, source_profile_id, source_profile_name, target_profile_id, target_profile_name,
+		note, changes_json, created_at FROM profile_promotions
+		WHERE source_profile_id = ? OR target_profile_id = ? ORDER BY id DESC LIMIT ?`, profileID, profileID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile promotions: %w", err)
+	}
+	defer rows.Close()
+
+	promotions := []models.ProfilePromotion{}
+	for rows.Next() {
+		var promotion models.ProfilePromotion
+		var changesJSON string
+		if err := rows.Scan(&promotion.ID, &promotion.UserID, &promotion.SourceProfileID, &promotion.SourceProfileName,
+			&promotion.TargetProfileID, &promotion.TargetProfileName, &promotion.Note, &changesJSON, &promotion.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan profile promotion: %w", err)
+		}
+		if err := json.Unmarshal([]byte(changesJSON), &promotion.Changes); err != nil {
+			return nil, fmt.Errorf("failed to parse promotion changes: %w", err)
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	return promotions, rows.Err()
+}
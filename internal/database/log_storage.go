@@ -33,6 +33,7 @@ func (db *Database) InitializeLogTables() error {
 		`CREATE INDEX IF NOT EXISTS idx_service_logs_level ON service_logs(level);`,
 		`CREATE INDEX IF NOT EXISTS idx_service_logs_message_fts ON service_logs(message);`,
 		`CREATE INDEX IF NOT EXISTS idx_service_logs_created_at ON service_logs(created_at);`,
+		`CREATE INDEX IF NOT EXISTS idx_service_logs_service_timestamp ON service_logs(service_id, timestamp, id);`,
 	}
 
 	// Create log retention settings table
@@ -286,6 +287,91 @@ func (db *Database) SearchLogs(criteria LogSearchCriteria) ([]LogSearchResult, i
 	return results, totalCount, nil
 }
 
+// LogContext is the neighborhood of one log entry of a service, in
+// chronological order
+type LogContext struct {
+	Entry         LogSearchResult   `json:"entry"`
+	Before        []LogSearchResult `json:"before"`
+	After         []LogSearchResult `json:"after"`
+	HasMoreBefore bool              `json:"hasMoreBefore"`
+	HasMoreAfter  bool              `json:"hasMoreAfter"`
+}
+
+// GetLogContext returns up to before and after entries around a service's log
+// entry. The entry is the one with entryID when set, otherwise the first at or
+// after timestamp. Entries are ordered by timestamp and then ID, so entries
+// sharing a timestamp keep the order they were stored in; every query walks
+// the (service_id, timestamp, id) index.
+func (db *Database) GetLogContext(serviceID string, timestamp time.Time, entryID int64, before, after int) (*LogContext, error) {
+	const columns = "SELECT id, service_id, timestamp, level, message, created_at FROM service_logs"
+
+	var anchor *sql.Row
+	if entryID > 0 {
+		anchor = db.DB.QueryRow(columns+" WHERE id = ? AND service_id = ?", entryID, serviceID)
+	} else {
+		anchor = db.DB.QueryRow(columns+" WHERE service_id = ? AND timestamp >= ? ORDER BY timestamp, id LIMIT 1", serviceID, timestamp)
+	}
+
+	logContext := &LogContext{Before: []LogSearchResult{}, After: []LogSearchResult{}}
+	entry := &logContext.Entry
+	err := anchor.Scan(&entry.ID, &entry.ServiceID, &entry.Timestamp, &entry.Level, &entry.Message, &entry.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("log entry of service %s not found", serviceID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log entry: %w", err)
+	}
+
+	// One extra row tells whether there is more beyond the window
+	logContext.Before, err = db.queryLogWindow(columns+`
+		WHERE service_id = ? AND (timestamp < ? OR (timestamp = ? AND id < ?))
+		ORDER BY timestamp DESC, id DESC LIMIT ?`,
+		serviceID, entry.Timestamp, entry.Timestamp, entry.ID, before+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(logContext.Before) > before {
+		logContext.Before = logContext.Before[:before]
+		logContext.HasMoreBefore = true
+	}
+	for i, j := 0, len(logContext.Before)-1; i < j; i, j = i+1, j-1 {
+		logContext.Before[i], logContext.Before[j] = logContext.Before[j], logContext.Before[i]
+	}
+
+	logContext.After, err = db.queryLogWindow(columns+`
+		WHERE service_id = ? AND (timestamp > ? OR (timestamp = ? AND id > ?))
+		ORDER BY timestamp, id LIMIT ?`,
+		serviceID, entry.Timestamp, entry.Timestamp, entry.ID, after+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(logContext.After) > after {
+		logContext.After = logContext.After[:after]
+		logContext.HasMoreAfter = true
+	}
+
+	return logContext, nil
+}
+
+// queryLogWindow runs one side of a GetLogContext query
+func (db *Database) queryLogWindow(query string, args ...interface{}) ([]LogSearchResult, error) {
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query log context: %w", err)
+	}
+	defer rows.Close()
+
+	results := []LogSearchResult{}
+	for rows.Next() {
+		var result LogSearchResult
+		if err := rows.Scan(&result.ID, &result.ServiceID, &result.Timestamp, &result.Level, &result.Message, &result.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan log context entry: %w", err)
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
 // GetRecentLogs retrieves the most recent logs for a service
 func (db *Database) GetRecentLogs(serviceID string, limit int) ([]models.LogEntry, error) {
 	query := `
@@ -456,6 +542,9 @@ func (db *Database) GetLogStatistics() (map[string]interface{}, error) {
 
 // ClearServiceLogs deletes all logs for a specific service from the database
 func (db *Database) ClearServiceLogs(serviceID string) error {
+	// Write queued entries first so they are cleared too
+	db.FlushLogs()
+
 	query := `DELETE FROM service_logs WHERE service_id = ?`
 	
 	result, err := db.DB.Exec(query, serviceID)
@@ -471,6 +560,8 @@ func (db *Database) ClearServiceLogs(serviceID string) error {
 
 // ClearAllServiceLogs deletes logs for multiple services from the database
 func (db *Database) ClearAllServiceLogs(serviceIDs []string) (map[string]error, error) {
+	db.FlushLogs()
+
 	if len(serviceIDs) == 0 {
 		// Clear all logs
 		result, err := db.DB.Exec("DELETE FROM service_logs")
@@ -0,0 +1,313 @@
+// Package database - VACUUM, ANALYZE and integrity checks, and their history
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	maintenanceRunsKept      = 50
+	vacuumProgressInterval   = time.Second
+	integrityProblemsPerTask = 100 // Messages kept from the integrity check
+)
+
+// MaintenanceProgress receives how far a maintenance task is
+type MaintenanceProgress func(percent int, detail string)
+
+// userTables returns the tables of the schema, without SQLite's own
+func (db *Database) userTables(ctx context.Context) ([]string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// quoteIdentifier quotes a table name for a statement
+func quoteIdentifier(name string) string {
+	quoted := []byte{'"'}
+	for i := 0; i < len(name); i++ {
+		if name[i] == '"' {
+			quoted = append(quoted, '"')
+		}
+		quoted = append(quoted, name[i])
+	}
+	return string(append(quoted, '"'))
+}
+
+// FileSizeBytes returns the size of the database file on disk
+func (db *Database) FileSizeBytes() int64 {
+	info, err := os.Stat(db.path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// Vacuum rebuilds the database so the pages freed by deleted logs go back to
+// the disk, then truncates the WAL. Writers wait meanwhile: the log writer
+// holds its batches back and other writes wait for the busy timeout. The
+// progress is estimated from the pages copied into the WAL.
+func (db *Database) Vacuum(ctx context.Context, progress MaintenanceProgress) error {
+	var pageCount, freePages, pageSize int64
+	if err := db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount); err != nil {
+		return fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "PRAGMA freelist_count").Scan(&freePages); err != nil {
+		return fmt.Errorf("failed to read free pages: %w", err)
+	}
+	if err := db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return fmt.Errorf("failed to read page size: %w", err)
+	}
+	expected := (pageCount - freePages) * pageSize
+
+	db.FlushLogs()
+	db.maintenanceMutex.Lock()
+	defer db.maintenanceMutex.Unlock()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Start from an empty WAL so its growth tracks the copy
+	if _, err := conn.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint the WAL: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(vacuumProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if info, err := os.Stat(db.path + "-wal"); err == nil && expected > 0 {
+					progress(int(min(99, info.Size()*100/expected)), fmt.Sprintf("copied about %d of %d bytes", min(info.Size(), expected), expected))
+				}
+			}
+		}
+	}()
+	_, err = conn.ExecContext(ctx, "VACUUM")
+	close(done)
+	if err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+
+	progress(99, "truncating the WAL")
+	if _, err := conn.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint the WAL: %w", err)
+	}
+	progress(100, fmt.Sprintf("freed %d pages", freePages))
+	return nil
+}
+
+// Analyze refreshes the query planner statistics one table at a time
+func (db *Database) Analyze(ctx context.Context, progress MaintenanceProgress) error {
+	tables, err := db.userTables(ctx)
+	if err != nil {
+		return err
+	}
+	for i, table := range tables {
+		progress(i*100/len(tables), "analyzing "+table)
+		if _, err := db.ExecContext(ctx, "ANALYZE "+quoteIdentifier(table)); err != nil {
+			return fmt.Errorf("failed to analyze %s: %w", table, err)
+		}
+	}
+	progress(100, fmt.Sprintf("analyzed %d tables", len(tables)))
+	return nil
+}
+
+// IntegrityCheck checks each table and its indexes and returns the problems
+// found; none means the database is intact
+func (db *Database) IntegrityCheck(ctx context.Context, progress MaintenanceProgress) ([]string, error) {
+	tables, err := db.userTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := []string{}
+	for i, table := range tables {
+		progress(i*100/len(tables), "checking "+table)
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA integrity_check(%s)", quoteIdentifier(table)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", table, err)
+		}
+		for rows.Next() {
+			var message string
+			if err := rows.Scan(&message); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to read the check of %s: %w", table, err)
+			}
+			if message != "ok" && len(problems) < integrityProblemsPerTask {
+				problems = append(problems, message)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", table, err)
+		}
+	}
+	progress(100, fmt.Sprintf("checked %d tables", len(tables)))
+	return problems, nil
+}
+
+// GetMaintenanceSettings returns the maintenance schedule, defaulting to a
+// weekly VACUUM and ANALYZE
+func (db *Database) GetMaintenanceSettings() (*models.MaintenanceSettings, error) {
+	settings := &models.MaintenanceSettings{
+		Enabled:              true,
+		IntervalHours:        168,
+		Tasks:                []string{models.MaintenanceVacuum, models.MaintenanceAnalyze},
+		VacuumMinFreePercent: 20,
+	}
+	var tasks string
+	var updatedAt sql.NullTime
+	err := db.QueryRow("SELECT is_enabled, interval_hours, tasks, vacuum_min_free_percent, updated_at FROM maintenance_settings WHERE id = 1").
+		Scan(&settings.Enabled, &settings.IntervalHours, &tasks, &settings.VacuumMinFreePercent, &updatedAt)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query maintenance settings: %w", err)
+	}
+	if err := json.Unmarshal([]byte(tasks), &settings.Tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance tasks: %w", err)
+	}
+	if updatedAt.Valid {
+		settings.UpdatedAt = updatedAt.Time
+	}
+	return settings, nil
+}
+
+// SaveMaintenanceSettings creates or replaces the maintenance schedule
+func (db *Database) SaveMaintenanceSettings(settings models.MaintenanceSettings) error {
+	tasks, err := json.Marshal(settings.Tasks)
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance tasks: %w", err)
+	}
+	_, err = db.Exec(`
+		INSERT INTO maintenance_settings (id, is_enabled, interval_hours, tasks, vacuum_min_free_percent)
+		VALUES (1, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			is_enabled = excluded.is_enabled, interval_hours = excluded.interval_hours,
+			tasks = excluded.tasks, vacuum_min_free_percent = excluded.vacuum_min_free_percent,
+			updated_at = CURRENT_TIMESTAMP`,
+		settings.Enabled, settings.IntervalHours, string(tasks), settings.VacuumMinFreePercent)
+	if err != nil {
+		return fmt.Errorf("failed to save maintenance settings: %w", err)
+	}
+	return nil
+}
+
+// InsertMaintenanceRun records a run that started and sets its ID
+func (db *Database) InsertMaintenanceRun(run *models.MaintenanceRun) error {
+	tasks, err := json.Marshal(run.Tasks)
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance tasks: %w", err)
+	}
+	result, err := db.Exec(`INSERT INTO maintenance_runs (tasks, trigger_type, status, started_at, size_before_bytes) VALUES (?, ?, ?, ?, ?)`,
+		string(tasks), run.Trigger, run.Status, run.StartedAt.UTC(), run.SizeBeforeBytes)
+	if err != nil {
+		return fmt.Errorf("failed to insert maintenance run: %w", err)
+	}
+	run.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to insert maintenance run: %w", err)
+	}
+
+	if _, err := db.Exec(`DELETE FROM maintenance_runs WHERE id <= ?`, run.ID-maintenanceRunsKept); err != nil {
+		return fmt.Errorf("failed to prune maintenance runs: %w", err)
+	}
+	return nil
+}
+
+// FinishMaintenanceRun records the outcome of a run
+func (db *Database) FinishMaintenanceRun(run *models.MaintenanceRun) error {
+	problems, err := json.Marshal(run.Problems)
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance problems: %w", err)
+	}
+	var finishedAt any
+	if run.FinishedAt != nil {
+		finishedAt = run.FinishedAt.UTC()
+	}
+	_, err = db.Exec(`UPDATE maintenance_runs SET status = ?, finished_at = ?, size_after_bytes = ?, problems = ?, error = ? WHERE id = ?`,
+		run.Status, finishedAt, run.SizeAfterBytes, string(problems), run.Error, run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update maintenance run %d: %w", run.ID, err)
+	}
+	return nil
+}
+
+// FailUnfinishedMaintenanceRuns marks the runs a stop of Vertex interrupted
+func (db *Database) FailUnfinishedMaintenanceRuns() error {
+	_, err := db.Exec(`UPDATE maintenance_runs SET status = 'failed', error = 'interrupted by a restart of Vertex' WHERE status = 'running'`)
+	if err != nil {
+		return fmt.Errorf("failed to update maintenance runs: %w", err)
+	}
+	return nil
+}
+
+// ListMaintenanceRuns returns the recent maintenance runs, newest first
+func (db *Database) ListMaintenanceRuns(limit int) ([]models.MaintenanceRun, error) {
+	rows, err := db.Query(`
+		SELECT id, tasks, trigger_type, status, started_at, finished_at, size_before_bytes, size_after_bytes, problems, error
+		FROM maintenance_runs ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query maintenance runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.MaintenanceRun{}
+	for rows.Next() {
+		var run models.MaintenanceRun
+		var tasks, problems string
+		var finishedAt sql.NullTime
+		if err := rows.Scan(&run.ID, &tasks, &run.Trigger, &run.Status, &run.StartedAt, &finishedAt,
+			&run.SizeBeforeBytes, &run.SizeAfterBytes, &problems, &run.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance run: %w", err)
+		}
+		json.Unmarshal([]byte(tasks), &run.Tasks)
+		json.Unmarshal([]byte(problems), &run.Problems)
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// LastScheduledMaintenanceAt returns when the schedule last ran, or nil
+func (db *Database) LastScheduledMaintenanceAt() (*time.Time, error) {
+	var startedAt time.Time
+	err := db.QueryRow(`SELECT started_at FROM maintenance_runs WHERE trigger_type = ? ORDER BY id DESC LIMIT 1`, models.MaintenanceTriggerScheduled).Scan(&startedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query maintenance runs: %w", err)
+	}
+	return &startedAt, nil
+}
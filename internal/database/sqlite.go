@@ -0,0 +1,327 @@
+// Package database - SQLite connection tuning, batched log writes and health stats
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	sqliteBusyTimeoutMs = 5000 // How long a connection waits for a lock before "database is locked"
+	sqliteMaxOpenConns  = 8
+	logQueueSize        = 10000 // Log entries waiting to be written
+	logBatchSize        = 500
+	logBatchInterval    = 100 * time.Millisecond
+)
+
+// sqliteDSN opens the database in WAL mode, so API reads are not blocked by
+// log writes, with a busy timeout so concurrent writers wait for each other
+// instead of failing. Transactions take the write lock when they begin, which
+// a busy timeout can wait for, rather than when they first write.
+func sqliteDSN(path string) string {
+	return fmt.Sprintf("%s?_journal_mode=WAL&_busy_timeout=%d&_synchronous=NORMAL&_txlock=immediate", path, sqliteBusyTimeoutMs)
+}
+
+// LogQueueStats describes the queue that batches log inserts
+type LogQueueStats struct {
+	Pending       int        `json:"pending"`
+	Capacity      int        `json:"capacity"`
+	Batches       int64      `json:"batches"`       // Transactions committed
+	Written       int64      `json:"written"`       // Entries stored
+	Failed        int64      `json:"failed"`        // Entries lost to failed batches
+	BlockedWrites int64      `json:"blockedWrites"` // Entries that waited for room in a full queue
+	LastBatchMs   float64    `json:"lastBatchMs"`
+	LastError     string     `json:"lastError,omitempty"`
+	LastErrorAt   *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// DBStats reports the health of the SQLite database
+type DBStats struct {
+	Path               string        `json:"path"`
+	JournalMode        string        `json:"journalMode"`
+	BusyTimeoutMs      int           `json:"busyTimeoutMs"`
+	SizeBytes          int64         `json:"sizeBytes"`
+	FileSizeBytes      int64         `json:"fileSizeBytes"` // The file on disk; VACUUM shrinks it
+	WALSizeBytes       int64         `json:"walSizeBytes"`
+	FreePages          int64         `json:"freePages"`
+	FragmentationPct   float64       `json:"fragmentationPercent"` // Share of the pages that are free
+	MaxOpenConnections int           `json:"maxOpenConnections"`
+	OpenConnections    int           `json:"openConnections"`
+	InUse              int           `json:"inUse"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"waitCount"` // Queries that waited for a free connection
+	WaitDurationMs     int64         `json:"waitDurationMs"`
+	LogQueue           LogQueueStats `json:"logQueue"`
+}
+
+type queuedLogEntry struct {
+	serviceID string
+	entry     models.LogEntry
+}
+
+// logWriteQueue serializes log inserts through a single writer that commits
+// them in batches, so bursts of output take one transaction instead of one
+// write lock per line
+type logWriteQueue struct {
+	entries chan queuedLogEntry
+	flushes chan chan struct{}
+	done    chan struct{}
+
+	closeMutex sync.RWMutex
+	closed     bool
+
+	statsMutex sync.Mutex
+	stats      LogQueueStats
+}
+
+// tuneConnections sizes the connection pool; with WAL, readers run
+// alongside the one writer
+func (db *Database) tuneConnections() {
+	db.SetMaxOpenConns(sqliteMaxOpenConns)
+	db.SetMaxIdleConns(sqliteMaxOpenConns)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+}
+
+// startLogWriter starts the goroutine that writes queued log entries
+func (db *Database) startLogWriter() {
+	db.logQueue = &logWriteQueue{
+		entries: make(chan queuedLogEntry, logQueueSize),
+		flushes: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stats:   LogQueueStats{Capacity: logQueueSize},
+	}
+	go db.runLogWriter(db.logQueue)
+}
+
+// QueueLogEntry stores a log entry in the next batch. It blocks while the
+// queue is full, and writes directly once the database is closing.
+func (db *Database) QueueLogEntry(serviceID string, logEntry models.LogEntry) {
+	q := db.logQueue
+	if q == nil {
+		if err := db.StoreLogEntry(serviceID, logEntry); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+		return
+	}
+
+	q.closeMutex.RLock()
+	defer q.closeMutex.RUnlock()
+	if q.closed {
+		if err := db.StoreLogEntry(serviceID, logEntry); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+		return
+	}
+
+	queued := queuedLogEntry{serviceID: serviceID, entry: logEntry}
+	select {
+	case q.entries <- queued:
+	default:
+		q.statsMutex.Lock()
+		q.stats.BlockedWrites++
+		q.statsMutex.Unlock()
+		q.entries <- queued
+	}
+}
+
+// FlushLogs waits until the log entries queued so far are written
+func (db *Database) FlushLogs() {
+	q := db.logQueue
+	if q == nil {
+		return
+	}
+
+	q.closeMutex.RLock()
+	defer q.closeMutex.RUnlock()
+	if q.closed {
+		return
+	}
+
+	reply := make(chan struct{})
+	q.flushes <- reply
+	<-reply
+}
+
+// Close writes the queued log entries and closes the database
+func (db *Database) Close() error {
+	if q := db.logQueue; q != nil {
+		q.closeMutex.Lock()
+		if !q.closed {
+			q.closed = true
+			close(q.entries)
+			<-q.done
+		}
+		q.closeMutex.Unlock()
+	}
+	return db.DB.Close()
+}
+
+func (db *Database) runLogWriter(q *logWriteQueue) {
+	defer close(q.done)
+
+	ticker := time.NewTicker(logBatchInterval)
+	defer ticker.Stop()
+
+	batch := make([]queuedLogEntry, 0, logBatchSize)
+	write := func() {
+		if len(batch) > 0 {
+			db.writeLogBatch(q, batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case queued, ok := <-q.entries:
+			if !ok {
+				write()
+				return
+			}
+			batch = append(batch, queued)
+			if len(batch) >= logBatchSize {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case reply := <-q.flushes:
+			for drained := false; !drained; {
+				select {
+				case queued := <-q.entries:
+					batch = append(batch, queued)
+					if len(batch) >= logBatchSize {
+						write()
+					}
+				default:
+					drained = true
+				}
+			}
+			write()
+			close(reply)
+		}
+	}
+}
+
+// writeLogBatch inserts a batch of log entries in one transaction
+func (db *Database) writeLogBatch(q *logWriteQueue, batch []queuedLogEntry) {
+	started := time.Now()
+	db.maintenanceMutex.RLock()
+	err := db.insertLogBatch(batch)
+	db.maintenanceMutex.RUnlock()
+	elapsed := time.Since(started)
+
+	q.statsMutex.Lock()
+	defer q.statsMutex.Unlock()
+	q.stats.LastBatchMs = float64(elapsed.Microseconds()) / 1000
+	if err != nil {
+		now := time.Now()
+		q.stats.Failed += int64(len(batch))
+		q.stats.LastError = err.Error()
+		q.stats.LastErrorAt = &now
+		log.Printf("[ERROR] Failed to store %d log entries: %v", len(batch), err)
+		return
+	}
+	q.stats.Batches++
+	q.stats.Written += int64(len(batch))
+}
+
+func (db *Database) insertLogBatch(batch []queuedLogEntry) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO service_logs (service_id, timestamp, level, message) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, queued := range batch {
+		timestamp, err := time.Parse(time.RFC3339Nano, queued.entry.Timestamp)
+		if err != nil {
+			timestamp = time.Now()
+		}
+		if _, err := stmt.Exec(queued.serviceID, timestamp, queued.entry.Level, queued.entry.Message); err != nil {
+			return fmt.Errorf("failed to insert log entry for service %s: %w", queued.serviceID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit log batch: %w", err)
+	}
+	return nil
+}
+
+// HealthStats returns the journal mode, size, fragmentation and connection
+// pool usage of the database and the state of the log write queue
+func (db *Database) HealthStats() (*DBStats, error) {
+	stats := &DBStats{Path: db.path}
+
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&stats.JournalMode); err != nil {
+		return nil, fmt.Errorf("failed to read journal mode: %w", err)
+	}
+	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&stats.BusyTimeoutMs); err != nil {
+		return nil, fmt.Errorf("failed to read busy timeout: %w", err)
+	}
+	var pageCount, pageSize int64
+	if err := db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return nil, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return nil, fmt.Errorf("failed to read page size: %w", err)
+	}
+	if err := db.QueryRow("PRAGMA freelist_count").Scan(&stats.FreePages); err != nil {
+		return nil, fmt.Errorf("failed to read free pages: %w", err)
+	}
+	stats.SizeBytes = pageCount * pageSize
+	stats.FileSizeBytes = db.FileSizeBytes()
+	if pageCount > 0 {
+		stats.FragmentationPct = math.Round(float64(stats.FreePages)*1000/float64(pageCount)) / 10
+	}
+	if info, err := os.Stat(db.path + "-wal"); err == nil {
+		stats.WALSizeBytes = info.Size()
+	}
+
+	pool := db.Stats()
+	stats.MaxOpenConnections = pool.MaxOpenConnections
+	stats.OpenConnections = pool.OpenConnections
+	stats.InUse = pool.InUse
+	stats.Idle = pool.Idle
+	stats.WaitCount = pool.WaitCount
+	stats.WaitDurationMs = pool.WaitDuration.Milliseconds()
+
+	stats.LogQueue = db.LogQueueStatus()
+	return stats, nil
+}
+
+// LogQueueStatus returns the state of the queue that batches log inserts
+func (db *Database) LogQueueStatus() LogQueueStats {
+	q := db.logQueue
+	if q == nil {
+		return LogQueueStats{}
+	}
+	q.statsMutex.Lock()
+	stats := q.stats
+	q.statsMutex.Unlock()
+	stats.Pending = len(q.entries)
+	return stats
+}
+
+// Probe times a trivial query, which waits behind a saturated connection
+// pool like any API read would
+func (db *Database) Probe(ctx context.Context) (time.Duration, error) {
+	started := time.Now()
+	var one int
+	if err := db.QueryRowContext(ctx, "SELECT 1").Scan(&one); err != nil {
+		return time.Since(started), fmt.Errorf("failed to query database: %w", err)
+	}
+	return time.Since(started), nil
+}
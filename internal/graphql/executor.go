@@ -0,0 +1,711 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResolveParams are passed to resolvers
+type ResolveParams struct {
+	Context context.Context
+	Source  any            // Value of the parent object; the event for subscription fields
+	Args    map[string]any // Arguments with variables substituted
+}
+
+// ResolveFunc returns the value of a field
+type ResolveFunc func(p ResolveParams) (any, error)
+
+// SubscribeFunc returns the event stream of a subscription field. The
+// channel must be closed once p.Context is done.
+type SubscribeFunc func(p ResolveParams) (<-chan any, error)
+
+// Field describes one field of an object type
+type Field struct {
+	Type        *Object           // Object type of the value (or its elements); nil for scalars
+	TypeName    string            // Scalar type shown in the schema, e.g. "String" or "[Int]"
+	List        bool              // The value is a list of Type
+	Args        map[string]string // Accepted arguments and their types
+	Description string
+	Resolve     ResolveFunc   // Defaults to the struct field or map key of the same name
+	Subscribe   SubscribeFunc // Subscription root fields only
+
+	index []int // Struct field index used by the default resolver
+}
+
+// Object is a GraphQL object type
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Schema holds the root types
+type Schema struct {
+	Query        *Object
+	Subscription *Object
+}
+
+// Request is a GraphQL request as sent over HTTP or a subscription socket
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Error is a request or field error
+type Error struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// Response is the result of a query or one subscription event
+type Response struct {
+	Data   any     `json:"data,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// ErrorResponse returns a response carrying a single request error
+func ErrorResponse(err error) *Response {
+	return &Response{Errors: []Error{{Message: err.Error()}}}
+}
+
+// executor holds the state of one operation execution
+type executor struct {
+	variables map[string]any
+	fragments map[string]*fragment
+	errors    []Error
+}
+
+// prepare parses a request and selects the operation to run
+func (s *Schema) prepare(req Request) (*operation, *executor, error) {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var op *operation
+	if req.OperationName == "" {
+		if len(doc.operations) > 1 {
+			return nil, nil, fmt.Errorf("operationName is required when the document has several operations")
+		}
+		op = doc.operations[0]
+	} else {
+		for _, candidate := range doc.operations {
+			if candidate.name == req.OperationName {
+				op = candidate
+			}
+		}
+		if op == nil {
+			return nil, nil, fmt.Errorf("unknown operation %q", req.OperationName)
+		}
+	}
+
+	e := &executor{variables: make(map[string]any), fragments: doc.fragments}
+	for _, def := range op.variables {
+		value, provided := req.Variables[def.name]
+		switch {
+		case provided && value != nil:
+			e.variables[def.name] = value
+		case !provided && def.hasDefault:
+			e.variables[def.name] = def.defaultValue
+		case def.nonNull:
+			return nil, nil, fmt.Errorf("variable $%s is required", def.name)
+		}
+	}
+
+	root := s.Query
+	if op.kind == "subscription" {
+		root = s.Subscription
+		if root == nil {
+			return nil, nil, fmt.Errorf("subscriptions are not supported")
+		}
+	}
+	if err := e.validate(root, op.selections, make(map[string]bool)); err != nil {
+		return nil, nil, err
+	}
+	return op, e, nil
+}
+
+// Execute runs a query operation
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	op, e, err := s.prepare(req)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	if op.kind != "query" {
+		return ErrorResponse(fmt.Errorf("%s operations must be sent over the websocket endpoint", op.kind))
+	}
+
+	data := e.executeSelections(ctx, s.Query, nil, op.selections, nil)
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// Subscribe runs a subscription and returns one response per event until
+// ctx is done or the event stream ends. A query yields a single response.
+func (s *Schema) Subscribe(ctx context.Context, req Request) (<-chan *Response, error) {
+	op, e, err := s.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.kind == "query" {
+		out := make(chan *Response, 1)
+		data := e.executeSelections(ctx, s.Query, nil, op.selections, nil)
+		out <- &Response{Data: data, Errors: e.errors}
+		close(out)
+		return out, nil
+	}
+
+	fields, err := e.collectFields(op.selections)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 1 {
+		return nil, fmt.Errorf("a subscription must select exactly one field")
+	}
+	sel := fields[0]
+	field := s.Subscription.Fields[sel.name]
+	if field == nil || field.Subscribe == nil {
+		return nil, fmt.Errorf("cannot subscribe to %q", sel.name)
+	}
+
+	args, err := e.arguments(field, sel)
+	if err != nil {
+		return nil, err
+	}
+	events, err := field.Subscribe(ResolveParams{Context: ctx, Args: args})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *Response)
+	go func() {
+		defer close(out)
+		key := sel.responseKey()
+		for {
+			var event any
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok = <-events:
+				if !ok {
+					return
+				}
+			}
+
+			run := &executor{variables: e.variables, fragments: e.fragments}
+			value := event
+			if field.Resolve != nil {
+				value, err = run.resolve(field, ResolveParams{Context: ctx, Source: event, Args: args})
+				if err != nil {
+					run.addError([]any{key}, err)
+					value = nil
+				}
+			}
+			data := newOrderedMap()
+			data.set(key, run.completeValue(ctx, field, value, sel.selections, []any{key}))
+
+			select {
+			case out <- &Response{Data: data, Errors: run.errors}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// validate checks every selected field exists, takes the given arguments
+// and has a selection set exactly when it is an object
+func (e *executor) validate(obj *Object, selections []selection, visiting map[string]bool) error {
+	for _, sel := range selections {
+		switch {
+		case sel.spread != "":
+			frag := e.fragments[sel.spread]
+			if frag == nil {
+				return fmt.Errorf("unknown fragment %q", sel.spread)
+			}
+			if visiting[sel.spread] {
+				return fmt.Errorf("fragment %q references itself", sel.spread)
+			}
+			visiting[sel.spread] = true
+			err := e.validate(obj, frag.selections, visiting)
+			delete(visiting, sel.spread)
+			if err != nil {
+				return err
+			}
+			continue
+		case sel.isInline:
+			if err := e.validate(obj, sel.inline, visiting); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if sel.name == "__typename" {
+			continue
+		}
+		if sel.name == "__schema" || sel.name == "__type" {
+			return fmt.Errorf("introspection is not supported; GET /api/graphql/schema returns the schema")
+		}
+		field := obj.Fields[sel.name]
+		if field == nil {
+			return fmt.Errorf("cannot query field %q on type %s", sel.name, obj.Name)
+		}
+		for _, arg := range sel.arguments {
+			if _, known := field.Args[arg.name]; !known {
+				return fmt.Errorf("unknown argument %q on field %s.%s", arg.name, obj.Name, sel.name)
+			}
+		}
+		if field.Type == nil && len(sel.selections) > 0 {
+			return fmt.Errorf("field %s.%s is a scalar and cannot have a selection set", obj.Name, sel.name)
+		}
+		if field.Type != nil {
+			if len(sel.selections) == 0 {
+				return fmt.Errorf("field %s.%s of type %s must have a selection set", obj.Name, sel.name, field.Type.Name)
+			}
+			if err := e.validate(field.Type, sel.selections, visiting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// collectFields flattens fragments and applies @skip/@include, merging the
+// sub-selections of fields selected more than once under the same key
+func (e *executor) collectFields(selections []selection) ([]selection, error) {
+	var fields []selection
+	positions := make(map[string]int)
+
+	var collect func(selections []selection) error
+	collect = func(selections []selection) error {
+		for _, sel := range selections {
+			include, err := e.included(sel.directives)
+			if err != nil {
+				return err
+			}
+			if !include {
+				continue
+			}
+
+			switch {
+			case sel.spread != "":
+				if err := collect(e.fragments[sel.spread].selections); err != nil {
+					return err
+				}
+			case sel.isInline:
+				if err := collect(sel.inline); err != nil {
+					return err
+				}
+			default:
+				key := sel.responseKey()
+				if i, exists := positions[key]; exists {
+					if fields[i].name != sel.name {
+						return fmt.Errorf("fields %q and %q conflict on response key %q", fields[i].name, sel.name, key)
+					}
+					fields[i].selections = append(fields[i].selections, sel.selections...)
+					continue
+				}
+				positions[key] = len(fields)
+				sel.selections = append([]selection(nil), sel.selections...)
+				fields = append(fields, sel)
+			}
+		}
+		return nil
+	}
+
+	return fields, collect(selections)
+}
+
+// included evaluates the @skip and @include directives
+func (e *executor) included(directives []directive) (bool, error) {
+	for _, d := range directives {
+		if d.name != "skip" && d.name != "include" {
+			continue
+		}
+		var condition any
+		for _, arg := range d.arguments {
+			if arg.name == "if" {
+				condition = e.value(arg.value)
+			}
+		}
+		value, ok := condition.(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s requires a Boolean 'if' argument", d.name)
+		}
+		if (d.name == "skip") == value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e *executor) arguments(field *Field, sel selection) (map[string]any, error) {
+	args := make(map[string]any, len(sel.arguments))
+	for _, arg := range sel.arguments {
+		if _, known := field.Args[arg.name]; !known {
+			return nil, fmt.Errorf("unknown argument %q on field %s", arg.name, sel.name)
+		}
+		args[arg.name] = e.value(arg.value)
+	}
+	return args, nil
+}
+
+// value substitutes variables in an argument value
+func (e *executor) value(v any) any {
+	switch v := v.(type) {
+	case variableRef:
+		return e.variables[string(v)]
+	case enumValue:
+		return string(v)
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = e.value(item)
+		}
+		return list
+	case map[string]any:
+		object := make(map[string]any, len(v))
+		for key, item := range v {
+			object[key] = e.value(item)
+		}
+		return object
+	}
+	return v
+}
+
+func (e *executor) executeSelections(ctx context.Context, obj *Object, source any, selections []selection, path []any) *orderedMap {
+	result := newOrderedMap()
+
+	fields, err := e.collectFields(selections)
+	if err != nil {
+		e.addError(path, err)
+		return result
+	}
+
+	for _, sel := range fields {
+		key := sel.responseKey()
+		fieldPath := append(append([]any(nil), path...), key)
+
+		if sel.name == "__typename" {
+			result.set(key, obj.Name)
+			continue
+		}
+		field := obj.Fields[sel.name]
+
+		args, err := e.arguments(field, sel)
+		if err != nil {
+			e.addError(fieldPath, err)
+			result.set(key, nil)
+			continue
+		}
+		value, err := e.resolve(field, ResolveParams{Context: ctx, Source: source, Args: args})
+		if err != nil {
+			e.addError(fieldPath, err)
+			result.set(key, nil)
+			continue
+		}
+		result.set(key, e.completeValue(ctx, field, value, sel.selections, fieldPath))
+	}
+	return result
+}
+
+// resolve calls the field's resolver, or reads the field from the source
+func (e *executor) resolve(field *Field, p ResolveParams) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+
+	if field.Resolve != nil {
+		return field.Resolve(p)
+	}
+	return defaultResolve(field, p.Source), nil
+}
+
+func defaultResolve(field *Field, source any) any {
+	v := reflect.ValueOf(source)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct && field.index != nil {
+		return v.FieldByIndex(field.index).Interface()
+	}
+	return nil
+}
+
+// completeValue resolves the sub-selections of object values; scalars are
+// returned as they are and encoded as JSON
+func (e *executor) completeValue(ctx context.Context, field *Field, value any, selections []selection, path []any) any {
+	if field.Type == nil || isNil(value) {
+		return value
+	}
+
+	v := reflect.ValueOf(value)
+	if field.List && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		list := make([]any, v.Len())
+		for i := range list {
+			item := v.Index(i)
+			if item.CanAddr() {
+				item = item.Addr() // Avoid copying structs that hold a mutex
+			}
+			list[i] = e.executeSelections(ctx, field.Type, item.Interface(), selections, append(append([]any(nil), path...), i))
+		}
+		return list
+	}
+	return e.executeSelections(ctx, field.Type, value, selections, path)
+}
+
+func isNil(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+func (e *executor) addError(path []any, err error) {
+	e.errors = append(e.errors, Error{Message: err.Error(), Path: path})
+}
+
+func (sel selection) responseKey() string {
+	if sel.alias != "" {
+		return sel.alias
+	}
+	return sel.name
+}
+
+// orderedMap keeps response fields in selection order
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]any)}
+}
+
+func (m *orderedMap) set(key string, value any) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// MarshalJSON encodes the fields in selection order
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		value, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// StructObject builds an object type exposing every JSON field of a struct.
+// Nested structs become object types of their own; maps and other values
+// that have no fixed shape are exposed as JSON scalars. Fields in extra are
+// added or replace the generated ones.
+func StructObject(name string, sample any, extra map[string]*Field) *Object {
+	obj := structObject(name, reflect.TypeOf(sample), make(map[reflect.Type]*Object))
+	for fieldName, field := range extra {
+		obj.Fields[fieldName] = field
+	}
+	return obj
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func structObject(name string, t reflect.Type, seen map[reflect.Type]*Object) *Object {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if obj, exists := seen[t]; exists {
+		return obj
+	}
+	obj := &Object{Name: name, Fields: make(map[string]*Field)}
+	seen[t] = obj
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName == "" {
+			jsonName = sf.Name
+		}
+
+		field := &Field{index: sf.Index}
+		ft := sf.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && ft != timeType:
+			field.Type = structObject(typeName(ft, name, sf.Name), ft, seen)
+		case (ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array) && isStruct(ft.Elem()):
+			field.Type = structObject(typeName(derefType(ft.Elem()), name, sf.Name), ft.Elem(), seen)
+			field.List = true
+		default:
+			field.TypeName = scalarName(ft)
+		}
+		obj.Fields[jsonName] = field
+	}
+	return obj
+}
+
+// typeName names the object type of a nested struct after its Go type, or
+// after the parent type and field for anonymous structs
+func typeName(t reflect.Type, parent, field string) string {
+	if t.Name() != "" {
+		return t.Name()
+	}
+	return parent + field
+}
+
+func isStruct(t reflect.Type) bool {
+	t = derefType(t)
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// scalarName names the GraphQL scalar a Go type is exposed as
+func scalarName(t reflect.Type) string {
+	if t == timeType {
+		return "Time"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "String"
+	case reflect.Bool:
+		return "Boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Int"
+	case reflect.Float32, reflect.Float64:
+		return "Float"
+	case reflect.Slice, reflect.Array:
+		return "[" + scalarName(derefType(t.Elem())) + "]"
+	}
+	return "JSON"
+}
+
+// SDL prints the schema in the GraphQL schema definition language
+func (s *Schema) SDL() string {
+	var objects []*Object
+	seen := make(map[*Object]bool)
+	var visit func(obj *Object)
+	visit = func(obj *Object) {
+		if obj == nil || seen[obj] {
+			return
+		}
+		seen[obj] = true
+		objects = append(objects, obj)
+		for _, name := range sortedKeys(obj.Fields) {
+			visit(obj.Fields[name].Type)
+		}
+	}
+	visit(s.Query)
+	visit(s.Subscription)
+
+	var b strings.Builder
+	b.WriteString("scalar Time\nscalar JSON\n")
+	for _, obj := range objects {
+		fmt.Fprintf(&b, "\ntype %s {\n", obj.Name)
+		for _, name := range sortedKeys(obj.Fields) {
+			field := obj.Fields[name]
+			if field.Description != "" {
+				fmt.Fprintf(&b, "  # %s\n", field.Description)
+			}
+			b.WriteString("  " + name)
+			if len(field.Args) > 0 {
+				var args []string
+				for _, arg := range sortedKeys(field.Args) {
+					args = append(args, arg+": "+field.Args[arg])
+				}
+				b.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			typeName := field.TypeName
+			if field.Type != nil {
+				typeName = field.Type.Name
+				if field.List {
+					typeName = "[" + typeName + "]"
+				}
+			}
+			b.WriteString(": " + typeName + "\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// StringArg returns a string argument, or "" when it is absent
+func StringArg(args map[string]any, name string) (string, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", fmt.Errorf("argument %q must be a String", name)
+}
+
+// IntArg returns an integer argument, or def when it is absent
+func IntArg(args map[string]any, name string, def int) (int, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return def, nil
+	case int:
+		return v, nil
+	case float64: // Variables decoded from JSON
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %q must be an Int", name)
+}
@@ -0,0 +1,384 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testService struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Port   int    `json:"port"`
+	Secret string `json:"-"`
+	Health struct {
+		Healthy bool `json:"healthy"`
+	} `json:"health"`
+}
+
+// testSchema exposes a fixed list of services
+func testSchema() *Schema {
+	api := testService{Name: "api", Status: "running", Port: 8080}
+	api.Health.Healthy = true
+	services := []testService{api, {Name: "worker", Status: "stopped", Port: 9090}}
+
+	service := StructObject("Service", testService{}, map[string]*Field{
+		"broken": {
+			TypeName: "String",
+			Resolve: func(p ResolveParams) (any, error) {
+				return nil, errors.New("broken resolver")
+			},
+		},
+	})
+
+	return &Schema{
+		Query: &Object{
+			Name: "Query",
+			Fields: map[string]*Field{
+				"services": {
+					Type: service,
+					List: true,
+					Args: map[string]string{"status": "String"},
+					Resolve: func(p ResolveParams) (any, error) {
+						status, err := StringArg(p.Args, "status")
+						if err != nil {
+							return nil, err
+						}
+						var result []testService
+						for _, s := range services {
+							if status == "" || s.Status == status {
+								result = append(result, s)
+							}
+						}
+						return result, nil
+					},
+				},
+				"service": {
+					Type: service,
+					Args: map[string]string{"name": "String!"},
+					Resolve: func(p ResolveParams) (any, error) {
+						name, err := StringArg(p.Args, "name")
+						if err != nil {
+							return nil, err
+						}
+						for i := range services {
+							if services[i].Name == name {
+								return &services[i], nil
+							}
+						}
+						return nil, nil
+					},
+				},
+				"count": {
+					TypeName: "Int",
+					Args:     map[string]string{"limit": "Int"},
+					Resolve: func(p ResolveParams) (any, error) {
+						return IntArg(p.Args, "limit", len(services))
+					},
+				},
+				"panics": {
+					TypeName: "String",
+					Resolve: func(p ResolveParams) (any, error) {
+						panic("boom")
+					},
+				},
+			},
+		},
+		Subscription: &Object{
+			Name: "Subscription",
+			Fields: map[string]*Field{
+				"ticks": {
+					TypeName: "Int",
+					Args:     map[string]string{"count": "Int"},
+					Subscribe: func(p ResolveParams) (<-chan any, error) {
+						count, err := IntArg(p.Args, "count", 1)
+						if err != nil {
+							return nil, err
+						}
+						events := make(chan any)
+						go func() {
+							defer close(events)
+							for i := 1; i <= count; i++ {
+								select {
+								case events <- i:
+								case <-p.Context.Done():
+									return
+								}
+							}
+						}()
+						return events, nil
+					},
+				},
+			},
+		},
+	}
+}
+
+// execute runs a request and returns the JSON encoded response
+func execute(t *testing.T, req Request) string {
+	t.Helper()
+	body, err := json.Marshal(testSchema().Execute(context.Background(), req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestExecute_SelectsFieldsInOrder(t *testing.T) {
+	got := execute(t, Request{Query: `{ services { port name health { healthy } } }`})
+
+	want := `{"data":{"services":[{"port":8080,"name":"api","health":{"healthy":true}},{"port":9090,"name":"worker","health":{"healthy":false}}]}}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_Aliases(t *testing.T) {
+	got := execute(t, Request{Query: `{ a: service(name: "api") { n: name } w: service(name: "worker") { name } total: count }`})
+
+	want := `{"data":{"a":{"n":"api"},"w":{"name":"worker"},"total":2}}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_Fragments(t *testing.T) {
+	got := execute(t, Request{Query: `
+		{ service(name: "api") { ...Basics ... on Service { port } ... { __typename } } }
+		fragment Basics on Service { name status }
+	`})
+
+	want := `{"data":{"service":{"name":"api","status":"running","port":8080,"__typename":"Service"}}}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_MergesRepeatedFields(t *testing.T) {
+	got := execute(t, Request{Query: `{ service(name: "api") { name } service(name: "api") { port } }`})
+
+	want := `{"data":{"service":{"name":"api","port":8080}}}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_Variables(t *testing.T) {
+	query := `query Q($status: String = "stopped", $limit: Int) { services(status: $status) { name } count(limit: $limit) }`
+
+	// Create request relying on the default value
+	got := execute(t, Request{Query: query})
+	want := `{"data":{"services":[{"name":"worker"}],"count":2}}`
+	if got != want {
+		t.Errorf("Unexpected response with defaults: got %s want %s", got, want)
+	}
+
+	// Create request overriding the default; JSON numbers arrive as float64
+	got = execute(t, Request{Query: query, Variables: map[string]any{"status": "running", "limit": float64(1)}})
+	want = `{"data":{"services":[{"name":"api"}],"count":1}}`
+	if got != want {
+		t.Errorf("Unexpected response with variables: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_SkipAndInclude(t *testing.T) {
+	query := `query ($withPort: Boolean!, $skipName: Boolean = false) {
+		service(name: "api") {
+			name @skip(if: $skipName)
+			port @include(if: $withPort)
+			status @include(if: false)
+			...Health @skip(if: true)
+		}
+	}
+	fragment Health on Service { health { healthy } }`
+
+	got := execute(t, Request{Query: query, Variables: map[string]any{"withPort": true}})
+	want := `{"data":{"service":{"name":"api","port":8080}}}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+
+	got = execute(t, Request{Query: query, Variables: map[string]any{"withPort": false, "skipName": true}})
+	want = `{"data":{"service":{}}}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_OperationName(t *testing.T) {
+	query := `query A { count } query B { service(name: "api") { name } }`
+
+	got := execute(t, Request{Query: query, OperationName: "B"})
+	want := `{"data":{"service":{"name":"api"}}}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_FieldErrors(t *testing.T) {
+	got := execute(t, Request{Query: `{ service(name: "api") { name broken } panics }`})
+
+	want := `{"data":{"service":{"name":"api","broken":null},"panics":null},"errors":[{"message":"broken resolver","path":["service","broken"]},{"message":"internal error: boom","path":["panics"]}]}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_RequestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{"syntax error", Request{Query: `{ services { name }`}, "syntax error"},
+		{"unknown field", Request{Query: `{ services { uptime } }`}, `cannot query field "uptime" on type Service`},
+		{"hidden field", Request{Query: `{ services { Secret } }`}, `cannot query field "Secret"`},
+		{"unknown argument", Request{Query: `{ service(id: 1) { name } }`}, `unknown argument "id"`},
+		{"missing selection set", Request{Query: `{ services }`}, "must have a selection set"},
+		{"selection on scalar", Request{Query: `{ count { value } }`}, "is a scalar"},
+		{"unknown fragment", Request{Query: `{ services { ...Missing } }`}, `unknown fragment "Missing"`},
+		{"fragment cycle", Request{Query: `{ services { ...A } } fragment A on Service { ...A }`}, "references itself"},
+		{"missing variable", Request{Query: `query ($name: String!) { service(name: $name) { name } }`}, "variable $name is required"},
+		{"null variable", Request{Query: `query ($name: String!) { service(name: $name) { name } }`, Variables: map[string]any{"name": nil}}, "variable $name is required"},
+		{"ambiguous operation", Request{Query: `query A { count } query B { count }`}, "operationName is required"},
+		{"unknown operation", Request{Query: `query A { count }`, OperationName: "B"}, `unknown operation "B"`},
+		{"introspection", Request{Query: `{ __schema { types { name } } }`}, "introspection is not supported"},
+		{"subscription over http", Request{Query: `subscription { ticks }`}, "must be sent over the websocket endpoint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := testSchema().Execute(context.Background(), tt.req)
+			if resp.Data != nil {
+				t.Errorf("Expected no data, got %v", resp.Data)
+			}
+			if len(resp.Errors) != 1 {
+				t.Fatalf("Expected 1 error, got %v", resp.Errors)
+			}
+			if !strings.Contains(resp.Errors[0].Message, tt.want) {
+				t.Errorf("Expected error containing %q, got %q", tt.want, resp.Errors[0].Message)
+			}
+		})
+	}
+}
+
+func TestExecute_DirectiveErrors(t *testing.T) {
+	got := execute(t, Request{Query: `{ service(name: "api") { name @include(if: "yes") } }`})
+
+	want := `{"data":{"service":{}},"errors":[{"message":"@include requires a Boolean 'if' argument","path":["service"]}]}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestExecute_ConflictingAliases(t *testing.T) {
+	got := execute(t, Request{Query: `{ service(name: "api") { x: name x: status } }`})
+
+	want := `{"data":{"service":{}},"errors":[{"message":"fields \"name\" and \"status\" conflict on response key \"x\"","path":["service"]}]}`
+	if got != want {
+		t.Errorf("Unexpected response: got %s want %s", got, want)
+	}
+}
+
+func TestSubscribe_StreamsEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	responses, err := testSchema().Subscribe(ctx, Request{Query: `subscription { tick: ticks(count: 3) }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for resp := range responses {
+		body, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(body))
+	}
+
+	want := []string{`{"data":{"tick":1}}`, `{"data":{"tick":2}}`, `{"data":{"tick":3}}`}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Unexpected events: got %v want %v", got, want)
+	}
+}
+
+func TestSubscribe_Query(t *testing.T) {
+	responses, err := testSchema().Subscribe(context.Background(), Request{Query: `{ count }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	for resp := range responses {
+		count++
+		body, _ := json.Marshal(resp)
+		if string(body) != `{"data":{"count":2}}` {
+			t.Errorf("Unexpected response: got %s", body)
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected a single response, got %d", count)
+	}
+}
+
+func TestSubscribe_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"two fields", `subscription { a: ticks b: ticks }`, "exactly one field"},
+		{"unknown field", `subscription { events }`, `cannot query field "events"`},
+		{"bad argument", `subscription { ticks(count: "many") }`, `argument "count" must be an Int`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testSchema().Subscribe(context.Background(), Request{Query: tt.query})
+			if err == nil {
+				t.Fatalf("Expected an error for %q", tt.query)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestSubscribe_NotSupported(t *testing.T) {
+	schema := testSchema()
+	schema.Subscription = nil
+
+	_, err := schema.Subscribe(context.Background(), Request{Query: `subscription { ticks }`})
+	if err == nil || !strings.Contains(err.Error(), "subscriptions are not supported") {
+		t.Errorf("Expected subscriptions to be unsupported, got %v", err)
+	}
+}
+
+func TestSDL(t *testing.T) {
+	sdl := testSchema().SDL()
+
+	for _, want := range []string{
+		"type Query {",
+		"  service(name: String!): Service\n",
+		"  services(status: String): [Service]\n",
+		"type Service {",
+		"  health: ServiceHealth\n",
+		"  port: Int\n",
+		"type ServiceHealth {",
+		"  healthy: Boolean\n",
+		"type Subscription {",
+	} {
+		if !strings.Contains(sdl, want) {
+			t.Errorf("Expected SDL to contain %q, got:\n%s", want, sdl)
+		}
+	}
+	if strings.Contains(sdl, "Secret") {
+		t.Errorf("Expected fields tagged json:\"-\" to be hidden, got:\n%s", sdl)
+	}
+}
@@ -0,0 +1,629 @@
+// Package graphql is a small GraphQL engine for the dashboard API. It parses
+// query and subscription documents (fields, aliases, arguments, variables,
+// fragments and the @skip/@include directives) and executes them against a
+// schema of resolver functions. Mutations and introspection are not supported.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL request
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // "query" or "subscription"
+	name       string
+	variables  []variableDefinition
+	selections []selection
+}
+
+type variableDefinition struct {
+	name         string
+	nonNull      bool
+	defaultValue any
+	hasDefault   bool
+}
+
+type fragment struct {
+	name       string
+	selections []selection
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	alias      string
+	name       string
+	arguments  []argument
+	directives []directive
+	selections []selection
+	spread     string      // fragment name for ...Name
+	inline     []selection // selections of an inline fragment
+	isInline   bool
+}
+
+type argument struct {
+	name  string
+	value any
+}
+
+type directive struct {
+	name      string
+	arguments []argument
+}
+
+// variableRef is an argument value of the form $name
+type variableRef string
+
+// enumValue is an unquoted name used as a value
+type enumValue string
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type parser struct {
+	src    string
+	pos    int
+	tok    token
+	peeked bool
+}
+
+// parse parses a GraphQL document
+func parse(src string) (*document, error) {
+	p := &parser{src: src}
+	doc := &document{fragments: make(map[string]*fragment)}
+
+	for {
+		tok, err := p.peek()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind == tokenEOF {
+			break
+		}
+
+		switch {
+		case tok.kind == tokenPunct && tok.value == "{":
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+
+		case tok.kind == tokenName && (tok.value == "query" || tok.value == "subscription" || tok.value == "mutation"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+
+		case tok.kind == tokenName && tok.value == "fragment":
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := doc.fragments[frag.name]; exists {
+				return nil, fmt.Errorf("fragment %s is defined more than once", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+
+		default:
+			return nil, p.errorf(tok, "unexpected %q", tok.value)
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document contains no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	kindTok, _ := p.next()
+	if kindTok.value == "mutation" {
+		return nil, fmt.Errorf("mutations are not supported; use the REST API")
+	}
+	op := &operation{kind: kindTok.value}
+
+	tok, err := p.peek()
+	if err != nil {
+		return nil, err
+	}
+	if tok.kind == tokenName {
+		p.next()
+		op.name = tok.value
+	}
+
+	if p.skipPunct("(") {
+		for !p.skipPunct(")") {
+			def, err := p.parseVariableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.variables = append(op.variables, def)
+		}
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	op.selections, err = p.parseSelectionSet()
+	return op, err
+}
+
+func (p *parser) parseVariableDefinition() (variableDefinition, error) {
+	var def variableDefinition
+	if err := p.expectPunct("$"); err != nil {
+		return def, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return def, err
+	}
+	def.name = name
+	if err := p.expectPunct(":"); err != nil {
+		return def, err
+	}
+	if def.nonNull, err = p.parseType(); err != nil {
+		return def, err
+	}
+	if p.skipPunct("=") {
+		if def.defaultValue, err = p.parseValue(true); err != nil {
+			return def, err
+		}
+		def.hasDefault = true
+	}
+	_, err = p.parseDirectives()
+	return def, err
+}
+
+// parseType skips a type reference such as [String!]! and reports whether
+// the outer type is non-null. Values are checked by the resolvers instead.
+func (p *parser) parseType() (bool, error) {
+	if p.skipPunct("[") {
+		if _, err := p.parseType(); err != nil {
+			return false, err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return false, err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return false, err
+	}
+	return p.skipPunct("!"), nil
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	p.next() // "fragment"
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if on, err := p.expectName(); err != nil || on != "on" {
+		return nil, fmt.Errorf("expected 'on' after fragment %s", name)
+	}
+	if _, err := p.expectName(); err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: name, selections: selections}, nil
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []selection
+	for !p.skipPunct("}") {
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("selection set cannot be empty")
+	}
+	return selections, nil
+}
+
+func (p *parser) parseSelection() (selection, error) {
+	var sel selection
+	var err error
+
+	if p.skipPunct("...") {
+		tok, err := p.peek()
+		if err != nil {
+			return sel, err
+		}
+		if tok.kind == tokenName && tok.value != "on" {
+			p.next()
+			sel.spread = tok.value
+			sel.directives, err = p.parseDirectives()
+			return sel, err
+		}
+		// Inline fragment; the type condition is accepted but not checked
+		if tok.kind == tokenName && tok.value == "on" {
+			p.next()
+			if _, err := p.expectName(); err != nil {
+				return sel, err
+			}
+		}
+		sel.isInline = true
+		if sel.directives, err = p.parseDirectives(); err != nil {
+			return sel, err
+		}
+		sel.inline, err = p.parseSelectionSet()
+		return sel, err
+	}
+
+	if sel.name, err = p.expectName(); err != nil {
+		return sel, err
+	}
+	if p.skipPunct(":") {
+		sel.alias = sel.name
+		if sel.name, err = p.expectName(); err != nil {
+			return sel, err
+		}
+	}
+	if sel.arguments, err = p.parseArguments(); err != nil {
+		return sel, err
+	}
+	if sel.directives, err = p.parseDirectives(); err != nil {
+		return sel, err
+	}
+
+	tok, err := p.peek()
+	if err != nil {
+		return sel, err
+	}
+	if tok.kind == tokenPunct && tok.value == "{" {
+		sel.selections, err = p.parseSelectionSet()
+	}
+	return sel, err
+}
+
+func (p *parser) parseArguments() ([]argument, error) {
+	if !p.skipPunct("(") {
+		return nil, nil
+	}
+
+	var arguments []argument
+	for !p.skipPunct(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument{name: name, value: value})
+	}
+	return arguments, nil
+}
+
+func (p *parser) parseDirectives() ([]directive, error) {
+	var directives []directive
+	for p.skipPunct("@") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive{name: name, arguments: arguments})
+	}
+	return directives, nil
+}
+
+// parseValue parses an argument value; constant disallows variables
+func (p *parser) parseValue(constant bool) (any, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid integer %s", tok.value)
+		}
+		return int(n), nil
+	case tokenFloat:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %s", tok.value)
+		}
+		return f, nil
+	case tokenString:
+		return tok.value, nil
+	case tokenName:
+		switch tok.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enumValue(tok.value), nil
+	case tokenPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, p.errorf(tok, "variables are not allowed here")
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			return variableRef(name), nil
+		case "[":
+			list := []any{}
+			for !p.skipPunct("]") {
+				value, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, value)
+			}
+			return list, nil
+		case "{":
+			object := map[string]any{}
+			for !p.skipPunct("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunct(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+				object[name] = value
+			}
+			return object, nil
+		}
+	}
+	return nil, p.errorf(tok, "unexpected %q in value", tok.value)
+}
+
+func (p *parser) expectName() (string, error) {
+	tok, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if tok.kind != tokenName {
+		return "", p.errorf(tok, "expected a name, found %q", tok.value)
+	}
+	return tok.value, nil
+}
+
+func (p *parser) expectPunct(value string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok.kind != tokenPunct || tok.value != value {
+		return p.errorf(tok, "expected %q, found %q", value, tok.value)
+	}
+	return nil
+}
+
+// skipPunct consumes the next token if it is the given punctuator
+func (p *parser) skipPunct(value string) bool {
+	tok, err := p.peek()
+	if err != nil || tok.kind != tokenPunct || tok.value != value {
+		return false
+	}
+	p.next()
+	return true
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	line, column := 1, 1
+	for _, r := range p.src[:tok.pos] {
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return fmt.Errorf("syntax error at %d:%d: %s", line, column, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() (token, error) {
+	if p.peeked {
+		return p.tok, nil
+	}
+	tok, err := p.lex()
+	if err != nil {
+		return tok, err
+	}
+	p.tok = tok
+	p.peeked = true
+	return tok, nil
+}
+
+func (p *parser) next() (token, error) {
+	tok, err := p.peek()
+	p.peeked = false
+	if err == nil && tok.kind == tokenEOF {
+		return tok, p.errorf(tok, "unexpected end of document")
+	}
+	return tok, err
+}
+
+// lex reads the next token, skipping whitespace, commas and comments
+func (p *parser) lex() (token, error) {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		break
+	}
+	if p.pos >= len(p.src) {
+		return token{kind: tokenEOF, pos: p.pos}, nil
+	}
+
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		return token{kind: tokenPunct, value: "...", pos: start}, nil
+
+	case strings.IndexByte("!$():=@[]{|}&", c) >= 0:
+		p.pos++
+		return token{kind: tokenPunct, value: string(c), pos: start}, nil
+
+	case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		for p.pos < len(p.src) && isNameChar(p.src[p.pos]) {
+			p.pos++
+		}
+		return token{kind: tokenName, value: p.src[start:p.pos], pos: start}, nil
+
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.lexNumber()
+
+	case c == '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			end := strings.Index(p.src[p.pos+3:], `"""`)
+			if end < 0 {
+				return token{}, p.errorf(token{pos: start}, "unterminated block string")
+			}
+			value := p.src[p.pos+3 : p.pos+3+end]
+			p.pos += end + 6
+			return token{kind: tokenString, value: value, pos: start}, nil
+		}
+		return p.lexString()
+	}
+
+	r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+	return token{}, p.errorf(token{pos: start}, "unexpected character %q", r)
+}
+
+func (p *parser) lexNumber() (token, error) {
+	start := p.pos
+	kind := tokenInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokenFloat
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokenFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	return token{kind: kind, value: p.src[start:p.pos], pos: start}, nil
+}
+
+func (p *parser) lexString() (token, error) {
+	start := p.pos
+	p.pos++ // opening quote
+
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return token{kind: tokenString, value: b.String(), pos: start}, nil
+		case c == '\n':
+			return token{}, p.errorf(token{pos: start}, "unterminated string")
+		case c == '\\' && p.pos+1 < len(p.src):
+			escape := p.src[p.pos+1]
+			p.pos += 2
+			switch escape {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'u':
+				if p.pos+4 > len(p.src) {
+					return token{}, p.errorf(token{pos: start}, "invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					return token{}, p.errorf(token{pos: start}, "invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				p.pos += 4
+			default:
+				b.WriteByte(escape)
+			}
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+	return token{}, p.errorf(token{pos: start}, "unterminated string")
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,180 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse_ShorthandQuery(t *testing.T) {
+	doc, err := parse(`{ services { name status } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.operations) != 1 {
+		t.Fatalf("Expected 1 operation, got %d", len(doc.operations))
+	}
+	op := doc.operations[0]
+	if op.kind != "query" || op.name != "" {
+		t.Errorf("Expected anonymous query, got kind %q name %q", op.kind, op.name)
+	}
+	if len(op.selections) != 1 || op.selections[0].name != "services" {
+		t.Fatalf("Expected a single services selection, got %+v", op.selections)
+	}
+	if got := len(op.selections[0].selections); got != 2 {
+		t.Errorf("Expected 2 sub-selections, got %d", got)
+	}
+}
+
+func TestParse_AliasesAndArguments(t *testing.T) {
+	doc, err := parse(`query Lookup { first: service(name: "api", limit: 5, ratio: 0.5, on: true, mode: FAST, tags: ["a", "b"], filter: {env: "dev"}) { name } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := doc.operations[0]
+	if op.name != "Lookup" {
+		t.Errorf("Expected operation name Lookup, got %q", op.name)
+	}
+	sel := op.selections[0]
+	if sel.alias != "first" || sel.name != "service" {
+		t.Errorf("Expected alias first for service, got alias %q name %q", sel.alias, sel.name)
+	}
+	if sel.responseKey() != "first" {
+		t.Errorf("Expected response key first, got %q", sel.responseKey())
+	}
+
+	args := make(map[string]any)
+	for _, arg := range sel.arguments {
+		args[arg.name] = arg.value
+	}
+	if args["name"] != "api" {
+		t.Errorf("Expected name argument api, got %v", args["name"])
+	}
+	if args["limit"] != 5 {
+		t.Errorf("Expected limit argument 5, got %v", args["limit"])
+	}
+	if args["ratio"] != 0.5 {
+		t.Errorf("Expected ratio argument 0.5, got %v", args["ratio"])
+	}
+	if args["on"] != true {
+		t.Errorf("Expected on argument true, got %v", args["on"])
+	}
+	if args["mode"] != enumValue("FAST") {
+		t.Errorf("Expected mode argument to be enum FAST, got %#v", args["mode"])
+	}
+	if tags, ok := args["tags"].([]any); !ok || len(tags) != 2 || tags[1] != "b" {
+		t.Errorf("Expected tags argument [a b], got %#v", args["tags"])
+	}
+	if filter, ok := args["filter"].(map[string]any); !ok || filter["env"] != "dev" {
+		t.Errorf("Expected filter argument {env: dev}, got %#v", args["filter"])
+	}
+}
+
+func TestParse_VariableDefinitions(t *testing.T) {
+	doc, err := parse(`query Q($name: String!, $limit: Int = 10, $tags: [String!]) { service(name: $name) { name } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := doc.operations[0].variables
+	if len(vars) != 3 {
+		t.Fatalf("Expected 3 variable definitions, got %d", len(vars))
+	}
+	if vars[0].name != "name" || !vars[0].nonNull || vars[0].hasDefault {
+		t.Errorf("Unexpected definition for $name: %+v", vars[0])
+	}
+	if vars[1].name != "limit" || vars[1].nonNull || !vars[1].hasDefault || vars[1].defaultValue != 10 {
+		t.Errorf("Unexpected definition for $limit: %+v", vars[1])
+	}
+	if vars[2].name != "tags" || vars[2].nonNull {
+		t.Errorf("Unexpected definition for $tags: %+v", vars[2])
+	}
+
+	arg := doc.operations[0].selections[0].arguments[0]
+	if arg.value != variableRef("name") {
+		t.Errorf("Expected argument to reference $name, got %#v", arg.value)
+	}
+}
+
+func TestParse_Fragments(t *testing.T) {
+	doc, err := parse(`
+		query { services { ...Basics ... on Service @include(if: true) { port } ... { status } } }
+		fragment Basics on Service { name }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frag := doc.fragments["Basics"]
+	if frag == nil || len(frag.selections) != 1 || frag.selections[0].name != "name" {
+		t.Fatalf("Expected fragment Basics selecting name, got %+v", frag)
+	}
+
+	selections := doc.operations[0].selections[0].selections
+	if len(selections) != 3 {
+		t.Fatalf("Expected 3 selections, got %d", len(selections))
+	}
+	if selections[0].spread != "Basics" {
+		t.Errorf("Expected spread of Basics, got %+v", selections[0])
+	}
+	if !selections[1].isInline || len(selections[1].directives) != 1 || selections[1].directives[0].name != "include" {
+		t.Errorf("Expected inline fragment with @include, got %+v", selections[1])
+	}
+	if !selections[2].isInline || selections[2].inline[0].name != "status" {
+		t.Errorf("Expected inline fragment without type condition, got %+v", selections[2])
+	}
+}
+
+func TestParse_CommentsAndStrings(t *testing.T) {
+	doc, err := parse("# list services\n{ service(name: \"a\\\"b\\u0041\") { name } # trailing\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.operations[0].selections[0].arguments[0].value; got != `a"bA` {
+		t.Errorf("Expected escaped string a\"bA, got %q", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"empty document", ``, "no operation"},
+		{"only fragment", `fragment F on Service { name }`, "no operation"},
+		{"empty selection set", `{ }`, "selection set cannot be empty"},
+		{"unclosed selection set", `{ services { name }`, "syntax error"},
+		{"unexpected token", `{ services } }`, "syntax error"},
+		{"mutation", `mutation { restart }`, "mutations are not supported"},
+		{"duplicate fragment", `{ a } fragment F on T { a } fragment F on T { b }`, "defined more than once"},
+		{"fragment without on", `{ a } fragment F Service { a }`, "expected 'on'"},
+		{"variable in default", `query ($a: Int = $b) { a }`, "variables are not allowed here"},
+		{"unterminated string", `{ service(name: "api) { name } }`, "syntax error"},
+		{"missing argument value", `{ service(name:) { name } }`, "syntax error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.query)
+			if err == nil {
+				t.Fatalf("Expected an error for %q", tt.query)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestParse_ErrorPosition(t *testing.T) {
+	_, err := parse("{\n  services {\n    name\n  }\n  )\n}")
+	if err == nil {
+		t.Fatal("Expected a syntax error")
+	}
+	if !strings.Contains(err.Error(), "at 5:3") {
+		t.Errorf("Expected error at 5:3, got %q", err.Error())
+	}
+}
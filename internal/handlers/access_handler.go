@@ -0,0 +1,159 @@
+// Package handlers - Anonymous read-only access to the dashboard
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+// anonymousReadableRoutes are the API routes visitors who have not signed in
+// may GET while anonymous read-only access is on: enough for a dashboard of
+// services, their status, health, metrics and logs
+var anonymousReadableRoutes = map[string]bool{
+	"/api/services":              true,
+	"/api/services/{id}":         true,
+	"/api/services/{id}/logs":    true,
+	"/api/services/{id}/metrics": true,
+	"/api/services/{id}/health":  true,
+	"/api/system/metrics":        true,
+}
+
+func registerAccessRoutes(h *Handler, r *mux.Router) {
+	r.Use(h.anonymousAccessMiddleware)
+	r.HandleFunc("/api/auth/access", h.getAccessSettingsHandler).Methods("GET")
+	r.HandleFunc("/api/auth/access", h.setAccessSettingsHandler).Methods("PUT")
+}
+
+// anonymousAccessMiddleware holds visitors who have not signed in to the
+// dashboard routes while anonymous read-only access is on. Signing in,
+// first-run setup, health pushed with a service's token and everything
+// outside the API and its WebSocket stay open.
+func (h *Handler) anonymousAccessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if (!strings.HasPrefix(path, "/api/") && path != "/ws") ||
+			strings.HasPrefix(path, "/api/auth/") || strings.HasPrefix(path, "/api/setup/") ||
+			path == services.HealthPushPath || strings.HasPrefix(path, services.HealthPushPath+"/") ||
+			!h.authService.AnonymousReadOnly() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if claims, ok := extractClaimsFromRequest(r, h.authService); ok && claims != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Browsers cannot set headers on a WebSocket, so the UI passes its token in the URL
+		if token := r.URL.Query().Get("token"); path == "/ws" && token != "" {
+			if _, err := h.authService.ValidateToken(token); err == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if !isMutatingMethod(r.Method) {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil && anonymousReadableRoutes[template] {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if isMutatingMethod(r.Method) {
+			http.Error(w, "Sign in to make changes; anonymous access is read-only", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Sign in to see this; anonymous access only covers the service dashboard", http.StatusUnauthorized)
+	})
+}
+
+// isAnonymousViewer reports whether a request comes from a visitor who has
+// not signed in, let through by anonymous read-only access
+func (h *Handler) isAnonymousViewer(r *http.Request) bool {
+	if claims, ok := extractClaimsFromRequest(r, h.authService); ok && claims != nil {
+		return false
+	}
+	return h.authService.AnonymousReadOnly()
+}
+
+// redactForAnonymous drops what a service shows only to signed-in users,
+// such as its environment variables, which may hold secrets
+func redactForAnonymous(service *models.Service) {
+	service.EnvVars = map[string]models.EnvVar{}
+}
+
+// getAccessSettingsHandler returns whether anonymous read-only access is on.
+// It is public, so the UI knows whether to show the dashboard before login.
+func (h *Handler) getAccessSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	settings, err := h.authService.GetAccessSettings()
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get access settings: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(settings)
+}
+
+// setAccessSettingsHandler turns anonymous read-only access and required
+// two-factor authentication on or off
+func (h *Handler) setAccessSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		AnonymousReadOnly *bool `json:"anonymousReadOnly"`
+		RequireTwoFactor  *bool `json:"requireTwoFactor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || (request.AnonymousReadOnly == nil && request.RequireTwoFactor == nil) {
+		http.Error(w, "anonymousReadOnly or requireTwoFactor is required", http.StatusBadRequest)
+		return
+	}
+
+	var settings *models.AccessSettings
+	var err error
+	if request.RequireTwoFactor != nil {
+		settings, err = h.authService.SetRequireTwoFactor(*request.RequireTwoFactor, claims)
+	}
+	if err == nil && request.AnonymousReadOnly != nil {
+		settings, err = h.authService.SetAnonymousReadOnly(*request.AnonymousReadOnly, claims)
+	}
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "only admins"):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to save access settings: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(settings)
+}
+
+// isMutatingMethod reports whether a request may change state
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
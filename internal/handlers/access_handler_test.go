@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+// newAccessTestRouter returns a router behind the anonymous access
+// middleware, with anonymous read-only access set as given, and the token of
+// a signed-in user
+func newAccessTestRouter(t *testing.T, anonymous bool) (*mux.Router, string) {
+	t.Helper()
+	db, err := database.NewDatabaseWithPath(filepath.Join(t.TempDir(), "vertex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	authService := services.NewAuthService(db)
+	admin := &models.JWTClaims{UserID: "admin-1", Username: "admin", Role: "admin"}
+	if _, err := authService.SetAnonymousReadOnly(anonymous, admin); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authService.Register(&models.UserRegistration{Username: "alice", Email: "alice@example.com", Password: "secret123"}); err != nil {
+		t.Fatal(err)
+	}
+	login, err := authService.Login(&models.UserLogin{Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handler{authService: authService}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := mux.NewRouter()
+	r.Use(h.anonymousAccessMiddleware)
+	r.HandleFunc("/api/services", ok).Methods("GET")
+	r.HandleFunc("/api/services/{id}", ok).Methods("GET", "PUT")
+	r.HandleFunc("/api/services/{id}/logs", ok).Methods("GET")
+	r.HandleFunc("/api/services/{id}/health", ok).Methods("GET", "POST")
+	r.HandleFunc("/api/services/{id}/env-vars", ok).Methods("GET")
+	r.HandleFunc("/api/services/{id}/start", ok).Methods("POST")
+	r.HandleFunc("/api/auth/login", ok).Methods("POST")
+	r.HandleFunc("/api/setup/status", ok).Methods("GET")
+	r.HandleFunc(services.HealthPushPath, ok).Methods("POST")
+	r.HandleFunc(services.HealthPushTokenRoute, ok).Methods("POST")
+	r.HandleFunc("/ws", ok).Methods("GET")
+	r.PathPrefix("/").HandlerFunc(ok)
+	return r, login.Token
+}
+
+func TestAnonymousAccessMiddleware_ReadOnlyDashboard(t *testing.T) {
+	router, token := newAccessTestRouter(t, true)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"service list", "GET", "/api/services", "", http.StatusOK},
+		{"service", "GET", "/api/services/svc-1", "", http.StatusOK},
+		{"service logs", "GET", "/api/services/svc-1/logs", "", http.StatusOK},
+		{"service health", "GET", "/api/services/svc-1/health", "", http.StatusOK},
+		{"health check", "POST", "/api/services/svc-1/health", "", http.StatusUnauthorized},
+		{"route outside the dashboard", "GET", "/api/services/svc-1/env-vars", "", http.StatusUnauthorized},
+		{"change", "PUT", "/api/services/svc-1", "", http.StatusUnauthorized},
+		{"start", "POST", "/api/services/svc-1/start", "", http.StatusUnauthorized},
+		{"websocket", "GET", "/ws", "", http.StatusUnauthorized},
+		{"sign in", "POST", "/api/auth/login", "", http.StatusOK},
+		{"setup", "GET", "/api/setup/status", "", http.StatusOK},
+		{"health push", "POST", services.HealthPushPath, "", http.StatusOK},
+		{"health push with token in path", "POST", services.HealthPushPath + "/abc123", "", http.StatusOK},
+		{"web app", "GET", "/index.html", "", http.StatusOK},
+		{"signed-in change", "PUT", "/api/services/svc-1", token, http.StatusOK},
+		{"signed-in start", "POST", "/api/services/svc-1/start", token, http.StatusOK},
+		{"signed-in env vars", "GET", "/api/services/svc-1/env-vars", token, http.StatusOK},
+		{"invalid token", "POST", "/api/services/svc-1/start", "not-a-token", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create request
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.want {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnonymousAccessMiddleware_WebSocketToken(t *testing.T) {
+	router, token := newAccessTestRouter(t, true)
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"?token=" + token, http.StatusOK},
+		{"?token=not-a-token", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", "/ws"+tt.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != tt.want {
+			t.Errorf("Query %q: got %v want %v", tt.query, status, tt.want)
+		}
+	}
+}
+
+func TestAnonymousAccessMiddleware_Off(t *testing.T) {
+	router, _ := newAccessTestRouter(t, false)
+
+	// Create requests the handlers authorize themselves while anonymous access is off
+	for _, path := range []string{"/api/services/svc-1/env-vars", "/ws"} {
+		req, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("Path %s: got %v want %v", path, status, http.StatusOK)
+		}
+	}
+}
+
+func TestAnonymousReadableRoutes_AreAPIReadRoutes(t *testing.T) {
+	// Collect the GET routes of services and system metrics
+	h := &Handler{}
+	router := mux.NewRouter()
+	registerServiceRoutes(h, router)
+	registerUtilityRoutes(h, router)
+	readRoutes := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		if methods, err := route.GetMethods(); err == nil {
+			for _, method := range methods {
+				if method == http.MethodGet {
+					readRoutes[template] = true
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for route := range anonymousReadableRoutes {
+		if !readRoutes[route] {
+			t.Errorf("Anonymous readable route %s is not a GET route of the API", route)
+		}
+	}
+}
+
+func TestIsAnonymousViewer(t *testing.T) {
+	db, err := database.NewDatabaseWithPath(filepath.Join(t.TempDir(), "vertex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	h := &Handler{authService: services.NewAuthService(db)}
+
+	req := httptest.NewRequest("GET", "/api/services", nil)
+	if h.isAnonymousViewer(req) {
+		t.Error("Expected no anonymous viewers while anonymous access is off")
+	}
+
+	admin := &models.JWTClaims{UserID: "admin-1", Username: "admin", Role: "admin"}
+	if _, err := h.authService.SetAnonymousReadOnly(true, admin); err != nil {
+		t.Fatal(err)
+	}
+	if !h.isAnonymousViewer(req) {
+		t.Error("Expected a request without a token to be an anonymous viewer")
+	}
+}
+
+func TestRedactForAnonymous(t *testing.T) {
+	service := &models.Service{
+		ID:      "test-service-1",
+		EnvVars: map[string]models.EnvVar{"DB_PASSWORD": {Name: "DB_PASSWORD", Value: "hunter2"}},
+	}
+
+	redactForAnonymous(service)
+
+	if len(service.EnvVars) != 0 {
+		t.Errorf("Expected environment variables to be removed, got %v", service.EnvVars)
+	}
+}
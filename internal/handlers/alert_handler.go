@@ -0,0 +1,85 @@
+// Package handlers - Alert routing settings
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerAlertRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/alerts/settings", h.getAlertSettingsHandler).Methods("GET")
+	r.HandleFunc("/api/alerts/settings", h.setAlertSettingsHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/alerts/test", h.sendTestAlertHandler).Methods("POST")
+}
+
+// getAlertSettingsHandler returns where alerts are routed, without the Slack token
+func (h *Handler) getAlertSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	settings, err := h.serviceManager.GetAlertSettings()
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get alert settings: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(settings)
+}
+
+// setAlertSettingsHandler replaces the global, team and Slack alert destinations
+func (h *Handler) setAlertSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var settings models.AlertSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.SetAlertSettings(settings); err != nil {
+		requestLogf(r, "[ERROR] Failed to save alert settings: %v", err)
+		if strings.Contains(err.Error(), "failed to") {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.serviceManager.GetAlertSettings()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
+
+// sendTestAlertHandler sends a test alert along a service's route and
+// reports where it went
+func (h *Handler) sendTestAlertHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	delivery, err := h.serviceManager.SendTestAlert(serviceUUID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "no alert destination"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			requestLogf(r, "[ERROR] Test alert for service %s failed: %v", serviceUUID, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(delivery)
+}
@@ -0,0 +1,187 @@
+// Package handlers - Database backups, their schedule and restoring them
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func registerBackupRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/system/backups", h.listBackupsHandler).Methods("GET")
+	r.HandleFunc("/api/system/backups", h.createBackupHandler).Methods("POST")
+	r.HandleFunc("/api/system/backups/settings", h.getBackupSettingsHandler).Methods("GET")
+	r.HandleFunc("/api/system/backups/settings", h.setBackupSettingsHandler).Methods("PUT")
+	r.HandleFunc("/api/system/backups/restore", h.restoreBackupHandler).Methods("POST")
+	r.HandleFunc("/api/system/backups/restore", h.cancelRestoreHandler).Methods("DELETE")
+	r.HandleFunc("/api/system/backups/{name}", h.deleteBackupHandler).Methods("DELETE")
+}
+
+// writeBackupError maps a backup error to its status code
+func writeBackupError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "only admins"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "already exists"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// listBackupsHandler returns the backups of the database, newest first, and
+// the restore waiting for the next start
+func (h *Handler) listBackupsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	backups, err := h.serviceManager.ListBackups()
+	if err != nil {
+		writeBackupError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"backups":        backups,
+		"pendingRestore": h.serviceManager.GetDatabase().PendingRestore(),
+	})
+}
+
+// createBackupHandler backs up the database now
+func (h *Handler) createBackupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	backup, err := h.serviceManager.CreateBackup()
+	if err != nil {
+		writeBackupError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(backup)
+}
+
+// deleteBackupHandler removes a backup
+func (h *Handler) deleteBackupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.serviceManager.DeleteBackup(mux.Vars(r)["name"], claims); err != nil {
+		writeBackupError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getBackupSettingsHandler returns the automatic backup settings
+func (h *Handler) getBackupSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.serviceManager.GetBackupSettings()
+	if err != nil {
+		writeBackupError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(settings)
+}
+
+// setBackupSettingsHandler turns the daily backups on or off and sets how
+// many are kept
+func (h *Handler) setBackupSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var settings models.BackupSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.serviceManager.SetBackupSettings(settings, claims)
+	if err != nil {
+		writeBackupError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
+
+// restoreBackupHandler stages a backup, picked by name or point in time, to
+// replace the database when Vertex restarts
+func (h *Handler) restoreBackupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var request services.BackupRestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.serviceManager.RestoreBackup(request, claims)
+	if err != nil {
+		writeBackupError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// cancelRestoreHandler drops the backup waiting to replace the database
+func (h *Handler) cancelRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.serviceManager.CancelRestore(claims); err != nil {
+		writeBackupError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
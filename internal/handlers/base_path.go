@@ -0,0 +1,102 @@
+// Package handlers - Serving Vertex under a path prefix behind a reverse proxy
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// One or more URL path segments, e.g. /vertex or /tools/vertex
+var basePathPattern = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)+$`)
+
+// basePathKey is the request context key of the base path
+type basePathKey struct{}
+
+// basePathFromContext returns the base path Vertex is served under, or "" at
+// the root, for building URLs the browser or a proxied service sees
+func basePathFromContext(ctx context.Context) string {
+	basePath, _ := ctx.Value(basePathKey{}).(string)
+	return basePath
+}
+
+// NormalizeBasePath checks a --base-path value and returns it with a leading
+// slash and without a trailing one; "" and "/" serve Vertex at the root
+func NormalizeBasePath(basePath string) (string, error) {
+	basePath = strings.TrimRight(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	if !basePathPattern.MatchString(basePath) {
+		return "", fmt.Errorf("invalid base path '%s'; use a path such as /vertex", basePath)
+	}
+	for _, segment := range strings.Split(basePath[1:], "/") {
+		if segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid base path '%s'; use a path such as /vertex", basePath)
+		}
+	}
+	return basePath, nil
+}
+
+// BasePathMiddleware serves Vertex under basePath. Proxies that forward the
+// prefix and proxies that strip it both work: the prefix is removed when a
+// request has it, and requests without it are served as they are. Either way
+// the request context carries basePath for basePathFromContext.
+func BasePathMiddleware(basePath string, next http.Handler) http.Handler {
+	if basePath == "" {
+		return next
+	}
+	stripped := http.StripPrefix(basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), basePathKey{}, basePath))
+		switch {
+		case r.URL.Path == basePath:
+			// Relative asset URLs only resolve under the trailing slash
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, basePath+"/"):
+			stripped.ServeHTTP(w, r)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// UIHandler serves the embedded web interface. index.html tells the UI the
+// base path, so its API calls and WebSocket go through the proxy too.
+func UIHandler(ui fs.FS, basePath string) http.Handler {
+	files := http.FileServer(http.FS(ui))
+	index, err := fs.ReadFile(ui, "index.html")
+	if err != nil || basePath == "" {
+		return files
+	}
+
+	quoted, _ := json.Marshal(basePath)
+	script := fmt.Sprintf("<script>window.__VERTEX_BASE_PATH__ = %s;</script>\n", quoted)
+	if i := bytes.Index(index, []byte("</head>")); i >= 0 {
+		index = append(index[:i:i], append([]byte(script), index[i:]...)...)
+	} else {
+		index = append([]byte(script), index...)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			files.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(index)
+	})
+}
@@ -0,0 +1,247 @@
+// Package handlers - Opt-in chaos testing of local services
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func registerChaosRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/chaos", h.getChaosStatusHandler).Methods("GET")
+	r.HandleFunc("/api/chaos/faults", h.getChaosFaultsHandler).Methods("GET")
+	r.HandleFunc("/api/chaos/faults/{id}", h.endChaosFaultHandler).Methods("DELETE")
+	r.HandleFunc("/api/chaos/kill", h.chaosKillHandler).Methods("POST")
+	r.HandleFunc("/api/chaos/latency", h.chaosLatencyHandler).Methods("POST")
+	r.HandleFunc("/api/chaos/block", h.chaosBlockHandler).Methods("POST")
+	r.HandleFunc("/api/chaos/schedule", h.setChaosScheduleHandler).Methods("PUT")
+	r.HandleFunc("/api/chaos/schedule", h.stopChaosScheduleHandler).Methods("DELETE")
+}
+
+// writeChaosError maps chaos failures to a status code
+func writeChaosError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrChaosDisabled):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "not running"), strings.Contains(err.Error(), "is paused"),
+		strings.Contains(err.Error(), "no running service"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// activeChaosProfile returns the user's active profile, answering 401 when
+// the request is not authenticated
+func (h *Handler) activeChaosProfile(w http.ResponseWriter, r *http.Request) (*models.ServiceProfile, bool) {
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return nil, false
+	}
+	profile, err := h.profileService.GetActiveProfile(claims.UserID)
+	if err != nil {
+		return nil, true
+	}
+	return profile, true
+}
+
+// decodeChaosRequest reads a fault request, allowing an empty body
+func decodeChaosRequest(w http.ResponseWriter, r *http.Request) (models.ChaosFaultRequest, bool) {
+	var req models.ChaosFaultRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// getChaosStatusHandler reports whether chaos testing is enabled, the kill
+// schedule and the faults in effect
+func (h *Handler) getChaosStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	json.NewEncoder(w).Encode(h.serviceManager.GetChaosStatus())
+}
+
+// getChaosFaultsHandler returns the audit trail of injected faults
+func (h *Handler) getChaosFaultsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	faults, err := h.serviceManager.GetChaosFaults(limit)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get chaos faults: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(faults)
+}
+
+// endChaosFaultHandler undoes an active latency or block fault early
+func (h *Handler) endChaosFaultHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if _, ok := h.activeChaosProfile(w, r); !ok {
+		return
+	}
+	faultID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || faultID <= 0 {
+		http.Error(w, "Invalid fault ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.EndChaosFault(faultID); err != nil {
+		writeChaosError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ended"})
+}
+
+// chaosKillHandler kills the given service, or a random running service of
+// the active profile without one
+func (h *Handler) chaosKillHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	profile, ok := h.activeChaosProfile(w, r)
+	if !ok {
+		return
+	}
+	req, ok := decodeChaosRequest(w, r)
+	if !ok {
+		return
+	}
+	profileID := ""
+	if profile != nil {
+		profileID = profile.ID
+	}
+
+	var fault *models.ChaosFault
+	var err error
+	if req.ServiceID != "" {
+		fault, err = h.serviceManager.KillChaosService(req.ServiceID, profileID, services.ChaosTriggerManual)
+	} else if profile == nil {
+		err = errors.New("no active profile to pick a service from; pass serviceId")
+	} else {
+		fault, err = h.serviceManager.KillRandomChaosService(profileID, profile.Services, services.ChaosTriggerManual)
+	}
+	if err != nil {
+		writeChaosError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(fault)
+}
+
+// chaosLatencyHandler starts a latency proxy in front of a service
+func (h *Handler) chaosLatencyHandler(w http.ResponseWriter, r *http.Request) {
+	h.injectChaosFault(w, r, h.serviceManager.InjectChaosLatency)
+}
+
+// chaosBlockHandler freezes a service so its port stops answering
+func (h *Handler) chaosBlockHandler(w http.ResponseWriter, r *http.Request) {
+	h.injectChaosFault(w, r, h.serviceManager.BlockChaosService)
+}
+
+// injectChaosFault runs a fault that lasts for a duration against the
+// requested service
+func (h *Handler) injectChaosFault(w http.ResponseWriter, r *http.Request, inject func(models.ChaosFaultRequest, string) (*models.ChaosFault, error)) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	profile, ok := h.activeChaosProfile(w, r)
+	if !ok {
+		return
+	}
+	req, ok := decodeChaosRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.ServiceID == "" {
+		http.Error(w, "serviceId is required", http.StatusBadRequest)
+		return
+	}
+	profileID := ""
+	if profile != nil {
+		profileID = profile.ID
+	}
+
+	fault, err := inject(req, profileID)
+	if err != nil {
+		writeChaosError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(fault)
+}
+
+// setChaosScheduleHandler kills a random service of a profile, by default the
+// active one, at an interval
+func (h *Handler) setChaosScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	profile, ok := h.activeChaosProfile(w, r)
+	if !ok {
+		return
+	}
+	var request struct {
+		ProfileID       string `json:"profileId"`
+		IntervalSeconds int    `json:"intervalSeconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if request.ProfileID == "" && profile != nil {
+		request.ProfileID = profile.ID
+	}
+
+	schedule, err := h.serviceManager.SetChaosSchedule(request.ProfileID, request.IntervalSeconds)
+	if err != nil {
+		writeChaosError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// stopChaosScheduleHandler stops the kill schedule
+func (h *Handler) stopChaosScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if _, ok := h.activeChaosProfile(w, r); !ok {
+		return
+	}
+	if err := h.serviceManager.StopChaosSchedule(); err != nil {
+		writeChaosError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
+}
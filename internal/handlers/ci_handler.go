@@ -3,15 +3,23 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
 )
 
 func registerCIRoutes(h *Handler, r *mux.Router) {
 	r.HandleFunc("/api/services/{id}/gitlab-ci", h.getGitLabCIHandler).Methods("GET")
 	r.HandleFunc("/api/services/gitlab-ci/all", h.getAllGitLabCIHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/test", h.runServiceTestsHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/test", h.cancelServiceTestsHandler).Methods("DELETE")
+	r.HandleFunc("/api/services/{id}/test/runs", h.getTestRunsHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/test/runs/{runId}", h.getTestRunHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/builds", h.getServiceBuildsHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/builds/{buildId}", h.getServiceBuildHandler).Methods("GET")
 }
 
 // getGitLabCIHandler returns GitLab CI configuration for a specific service
@@ -29,13 +37,13 @@ func (h *Handler) getGitLabCIHandler(w http.ResponseWriter, r *http.Request) {
 
 	config, err := h.serviceManager.ParseGitLabCI(serviceUUID)
 	if err != nil {
-		log.Printf("Failed to parse GitLab CI for service UUID %s: %v", serviceUUID, err)
+		requestLogf(r, "Failed to parse GitLab CI for service UUID %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to parse GitLab CI: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(config); err != nil {
-		log.Printf("Failed to encode GitLab CI config: %v", err)
+		requestLogf(r, "Failed to encode GitLab CI config: %v", err)
 		http.Error(w, "Failed to encode GitLab CI config", http.StatusInternalServerError)
 		return
 	}
@@ -49,8 +57,151 @@ func (h *Handler) getAllGitLabCIHandler(w http.ResponseWriter, r *http.Request)
 	configs := h.serviceManager.GetAllGitLabCIConfigs()
 
 	if err := json.NewEncoder(w).Encode(configs); err != nil {
-		log.Printf("Failed to encode GitLab CI configs: %v", err)
+		requestLogf(r, "Failed to encode GitLab CI configs: %v", err)
 		http.Error(w, "Failed to encode GitLab CI configs", http.StatusInternalServerError)
 		return
 	}
 }
+
+// runServiceTestsHandler starts the test suite of a service; progress is streamed over the websocket
+func (h *Handler) runServiceTestsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var req models.TestRunRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	run, err := h.serviceManager.RunServiceTests(serviceUUID, h.requestProjectsDir(r, serviceUUID), req)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to run tests of service %s: %v", serviceUUID, err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "already running"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// cancelServiceTestsHandler stops the running test suite of a service
+func (h *Handler) cancelServiceTestsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if err := h.serviceManager.CancelServiceTests(serviceUUID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
+}
+
+// getTestRunsHandler returns the test run history of a service
+func (h *Handler) getTestRunsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	runs, err := h.serviceManager.GetTestRuns(serviceUUID, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get test runs of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(runs)
+}
+
+// getTestRunHandler returns a test run with its per-test results
+func (h *Handler) getTestRunHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	vars := mux.Vars(r)
+	runID, err := strconv.ParseInt(vars["runId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.serviceManager.GetTestRun(vars["id"], runID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get test run %d: %v", runID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(run)
+}
+
+// getServiceBuildsHandler returns the build history of a service
+func (h *Handler) getServiceBuildsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	builds, err := h.serviceManager.GetServiceBuilds(serviceUUID, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get builds of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(builds)
+}
+
+// getServiceBuildHandler returns a build with its output
+func (h *Handler) getServiceBuildHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	vars := mux.Vars(r)
+	buildID, err := strconv.ParseInt(vars["buildId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid build ID", http.StatusBadRequest)
+		return
+	}
+
+	build, err := h.serviceManager.GetServiceBuild(vars["id"], buildID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get build %d: %v", buildID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(build)
+}
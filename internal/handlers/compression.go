@@ -0,0 +1,173 @@
+// Package handlers - Response compression middleware
+package handlers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Content types worth compressing; images, archives and the like are already compressed
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"image/svg+xml",
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		writer, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return writer
+	},
+}
+
+// CompressionMiddleware compresses API responses and UI assets with brotli or
+// gzip depending on the client's Accept-Encoding. Websocket upgrades and
+// responses that are already encoded are passed through untouched.
+func CompressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		// Range responses must stay byte-addressable, so they are sent as-is
+		if encoding == "" || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+			r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressedResponseWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding picks brotli over gzip when the client accepts both
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.ReplaceAll(strings.TrimSpace(params), " ", "") == "q=0" {
+			continue
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	switch {
+	case accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	default:
+		return ""
+	}
+}
+
+// compressedResponseWriter decides on the first write whether to compress,
+// based on the response headers set by the handler
+type compressedResponseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	writer      io.WriteCloser
+	decided     bool
+	wroteHeader bool
+}
+
+func (cw *compressedResponseWriter) decide() {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" || !isCompressible(header.Get("Content-Type")) {
+		return
+	}
+
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+
+	if cw.encoding == "br" {
+		cw.writer = brotli.NewWriterLevel(cw.ResponseWriter, brotli.DefaultCompression)
+		return
+	}
+	gz := gzipWriterPool.Get().(*gzip.Writer)
+	gz.Reset(cw.ResponseWriter)
+	cw.writer = gz
+}
+
+func (cw *compressedResponseWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	// Bodiless responses must not advertise an encoding
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < 200 {
+		cw.decided = true
+	}
+	cw.decide()
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressedResponseWriter) Write(data []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(data))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.writer == nil {
+		return cw.ResponseWriter.Write(data)
+	}
+	return cw.writer.Write(data)
+}
+
+// Flush pushes buffered compressed data to the client, e.g. for streamed logs
+func (cw *compressedResponseWriter) Flush() {
+	if flusher, ok := cw.writer.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *compressedResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+// Close finishes the compressed stream and returns pooled writers
+func (cw *compressedResponseWriter) Close() {
+	if cw.writer == nil {
+		return
+	}
+	cw.writer.Close()
+	if gz, ok := cw.writer.(*gzip.Writer); ok {
+		gzipWriterPool.Put(gz)
+	}
+	cw.writer = nil
+}
+
+func isCompressible(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,12 +3,12 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
 )
 
 func registerConfigRoutes(h *Handler, r *mux.Router) {
@@ -18,6 +18,7 @@ func registerConfigRoutes(h *Handler, r *mux.Router) {
 	r.HandleFunc("/api/configurations/{id}/apply", h.applyConfigurationHandler).Methods("POST")
 	r.HandleFunc("/api/config/global", h.getGlobalConfigHandler).Methods("GET")
 	r.HandleFunc("/api/config/global", h.updateGlobalConfigHandler).Methods("PUT")
+	r.HandleFunc("/api/config/apply", h.applyDeclarativeConfigHandler).Methods("POST")
 }
 
 func (h *Handler) getConfigurationsHandler(w http.ResponseWriter, r *http.Request) {
@@ -44,7 +45,7 @@ func (h *Handler) saveConfigurationHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := h.serviceManager.SaveConfiguration(&config); err != nil {
-		log.Printf("Failed to save configuration: %v", err)
+		requestLogf(r, "Failed to save configuration: %v", err)
 		http.Error(w, "Failed to save configuration", http.StatusInternalServerError)
 		return
 	}
@@ -77,7 +78,7 @@ func (h *Handler) updateConfigurationHandler(w http.ResponseWriter, r *http.Requ
 	config.ID = configID
 
 	if err := h.serviceManager.UpdateConfiguration(&config); err != nil {
-		log.Printf("Failed to update configuration: %v", err)
+		requestLogf(r, "Failed to update configuration: %v", err)
 		http.Error(w, "Failed to update configuration", http.StatusInternalServerError)
 		return
 	}
@@ -101,7 +102,7 @@ func (h *Handler) applyConfigurationHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	if err := h.serviceManager.ApplyConfiguration(configID); err != nil {
-		log.Printf("Failed to apply configuration: %v", err)
+		requestLogf(r, "Failed to apply configuration: %v", err)
 		http.Error(w, "Failed to apply configuration", http.StatusInternalServerError)
 		return
 	}
@@ -139,3 +140,54 @@ func (h *Handler) updateGlobalConfigHandler(w http.ResponseWriter, r *http.Reque
 
 	json.NewEncoder(w).Encode(config)
 }
+
+// applyDeclarativeConfigHandler reconciles services, profiles and environment
+// variables with a vertex.yaml. The YAML can be posted as content; otherwise
+// the file in the projects root is used. Profiles are applied for the caller.
+func (h *Handler) applyDeclarativeConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		services.ConfigApplyOptions
+		Content string `json:"content"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	applier := services.NewConfigApplier(h.serviceManager, h.profileService)
+
+	var config *models.DeclarativeConfig
+	var err error
+	source := "request"
+	if req.Content != "" {
+		config, err = services.ParseDeclarativeConfig([]byte(req.Content))
+	} else {
+		source = applier.DefaultConfigPath()
+		config, err = services.LoadDeclarativeConfig(source)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := applier.Apply(config, claims.UserID, req.ConfigApplyOptions)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to apply declarative configuration from %s: %v", source, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result.Source = source
+
+	json.NewEncoder(w).Encode(result)
+}
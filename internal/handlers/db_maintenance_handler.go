@@ -0,0 +1,126 @@
+// Package handlers - VACUUM, ANALYZE and integrity checks of the database
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerMaintenanceRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/system/db/maintenance", h.getMaintenanceStatusHandler).Methods("GET")
+	r.HandleFunc("/api/system/db/maintenance", h.startMaintenanceHandler).Methods("POST")
+	r.HandleFunc("/api/system/db/maintenance/settings", h.getMaintenanceSettingsHandler).Methods("GET")
+	r.HandleFunc("/api/system/db/maintenance/settings", h.setMaintenanceSettingsHandler).Methods("PUT")
+}
+
+// writeMaintenanceError maps a maintenance error to its status code
+func writeMaintenanceError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "only admins"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case strings.Contains(err.Error(), "already running"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// getMaintenanceStatusHandler returns the running maintenance with its
+// progress, the recent runs and when the schedule runs next
+func (h *Handler) getMaintenanceStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.serviceManager.GetMaintenanceStatus()
+	if err != nil {
+		writeMaintenanceError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// startMaintenanceHandler starts VACUUM, ANALYZE and integrity checks in the
+// order given; the run continues in the background
+func (h *Handler) startMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var request struct {
+		Tasks []string `json:"tasks"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.serviceManager.StartMaintenance(request.Tasks, claims)
+	if err != nil {
+		writeMaintenanceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// getMaintenanceSettingsHandler returns the maintenance schedule
+func (h *Handler) getMaintenanceSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.serviceManager.GetMaintenanceSettings()
+	if err != nil {
+		writeMaintenanceError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(settings)
+}
+
+// setMaintenanceSettingsHandler turns the scheduled maintenance on or off and
+// sets its interval and tasks
+func (h *Handler) setMaintenanceSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var settings models.MaintenanceSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.serviceManager.SetMaintenanceSettings(settings, claims)
+	if err != nil {
+		writeMaintenanceError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
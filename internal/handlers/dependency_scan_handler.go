@@ -0,0 +1,145 @@
+// Package handlers - Dependency license and vulnerability scans
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerDependencyScanRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/scan", h.scanServiceDependenciesHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/scan", h.cancelDependencyScanHandler).Methods("DELETE")
+	r.HandleFunc("/api/services/{id}/scans", h.getDependencyScansHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/scans/{scanId}", h.getDependencyScanHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/scan-summary", h.getProfileScanSummaryHandler).Methods("GET")
+}
+
+// scanServiceDependenciesHandler starts a dependency scan of a service
+func (h *Handler) scanServiceDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var req models.DependencyScanRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	scan, err := h.serviceManager.ScanServiceDependencies(serviceUUID, h.requestProjectsDir(r, serviceUUID), req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"), strings.Contains(err.Error(), "does not exist"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "already running"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to scan dependencies of service %s: %v", serviceUUID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(scan)
+}
+
+// cancelDependencyScanHandler stops the running dependency scan of a service
+func (h *Handler) cancelDependencyScanHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if err := h.serviceManager.CancelDependencyScan(serviceUUID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
+}
+
+// getDependencyScansHandler returns the scan history of a service with the
+// severity summary of each scan
+func (h *Handler) getDependencyScansHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	scans, err := h.serviceManager.GetDependencyScans(serviceUUID, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get dependency scans of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(scans)
+}
+
+// getDependencyScanHandler returns a dependency scan with its findings
+func (h *Handler) getDependencyScanHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	vars := mux.Vars(r)
+	scanID, err := strconv.ParseInt(vars["scanId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid scan ID", http.StatusBadRequest)
+		return
+	}
+
+	scan, err := h.serviceManager.GetDependencyScan(vars["id"], scanID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get dependency scan %d: %v", scanID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(scan)
+}
+
+// getProfileScanSummaryHandler adds up the latest scans of a profile's services
+func (h *Handler) getProfileScanSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := h.profileService.GetServiceProfile(mux.Vars(r)["id"], claims.UserID)
+	if err != nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	summary, err := h.serviceManager.GetProfileScanSummary(profile.ID, profile.Services)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get scan summary for profile %s: %v", profile.ID, err)
+		http.Error(w, "Failed to get profile scan summary", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(summary)
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 
@@ -61,7 +60,7 @@ func (dh *dockerComposeHandler) generateDockerComposeHandler(w http.ResponseWrit
 	// Get profile
 	profile, err := dh.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to get service profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -83,19 +82,19 @@ func (dh *dockerComposeHandler) generateDockerComposeHandler(w http.ResponseWrit
 		request.Environment = "development"
 	}
 
-	log.Printf("[INFO] Generating Docker Compose for profile '%s' (environment: %s)", profile.Name, request.Environment)
+	requestLogf(r, "[INFO] Generating Docker Compose for profile '%s' (environment: %s)", profile.Name, request.Environment)
 
 	// Generate Docker Compose
 	compose, err := dh.dockerComposeService.GenerateFromProfile(profile, request)
 	if err != nil {
-		log.Printf("[ERROR] Failed to generate Docker Compose: %v", err)
+		requestLogf(r, "[ERROR] Failed to generate Docker Compose: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to generate Docker Compose: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Validate the generated compose file
 	if err := compose.Validate(); err != nil {
-		log.Printf("[ERROR] Generated Docker Compose is invalid: %v", err)
+		requestLogf(r, "[ERROR] Generated Docker Compose is invalid: %v", err)
 		http.Error(w, fmt.Sprintf("Generated Docker Compose is invalid: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -113,7 +112,7 @@ func (dh *dockerComposeHandler) generateDockerComposeHandler(w http.ResponseWrit
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -137,7 +136,7 @@ func (dh *dockerComposeHandler) downloadDockerComposeHandler(w http.ResponseWrit
 	// Get profile
 	profile, err := dh.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to get service profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -160,7 +159,7 @@ func (dh *dockerComposeHandler) downloadDockerComposeHandler(w http.ResponseWrit
 	// Generate Docker Compose
 	compose, err := dh.dockerComposeService.GenerateFromProfile(profile, request)
 	if err != nil {
-		log.Printf("[ERROR] Failed to generate Docker Compose: %v", err)
+		requestLogf(r, "[ERROR] Failed to generate Docker Compose: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to generate Docker Compose: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -173,11 +172,11 @@ func (dh *dockerComposeHandler) downloadDockerComposeHandler(w http.ResponseWrit
 	// Write YAML content
 	yamlContent := compose.ToYAML()
 	if _, err := w.Write([]byte(yamlContent)); err != nil {
-		log.Printf("[ERROR] Failed to write YAML content: %v", err)
+		requestLogf(r, "[ERROR] Failed to write YAML content: %v", err)
 		return
 	}
 
-	log.Printf("[INFO] Docker Compose file downloaded for profile '%s'", profile.Name)
+	requestLogf(r, "[INFO] Docker Compose file downloaded for profile '%s'", profile.Name)
 }
 
 // previewDockerComposeHandler returns a preview of the Docker Compose without generating the full file
@@ -200,7 +199,7 @@ func (dh *dockerComposeHandler) previewDockerComposeHandler(w http.ResponseWrite
 	// Get profile
 	profile, err := dh.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to get service profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -223,7 +222,7 @@ func (dh *dockerComposeHandler) previewDockerComposeHandler(w http.ResponseWrite
 	// Generate Docker Compose
 	compose, err := dh.dockerComposeService.GenerateFromProfile(profile, request)
 	if err != nil {
-		log.Printf("[ERROR] Failed to generate Docker Compose preview: %v", err)
+		requestLogf(r, "[ERROR] Failed to generate Docker Compose preview: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to generate Docker Compose preview: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -257,7 +256,7 @@ func (dh *dockerComposeHandler) previewDockerComposeHandler(w http.ResponseWrite
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode preview response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode preview response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -281,7 +280,7 @@ func (dh *dockerComposeHandler) generateOverrideHandler(w http.ResponseWriter, r
 	// Get profile
 	profile, err := dh.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to get service profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -293,7 +292,7 @@ func (dh *dockerComposeHandler) generateOverrideHandler(w http.ResponseWriter, r
 	// Generate override file
 	override, err := dh.dockerComposeService.GenerateOverrideFile(profile)
 	if err != nil {
-		log.Printf("[ERROR] Failed to generate Docker Compose override: %v", err)
+		requestLogf(r, "[ERROR] Failed to generate Docker Compose override: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to generate override file: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -306,11 +305,11 @@ func (dh *dockerComposeHandler) generateOverrideHandler(w http.ResponseWriter, r
 	// Write YAML content
 	yamlContent := override.ToYAML()
 	if _, err := w.Write([]byte(yamlContent)); err != nil {
-		log.Printf("[ERROR] Failed to write override YAML content: %v", err)
+		requestLogf(r, "[ERROR] Failed to write override YAML content: %v", err)
 		return
 	}
 
-	log.Printf("[INFO] Docker Compose override file generated for profile '%s'", profile.Name)
+	requestLogf(r, "[INFO] Docker Compose override file generated for profile '%s'", profile.Name)
 }
 
 // getDockerConfigHandler retrieves Docker configuration for a profile
@@ -333,7 +332,7 @@ func (dh *dockerComposeHandler) getDockerConfigHandler(w http.ResponseWriter, r
 	// Verify profile access
 	_, err := dh.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to verify profile access: %v", err)
+		requestLogf(r, "[ERROR] Failed to verify profile access: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -345,13 +344,13 @@ func (dh *dockerComposeHandler) getDockerConfigHandler(w http.ResponseWriter, r
 	// Get Docker config - we'll need to add a method to access the database through the service
 	config, err := dh.dockerComposeService.GetDockerConfig(profileID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get Docker config: %v", err)
+		requestLogf(r, "[ERROR] Failed to get Docker config: %v", err)
 		http.Error(w, "Failed to get Docker configuration", http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(config); err != nil {
-		log.Printf("[ERROR] Failed to encode Docker config response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode Docker config response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -377,7 +376,7 @@ func (dh *dockerComposeHandler) updateDockerConfigHandler(w http.ResponseWriter,
 	// Verify profile access
 	_, err := dh.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to verify profile access: %v", err)
+		requestLogf(r, "[ERROR] Failed to verify profile access: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -388,7 +387,7 @@ func (dh *dockerComposeHandler) updateDockerConfigHandler(w http.ResponseWriter,
 
 	var config models.DockerConfig
 	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
-		log.Printf("[ERROR] Invalid request body: %v", err)
+		requestLogf(r, "[ERROR] Invalid request body: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -398,7 +397,7 @@ func (dh *dockerComposeHandler) updateDockerConfigHandler(w http.ResponseWriter,
 
 	// Save Docker config
 	if err := dh.dockerComposeService.SaveDockerConfig(&config); err != nil {
-		log.Printf("[ERROR] Failed to save Docker config: %v", err)
+		requestLogf(r, "[ERROR] Failed to save Docker config: %v", err)
 		http.Error(w, "Failed to save Docker configuration", http.StatusInternalServerError)
 		return
 	}
@@ -407,7 +406,7 @@ func (dh *dockerComposeHandler) updateDockerConfigHandler(w http.ResponseWriter,
 		"message": "Docker configuration updated successfully",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -433,7 +432,7 @@ func (dh *dockerComposeHandler) deleteDockerConfigHandler(w http.ResponseWriter,
 	// Verify profile access
 	_, err := dh.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to verify profile access: %v", err)
+		requestLogf(r, "[ERROR] Failed to verify profile access: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -444,7 +443,7 @@ func (dh *dockerComposeHandler) deleteDockerConfigHandler(w http.ResponseWriter,
 
 	// Delete Docker config
 	if err := dh.dockerComposeService.DeleteDockerConfig(profileID); err != nil {
-		log.Printf("[ERROR] Failed to delete Docker config: %v", err)
+		requestLogf(r, "[ERROR] Failed to delete Docker config: %v", err)
 		http.Error(w, "Failed to delete Docker configuration", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,45 @@
+// Package handlers - Directory browser for picking projects directories
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func registerFSRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/fs/list", h.listDirectoriesHandler).Methods("GET")
+}
+
+// listDirectoriesHandler lists the subdirectories of ?path= inside the
+// browse roots, or the roots themselves without a path
+func (h *Handler) listDirectoriesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	listing, err := h.serviceManager.BrowseDirectories(r.URL.Query().Get("path"))
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "outside the allowed roots"), strings.Contains(err.Error(), "permission denied"):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(listing)
+}
@@ -0,0 +1,592 @@
+// Package handlers - GraphQL endpoint for the dashboard
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/zechtz/vertex/internal/graphql"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+const (
+	graphQLWSProtocol     = "graphql-transport-ws"
+	graphQLInitTimeout    = 10 * time.Second
+	defaultGraphQLLogTail = 100
+)
+
+func registerGraphQLRoutes(h *Handler, r *mux.Router) {
+	h.graphqlSchema = h.newGraphQLSchema()
+	r.HandleFunc("/api/graphql", h.graphqlHandler).Methods("GET", "POST")
+	r.HandleFunc("/api/graphql/schema", h.graphqlSchemaHandler).Methods("GET")
+}
+
+// graphQLRequestContext carries the caller of one HTTP request or websocket
+// connection to the resolvers
+type graphQLRequestContext struct {
+	claims *models.JWTClaims
+
+	mutex         sync.Mutex
+	activeProfile *models.ServiceProfile
+	profileLoaded bool
+}
+
+type graphQLContextKey struct{}
+
+func graphQLCaller(ctx context.Context) *graphQLRequestContext {
+	caller, _ := ctx.Value(graphQLContextKey{}).(*graphQLRequestContext)
+	if caller == nil {
+		return &graphQLRequestContext{}
+	}
+	return caller
+}
+
+// requireClaims returns the caller's claims, or an error for anonymous callers
+func (c *graphQLRequestContext) requireClaims() (*models.JWTClaims, error) {
+	if c.claims == nil {
+		return nil, fmt.Errorf("authentication required")
+	}
+	return c.claims, nil
+}
+
+// graphqlHandler executes queries sent as POST bodies or GET parameters and
+// upgrades websocket requests for subscriptions
+func (h *Handler) graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	if websocket.IsWebSocketUpgrade(r) {
+		h.graphqlWebSocketHandler(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var req graphql.Request
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				http.Error(w, "Invalid variables", http.StatusBadRequest)
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	caller := &graphQLRequestContext{}
+	if claims, ok := extractClaimsFromRequest(r, h.authService); ok {
+		caller.claims = claims
+	}
+	ctx := context.WithValue(r.Context(), graphQLContextKey{}, caller)
+
+	json.NewEncoder(w).Encode(h.graphqlSchema.Execute(ctx, req))
+}
+
+// graphqlSchemaHandler returns the schema in SDL, in place of introspection
+func (h *Handler) graphqlSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write([]byte(h.graphqlSchema.SDL()))
+}
+
+// graphQLWSMessage is a graphql-transport-ws protocol message
+type graphQLWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlWebSocketHandler serves subscriptions (and queries) over the
+// graphql-transport-ws protocol. The token is taken from the Authorization
+// header or the "authorization" field of the connection_init payload.
+func (h *Handler) graphqlWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	header := http.Header{}
+	if slices.Contains(websocket.Subprotocols(r), graphQLWSProtocol) {
+		header.Set("Sec-WebSocket-Protocol", graphQLWSProtocol)
+	}
+	conn, err := h.upgrader.Upgrade(w, r, header)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var writeMutex sync.Mutex
+	send := func(message graphQLWSMessage) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		return conn.WriteJSON(message)
+	}
+	closeWith := func(code int, reason string) {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	caller := &graphQLRequestContext{}
+	if claims, ok := extractClaimsFromRequest(r, h.authService); ok {
+		caller.claims = claims
+	}
+	ctx = context.WithValue(ctx, graphQLContextKey{}, caller)
+
+	var subscriptionsMutex sync.Mutex
+	subscriptions := make(map[string]context.CancelFunc)
+	acknowledged := false
+
+	conn.SetReadDeadline(time.Now().Add(graphQLInitTimeout))
+	for {
+		var message graphQLWSMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			if !acknowledged {
+				closeWith(4408, "Connection initialisation timeout")
+			}
+			return
+		}
+
+		switch message.Type {
+		case "connection_init":
+			if acknowledged {
+				closeWith(4429, "Too many initialisation requests")
+				return
+			}
+			var payload map[string]any
+			json.Unmarshal(message.Payload, &payload)
+			if token, _ := payload["authorization"].(string); token != "" {
+				claims, err := h.authService.ValidateToken(strings.TrimPrefix(token, "Bearer "))
+				if err != nil {
+					closeWith(4403, "Forbidden")
+					return
+				}
+				caller.claims = claims
+			}
+			acknowledged = true
+			conn.SetReadDeadline(time.Time{})
+			send(graphQLWSMessage{Type: "connection_ack"})
+
+		case "ping":
+			send(graphQLWSMessage{Type: "pong"})
+
+		case "pong":
+
+		case "subscribe":
+			if !acknowledged {
+				closeWith(4401, "Unauthorized")
+				return
+			}
+			var req graphql.Request
+			if err := json.Unmarshal(message.Payload, &req); err != nil || message.ID == "" {
+				closeWith(4400, "Invalid subscribe message")
+				return
+			}
+
+			subscriptionsMutex.Lock()
+			if _, exists := subscriptions[message.ID]; exists {
+				subscriptionsMutex.Unlock()
+				closeWith(4409, fmt.Sprintf("Subscriber for %s already exists", message.ID))
+				return
+			}
+			subCtx, subCancel := context.WithCancel(ctx)
+			subscriptions[message.ID] = subCancel
+			subscriptionsMutex.Unlock()
+
+			results, err := h.graphqlSchema.Subscribe(subCtx, req)
+			if err != nil {
+				errorsJSON, _ := json.Marshal(graphql.ErrorResponse(err).Errors)
+				send(graphQLWSMessage{ID: message.ID, Type: "error", Payload: errorsJSON})
+				subscriptionsMutex.Lock()
+				delete(subscriptions, message.ID)
+				subscriptionsMutex.Unlock()
+				subCancel()
+				continue
+			}
+
+			go func(id string) {
+				for result := range results {
+					payload, err := json.Marshal(result)
+					if err != nil {
+						requestLogf(r, "[WARN] Failed to encode GraphQL subscription event: %v", err)
+						continue
+					}
+					if err := send(graphQLWSMessage{ID: id, Type: "next", Payload: payload}); err != nil {
+						conn.Close() // Ends the read loop, which cancels the other subscriptions
+						return
+					}
+				}
+
+				subscriptionsMutex.Lock()
+				subCancel, active := subscriptions[id]
+				delete(subscriptions, id)
+				subscriptionsMutex.Unlock()
+				// Only tell the client when the stream ended on the server side
+				if active {
+					subCancel()
+					if ctx.Err() == nil {
+						send(graphQLWSMessage{ID: id, Type: "complete"})
+					}
+				}
+			}(message.ID)
+
+		case "complete":
+			subscriptionsMutex.Lock()
+			if subCancel, exists := subscriptions[message.ID]; exists {
+				delete(subscriptions, message.ID)
+				subCancel()
+			}
+			subscriptionsMutex.Unlock()
+
+		default:
+			closeWith(4400, fmt.Sprintf("Unexpected message type %q", message.Type))
+			return
+		}
+	}
+}
+
+// graphQLLogEvent is a log line delivered to logEntry subscribers
+type graphQLLogEvent struct {
+	ServiceID string `json:"serviceId"`
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+}
+
+// newGraphQLSchema builds the types and resolvers exposed at /api/graphql
+func (h *Handler) newGraphQLSchema() *graphql.Schema {
+	logEntryType := graphql.StructObject("LogEntry", models.LogEntry{}, nil)
+	logEventType := graphql.StructObject("LogEvent", graphQLLogEvent{}, nil)
+
+	serviceType := graphql.StructObject("Service", models.Service{}, map[string]*graphql.Field{
+		"logs": {
+			Type:        logEntryType,
+			List:        true,
+			Args:        map[string]string{"limit": "Int", "level": "String"},
+			Description: "Latest buffered log lines, oldest first (requires authentication; default limit 100)",
+			Resolve:     h.resolveServiceLogs,
+		},
+	})
+
+	profileType := graphql.StructObject("Profile", models.ServiceProfile{}, map[string]*graphql.Field{
+		"services": {
+			Type:    serviceType,
+			List:    true,
+			Resolve: h.resolveProfileServices,
+		},
+		"serviceIds": {
+			TypeName: "[String]",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*models.ServiceProfile).Services, nil
+			},
+		},
+	})
+
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"services": {
+			Type: serviceType,
+			List: true,
+			Args: map[string]string{
+				"status": "String", "buildSystem": "String", "tag": "[String]",
+				"minPort": "Int", "maxPort": "Int", "sort": "String", "order": "String",
+			},
+			Description: "Services filtered and sorted like GET /api/services",
+			Resolve:     h.resolveServices,
+		},
+		"service": {
+			Type:    serviceType,
+			Args:    map[string]string{"id": "String!"},
+			Resolve: h.resolveService,
+		},
+		"profiles": {
+			Type:        profileType,
+			List:        true,
+			Description: "The caller's service profiles (requires authentication)",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				claims, err := graphQLCaller(p.Context).requireClaims()
+				if err != nil {
+					return nil, err
+				}
+				profiles, err := h.profileService.GetServiceProfiles(claims.UserID)
+				if err != nil {
+					return nil, err
+				}
+				return profiles, nil
+			},
+		},
+		"profile": {
+			Type: profileType,
+			Args: map[string]string{"id": "String!"},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				claims, err := graphQLCaller(p.Context).requireClaims()
+				if err != nil {
+					return nil, err
+				}
+				profileID, err := graphql.StringArg(p.Args, "id")
+				if err != nil {
+					return nil, err
+				}
+				return h.profileService.GetServiceProfile(profileID, claims.UserID)
+			},
+		},
+		"activeProfile": {
+			Type:        profileType,
+			Description: "The caller's active profile, null when none is active (requires authentication)",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return graphQLCaller(p.Context).loadActiveProfile(h.profileService)
+			},
+		},
+		"systemMetrics": {
+			TypeName:    "JSON",
+			Description: "Host resource summary as returned by GET /api/system/metrics",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return h.serviceManager.GetSystemResourceSummary(), nil
+			},
+		},
+		"dependencies": {
+			TypeName:    "JSON",
+			Description: "Configured dependencies by service UUID",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return h.serviceManager.GetDatabase().GetAllServiceDependencies()
+			},
+		},
+	}}
+
+	subscription := &graphql.Object{Name: "Subscription", Fields: map[string]*graphql.Field{
+		"serviceUpdated": {
+			Type:        serviceType,
+			Args:        map[string]string{"id": "String"},
+			Description: "The full service whenever one of its fields changes",
+			Subscribe:   h.subscribeServiceUpdates,
+		},
+		"logEntry": {
+			Type:        logEventType,
+			Args:        map[string]string{"serviceId": "String", "level": "String"},
+			Description: "New log lines (requires authentication)",
+			Subscribe:   h.subscribeLogEntries,
+		},
+	}}
+
+	return &graphql.Schema{Query: query, Subscription: subscription}
+}
+
+// loadActiveProfile returns the caller's active profile, loading it once per request
+func (c *graphQLRequestContext) loadActiveProfile(profileService *services.ProfileService) (*models.ServiceProfile, error) {
+	claims, err := c.requireClaims()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !c.profileLoaded {
+		c.activeProfile, err = profileService.GetActiveProfile(claims.UserID)
+		if err != nil {
+			return nil, err
+		}
+		c.profileLoaded = true
+	}
+	return c.activeProfile, nil
+}
+
+func (h *Handler) resolveServices(p graphql.ResolveParams) (any, error) {
+	params := url.Values{}
+	for _, name := range []string{"status", "buildSystem", "sort", "order"} {
+		value, err := graphql.StringArg(p.Args, name)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	for _, name := range []string{"minPort", "maxPort"} {
+		value, err := graphql.IntArg(p.Args, name, 0)
+		if err != nil {
+			return nil, err
+		}
+		if value != 0 {
+			params.Set(name, fmt.Sprint(value))
+		}
+	}
+	switch tags := p.Args["tag"].(type) {
+	case nil:
+	case string:
+		params.Add("tag", tags)
+	case []any:
+		for _, tag := range tags {
+			params.Add("tag", fmt.Sprint(tag))
+		}
+	default:
+		return nil, fmt.Errorf("argument \"tag\" must be a list of Strings")
+	}
+
+	query, err := parseServiceQueryValues(params)
+	if err != nil {
+		return nil, err
+	}
+	return h.serviceManager.QueryServices(query)
+}
+
+func (h *Handler) resolveService(p graphql.ResolveParams) (any, error) {
+	serviceUUID, err := graphql.StringArg(p.Args, "id")
+	if err != nil {
+		return nil, err
+	}
+	return h.serviceSnapshot(serviceUUID), nil
+}
+
+// serviceSnapshot returns a copy of a service from the read replica, or nil
+func (h *Handler) serviceSnapshot(serviceUUID string) *models.Service {
+	service, _ := h.serviceManager.GetServiceSnapshot(serviceUUID)
+	return service
+}
+
+func (h *Handler) resolveProfileServices(p graphql.ResolveParams) (any, error) {
+	profile := p.Source.(*models.ServiceProfile)
+	snapshot := h.serviceManager.GetServiceSnapshots()
+
+	result := make([]*models.Service, 0, len(profile.Services))
+	for i := range snapshot {
+		if slices.Contains(profile.Services, snapshot[i].ID) {
+			result = append(result, snapshot[i])
+		}
+	}
+	return result, nil
+}
+
+// resolveServiceLogs applies the access rule of GET /api/services/{id}/logs:
+// only services of the caller's active profile
+func (h *Handler) resolveServiceLogs(p graphql.ResolveParams) (any, error) {
+	service := p.Source.(*models.Service)
+	profile, err := graphQLCaller(p.Context).loadActiveProfile(h.profileService)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil || !slices.Contains(profile.Services, service.ID) {
+		return nil, fmt.Errorf("service not found in current profile")
+	}
+
+	limit, err := graphql.IntArg(p.Args, "limit", defaultGraphQLLogTail)
+	if err != nil {
+		return nil, err
+	}
+	level, err := graphql.StringArg(p.Args, "level")
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]models.LogEntry, 0, len(service.Logs))
+	for _, entry := range service.Logs {
+		if level == "" || strings.EqualFold(entry.Level, level) {
+			logs = append(logs, entry)
+		}
+	}
+	if limit >= 0 && len(logs) > limit {
+		logs = logs[len(logs)-limit:]
+	}
+	return logs, nil
+}
+
+// subscribeServiceUpdates turns service_update broadcasts into service snapshots
+func (h *Handler) subscribeServiceUpdates(p graphql.ResolveParams) (<-chan any, error) {
+	filterID, err := graphql.StringArg(p.Args, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	messages, unsubscribe := h.serviceManager.SubscribeBroadcasts()
+	events := make(chan any)
+	go func() {
+		defer close(events)
+		defer unsubscribe()
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case message := <-messages:
+				if message.Type != "service_update" {
+					continue
+				}
+				delta, ok := message.Payload.(map[string]json.RawMessage)
+				if !ok {
+					continue
+				}
+				var serviceUUID string
+				if json.Unmarshal(delta["id"], &serviceUUID) != nil || (filterID != "" && serviceUUID != filterID) {
+					continue
+				}
+				service := h.serviceSnapshot(serviceUUID)
+				if service == nil {
+					continue
+				}
+				select {
+				case events <- service:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// subscribeLogEntries streams new log lines, optionally of one service or level
+func (h *Handler) subscribeLogEntries(p graphql.ResolveParams) (<-chan any, error) {
+	if _, err := graphQLCaller(p.Context).requireClaims(); err != nil {
+		return nil, err
+	}
+	serviceUUID, err := graphql.StringArg(p.Args, "serviceId")
+	if err != nil {
+		return nil, err
+	}
+	level, err := graphql.StringArg(p.Args, "level")
+	if err != nil {
+		return nil, err
+	}
+
+	messages, unsubscribe := h.serviceManager.SubscribeBroadcasts()
+	events := make(chan any)
+	go func() {
+		defer close(events)
+		defer unsubscribe()
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case message := <-messages:
+				entry, ok := message.Payload.(services.LogEntryMessage)
+				if !ok || (serviceUUID != "" && entry.ServiceUUID != serviceUUID) ||
+					(level != "" && !strings.EqualFold(entry.LogEntry.Level, level)) {
+					continue
+				}
+				event := &graphQLLogEvent{
+					ServiceID: entry.ServiceUUID,
+					Timestamp: entry.LogEntry.Timestamp,
+					Level:     entry.LogEntry.Level,
+					Message:   entry.LogEntry.Message,
+				}
+				select {
+				case events <- event:
+				case <-p.Context.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/zechtz/vertex/internal/graphql"
 	"github.com/zechtz/vertex/internal/models"
 	"github.com/zechtz/vertex/internal/services"
 )
@@ -23,11 +24,13 @@ type Handler struct {
 	autoDiscoveryService *services.AutoDiscoveryService
 	authService          *services.AuthService
 	profileService       *services.ProfileService
+	setupService         *services.SetupService
 	upgrader             websocket.Upgrader
+	graphqlSchema        *graphql.Schema
 }
 
 func NewHandler(sm *services.Manager) *Handler {
-	return &Handler{
+	h := &Handler{
 		serviceManager:       sm,
 		topologyService:      services.NewTopologyService(sm),
 		autoDiscoveryService: services.NewAutoDiscoveryService(sm),
@@ -39,6 +42,8 @@ func NewHandler(sm *services.Manager) *Handler {
 			},
 		},
 	}
+	h.setupService = services.NewSetupService(sm, h.authService, h.profileService, h.autoDiscoveryService)
+	return h
 }
 
 // getServiceProjectsDir determines the appropriate projects directory for a service
@@ -90,6 +95,15 @@ func (h *Handler) getServiceProjectsDir(serviceUUID string) string {
 	return defaultProjectsDir
 }
 
+// requestProjectsDir resolves a service's projects directory for the calling
+// user when the request is authenticated
+func (h *Handler) requestProjectsDir(r *http.Request, serviceUUID string) string {
+	if claims, ok := extractClaimsFromRequest(r, h.authService); ok && claims != nil {
+		return h.getServiceProjectsDirForUser(serviceUUID, claims.UserID)
+	}
+	return h.getServiceProjectsDir(serviceUUID)
+}
+
 // getServiceProjectsDirForUser determines the appropriate projects directory for a service for a specific user
 // Checks the user's active profile first, then falls back to global logic
 func (h *Handler) getServiceProjectsDirForUser(serviceUUID, userID string) string {
@@ -133,9 +147,16 @@ func (h *Handler) getServiceProjectsDirForUser(serviceUUID, userID string) strin
 }
 
 func (h *Handler) RegisterRoutes(r *mux.Router) {
+	registerRequestRoutes(h, r)
+	registerAccessRoutes(h, r)
+	registerTwoFactorRoutes(h, r)
 	registerUtilityRoutes(h, r)
-	// Authentication routes (public)
+	registerUpdateRoutes(h, r)
+	registerBackupRoutes(h, r)
+	registerMaintenanceRoutes(h, r)
+	// Authentication and first-run setup routes (public)
 	registerUserRoutes(h, r)
+	registerSetupRoutes(h, r)
 
 	// Profile Management routes (protected)
 
@@ -144,11 +165,40 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	registerCIRoutes(h, r)
 	registerConfigRoutes(h, r)
 	registerServiceRoutes(h, r)
+	registerTrafficRoutes(h, r)
+	registerLogFileRoutes(h, r)
+	registerLogLevelRuleRoutes(h, r)
+	registerLogIngestionRoutes(h, r)
+	registerHealthCheckRoutes(h, r)
+	registerResourceLimitRoutes(h, r)
+	registerReadmeRoutes(h, r)
+	registerNginxLocationRoutes(h, r)
+	registerNginxTemplateRoutes(h, r)
+	registerHostnameRoutes(h, r)
+	registerServiceDomainRoutes(h, r)
+	registerAlertRoutes(h, r)
+	registerNotificationRoutes(h, r)
+	registerFSRoutes(h, r)
+	registerChaosRoutes(h, r)
+	registerDependencyScanRoutes(h, r)
+	registerMigrationRoutes(h, r)
+	registerOrphanRoutes(h, r)
 	registerUptimeRoutes(h, r)
+	registerRestartStatsRoutes(h, r)
+	registerServiceStdinRoutes(h, r)
+	registerHealthPushRoutes(h, r)
+	registerJavaRoutes(h, r)
 	registerDockerComposeRoutes(h, r)
+	registerGraphQLRoutes(h, r)
+	registerJVMPresetRoutes(h, r)
+	registerInfrastructureRoutes(h, r)
+	registerProbeRoutes(h, r)
 
 	// Service routes (will be protected later)
 	registerTopologyRoutes(h, r)
+
+	// Reverse proxy to managed services
+	registerProxyRoutes(h, r)
 }
 
 // sendAutoDiscoveryResponse sends the auto-discovery scan results
@@ -0,0 +1,72 @@
+// Package handlers - Per-service health check client settings
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerHealthCheckRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/health-check", h.getHealthCheckConfigHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/health-check", h.setHealthCheckConfigHandler).Methods("PUT")
+}
+
+// getHealthCheckConfigHandler returns a service's health check settings
+func (h *Handler) getHealthCheckConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	config, err := h.serviceManager.GetHealthCheckConfig(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get health check settings for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(config)
+}
+
+// setHealthCheckConfigHandler replaces a service's health check headers,
+// authentication, timeout and TLS settings
+func (h *Handler) setHealthCheckConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var config models.HealthCheckConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	config.ServiceID = serviceUUID
+
+	if err := h.serviceManager.SetHealthCheckConfig(config); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to save health check settings for service %s: %v", serviceUUID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	saved, err := h.serviceManager.GetHealthCheckConfig(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
@@ -0,0 +1,127 @@
+// Package handlers - Health that services push to Vertex themselves
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+// Largest health report a service may push
+const maxHealthPushBodyBytes = 256 * 1024
+
+func registerHealthPushRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/health-push", h.getHealthPushHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/health-push", h.disableHealthPushHandler).Methods("DELETE")
+	r.HandleFunc("/api/services/{id}/health-push/token", h.generateHealthPushTokenHandler).Methods("POST")
+	r.HandleFunc(services.HealthPushPath, h.ingestHealthPushHandler).Methods("POST")
+	r.HandleFunc(services.HealthPushTokenRoute, h.ingestHealthPushHandler).Methods("POST")
+}
+
+// writeHealthPushError maps a health push error to its status code
+func writeHealthPushError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "invalid health push token"):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// getHealthPushHandler returns whether a service pushes its own health and
+// the last report it pushed
+func (h *Handler) getHealthPushHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	push, err := h.serviceManager.GetHealthPush(mux.Vars(r)["id"])
+	if err != nil {
+		writeHealthPushError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(push)
+}
+
+// generateHealthPushTokenHandler gives a service a new health ingestion
+// token, which is only shown in this response
+func (h *Handler) generateHealthPushTokenHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	push, err := h.serviceManager.GenerateHealthPushToken(mux.Vars(r)["id"])
+	if err != nil {
+		writeHealthPushError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(push)
+}
+
+// disableHealthPushHandler drops a service's health ingestion token
+func (h *Handler) disableHealthPushHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.serviceManager.DisableHealthPush(mux.Vars(r)["id"]); err != nil {
+		writeHealthPushError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ingestHealthPushHandler takes a health report from a service. The service's
+// token, sent in the path or as a bearer token, stands in for signing in, so a
+// startup script or an actuator webhook can call it without credentials.
+func (h *Handler) ingestHealthPushHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	token, found := mux.Vars(r)["token"]
+	if !found {
+		token, found = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if !found || strings.TrimSpace(token) == "" {
+		http.Error(w, "Health push token required", http.StatusUnauthorized)
+		return
+	}
+
+	var report models.HealthPushReport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHealthPushBodyBytes)).Decode(&report); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	push, err := h.serviceManager.IngestHealthPush(token, report)
+	if err != nil {
+		writeHealthPushError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       "accepted",
+		"healthStatus": push.HealthStatus,
+	})
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func TestIngestHealthPushHandler_RequiresBearerToken(t *testing.T) {
+	handler := &Handler{}
+
+	tests := []struct {
+		name          string
+		authorization string
+	}{
+		{"missing header", ""},
+		{"basic auth", "Basic dXNlcjpwYXNz"},
+		{"empty token", "Bearer   "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create request
+			req, err := http.NewRequest("POST", services.HealthPushPath, strings.NewReader(`{"status":"UP"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ingestHealthPushHandler(rr, req)
+
+			if status := rr.Code; status != http.StatusUnauthorized {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestIngestHealthPushHandler_InvalidJSON(t *testing.T) {
+	handler := &Handler{}
+
+	// Create request
+	req, err := http.NewRequest("POST", services.HealthPushPath, strings.NewReader(`{"status":`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer abc123")
+	rr := httptest.NewRecorder()
+
+	handler.ingestHealthPushHandler(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestWriteHealthPushError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("service UUID x not found"), http.StatusNotFound},
+		{errors.New("invalid health push token"), http.StatusUnauthorized},
+		{errors.New("failed to save health push report: disk full"), http.StatusInternalServerError},
+		{errors.New("unsupported status 'GREEN'"), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		writeHealthPushError(rr, tt.err)
+		if status := rr.Code; status != tt.want {
+			t.Errorf("Error %q: got %v want %v", tt.err, status, tt.want)
+		}
+	}
+}
+
+func TestIngestHealthPushHandler_TokenInPath(t *testing.T) {
+	handler := &Handler{}
+	router := mux.NewRouter()
+	router.HandleFunc(services.HealthPushTokenRoute, handler.ingestHealthPushHandler).Methods("POST")
+
+	// Create request; the token is read from the path, so no header is needed
+	req, err := http.NewRequest("POST", services.HealthPushPath+"/abc123", strings.NewReader(`{"status":`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+}
+
+func TestRequestTracingMiddleware_RedactsHealthPushToken(t *testing.T) {
+	handler := &Handler{}
+	router := mux.NewRouter()
+	router.Use(handler.requestTracingMiddleware)
+	router.HandleFunc(services.HealthPushTokenRoute, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("POST")
+
+	// Create request
+	req := httptest.NewRequest("POST", services.HealthPushPath+"/secret-push-token", nil)
+	req.Header.Set(requestIDHeader, "push-trace-1")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	for _, record := range recentRequests.snapshot() {
+		if record.RequestID != "push-trace-1" {
+			continue
+		}
+		if strings.Contains(record.Path, "secret-push-token") || record.Path != services.HealthPushTokenRoute {
+			t.Errorf("Expected the token to be left out of the recorded path, got %q", record.Path)
+		}
+		return
+	}
+	t.Error("Expected the health push to be recorded")
+}
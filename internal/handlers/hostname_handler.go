@@ -0,0 +1,105 @@
+// Package handlers - Per-profile local hostnames
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func registerHostnameRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/profiles/{id}/hostnames", h.getProfileHostnamesHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/hostnames", h.setProfileHostnamesHandler).Methods("PUT")
+	r.HandleFunc("/api/hostnames/apply", h.applyProfileHostnamesHandler).Methods("POST")
+}
+
+// profileHostnamesResponse is a profile's saved hostnames and the outcome of
+// regenerating the hosts file and vertex.conf
+type profileHostnamesResponse struct {
+	Hostnames []models.ProfileHostname `json:"hostnames"`
+	*services.HostnamesResult
+}
+
+// getProfileHostnamesHandler returns the local hostnames of a profile
+func (h *Handler) getProfileHostnamesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	hostnames, err := h.serviceManager.GetProfileHostnames(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get hostnames of profile %s: %v", profileID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(hostnames)
+}
+
+// setProfileHostnamesHandler replaces the local hostnames of a profile and
+// regenerates the hosts file entries and nginx servers
+func (h *Handler) setProfileHostnamesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	profileID := mux.Vars(r)["id"]
+	profile, err := h.profileService.GetServiceProfile(profileID, claims.UserID)
+	if err != nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	var hostnames []models.ProfileHostname
+	if err := json.NewDecoder(r.Body).Decode(&hostnames); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.serviceManager.SetProfileHostnames(profileID, profile.Services, hostnames)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to save hostnames of profile %s: %v", profileID, err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	saved, err := h.serviceManager.GetProfileHostnames(profileID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(profileHostnamesResponse{Hostnames: saved, HostnamesResult: result})
+}
+
+// applyProfileHostnamesHandler regenerates the hostnames of all profiles,
+// e.g. after a service's port changed
+func (h *Handler) applyProfileHostnamesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	result, err := h.serviceManager.ApplyProfileHostnames()
+	if err != nil {
+		requestLogf(r, "[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
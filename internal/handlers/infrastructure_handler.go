@@ -0,0 +1,23 @@
+// Package handlers - Infrastructure types Vertex runs next to the Java services
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func registerInfrastructureRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/infrastructure", h.getInfrastructureHandler).Methods("GET")
+}
+
+// getInfrastructureHandler lists the infrastructure types with their
+// development defaults and how they can run on this machine
+func (h *Handler) getInfrastructureHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	json.NewEncoder(w).Encode(services.GetInfrastructureStatus())
+}
@@ -0,0 +1,184 @@
+// Package handlers - JDK listing and provisioning handlers
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func registerJavaRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/java/versions", h.getJavaVersionsHandler).Methods("GET")
+	r.HandleFunc("/api/java/install", h.installJavaHandler).Methods("POST")
+	r.HandleFunc("/api/java/install/{version}", h.getJavaInstallHandler).Methods("GET")
+	r.HandleFunc("/api/java/assign", h.assignJavaHandler).Methods("POST")
+}
+
+// getJavaVersionsHandler lists installed JDKs and the Temurin versions available for install
+func (h *Handler) getJavaVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	response := map[string]interface{}{
+		"installed":  services.ListInstalledJDKs(),
+		"installDir": services.GetJDKInstallDir(),
+	}
+
+	// Remote release info is best-effort so the list still works offline
+	if releases, err := services.GetTemurinReleases(); err != nil {
+		requestLogf(r, "[WARN] Failed to fetch Temurin releases: %v", err)
+		response["available"] = []int{}
+		response["availableError"] = err.Error()
+	} else {
+		response["available"] = releases.AvailableReleases
+		response["lts"] = releases.AvailableLTSReleases
+		response["mostRecentLts"] = releases.MostRecentLTS
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// installJavaHandler starts downloading a Temurin JDK in the background and
+// optionally assigns it to a service or profile once installed
+func (h *Handler) installJavaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var request struct {
+		Version   int    `json:"version"`
+		ServiceID string `json:"serviceId"`
+		ProfileID string `json:"profileId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.Version == 0 {
+		http.Error(w, "Java version is required", http.StatusBadRequest)
+		return
+	}
+
+	// The assignment runs after this request, so check who may make it now
+	userID, status, err := h.javaHomeAssigner(r, request.ServiceID, request.ProfileID)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	job, err := h.serviceManager.StartTemurinInstall(request.Version, func(installation *services.JDKInstallation) error {
+		return h.applyJavaHome(installation.JavaHome, request.ServiceID, request.ProfileID, userID)
+	})
+	if err != nil {
+		http.Error(w, "Failed to install JDK: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// getJavaInstallHandler reports the progress of the latest install of a Java version
+func (h *Handler) getJavaInstallHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		http.Error(w, "Invalid Java version", http.StatusBadRequest)
+		return
+	}
+
+	job, exists := services.GetTemurinInstall(version)
+	if !exists {
+		http.Error(w, fmt.Sprintf("No install of Temurin %d was started", version), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(job)
+}
+
+// assignJavaHandler assigns an existing JDK as a service or profile Java home
+func (h *Handler) assignJavaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var request struct {
+		JavaHome  string `json:"javaHome"`
+		ServiceID string `json:"serviceId"`
+		ProfileID string `json:"profileId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.JavaHome == "" || (request.ServiceID == "" && request.ProfileID == "") {
+		http.Error(w, "javaHome and a serviceId or profileId are required", http.StatusBadRequest)
+		return
+	}
+
+	if status, err := h.assignJavaHome(r, request.JavaHome, request.ServiceID, request.ProfileID); err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// assignJavaHome applies a Java home to the given service and/or profile,
+// returning the HTTP status to report on failure
+func (h *Handler) assignJavaHome(r *http.Request, javaHome, serviceID, profileID string) (int, error) {
+	userID, status, err := h.javaHomeAssigner(r, serviceID, profileID)
+	if err != nil {
+		return status, err
+	}
+	if err := h.applyJavaHome(javaHome, serviceID, profileID, userID); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// javaHomeAssigner returns the user a profile's Java home is changed for,
+// which needs a login; changing a service's needs none
+func (h *Handler) javaHomeAssigner(r *http.Request, serviceID, profileID string) (string, int, error) {
+	if serviceID != "" {
+		if _, exists := h.serviceManager.GetServiceByUUID(serviceID); !exists {
+			return "", http.StatusNotFound, fmt.Errorf("service UUID %s not found", serviceID)
+		}
+	}
+	if profileID == "" {
+		return "", http.StatusOK, nil
+	}
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		return "", http.StatusUnauthorized, fmt.Errorf("authentication required to update a profile")
+	}
+	return claims.UserID, http.StatusOK, nil
+}
+
+// applyJavaHome sets the Java home of the given service and/or profile
+func (h *Handler) applyJavaHome(javaHome, serviceID, profileID, userID string) error {
+	if serviceID != "" {
+		if err := h.serviceManager.SetServiceJavaHome(serviceID, javaHome); err != nil {
+			log.Printf("[ERROR] Failed to assign JDK to service %s: %v", serviceID, err)
+			return err
+		}
+	}
+
+	if profileID != "" {
+		if err := h.profileService.SetProfileJavaHomeOverride(profileID, userID, javaHome); err != nil {
+			log.Printf("[ERROR] Failed to assign JDK to profile %s: %v", profileID, err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Package handlers - Named JVM option presets
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerJVMPresetRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/jvm-presets", h.getJVMPresetsHandler).Methods("GET")
+	r.HandleFunc("/api/jvm-presets/{name}", h.saveJVMPresetHandler).Methods("PUT")
+	r.HandleFunc("/api/jvm-presets/{name}", h.deleteJVMPresetHandler).Methods("DELETE")
+}
+
+// getJVMPresetsHandler lists the JVM presets with the options they expand to
+func (h *Handler) getJVMPresetsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	presets, err := h.serviceManager.GetJVMPresets()
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get JVM presets: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(presets)
+}
+
+// saveJVMPresetHandler creates or replaces a JVM preset
+func (h *Handler) saveJVMPresetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var preset models.JVMPreset
+	if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	preset.Name = mux.Vars(r)["name"]
+
+	saved, err := h.serviceManager.SaveJVMPreset(preset)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "failed to") {
+			requestLogf(r, "[ERROR] Failed to save JVM preset %s: %v", preset.Name, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(saved)
+}
+
+// deleteJVMPresetHandler deletes a JVM preset that no service uses
+func (h *Handler) deleteJVMPresetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	name := mux.Vars(r)["name"]
+	if err := h.serviceManager.DeleteJVMPreset(name); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "still used"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			requestLogf(r, "[ERROR] Failed to delete JVM preset %s: %v", name, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "JVM preset deleted"})
+}
@@ -0,0 +1,114 @@
+// Package handlers - Rotating per-service log files
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func registerLogFileRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/log-file", h.getLogFileConfigHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/log-file", h.setLogFileConfigHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/logs/download", h.downloadLogFilesHandler).Methods("GET")
+}
+
+// getLogFileConfigHandler returns a service's log file settings and the files on disk
+func (h *Handler) getLogFileConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	config, err := h.serviceManager.GetLogFileConfig(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get log file settings for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(config)
+}
+
+// setLogFileConfigHandler enables, disables or resizes a service's log files
+func (h *Handler) setLogFileConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var config models.LogFileConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	config.ServiceID = serviceUUID
+
+	if err := h.serviceManager.SetLogFileConfig(config); err != nil {
+		requestLogf(r, "[ERROR] Failed to save log file settings for service %s: %v", serviceUUID, err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.serviceManager.GetLogFileConfig(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
+
+// downloadLogFilesHandler streams a service's log files as a .tar.gz
+// (format=gz, the default) or as one plain-text file (format=text)
+func (h *Handler) downloadLogFilesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	service, exists := h.serviceManager.GetServiceByUUID(serviceUUID)
+	if !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.LogArchiveGzip
+	}
+	if format == "txt" {
+		format = services.LogArchiveText
+	}
+
+	filename := fmt.Sprintf("%s-logs-%s", unsafeFilenameChars.ReplaceAllString(service.Name, "_"), time.Now().Format("20060102-150405"))
+	switch format {
+	case services.LogArchiveGzip:
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.tar.gz\"", filename))
+	case services.LogArchiveText:
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.log\"", filename))
+	default:
+		http.Error(w, fmt.Sprintf("Unsupported format '%s' (use gz or text)", format), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.WriteLogArchive(serviceUUID, format, w); err != nil {
+		// Headers are already sent; the client sees a truncated download
+		requestLogf(r, "[ERROR] Failed to write log archive for service %s: %v", serviceUUID, err)
+	}
+}
@@ -0,0 +1,73 @@
+// Package handlers - Per-service log ingestion controls
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerLogIngestionRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/log-ingestion", h.getLogIngestionConfigHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/log-ingestion", h.setLogIngestionConfigHandler).Methods("PUT")
+}
+
+// getLogIngestionConfigHandler returns a service's log ingestion settings and
+// the lines they kept and dropped
+func (h *Handler) getLogIngestionConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	config, err := h.serviceManager.GetLogIngestionConfig(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get log ingestion settings for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(config)
+}
+
+// setLogIngestionConfigHandler replaces the drop patterns, DEBUG sampling and
+// rate cap of a service's captured output
+func (h *Handler) setLogIngestionConfigHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var config models.LogIngestionConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	config.ServiceID = serviceUUID
+
+	if err := h.serviceManager.SetLogIngestionConfig(config); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to save log ingestion settings for service %s: %v", serviceUUID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	saved, err := h.serviceManager.GetLogIngestionConfig(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
@@ -0,0 +1,72 @@
+// Package handlers - Rules that reclassify the level of service log lines
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerLogLevelRuleRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/log-level-rules", h.getLogLevelRulesHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/log-level-rules", h.setLogLevelRulesHandler).Methods("PUT")
+}
+
+// getLogLevelRulesHandler returns a service's log level rules and the defaults
+func (h *Handler) getLogLevelRulesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	rules, err := h.serviceManager.GetLogLevelRules(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get log level rules for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rules)
+}
+
+// setLogLevelRulesHandler replaces a service's log level rules
+func (h *Handler) setLogLevelRulesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	// Leaving out useDefaults keeps the defaults
+	rules := models.LogLevelRules{UseDefaults: true}
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	rules.ServiceID = serviceUUID
+
+	if err := h.serviceManager.SetLogLevelRules(rules); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to save log level rules for service %s: %v", serviceUUID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	saved, err := h.serviceManager.GetLogLevelRules(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
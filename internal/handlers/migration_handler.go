@@ -0,0 +1,139 @@
+// Package handlers - Flyway and Liquibase database migrations
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerMigrationRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/migrations", h.getServiceMigrationsHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/migrate", h.runServiceMigrationsHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/migrate", h.cancelServiceMigrationsHandler).Methods("DELETE")
+	r.HandleFunc("/api/services/{id}/migrations/runs", h.getMigrationRunsHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/migrations/runs/{runId}", h.getMigrationRunHandler).Methods("GET")
+}
+
+// getServiceMigrationsHandler returns the migration tool of a service and its
+// applied and pending migrations as of the latest run
+func (h *Handler) getServiceMigrationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	setup, err := h.serviceManager.GetServiceMigrations(serviceUUID, h.requestProjectsDir(r, serviceUUID))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get migrations of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(setup)
+}
+
+// runServiceMigrationsHandler applies or lists the migrations of a service in the background
+func (h *Handler) runServiceMigrationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var req models.MigrationRunRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	run, err := h.serviceManager.RunServiceMigrations(serviceUUID, h.requestProjectsDir(r, serviceUUID), req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"), strings.Contains(err.Error(), "does not exist"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "already running"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to run migrations of service %s: %v", serviceUUID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// cancelServiceMigrationsHandler stops the running migration run of a service
+func (h *Handler) cancelServiceMigrationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if err := h.serviceManager.CancelServiceMigrations(serviceUUID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
+}
+
+// getMigrationRunsHandler returns the migration run history of a service
+func (h *Handler) getMigrationRunsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	runs, err := h.serviceManager.GetMigrationRuns(serviceUUID, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get migration runs of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(runs)
+}
+
+// getMigrationRunHandler returns a migration run with its migrations and output
+func (h *Handler) getMigrationRunHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	vars := mux.Vars(r)
+	runID, err := strconv.ParseInt(vars["runId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.serviceManager.GetMigrationRun(vars["id"], runID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get migration run %d: %v", runID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(run)
+}
@@ -0,0 +1,97 @@
+// Package handlers - Per-service nginx locations
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/installer"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerNginxLocationRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/nginx-location", h.getNginxLocationHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/nginx-location", h.setNginxLocationHandler).Methods("PUT")
+	r.HandleFunc("/api/nginx/locations/apply", h.applyNginxLocationsHandler).Methods("POST")
+}
+
+// nginxLocationResponse is a service's saved location and the outcome of
+// regenerating vertex.conf
+type nginxLocationResponse struct {
+	*models.NginxLocationConfig
+	Nginx *installer.NginxLocationsResult `json:"nginx"`
+}
+
+// getNginxLocationHandler returns a service's nginx location settings
+func (h *Handler) getNginxLocationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	config, err := h.serviceManager.GetNginxLocationConfig(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get nginx location for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(config)
+}
+
+// setNginxLocationHandler enables, disables or moves a service's nginx
+// location and regenerates vertex.conf
+func (h *Handler) setNginxLocationHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var config models.NginxLocationConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	config.ServiceID = serviceUUID
+
+	result, err := h.serviceManager.SetNginxLocationConfig(config)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to save nginx location for service %s: %v", serviceUUID, err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	saved, err := h.serviceManager.GetNginxLocationConfig(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(nginxLocationResponse{NginxLocationConfig: saved, Nginx: result})
+}
+
+// applyNginxLocationsHandler regenerates the service locations, e.g. after
+// a service's port changed
+func (h *Handler) applyNginxLocationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	result, err := h.serviceManager.ApplyNginxLocations()
+	if err != nil {
+		requestLogf(r, "[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
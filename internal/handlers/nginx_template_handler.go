@@ -0,0 +1,95 @@
+// Package handlers - The template vertex.conf is rendered from
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/installer"
+)
+
+func registerNginxTemplateRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/nginx/template", h.getNginxTemplateHandler).Methods("GET")
+	r.HandleFunc("/api/nginx/template", h.setNginxTemplateHandler).Methods("PUT")
+	r.HandleFunc("/api/nginx/template", h.resetNginxTemplateHandler).Methods("DELETE")
+}
+
+// writeNginxTemplateError maps a template error to its status code; a
+// template that does not render or that nginx rejects is a bad request
+func writeNginxTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "only admins"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// getNginxTemplateHandler returns the nginx template and its overrides
+func (h *Handler) getNginxTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	tmpl, err := h.serviceManager.GetNginxTemplate()
+	if err != nil {
+		writeNginxTemplateError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(tmpl)
+}
+
+// setNginxTemplateHandler validates a new template and overrides, then
+// re-renders vertex.conf from them
+func (h *Handler) setNginxTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var tmpl installer.NginxTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.serviceManager.SetNginxTemplate(tmpl, claims)
+	if err != nil {
+		writeNginxTemplateError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// resetNginxTemplateHandler restores the built-in template without overrides
+func (h *Handler) resetNginxTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.serviceManager.SetNginxTemplate(installer.NginxTemplate{}, claims)
+	if err != nil {
+		writeNginxTemplateError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
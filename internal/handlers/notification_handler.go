@@ -0,0 +1,229 @@
+// Package handlers - Email notification channels and subscriptions
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerNotificationRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/notifications/channels", h.getNotificationChannelsHandler).Methods("GET")
+	r.HandleFunc("/api/notifications/channels", h.createNotificationChannelHandler).Methods("POST")
+	r.HandleFunc("/api/notifications/channels/{id}", h.updateNotificationChannelHandler).Methods("PUT")
+	r.HandleFunc("/api/notifications/channels/{id}", h.deleteNotificationChannelHandler).Methods("DELETE")
+	r.HandleFunc("/api/notifications/channels/{id}/test", h.testNotificationChannelHandler).Methods("POST")
+	r.HandleFunc("/api/notifications/channels/{id}/subscription", h.setNotificationSubscriptionHandler).Methods("PUT")
+	r.HandleFunc("/api/notifications/channels/{id}/subscription", h.deleteNotificationSubscriptionHandler).Methods("DELETE")
+}
+
+// notificationChannelID reads the channel ID from the path, answering 400
+// when it is not a number
+func notificationChannelID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	channelID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || channelID <= 0 {
+		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return channelID, true
+}
+
+// writeNotificationError maps notification failures to a status code
+func writeNotificationError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// getNotificationChannelsHandler lists the notification channels with the
+// user's subscription to each, without SMTP passwords
+func (h *Handler) getNotificationChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	channels, err := h.serviceManager.GetNotificationChannels(claims.UserID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get notification channels: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(channels)
+}
+
+// createNotificationChannelHandler adds an SMTP notification channel
+func (h *Handler) createNotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var channel models.NotificationChannel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	channelID, err := h.serviceManager.CreateNotificationChannel(channel)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"id": channelID})
+}
+
+// updateNotificationChannelHandler replaces the settings of a channel
+func (h *Handler) updateNotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	channelID, ok := notificationChannelID(w, r)
+	if !ok {
+		return
+	}
+
+	var channel models.NotificationChannel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	channel.ID = channelID
+
+	if err := h.serviceManager.UpdateNotificationChannel(channel); err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
+
+// deleteNotificationChannelHandler removes a channel and its subscriptions
+func (h *Handler) deleteNotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	channelID, ok := notificationChannelID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceManager.DeleteNotificationChannel(channelID); err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// testNotificationChannelHandler emails a test notification to the given
+// address, or to the user's own
+func (h *Handler) testNotificationChannelHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	channelID, ok := notificationChannelID(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Email string `json:"email"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	to := strings.TrimSpace(request.Email)
+	if to == "" {
+		to = claims.Email
+	}
+
+	if err := h.serviceManager.SendTestNotification(channelID, to); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "invalid email"):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			requestLogf(r, "[ERROR] Test notification through channel %d failed: %v", channelID, err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "sent", "email": to})
+}
+
+// setNotificationSubscriptionHandler subscribes the user to a channel or
+// changes their subscription
+func (h *Handler) setNotificationSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	channelID, ok := notificationChannelID(w, r)
+	if !ok {
+		return
+	}
+
+	var subscription models.NotificationSubscription
+	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	subscription.ChannelID = channelID
+	subscription.UserID = claims.UserID
+
+	saved, err := h.serviceManager.SetNotificationSubscription(subscription, claims.Email)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
+
+// deleteNotificationSubscriptionHandler unsubscribes the user from a channel
+func (h *Handler) deleteNotificationSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	channelID, ok := notificationChannelID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceManager.DeleteNotificationSubscription(channelID, claims.UserID); err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "unsubscribed"})
+}
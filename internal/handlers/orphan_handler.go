@@ -0,0 +1,97 @@
+// Package handlers - Processes left running by a crashed Vertex
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func registerOrphanRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/orphans", h.getOrphanedProcessesHandler).Methods("GET")
+	r.HandleFunc("/api/orphans/scan", h.scanOrphanedProcessesHandler).Methods("POST")
+	r.HandleFunc("/api/orphans/{pid}/adopt", h.adoptOrphanedProcessHandler).Methods("POST")
+	r.HandleFunc("/api/orphans/{pid}/kill", h.killOrphanedProcessHandler).Methods("POST")
+}
+
+// writeOrphanError maps orphan failures to a status code
+func writeOrphanError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "already running"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// getOrphanedProcessesHandler lists the processes of stopped services that
+// are waiting to be adopted or killed
+func (h *Handler) getOrphanedProcessesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	json.NewEncoder(w).Encode(h.serviceManager.GetOrphanedProcesses())
+}
+
+// scanOrphanedProcessesHandler looks again for processes holding the ports
+// of stopped services
+func (h *Handler) scanOrphanedProcessesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	orphans, err := h.serviceManager.ScanOrphanedProcesses()
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to scan for orphaned processes: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(orphans)
+}
+
+// adoptOrphanedProcessHandler reattaches an orphaned process to its service
+func (h *Handler) adoptOrphanedProcessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	pid, err := strconv.Atoi(mux.Vars(r)["pid"])
+	if err != nil || pid <= 0 {
+		http.Error(w, "Invalid PID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.AdoptOrphanedProcess(pid); err != nil {
+		writeOrphanError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "adopted", "pid": pid})
+}
+
+// killOrphanedProcessHandler terminates an orphaned process and its group
+func (h *Handler) killOrphanedProcessHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	pid, err := strconv.Atoi(mux.Vars(r)["pid"])
+	if err != nil || pid <= 0 {
+		http.Error(w, "Invalid PID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.KillOrphanedProcess(pid); err != nil {
+		writeOrphanError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "killed", "pid": pid})
+}
@@ -0,0 +1,164 @@
+// Package handlers - Synthetic request probes and their objectives
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerProbeRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/probes", h.getServiceProbesHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/probes", h.createServiceProbeHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/probes/{probeId}", h.updateServiceProbeHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/probes/{probeId}", h.deleteServiceProbeHandler).Methods("DELETE")
+	r.HandleFunc("/api/services/{id}/probes/{probeId}/results", h.getProbeResultsHandler).Methods("GET")
+}
+
+// getServiceProbesHandler lists a service's probes with their rolling success
+// rate, p95 latency and whether they meet their objective
+func (h *Handler) getServiceProbesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	probes, err := h.serviceManager.GetServiceProbes(serviceUUID)
+	if err != nil {
+		writeProbeError(w, serviceUUID, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(probes)
+}
+
+// createServiceProbeHandler adds a probe to a service; probes are enabled
+// unless the request says otherwise
+func (h *Handler) createServiceProbeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	probe := models.ServiceProbe{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&probe); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	probe.ID = 0
+	probe.ServiceID = serviceUUID
+
+	if err := h.serviceManager.SaveServiceProbe(&probe); err != nil {
+		writeProbeError(w, serviceUUID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(probe)
+}
+
+// updateServiceProbeHandler replaces a probe's request and objective
+func (h *Handler) updateServiceProbeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	probeID, err := strconv.ParseInt(mux.Vars(r)["probeId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid probe ID", http.StatusBadRequest)
+		return
+	}
+
+	probe := models.ServiceProbe{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&probe); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	probe.ID = probeID
+	probe.ServiceID = serviceUUID
+
+	if err := h.serviceManager.SaveServiceProbe(&probe); err != nil {
+		writeProbeError(w, serviceUUID, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(probe)
+}
+
+// deleteServiceProbeHandler removes a probe and its results
+func (h *Handler) deleteServiceProbeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	probeID, err := strconv.ParseInt(mux.Vars(r)["probeId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid probe ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.DeleteServiceProbe(serviceUUID, probeID); err != nil {
+		writeProbeError(w, serviceUUID, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Probe deleted"})
+}
+
+// getProbeResultsHandler returns the results of a probe, oldest first. hours
+// (default 24) bounds how far back they go and limit (default 1000) how many
+// of the newest are returned.
+func (h *Handler) getProbeResultsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	probeID, err := strconv.ParseInt(mux.Vars(r)["probeId"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid probe ID", http.StatusBadRequest)
+		return
+	}
+
+	hours := 24
+	if value := r.URL.Query().Get("hours"); value != "" {
+		if hours, err = strconv.Atoi(value); err != nil || hours <= 0 {
+			http.Error(w, "Invalid hours", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := 1000
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.serviceManager.GetProbeResults(serviceUUID, probeID, time.Now().Add(-time.Duration(hours)*time.Hour), limit)
+	if err != nil {
+		writeProbeError(w, serviceUUID, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+// writeProbeError maps a probe error to its status code
+func writeProbeError(w http.ResponseWriter, serviceUUID string, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "already exists"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] Probe request for service %s failed: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
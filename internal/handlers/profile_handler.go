@@ -3,12 +3,15 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/gorilla/mux"
 	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
 )
 
 func registerProfileRoutes(h *Handler, r *mux.Router) {
@@ -27,8 +30,38 @@ func registerProfileRoutes(h *Handler, r *mux.Router) {
 	r.HandleFunc("/api/profiles/{id}/service-configs/{service}", h.getProfileServiceConfigHandler).Methods("GET")
 	r.HandleFunc("/api/profiles/{id}/service-configs/{service}", h.setProfileServiceConfigHandler).Methods("POST")
 	r.HandleFunc("/api/profiles/{id}/service-configs/{service}/{key}", h.deleteProfileServiceConfigHandler).Methods("DELETE")
+	r.HandleFunc("/api/profiles/{id}/property-overrides/{service}", h.getPropertyOverridesHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/property-overrides/{service}", h.setPropertyOverridesHandler).Methods("PUT")
+	r.HandleFunc("/api/profiles/import", h.importProfileHandler).Methods("POST")
+	r.HandleFunc("/api/profiles/{id}/export", h.exportProfileHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/promote", h.promoteProfileHandler).Methods("POST")
+	r.HandleFunc("/api/profiles/{id}/promotions", h.getProfilePromotionsHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/git/switch", h.switchProfileBranchesHandler).Methods("POST")
+	r.HandleFunc("/api/profiles/{id}/file-overlays", h.getFileOverlaysHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/file-overlays/materialize", h.materializeFileOverlaysHandler).Methods("POST")
+	r.HandleFunc("/api/profiles/{id}/file-overlays/{service}", h.setFileOverlayHandler).Methods("PUT")
+	r.HandleFunc("/api/profiles/{id}/file-overlays/{service}", h.deleteFileOverlayHandler).Methods("DELETE")
+	r.HandleFunc("/api/profiles/{id}/jvm-presets", h.getJVMPresetOverridesHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/jvm-presets/{service}", h.setJVMPresetOverrideHandler).Methods("PUT")
+	r.HandleFunc("/api/profiles/{id}/java-versions", h.getJavaVersionOverridesHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/java-versions/{service}", h.setJavaVersionOverrideHandler).Methods("PUT")
 	r.HandleFunc("/api/profiles/{id}/services", h.addServiceToProfileHandler).Methods("POST")
 	r.HandleFunc("/api/profiles/{id}/services/{service}", h.removeServiceFromProfileHandler).Methods("DELETE")
+	r.HandleFunc("/api/profiles/{id}/files/diff", h.getProfileFilesDiffHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/events", h.getProfileEventsHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/log-sink", h.getProfileLogSinkHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/log-sink", h.setProfileLogSinkHandler).Methods("PUT")
+	r.HandleFunc("/api/profiles/{id}/log-sink", h.deleteProfileLogSinkHandler).Methods("DELETE")
+	r.HandleFunc("/api/profiles/{id}/log-sink/status", h.getProfileLogSinkStatusHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/memory-budget", h.getProfileMemoryBudgetHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/memory-budget", h.setProfileMemoryBudgetHandler).Methods("PUT")
+	r.HandleFunc("/api/profiles/{id}/port-pool", h.getProfilePortPoolHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/port-pool", h.setProfilePortPoolHandler).Methods("PUT")
+	r.HandleFunc("/api/profiles/{id}/repository-credentials", h.getRepositoryCredentialsHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/repository-credentials/{serverId}", h.setRepositoryCredentialHandler).Methods("PUT")
+	r.HandleFunc("/api/profiles/{id}/repository-credentials/{serverId}", h.deleteRepositoryCredentialHandler).Methods("DELETE")
+	r.HandleFunc("/api/profiles/{id}/build-settings", h.getProfileBuildSettingsHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/build-settings", h.setProfileBuildSettingsHandler).Methods("PUT")
 }
 
 func (h *Handler) getServiceProfilesHandler(w http.ResponseWriter, r *http.Request) {
@@ -42,7 +75,7 @@ func (h *Handler) getServiceProfilesHandler(w http.ResponseWriter, r *http.Reque
 
 	profiles, err := h.profileService.GetServiceProfiles(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get service profiles: %v", err)
+		requestLogf(r, "[ERROR] Failed to get service profiles: %v", err)
 		http.Error(w, "Failed to get service profiles", http.StatusInternalServerError)
 		return
 	}
@@ -83,7 +116,7 @@ func (h *Handler) getServiceProfilesHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode profiles response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode profiles response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -107,7 +140,7 @@ func (h *Handler) getServiceProfileHandler(w http.ResponseWriter, r *http.Reques
 
 	profile, err := h.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to get service profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -117,7 +150,7 @@ func (h *Handler) getServiceProfileHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := json.NewEncoder(w).Encode(profile); err != nil {
-		log.Printf("[ERROR] Failed to encode profile response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode profile response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -134,16 +167,16 @@ func (h *Handler) createServiceProfileHandler(w http.ResponseWriter, r *http.Req
 
 	var req models.CreateProfileRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[ERROR] Invalid request body: %v", err)
+		requestLogf(r, "[ERROR] Invalid request body: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[DEBUG] Create profile request: %+v", req)
+	requestLogf(r, "[DEBUG] Create profile request: %+v", req)
 
 	profile, err := h.profileService.CreateServiceProfile(claims.UserID, &req)
 	if err != nil {
-		log.Printf("[ERROR] Failed to create service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to create service profile: %v", err)
 		if strings.Contains(err.Error(), "invalid services") {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 		} else {
@@ -154,7 +187,7 @@ func (h *Handler) createServiceProfileHandler(w http.ResponseWriter, r *http.Req
 
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(profile); err != nil {
-		log.Printf("[ERROR] Failed to encode profile response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode profile response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -178,16 +211,16 @@ func (h *Handler) updateServiceProfileHandler(w http.ResponseWriter, r *http.Req
 
 	var req models.UpdateProfileRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[ERROR] Invalid request body: %v", err)
+		requestLogf(r, "[ERROR] Invalid request body: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[DEBUG] Update profile request for ID %s: %+v", profileID, req)
+	requestLogf(r, "[DEBUG] Update profile request for ID %s: %+v", profileID, req)
 
 	profile, err := h.profileService.UpdateServiceProfile(profileID, claims.UserID, &req)
 	if err != nil {
-		log.Printf("[ERROR] Failed to update service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to update service profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else if strings.Contains(err.Error(), "invalid services") {
@@ -199,7 +232,7 @@ func (h *Handler) updateServiceProfileHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := json.NewEncoder(w).Encode(profile); err != nil {
-		log.Printf("[ERROR] Failed to encode profile response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode profile response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -223,7 +256,7 @@ func (h *Handler) deleteServiceProfileHandler(w http.ResponseWriter, r *http.Req
 
 	err := h.profileService.DeleteServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to delete service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to delete service profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -253,7 +286,7 @@ func (h *Handler) applyServiceProfileHandler(w http.ResponseWriter, r *http.Requ
 
 	err := h.profileService.ApplyProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to apply service profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to apply service profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -266,7 +299,7 @@ func (h *Handler) applyServiceProfileHandler(w http.ResponseWriter, r *http.Requ
 		"message": "Profile applied successfully",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -290,7 +323,7 @@ func (h *Handler) setActiveProfileHandler(w http.ResponseWriter, r *http.Request
 
 	err := h.profileService.SetActiveProfile(claims.UserID, profileID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to set active profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to set active profile: %v", err)
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "access denied") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -303,7 +336,7 @@ func (h *Handler) setActiveProfileHandler(w http.ResponseWriter, r *http.Request
 		"message": "Active profile set successfully",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -320,7 +353,7 @@ func (h *Handler) getActiveProfileHandler(w http.ResponseWriter, r *http.Request
 
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile: %v", err)
 		http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
 		return
 	}
@@ -331,7 +364,7 @@ func (h *Handler) getActiveProfileHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := json.NewEncoder(w).Encode(profile); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -355,7 +388,7 @@ func (h *Handler) getProfileContextHandler(w http.ResponseWriter, r *http.Reques
 
 	context, err := h.profileService.GetProfileContext(claims.UserID, profileID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get profile context: %v", err)
+		requestLogf(r, "[ERROR] Failed to get profile context: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -365,7 +398,7 @@ func (h *Handler) getProfileContextHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := json.NewEncoder(w).Encode(context); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -389,7 +422,7 @@ func (h *Handler) getProfileEnvVarsHandler(w http.ResponseWriter, r *http.Reques
 
 	envVars, err := h.profileService.GetProfileEnvVars(claims.UserID, profileID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get profile env vars: %v", err)
+		requestLogf(r, "[ERROR] Failed to get profile env vars: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -399,7 +432,7 @@ func (h *Handler) getProfileEnvVarsHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := json.NewEncoder(w).Encode(envVars); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -440,7 +473,7 @@ func (h *Handler) setProfileEnvVarHandler(w http.ResponseWriter, r *http.Request
 
 	err := h.profileService.SetProfileEnvVar(claims.UserID, profileID, request.Name, request.Value, request.Description, request.IsRequired)
 	if err != nil {
-		log.Printf("[ERROR] Failed to set profile env var: %v", err)
+		requestLogf(r, "[ERROR] Failed to set profile env var: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -453,7 +486,7 @@ func (h *Handler) setProfileEnvVarHandler(w http.ResponseWriter, r *http.Request
 		"message": "Environment variable set successfully",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -479,7 +512,7 @@ func (h *Handler) deleteProfileEnvVarHandler(w http.ResponseWriter, r *http.Requ
 
 	err := h.profileService.DeleteProfileEnvVar(claims.UserID, profileID, name)
 	if err != nil {
-		log.Printf("[ERROR] Failed to delete profile env var: %v", err)
+		requestLogf(r, "[ERROR] Failed to delete profile env var: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile or variable not found", http.StatusNotFound)
 		} else {
@@ -511,7 +544,7 @@ func (h *Handler) getProfileServiceConfigHandler(w http.ResponseWriter, r *http.
 
 	_, err := h.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to verify profile access: %v", err)
+		requestLogf(r, "[ERROR] Failed to verify profile access: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -522,13 +555,13 @@ func (h *Handler) getProfileServiceConfigHandler(w http.ResponseWriter, r *http.
 
 	config, err := h.profileService.GetDatabase().GetProfileServiceConfig(profileID, serviceName)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get profile service config: %v", err)
+		requestLogf(r, "[ERROR] Failed to get profile service config: %v", err)
 		http.Error(w, "Failed to get service config", http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(config); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -575,7 +608,7 @@ func (h *Handler) setProfileServiceConfigHandler(w http.ResponseWriter, r *http.
 
 	_, err := h.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to verify profile access: %v", err)
+		requestLogf(r, "[ERROR] Failed to verify profile access: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -586,7 +619,7 @@ func (h *Handler) setProfileServiceConfigHandler(w http.ResponseWriter, r *http.
 
 	err = h.profileService.GetDatabase().SetProfileServiceConfig(profileID, serviceName, request.Key, request.Value, request.ConfigType, request.Description)
 	if err != nil {
-		log.Printf("[ERROR] Failed to set profile service config: %v", err)
+		requestLogf(r, "[ERROR] Failed to set profile service config: %v", err)
 		http.Error(w, "Failed to set service config", http.StatusInternalServerError)
 		return
 	}
@@ -595,7 +628,7 @@ func (h *Handler) setProfileServiceConfigHandler(w http.ResponseWriter, r *http.
 		"message": "Service configuration set successfully",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -622,7 +655,7 @@ func (h *Handler) deleteProfileServiceConfigHandler(w http.ResponseWriter, r *ht
 
 	_, err := h.profileService.GetServiceProfile(profileID, claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to verify profile access: %v", err)
+		requestLogf(r, "[ERROR] Failed to verify profile access: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile not found", http.StatusNotFound)
 		} else {
@@ -633,7 +666,7 @@ func (h *Handler) deleteProfileServiceConfigHandler(w http.ResponseWriter, r *ht
 
 	err = h.profileService.GetDatabase().DeleteProfileServiceConfig(profileID, serviceName, key)
 	if err != nil {
-		log.Printf("[ERROR] Failed to delete profile service config: %v", err)
+		requestLogf(r, "[ERROR] Failed to delete profile service config: %v", err)
 		http.Error(w, "Failed to delete service config", http.StatusInternalServerError)
 		return
 	}
@@ -672,7 +705,7 @@ func (h *Handler) addServiceToProfileHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	log.Printf("[INFO] Adding service '%s' to profile '%s' for user '%s'", request.ServiceName, profileID, claims.UserID)
+	requestLogf(r, "[INFO] Adding service '%s' to profile '%s' for user '%s'", request.ServiceName, profileID, claims.UserID)
 
 	// Convert service name to UUID
 	services := h.serviceManager.GetServices()
@@ -685,14 +718,14 @@ func (h *Handler) addServiceToProfileHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	if serviceUUID == "" {
-		log.Printf("[ERROR] Service '%s' not found", request.ServiceName)
+		requestLogf(r, "[ERROR] Service '%s' not found", request.ServiceName)
 		http.Error(w, "Service not found", http.StatusNotFound)
 		return
 	}
 
 	err := h.profileService.AddServiceToProfile(claims.UserID, profileID, serviceUUID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to add service to profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to add service to profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile or service not found", http.StatusNotFound)
 		} else if strings.Contains(err.Error(), "already exists") {
@@ -707,7 +740,7 @@ func (h *Handler) addServiceToProfileHandler(w http.ResponseWriter, r *http.Requ
 		"message": "Service added to profile successfully",
 	}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -731,11 +764,11 @@ func (h *Handler) removeServiceFromProfileHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	log.Printf("[INFO] Removing service '%s' from profile '%s' for user '%s'", serviceName, profileID, claims.UserID)
+	requestLogf(r, "[INFO] Removing service '%s' from profile '%s' for user '%s'", serviceName, profileID, claims.UserID)
 
 	err := h.profileService.RemoveServiceFromProfile(claims.UserID, profileID, serviceName)
 	if err != nil {
-		log.Printf("[ERROR] Failed to remove service from profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to remove service from profile: %v", err)
 		if strings.Contains(err.Error(), "not found") {
 			http.Error(w, "Profile or service not found", http.StatusNotFound)
 		} else {
@@ -749,3 +782,891 @@ func (h *Handler) removeServiceFromProfileHandler(w http.ResponseWriter, r *http
 		"message": fmt.Sprintf("Service '%s' removed from profile successfully", serviceName),
 	})
 }
+
+// getProfileFilesDiffHandler lists every modified configuration file across the services of a profile
+func (h *Handler) getProfileFilesDiffHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profileID := mux.Vars(r)["id"]
+	profile, err := h.profileService.GetServiceProfile(profileID, claims.UserID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get profile %s: %v", profileID, err)
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	projectsDir := profile.ProjectsDir
+	if projectsDir == "" {
+		projectsDir = h.serviceManager.GetConfig().ProjectsDir
+	}
+
+	files := []services.ConfigFileDiff{}
+	skipped := map[string]string{}
+
+	for _, serviceUUID := range profile.Services {
+		diffs, err := h.serviceManager.GetServiceFileDiffsWithProjectsDir(serviceUUID, projectsDir, true)
+		if err != nil {
+			skipped[serviceUUID] = err.Error()
+			continue
+		}
+		files = append(files, diffs...)
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"files":   files,
+		"skipped": skipped,
+	})
+}
+
+// profileServiceFromRequest verifies the caller owns the profile in the URL and
+// that the service belongs to it, writing an error response otherwise
+func (h *Handler) profileServiceFromRequest(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", "", false
+	}
+
+	vars := mux.Vars(r)
+	profileID := vars["id"]
+	serviceUUID := vars["service"]
+
+	profile, err := h.profileService.GetServiceProfile(profileID, claims.UserID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to verify profile access: %v", err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to verify profile access", http.StatusInternalServerError)
+		}
+		return "", "", false
+	}
+
+	if !slices.Contains(profile.Services, serviceUUID) {
+		http.Error(w, "Service is not part of this profile", http.StatusNotFound)
+		return "", "", false
+	}
+
+	return profileID, serviceUUID, true
+}
+
+// getPropertyOverridesHandler returns the Spring property overrides of a service in a profile
+func (h *Handler) getPropertyOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, serviceUUID, ok := h.profileServiceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	overrides, err := h.serviceManager.GetPropertyOverrides(profileID, serviceUUID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get property overrides: %v", err)
+		http.Error(w, "Failed to get property overrides", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(overrides)
+}
+
+// setPropertyOverridesHandler replaces the Spring property overrides of a service in a
+// profile. They are written to application-override.properties on the next start.
+func (h *Handler) setPropertyOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, serviceUUID, ok := h.profileServiceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var overrides map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.SetPropertyOverrides(profileID, serviceUUID, overrides); err != nil {
+		requestLogf(r, "[ERROR] Failed to set property overrides: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Property overrides saved; restart the service to apply them",
+		"overrides": overrides,
+	})
+}
+
+// getFileOverlaysHandler lists the per-profile variants of service files
+func (h *Handler) getFileOverlaysHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	overlays, err := h.serviceManager.GetProfileFileOverlays(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get file overlays: %v", err)
+		http.Error(w, "Failed to get file overlays", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(overlays)
+}
+
+// setFileOverlayHandler creates or replaces a profile's variant of a service file.
+// It is written into the service directory when the profile is applied.
+func (h *Handler) setFileOverlayHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, serviceUUID, ok := h.profileServiceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	overlay := &models.ProfileFileOverlay{ProfileID: profileID, ServiceID: serviceUUID, Path: req.Path, Content: req.Content}
+	if err := h.serviceManager.SaveProfileFileOverlay(overlay); err != nil {
+		if strings.Contains(err.Error(), "failed to") {
+			requestLogf(r, "[ERROR] Failed to save file overlay: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "File overlay saved; it is written when the profile is applied",
+		"overlay": overlay,
+	})
+}
+
+// deleteFileOverlayHandler removes a profile's variant of a service file, named
+// by the path query parameter, restoring the original if it is written
+func (h *Handler) deleteFileOverlayHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, serviceUUID, ok := h.profileServiceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceManager.DeleteProfileFileOverlay(profileID, serviceUUID, r.URL.Query().Get("path")); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to delete file overlay: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "File overlay deleted"})
+}
+
+// materializeFileOverlaysHandler writes a profile's file overlays into its service
+// directories without applying the rest of the profile
+func (h *Handler) materializeFileOverlaysHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.profileService.MaterializeFileOverlays(mux.Vars(r)["id"], claims.UserID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to write file overlays: %v", err)
+		http.Error(w, "Failed to write file overlays", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// getJVMPresetOverridesHandler returns the JVM preset each service of a profile
+// uses instead of its own, keyed by service UUID
+func (h *Handler) getJVMPresetOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	overrides, err := h.serviceManager.GetJVMPresetOverrides(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get JVM preset overrides: %v", err)
+		http.Error(w, "Failed to get JVM preset overrides", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(overrides)
+}
+
+// setJVMPresetOverrideHandler makes a service use another JVM preset within a
+// profile; an empty preset removes the override
+func (h *Handler) setJVMPresetOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, serviceUUID, ok := h.profileServiceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var override models.JVMPresetOverride
+	if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.SetJVMPresetOverride(profileID, serviceUUID, override.Preset); err != nil {
+		requestLogf(r, "[ERROR] Failed to set JVM preset override: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "JVM preset override saved; restart the service to apply it",
+		"preset":  override.Preset,
+	})
+}
+
+// getJavaVersionOverridesHandler returns the Java version each service of a
+// profile is pinned to and the JDK it resolves to, keyed by service UUID
+func (h *Handler) getJavaVersionOverridesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	overrides, err := h.serviceManager.GetJavaVersionOverrides(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get Java version overrides: %v", err)
+		http.Error(w, "Failed to get Java version overrides", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(overrides)
+}
+
+// setJavaVersionOverrideHandler pins a service to a Java version within a
+// profile; an empty version removes the override
+func (h *Handler) setJavaVersionOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, serviceUUID, ok := h.profileServiceFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var override models.JavaVersionOverride
+	if err := json.NewDecoder(r.Body).Decode(&override); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.serviceManager.SetJavaVersionOverride(profileID, serviceUUID, override.Version)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to set Java version override: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Java version override saved; restart the service to apply it",
+		"version":  saved.Version,
+		"javaHome": saved.JavaHome,
+	})
+}
+
+// getProfileEventsHandler returns the merged event timeline of all services in a profile
+func (h *Handler) getProfileEventsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := h.profileService.GetServiceProfile(mux.Vars(r)["id"], claims.UserID)
+	if err != nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	since, limit, err := parseEventQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.serviceManager.GetServiceEvents(profile.Services, since, limit)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get events for profile %s: %v", profile.ID, err)
+		http.Error(w, "Failed to get profile events", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(events)
+}
+
+// ownedProfileFromRequest verifies the caller owns the profile in the URL,
+// writing an error response otherwise
+func (h *Handler) ownedProfileFromRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", false
+	}
+
+	profileID := mux.Vars(r)["id"]
+	if _, err := h.profileService.GetServiceProfile(profileID, claims.UserID); err != nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return "", false
+	}
+
+	return profileID, true
+}
+
+// getProfileLogSinkHandler returns the external log sink of a profile; the password is never returned
+func (h *Handler) getProfileLogSinkHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	config, err := h.serviceManager.GetLogSinkConfig(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get log sink for profile %s: %v", profileID, err)
+		http.Error(w, "Failed to get log sink", http.StatusInternalServerError)
+		return
+	}
+	if config == nil {
+		http.Error(w, "No log sink configured for this profile", http.StatusNotFound)
+		return
+	}
+
+	config.Password = ""
+	json.NewEncoder(w).Encode(config)
+}
+
+// setProfileLogSinkHandler creates or replaces the external log sink of a profile
+func (h *Handler) setProfileLogSinkHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var config models.LogSinkConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	config.ProfileID = profileID
+
+	// Keep the stored password when the client sends the config back without it
+	if config.Password == "" && config.Username != "" {
+		if existing, err := h.serviceManager.GetLogSinkConfig(profileID); err == nil && existing != nil &&
+			existing.Username == config.Username {
+			config.Password = existing.Password
+		}
+	}
+
+	if err := h.serviceManager.SetLogSinkConfig(config); err != nil {
+		requestLogf(r, "[ERROR] Failed to save log sink for profile %s: %v", profileID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requestLogf(r, "[INFO] Log sink for profile %s set to %s (%s)", profileID, config.Type, config.URL)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Log sink saved"})
+}
+
+// deleteProfileLogSinkHandler removes the external log sink of a profile
+func (h *Handler) deleteProfileLogSinkHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceManager.DeleteLogSinkConfig(profileID); err != nil {
+		requestLogf(r, "[ERROR] Failed to delete log sink for profile %s: %v", profileID, err)
+		http.Error(w, "Failed to delete log sink", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Log sink removed"})
+}
+
+// getProfileLogSinkStatusHandler reports shipped, queued and dropped log counts
+func (h *Handler) getProfileLogSinkStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	status, running := h.serviceManager.GetLogSinkStatus(profileID)
+	if !running {
+		http.Error(w, "No log sink is running for this profile", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
+
+// getRepositoryCredentialsHandler lists a profile's artifact repository
+// credentials; secrets are never returned
+func (h *Handler) getRepositoryCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	credentials, err := h.serviceManager.GetRepositoryCredentials(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get repository credentials for profile %s: %v", profileID, err)
+		http.Error(w, "Failed to get repository credentials", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(credentials)
+}
+
+// setRepositoryCredentialHandler creates or replaces a repository credential;
+// leaving the secret empty keeps the stored one
+func (h *Handler) setRepositoryCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var credential models.RepositoryCredential
+	if err := json.NewDecoder(r.Body).Decode(&credential); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	credential.ProfileID = profileID
+	credential.ServerID = mux.Vars(r)["serverId"]
+
+	if err := h.serviceManager.SetRepositoryCredential(credential); err != nil {
+		requestLogf(r, "[ERROR] Failed to save repository credential %s for profile %s: %v", credential.ServerID, profileID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requestLogf(r, "[INFO] Repository credential %s (%s) saved for profile %s", credential.ServerID, credential.Kind, profileID)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Repository credential saved"})
+}
+
+// deleteRepositoryCredentialHandler removes a repository credential of a profile
+func (h *Handler) deleteRepositoryCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	serverID := mux.Vars(r)["serverId"]
+	if err := h.serviceManager.DeleteRepositoryCredential(profileID, serverID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to delete repository credential %s for profile %s: %v", serverID, profileID, err)
+		http.Error(w, "Failed to delete repository credential", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Repository credential removed"})
+}
+
+// getProfileBuildSettingsHandler returns a profile's offline, mirror and
+// build option settings
+func (h *Handler) getProfileBuildSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.serviceManager.GetProfileBuildSettings(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get build settings for profile %s: %v", profileID, err)
+		http.Error(w, "Failed to get build settings", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(settings)
+}
+
+// setProfileBuildSettingsHandler replaces a profile's build settings; they
+// apply from the next start, build or test run of its services
+func (h *Handler) setProfileBuildSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var settings models.ProfileBuildSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	settings.ProfileID = profileID
+
+	if err := h.serviceManager.SetProfileBuildSettings(settings); err != nil {
+		if strings.Contains(err.Error(), "failed to") {
+			requestLogf(r, "[ERROR] Failed to save build settings for profile %s: %v", profileID, err)
+			http.Error(w, "Failed to save build settings", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.serviceManager.GetProfileBuildSettings(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get build settings for profile %s: %v", profileID, err)
+		http.Error(w, "Failed to get build settings", http.StatusInternalServerError)
+		return
+	}
+
+	requestLogf(r, "[INFO] Build settings saved for profile %s (offline: %t, mirror: %s)", profileID, saved.Offline, saved.MirrorURL)
+	json.NewEncoder(w).Encode(saved)
+}
+
+// getProfileMemoryBudgetHandler returns a profile's memory budget with current and estimated usage
+func (h *Handler) getProfileMemoryBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.serviceManager.GetMemoryBudgetStatus(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get memory budget for profile %s: %v", profileID, err)
+		http.Error(w, "Failed to get memory budget", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
+
+// setProfileMemoryBudgetHandler sets a profile's memory budget; 0 disables it
+func (h *Handler) setProfileMemoryBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	profileID := mux.Vars(r)["id"]
+
+	var req struct {
+		MemoryBudgetMB   int    `json:"memoryBudgetMb"`
+		MemoryBudgetMode string `json:"memoryBudgetMode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.SetProfileMemoryBudget(profileID, claims.UserID, req.MemoryBudgetMB, req.MemoryBudgetMode); err != nil {
+		requestLogf(r, "[ERROR] Failed to set memory budget for profile %s: %v", profileID, err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	status, err := h.serviceManager.GetMemoryBudgetStatus(profileID)
+	if err != nil {
+		http.Error(w, "Failed to get memory budget", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
+
+// getProfilePortPoolHandler returns a profile's port pool and the services holding its ports
+func (h *Handler) getProfilePortPoolHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profileID, ok := h.ownedProfileFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.serviceManager.GetPortPoolStatus(profileID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get port pool for profile %s: %v", profileID, err)
+		http.Error(w, "Failed to get port pool", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
+
+// setProfilePortPoolHandler sets the ports a profile's new services are
+// assigned from; 0-0 returns it to the default pool
+func (h *Handler) setProfilePortPoolHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	profileID := mux.Vars(r)["id"]
+
+	var req struct {
+		Start int `json:"start"`
+		End   int `json:"end"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.profileService.SetProfilePortPool(profileID, claims.UserID, req.Start, req.End); err != nil {
+		requestLogf(r, "[ERROR] Failed to set port pool for profile %s: %v", profileID, err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	status, err := h.serviceManager.GetPortPoolStatus(profileID)
+	if err != nil {
+		http.Error(w, "Failed to get port pool", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
+
+// exportProfileHandler returns a profile as a bundle another user can import.
+// The bundle is sent as a download named after the profile.
+func (h *Handler) exportProfileHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	bundle, err := h.profileService.ExportProfile(mux.Vars(r)["id"], claims.UserID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to export profile: %v", err)
+		http.Error(w, "Failed to export profile", http.StatusInternalServerError)
+		return
+	}
+
+	filename := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, bundle.Name)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".profile.json"))
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(bundle)
+}
+
+// importProfileHandler creates a profile for the caller from an exported
+// bundle, mapping its services to local ones by name or directory
+func (h *Handler) importProfileHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ProfileImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.profileService.ImportProfile(claims.UserID, &req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "already exists"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to import profile: %v", err)
+			http.Error(w, "Failed to import profile", http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// promoteProfileHandler copies selected environment variables and service
+// configuration of a profile into another profile, or previews the copy when
+// dryRun is set
+func (h *Handler) promoteProfileHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ProfilePromotionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	promotion, err := h.profileService.PromoteProfile(mux.Vars(r)["id"], claims.UserID, &req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "profile not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to promote profile: %v", err)
+			http.Error(w, "Failed to promote profile", http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(promotion)
+}
+
+// getProfilePromotionsHandler returns the audit trail of promotions from or
+// to a profile, newest first; limit defaults to 50
+func (h *Handler) getProfilePromotionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	limit := 50
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	promotions, err := h.profileService.GetProfilePromotions(mux.Vars(r)["id"], claims.UserID, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, "Profile not found", http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get profile promotions: %v", err)
+		http.Error(w, "Failed to get profile promotions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(promotions)
+}
+
+// switchProfileBranchesHandler checks out a branch, or a branch per service,
+// in every service repository of a profile and reports each repository
+func (h *Handler) switchProfileBranchesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := h.profileService.GetServiceProfile(mux.Vars(r)["id"], claims.UserID)
+	if err != nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	var req services.ProfileBranchSwitchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.serviceManager.SwitchProfileBranches(profile, &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if result.Aborted {
+		w.WriteHeader(http.StatusConflict)
+	}
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,153 @@
+// Package handlers - Reverse proxy to managed services
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const proxyTokenParam = "vertex_token"
+
+func registerProxyRoutes(h *Handler, r *mux.Router) {
+	r.PathPrefix("/proxy/{serviceName}").HandlerFunc(h.proxyServiceHandler)
+}
+
+// proxyAuthRequired reports whether proxied requests must carry a valid Vertex
+// token, enabled with VERTEX_PROXY_REQUIRE_AUTH=true
+func proxyAuthRequired() bool {
+	value := strings.ToLower(os.Getenv("VERTEX_PROXY_REQUIRE_AUTH"))
+	return value == "true" || value == "1" || value == "yes"
+}
+
+// proxyServiceHandler forwards /proxy/{serviceName}/... to the service's port on
+// localhost so every service can be reached through the Vertex origin
+func (h *Handler) proxyServiceHandler(w http.ResponseWriter, r *http.Request) {
+	serviceName := mux.Vars(r)["serviceName"]
+
+	if proxyAuthRequired() && !h.authorizeProxyRequest(w, r) {
+		requestLogf(r, "[WARN] [proxy] Rejected unauthenticated request %s %s", r.Method, r.URL.Path)
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	service, exists := h.serviceManager.GetServiceByName(serviceName)
+	if !exists {
+		http.Error(w, fmt.Sprintf("Service '%s' not found", serviceName), http.StatusNotFound)
+		return
+	}
+
+	service.Mutex.RLock()
+	port := service.Port
+	status := service.Status
+	service.Mutex.RUnlock()
+
+	if port <= 0 {
+		http.Error(w, fmt.Sprintf("Service '%s' has no port configured", serviceName), http.StatusBadGateway)
+		return
+	}
+	if status != "running" {
+		http.Error(w, fmt.Sprintf("Service '%s' is not running (status: %s)", serviceName, status), http.StatusServiceUnavailable)
+		return
+	}
+
+	proxy := h.serviceProxy(r, serviceName, port)
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+
+	proxy.ServeHTTP(recorder, r)
+
+	requestLogf(r, "[INFO] [proxy] %s %s -> %s:%d %d in %s", r.Method, r.URL.Path, serviceName, port, recorder.status, time.Since(start))
+}
+
+// serviceProxy returns the reverse proxy that forwards r to the service
+// listening on port on localhost
+func (h *Handler) serviceProxy(r *http.Request, serviceName string, port int) *httputil.ReverseProxy {
+	target := &url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", port)}
+	prefix := "/proxy/" + serviceName
+
+	// An Authorization header carrying the Vertex token is for Vertex, not the
+	// service; any other credentials are the service's own and pass through
+	_, vertexAuthorization := extractClaimsFromRequest(r, h.authService)
+
+	return &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = strings.TrimPrefix(req.URL.Path, prefix)
+			if req.URL.Path == "" {
+				req.URL.Path = "/"
+			}
+			req.URL.RawPath = ""
+
+			query := req.URL.Query()
+			if query.Has(proxyTokenParam) {
+				query.Del(proxyTokenParam)
+				req.URL.RawQuery = query.Encode()
+			}
+
+			// Don't leak the Vertex token cookie to the service
+			if cookies := req.Cookies(); len(cookies) > 0 {
+				req.Header.Del("Cookie")
+				for _, cookie := range cookies {
+					if cookie.Name != proxyTokenParam {
+						req.AddCookie(cookie)
+					}
+				}
+			}
+
+			// Don't leak the Vertex token header to the service either
+			if vertexAuthorization {
+				req.Header.Del("Authorization")
+			}
+
+			// The path the browser sees, under the base path when there is one
+			req.Header.Set("X-Forwarded-Prefix", basePathFromContext(r.Context())+prefix)
+			if requestID := requestIDFromContext(r.Context()); requestID != "" {
+				req.Header.Set(requestIDHeader, requestID)
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			requestLogf(r, "[ERROR] [proxy] %s %s -> %s failed: %v", req.Method, r.URL.Path, serviceName, err)
+			http.Error(w, fmt.Sprintf("Service '%s' is not reachable on port %d", serviceName, port), http.StatusBadGateway)
+		},
+	}
+}
+
+// authorizeProxyRequest accepts a Vertex token from the Authorization header, a
+// vertex_token query parameter or cookie. A query token is stored in a cookie so
+// assets loaded by a proxied page are authorized too; the cookie is scoped to
+// the proxy path under the base path the browser sees.
+func (h *Handler) authorizeProxyRequest(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := extractClaimsFromRequest(r, h.authService); ok {
+		return true
+	}
+
+	if token := r.URL.Query().Get(proxyTokenParam); token != "" {
+		if _, err := h.authService.ValidateToken(token); err != nil {
+			return false
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     proxyTokenParam,
+			Value:    token,
+			Path:     basePathFromContext(r.Context()) + "/proxy/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		return true
+	}
+
+	if cookie, err := r.Cookie(proxyTokenParam); err == nil {
+		_, err := h.authService.ValidateToken(cookie.Value)
+		return err == nil
+	}
+
+	return false
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+func TestServiceProxy_ForwardedPrefix(t *testing.T) {
+	// Create a service that echoes the prefix and path it was called with
+	var gotPrefix, gotPath string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPrefix = r.Header.Get("X-Forwarded-Prefix")
+		gotPath = r.URL.Path
+	}))
+	defer backend.Close()
+
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(backendURL.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, _, _ := newAuthTestHandler(t)
+	proxy := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serviceProxy(r, "api", port).ServeHTTP(w, r)
+	})
+
+	tests := []struct {
+		name       string
+		basePath   string
+		path       string
+		wantPrefix string
+	}{
+		{"root", "", "/proxy/api/users", "/proxy/api"},
+		{"base path", "/vertex", "/vertex/proxy/api/users", "/vertex/proxy/api"},
+		{"base path stripped by the outer proxy", "/tools/vertex", "/proxy/api/users", "/tools/vertex/proxy/api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPrefix, gotPath = "", ""
+			// Create request
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			BasePathMiddleware(tt.basePath, proxy).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Proxy returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+			if gotPrefix != tt.wantPrefix {
+				t.Errorf("X-Forwarded-Prefix = %q, want %q", gotPrefix, tt.wantPrefix)
+			}
+			if gotPath != "/users" {
+				t.Errorf("Service got path %q, want /users", gotPath)
+			}
+		})
+	}
+}
+
+func TestAuthorizeProxyRequest_CookiePath(t *testing.T) {
+	h, _, token := newAuthTestHandler(t)
+	authorize := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorizeProxyRequest(w, r) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		basePath string
+		path     string
+		wantPath string
+	}{
+		{"root", "", "/proxy/api/", "/proxy/"},
+		{"base path", "/vertex", "/vertex/proxy/api/", "/vertex/proxy/"},
+		{"base path stripped by the outer proxy", "/tools/vertex", "/proxy/api/", "/tools/vertex/proxy/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create request
+			req := httptest.NewRequest("GET", tt.path+"?"+proxyTokenParam+"="+token, nil)
+			rr := httptest.NewRecorder()
+
+			BasePathMiddleware(tt.basePath, authorize).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+			cookies := rr.Result().Cookies()
+			if len(cookies) != 1 || cookies[0].Name != proxyTokenParam {
+				t.Fatalf("Expected the %s cookie, got %v", proxyTokenParam, cookies)
+			}
+			if cookies[0].Path != tt.wantPath {
+				t.Errorf("Cookie path = %q, want %q", cookies[0].Path, tt.wantPath)
+			}
+		})
+	}
+}
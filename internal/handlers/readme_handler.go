@@ -0,0 +1,94 @@
+// Package handlers - Service READMEs and notes
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func registerReadmeRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/readme", h.getServiceReadmeHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/notes", h.getServiceNotesHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/notes", h.setServiceNotesHandler).Methods("PUT")
+}
+
+// getServiceReadmeHandler returns the README of a service's repository as
+// sanitized HTML, with the service's notes
+func (h *Handler) getServiceReadmeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	readme, err := h.serviceManager.GetServiceReadmeWithProjectsDir(serviceUUID, h.requestProjectsDir(r, serviceUUID))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get README for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(readme)
+}
+
+// getServiceNotesHandler returns a service's notes
+func (h *Handler) getServiceNotesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if _, exists := h.serviceManager.GetServiceByUUID(serviceUUID); !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	notes, err := h.serviceManager.GetServiceNotes(serviceUUID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get notes for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(notes)
+}
+
+// setServiceNotesHandler replaces a service's notes
+func (h *Handler) setServiceNotesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var request struct {
+		Notes string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.SetServiceNotes(serviceUUID, request.Notes); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to save notes for service %s: %v", serviceUUID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	saved, err := h.serviceManager.GetServiceNotes(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
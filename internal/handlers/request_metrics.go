@@ -0,0 +1,245 @@
+// Package handlers - Per-endpoint API metrics and the Prometheus exporter
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unmatchedRoute is the route of API requests that matched no API route
+const unmatchedRoute = "(unmatched)"
+
+// requestDurationBuckets are the upper bounds, in seconds, of the request
+// duration histogram
+var requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// EndpointStats summarizes the requests to one method and route since Vertex started
+type EndpointStats struct {
+	Method       string        `json:"method"`
+	Route        string        `json:"route"`
+	Count        int64         `json:"count"`
+	ClientErrors int64         `json:"clientErrors"` // 4xx responses
+	ServerErrors int64         `json:"serverErrors"` // 5xx responses
+	AvgMs        float64       `json:"avgMs"`
+	MaxMs        int64         `json:"maxMs"`
+	P95Ms        int64         `json:"p95Ms"` // Upper bound of the histogram bucket holding the 95th percentile; -1 when slower than the last bucket
+	Statuses     map[int]int64 `json:"statuses"`
+	LastSeen     time.Time     `json:"lastSeen"`
+
+	buckets     []int64 // Requests per duration bucket, the last one for those slower than every bound
+	durationSum time.Duration
+}
+
+// endpointRegistry keeps the stats of every endpoint called since Vertex started
+type endpointRegistry struct {
+	mutex     sync.Mutex
+	endpoints map[string]*EndpointStats
+	started   time.Time
+}
+
+var endpointMetrics = &endpointRegistry{endpoints: make(map[string]*EndpointStats), started: time.Now()}
+
+func (er *endpointRegistry) observe(method, route string, status int, duration time.Duration) {
+	er.mutex.Lock()
+	defer er.mutex.Unlock()
+
+	key := method + " " + route
+	stats, exists := er.endpoints[key]
+	if !exists {
+		stats = &EndpointStats{
+			Method:   method,
+			Route:    route,
+			Statuses: make(map[int]int64),
+			buckets:  make([]int64, len(requestDurationBuckets)+1),
+		}
+		er.endpoints[key] = stats
+	}
+
+	stats.Count++
+	stats.Statuses[status]++
+	switch {
+	case status >= 500:
+		stats.ServerErrors++
+	case status >= 400:
+		stats.ClientErrors++
+	}
+	stats.durationSum += duration
+	if ms := duration.Milliseconds(); ms > stats.MaxMs {
+		stats.MaxMs = ms
+	}
+	stats.LastSeen = time.Now()
+
+	bucket := sort.SearchFloat64s(requestDurationBuckets, duration.Seconds())
+	stats.buckets[bucket]++
+}
+
+// snapshot returns a copy of every endpoint's stats, busiest first
+func (er *endpointRegistry) snapshot() []EndpointStats {
+	er.mutex.Lock()
+	defer er.mutex.Unlock()
+
+	result := make([]EndpointStats, 0, len(er.endpoints))
+	for _, stats := range er.endpoints {
+		copied := *stats
+		copied.Statuses = make(map[int]int64, len(stats.Statuses))
+		for status, count := range stats.Statuses {
+			copied.Statuses[status] = count
+		}
+		copied.buckets = append([]int64(nil), stats.buckets...)
+		copied.AvgMs = float64(stats.durationSum.Microseconds()) / 1000 / float64(stats.Count)
+		copied.P95Ms = percentileBucketMs(copied.buckets, stats.Count, 0.95)
+		result = append(result, copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].Route != result[j].Route {
+			return result[i].Route < result[j].Route
+		}
+		return result[i].Method < result[j].Method
+	})
+	return result
+}
+
+// percentileBucketMs returns the upper bound in milliseconds of the bucket
+// holding the given percentile, or -1 when it is past the last bound
+func percentileBucketMs(buckets []int64, count int64, percentile float64) int64 {
+	rank := int64(float64(count)*percentile + 0.999999)
+	var seen int64
+	for i, bucketCount := range buckets {
+		seen += bucketCount
+		if seen >= rank {
+			if i == len(requestDurationBuckets) {
+				return -1
+			}
+			return int64(requestDurationBuckets[i] * 1000)
+		}
+	}
+	return -1
+}
+
+// getSystemRequestsHandler returns the recent API access log, newest first,
+// and per-endpoint stats since Vertex started. status filters the log by code
+// or class (500, 5xx, 4xx or "error" for both), route by path template and
+// user by username; limit defaults to 100. Only admins may list it.
+func (h *Handler) getSystemRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != "admin" {
+		http.Error(w, "only admins can list API requests", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	matchStatus, err := requestStatusFilter(query.Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	route := query.Get("route")
+	user := query.Get("user")
+
+	requests := []RequestRecord{}
+	for _, record := range recentRequests.snapshot() {
+		if !matchStatus(record.Status) || (route != "" && record.Route != route) || (user != "" && record.User != user) {
+			continue
+		}
+		requests = append(requests, record)
+		if len(requests) >= limit {
+			break
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"requests":  requests,
+		"endpoints": endpointMetrics.snapshot(),
+		"since":     endpointMetrics.started,
+	})
+}
+
+// requestStatusFilter parses the status filter of the access log
+func requestStatusFilter(value string) (func(int) bool, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return func(int) bool { return true }, nil
+	case "error":
+		return func(status int) bool { return status >= 400 }, nil
+	case "2xx", "3xx", "4xx", "5xx":
+		class := int(value[0]-'0') * 100
+		return func(status int) bool { return status >= class && status < class+100 }, nil
+	}
+	code, err := strconv.Atoi(value)
+	if err != nil || code < 100 || code > 599 {
+		return nil, fmt.Errorf("invalid status filter %q", value)
+	}
+	return func(status int) bool { return status == code }, nil
+}
+
+// prometheusMetricsHandler exports the API request counters and duration
+// histograms in the Prometheus text format
+func (h *Handler) prometheusMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	endpoints := endpointMetrics.snapshot()
+	var b strings.Builder
+
+	b.WriteString("# HELP vertex_http_requests_total API requests handled, by method, route and status.\n")
+	b.WriteString("# TYPE vertex_http_requests_total counter\n")
+	for _, stats := range endpoints {
+		statuses := make([]int, 0, len(stats.Statuses))
+		for status := range stats.Statuses {
+			statuses = append(statuses, status)
+		}
+		sort.Ints(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "vertex_http_requests_total{method=%q,route=%q,status=\"%d\"} %d\n",
+				stats.Method, stats.Route, status, stats.Statuses[status])
+		}
+	}
+
+	b.WriteString("# HELP vertex_http_request_duration_seconds API request durations, by method and route.\n")
+	b.WriteString("# TYPE vertex_http_request_duration_seconds histogram\n")
+	for _, stats := range endpoints {
+		labels := fmt.Sprintf("method=%q,route=%q", stats.Method, stats.Route)
+		var cumulative int64
+		for i, bound := range requestDurationBuckets {
+			cumulative += stats.buckets[i]
+			fmt.Fprintf(&b, "vertex_http_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				labels, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(&b, "vertex_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, stats.Count)
+		fmt.Fprintf(&b, "vertex_http_request_duration_seconds_sum{%s} %g\n", labels, stats.durationSum.Seconds())
+		fmt.Fprintf(&b, "vertex_http_request_duration_seconds_count{%s} %d\n", labels, stats.Count)
+	}
+
+	w.Write([]byte(b.String()))
+}
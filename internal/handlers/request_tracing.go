@@ -0,0 +1,293 @@
+// Package handlers - Request ID middleware and recent request tracking
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+const (
+	requestIDHeader     = "X-Request-Id"
+	recentRequestsLimit = 500
+	slowRequestDefault  = 500 * time.Millisecond
+	requestErrorLimit   = 256
+	maxRequestIDLength  = 128
+)
+
+// requestIDKey is the request context key of the request ID
+type requestIDKey struct{}
+
+// requestIDFromContext returns the ID requestTracingMiddleware gave a request
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// validRequestID reports whether a client-supplied request ID is short and
+// limited to letters, digits, '.', '_' and '-', so it is safe to echo in
+// headers and log lines
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// requestLogf logs like log.Printf, tagging the line with the request's ID
+// after its level, as in "[ERROR] [req=<id>] ..."
+func requestLogf(r *http.Request, format string, args ...interface{}) {
+	requestID := requestIDFromContext(r.Context())
+	if requestID == "" {
+		log.Printf(format, args...)
+		return
+	}
+	tag := "[req=" + strings.ReplaceAll(requestID, "%", "%%") + "] "
+	if strings.HasPrefix(format, "[") {
+		if end := strings.Index(format, "] "); end >= 0 {
+			log.Printf(format[:end+2]+tag+format[end+2:], args...)
+			return
+		}
+	}
+	log.Printf(tag+format, args...)
+}
+
+// requestDebugLogging reports whether every API request is logged, enabled
+// with VERTEX_LOG_LEVEL=debug; otherwise only slow requests are
+func requestDebugLogging() bool {
+	return strings.EqualFold(os.Getenv("VERTEX_LOG_LEVEL"), "debug")
+}
+
+// WatchdogUserAgent identifies the daemon's own watchdog self-checks, which
+// are not logged or recorded
+const WatchdogUserAgent = "vertex-watchdog"
+
+// RequestRecord captures a completed API call. Route is the path template
+// the request matched, such as /api/services/{id}/start.
+type RequestRecord struct {
+	RequestID  string    `json:"requestId"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Route      string    `json:"route"`
+	Status     int       `json:"status"`
+	DurationMs int64     `json:"durationMs"`
+	User       string    `json:"user"`
+	Error      string    `json:"error,omitempty"` // Start of the response body of failed requests
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// requestLog is a fixed-size ring buffer of recent API calls
+type requestLog struct {
+	mutex   sync.Mutex
+	records []RequestRecord
+	next    int
+	full    bool
+}
+
+var recentRequests = &requestLog{records: make([]RequestRecord, recentRequestsLimit)}
+
+func (rl *requestLog) add(record RequestRecord) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.records[rl.next] = record
+	rl.next = (rl.next + 1) % len(rl.records)
+	if rl.next == 0 {
+		rl.full = true
+	}
+}
+
+// snapshot returns records newest first
+func (rl *requestLog) snapshot() []RequestRecord {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	count := rl.next
+	if rl.full {
+		count = len(rl.records)
+	}
+
+	result := make([]RequestRecord, 0, count)
+	for i := 1; i <= count; i++ {
+		idx := (rl.next - i + len(rl.records)) % len(rl.records)
+		result = append(result, rl.records[idx])
+	}
+	return result
+}
+
+// statusRecorder captures the response status, and the start of the body of
+// failed requests, while staying usable for websocket upgrades
+type statusRecorder struct {
+	http.ResponseWriter
+	status    int
+	errorBody []byte
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(data []byte) (int, error) {
+	if sr.status >= http.StatusBadRequest && len(sr.errorBody) < requestErrorLimit {
+		sr.errorBody = append(sr.errorBody, data[:min(len(data), requestErrorLimit-len(sr.errorBody))]...)
+	}
+	return sr.ResponseWriter.Write(data)
+}
+
+// errorMessage returns the first line of a failed request's response body
+func (sr *statusRecorder) errorMessage() string {
+	message, _, _ := strings.Cut(string(sr.errorBody), "\n")
+	return strings.TrimSpace(message)
+}
+
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	return hijacker.Hijack()
+}
+
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func registerRequestRoutes(h *Handler, r *mux.Router) {
+	r.Use(h.requestTracingMiddleware)
+	r.HandleFunc("/api/requests/recent", h.getRecentRequestsHandler).Methods("GET")
+	r.HandleFunc("/api/system/requests", h.getSystemRequestsHandler).Methods("GET")
+	r.HandleFunc("/metrics", h.prometheusMetricsHandler).Methods("GET")
+}
+
+// requestTracingMiddleware assigns every request an ID, keeping a valid one the
+// client sent, returns it in X-Request-Id and puts it in the request context for requestLogf, logs slow requests (all of
+// them at debug level) with the ID and records API calls for /api/requests/recent,
+// /api/system/requests and the per-endpoint metrics
+func (h *Handler) requestTracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID))
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		next.ServeHTTP(recorder, r)
+
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.UserAgent() == WatchdogUserAgent {
+			return
+		}
+
+		duration := time.Since(start)
+		user := ""
+		if claims, ok := extractClaimsFromRequest(r, h.authService); ok && claims != nil {
+			user = claims.Username
+		}
+
+		route := unmatchedRoute
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil && template != "/" {
+				route = template
+			}
+		}
+
+		// A health push token in the path is a credential; keep it out of the log
+		path := r.URL.Path
+		if route == services.HealthPushTokenRoute {
+			path = route
+		}
+
+		recentRequests.add(RequestRecord{
+			RequestID:  requestID,
+			Method:     r.Method,
+			Path:       path,
+			Route:      route,
+			Status:     recorder.status,
+			DurationMs: duration.Milliseconds(),
+			User:       user,
+			Error:      recorder.errorMessage(),
+			Timestamp:  start,
+		})
+		endpointMetrics.observe(r.Method, route, recorder.status, duration)
+
+		if duration >= slowRequestDefault {
+			log.Printf("[WARN] [req=%s] Slow request %s %s -> %d in %s (user: %s)", requestID, r.Method, path, recorder.status, duration, user)
+		} else if requestDebugLogging() {
+			log.Printf("[DEBUG] [req=%s] %s %s -> %d in %s", requestID, r.Method, path, recorder.status, duration)
+		}
+	})
+}
+
+// getRecentRequestsHandler lists recent API calls, by default only those slower than 500ms
+func (h *Handler) getRecentRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	minDuration := slowRequestDefault.Milliseconds()
+	if value := r.URL.Query().Get("minDurationMs"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Invalid minDurationMs", http.StatusBadRequest)
+			return
+		}
+		minDuration = parsed
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	requests := []RequestRecord{}
+	for _, record := range recentRequests.snapshot() {
+		if record.DurationMs < minDuration {
+			continue
+		}
+		requests = append(requests, record)
+		if len(requests) >= limit {
+			break
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"requests":      requests,
+		"minDurationMs": minDuration,
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+// newAuthTestHandler returns a handler with auth and profile services and the
+// tokens of an admin and of a regular user
+func newAuthTestHandler(t *testing.T) (*Handler, string, string) {
+	t.Helper()
+	db, err := database.NewDatabaseWithPath(filepath.Join(t.TempDir(), "vertex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	authService := services.NewAuthService(db)
+	if _, err := authService.RegisterAdmin(&models.UserRegistration{Username: "admin", Email: "admin@example.com", Password: "secret123"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authService.Register(&models.UserRegistration{Username: "alice", Email: "alice@example.com", Password: "secret123"}); err != nil {
+		t.Fatal(err)
+	}
+	adminLogin, err := authService.Login(&models.UserLogin{Email: "admin@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userLogin, err := authService.Login(&models.UserLogin{Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{authService: authService, profileService: services.NewProfileService(db, nil)}
+	return h, adminLogin.Token, userLogin.Token
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		requestID string
+		want      bool
+	}{
+		{"", false},
+		{"3f2b8c1e-9d4a-4c55-8a0e-1b2c3d4e5f60", true},
+		{"trace_01.abc-DEF", true},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+		{"id with spaces", false},
+		{"id\r\nX-Injected: 1", false},
+		{"%s%d", false},
+		{"ïd", false},
+	}
+
+	for _, tt := range tests {
+		if got := validRequestID(tt.requestID); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.requestID, got, tt.want)
+		}
+	}
+}
+
+func TestRequestTracingMiddleware_RequestID(t *testing.T) {
+	h := &Handler{}
+	router := mux.NewRouter()
+	router.Use(h.requestTracingMiddleware)
+	router.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name      string
+		requestID string
+		keep      bool
+	}{
+		{"valid ID is kept", "client-trace.42", true},
+		{"missing ID is generated", "", false},
+		{"invalid ID is replaced", "bad id\twith tabs", false},
+		{"long ID is replaced", strings.Repeat("x", maxRequestIDLength+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create request
+			req := httptest.NewRequest("GET", "/ping", nil)
+			if tt.requestID != "" {
+				req.Header.Set(requestIDHeader, tt.requestID)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(requestIDHeader)
+			if tt.keep && got != tt.requestID {
+				t.Errorf("Expected X-Request-Id %q to be kept, got %q", tt.requestID, got)
+			}
+			if !tt.keep && (got == tt.requestID || !validRequestID(got)) {
+				t.Errorf("Expected a generated X-Request-Id, got %q", got)
+			}
+		})
+	}
+}
+
+func TestRequestLogRoutes_Authentication(t *testing.T) {
+	h, adminToken, userToken := newAuthTestHandler(t)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/requests/recent", h.getRecentRequestsHandler).Methods("GET")
+	router.HandleFunc("/api/system/requests", h.getSystemRequestsHandler).Methods("GET")
+	router.HandleFunc("/metrics", h.prometheusMetricsHandler).Methods("GET")
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"recent requests without token", "/api/requests/recent", "", http.StatusUnauthorized},
+		{"recent requests as user", "/api/requests/recent", userToken, http.StatusOK},
+		{"system requests without token", "/api/system/requests", "", http.StatusUnauthorized},
+		{"system requests as user", "/api/system/requests", userToken, http.StatusForbidden},
+		{"system requests as admin", "/api/system/requests", adminToken, http.StatusOK},
+		{"metrics without token", "/metrics", "", http.StatusUnauthorized},
+		{"metrics with invalid token", "/metrics", "not-a-token", http.StatusUnauthorized},
+		{"metrics as user", "/metrics", userToken, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create request
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.want {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Package handlers - Per-service ulimits and JVM memory hints
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerResourceLimitRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/limits", h.getResourceLimitsHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/limits", h.setResourceLimitsHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/limits", h.removeResourceLimitsHandler).Methods("DELETE")
+}
+
+// getResourceLimitsHandler returns a service's ulimits and memory hints
+func (h *Handler) getResourceLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	limits, err := h.serviceManager.GetServiceResourceLimits(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get resource limits for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(limits)
+}
+
+// setResourceLimitsHandler replaces a service's ulimits and memory hints;
+// they apply from its next start
+func (h *Handler) setResourceLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var limits models.ServiceResourceLimits
+	if err := json.NewDecoder(r.Body).Decode(&limits); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	limits.ServiceID = serviceUUID
+
+	if err := h.serviceManager.SetServiceResourceLimits(limits); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			requestLogf(r, "[ERROR] Failed to save resource limits for service %s: %v", serviceUUID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	saved, err := h.serviceManager.GetServiceResourceLimits(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(saved)
+}
+
+// removeResourceLimitsHandler drops a service's ulimits and memory hints
+func (h *Handler) removeResourceLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if err := h.serviceManager.RemoveServiceResourceLimits(serviceUUID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to remove resource limits for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+// Package handlers - Restart statistics of services
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerRestartStatsRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/restarts", h.getServiceRestartStatsHandler).Methods("GET")
+	r.HandleFunc("/api/profiles/{id}/restart-leaderboard", h.getRestartLeaderboardHandler).Methods("GET")
+}
+
+// getServiceRestartStatsHandler returns how often a service restarted in the
+// last hour, day and week
+func (h *Handler) getServiceRestartStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if _, exists := h.serviceManager.GetServiceSnapshot(serviceUUID); !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	stats, err := h.serviceManager.GetRestartStats()
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get restart statistics: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	serviceStats, exists := stats[serviceUUID]
+	if !exists {
+		serviceStats = &models.RestartStats{}
+	}
+	json.NewEncoder(w).Encode(serviceStats)
+}
+
+// getRestartLeaderboardHandler ranks the services of a profile by their
+// restarts within ?window=1h|24h|7d, at most ?limit of them
+func (h *Handler) getRestartLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	profile, err := h.profileService.GetServiceProfile(mux.Vars(r)["id"], claims.UserID)
+	if err != nil {
+		http.Error(w, "Profile not found", http.StatusNotFound)
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	board, err := h.serviceManager.GetRestartLeaderboard(profile, r.URL.Query().Get("window"), limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "failed to") {
+			requestLogf(r, "[ERROR] Failed to rank restarts of profile %s: %v", profile.ID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(board)
+}
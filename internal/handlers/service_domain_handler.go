@@ -0,0 +1,120 @@
+// Package handlers - Per-service nginx subdomains
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func registerServiceDomainRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/domain", h.getServiceDomainHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/domain", h.setServiceDomainHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/domain", h.deleteServiceDomainHandler).Methods("DELETE")
+	r.HandleFunc("/api/domains/apply", h.applyServiceDomainsHandler).Methods("POST")
+}
+
+// serviceDomainResponse is a service's saved subdomain and the outcome of
+// regenerating vertex.conf, the certificate and the hosts file
+type serviceDomainResponse struct {
+	*models.ServiceDomainConfig
+	*services.ServiceDomainsResult
+}
+
+// getServiceDomainHandler returns a service's subdomain settings
+func (h *Handler) getServiceDomainHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	config, err := h.serviceManager.GetServiceDomainConfig(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get domain of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(config)
+}
+
+// setServiceDomainHandler enables, disables or renames a service's subdomain
+// and regenerates the service domains
+func (h *Handler) setServiceDomainHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var config models.ServiceDomainConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	config.ServiceID = serviceUUID
+
+	result, err := h.serviceManager.SetServiceDomainConfig(config)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to save domain of service %s: %v", serviceUUID, err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "already"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case strings.Contains(err.Error(), "failed to"):
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	saved, err := h.serviceManager.GetServiceDomainConfig(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(serviceDomainResponse{ServiceDomainConfig: saved, ServiceDomainsResult: result})
+}
+
+// deleteServiceDomainHandler removes a service's subdomain from nginx and
+// the hosts file
+func (h *Handler) deleteServiceDomainHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	result, err := h.serviceManager.RemoveServiceDomainConfig(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to remove domain of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// applyServiceDomainsHandler regenerates the service domains, e.g. after a
+// service's port changed
+func (h *Handler) applyServiceDomainsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	result, err := h.serviceManager.ApplyServiceDomains()
+	if err != nil {
+		requestLogf(r, "[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
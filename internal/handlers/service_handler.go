@@ -2,47 +2,85 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
 )
 
 func registerServiceRoutes(h *Handler, r *mux.Router) {
 	// Service CRUD operations (RESTful with UUIDs)
 	r.HandleFunc("/api/services", h.getServicesHandler).Methods("GET")
 	r.HandleFunc("/api/services", h.createServiceHandler).Methods("POST")
+	r.HandleFunc("/api/services/tags", h.getServiceTagIndexHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}", h.getServiceHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}", h.updateServiceHandler).Methods("PUT")
 	r.HandleFunc("/api/services/{id}", h.deleteServiceHandler).Methods("DELETE")
+	r.HandleFunc("/api/services/{id}/delete-impact", h.getDeletionImpactHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/clone", h.cloneServiceHandler).Methods("POST")
 
 	// Service operations (by UUID)
 	r.HandleFunc("/api/services/{id}/start", h.startServiceHandler).Methods("POST")
 	r.HandleFunc("/api/services/{id}/stop", h.stopServiceHandler).Methods("POST")
 	r.HandleFunc("/api/services/{id}/restart", h.restartServiceHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/pause", h.pauseServiceHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/resume", h.resumeServiceHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/operations", h.getServiceOperationsHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/archive", h.archiveServiceHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/unarchive", h.unarchiveServiceHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/health", h.getHealthHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/health", h.checkHealthHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/memory-admission", h.getMemoryAdmissionHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/preflight", h.getPreflightHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/crash-loop", h.getCrashLoopHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/crash-loop/reset", h.resetCrashLoopHandler).Methods("POST")
 	r.HandleFunc("/api/services/{id}/env-vars", h.getServiceEnvVarsHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/env-vars", h.updateServiceEnvVarsHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/env-vars/refresh", h.refreshServiceEnvVarsHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/effective-env", h.getEffectiveEnvHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/log-level", h.getServiceLogLevelHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/log-level", h.setServiceLogLevelHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/discovery-env", h.getDiscoveryEnvHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/install-libraries", h.installLibrariesHandler).Methods("POST")
 	r.HandleFunc("/api/services/{id}/libraries/preview", h.previewLibrariesHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/libraries/install", h.installSelectedLibrariesHandler).Methods("POST")
 	r.HandleFunc("/api/services/{id}/files", h.getServiceFilesHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/files/diff", h.getServiceFilesDiffHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/files/{filename}", h.updateServiceFileHandler).Methods("PUT")
 
 	r.HandleFunc("/api/services/start-all", h.startAllHandler).Methods("POST")
+	r.HandleFunc("/api/services/start-all/report", h.getStartupReportHandler).Methods("GET")
 	r.HandleFunc("/api/services/stop-all", h.stopAllHandler).Methods("POST")
 	r.HandleFunc("/api/services/{id}/port-cleanup", h.portCleanupHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/port-holders", h.getPortHoldersHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/logs", h.getLogsHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/logs", h.clearLogsHandler).Methods("DELETE")
+	r.HandleFunc("/api/services/{id}/errors", h.getServiceErrorsHandler).Methods("GET")
 	r.HandleFunc("/api/services/logs/clear", h.clearAllLogsHandler).Methods("DELETE")
 	r.HandleFunc("/api/services/{id}/metrics", h.getServiceMetricsHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/events", h.getServiceEventsHandler).Methods("GET")
+
+	r.HandleFunc("/api/services/{id}/hooks", h.getServiceHooksHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/hooks", h.updateServiceHooksHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/tags", h.updateServiceTagsHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/external-dependencies", h.getExternalDependenciesHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/external-dependencies", h.updateExternalDependenciesHandler).Methods("PUT")
+	r.HandleFunc("/api/services/{id}/external-dependencies/check", h.checkExternalDependenciesHandler).Methods("POST")
+
+	r.HandleFunc("/api/services/{id}/gateway/routes", h.getGatewayRoutesHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/gateway/test", h.testGatewayRouteHandler).Methods("POST")
 
 	r.HandleFunc("/api/services/{id}/wrapper/validate", h.validateWrapperHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/wrapper/generate", h.generateWrapperHandler).Methods("POST")
@@ -52,6 +90,9 @@ func registerServiceRoutes(h *Handler, r *mux.Router) {
 	r.HandleFunc("/api/services/{id}/git/info", h.getGitInfoHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/git/branches", h.getGitBranchesHandler).Methods("GET")
 	r.HandleFunc("/api/services/{id}/git/switch", h.switchGitBranchHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/git/stashes", h.getGitStashesHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/git/stashes/{commit}/apply", h.applyGitStashHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/git/stashes/{commit}", h.dropGitStashHandler).Methods("DELETE")
 
 	// Utility endpoints
 	r.HandleFunc("/api/services/available-for-profile", h.getAvailableServicesForProfileHandler).Methods("GET")
@@ -62,10 +103,76 @@ func (h *Handler) getServicesHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	services := h.serviceManager.GetServices()
+	query, err := parseServiceQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	services, err := h.serviceManager.QueryServices(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.isAnonymousViewer(r) {
+		for i := range services {
+			redactForAnonymous(services[i])
+		}
+	}
 	json.NewEncoder(w).Encode(services)
 }
 
+// parseServiceQuery reads the listing filters from the query string:
+// tag=key or tag=key:value (repeatable), status and buildSystem (comma separated),
+// minPort, maxPort, archived (include or only; archived services are hidden by default),
+// sort (order, name, status, port, buildSystem, tag:<key>) and order (asc/desc)
+func parseServiceQuery(r *http.Request) (services.ServiceQuery, error) {
+	return parseServiceQueryValues(r.URL.Query())
+}
+
+// parseServiceQueryValues reads the listing filters described at parseServiceQuery
+func parseServiceQueryValues(params url.Values) (services.ServiceQuery, error) {
+	query := services.ServiceQuery{
+		Tags:       make(map[string]string),
+		Archived:   params.Get("archived"),
+		SortBy:     params.Get("sort"),
+		Descending: strings.EqualFold(params.Get("order"), "desc"),
+	}
+
+	for _, tag := range params["tag"] {
+		key, value, _ := strings.Cut(tag, ":")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" {
+			return query, fmt.Errorf("invalid tag filter '%s'", tag)
+		}
+		query.Tags[key] = strings.TrimSpace(value)
+	}
+
+	splitList := func(value string) []string {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list
+	}
+	query.Statuses = splitList(params.Get("status"))
+	query.BuildSystems = splitList(params.Get("buildSystem"))
+
+	for name, target := range map[string]*int{"minPort": &query.MinPort, "maxPort": &query.MaxPort} {
+		if value := params.Get(name); value != "" {
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 0 {
+				return query, fmt.Errorf("invalid %s", name)
+			}
+			*target = port
+		}
+	}
+
+	return query, nil
+}
+
 func (h *Handler) getAvailableServicesForProfileHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -82,7 +189,7 @@ func (h *Handler) getAvailableServicesForProfileHandler(w http.ResponseWriter, r
 
 	userProfiles, err := h.profileService.GetServiceProfiles(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get user profiles: %v", err)
+		requestLogf(r, "[ERROR] Failed to get user profiles: %v", err)
 		http.Error(w, "Failed to get user profiles", http.StatusInternalServerError)
 		return
 	}
@@ -97,14 +204,14 @@ func (h *Handler) getAvailableServicesForProfileHandler(w http.ResponseWriter, r
 		}
 	}
 
-	var availableServices []models.Service
+	var availableServices []*models.Service
 	for _, service := range allServices {
 		if !assignedServices[service.ID] {
 			availableServices = append(availableServices, service)
 		}
 	}
 
-	log.Printf("[DEBUG] Total services: %d, Assigned services (excluding profile %s): %d, Available services: %d",
+	requestLogf(r, "[DEBUG] Total services: %d, Assigned services (excluding profile %s): %d, Available services: %d",
 		len(allServices), excludeProfileID, len(assignedServices), len(availableServices))
 
 	json.NewEncoder(w).Encode(availableServices)
@@ -132,12 +239,12 @@ func (h *Handler) startServiceHandler(w http.ResponseWriter, r *http.Request) {
 	globalConfig := h.serviceManager.GetConfig()
 	if projectsDir != globalConfig.ProjectsDir {
 		if err := h.serviceManager.StartServiceWithProjectsDir(serviceUUID, projectsDir); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeStartError(w, err)
 			return
 		}
 	} else {
 		if err := h.serviceManager.StartService(serviceUUID); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeStartError(w, err)
 			return
 		}
 	}
@@ -145,6 +252,42 @@ func (h *Handler) startServiceHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "started"})
 }
 
+// writeStartError reports a failed start; a start refused by the profile's
+// memory budget is a conflict carrying the services suggested to stop, and
+// one refused by a preflight check fails its precondition with the checks
+func writeStartError(w http.ResponseWriter, err error) {
+	if writeOperationConflict(w, err) {
+		return
+	}
+	var budgetErr *services.MemoryBudgetError
+	if errors.As(err, &budgetErr) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(budgetErr.Admission)
+		return
+	}
+	var preflightErr *services.PreflightError
+	if errors.As(err, &preflightErr) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		json.NewEncoder(w).Encode(preflightErr.Result)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// getPreflightHandler runs the pre-start checks of a service without starting it
+func (h *Handler) getPreflightHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	result, err := h.serviceManager.RunPreflight(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
 func (h *Handler) stopServiceHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	serviceUUID := vars["id"]
@@ -158,6 +301,9 @@ func (h *Handler) stopServiceHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	if err := h.serviceManager.StopService(serviceUUID); err != nil {
+		if writeOperationConflict(w, err) {
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -165,6 +311,36 @@ func (h *Handler) stopServiceHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
 }
 
+// writeOperationConflict answers 409 with the operation in progress when the
+// same operation was requested twice, and reports whether it did
+func writeOperationConflict(w http.ResponseWriter, err error) bool {
+	var conflictErr *services.OperationConflictError
+	if !errors.As(err, &conflictErr) {
+		return false
+	}
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":     conflictErr.Error(),
+		"operation": conflictErr.Operation,
+	})
+	return true
+}
+
+// getServiceOperationsHandler lists the lifecycle operations queued or
+// running for a service
+func (h *Handler) getServiceOperationsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	operations, err := h.serviceManager.GetServiceOperations(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(operations)
+}
+
 func (h *Handler) restartServiceHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	serviceUUID := vars["id"]
@@ -181,12 +357,12 @@ func (h *Handler) restartServiceHandler(w http.ResponseWriter, r *http.Request)
 	globalConfig := h.serviceManager.GetConfig()
 	if projectsDir != globalConfig.ProjectsDir {
 		if err := h.serviceManager.RestartServiceWithProjectsDir(serviceUUID, projectsDir); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeStartError(w, err)
 			return
 		}
 	} else {
 		if err := h.serviceManager.RestartService(serviceUUID); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeStartError(w, err)
 			return
 		}
 	}
@@ -194,6 +370,101 @@ func (h *Handler) restartServiceHandler(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(map[string]string{"status": "restarted"})
 }
 
+// pauseServiceHandler freezes a running service with SIGSTOP
+func (h *Handler) pauseServiceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if err := h.serviceManager.PauseService(mux.Vars(r)["id"]); err != nil {
+		writePauseError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": services.StatusPaused})
+}
+
+// resumeServiceHandler continues a paused service with SIGCONT
+func (h *Handler) resumeServiceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if err := h.serviceManager.ResumeService(mux.Vars(r)["id"]); err != nil {
+		writePauseError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "running"})
+}
+
+// writePauseError maps pause and resume failures to a status code
+func writePauseError(w http.ResponseWriter, err error) {
+	if writeOperationConflict(w, err) {
+		return
+	}
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "is not running"), strings.Contains(err.Error(), "is not paused"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// archiveServiceHandler stops a service and hides it from listings and
+// start-all while keeping its configuration
+func (h *Handler) archiveServiceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if err := h.serviceManager.ArchiveService(mux.Vars(r)["id"]); err != nil {
+		writeArchiveError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "archived"})
+}
+
+// unarchiveServiceHandler brings an archived service back
+func (h *Handler) unarchiveServiceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if err := h.serviceManager.UnarchiveService(mux.Vars(r)["id"]); err != nil {
+		writeArchiveError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "unarchived"})
+}
+
+// writeArchiveError maps archive and unarchive failures to a status code
+func writeArchiveError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "already archived"), strings.Contains(err.Error(), "is not archived"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// getHealthHandler returns a service's health and how often it changed lately
+func (h *Handler) getHealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	state, err := h.serviceManager.GetHealthFlapState(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(state)
+}
+
 func (h *Handler) checkHealthHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	serviceUUID := vars["id"]
@@ -220,7 +491,7 @@ func (h *Handler) createServiceHandler(w http.ResponseWriter, r *http.Request) {
 
 	var service models.Service
 	if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
-		log.Printf("[ERROR] Failed to decode request body: %v", err)
+		requestLogf(r, "[ERROR] Failed to decode request body: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -230,7 +501,8 @@ func (h *Handler) createServiceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if service.Dir == "" {
+	// Infrastructure services keep their data in the Vertex data directory
+	if service.Dir == "" && service.InfraType == "" {
 		http.Error(w, "Service directory is required", http.StatusBadRequest)
 		return
 	}
@@ -240,10 +512,6 @@ func (h *Handler) createServiceHandler(w http.ResponseWriter, r *http.Request) {
 		service.ID = uuid.New().String()
 	}
 
-	if service.Port == 0 {
-		service.Port = 8080
-	}
-
 	if service.BuildSystem == "" {
 		service.BuildSystem = "auto"
 	}
@@ -258,20 +526,28 @@ func (h *Handler) createServiceHandler(w http.ResponseWriter, r *http.Request) {
 		service.EnvVars = make(map[string]models.EnvVar)
 	}
 
-	log.Printf("[INFO] Creating new service: %s (UUID: %s)", service.Name, service.ID)
+	requestLogf(r, "[INFO] Creating new service: %s (UUID: %s)", service.Name, service.ID)
 
-	if err := h.serviceManager.AddService(&service); err != nil {
-		log.Printf("[ERROR] Failed to create service: %v", err)
+	// Without a port the service gets a free one from the pool of the
+	// profile it is created for
+	if err := h.serviceManager.AddServiceInProfile(&service, r.URL.Query().Get("profileId")); err != nil {
+		requestLogf(r, "[ERROR] Failed to create service: %v", err)
 		if strings.Contains(err.Error(), "already exists") {
 			http.Error(w, "Service with this UUID or path already exists", http.StatusConflict)
+		} else if strings.Contains(err.Error(), "exhausted") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if strings.Contains(err.Error(), "invalid owner") || strings.Contains(err.Error(), "invalid run-as user") || strings.Contains(err.Error(), "invalid execution mode") || strings.Contains(err.Error(), "invalid working directory") || strings.Contains(err.Error(), "invalid port") || strings.Contains(err.Error(), "invalid infrastructure") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 		} else {
 			http.Error(w, "Failed to create service", http.StatusInternalServerError)
 		}
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(service); err != nil {
-		log.Printf("[ERROR] Failed to encode response: %v", err)
+	if err := json.NewEncoder(w).Encode(&service); err != nil {
+		requestLogf(r, "[ERROR] Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -289,15 +565,18 @@ func (h *Handler) getServiceHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	service, exists := h.serviceManager.GetServiceByUUID(serviceUUID)
+	service, exists := h.serviceManager.GetServiceSnapshot(serviceUUID)
 	if !exists {
-		log.Printf("[ERROR] Service with UUID %s not found", serviceUUID)
+		requestLogf(r, "[ERROR] Service with UUID %s not found", serviceUUID)
 		http.Error(w, fmt.Sprintf("Service with UUID %s not found", serviceUUID), http.StatusNotFound)
 		return
 	}
+	if h.isAnonymousViewer(r) {
+		redactForAnonymous(service)
+	}
 
 	if err := json.NewEncoder(w).Encode(service); err != nil {
-		log.Printf("[ERROR] Failed to encode service response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode service response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -317,17 +596,17 @@ func (h *Handler) updateServiceHandler(w http.ResponseWriter, r *http.Request) {
 
 	var serviceConfig models.ServiceConfigRequest
 	if err := json.NewDecoder(r.Body).Decode(&serviceConfig); err != nil {
-		log.Printf("[ERROR] Failed to decode service config: %v", err)
+		requestLogf(r, "[ERROR] Failed to decode service config: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[DEBUG] Received service config for UUID %s: %+v", serviceUUID, serviceConfig)
+	requestLogf(r, "[DEBUG] Received service config for UUID %s: %+v", serviceUUID, serviceConfig)
 
 	if serviceConfig.ID != "" && serviceConfig.ID != serviceUUID {
-		log.Printf("[INFO] Renaming service UUID %s to %s", serviceUUID, serviceConfig.ID)
+		requestLogf(r, "[INFO] Renaming service UUID %s to %s", serviceUUID, serviceConfig.ID)
 		if err := h.serviceManager.RenameService(serviceUUID, serviceConfig.ID); err != nil {
-			log.Printf("[ERROR] Failed to rename service UUID %s to %s: %v", serviceUUID, serviceConfig.ID, err)
+			requestLogf(r, "[ERROR] Failed to rename service UUID %s to %s: %v", serviceUUID, serviceConfig.ID, err)
 			http.Error(w, fmt.Sprintf("Failed to rename service: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -337,7 +616,11 @@ func (h *Handler) updateServiceHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.serviceManager.UpdateService(&serviceConfig); err != nil {
-		log.Printf("[ERROR] Failed to update service UUID %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to update service UUID %s: %v", serviceUUID, err)
+		if strings.Contains(err.Error(), "invalid owner") || strings.Contains(err.Error(), "invalid run-as user") || strings.Contains(err.Error(), "invalid execution mode") || strings.Contains(err.Error(), "invalid working directory") || strings.Contains(err.Error(), "invalid port") || strings.Contains(err.Error(), "invalid infrastructure") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, fmt.Sprintf("Failed to update service: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -354,10 +637,37 @@ func (h *Handler) deleteServiceHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	log.Printf("[INFO] Delete service request for UUID: %s", serviceUUID)
+	requestLogf(r, "[INFO] Delete service request for UUID: %s", serviceUUID)
+
+	// A service that is referenced or has stored data is only deleted with the
+	// token of its current impact report
+	impact, err := h.serviceManager.GetServiceDeletionImpact(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to analyze deletion of service UUID %s: %v", serviceUUID, err)
+		http.Error(w, fmt.Sprintf("Failed to delete service: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !impact.Empty && r.URL.Query().Get("confirm") != impact.ConfirmationToken {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  "Deleting this service affects profiles, dependents or stored data; repeat the request with ?confirm=<confirmationToken>",
+			"impact": impact,
+		})
+		return
+	}
 
 	if err := h.serviceManager.DeleteService(serviceUUID); err != nil {
-		log.Printf("[ERROR] Failed to delete service UUID %s: %v", serviceUUID, err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to delete service UUID %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to delete service: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -370,14 +680,35 @@ func (h *Handler) deleteServiceHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// getDeletionImpactHandler reports what deleting a service affects, with the
+// confirmation token the delete request needs when that is anything
+func (h *Handler) getDeletionImpactHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	impact, err := h.serviceManager.GetServiceDeletionImpact(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to analyze deletion of service UUID %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(impact)
+}
+
 func (h *Handler) normalizeServiceOrderHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	log.Printf("[INFO] Normalizing service orders")
+	requestLogf(r, "[INFO] Normalizing service orders")
 
 	if err := h.serviceManager.NormalizeServiceOrders(); err != nil {
-		log.Printf("[ERROR] Failed to normalize service orders: %v", err)
+		requestLogf(r, "[ERROR] Failed to normalize service orders: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to normalize service orders: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -412,6 +743,32 @@ func (h *Handler) getServiceEnvVarsHandler(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(map[string]interface{}{"envVars": envVars})
 }
 
+// getEffectiveEnvHandler returns the environment a service would start with
+// right now and the source of each value; ?inherited=true also lists the
+// variables of Vertex's own environment
+func (h *Handler) getEffectiveEnvHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	inherited := r.URL.Query().Get("inherited") == "true"
+	env, err := h.serviceManager.GetEffectiveEnv(mux.Vars(r)["id"], inherited)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to resolve environment of service %s: %v", mux.Vars(r)["id"], err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(env)
+}
+
 func (h *Handler) updateServiceEnvVarsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	serviceUUID := vars["id"]
@@ -434,6 +791,10 @@ func (h *Handler) updateServiceEnvVarsHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := h.serviceManager.UpdateServiceEnvVars(serviceUUID, request.EnvVars); err != nil {
+		if strings.Contains(err.Error(), "invalid environment variables") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -441,6 +802,90 @@ func (h *Handler) updateServiceEnvVarsHandler(w http.ResponseWriter, r *http.Req
 	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 }
 
+// refreshServiceEnvVarsHandler saves a service's environment variables and
+// applies them to the running service without a restart where supported
+func (h *Handler) refreshServiceEnvVarsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var request struct {
+		EnvVars map[string]models.EnvVar `json:"envVars"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.serviceManager.RefreshServiceEnv(serviceUUID, request.EnvVars)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to refresh environment of service %s: %v", serviceUUID, err)
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else if strings.Contains(err.Error(), "invalid environment variables") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// getServiceLogLevelHandler returns the level of a service's logger, or its
+// LOG_LEVEL for non-Spring services
+func (h *Handler) getServiceLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	result, err := h.serviceManager.GetServiceLogLevel(serviceUUID, r.URL.Query().Get("logger"))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get log level of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// setServiceLogLevelHandler changes a service's log level through its
+// actuator, or its LOG_LEVEL for non-Spring services
+func (h *Handler) setServiceLogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var request services.LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.serviceManager.SetServiceLogLevel(serviceUUID, request)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to set log level of service %s: %v", serviceUUID, err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "failed to"):
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
 func (h *Handler) installLibrariesHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	serviceUUID := vars["id"]
@@ -463,11 +908,11 @@ func (h *Handler) installLibrariesHandler(w http.ResponseWriter, r *http.Request
 	// Get the correct projects directory using profile-aware logic
 	projectsDir := h.getServiceProjectsDir(serviceUUID)
 
-	log.Printf("[INFO] Installing libraries for service %s (auto-discovery from .gitlab-ci.yml) using projects dir: %s", serviceUUID, projectsDir)
+	requestLogf(r, "[INFO] Installing libraries for service %s (auto-discovery from .gitlab-ci.yml) using projects dir: %s", serviceUUID, projectsDir)
 
 	// Call InstallLibrariesWithProjectsDir to use the correct directory
 	if err := h.serviceManager.InstallLibrariesWithProjectsDir(serviceUUID, []models.LibraryInstallation{}, projectsDir); err != nil {
-		log.Printf("[ERROR] Failed to install libraries for service UUID %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to install libraries for service UUID %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to install libraries: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -494,7 +939,7 @@ func (h *Handler) startAllHandler(w http.ResponseWriter, r *http.Request) {
 	// Get user's active profile
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile for start all: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile for start all: %v", err)
 		// Fall back to global start all if no active profile
 		if err := h.serviceManager.StartAllServices(); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -529,6 +974,20 @@ func (h *Handler) startAllHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// getStartupReportHandler returns the latest profile start: the order the
+// services started in and how long each waited for its dependencies
+func (h *Handler) getStartupReportHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	report := h.serviceManager.GetStartupReport()
+	if report == nil {
+		http.Error(w, "No profile start has run yet", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
 func (h *Handler) stopAllHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -543,7 +1002,7 @@ func (h *Handler) stopAllHandler(w http.ResponseWriter, r *http.Request) {
 	// Get user's active profile
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile for stop all: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile for stop all: %v", err)
 		// Fall back to global stop all if no active profile
 		if err := h.serviceManager.StopAllServices(); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -572,6 +1031,9 @@ func (h *Handler) stopAllHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// portCleanupHandler frees a service's port. Processes Vertex did not start
+// are only killed with ?force=true (or {"force": true}); without it they are
+// listed and the response is 409.
 func (h *Handler) portCleanupHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	serviceUUID := vars["id"]
@@ -591,18 +1053,60 @@ func (h *Handler) portCleanupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var request struct {
+		Force bool `json:"force"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	force := request.Force || r.URL.Query().Get("force") == "true"
+
 	// Perform port cleanup
-	result := h.serviceManager.CleanupPort(service.Port)
+	result := h.serviceManager.CleanupPort(service.Port, force)
+
+	status := "port cleanup completed"
+	if result.ConfirmationRequired {
+		status = "confirmation required: some processes on the port were not started by Vertex"
+		w.WriteHeader(http.StatusConflict)
+	}
 
 	// Return detailed cleanup result
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":               status,
+		"port":                 result.Port,
+		"processesFound":       result.ProcessesFound,
+		"processesKilled":      result.ProcessesKilled,
+		"pids":                 result.PIDs,
+		"holders":              result.Holders,
+		"skipped":              result.Skipped,
+		"confirmationRequired": result.ConfirmationRequired,
+		"errors":               result.Errors,
+	})
+}
+
+// getPortHoldersHandler lists the processes listening on a service's port
+// without killing any
+func (h *Handler) getPortHoldersHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	service, exists := h.serviceManager.GetServiceByUUID(serviceUUID)
+	if !exists {
+		http.Error(w, fmt.Sprintf("Service '%s' not found", serviceUUID), http.StatusNotFound)
+		return
+	}
+	if service.Port <= 0 {
+		http.Error(w, "Service does not have a valid port configured", http.StatusBadRequest)
+		return
+	}
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":          "port cleanup completed",
-		"port":            result.Port,
-		"processesFound":  result.ProcessesFound,
-		"processesKilled": result.ProcessesKilled,
-		"pids":            result.PIDs,
-		"errors":          result.Errors,
+		"port":    service.Port,
+		"holders": h.serviceManager.FindPortHolders(service.Port),
 	})
 }
 
@@ -613,33 +1117,35 @@ func (h *Handler) getLogsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Check authentication
+	// Check authentication; anonymous viewers see the logs of every service
 	claims, ok := extractClaimsFromRequest(r, h.authService)
-	if !ok || claims == nil {
+	if (!ok || claims == nil) && !h.authService.AnonymousReadOnly() {
 		http.Error(w, "Authentication required", http.StatusUnauthorized)
 		return
 	}
 
-	// Get user's active profile
-	profile, err := h.profileService.GetActiveProfile(claims.UserID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile for logs: %v", err)
-		http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
-		return
-	}
-
-	// Check if the service belongs to the current profile
-	serviceInProfile := false
-	for _, profileServiceID := range profile.Services {
-		if profileServiceID == serviceUUID {
-			serviceInProfile = true
-			break
+	if ok && claims != nil {
+		// Get user's active profile
+		profile, err := h.profileService.GetActiveProfile(claims.UserID)
+		if err != nil {
+			requestLogf(r, "[ERROR] Failed to get active profile for logs: %v", err)
+			http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
+			return
 		}
-	}
 
-	if !serviceInProfile {
-		http.Error(w, "Service not found in current profile", http.StatusForbidden)
-		return
+		// Check if the service belongs to the current profile
+		serviceInProfile := false
+		for _, profileServiceID := range profile.Services {
+			if profileServiceID == serviceUUID {
+				serviceInProfile = true
+				break
+			}
+		}
+
+		if !serviceInProfile {
+			http.Error(w, "Service not found in current profile", http.StatusForbidden)
+			return
+		}
 	}
 
 	service, exists := h.serviceManager.GetServiceByUUID(serviceUUID)
@@ -672,7 +1178,7 @@ func (h *Handler) clearLogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get user's active profile
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile for clear logs: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile for clear logs: %v", err)
 		http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
 		return
 	}
@@ -722,7 +1228,7 @@ func (h *Handler) clearAllLogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get user's active profile
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile for clear all logs: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile for clear all logs: %v", err)
 		http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
 		return
 	}
@@ -816,7 +1322,24 @@ func (h *Handler) getServiceMetricsHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	profileID, allowed := h.requestProfileScope(r, serviceUUID)
+	if !allowed {
+		// Services outside the profile are not revealed to exist
+		http.Error(w, fmt.Sprintf("Service '%s' not found", serviceUUID), http.StatusNotFound)
+		return
+	}
+
+	processes := h.serviceManager.GetServiceProcesses(serviceUUID)
+	if h.isAnonymousViewer(r) {
+		// Command lines can carry credentials, such as -D system properties
+		for i := range processes {
+			processes[i].Command = ""
+		}
+	}
+
 	service.Mutex.RLock()
+	serviceMetrics := service.Metrics
+	serviceMetrics.UptimeStats = services.GetUptimeTracker().CalculateUptimeStats(profileID, service.ID)
 	metrics := map[string]interface{}{
 		"serviceName":   service.Name,
 		"cpuPercent":    service.CPUPercent,
@@ -825,14 +1348,31 @@ func (h *Handler) getServiceMetricsHandler(w http.ResponseWriter, r *http.Reques
 		"diskUsage":     service.DiskUsage,
 		"networkRx":     service.NetworkRx,
 		"networkTx":     service.NetworkTx,
-		"metrics":       service.Metrics,
+		"metrics":       serviceMetrics,
 		"status":        service.Status,
 		"healthStatus":  service.HealthStatus,
 		"pid":           service.PID,
+		"processes":     processes, // Per-process breakdown of the totals above
 		"uptime":        service.Uptime,
 		"lastStarted":   service.LastStarted,
 		"timestamp":     time.Now(),
 	}
+
+	// Runtime metrics belong to whichever profile started the process; hide
+	// them from users whose active profile did not
+	if profileID != "" && service.ProfileID != profileID {
+		metrics["cpuPercent"] = 0
+		metrics["memoryUsage"] = 0
+		metrics["memoryPercent"] = 0
+		metrics["diskUsage"] = 0
+		metrics["networkRx"] = 0
+		metrics["networkTx"] = 0
+		metrics["processes"] = []models.ProcessUsage{}
+		serviceMetrics.ResponseTimes = nil
+		serviceMetrics.RequestCount = 0
+		serviceMetrics.ErrorRate = 0
+		metrics["metrics"] = serviceMetrics
+	}
 	service.Mutex.RUnlock()
 
 	json.NewEncoder(w).Encode(metrics)
@@ -851,16 +1391,16 @@ func (h *Handler) getServiceFilesHandler(w http.ResponseWriter, r *http.Request)
 	if ok && claims != nil {
 		// User is authenticated, use profile-aware directory lookup
 		projectsDir = h.getServiceProjectsDirForUser(serviceUUID, claims.UserID)
-		log.Printf("[INFO] Loading files for service %s from projects directory: %s (user: %s)", serviceUUID, projectsDir, claims.UserID)
+		requestLogf(r, "[INFO] Loading files for service %s from projects directory: %s (user: %s)", serviceUUID, projectsDir, claims.UserID)
 	} else {
 		// User not authenticated or no valid token, use global logic
 		projectsDir = h.getServiceProjectsDir(serviceUUID)
-		log.Printf("[INFO] Loading files for service %s from projects directory: %s (no auth)", serviceUUID, projectsDir)
+		requestLogf(r, "[INFO] Loading files for service %s from projects directory: %s (no auth)", serviceUUID, projectsDir)
 	}
 
 	files, err := h.serviceManager.GetServiceFilesWithProjectsDir(serviceUUID, projectsDir)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get service files for %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to get service files for %s: %v", serviceUUID, err)
 		// Return a JSON error response instead of plain text
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]any{
@@ -871,10 +1411,41 @@ func (h *Handler) getServiceFilesHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	log.Printf("[INFO] Found %d files for service %s", len(files), serviceUUID)
+	requestLogf(r, "[INFO] Found %d files for service %s", len(files), serviceUUID)
 	json.NewEncoder(w).Encode(map[string]any{"files": files})
 }
 
+// getServiceFilesDiffHandler compares a service's configuration files with the versions committed in git
+func (h *Handler) getServiceFilesDiffHandler(w http.ResponseWriter, r *http.Request) {
+	serviceUUID := mux.Vars(r)["id"]
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	var projectsDir string
+	if ok && claims != nil {
+		projectsDir = h.getServiceProjectsDirForUser(serviceUUID, claims.UserID)
+	} else {
+		projectsDir = h.getServiceProjectsDir(serviceUUID)
+	}
+
+	modifiedOnly := r.URL.Query().Get("modifiedOnly") == "true"
+
+	diffs, err := h.serviceManager.GetServiceFileDiffsWithProjectsDir(serviceUUID, projectsDir, modifiedOnly)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to diff service files for %s: %v", serviceUUID, err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]any{
+			"error":   err.Error(),
+			"service": serviceUUID,
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"files": diffs})
+}
+
 func (h *Handler) updateServiceFileHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	serviceUUID := vars["id"]
@@ -898,11 +1469,11 @@ func (h *Handler) updateServiceFileHandler(w http.ResponseWriter, r *http.Reques
 	if ok && claims != nil {
 		// User is authenticated, use profile-aware directory lookup
 		projectsDir = h.getServiceProjectsDirForUser(serviceUUID, claims.UserID)
-		log.Printf("[INFO] Updating file for service %s from projects directory: %s (user: %s)", serviceUUID, projectsDir, claims.UserID)
+		requestLogf(r, "[INFO] Updating file for service %s from projects directory: %s (user: %s)", serviceUUID, projectsDir, claims.UserID)
 	} else {
 		// User not authenticated or no valid token, use global logic
 		projectsDir = h.getServiceProjectsDir(serviceUUID)
-		log.Printf("[INFO] Updating file for service %s from projects directory: %s (no auth)", serviceUUID, projectsDir)
+		requestLogf(r, "[INFO] Updating file for service %s from projects directory: %s (no auth)", serviceUUID, projectsDir)
 	}
 
 	if err := h.serviceManager.UpdateServiceFileWithProjectsDir(serviceUUID, filename, request.Content, projectsDir); err != nil {
@@ -936,12 +1507,12 @@ func (h *Handler) previewLibrariesHandler(w http.ResponseWriter, r *http.Request
 	// Get the correct projects directory using profile-aware logic
 	projectsDir := h.getServiceProjectsDir(serviceUUID)
 
-	log.Printf("[INFO] Previewing libraries for service %s using projects dir: %s", serviceUUID, projectsDir)
+	requestLogf(r, "[INFO] Previewing libraries for service %s using projects dir: %s", serviceUUID, projectsDir)
 
 	// Get library preview
 	preview, err := h.serviceManager.PreviewLibraryInstallation(serviceUUID, projectsDir)
 	if err != nil {
-		log.Printf("[ERROR] Failed to preview libraries for service UUID %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to preview libraries for service UUID %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to preview libraries: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -971,7 +1542,7 @@ func (h *Handler) installSelectedLibrariesHandler(w http.ResponseWriter, r *http
 
 	var request models.LibraryInstallRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		log.Printf("[ERROR] Failed to decode request body for service %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to decode request body for service %s: %v", serviceUUID, err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -989,13 +1560,13 @@ func (h *Handler) installSelectedLibrariesHandler(w http.ResponseWriter, r *http
 	// Get the correct projects directory using profile-aware logic
 	projectsDir := h.getServiceProjectsDir(serviceUUID)
 
-	log.Printf("[INFO] Installing libraries for service %s in environments %v using projects dir: %s",
+	requestLogf(r, "[INFO] Installing libraries for service %s in environments %v using projects dir: %s",
 		serviceUUID, request.Environments, projectsDir)
 
 	// Get library preview to understand what needs to be installed
 	preview, err := h.serviceManager.PreviewLibraryInstallation(serviceUUID, projectsDir)
 	if err != nil {
-		log.Printf("[ERROR] Failed to preview libraries for service UUID %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to preview libraries for service UUID %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to preview libraries: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -1021,12 +1592,12 @@ func (h *Handler) installSelectedLibrariesHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	log.Printf("[INFO] Installing %d libraries for service %s from %d environments",
+	requestLogf(r, "[INFO] Installing %d libraries for service %s from %d environments",
 		len(librariesToInstall), serviceUUID, len(request.Environments))
 
 	// Install the selected libraries
 	if err := h.serviceManager.InstallLibrariesWithProjectsDir(serviceUUID, librariesToInstall, projectsDir); err != nil {
-		log.Printf("[ERROR] Failed to install libraries for service UUID %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to install libraries for service UUID %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to install libraries: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -1067,7 +1638,7 @@ func (h *Handler) validateWrapperHandler(w http.ResponseWriter, r *http.Request)
 	projectsDir := h.getServiceProjectsDir(serviceUUID)
 	serviceDir := fmt.Sprintf("%s/%s", projectsDir, service.Dir)
 
-	log.Printf("[INFO] Validating wrapper for service %s in directory: %s", service.Name, serviceDir)
+	requestLogf(r, "[INFO] Validating wrapper for service %s in directory: %s", service.Name, serviceDir)
 
 	// Import the services package to access build system functions
 	buildSystem := h.serviceManager.DetectBuildSystem(serviceDir)
@@ -1085,9 +1656,9 @@ func (h *Handler) validateWrapperHandler(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		response["error"] = err.Error()
 		response["isValid"] = false
-		log.Printf("[WARN] Wrapper validation failed for service %s: %v", service.Name, err)
+		requestLogf(r, "[WARN] Wrapper validation failed for service %s: %v", service.Name, err)
 	} else {
-		log.Printf("[INFO] Wrapper validation successful for service %s", service.Name)
+		requestLogf(r, "[INFO] Wrapper validation successful for service %s", service.Name)
 	}
 
 	// Check which wrapper files exist
@@ -1131,9 +1702,9 @@ func (h *Handler) generateWrapperHandler(w http.ResponseWriter, r *http.Request)
 
 	// Log the PATH environment variable
 	path := os.Getenv("PATH")
-	log.Printf("[DEBUG] PATH environment variable: %s", path)
+	requestLogf(r, "[DEBUG] PATH environment variable: %s", path)
 
-	log.Printf("[INFO] Generating wrapper for service %s in directory: %s", service.Name, serviceDir)
+	requestLogf(r, "[INFO] Generating wrapper for service %s in directory: %s", service.Name, serviceDir)
 
 	buildSystem := h.serviceManager.DetectBuildSystem(serviceDir)
 	var err error
@@ -1142,7 +1713,7 @@ func (h *Handler) generateWrapperHandler(w http.ResponseWriter, r *http.Request)
 	case "maven":
 		// Check if mvn is in PATH
 		if _, err := exec.LookPath("mvn"); err != nil {
-			log.Printf("[ERROR] mvn not found in PATH: %v", err)
+			requestLogf(r, "[ERROR] mvn not found in PATH: %v", err)
 			http.Error(w, "Maven (mvn) not found in PATH", http.StatusInternalServerError)
 			return
 		}
@@ -1154,7 +1725,7 @@ func (h *Handler) generateWrapperHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err != nil {
-		log.Printf("[ERROR] Failed to generate wrapper for service %s: %v", service.Name, err)
+		requestLogf(r, "[ERROR] Failed to generate wrapper for service %s: %v", service.Name, err)
 		response := map[string]interface{}{
 			"status":      "error",
 			"message":     fmt.Sprintf("Failed to generate wrapper: %v", err),
@@ -1167,7 +1738,7 @@ func (h *Handler) generateWrapperHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	log.Printf("[INFO] Successfully generated %s wrapper for service %s", buildSystem, service.Name)
+	requestLogf(r, "[INFO] Successfully generated %s wrapper for service %s", buildSystem, service.Name)
 
 	response := map[string]interface{}{
 		"status":      "success",
@@ -1204,12 +1775,12 @@ func (h *Handler) repairWrapperHandler(w http.ResponseWriter, r *http.Request) {
 	projectsDir := h.getServiceProjectsDir(serviceUUID)
 	serviceDir := fmt.Sprintf("%s/%s", projectsDir, service.Dir)
 
-	log.Printf("[INFO] Repairing wrapper for service %s in directory: %s", service.Name, serviceDir)
+	requestLogf(r, "[INFO] Repairing wrapper for service %s in directory: %s", service.Name, serviceDir)
 
 	// Use the RepairWrapper function which detects build system and repairs accordingly
 	err := h.serviceManager.RepairWrapper(serviceDir)
 	if err != nil {
-		log.Printf("[ERROR] Failed to repair wrapper for service %s: %v", service.Name, err)
+		requestLogf(r, "[ERROR] Failed to repair wrapper for service %s: %v", service.Name, err)
 		response := map[string]interface{}{
 			"status":      "error",
 			"message":     fmt.Sprintf("Failed to repair wrapper: %v", err),
@@ -1222,7 +1793,7 @@ func (h *Handler) repairWrapperHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	buildSystem := h.serviceManager.DetectBuildSystem(serviceDir)
-	log.Printf("[INFO] Successfully repaired %s wrapper for service %s", buildSystem, service.Name)
+	requestLogf(r, "[INFO] Successfully repaired %s wrapper for service %s", buildSystem, service.Name)
 
 	response := map[string]interface{}{
 		"status":      "success",
@@ -1256,7 +1827,7 @@ func (h *Handler) getGitInfoHandler(w http.ResponseWriter, r *http.Request) {
 
 	gitInfo, err := h.serviceManager.GetGitInfo(serviceUUID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get git info for service %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to get git info for service %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to get git info: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -1284,7 +1855,7 @@ func (h *Handler) getGitBranchesHandler(w http.ResponseWriter, r *http.Request)
 
 	branches, err := h.serviceManager.GetGitBranches(serviceUUID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get git branches for service %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to get git branches for service %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to get git branches: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -1313,7 +1884,8 @@ func (h *Handler) switchGitBranchHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	var req struct {
-		Branch string `json:"branch"`
+		Branch    string `json:"branch"`
+		AutoStash *bool  `json:"autoStash"` // Stash uncommitted changes instead of refusing; on unless false
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -1326,9 +1898,9 @@ func (h *Handler) switchGitBranchHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := h.serviceManager.SwitchGitBranch(serviceUUID, req.Branch)
+	stash, err := h.serviceManager.SwitchGitBranch(serviceUUID, req.Branch, req.AutoStash == nil || *req.AutoStash)
 	if err != nil {
-		log.Printf("[ERROR] Failed to switch git branch for service %s: %v", serviceUUID, err)
+		requestLogf(r, "[ERROR] Failed to switch git branch for service %s: %v", serviceUUID, err)
 		http.Error(w, fmt.Sprintf("Failed to switch branch: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -1337,5 +1909,450 @@ func (h *Handler) switchGitBranchHandler(w http.ResponseWriter, r *http.Request)
 		"status":  "success",
 		"branch":  req.Branch,
 		"message": fmt.Sprintf("Successfully switched to branch '%s'", req.Branch),
+		"stash":   stash,
 	})
 }
+
+// getGitStashesHandler lists the stashes Vertex made of a service's
+// uncommitted changes when switching branches, newest first
+func (h *Handler) getGitStashesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	stashes, err := h.serviceManager.GetServiceStashes(mux.Vars(r)["id"])
+	if err != nil {
+		writeGitStashError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(stashes)
+}
+
+// applyGitStashHandler puts a Vertex stash back into a service's clean
+// working tree and drops it
+func (h *Handler) applyGitStashHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	vars := mux.Vars(r)
+	stash, err := h.serviceManager.ApplyServiceStash(vars["id"], vars["commit"])
+	if err != nil {
+		writeGitStashError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(stash)
+}
+
+// dropGitStashHandler deletes a Vertex stash without applying it
+func (h *Handler) dropGitStashHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	vars := mux.Vars(r)
+	if err := h.serviceManager.DropServiceStash(vars["id"], vars["commit"]); err != nil {
+		writeGitStashError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Stash dropped"})
+}
+
+// writeGitStashError maps a stash error to its status code
+func writeGitStashError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "uncommitted changes"), strings.Contains(err.Error(), "does not apply cleanly"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "not a git repository"):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("[ERROR] Git stash request failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// getServiceHooksHandler returns the lifecycle hooks configured for a service
+func (h *Handler) getServiceHooksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	hooks, err := h.serviceManager.GetServiceHooks(serviceUUID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get hooks for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(hooks)
+}
+
+// updateServiceHooksHandler replaces the lifecycle hooks of a service
+func (h *Handler) updateServiceHooksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var hooks []models.ServiceHook
+	if err := json.NewDecoder(r.Body).Decode(&hooks); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.UpdateServiceHooks(serviceUUID, hooks); err != nil {
+		requestLogf(r, "[ERROR] Failed to update hooks for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.serviceManager.GetServiceHooks(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// getServiceTagIndexHandler lists every tag key in use with its values, for building filters
+func (h *Handler) getServiceTagIndexHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	json.NewEncoder(w).Encode(h.serviceManager.GetTagIndex())
+}
+
+// updateServiceTagsHandler replaces the tags of a service
+func (h *Handler) updateServiceTagsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var tags map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&tags); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.UpdateServiceTags(serviceUUID, tags); err != nil {
+		requestLogf(r, "[ERROR] Failed to update tags for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	service, _ := h.serviceManager.GetServiceByUUID(serviceUUID)
+	service.Mutex.RLock()
+	defer service.Mutex.RUnlock()
+	json.NewEncoder(w).Encode(service.Tags)
+}
+
+// getCrashLoopHandler returns the crash loop alert of a quarantined service
+func (h *Handler) getCrashLoopHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if _, exists := h.serviceManager.GetServiceByUUID(serviceUUID); !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	alert := h.serviceManager.GetCrashLoopAlert(serviceUUID)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"crashLooping": alert != nil,
+		"alert":        alert,
+	})
+}
+
+// resetCrashLoopHandler clears the crash-looping state so the service can be started again
+func (h *Handler) resetCrashLoopHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if err := h.serviceManager.ResetCrashLoop(serviceUUID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "reset"})
+}
+
+// getExternalDependenciesHandler returns the external resources a service depends on
+func (h *Handler) getExternalDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	dependencies, err := h.serviceManager.GetExternalDependencies(serviceUUID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get external dependencies for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(dependencies)
+}
+
+// getDiscoveryEnvHandler returns the service discovery variables a service receives on start
+func (h *Handler) getDiscoveryEnvHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	env, err := h.serviceManager.GetDiscoveryEnv(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(env)
+}
+
+// updateExternalDependenciesHandler replaces the external dependencies of a service
+func (h *Handler) updateExternalDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var dependencies []models.ExternalDependency
+	if err := json.NewDecoder(r.Body).Decode(&dependencies); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceManager.UpdateExternalDependencies(serviceUUID, dependencies); err != nil {
+		requestLogf(r, "[ERROR] Failed to update external dependencies for service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.serviceManager.GetExternalDependencies(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// checkExternalDependenciesHandler probes the external dependencies of a service now
+func (h *Handler) checkExternalDependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	results, err := h.serviceManager.CheckExternalDependencies(serviceUUID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(results)
+}
+
+// parseEventQuery reads ?since= (RFC3339 timestamp or a duration such as 24h,
+// default 7 days) and ?limit= (default 200, max 1000)
+func parseEventQuery(r *http.Request) (time.Time, int, error) {
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	if value := r.URL.Query().Get("since"); value != "" {
+		if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+			since = parsed
+		} else if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			since = time.Now().Add(-duration)
+		} else {
+			return since, 0, fmt.Errorf("invalid since: use an RFC3339 timestamp or a duration like 24h")
+		}
+	}
+
+	limit := 200
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return since, 0, fmt.Errorf("invalid limit")
+		}
+		limit = min(parsed, 1000)
+	}
+
+	return since, limit, nil
+}
+
+// getServiceEventsHandler returns the event timeline of a service, newest first
+func (h *Handler) getServiceEventsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if _, exists := h.serviceManager.GetServiceByUUID(serviceUUID); !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	since, limit, err := parseEventQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.serviceManager.GetServiceEvents([]string{serviceUUID}, since, limit)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get events for service %s: %v", serviceUUID, err)
+		http.Error(w, "Failed to get service events", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(events)
+}
+
+// cloneServiceHandler copies a service definition under a new name, optionally
+// scaffolding a renamed copy of its directory. The clone joins the caller's
+// active profile when the source is part of it.
+func (h *Handler) cloneServiceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if _, exists := h.serviceManager.GetServiceByUUID(serviceUUID); !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	var req services.CloneServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, authenticated := extractClaimsFromRequest(r, h.authService)
+	var projectsDir string
+	if authenticated {
+		projectsDir = h.getServiceProjectsDirForUser(serviceUUID, claims.UserID)
+	} else {
+		projectsDir = h.getServiceProjectsDir(serviceUUID)
+	}
+
+	result, err := h.serviceManager.CloneService(serviceUUID, projectsDir, req)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to clone service %s: %v", serviceUUID, err)
+		if strings.Contains(err.Error(), "already exists") {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	if authenticated {
+		if activeProfile, err := h.profileService.GetActiveProfile(claims.UserID); err == nil && activeProfile != nil &&
+			slices.Contains(activeProfile.Services, serviceUUID) {
+			if err := h.profileService.AddServiceToProfile(claims.UserID, activeProfile.ID, result.Service.ID); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to add clone to profile %s: %v", activeProfile.Name, err))
+			}
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// getGatewayRoutesHandler lists a gateway service's routes and the services they target
+func (h *Handler) getGatewayRoutesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if _, exists := h.serviceManager.GetServiceByUUID(serviceUUID); !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	routes, err := h.serviceManager.GetGatewayRoutes(serviceUUID, h.requestProjectsDir(r, serviceUUID))
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to read gateway routes of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	json.NewEncoder(w).Encode(routes)
+}
+
+// testGatewayRouteHandler sends a request through a gateway service and
+// reports the matched route with downstream and upstream timings
+func (h *Handler) testGatewayRouteHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	service, exists := h.serviceManager.GetServiceByUUID(serviceUUID)
+	if !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	var req services.GatewayTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	service.Mutex.RLock()
+	running := service.Status == "running"
+	service.Mutex.RUnlock()
+	if !running {
+		http.Error(w, "Gateway service is not running", http.StatusConflict)
+		return
+	}
+
+	result, err := h.serviceManager.TestGatewayRoute(serviceUUID, h.requestProjectsDir(r, serviceUUID), req)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to test gateway route of service %s: %v", serviceUUID, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// getMemoryAdmissionHandler previews whether starting a service fits its profile's memory budget
+func (h *Handler) getMemoryAdmissionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	admission, err := h.serviceManager.CheckMemoryAdmission(serviceUUID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	json.NewEncoder(w).Encode(admission)
+}
+
+// getServiceErrorsHandler returns a service's errors deduplicated into clusters
+// with counts and first/last seen times
+func (h *Handler) getServiceErrorsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	clusters, exists := h.serviceManager.GetErrorClusters(serviceUUID)
+	if !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(clusters)
+}
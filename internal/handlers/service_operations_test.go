@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func TestWriteOperationConflict(t *testing.T) {
+	// Create a conflict wrapped the way callers return it
+	conflict := &services.OperationConflictError{Operation: models.ServiceOperation{
+		ID:          7,
+		ServiceID:   "test-service-1",
+		ServiceName: "Test Service 1",
+		Name:        "restart",
+		State:       models.OperationQueued,
+	}}
+	rr := httptest.NewRecorder()
+
+	if !writeOperationConflict(rr, fmt.Errorf("restart failed: %w", conflict)) {
+		t.Fatal("Expected the conflict to be written")
+	}
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("Handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+	}
+
+	var response struct {
+		Error     string                  `json:"error"`
+		Operation models.ServiceOperation `json:"operation"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error != "restart of Test Service 1 is already queued" {
+		t.Errorf("Unexpected error message: got %q", response.Error)
+	}
+	if response.Operation.ID != 7 || response.Operation.Name != "restart" {
+		t.Errorf("Unexpected operation: got %+v", response.Operation)
+	}
+}
+
+func TestWriteOperationConflict_OtherError(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	if writeOperationConflict(rr, errors.New("port in use")) {
+		t.Error("Expected other errors to be left to the caller")
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected nothing to be written, got %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,117 @@
+// Package handlers - Input sent to the stdin of interactive services
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+func registerServiceStdinRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/stdin", h.sendServiceInputHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/stdin/ws", h.serviceInputWebSocketHandler).Methods("GET")
+}
+
+// serviceInputRequest is input for the stdin of an interactive service
+type serviceInputRequest struct {
+	Input string `json:"input"` // Sent as one line; a newline is added if missing
+}
+
+// serviceInputStatus maps an input error to its status code; a service that
+// is not interactive, not running or paused is a conflict with its current
+// state, and one that stopped reading its input is unavailable
+func serviceInputStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "not interactive") || strings.Contains(err.Error(), "not running") ||
+		strings.Contains(err.Error(), "paused") || strings.Contains(err.Error(), "restart it"):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "its input"):
+		return http.StatusServiceUnavailable
+	case strings.Contains(err.Error(), "failed to"):
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// sendServiceInputHandler writes a line to the stdin of a running
+// interactive service; its output follows in the service's logs
+func (h *Handler) sendServiceInputHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if claims, ok := extractClaimsFromRequest(r, h.authService); !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	var request serviceInputRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	serviceUUID := mux.Vars(r)["id"]
+	if err := h.serviceManager.SendServiceInput(serviceUUID, request.Input); err != nil {
+		status := serviceInputStatus(err)
+		if status == http.StatusInternalServerError {
+			requestLogf(r, "[ERROR] %v", err)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "sent"})
+}
+
+// serviceInputWebSocketHandler keeps a channel open for typing into an
+// interactive service: each text message is sent to its stdin as one line and
+// answered with {"status": "sent"} or {"error": "..."}. Browsers cannot set
+// headers on a WebSocket, so the token may also be passed as ?token=.
+func (h *Handler) serviceInputWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if token := r.URL.Query().Get("token"); !ok && token != "" {
+		if tokenClaims, err := h.authService.ValidateToken(token); err == nil {
+			claims, ok = tokenClaims, true
+		}
+	}
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	serviceUUID := mux.Vars(r)["id"]
+	if _, exists := h.serviceManager.GetServiceByUUID(serviceUUID); !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+
+		reply := map[string]string{"status": "sent"}
+		if err := h.serviceManager.SendServiceInput(serviceUUID, string(data)); err != nil {
+			reply = map[string]string{"error": err.Error()}
+		}
+		if err := conn.WriteJSON(reply); err != nil {
+			return
+		}
+	}
+}
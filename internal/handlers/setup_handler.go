@@ -0,0 +1,59 @@
+// Package handlers - First-run setup wizard
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/services"
+)
+
+func registerSetupRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/setup/status", h.getSetupStatusHandler).Methods("GET")
+	r.HandleFunc("/api/setup/complete", h.completeSetupHandler).Methods("POST")
+}
+
+// getSetupStatusHandler tells the UI whether to show the setup wizard. It is
+// public because no user exists yet on a fresh database.
+func (h *Handler) getSetupStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	status, err := h.setupService.Status()
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get setup status: %v", err)
+		http.Error(w, "Failed to get setup status", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(status)
+}
+
+// completeSetupHandler creates the admin user, saves the projects directory,
+// optionally imports discovered services and creates the first profile. It
+// is refused once setup has completed.
+func (h *Handler) completeSetupHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var req services.SetupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.setupService.Complete(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrSetupCompleted) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		requestLogf(r, "[ERROR] Setup failed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
@@ -4,7 +4,6 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"sort"
 	"time"
@@ -34,13 +33,13 @@ func (h *Handler) getTopologyHandler(w http.ResponseWriter, r *http.Request) {
 		// For backward compatibility, fall back to global topology if not authenticated
 		topology, err := h.topologyService.GenerateTopology()
 		if err != nil {
-			log.Printf("Failed to generate topology: %v", err)
+			requestLogf(r, "Failed to generate topology: %v", err)
 			http.Error(w, "Failed to generate topology", http.StatusInternalServerError)
 			return
 		}
 
 		if err := json.NewEncoder(w).Encode(topology); err != nil {
-			log.Printf("Failed to encode topology: %v", err)
+			requestLogf(r, "Failed to encode topology: %v", err)
 			http.Error(w, "Failed to encode topology", http.StatusInternalServerError)
 			return
 		}
@@ -50,17 +49,17 @@ func (h *Handler) getTopologyHandler(w http.ResponseWriter, r *http.Request) {
 	// Get user's active profile
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[INFO] No active profile found for topology, using global view: %v", err)
+		requestLogf(r, "[INFO] No active profile found for topology, using global view: %v", err)
 		// Fall back to global topology if no active profile
 		topology, err := h.topologyService.GenerateTopology()
 		if err != nil {
-			log.Printf("Failed to generate topology: %v", err)
+			requestLogf(r, "Failed to generate topology: %v", err)
 			http.Error(w, "Failed to generate topology", http.StatusInternalServerError)
 			return
 		}
 
 		if err := json.NewEncoder(w).Encode(topology); err != nil {
-			log.Printf("Failed to encode topology: %v", err)
+			requestLogf(r, "Failed to encode topology: %v", err)
 			http.Error(w, "Failed to encode topology", http.StatusInternalServerError)
 			return
 		}
@@ -77,13 +76,13 @@ func (h *Handler) getTopologyHandler(w http.ResponseWriter, r *http.Request) {
 	// Generate topology for the active profile
 	topology, err := h.topologyService.GenerateTopologyForProfile(string(servicesJSON))
 	if err != nil {
-		log.Printf("Failed to generate profile topology: %v", err)
+		requestLogf(r, "Failed to generate profile topology: %v", err)
 		http.Error(w, "Failed to generate topology", http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(topology); err != nil {
-		log.Printf("Failed to encode topology: %v", err)
+		requestLogf(r, "Failed to encode topology: %v", err)
 		http.Error(w, "Failed to encode topology", http.StatusInternalServerError)
 		return
 	}
@@ -130,7 +129,7 @@ func (h *Handler) getDependenciesHandler(w http.ResponseWriter, r *http.Request)
 	db := h.serviceManager.GetDatabase()
 	allDependencies, err := db.GetAllServiceDependencies()
 	if err != nil {
-		log.Printf("Failed to load dependencies from database: %v", err)
+		requestLogf(r, "Failed to load dependencies from database: %v", err)
 		http.Error(w, "Failed to load dependencies", http.StatusInternalServerError)
 		return
 	}
@@ -155,7 +154,7 @@ func (h *Handler) getDependenciesHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := json.NewEncoder(w).Encode(dependencies); err != nil {
-		log.Printf("Failed to encode dependencies: %v", err)
+		requestLogf(r, "Failed to encode dependencies: %v", err)
 		http.Error(w, "Failed to encode dependencies", http.StatusInternalServerError)
 		return
 	}
@@ -168,7 +167,7 @@ func (h *Handler) saveDependenciesHandler(w http.ResponseWriter, r *http.Request
 
 	var configData map[string]any
 	if err := json.NewDecoder(r.Body).Decode(&configData); err != nil {
-		log.Printf("Failed to decode dependencies config: %v", err)
+		requestLogf(r, "Failed to decode dependencies config: %v", err)
 		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
 		return
 	}
@@ -177,7 +176,19 @@ func (h *Handler) saveDependenciesHandler(w http.ResponseWriter, r *http.Request
 	services := h.serviceManager.GetServices()
 	serviceMap := make(map[string]*models.Service)
 	for i := range services {
-		serviceMap[services[i].ID] = &services[i]
+		serviceMap[services[i].ID] = services[i]
+	}
+
+	// Check the readiness probes before saving anything
+	for serviceID, config := range configData {
+		if configMap, ok := config.(map[string]any); ok {
+			if depsList, ok := configMap["dependencies"].([]interface{}); ok {
+				if err := h.serviceManager.ValidateServiceDependencies(depsList); err != nil {
+					http.Error(w, fmt.Sprintf("Invalid dependencies for %s: %v", serviceID, err), http.StatusBadRequest)
+					return
+				}
+			}
+		}
 	}
 
 	// Process each service's configuration
@@ -185,7 +196,7 @@ func (h *Handler) saveDependenciesHandler(w http.ResponseWriter, r *http.Request
 		if configMap, ok := config.(map[string]any); ok {
 			service := serviceMap[serviceID]
 			if service == nil {
-				log.Printf("Service %s not found, skipping", serviceID)
+				requestLogf(r, "Service %s not found, skipping", serviceID)
 				continue
 			}
 
@@ -193,7 +204,7 @@ func (h *Handler) saveDependenciesHandler(w http.ResponseWriter, r *http.Request
 			if order, exists := configMap["order"]; exists {
 				if orderFloat, ok := order.(float64); ok {
 					service.Order = int(orderFloat)
-					log.Printf("Updated order for %s to %d", service.Name, service.Order)
+					requestLogf(r, "Updated order for %s to %d", service.Name, service.Order)
 				}
 			}
 
@@ -203,22 +214,22 @@ func (h *Handler) saveDependenciesHandler(w http.ResponseWriter, r *http.Request
 					// Save dependencies to database
 					db := h.serviceManager.GetDatabase()
 					if err := db.SaveServiceDependencies(serviceID, depsList); err != nil {
-						log.Printf("Failed to save dependencies for %s: %v", service.Name, err)
+						requestLogf(r, "Failed to save dependencies for %s: %v", service.Name, err)
 						http.Error(w, fmt.Sprintf("Failed to save dependencies for %s", service.Name), http.StatusInternalServerError)
 						return
 					}
-					log.Printf("Saved %d dependencies for %s", len(depsList), service.Name)
+					requestLogf(r, "Saved %d dependencies for %s", len(depsList), service.Name)
 				}
 			}
 
 			// Update the service in the service manager
 			if err := h.serviceManager.UpdateServiceInDB(service); err != nil {
-				log.Printf("Failed to update service %s in database: %v", service.Name, err)
+				requestLogf(r, "Failed to update service %s in database: %v", service.Name, err)
 			}
 		}
 	}
 
-	log.Printf("Dependencies configuration saved successfully")
+	requestLogf(r, "Dependencies configuration saved successfully")
 
 	response := map[string]interface{}{
 		"status":  "success",
@@ -226,7 +237,7 @@ func (h *Handler) saveDependenciesHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode response: %v", err)
+		requestLogf(r, "Failed to encode response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -254,7 +265,7 @@ func (h *Handler) getDependencyGraphHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		log.Printf("Failed to encode dependency graph: %v", err)
+		requestLogf(r, "Failed to encode dependency graph: %v", err)
 		http.Error(w, "Failed to encode dependency graph", http.StatusInternalServerError)
 		return
 	}
@@ -295,7 +306,7 @@ func (h *Handler) validateDependenciesHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		log.Printf("Failed to encode validation result: %v", err)
+		requestLogf(r, "Failed to encode validation result: %v", err)
 		http.Error(w, "Failed to encode validation result", http.StatusInternalServerError)
 		return
 	}
@@ -326,12 +337,12 @@ func (h *Handler) getStartupOrderHandler(w http.ResponseWriter, r *http.Request)
 	// Simple ordering based on service Order field for now
 	// In a real implementation, this would use the dependency manager
 	services := h.serviceManager.GetServices()
-	serviceMap := make(map[string]models.Service)
+	serviceMap := make(map[string]*models.Service)
 	for _, service := range services {
 		serviceMap[service.Name] = service
 	}
 
-	var orderedServices []models.Service
+	var orderedServices []*models.Service
 	for _, name := range request.Services {
 		if service, exists := serviceMap[name]; exists {
 			orderedServices = append(orderedServices, service)
@@ -354,7 +365,7 @@ func (h *Handler) getStartupOrderHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		log.Printf("Failed to encode startup order: %v", err)
+		requestLogf(r, "Failed to encode startup order: %v", err)
 		http.Error(w, "Failed to encode startup order", http.StatusInternalServerError)
 		return
 	}
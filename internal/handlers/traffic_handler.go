@@ -0,0 +1,100 @@
+// Package handlers - Per-service debugging proxy for HTTP traffic
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerTrafficRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/services/{id}/traffic", h.getServiceTrafficHandler).Methods("GET")
+	r.HandleFunc("/api/services/{id}/traffic", h.resetServiceTrafficHandler).Methods("DELETE")
+	r.HandleFunc("/api/services/{id}/traffic/capture", h.startTrafficCaptureHandler).Methods("POST")
+	r.HandleFunc("/api/services/{id}/traffic/capture", h.stopTrafficCaptureHandler).Methods("DELETE")
+}
+
+// getServiceTrafficHandler returns request counts, statuses and latency
+// percentiles per endpoint sent through the service's debugging proxy
+func (h *Handler) getServiceTrafficHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	summary, exists := h.serviceManager.GetTrafficSummary(serviceUUID)
+	if !exists {
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(summary)
+}
+
+// resetServiceTrafficHandler clears the recorded requests of a service
+func (h *Handler) resetServiceTrafficHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if err := h.serviceManager.ResetTrafficStats(serviceUUID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "reset"})
+}
+
+// startTrafficCaptureHandler starts a debugging proxy that forwards to a
+// service and records what is sent through it
+func (h *Handler) startTrafficCaptureHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+
+	var req models.TrafficCaptureRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+
+	summary, err := h.serviceManager.StartTrafficCapture(serviceUUID, req)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to start traffic capture for service %s: %v", serviceUUID, err)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "already running"):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(summary)
+}
+
+// stopTrafficCaptureHandler shuts the debugging proxy down, keeping the samples
+func (h *Handler) stopTrafficCaptureHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	serviceUUID := mux.Vars(r)["id"]
+	if err := h.serviceManager.StopTrafficCapture(serviceUUID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
+}
@@ -0,0 +1,202 @@
+// Package handlers - Two-factor authentication with one-time codes (TOTP)
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func registerTwoFactorRoutes(h *Handler, r *mux.Router) {
+	r.Use(h.twoFactorSetupMiddleware)
+	r.HandleFunc("/api/auth/2fa", h.getTwoFactorStatusHandler).Methods("GET")
+	r.HandleFunc("/api/auth/2fa/enroll", h.enrollTwoFactorHandler).Methods("POST")
+	r.HandleFunc("/api/auth/2fa/confirm", h.confirmTwoFactorHandler).Methods("POST")
+	r.HandleFunc("/api/auth/2fa/recovery-codes", h.regenerateRecoveryCodesHandler).Methods("POST")
+	r.HandleFunc("/api/auth/2fa/disable", h.disableTwoFactorHandler).Methods("POST")
+	r.HandleFunc("/api/auth/2fa/users/{userId}", h.resetTwoFactorHandler).Methods("DELETE")
+}
+
+// twoFactorSetupMiddleware holds signed-in users to the /api/auth/ routes
+// while admins require two-factor authentication and they have not set it up
+func (h *Handler) twoFactorSetupMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if !strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/api/auth/") || strings.HasPrefix(path, "/api/setup/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		claims, ok := extractClaimsFromRequest(r, h.authService)
+		if !ok || claims == nil || !h.authService.TwoFactorSetupRequired(claims.UserID) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		http.Error(w, "Two-factor authentication is required; set it up under /api/auth/2fa first", http.StatusForbidden)
+	})
+}
+
+// writeTwoFactorError maps a two-factor error to its status code
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "only admins"):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case strings.Contains(err.Error(), "not found"):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "already"):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case strings.Contains(err.Error(), "too many"):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("[ERROR] %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// decodeTwoFactorCode reads the code of a request, answering 400 without one
+func decodeTwoFactorCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var request models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || strings.TrimSpace(request.Code) == "" {
+		http.Error(w, "code is required", http.StatusBadRequest)
+		return "", false
+	}
+	return request.Code, true
+}
+
+// getTwoFactorStatusHandler returns whether the signed-in user has
+// two-factor authentication and whether admins require it
+func (h *Handler) getTwoFactorStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	status, err := h.authService.GetTwoFactorStatus(claims)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(status)
+}
+
+// enrollTwoFactorHandler creates the secret to add to an authenticator app
+func (h *Handler) enrollTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.authService.EnrollTwoFactor(claims)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// confirmTwoFactorHandler turns two-factor authentication on with the first
+// code from the authenticator app and returns the recovery codes
+func (h *Handler) confirmTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(claims, code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(codes)
+}
+
+// regenerateRecoveryCodesHandler replaces the signed-in user's recovery codes
+func (h *Handler) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(claims, code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(codes)
+}
+
+// disableTwoFactorHandler turns two-factor authentication off for the
+// signed-in user
+func (h *Handler) disableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	code, ok := decodeTwoFactorCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(claims, code); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resetTwoFactorHandler turns two-factor authentication off for a user who
+// lost their authenticator and recovery codes
+func (h *Handler) resetTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.authService.ResetTwoFactor(mux.Vars(r)["userId"], claims); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
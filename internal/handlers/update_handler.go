@@ -0,0 +1,81 @@
+// Package handlers - Offline update bundle upload
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gorilla/mux"
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/installer"
+)
+
+const maxUpdateBundleSize = 512 << 20 // 512MB
+
+func registerUpdateRoutes(h *Handler, r *mux.Router) {
+	r.HandleFunc("/api/system/update/upload", h.uploadUpdateBundleHandler).Methods("POST")
+}
+
+// uploadUpdateBundleHandler accepts a release bundle as multipart form data
+// ("bundle", plus "checksum" and optional "signature" and "insecure" fields),
+// verifies it and stages it for `vertex update --file`. Builds without a
+// signing key only accept bundles with insecure=true. Only admins may stage a
+// bundle. The update itself is applied from the command line since it restarts
+// the server handling this request.
+func (h *Handler) uploadUpdateBundleHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if claims.Role != "admin" {
+		http.Error(w, "only admins can stage updates", http.StatusForbidden)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUpdateBundleSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid upload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("bundle")
+	if err != nil {
+		http.Error(w, "Missing bundle file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	checksum := r.FormValue("checksum")
+	signature := r.FormValue("signature")
+	insecure := r.FormValue("insecure") == "true"
+	if checksum == "" {
+		http.Error(w, "checksum is required", http.StatusBadRequest)
+		return
+	}
+
+	updateDir := filepath.Join(database.GetDataDir(), "updates")
+	bundlePath, err := installer.StageUpdateBundle(updateDir, header.Filename, file, checksum, signature, insecure)
+	if err != nil {
+		requestLogf(r, "[WARN] Rejected update bundle %s: %v", header.Filename, err)
+		http.Error(w, fmt.Sprintf("Bundle verification failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	requestLogf(r, "[INFO] Staged verified update bundle at %s", bundlePath)
+
+	command := fmt.Sprintf("vertex update --file %q", bundlePath)
+	if insecure {
+		command += " --insecure"
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Update bundle verified and staged",
+		"path":    bundlePath,
+		"command": command,
+	})
+}
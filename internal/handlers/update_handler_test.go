@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUploadUpdateBundleHandler_RequiresAdmin(t *testing.T) {
+	h, adminToken, userToken := newAuthTestHandler(t)
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"without token", "", http.StatusUnauthorized},
+		{"as user", userToken, http.StatusForbidden},
+		// An admin gets past the role check to the form validation
+		{"as admin", adminToken, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create request
+			req := httptest.NewRequest("POST", "/api/system/update/upload", strings.NewReader("not a form"))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+
+			h.uploadUpdateBundleHandler(rr, req)
+
+			if status := rr.Code; status != tt.want {
+				t.Errorf("Handler returned wrong status code: got %v want %v", status, tt.want)
+			}
+		})
+	}
+}
@@ -28,17 +28,16 @@ func (h *Handler) getUptimeStatisticsHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	uptimeTracker := services.GetUptimeTracker()
-	allStats := uptimeTracker.GetAllUptimeStats()
 
-	// Get services from active profile, fallback to all services if no active profile
-	var services []models.Service
+	// Get services from the active profile; without one there is nothing to report
+	var services []*models.Service
+	allStats := map[string]models.UptimeStatistics{}
 	activeProfile, err := h.profileService.GetActiveProfile(claims.UserID)
-	if err != nil || activeProfile == nil {
-		// No active profile, show all services (fallback for backward compatibility)
-		services = h.serviceManager.GetServices()
-	} else {
-		// Filter services by active profile
-		allServices := h.serviceManager.GetServices()
+	if err == nil && activeProfile != nil {
+		// Only events recorded under the active profile are counted
+		allStats = uptimeTracker.GetProfileUptimeStats(activeProfile.ID)
+
+		allServices := h.serviceManager.GetServiceSnapshots()
 		for _, service := range allServices {
 			// Check if service is in the active profile
 			for _, serviceUUID := range activeProfile.Services {
@@ -104,14 +103,21 @@ func (h *Handler) getServiceUptimeStatisticsHandler(w http.ResponseWriter, r *ht
 	vars := mux.Vars(r)
 	serviceID := vars["id"]
 
-	service, exists := h.serviceManager.GetServiceByUUID(serviceID)
+	service, exists := h.serviceManager.GetServiceSnapshot(serviceID)
 	if !exists {
 		http.Error(w, "Service not found", http.StatusNotFound)
 		return
 	}
 
+	profileID, allowed := h.requestProfileScope(r, serviceID)
+	if !allowed {
+		// Services outside the profile are not revealed to exist
+		http.Error(w, "Service not found", http.StatusNotFound)
+		return
+	}
+
 	uptimeTracker := services.GetUptimeTracker()
-	stats := uptimeTracker.CalculateUptimeStats(serviceID, service)
+	stats := uptimeTracker.CalculateUptimeStats(profileID, serviceID)
 
 	response := map[string]interface{}{
 		"serviceName":  service.Name,
@@ -125,8 +131,32 @@ func (h *Handler) getServiceUptimeStatisticsHandler(w http.ResponseWriter, r *ht
 	json.NewEncoder(w).Encode(response)
 }
 
+// requestProfileScope resolves the active profile of the requesting user and
+// reports whether the given service belongs to it. Unauthenticated requests get
+// an empty profile ID and are allowed; signed-in users without an active
+// profile have no services in scope.
+func (h *Handler) requestProfileScope(r *http.Request, serviceUUID string) (string, bool) {
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		return "", true
+	}
+
+	profile, err := h.profileService.GetActiveProfile(claims.UserID)
+	if err != nil || profile == nil {
+		return "", false
+	}
+
+	for _, id := range profile.Services {
+		if id == serviceUUID {
+			return profile.ID, true
+		}
+	}
+
+	return profile.ID, false
+}
+
 // Helper functions
-func countRunningServices(services []models.Service) int {
+func countRunningServices(services []*models.Service) int {
 	count := 0
 	for _, service := range services {
 		if service.Status == "running" {
@@ -136,7 +166,7 @@ func countRunningServices(services []models.Service) int {
 	return count
 }
 
-func countUnhealthyServices(services []models.Service) int {
+func countUnhealthyServices(services []*models.Service) int {
 	count := 0
 	for _, service := range services {
 		if service.Status == "running" && service.HealthStatus == "unhealthy" {
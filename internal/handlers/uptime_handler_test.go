@@ -12,15 +12,15 @@ import (
 
 // ServiceManagerInterface for testing
 type ServiceManagerInterface interface {
-	GetServices() []models.Service
+	GetServices() []*models.Service
 	GetServiceByUUID(uuid string) (*models.Service, bool)
 }
 
 // MockServiceManager for testing
 type MockServiceManager struct{}
 
-func (msm *MockServiceManager) GetServices() []models.Service {
-	return []models.Service{
+func (msm *MockServiceManager) GetServices() []*models.Service {
+	return []*models.Service{
 		{
 			ID:           "test-service-1",
 			Name:         "Test Service 1",
@@ -245,3 +245,46 @@ func TestGetServiceUptimeStatisticsHandler_NotFound(t *testing.T) {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
 	}
 }
+
+func TestRequestProfileScope(t *testing.T) {
+	h, adminToken, userToken := newAuthTestHandler(t)
+
+	// Create an active profile for the user holding svc-1
+	claims, err := h.authService.ValidateToken(userToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := h.profileService.CreateServiceProfile(claims.UserID, &models.CreateProfileRequest{Name: "dev", Services: []string{"svc-1"}, IsActive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		serviceID   string
+		wantProfile string
+		wantAllowed bool
+	}{
+		{"service in the active profile", userToken, "svc-1", profile.ID, true},
+		{"service outside the active profile", userToken, "svc-2", profile.ID, false},
+		{"no active profile", adminToken, "svc-1", "", false},
+		{"unauthenticated", "", "svc-1", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Create request
+			req := httptest.NewRequest("GET", "/api/uptime/statistics/"+tt.serviceID, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			profileID, allowed := h.requestProfileScope(req, tt.serviceID)
+
+			if profileID != tt.wantProfile || allowed != tt.wantAllowed {
+				t.Errorf("requestProfileScope() = (%q, %v), want (%q, %v)", profileID, allowed, tt.wantProfile, tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -2,12 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/zechtz/vertex/internal/models"
+	"github.com/zechtz/vertex/internal/services"
 )
 
 func registerUserRoutes(h *Handler, r *mux.Router) {
@@ -16,6 +17,8 @@ func registerUserRoutes(h *Handler, r *mux.Router) {
 	r.HandleFunc("/api/auth/user", h.getCurrentUserHandler).Methods("GET")
 	r.HandleFunc("/api/user/profile", h.getUserProfileHandler).Methods("GET")
 	r.HandleFunc("/api/user/profile", h.updateUserProfileHandler).Methods("PUT")
+	r.HandleFunc("/api/user/preferences", h.getUserPreferencesHandler).Methods("GET")
+	r.HandleFunc("/api/user/preferences", h.patchUserPreferencesHandler).Methods("PATCH")
 }
 
 // registerHandler handles user registration
@@ -25,32 +28,32 @@ func (h *Handler) registerHandler(w http.ResponseWriter, r *http.Request) {
 
 	var registration models.UserRegistration
 	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
-		log.Printf("[ERROR] Failed to decode registration request: %v", err)
+		requestLogf(r, "[ERROR] Failed to decode registration request: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	if err := registration.Validate(); err != nil {
-		log.Printf("[ERROR] Validation failed: %v", err)
+		requestLogf(r, "[ERROR] Validation failed: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	user, err := h.authService.Register(&registration)
 	if err != nil {
-		log.Printf("[ERROR] Failed to register user: %v", err)
+		requestLogf(r, "[ERROR] Failed to register user: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[INFO] User registered successfully: %s (%s)", user.Username, user.Email)
+	requestLogf(r, "[INFO] User registered successfully: %s (%s)", user.Username, user.Email)
 
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"message": "User registered successfully",
 		"user":    user,
 	}); err != nil {
-		log.Printf("[ERROR] Failed to encode registration response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode registration response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -63,7 +66,7 @@ func (h *Handler) loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	var login models.UserLogin
 	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
-		log.Printf("[ERROR] Failed to decode login request: %v", err)
+		requestLogf(r, "[ERROR] Failed to decode login request: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -75,15 +78,28 @@ func (h *Handler) loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	authResponse, err := h.authService.Login(&login)
 	if err != nil {
-		log.Printf("[ERROR] Failed to login user: %v", err)
+		// The password was right; the UI asks for the one-time code and sends both again
+		if errors.Is(err, services.ErrTwoFactorRequired) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":             "Enter the code from your authenticator app or a recovery code",
+				"twoFactorRequired": true,
+			})
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to login user: %v", err)
+		if strings.Contains(err.Error(), "too many") {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	log.Printf("[INFO] User logged in successfully: %s", authResponse.User.Username)
+	requestLogf(r, "[INFO] User logged in successfully: %s", authResponse.User.Username)
 
 	if err := json.NewEncoder(w).Encode(authResponse); err != nil {
-		log.Printf("[ERROR] Failed to encode login response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode login response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -108,20 +124,20 @@ func (h *Handler) getCurrentUserHandler(w http.ResponseWriter, r *http.Request)
 
 	claims, err := h.authService.ValidateToken(tokenParts[1])
 	if err != nil {
-		log.Printf("[ERROR] Failed to validate token: %v", err)
+		requestLogf(r, "[ERROR] Failed to validate token: %v", err)
 		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 		return
 	}
 
 	user, err := h.authService.GetUserByID(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get user: %v", err)
+		requestLogf(r, "[ERROR] Failed to get user: %v", err)
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(user); err != nil {
-		log.Printf("[ERROR] Failed to encode user response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode user response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -139,13 +155,13 @@ func (h *Handler) getUserProfileHandler(w http.ResponseWriter, r *http.Request)
 
 	profile, err := h.profileService.GetUserProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get user profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to get user profile: %v", err)
 		http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(profile); err != nil {
-		log.Printf("[ERROR] Failed to encode profile response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode profile response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -163,20 +179,96 @@ func (h *Handler) updateUserProfileHandler(w http.ResponseWriter, r *http.Reques
 
 	var req models.UserProfileUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("[ERROR] Invalid request body: %v", err)
+		requestLogf(r, "[ERROR] Invalid request body: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	profile, err := h.profileService.UpdateUserProfile(claims.UserID, &req)
 	if err != nil {
-		log.Printf("[ERROR] Failed to update user profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to update user profile: %v", err)
 		http.Error(w, "Failed to update user profile", http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(profile); err != nil {
-		log.Printf("[ERROR] Failed to encode profile response: %v", err)
+		requestLogf(r, "[ERROR] Failed to encode profile response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getUserPreferencesHandler returns the current user's preferences with an
+// ETag; a matching If-None-Match yields 304 so tabs can poll cheaply
+func (h *Handler) getUserPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prefs, etag, err := h.profileService.GetUserPreferences(claims.UserID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get user preferences: %v", err)
+		http.Error(w, "Failed to get user preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && services.ETagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(prefs); err != nil {
+		requestLogf(r, "[ERROR] Failed to encode preferences response: %v", err)
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// patchUserPreferencesHandler changes the preferences present in the body.
+// With If-Match the change is only applied if nobody saved in between;
+// otherwise it fails with 412 and the current preferences and ETag.
+func (h *Handler) patchUserPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var patch models.UserPreferencesPatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		requestLogf(r, "[ERROR] Invalid request body: %v", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	prefs, etag, err := h.profileService.PatchUserPreferences(claims.UserID, &patch, r.Header.Get("If-Match"))
+	if err != nil {
+		if errors.Is(err, services.ErrPreferencesConflict) {
+			w.Header().Set("ETag", etag)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			json.NewEncoder(w).Encode(prefs)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to update user preferences: %v", err)
+		if strings.HasPrefix(err.Error(), "failed to") {
+			http.Error(w, "Failed to update user preferences", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	if err := json.NewEncoder(w).Encode(prefs); err != nil {
+		requestLogf(r, "[ERROR] Failed to encode preferences response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -3,13 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"github.com/zechtz/vertex/internal/database"
 	"github.com/zechtz/vertex/internal/models"
 	"github.com/zechtz/vertex/internal/services"
@@ -17,9 +18,12 @@ import (
 
 func registerUtilityRoutes(h *Handler, r *mux.Router) {
 	r.HandleFunc("/api/system/metrics", h.getSystemMetricsHandler).Methods("GET")
+	r.HandleFunc("/api/system/db", h.getDatabaseStatsHandler).Methods("GET")
+	r.HandleFunc("/api/system/health", h.getSystemHealthHandler).Methods("GET")
 	r.HandleFunc("/api/system/logs/cleanup", h.cleanupLogsHandler).Methods("POST")
 
 	r.HandleFunc("/api/logs/search", h.searchLogsHandler).Methods("POST")
+	r.HandleFunc("/api/logs/context", h.getLogContextHandler).Methods("GET")
 	r.HandleFunc("/api/logs/statistics", h.getLogStatisticsHandler).Methods("GET")
 	r.HandleFunc("/api/logs/export", h.exportLogsHandler).Methods("POST")
 
@@ -32,6 +36,7 @@ func registerUtilityRoutes(h *Handler, r *mux.Router) {
 	r.HandleFunc("/api/env-vars/global", h.getGlobalEnvVarsHandler).Methods("GET")
 	r.HandleFunc("/api/env-vars/global", h.updateGlobalEnvVarsHandler).Methods("PUT")
 	r.HandleFunc("/api/env-vars/reload", h.reloadEnvVarsHandler).Methods("POST")
+	r.HandleFunc("/api/env-vars/bulk-update", h.bulkUpdateEnvVarsHandler).Methods("POST")
 	r.HandleFunc("/api/env-vars/cleanup", h.cleanupGlobalEnvVarsHandler).Methods("POST")
 
 	r.HandleFunc("/api/auto-discovery/scan", h.scanAutoDiscoveryHandler).Methods("POST")
@@ -50,7 +55,7 @@ func (h *Handler) getSystemMetricsHandler(w http.ResponseWriter, r *http.Request
 	summary := h.serviceManager.GetSystemResourceSummary()
 
 	// Add individual service metrics
-	services := h.serviceManager.GetServices()
+	services := h.serviceManager.GetServiceSnapshots()
 	serviceMetrics := make([]map[string]any, 0)
 
 	for _, service := range services {
@@ -78,9 +83,45 @@ func (h *Handler) getSystemMetricsHandler(w http.ResponseWriter, r *http.Request
 		"services": serviceMetrics,
 	}
 
+	// Add the size and fragmentation of Vertex's own database
+	if stats, err := h.serviceManager.GetDatabase().HealthStats(); err == nil {
+		response["database"] = map[string]any{
+			"sizeBytes":            stats.SizeBytes,
+			"fileSizeBytes":        stats.FileSizeBytes,
+			"walSizeBytes":         stats.WALSizeBytes,
+			"freePages":            stats.FreePages,
+			"fragmentationPercent": stats.FragmentationPct,
+		}
+	}
+
 	json.NewEncoder(w).Encode(response)
 }
 
+// getDatabaseStatsHandler reports the journal mode, size, fragmentation,
+// connection pool and log write queue of the SQLite database
+func (h *Handler) getDatabaseStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	stats, err := h.serviceManager.GetDatabase().HealthStats()
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get database stats: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(stats)
+}
+
+// getSystemHealthHandler reports the health of Vertex itself rather than of
+// its services
+func (h *Handler) getSystemHealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	json.NewEncoder(w).Encode(h.serviceManager.GetSystemHealth(r.Context()))
+}
+
 func (h *Handler) cleanupLogsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -98,7 +139,7 @@ func (h *Handler) cleanupLogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Try to parse request body for custom parameters
 	if r.ContentLength > 0 {
 		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-			log.Printf("[WARN] Failed to parse log cleanup request: %v", err)
+			requestLogf(r, "[WARN] Failed to parse log cleanup request: %v", err)
 			// Continue with defaults
 		}
 	}
@@ -114,7 +155,7 @@ func (h *Handler) cleanupLogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Perform cleanup
 	err := h.serviceManager.CleanupOldLogs(request.MaxDays, request.MaxLogsPerService)
 	if err != nil {
-		log.Printf("[ERROR] Log cleanup failed: %v", err)
+		requestLogf(r, "[ERROR] Log cleanup failed: %v", err)
 		http.Error(w, fmt.Sprintf("Log cleanup failed: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -143,7 +184,7 @@ func (h *Handler) searchLogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get user's active profile
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile for log search: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile for log search: %v", err)
 		http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
 		return
 	}
@@ -250,6 +291,99 @@ func (h *Handler) searchLogsHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// maxLogContextLines caps the entries returned on each side of a log entry
+const maxLogContextLines = 500
+
+// getLogContextHandler returns the entries around a log entry of a service in
+// the active profile, for showing a search hit in context
+func (h *Handler) getLogContextHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok || claims == nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := h.profileService.GetActiveProfile(claims.UserID)
+	if err != nil {
+		requestLogf(r, "[ERROR] Failed to get active profile for log context: %v", err)
+		http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	serviceID := query.Get("serviceId")
+	if serviceID == "" {
+		http.Error(w, "serviceId is required", http.StatusBadRequest)
+		return
+	}
+	inProfile := false
+	for _, id := range profile.Services {
+		if id == serviceID {
+			inProfile = true
+			break
+		}
+	}
+	if !inProfile {
+		http.Error(w, "Service not found in the active profile", http.StatusNotFound)
+		return
+	}
+
+	var entryID int64
+	if raw := query.Get("id"); raw != "" {
+		if entryID, err = strconv.ParseInt(raw, 10, 64); err != nil || entryID <= 0 {
+			http.Error(w, "id must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+	var timestamp time.Time
+	if raw := query.Get("timestamp"); raw != "" {
+		if timestamp, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid timestamp format: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else if entryID == 0 {
+		http.Error(w, "timestamp or id is required", http.StatusBadRequest)
+		return
+	}
+
+	lines := func(name string) (int, bool) {
+		raw := query.Get(name)
+		if raw == "" {
+			return 50, true
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > maxLogContextLines {
+			http.Error(w, fmt.Sprintf("%s must be between 0 and %d", name, maxLogContextLines), http.StatusBadRequest)
+			return 0, false
+		}
+		return n, true
+	}
+	before, ok := lines("before")
+	if !ok {
+		return
+	}
+	after, ok := lines("after")
+	if !ok {
+		return
+	}
+
+	logContext, err := h.serviceManager.GetDatabase().GetLogContext(serviceID, timestamp, entryID, before, after)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		requestLogf(r, "[ERROR] Failed to get log context for service %s: %v", serviceID, err)
+		http.Error(w, fmt.Sprintf("Failed to get log context: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(logContext)
+}
+
 func (h *Handler) getLogStatisticsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -264,7 +398,7 @@ func (h *Handler) getLogStatisticsHandler(w http.ResponseWriter, r *http.Request
 	// Get user's active profile
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile for log statistics: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile for log statistics: %v", err)
 		http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
 		return
 	}
@@ -322,7 +456,7 @@ func (h *Handler) exportLogsHandler(w http.ResponseWriter, r *http.Request) {
 	// Get user's active profile
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile for log export: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile for log export: %v", err)
 		http.Error(w, "Failed to get active profile", http.StatusInternalServerError)
 		return
 	}
@@ -482,7 +616,7 @@ func (h *Handler) fixLombokHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	log.Printf("[INFO] Starting Lombok compatibility check for all services")
+	requestLogf(r, "[INFO] Starting Lombok compatibility check for all services")
 
 	// Get all services
 	services := h.serviceManager.GetServices()
@@ -493,10 +627,10 @@ func (h *Handler) fixLombokHandler(w http.ResponseWriter, r *http.Request) {
 
 		if err := h.serviceManager.CheckAndFixLombokCompatibility(serviceDir, service.Name); err != nil {
 			results[service.Name] = fmt.Sprintf("Error: %v", err)
-			log.Printf("[ERROR] Lombok fix failed for service %s: %v", service.Name, err)
+			requestLogf(r, "[ERROR] Lombok fix failed for service %s: %v", service.Name, err)
 		} else {
 			results[service.Name] = "Success"
-			log.Printf("[INFO] Lombok compatibility checked for service %s", service.Name)
+			requestLogf(r, "[INFO] Lombok compatibility checked for service %s", service.Name)
 		}
 	}
 
@@ -512,7 +646,7 @@ func (h *Handler) setupEnvironmentHandler(w http.ResponseWriter, r *http.Request
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	log.Printf("[INFO] Setting up environment variables")
+	requestLogf(r, "[INFO] Setting up environment variables")
 
 	// Get working directory
 	workingDir, err := os.Getwd()
@@ -538,7 +672,7 @@ func (h *Handler) syncEnvironmentHandler(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	log.Printf("[INFO] Syncing environment variables from database")
+	requestLogf(r, "[INFO] Syncing environment variables from database")
 
 	// Get working directory
 	workingDir, err := os.Getwd()
@@ -620,6 +754,41 @@ func (h *Handler) updateGlobalEnvVarsHandler(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
 }
 
+// bulkUpdateEnvVarsHandler applies env var changes across services and profiles, or previews them when dryRun is set
+func (h *Handler) bulkUpdateEnvVarsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	claims, ok := extractClaimsFromRequest(r, h.authService)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request models.BulkEnvVarUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	previews, err := h.profileService.BulkUpdateEnvVars(claims.UserID, &request)
+	if err != nil {
+		requestLogf(r, "[ERROR] Bulk env var update failed: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := "updated"
+	if request.DryRun {
+		status = "preview"
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  status,
+		"targets": previews,
+	})
+}
+
 func (h *Handler) reloadEnvVarsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -661,12 +830,12 @@ func (h *Handler) scanAutoDiscoveryHandler(w http.ResponseWriter, r *http.Reques
 	// Get user's active profile to determine scan directory
 	profile, err := h.profileService.GetActiveProfile(claims.UserID)
 	if err != nil {
-		log.Printf("[ERROR] Failed to get active profile: %v", err)
+		requestLogf(r, "[ERROR] Failed to get active profile: %v", err)
 		// Fall back to global directory if no active profile
-		log.Printf("[INFO] Starting auto-discovery scan (global directory)")
+		requestLogf(r, "[INFO] Starting auto-discovery scan (global directory)")
 		discoveredServices, err := h.autoDiscoveryService.ScanProjectDirectory()
 		if err != nil {
-			log.Printf("[ERROR] Auto-discovery scan failed: %v", err)
+			requestLogf(r, "[ERROR] Auto-discovery scan failed: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to scan project directory: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -680,7 +849,7 @@ func (h *Handler) scanAutoDiscoveryHandler(w http.ResponseWriter, r *http.Reques
 		scanDir = profile.ProjectsDir
 	}
 
-	log.Printf("[INFO] Starting auto-discovery scan in profile directory: %s", scanDir)
+	requestLogf(r, "[INFO] Starting auto-discovery scan in profile directory: %s", scanDir)
 
 	var discoveredServices []services.DiscoveredService
 
@@ -692,7 +861,7 @@ func (h *Handler) scanAutoDiscoveryHandler(w http.ResponseWriter, r *http.Reques
 	}
 	
 	if err != nil {
-		log.Printf("[ERROR] Auto-discovery scan failed: %v", err)
+		requestLogf(r, "[ERROR] Auto-discovery scan failed: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to scan project directory: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -709,13 +878,13 @@ func (h *Handler) getDiscoveredServicesHandler(w http.ResponseWriter, r *http.Re
 	// In a more advanced implementation, we could cache results
 	discoveredServices, err := h.autoDiscoveryService.ScanProjectDirectory()
 	if err != nil {
-		log.Printf("[ERROR] Failed to get discovered services: %v", err)
+		requestLogf(r, "[ERROR] Failed to get discovered services: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to get discovered services: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	if err := json.NewEncoder(w).Encode(discoveredServices); err != nil {
-		log.Printf("Failed to encode discovered services: %v", err)
+		requestLogf(r, "Failed to encode discovered services: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -728,43 +897,42 @@ func (h *Handler) importDiscoveredServiceHandler(w http.ResponseWriter, r *http.
 
 	var discoveredService services.DiscoveredService
 	if err := json.NewDecoder(r.Body).Decode(&discoveredService); err != nil {
-		log.Printf("[ERROR] Failed to decode discovered service: %v", err)
+		requestLogf(r, "[ERROR] Failed to decode discovered service: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[INFO] Importing discovered service: %s from %s", discoveredService.Name, discoveredService.Path)
+	requestLogf(r, "[INFO] Importing discovered service: %s from %s", discoveredService.Name, discoveredService.Path)
 
 	// Check if a service with the same path already exists globally
 	var service *models.Service
 	allServices := h.serviceManager.GetServices()
 	for _, existingService := range allServices {
-		if existingService.Dir == discoveredService.Path || 
-		   strings.TrimPrefix(existingService.Dir, "/") == strings.TrimPrefix(discoveredService.Path, "/") {
-			log.Printf("[INFO] Found existing service '%s' (UUID: %s) with same path '%s' - reusing existing service without modification", 
+		if existingService.WorkingDir == discoveredService.Module && (existingService.Dir == discoveredService.Path || 
+		   strings.TrimPrefix(existingService.Dir, "/") == strings.TrimPrefix(discoveredService.Path, "/")) {
+			requestLogf(r, "[INFO] Found existing service '%s' (UUID: %s) with same path '%s' - reusing existing service without modification", 
 				existingService.Name, existingService.ID, existingService.Dir)
-			// Make a copy to avoid any modifications to the original
-			serviceCopy := existingService
-			service = &serviceCopy
+			// GetServices returns copies, so the original is not modified
+			service = existingService
 			break
 		}
 	}
 
 	// If no existing service found, create a new one
 	if service == nil {
-		log.Printf("[INFO] No existing service found with path '%s' - creating new service", discoveredService.Path)
+		requestLogf(r, "[INFO] No existing service found with path '%s' - creating new service", discoveredService.Path)
 		
 		// Check for name conflicts and generate unique name if needed
 		originalName := discoveredService.Name
 		uniqueName := h.generateUniqueServiceName(originalName)
 		if uniqueName != originalName {
-			log.Printf("[INFO] Service name '%s' already exists, using unique name '%s'", originalName, uniqueName)
+			requestLogf(r, "[INFO] Service name '%s' already exists, using unique name '%s'", originalName, uniqueName)
 			discoveredService.Name = uniqueName
 		}
 		
 		newService, err := h.autoDiscoveryService.CreateServiceFromDiscovered(discoveredService)
 		if err != nil {
-			log.Printf("[ERROR] Failed to import discovered service %s: %v", discoveredService.Name, err)
+			requestLogf(r, "[ERROR] Failed to import discovered service %s: %v", discoveredService.Name, err)
 			http.Error(w, fmt.Sprintf("Failed to import service: %v", err), http.StatusInternalServerError)
 			return
 		}
@@ -773,28 +941,28 @@ func (h *Handler) importDiscoveredServiceHandler(w http.ResponseWriter, r *http.
 
 	// Try to add the service to the user's active profile (if authenticated)
 	if claims, ok := extractClaimsFromRequest(r, h.authService); ok && claims != nil {
-		log.Printf("[DEBUG] Single import - User authenticated: %s", claims.UserID)
+		requestLogf(r, "[DEBUG] Single import - User authenticated: %s", claims.UserID)
 		if activeProfile, err := h.profileService.GetActiveProfile(claims.UserID); err == nil && activeProfile != nil {
-			log.Printf("[DEBUG] Single import - Adding service %s (UUID: %s) to active profile %s (ID: %s)", service.Name, service.ID, activeProfile.Name, activeProfile.ID)
+			requestLogf(r, "[DEBUG] Single import - Adding service %s (UUID: %s) to active profile %s (ID: %s)", service.Name, service.ID, activeProfile.Name, activeProfile.ID)
 
 			// Verify service exists in service manager
 			if _, exists := h.serviceManager.GetServiceByUUID(service.ID); !exists {
-				log.Printf("[ERROR] Service %s (UUID: %s) not found in service manager after creation", service.Name, service.ID)
+				requestLogf(r, "[ERROR] Service %s (UUID: %s) not found in service manager after creation", service.Name, service.ID)
 			} else {
-				log.Printf("[DEBUG] Service %s (UUID: %s) confirmed to exist in service manager", service.Name, service.ID)
+				requestLogf(r, "[DEBUG] Service %s (UUID: %s) confirmed to exist in service manager", service.Name, service.ID)
 			}
 
 			// Add service to the active profile using service ID
 			if err := h.profileService.AddServiceToProfile(claims.UserID, activeProfile.ID, service.ID); err != nil {
-				log.Printf("[WARN] Successfully imported service %s but failed to add to active profile %s: %v", service.Name, activeProfile.Name, err)
+				requestLogf(r, "[WARN] Successfully imported service %s but failed to add to active profile %s: %v", service.Name, activeProfile.Name, err)
 			} else {
-				log.Printf("[INFO] Successfully imported service %s and added to active profile %s", service.Name, activeProfile.Name)
+				requestLogf(r, "[INFO] Successfully imported service %s and added to active profile %s", service.Name, activeProfile.Name)
 			}
 		} else {
-			log.Printf("[WARN] Failed to get active profile for user %s: %v", claims.UserID, err)
+			requestLogf(r, "[WARN] Failed to get active profile for user %s: %v", claims.UserID, err)
 		}
 	} else {
-		log.Printf("[WARN] Single import - No authentication found, service will not be added to profile")
+		requestLogf(r, "[WARN] Single import - No authentication found, service will not be added to profile")
 	}
 
 	result := map[string]any{
@@ -803,10 +971,10 @@ func (h *Handler) importDiscoveredServiceHandler(w http.ResponseWriter, r *http.
 		"service": service,
 	}
 
-	log.Printf("[INFO] Successfully imported service: %s", service.Name)
+	requestLogf(r, "[INFO] Successfully imported service: %s", service.Name)
 
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		log.Printf("Failed to encode import service response: %v", err)
+		requestLogf(r, "Failed to encode import service response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -821,27 +989,27 @@ func (h *Handler) importDiscoveredServicesBulkHandler(w http.ResponseWriter, r *
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		log.Printf("[ERROR] Failed to decode bulk import request: %v", err)
+		requestLogf(r, "[ERROR] Failed to decode bulk import request: %v", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	log.Printf("[INFO] Bulk importing %d discovered services", len(request.Services))
+	requestLogf(r, "[INFO] Bulk importing %d discovered services", len(request.Services))
 
 	// Get user's active profile for adding services (if authenticated)
 	var activeProfile *models.ServiceProfile
 	var userClaims *models.JWTClaims
 	if claims, ok := extractClaimsFromRequest(r, h.authService); ok && claims != nil {
 		userClaims = claims
-		log.Printf("[DEBUG] Bulk import - User authenticated: %s", claims.UserID)
+		requestLogf(r, "[DEBUG] Bulk import - User authenticated: %s", claims.UserID)
 		if profile, err := h.profileService.GetActiveProfile(claims.UserID); err == nil {
 			activeProfile = profile
-			log.Printf("[INFO] Will add imported services to active profile: %s (ID: %s)", activeProfile.Name, activeProfile.ID)
+			requestLogf(r, "[INFO] Will add imported services to active profile: %s (ID: %s)", activeProfile.Name, activeProfile.ID)
 		} else {
-			log.Printf("[WARN] Failed to get active profile for user %s: %v", claims.UserID, err)
+			requestLogf(r, "[WARN] Failed to get active profile for user %s: %v", claims.UserID, err)
 		}
 	} else {
-		log.Printf("[WARN] Bulk import - No authentication found, services will not be added to profile")
+		requestLogf(r, "[WARN] Bulk import - No authentication found, services will not be added to profile")
 	}
 
 	var importedServices []any
@@ -849,38 +1017,37 @@ func (h *Handler) importDiscoveredServicesBulkHandler(w http.ResponseWriter, r *
 	var profileErrors []string
 
 	for _, discoveredService := range request.Services {
-		log.Printf("[INFO] Importing discovered service: %s from %s", discoveredService.Name, discoveredService.Path)
+		requestLogf(r, "[INFO] Importing discovered service: %s from %s", discoveredService.Name, discoveredService.Path)
 
 		// Check if a service with the same path already exists globally
 		var service *models.Service
 		allServices := h.serviceManager.GetServices()
 		for _, existingService := range allServices {
-			if existingService.Dir == discoveredService.Path || 
-			   strings.TrimPrefix(existingService.Dir, "/") == strings.TrimPrefix(discoveredService.Path, "/") {
-				log.Printf("[INFO] Found existing service '%s' (UUID: %s) with same path '%s' - reusing existing service without modification", 
+			if existingService.WorkingDir == discoveredService.Module && (existingService.Dir == discoveredService.Path || 
+			   strings.TrimPrefix(existingService.Dir, "/") == strings.TrimPrefix(discoveredService.Path, "/")) {
+				requestLogf(r, "[INFO] Found existing service '%s' (UUID: %s) with same path '%s' - reusing existing service without modification", 
 					existingService.Name, existingService.ID, existingService.Dir)
-				// Make a copy to avoid any modifications to the original
-				serviceCopy := existingService
-				service = &serviceCopy
+				// GetServices returns copies, so the original is not modified
+				service = existingService
 				break
 			}
 		}
 
 		// If no existing service found, create a new one
 		if service == nil {
-			log.Printf("[INFO] No existing service found with path '%s' - creating new service", discoveredService.Path)
+			requestLogf(r, "[INFO] No existing service found with path '%s' - creating new service", discoveredService.Path)
 			
 			// Check for name conflicts and generate unique name if needed
 			originalName := discoveredService.Name
 			uniqueName := h.generateUniqueServiceName(originalName)
 			if uniqueName != originalName {
-				log.Printf("[INFO] Service name '%s' already exists, using unique name '%s'", originalName, uniqueName)
+				requestLogf(r, "[INFO] Service name '%s' already exists, using unique name '%s'", originalName, uniqueName)
 				discoveredService.Name = uniqueName
 			}
 			
 			newService, err := h.autoDiscoveryService.CreateServiceFromDiscovered(discoveredService)
 			if err != nil {
-				log.Printf("[ERROR] Failed to import discovered service %s: %v", discoveredService.Name, err)
+				requestLogf(r, "[ERROR] Failed to import discovered service %s: %v", discoveredService.Name, err)
 				errors = append(errors, fmt.Sprintf("Failed to import %s: %v", discoveredService.Name, err))
 				continue
 			}
@@ -888,23 +1055,23 @@ func (h *Handler) importDiscoveredServicesBulkHandler(w http.ResponseWriter, r *
 		}
 
 		importedServices = append(importedServices, service)
-		log.Printf("[INFO] Successfully imported service: %s", service.Name)
+		requestLogf(r, "[INFO] Successfully imported service: %s", service.Name)
 
 		// Add to active profile if available
 		if activeProfile != nil && userClaims != nil {
-			log.Printf("[DEBUG] Adding service %s (UUID: %s) to profile %s (ID: %s)", service.Name, service.ID, activeProfile.Name, activeProfile.ID)
+			requestLogf(r, "[DEBUG] Adding service %s (UUID: %s) to profile %s (ID: %s)", service.Name, service.ID, activeProfile.Name, activeProfile.ID)
 
 			// Verify service exists in service manager
 			if _, exists := h.serviceManager.GetServiceByUUID(service.ID); !exists {
 				profileErrors = append(profileErrors, fmt.Sprintf("Service %s (UUID: %s) not found in service manager", service.Name, service.ID))
-				log.Printf("[ERROR] Service %s (UUID: %s) not found in service manager after creation", service.Name, service.ID)
+				requestLogf(r, "[ERROR] Service %s (UUID: %s) not found in service manager after creation", service.Name, service.ID)
 			} else {
-				log.Printf("[DEBUG] Service %s (UUID: %s) confirmed to exist in service manager", service.Name, service.ID)
+				requestLogf(r, "[DEBUG] Service %s (UUID: %s) confirmed to exist in service manager", service.Name, service.ID)
 				if err := h.profileService.AddServiceToProfile(userClaims.UserID, activeProfile.ID, service.ID); err != nil {
 					profileErrors = append(profileErrors, fmt.Sprintf("Failed to add %s to profile: %v", service.Name, err))
-					log.Printf("[WARN] Failed to add service %s to active profile %s: %v", service.Name, activeProfile.Name, err)
+					requestLogf(r, "[WARN] Failed to add service %s to active profile %s: %v", service.Name, activeProfile.Name, err)
 				} else {
-					log.Printf("[INFO] Successfully added service %s to active profile %s", service.Name, activeProfile.Name)
+					requestLogf(r, "[INFO] Successfully added service %s to active profile %s", service.Name, activeProfile.Name)
 				}
 			}
 		}
@@ -926,10 +1093,10 @@ func (h *Handler) importDiscoveredServicesBulkHandler(w http.ResponseWriter, r *
 		"totalImported":    len(importedServices),
 	}
 
-	log.Printf("[INFO] Bulk import completed: %d/%d services imported successfully", len(importedServices), len(request.Services))
+	requestLogf(r, "[INFO] Bulk import completed: %d/%d services imported successfully", len(importedServices), len(request.Services))
 
 	if err := json.NewEncoder(w).Encode(result); err != nil {
-		log.Printf("Failed to encode bulk import response: %v", err)
+		requestLogf(r, "Failed to encode bulk import response: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
@@ -946,11 +1113,18 @@ func (h *Handler) websocketHandler(w http.ResponseWriter, r *http.Request) {
 	h.serviceManager.AddWebSocketClient(conn)
 	defer h.serviceManager.RemoveWebSocketClient(conn)
 
+	// Clients may subscribe to topics and resume after a reconnect
 	for {
-		_, _, err := conn.ReadMessage()
+		messageType, data, err := conn.ReadMessage()
 		if err != nil {
 			break
 		}
+		if messageType != websocket.TextMessage {
+			continue
+		}
+		if err := h.serviceManager.HandleWebSocketMessage(conn, data); err != nil {
+			break
+		}
 	}
 }
 
@@ -991,7 +1165,7 @@ func (h *Handler) getJavaDiagnosticsHandler(w http.ResponseWriter, r *http.Reque
 	diagnostics := javaEnv.GetDiagnostics()
 
 	if err := json.NewEncoder(w).Encode(diagnostics); err != nil {
-		log.Printf("Failed to encode Java diagnostics: %v", err)
+		requestLogf(r, "Failed to encode Java diagnostics: %v", err)
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
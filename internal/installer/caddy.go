@@ -0,0 +1,373 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// CaddyInstaller handles Caddy configuration for Vertex. Caddy runs as a user
+// service from a Caddyfile in the data directory and issues certificates
+// from its own internal CA, so no mkcert or system config files are needed.
+type CaddyInstaller struct {
+	Domain       string
+	Port         string
+	HTTPSEnabled bool
+	ListenPort   int    // Port Caddy listens on; 0 uses 80/443, which needs a capability on Linux
+	NoSudo       bool   // Print privileged commands instead of running them
+	ConfigDir    string // Where the Caddyfile is written
+}
+
+// NewCaddyInstaller creates a new Caddy installer
+func NewCaddyInstaller(domain, port, configDir string) *CaddyInstaller {
+	return &CaddyInstaller{
+		Domain:    domain,
+		Port:      port,
+		ConfigDir: configDir,
+	}
+}
+
+// EnableHTTPS enables HTTPS with Caddy's internal CA
+func (ci *CaddyInstaller) EnableHTTPS(enable bool) {
+	ci.HTTPSEnabled = enable
+}
+
+// IsCaddyInstalled checks if caddy is installed
+func (ci *CaddyInstaller) IsCaddyInstalled() bool {
+	_, err := exec.LookPath("caddy")
+	return err == nil
+}
+
+func (ci *CaddyInstaller) caddyfilePath() string {
+	return filepath.Join(ci.ConfigDir, "Caddyfile")
+}
+
+// siteAddress is the Caddyfile site address, which also decides whether
+// Caddy serves HTTPS
+func (ci *CaddyInstaller) siteAddress() string {
+	address := ci.Domain
+	if !ci.HTTPSEnabled {
+		address = "http://" + address
+	}
+	if ci.ListenPort > 0 {
+		address = fmt.Sprintf("%s:%d", address, ci.ListenPort)
+	}
+	return address
+}
+
+// URL is where Vertex is reachable through Caddy
+func (ci *CaddyInstaller) URL() string {
+	protocol := "http"
+	if ci.HTTPSEnabled {
+		protocol = "https"
+	}
+	if ci.ListenPort > 0 {
+		return fmt.Sprintf("%s://%s:%d", protocol, ci.Domain, ci.ListenPort)
+	}
+	return fmt.Sprintf("%s://%s", protocol, ci.Domain)
+}
+
+// renderCaddyfile returns the Caddy configuration for Vertex. Caddy proxies
+// WebSockets and redirects HTTP to HTTPS on its own.
+func (ci *CaddyInstaller) renderCaddyfile() string {
+	var global string
+	if ci.HTTPSEnabled {
+		global = "\tlocal_certs\n"
+	}
+	if ci.ListenPort > 0 {
+		// Without the standard ports there is nothing to redirect from
+		global += "\tauto_https disable_redirects\n"
+	}
+
+	return fmt.Sprintf(`# Vertex Service Manager Configuration
+{
+	admin localhost:2019
+%s}
+
+%s {
+	encode gzip
+
+	header {
+		X-Frame-Options DENY
+		X-Content-Type-Options nosniff
+	}
+
+	@static path_regexp \.(js|css|png|jpg|jpeg|gif|ico|svg|woff|woff2|ttf|eot)$
+	header @static Cache-Control "public, max-age=31536000, immutable"
+
+	reverse_proxy 127.0.0.1:%s
+}
+`, global, ci.siteAddress(), ci.Port)
+}
+
+// InstallCaddyConfig writes the Caddyfile and runs Caddy as a user service
+func (ci *CaddyInstaller) InstallCaddyConfig() error {
+	if err := os.MkdirAll(ci.ConfigDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", ci.ConfigDir, err)
+	}
+
+	var steps []privilegedStep
+	installedBefore := ci.IsCaddyInstalled()
+	if !installedBefore {
+		install := packageInstallCommand("caddy")
+		if ci.NoSudo && strings.Contains(install, "sudo") {
+			steps = append(steps, privilegedStep{"Install caddy", install})
+		} else {
+			fmt.Printf("📦 Caddy not found, installing automatically...\n")
+			if output, err := exec.Command("sh", "-c", install).CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to install caddy: %s", strings.TrimSpace(string(output)))
+			}
+			fmt.Printf("✅ Caddy installed successfully\n")
+		}
+	}
+
+	fmt.Printf("🌐 Configuring caddy for %s...\n", ci.Domain)
+	caddyfile := ci.caddyfilePath()
+	if err := os.WriteFile(caddyfile, []byte(ci.renderCaddyfile()), 0644); err != nil {
+		return fmt.Errorf("failed to write Caddyfile: %v", err)
+	}
+	fmt.Printf("✅ Generated %s\n", caddyfile)
+
+	if ci.IsCaddyInstalled() {
+		if output, err := exec.Command("caddy", "validate", "--config", caddyfile, "--adapter", "caddyfile").CombinedOutput(); err != nil {
+			return fmt.Errorf("caddy configuration test failed: %s", strings.TrimSpace(string(output)))
+		}
+		fmt.Printf("✅ Caddy configuration test passed\n")
+	}
+
+	// Binding 80/443 as a normal user needs a capability on Linux
+	if runtime.GOOS == "linux" && ci.ListenPort == 0 {
+		caddyPath, err := exec.LookPath("caddy")
+		if err != nil {
+			caddyPath = "/usr/bin/caddy"
+		}
+		steps = append(steps, privilegedStep{"Allow caddy to bind ports 80 and 443", "sudo setcap cap_net_bind_service=+ep " + shellQuote(caddyPath)})
+	}
+
+	if step := hostsEntryStep(ci.Domain); step != nil {
+		steps = append(steps, *step)
+	}
+
+	if !ci.NoSudo {
+		for _, step := range steps {
+			fmt.Printf("🔐 %s...\n", step.Comment)
+			if output, err := exec.Command("sh", "-c", step.Command).CombinedOutput(); err != nil {
+				fmt.Printf("⚠️  Could not %s: %s\n", strings.ToLower(step.Comment), strings.TrimSpace(string(output)))
+				fmt.Printf("Please run manually: %s\n", step.Command)
+			}
+		}
+		steps = nil
+	}
+
+	if ci.IsCaddyInstalled() {
+		if err := ci.startCaddyService(); err != nil {
+			fmt.Printf("⚠️  Could not start caddy automatically: %v\n", err)
+			fmt.Printf("Please start caddy manually:\n  caddy run --config %s --adapter caddyfile\n", caddyfile)
+		}
+	}
+
+	if len(steps) > 0 {
+		if installedBefore {
+			steps = append(steps, privilegedStep{"Restart caddy with the new permissions", ci.restartCommand()})
+		} else {
+			steps = append(steps, privilegedStep{"Set up the caddy service now that caddy is installed", ci.reinstallCommand()})
+		}
+	}
+
+	// The internal CA exists once Caddy has started; trusting it prompts for a password
+	if ci.HTTPSEnabled {
+		if ci.NoSudo {
+			steps = append(steps, privilegedStep{"Trust Caddy's local certificate authority (caddy asks for your password)", "caddy trust"})
+		} else if err := exec.Command("caddy", "trust").Run(); err != nil {
+			fmt.Printf("⚠️  Could not trust Caddy's local CA: %v\n", err)
+			fmt.Printf("Please run: caddy trust\n")
+		}
+	}
+
+	if len(steps) > 0 {
+		return writePrivilegedScript(ci.ConfigDir, ci.Domain, steps)
+	}
+
+	fmt.Printf("✅ Caddy configured successfully!\n")
+	fmt.Printf("🌐 Vertex is now available at: %s\n", ci.URL())
+	return nil
+}
+
+// startCaddyService runs Caddy as a systemd user service on Linux, a
+// LaunchAgent on macOS, and in the background elsewhere
+func (ci *CaddyInstaller) startCaddyService() error {
+	caddyPath, err := exec.LookPath("caddy")
+	if err != nil {
+		return err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		systemdDir := filepath.Join(homeDir, ".config", "systemd", "user")
+		if err := os.MkdirAll(systemdDir, 0755); err != nil {
+			return err
+		}
+		serviceContent := fmt.Sprintf(`[Unit]
+Description=Caddy proxy for Vertex
+After=network.target
+
+[Service]
+Type=notify
+ExecStart=%s run --environ --config %s --adapter caddyfile
+ExecReload=%s reload --config %s --adapter caddyfile --force
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=default.target`, caddyPath, ci.caddyfilePath(), caddyPath, ci.caddyfilePath())
+		if err := os.WriteFile(filepath.Join(systemdDir, "vertex-caddy.service"), []byte(serviceContent), 0644); err != nil {
+			return err
+		}
+		for _, args := range [][]string{
+			{"--user", "daemon-reload"},
+			{"--user", "enable", "vertex-caddy"},
+			{"--user", "restart", "vertex-caddy"},
+		} {
+			if output, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+				return fmt.Errorf("systemctl %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(output)))
+			}
+		}
+
+	case "darwin":
+		plistFile := filepath.Join(homeDir, "Library", "LaunchAgents", "com.vertex.caddy.plist")
+		if err := os.MkdirAll(filepath.Dir(plistFile), 0755); err != nil {
+			return err
+		}
+		plistContent := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+    <key>Label</key>
+    <string>com.vertex.caddy</string>
+    <key>ProgramArguments</key>
+    <array>
+        <string>%s</string>
+        <string>run</string>
+        <string>--config</string>
+        <string>%s</string>
+        <string>--adapter</string>
+        <string>caddyfile</string>
+    </array>
+    <key>RunAtLoad</key>
+    <true/>
+    <key>KeepAlive</key>
+    <true/>
+    <key>StandardOutPath</key>
+    <string>%s/caddy.stdout.log</string>
+    <key>StandardErrorPath</key>
+    <string>%s/caddy.stderr.log</string>
+</dict>
+</plist>`, caddyPath, ci.caddyfilePath(), ci.ConfigDir, ci.ConfigDir)
+		if err := os.WriteFile(plistFile, []byte(plistContent), 0644); err != nil {
+			return err
+		}
+		exec.Command("launchctl", "unload", plistFile).Run()
+		if output, err := exec.Command("launchctl", "load", plistFile).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to load LaunchAgent: %s", strings.TrimSpace(string(output)))
+		}
+
+	default:
+		exec.Command(caddyPath, "stop").Run()
+		if output, err := exec.Command(caddyPath, "start", "--config", ci.caddyfilePath(), "--adapter", "caddyfile").CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to start caddy: %s", strings.TrimSpace(string(output)))
+		}
+	}
+
+	fmt.Printf("✅ Caddy service started\n")
+	return nil
+}
+
+func (ci *CaddyInstaller) restartCommand() string {
+	switch runtime.GOOS {
+	case "linux":
+		return "systemctl --user restart vertex-caddy"
+	case "darwin":
+		return "launchctl kickstart -k gui/$(id -u)/com.vertex.caddy"
+	default:
+		return "caddy reload --config " + shellQuote(ci.caddyfilePath()) + " --adapter caddyfile"
+	}
+}
+
+func (ci *CaddyInstaller) reinstallCommand() string {
+	command := "vertex install --proxy caddy --domain " + shellQuote(ci.Domain) + " --port " + ci.Port
+	if ci.HTTPSEnabled {
+		command += " --https"
+	}
+	if ci.ListenPort > 0 {
+		command += fmt.Sprintf(" --proxy-port %d", ci.ListenPort)
+	}
+	return command + " --no-sudo"
+}
+
+// UninstallCaddyConfig stops the Caddy user service and removes its files
+func (ci *CaddyInstaller) UninstallCaddyConfig() error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	switch runtime.GOOS {
+	case "linux":
+		serviceFile := filepath.Join(homeDir, ".config", "systemd", "user", "vertex-caddy.service")
+		if _, err := os.Stat(serviceFile); err != nil {
+			return nil
+		}
+		exec.Command("systemctl", "--user", "stop", "vertex-caddy").Run()
+		exec.Command("systemctl", "--user", "disable", "vertex-caddy").Run()
+		os.Remove(serviceFile)
+		exec.Command("systemctl", "--user", "daemon-reload").Run()
+	case "darwin":
+		plistFile := filepath.Join(homeDir, "Library", "LaunchAgents", "com.vertex.caddy.plist")
+		if _, err := os.Stat(plistFile); err != nil {
+			return nil
+		}
+		exec.Command("launchctl", "unload", plistFile).Run()
+		os.Remove(plistFile)
+	default:
+		exec.Command("caddy", "stop").Run()
+	}
+
+	fmt.Printf("✅ Caddy proxy removed\n")
+	return nil
+}
+
+// VerifyInstallation checks that Caddy is configured for the domain and
+// Vertex answers through it
+func (ci *CaddyInstaller) VerifyInstallation() error {
+	var checks proxyChecklist
+
+	checks.check("caddy installed", func() error {
+		if !ci.IsCaddyInstalled() {
+			return fmt.Errorf("caddy not found in PATH")
+		}
+		return nil
+	}())
+
+	checks.check("Caddyfile at "+ci.caddyfilePath(), func() error {
+		content, err := os.ReadFile(ci.caddyfilePath())
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(content), ci.siteAddress()+" {") {
+			return fmt.Errorf("not configured for %s", ci.siteAddress())
+		}
+		return nil
+	}())
+
+	checks.checkHostsEntry(ci.Domain)
+	checks.checkReachable(ci.URL()+"/", ci.Port, "caddy trust")
+
+	return checks.result(ci.Domain)
+}
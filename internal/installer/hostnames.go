@@ -0,0 +1,194 @@
+package installer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// NginxHostname proxies a local hostname of a profile, e.g. api.test, to a
+// managed service through a server block of its own
+type NginxHostname struct {
+	Hostname    string `json:"hostname"`
+	Port        int    `json:"port"`
+	ServiceName string `json:"serviceName"`
+}
+
+// HostsFileResult reports whether the hosts file entries were updated and
+// what is left for the user to do
+type HostsFileResult struct {
+	HostsFile    string `json:"hostsFile"`
+	Updated      bool   `json:"updated"`                // The hosts file was rewritten
+	Instructions string `json:"instructions,omitempty"` // Commands to run when Vertex could not write it itself
+}
+
+const (
+	nginxHostnamesFile        = "hostnames.json"
+	nginxHostnamesBeginMarker = "# BEGIN profile hostnames (managed by Vertex)"
+	nginxHostnamesEndMarker   = "# END profile hostnames"
+	hostsBeginMarker          = "# BEGIN Vertex profile hostnames"
+	hostsEndMarker            = "# END Vertex profile hostnames"
+)
+
+// LoadNginxHostnames reads the profile hostnames saved in the nginx output
+// directory; a missing file means no hostnames
+func LoadNginxHostnames(outputDir string) ([]NginxHostname, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, nginxHostnamesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nginx profile hostnames: %v", err)
+	}
+
+	var hostnames []NginxHostname
+	if err := json.Unmarshal(data, &hostnames); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", nginxHostnamesFile, err)
+	}
+	return hostnames, nil
+}
+
+// saveNginxHostnames records the profile hostnames so a later install
+// renders them too
+func saveNginxHostnames(outputDir string, hostnames []NginxHostname) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", outputDir, err)
+	}
+	data, err := json.MarshalIndent(hostnames, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, nginxHostnamesFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save nginx profile hostnames: %v", err)
+	}
+	return nil
+}
+
+// renderHostnameServers returns a plain HTTP server block per profile
+// hostname between the markers ApplyNginxHostnames replaces
+func renderHostnameServers(hostnames []NginxHostname) string {
+	var b strings.Builder
+	b.WriteString(nginxHostnamesBeginMarker + "\n")
+	for _, hostname := range hostnames {
+		fmt.Fprintf(&b, `server {
+    listen 80;
+    server_name %s;
+
+    location / {
+        proxy_pass http://127.0.0.1:%d;
+        proxy_http_version 1.1;
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection "upgrade";
+        proxy_read_timeout 300;
+    }
+}
+`, hostname.Hostname, hostname.Port)
+	}
+	b.WriteString(nginxHostnamesEndMarker + "\n")
+	return b.String()
+}
+
+// replaceHostnameServers swaps the profile hostname servers of a rendered
+// vertex.conf, appending them to configurations written before they existed
+func replaceHostnameServers(config string, hostnames []NginxHostname) string {
+	rendered := renderHostnameServers(hostnames)
+
+	begin := strings.Index(config, nginxHostnamesBeginMarker)
+	end := strings.Index(config, nginxHostnamesEndMarker+"\n")
+	if begin >= 0 && end > begin {
+		return config[:begin] + rendered + config[end+len(nginxHostnamesEndMarker)+1:]
+	}
+	return strings.TrimRight(config, "\n") + "\n\n" + rendered
+}
+
+// ApplyNginxHostnames saves the profile hostnames and rewrites their server
+// blocks into the installed vertex.conf
+func ApplyNginxHostnames(outputDir string, hostnames []NginxHostname) (*NginxLocationsResult, error) {
+	if err := saveNginxHostnames(outputDir, hostnames); err != nil {
+		return nil, err
+	}
+	return patchVertexConf(outputDir, func(config string) (string, error) {
+		return replaceHostnameServers(config, hostnames), nil
+	})
+}
+
+// hostsFilePath returns the location of the system hosts file
+func hostsFilePath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("SystemRoot"), "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
+
+// replaceHostsBlock swaps the Vertex block of a hosts file for one pointing
+// the hostnames at the loopback address; no hostnames removes the block
+func replaceHostsBlock(content string, hostnames []string) string {
+	begin := strings.Index(content, hostsBeginMarker)
+	end := strings.Index(content, hostsEndMarker)
+	if begin >= 0 && end > begin {
+		rest := strings.TrimPrefix(content[end+len(hostsEndMarker):], "\n")
+		content = content[:begin] + rest
+	}
+	if len(hostnames) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	b.WriteString(hostsBeginMarker + "\n")
+	for _, hostname := range hostnames {
+		fmt.Fprintf(&b, "127.0.0.1 %s\n", hostname)
+	}
+	b.WriteString(hostsEndMarker + "\n")
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + b.String()
+}
+
+// ApplyHostsEntries points the hostnames at 127.0.0.1 in the hosts file,
+// replacing the entries Vertex wrote before. When the hosts file is not
+// writable, the updated file goes to outputDir and the result carries the
+// command that installs it.
+func ApplyHostsEntries(outputDir string, hostnames []string) (*HostsFileResult, error) {
+	hostsFile := hostsFilePath()
+	result := &HostsFileResult{HostsFile: hostsFile}
+
+	content, err := os.ReadFile(hostsFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %v", hostsFile, err)
+	}
+	updated := replaceHostsBlock(string(content), hostnames)
+	if updated == string(content) {
+		return result, nil
+	}
+
+	if err := os.WriteFile(hostsFile, []byte(updated), 0644); err != nil {
+		if !errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("failed to write %s: %v", hostsFile, err)
+		}
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %v", outputDir, err)
+		}
+		generated := filepath.Join(outputDir, "hosts")
+		if err := os.WriteFile(generated, []byte(updated), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write hosts file: %v", err)
+		}
+		result.Instructions = fmt.Sprintf("sudo install -m 644 %s %s", shellQuote(generated), shellQuote(hostsFile))
+		if runtime.GOOS == "windows" {
+			result.Instructions = fmt.Sprintf("copy %s over %s from an administrator prompt", generated, hostsFile)
+		}
+		return result, nil
+	}
+
+	result.Updated = true
+	return result, nil
+}
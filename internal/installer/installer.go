@@ -12,11 +12,16 @@ import (
 type ServiceInstaller struct {
 	BinaryPath   string
 	Port         string
+	Bind         string // Address Vertex listens on
+	BasePath     string // Path prefix Vertex is served under behind a reverse proxy, e.g. /vertex
 	DataDir      string
 	User         string
 	Domain       string
-	EnableNginx  bool
+	EnableNginx  bool   // Expose Vertex on Domain through a reverse proxy
+	Proxy        string // "nginx" or "caddy"
+	ProxyPort    int    // Port caddy listens on; 0 uses 80/443
 	HTTPSEnabled bool
+	NoSudo       bool // Leave privileged proxy steps to a generated script
 }
 
 // NewServiceInstaller creates a new service installer
@@ -37,10 +42,12 @@ func NewServiceInstaller() *ServiceInstaller {
 	return &ServiceInstaller{
 		BinaryPath:   execPath,
 		Port:         "54321",
+		Bind:         "127.0.0.1",
 		DataDir:      dataDir,
 		User:         user,
 		Domain:       "vertex.dev",
 		EnableNginx:  false,
+		Proxy:        ProxyNginx,
 		HTTPSEnabled: false,
 	}
 }
@@ -69,7 +76,12 @@ func (si *ServiceInstaller) Install() error {
 		return serviceErr
 	}
 	if si.EnableNginx {
-		if err := si.installNginxConfig(); err != nil {
+		if si.Proxy == ProxyCaddy {
+			if err := si.caddyInstaller().InstallCaddyConfig(); err != nil {
+				fmt.Printf("⚠️  Caddy configuration failed: %v\n", err)
+				fmt.Printf("Service is still accessible at http://localhost:%s\n", si.Port)
+			}
+		} else if err := si.installNginxConfig(); err != nil {
 			fmt.Printf("⚠️  Nginx configuration failed: %v\n", err)
 			fmt.Printf("Service is still accessible at http://localhost:%s\n", si.Port)
 		}
@@ -162,6 +174,9 @@ func (si *ServiceInstaller) installMacOSService() error {
 	if mavenHome != "" {
 		envVars["MAVEN_HOME"] = mavenHome
 	}
+	if si.BasePath != "" {
+		envVars["VERTEX_BASE_PATH"] = si.BasePath
+	}
 	envVarsXML := ""
 	for key, value := range envVars {
 		envVarsXML += fmt.Sprintf("        <key>%s</key>\n        <string>%s</string>\n", key, value)
@@ -177,6 +192,8 @@ func (si *ServiceInstaller) installMacOSService() error {
         <string>%s</string>
         <string>--port</string>
         <string>%s</string>
+        <string>--bind</string>
+        <string>%s</string>
     </array>
     <key>EnvironmentVariables</key>
     <dict>
@@ -191,7 +208,7 @@ func (si *ServiceInstaller) installMacOSService() error {
     <key>StandardErrorPath</key>
     <string>%s/vertex.stderr.log</string>
 </dict>
-</plist>`, binaryPath, si.Port, envVarsXML, si.DataDir, si.DataDir)
+</plist>`, binaryPath, si.Port, si.Bind, envVarsXML, si.DataDir, si.DataDir)
 	if err := os.WriteFile(plistFile, []byte(plistContent), 0644); err != nil {
 		return err
 	}
@@ -233,22 +250,44 @@ func (si *ServiceInstaller) installLinuxService() error {
 	if mavenHome != "" {
 		envVars = append(envVars, fmt.Sprintf("Environment=MAVEN_HOME=%s", mavenHome))
 	}
+	if si.BasePath != "" {
+		envVars = append(envVars, fmt.Sprintf("Environment=VERTEX_BASE_PATH=%s", si.BasePath))
+	}
 	envVarsStr := ""
 	for _, env := range envVars {
 		envVarsStr += env + "\n"
 	}
+	// Type=notify: Vertex reports READY=1 once it listens and pings the
+	// watchdog while its API answers. KillMode=mixed sends SIGTERM to Vertex
+	// only, so it can stop the managed services itself before the rest of
+	// the cgroup is killed. Sandboxing options that need privileges
+	// (ProtectSystem, PrivateTmp, ...) are not available to user units.
 	serviceContent := fmt.Sprintf(`[Unit]
 Description=Vertex Service Manager
 After=network.target
+StartLimitIntervalSec=300
+StartLimitBurst=5
 
 [Service]
-Type=simple
-ExecStart=%s --port %s
-%sRestart=always
+Type=notify
+NotifyAccess=main
+ExecStart=%s --port %s --bind %s
+%sRestart=on-failure
 RestartSec=5
+WatchdogSec=%d
+TimeoutStartSec=60
+TimeoutStopSec=60
+KillMode=mixed
+LimitNOFILE=65536
+TasksMax=8192
+UMask=0022
+StandardOutput=journal
+StandardError=journal
+SyslogIdentifier=vertex
 
 [Install]
-WantedBy=default.target`, binaryPath, si.Port, envVarsStr)
+WantedBy=default.target
+`, binaryPath, si.Port, si.Bind, envVarsStr, systemdWatchdogSec)
 	if err := os.WriteFile(serviceFile, []byte(serviceContent), 0644); err != nil {
 		return err
 	}
@@ -294,7 +333,10 @@ set PATH=%s
 	if mavenHome != "" {
 		batchContent += fmt.Sprintf("set MAVEN_HOME=%s\n", mavenHome)
 	}
-	batchContent += fmt.Sprintf(`"%s" --port %s`, binaryPath, si.Port)
+	if si.BasePath != "" {
+		batchContent += fmt.Sprintf("set VERTEX_BASE_PATH=%s\n", si.BasePath)
+	}
+	batchContent += fmt.Sprintf(`"%s" --port %s --bind %s`, binaryPath, si.Port, si.Bind)
 	if err := os.WriteFile(batchFile, []byte(batchContent), 0644); err != nil {
 		return err
 	}
@@ -316,6 +358,9 @@ set PATH=%s
 // Uninstall removes the service
 func (si *ServiceInstaller) Uninstall() error {
 	fmt.Printf("🗑️ Uninstalling Vertex service...\n")
+	if err := si.caddyInstaller().UninstallCaddyConfig(); err != nil {
+		fmt.Printf("⚠️  Could not remove caddy proxy: %v\n", err)
+	}
 	switch runtime.GOOS {
 	case "darwin":
 		return si.uninstallMacOSService()
@@ -372,9 +417,43 @@ func (si *ServiceInstaller) uninstallWindowsService() error {
 
 // installNginxConfig installs nginx configuration for domain access
 func (si *ServiceInstaller) installNginxConfig() error {
+	nginxInstaller := si.nginxInstaller()
+	if err := nginxInstaller.InstallNginxConfig(); err != nil {
+		return err
+	}
+
+	// Service domains are subdomains of the site, so the server needs to know it
+	site := NginxSite{Domain: nginxInstaller.Domain, HTTPS: nginxInstaller.HTTPSEnabled, Port: nginxInstaller.Port}
+	if err := saveNginxSite(nginxInstaller.OutputDir, site); err != nil {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	return nil
+}
+
+// VerifyProxy checks the nginx setup for the domain, typically after running
+// the privileged script generated in low-privilege mode
+func (si *ServiceInstaller) VerifyProxy() error {
+	if si.Proxy == ProxyCaddy {
+		return si.caddyInstaller().VerifyInstallation()
+	}
+	return si.nginxInstaller().VerifyInstallation()
+}
+
+func (si *ServiceInstaller) caddyInstaller() *CaddyInstaller {
+	caddyInstaller := NewCaddyInstaller(si.Domain, si.Port, filepath.Join(si.DataDir, "caddy"))
+	caddyInstaller.EnableHTTPS(si.HTTPSEnabled)
+	caddyInstaller.ListenPort = si.ProxyPort
+	caddyInstaller.NoSudo = si.NoSudo
+	return caddyInstaller
+}
+
+func (si *ServiceInstaller) nginxInstaller() *NginxInstaller {
 	nginxInstaller := NewNginxInstaller(si.Domain, si.Port)
 	nginxInstaller.EnableHTTPS(si.HTTPSEnabled)
-	return nginxInstaller.InstallNginxConfig()
+	nginxInstaller.NoSudo = si.NoSudo
+	nginxInstaller.OutputDir = filepath.Join(si.DataDir, "nginx")
+	nginxInstaller.loadManagedBlocks()
+	return nginxInstaller
 }
 
 // SetDomain sets the domain for nginx configuration
@@ -391,3 +470,20 @@ func (si *ServiceInstaller) EnableNginxProxy(enable bool) {
 func (si *ServiceInstaller) EnableHTTPS(enable bool) {
 	si.HTTPSEnabled = enable
 }
+
+// SetProxy selects the reverse proxy ("nginx" or "caddy") and, for caddy,
+// the port it listens on
+func (si *ServiceInstaller) SetProxy(proxy string, port int) error {
+	if proxy != ProxyNginx && proxy != ProxyCaddy {
+		return fmt.Errorf("unsupported proxy '%s', use nginx or caddy", proxy)
+	}
+	si.Proxy = proxy
+	si.ProxyPort = port
+	return nil
+}
+
+// SetNoSudo enables low-privilege mode: proxy configs are generated into the
+// data directory and privileged commands are printed instead of run
+func (si *ServiceInstaller) SetNoSudo(noSudo bool) {
+	si.NoSudo = noSudo
+}
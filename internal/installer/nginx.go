@@ -18,6 +18,11 @@ type NginxInstaller struct {
 	ConfigPath string
 	SitesPath  string
 	HTTPSEnabled bool
+	NoSudo       bool   // Generate files and a script of privileged commands instead of running sudo
+	OutputDir    string // Where configs and the script are generated when NoSudo is set
+	Locations    []NginxLocation // Managed services exposed under their own path prefix
+	Hostnames    []NginxHostname // Profile hostnames proxied to managed services
+	ServiceDomains []NginxServiceDomain // Subdomains of Domain proxied to managed services
 }
 
 // NewNginxInstaller creates a new nginx installer
@@ -211,51 +216,8 @@ func (ni *NginxInstaller) createSitesDirectory() error {
 
 // createNginxDirectories creates nginx log and run directories with proper permissions
 func (ni *NginxInstaller) createNginxDirectories() error {
-	var directories []string
-	var logFiles []string
-	var pidFiles []string
-	
-	switch runtime.GOOS {
-	case "darwin":
-		// Detect homebrew path and create corresponding directories
-		if strings.Contains(ni.ConfigPath, "/opt/homebrew/") {
-			directories = []string{
-				"/opt/homebrew/var/log/nginx",
-				"/opt/homebrew/var/run",
-			}
-			logFiles = []string{
-				"/opt/homebrew/var/log/nginx/access.log",
-				"/opt/homebrew/var/log/nginx/error.log",
-			}
-			pidFiles = []string{
-				"/opt/homebrew/var/run/nginx.pid",
-			}
-		} else {
-			directories = []string{
-				"/usr/local/var/log/nginx",
-				"/usr/local/var/run",
-			}
-			logFiles = []string{
-				"/usr/local/var/log/nginx/access.log",
-				"/usr/local/var/log/nginx/error.log",
-			}
-			pidFiles = []string{
-				"/usr/local/var/run/nginx.pid",
-			}
-		}
-	case "linux":
-		directories = []string{
-			"/var/log/nginx",
-			"/var/run/nginx",
-		}
-		logFiles = []string{
-			"/var/log/nginx/access.log",
-			"/var/log/nginx/error.log",
-		}
-		pidFiles = []string{
-			"/var/run/nginx/nginx.pid",
-		}
-	default:
+	directories, logFiles, pidFiles := ni.runtimePaths()
+	if len(directories) == 0 {
 		// Skip directory creation for unsupported platforms
 		return nil
 	}
@@ -373,8 +335,59 @@ func (ni *NginxInstaller) createNginxDirectories() error {
 	return nil
 }
 
+// runtimePaths returns the log and PID locations nginx needs on this platform
+func (ni *NginxInstaller) runtimePaths() (directories, logFiles, pidFiles []string) {
+	switch runtime.GOOS {
+	case "darwin":
+		// Detect homebrew path and create corresponding directories
+		if strings.Contains(ni.ConfigPath, "/opt/homebrew/") {
+			directories = []string{
+				"/opt/homebrew/var/log/nginx",
+				"/opt/homebrew/var/run",
+			}
+			logFiles = []string{
+				"/opt/homebrew/var/log/nginx/access.log",
+				"/opt/homebrew/var/log/nginx/error.log",
+			}
+			pidFiles = []string{
+				"/opt/homebrew/var/run/nginx.pid",
+			}
+		} else {
+			directories = []string{
+				"/usr/local/var/log/nginx",
+				"/usr/local/var/run",
+			}
+			logFiles = []string{
+				"/usr/local/var/log/nginx/access.log",
+				"/usr/local/var/log/nginx/error.log",
+			}
+			pidFiles = []string{
+				"/usr/local/var/run/nginx.pid",
+			}
+		}
+	case "linux":
+		directories = []string{
+			"/var/log/nginx",
+			"/var/run/nginx",
+		}
+		logFiles = []string{
+			"/var/log/nginx/access.log",
+			"/var/log/nginx/error.log",
+		}
+		pidFiles = []string{
+			"/var/run/nginx/nginx.pid",
+		}
+	}
+
+	return directories, logFiles, pidFiles
+}
+
 // InstallNginxConfig creates nginx configuration for Vertex
 func (ni *NginxInstaller) InstallNginxConfig() error {
+	if ni.NoSudo {
+		return ni.installWithoutSudo()
+	}
+
 	if !ni.IsNginxInstalled() {
 		fmt.Printf("📦 Nginx not found, installing automatically...\n")
 		if err := ni.installNginx(); err != nil {
@@ -447,157 +460,48 @@ func (ni *NginxInstaller) InstallNginxConfig() error {
 	return nil
 }
 
+// renderConfig returns the nginx server configuration for Vertex, rendered
+// from the template in the output directory. The built-in template is
+// written there first so users have a file to customize.
+func (ni *NginxInstaller) renderConfig() (string, error) {
+	tmpl, err := LoadNginxTemplate(ni.OutputDir)
+	if err != nil {
+		return "", err
+	}
+	if ni.OutputDir != "" {
+		if _, err := os.Stat(tmpl.Path); os.IsNotExist(err) {
+			if err := saveNginxTemplate(ni.OutputDir, tmpl); err != nil {
+				fmt.Printf("⚠️  %v\n", err)
+			}
+		}
+	}
+	return ni.renderConfigFrom(tmpl)
+}
+
+// renderConfigFrom renders the Vertex site from a template, followed by the
+// server blocks of the profile hostnames and service domains
+func (ni *NginxInstaller) renderConfigFrom(tmpl *NginxTemplate) (string, error) {
+	config, err := ni.renderSite(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	// Profile hostnames and service domains get server blocks of their own after the Vertex site
+	return config + "\n\n" + renderHostnameServers(ni.Hostnames) + "\n" + renderServiceDomainServers(ni.ServiceDomains), nil
+}
+
 // createNginxConfig creates the nginx configuration file
 func (ni *NginxInstaller) createNginxConfig(configFile string) error {
-	var config string
-	
-	if ni.HTTPSEnabled {
-		// HTTPS configuration with SSL certificates
-		sslDir := filepath.Join(os.Getenv("HOME"), ".vertex", "ssl")
-		certFile := filepath.Join(sslDir, ni.Domain+".pem")
-		keyFile := filepath.Join(sslDir, ni.Domain+"-key.pem")
-		
-		config = fmt.Sprintf(`# Vertex Service Manager Configuration (HTTPS)
-# HTTP to HTTPS redirect
-server {
-    listen 80;
-    server_name %s;
-    return 301 https://$server_name$request_uri;
-}
+	config, err := ni.renderConfig()
+	if err != nil {
+		return err
+	}
 
-# HTTPS server
-server {
-    listen 443 ssl http2;
-    server_name %s;
-
-    # SSL Configuration
-    ssl_certificate %s;
-    ssl_certificate_key %s;
-    ssl_session_timeout 1d;
-    ssl_session_cache shared:SSL:50m;
-    ssl_session_tickets off;
-
-    # Modern configuration
-    ssl_protocols TLSv1.2 TLSv1.3;
-    ssl_ciphers ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384;
-    ssl_prefer_server_ciphers off;
-
-    # Security headers
-    add_header X-Frame-Options DENY;
-    add_header X-Content-Type-Options nosniff;
-    add_header X-XSS-Protection "1; mode=block";
-    add_header Strict-Transport-Security "max-age=63072000" always;
-
-    # Proxy settings
-    proxy_set_header Host $host;
-    proxy_set_header X-Real-IP $remote_addr;
-    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
-    proxy_set_header X-Forwarded-Proto $scheme;
-
-    # Main application
-    location / {
-        proxy_pass http://127.0.0.1:%s;
-        proxy_http_version 1.1;
-        proxy_set_header Upgrade $http_upgrade;
-        proxy_set_header Connection 'upgrade';
-        proxy_cache_bypass $http_upgrade;
-        proxy_read_timeout 86400;
-    }
-
-    # WebSocket support for real-time features
-    location /ws {
-        proxy_pass http://127.0.0.1:%s;
-        proxy_http_version 1.1;
-        proxy_set_header Upgrade $http_upgrade;
-        proxy_set_header Connection "upgrade";
-        proxy_set_header Host $host;
-        proxy_cache_bypass $http_upgrade;
-    }
-
-    # API endpoints
-    location /api/ {
-        proxy_pass http://127.0.0.1:%s;
-        proxy_http_version 1.1;
-        proxy_set_header Host $host;
-        proxy_set_header X-Real-IP $remote_addr;
-        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
-        proxy_set_header X-Forwarded-Proto $scheme;
-    }
-
-    # Static assets with caching
-    location ~* \.(js|css|png|jpg|jpeg|gif|ico|svg|woff|woff2|ttf|eot)$ {
-        proxy_pass http://127.0.0.1:%s;
-        expires 1y;
-        add_header Cache-Control "public, immutable";
-    }
-
-    # Gzip compression
-    gzip on;
-    gzip_vary on;
-    gzip_min_length 1024;
-    gzip_types text/plain text/css text/xml text/javascript application/javascript application/xml+rss application/json;
-}`, ni.Domain, ni.Domain, certFile, keyFile, ni.Port, ni.Port, ni.Port, ni.Port)
-	} else {
-		// HTTP configuration
-		config = fmt.Sprintf(`# Vertex Service Manager Configuration
-server {
-    listen 80;
-    server_name %s;
-
-    # Security headers
-    add_header X-Frame-Options DENY;
-    add_header X-Content-Type-Options nosniff;
-    add_header X-XSS-Protection "1; mode=block";
-
-    # Proxy settings
-    proxy_set_header Host $host;
-    proxy_set_header X-Real-IP $remote_addr;
-    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
-    proxy_set_header X-Forwarded-Proto $scheme;
-
-    # Main application
-    location / {
-        proxy_pass http://127.0.0.1:%s;
-        proxy_http_version 1.1;
-        proxy_set_header Upgrade $http_upgrade;
-        proxy_set_header Connection 'upgrade';
-        proxy_cache_bypass $http_upgrade;
-        proxy_read_timeout 86400;
-    }
-
-    # WebSocket support for real-time features
-    location /ws {
-        proxy_pass http://127.0.0.1:%s;
-        proxy_http_version 1.1;
-        proxy_set_header Upgrade $http_upgrade;
-        proxy_set_header Connection "upgrade";
-        proxy_set_header Host $host;
-        proxy_cache_bypass $http_upgrade;
-    }
-
-    # API endpoints
-    location /api/ {
-        proxy_pass http://127.0.0.1:%s;
-        proxy_http_version 1.1;
-        proxy_set_header Host $host;
-        proxy_set_header X-Real-IP $remote_addr;
-        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
-        proxy_set_header X-Forwarded-Proto $scheme;
-    }
-
-    # Static assets with caching
-    location ~* \.(js|css|png|jpg|jpeg|gif|ico|svg|woff|woff2|ttf|eot)$ {
-        proxy_pass http://127.0.0.1:%s;
-        expires 1y;
-        add_header Cache-Control "public, immutable";
-    }
-
-    # Gzip compression
-    gzip on;
-    gzip_vary on;
-    gzip_min_length 1024;
-    gzip_types text/plain text/css text/xml text/javascript application/javascript application/xml+rss application/json;
-}`, ni.Domain, ni.Port, ni.Port, ni.Port, ni.Port)
+	// Keep hand edits of the installed file; they belong in the template
+	if current, err := os.ReadFile(configFile); err == nil && string(current) != config && ni.OutputDir != "" {
+		if backup, err := backupNginxConfig(ni.OutputDir, string(current)); err == nil {
+			fmt.Printf("⚠️  %s differed from the template; kept it as %s\n", configFile, backup)
+		}
 	}
 
 	// Try to write file normally first
@@ -784,6 +688,9 @@ func (ni *NginxInstaller) UninstallNginxConfig() error {
 
 	// Remove from hosts file
 	ni.removeFromHosts()
+	if result, err := ApplyHostsEntries(ni.OutputDir, nil); err == nil && result.Instructions != "" {
+		fmt.Printf("⚠️  To remove the profile hostnames from %s run: %s\n", result.HostsFile, result.Instructions)
+	}
 
 	// Reload nginx
 	ni.reloadNginx()
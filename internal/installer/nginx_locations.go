@@ -0,0 +1,193 @@
+package installer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// NginxLocation exposes a managed service under a path prefix of the Vertex
+// site, e.g. https://vertex.dev/svc/user-service/
+type NginxLocation struct {
+	Path        string `json:"path"` // Prefix ending in "/", stripped before proxying
+	Port        int    `json:"port"`
+	ServiceName string `json:"serviceName"`
+}
+
+// NginxLocationsResult reports where the service locations were written and
+// what is left for the user to do
+type NginxLocationsResult struct {
+	ConfigFile   string `json:"configFile,omitempty"`   // vertex.conf that was rewritten
+	Reloaded     bool   `json:"reloaded"`               // nginx picked up the change
+	Instructions string `json:"instructions,omitempty"` // Commands to run when Vertex could not finish itself
+}
+
+const (
+	nginxLocationsFile        = "locations.json"
+	nginxLocationsBeginMarker = "    # BEGIN service locations (managed by Vertex)"
+	nginxLocationsEndMarker   = "    # END service locations"
+	nginxMainLocationComment  = "    # Main application"
+)
+
+// LoadNginxLocations reads the service locations saved in the nginx output
+// directory; a missing file means no locations
+func LoadNginxLocations(outputDir string) ([]NginxLocation, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, nginxLocationsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nginx service locations: %v", err)
+	}
+
+	var locations []NginxLocation
+	if err := json.Unmarshal(data, &locations); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", nginxLocationsFile, err)
+	}
+	return locations, nil
+}
+
+// saveNginxLocations records the service locations so a later install
+// renders them too
+func saveNginxLocations(outputDir string, locations []NginxLocation) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", outputDir, err)
+	}
+	data, err := json.MarshalIndent(locations, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, nginxLocationsFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save nginx service locations: %v", err)
+	}
+	return nil
+}
+
+// renderLocations returns the location blocks of the managed services between
+// the markers ApplyNginxLocations replaces. They use ^~ so the static asset
+// regex location does not take over the services' own .js and .css files.
+func renderLocations(locations []NginxLocation) string {
+	var b strings.Builder
+	b.WriteString(nginxLocationsBeginMarker + "\n")
+	for _, location := range locations {
+		fmt.Fprintf(&b, `    location ^~ %s {
+        proxy_pass http://127.0.0.1:%d/;
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection "upgrade";
+        proxy_set_header X-Forwarded-Prefix %s;
+        proxy_read_timeout 300;
+    }
+`, location.Path, location.Port, strings.TrimSuffix(location.Path, "/"))
+	}
+	b.WriteString(nginxLocationsEndMarker + "\n")
+	return b.String()
+}
+
+// replaceLocations swaps the service locations of a rendered vertex.conf.
+// Configurations written before service locations existed get them inserted
+// ahead of the main application location of their last server block.
+func replaceLocations(config string, locations []NginxLocation) (string, error) {
+	rendered := renderLocations(locations)
+
+	begin := strings.Index(config, nginxLocationsBeginMarker)
+	end := strings.Index(config, nginxLocationsEndMarker+"\n")
+	if begin >= 0 && end > begin {
+		return config[:begin] + rendered + config[end+len(nginxLocationsEndMarker)+1:], nil
+	}
+
+	mainLocation := strings.LastIndex(config, nginxMainLocationComment)
+	if mainLocation < 0 {
+		return "", fmt.Errorf("vertex.conf has no '%s' location to add the services before; re-run the nginx install", strings.TrimSpace(nginxMainLocationComment))
+	}
+	return config[:mainLocation] + rendered + "\n" + config[mainLocation:], nil
+}
+
+// ApplyNginxLocations saves the service locations and rewrites them into the
+// installed vertex.conf
+func ApplyNginxLocations(outputDir string, locations []NginxLocation) (*NginxLocationsResult, error) {
+	result, err := patchVertexConf(outputDir, func(config string) (string, error) {
+		return replaceLocations(config, locations)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Saved only once nginx accepted them, so a rejected location is not
+	// rendered again by the next install
+	if err := saveNginxLocations(outputDir, locations); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// patchVertexConf rewrites the installed vertex.conf with patch, reloading
+// nginx when it can do so without sudo. The patched file is checked with
+// nginx -t before it is written, and the previous file is put back if nginx
+// then rejects its full configuration. When the installed file is not
+// writable, the updated file goes to outputDir and the result carries the
+// commands that install it.
+func patchVertexConf(outputDir string, patch func(config string) (string, error)) (*NginxLocationsResult, error) {
+	installed := filepath.Join(NewNginxInstaller("", "").SitesPath, "vertex.conf")
+	generated := filepath.Join(outputDir, "vertex.conf")
+	installCommand := fmt.Sprintf("sudo install -m 644 %s %s && sudo nginx -t && sudo nginx -s reload",
+		shellQuote(generated), shellQuote(installed))
+
+	source := installed
+	config, err := os.ReadFile(installed)
+	if errors.Is(err, os.ErrNotExist) {
+		source = generated
+		config, err = os.ReadFile(generated)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return &NginxLocationsResult{
+			Instructions: "nginx is not configured for Vertex yet; run: vertex install --nginx",
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", source, err)
+	}
+
+	updated, err := patch(string(config))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ValidateNginxConfig(updated); err != nil {
+		return nil, fmt.Errorf("%v; %s was left unchanged", err, source)
+	}
+
+	if err := os.WriteFile(source, []byte(updated), 0644); err != nil {
+		if !errors.Is(err, os.ErrPermission) {
+			return nil, fmt.Errorf("failed to write %s: %v", source, err)
+		}
+		if err := os.WriteFile(generated, []byte(updated), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write nginx config: %v", err)
+		}
+		return &NginxLocationsResult{ConfigFile: generated, Instructions: installCommand}, nil
+	}
+
+	result := &NginxLocationsResult{ConfigFile: source}
+	if source == generated {
+		// Generated with --no-sudo and not installed yet
+		result.Instructions = installCommand
+		return result, nil
+	}
+
+	if output, err := exec.Command("nginx", "-t").CombinedOutput(); err != nil {
+		if err := os.WriteFile(source, config, 0644); err != nil {
+			return nil, fmt.Errorf("nginx rejected the updated %s (%s) and it could not be restored: %v",
+				source, strings.TrimSpace(string(output)), err)
+		}
+		return nil, fmt.Errorf("nginx rejected the updated %s (%s); the previous version was restored",
+			source, strings.TrimSpace(string(output)))
+	}
+	if err := exec.Command("nginx", "-s", "reload").Run(); err != nil {
+		result.Instructions = "sudo nginx -s reload"
+		return result, nil
+	}
+	result.Reloaded = true
+	return result, nil
+}
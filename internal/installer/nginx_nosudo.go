@@ -0,0 +1,162 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// installWithoutSudo generates the nginx configuration and certificates into
+// the output directory and writes every command that needs root into a single
+// script for the user to review and run. Nothing here invokes sudo.
+func (ni *NginxInstaller) installWithoutSudo() error {
+	if ni.OutputDir == "" {
+		return fmt.Errorf("no output directory set for low-privilege mode")
+	}
+	if err := os.MkdirAll(ni.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", ni.OutputDir, err)
+	}
+
+	fmt.Printf("🌐 Generating nginx configuration for %s in %s (no sudo)...\n", ni.Domain, ni.OutputDir)
+
+	var steps []privilegedStep
+	if !ni.IsNginxInstalled() {
+		steps = append(steps, privilegedStep{"Install nginx", ni.nginxInstallCommand()})
+	}
+
+	if ni.HTTPSEnabled {
+		steps = append(steps, ni.prepareCertificatesWithoutSudo()...)
+	}
+
+	config, err := ni.renderConfig()
+	if err != nil {
+		return err
+	}
+	generatedConfig := filepath.Join(ni.OutputDir, "vertex.conf")
+	if err := os.WriteFile(generatedConfig, []byte(config), 0644); err != nil {
+		return fmt.Errorf("failed to write nginx config: %v", err)
+	}
+	fmt.Printf("✅ Generated %s\n", generatedConfig)
+
+	configFile := filepath.Join(ni.SitesPath, "vertex.conf")
+	steps = append(steps,
+		privilegedStep{"Create the nginx sites directory", "sudo mkdir -p " + shellQuote(ni.SitesPath)},
+		privilegedStep{"Install the generated configuration", "sudo install -m 644 " + shellQuote(generatedConfig) + " " + shellQuote(configFile)},
+	)
+	if runtime.GOOS == "linux" {
+		steps = append(steps, privilegedStep{"Enable the site", "sudo ln -sf " + shellQuote(configFile) + " /etc/nginx/sites-enabled/vertex.conf"})
+	}
+
+	if step := hostsEntryStep(ni.Domain); step != nil {
+		steps = append(steps, *step)
+	}
+
+	if directories, _, _ := ni.runtimePaths(); len(directories) > 0 {
+		quoted := make([]string, 0, len(directories))
+		for _, dir := range directories {
+			quoted = append(quoted, shellQuote(dir))
+		}
+		steps = append(steps, privilegedStep{"Create nginx log and PID directories", "sudo mkdir -p " + strings.Join(quoted, " ")})
+	}
+
+	steps = append(steps, privilegedStep{"Check the nginx configuration", "sudo nginx -t"})
+	switch runtime.GOOS {
+	case "darwin":
+		steps = append(steps, privilegedStep{"Start or restart nginx", "brew services restart nginx"})
+	case "linux":
+		steps = append(steps, privilegedStep{"Start nginx and reload the configuration", "sudo systemctl enable --now nginx && sudo systemctl reload nginx"})
+	default:
+		steps = append(steps, privilegedStep{"Reload nginx", "nginx -s reload"})
+	}
+
+	return writePrivilegedScript(ni.OutputDir, ni.Domain, steps)
+}
+
+// prepareCertificatesWithoutSudo generates the certificate when mkcert is
+// available and returns the steps that need root, such as trusting the local CA
+func (ni *NginxInstaller) prepareCertificatesWithoutSudo() []privilegedStep {
+	sslDir := filepath.Join(os.Getenv("HOME"), ".vertex", "ssl")
+	certFile := filepath.Join(sslDir, ni.Domain+".pem")
+	keyFile := filepath.Join(sslDir, ni.Domain+"-key.pem")
+	generate := fmt.Sprintf("mkdir -p %s && mkcert -cert-file %s -key-file %s %s",
+		shellQuote(sslDir), shellQuote(certFile), shellQuote(keyFile), ni.Domain)
+
+	if !ni.isMkcertInstalled() {
+		return []privilegedStep{
+			{"Install mkcert", ni.mkcertInstallCommand()},
+			{"Trust the local certificate authority (mkcert asks for your password)", "mkcert -install"},
+			{"Generate the certificate for " + ni.Domain, generate},
+		}
+	}
+
+	var steps []privilegedStep
+	if err := os.MkdirAll(sslDir, 0755); err == nil {
+		cmd := exec.Command("mkcert", "-cert-file", certFile, "-key-file", keyFile, ni.Domain)
+		cmd.Dir = sslDir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			fmt.Printf("⚠️  Could not generate certificate: %s\n", strings.TrimSpace(string(output)))
+			steps = append(steps, privilegedStep{"Generate the certificate for " + ni.Domain, generate})
+		} else {
+			fmt.Printf("✅ Generated certificate %s\n", certFile)
+		}
+	}
+	return append([]privilegedStep{{"Trust the local certificate authority (mkcert asks for your password)", "mkcert -install"}}, steps...)
+}
+
+func (ni *NginxInstaller) nginxInstallCommand() string {
+	return packageInstallCommand("nginx")
+}
+
+func (ni *NginxInstaller) mkcertInstallCommand() string {
+	return packageInstallCommand("mkcert")
+}
+
+// VerifyInstallation checks, without elevated privileges, that the proxy
+// setup is in place and Vertex answers through the domain
+func (ni *NginxInstaller) VerifyInstallation() error {
+	var checks proxyChecklist
+
+	checks.check("nginx installed", func() error {
+		if !ni.IsNginxInstalled() {
+			return fmt.Errorf("nginx not found in PATH")
+		}
+		return nil
+	}())
+
+	configFile := filepath.Join(ni.SitesPath, "vertex.conf")
+	checks.check("configuration installed at "+configFile, func() error {
+		installed, err := os.ReadFile(configFile)
+		if err != nil {
+			return err
+		}
+		if ni.OutputDir != "" {
+			if generated, err := os.ReadFile(filepath.Join(ni.OutputDir, "vertex.conf")); err == nil && string(generated) != string(installed) {
+				return fmt.Errorf("differs from the generated %s", filepath.Join(ni.OutputDir, "vertex.conf"))
+			}
+		}
+		if !strings.Contains(string(installed), "server_name "+ni.Domain+";") {
+			return fmt.Errorf("not configured for %s", ni.Domain)
+		}
+		return nil
+	}())
+
+	if runtime.GOOS == "linux" {
+		checks.check("site enabled", func() error {
+			_, err := os.Stat("/etc/nginx/sites-enabled/vertex.conf")
+			return err
+		}())
+	}
+
+	checks.checkHostsEntry(ni.Domain)
+
+	protocol := "http"
+	if ni.HTTPSEnabled {
+		protocol = "https"
+	}
+	checks.checkReachable(fmt.Sprintf("%s://%s/", protocol, ni.Domain), ni.Port, "mkcert -install")
+
+	return checks.result(ni.Domain)
+}
@@ -0,0 +1,475 @@
+package installer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// NginxOverrides adjust the Vertex site without editing the template
+type NginxOverrides struct {
+	ClientMaxBodySize string          `json:"clientMaxBodySize,omitempty"` // e.g. 100m; nginx defaults to 1m
+	Headers           []NginxHeader   `json:"headers,omitempty"`           // Extra response headers of the Vertex site
+	Upstreams         []NginxUpstream `json:"upstreams,omitempty"`         // Upstream blocks ahead of the Vertex site
+	ServerConfig      string          `json:"serverConfig,omitempty"`      // Directives added to the end of the Vertex server block
+}
+
+// NginxHeader is a response header added with add_header
+type NginxHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NginxUpstream is a named group of servers that ServerConfig locations can
+// proxy to
+type NginxUpstream struct {
+	Name    string   `json:"name"`
+	Servers []string `json:"servers"` // host:port, optionally followed by parameters such as weight=2
+}
+
+// NginxTemplate is the template vertex.conf is rendered from and the
+// overrides it is rendered with
+type NginxTemplate struct {
+	Path      string         `json:"path"`
+	Template  string         `json:"template"`
+	Custom    bool           `json:"custom"` // The template differs from the built-in one
+	Overrides NginxOverrides `json:"overrides"`
+}
+
+// nginxTemplateData is what the template of the Vertex site can use
+type nginxTemplateData struct {
+	Domain       string
+	Port         string
+	HTTPS        bool
+	CertFile     string
+	KeyFile      string
+	Locations    string // The managed service locations, markers included
+	Overrides    NginxOverrides
+	TemplateFile string
+}
+
+const (
+	nginxTemplateFile  = "vertex.conf.tmpl"
+	nginxOverridesFile = "overrides.json"
+	nginxBackupFile    = "vertex.conf.bak"
+)
+
+var (
+	nginxHeaderNamePattern   = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	nginxUpstreamNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	nginxBodySizePattern     = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+)
+
+// defaultNginxTemplate renders the Vertex site as Vertex always has; it is
+// written to the output directory for users to customize
+const defaultNginxTemplate = `# Vertex Service Manager Configuration{{if .HTTPS}} (HTTPS){{end}}
+# Rendered by Vertex from {{.TemplateFile}}; edit the template, not this file
+{{- range .Overrides.Upstreams}}
+
+upstream {{.Name}} {
+{{- range .Servers}}
+    server {{.}};
+{{- end}}
+}
+{{- end}}
+{{- if .HTTPS}}
+
+# HTTP to HTTPS redirect
+server {
+    listen 80;
+    server_name {{.Domain}};
+    return 301 https://$server_name$request_uri;
+}
+
+# HTTPS server
+server {
+    listen 443 ssl http2;
+    server_name {{.Domain}};
+
+    # SSL Configuration
+    ssl_certificate {{.CertFile}};
+    ssl_certificate_key {{.KeyFile}};
+    ssl_session_timeout 1d;
+    ssl_session_cache shared:SSL:50m;
+    ssl_session_tickets off;
+
+    # Modern configuration
+    ssl_protocols TLSv1.2 TLSv1.3;
+    ssl_ciphers ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384;
+    ssl_prefer_server_ciphers off;
+{{- else}}
+
+server {
+    listen 80;
+    server_name {{.Domain}};
+{{- end}}
+
+    # Security headers
+    add_header X-Frame-Options DENY;
+    add_header X-Content-Type-Options nosniff;
+    add_header X-XSS-Protection "1; mode=block";
+{{- if .HTTPS}}
+    add_header Strict-Transport-Security "max-age=63072000" always;
+{{- end}}
+{{- range .Overrides.Headers}}
+    add_header {{.Name}} {{quote .Value}};
+{{- end}}
+{{- with .Overrides.ClientMaxBodySize}}
+
+    client_max_body_size {{.}};
+{{- end}}
+
+    # Proxy settings
+    proxy_set_header Host $host;
+    proxy_set_header X-Real-IP $remote_addr;
+    proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+    proxy_set_header X-Forwarded-Proto $scheme;
+
+{{.Locations}}
+    # Main application
+    location / {
+        proxy_pass http://127.0.0.1:{{.Port}};
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection 'upgrade';
+        proxy_cache_bypass $http_upgrade;
+        proxy_read_timeout 86400;
+    }
+
+    # WebSocket support for real-time features
+    location /ws {
+        proxy_pass http://127.0.0.1:{{.Port}};
+        proxy_http_version 1.1;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection "upgrade";
+        proxy_set_header Host $host;
+        proxy_cache_bypass $http_upgrade;
+    }
+
+    # API endpoints
+    location /api/ {
+        proxy_pass http://127.0.0.1:{{.Port}};
+        proxy_http_version 1.1;
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+    }
+
+    # Static assets with caching
+    location ~* \.(js|css|png|jpg|jpeg|gif|ico|svg|woff|woff2|ttf|eot)$ {
+        proxy_pass http://127.0.0.1:{{.Port}};
+        expires 1y;
+        add_header Cache-Control "public, immutable";
+    }
+
+    # Gzip compression
+    gzip on;
+    gzip_vary on;
+    gzip_min_length 1024;
+    gzip_types text/plain text/css text/xml text/javascript application/javascript application/xml+rss application/json;
+{{- with .Overrides.ServerConfig}}
+
+    # From overrides.json
+{{indent .}}
+{{- end}}
+}`
+
+// nginxTemplateFuncs are the functions a template can use besides the
+// text/template builtins
+var nginxTemplateFuncs = template.FuncMap{
+	"quote":  nginxQuote,
+	"indent": indentNginxBlock,
+}
+
+// nginxQuote returns a value as a double-quoted nginx string
+func nginxQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// indentNginxBlock indents each line of a block of directives by four spaces
+func indentNginxBlock(block string) string {
+	lines := strings.Split(strings.TrimRight(block, "\n"), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = "    " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LoadNginxTemplate reads the template and overrides saved in the nginx
+// output directory, falling back to the built-in template and no overrides
+func LoadNginxTemplate(outputDir string) (*NginxTemplate, error) {
+	tmpl := &NginxTemplate{Path: filepath.Join(outputDir, nginxTemplateFile), Template: defaultNginxTemplate}
+	if outputDir == "" {
+		tmpl.Path = ""
+		return tmpl, nil
+	}
+
+	data, err := os.ReadFile(tmpl.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read nginx template: %v", err)
+	}
+	if err == nil {
+		tmpl.Template = string(data)
+		tmpl.Custom = tmpl.Template != defaultNginxTemplate
+	}
+
+	data, err = os.ReadFile(filepath.Join(outputDir, nginxOverridesFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read nginx overrides: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &tmpl.Overrides); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", nginxOverridesFile, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// saveNginxTemplate writes the template and overrides to the output
+// directory, where users edit them
+func saveNginxTemplate(outputDir string, tmpl *NginxTemplate) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", outputDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, nginxTemplateFile), []byte(tmpl.Template), 0644); err != nil {
+		return fmt.Errorf("failed to save nginx template: %v", err)
+	}
+	data, err := json.MarshalIndent(tmpl.Overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, nginxOverridesFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save nginx overrides: %v", err)
+	}
+	return nil
+}
+
+// validateNginxOverrides rejects overrides that would break out of the
+// directive they are rendered into
+func validateNginxOverrides(overrides NginxOverrides) error {
+	if overrides.ClientMaxBodySize != "" && !nginxBodySizePattern.MatchString(overrides.ClientMaxBodySize) {
+		return fmt.Errorf("invalid clientMaxBodySize '%s' (use a size such as 100m)", overrides.ClientMaxBodySize)
+	}
+	for _, header := range overrides.Headers {
+		if !nginxHeaderNamePattern.MatchString(header.Name) {
+			return fmt.Errorf("invalid header name '%s'", header.Name)
+		}
+		if strings.ContainsAny(header.Value, "\r\n") {
+			return fmt.Errorf("header %s spans more than one line", header.Name)
+		}
+	}
+	names := make(map[string]bool)
+	for _, upstream := range overrides.Upstreams {
+		if !nginxUpstreamNamePattern.MatchString(upstream.Name) {
+			return fmt.Errorf("invalid upstream name '%s'", upstream.Name)
+		}
+		if names[upstream.Name] {
+			return fmt.Errorf("upstream %s is defined twice", upstream.Name)
+		}
+		names[upstream.Name] = true
+		if len(upstream.Servers) == 0 {
+			return fmt.Errorf("upstream %s has no servers", upstream.Name)
+		}
+		for _, server := range upstream.Servers {
+			if strings.TrimSpace(server) == "" || strings.ContainsAny(server, ";{}\r\n") {
+				return fmt.Errorf("invalid server '%s' in upstream %s", server, upstream.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// renderSite renders the Vertex site from a template. The service locations
+// must be part of it, since later changes are patched in between their
+// markers.
+func (ni *NginxInstaller) renderSite(tmpl *NginxTemplate) (string, error) {
+	if err := validateNginxOverrides(tmpl.Overrides); err != nil {
+		return "", err
+	}
+	parsed, err := template.New(nginxTemplateFile).Funcs(nginxTemplateFuncs).Option("missingkey=error").Parse(tmpl.Template)
+	if err != nil {
+		return "", fmt.Errorf("invalid nginx template: %v", err)
+	}
+
+	data := nginxTemplateData{
+		Domain:       ni.Domain,
+		Port:         ni.Port,
+		HTTPS:        ni.HTTPSEnabled,
+		Locations:    renderLocations(ni.Locations),
+		Overrides:    tmpl.Overrides,
+		TemplateFile: tmpl.Path,
+	}
+	if data.TemplateFile == "" {
+		data.TemplateFile = "the built-in template"
+	}
+	if ni.HTTPSEnabled {
+		sslDir := filepath.Join(os.Getenv("HOME"), ".vertex", "ssl")
+		data.CertFile = filepath.Join(sslDir, ni.Domain+".pem")
+		data.KeyFile = filepath.Join(sslDir, ni.Domain+"-key.pem")
+	}
+
+	var b strings.Builder
+	if err := parsed.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid nginx template: %v", err)
+	}
+	if !strings.Contains(b.String(), nginxLocationsBeginMarker) {
+		return "", fmt.Errorf("invalid nginx template: it must render {{.Locations}} inside the Vertex server block")
+	}
+	return b.String(), nil
+}
+
+// loadManagedBlocks reads the service locations, profile hostnames and
+// service domains saved in the output directory
+func (ni *NginxInstaller) loadManagedBlocks() {
+	locations, err := LoadNginxLocations(ni.OutputDir)
+	if err != nil {
+		fmt.Printf("⚠️  Skipping service locations: %v\n", err)
+	}
+	ni.Locations = locations
+	hostnames, err := LoadNginxHostnames(ni.OutputDir)
+	if err != nil {
+		fmt.Printf("⚠️  Skipping profile hostnames: %v\n", err)
+	}
+	ni.Hostnames = hostnames
+	domains, err := LoadNginxServiceDomains(ni.OutputDir)
+	if err != nil {
+		fmt.Printf("⚠️  Skipping service domains: %v\n", err)
+	}
+	ni.ServiceDomains = domains
+}
+
+// ValidateNginxConfig runs nginx -t on a rendered vertex.conf inside a
+// minimal nginx.conf of its own, so the installed configuration is not
+// touched. It reports false when nginx is not installed to check with.
+func ValidateNginxConfig(config string) (bool, error) {
+	if _, err := exec.LookPath("nginx"); err != nil {
+		return false, nil
+	}
+
+	dir, err := os.MkdirTemp("", "vertex-nginx-test")
+	if err != nil {
+		return false, fmt.Errorf("failed to create a directory to test nginx in: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	site := filepath.Join(dir, "vertex.conf")
+	if err := os.WriteFile(site, []byte(config), 0644); err != nil {
+		return false, fmt.Errorf("failed to write nginx test config: %v", err)
+	}
+	main := filepath.Join(dir, "nginx.conf")
+	wrapper := fmt.Sprintf("error_log %s;\npid %s;\nevents {}\nhttp {\n    include %s;\n}\n",
+		filepath.Join(dir, "error.log"), filepath.Join(dir, "nginx.pid"), site)
+	if err := os.WriteFile(main, []byte(wrapper), 0644); err != nil {
+		return false, fmt.Errorf("failed to write nginx test config: %v", err)
+	}
+
+	output, err := exec.Command("nginx", "-t", "-q", "-p", dir, "-c", main).CombinedOutput()
+	if err != nil {
+		return true, fmt.Errorf("nginx rejected the configuration: %s", strings.TrimSpace(string(output)))
+	}
+	return true, nil
+}
+
+// backupNginxConfig keeps a vertex.conf about to be replaced in the output
+// directory and returns where
+func backupNginxConfig(outputDir, config string) (string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", outputDir, err)
+	}
+	backup := filepath.Join(outputDir, nginxBackupFile)
+	if err := os.WriteFile(backup, []byte(config), 0644); err != nil {
+		return "", fmt.Errorf("failed to keep the edited vertex.conf: %v", err)
+	}
+	return backup, nil
+}
+
+// NginxTemplateResult reports the rendering of a changed template
+type NginxTemplateResult struct {
+	*NginxLocationsResult
+	Validated bool   `json:"validated"`        // nginx -t accepted the rendered configuration
+	Backup    string `json:"backup,omitempty"` // Where vertex.conf was kept before it was replaced
+}
+
+// ApplyNginxTemplate renders vertex.conf from a template and its overrides
+// and checks it with nginx -t before saving them and replacing the installed
+// configuration. An invalid template leaves everything as it was. previous
+// is the template vertex.conf was last rendered from, nil for the saved one;
+// the replaced vertex.conf is kept as vertex.conf.bak when it differs from
+// what previous renders, i.e. it was edited by hand.
+func ApplyNginxTemplate(outputDir string, tmpl, previous *NginxTemplate) (*NginxTemplateResult, error) {
+	tmpl.Path = filepath.Join(outputDir, nginxTemplateFile)
+	tmpl.Custom = tmpl.Template != defaultNginxTemplate
+
+	site, err := LoadNginxSite(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if site == nil {
+		// Nothing installed to render for yet; check the template on its own
+		ni := &NginxInstaller{Domain: "vertex.local", Port: NewServiceInstaller().Port}
+		if _, err := ni.renderSite(tmpl); err != nil {
+			return nil, err
+		}
+		if err := saveNginxTemplate(outputDir, tmpl); err != nil {
+			return nil, err
+		}
+		return &NginxTemplateResult{NginxLocationsResult: &NginxLocationsResult{
+			Instructions: "nginx is not configured for Vertex yet; run: vertex install --nginx",
+		}}, nil
+	}
+
+	port := site.Port
+	if port == "" {
+		port = NewServiceInstaller().Port
+	}
+	ni := NewNginxInstaller(site.Domain, port)
+	ni.EnableHTTPS(site.HTTPS)
+	ni.OutputDir = outputDir
+	ni.loadManagedBlocks()
+
+	config, err := ni.renderConfigFrom(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		if previous, err = LoadNginxTemplate(outputDir); err != nil {
+			return nil, err
+		}
+	}
+	rendered, err := ni.renderConfigFrom(previous)
+	if err != nil {
+		rendered = ""
+	}
+	validated, err := ValidateNginxConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveNginxTemplate(outputDir, tmpl); err != nil {
+		return nil, err
+	}
+
+	result := &NginxTemplateResult{Validated: validated}
+	result.NginxLocationsResult, err = patchVertexConf(outputDir, func(current string) (string, error) {
+		if current != config && current != rendered {
+			backup, err := backupNginxConfig(outputDir, current)
+			if err != nil {
+				return "", err
+			}
+			result.Backup = backup
+		}
+		return config, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
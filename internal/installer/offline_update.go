@@ -0,0 +1,307 @@
+package installer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// updatePublicKey is the base64 encoded ed25519 key release bundles are signed
+// with, using Ed25519ph so bundles can be verified without reading them into
+// memory. It is set at build time with
+// -ldflags "-X github.com/zechtz/vertex/internal/installer.updatePublicKey=...";
+// offline updates must then carry a valid <bundle>.sig signature. Builds
+// without it refuse offline updates unless insecure updates are allowed.
+var updatePublicKey = ""
+
+// errNoUpdatePublicKey is returned when a bundle cannot be verified because
+// this build has no signing key
+var errNoUpdatePublicKey = fmt.Errorf("this build has no update signing key, so the bundle's signature cannot be verified; installing it on its checksum alone must be allowed explicitly (--insecure)")
+
+// UpdateFromFile updates Vertex from a local release artifact instead of the
+// binary in the current directory, for machines without internet access.
+// The artifact may be a .tar.gz/.tgz or .zip bundle containing the vertex
+// binary, or the binary itself. Its SHA-256 is checked against checksum if
+// given, otherwise against <file>.sha256 or checksums.txt next to it. Its
+// signature is required unless insecure is set.
+func UpdateFromFile(bundlePath, checksum string, insecure bool) error {
+	fmt.Println("Verifying update bundle", bundlePath)
+
+	if err := VerifyUpdateBundle(bundlePath, checksum, insecure); err != nil {
+		return err
+	}
+	if updatePublicKey == "" {
+		fmt.Println("WARNING: this bundle is unsigned and was verified by its checksum only")
+	}
+
+	tempDir, err := os.MkdirTemp("", "vertex-update-")
+	if err != nil {
+		return fmt.Errorf("could not create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	binaryPath, err := extractUpdateBinary(bundlePath, tempDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Bundle verified, installing...")
+	return installBinary(binaryPath)
+}
+
+// StageUpdateBundle stores an uploaded bundle in dir, verifies it and returns
+// the path it was saved to. Bundles that fail verification are removed.
+func StageUpdateBundle(dir, filename string, bundle io.Reader, checksum, signature string, insecure bool) (string, error) {
+	filename = filepath.Base(filename)
+	if filename == "." || filename == string(filepath.Separator) || filename == "" {
+		return "", fmt.Errorf("invalid bundle filename")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create update directory: %w", err)
+	}
+
+	bundlePath := filepath.Join(dir, filename)
+	out, err := os.Create(bundlePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create bundle file: %w", err)
+	}
+	if _, err := io.Copy(out, bundle); err != nil {
+		out.Close()
+		os.Remove(bundlePath)
+		return "", fmt.Errorf("failed to save bundle: %w", err)
+	}
+	out.Close()
+
+	if signature != "" {
+		if err := os.WriteFile(bundlePath+".sig", []byte(signature), 0644); err != nil {
+			os.Remove(bundlePath)
+			return "", fmt.Errorf("failed to save signature: %w", err)
+		}
+	}
+
+	if err := VerifyUpdateBundle(bundlePath, checksum, insecure); err != nil {
+		os.Remove(bundlePath)
+		os.Remove(bundlePath + ".sig")
+		return "", err
+	}
+
+	// Keep the checksum next to the bundle so `vertex update --file` can re-verify it
+	if checksum != "" {
+		line := fmt.Sprintf("%s  %s\n", strings.ToLower(checksum), filename)
+		if err := os.WriteFile(bundlePath+".sha256", []byte(line), 0644); err != nil {
+			return "", fmt.Errorf("failed to save checksum: %w", err)
+		}
+	}
+
+	// Make sure the bundle actually contains a binary for this platform
+	tempDir, err := os.MkdirTemp("", "vertex-update-")
+	if err != nil {
+		return "", fmt.Errorf("could not create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if _, err := extractUpdateBinary(bundlePath, tempDir); err != nil {
+		os.Remove(bundlePath)
+		return "", err
+	}
+
+	return bundlePath, nil
+}
+
+// VerifyUpdateBundle checks the SHA-256 checksum and the ed25519 signature of
+// a bundle. Without a public key compiled in, it fails unless insecure is set,
+// in which case only the checksum is checked.
+func VerifyUpdateBundle(bundlePath, checksum string, insecure bool) error {
+	var publicKey ed25519.PublicKey
+	if updatePublicKey == "" {
+		if !insecure {
+			return errNoUpdatePublicKey
+		}
+	} else {
+		key, err := base64.StdEncoding.DecodeString(updatePublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid update public key compiled into this build")
+		}
+		publicKey = key
+	}
+
+	if checksum == "" {
+		var err error
+		checksum, err = lookupChecksum(bundlePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Hash the bundle in one pass rather than reading it into memory
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		return fmt.Errorf("could not read bundle: %w", err)
+	}
+	defer file.Close()
+
+	sha256Hash := sha256.New()
+	sha512Hash := sha512.New()
+	if _, err := io.Copy(io.MultiWriter(sha256Hash, sha512Hash), file); err != nil {
+		return fmt.Errorf("could not read bundle: %w", err)
+	}
+
+	actual := hex.EncodeToString(sha256Hash.Sum(nil))
+	if !strings.EqualFold(actual, strings.TrimSpace(checksum)) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", checksum, actual)
+	}
+
+	if publicKey == nil {
+		return nil
+	}
+
+	sigData, err := os.ReadFile(bundlePath + ".sig")
+	if err != nil {
+		return fmt.Errorf("bundle signature is required but %s.sig could not be read: %w", filepath.Base(bundlePath), err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigData)))
+	if err != nil {
+		signature = sigData
+	}
+
+	options := &ed25519.Options{Hash: crypto.SHA512}
+	if err := ed25519.VerifyWithOptions(publicKey, sha512Hash.Sum(nil), signature, options); err != nil {
+		return fmt.Errorf("bundle signature verification failed")
+	}
+
+	return nil
+}
+
+// lookupChecksum finds the expected checksum in <bundle>.sha256 or in a
+// checksums.txt in the same directory, both in sha256sum format
+func lookupChecksum(bundlePath string) (string, error) {
+	name := filepath.Base(bundlePath)
+	candidates := []string{bundlePath + ".sha256", filepath.Join(filepath.Dir(bundlePath), "checksums.txt")}
+
+	for _, candidate := range candidates {
+		file, err := os.Open(candidate)
+		if err != nil {
+			continue
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 {
+				continue
+			}
+			// A single-entry .sha256 file may omit the filename
+			if len(fields) == 1 || strings.TrimPrefix(fields[1], "*") == name {
+				file.Close()
+				return fields[0], nil
+			}
+		}
+		file.Close()
+	}
+
+	return "", fmt.Errorf("no checksum found for %s: pass --checksum or place %s.sha256 next to it", name, name)
+}
+
+// extractUpdateBinary writes the vertex binary contained in the bundle to
+// destDir and returns its path. A bare binary is copied as-is.
+func extractUpdateBinary(bundlePath, destDir string) (string, error) {
+	destPath := filepath.Join(destDir, binaryName())
+	lower := strings.ToLower(bundlePath)
+
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz"):
+		file, err := os.Open(bundlePath)
+		if err != nil {
+			return "", fmt.Errorf("could not open bundle: %w", err)
+		}
+		defer file.Close()
+
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return "", fmt.Errorf("invalid gzip bundle: %w", err)
+		}
+		defer gz.Close()
+
+		tr := tar.NewReader(gz)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("invalid tar bundle: %w", err)
+			}
+			if header.Typeflag == tar.TypeReg && isVertexBinary(header.Name) {
+				return destPath, writeBinary(destPath, tr)
+			}
+		}
+
+	case strings.HasSuffix(lower, ".zip"):
+		zr, err := zip.OpenReader(bundlePath)
+		if err != nil {
+			return "", fmt.Errorf("invalid zip bundle: %w", err)
+		}
+		defer zr.Close()
+
+		for _, entry := range zr.File {
+			if entry.FileInfo().IsDir() || !isVertexBinary(entry.Name) {
+				continue
+			}
+			rc, err := entry.Open()
+			if err != nil {
+				return "", fmt.Errorf("could not read %s from bundle: %w", entry.Name, err)
+			}
+			err = writeBinary(destPath, rc)
+			rc.Close()
+			return destPath, err
+		}
+
+	default:
+		file, err := os.Open(bundlePath)
+		if err != nil {
+			return "", fmt.Errorf("could not open bundle: %w", err)
+		}
+		defer file.Close()
+		return destPath, writeBinary(destPath, file)
+	}
+
+	return "", fmt.Errorf("bundle does not contain a vertex binary for %s/%s", runtime.GOOS, runtime.GOARCH)
+}
+
+// isVertexBinary reports whether an archive entry is the vertex binary for
+// this platform, either plainly named or as produced by release.sh
+func isVertexBinary(name string) bool {
+	base := filepath.Base(filepath.ToSlash(name))
+	platformName := fmt.Sprintf("vertex-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		platformName += ".exe"
+	}
+	return base == binaryName() || base == platformName
+}
+
+func writeBinary(destPath string, r io.Reader) error {
+	out, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create binary: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("failed to extract binary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package installer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Reverse proxies that can expose Vertex on a domain
+const (
+	ProxyNginx = "nginx"
+	ProxyCaddy = "caddy"
+)
+
+// privilegedScriptName is the reviewable script written in low-privilege mode
+const privilegedScriptName = "install-privileged.sh"
+
+// privilegedStep is one command the user runs in low-privilege mode
+type privilegedStep struct {
+	Comment string
+	Command string
+}
+
+// writePrivilegedScript prints the steps that were not run and saves them as
+// a script in outputDir
+func writePrivilegedScript(outputDir, domain string, steps []privilegedStep) error {
+	scriptPath := filepath.Join(outputDir, privilegedScriptName)
+	if err := os.WriteFile(scriptPath, []byte(renderPrivilegedScript(domain, steps)), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %v", scriptPath, err)
+	}
+
+	fmt.Printf("\n🔐 These commands need elevated privileges and were not run:\n\n")
+	for _, step := range steps {
+		fmt.Printf("  # %s\n  %s\n\n", step.Comment, step.Command)
+	}
+	fmt.Printf("📝 The same commands are in %s — review it, then run:\n", scriptPath)
+	fmt.Printf("  sh %s\n", scriptPath)
+	fmt.Printf("Afterwards check the setup with: vertex verify-proxy --domain %s\n", domain)
+	return nil
+}
+
+func renderPrivilegedScript(domain string, steps []privilegedStep) string {
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\n")
+	script.WriteString("# Privileged steps to expose Vertex at " + domain + ", generated by `vertex install --no-sudo`.\n")
+	script.WriteString("# Review each command before running this script as your normal user.\n")
+	script.WriteString("set -e\n")
+	for _, step := range steps {
+		script.WriteString("\n# " + step.Comment + "\n")
+		script.WriteString(step.Command + "\n")
+	}
+	script.WriteString("\necho \"Done. Verify with: vertex verify-proxy --domain " + domain + "\"\n")
+	return script.String()
+}
+
+// packageInstallCommand returns the install command for the first package manager found
+func packageInstallCommand(pkg string) string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "brew install " + pkg
+	case "windows":
+		return "choco install " + pkg + " -y"
+	}
+
+	managers := []struct{ name, command string }{
+		{"apt", "sudo apt update && sudo apt install -y " + pkg},
+		{"dnf", "sudo dnf install -y " + pkg},
+		{"yum", "sudo yum install -y " + pkg},
+		{"pacman", "sudo pacman -S --noconfirm " + pkg},
+		{"zypper", "sudo zypper install -y " + pkg},
+	}
+	for _, manager := range managers {
+		if _, err := exec.LookPath(manager.name); err == nil {
+			return manager.command
+		}
+	}
+	return "# install " + pkg + " with your package manager"
+}
+
+// hostsEntryStep returns the step adding the domain to /etc/hosts, or nil
+// when it is already present
+func hostsEntryStep(domain string) *privilegedStep {
+	hostEntry := fmt.Sprintf("127.0.0.1 %s", domain)
+	if content, err := os.ReadFile("/etc/hosts"); err == nil && strings.Contains(string(content), hostEntry) {
+		return nil
+	}
+	return &privilegedStep{"Resolve " + domain + " locally",
+		fmt.Sprintf("grep -qxF %s /etc/hosts || echo %s | sudo tee -a /etc/hosts > /dev/null", shellQuote(hostEntry), shellQuote(hostEntry))}
+}
+
+func shellQuote(value string) string {
+	if value != "" && strings.IndexFunc(value, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:@", r))
+	}) < 0 {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// proxyChecklist prints the outcome of verification checks and counts failures
+type proxyChecklist struct {
+	failed int
+}
+
+func (c *proxyChecklist) check(name string, err error) {
+	if err != nil {
+		c.failed++
+		fmt.Printf("❌ %s: %v\n", name, err)
+		return
+	}
+	fmt.Printf("✅ %s\n", name)
+}
+
+// checkHostsEntry verifies the domain resolves locally
+func (c *proxyChecklist) checkHostsEntry(domain string) {
+	c.check(domain+" in /etc/hosts", func() error {
+		content, err := os.ReadFile("/etc/hosts")
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(content), fmt.Sprintf("127.0.0.1 %s", domain)) {
+			return fmt.Errorf("entry missing")
+		}
+		return nil
+	}())
+}
+
+// checkReachable verifies Vertex answers through the proxy; trustHint is
+// the command that trusts the proxy's certificate authority
+func (c *proxyChecklist) checkReachable(url, port, trustHint string) {
+	c.check("Vertex reachable at "+url, func() error {
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Get(url)
+		if err != nil {
+			var certErr *tls.CertificateVerificationError
+			if errors.As(err, &certErr) {
+				return fmt.Errorf("certificate not trusted, run: %s", trustHint)
+			}
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("proxy returned status %d, is Vertex running on port %s?", resp.StatusCode, port)
+		}
+		return nil
+	}())
+}
+
+func (c *proxyChecklist) result(domain string) error {
+	if c.failed > 0 {
+		return fmt.Errorf("%d checks failed", c.failed)
+	}
+	fmt.Printf("🎉 Proxy setup for %s verified\n", domain)
+	return nil
+}
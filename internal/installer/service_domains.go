@@ -0,0 +1,212 @@
+package installer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// NginxServiceDomain proxies a subdomain of the Vertex domain, e.g.
+// payments.vertex.dev, to a managed service through a server block of its own
+type NginxServiceDomain struct {
+	Domain      string `json:"domain"`
+	Port        int    `json:"port"`
+	ServiceName string `json:"serviceName"`
+	CertFile    string `json:"certFile,omitempty"` // Served over HTTPS with this certificate when set
+	KeyFile     string `json:"keyFile,omitempty"`
+}
+
+// NginxSite is the domain Vertex itself was installed under, recorded so the
+// server can put service domains under it and re-render the site
+type NginxSite struct {
+	Domain string `json:"domain"`
+	HTTPS  bool   `json:"https"`
+	Port   string `json:"port,omitempty"` // Vertex's port; missing from sites recorded before templates
+}
+
+const (
+	nginxSiteFile                  = "site.json"
+	nginxServiceDomainsFile        = "domains.json"
+	nginxServiceDomainsBeginMarker = "# BEGIN service domains (managed by Vertex)"
+	nginxServiceDomainsEndMarker   = "# END service domains"
+	serviceDomainsCertName         = "vertex-service-domains"
+)
+
+// LoadNginxSite reads the domain nginx was installed for; a missing file
+// means nginx was installed before it was recorded, or not at all
+func LoadNginxSite(outputDir string) (*NginxSite, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, nginxSiteFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nginx site: %v", err)
+	}
+
+	var site NginxSite
+	if err := json.Unmarshal(data, &site); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", nginxSiteFile, err)
+	}
+	return &site, nil
+}
+
+// saveNginxSite records the domain nginx is installed for
+func saveNginxSite(outputDir string, site NginxSite) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", outputDir, err)
+	}
+	data, err := json.MarshalIndent(site, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, nginxSiteFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save nginx site: %v", err)
+	}
+	return nil
+}
+
+// LoadNginxServiceDomains reads the service domains saved in the nginx
+// output directory; a missing file means no domains
+func LoadNginxServiceDomains(outputDir string) ([]NginxServiceDomain, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, nginxServiceDomainsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nginx service domains: %v", err)
+	}
+
+	var domains []NginxServiceDomain
+	if err := json.Unmarshal(data, &domains); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", nginxServiceDomainsFile, err)
+	}
+	return domains, nil
+}
+
+// saveNginxServiceDomains records the service domains so a later install
+// renders them too
+func saveNginxServiceDomains(outputDir string, domains []NginxServiceDomain) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", outputDir, err)
+	}
+	data, err := json.MarshalIndent(domains, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, nginxServiceDomainsFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to save nginx service domains: %v", err)
+	}
+	return nil
+}
+
+// renderServiceDomainServers returns the server blocks of the service domains
+// between the markers ApplyNginxServiceDomains replaces. Domains with a
+// certificate redirect HTTP to HTTPS.
+func renderServiceDomainServers(domains []NginxServiceDomain) string {
+	var b strings.Builder
+	b.WriteString(nginxServiceDomainsBeginMarker + "\n")
+	for _, domain := range domains {
+		proxy := fmt.Sprintf(`    location / {
+        proxy_pass http://127.0.0.1:%d;
+        proxy_http_version 1.1;
+        proxy_set_header Host $host;
+        proxy_set_header X-Real-IP $remote_addr;
+        proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+        proxy_set_header X-Forwarded-Proto $scheme;
+        proxy_set_header Upgrade $http_upgrade;
+        proxy_set_header Connection "upgrade";
+        proxy_read_timeout 300;
+    }
+`, domain.Port)
+
+		if domain.CertFile == "" {
+			fmt.Fprintf(&b, "server {\n    listen 80;\n    server_name %s;\n\n%s}\n", domain.Domain, proxy)
+			continue
+		}
+		fmt.Fprintf(&b, `server {
+    listen 80;
+    server_name %s;
+    return 301 https://$server_name$request_uri;
+}
+
+server {
+    listen 443 ssl http2;
+    server_name %s;
+
+    ssl_certificate %s;
+    ssl_certificate_key %s;
+    ssl_protocols TLSv1.2 TLSv1.3;
+
+%s}
+`, domain.Domain, domain.Domain, domain.CertFile, domain.KeyFile, proxy)
+	}
+	b.WriteString(nginxServiceDomainsEndMarker + "\n")
+	return b.String()
+}
+
+// replaceServiceDomainServers swaps the service domain servers of a rendered
+// vertex.conf, appending them to configurations written before they existed
+func replaceServiceDomainServers(config string, domains []NginxServiceDomain) string {
+	rendered := renderServiceDomainServers(domains)
+
+	begin := strings.Index(config, nginxServiceDomainsBeginMarker)
+	end := strings.Index(config, nginxServiceDomainsEndMarker+"\n")
+	if begin >= 0 && end > begin {
+		return config[:begin] + rendered + config[end+len(nginxServiceDomainsEndMarker)+1:]
+	}
+	return strings.TrimRight(config, "\n") + "\n\n" + rendered
+}
+
+// ApplyNginxServiceDomains saves the service domains and rewrites their
+// server blocks into the installed vertex.conf
+func ApplyNginxServiceDomains(outputDir string, domains []NginxServiceDomain) (*NginxLocationsResult, error) {
+	if err := saveNginxServiceDomains(outputDir, domains); err != nil {
+		return nil, err
+	}
+	return patchVertexConf(outputDir, func(config string) (string, error) {
+		return replaceServiceDomainServers(config, domains), nil
+	})
+}
+
+// ServiceDomainsCertificate returns a certificate covering every service
+// domain as a subject alternative name, generating it with mkcert when the
+// domains changed since the last one. The local CA is trusted by the HTTPS
+// install of nginx.
+func ServiceDomainsCertificate(outputDir string, domains []string) (certFile, keyFile string, err error) {
+	sslDir := filepath.Join(os.Getenv("HOME"), ".vertex", "ssl")
+	certFile = filepath.Join(sslDir, serviceDomainsCertName+".pem")
+	keyFile = filepath.Join(sslDir, serviceDomainsCertName+"-key.pem")
+
+	if previous, err := LoadNginxServiceDomains(outputDir); err == nil && len(previous) == len(domains) {
+		covered := true
+		for _, domain := range previous {
+			if domain.CertFile != certFile || !slices.Contains(domains, domain.Domain) {
+				covered = false
+				break
+			}
+		}
+		if _, err := os.Stat(certFile); covered && err == nil {
+			return certFile, keyFile, nil
+		}
+	}
+
+	if _, err := exec.LookPath("mkcert"); err != nil {
+		return "", "", fmt.Errorf("mkcert is not installed")
+	}
+	if err := os.MkdirAll(sslDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create SSL directory: %v", err)
+	}
+
+	args := append([]string{"-cert-file", certFile, "-key-file", keyFile}, domains...)
+	cmd := exec.Command("mkcert", args...)
+	cmd.Dir = sslDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", "", fmt.Errorf("failed to generate certificate: %s", strings.TrimSpace(string(output)))
+	}
+	return certFile, keyFile, nil
+}
@@ -386,6 +386,11 @@ func (sm *ServiceManager) showLinuxStatus() error {
 		}
 	}
 	
+	// Show restart and watchdog state
+	if unitState, err := readSystemdUnitState(sm.serviceName); err == nil {
+		printSystemdUnitState(unitState)
+	}
+	
 	// Show systemd status
 	fmt.Printf("📋 Service details:\n")
 	fmt.Printf(string(output))
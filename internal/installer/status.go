@@ -0,0 +1,74 @@
+// Package installer - Service status for scripts
+package installer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// ServiceStatus is what `vertex status` reports, for --output json and yaml
+type ServiceStatus struct {
+	Installed bool        `json:"installed"`
+	Running   bool        `json:"running"` // Answers on localhost
+	URL       string      `json:"url,omitempty"`
+	ProxyURLs []string    `json:"proxyUrls"`
+	Unit      *UnitStatus `json:"unit,omitempty"` // systemd only
+}
+
+// UnitStatus is the systemd state of the Vertex unit
+type UnitStatus struct {
+	ActiveState string `json:"activeState"`
+	SubState    string `json:"subState"`
+	Result      string `json:"result"`
+	Restarts    int    `json:"restarts"`
+	MainPID     int    `json:"mainPid"`
+}
+
+// Status checks whether the Vertex service is installed and answering
+func (sm *ServiceManager) Status() (*ServiceStatus, error) {
+	status := &ServiceStatus{ProxyURLs: []string{}}
+
+	switch runtime.GOOS {
+	case "darwin":
+		plistFile := filepath.Join(sm.homeDir, "Library", "LaunchAgents", "com.vertex.manager.plist")
+		_, err := os.Stat(plistFile)
+		status.Installed = err == nil
+	case "linux":
+		serviceFile := filepath.Join(sm.homeDir, ".config", "systemd", "user", "vertex.service")
+		_, err := os.Stat(serviceFile)
+		status.Installed = err == nil
+		if status.Installed {
+			if state, err := readSystemdUnitState(sm.serviceName); err == nil {
+				status.Unit = &UnitStatus{
+					ActiveState: state.ActiveState,
+					SubState:    state.SubState,
+					Result:      state.Result,
+					Restarts:    state.Restarts,
+					MainPID:     state.MainPID,
+				}
+			}
+		}
+	case "windows":
+		status.Installed = exec.Command("schtasks", "/query", "/tn", "VertexServiceManager").Run() == nil
+	default:
+		return nil, fmt.Errorf("status viewing not supported on %s", runtime.GOOS)
+	}
+
+	// A Vertex started by hand answers too
+	status.Running = sm.testConnection()
+	if !status.Running {
+		return status, nil
+	}
+	status.URL = "http://localhost:54321"
+	if runtime.GOOS != "windows" {
+		for _, url := range []string{"https://vertex.dev", "http://vertex.dev", "http://vertex.local", "https://vertex.local"} {
+			if sm.checkNginxProxy(url) {
+				status.ProxyURLs = append(status.ProxyURLs, url)
+			}
+		}
+	}
+	return status, nil
+}
@@ -0,0 +1,190 @@
+// Package installer - systemd notify protocol and watchdog
+package installer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// systemdWatchdogSec is the WatchdogSec of the generated unit. Vertex pings
+// at half this interval while its HTTP API answers; a daemon that stops
+// answering for this long is killed and restarted by systemd.
+const systemdWatchdogSec = 30
+
+// SystemdNotify sends a state change (e.g. "READY=1", "WATCHDOG=1",
+// "STOPPING=1") to systemd. It reports false without error when Vertex was
+// not started by a Type=notify unit.
+func SystemdNotify(state string) (bool, error) {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return false, nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(socketPath, "@") {
+		socketPath = "\x00" + socketPath[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to systemd notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return true, nil
+}
+
+// SystemdWatchdogInterval returns the watchdog timeout systemd expects this
+// process to honour, or 0 when the watchdog is not enabled for it
+func SystemdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunSystemdWatchdog pings the systemd watchdog at half its timeout for as
+// long as check succeeds, until ctx is cancelled. A failing check skips the
+// ping so that systemd restarts a daemon that stays unresponsive.
+func RunSystemdWatchdog(ctx context.Context, check func(ctx context.Context) error) {
+	timeout := SystemdWatchdogInterval()
+	if timeout == 0 {
+		return
+	}
+	interval := timeout / 2
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		err := check(checkCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("[WARN] Watchdog self-check failed, not pinging systemd: %v", err)
+			continue
+		}
+		if _, err := SystemdNotify("WATCHDOG=1"); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+	}
+}
+
+// systemdUnitState is what `systemctl show` reports about the Vertex unit
+type systemdUnitState struct {
+	ActiveState      string
+	SubState         string
+	Result           string // "watchdog" after a watchdog kill
+	Restarts         int
+	WatchdogSec      time.Duration
+	LastWatchdogPing string
+	MainPID          int
+}
+
+// readSystemdUnitState queries the user manager for the state of the unit
+func readSystemdUnitState(unit string) (*systemdUnitState, error) {
+	output, err := exec.Command("systemctl", "--user", "show", unit,
+		"--property=ActiveState,SubState,Result,NRestarts,WatchdogUSec,WatchdogTimestamp,MainPID").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query systemd: %w", err)
+	}
+
+	state := &systemdUnitState{}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "ActiveState":
+			state.ActiveState = value
+		case "SubState":
+			state.SubState = value
+		case "Result":
+			state.Result = value
+		case "NRestarts":
+			state.Restarts, _ = strconv.Atoi(value)
+		case "WatchdogUSec":
+			state.WatchdogSec = parseSystemdTimespan(value)
+		case "WatchdogTimestamp":
+			state.LastWatchdogPing = value
+		case "MainPID":
+			state.MainPID, _ = strconv.Atoi(value)
+		}
+	}
+	return state, nil
+}
+
+// parseSystemdTimespan parses the values systemctl show prints for time
+// spans: plain microseconds on old versions, "30s" or "1min 30s" on newer ones
+func parseSystemdTimespan(value string) time.Duration {
+	if value == "" || value == "infinity" || value == "0" {
+		return 0
+	}
+	if usec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(usec) * time.Microsecond
+	}
+
+	var total time.Duration
+	for _, part := range strings.Fields(value) {
+		part = strings.Replace(part, "min", "m", 1)
+		d, err := time.ParseDuration(part)
+		if err != nil {
+			return 0
+		}
+		total += d
+	}
+	return total
+}
+
+// printSystemdUnitState prints the restart and watchdog state of the unit
+func printSystemdUnitState(state *systemdUnitState) {
+	fmt.Printf("⚙️  systemd: %s (%s)", state.ActiveState, state.SubState)
+	if state.MainPID > 0 {
+		fmt.Printf(", PID %d", state.MainPID)
+	}
+	fmt.Printf("\n")
+
+	if state.WatchdogSec > 0 {
+		lastPing := state.LastWatchdogPing
+		if lastPing == "" {
+			lastPing = "never"
+		}
+		fmt.Printf("🐕 Watchdog: %s timeout, last ping: %s\n", state.WatchdogSec, lastPing)
+	} else {
+		fmt.Printf("🐕 Watchdog: disabled (re-run 'vertex install' to enable it)\n")
+	}
+
+	if state.Restarts > 0 {
+		fmt.Printf("🔁 Restarted %d time(s) since the unit was started\n", state.Restarts)
+	}
+	switch state.Result {
+	case "", "success":
+	case "watchdog":
+		fmt.Printf("⚠️  Last failure: the daemon stopped answering and was killed by the watchdog\n")
+	default:
+		fmt.Printf("⚠️  Last failure: %s\n", state.Result)
+	}
+}
@@ -10,19 +10,40 @@ import (
 )
 
 func UpdateService() error {
+	// Get current working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("could not get working directory: %w", err)
+	}
+
+	// Source path is vertex binary in current directory
+	return installBinary(filepath.Join(wd, binaryName()))
+}
+
+// installBinary stops the running service, replaces the installed binary with
+// srcPath and starts the service again
+func installBinary(srcPath string) error {
 	switch runtime.GOOS {
 	case "darwin":
-		return updateMacOS()
+		return updateMacOS(srcPath)
 	case "linux":
-		return updateLinux()
+		return updateLinux(srcPath)
 	case "windows":
-		return updateWindows()
+		return updateWindows(srcPath)
 	default:
 		return fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
 }
 
-func updateMacOS() error {
+// binaryName returns the platform specific name of the vertex executable
+func binaryName() string {
+	if runtime.GOOS == "windows" {
+		return "vertex.exe"
+	}
+	return "vertex"
+}
+
+func updateMacOS(srcPath string) error {
 	fmt.Println("Updating Vertex on macOS...")
 
 	// Stop all vertex services
@@ -31,15 +52,6 @@ func updateMacOS() error {
 		fmt.Println("Could not stop launchctl service (might not be running):", err)
 	}
 
-	// Get current working directory
-	wd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("could not get working directory: %w", err)
-	}
-
-	// Source path is vertex binary in current directory
-	srcPath := filepath.Join(wd, "vertex")
-
 	// Destination path
 	installDir := filepath.Join(os.Getenv("HOME"), ".local", "bin", "vertex")
 
@@ -69,7 +81,7 @@ func updateMacOS() error {
 	return nil
 }
 
-func updateLinux() error {
+func updateLinux(srcPath string) error {
 	fmt.Println("Updating Vertex on Linux...")
 
 	// Stop all vertex services
@@ -78,15 +90,6 @@ func updateLinux() error {
 		fmt.Println("Could not stop systemd service (might not be running):", err)
 	}
 
-	// Get current working directory
-	wd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("could not get working directory: %w", err)
-	}
-
-	// Source path is vertex binary in current directory
-	srcPath := filepath.Join(wd, "vertex")
-
 	// Destination path
 	installDir := filepath.Join(os.Getenv("HOME"), ".local", "bin", "vertex")
 
@@ -116,7 +119,7 @@ func updateLinux() error {
 	return nil
 }
 
-func updateWindows() error {
+func updateWindows(srcPath string) error {
 	fmt.Println("Updating Vertex on Windows...")
 
 	// Stop all vertex services
@@ -125,15 +128,6 @@ func updateWindows() error {
 		fmt.Println("Could not stop service (might not be running):", err)
 	}
 
-	// Get current working directory
-	wd, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("could not get working directory: %w", err)
-	}
-
-	// Source path is vertex binary in current directory
-	srcPath := filepath.Join(wd, "vertex.exe")
-
 	// Destination path
 	installDir := filepath.Join(os.Getenv("ProgramFiles"), "Vertex", "vertex.exe")
 
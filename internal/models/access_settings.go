@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AccessSettings controls what Vertex shows to visitors who have not signed in
+// and how accounts sign in
+type AccessSettings struct {
+	// Lets anyone open a read-only dashboard of services, their status,
+	// metrics and logs without signing in; every change still needs a login
+	AnonymousReadOnly bool `json:"anonymousReadOnly"`
+	// Every account must sign in with a one-time code; accounts without
+	// two-factor authentication can only set it up until they do
+	RequireTwoFactor bool      `json:"requireTwoFactor"`
+	UpdatedBy        string    `json:"updatedBy,omitempty"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// ServiceOwner identifies who is responsible for a service and where its
+// alerts go
+type ServiceOwner struct {
+	Team         string `json:"team" yaml:"team"`
+	SlackChannel string `json:"slackChannel" yaml:"slackChannel"` // e.g. #payments-alerts, or a channel ID
+	Email        string `json:"email" yaml:"email"`
+}
+
+// AlertSettings decides where alerts about failing services are delivered.
+// A service whose owner has a Slack channel is alerted there, otherwise on its
+// team's webhook, otherwise on the global webhook.
+type AlertSettings struct {
+	WebhookURL    string            `json:"webhookUrl"`           // Global fallback
+	TeamWebhooks  map[string]string `json:"teamWebhooks"`         // Webhook URL by owner team
+	SlackToken    string            `json:"slackToken,omitempty"` // Bot token for chat.postMessage; stored encrypted and never returned
+	HasSlackToken bool              `json:"hasSlackToken"`
+	Cooldown      int               `json:"cooldownSeconds"` // Minimum seconds between alerts of one kind for a service
+	UpdatedAt     time.Time         `json:"updatedAt"`
+}
+
+// ServiceAlert is the JSON body posted to alert webhooks
+type ServiceAlert struct {
+	ServiceID   string       `json:"serviceId"`
+	ServiceName string       `json:"serviceName"`
+	Event       string       `json:"event"` // Service event type, e.g. crashed or crash-looping
+	Message     string       `json:"message"`
+	Owner       ServiceOwner `json:"owner"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Text        string       `json:"text"` // Human-readable summary; also what Slack webhooks display
+}
+
+// AlertDelivery reports where an alert was routed
+type AlertDelivery struct {
+	Channel string `json:"channel"` // "slack", "team-webhook" or "webhook"
+	Target  string `json:"target"`  // Slack channel, team name or "global"
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// How a database backup was made
+const (
+	BackupKindDaily      = "daily"       // Taken by the schedule and pruned by the retention settings
+	BackupKindManual     = "manual"      // Asked for through the API; kept until deleted
+	BackupKindPreRestore = "pre-restore" // The database as it was before a restore replaced it
+)
+
+// DatabaseBackup is a copy of the Vertex database in the backups directory
+type DatabaseBackup struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"createdAt"`
+	SizeBytes int64     `json:"sizeBytes"`
+}
+
+// BackupSettings controls the automatic daily backups and how many are kept
+type BackupSettings struct {
+	Enabled    bool      `json:"enabled"`
+	KeepDaily  int       `json:"keepDaily"`  // Most recent days that keep their newest backup
+	KeepWeekly int       `json:"keepWeekly"` // Most recent weeks that keep their newest backup
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// BackupStatus reports the automatic backups in the system health
+type BackupStatus struct {
+	Enabled        bool       `json:"enabled"`
+	Count          int        `json:"count"`
+	TotalBytes     int64      `json:"totalBytes"`
+	LastBackup     string     `json:"lastBackup,omitempty"`
+	LastBackupAt   *time.Time `json:"lastBackupAt,omitempty"`
+	NextBackupAt   *time.Time `json:"nextBackupAt,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	LastErrorAt    *time.Time `json:"lastErrorAt,omitempty"`
+	PendingRestore string     `json:"pendingRestore,omitempty"` // Backup that replaces the database at the next start
+}
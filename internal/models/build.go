@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Build states
+const (
+	BuildRunning   = "running"
+	BuildSucceeded = "succeeded"
+	BuildFailed    = "failed"
+)
+
+// ServiceBuild is the build phase of one service start: the compile output
+// Maven or Gradle prints before the application itself runs
+type ServiceBuild struct {
+	ID           int64      `json:"id"`
+	ServiceID    string     `json:"serviceId"`
+	ServiceName  string     `json:"serviceName"`
+	Status       string     `json:"status"`
+	BuildSystem  string     `json:"buildSystem"`
+	StartedAt    time.Time  `json:"startedAt"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+	DurationMs   int64      `json:"durationMs"`
+	ArtifactPath string     `json:"artifactPath,omitempty"` // Relative to the service directory
+	ArtifactSize int64      `json:"artifactSize"`           // Bytes
+	Lines        int        `json:"lines"`                  // Lines of build output
+	Output       string     `json:"output,omitempty"`       // Tail of the build output
+	Error        string     `json:"error,omitempty"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// ProfileBuildSettings adjust the Maven and Gradle commands of a profile's
+// services, e.g. to build offline at home or through a mirror on a
+// corporate network, without editing each service's JavaOpts
+type ProfileBuildSettings struct {
+	ProfileID  string    `json:"profileId"`
+	Offline    bool      `json:"offline"`              // mvn -o, gradle --offline
+	MirrorURL  string    `json:"mirrorUrl,omitempty"`  // Repository every dependency is downloaded from
+	MirrorOf   string    `json:"mirrorOf,omitempty"`   // Maven repositories the mirror replaces, "*" by default
+	MavenOpts  string    `json:"mavenOpts,omitempty"`  // Added to MAVEN_OPTS
+	GradleOpts string    `json:"gradleOpts,omitempty"` // Added to GRADLE_OPTS
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ChaosFault is a fault injected by chaos testing, kept as an audit trail
+type ChaosFault struct {
+	ID          int64      `json:"id"`
+	Type        string     `json:"type"` // "kill", "latency" or "block"
+	ServiceID   string     `json:"serviceId"`
+	ServiceName string     `json:"serviceName"`
+	ProfileID   string     `json:"profileId,omitempty"`
+	Detail      string     `json:"detail"`  // What was injected, e.g. the delay and proxy port
+	Trigger     string     `json:"trigger"` // "manual" or "schedule"
+	Status      string     `json:"status"`  // "active", "ended", "failed" or "interrupted"
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	EndedAt     *time.Time `json:"endedAt,omitempty"`
+}
+
+// ChaosFaultRequest injects a fault into a service. Killing without a
+// service picks a random running service of the active profile.
+type ChaosFaultRequest struct {
+	ServiceID       string `json:"serviceId"`
+	DelayMs         int    `json:"delayMs"`         // Latency added to each request chunk
+	JitterMs        int    `json:"jitterMs"`        // Random extra latency up to this much
+	ListenPort      int    `json:"listenPort"`      // Port of the latency proxy; 0 picks a free port
+	DurationSeconds int    `json:"durationSeconds"` // How long latency or a block lasts
+}
+
+// ChaosSchedule kills a random running service of a profile at an interval
+type ChaosSchedule struct {
+	ProfileID       string    `json:"profileId"`
+	IntervalSeconds int       `json:"intervalSeconds"`
+	NextRunAt       time.Time `json:"nextRunAt"`
+}
+
+// ChaosStatus reports whether chaos testing is enabled and what is running
+type ChaosStatus struct {
+	Enabled      bool           `json:"enabled"`
+	Schedule     *ChaosSchedule `json:"schedule,omitempty"`
+	ActiveFaults []ChaosFault   `json:"activeFaults"`
+}
@@ -24,6 +24,7 @@ type ServiceConfigRequest struct {
 	Name           string            `json:"name"`
 	Dir            string            `json:"dir"`
 	JavaOpts       string            `json:"javaOpts"`
+	JavaOptsPreset string            `json:"javaOptsPreset"` // Name of the JVM preset applied before JavaOpts ("" = none)
 	HealthURL      string            `json:"healthUrl"`
 	Port           int               `json:"port"`
 	Order          int               `json:"order"`
@@ -31,5 +32,19 @@ type ServiceConfigRequest struct {
 	IsEnabled      bool              `json:"isEnabled"`
 	BuildSystem    string            `json:"buildSystem"`    // "maven", "gradle", or "auto"
 	VerboseLogging bool              `json:"verboseLogging"` // Enable verbose/debug logging for build tools
+	IdleMinutes    int               `json:"idleMinutes"`    // Auto-suspend after this many idle minutes (0 = disabled)
+	HealthInterval int               `json:"healthInterval"` // Seconds between health checks (0 = default)
+	SkipDiscovery  bool              `json:"skipDiscovery"`  // Don't inject the profile's other services' addresses
+	Owner          ServiceOwner      `json:"owner"`          // Team and contacts its alerts are routed to
+	RunAsUser      string            `json:"runAsUser"`      // OS user the service process runs as (empty = the Vertex user)
+	ExecutionMode  string            `json:"executionMode"`  // "build-tool" (default) or "jar"
+	WorkingDir     string            `json:"workingDir"`     // Subdirectory of dir to build and run from
+	StartCommand   string            `json:"startCommand"`   // Overrides the build system's run command
+	BuildCommand   string            `json:"buildCommand"`   // Runs before the start command; replaces the package command in jar mode
+	AutoMigrate    bool              `json:"autoMigrate"`    // Apply pending database migrations before each start
+	Interactive    bool              `json:"interactive"`    // Keep stdin open so input can be sent to the process
+	ReloadOnSIGHUP bool              `json:"reloadOnSighup"` // The process re-reads its configuration on SIGHUP
+	InfraType      string            `json:"infraType"`      // Infrastructure Vertex runs instead of a Java service, such as "redis"
+	InfraRuntime   string            `json:"infraRuntime"`   // "container", "binary" or empty to pick one
 	EnvVars        map[string]EnvVar `json:"envVars"`
 }
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Database maintenance tasks, run in the order they are given
+const (
+	MaintenanceVacuum         = "vacuum"          // Rebuilds the file to give free pages back to the disk
+	MaintenanceAnalyze        = "analyze"         // Refreshes the statistics the query planner uses
+	MaintenanceIntegrityCheck = "integrity-check" // Checks each table and its indexes for corruption
+)
+
+// How a maintenance run was started
+const (
+	MaintenanceTriggerManual    = "manual"
+	MaintenanceTriggerScheduled = "scheduled"
+)
+
+// MaintenanceRun is one run of database maintenance tasks
+type MaintenanceRun struct {
+	ID              int64      `json:"id"`
+	Tasks           []string   `json:"tasks"`
+	Trigger         string     `json:"trigger"`
+	Status          string     `json:"status"` // running, completed, failed
+	CurrentTask     string     `json:"currentTask,omitempty"`
+	ProgressPercent int        `json:"progressPercent"` // Of the current task while running
+	ProgressDetail  string     `json:"progressDetail,omitempty"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	SizeBeforeBytes int64      `json:"sizeBeforeBytes"`
+	SizeAfterBytes  int64      `json:"sizeAfterBytes,omitempty"`
+	Problems        []string   `json:"problems"` // Found by the integrity check
+	Error           string     `json:"error,omitempty"`
+}
+
+// MaintenanceSettings controls the scheduled database maintenance
+type MaintenanceSettings struct {
+	Enabled              bool      `json:"enabled"`
+	IntervalHours        int       `json:"intervalHours"`        // Between scheduled runs
+	Tasks                []string  `json:"tasks"`                // Tasks of a scheduled run
+	VacuumMinFreePercent int       `json:"vacuumMinFreePercent"` // A scheduled VACUUM is skipped below this fragmentation
+	UpdatedAt            time.Time `json:"updatedAt"`
+}
+
+// MaintenanceStatus is the running maintenance, the recent runs and when the
+// schedule runs next
+type MaintenanceStatus struct {
+	Running   *MaintenanceRun      `json:"running,omitempty"`
+	Recent    []MaintenanceRun     `json:"recent"`
+	Settings  *MaintenanceSettings `json:"settings"`
+	NextRunAt *time.Time           `json:"nextRunAt,omitempty"`
+}
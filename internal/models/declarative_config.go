@@ -0,0 +1,57 @@
+package models
+
+// DeclarativeConfig is the content of a vertex.yaml file describing services,
+// profiles and environment variables. Optional fields left out of the file
+// are not managed: the stored value is kept as is.
+type DeclarativeConfig struct {
+	ProjectsDir string               `yaml:"projectsDir" json:"projectsDir"`
+	JavaHome    *string              `yaml:"javaHome" json:"javaHome"`
+	Env         map[string]string    `yaml:"env" json:"env"` // Global environment variables
+	Services    []DeclarativeService `yaml:"services" json:"services"`
+	Profiles    []DeclarativeProfile `yaml:"profiles" json:"profiles"`
+}
+
+// DeclarativeService describes a service; it is matched to an existing one by name
+type DeclarativeService struct {
+	Name           string              `yaml:"name" json:"name"`
+	Dir            string              `yaml:"dir" json:"dir"`
+	Port           *int                `yaml:"port" json:"port"`
+	Order          *int                `yaml:"order" json:"order"`
+	Description    *string             `yaml:"description" json:"description"`
+	JavaOpts       *string             `yaml:"javaOpts" json:"javaOpts"`
+	JavaOptsPreset *string             `yaml:"javaOptsPreset" json:"javaOptsPreset"`
+	HealthURL      *string             `yaml:"healthUrl" json:"healthUrl"`
+	BuildSystem    *string             `yaml:"buildSystem" json:"buildSystem"`
+	Enabled        *bool               `yaml:"enabled" json:"enabled"`
+	VerboseLogging *bool               `yaml:"verboseLogging" json:"verboseLogging"`
+	IdleMinutes    *int                `yaml:"idleMinutes" json:"idleMinutes"`
+	HealthInterval *int                `yaml:"healthInterval" json:"healthInterval"`
+	SkipDiscovery  *bool               `yaml:"skipDiscovery" json:"skipDiscovery"`
+	Owner          *ServiceOwner       `yaml:"owner" json:"owner"`
+	RunAsUser      *string             `yaml:"runAsUser" json:"runAsUser"`
+	ExecutionMode  *string             `yaml:"executionMode" json:"executionMode"`
+	WorkingDir     *string             `yaml:"workingDir" json:"workingDir"`
+	StartCommand   *string             `yaml:"startCommand" json:"startCommand"`
+	BuildCommand   *string             `yaml:"buildCommand" json:"buildCommand"`
+	AutoMigrate    *bool               `yaml:"autoMigrate" json:"autoMigrate"`
+	Interactive    *bool               `yaml:"interactive" json:"interactive"`
+	ReloadOnSIGHUP *bool               `yaml:"reloadOnSighup" json:"reloadOnSighup"`
+	InfraType      *string             `yaml:"infraType" json:"infraType"`
+	InfraRuntime   *string             `yaml:"infraRuntime" json:"infraRuntime"`
+	Env            map[string]string   `yaml:"env" json:"env"`
+	Tags           map[string]string   `yaml:"tags" json:"tags"`
+	DependsOn      []string            `yaml:"dependsOn" json:"dependsOn"` // Names of services this one needs (hard dependencies)
+	Logs           *LogIngestionConfig `yaml:"logs" json:"logs"`           // Drop patterns, DEBUG sampling and rate cap of its output
+}
+
+// DeclarativeProfile describes a profile of the applying user, matched by name
+type DeclarativeProfile struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description *string           `yaml:"description" json:"description"`
+	ProjectsDir *string           `yaml:"projectsDir" json:"projectsDir"`
+	JavaHome    *string           `yaml:"javaHome" json:"javaHome"`
+	Services    []string          `yaml:"services" json:"services"` // Service names
+	Env         map[string]string `yaml:"env" json:"env"`
+	Default     *bool             `yaml:"default" json:"default"`
+	Active      *bool             `yaml:"active" json:"active"`
+}
@@ -0,0 +1,39 @@
+package models
+
+// ServiceDeletionImpact lists what deleting a service affects. When it is
+// not empty, deleting the service needs its confirmation token.
+type ServiceDeletionImpact struct {
+	ServiceID         string              `json:"serviceId"`
+	ServiceName       string              `json:"serviceName"`
+	Running           bool                `json:"running"` // The service is stopped before it is deleted
+	Profiles          []ImpactedProfile   `json:"profiles"`
+	Dependents        []ImpactedDependent `json:"dependents"`
+	StoredData        []StoredDataCount   `json:"storedData"` // Only kinds the service has data of
+	Empty             bool                `json:"empty"`
+	ConfirmationToken string              `json:"confirmationToken,omitempty"`
+}
+
+// ImpactedProfile is a profile that lists the service
+type ImpactedProfile struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	UserID string `json:"userId"`
+}
+
+// ImpactedDependent is a service configured to depend on the service, and
+// what it does once the dependency is gone
+type ImpactedDependent struct {
+	ServiceID   string `json:"serviceId"`
+	ServiceName string `json:"serviceName"`
+	ProfileID   string `json:"profileId,omitempty"` // Set for dependencies scoped to a profile
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+	Behavior    string `json:"behavior"`
+}
+
+// StoredDataCount is how many records of one kind are lost with the service
+type StoredDataCount struct {
+	Kind  string `json:"kind"` // e.g. "logs" or "envVars"
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
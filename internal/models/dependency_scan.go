@@ -0,0 +1,91 @@
+package models
+
+import "time"
+
+// Dependency scan states
+const (
+	DependencyScanRunning   = "running"
+	DependencyScanCompleted = "completed"
+	DependencyScanError     = "error" // The scanner failed or its report could not be read
+)
+
+// Dependency scanners
+const (
+	ScannerOSV             = "osv-scanner"
+	ScannerDependencyCheck = "dependency-check" // OWASP Dependency-Check command line
+	ScannerMavenPlugin     = "maven"            // org.owasp:dependency-check-maven
+)
+
+// Severities of a vulnerability finding
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityUnknown  = "unknown"
+)
+
+// SeveritySummary counts vulnerability findings by severity
+type SeveritySummary struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Unknown  int `json:"unknown"`
+	Total    int `json:"total"`
+}
+
+// DependencyScan is one license and vulnerability scan of a service's
+// dependencies
+type DependencyScan struct {
+	ID           int64               `json:"id"`
+	ServiceID    string              `json:"serviceId"`
+	ServiceName  string              `json:"serviceName"`
+	Scanner      string              `json:"scanner"`
+	Status       string              `json:"status"`
+	Command      string              `json:"command"`
+	StartedAt    time.Time           `json:"startedAt"`
+	FinishedAt   *time.Time          `json:"finishedAt,omitempty"`
+	DurationMs   int64               `json:"durationMs"`
+	Dependencies int                 `json:"dependencies"` // Packages or files the scanner looked at
+	Summary      SeveritySummary     `json:"summary"`
+	Licenses     map[string]int      `json:"licenses"` // Dependencies per license, where the scanner reports them
+	Findings     []DependencyFinding `json:"findings,omitempty"`
+	Output       string              `json:"output,omitempty"` // Tail of the scanner output
+	Error        string              `json:"error,omitempty"`
+}
+
+// DependencyFinding is a vulnerability of one dependency
+type DependencyFinding struct {
+	Package         string   `json:"package"`
+	Version         string   `json:"version,omitempty"`
+	Ecosystem       string   `json:"ecosystem,omitempty"`
+	VulnerabilityID string   `json:"vulnerabilityId"`
+	Aliases         []string `json:"aliases,omitempty"`
+	Severity        string   `json:"severity"`
+	Score           float64  `json:"score,omitempty"` // CVSS base score when known
+	Summary         string   `json:"summary,omitempty"`
+	FixedVersion    string   `json:"fixedVersion,omitempty"`
+}
+
+// DependencyScanRequest picks the scanner; empty picks the first available
+type DependencyScanRequest struct {
+	Scanner string `json:"scanner"`
+}
+
+// ServiceScanSummary is the latest completed scan of a service
+type ServiceScanSummary struct {
+	ServiceID   string          `json:"serviceId"`
+	ServiceName string          `json:"serviceName"`
+	ScanID      int64           `json:"scanId,omitempty"`
+	Scanner     string          `json:"scanner,omitempty"`
+	ScannedAt   *time.Time      `json:"scannedAt,omitempty"` // Nil when the service was never scanned
+	Summary     SeveritySummary `json:"summary"`
+}
+
+// ProfileScanSummary adds up the latest scans of a profile's services
+type ProfileScanSummary struct {
+	ProfileID string               `json:"profileId"`
+	Totals    SeveritySummary      `json:"totals"`
+	Services  []ServiceScanSummary `json:"services"`
+}
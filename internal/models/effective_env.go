@@ -0,0 +1,38 @@
+package models
+
+// Sources of the variables a service process starts with
+const (
+	EnvSourceInherited   = "inherited"   // The environment Vertex itself runs in
+	EnvSourceJavaHome    = "javaHome"    // Java home override, or the service's JAVA_HOME
+	EnvSourceJavaVersion = "javaVersion" // The Java version the profile pins the service to
+	EnvSourceDiscovery   = "discovery"   // Where the other services of the profile listen
+	EnvSourceGlobal      = "global"      // Global environment variables
+	EnvSourceProfile     = "profile"     // Profile variables, applied as global ones
+	EnvSourceService     = "service"     // The service's own variables
+	EnvSourceEureka      = "eureka"      // The service's Eureka overrides
+	EnvSourceCredentials = "credentials" // The profile's repository credentials
+)
+
+// EffectiveEnvVar is one variable of the environment a service starts with
+type EffectiveEnvVar struct {
+	Name       string           `json:"name"`
+	Value      string           `json:"value"`
+	Source     string           `json:"source"`
+	Secret     bool             `json:"secret,omitempty"`     // The value is a secret reference or hidden
+	Overridden []EnvVarOverride `json:"overridden,omitempty"` // Earlier values this one replaced, in order
+}
+
+// EnvVarOverride is a value of a variable that a later source replaced
+type EnvVarOverride struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// EffectiveEnv is the environment a service would start with right now
+type EffectiveEnv struct {
+	ServiceID   string            `json:"serviceId"`
+	ServiceName string            `json:"serviceName"`
+	ProfileID   string            `json:"profileId,omitempty"`
+	Variables   []EffectiveEnvVar `json:"variables"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// Kinds of external resources a service can depend on
+const (
+	ExternalDependencyTCP  = "tcp"
+	ExternalDependencyHTTP = "http"
+	ExternalDependencyJDBC = "jdbc"
+)
+
+// ExternalDependency is a resource outside Vertex (database, broker, URL) that
+// must be reachable before a service is started
+type ExternalDependency struct {
+	ID             int    `json:"id"`
+	ServiceID      string `json:"serviceId"`
+	Name           string `json:"name"`   // Display name, e.g. "Postgres"
+	Type           string `json:"type"`   // "tcp", "http" or "jdbc"
+	Target         string `json:"target"` // host:port, URL or JDBC URL
+	TimeoutSeconds int    `json:"timeoutSeconds"`
+	Required       bool   `json:"required"` // Block startup when unreachable
+	Enabled        bool   `json:"enabled"`
+}
+
+// ExternalDependencyStatus is the outcome of checking an external dependency
+type ExternalDependencyStatus struct {
+	Dependency ExternalDependency `json:"dependency"`
+	Reachable  bool               `json:"reachable"`
+	Error      string             `json:"error,omitempty"`
+	LatencyMs  int64              `json:"latencyMs"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ProfileFileOverlay is a profile's variant of a file of a service, such as
+// src/main/resources/application-dev.yml. It is written into the service
+// directory while the profile is applied and the original is put back when
+// another profile is.
+type ProfileFileOverlay struct {
+	ProfileID    string    `json:"profileId"`
+	ServiceID    string    `json:"serviceId"`
+	ServiceName  string    `json:"serviceName,omitempty"` // Filled in on read
+	Path         string    `json:"path"`                  // Relative to the service directory, with forward slashes
+	Content      string    `json:"content"`               // {profile}, {service}, {port} and {env:NAME} are filled in when written
+	Materialized bool      `json:"materialized"`          // Currently written into the service directory
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// MaterializedFileOverlay records an overlay written into a service
+// directory together with the file it replaced
+type MaterializedFileOverlay struct {
+	FilePath        string    `json:"filePath"` // Absolute path of the written file
+	ProfileID       string    `json:"profileId"`
+	ServiceID       string    `json:"serviceId"`
+	Path            string    `json:"path"`
+	OriginalExisted bool      `json:"originalExisted"` // False when the overlay created the file
+	OriginalContent string    `json:"-"`
+	WrittenContent  string    `json:"-"` // Rendered overlay, to tell whether the file was edited since
+	MaterializedAt  time.Time `json:"materializedAt"`
+}
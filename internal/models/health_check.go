@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Health check authentication
+const (
+	HealthAuthDefault = ""       // Actuator endpoints get CONFIG_USERNAME/CONFIG_PASSWORD basic auth
+	HealthAuthNone    = "none"   // No Authorization header
+	HealthAuthBasic   = "basic"  // Basic auth from UsernameEnv and PasswordEnv
+	HealthAuthBearer  = "bearer" // Bearer token from TokenEnv
+)
+
+// HealthCheckConfig tunes the HTTP client of a service's health checks.
+// Credentials are never stored: they name environment variables, resolved
+// like the service's own environment (global, profile, then service).
+type HealthCheckConfig struct {
+	ServiceID          string            `json:"serviceId"`
+	Headers            map[string]string `json:"headers"` // Values may reference ${VAR}
+	AuthType           string            `json:"authType"`
+	UsernameEnv        string            `json:"usernameEnv,omitempty"`
+	PasswordEnv        string            `json:"passwordEnv,omitempty"`
+	TokenEnv           string            `json:"tokenEnv,omitempty"`
+	TimeoutSeconds     int               `json:"timeoutSeconds"` // 0 = the default of 10 seconds
+	InsecureSkipVerify bool              `json:"insecureSkipVerify"`
+}
+
+// HealthPushReport is what a service pushes to its health ingestion URL. A
+// Spring Boot actuator health document can be sent as is.
+type HealthPushReport struct {
+	Status     string            `json:"status"`               // UP, DOWN, OUT_OF_SERVICE or UNKNOWN; healthy and unhealthy also work
+	Build      map[string]string `json:"build,omitempty"`      // Such as version, commit and time
+	Components map[string]any    `json:"components,omitempty"` // Actuator health components, kept as sent
+	Details    map[string]any    `json:"details,omitempty"`
+}
+
+// HealthPush is the push-based health of a service: whether it has an
+// ingestion token, and the last report it pushed
+type HealthPush struct {
+	ServiceID    string            `json:"serviceId"`
+	Enabled      bool              `json:"enabled"`
+	Token        string            `json:"token,omitempty"`      // Only returned when generated; Vertex keeps a hash
+	IngestPath   string            `json:"ingestPath,omitempty"` // Where to post reports; only returned with the token
+	CreatedAt    *time.Time        `json:"createdAt,omitempty"`
+	HealthStatus string            `json:"healthStatus,omitempty"` // The health the last report stands for
+	LastReport   *HealthPushReport `json:"lastReport,omitempty"`
+	LastPushedAt *time.Time        `json:"lastPushedAt,omitempty"`
+	Fresh        bool              `json:"fresh"` // Whether the last report still replaces the health check
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// JVMPreset is a named set of JVM options (heap sizes, garbage collector)
+// that services reference instead of repeating them in their own JAVA_OPTS
+type JVMPreset struct {
+	Name        string    `json:"name"` // e.g. "small", "medium", "large"
+	Description string    `json:"description"`
+	Xms         string    `json:"xms"`       // Initial heap, e.g. "256m"
+	Xmx         string    `json:"xmx"`       // Maximum heap, e.g. "512m"
+	GC          string    `json:"gc"`        // "g1", "parallel", "serial", "zgc", "shenandoah" or "" for the JVM default
+	ExtraOpts   string    `json:"extraOpts"` // Additional flags appended after the generated ones
+	JavaOpts    string    `json:"javaOpts"`  // Options the preset expands to; ignored on save
+	UsedBy      []string  `json:"usedBy"`    // Names of services referencing the preset; ignored on save
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// JVMPresetOverride replaces a service's preset within one profile
+type JVMPresetOverride struct {
+	Preset string `json:"preset"` // Empty to use the service's own preset
+}
+
+// JavaVersionOverride pins a service to a Java version within one profile
+type JavaVersionOverride struct {
+	Version  string `json:"version"`            // A major version such as "21", an installed JDK's name or a Java home; empty to remove
+	JavaHome string `json:"javaHome,omitempty"` // The installed JDK the version resolves to; ignored on save
+	Problem  string `json:"problem,omitempty"`  // Why the version does not resolve; ignored on save
+}
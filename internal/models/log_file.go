@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// LogFileConfig writes a service's captured logs to size-rotated files in the
+// data directory, in addition to SQLite
+type LogFileConfig struct {
+	ServiceID string        `json:"serviceId"`
+	Enabled   bool          `json:"enabled"`
+	MaxSizeMB int           `json:"maxSizeMb"` // Size at which the current file is rotated
+	MaxFiles  int           `json:"maxFiles"`  // Rotated files kept besides the current one
+	Files     []LogFileInfo `json:"files"`     // Files currently on disk, newest first; ignored on save
+}
+
+// LogFileInfo describes one log file of a service
+type LogFileInfo struct {
+	Name       string    `json:"name"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
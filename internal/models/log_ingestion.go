@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// LogIngestionConfig limits how much of a service's output Vertex keeps.
+// Dropped lines are not stored, shown, written to log files or shipped.
+type LogIngestionConfig struct {
+	ServiceID          string             `yaml:"-" json:"serviceId"`
+	DropBanner         bool               `yaml:"dropBanner" json:"dropBanner"`                 // Drop the Spring Boot startup banner
+	DropPatterns       []string           `yaml:"dropPatterns" json:"dropPatterns"`             // Regular expressions; matching lines are dropped
+	DebugSamplePercent int                `yaml:"debugSamplePercent" json:"debugSamplePercent"` // Share of DEBUG and TRACE lines kept; 0 keeps them all
+	MaxLinesPerSecond  int                `yaml:"maxLinesPerSecond" json:"maxLinesPerSecond"`   // Lines kept per second; 0 for no cap
+	Stats              *LogIngestionStats `yaml:"-" json:"stats,omitempty"`                     // Ignored on save
+}
+
+// LogIngestionStats counts the lines of a service kept and dropped since
+// Vertex started or its ingestion settings last changed
+type LogIngestionStats struct {
+	Kept              int64     `json:"kept"`
+	DroppedByPattern  int64     `json:"droppedByPattern"` // Banner and drop patterns
+	DroppedBySampling int64     `json:"droppedBySampling"`
+	DroppedByRate     int64     `json:"droppedByRate"`
+	Since             time.Time `json:"since"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// LogLevelRule gives the log lines matching Pattern the level Level
+type LogLevelRule struct {
+	Pattern string `json:"pattern"` // Regular expression searched for in the line
+	Level   string `json:"level"`   // TRACE, DEBUG, INFO, WARN or ERROR
+}
+
+// LogLevelRules reclassify a service's log lines before the level keyword in
+// the line is looked at. The service's rules are tried first, then the
+// defaults unless turned off; the first match wins.
+type LogLevelRules struct {
+	ServiceID   string         `json:"serviceId"`
+	UseDefaults bool           `json:"useDefaults"`
+	Rules       []LogLevelRule `json:"rules"`
+	Defaults    []LogLevelRule `json:"defaults"` // The built-in rules for Spring Boot output; ignored on save
+}
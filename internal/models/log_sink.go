@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// External log sink backends
+const (
+	LogSinkLoki          = "loki"
+	LogSinkElasticsearch = "elasticsearch"
+)
+
+// LogSinkConfig ships the captured logs of a profile's services to an
+// external store in addition to, or instead of, SQLite
+type LogSinkConfig struct {
+	ProfileID            string            `json:"profileId"`
+	Type                 string            `json:"type"`            // "loki" or "elasticsearch"
+	URL                  string            `json:"url"`             // Base URL, e.g. http://localhost:3100
+	Index                string            `json:"index,omitempty"` // Elasticsearch index name
+	Labels               map[string]string `json:"labels,omitempty"`
+	Username             string            `json:"username,omitempty"`
+	Password             string            `json:"password,omitempty"`
+	BatchSize            int               `json:"batchSize"`
+	FlushIntervalSeconds int               `json:"flushIntervalSeconds"`
+	BufferSize           int               `json:"bufferSize"`    // Entries queued before new ones are dropped
+	DisableSQLite        bool              `json:"disableSqlite"` // Skip the local service_logs table
+	Enabled              bool              `json:"enabled"`
+}
+
+// LogSinkStatus reports the delivery state of a profile's log sink
+type LogSinkStatus struct {
+	ProfileID   string    `json:"profileId"`
+	Type        string    `json:"type"`
+	Queued      int       `json:"queued"`
+	Shipped     int64     `json:"shipped"`
+	Dropped     int64     `json:"dropped"`
+	Failed      int64     `json:"failed"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastShipped time.Time `json:"lastShipped,omitempty"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// Database migration tools
+const (
+	MigrationToolFlyway    = "flyway"
+	MigrationToolLiquibase = "liquibase"
+)
+
+// How a migration tool is run
+const (
+	MigrationRunnerCLI    = "cli"    // The tool's command line, found on the PATH
+	MigrationRunnerMaven  = "maven"  // The tool's Maven plugin, invoked by its coordinates
+	MigrationRunnerGradle = "gradle" // The tool's Gradle plugin, applied in the build file
+)
+
+// Migration run actions
+const (
+	MigrationActionInfo    = "info"    // List applied and pending migrations
+	MigrationActionMigrate = "migrate" // Apply pending migrations, then list them
+)
+
+// Migration run states
+const (
+	MigrationRunRunning   = "running"
+	MigrationRunCompleted = "completed"
+	MigrationRunError     = "error"
+)
+
+// Migration states; tools may report others, such as "missing" or "ignored"
+const (
+	MigrationApplied = "applied"
+	MigrationPending = "pending"
+	MigrationFailed  = "failed"
+)
+
+// MigrationSetup is what Vertex found out about the migrations of a service
+type MigrationSetup struct {
+	ServiceID   string        `json:"serviceId"`
+	ServiceName string        `json:"serviceName"`
+	Detected    bool          `json:"detected"`
+	Tool        string        `json:"tool,omitempty"`
+	Runner      string        `json:"runner,omitempty"`      // Empty when neither the CLI nor a build plugin can run the tool
+	Locations   []string      `json:"locations,omitempty"`   // Flyway script locations, or the Liquibase changelog
+	DetectedBy  []string      `json:"detectedBy,omitempty"`  // Why the tool was detected
+	DatabaseURL string        `json:"databaseUrl,omitempty"` // JDBC URL the tool connects to
+	AutoMigrate bool          `json:"autoMigrate"`
+	Applied     int           `json:"applied"` // From the latest completed run
+	Pending     int           `json:"pending"`
+	Migrations  []Migration   `json:"migrations"`
+	LastRun     *MigrationRun `json:"lastRun,omitempty"`
+	Error       string        `json:"error,omitempty"` // Why the migrations cannot be run
+}
+
+// MigrationRun is one execution of a migration tool against a service's database
+type MigrationRun struct {
+	ID          int64       `json:"id"`
+	ServiceID   string      `json:"serviceId"`
+	ServiceName string      `json:"serviceName"`
+	Tool        string      `json:"tool"`
+	Runner      string      `json:"runner"`
+	Action      string      `json:"action"`
+	Status      string      `json:"status"`
+	Command     string      `json:"command"`
+	StartedAt   time.Time   `json:"startedAt"`
+	FinishedAt  *time.Time  `json:"finishedAt,omitempty"`
+	DurationMs  int64       `json:"durationMs"`
+	Applied     int         `json:"applied"`
+	Pending     int         `json:"pending"`
+	Migrations  []Migration `json:"migrations,omitempty"`
+	Output      string      `json:"output,omitempty"` // Tail of the tool output
+	Error       string      `json:"error,omitempty"`
+}
+
+// Migration is a Flyway migration or a Liquibase changeset
+type Migration struct {
+	Version     string `json:"version"`               // Flyway version, or Liquibase changeset ID
+	Description string `json:"description,omitempty"` // Flyway description, or Liquibase changeset author
+	Script      string `json:"script,omitempty"`      // Flyway script, or Liquibase changelog
+	State       string `json:"state"`
+	InstalledOn string `json:"installedOn,omitempty"` // As the tool printed it
+}
+
+// MigrationRunRequest picks what a migration run does
+type MigrationRunRequest struct {
+	Action string `json:"action"` // "migrate" (default) or "info"
+}
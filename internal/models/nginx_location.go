@@ -0,0 +1,10 @@
+package models
+
+// NginxLocationConfig exposes a service under a path prefix of the Vertex
+// nginx site, e.g. https://vertex.dev/svc/user-service/
+type NginxLocationConfig struct {
+	ServiceID string `json:"serviceId"`
+	Enabled   bool   `json:"enabled"`
+	Path      string `json:"path"` // Prefix starting and ending in "/", stripped before proxying
+	Port      int    `json:"port"` // Service port requests are proxied to; ignored on save
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// NotificationChannel is an SMTP server that sends email notifications to the
+// users subscribed to it
+type NotificationChannel struct {
+	ID              int64                     `json:"id"`
+	Name            string                    `json:"name"`
+	Type            string                    `json:"type"` // "email"
+	SMTPHost        string                    `json:"smtpHost"`
+	SMTPPort        int                       `json:"smtpPort"`
+	SMTPUsername    string                    `json:"smtpUsername"`
+	SMTPPassword    string                    `json:"smtpPassword,omitempty"` // Stored encrypted and never returned
+	HasSMTPPassword bool                      `json:"hasSmtpPassword"`
+	FromAddress     string                    `json:"fromAddress"`
+	ImplicitTLS     bool                      `json:"implicitTls"` // TLS from the first byte (port 465) instead of STARTTLS
+	Enabled         bool                      `json:"enabled"`
+	Subscription    *NotificationSubscription `json:"subscription,omitempty"` // The requesting user's subscription
+	CreatedAt       time.Time                 `json:"createdAt"`
+	UpdatedAt       time.Time                 `json:"updatedAt"`
+}
+
+// NotificationSubscription is a user's subscription to a notification
+// channel
+type NotificationSubscription struct {
+	ChannelID int64     `json:"channelId"`
+	UserID    string    `json:"userId"`
+	Email     string    `json:"email"`
+	Events    []string  `json:"events"` // "crashed", "crash-looping" and "unhealthy"
+	Mode      string    `json:"mode"`   // "immediate" or "digest" (hourly)
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PendingNotification is a notification waiting for the next hourly digest
+// of a subscription
+type PendingNotification struct {
+	ID          int64     `json:"id"`
+	ChannelID   int64     `json:"channelId"`
+	UserID      string    `json:"userId"`
+	ServiceID   string    `json:"serviceId"`
+	ServiceName string    `json:"serviceName"`
+	Event       string    `json:"event"`
+	Message     string    `json:"message"`
+	Timestamp   time.Time `json:"timestamp"`
+}
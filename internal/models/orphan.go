@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// How an orphaned process was matched to a service
+const (
+	OrphanMatchPID  = "pid"  // The PID the service had when Vertex last ran
+	OrphanMatchPort = "port" // Listening on the service's port
+)
+
+// OrphanedProcess is a process left running by an earlier Vertex run, found
+// on startup because it still has a service's PID or port
+type OrphanedProcess struct {
+	PID         int       `json:"pid"` // Process group leader where it can be found
+	ServiceID   string    `json:"serviceId"`
+	ServiceName string    `json:"serviceName"`
+	Port        int       `json:"port,omitempty"`
+	Match       string    `json:"match"`
+	Command     string    `json:"command"`
+	Cwd         string    `json:"cwd,omitempty"`
+	StartedAt   time.Time `json:"startedAt,omitempty"`
+	Adoptable   bool      `json:"adoptable"` // Runs in the service's directory; otherwise it can only be killed
+	DetectedAt  time.Time `json:"detectedAt"`
+}
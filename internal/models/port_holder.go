@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PortHolder is a process listening on a port Vertex wants to free, with
+// what is known about who started it
+type PortHolder struct {
+	PID             int       `json:"pid"`
+	Name            string    `json:"name"`
+	Command         string    `json:"command"`
+	User            string    `json:"user,omitempty"`
+	StartedAt       time.Time `json:"startedAt,omitempty"`
+	Cwd             string    `json:"cwd,omitempty"`
+	SpawnedByVertex bool      `json:"spawnedByVertex"`     // A service process or another child of Vertex
+	ServiceID       string    `json:"serviceId,omitempty"` // The service it was started for, when known
+	ServiceName     string    `json:"serviceName,omitempty"`
+}
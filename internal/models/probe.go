@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// ServiceProbe is a synthetic request sent to a service on an interval, and
+// the service level objective (SLO) its results are held to
+type ServiceProbe struct {
+	ID                int64     `json:"id"`
+	ServiceID         string    `json:"serviceId"`
+	Name              string    `json:"name"`
+	URL               string    `json:"url"`               // A path such as /api/orders goes to the service's port on localhost
+	Method            string    `json:"method"`            // GET or HEAD
+	IntervalSeconds   int       `json:"intervalSeconds"`   // Time between requests
+	ExpectedStatus    int       `json:"expectedStatus"`    // 0 accepts any 2xx or 3xx
+	MaxLatencyMs      int       `json:"maxLatencyMs"`      // p95 latency objective; 0 = none
+	TargetSuccessRate float64   `json:"targetSuccessRate"` // Percentage of requests that must succeed
+	WindowMinutes     int       `json:"windowMinutes"`     // Rolling window the objective is measured over
+	Enabled           bool      `json:"enabled"`
+	CreatedAt         time.Time `json:"createdAt"`
+	UpdatedAt         time.Time `json:"updatedAt"`
+}
+
+// ProbeResult is one request of a probe
+type ProbeResult struct {
+	ProbeID    int64     `json:"probeId"`
+	Timestamp  time.Time `json:"timestamp"`
+	Success    bool      `json:"success"` // The expected status came back
+	StatusCode int       `json:"statusCode,omitempty"`
+	LatencyMs  int64     `json:"latencyMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// ProbeStatus is a probe with its rolling success rate and p95 latency over
+// its window and whether they meet its objective
+type ProbeStatus struct {
+	Probe         ServiceProbe `json:"probe"`
+	Samples       int          `json:"samples"`
+	SuccessRate   float64      `json:"successRate"` // Percentage; 100 with no samples
+	P95LatencyMs  int64        `json:"p95LatencyMs"`
+	Violated      bool         `json:"violated"`
+	Violations    []string     `json:"violations"`
+	ViolatedSince *time.Time   `json:"violatedSince,omitempty"`
+	LastResult    *ProbeResult `json:"lastResult,omitempty"`
+}
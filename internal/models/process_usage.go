@@ -0,0 +1,23 @@
+package models
+
+// How a process was attributed to a service
+const (
+	ProcessRoleMain  = "main"  // The process Vertex started
+	ProcessRoleChild = "child" // Spawned by the main process or one of its children
+	ProcessRoleGroup = "group" // In the service's process group but no longer under the main process, e.g. reparented after its parent exited
+)
+
+// ProcessUsage is the resource usage of one process of a service, such as a
+// surefire fork under Maven or a worker under Node. A service's metrics are
+// the sum of all of them.
+type ProcessUsage struct {
+	PID           int32   `json:"pid"`
+	PPID          int32   `json:"ppid"`
+	Role          string  `json:"role"`
+	Name          string  `json:"name"`
+	Command       string  `json:"command"`
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryUsage   uint64  `json:"memoryUsage"` // Resident Set Size, in bytes
+	MemoryPercent float32 `json:"memoryPercent"`
+	DiskUsage     uint64  `json:"diskUsage"` // Bytes read and written
+}
@@ -15,6 +15,10 @@ type ServiceProfile struct {
 	JavaHomeOverride string            `json:"javaHomeOverride" db:"java_home_override"`
 	IsDefault        bool              `json:"isDefault" db:"is_default"`
 	IsActive         bool              `json:"isActive" db:"is_active"`
+	MemoryBudgetMB   int               `json:"memoryBudgetMb" db:"memory_budget_mb"`     // 0 means no budget
+	MemoryBudgetMode string            `json:"memoryBudgetMode" db:"memory_budget_mode"` // "warn" or "enforce"
+	PortPoolStart    int               `json:"portPoolStart" db:"port_pool_start"`       // First port assigned to new services; 0 means the default pool
+	PortPoolEnd      int               `json:"portPoolEnd" db:"port_pool_end"`
 	CreatedAt        time.Time         `json:"createdAt" db:"created_at"`
 	UpdatedAt        time.Time         `json:"updatedAt" db:"updated_at"`
 }
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// ProfileBundleFormatVersion is the version of the profile bundle format
+// written by exports; imports reject bundles of a newer version
+const ProfileBundleFormatVersion = 1
+
+// ProfileBundle is a profile exported to share with other users. Services are
+// identified by name and directory rather than UUID, since UUIDs differ
+// between installations; repository credentials and log sinks are left out.
+type ProfileBundle struct {
+	FormatVersion    int                          `json:"formatVersion"`
+	ExportedAt       time.Time                    `json:"exportedAt"`
+	Name             string                       `json:"name"`
+	Description      string                       `json:"description"`
+	ProjectsDir      string                       `json:"projectsDir"`
+	JavaHomeOverride string                       `json:"javaHomeOverride"`
+	EnvVars          map[string]string            `json:"envVars"`
+	MemoryBudgetMB   int                          `json:"memoryBudgetMb"`
+	MemoryBudgetMode string                       `json:"memoryBudgetMode"`
+	PortPoolStart    int                          `json:"portPoolStart"`
+	PortPoolEnd      int                          `json:"portPoolEnd"`
+	Services         []ProfileBundleService       `json:"services"`
+	ProfileEnvVars   []ProfileBundleEnvVar        `json:"profileEnvVars"`
+	ServiceConfigs   []ProfileBundleServiceConfig `json:"serviceConfigs"`
+	FileOverlays     []ProfileBundleFileOverlay   `json:"fileOverlays"`
+	BuildSettings    *ProfileBuildSettings        `json:"buildSettings,omitempty"`
+}
+
+// ProfileBundleService identifies a service of a bundle. ID is its UUID on
+// the installation the bundle was exported from.
+type ProfileBundleService struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+}
+
+type ProfileBundleEnvVar struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+	IsRequired  bool   `json:"isRequired"`
+}
+
+// ProfileBundleServiceConfig is a profile-scoped service configuration value;
+// Service is the name of the bundle service it belongs to
+type ProfileBundleServiceConfig struct {
+	Service     string `json:"service"`
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+type ProfileBundleFileOverlay struct {
+	Service string `json:"service"`
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// ProfileImportRequest imports a bundle as a new profile of the caller. Name
+// replaces the bundle's name, such as when the caller already has a profile
+// called that.
+type ProfileImportRequest struct {
+	Bundle ProfileBundle `json:"bundle"`
+	Name   string        `json:"name"`
+}
+
+// ProfileImportMapping records which local service a bundle service was
+// mapped to. MatchedBy is "name" or "dir", and empty when nothing matched.
+type ProfileImportMapping struct {
+	Name      string `json:"name"`
+	Dir       string `json:"dir"`
+	SourceID  string `json:"sourceId"`
+	ServiceID string `json:"serviceId,omitempty"`
+	MatchedBy string `json:"matchedBy,omitempty"`
+}
+
+type ProfileImportResult struct {
+	Profile  *ServiceProfile        `json:"profile"`
+	Services []ProfileImportMapping `json:"services"`
+	Warnings []string               `json:"warnings"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// ProfileHostname is a local hostname a profile declares, e.g. api.test, that
+// Vertex points at 127.0.0.1 in the hosts file and proxies to a service of
+// the profile through nginx
+type ProfileHostname struct {
+	Hostname    string `json:"hostname"`
+	ProfileID   string `json:"profileId"`
+	ServiceID   string `json:"serviceId"`
+	ServiceName string `json:"serviceName,omitempty"` // Filled in on read
+	Port        int    `json:"port,omitempty"`        // Service port requests are proxied to; ignored on save
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ProfilePromotionRequest copies profile environment variables and service
+// configuration from one profile to another, such as from dev to staging.
+// With nothing selected and DryRun set, every candidate is listed.
+type ProfilePromotionRequest struct {
+	TargetProfileID string               `json:"targetProfileId"`
+	EnvVars         []string             `json:"envVars"`        // Names of the profile environment variables to promote
+	ServiceConfigs  []PromotionConfigKey `json:"serviceConfigs"` // Service configuration values to promote
+	Note            string               `json:"note"`
+	DryRun          bool                 `json:"dryRun"`
+}
+
+// PromotionConfigKey selects a service configuration value of the source profile
+type PromotionConfigKey struct {
+	ServiceID string `json:"serviceId"`
+	Key       string `json:"key"`
+}
+
+// PromotionChange is one value a promotion copies, with the target's value
+// before the promotion
+type PromotionChange struct {
+	Kind        string `json:"kind"` // "envVar" or "serviceConfig"
+	ServiceID   string `json:"serviceId,omitempty"`
+	ServiceName string `json:"serviceName,omitempty"`
+	Key         string `json:"key"`
+	ConfigType  string `json:"configType,omitempty"`
+	Before      string `json:"before"`
+	After       string `json:"after"`
+	Action      string `json:"action"` // "add", "change" or "unchanged"
+}
+
+// ProfilePromotion is a promotion between two profiles. Promotions that were
+// applied are kept as an audit trail; dry runs have no ID.
+type ProfilePromotion struct {
+	ID                int64             `json:"id,omitempty"`
+	UserID            string            `json:"userId"`
+	SourceProfileID   string            `json:"sourceProfileId"`
+	SourceProfileName string            `json:"sourceProfileName"`
+	TargetProfileID   string            `json:"targetProfileId"`
+	TargetProfileName string            `json:"targetProfileName"`
+	Note              string            `json:"note"`
+	Changes           []PromotionChange `json:"changes"`
+	DryRun            bool              `json:"dryRun"`
+	CreatedAt         time.Time         `json:"createdAt"`
+}
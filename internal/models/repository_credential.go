@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Artifact repository kinds
+const (
+	RepositoryNexus       = "nexus"
+	RepositoryArtifactory = "artifactory"
+	RepositoryGitLab      = "gitlab"
+)
+
+// RepositoryCredential lets the builds of a profile's services authenticate
+// against a private Maven repository or package registry
+type RepositoryCredential struct {
+	ProfileID   string    `json:"profileId"`
+	ServerID    string    `json:"serverId"` // <repository><id> in pom.xml, or the repository name in Gradle
+	Kind        string    `json:"kind"`     // "nexus", "artifactory" or "gitlab"
+	URL         string    `json:"url,omitempty"`
+	Username    string    `json:"username,omitempty"`
+	Secret      string    `json:"secret,omitempty"`      // Password or token; stored encrypted and never returned
+	TokenHeader string    `json:"tokenHeader,omitempty"` // Send the secret in this header instead of basic auth, e.g. Private-Token
+	HasSecret   bool      `json:"hasSecret"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// ServiceResourceLimits are the ulimits and container-style memory hints a
+// service is started with. Zero leaves a setting as Vertex itself runs.
+type ServiceResourceLimits struct {
+	ServiceID     string `json:"serviceId"`
+	NoFile        int    `json:"nofile"`        // Open files (ulimit -n), raised up to the hard limit
+	NProc         int    `json:"nproc"`         // Processes of the user (ulimit -u), raised up to the hard limit
+	MemoryLimitMB int    `json:"memoryLimitMb"` // Memory the JVM sizes itself for, as if it were a container limit (-XX:MaxRAM)
+	HeapPercent   int    `json:"heapPercent"`   // Share of that memory given to the heap (-XX:MaxRAMPercentage); the JVM default is 25
+	CPUs          int    `json:"cpus"`          // Processors the JVM sizes its thread pools for (-XX:ActiveProcessorCount)
+}
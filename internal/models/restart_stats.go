@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Why a service was restarted
+const (
+	RestartManual = "manual" // A restart asked for through the UI, API or CLI
+	RestartCrash  = "crash"  // The process exited on its own with an error and has to be started again
+)
+
+// ServiceRestart is one restart or crash of a service
+type ServiceRestart struct {
+	ServiceID string    `json:"serviceId"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RestartCounts is how often a service restarted within a time window
+type RestartCounts struct {
+	Total   int `json:"total"`
+	Manual  int `json:"manual"`
+	Crashes int `json:"crashes"`
+}
+
+// RestartStats is how often a service restarted in the last hour, day and
+// week
+type RestartStats struct {
+	LastHour      RestartCounts `json:"lastHour"`
+	LastDay       RestartCounts `json:"lastDay"`
+	LastWeek      RestartCounts `json:"lastWeek"`
+	LastRestartAt *time.Time    `json:"lastRestartAt,omitempty"`
+}
+
+// RestartLeaderboardEntry is a service of a profile ranked by its restarts
+type RestartLeaderboardEntry struct {
+	ServiceID   string       `json:"serviceId"`
+	ServiceName string       `json:"serviceName"`
+	Status      string       `json:"status"`
+	Restarts    RestartStats `json:"restarts"`
+}
+
+// RestartLeaderboard is the services of a profile that restarted the most
+// within a window, most restarts first
+type RestartLeaderboard struct {
+	ProfileID string                    `json:"profileId"`
+	Window    string                    `json:"window"` // 1h, 24h or 7d
+	Services  []RestartLeaderboardEntry `json:"services"`
+}
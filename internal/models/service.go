@@ -2,7 +2,9 @@
 package models
 
 import (
+	"io"
 	"os/exec"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -13,25 +15,46 @@ type Service struct {
 	Dir               string              `json:"dir"`
 	ExtraEnv          string              `json:"extraEnv"`
 	JavaOpts          string              `json:"javaOpts"`
+	JavaOptsPreset    string              `json:"javaOptsPreset"` // Name of the JVM preset applied before JavaOpts ("" = none)
 	Status            string              `json:"status"`
 	HealthStatus      string              `json:"healthStatus"`
+	Flapping          bool                `json:"flapping"`  // Health toggles too often; its changes are not alerted
+	FlapScore         float64             `json:"flapScore"` // Recent healthy/unhealthy changes, weighted by age
 	HealthURL         string              `json:"healthUrl"`
 	Port              int                 `json:"port"`
 	PID               int                 `json:"pid"`
 	Order             int                 `json:"order"`
 	LastStarted       time.Time           `json:"lastStarted"`
+	ProfileID         string              `json:"profileId"` // Profile the service was last started under
 	Uptime            string              `json:"uptime"`
 	Description       string              `json:"description"`
 	IsEnabled         bool                `json:"isEnabled"`
+	ArchivedAt        *time.Time          `json:"archivedAt,omitempty"` // Set while the service is archived: hidden, never started, config kept
 	BuildSystem       string              `json:"buildSystem"`       // "maven", "gradle", or "auto"
 	VerboseLogging    bool                `json:"verboseLogging"`    // Enable verbose/debug logging for build tools
+	IdleMinutes       int                 `json:"idleMinutes"`       // Auto-suspend after this many idle minutes (0 = disabled)
+	HealthInterval    int                 `json:"healthInterval"`    // Seconds between health checks (0 = default)
+	SkipDiscovery     bool                `json:"skipDiscovery"`     // Don't inject <SERVICE>_HOST/_PORT/_URL of the profile's other services
+	Owner             ServiceOwner        `json:"owner"`             // Team and contacts its alerts are routed to
+	RunAsUser         string              `json:"runAsUser"`         // OS user the service process runs as (empty = the Vertex user)
+	ExecutionMode     string              `json:"executionMode"`     // "build-tool" (spring-boot:run/bootRun, default) or "jar" (java -jar of the packaged jar)
+	WorkingDir        string              `json:"workingDir"`        // Subdirectory of Dir the service is built and run from, such as a monorepo module
+	StartCommand      string              `json:"startCommand"`      // Replaces the build system's run command (empty = default)
+	BuildCommand      string              `json:"buildCommand"`      // Runs before the start command, or replaces the jar execution mode's package command
+	AutoMigrate       bool                `json:"autoMigrate"`       // Apply pending Flyway/Liquibase migrations before each start
+	Interactive       bool                `json:"interactive"`       // Keep stdin of the process open so input can be sent to it
+	ReloadOnSIGHUP    bool                `json:"reloadOnSighup"`    // Re-reads its configuration on SIGHUP, so environment changes are signalled instead of requiring a restart
+	InfraType         string              `json:"infraType"`         // "redis", "kafka", "rabbitmq", "minio" or "postgres" for infrastructure run by Vertex (empty = a Java service)
+	InfraRuntime      string              `json:"infraRuntime"`      // How infrastructure runs: "container", "binary" or empty to pick one
 	GitBranch         string              `json:"gitBranch"`         // Current git branch (if service is a git repo)
 	GitHasUncommitted bool                `json:"gitHasUncommitted"` // Has uncommitted changes
 	GitCommitsAhead   int                 `json:"gitCommitsAhead"`   // Commits ahead of remote
 	GitCommitsBehind  int                 `json:"gitCommitsBehind"`  // Commits behind remote
 	GitIsClean        bool                `json:"gitIsClean"`        // No uncommitted changes and in sync
 	EnvVars           map[string]EnvVar   `json:"envVars"`
+	Tags              map[string]string   `json:"tags"` // Arbitrary key/value labels, e.g. team, tier, language
 	Cmd               *exec.Cmd           `json:"-"`
+	Stdin             io.WriteCloser      `json:"-"` // Stdin of the process while an interactive service runs
 	Logs              []LogEntry          `json:"logs"`
 	Mutex             sync.RWMutex        `json:"-"`
 	CPUPercent        float64             `json:"cpuPercent"`
@@ -41,7 +64,29 @@ type Service struct {
 	NetworkRx         uint64              `json:"networkRx"` // bytes received
 	NetworkTx         uint64              `json:"networkTx"` // bytes transmitted
 	Metrics           ServiceMetrics      `json:"metrics"`
+	Restarts          *RestartStats       `json:"restarts,omitempty"` // Set in service listings
 	Dependencies      []ServiceDependency `json:"dependencies"`
 	DependentOn       []string            `json:"dependentOn"`  // Services that depend on this one
 	StartupDelay      time.Duration       `json:"startupDelay"` // Delay before starting after dependencies
+
+	// Eureka instance overrides injected into the environment at start
+	EurekaHostname        string `json:"eurekaHostname,omitempty"`        // Sets eureka.instance.hostname (empty = the service's own setting)
+	EurekaPreferIPAddress *bool  `json:"eurekaPreferIpAddress,omitempty"` // Sets eureka.instance.prefer-ip-address (nil = the service's own setting)
+}
+
+// Clone returns a copy of the service without its mutex, for reading a
+// service after its lock is released. Maps and slices are shared with the
+// original, as they were when services were copied by value. The caller must
+// hold the service's read lock.
+func (s *Service) Clone() *Service {
+	clone := &Service{}
+	src := reflect.ValueOf(s).Elem()
+	dst := reflect.ValueOf(clone).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if src.Type().Field(i).Name == "Mutex" {
+			continue
+		}
+		dst.Field(i).Set(src.Field(i))
+	}
+	return clone
 }
@@ -32,3 +32,56 @@ type ServiceDependency struct {
 	Required      bool          `json:"required"`      // Whether this dependency is required for startup
 	Description   string        `json:"description"`   // Human-readable description
 }
+
+// DependencyReadiness is an HTTP probe a dependency must pass before the
+// service depending on it starts, e.g. the config server serving the
+// dependent's configuration. The URL may use {service} for the dependent's
+// name and {port} for the dependency's port.
+type DependencyReadiness struct {
+	URL            string `json:"url"`
+	ExpectedStatus int    `json:"expectedStatus,omitempty"` // Defaults to any 2xx status
+	BodyContains   string `json:"bodyContains,omitempty"`
+	JSONPath       string `json:"jsonPath,omitempty"`  // e.g. $.propertySources[*].name
+	JSONValue      string `json:"jsonValue,omitempty"` // Value expected at JSONPath; empty accepts any non-null value
+}
+
+// Outcomes of a dependency wait during a profile start
+const (
+	DependencyReady      = "ready"
+	DependencyTimedOut   = "timeout"
+	DependencyNotRunning = "not-running"
+	DependencyFailed     = "failed" // Failed or was skipped earlier in the same start
+	DependencyCancelled  = "cancelled"
+)
+
+// DependencyWaitResult is how long a service waited for one dependency
+// before it started, within the dependency's timeout and retry interval
+type DependencyWaitResult struct {
+	ServiceID            string `json:"serviceId"`
+	ServiceName          string `json:"serviceName"`
+	Required             bool   `json:"required"`
+	TimeoutSeconds       int    `json:"timeoutSeconds"`
+	RetryIntervalSeconds int    `json:"retryIntervalSeconds"`
+	Attempts             int    `json:"attempts"`
+	WaitedMs             int64  `json:"waitedMs"`
+	Outcome              string `json:"outcome"`
+	Error                string `json:"error,omitempty"`
+}
+
+// ServiceStartupResult is one service of a profile start
+type ServiceStartupResult struct {
+	ServiceID    string                 `json:"serviceId"`
+	ServiceName  string                 `json:"serviceName"`
+	Outcome      string                 `json:"outcome"` // pending, started, failed, skipped, already-running, disabled
+	Error        string                 `json:"error,omitempty"`
+	Dependencies []DependencyWaitResult `json:"dependencies"`
+}
+
+// StartupReport is the latest profile start: the order the services started
+// in and what each waited for
+type StartupReport struct {
+	StartedAt  time.Time              `json:"startedAt"`
+	FinishedAt *time.Time             `json:"finishedAt,omitempty"`
+	Status     string                 `json:"status"` // running, completed, failed, cancelled
+	Services   []ServiceStartupResult `json:"services"`
+}
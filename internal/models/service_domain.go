@@ -0,0 +1,12 @@
+package models
+
+// ServiceDomainConfig serves a service on a subdomain of the Vertex domain,
+// e.g. https://payments.vertex.dev, through a server block of its own
+type ServiceDomainConfig struct {
+	ServiceID string `json:"serviceId"`
+	Enabled   bool   `json:"enabled"`
+	Subdomain string `json:"subdomain"` // Label in front of the Vertex domain, e.g. payments
+	Domain    string `json:"domain"`    // Full name, e.g. payments.vertex.dev; ignored on save
+	URL       string `json:"url"`       // Where the service is reached; ignored on save
+	Port      int    `json:"port"`      // Service port requests are proxied to; ignored on save
+}
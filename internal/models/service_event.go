@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Service event types recorded in the per-service timeline
+const (
+	EventStarted         = "started"
+	EventAdopted         = "adopted" // A process left by an earlier Vertex run was reattached
+	EventStopped         = "stopped"
+	EventCrashed         = "crashed"
+	EventCrashLooping    = "crash-looping"
+	EventSuspended       = "suspended"
+	EventPaused          = "paused"
+	EventResumed         = "resumed"
+	EventHealthChanged   = "health-changed"
+	EventHealthFlapping  = "health-flapping" // Health toggles too often; its changes are damped until it settles
+	EventSLOViolated     = "slo-violated"    // A probe's success rate or p95 latency missed its objective
+	EventSLORecovered    = "slo-recovered"
+	EventBranchSwitched  = "branch-switched"
+	EventEnvVarsChanged  = "env-vars-changed"
+	EventConfigEdited    = "config-edited"
+	EventConfigFileSaved = "config-file-saved"
+)
+
+// ServiceEvent is a persisted lifecycle or configuration change of a service
+type ServiceEvent struct {
+	ID          int64     `json:"id"`
+	ServiceID   string    `json:"serviceId"`
+	ServiceName string    `json:"serviceName"`
+	Type        string    `json:"type"`
+	Message     string    `json:"message"`
+	Timestamp   time.Time `json:"timestamp"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// Lifecycle phases a service hook can be attached to
+const (
+	HookPhasePreStart  = "pre-start"
+	HookPhasePostStart = "post-start"
+	HookPhasePostStop  = "post-stop"
+)
+
+// Supported hook actions
+const (
+	HookTypeCommand = "command"
+	HookTypeHTTP    = "http"
+)
+
+// ServiceHook is a shell command or HTTP call run around a service's lifecycle
+type ServiceHook struct {
+	ID             int    `json:"id"`
+	ServiceID      string `json:"serviceId"`
+	Phase          string `json:"phase"` // "pre-start", "post-start", "post-stop"
+	Type           string `json:"type"`  // "command" or "http"
+	Command        string `json:"command"`
+	URL            string `json:"url"`
+	Method         string `json:"method"`
+	Body           string `json:"body"`
+	TimeoutSeconds int    `json:"timeoutSeconds"`
+	AbortOnFailure bool   `json:"abortOnFailure"` // Abort startup (pre-start) or stop the service (post-start) on failure
+	Enabled        bool   `json:"enabled"`
+}
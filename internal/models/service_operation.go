@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// States of a lifecycle operation on a service's actor
+const (
+	OperationQueued  = "queued"  // Waiting for the operation ahead of it
+	OperationRunning = "running" // Being carried out
+)
+
+// ServiceOperation is a lifecycle operation (start, stop, restart, pause,
+// resume, suspend) that was accepted for a service and has not finished
+type ServiceOperation struct {
+	ID          int64      `json:"id"`
+	ServiceID   string     `json:"serviceId"`
+	ServiceName string     `json:"serviceName"`
+	Name        string     `json:"name"`
+	State       string     `json:"state"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+}
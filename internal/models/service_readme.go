@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ServiceNotes is free-form Markdown kept with a service, such as steps that
+// must happen before it can start
+type ServiceNotes struct {
+	ServiceID string     `json:"serviceId"`
+	Notes     string     `json:"notes"`
+	HTML      string     `json:"html"` // Notes rendered to sanitized HTML
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// ServiceReadme is the README of a service's repository rendered to
+// sanitized HTML, together with the service's notes
+type ServiceReadme struct {
+	ServiceID string       `json:"serviceId"`
+	Path      string       `json:"path,omitempty"`   // Relative to the service directory; empty when none was found
+	Format    string       `json:"format,omitempty"` // "markdown" or "text"
+	HTML      string       `json:"html"`
+	Truncated bool         `json:"truncated,omitempty"` // Only the start of a large README was rendered
+	Notes     ServiceNotes `json:"notes"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestServiceClone(t *testing.T) {
+	preferIP := true
+	service := &Service{
+		ID:                    "svc-1",
+		Name:                  "api",
+		Port:                  8080,
+		LastStarted:           time.Now(),
+		EnvVars:               map[string]EnvVar{"PORT": {Name: "PORT", Value: "8080"}},
+		DependentOn:           []string{"gateway"},
+		StartupDelay:          time.Second,
+		EurekaPreferIPAddress: &preferIP,
+	}
+
+	// Create the clone while the original is locked, as callers do
+	service.Mutex.Lock()
+	clone := service.Clone()
+	service.Mutex.Unlock()
+
+	// Compare every field except the mutex
+	serviceValue := reflect.ValueOf(service).Elem()
+	cloneValue := reflect.ValueOf(clone).Elem()
+	for i := 0; i < serviceValue.NumField(); i++ {
+		name := serviceValue.Type().Field(i).Name
+		if name == "Mutex" {
+			continue
+		}
+		if !reflect.DeepEqual(serviceValue.Field(i).Interface(), cloneValue.Field(i).Interface()) {
+			t.Errorf("Clone field %s: got %v want %v", name, cloneValue.Field(i), serviceValue.Field(i))
+		}
+	}
+
+	if !clone.Mutex.TryLock() {
+		t.Error("Expected the clone to have its own unlocked mutex")
+	}
+}
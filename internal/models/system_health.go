@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// States of Vertex itself and of its subsystems
+const (
+	SystemHealthy  = "healthy"
+	SystemDegraded = "degraded"
+)
+
+// SystemHealth reports the state of the Vertex process, as opposed to the
+// services it runs
+type SystemHealth struct {
+	Status           string            `json:"status"` // "degraded" when any subsystem is
+	StartedAt        time.Time         `json:"startedAt"`
+	UptimeSeconds    int64             `json:"uptimeSeconds"`
+	Goroutines       int               `json:"goroutines"`
+	Memory           DaemonMemory      `json:"memory"`
+	Database         DaemonDatabase    `json:"database"`
+	WebSocketClients int               `json:"webSocketClients"`
+	Subscribers      int               `json:"subscribers"` // In-process listeners such as GraphQL subscriptions
+	Queues           DaemonQueues      `json:"queues"`
+	Backups          BackupStatus      `json:"backups"`
+	Subsystems       []SubsystemHealth `json:"subsystems"`
+	ErrorCount       int64             `json:"errorCount"` // Errors logged since start
+	LastErrors       []DaemonError     `json:"lastErrors"` // Most recent first
+}
+
+// DaemonMemory is the memory use of the Vertex process
+type DaemonMemory struct {
+	RSSBytes       uint64  `json:"rssBytes,omitempty"`
+	HeapAllocBytes uint64  `json:"heapAllocBytes"`
+	HeapInuseBytes uint64  `json:"heapInuseBytes"`
+	SysBytes       uint64  `json:"sysBytes"` // Obtained from the OS by the Go runtime
+	NumGC          uint32  `json:"numGc"`
+	LastGCPauseMs  float64 `json:"lastGcPauseMs"`
+	OpenFiles      int32   `json:"openFiles,omitempty"`
+}
+
+// DaemonDatabase is the responsiveness of the SQLite database
+type DaemonDatabase struct {
+	LatencyMs float64 `json:"latencyMs"` // Time to answer a trivial query
+	Error     string  `json:"error,omitempty"`
+}
+
+// DaemonQueues holds the depth of Vertex's internal queues
+type DaemonQueues struct {
+	Broadcast         int `json:"broadcast"` // WebSocket messages waiting for the next batch
+	LogWrites         int `json:"logWrites"`
+	LogWritesCapacity int `json:"logWritesCapacity"`
+	LogSinks          int `json:"logSinks"`          // Records waiting to be shipped, all profiles
+	ServiceOperations int `json:"serviceOperations"` // Lifecycle operations queued or running
+	HealthChecks      int `json:"healthChecks"`      // Scheduled health checks in flight
+}
+
+// SubsystemHealth is the state of one part of Vertex
+type SubsystemHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"` // Why it is degraded
+}
+
+// DaemonError is an error Vertex logged about itself
+type DaemonError struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Test run states
+const (
+	TestRunRunning = "running"
+	TestRunPassed  = "passed"
+	TestRunFailed  = "failed" // Tests ran and some failed
+	TestRunError   = "error"  // The build failed or no tests could run
+)
+
+// Test case outcomes
+const (
+	TestCasePassed  = "passed"
+	TestCaseFailed  = "failed"
+	TestCaseError   = "error"
+	TestCaseSkipped = "skipped"
+)
+
+// TestRun is one execution of a service's test suite
+type TestRun struct {
+	ID          int64             `json:"id"`
+	ServiceID   string            `json:"serviceId"`
+	ServiceName string            `json:"serviceName"`
+	Status      string            `json:"status"`
+	Command     string            `json:"command"`
+	Filter      string            `json:"filter,omitempty"`
+	StartedAt   time.Time         `json:"startedAt"`
+	FinishedAt  *time.Time        `json:"finishedAt,omitempty"`
+	DurationMs  int64             `json:"durationMs"`
+	Total       int               `json:"total"`
+	Failures    int               `json:"failures"`
+	Errors      int               `json:"errors"`
+	Skipped     int               `json:"skipped"`
+	Suites      []TestSuiteResult `json:"suites,omitempty"`
+	Output      string            `json:"output,omitempty"` // Tail of the build output
+	Error       string            `json:"error,omitempty"`
+}
+
+// TestSuiteResult is a test class parsed from a surefire or JUnit XML report
+type TestSuiteResult struct {
+	Name     string           `json:"name"`
+	Tests    int              `json:"tests"`
+	Failures int              `json:"failures"`
+	Errors   int              `json:"errors"`
+	Skipped  int              `json:"skipped"`
+	Time     float64          `json:"time"` // Seconds
+	Cases    []TestCaseResult `json:"cases"`
+}
+
+// TestCaseResult is a single test method
+type TestCaseResult struct {
+	Name      string  `json:"name"`
+	ClassName string  `json:"className"`
+	Status    string  `json:"status"`
+	Time      float64 `json:"time"`
+	Message   string  `json:"message,omitempty"`
+	Details   string  `json:"details,omitempty"` // Stack trace of a failure or error
+}
+
+// TestRunRequest optionally narrows a run to matching tests
+type TestRunRequest struct {
+	Filter string `json:"filter"` // Passed to -Dtest (Maven) or --tests (Gradle)
+}
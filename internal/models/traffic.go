@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// TrafficCaptureRequest starts a debugging proxy that records the HTTP
+// traffic sent through it to a service
+type TrafficCaptureRequest struct {
+	ListenPort int `json:"listenPort"` // Port the debugging proxy listens on; 0 picks a free port
+}
+
+// TrafficSummary aggregates the requests sent through a service's debugging
+// proxy; requests to the service's own port are not included
+type TrafficSummary struct {
+	ServiceID     string                 `json:"serviceId"`
+	ServiceName   string                 `json:"serviceName"`
+	Capturing     bool                   `json:"capturing"`
+	ListenPort    int                    `json:"listenPort,omitempty"`
+	TargetPort    int                    `json:"targetPort"`
+	StartedAt     *time.Time             `json:"startedAt,omitempty"`
+	TotalRequests int64                  `json:"totalRequests"`
+	Endpoints     []TrafficEndpointStats `json:"endpoints"`
+}
+
+// TrafficEndpointStats summarizes requests sharing a method and path template
+type TrafficEndpointStats struct {
+	Method   string           `json:"method"`
+	Path     string           `json:"path"` // Template such as /api/users/{id}
+	Count    int64            `json:"count"`
+	Statuses map[string]int64 `json:"statuses"` // Status code -> count; 502 also covers an unreachable service
+	Errors   int64            `json:"errors"`   // 5xx responses
+	P50Ms    float64          `json:"p50Ms"`
+	P90Ms    float64          `json:"p90Ms"`
+	P99Ms    float64          `json:"p99Ms"`
+	MaxMs    float64          `json:"maxMs"`
+	LastSeen time.Time        `json:"lastSeen"`
+}
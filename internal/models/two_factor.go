@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// UserTwoFactor is a user's TOTP secret and recovery codes as stored; it is
+// never sent to clients
+type UserTwoFactor struct {
+	UserID        string
+	Secret        string // Base32
+	Enabled       bool   // False until the first code is confirmed
+	RecoveryCodes []string
+	LastUsedStep  int64 // Time step of the last accepted code, which cannot be used again
+	CreatedAt     time.Time
+	EnabledAt     *time.Time
+}
+
+// TwoFactorStatus tells a user whether they sign in with a one-time code
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	Pending           bool       `json:"pending"` // Enrolled but no code confirmed yet
+	EnabledAt         *time.Time `json:"enabledAt,omitempty"`
+	RecoveryCodesLeft int        `json:"recoveryCodesLeft"`
+	Required          bool       `json:"required"` // Admins require it for every account
+}
+
+// TwoFactorEnrollment is the secret to add to an authenticator app, shown
+// once when enrolling
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`     // For typing in by hand
+	OTPAuthURL string `json:"otpauthUrl"` // The otpauth:// URI to show as a QR code
+}
+
+// TwoFactorRecoveryCodes are single-use codes that stand in for a one-time
+// code when the authenticator is lost; they are shown once
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// TwoFactorCodeRequest carries a one-time or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
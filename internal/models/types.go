@@ -20,5 +20,44 @@ type EnvVar struct {
 	Name        string `json:"name"`
 	Value       string `json:"value"`
 	Description string `json:"description"`
-	IsRequired  bool   `json:"isRequired"`
+	IsRequired  bool   `json:"isRequired"`        // The service does not start while the value is empty
+	Type        string `json:"type,omitempty"`    // The value must be of this type, e.g. "int" or "https-url"
+	Pattern     string `json:"pattern,omitempty"` // The whole value must match this regular expression
+}
+
+// Types a service env var can require of its value
+const (
+	EnvVarTypeString   = ""
+	EnvVarTypeInt      = "int"
+	EnvVarTypeNumber   = "number"
+	EnvVarTypeBool     = "bool"
+	EnvVarTypePort     = "port"
+	EnvVarTypeURL      = "url"
+	EnvVarTypeHTTPSURL = "https-url"
+)
+
+// EnvVarChange is a single set or delete operation in a bulk env var update
+type EnvVarChange struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+	Action      string `json:"action"` // "set" or "delete"
+}
+
+// BulkEnvVarUpdateRequest applies the same env var changes to several services and profiles
+type BulkEnvVarUpdateRequest struct {
+	ServiceIDs []string       `json:"serviceIds"`
+	ProfileIDs []string       `json:"profileIds"`
+	Changes    []EnvVarChange `json:"changes"`
+	DryRun     bool           `json:"dryRun"`
+}
+
+// EnvVarUpdatePreview shows the before/after env vars of one bulk update target
+type EnvVarUpdatePreview struct {
+	TargetType string            `json:"targetType"` // "service" or "profile"
+	TargetID   string            `json:"targetId"`
+	TargetName string            `json:"targetName"`
+	Before     map[string]string `json:"before"`
+	After      map[string]string `json:"after"`
+	Changed    []string          `json:"changed"`
 }
@@ -15,6 +15,11 @@ type User struct {
 	Role      string    `json:"role" db:"role"`
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 	LastLogin time.Time `json:"lastLogin" db:"last_login"`
+
+	TwoFactorEnabled bool `json:"twoFactorEnabled"`
+	// Admins require two-factor authentication and the user has not set it
+	// up; until they do, only the /api/auth/ routes answer them
+	TwoFactorSetupRequired bool `json:"twoFactorSetupRequired,omitempty"`
 }
 
 type UserRegistration struct {
@@ -43,6 +48,7 @@ func (ur *UserRegistration) Validate() error {
 type UserLogin struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	Code     string `json:"code"` // One-time or recovery code, for accounts with two-factor authentication
 }
 
 type AuthResponse struct {
@@ -68,12 +74,37 @@ type UserProfile struct {
 }
 
 type UserPreferences struct {
-	Theme                string          `json:"theme"`
-	Language             string          `json:"language"`
-	NotificationSettings map[string]bool `json:"notificationSettings"`
-	DashboardLayout      string          `json:"dashboardLayout"`
-	AutoRefresh          bool            `json:"autoRefresh"`
-	RefreshInterval      int             `json:"refreshInterval"` // seconds
+	Theme                string               `json:"theme"`
+	Language             string               `json:"language"`
+	NotificationSettings map[string]bool      `json:"notificationSettings"`
+	DashboardLayout      string               `json:"dashboardLayout"`
+	AutoRefresh          bool                 `json:"autoRefresh"`
+	RefreshInterval      int                  `json:"refreshInterval"` // seconds
+	ColumnLayouts        map[string][]string  `json:"columnLayouts"`   // View name -> visible columns in order
+	PinnedServices       []string             `json:"pinnedServices"`  // Service UUIDs shown first
+	DefaultLogFilters    LogFilterPreferences `json:"defaultLogFilters"`
+	FavoriteProfiles     []string             `json:"favoriteProfiles"` // Service profile IDs
+}
+
+// LogFilterPreferences are the filters a log view opens with
+type LogFilterPreferences struct {
+	Levels []string `json:"levels"` // e.g. ["WARN", "ERROR"]; empty shows every level
+	Search string   `json:"search"`
+}
+
+// UserPreferencesPatch changes only the preferences that are present.
+// ColumnLayouts is merged by view; a null layout removes that view's entry.
+type UserPreferencesPatch struct {
+	Theme                *string               `json:"theme"`
+	Language             *string               `json:"language"`
+	NotificationSettings map[string]bool       `json:"notificationSettings"` // Merged by key
+	DashboardLayout      *string               `json:"dashboardLayout"`
+	AutoRefresh          *bool                 `json:"autoRefresh"`
+	RefreshInterval      *int                  `json:"refreshInterval"`
+	ColumnLayouts        map[string][]string   `json:"columnLayouts"`
+	PinnedServices       *[]string             `json:"pinnedServices"`
+	DefaultLogFilters    *LogFilterPreferences `json:"defaultLogFilters"`
+	FavoriteProfiles     *[]string             `json:"favoriteProfiles"`
 }
 
 type UserProfileUpdateRequest struct {
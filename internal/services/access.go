@@ -0,0 +1,119 @@
+// Package services - Anonymous read-only access and the two-factor policy
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// GetAccessSettings returns what visitors who have not signed in may do
+func (as *AuthService) GetAccessSettings() (*models.AccessSettings, error) {
+	as.accessMutex.RLock()
+	cached := as.access
+	as.accessMutex.RUnlock()
+	if cached != nil {
+		settings := *cached
+		return &settings, nil
+	}
+
+	as.accessMutex.Lock()
+	defer as.accessMutex.Unlock()
+	if as.access == nil {
+		settings, err := as.db.GetAccessSettings()
+		if err != nil {
+			return nil, err
+		}
+		as.access = settings
+	}
+	settings := *as.access
+	return &settings, nil
+}
+
+// AnonymousReadOnly reports whether visitors who have not signed in get the
+// read-only dashboard. It is off when the settings cannot be read.
+func (as *AuthService) AnonymousReadOnly() bool {
+	settings, err := as.GetAccessSettings()
+	if err != nil {
+		log.Printf("[WARN] Failed to read access settings, anonymous access stays off: %v", err)
+		return false
+	}
+	return settings.AnonymousReadOnly
+}
+
+// SetAnonymousReadOnly turns the anonymous read-only dashboard on or off.
+// Only admins may change it.
+func (as *AuthService) SetAnonymousReadOnly(enabled bool, claims *models.JWTClaims) (*models.AccessSettings, error) {
+	if claims.Role != "admin" {
+		return nil, fmt.Errorf("only admins can change anonymous access")
+	}
+
+	settings, err := as.saveAccessSettings(claims, func(settings *models.AccessSettings) {
+		settings.AnonymousReadOnly = enabled
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Anonymous read-only access turned %s by %s", onOff(enabled), claims.Username)
+	return settings, nil
+}
+
+// SetRequireTwoFactor requires every account to sign in with a one-time
+// code, or stops requiring it. Only admins may change it, and only once
+// their own account has two-factor authentication.
+func (as *AuthService) SetRequireTwoFactor(required bool, claims *models.JWTClaims) (*models.AccessSettings, error) {
+	if claims.Role != "admin" {
+		return nil, fmt.Errorf("only admins can require two-factor authentication")
+	}
+	if required {
+		enabled, err := as.twoFactorEnabled(claims.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return nil, fmt.Errorf("enable two-factor authentication for your own account before requiring it")
+		}
+	}
+
+	settings, err := as.saveAccessSettings(claims, func(settings *models.AccessSettings) {
+		settings.RequireTwoFactor = required
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Required two-factor authentication turned %s by %s", onOff(required), claims.Username)
+	return settings, nil
+}
+
+// saveAccessSettings changes the access settings and refreshes the cache
+func (as *AuthService) saveAccessSettings(claims *models.JWTClaims, change func(*models.AccessSettings)) (*models.AccessSettings, error) {
+	as.accessMutex.Lock()
+	defer as.accessMutex.Unlock()
+
+	current, err := as.db.GetAccessSettings()
+	if err != nil {
+		return nil, err
+	}
+	settings := *current
+	change(&settings)
+	settings.UpdatedBy = claims.Username
+	if err := as.db.SaveAccessSettings(settings); err != nil {
+		return nil, err
+	}
+	saved, err := as.db.GetAccessSettings()
+	if err != nil {
+		return nil, err
+	}
+	as.access = saved
+
+	result := *saved
+	return &result, nil
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
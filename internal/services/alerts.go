@@ -0,0 +1,361 @@
+// Package services - Routing alerts about failing services to their owners
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	defaultAlertCooldownSeconds = 300
+	alertRequestTimeout         = 10 * time.Second
+	slackPostMessageURL         = "https://slack.com/api/chat.postMessage"
+)
+
+// Alert delivery channels reported in models.AlertDelivery
+const (
+	AlertChannelSlack       = "slack"
+	AlertChannelTeamWebhook = "team-webhook"
+	AlertChannelWebhook     = "webhook"
+)
+
+var (
+	ownerTeamRegex    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ._-]{0,63}$`)
+	slackChannelRegex = regexp.MustCompile(`^(#[a-z0-9][a-z0-9._-]{0,79}|[CG][A-Z0-9]{8,})$`)
+	slackChannelID    = regexp.MustCompile(`^[CG][A-Z0-9]{8,}$`)
+)
+
+// Last alert per service and event type, for the cooldown
+var (
+	lastAlerts      = make(map[string]time.Time)
+	lastAlertsMutex sync.Mutex
+)
+
+// validateServiceOwner trims and checks a service's owner fields; all of them
+// are optional. Slack channel names get a leading # when it is missing.
+func validateServiceOwner(owner *models.ServiceOwner) error {
+	owner.Team = strings.TrimSpace(owner.Team)
+	owner.SlackChannel = strings.TrimSpace(owner.SlackChannel)
+	owner.Email = strings.TrimSpace(owner.Email)
+
+	if owner.Team != "" && !ownerTeamRegex.MatchString(owner.Team) {
+		return fmt.Errorf("invalid owner team '%s' (letters, digits, spaces, '.', '_' and '-', up to 64 characters)", owner.Team)
+	}
+
+	if owner.SlackChannel != "" {
+		if !strings.HasPrefix(owner.SlackChannel, "#") && !slackChannelID.MatchString(owner.SlackChannel) {
+			owner.SlackChannel = "#" + owner.SlackChannel
+		}
+		if strings.HasPrefix(owner.SlackChannel, "#") {
+			owner.SlackChannel = strings.ToLower(owner.SlackChannel)
+		}
+		if !slackChannelRegex.MatchString(owner.SlackChannel) {
+			return fmt.Errorf("invalid owner Slack channel '%s' (use a channel name such as #payments-alerts or a channel ID)", owner.SlackChannel)
+		}
+	}
+
+	if owner.Email != "" {
+		address, err := mail.ParseAddress(owner.Email)
+		if err != nil || address.Address != owner.Email {
+			return fmt.Errorf("invalid owner email '%s'", owner.Email)
+		}
+	}
+	return nil
+}
+
+// GetAlertSettings returns the alert routing settings without the Slack token
+func (sm *Manager) GetAlertSettings() (*models.AlertSettings, error) {
+	settings, err := sm.db.GetAlertSettings()
+	if err != nil {
+		return nil, err
+	}
+	settings.HasSlackToken = settings.SlackToken != ""
+	settings.SlackToken = ""
+	return settings, nil
+}
+
+// SetAlertSettings validates and saves the alert routing settings. An empty
+// Slack token keeps the stored one unless hasSlackToken is false, which
+// removes it.
+func (sm *Manager) SetAlertSettings(settings models.AlertSettings) error {
+	settings.WebhookURL = strings.TrimSpace(settings.WebhookURL)
+	if settings.WebhookURL != "" {
+		if err := validateAlertWebhookURL(settings.WebhookURL); err != nil {
+			return err
+		}
+	}
+
+	teamWebhooks := make(map[string]string, len(settings.TeamWebhooks))
+	for team, webhookURL := range settings.TeamWebhooks {
+		team = strings.TrimSpace(team)
+		webhookURL = strings.TrimSpace(webhookURL)
+		if !ownerTeamRegex.MatchString(team) {
+			return fmt.Errorf("invalid team name '%s'", team)
+		}
+		if err := validateAlertWebhookURL(webhookURL); err != nil {
+			return fmt.Errorf("team %s: %w", team, err)
+		}
+		teamWebhooks[team] = webhookURL
+	}
+	settings.TeamWebhooks = teamWebhooks
+
+	if settings.Cooldown < 0 {
+		return fmt.Errorf("cooldownSeconds cannot be negative")
+	}
+	if settings.Cooldown == 0 {
+		settings.Cooldown = defaultAlertCooldownSeconds
+	}
+
+	settings.SlackToken = strings.TrimSpace(settings.SlackToken)
+	if settings.SlackToken == "" {
+		if settings.HasSlackToken {
+			stored, err := sm.db.GetAlertSettings()
+			if err != nil {
+				return err
+			}
+			settings.SlackToken = stored.SlackToken
+		}
+	} else {
+		encrypted, err := encryptRepositorySecret(settings.SlackToken)
+		if err != nil {
+			return err
+		}
+		settings.SlackToken = encrypted
+	}
+
+	return sm.db.SaveAlertSettings(settings)
+}
+
+func validateAlertWebhookURL(webhookURL string) error {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("invalid webhook URL '%s'", webhookURL)
+	}
+	return nil
+}
+
+// isAlertEvent reports whether a timeline event means the service is failing
+func isAlertEvent(service *models.Service, eventType string) bool {
+	switch eventType {
+	case models.EventCrashed, models.EventCrashLooping, models.EventHealthFlapping, models.EventSLOViolated:
+		return true
+	case models.EventHealthChanged:
+		return service.HealthStatus == "unhealthy"
+	}
+	return false
+}
+
+// alertServiceOwner sends an alert for a failing service's event in the
+// background. It reads the service, so the caller must hold its mutex or
+// otherwise own it, as for recordServiceEvent.
+func (sm *Manager) alertServiceOwner(service *models.Service, event models.ServiceEvent) {
+	if !isAlertEvent(service, event.Type) {
+		return
+	}
+
+	alert := newServiceAlert(service, event.Type, event.Message, event.Timestamp)
+	go func() {
+		settings, err := sm.db.GetAlertSettings()
+		if err != nil {
+			log.Printf("[WARN] Failed to load alert settings: %v", err)
+			return
+		}
+		if !claimAlertSlot(alert.ServiceID+"|"+alert.Event, time.Duration(settings.Cooldown)*time.Second) {
+			return
+		}
+
+		delivery, err := sm.sendServiceAlert(settings, alert)
+		switch {
+		case err != nil:
+			log.Printf("[WARN] Failed to deliver %s alert for service %s: %v", alert.Event, alert.ServiceName, err)
+		case delivery != nil:
+			log.Printf("[INFO] Sent %s alert for service %s via %s (%s)", alert.Event, alert.ServiceName, delivery.Channel, delivery.Target)
+		}
+	}()
+}
+
+// SendTestAlert sends a test alert for a service along the route its real
+// alerts take, ignoring the cooldown
+func (sm *Manager) SendTestAlert(serviceUUID string) (*models.AlertDelivery, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	alert := newServiceAlert(service, "test", "Test alert sent from Vertex", time.Now())
+	service.Mutex.RUnlock()
+
+	settings, err := sm.db.GetAlertSettings()
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := sm.sendServiceAlert(settings, alert)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, fmt.Errorf("no alert destination is configured for service %s", alert.ServiceName)
+	}
+	return delivery, nil
+}
+
+func newServiceAlert(service *models.Service, eventType, message string, timestamp time.Time) models.ServiceAlert {
+	alert := models.ServiceAlert{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		Event:       eventType,
+		Message:     message,
+		Owner:       service.Owner,
+		Timestamp:   timestamp,
+	}
+
+	alert.Text = fmt.Sprintf("[Vertex] %s %s: %s", service.Name, eventType, message)
+	var contacts []string
+	if service.Owner.Team != "" {
+		contacts = append(contacts, "team "+service.Owner.Team)
+	}
+	if service.Owner.Email != "" {
+		contacts = append(contacts, service.Owner.Email)
+	}
+	if len(contacts) > 0 {
+		alert.Text += " (owner: " + strings.Join(contacts, ", ") + ")"
+	}
+	return alert
+}
+
+// claimAlertSlot reports whether an alert may be sent now and, if so, starts
+// its cooldown
+func claimAlertSlot(key string, cooldown time.Duration) bool {
+	lastAlertsMutex.Lock()
+	defer lastAlertsMutex.Unlock()
+
+	if last, exists := lastAlerts[key]; exists && time.Since(last) < cooldown {
+		return false
+	}
+	lastAlerts[key] = time.Now()
+	return true
+}
+
+// sendServiceAlert delivers an alert to the owner's Slack channel, else to the
+// owner team's webhook, else to the global webhook. A destination that fails
+// falls through to the next one. It returns nil without an error when nothing
+// is configured for the service.
+func (sm *Manager) sendServiceAlert(settings *models.AlertSettings, alert models.ServiceAlert) (*models.AlertDelivery, error) {
+	ctx, cancel := context.WithTimeout(sm.ctx, alertRequestTimeout)
+	defer cancel()
+
+	var failures []string
+
+	if alert.Owner.SlackChannel != "" && settings.SlackToken != "" {
+		err := postSlackAlert(ctx, settings.SlackToken, alert)
+		if err == nil {
+			return &models.AlertDelivery{Channel: AlertChannelSlack, Target: alert.Owner.SlackChannel}, nil
+		}
+		failures = append(failures, fmt.Sprintf("slack %s: %v", alert.Owner.SlackChannel, err))
+	}
+
+	if alert.Owner.Team != "" {
+		for team, webhookURL := range settings.TeamWebhooks {
+			if !strings.EqualFold(team, alert.Owner.Team) {
+				continue
+			}
+			err := postAlertWebhook(ctx, webhookURL, alert)
+			if err == nil {
+				return &models.AlertDelivery{Channel: AlertChannelTeamWebhook, Target: team, Error: strings.Join(failures, "; ")}, nil
+			}
+			failures = append(failures, fmt.Sprintf("team %s webhook: %v", team, err))
+			break
+		}
+	}
+
+	if settings.WebhookURL != "" {
+		err := postAlertWebhook(ctx, settings.WebhookURL, alert)
+		if err == nil {
+			return &models.AlertDelivery{Channel: AlertChannelWebhook, Target: "global", Error: strings.Join(failures, "; ")}, nil
+		}
+		failures = append(failures, fmt.Sprintf("global webhook: %v", err))
+	}
+
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+	return nil, nil
+}
+
+// postSlackAlert posts the alert text to a channel with a bot token
+func postSlackAlert(ctx context.Context, storedToken string, alert models.ServiceAlert) error {
+	token, err := decryptRepositorySecret(storedToken)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt Slack token: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]string{"channel": alert.Owner.SlackChannel, "text": alert.Text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackPostMessageURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Slack answers 200 with ok=false for errors such as channel_not_found
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return fmt.Errorf("unexpected Slack response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack rejected the message: %s", result.Error)
+	}
+	return nil
+}
+
+// postAlertWebhook posts the alert as JSON. Its text field is what Slack and
+// compatible incoming webhooks display; other receivers get the full alert.
+func postAlertWebhook(ctx context.Context, webhookURL string, alert models.ServiceAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
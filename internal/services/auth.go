@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -18,6 +20,13 @@ import (
 type AuthService struct {
 	db        *database.Database
 	jwtSecret []byte
+
+	accessMutex sync.RWMutex
+	access      *models.AccessSettings // Cached, since every API request consults it
+
+	twoFactorMutex        sync.Mutex
+	twoFactorEnabledUsers map[string]bool // Cached by user ID, for the same reason
+	twoFactorFailures     map[string]*twoFactorFailures
 }
 
 func NewAuthService(db *database.Database) *AuthService {
@@ -38,13 +47,33 @@ func NewAuthService(db *database.Database) *AuthService {
 	}
 
 	return &AuthService{
-		db:        db,
-		jwtSecret: secret,
+		db:                    db,
+		jwtSecret:             secret,
+		twoFactorEnabledUsers: make(map[string]bool),
+		twoFactorFailures:     make(map[string]*twoFactorFailures),
 	}
 }
 
 // Register creates a new user account
 func (as *AuthService) Register(registration *models.UserRegistration) (*models.User, error) {
+	return as.registerWithRole(registration, "user")
+}
+
+// RegisterAdmin creates the administrator account during first-run setup
+func (as *AuthService) RegisterAdmin(registration *models.UserRegistration) (*models.User, error) {
+	return as.registerWithRole(registration, "admin")
+}
+
+// HasUsers reports whether any user account exists yet
+func (as *AuthService) HasUsers() (bool, error) {
+	var count int
+	if err := as.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (as *AuthService) registerWithRole(registration *models.UserRegistration, role string) (*models.User, error) {
 	// Check if user already exists
 	if exists, err := as.userExists(registration.Email, registration.Username); err != nil {
 		return nil, fmt.Errorf("failed to check if user exists: %w", err)
@@ -67,7 +96,7 @@ func (as *AuthService) Register(registration *models.UserRegistration) (*models.
 		Username:  registration.Username,
 		Email:     registration.Email,
 		Password:  string(hashedPassword),
-		Role:      "user",
+		Role:      role,
 		CreatedAt: time.Now(),
 	}
 
@@ -97,6 +126,18 @@ func (as *AuthService) Login(login *models.UserLogin) (*models.AuthResponse, err
 		return nil, fmt.Errorf("invalid email or password")
 	}
 
+	// Accounts with two-factor authentication also need a one-time code
+	if enabled, err := as.twoFactorEnabled(user.ID); err != nil {
+		return nil, err
+	} else if enabled {
+		if strings.TrimSpace(login.Code) == "" {
+			return nil, ErrTwoFactorRequired
+		}
+		if _, err := as.verifyTwoFactorCode(user.ID, login.Code); err != nil {
+			return nil, err
+		}
+	}
+
 	// Update last login
 	if err := as.updateLastLogin(user.ID); err != nil {
 		log.Printf("Failed to update last login for user %s: %v", user.ID, err)
@@ -111,6 +152,7 @@ func (as *AuthService) Login(login *models.UserLogin) (*models.AuthResponse, err
 	// Don't return password hash
 	user.Password = ""
 	user.LastLogin = time.Now()
+	as.setUserTwoFactor(user)
 
 	return &models.AuthResponse{
 		User:  *user,
@@ -150,6 +192,7 @@ func (as *AuthService) GetUserByID(userID string) (*models.User, error) {
 	}
 	// Don't return password hash
 	user.Password = ""
+	as.setUserTwoFactor(user)
 	return user, nil
 }
 
@@ -174,6 +217,14 @@ func (as *AuthService) saveUser(user *models.User) error {
 	return err
 }
 
+// deleteUser removes a user
+func (as *AuthService) deleteUser(userID string) error {
+	if _, err := as.db.Exec("DELETE FROM users WHERE id = ?", userID); err != nil {
+		return fmt.Errorf("failed to delete user %s: %w", userID, err)
+	}
+	return nil
+}
+
 func (as *AuthService) getUserByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	query := `
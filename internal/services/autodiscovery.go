@@ -32,15 +32,24 @@ type DiscoveredService struct {
 	Properties  map[string]string `json:"properties"`
 	IsValid     bool              `json:"isValid"`
 	Exists      bool              `json:"exists"`
+
+	// A module of a multi-module build is run from the repository at Path
+	Module       string `json:"module,omitempty"`       // The module's directory, relative to Path
+	BuildCommand string `json:"buildCommand,omitempty"` // Builds the module and the modules it depends on
+	StartCommand string `json:"startCommand,omitempty"` // Runs the module from the repository
 }
 
 type MavenPOM struct {
-	XMLName      xml.Name `xml:"project"`
-	GroupID      string   `xml:"groupId"`
-	ArtifactID   string   `xml:"artifactId"`
-	Version      string   `xml:"version"`
-	Name         string   `xml:"name"`
-	Description  string   `xml:"description"`
+	XMLName     xml.Name `xml:"project"`
+	GroupID     string   `xml:"groupId"`
+	ArtifactID  string   `xml:"artifactId"`
+	Version     string   `xml:"version"`
+	Name        string   `xml:"name"`
+	Description string   `xml:"description"`
+	Packaging   string   `xml:"packaging"`
+	Modules     struct {
+		Module []string `xml:"module"`
+	} `xml:"modules"`
 	Dependencies struct {
 		Dependency []struct {
 			GroupID    string `xml:"groupId"`
@@ -58,6 +67,14 @@ type MavenPOM struct {
 		ArtifactID string `xml:"artifactId"`
 		Version    string `xml:"version"`
 	} `xml:"parent"`
+	Build struct {
+		Plugins struct {
+			Plugin []struct {
+				GroupID    string `xml:"groupId"`
+				ArtifactID string `xml:"artifactId"`
+			} `xml:"plugin"`
+		} `xml:"plugins"`
+	} `xml:"build"`
 }
 
 func NewAutoDiscoveryService(manager *Manager) *AutoDiscoveryService {
@@ -90,6 +107,24 @@ func (ads *AutoDiscoveryService) ScanDirectory(scanDir string) ([]DiscoveredServ
 			return nil // Continue walking
 		}
 
+		// A multi-module build is discovered as its runnable modules
+		if info.IsDir() {
+			if modules, ok := ads.discoverModules(path, scanDir); ok {
+				discoveredServices = append(discoveredServices, modules...)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// A multi-module build is discovered as its runnable modules
+		if info.IsDir() {
+			if modules, ok := ads.discoverModules(path, scanDir); ok {
+				discoveredServices = append(discoveredServices, modules...)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Look for Maven projects (pom.xml files)
 		if info.Name() == "pom.xml" {
 			service, err := ads.analyzeMavenProjectWithScanDir(path, scanDir)
@@ -152,6 +187,15 @@ func (ads *AutoDiscoveryService) ScanDirectoryForProfile(scanDir string, profile
 			return nil
 		}
 
+		// A multi-module build is discovered as its runnable modules
+		if info.IsDir() {
+			if modules, ok := ads.discoverModules(path, scanDir); ok {
+				discoveredServices = append(discoveredServices, modules...)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Look for Maven projects (pom.xml files)
 		if info.Name() == "pom.xml" {
 			service, err := ads.analyzeMavenProjectWithScanDir(path, scanDir)
@@ -315,6 +359,12 @@ func (ads *AutoDiscoveryService) analyzeGradleProjectWithScanDir(buildPath, scan
 }
 
 func (ads *AutoDiscoveryService) isSpringBootProject(pom MavenPOM) bool {
+	return isSpringBootPOM(pom)
+}
+
+// isSpringBootPOM reports whether a pom inherits from the Spring Boot parent
+// or depends on a Spring Boot starter
+func isSpringBootPOM(pom MavenPOM) bool {
 	// Check for Spring Boot parent
 	if pom.Parent.GroupID == "org.springframework.boot" && pom.Parent.ArtifactID == "spring-boot-starter-parent" {
 		return true
@@ -455,7 +505,7 @@ func (ads *AutoDiscoveryService) checkExistingServices(discoveredServices *[]Dis
 
 		// If not already flagged as existing, check for path conflicts using system-wide validation
 		if !(*discoveredServices)[i].Exists {
-			if err := ads.manager.ValidateServiceUniqueness((*discoveredServices)[i].Name, (*discoveredServices)[i].Path); err != nil {
+			if err := ads.manager.ValidateServiceUniqueness((*discoveredServices)[i].Name, (*discoveredServices)[i].Path, (*discoveredServices)[i].Module); err != nil {
 				(*discoveredServices)[i].Exists = true
 			}
 		}
@@ -469,10 +519,10 @@ func (ads *AutoDiscoveryService) checkExistingServicesForProfile(discoveredServi
 
 	// Get all global services to check paths
 	allServices := ads.manager.GetServices()
-	globalServicePathMap := make(map[string]models.Service)
+	globalServicePathMap := make(map[string]*models.Service)
 
 	for _, service := range allServices {
-		normalizedPath := filepath.ToSlash(filepath.Clean(service.Dir))
+		normalizedPath := filepath.ToSlash(filepath.Join(service.Dir, service.WorkingDir))
 		globalServicePathMap[normalizedPath] = service
 	}
 
@@ -485,10 +535,10 @@ func (ads *AutoDiscoveryService) checkExistingServicesForProfile(discoveredServi
 		(*discoveredServices)[i].Exists = false
 
 		// Check if a service with this path exists globally
-		normalizedDiscoveredPath := filepath.ToSlash(filepath.Clean((*discoveredServices)[i].Path))
+		normalizedDiscoveredPath := filepath.ToSlash(filepath.Join((*discoveredServices)[i].Path, (*discoveredServices)[i].Module))
 		var matchingGlobalService *models.Service
 		if globalService, exists := globalServicePathMap[normalizedDiscoveredPath]; exists {
-			matchingGlobalService = &globalService
+			matchingGlobalService = globalService
 		}
 
 		// If service exists globally, check if it's in this profile
@@ -511,11 +561,21 @@ func (ads *AutoDiscoveryService) CreateServiceFromDiscovered(discovered Discover
 	// Determine next order
 	nextOrder := ads.getNextServiceOrder()
 
+	// A port another service already has is left to the port pool
+	port := discovered.Port
+	if port > 0 && ads.manager.servicePortTaken(port) {
+		log.Printf("[INFO] Port %d of discovered service %s is taken; assigning one from the port pool", port, discovered.Name)
+		port = 0
+	}
+
 	service := &models.Service{
 		ID:           uuid.New().String(),
 		Name:         discovered.Name,
 		Dir:          discovered.Path,
-		Port:         discovered.Port,
+		Port:         port,
+		WorkingDir:   discovered.Module,
+		BuildCommand: discovered.BuildCommand,
+		StartCommand: discovered.StartCommand,
 		Description:  discovered.Description,
 		Order:        nextOrder,
 		IsEnabled:    true,
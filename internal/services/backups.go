@@ -0,0 +1,351 @@
+// Package services - Scheduled backups of the database and their retention
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	backupCheckInterval = time.Hour       // How often the schedule looks for a missing daily backup
+	backupInitialDelay  = 5 * time.Minute // First look after startup
+	maxBackupKeepDaily  = 365
+	maxBackupKeepWeekly = 104
+)
+
+var (
+	backupMutex sync.Mutex // One backup or pruning at a time
+
+	backupStateMutex  sync.Mutex
+	lastBackupError   string
+	lastBackupErrorAt *time.Time
+	nextBackupCheckAt *time.Time
+)
+
+// BackupRestoreRequest picks the backup to restore: by name, or the newest
+// one taken at or before a point in time
+type BackupRestoreRequest struct {
+	Name string     `json:"name"`
+	At   *time.Time `json:"at"`
+}
+
+// BackupRestoreResult reports the backup staged to replace the database
+type BackupRestoreResult struct {
+	Backup          models.DatabaseBackup `json:"backup"`
+	RestartRequired bool                  `json:"restartRequired"`
+	Message         string                `json:"message"`
+}
+
+// GetBackupSettings returns the automatic backup settings
+func (sm *Manager) GetBackupSettings() (*models.BackupSettings, error) {
+	return sm.db.GetBackupSettings()
+}
+
+// SetBackupSettings validates and saves the automatic backup settings, then
+// prunes the daily backups the new retention no longer keeps. Only admins may
+// change them.
+func (sm *Manager) SetBackupSettings(settings models.BackupSettings, claims *models.JWTClaims) (*models.BackupSettings, error) {
+	if claims.Role != "admin" {
+		return nil, fmt.Errorf("only admins can change backup settings")
+	}
+	if settings.KeepDaily < 1 || settings.KeepDaily > maxBackupKeepDaily {
+		return nil, fmt.Errorf("keepDaily must be between 1 and %d", maxBackupKeepDaily)
+	}
+	if settings.KeepWeekly < 0 || settings.KeepWeekly > maxBackupKeepWeekly {
+		return nil, fmt.Errorf("keepWeekly must be between 0 and %d", maxBackupKeepWeekly)
+	}
+
+	if err := sm.db.SaveBackupSettings(settings); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Backup settings changed by %s: enabled=%t, keep %d daily and %d weekly", claims.Username, settings.Enabled, settings.KeepDaily, settings.KeepWeekly)
+
+	backupMutex.Lock()
+	sm.pruneBackups(settings)
+	backupMutex.Unlock()
+	return sm.db.GetBackupSettings()
+}
+
+// ListBackups returns the backups of the database, newest first
+func (sm *Manager) ListBackups() ([]models.DatabaseBackup, error) {
+	return sm.db.ListBackups()
+}
+
+// CreateBackup backs up the database now. Manual backups are not pruned.
+func (sm *Manager) CreateBackup() (*models.DatabaseBackup, error) {
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	backup, err := sm.db.CreateBackup(models.BackupKindManual)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Backed up the database to %s (%d bytes)", backup.Name, backup.SizeBytes)
+	return backup, nil
+}
+
+// DeleteBackup removes a backup. Only admins may delete backups.
+func (sm *Manager) DeleteBackup(name string, claims *models.JWTClaims) error {
+	if claims.Role != "admin" {
+		return fmt.Errorf("only admins can delete backups")
+	}
+
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	if err := sm.db.DeleteBackup(name); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Backup %s deleted by %s", name, claims.Username)
+	return nil
+}
+
+// RestoreBackup stages a backup to replace the database when Vertex next
+// starts; the database it replaces is kept as a pre-restore backup. Only
+// admins may restore.
+func (sm *Manager) RestoreBackup(req BackupRestoreRequest, claims *models.JWTClaims) (*BackupRestoreResult, error) {
+	if claims.Role != "admin" {
+		return nil, fmt.Errorf("only admins can restore backups")
+	}
+	if (req.Name == "") == (req.At == nil) {
+		return nil, fmt.Errorf("give either the name of a backup or a point in time")
+	}
+
+	backups, err := sm.db.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	backup, err := selectBackup(backups, req)
+	if err != nil {
+		return nil, err
+	}
+
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+	if err := sm.db.StageRestore(backup.Name); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Backup %s staged for restore by %s; it replaces the database when Vertex restarts", backup.Name, claims.Username)
+	return &BackupRestoreResult{
+		Backup:          *backup,
+		RestartRequired: true,
+		Message:         fmt.Sprintf("Restart Vertex to restore the database as it was at %s", backup.CreatedAt.Local().Format(time.RFC1123)),
+	}, nil
+}
+
+// CancelRestore drops the backup staged to replace the database. Only
+// admins may cancel a restore.
+func (sm *Manager) CancelRestore(claims *models.JWTClaims) error {
+	if claims.Role != "admin" {
+		return fmt.Errorf("only admins can cancel a restore")
+	}
+	if err := sm.db.CancelRestore(); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Pending restore cancelled by %s", claims.Username)
+	return nil
+}
+
+// selectBackup finds the backup a restore asks for. A point in time picks the
+// newest backup taken at or before it.
+func selectBackup(backups []models.DatabaseBackup, req BackupRestoreRequest) (*models.DatabaseBackup, error) {
+	for i := range backups { // Newest first
+		backup := &backups[i]
+		if req.Name != "" && backup.Name == req.Name {
+			return backup, nil
+		}
+		if req.At != nil && !backup.CreatedAt.After(*req.At) {
+			return backup, nil
+		}
+	}
+	if req.Name != "" {
+		return nil, fmt.Errorf("backup %s not found", req.Name)
+	}
+	return nil, fmt.Errorf("backup taken at or before %s not found", req.At.Format(time.RFC3339))
+}
+
+// backupsToPrune returns the daily backups the retention does not keep. The
+// newest backup of each of the last keepDaily days and of each of the last
+// keepWeekly weeks is kept; manual and pre-restore backups always are.
+func backupsToPrune(backups []models.DatabaseBackup, keepDaily, keepWeekly int) []models.DatabaseBackup {
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	prune := []models.DatabaseBackup{}
+
+	for _, backup := range backups { // Newest first
+		if backup.Kind != models.BackupKindDaily {
+			continue
+		}
+		local := backup.CreatedAt.Local()
+		day := local.Format("2006-01-02")
+		year, number := local.ISOWeek()
+		week := fmt.Sprintf("%d-W%02d", year, number)
+
+		keep := false
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep = true
+		}
+		if !weeks[week] && len(weeks) < keepWeekly {
+			weeks[week] = true
+			keep = true
+		}
+		if !keep {
+			prune = append(prune, backup)
+		}
+	}
+	return prune
+}
+
+// pruneBackups deletes the daily backups past the retention. The caller
+// holds backupMutex.
+func (sm *Manager) pruneBackups(settings models.BackupSettings) {
+	backups, err := sm.db.ListBackups()
+	if err != nil {
+		log.Printf("[WARN] Failed to list backups for pruning: %v", err)
+		return
+	}
+	for _, backup := range backupsToPrune(backups, settings.KeepDaily, settings.KeepWeekly) {
+		if err := sm.db.DeleteBackup(backup.Name); err != nil {
+			log.Printf("[WARN] Failed to prune backup %s: %v", backup.Name, err)
+			continue
+		}
+		log.Printf("[INFO] Pruned backup %s", backup.Name)
+	}
+}
+
+// startBackupRoutine takes the daily backup when today has none yet and
+// prunes the old ones
+func (sm *Manager) startBackupRoutine(ctx context.Context) {
+	ticker := time.NewTicker(backupCheckInterval)
+	defer ticker.Stop()
+
+	initialDelay := time.NewTimer(backupInitialDelay)
+	defer initialDelay.Stop()
+	setNextBackupCheck(time.Now().Add(backupInitialDelay))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-initialDelay.C:
+		case <-ticker.C:
+		}
+		setNextBackupCheck(time.Now().Add(backupCheckInterval))
+		sm.runScheduledBackup(time.Now())
+	}
+}
+
+func setNextBackupCheck(at time.Time) {
+	backupStateMutex.Lock()
+	nextBackupCheckAt = &at
+	backupStateMutex.Unlock()
+}
+
+// runScheduledBackup takes the daily backup if today has none yet
+func (sm *Manager) runScheduledBackup(now time.Time) {
+	settings, err := sm.db.GetBackupSettings()
+	if err != nil {
+		log.Printf("[WARN] Failed to read backup settings: %v", err)
+		return
+	}
+	if !settings.Enabled {
+		return
+	}
+
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	backups, err := sm.db.ListBackups()
+	if err != nil {
+		recordBackupError(err, now)
+		return
+	}
+	if latest := latestDailyBackup(backups); latest != nil && sameLocalDay(latest.CreatedAt, now) {
+		return
+	}
+
+	backup, err := sm.db.CreateBackup(models.BackupKindDaily)
+	if err != nil {
+		recordBackupError(err, now)
+		return
+	}
+	log.Printf("[INFO] Took the daily backup %s (%d bytes)", backup.Name, backup.SizeBytes)
+
+	backupStateMutex.Lock()
+	lastBackupError = ""
+	lastBackupErrorAt = nil
+	backupStateMutex.Unlock()
+
+	sm.pruneBackups(*settings)
+}
+
+func recordBackupError(err error, now time.Time) {
+	log.Printf("[ERROR] Daily backup failed: %v", err)
+	backupStateMutex.Lock()
+	lastBackupError = err.Error()
+	lastBackupErrorAt = &now
+	backupStateMutex.Unlock()
+}
+
+// latestDailyBackup returns the newest scheduled backup, or nil
+func latestDailyBackup(backups []models.DatabaseBackup) *models.DatabaseBackup {
+	for i := range backups {
+		if backups[i].Kind == models.BackupKindDaily {
+			return &backups[i]
+		}
+	}
+	return nil
+}
+
+func sameLocalDay(a, b time.Time) bool {
+	return a.Local().Format("2006-01-02") == b.Local().Format("2006-01-02")
+}
+
+// backupsHealth reports the backups and flags a failed daily backup
+func (sm *Manager) backupsHealth(status *models.BackupStatus) models.SubsystemHealth {
+	if settings, err := sm.db.GetBackupSettings(); err == nil {
+		status.Enabled = settings.Enabled
+	}
+	status.PendingRestore = sm.db.PendingRestore()
+
+	backups, err := sm.db.ListBackups()
+	if err != nil {
+		return subsystemHealth("backups", err.Error())
+	}
+	status.Count = len(backups)
+	for _, backup := range backups {
+		status.TotalBytes += backup.SizeBytes
+	}
+	if len(backups) > 0 {
+		status.LastBackup = backups[0].Name
+		status.LastBackupAt = &backups[0].CreatedAt
+	}
+
+	backupStateMutex.Lock()
+	status.LastError = lastBackupError
+	status.LastErrorAt = lastBackupErrorAt
+	next := nextBackupCheckAt
+	backupStateMutex.Unlock()
+
+	if status.Enabled {
+		if latest := latestDailyBackup(backups); latest != nil && sameLocalDay(latest.CreatedAt, time.Now()) {
+			now := time.Now()
+			tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+			status.NextBackupAt = &tomorrow
+		} else {
+			status.NextBackupAt = next
+		}
+	}
+
+	if status.Enabled && status.LastError != "" {
+		return subsystemHealth("backups", "the daily backup failed: "+status.LastError)
+	}
+	return subsystemHealth("backups", "")
+}
@@ -0,0 +1,265 @@
+// Package services - Batched WebSocket broadcasting with service deltas
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Messages queued within this window are sent to clients together
+const broadcastBatchWindow = 100 * time.Millisecond
+
+// wsBroadcaster queues outgoing WebSocket messages and coalesces service
+// updates into deltas holding only the fields that changed since the last
+// broadcast. Logs are never part of service updates; they are sent as
+// log_entry messages.
+type wsBroadcaster struct {
+	mutex     sync.Mutex
+	queue     []WebSocketMessage
+	pending   map[string]map[string]json.RawMessage // service UUID -> changed fields queued for the next flush
+	lastSent  map[string]map[string]json.RawMessage // service UUID -> fields as last broadcast
+	scheduled bool
+
+	subscribers map[chan WebSocketMessage]bool // In-process listeners such as GraphQL subscriptions
+
+	// Held while a batch is written, so batches reach each client in order
+	flushMutex sync.Mutex
+}
+
+// LogEntryMessage is the payload of log_entry messages
+type LogEntryMessage struct {
+	ServiceUUID string          `json:"serviceUUID"`
+	LogEntry    models.LogEntry `json:"logEntry"`
+}
+
+// broadcastUpdate publishes a service to the read replica and queues the
+// fields that changed since its last broadcast
+func (sm *Manager) broadcastUpdate(service *models.Service) {
+	sm.publishService(service)
+
+	data, err := json.Marshal(service)
+	if err != nil {
+		log.Printf("[WARN] Failed to encode update for service %s: %v", service.Name, err)
+		return
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return
+	}
+	delete(fields, "logs")
+
+	b := &sm.broadcaster
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.lastSent == nil {
+		b.lastSent = make(map[string]map[string]json.RawMessage)
+		b.pending = make(map[string]map[string]json.RawMessage)
+	}
+
+	previous := b.lastSent[service.ID]
+	changes := make(map[string]json.RawMessage)
+	for name, value := range fields {
+		if old, exists := previous[name]; !exists || !bytes.Equal(old, value) {
+			changes[name] = value
+		}
+	}
+	// An omitempty field that was cleared is sent as null so clients drop it
+	for name := range previous {
+		if _, exists := fields[name]; !exists {
+			changes[name] = json.RawMessage("null")
+		}
+	}
+	b.lastSent[service.ID] = fields
+	if len(changes) == 0 {
+		return
+	}
+
+	delta, queued := b.pending[service.ID]
+	if !queued {
+		delta = map[string]json.RawMessage{"id": fields["id"]}
+		b.pending[service.ID] = delta
+		// The delta map is filled in until the flush, keeping its place in the queue
+		b.queue = append(b.queue, WebSocketMessage{Type: "service_update", Payload: delta})
+	}
+	for name, value := range changes {
+		delta[name] = value
+	}
+	sm.scheduleBroadcastFlush()
+}
+
+// forgetBroadcastState drops what was last broadcast for a deleted service
+func (sm *Manager) forgetBroadcastState(serviceUUID string) {
+	b := &sm.broadcaster
+	b.mutex.Lock()
+	delete(b.lastSent, serviceUUID)
+	b.mutex.Unlock()
+}
+
+func (sm *Manager) broadcastLogEntry(serviceUUID string, logEntry models.LogEntry) {
+	sm.mutex.RLock()
+	_, exists := sm.services[serviceUUID]
+	sm.mutex.RUnlock()
+	if !exists {
+		log.Printf("[WARN] Service UUID %s not found for log broadcast", serviceUUID)
+		return
+	}
+
+	sm.broadcastMessage(WebSocketMessage{
+		Type: "log_entry",
+		Payload: LogEntryMessage{
+			ServiceUUID: serviceUUID,
+			LogEntry:    logEntry,
+		},
+	})
+}
+
+// broadcastMessage queues an arbitrary message for all websocket clients
+func (sm *Manager) broadcastMessage(message WebSocketMessage) {
+	b := &sm.broadcaster
+	b.mutex.Lock()
+	b.queue = append(b.queue, message)
+	sm.scheduleBroadcastFlush()
+	b.mutex.Unlock()
+}
+
+// scheduleBroadcastFlush starts the batch window; the broadcaster mutex must be held
+func (sm *Manager) scheduleBroadcastFlush() {
+	if sm.broadcaster.scheduled {
+		return
+	}
+	sm.broadcaster.scheduled = true
+	time.AfterFunc(broadcastBatchWindow, sm.flushBroadcasts)
+}
+
+// wsDelivery is a batch encoded for one client
+type wsDelivery struct {
+	conn   *websocket.Conn
+	client *wsClient
+	data   []byte
+}
+
+// flushBroadcasts numbers the queued messages and sends each client those on
+// its topics, wrapped in a single "batch" message when there is more than one.
+// The writes happen after the clientsMutex is released, so a slow client only
+// delays the batches behind it.
+func (sm *Manager) flushBroadcasts() {
+	b := &sm.broadcaster
+	b.flushMutex.Lock()
+	defer b.flushMutex.Unlock()
+
+	// Held while numbering so messages are numbered in the order they are sent
+	sm.clientsMutex.Lock()
+	b.mutex.Lock()
+	queue := b.queue
+	b.queue = nil
+	b.pending = make(map[string]map[string]json.RawMessage)
+	b.scheduled = false
+	sequenced := make([]wsSequenced, 0, len(queue))
+	for i := range queue {
+		message, err := sm.sequenceBroadcast(&queue[i])
+		if err != nil {
+			log.Printf("[WARN] Failed to encode websocket message %s: %v", queue[i].Type, err)
+			continue
+		}
+		sequenced = append(sequenced, message)
+	}
+	// A slow subscriber misses messages rather than holding up the others
+	for subscriber := range b.subscribers {
+		for _, message := range queue {
+			select {
+			case subscriber <- message:
+			default:
+			}
+		}
+	}
+	b.mutex.Unlock()
+
+	if len(sequenced) == 0 {
+		sm.clientsMutex.Unlock()
+		return
+	}
+
+	// Clients without subscriptions get everything and share one encoding
+	var everything []byte
+	deliveries := make([]wsDelivery, 0, len(sm.clients))
+	for conn, client := range sm.clients {
+		var data []byte
+		if client.topics == nil {
+			if everything == nil {
+				everything = encodeWSBatch(sequenced)
+			}
+			data = everything
+		} else {
+			var wanted []wsSequenced
+			for _, message := range sequenced {
+				if client.wants(message.topic) {
+					wanted = append(wanted, message)
+				}
+			}
+			data = encodeWSBatch(wanted)
+		}
+		if data == nil {
+			continue
+		}
+		deliveries = append(deliveries, wsDelivery{conn: conn, client: client, data: data})
+	}
+	sm.clientsMutex.Unlock()
+
+	for _, delivery := range deliveries {
+		if err := delivery.client.write(delivery.conn, delivery.data); err != nil {
+			sm.RemoveWebSocketClient(delivery.conn)
+			delivery.conn.Close()
+		}
+	}
+}
+
+// encodeWSBatch encodes messages for one write, or returns nil when there
+// are none
+func encodeWSBatch(messages []wsSequenced) []byte {
+	switch len(messages) {
+	case 0:
+		return nil
+	case 1:
+		return messages[0].data
+	}
+	payload := make([]json.RawMessage, len(messages))
+	for i, message := range messages {
+		payload[i] = message.data
+	}
+	data, err := json.Marshal(WebSocketMessage{Type: "batch", Payload: payload})
+	if err != nil {
+		log.Printf("[WARN] Failed to encode websocket batch: %v", err)
+		return nil
+	}
+	return data
+}
+
+// SubscribeBroadcasts returns a channel receiving every message sent to
+// websocket clients, unbatched, and a function that ends the subscription
+func (sm *Manager) SubscribeBroadcasts() (<-chan WebSocketMessage, func()) {
+	ch := make(chan WebSocketMessage, 256)
+
+	b := &sm.broadcaster
+	b.mutex.Lock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[chan WebSocketMessage]bool)
+	}
+	b.subscribers[ch] = true
+	b.mutex.Unlock()
+
+	return ch, func() {
+		b.mutex.Lock()
+		if b.subscribers[ch] {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+		b.mutex.Unlock()
+	}
+}
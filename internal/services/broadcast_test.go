@@ -0,0 +1,114 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// newTestWebSocketClient connects a websocket client to the manager and
+// returns the server side connection the manager writes to, and the client side
+func newTestWebSocketClient(t *testing.T, sm *Manager) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+	serverConns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		serverConns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	conn := <-serverConns
+	t.Cleanup(func() { conn.Close() })
+	if sm.clients == nil {
+		sm.clients = make(map[*websocket.Conn]*wsClient)
+	}
+	sm.AddWebSocketClient(conn)
+	return conn, client
+}
+
+func TestUnregisterService_ForgetsBroadcastState(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api", Status: "running"}
+	sm.mutex.Lock()
+	sm.registerService(service)
+	sm.mutex.Unlock()
+
+	sm.broadcastUpdate(service)
+	sm.broadcaster.mutex.Lock()
+	_, tracked := sm.broadcaster.lastSent["svc-1"]
+	sm.broadcaster.mutex.Unlock()
+	if !tracked {
+		t.Fatal("Expected the broadcast service to be tracked")
+	}
+
+	sm.mutex.Lock()
+	sm.unregisterService("svc-1")
+	sm.mutex.Unlock()
+
+	sm.broadcaster.mutex.Lock()
+	_, tracked = sm.broadcaster.lastSent["svc-1"]
+	sm.broadcaster.mutex.Unlock()
+	if tracked {
+		t.Error("Expected the deleted service's last broadcast to be dropped")
+	}
+}
+
+func TestFlushBroadcasts_WritesOutsideClientsMutex(t *testing.T) {
+	sm := newTestManager(t)
+	conn, client := newTestWebSocketClient(t, sm)
+
+	// Hold the client's writer so the flush stalls on it, like a slow client
+	sm.clientsMutex.RLock()
+	stalled := sm.clients[conn]
+	sm.clientsMutex.RUnlock()
+	stalled.writeMutex.Lock()
+
+	sm.broadcaster.queue = []WebSocketMessage{{Type: "service_event", Payload: "started"}}
+	done := make(chan struct{})
+	go func() {
+		sm.flushBroadcasts()
+		close(done)
+	}()
+
+	// Other clients can connect while the write is pending
+	deadline := time.Now().Add(2 * time.Second)
+	for !sm.clientsMutex.TryLock() {
+		if time.Now().After(deadline) {
+			stalled.writeMutex.Unlock()
+			t.Fatal("Expected the clients mutex to be released while writing")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sm.clientsMutex.Unlock()
+
+	stalled.writeMutex.Unlock()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the flush to finish once the client accepts the write")
+	}
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"service_event"`) {
+		t.Errorf("Expected the queued message, got %s", data)
+	}
+}
@@ -0,0 +1,207 @@
+// Package services - Profile-level Maven and Gradle build settings
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// buildMirrorID names the mirror in the generated Maven settings and Gradle
+// init script; a repository credential with this server id authenticates it
+const buildMirrorID = "vertex-mirror"
+
+var mirrorOfRegex = regexp.MustCompile(`^[A-Za-z0-9*!,._:-]+$`)
+
+// GetProfileBuildSettings returns the build settings of a profile, empty when
+// none were saved
+func (sm *Manager) GetProfileBuildSettings(profileID string) (*models.ProfileBuildSettings, error) {
+	settings, err := sm.db.GetProfileBuildSettings(profileID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &models.ProfileBuildSettings{ProfileID: profileID}
+	}
+	return settings, nil
+}
+
+// SetProfileBuildSettings validates and saves the build settings of a profile
+func (sm *Manager) SetProfileBuildSettings(settings models.ProfileBuildSettings) error {
+	settings.MirrorURL = strings.TrimSpace(settings.MirrorURL)
+	settings.MirrorOf = strings.TrimSpace(settings.MirrorOf)
+	settings.MavenOpts = strings.TrimSpace(settings.MavenOpts)
+	settings.GradleOpts = strings.TrimSpace(settings.GradleOpts)
+
+	if settings.MirrorURL != "" {
+		if parsed, err := url.Parse(settings.MirrorURL); err != nil || parsed.Host == "" ||
+			(parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return fmt.Errorf("invalid mirror URL '%s'", settings.MirrorURL)
+		}
+		if settings.MirrorOf == "" {
+			settings.MirrorOf = "*"
+		}
+	} else {
+		settings.MirrorOf = ""
+	}
+	if settings.MirrorOf != "" && !mirrorOfRegex.MatchString(settings.MirrorOf) {
+		return fmt.Errorf("invalid mirrorOf '%s'", settings.MirrorOf)
+	}
+	if strings.ContainsAny(settings.MavenOpts, "\r\n\x00") {
+		return fmt.Errorf("mavenOpts must be a single line")
+	}
+	if strings.ContainsAny(settings.GradleOpts, "\r\n\x00") {
+		return fmt.Errorf("gradleOpts must be a single line")
+	}
+
+	return sm.db.SaveProfileBuildSettings(settings)
+}
+
+// profileBuildSettings loads the build settings of a service's profile, or
+// nil when there are none
+func (sm *Manager) profileBuildSettings(profileID, serviceName string) *models.ProfileBuildSettings {
+	if profileID == "" {
+		return nil
+	}
+	settings, err := sm.db.GetProfileBuildSettings(profileID)
+	if err != nil {
+		log.Printf("[WARN] Failed to load build settings for service %s: %v", serviceName, err)
+		return nil
+	}
+	return settings
+}
+
+// applyBuildSettings adds the profile's build settings to a Maven or Gradle
+// command: the offline flag, extra MAVEN_OPTS or GRADLE_OPTS ahead of the
+// service's own JavaOpts, and for Gradle an init script that sends every
+// repository to the mirror. The Maven mirror is part of the settings file
+// written by applyRepositoryCredentials.
+func (sm *Manager) applyBuildSettings(cmdString string, buildSystem BuildSystemType, profileID, serviceName string) string {
+	settings := sm.profileBuildSettings(profileID, serviceName)
+	if settings == nil {
+		return cmdString
+	}
+
+	switch buildSystem {
+	case BuildSystemMaven:
+		cmdString = prependBuildOpts(cmdString, "MAVEN_OPTS", settings.MavenOpts)
+		if settings.Offline {
+			cmdString += " -o"
+		}
+	case BuildSystemGradle:
+		cmdString = prependBuildOpts(cmdString, "GRADLE_OPTS", settings.GradleOpts)
+		if settings.MirrorURL != "" {
+			path := filepath.Join(database.GetDataDir(), "build-settings", profileID, "mirror.init.gradle")
+			if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+				log.Printf("[WARN] Failed to create build settings directory for service %s: %v", serviceName, err)
+			} else if err := os.WriteFile(path, []byte(renderGradleMirrorScript(settings.MirrorURL)), 0600); err != nil {
+				log.Printf("[WARN] Failed to write Gradle mirror script for service %s: %v", serviceName, err)
+			} else {
+				cmdString += " --init-script " + shellQuote(path)
+			}
+		}
+		if settings.Offline {
+			cmdString += " --offline"
+		}
+	default:
+		return cmdString
+	}
+
+	log.Printf("[INFO] Service %s: applying build settings of its profile (offline: %t, mirror: %s)", serviceName, settings.Offline, settings.MirrorURL)
+	return cmdString
+}
+
+// prependBuildOpts exports opts in the variable for the whole command. An
+// assignment of the variable inside the command, as GetStartCommand makes
+// for JavaOpts, keeps its value after them so the service's options win.
+func prependBuildOpts(cmdString, variable, opts string) string {
+	if opts == "" {
+		return cmdString
+	}
+	inline := variable + `="`
+	cmdString = strings.ReplaceAll(cmdString, inline, inline+"$"+variable+" ")
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`").Replace(opts)
+	return fmt.Sprintf(`export %s="${%s:+$%s }%s"; %s`, variable, variable, variable, escaped, cmdString)
+}
+
+// renderMavenMirror writes the <mirrors> section of a settings.xml
+func renderMavenMirror(settings *models.ProfileBuildSettings) string {
+	mirrorOf := settings.MirrorOf
+	if mirrorOf == "" {
+		mirrorOf = "*"
+	}
+	var mirror strings.Builder
+	mirror.WriteString("  <mirrors>\n")
+	mirror.WriteString("    <mirror>\n")
+	mirror.WriteString("      <id>" + buildMirrorID + "</id>\n")
+	mirror.WriteString("      <url>" + xmlEscape(settings.MirrorURL) + "</url>\n")
+	mirror.WriteString("      <mirrorOf>" + xmlEscape(mirrorOf) + "</mirrorOf>\n")
+	mirror.WriteString("    </mirror>\n")
+	mirror.WriteString("  </mirrors>\n")
+	return mirror.String()
+}
+
+// renderGradleMirrorScript writes an init script that replaces the remote
+// repositories of the settings, buildscripts and projects with the mirror,
+// after Gradle's enterprise repository example. Local repositories stay.
+// Credentials come from the ORG_GRADLE_PROJECT_ variables of a repository
+// credential named vertex-mirror.
+func renderGradleMirrorScript(mirrorURL string) string {
+	quoted := "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(mirrorURL) + "'"
+	prefix := "ORG_GRADLE_PROJECT_" + gradlePropertyPrefix(buildMirrorID)
+
+	var script strings.Builder
+	script.WriteString("// Generated by Vertex from profile build settings - do not edit.\n")
+	script.WriteString("def mirrorUrl = " + quoted + "\n")
+	script.WriteString("def mirrorUsername = System.getenv('" + prefix + "Username')\n")
+	script.WriteString("def mirrorPassword = System.getenv('" + prefix + "Password')\n")
+	script.WriteString(`
+def addMirror = { RepositoryHandler repositories ->
+    if (repositories.findByName('vertexMirror') != null) {
+        return
+    }
+    repositories.maven { repo ->
+        repo.name = 'vertexMirror'
+        repo.url = mirrorUrl
+        if (mirrorUsername) {
+            repo.credentials { it.username = mirrorUsername; it.password = mirrorPassword }
+        }
+    }
+}
+
+def useMirror = { RepositoryHandler repositories ->
+    repositories.all { ArtifactRepository repo ->
+        def local = repo instanceof FlatDirectoryArtifactRepository ||
+            ((repo instanceof MavenArtifactRepository || repo instanceof IvyArtifactRepository) && repo.url?.scheme == 'file')
+        if (repo.name == 'vertexMirror' || local) {
+            return
+        }
+        repositories.remove(repo)
+        addMirror(repositories)
+    }
+}
+
+settingsEvaluated { settings ->
+    if (settings.pluginManagement.repositories.isEmpty()) {
+        addMirror(settings.pluginManagement.repositories)
+    }
+    useMirror(settings.pluginManagement.repositories)
+    if (settings.hasProperty('dependencyResolutionManagement')) {
+        useMirror(settings.dependencyResolutionManagement.repositories)
+    }
+}
+
+allprojects { project ->
+    useMirror(project.buildscript.repositories)
+    useMirror(project.repositories)
+}
+`)
+	return script.String()
+}
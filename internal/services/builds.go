@@ -0,0 +1,285 @@
+// Package services - Separating the build output of a start from the runtime log
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	buildHistoryLimit  = 50  // Builds kept per service
+	buildOutputLines   = 500 // Lines of build output stored with a build
+	buildDrainTimeout  = 2 * time.Second
+	buildSummaryPrefix = "[vertex] "
+)
+
+var (
+	// The goal or task that runs the application after compiling it, or the
+	// line a jar mode start prints before java -jar
+	buildRunGoalRegex = regexp.MustCompile(`^\[INFO\] --- spring-boot[\w-]*:\S+:run\b|^> Task :(\S+:)?bootRun\b|^\[vertex\] Running `)
+	buildFailureRegex = regexp.MustCompile(`BUILD FAIL(URE|ED)`)
+	// Output of the application itself, for builds whose run goal was not recognized
+	applicationOutputRegex = regexp.MustCompile(`:: Spring Boot ::|^\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}:\d{2}`)
+	ansiEscapeRegex        = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// BuildOutput is streamed to websocket clients while a service builds
+type BuildOutput struct {
+	BuildID   int64  `json:"buildId"`
+	ServiceID string `json:"serviceId"`
+	Line      string `json:"line"`
+}
+
+// buildPhase collects the output of a service start until the application
+// runs. Maven and Gradle compile and run in one process, so the phase ends
+// at the line that starts the run goal.
+type buildPhase struct {
+	mutex       sync.Mutex
+	build       *models.ServiceBuild
+	serviceDir  string
+	buildSystem BuildSystemType
+	capturing   bool
+	failed      bool // Maven or Gradle reported a build failure
+	finished    bool
+	tail        []string
+	readers     sync.WaitGroup
+}
+
+// beginBuild records a new build for a service that was just started. It
+// returns nil, leaving all output in the runtime log, when the build system
+// is unknown or the build cannot be recorded.
+func (sm *Manager) beginBuild(service *models.Service, serviceDir string, buildSystem BuildSystemType) *buildPhase {
+	if buildSystem != BuildSystemMaven && buildSystem != BuildSystemGradle {
+		return nil
+	}
+
+	build := &models.ServiceBuild{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		Status:      models.BuildRunning,
+		BuildSystem: string(buildSystem),
+		StartedAt:   time.Now(),
+	}
+	id, err := sm.db.InsertServiceBuild(build)
+	if err != nil {
+		log.Printf("[WARN] Failed to record build of service %s: %v", service.Name, err)
+		return nil
+	}
+	build.ID = id
+
+	if err := sm.db.PruneServiceBuilds(service.ID, buildHistoryLimit); err != nil {
+		log.Printf("[WARN] Failed to prune builds of service %s: %v", service.Name, err)
+	}
+
+	phase := &buildPhase{build: build, serviceDir: serviceDir, buildSystem: buildSystem, capturing: true}
+	phase.readers.Add(2) // stdout and stderr
+
+	started := *build
+	sm.broadcastMessage(WebSocketMessage{Type: "build", Payload: started})
+	return phase
+}
+
+// readerDone marks one of the service's output pipes as drained
+func (phase *buildPhase) readerDone() {
+	if phase != nil {
+		phase.readers.Done()
+	}
+}
+
+// captureBuildLine stores a line of the build phase and reports whether it
+// belongs there; once the application runs, lines go to the runtime log
+func (sm *Manager) captureBuildLine(service *models.Service, phase *buildPhase, line string) bool {
+	if phase == nil {
+		return false
+	}
+
+	plain := ansiEscapeRegex.ReplaceAllString(line, "")
+
+	phase.mutex.Lock()
+	if !phase.capturing {
+		phase.mutex.Unlock()
+		return false
+	}
+	if !phase.failed && applicationOutputRegex.MatchString(plain) {
+		phase.capturing = false
+		phase.mutex.Unlock()
+		sm.finishBuild(service, phase, "")
+		return false
+	}
+
+	phase.tail = append(phase.tail, line)
+	if len(phase.tail) > buildOutputLines {
+		phase.tail = phase.tail[1:]
+	}
+	phase.build.Lines++
+	if buildFailureRegex.MatchString(plain) {
+		phase.failed = true
+	}
+	runStarted := !phase.failed && buildRunGoalRegex.MatchString(plain)
+	if runStarted {
+		phase.capturing = false
+	}
+	buildID := phase.build.ID
+	phase.mutex.Unlock()
+
+	sm.broadcastMessage(WebSocketMessage{
+		Type:    "build_output",
+		Payload: BuildOutput{BuildID: buildID, ServiceID: service.ID, Line: line},
+	})
+	if runStarted {
+		sm.finishBuild(service, phase, "")
+	}
+	return true
+}
+
+// endBuild closes the build of a service whose process exited. A build
+// still in progress never got to run the application, so it failed.
+func (sm *Manager) endBuild(service *models.Service, phase *buildPhase, exitErr error) {
+	if phase == nil {
+		return
+	}
+
+	// Let the readers store the last lines of a failed build first
+	drained := make(chan struct{})
+	go func() {
+		phase.readers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(buildDrainTimeout):
+	}
+
+	reason := "process exited before the application started"
+	if exitErr != nil {
+		reason = fmt.Sprintf("%s: %v", reason, exitErr)
+	}
+	sm.finishBuild(service, phase, reason)
+}
+
+// finishBuild stores the outcome of a build once and notes it in the
+// runtime log. An empty failure reason means the build succeeded, unless
+// the build tool reported a failure.
+func (sm *Manager) finishBuild(service *models.Service, phase *buildPhase, failureReason string) {
+	phase.mutex.Lock()
+	if phase.finished {
+		phase.mutex.Unlock()
+		return
+	}
+	phase.finished = true
+	phase.capturing = false
+
+	build := phase.build
+	finishedAt := time.Now()
+	build.FinishedAt = &finishedAt
+	build.DurationMs = finishedAt.Sub(build.StartedAt).Milliseconds()
+	build.Output = strings.Join(phase.tail, "\n")
+	switch {
+	case phase.failed:
+		build.Status = models.BuildFailed
+		build.Error = fmt.Sprintf("the %s build reported a failure", phase.buildSystem)
+	case failureReason != "":
+		build.Status = models.BuildFailed
+		build.Error = failureReason
+	default:
+		build.Status = models.BuildSucceeded
+	}
+	phase.mutex.Unlock()
+
+	if build.Status == models.BuildSucceeded {
+		build.ArtifactPath, build.ArtifactSize = buildArtifact(phase.serviceDir, phase.buildSystem, build.StartedAt)
+	}
+
+	if err := sm.db.UpdateServiceBuild(build); err != nil {
+		log.Printf("[ERROR] Failed to store build of service %s: %v", build.ServiceName, err)
+	}
+
+	duration := time.Duration(build.DurationMs) * time.Millisecond
+	log.Printf("[INFO] Build %d of service %s %s in %s", build.ID, build.ServiceName, build.Status, duration)
+
+	summary := *build
+	summary.Output = ""
+	sm.broadcastMessage(WebSocketMessage{Type: "build", Payload: summary})
+
+	// Leave a pointer to the build output in the otherwise clean runtime log
+	entry := models.LogEntry{
+		Timestamp: finishedAt.Format(time.RFC3339Nano),
+		Level:     "INFO",
+		Message: fmt.Sprintf("%sBuild #%d succeeded in %s (%d lines of build output, see /api/services/%s/builds/%d)",
+			buildSummaryPrefix, build.ID, duration.Round(100*time.Millisecond), build.Lines, build.ServiceID, build.ID),
+	}
+	if build.Status == models.BuildFailed {
+		entry.Level = "ERROR"
+		entry.Message = fmt.Sprintf("%sBuild #%d failed after %s: %s (see /api/services/%s/builds/%d)",
+			buildSummaryPrefix, build.ID, duration.Round(100*time.Millisecond), build.Error, build.ServiceID, build.ID)
+	}
+	sm.emitLogEntry(service, entry)
+}
+
+// buildArtifact returns the newest jar the build wrote, or else the compiled
+// classes directory, with its size in bytes
+func buildArtifact(serviceDir string, buildSystem BuildSystemType, since time.Time) (string, int64) {
+	libsDir, classesDir := "target", filepath.Join("target", "classes")
+	if buildSystem == BuildSystemGradle {
+		libsDir, classesDir = filepath.Join("build", "libs"), filepath.Join("build", "classes")
+	}
+
+	jars, _ := filepath.Glob(filepath.Join(serviceDir, libsDir, "*.jar"))
+	var newest os.FileInfo
+	newestPath := ""
+	for _, jar := range jars {
+		info, err := os.Stat(jar)
+		if err != nil || info.ModTime().Before(since) {
+			continue
+		}
+		if newest == nil || info.ModTime().After(newest.ModTime()) {
+			newest, newestPath = info, jar
+		}
+	}
+	if newest != nil {
+		rel, _ := filepath.Rel(serviceDir, newestPath)
+		return rel, newest.Size()
+	}
+
+	var size int64
+	err := filepath.WalkDir(filepath.Join(serviceDir, classesDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", 0
+	}
+	return classesDir, size
+}
+
+// GetServiceBuilds returns the recent builds of a service, newest first
+func (sm *Manager) GetServiceBuilds(serviceUUID string, limit int) ([]models.ServiceBuild, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	if limit <= 0 || limit > buildHistoryLimit {
+		limit = buildHistoryLimit
+	}
+	return sm.db.GetServiceBuilds(serviceUUID, limit)
+}
+
+// GetServiceBuild returns a build with its output
+func (sm *Manager) GetServiceBuild(serviceUUID string, buildID int64) (*models.ServiceBuild, error) {
+	return sm.db.GetServiceBuild(serviceUUID, buildID)
+}
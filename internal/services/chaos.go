@@ -0,0 +1,546 @@
+// Package services - Opt-in chaos testing of local services
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Kinds of chaos faults
+const (
+	ChaosFaultKill    = "kill"    // SIGKILL the service's process group
+	ChaosFaultLatency = "latency" // Proxy that delays requests to the service
+	ChaosFaultBlock   = "block"   // Freeze the service so its port stops answering
+)
+
+// What injected a chaos fault
+const (
+	ChaosTriggerManual   = "manual"
+	ChaosTriggerSchedule = "schedule"
+)
+
+const (
+	defaultChaosDuration = time.Minute
+	maxChaosDuration     = time.Hour
+	maxChaosDelay        = time.Minute
+	minChaosInterval     = 30 * time.Second
+	chaosDialTimeout     = 5 * time.Second
+)
+
+// ErrChaosDisabled is returned by every chaos operation unless Vertex was
+// started with VERTEX_CHAOS_ENABLED=true
+var ErrChaosDisabled = errors.New("chaos testing is disabled; start Vertex with VERTEX_CHAOS_ENABLED=true")
+
+// activeChaosFault is a latency proxy or block that is still in effect
+type activeChaosFault struct {
+	fault models.ChaosFault
+	stop  func() error // Undoes the fault
+	timer *time.Timer
+}
+
+// chaosScheduleState is the running kill schedule
+type chaosScheduleState struct {
+	schedule models.ChaosSchedule
+	cancel   context.CancelFunc
+}
+
+var (
+	activeChaosFaults = make(map[int64]*activeChaosFault)
+	chaosSchedule     *chaosScheduleState
+	chaosMutex        sync.Mutex
+)
+
+// chaosEnabled reports whether chaos testing was opted into with
+// VERTEX_CHAOS_ENABLED=true
+func chaosEnabled() bool {
+	value := strings.ToLower(os.Getenv("VERTEX_CHAOS_ENABLED"))
+	return value == "true" || value == "1" || value == "yes"
+}
+
+// GetChaosStatus returns whether chaos testing is enabled, the kill schedule
+// and the faults in effect
+func (sm *Manager) GetChaosStatus() models.ChaosStatus {
+	chaosMutex.Lock()
+	defer chaosMutex.Unlock()
+
+	status := models.ChaosStatus{Enabled: chaosEnabled(), ActiveFaults: []models.ChaosFault{}}
+	if chaosSchedule != nil {
+		schedule := chaosSchedule.schedule
+		status.Schedule = &schedule
+	}
+	for _, active := range activeChaosFaults {
+		status.ActiveFaults = append(status.ActiveFaults, active.fault)
+	}
+	slices.SortFunc(status.ActiveFaults, func(a, b models.ChaosFault) int { return int(a.ID - b.ID) })
+	return status
+}
+
+// GetChaosFaults returns the audit trail of injected faults, newest first
+func (sm *Manager) GetChaosFaults(limit int) ([]models.ChaosFault, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	return sm.db.GetChaosFaults(limit)
+}
+
+// recordChaosFault stores a fault in the audit trail and notes it in the
+// service's log
+func (sm *Manager) recordChaosFault(service *models.Service, fault *models.ChaosFault) error {
+	id, err := sm.db.InsertChaosFault(*fault)
+	if err != nil {
+		return err
+	}
+	fault.ID = id
+
+	message := fmt.Sprintf("Chaos: %s %s", fault.Type, fault.Detail)
+	if fault.Error != "" {
+		message += " failed: " + fault.Error
+	}
+	sm.logHookOutput(service, "WARN", message)
+	return nil
+}
+
+// KillChaosService kills a running service with SIGKILL, as if it crashed,
+// so its crash handling and its dependents' retries can be observed
+func (sm *Manager) KillChaosService(serviceUUID, profileID, trigger string) (*models.ChaosFault, error) {
+	if !chaosEnabled() {
+		return nil, ErrChaosDisabled
+	}
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	running := service.Status == "running" && service.Cmd != nil && service.Cmd.Process != nil
+	pid := 0
+	if running {
+		pid = service.Cmd.Process.Pid
+	}
+	service.Mutex.RUnlock()
+	if !running {
+		return nil, fmt.Errorf("service %s is not running", service.Name)
+	}
+
+	now := time.Now()
+	fault := &models.ChaosFault{
+		Type:        ChaosFaultKill,
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		ProfileID:   profileID,
+		Detail:      fmt.Sprintf("SIGKILL to process group of PID %d", pid),
+		Trigger:     trigger,
+		Status:      "ended",
+		StartedAt:   now,
+		EndedAt:     &now,
+	}
+
+	pgid, err := GetProcessGroup(pid)
+	if err == nil {
+		err = ForceKillProcessGroup(pgid)
+	}
+	if err != nil {
+		fault.Status = "failed"
+		fault.Error = err.Error()
+	}
+
+	if recordErr := sm.recordChaosFault(service, fault); recordErr != nil {
+		log.Printf("[WARN] %v", recordErr)
+	}
+	if err != nil {
+		return fault, fmt.Errorf("failed to kill %s: %w", service.Name, err)
+	}
+	log.Printf("[WARN] Chaos: killed service %s (PID %d, %s)", service.Name, pid, trigger)
+	return fault, nil
+}
+
+// KillRandomChaosService kills a random running service among the given ones
+func (sm *Manager) KillRandomChaosService(profileID string, serviceUUIDs []string, trigger string) (*models.ChaosFault, error) {
+	if !chaosEnabled() {
+		return nil, ErrChaosDisabled
+	}
+
+	var candidates []string
+	for _, serviceUUID := range serviceUUIDs {
+		service, exists := sm.GetServiceByUUID(serviceUUID)
+		if !exists {
+			continue
+		}
+		service.Mutex.RLock()
+		running := service.Status == "running" && service.Cmd != nil && service.ArchivedAt == nil
+		service.Mutex.RUnlock()
+		if running {
+			candidates = append(candidates, serviceUUID)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no running service in the profile to kill")
+	}
+	return sm.KillChaosService(candidates[rand.Intn(len(candidates))], profileID, trigger)
+}
+
+// chaosDuration turns a requested duration into one within bounds
+func chaosDuration(seconds int) (time.Duration, error) {
+	if seconds < 0 {
+		return 0, fmt.Errorf("durationSeconds cannot be negative")
+	}
+	if seconds == 0 {
+		return defaultChaosDuration, nil
+	}
+	duration := time.Duration(seconds) * time.Second
+	if duration > maxChaosDuration {
+		return 0, fmt.Errorf("durationSeconds cannot exceed %d", int(maxChaosDuration.Seconds()))
+	}
+	return duration, nil
+}
+
+// InjectChaosLatency starts a proxy on a local port that forwards to the
+// service and delays every chunk sent to it. Point a dependent service at the
+// proxy port to see how it copes with a slow dependency.
+func (sm *Manager) InjectChaosLatency(req models.ChaosFaultRequest, profileID string) (*models.ChaosFault, error) {
+	if !chaosEnabled() {
+		return nil, ErrChaosDisabled
+	}
+	service, exists := sm.GetServiceByUUID(req.ServiceID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", req.ServiceID)
+	}
+
+	service.Mutex.RLock()
+	servicePort := service.Port
+	service.Mutex.RUnlock()
+	if servicePort <= 0 {
+		return nil, fmt.Errorf("service %s has no port configured", service.Name)
+	}
+
+	delay := time.Duration(req.DelayMs) * time.Millisecond
+	jitter := time.Duration(req.JitterMs) * time.Millisecond
+	if delay <= 0 || delay > maxChaosDelay {
+		return nil, fmt.Errorf("delayMs must be between 1 and %d", maxChaosDelay.Milliseconds())
+	}
+	if jitter < 0 || jitter > maxChaosDelay {
+		return nil, fmt.Errorf("jitterMs must be between 0 and %d", maxChaosDelay.Milliseconds())
+	}
+	duration, err := chaosDuration(req.DurationSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if req.ListenPort < 0 || req.ListenPort > 65535 {
+		return nil, fmt.Errorf("invalid listen port %d", req.ListenPort)
+	}
+	if req.ListenPort == servicePort {
+		return nil, fmt.Errorf("listen port %d is the port of %s itself", req.ListenPort, service.Name)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", req.ListenPort))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on port %d: %w", req.ListenPort, err)
+	}
+	listenPort := listener.Addr().(*net.TCPAddr).Port
+	proxy := newLatencyProxy(listener, fmt.Sprintf("localhost:%d", servicePort), delay, jitter)
+
+	fault := &models.ChaosFault{
+		Type:        ChaosFaultLatency,
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		ProfileID:   profileID,
+		Detail:      fmt.Sprintf("%s (+%s jitter) on 127.0.0.1:%d -> localhost:%d for %s", delay, jitter, listenPort, servicePort, duration),
+		Trigger:     ChaosTriggerManual,
+		Status:      "active",
+		StartedAt:   time.Now(),
+	}
+	if err := sm.recordChaosFault(service, fault); err != nil {
+		proxy.Close()
+		return nil, err
+	}
+
+	go proxy.serve()
+	sm.trackChaosFault(*fault, duration, proxy.Close)
+	log.Printf("[WARN] Chaos: adding %s latency to %s on 127.0.0.1:%d", delay, service.Name, listenPort)
+	return fault, nil
+}
+
+// BlockChaosService freezes a running service for a while so connections to
+// its port hang, as when a dependency stops answering, then resumes it
+func (sm *Manager) BlockChaosService(req models.ChaosFaultRequest, profileID string) (*models.ChaosFault, error) {
+	if !chaosEnabled() {
+		return nil, ErrChaosDisabled
+	}
+	service, exists := sm.GetServiceByUUID(req.ServiceID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", req.ServiceID)
+	}
+	duration, err := chaosDuration(req.DurationSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sm.PauseService(req.ServiceID); err != nil {
+		return nil, err
+	}
+
+	service.Mutex.RLock()
+	servicePort := service.Port
+	service.Mutex.RUnlock()
+	fault := &models.ChaosFault{
+		Type:        ChaosFaultBlock,
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		ProfileID:   profileID,
+		Detail:      fmt.Sprintf("port %d frozen for %s", servicePort, duration),
+		Trigger:     ChaosTriggerManual,
+		Status:      "active",
+		StartedAt:   time.Now(),
+	}
+	if err := sm.recordChaosFault(service, fault); err != nil {
+		sm.ResumeService(req.ServiceID)
+		return nil, err
+	}
+
+	sm.trackChaosFault(*fault, duration, func() error {
+		service.Mutex.RLock()
+		paused := service.Status == StatusPaused
+		service.Mutex.RUnlock()
+		if !paused {
+			return nil // Resumed or stopped by someone else meanwhile
+		}
+		return sm.ResumeService(service.ID)
+	})
+	log.Printf("[WARN] Chaos: blocked %s on port %d for %s", service.Name, servicePort, duration)
+	return fault, nil
+}
+
+// trackChaosFault keeps an active fault until its duration elapses or it is
+// ended early
+func (sm *Manager) trackChaosFault(fault models.ChaosFault, duration time.Duration, stop func() error) {
+	chaosMutex.Lock()
+	defer chaosMutex.Unlock()
+
+	active := &activeChaosFault{fault: fault, stop: stop}
+	active.timer = time.AfterFunc(duration, func() {
+		sm.endChaosFault(fault.ID)
+	})
+	activeChaosFaults[fault.ID] = active
+}
+
+// EndChaosFault undoes an active latency or block fault before its time is up
+func (sm *Manager) EndChaosFault(faultID int64) error {
+	if !chaosEnabled() {
+		return ErrChaosDisabled
+	}
+	if !sm.endChaosFault(faultID) {
+		return fmt.Errorf("active chaos fault %d not found", faultID)
+	}
+	return nil
+}
+
+// endChaosFault undoes an active fault and closes its audit entry; it
+// reports false when the fault is not active
+func (sm *Manager) endChaosFault(faultID int64) bool {
+	chaosMutex.Lock()
+	active, exists := activeChaosFaults[faultID]
+	delete(activeChaosFaults, faultID)
+	chaosMutex.Unlock()
+	if !exists {
+		return false
+	}
+
+	active.timer.Stop()
+	status, message := "ended", ""
+	if err := active.stop(); err != nil {
+		status, message = "failed", err.Error()
+		log.Printf("[WARN] Chaos: failed to undo %s of %s: %v", active.fault.Type, active.fault.ServiceName, err)
+	} else {
+		log.Printf("[INFO] Chaos: ended %s of %s", active.fault.Type, active.fault.ServiceName)
+	}
+	if err := sm.db.EndChaosFault(faultID, status, message, time.Now()); err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+	return true
+}
+
+// SetChaosSchedule kills a random running service of a profile every
+// interval, replacing an earlier schedule
+func (sm *Manager) SetChaosSchedule(profileID string, intervalSeconds int) (*models.ChaosSchedule, error) {
+	if !chaosEnabled() {
+		return nil, ErrChaosDisabled
+	}
+	interval := time.Duration(intervalSeconds) * time.Second
+	if interval < minChaosInterval {
+		return nil, fmt.Errorf("intervalSeconds must be at least %d", int(minChaosInterval.Seconds()))
+	}
+	if profileID == "" {
+		return nil, fmt.Errorf("a profile is required for the kill schedule")
+	}
+
+	ctx, cancel := context.WithCancel(sm.ctx)
+	state := &chaosScheduleState{
+		schedule: models.ChaosSchedule{ProfileID: profileID, IntervalSeconds: intervalSeconds, NextRunAt: time.Now().Add(interval)},
+		cancel:   cancel,
+	}
+
+	chaosMutex.Lock()
+	if chaosSchedule != nil {
+		chaosSchedule.cancel()
+	}
+	chaosSchedule = state
+	chaosMutex.Unlock()
+
+	go sm.runChaosSchedule(ctx, state, interval)
+	log.Printf("[WARN] Chaos: killing a random service of profile %s every %s", profileID, interval)
+
+	schedule := state.schedule
+	return &schedule, nil
+}
+
+// StopChaosSchedule stops the kill schedule
+func (sm *Manager) StopChaosSchedule() error {
+	chaosMutex.Lock()
+	defer chaosMutex.Unlock()
+
+	if chaosSchedule == nil {
+		return fmt.Errorf("chaos schedule not found")
+	}
+	chaosSchedule.cancel()
+	chaosSchedule = nil
+	log.Printf("[INFO] Chaos: stopped the kill schedule")
+	return nil
+}
+
+// runChaosSchedule kills a random running service of the schedule's profile
+// at every tick until the schedule is replaced or stopped
+func (sm *Manager) runChaosSchedule(ctx context.Context, state *chaosScheduleState, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		chaosMutex.Lock()
+		state.schedule.NextRunAt = time.Now().Add(interval)
+		chaosMutex.Unlock()
+
+		serviceUUIDs, err := sm.profileServiceUUIDs(state.schedule.ProfileID)
+		if err != nil {
+			log.Printf("[WARN] Chaos: %v", err)
+			continue
+		}
+		if _, err := sm.KillRandomChaosService(state.schedule.ProfileID, serviceUUIDs, ChaosTriggerSchedule); err != nil {
+			log.Printf("[INFO] Chaos: scheduled kill skipped: %v", err)
+		}
+	}
+}
+
+// latencyProxy forwards TCP connections to a target, delaying every chunk
+// sent to it
+type latencyProxy struct {
+	listener net.Listener
+	target   string
+	delay    time.Duration
+	jitter   time.Duration
+
+	mutex  sync.Mutex
+	conns  map[net.Conn]bool
+	closed bool
+}
+
+func newLatencyProxy(listener net.Listener, target string, delay, jitter time.Duration) *latencyProxy {
+	return &latencyProxy{listener: listener, target: target, delay: delay, jitter: jitter, conns: make(map[net.Conn]bool)}
+}
+
+// serve accepts connections until the proxy is closed
+func (p *latencyProxy) serve() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.handle(client)
+	}
+}
+
+// track remembers a connection so Close can cut it; it refuses connections
+// once the proxy is closed
+func (p *latencyProxy) track(conn net.Conn) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		conn.Close()
+		return false
+	}
+	p.conns[conn] = true
+	return true
+}
+
+func (p *latencyProxy) handle(client net.Conn) {
+	if !p.track(client) {
+		return
+	}
+	upstream, err := net.DialTimeout("tcp", p.target, chaosDialTimeout)
+	if err != nil {
+		client.Close()
+		return
+	}
+	if !p.track(upstream) {
+		client.Close()
+		return
+	}
+	defer func() {
+		client.Close()
+		upstream.Close()
+		p.mutex.Lock()
+		delete(p.conns, client)
+		delete(p.conns, upstream)
+		p.mutex.Unlock()
+	}()
+
+	go func() {
+		io.Copy(client, upstream)
+		client.Close()
+	}()
+
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := client.Read(buffer)
+		if n > 0 {
+			wait := p.delay
+			if p.jitter > 0 {
+				wait += time.Duration(rand.Int63n(int64(p.jitter) + 1))
+			}
+			time.Sleep(wait)
+			if _, writeErr := upstream.Write(buffer[:n]); writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Close stops accepting connections and cuts the open ones
+func (p *latencyProxy) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	for conn := range p.conns {
+		conn.Close()
+	}
+	return p.listener.Close()
+}
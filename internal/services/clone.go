@@ -0,0 +1,495 @@
+// Package services - Service cloning and directory scaffolding
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Build output and tool directories that are not copied when scaffolding
+var scaffoldSkipDirs = map[string]bool{
+	".git":         true,
+	".gradle":      true,
+	".idea":        true,
+	".vscode":      true,
+	"build":        true,
+	"node_modules": true,
+	"out":          true,
+	"target":       true,
+}
+
+// Text files in which the old package and names are rewritten
+var scaffoldRewriteExts = map[string]bool{
+	".java":       true,
+	".kt":         true,
+	".groovy":     true,
+	".xml":        true,
+	".properties": true,
+	".yml":        true,
+	".yaml":       true,
+	".gradle":     true,
+	".kts":        true,
+	".json":       true,
+	".md":         true,
+}
+
+var (
+	javaPackageRegex = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;?`)
+	validPackage     = regexp.MustCompile(`^[a-zA-Z_][\w]*(\.[a-zA-Z_][\w]*)*$`)
+)
+
+// CloneServiceRequest describes the copy to create from an existing service
+type CloneServiceRequest struct {
+	Name     string `json:"name"`
+	Dir      string `json:"dir"`      // Relative to the projects directory; defaults to a sibling named after the clone
+	Port     int    `json:"port"`     // Defaults to the next free port after the source's
+	Scaffold bool   `json:"scaffold"` // Copy the source directory and rename artifactId/package
+	Package  string `json:"package"`  // New base package when scaffolding, e.g. com.acme.payments.v2
+}
+
+// CloneServiceResult reports the cloned service and what scaffolding changed
+type CloneServiceResult struct {
+	Service        *models.Service `json:"service"`
+	ScaffoldedDir  string          `json:"scaffoldedDir,omitempty"`
+	OldPackage     string          `json:"oldPackage,omitempty"`
+	RewrittenFiles int             `json:"rewrittenFiles"`
+	Warnings       []string        `json:"warnings,omitempty"`
+}
+
+// CloneService copies a service definition (settings, env vars, dependencies,
+// hooks, external dependencies and tags) under a new name. With Scaffold set,
+// the source directory is copied as well and its artifactId, application
+// name, port and base package are renamed for the clone.
+func (sm *Manager) CloneService(sourceUUID, projectsDir string, req CloneServiceRequest) (*CloneServiceResult, error) {
+	source, exists := sm.GetServiceByUUID(sourceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", sourceUUID)
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if _, taken := sm.GetServiceByName(req.Name); taken {
+		return nil, fmt.Errorf("service %s already exists", req.Name)
+	}
+	if req.Package != "" && !validPackage.MatchString(req.Package) {
+		return nil, fmt.Errorf("invalid package name '%s'", req.Package)
+	}
+
+	source.Mutex.RLock()
+	clone := &models.Service{
+		ID:             uuid.New().String(),
+		Name:           req.Name,
+		Dir:            req.Dir,
+		ExtraEnv:       source.ExtraEnv,
+		JavaOpts:       source.JavaOpts,
+		JavaOptsPreset: source.JavaOptsPreset,
+		HealthURL:      source.HealthURL,
+		Port:           req.Port,
+		Order:          source.Order + 1,
+		Description:    source.Description,
+		IsEnabled:      source.IsEnabled,
+		BuildSystem:    source.BuildSystem,
+		VerboseLogging: source.VerboseLogging,
+		IdleMinutes:    source.IdleMinutes,
+		HealthInterval: source.HealthInterval,
+		SkipDiscovery:  source.SkipDiscovery,
+		Owner:          source.Owner,
+		RunAsUser:      source.RunAsUser,
+		ExecutionMode:  source.ExecutionMode,
+		WorkingDir:     source.WorkingDir,
+		StartCommand:   source.StartCommand,
+		BuildCommand:   source.BuildCommand,
+		AutoMigrate:    source.AutoMigrate,
+		Interactive:    source.Interactive,
+		ReloadOnSIGHUP: source.ReloadOnSIGHUP,
+		InfraType:      source.InfraType,
+		InfraRuntime:   source.InfraRuntime,
+		EnvVars:        make(map[string]models.EnvVar, len(source.EnvVars)),
+		Tags:           make(map[string]string, len(source.Tags)),
+		Status:         "stopped",
+		HealthStatus:   "unknown",
+		Logs:           []models.LogEntry{},
+	}
+	for name, envVar := range source.EnvVars {
+		clone.EnvVars[name] = envVar
+	}
+	for key, value := range source.Tags {
+		clone.Tags[key] = value
+	}
+	sourceDir := source.Dir
+	sourcePort := source.Port
+	source.Mutex.RUnlock()
+
+	if req.Scaffold && clone.InfraType != "" {
+		return nil, fmt.Errorf("infrastructure services have no directory to scaffold")
+	}
+	if clone.Dir == "" && clone.InfraType == "" {
+		if !req.Scaffold {
+			return nil, fmt.Errorf("dir is required unless scaffolding a new directory")
+		}
+		clone.Dir = filepath.Join(filepath.Dir(sourceDir), req.Name)
+	}
+	if clone.Port == 0 {
+		clone.Port = sm.nextFreeServicePort(sourcePort + 1)
+	}
+	if clone.HealthURL != "" && sourcePort > 0 {
+		clone.HealthURL = strings.Replace(clone.HealthURL, ":"+strconv.Itoa(sourcePort), ":"+strconv.Itoa(clone.Port), 1)
+	}
+
+	result := &CloneServiceResult{Service: clone}
+
+	if req.Scaffold {
+		src := filepath.Join(projectsDir, sourceDir)
+		dst := filepath.Join(projectsDir, clone.Dir)
+		if err := scaffoldServiceDir(src, dst, sourcePort, clone.Port, req, result); err != nil {
+			return nil, err
+		}
+		result.ScaffoldedDir = dst
+	} else if info, err := os.Stat(filepath.Join(projectsDir, clone.Dir)); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("directory %s does not exist", filepath.Join(projectsDir, clone.Dir))
+	}
+
+	if err := sm.AddService(clone); err != nil {
+		if result.ScaffoldedDir != "" {
+			os.RemoveAll(result.ScaffoldedDir)
+		}
+		return nil, err
+	}
+
+	sm.copyServiceRelations(sourceUUID, clone, result)
+
+	log.Printf("[INFO] Cloned service %s into %s (UUID: %s)", source.Name, clone.Name, clone.ID)
+	return result, nil
+}
+
+// copyServiceRelations copies the records stored outside the services table.
+// Failures are reported as warnings since the clone itself already exists.
+func (sm *Manager) copyServiceRelations(sourceUUID string, clone *models.Service, result *CloneServiceResult) {
+	warn := func(what string, err error) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to copy %s: %v", what, err))
+	}
+
+	if len(clone.EnvVars) > 0 {
+		if err := sm.UpdateServiceEnvVars(clone.ID, clone.EnvVars); err != nil {
+			warn("environment variables", err)
+		}
+	}
+	if len(clone.Tags) > 0 {
+		if err := sm.UpdateServiceTags(clone.ID, clone.Tags); err != nil {
+			warn("tags", err)
+		}
+	}
+
+	if dependencies, err := sm.db.LoadServiceDependencies(sourceUUID); err != nil {
+		warn("dependencies", err)
+	} else if len(dependencies) > 0 {
+		// SaveServiceDependencies expects JSON-decoded values, where numbers are float64
+		converted := make([]any, 0, len(dependencies))
+		for _, dep := range dependencies {
+			for _, key := range []string{"timeoutSeconds", "retryIntervalSeconds"} {
+				if value, ok := dep[key].(int); ok {
+					dep[key] = float64(value)
+				}
+			}
+			converted = append(converted, dep)
+		}
+		if err := sm.db.SaveServiceDependencies(clone.ID, converted); err != nil {
+			warn("dependencies", err)
+		}
+	}
+
+	if hooks, err := sm.GetServiceHooks(sourceUUID); err != nil {
+		warn("hooks", err)
+	} else if len(hooks) > 0 {
+		if err := sm.UpdateServiceHooks(clone.ID, hooks); err != nil {
+			warn("hooks", err)
+		}
+	}
+
+	if externals, err := sm.db.GetExternalDependencies(sourceUUID); err != nil {
+		warn("external dependencies", err)
+	} else if len(externals) > 0 {
+		if err := sm.db.SaveExternalDependencies(clone.ID, externals); err != nil {
+			warn("external dependencies", err)
+		}
+	}
+}
+
+// nextFreeServicePort returns the first port from start not used by another service
+func (sm *Manager) nextFreeServicePort(start int) int {
+	used := make(map[int]bool)
+	for _, service := range sm.GetServices() {
+		used[service.Port] = true
+	}
+	port := start
+	for used[port] {
+		port++
+	}
+	return port
+}
+
+// scaffoldServiceDir copies the source project and renames it for the clone
+func scaffoldServiceDir(src, dst string, oldPort, newPort int, req CloneServiceRequest, result *CloneServiceResult) error {
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return fmt.Errorf("source directory %s does not exist", src)
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("target directory %s already exists", dst)
+	}
+
+	oldArtifactID, oldAppName := readProjectNames(src)
+	oldPackage := findBasePackage(src)
+
+	if err := copyProjectTree(src, dst); err != nil {
+		os.RemoveAll(dst)
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+
+	newPackage := req.Package
+	if newPackage != "" && oldPackage == "" {
+		result.Warnings = append(result.Warnings, "Could not detect the base package of the source; package was not renamed")
+		newPackage = ""
+	}
+	if newPackage != "" && newPackage != oldPackage {
+		if err := movePackageDirs(dst, oldPackage, newPackage); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to move package directories: %v", err))
+		}
+		result.OldPackage = oldPackage
+	} else {
+		newPackage = ""
+	}
+
+	rewrite := func(content string, ext, name string) string {
+		if newPackage != "" {
+			content = replaceQualifiedName(content, oldPackage, newPackage)
+		}
+		switch {
+		case name == "pom.xml" && oldArtifactID != "":
+			content = renamePomArtifact(content, oldArtifactID, req.Name)
+		case strings.HasPrefix(name, "settings.gradle") && oldArtifactID != "":
+			content = strings.Replace(content, "'"+oldArtifactID+"'", "'"+req.Name+"'", 1)
+			content = strings.Replace(content, `"`+oldArtifactID+`"`, `"`+req.Name+`"`, 1)
+		case ext == ".properties":
+			content = rewriteProperty(content, "spring.application.name", oldAppName, req.Name)
+			content = rewriteProperty(content, "server.port", strconv.Itoa(oldPort), strconv.Itoa(newPort))
+		case ext == ".yml" || ext == ".yaml":
+			content = rewriteYAMLValue(content, "name", oldAppName, req.Name)
+			content = rewriteYAMLValue(content, "port", strconv.Itoa(oldPort), strconv.Itoa(newPort))
+		}
+		return content
+	}
+
+	return filepath.WalkDir(dst, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !scaffoldRewriteExts[ext] {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		updated := rewrite(string(data), ext, d.Name())
+		if updated == string(data) {
+			return nil
+		}
+		result.RewrittenFiles++
+		return os.WriteFile(path, []byte(updated), 0644)
+	})
+}
+
+func copyProjectTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			if rel != "." && scaffoldSkipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// readProjectNames returns the artifactId (or Gradle project name) and the
+// spring.application.name of a project, when they can be found
+func readProjectNames(projectDir string) (string, string) {
+	var artifactID string
+	if data, err := os.ReadFile(filepath.Join(projectDir, "pom.xml")); err == nil {
+		var pom MavenPOM
+		if xml.Unmarshal(data, &pom) == nil {
+			artifactID = pom.ArtifactID
+		}
+	}
+	for _, name := range []string{"settings.gradle", "settings.gradle.kts"} {
+		if artifactID != "" {
+			break
+		}
+		if data, err := os.ReadFile(filepath.Join(projectDir, name)); err == nil {
+			artifactID = extractGradleProjectName(string(data))
+		}
+	}
+
+	appName := artifactID
+	for _, name := range []string{"application.properties", "bootstrap.properties"} {
+		data, err := os.ReadFile(filepath.Join(projectDir, "src", "main", "resources", name))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+			if found && strings.TrimSpace(key) == "spring.application.name" {
+				appName = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	return artifactID, appName
+}
+
+// findBasePackage returns the package of the @SpringBootApplication class
+func findBasePackage(projectDir string) string {
+	var basePackage string
+	for _, root := range []string{"src/main/java", "src/main/kotlin"} {
+		filepath.WalkDir(filepath.Join(projectDir, root), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || basePackage != "" {
+				return filepath.SkipDir
+			}
+			if d.IsDir() || (!strings.HasSuffix(path, ".java") && !strings.HasSuffix(path, ".kt")) {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || !strings.Contains(string(data), "@SpringBootApplication") {
+				return nil
+			}
+			if match := javaPackageRegex.FindStringSubmatch(string(data)); match != nil {
+				basePackage = match[1]
+			}
+			return nil
+		})
+		if basePackage != "" {
+			break
+		}
+	}
+	return basePackage
+}
+
+// movePackageDirs moves the sources of oldPackage to the directory of newPackage
+// in every main and test source root
+func movePackageDirs(projectDir, oldPackage, newPackage string) error {
+	oldPath := filepath.FromSlash(strings.ReplaceAll(oldPackage, ".", "/"))
+	newPath := filepath.FromSlash(strings.ReplaceAll(newPackage, ".", "/"))
+
+	for _, root := range []string{"src/main/java", "src/main/kotlin", "src/test/java", "src/test/kotlin"} {
+		from := filepath.Join(projectDir, root, oldPath)
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		to := filepath.Join(projectDir, root, newPath)
+		if strings.HasPrefix(to+string(filepath.Separator), from+string(filepath.Separator)) {
+			// The new package is nested in the old one; move via a temporary directory
+			tmp := from + ".vertex-move"
+			if err := os.Rename(from, tmp); err != nil {
+				return err
+			}
+			from = tmp
+		}
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+		removeEmptyParents(filepath.Dir(filepath.Join(projectDir, root, oldPath)), filepath.Join(projectDir, root))
+	}
+	return nil
+}
+
+func removeEmptyParents(dir, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// replaceQualifiedName replaces a package name where it appears as a whole
+// qualified name, leaving longer names that merely start with it untouched
+func replaceQualifiedName(content, oldName, newName string) string {
+	pattern := regexp.MustCompile(`(^|[^\w.])` + regexp.QuoteMeta(oldName) + `(\.|[^\w.]|$)`)
+	return pattern.ReplaceAllString(content, "${1}"+newName+"${2}")
+}
+
+// renamePomArtifact renames the project's own artifactId and name, skipping the parent block
+func renamePomArtifact(content, oldArtifactID, newArtifactID string) string {
+	offset := 0
+	if idx := strings.Index(content, "</parent>"); idx >= 0 {
+		offset = idx
+	}
+	head, tail := content[:offset], content[offset:]
+	tail = strings.Replace(tail, "<artifactId>"+oldArtifactID+"</artifactId>", "<artifactId>"+newArtifactID+"</artifactId>", 1)
+	tail = strings.Replace(tail, "<name>"+oldArtifactID+"</name>", "<name>"+newArtifactID+"</name>", 1)
+	return head + tail
+}
+
+func rewriteProperty(content, key, oldValue, newValue string) string {
+	if oldValue == "" {
+		return content
+	}
+	pattern := regexp.MustCompile(`(?m)^(\s*` + regexp.QuoteMeta(key) + `\s*[=:]\s*)` + regexp.QuoteMeta(oldValue) + `\s*$`)
+	return pattern.ReplaceAllString(content, "${1}"+newValue)
+}
+
+func rewriteYAMLValue(content, key, oldValue, newValue string) string {
+	if oldValue == "" {
+		return content
+	}
+	pattern := regexp.MustCompile(`(?m)^(\s*` + regexp.QuoteMeta(key) + `:\s*["']?)` + regexp.QuoteMeta(oldValue) + `(["']?\s*)$`)
+	return pattern.ReplaceAllString(content, "${1}"+newValue+"${2}")
+}
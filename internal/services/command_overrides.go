@@ -0,0 +1,93 @@
+// Package services - Per-service working directory and command overrides
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// validateCommandOverrides trims the command overrides of a service and
+// checks that its working directory stays inside the service directory
+func validateCommandOverrides(workingDir, startCommand, buildCommand *string) error {
+	*startCommand = strings.TrimSpace(*startCommand)
+	*buildCommand = strings.TrimSpace(*buildCommand)
+
+	*workingDir = strings.TrimSpace(*workingDir)
+	if *workingDir == "" {
+		return nil
+	}
+	if filepath.IsAbs(*workingDir) {
+		return fmt.Errorf("invalid working directory '%s': use a path relative to the service directory", *workingDir)
+	}
+	dir := filepath.Clean(filepath.FromSlash(*workingDir))
+	if dir == ".." || strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid working directory '%s': it must be inside the service directory", *workingDir)
+	}
+	if dir == "." {
+		dir = ""
+	}
+	*workingDir = filepath.ToSlash(dir)
+	return nil
+}
+
+// serviceWorkingDir is the directory a service is built, run and tested
+// in: its directory, or the subdirectory set as its working directory. The
+// caller holds the service's lock.
+func serviceWorkingDir(projectsDir string, service *models.Service) string {
+	return filepath.Join(projectsDir, service.Dir, filepath.FromSlash(service.WorkingDir))
+}
+
+// commandOverrides holds a service's replacements for the commands its build
+// system would run, read once per start
+type commandOverrides struct {
+	ServiceDir string // The service directory, above the working directory
+	WorkingDir string // Relative to ServiceDir, "" for the service directory itself
+	Start      string
+	Build      string
+}
+
+// serviceCommandOverrides reads the command overrides of a service. The
+// caller holds the service's lock.
+func serviceCommandOverrides(projectsDir string, service *models.Service) commandOverrides {
+	return commandOverrides{
+		ServiceDir: filepath.Join(projectsDir, service.Dir),
+		WorkingDir: service.WorkingDir,
+		Start:      service.StartCommand,
+		Build:      service.BuildCommand,
+	}
+}
+
+// replacesBuildTool reports whether the overrides take over every call to the
+// build tool's wrapper in the given execution mode, so Vertex should neither
+// check nor regenerate it
+func (o commandOverrides) replacesBuildTool(executionMode string) bool {
+	if executionMode == ExecutionModeJar {
+		return o.Build != ""
+	}
+	return o.Start != ""
+}
+
+// expand fills in the placeholders of a command template:
+//
+//	{javaOpts}    the service's JVM options, unquoted
+//	{serviceDir}  the service directory, quoted
+//	{workingDir}  the working directory, quoted
+//	{module}      the working directory relative to the service directory, "." when unset
+//	{port}        the service's port
+func (o commandOverrides) expand(template, javaOpts string, port int) string {
+	module := o.WorkingDir
+	if module == "" {
+		module = "."
+	}
+	return strings.NewReplacer(
+		"{javaOpts}", javaOpts,
+		"{serviceDir}", shellQuote(o.ServiceDir),
+		"{workingDir}", shellQuote(filepath.Join(o.ServiceDir, filepath.FromSlash(o.WorkingDir))),
+		"{module}", shellQuote(module),
+		"{port}", strconv.Itoa(port),
+	).Replace(template)
+}
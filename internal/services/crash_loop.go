@@ -0,0 +1,128 @@
+// Package services - Crash loop detection and quarantine
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// StatusCrashLooping marks a service quarantined after repeated quick exits
+	StatusCrashLooping = "crash-looping"
+
+	crashLoopWindow    = 30 * time.Second // an exit within this long after start counts as a crash
+	crashLoopThreshold = 3                // consecutive crashes before quarantine
+	crashLoopLogLines  = 50
+)
+
+// CrashLoopAlert describes why a service was quarantined
+type CrashLoopAlert struct {
+	ServiceUUID   string            `json:"serviceUUID"`
+	ServiceName   string            `json:"serviceName"`
+	Crashes       int               `json:"crashes"`
+	WindowSeconds int               `json:"windowSeconds"`
+	LastError     string            `json:"lastError"`
+	DetectedAt    time.Time         `json:"detectedAt"`
+	LastLogs      []models.LogEntry `json:"lastLogs"`
+}
+
+type crashLoopState struct {
+	consecutive int
+	alert       *CrashLoopAlert
+}
+
+var (
+	crashLoopStates      = make(map[string]*crashLoopState)
+	crashLoopStatesMutex sync.Mutex
+)
+
+// recordServiceExit tracks unexpected exits and quarantines the service once it
+// has crashed shortly after start crashLoopThreshold times in a row. Must be
+// called with the service mutex held, before its status is changed.
+func (sm *Manager) recordServiceExit(service *models.Service, exitErr error) bool {
+	crashLoopStatesMutex.Lock()
+	defer crashLoopStatesMutex.Unlock()
+
+	state, exists := crashLoopStates[service.ID]
+	if !exists {
+		state = &crashLoopState{}
+		crashLoopStates[service.ID] = state
+	}
+
+	// Manual stops and idle suspends already changed the status; only count
+	// processes that died on their own right after starting
+	unexpected := service.Status == "running"
+	if !unexpected || time.Since(service.LastStarted) > crashLoopWindow {
+		state.consecutive = 0
+		return false
+	}
+
+	state.consecutive++
+	log.Printf("[WARN] Service %s exited %s after start (%d/%d quick exits)",
+		service.Name, time.Since(service.LastStarted).Round(time.Second), state.consecutive, crashLoopThreshold)
+
+	if state.consecutive < crashLoopThreshold {
+		return false
+	}
+
+	lastLogs := service.Logs
+	if len(lastLogs) > crashLoopLogLines {
+		lastLogs = lastLogs[len(lastLogs)-crashLoopLogLines:]
+	}
+
+	alert := &CrashLoopAlert{
+		ServiceUUID:   service.ID,
+		ServiceName:   service.Name,
+		Crashes:       state.consecutive,
+		WindowSeconds: int(crashLoopWindow / time.Second),
+		DetectedAt:    time.Now(),
+		LastLogs:      append([]models.LogEntry{}, lastLogs...),
+	}
+	if exitErr != nil {
+		alert.LastError = exitErr.Error()
+	}
+	state.alert = alert
+
+	log.Printf("[ERROR] Service %s is crash-looping; it will not be started again until reset", service.Name)
+	sm.broadcastMessage(WebSocketMessage{Type: "crash_loop_alert", Payload: alert})
+	return true
+}
+
+// GetCrashLoopAlert returns the alert of a quarantined service, or nil
+func (sm *Manager) GetCrashLoopAlert(serviceUUID string) *CrashLoopAlert {
+	crashLoopStatesMutex.Lock()
+	defer crashLoopStatesMutex.Unlock()
+
+	if state, exists := crashLoopStates[serviceUUID]; exists {
+		return state.alert
+	}
+	return nil
+}
+
+// ResetCrashLoop lifts the quarantine of a crash-looping service so it can be started again
+func (sm *Manager) ResetCrashLoop(serviceUUID string) error {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	crashLoopStatesMutex.Lock()
+	delete(crashLoopStates, serviceUUID)
+	crashLoopStatesMutex.Unlock()
+
+	service.Mutex.Lock()
+	defer service.Mutex.Unlock()
+
+	if service.Status == StatusCrashLooping {
+		service.Status = "stopped"
+		sm.updateServiceInDB(service)
+		sm.broadcastUpdate(service)
+	}
+
+	log.Printf("[INFO] Crash loop state reset for service %s", service.Name)
+	return nil
+}
@@ -46,16 +46,23 @@ func (sm *Manager) loadServices(config models.Config) error {
 		// Try to load existing service from database
 		var dbService models.Service
 		row := sm.db.QueryRow(`
-			SELECT id, name, dir, extra_env, java_opts, status, health_status, health_url, port, pid, service_order, last_started, description, is_enabled, build_system, verbose_logging
+			SELECT id, name, dir, extra_env, java_opts, status, health_status, health_url, port, pid, service_order, last_started, description, is_enabled, build_system, verbose_logging, idle_timeout_minutes, health_interval_seconds, java_opts_preset, skip_discovery,
+				COALESCE(owner_team, ''), COALESCE(owner_slack_channel, ''), COALESCE(owner_email, ''), COALESCE(run_as_user, ''), COALESCE(execution_mode, ''), COALESCE(working_dir, ''), COALESCE(start_command, ''), COALESCE(build_command, ''), COALESCE(auto_migrate, 0), COALESCE(infra_type, ''), COALESCE(infra_runtime, ''), COALESCE(interactive, 0), COALESCE(reload_on_sighup, 0), archived_at
 			FROM services WHERE id = ?`, service.ID)
 
 		var description sql.NullString
 		var isEnabled sql.NullBool
 		var buildSystem sql.NullString
 		var verboseLogging sql.NullBool
+		var idleTimeout sql.NullInt64
+		var healthInterval sql.NullInt64
+		var javaOptsPreset sql.NullString
+		var skipDiscovery sql.NullBool
+		var archivedAt sql.NullTime
 		err := row.Scan(&dbService.ID, &dbService.Name, &dbService.Dir, &dbService.ExtraEnv, &dbService.JavaOpts,
 			&dbService.Status, &dbService.HealthStatus, &dbService.HealthURL, &dbService.Port,
-			&dbService.PID, &dbService.Order, &dbService.LastStarted, &description, &isEnabled, &buildSystem, &verboseLogging)
+			&dbService.PID, &dbService.Order, &dbService.LastStarted, &description, &isEnabled, &buildSystem, &verboseLogging, &idleTimeout, &healthInterval, &javaOptsPreset, &skipDiscovery,
+			&dbService.Owner.Team, &dbService.Owner.SlackChannel, &dbService.Owner.Email, &dbService.RunAsUser, &dbService.ExecutionMode, &dbService.WorkingDir, &dbService.StartCommand, &dbService.BuildCommand, &dbService.AutoMigrate, &dbService.InfraType, &dbService.InfraRuntime, &dbService.Interactive, &dbService.ReloadOnSIGHUP, &archivedAt)
 
 		if err == sql.ErrNoRows {
 			// Service doesn't exist in DB, insert it
@@ -113,11 +120,26 @@ func (sm *Manager) loadServices(config models.Config) error {
 			} else {
 				dbService.VerboseLogging = false
 			}
+			if idleTimeout.Valid {
+				dbService.IdleMinutes = int(idleTimeout.Int64)
+			}
+			if healthInterval.Valid {
+				dbService.HealthInterval = int(healthInterval.Int64)
+			}
+			if javaOptsPreset.Valid {
+				dbService.JavaOptsPreset = javaOptsPreset.String
+			}
+			if skipDiscovery.Valid {
+				dbService.SkipDiscovery = skipDiscovery.Bool
+			}
+			if archivedAt.Valid {
+				dbService.ArchivedAt = &archivedAt.Time
+			}
 
 			// Load environment variables for this service
 			dbService.EnvVars = make(map[string]models.EnvVar)
 			envRows, err := sm.db.Query(`
-				SELECT var_name, var_value, description, is_required 
+				SELECT var_name, var_value, description, is_required, COALESCE(value_type, ''), COALESCE(value_pattern, '')
 				FROM service_env_vars 
 				WHERE service_id = ?`, dbService.ID)
 			if err == nil {
@@ -125,7 +147,7 @@ func (sm *Manager) loadServices(config models.Config) error {
 				for envRows.Next() {
 					var envVar models.EnvVar
 					var envDesc sql.NullString
-					err := envRows.Scan(&envVar.Name, &envVar.Value, &envDesc, &envVar.IsRequired)
+					err := envRows.Scan(&envVar.Name, &envVar.Value, &envDesc, &envVar.IsRequired, &envVar.Type, &envVar.Pattern)
 					if err == nil {
 						if envDesc.Valid {
 							envVar.Description = envDesc.String
@@ -318,35 +340,59 @@ func (sm *Manager) loadGlobalConfigFromDB() error {
 }
 
 func (sm *Manager) loadDynamicServices() error {
+	dbServices, err := sm.queryServicesFromDB()
+	if err != nil {
+		return err
+	}
+
+	for _, dbService := range dbServices {
+		// Skip if service is already loaded from config
+		if _, exists := sm.services[dbService.ID]; exists {
+			continue
+		}
+
+		// Add to services map
+		sm.services[dbService.ID] = dbService
+		log.Printf("[INFO] Loaded dynamic service from database: UUID %s (Name: %s)", dbService.ID, dbService.Name)
+	}
+
+	return nil
+}
+
+// queryServicesFromDB reads every service with its environment variables
+func (sm *Manager) queryServicesFromDB() ([]*models.Service, error) {
 	// Query all services from database
 	rows, err := sm.db.Query(`
-		SELECT id, name, dir, extra_env, java_opts, status, health_status, health_url, port, pid, service_order, last_started, description, is_enabled, build_system, verbose_logging
+		SELECT id, name, dir, extra_env, java_opts, status, health_status, health_url, port, pid, service_order, last_started, description, is_enabled, build_system, verbose_logging, idle_timeout_minutes, health_interval_seconds, java_opts_preset, skip_discovery,
+			COALESCE(owner_team, ''), COALESCE(owner_slack_channel, ''), COALESCE(owner_email, ''), COALESCE(run_as_user, ''), COALESCE(execution_mode, ''), COALESCE(working_dir, ''), COALESCE(start_command, ''), COALESCE(build_command, ''), COALESCE(auto_migrate, 0), COALESCE(infra_type, ''), COALESCE(infra_runtime, ''), COALESCE(interactive, 0), COALESCE(reload_on_sighup, 0), archived_at
 		FROM services`)
 	if err != nil {
-		return fmt.Errorf("failed to query dynamic services: %w", err)
+		return nil, fmt.Errorf("failed to query dynamic services: %w", err)
 	}
 	defer rows.Close()
 
+	var dbServices []*models.Service
 	for rows.Next() {
 		var dbService models.Service
 		var description sql.NullString
 		var isEnabled sql.NullBool
 		var buildSystem sql.NullString
 		var verboseLogging sql.NullBool
+		var idleTimeout sql.NullInt64
+		var healthInterval sql.NullInt64
+		var javaOptsPreset sql.NullString
+		var skipDiscovery sql.NullBool
+		var archivedAt sql.NullTime
 
 		err := rows.Scan(&dbService.ID, &dbService.Name, &dbService.Dir, &dbService.ExtraEnv, &dbService.JavaOpts,
 			&dbService.Status, &dbService.HealthStatus, &dbService.HealthURL, &dbService.Port,
-			&dbService.PID, &dbService.Order, &dbService.LastStarted, &description, &isEnabled, &buildSystem, &verboseLogging)
+			&dbService.PID, &dbService.Order, &dbService.LastStarted, &description, &isEnabled, &buildSystem, &verboseLogging, &idleTimeout, &healthInterval, &javaOptsPreset, &skipDiscovery,
+			&dbService.Owner.Team, &dbService.Owner.SlackChannel, &dbService.Owner.Email, &dbService.RunAsUser, &dbService.ExecutionMode, &dbService.WorkingDir, &dbService.StartCommand, &dbService.BuildCommand, &dbService.AutoMigrate, &dbService.InfraType, &dbService.InfraRuntime, &dbService.Interactive, &dbService.ReloadOnSIGHUP, &archivedAt)
 		if err != nil {
 			log.Printf("[WARN] Failed to scan dynamic service: %v", err)
 			continue
 		}
 
-		// Skip if service is already loaded from config
-		if _, exists := sm.services[dbService.ID]; exists {
-			continue
-		}
-
 		// Handle nullable fields
 		if description.Valid {
 			dbService.Description = description.String
@@ -366,18 +412,33 @@ func (sm *Manager) loadDynamicServices() error {
 		} else {
 			dbService.VerboseLogging = false
 		}
+		if idleTimeout.Valid {
+			dbService.IdleMinutes = int(idleTimeout.Int64)
+		}
+		if healthInterval.Valid {
+			dbService.HealthInterval = int(healthInterval.Int64)
+		}
+		if javaOptsPreset.Valid {
+			dbService.JavaOptsPreset = javaOptsPreset.String
+		}
+		if skipDiscovery.Valid {
+			dbService.SkipDiscovery = skipDiscovery.Bool
+		}
+		if archivedAt.Valid {
+			dbService.ArchivedAt = &archivedAt.Time
+		}
 
 		// Initialize required fields
 		dbService.EnvVars = make(map[string]models.EnvVar)
 		dbService.Logs = []models.LogEntry{}
 
 		// Load environment variables for this service
-		envRows, err := sm.db.Query("SELECT var_name, var_value, description, is_required FROM service_env_vars WHERE service_id = ?", dbService.ID)
+		envRows, err := sm.db.Query("SELECT var_name, var_value, description, is_required, COALESCE(value_type, ''), COALESCE(value_pattern, '') FROM service_env_vars WHERE service_id = ?", dbService.ID)
 		if err == nil {
 			for envRows.Next() {
 				var envVar models.EnvVar
 				var envDesc sql.NullString
-				err := envRows.Scan(&envVar.Name, &envVar.Value, &envDesc, &envVar.IsRequired)
+				err := envRows.Scan(&envVar.Name, &envVar.Value, &envDesc, &envVar.IsRequired, &envVar.Type, &envVar.Pattern)
 				if err == nil {
 					if envDesc.Valid {
 						envVar.Description = envDesc.String
@@ -388,21 +449,20 @@ func (sm *Manager) loadDynamicServices() error {
 			envRows.Close()
 		}
 
-		// Add to services map
-		sm.services[dbService.ID] = &dbService
-		log.Printf("[INFO] Loaded dynamic service from database: UUID %s (Name: %s)", dbService.ID, dbService.Name)
+		dbServices = append(dbServices, &dbService)
 	}
 
-	return rows.Err()
+	return dbServices, rows.Err()
 }
 
 func (sm *Manager) insertServiceInDB(service *models.Service) error {
 	_, err := sm.db.Exec(`
-		INSERT INTO services (id, name, dir, extra_env, java_opts, status, health_status, health_url, port, service_order, description, is_enabled, build_system, verbose_logging, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		INSERT INTO services (id, name, dir, extra_env, java_opts, status, health_status, health_url, port, service_order, description, is_enabled, build_system, verbose_logging, idle_timeout_minutes, health_interval_seconds, java_opts_preset, skip_discovery, owner_team, owner_slack_channel, owner_email, run_as_user, execution_mode, working_dir, start_command, build_command, auto_migrate, infra_type, infra_runtime, interactive, reload_on_sighup, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
 		service.ID, service.Name, service.Dir, service.ExtraEnv, service.JavaOpts, service.Status,
 		service.HealthStatus, service.HealthURL, service.Port, service.Order,
-		service.Description, service.IsEnabled, service.BuildSystem, service.VerboseLogging)
+		service.Description, service.IsEnabled, service.BuildSystem, service.VerboseLogging, service.IdleMinutes, service.HealthInterval, service.JavaOptsPreset, service.SkipDiscovery,
+		service.Owner.Team, service.Owner.SlackChannel, service.Owner.Email, service.RunAsUser, service.ExecutionMode, service.WorkingDir, service.StartCommand, service.BuildCommand, service.AutoMigrate, service.InfraType, service.InfraRuntime, service.Interactive, service.ReloadOnSIGHUP)
 
 	return err
 }
@@ -450,25 +510,19 @@ func (sm *Manager) UpdateServiceConfigInDB(service *models.Service) error {
 	_, err := sm.db.Exec(`
 		UPDATE services
 		SET name = ?, java_opts = ?, health_url = ?, port = ?, service_order = ?, description = ?,
-		    is_enabled = ?, build_system = ?, verbose_logging = ?, updated_at = CURRENT_TIMESTAMP
+		    is_enabled = ?, build_system = ?, verbose_logging = ?, idle_timeout_minutes = ?, health_interval_seconds = ?, java_opts_preset = ?, skip_discovery = ?,
+		    owner_team = ?, owner_slack_channel = ?, owner_email = ?, run_as_user = ?, execution_mode = ?, working_dir = ?, start_command = ?, build_command = ?, auto_migrate = ?, infra_type = ?, infra_runtime = ?, interactive = ?, reload_on_sighup = ?, updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?`,
 		service.Name, service.JavaOpts, service.HealthURL, service.Port, service.Order,
-		service.Description, service.IsEnabled, service.BuildSystem, service.VerboseLogging, service.ID)
+		service.Description, service.IsEnabled, service.BuildSystem, service.VerboseLogging, service.IdleMinutes, service.HealthInterval, service.JavaOptsPreset, service.SkipDiscovery,
+		service.Owner.Team, service.Owner.SlackChannel, service.Owner.Email, service.RunAsUser, service.ExecutionMode, service.WorkingDir, service.StartCommand, service.BuildCommand, service.AutoMigrate, service.InfraType, service.InfraRuntime, service.Interactive, service.ReloadOnSIGHUP, service.ID)
 
 	return err
 }
 
-func (sm *Manager) DeleteService(serviceUUID string) error {
-	_, err := sm.db.Exec("DELETE FROM services WHERE id = ?", serviceUUID)
-	if err != nil {
-		return fmt.Errorf("failed to delete service UUID %s: %w", serviceUUID, err)
-	}
-	return nil
-}
-
 func (sm *Manager) GetServiceEnvVars(serviceUUID string) (map[string]models.EnvVar, error) {
 	rows, err := sm.db.Query(`
-		SELECT var_name, var_value, description, is_required 
+		SELECT var_name, var_value, description, is_required, COALESCE(value_type, ''), COALESCE(value_pattern, '')
 		FROM service_env_vars 
 		WHERE service_id = ?`, serviceUUID)
 	if err != nil {
@@ -481,7 +535,7 @@ func (sm *Manager) GetServiceEnvVars(serviceUUID string) (map[string]models.EnvV
 		var envVar models.EnvVar
 		var description sql.NullString
 
-		err := rows.Scan(&envVar.Name, &envVar.Value, &description, &envVar.IsRequired)
+		err := rows.Scan(&envVar.Name, &envVar.Value, &description, &envVar.IsRequired, &envVar.Type, &envVar.Pattern)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan env var: %w", err)
 		}
@@ -497,6 +551,19 @@ func (sm *Manager) GetServiceEnvVars(serviceUUID string) (map[string]models.EnvV
 }
 
 func (sm *Manager) UpdateServiceEnvVars(serviceUUID string, envVars map[string]models.EnvVar) error {
+	// Refuse values that don't match the schemas the variables declare
+	current := make(map[string]models.EnvVar)
+	if service, exists := sm.GetServiceByUUID(serviceUUID); exists {
+		service.Mutex.RLock()
+		for name, envVar := range service.EnvVars {
+			current[name] = envVar
+		}
+		service.Mutex.RUnlock()
+	}
+	if err := validateEnvVarChanges(current, envVars); err != nil {
+		return err
+	}
+
 	// Start a transaction to ensure atomicity
 	tx, err := sm.db.Begin()
 	if err != nil {
@@ -517,9 +584,9 @@ func (sm *Manager) UpdateServiceEnvVars(serviceUUID string, envVars map[string]m
 		}
 
 		_, err = tx.Exec(`
-			INSERT INTO service_env_vars (service_id, var_name, var_value, description, is_required, updated_at)
-			VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
-			serviceUUID, envVar.Name, envVar.Value, envVar.Description, envVar.IsRequired)
+			INSERT INTO service_env_vars (service_id, var_name, var_value, description, is_required, value_type, value_pattern, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+			serviceUUID, envVar.Name, envVar.Value, envVar.Description, envVar.IsRequired, envVar.Type, envVar.Pattern)
 		if err != nil {
 			return fmt.Errorf("failed to insert service env var %s: %w", envVar.Name, err)
 		}
@@ -536,8 +603,13 @@ func (sm *Manager) UpdateServiceEnvVars(serviceUUID string, envVars map[string]m
 
 	if service, exists := sm.services[serviceUUID]; exists {
 		service.Mutex.Lock()
+		changes := describeEnvVarChanges(service.EnvVars, envVars)
 		service.EnvVars = envVars
 		service.Mutex.Unlock()
+
+		if changes != "" {
+			sm.recordServiceEvent(service, models.EventEnvVarsChanged, changes)
+		}
 	}
 
 	return nil
@@ -0,0 +1,295 @@
+// Package services - VACUUM, ANALYZE and integrity checks of the database,
+// on demand and on a schedule
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	maintenanceCheckInterval = time.Hour        // How often the schedule looks for a due run
+	maintenanceInitialDelay  = 10 * time.Minute // First look after startup, after the backup's
+	maintenanceRecentRuns    = 10
+	maxMaintenanceInterval   = 24 * 365
+)
+
+var (
+	maintenanceMutex   sync.Mutex
+	runningMaintenance *models.MaintenanceRun // nil when no maintenance runs
+)
+
+// maintenanceTasks are the tasks a run may have
+var maintenanceTasks = []string{models.MaintenanceVacuum, models.MaintenanceAnalyze, models.MaintenanceIntegrityCheck}
+
+// validateMaintenanceTasks checks the tasks of a run and drops repeats
+func validateMaintenanceTasks(tasks []string) ([]string, error) {
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("give at least one task: vacuum, analyze or integrity-check")
+	}
+	valid := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		if !slices.Contains(maintenanceTasks, task) {
+			return nil, fmt.Errorf("unknown maintenance task %q: use vacuum, analyze or integrity-check", task)
+		}
+		if !slices.Contains(valid, task) {
+			valid = append(valid, task)
+		}
+	}
+	return valid, nil
+}
+
+// StartMaintenance runs database maintenance tasks in the background and
+// returns the run; its progress is broadcast as db_maintenance messages.
+// Only admins may start maintenance.
+func (sm *Manager) StartMaintenance(tasks []string, claims *models.JWTClaims) (*models.MaintenanceRun, error) {
+	if claims.Role != "admin" {
+		return nil, fmt.Errorf("only admins can run database maintenance")
+	}
+	tasks, err := validateMaintenanceTasks(tasks)
+	if err != nil {
+		return nil, err
+	}
+	run, err := sm.beginMaintenance(tasks, models.MaintenanceTriggerManual)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Database maintenance %v started by %s", tasks, claims.Username)
+	go sm.runMaintenance(sm.ctx, run)
+	return copyMaintenanceRun(run), nil
+}
+
+// beginMaintenance records a new run unless one is already running
+func (sm *Manager) beginMaintenance(tasks []string, trigger string) (*models.MaintenanceRun, error) {
+	maintenanceMutex.Lock()
+	defer maintenanceMutex.Unlock()
+	if runningMaintenance != nil {
+		return nil, fmt.Errorf("maintenance is already running (run %d)", runningMaintenance.ID)
+	}
+
+	run := &models.MaintenanceRun{
+		Tasks:           tasks,
+		Trigger:         trigger,
+		Status:          "running",
+		StartedAt:       time.Now(),
+		SizeBeforeBytes: sm.db.FileSizeBytes(),
+		Problems:        []string{},
+	}
+	if err := sm.db.InsertMaintenanceRun(run); err != nil {
+		return nil, err
+	}
+	runningMaintenance = run
+	return run, nil
+}
+
+// runMaintenance runs the tasks of a run in order and stops at the first
+// that fails
+func (sm *Manager) runMaintenance(ctx context.Context, run *models.MaintenanceRun) {
+	var err error
+	for _, task := range run.Tasks {
+		sm.updateMaintenance(run, task, 0, "")
+		progress := func(percent int, detail string) {
+			sm.updateMaintenance(run, task, percent, detail)
+		}
+
+		switch task {
+		case models.MaintenanceVacuum:
+			err = sm.db.Vacuum(ctx, progress)
+		case models.MaintenanceAnalyze:
+			err = sm.db.Analyze(ctx, progress)
+		case models.MaintenanceIntegrityCheck:
+			var problems []string
+			problems, err = sm.db.IntegrityCheck(ctx, progress)
+			maintenanceMutex.Lock()
+			run.Problems = append(run.Problems, problems...)
+			maintenanceMutex.Unlock()
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	maintenanceMutex.Lock()
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.SizeAfterBytes = sm.db.FileSizeBytes()
+	run.CurrentTask = ""
+	run.ProgressDetail = ""
+	switch {
+	case err != nil:
+		run.Status = "failed"
+		run.Error = err.Error()
+	case len(run.Problems) > 0:
+		run.Status = "failed"
+		run.Error = fmt.Sprintf("integrity check found %d problems", len(run.Problems))
+	default:
+		run.Status = "completed"
+		run.ProgressPercent = 100
+	}
+	runningMaintenance = nil
+	done := copyMaintenanceRun(run)
+	maintenanceMutex.Unlock()
+
+	if err := sm.db.FinishMaintenanceRun(done); err != nil {
+		log.Printf("[WARN] Failed to record maintenance run %d: %v", done.ID, err)
+	}
+	if done.Status == "completed" {
+		log.Printf("[INFO] Database maintenance %v completed in %s; the file went from %d to %d bytes",
+			done.Tasks, finished.Sub(done.StartedAt).Round(time.Second), done.SizeBeforeBytes, done.SizeAfterBytes)
+	} else {
+		log.Printf("[ERROR] Database maintenance %v failed: %s", done.Tasks, done.Error)
+	}
+	sm.broadcastMessage(WebSocketMessage{Type: "db_maintenance", Payload: done})
+}
+
+// updateMaintenance records the progress of a run and broadcasts it
+func (sm *Manager) updateMaintenance(run *models.MaintenanceRun, task string, percent int, detail string) {
+	maintenanceMutex.Lock()
+	run.CurrentTask = task
+	run.ProgressPercent = percent
+	run.ProgressDetail = detail
+	update := copyMaintenanceRun(run)
+	maintenanceMutex.Unlock()
+
+	sm.broadcastMessage(WebSocketMessage{Type: "db_maintenance", Payload: update})
+}
+
+func copyMaintenanceRun(run *models.MaintenanceRun) *models.MaintenanceRun {
+	clone := *run
+	clone.Tasks = append([]string{}, run.Tasks...)
+	clone.Problems = append([]string{}, run.Problems...)
+	return &clone
+}
+
+// GetMaintenanceStatus returns the running maintenance, the recent runs and
+// when the schedule runs next
+func (sm *Manager) GetMaintenanceStatus() (*models.MaintenanceStatus, error) {
+	settings, err := sm.db.GetMaintenanceSettings()
+	if err != nil {
+		return nil, err
+	}
+	recent, err := sm.db.ListMaintenanceRuns(maintenanceRecentRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.MaintenanceStatus{Recent: recent, Settings: settings}
+	maintenanceMutex.Lock()
+	if runningMaintenance != nil {
+		status.Running = copyMaintenanceRun(runningMaintenance)
+	}
+	maintenanceMutex.Unlock()
+
+	if settings.Enabled {
+		last, err := sm.db.LastScheduledMaintenanceAt()
+		if err != nil {
+			return nil, err
+		}
+		next := time.Now()
+		if last != nil {
+			next = last.Add(time.Duration(settings.IntervalHours) * time.Hour)
+		}
+		status.NextRunAt = &next
+	}
+	return status, nil
+}
+
+// GetMaintenanceSettings returns the maintenance schedule
+func (sm *Manager) GetMaintenanceSettings() (*models.MaintenanceSettings, error) {
+	return sm.db.GetMaintenanceSettings()
+}
+
+// SetMaintenanceSettings validates and saves the maintenance schedule. Only
+// admins may change it.
+func (sm *Manager) SetMaintenanceSettings(settings models.MaintenanceSettings, claims *models.JWTClaims) (*models.MaintenanceSettings, error) {
+	if claims.Role != "admin" {
+		return nil, fmt.Errorf("only admins can change maintenance settings")
+	}
+	if settings.IntervalHours < 1 || settings.IntervalHours > maxMaintenanceInterval {
+		return nil, fmt.Errorf("intervalHours must be between 1 and %d", maxMaintenanceInterval)
+	}
+	if settings.VacuumMinFreePercent < 0 || settings.VacuumMinFreePercent > 100 {
+		return nil, fmt.Errorf("vacuumMinFreePercent must be between 0 and 100")
+	}
+	tasks, err := validateMaintenanceTasks(settings.Tasks)
+	if err != nil {
+		return nil, err
+	}
+	settings.Tasks = tasks
+
+	if err := sm.db.SaveMaintenanceSettings(settings); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Maintenance settings changed by %s: enabled=%t, %v every %d hours", claims.Username, settings.Enabled, settings.Tasks, settings.IntervalHours)
+	return sm.db.GetMaintenanceSettings()
+}
+
+// startMaintenanceRoutine runs the scheduled maintenance when it is due
+func (sm *Manager) startMaintenanceRoutine(ctx context.Context) {
+	if err := sm.db.FailUnfinishedMaintenanceRuns(); err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+
+	initialDelay := time.NewTimer(maintenanceInitialDelay)
+	defer initialDelay.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-initialDelay.C:
+		case <-ticker.C:
+		}
+		sm.runScheduledMaintenance(ctx, time.Now())
+	}
+}
+
+// runScheduledMaintenance runs the scheduled tasks once the interval since
+// the last scheduled run has passed. VACUUM rewrites the whole file, so it
+// is left out while little of the file is free.
+func (sm *Manager) runScheduledMaintenance(ctx context.Context, now time.Time) {
+	settings, err := sm.db.GetMaintenanceSettings()
+	if err != nil {
+		log.Printf("[WARN] Failed to read maintenance settings: %v", err)
+		return
+	}
+	if !settings.Enabled {
+		return
+	}
+	last, err := sm.db.LastScheduledMaintenanceAt()
+	if err != nil {
+		log.Printf("[WARN] %v", err)
+		return
+	}
+	if last != nil && now.Sub(*last) < time.Duration(settings.IntervalHours)*time.Hour {
+		return
+	}
+
+	tasks := settings.Tasks
+	if slices.Contains(tasks, models.MaintenanceVacuum) {
+		if stats, err := sm.db.HealthStats(); err == nil && stats.FragmentationPct < float64(settings.VacuumMinFreePercent) {
+			log.Printf("[INFO] Skipping the scheduled VACUUM: %.1f%% of the database is free, below %d%%", stats.FragmentationPct, settings.VacuumMinFreePercent)
+			tasks = slices.DeleteFunc(slices.Clone(tasks), func(task string) bool { return task == models.MaintenanceVacuum })
+		}
+	}
+	if len(tasks) == 0 {
+		return
+	}
+
+	run, err := sm.beginMaintenance(tasks, models.MaintenanceTriggerScheduled)
+	if err != nil {
+		log.Printf("[WARN] Scheduled database maintenance not started: %v", err)
+		return
+	}
+	log.Printf("[INFO] Scheduled database maintenance %v started", tasks)
+	sm.runMaintenance(ctx, run)
+}
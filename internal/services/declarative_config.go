@@ -0,0 +1,929 @@
+// Package services - Configuration as code: reconcile the database with vertex.yaml
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/zechtz/vertex/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// DeclarativeConfigFile is the file looked up in the projects root
+const DeclarativeConfigFile = "vertex.yaml"
+
+// Actions reported for each resource when applying a declarative configuration
+const (
+	ConfigActionCreate = "create"
+	ConfigActionUpdate = "update"
+	ConfigActionDelete = "delete"
+)
+
+// ConfigApplyOptions controls how a declarative configuration is applied
+type ConfigApplyOptions struct {
+	DryRun bool `json:"dryRun"` // Report the diff without changing anything
+	Prune  bool `json:"prune"`  // Delete services, profiles and global env vars missing from the file
+}
+
+// ConfigChange is one create, update or delete of the reconciliation
+type ConfigChange struct {
+	Kind    string   `json:"kind"` // "global", "env", "service" or "profile"
+	Name    string   `json:"name"`
+	Action  string   `json:"action"`
+	Fields  []string `json:"fields,omitempty"` // Changed fields of an update
+	Applied bool     `json:"applied"`
+	Skipped string   `json:"skipped,omitempty"` // Why a change was not applied
+	Error   string   `json:"error,omitempty"`
+}
+
+// ConfigApplyResult is the diff between the file and the database
+type ConfigApplyResult struct {
+	Source  string         `json:"source"`
+	DryRun  bool           `json:"dryRun"`
+	Creates int            `json:"creates"`
+	Updates int            `json:"updates"`
+	Deletes int            `json:"deletes"`
+	Errors  int            `json:"errors"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+func (r *ConfigApplyResult) add(change ConfigChange) {
+	switch change.Action {
+	case ConfigActionCreate:
+		r.Creates++
+	case ConfigActionUpdate:
+		r.Updates++
+	case ConfigActionDelete:
+		r.Deletes++
+	}
+	if change.Error != "" {
+		r.Errors++
+	}
+	r.Changes = append(r.Changes, change)
+}
+
+// ConfigApplier reconciles services, profiles and environment variables with a
+// declarative configuration
+type ConfigApplier struct {
+	manager  *Manager
+	profiles *ProfileService
+}
+
+func NewConfigApplier(manager *Manager, profiles *ProfileService) *ConfigApplier {
+	return &ConfigApplier{manager: manager, profiles: profiles}
+}
+
+// DefaultConfigPath returns vertex.yaml in the global projects directory
+func (ca *ConfigApplier) DefaultConfigPath() string {
+	return filepath.Join(ca.manager.GetConfig().ProjectsDir, DeclarativeConfigFile)
+}
+
+// LoadDeclarativeConfig reads and validates a vertex.yaml file
+func LoadDeclarativeConfig(path string) (*models.DeclarativeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return ParseDeclarativeConfig(data)
+}
+
+// ParseDeclarativeConfig parses and validates vertex.yaml content
+func ParseDeclarativeConfig(data []byte) (*models.DeclarativeConfig, error) {
+	var config models.DeclarativeConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, service := range config.Services {
+		if strings.TrimSpace(service.Name) == "" {
+			return nil, fmt.Errorf("services[%d]: name is required", i)
+		}
+		if strings.TrimSpace(service.Dir) == "" && (service.InfraType == nil || *service.InfraType == "") {
+			return nil, fmt.Errorf("service %s: dir is required", service.Name)
+		}
+		if seen[service.Name] {
+			return nil, fmt.Errorf("service %s is declared more than once", service.Name)
+		}
+		seen[service.Name] = true
+		if service.Logs != nil {
+			if err := normalizeLogIngestionConfig(service.Logs); err != nil {
+				return nil, fmt.Errorf("service %s: %w", service.Name, err)
+			}
+		}
+	}
+	for _, service := range config.Services {
+		for _, dependency := range service.DependsOn {
+			if !seen[dependency] {
+				return nil, fmt.Errorf("service %s depends on undeclared service %s", service.Name, dependency)
+			}
+		}
+	}
+
+	profileNames := make(map[string]bool)
+	for i, profile := range config.Profiles {
+		if strings.TrimSpace(profile.Name) == "" {
+			return nil, fmt.Errorf("profiles[%d]: name is required", i)
+		}
+		if profileNames[profile.Name] {
+			return nil, fmt.Errorf("profile %s is declared more than once", profile.Name)
+		}
+		profileNames[profile.Name] = true
+	}
+
+	return &config, nil
+}
+
+// DefaultOwner returns the user that owns profiles applied from the command
+// line: the first admin, or the first user when there is no admin
+func (ca *ConfigApplier) DefaultOwner() (string, error) {
+	var userID string
+	err := ca.manager.db.QueryRow(`SELECT id FROM users ORDER BY (role = 'admin') DESC, created_at ASC LIMIT 1`).Scan(&userID)
+	if err != nil {
+		return "", fmt.Errorf("no user found to own the profiles; complete setup first")
+	}
+	return userID, nil
+}
+
+// Apply reconciles the database with the configuration. Services, profiles
+// and global variables missing from the file are only deleted with Prune.
+// Profiles are created and updated for userID.
+func (ca *ConfigApplier) Apply(config *models.DeclarativeConfig, userID string, opts ConfigApplyOptions) (*ConfigApplyResult, error) {
+	result := &ConfigApplyResult{DryRun: opts.DryRun, Changes: []ConfigChange{}}
+
+	if err := ca.applyGlobal(config, opts, result); err != nil {
+		return nil, err
+	}
+
+	serviceIDs := ca.serviceIDsByName()
+
+	// Services first, then their dependencies, which may name services created in this run
+	for _, declared := range config.Services {
+		ca.applyService(declared, serviceIDs, opts, result)
+	}
+	for _, declared := range config.Services {
+		ca.applyServiceDependencies(declared, serviceIDs, opts, result)
+		ca.applyServiceLogIngestion(declared, serviceIDs, opts, result)
+	}
+
+	if len(config.Profiles) > 0 || opts.Prune {
+		if userID == "" {
+			return nil, fmt.Errorf("a user is required to apply profiles")
+		}
+		if err := ca.applyProfiles(config.Profiles, userID, serviceIDs, opts, result); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Prune {
+		ca.pruneServices(config, opts, result)
+	} else {
+		ca.reportUnmanagedServices(config, result)
+	}
+
+	log.Printf("[INFO] Applied declarative configuration (dry run: %v): %d creates, %d updates, %d deletes, %d errors",
+		opts.DryRun, result.Creates, result.Updates, result.Deletes, result.Errors)
+	return result, nil
+}
+
+func (ca *ConfigApplier) applyGlobal(config *models.DeclarativeConfig, opts ConfigApplyOptions, result *ConfigApplyResult) error {
+	current := ca.manager.GetConfig()
+
+	var fields []string
+	projectsDir := current.ProjectsDir
+	if config.ProjectsDir != "" && expandHome(config.ProjectsDir) != current.ProjectsDir {
+		projectsDir = expandHome(config.ProjectsDir)
+		fields = append(fields, "projectsDir")
+	}
+	javaHome := current.JavaHomeOverride
+	if config.JavaHome != nil && *config.JavaHome != current.JavaHomeOverride {
+		javaHome = *config.JavaHome
+		fields = append(fields, "javaHome")
+	}
+	if len(fields) > 0 {
+		change := ConfigChange{Kind: "global", Name: "config", Action: ConfigActionUpdate, Fields: fields}
+		if !opts.DryRun {
+			if _, err := ca.manager.UpdateGlobalConfig(projectsDir, javaHome); err != nil {
+				change.Error = err.Error()
+			} else {
+				change.Applied = true
+			}
+		}
+		result.add(change)
+	}
+
+	if config.Env == nil {
+		return nil
+	}
+	existing, err := ca.manager.GetGlobalEnvVars()
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(config.Env) {
+		value := config.Env[name]
+		previous, exists := existing[name]
+		if exists && previous == value {
+			continue
+		}
+		change := ConfigChange{Kind: "env", Name: name, Action: ConfigActionCreate}
+		if exists {
+			change.Action = ConfigActionUpdate
+			change.Fields = []string{"value"}
+		}
+		if !opts.DryRun {
+			if err := ca.manager.db.SetGlobalEnvVar(name, value); err != nil {
+				change.Error = err.Error()
+			} else {
+				os.Setenv(name, value)
+				change.Applied = true
+			}
+		}
+		result.add(change)
+	}
+	if opts.Prune {
+		for _, name := range sortedKeys(existing) {
+			if _, declared := config.Env[name]; declared {
+				continue
+			}
+			change := ConfigChange{Kind: "env", Name: name, Action: ConfigActionDelete}
+			if !opts.DryRun {
+				if err := ca.manager.db.DeleteGlobalEnvVar(name); err != nil {
+					change.Error = err.Error()
+				} else {
+					change.Applied = true
+				}
+			}
+			result.add(change)
+		}
+	}
+	return nil
+}
+
+// serviceIDsByName maps service names to UUIDs; names shared by several
+// services map to "" and cannot be managed from the file
+func (ca *ConfigApplier) serviceIDsByName() map[string]string {
+	ids := make(map[string]string)
+	for _, service := range ca.manager.GetServices() {
+		if _, duplicate := ids[service.Name]; duplicate {
+			ids[service.Name] = ""
+			continue
+		}
+		ids[service.Name] = service.ID
+	}
+	return ids
+}
+
+func (ca *ConfigApplier) applyService(declared models.DeclarativeService, serviceIDs map[string]string, opts ConfigApplyOptions, result *ConfigApplyResult) {
+	serviceID, exists := serviceIDs[declared.Name]
+	if exists && serviceID == "" {
+		result.add(ConfigChange{Kind: "service", Name: declared.Name, Action: ConfigActionUpdate,
+			Error: "several services share this name; rename them to manage it from the file"})
+		return
+	}
+
+	if !exists {
+		change := ConfigChange{Kind: "service", Name: declared.Name, Action: ConfigActionCreate}
+		if opts.DryRun {
+			serviceIDs[declared.Name] = "dry-run:" + declared.Name
+			result.add(change)
+			return
+		}
+
+		service := &models.Service{
+			ID:           uuid.New().String(),
+			Name:         declared.Name,
+			Dir:          declared.Dir,
+			Port:         ca.manager.nextFreeServicePort(8080),
+			Status:       "stopped",
+			HealthStatus: "unknown",
+			BuildSystem:  "auto",
+			IsEnabled:    true,
+			EnvVars:      make(map[string]models.EnvVar),
+		}
+		applyDeclaredServiceFields(service, declared)
+		if err := ca.manager.AddService(service); err != nil {
+			change.Error = err.Error()
+			result.add(change)
+			return
+		}
+		serviceIDs[declared.Name] = service.ID
+		if declared.Env != nil {
+			if err := ca.manager.UpdateServiceEnvVars(service.ID, service.EnvVars); err != nil {
+				change.Error = fmt.Sprintf("failed to save environment variables: %v", err)
+			}
+		}
+		if declared.Tags != nil {
+			if err := ca.manager.UpdateServiceTags(service.ID, declared.Tags); err != nil {
+				change.Error = fmt.Sprintf("failed to save tags: %v", err)
+			}
+		}
+		change.Applied = change.Error == ""
+		result.add(change)
+		return
+	}
+
+	service, found := ca.manager.GetServiceByUUID(serviceID)
+	if !found {
+		return
+	}
+
+	service.Mutex.RLock()
+	updated := &models.Service{
+		Name:           service.Name,
+		Dir:            service.Dir,
+		Port:           service.Port,
+		Order:          service.Order,
+		Description:    service.Description,
+		JavaOpts:       service.JavaOpts,
+		JavaOptsPreset: service.JavaOptsPreset,
+		HealthURL:      service.HealthURL,
+		BuildSystem:    service.BuildSystem,
+		IsEnabled:      service.IsEnabled,
+		VerboseLogging: service.VerboseLogging,
+		IdleMinutes:    service.IdleMinutes,
+		HealthInterval: service.HealthInterval,
+		SkipDiscovery:  service.SkipDiscovery,
+		Owner:          service.Owner,
+		RunAsUser:      service.RunAsUser,
+		ExecutionMode:  service.ExecutionMode,
+		WorkingDir:     service.WorkingDir,
+		StartCommand:   service.StartCommand,
+		BuildCommand:   service.BuildCommand,
+		AutoMigrate:    service.AutoMigrate,
+		Interactive:    service.Interactive,
+		ReloadOnSIGHUP: service.ReloadOnSIGHUP,
+		InfraType:      service.InfraType,
+		InfraRuntime:   service.InfraRuntime,
+		EnvVars:        make(map[string]models.EnvVar, len(service.EnvVars)),
+	}
+	for name, envVar := range service.EnvVars {
+		updated.EnvVars[name] = envVar
+	}
+	currentTags := make(map[string]string, len(service.Tags))
+	for key, value := range service.Tags {
+		currentTags[key] = value
+	}
+	service.Mutex.RUnlock()
+
+	before := updated.Clone()
+	beforeEnv := updated.EnvVars
+	updated.EnvVars = make(map[string]models.EnvVar, len(beforeEnv))
+	for name, envVar := range beforeEnv {
+		updated.EnvVars[name] = envVar
+	}
+	applyDeclaredServiceFields(updated, declared)
+
+	fields := diffServiceFields(before, beforeEnv, updated)
+	tagsChanged := declared.Tags != nil && !stringMapsEqual(currentTags, declared.Tags)
+	if tagsChanged {
+		fields = append(fields, "tags")
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	change := ConfigChange{Kind: "service", Name: declared.Name, Action: ConfigActionUpdate, Fields: fields}
+	if !opts.DryRun {
+		err := ca.manager.UpdateService(&models.ServiceConfigRequest{
+			ID:             serviceID,
+			Name:           updated.Name,
+			Dir:            updated.Dir,
+			JavaOpts:       updated.JavaOpts,
+			JavaOptsPreset: updated.JavaOptsPreset,
+			HealthURL:      updated.HealthURL,
+			Port:           updated.Port,
+			Order:          updated.Order,
+			Description:    updated.Description,
+			IsEnabled:      updated.IsEnabled,
+			BuildSystem:    updated.BuildSystem,
+			VerboseLogging: updated.VerboseLogging,
+			IdleMinutes:    updated.IdleMinutes,
+			HealthInterval: updated.HealthInterval,
+			SkipDiscovery:  updated.SkipDiscovery,
+			Owner:          updated.Owner,
+			RunAsUser:      updated.RunAsUser,
+			ExecutionMode:  updated.ExecutionMode,
+			WorkingDir:     updated.WorkingDir,
+			StartCommand:   updated.StartCommand,
+			BuildCommand:   updated.BuildCommand,
+			AutoMigrate:    updated.AutoMigrate,
+			Interactive:    updated.Interactive,
+			ReloadOnSIGHUP: updated.ReloadOnSIGHUP,
+			InfraType:      updated.InfraType,
+			InfraRuntime:   updated.InfraRuntime,
+			EnvVars:        updated.EnvVars,
+		})
+		if err == nil && slices.Contains(fields, "env") {
+			err = ca.manager.UpdateServiceEnvVars(serviceID, updated.EnvVars)
+		}
+		if err == nil && tagsChanged {
+			err = ca.manager.UpdateServiceTags(serviceID, declared.Tags)
+		}
+		if err != nil {
+			change.Error = err.Error()
+		} else {
+			change.Applied = true
+		}
+	}
+	result.add(change)
+}
+
+// applyDeclaredServiceFields copies the fields set in the file onto a service
+func applyDeclaredServiceFields(service *models.Service, declared models.DeclarativeService) {
+	service.Dir = declared.Dir
+	if declared.Port != nil {
+		service.Port = *declared.Port
+	}
+	if declared.Order != nil {
+		service.Order = *declared.Order
+	}
+	if declared.Description != nil {
+		service.Description = *declared.Description
+	}
+	if declared.JavaOpts != nil {
+		service.JavaOpts = *declared.JavaOpts
+	}
+	if declared.JavaOptsPreset != nil {
+		service.JavaOptsPreset = *declared.JavaOptsPreset
+	}
+	if declared.HealthURL != nil {
+		service.HealthURL = *declared.HealthURL
+	}
+	if declared.BuildSystem != nil {
+		service.BuildSystem = *declared.BuildSystem
+	}
+	if declared.Enabled != nil {
+		service.IsEnabled = *declared.Enabled
+	}
+	if declared.VerboseLogging != nil {
+		service.VerboseLogging = *declared.VerboseLogging
+	}
+	if declared.IdleMinutes != nil {
+		service.IdleMinutes = *declared.IdleMinutes
+	}
+	if declared.HealthInterval != nil {
+		service.HealthInterval = *declared.HealthInterval
+	}
+	if declared.SkipDiscovery != nil {
+		service.SkipDiscovery = *declared.SkipDiscovery
+	}
+	if declared.Owner != nil {
+		service.Owner = *declared.Owner
+	}
+	if declared.RunAsUser != nil {
+		service.RunAsUser = *declared.RunAsUser
+	}
+	if declared.ExecutionMode != nil {
+		service.ExecutionMode = *declared.ExecutionMode
+	}
+	if declared.WorkingDir != nil {
+		service.WorkingDir = *declared.WorkingDir
+	}
+	if declared.StartCommand != nil {
+		service.StartCommand = *declared.StartCommand
+	}
+	if declared.BuildCommand != nil {
+		service.BuildCommand = *declared.BuildCommand
+	}
+	if declared.AutoMigrate != nil {
+		service.AutoMigrate = *declared.AutoMigrate
+	}
+	if declared.Interactive != nil {
+		service.Interactive = *declared.Interactive
+	}
+	if declared.ReloadOnSIGHUP != nil {
+		service.ReloadOnSIGHUP = *declared.ReloadOnSIGHUP
+	}
+	if declared.InfraType != nil {
+		service.InfraType = *declared.InfraType
+	}
+	if declared.InfraRuntime != nil {
+		service.InfraRuntime = *declared.InfraRuntime
+	}
+	if declared.Env != nil {
+		envVars := make(map[string]models.EnvVar, len(declared.Env))
+		for name, value := range declared.Env {
+			envVar := service.EnvVars[name] // Keep description, required flag and schema
+			envVar.Name = name
+			envVar.Value = value
+			envVars[name] = envVar
+		}
+		service.EnvVars = envVars
+	}
+	if service.HealthURL == "" && service.Port > 0 {
+		service.HealthURL = fmt.Sprintf("http://localhost:%d/actuator/health", service.Port)
+	}
+}
+
+func diffServiceFields(before *models.Service, beforeEnv map[string]models.EnvVar, after *models.Service) []string {
+	var fields []string
+	check := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+	check("dir", before.Dir != after.Dir)
+	check("port", before.Port != after.Port)
+	check("order", before.Order != after.Order)
+	check("description", before.Description != after.Description)
+	check("javaOpts", before.JavaOpts != after.JavaOpts)
+	check("javaOptsPreset", before.JavaOptsPreset != after.JavaOptsPreset)
+	check("healthUrl", before.HealthURL != after.HealthURL)
+	check("buildSystem", before.BuildSystem != after.BuildSystem)
+	check("enabled", before.IsEnabled != after.IsEnabled)
+	check("verboseLogging", before.VerboseLogging != after.VerboseLogging)
+	check("idleMinutes", before.IdleMinutes != after.IdleMinutes)
+	check("healthInterval", before.HealthInterval != after.HealthInterval)
+	check("skipDiscovery", before.SkipDiscovery != after.SkipDiscovery)
+	check("owner", before.Owner != after.Owner)
+	check("runAsUser", before.RunAsUser != after.RunAsUser)
+	check("executionMode", before.ExecutionMode != after.ExecutionMode)
+	check("workingDir", before.WorkingDir != after.WorkingDir)
+	check("startCommand", before.StartCommand != after.StartCommand)
+	check("buildCommand", before.BuildCommand != after.BuildCommand)
+	check("autoMigrate", before.AutoMigrate != after.AutoMigrate)
+	check("interactive", before.Interactive != after.Interactive)
+	check("reloadOnSighup", before.ReloadOnSIGHUP != after.ReloadOnSIGHUP)
+	check("infraType", before.InfraType != after.InfraType)
+	check("infraRuntime", before.InfraRuntime != after.InfraRuntime)
+
+	changed, removed := diffEnvVars(beforeEnv, after.EnvVars)
+	check("env", len(changed)+len(removed) > 0)
+	return fields
+}
+
+func (ca *ConfigApplier) applyServiceDependencies(declared models.DeclarativeService, serviceIDs map[string]string, opts ConfigApplyOptions, result *ConfigApplyResult) {
+	if declared.DependsOn == nil {
+		return
+	}
+	serviceID := serviceIDs[declared.Name]
+	if serviceID == "" {
+		return
+	}
+
+	wanted := make([]string, 0, len(declared.DependsOn))
+	for _, name := range declared.DependsOn {
+		if id := serviceIDs[name]; id != "" {
+			wanted = append(wanted, id)
+		}
+	}
+	sort.Strings(wanted)
+
+	var existing []map[string]any
+	if !strings.HasPrefix(serviceID, "dry-run:") {
+		var err error
+		existing, err = ca.manager.db.LoadServiceDependencies(serviceID)
+		if err != nil {
+			result.add(ConfigChange{Kind: "service", Name: declared.Name, Action: ConfigActionUpdate, Fields: []string{"dependsOn"}, Error: err.Error()})
+			return
+		}
+	}
+	current := make([]string, 0, len(existing))
+	byID := make(map[string]map[string]any, len(existing))
+	for _, dependency := range existing {
+		id, _ := dependency["serviceId"].(string)
+		current = append(current, id)
+		byID[id] = dependency
+	}
+	sort.Strings(current)
+	if slices.Equal(current, wanted) {
+		return
+	}
+
+	change := ConfigChange{Kind: "service", Name: declared.Name, Action: ConfigActionUpdate, Fields: []string{"dependsOn"}}
+	if !opts.DryRun {
+		// Dependencies kept from the database keep their settings
+		dependencies := make([]any, 0, len(wanted))
+		for _, id := range wanted {
+			dependency, exists := byID[id]
+			if !exists {
+				dependency = map[string]any{"serviceId": id, "type": "hard", "healthCheck": true, "required": true}
+			}
+			for _, key := range []string{"timeoutSeconds", "retryIntervalSeconds"} {
+				if value, ok := dependency[key].(int); ok {
+					dependency[key] = float64(value)
+				}
+			}
+			dependencies = append(dependencies, dependency)
+		}
+		if err := ca.manager.db.SaveServiceDependencies(serviceID, dependencies); err != nil {
+			change.Error = err.Error()
+		} else {
+			change.Applied = true
+		}
+	}
+
+	// Dependencies of a service created in this run are part of its create
+	for i := range result.Changes {
+		created := &result.Changes[i]
+		if created.Kind == "service" && created.Name == declared.Name && created.Action == ConfigActionCreate {
+			if change.Error != "" && created.Error == "" {
+				created.Error = "failed to save dependencies: " + change.Error
+				created.Applied = false
+				result.Errors++
+			}
+			return
+		}
+	}
+	result.add(change)
+}
+
+// applyServiceLogIngestion saves the log ingestion settings declared for a
+// service when they differ from the stored ones
+func (ca *ConfigApplier) applyServiceLogIngestion(declared models.DeclarativeService, serviceIDs map[string]string, opts ConfigApplyOptions, result *ConfigApplyResult) {
+	if declared.Logs == nil {
+		return
+	}
+	serviceID := serviceIDs[declared.Name]
+	if serviceID == "" {
+		return
+	}
+
+	wanted := *declared.Logs
+	wanted.ServiceID = serviceID
+	wanted.Stats = nil
+	if !strings.HasPrefix(serviceID, "dry-run:") {
+		configs, err := ca.manager.db.GetLogIngestionConfigs()
+		if err != nil {
+			result.add(ConfigChange{Kind: "service", Name: declared.Name, Action: ConfigActionUpdate, Fields: []string{"logs"}, Error: err.Error()})
+			return
+		}
+		current := models.LogIngestionConfig{ServiceID: serviceID}
+		for _, stored := range configs {
+			if stored.ServiceID == serviceID {
+				current = stored
+				break
+			}
+		}
+		if current.DropBanner == wanted.DropBanner && slices.Equal(current.DropPatterns, wanted.DropPatterns) &&
+			current.DebugSamplePercent == wanted.DebugSamplePercent && current.MaxLinesPerSecond == wanted.MaxLinesPerSecond {
+			return
+		}
+	}
+
+	change := ConfigChange{Kind: "service", Name: declared.Name, Action: ConfigActionUpdate, Fields: []string{"logs"}}
+	if !opts.DryRun {
+		if err := ca.manager.SetLogIngestionConfig(wanted); err != nil {
+			change.Error = err.Error()
+		} else {
+			change.Applied = true
+		}
+	}
+
+	// Settings of a service created in this run are part of its create
+	for i := range result.Changes {
+		created := &result.Changes[i]
+		if created.Kind == "service" && created.Name == declared.Name && created.Action == ConfigActionCreate {
+			if change.Error != "" && created.Error == "" {
+				created.Error = "failed to save log ingestion settings: " + change.Error
+				created.Applied = false
+				result.Errors++
+			}
+			return
+		}
+	}
+	result.add(change)
+}
+
+func (ca *ConfigApplier) applyProfiles(declaredProfiles []models.DeclarativeProfile, userID string, serviceIDs map[string]string, opts ConfigApplyOptions, result *ConfigApplyResult) error {
+	existingProfiles, err := ca.profiles.GetServiceProfiles(userID)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]models.ServiceProfile, len(existingProfiles))
+	for _, profile := range existingProfiles {
+		byName[profile.Name] = profile
+	}
+
+	for _, declared := range declaredProfiles {
+		var serviceUUIDs []string
+		var missing []string
+		for _, name := range declared.Services {
+			if id := serviceIDs[name]; id != "" {
+				serviceUUIDs = append(serviceUUIDs, id)
+			} else {
+				missing = append(missing, name)
+			}
+		}
+
+		existing, exists := byName[declared.Name]
+		if !exists {
+			change := ConfigChange{Kind: "profile", Name: declared.Name, Action: ConfigActionCreate}
+			if len(missing) > 0 {
+				change.Error = "unknown services: " + strings.Join(missing, ", ")
+			} else if !opts.DryRun {
+				req := &models.CreateProfileRequest{
+					Name:        declared.Name,
+					Description: derefString(declared.Description, ""),
+					Services:    nonNilStrings(serviceUUIDs),
+					EnvVars:     declared.Env,
+					ProjectsDir: expandHome(derefString(declared.ProjectsDir, "")),
+					IsDefault:   declared.Default != nil && *declared.Default,
+					IsActive:    declared.Active != nil && *declared.Active,
+				}
+				req.JavaHomeOverride = derefString(declared.JavaHome, "")
+				if _, err := ca.profiles.CreateServiceProfile(userID, req); err != nil {
+					change.Error = err.Error()
+				} else {
+					change.Applied = true
+				}
+			}
+			result.add(change)
+			continue
+		}
+
+		req := &models.UpdateProfileRequest{
+			Name:             existing.Name,
+			Description:      derefString(declared.Description, existing.Description),
+			Services:         existing.Services,
+			EnvVars:          existing.EnvVars,
+			ProjectsDir:      existing.ProjectsDir,
+			JavaHomeOverride: derefString(declared.JavaHome, existing.JavaHomeOverride),
+			IsDefault:        existing.IsDefault,
+		}
+		if declared.ProjectsDir != nil {
+			req.ProjectsDir = expandHome(*declared.ProjectsDir)
+		}
+		if declared.Services != nil {
+			req.Services = nonNilStrings(serviceUUIDs)
+		}
+		if declared.Env != nil {
+			req.EnvVars = declared.Env
+		}
+		if declared.Default != nil {
+			req.IsDefault = *declared.Default
+		}
+		activate := declared.Active != nil && *declared.Active && !existing.IsActive
+
+		var fields []string
+		check := func(name string, changed bool) {
+			if changed {
+				fields = append(fields, name)
+			}
+		}
+		check("description", req.Description != existing.Description)
+		check("projectsDir", req.ProjectsDir != existing.ProjectsDir)
+		check("javaHome", req.JavaHomeOverride != existing.JavaHomeOverride)
+		check("services", !sameStringSet(req.Services, existing.Services))
+		check("env", !stringMapsEqual(req.EnvVars, existing.EnvVars))
+		check("default", req.IsDefault != existing.IsDefault)
+		check("active", activate)
+		if len(fields) == 0 && len(missing) == 0 {
+			continue
+		}
+
+		change := ConfigChange{Kind: "profile", Name: declared.Name, Action: ConfigActionUpdate, Fields: fields}
+		if len(missing) > 0 {
+			change.Error = "unknown services: " + strings.Join(missing, ", ")
+		} else if !opts.DryRun {
+			_, err := ca.profiles.UpdateServiceProfile(existing.ID, userID, req)
+			if err == nil && activate {
+				err = ca.profiles.SetActiveProfile(userID, existing.ID)
+			}
+			if err != nil {
+				change.Error = err.Error()
+			} else {
+				change.Applied = true
+			}
+		}
+		result.add(change)
+	}
+
+	if !opts.Prune {
+		return nil
+	}
+	declaredNames := make(map[string]bool, len(declaredProfiles))
+	for _, declared := range declaredProfiles {
+		declaredNames[declared.Name] = true
+	}
+	for _, profile := range existingProfiles {
+		if declaredNames[profile.Name] {
+			continue
+		}
+		change := ConfigChange{Kind: "profile", Name: profile.Name, Action: ConfigActionDelete}
+		if !opts.DryRun {
+			if err := ca.profiles.DeleteServiceProfile(profile.ID, userID); err != nil {
+				change.Error = err.Error()
+			} else {
+				change.Applied = true
+			}
+		}
+		result.add(change)
+	}
+	return nil
+}
+
+func (ca *ConfigApplier) pruneServices(config *models.DeclarativeConfig, opts ConfigApplyOptions, result *ConfigApplyResult) {
+	declared := make(map[string]bool, len(config.Services))
+	for _, service := range config.Services {
+		declared[service.Name] = true
+	}
+
+	// Read each live service under its lock; DeleteService does the rest
+	type pruneCandidate struct {
+		id, name, status string
+		order            int
+	}
+	var candidates []pruneCandidate
+	for _, service := range ca.manager.snapshotServices() {
+		service.Mutex.RLock()
+		candidate := pruneCandidate{id: service.ID, name: service.Name, status: service.Status, order: service.Order}
+		service.Mutex.RUnlock()
+		if !declared[candidate.name] {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].order < candidates[j].order })
+
+	for _, candidate := range candidates {
+		change := ConfigChange{Kind: "service", Name: candidate.name, Action: ConfigActionDelete}
+		if candidate.status == "running" || candidate.status == StatusPaused {
+			change.Skipped = "service is running; stop it first"
+		} else if !opts.DryRun {
+			if err := ca.manager.DeleteService(candidate.id); err != nil {
+				change.Error = err.Error()
+			} else {
+				change.Applied = true
+			}
+		}
+		result.add(change)
+	}
+}
+
+// reportUnmanagedServices lists services missing from the file that Prune would delete
+func (ca *ConfigApplier) reportUnmanagedServices(config *models.DeclarativeConfig, result *ConfigApplyResult) {
+	if len(config.Services) == 0 {
+		return
+	}
+	declared := make(map[string]bool, len(config.Services))
+	for _, service := range config.Services {
+		declared[service.Name] = true
+	}
+	for _, service := range ca.manager.GetServices() {
+		if !declared[service.Name] {
+			result.Changes = append(result.Changes, ConfigChange{
+				Kind:    "service",
+				Name:    service.Name,
+				Action:  ConfigActionDelete,
+				Skipped: "not in the file; apply with prune to delete",
+			})
+		}
+	}
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+func derefString(value *string, fallback string) string {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringMapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, exists := b[key]; !exists || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+func sameStringSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	sort.Strings(a)
+	sort.Strings(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,115 @@
+// Package services - Impact analysis before deleting a service
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// GetServiceDeletionImpact reports which profiles list a service, which
+// services depend on it and what stored data deleting it loses. A report that
+// is not empty carries the token DeleteService callers must confirm with.
+func (sm *Manager) GetServiceDeletionImpact(serviceUUID string) (*models.ServiceDeletionImpact, error) {
+	target, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	target.Mutex.RLock()
+	impact := &models.ServiceDeletionImpact{
+		ServiceID:   target.ID,
+		ServiceName: target.Name,
+		Running:     target.Status == "running" || target.Status == StatusPaused,
+		Dependents:  []models.ImpactedDependent{},
+	}
+	target.Mutex.RUnlock()
+
+	var err error
+	if impact.Profiles, err = sm.db.GetProfilesWithService(serviceUUID); err != nil {
+		return nil, err
+	}
+	if impact.StoredData, err = sm.db.CountServiceData(serviceUUID); err != nil {
+		return nil, err
+	}
+
+	allDependencies, err := sm.db.GetAllServiceDependencies()
+	if err != nil {
+		return nil, err
+	}
+	for dependentUUID, dependencies := range allDependencies {
+		for _, dependency := range dependencies {
+			if dependencyID, _ := dependency["serviceId"].(string); dependencyID != serviceUUID {
+				continue
+			}
+			dependent := models.ImpactedDependent{ServiceID: dependentUUID}
+			dependent.Type, _ = dependency["type"].(string)
+			dependent.Required, _ = dependency["required"].(bool)
+			impact.Dependents = append(impact.Dependents, dependent)
+		}
+	}
+
+	profileDependents, err := sm.db.GetProfileDependents(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	impact.Dependents = append(impact.Dependents, profileDependents...)
+
+	for i := range impact.Dependents {
+		if service, exists := sm.GetServiceByUUID(impact.Dependents[i].ServiceID); exists {
+			impact.Dependents[i].ServiceName = service.Name
+		}
+		impact.Dependents[i].Behavior = dependentBehavior(impact.Dependents[i], impact.ServiceName)
+	}
+	sort.SliceStable(impact.Dependents, func(i, j int) bool {
+		if impact.Dependents[i].ServiceName != impact.Dependents[j].ServiceName {
+			return impact.Dependents[i].ServiceName < impact.Dependents[j].ServiceName
+		}
+		return impact.Dependents[i].ProfileID < impact.Dependents[j].ProfileID
+	})
+
+	impact.Empty = len(impact.Profiles) == 0 && len(impact.Dependents) == 0 && len(impact.StoredData) == 0
+	if !impact.Empty {
+		impact.ConfirmationToken = deletionConfirmationToken(impact)
+	}
+	return impact, nil
+}
+
+// dependentBehavior describes what a dependent does once its dependency is
+// gone. Readiness waits skip dependencies that no longer exist, so the
+// dependent still starts; what it used the dependency for fails.
+func dependentBehavior(dependent models.ImpactedDependent, dependencyName string) string {
+	scope := ""
+	if dependent.ProfileID != "" {
+		scope = " in its profile"
+	}
+	if dependent.Required {
+		return fmt.Sprintf("Still starts%s but no longer waits for %s, which it requires; calls it makes to %s will fail", scope, dependencyName, dependencyName)
+	}
+	return fmt.Sprintf("Starts as before%s; calls it makes to the optional dependency %s will fail", scope, dependencyName)
+}
+
+// deletionConfirmationToken fingerprints what a deletion affects. It covers
+// the affected profiles, dependents and kinds of data but not their counts,
+// so logs written in the meantime do not invalidate it while a new dependent
+// does.
+func deletionConfirmationToken(impact *models.ServiceDeletionImpact) string {
+	parts := []string{impact.ServiceID}
+	for _, profile := range impact.Profiles {
+		parts = append(parts, "profile:"+profile.ID)
+	}
+	for _, dependent := range impact.Dependents {
+		parts = append(parts, "dependent:"+dependent.ProfileID+"/"+dependent.ServiceID)
+	}
+	for _, data := range impact.StoredData {
+		parts = append(parts, "data:"+data.Kind)
+	}
+	sort.Strings(parts[1:])
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
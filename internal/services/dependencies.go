@@ -35,7 +35,7 @@ func (dm *DependencyManager) InitializeDefaultDependencies() error {
 	// No default dependencies - users will configure through UI/API
 	services := dm.serviceManager.GetServices()
 	for i := range services {
-		service := &services[i]
+		service := services[i]
 		// Initialize with empty dependencies and no startup delay
 		service.Dependencies = []models.ServiceDependency{}
 		service.StartupDelay = 0
@@ -96,13 +96,13 @@ func (dm *DependencyManager) GetStartupOrder(serviceNames []string) ([]string, e
 	defer dm.mutex.RUnlock()
 
 	services := dm.serviceManager.GetServices()
-	serviceMap := make(map[string]models.Service)
+	serviceMap := make(map[string]*models.Service)
 	for _, service := range services {
 		serviceMap[service.Name] = service
 	}
 
 	// Filter requested services
-	var requestedServices []models.Service
+	var requestedServices []*models.Service
 	for _, name := range serviceNames {
 		if service, exists := serviceMap[name]; exists {
 			requestedServices = append(requestedServices, service)
@@ -119,7 +119,7 @@ func (dm *DependencyManager) GetStartupOrder(serviceNames []string) ([]string, e
 }
 
 // topologicalSort performs topological sorting on services based on dependencies
-func (dm *DependencyManager) topologicalSort(services []models.Service) ([]string, error) {
+func (dm *DependencyManager) topologicalSort(services []*models.Service) ([]string, error) {
 	// Build adjacency list and in-degree count
 	graph := make(map[string][]string)
 	inDegree := make(map[string]int)
@@ -185,7 +185,7 @@ func (dm *DependencyManager) CheckDependencies(ctx context.Context, serviceName
 	var targetService *models.Service
 	for i, service := range services {
 		if service.Name == serviceName {
-			targetService = &services[i]
+			targetService = services[i]
 			break
 		}
 	}
@@ -215,7 +215,7 @@ func (dm *DependencyManager) checkSingleDependency(ctx context.Context, dep mode
 
 	for i, service := range services {
 		if service.Name == dep.ServiceName {
-			depService = &services[i]
+			depService = services[i]
 			break
 		}
 	}
@@ -269,7 +269,7 @@ func (dm *DependencyManager) WaitForDependencies(ctx context.Context, serviceNam
 
 	for i, service := range services {
 		if service.Name == serviceName {
-			targetService = &services[i]
+			targetService = services[i]
 			break
 		}
 	}
@@ -380,7 +380,7 @@ func (dm *DependencyManager) ValidateDependencies() error {
 }
 
 // hasCycle detects cycles in the dependency graph using DFS
-func (dm *DependencyManager) hasCycle(serviceName string, services []models.Service, visited, recursionStack map[string]bool) bool {
+func (dm *DependencyManager) hasCycle(serviceName string, services []*models.Service, visited, recursionStack map[string]bool) bool {
 	visited[serviceName] = true
 	recursionStack[serviceName] = true
 
@@ -388,7 +388,7 @@ func (dm *DependencyManager) hasCycle(serviceName string, services []models.Serv
 	var currentService *models.Service
 	for i, service := range services {
 		if service.Name == serviceName {
-			currentService = &services[i]
+			currentService = services[i]
 			break
 		}
 	}
@@ -0,0 +1,341 @@
+// Package services - HTTP readiness probes of service dependencies
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	defaultDependencyTimeout       = 120 * time.Second
+	defaultDependencyRetryInterval = 5 * time.Second
+	readinessRequestTimeout        = 10 * time.Second
+	readinessBodyLimit             = 1 << 20
+)
+
+// jsonPathStep is one step of a JSONPath: an object key, an array index or
+// a wildcard over all keys or elements
+type jsonPathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the subset of JSONPath readiness probes use:
+// $.key.other[0].name, with [*] or .* as wildcards
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSONPath '%s' must start with $", path)
+	}
+
+	var steps []jsonPathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return nil, fmt.Errorf("JSONPath '%s' has an empty key", path)
+			}
+			steps = append(steps, jsonPathStep{key: key, wildcard: key == "*"})
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("JSONPath '%s' has an unclosed [", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			switch {
+			case inner == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, jsonPathStep{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("JSONPath '%s' has an invalid index [%s]", path, inner)
+				}
+				steps = append(steps, jsonPathStep{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("JSONPath '%s' is invalid at '%s'", path, rest)
+		}
+	}
+	return steps, nil
+}
+
+// evaluateJSONPath returns the values a parsed path selects from a document
+func evaluateJSONPath(document any, steps []jsonPathStep) []any {
+	values := []any{document}
+	for _, step := range steps {
+		var next []any
+		for _, value := range values {
+			switch typed := value.(type) {
+			case map[string]any:
+				if step.wildcard {
+					for _, child := range typed {
+						next = append(next, child)
+					}
+				} else if child, exists := typed[step.key]; exists && !step.isIndex {
+					next = append(next, child)
+				}
+			case []any:
+				switch {
+				case step.wildcard:
+					next = append(next, typed...)
+				case step.isIndex:
+					index := step.index
+					if index < 0 {
+						index += len(typed)
+					}
+					if index >= 0 && index < len(typed) {
+						next = append(next, typed[index])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// jsonValueString formats a selected value for comparison with JSONValue
+func jsonValueString(value any) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	default:
+		encoded, _ := json.Marshal(typed)
+		return string(encoded)
+	}
+}
+
+// parseDependencyReadiness decodes the "readiness" value of a dependency as
+// stored by the database; nil means the dependency has no probe
+func parseDependencyReadiness(value any) (*models.DependencyReadiness, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var probe models.DependencyReadiness
+	if err := json.Unmarshal(encoded, &probe); err != nil {
+		return nil, fmt.Errorf("invalid readiness probe: %v", err)
+	}
+	if strings.TrimSpace(probe.URL) == "" {
+		return nil, nil
+	}
+	return &probe, nil
+}
+
+// expandReadinessURL fills in the placeholders of a probe URL
+func expandReadinessURL(rawURL, serviceName string, dependencyPort int) string {
+	return strings.NewReplacer("{service}", url.PathEscape(serviceName), "{port}", strconv.Itoa(dependencyPort)).Replace(strings.TrimSpace(rawURL))
+}
+
+// validateDependencyReadiness checks a readiness probe before it is saved
+func validateDependencyReadiness(probe *models.DependencyReadiness) error {
+	parsed, err := url.Parse(expandReadinessURL(probe.URL, "service", 8080))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid readiness URL '%s'", probe.URL)
+	}
+	if probe.ExpectedStatus != 0 && (probe.ExpectedStatus < 100 || probe.ExpectedStatus > 599) {
+		return fmt.Errorf("invalid expected status %d", probe.ExpectedStatus)
+	}
+	if probe.JSONValue != "" && probe.JSONPath == "" {
+		return fmt.Errorf("jsonValue needs a jsonPath")
+	}
+	if probe.JSONPath != "" {
+		if _, err := parseJSONPath(probe.JSONPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateServiceDependencies checks the readiness probes of dependencies in
+// the form SaveServiceDependencies accepts
+func (sm *Manager) ValidateServiceDependencies(dependencies []any) error {
+	for i, dependency := range dependencies {
+		depMap, ok := dependency.(map[string]any)
+		if !ok {
+			continue
+		}
+		probe, err := parseDependencyReadiness(depMap["readiness"])
+		if err == nil && probe != nil {
+			err = validateDependencyReadiness(probe)
+		}
+		if err != nil {
+			return fmt.Errorf("dependency %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// probeDependencyReadiness runs a readiness probe once
+func probeDependencyReadiness(ctx context.Context, probe *models.DependencyReadiness, serviceName string, dependencyPort int) error {
+	probeURL := expandReadinessURL(probe.URL, serviceName, dependencyPort)
+
+	ctx, cancel := context.WithTimeout(ctx, readinessRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is not reachable", probeURL)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, readinessBodyLimit))
+
+	if probe.ExpectedStatus != 0 {
+		if resp.StatusCode != probe.ExpectedStatus {
+			return fmt.Errorf("%s returned status %d, expected %d", probeURL, resp.StatusCode, probe.ExpectedStatus)
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", probeURL, resp.StatusCode)
+	}
+
+	if probe.BodyContains != "" && !strings.Contains(string(body), probe.BodyContains) {
+		return fmt.Errorf("response of %s does not contain '%s'", probeURL, probe.BodyContains)
+	}
+
+	if probe.JSONPath != "" {
+		steps, err := parseJSONPath(probe.JSONPath)
+		if err != nil {
+			return err
+		}
+		var document any
+		if err := json.Unmarshal(body, &document); err != nil {
+			return fmt.Errorf("response of %s is not JSON", probeURL)
+		}
+		expected := strings.ReplaceAll(probe.JSONValue, "{service}", serviceName)
+		for _, value := range evaluateJSONPath(document, steps) {
+			if value == nil {
+				continue
+			}
+			if expected == "" || jsonValueString(value) == expected {
+				return nil
+			}
+		}
+		if expected == "" {
+			return fmt.Errorf("response of %s has nothing at %s", probeURL, probe.JSONPath)
+		}
+		return fmt.Errorf("response of %s has no '%s' at %s", probeURL, expected, probe.JSONPath)
+	}
+	return nil
+}
+
+// waitForDependencyReadiness runs before a service starts and waits until
+// the dependencies with a readiness probe are running and pass it. A
+// required dependency that is stopped or does not become ready in time
+// prevents the start.
+func (sm *Manager) waitForDependencyReadiness(ctx context.Context, service *models.Service) error {
+	dependencies, err := sm.db.LoadServiceDependencies(service.ID)
+	if err != nil {
+		sm.logHookOutput(service, "WARN", fmt.Sprintf("Could not load dependencies: %v", err))
+		return nil
+	}
+
+	for _, dependency := range dependencies {
+		probe, err := parseDependencyReadiness(dependency["readiness"])
+		if err != nil {
+			sm.logHookOutput(service, "WARN", fmt.Sprintf("Ignoring dependency: %v", err))
+			continue
+		}
+		if probe == nil {
+			continue
+		}
+		dependencyID, _ := dependency["serviceId"].(string)
+		depService, exists := sm.GetServiceByUUID(dependencyID)
+		if !exists {
+			continue
+		}
+		required, _ := dependency["required"].(bool)
+
+		timeout := defaultDependencyTimeout
+		if seconds, ok := dependency["timeoutSeconds"].(int); ok && seconds > 0 {
+			timeout = time.Duration(seconds) * time.Second
+		}
+		interval := defaultDependencyRetryInterval
+		if seconds, ok := dependency["retryIntervalSeconds"].(int); ok && seconds > 0 {
+			interval = time.Duration(seconds) * time.Second
+		}
+
+		err = sm.waitForReadinessProbe(ctx, service, depService, probe, timeout, interval)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("start of %s cancelled: %w", service.Name, ctx.Err())
+		}
+		if required {
+			return fmt.Errorf("dependency %s of %s is not ready: %w", depService.Name, service.Name, err)
+		}
+		sm.logHookOutput(service, "WARN", fmt.Sprintf("Starting without optional dependency %s: %v", depService.Name, err))
+	}
+	return nil
+}
+
+// waitForReadinessProbe polls a dependency's readiness probe until it
+// passes, the dependency stops or the timeout expires
+func (sm *Manager) waitForReadinessProbe(ctx context.Context, service, depService *models.Service, probe *models.DependencyReadiness, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	logged := false
+	for {
+		depService.Mutex.RLock()
+		status := depService.Status
+		port := depService.Port
+		depService.Mutex.RUnlock()
+
+		var err error
+		switch status {
+		case "stopped", "":
+			return fmt.Errorf("%s is not running", depService.Name)
+		case "running":
+			err = probeDependencyReadiness(ctx, probe, service.Name, port)
+			if err == nil {
+				if logged {
+					sm.logHookOutput(service, "INFO", fmt.Sprintf("Dependency %s is ready", depService.Name))
+				}
+				return nil
+			}
+		default:
+			err = fmt.Errorf("%s is %s", depService.Name, status)
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("gave up after %s: %v", timeout, err)
+		}
+		if !logged {
+			sm.logHookOutput(service, "INFO", fmt.Sprintf("Waiting for dependency %s to be ready: %v", depService.Name, err))
+			logged = true
+		}
+		if !sleepContext(ctx, interval) {
+			return ctx.Err()
+		}
+	}
+}
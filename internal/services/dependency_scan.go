@@ -0,0 +1,588 @@
+// Package services - Dependency license and vulnerability scans
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	dependencyScanTimeout      = time.Hour // The first Dependency-Check run downloads the NVD
+	dependencyScanHistoryLimit = 20        // Scans kept per service
+	dependencyScanOutputLines  = 200       // Lines of scanner output stored with a scan
+	dependencyCheckReportName  = "dependency-check-report.json"
+)
+
+// Dependency scans in progress, keyed by service UUID
+var (
+	activeDependencyScans      = make(map[string]*exec.Cmd)
+	activeDependencyScansMutex sync.Mutex
+)
+
+// ScanServiceDependencies starts a license and vulnerability scan of a
+// service's dependencies in the background and returns the new scan. The
+// finished scan is broadcast as a "dependency_scan" message.
+func (sm *Manager) ScanServiceDependencies(serviceUUID, projectsDir string, req models.DependencyScanRequest) (*models.DependencyScan, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	serviceName := service.Name
+	serviceDir := serviceWorkingDir(projectsDir, service)
+	buildSystem := service.BuildSystem
+	service.Mutex.RUnlock()
+
+	if _, err := os.Stat(serviceDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("service directory does not exist: %s", serviceDir)
+	}
+
+	effectiveBuildSystem := GetEffectiveBuildSystem(serviceDir, buildSystem)
+	scanner, err := pickDependencyScanner(req.Scanner, effectiveBuildSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	reportDir, err := os.MkdirTemp("", "vertex-scan-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+	cmdString := dependencyScanCommand(scanner, serviceDir, reportDir)
+	credentialEnv := map[string]string{}
+	if scanner == models.ScannerMavenPlugin {
+		cmdString, credentialEnv = sm.applyRepositoryCredentials(cmdString, effectiveBuildSystem, sm.getServiceProfileID(serviceUUID), serviceName)
+		cmdString = sm.applyBuildSettings(cmdString, effectiveBuildSystem, sm.getServiceProfileID(serviceUUID), serviceName)
+	}
+
+	runEnv, err := sm.testRunEnv(service)
+	if err != nil {
+		os.RemoveAll(reportDir)
+		return nil, err
+	}
+
+	cmd := exec.Command("bash", "-c", cmdString)
+	cmd.Dir = serviceDir
+	cmd.Env = runEnv
+	for key, value := range credentialEnv {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+	SetProcessGroup(cmd)
+
+	activeDependencyScansMutex.Lock()
+	if _, running := activeDependencyScans[serviceUUID]; running {
+		activeDependencyScansMutex.Unlock()
+		os.RemoveAll(reportDir)
+		return nil, fmt.Errorf("a dependency scan of service %s is already running", serviceName)
+	}
+	activeDependencyScans[serviceUUID] = cmd
+	activeDependencyScansMutex.Unlock()
+
+	scan := &models.DependencyScan{
+		ServiceID:   serviceUUID,
+		ServiceName: serviceName,
+		Scanner:     scanner,
+		Status:      models.DependencyScanRunning,
+		Command:     cmdString,
+		StartedAt:   time.Now(),
+		Licenses:    map[string]int{},
+	}
+
+	id, err := sm.db.InsertDependencyScan(scan)
+	if err != nil {
+		sm.finishActiveDependencyScan(serviceUUID)
+		os.RemoveAll(reportDir)
+		return nil, err
+	}
+	scan.ID = id
+
+	if err := sm.db.PruneDependencyScans(serviceUUID, dependencyScanHistoryLimit); err != nil {
+		log.Printf("[WARN] Failed to prune dependency scans of service %s: %v", serviceName, err)
+	}
+
+	// osv-scanner writes its report to stdout; the others write a file and
+	// log to stdout
+	var report bytes.Buffer
+	var output io.Reader
+	if scanner == models.ScannerOSV {
+		cmd.Stdout = &report
+		output, err = cmd.StderrPipe()
+	} else {
+		output, err = cmd.StdoutPipe()
+		cmd.Stderr = cmd.Stdout
+	}
+	if err != nil {
+		sm.finishActiveDependencyScan(serviceUUID)
+		os.RemoveAll(reportDir)
+		return nil, sm.failDependencyScan(scan, fmt.Errorf("failed to capture scanner output: %w", err))
+	}
+
+	log.Printf("[INFO] Scanning dependencies of service %s with command: %s", serviceName, cmdString)
+	if err := cmd.Start(); err != nil {
+		sm.finishActiveDependencyScan(serviceUUID)
+		os.RemoveAll(reportDir)
+		return nil, sm.failDependencyScan(scan, fmt.Errorf("failed to start %s: %w", scanner, err))
+	}
+
+	started := *scan
+	go sm.waitForDependencyScan(scan, cmd, output, &report, serviceDir, reportDir)
+	return &started, nil
+}
+
+// CancelDependencyScan stops the running dependency scan of a service
+func (sm *Manager) CancelDependencyScan(serviceUUID string) error {
+	activeDependencyScansMutex.Lock()
+	cmd, running := activeDependencyScans[serviceUUID]
+	activeDependencyScansMutex.Unlock()
+
+	if !running || cmd.Process == nil {
+		return fmt.Errorf("no dependency scan in progress for service UUID %s", serviceUUID)
+	}
+	return ForceKillProcessGroup(cmd.Process.Pid)
+}
+
+// GetDependencyScans returns the recent dependency scans of a service with
+// their severity summaries, newest first
+func (sm *Manager) GetDependencyScans(serviceUUID string, limit int) ([]models.DependencyScan, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	if limit <= 0 || limit > dependencyScanHistoryLimit {
+		limit = dependencyScanHistoryLimit
+	}
+	return sm.db.GetDependencyScans(serviceUUID, limit)
+}
+
+// GetDependencyScan returns a dependency scan with its findings and output
+func (sm *Manager) GetDependencyScan(serviceUUID string, scanID int64) (*models.DependencyScan, error) {
+	return sm.db.GetDependencyScan(serviceUUID, scanID)
+}
+
+// GetProfileScanSummary adds up the latest completed scan of every service
+// in a profile
+func (sm *Manager) GetProfileScanSummary(profileID string, serviceUUIDs []string) (*models.ProfileScanSummary, error) {
+	summary := &models.ProfileScanSummary{ProfileID: profileID, Services: []models.ServiceScanSummary{}}
+	for _, serviceUUID := range serviceUUIDs {
+		service, exists := sm.GetServiceByUUID(serviceUUID)
+		if !exists {
+			continue
+		}
+		entry := models.ServiceScanSummary{ServiceID: serviceUUID, ServiceName: service.Name}
+
+		latest, err := sm.db.GetLatestDependencyScan(serviceUUID)
+		if err != nil {
+			return nil, err
+		}
+		if latest != nil {
+			entry.ScanID = latest.ID
+			entry.Scanner = latest.Scanner
+			entry.ScannedAt = latest.FinishedAt
+			entry.Summary = latest.Summary
+
+			summary.Totals.Critical += latest.Summary.Critical
+			summary.Totals.High += latest.Summary.High
+			summary.Totals.Medium += latest.Summary.Medium
+			summary.Totals.Low += latest.Summary.Low
+			summary.Totals.Unknown += latest.Summary.Unknown
+			summary.Totals.Total += latest.Summary.Total
+		}
+		summary.Services = append(summary.Services, entry)
+	}
+
+	sort.Slice(summary.Services, func(i, j int) bool { return summary.Services[i].ServiceName < summary.Services[j].ServiceName })
+	return summary, nil
+}
+
+// pickDependencyScanner checks the requested scanner, or picks osv-scanner
+// or Dependency-Check when installed and the Maven plugin otherwise
+func pickDependencyScanner(requested string, buildSystem BuildSystemType) (string, error) {
+	switch requested {
+	case models.ScannerOSV, models.ScannerDependencyCheck:
+		if _, err := exec.LookPath(requested); err != nil {
+			return "", fmt.Errorf("%s is not installed or not on the PATH", requested)
+		}
+		return requested, nil
+	case models.ScannerMavenPlugin:
+		if buildSystem != BuildSystemMaven {
+			return "", fmt.Errorf("the Dependency-Check Maven plugin needs a Maven project")
+		}
+		return requested, nil
+	case "":
+		for _, scanner := range []string{models.ScannerOSV, models.ScannerDependencyCheck} {
+			if _, err := exec.LookPath(scanner); err == nil {
+				return scanner, nil
+			}
+		}
+		if buildSystem == BuildSystemMaven {
+			return models.ScannerMavenPlugin, nil
+		}
+		return "", fmt.Errorf("no dependency scanner found; install osv-scanner or OWASP Dependency-Check")
+	default:
+		return "", fmt.Errorf("invalid scanner '%s'; use %s, %s or %s", requested, models.ScannerOSV, models.ScannerDependencyCheck, models.ScannerMavenPlugin)
+	}
+}
+
+// dependencyScanCommand builds the scanner command. Dependency-Check writes
+// its JSON report into reportDir and never fails the build on findings.
+func dependencyScanCommand(scanner, serviceDir, reportDir string) string {
+	switch scanner {
+	case models.ScannerOSV:
+		return "osv-scanner --format json -r ."
+	case models.ScannerDependencyCheck:
+		return "dependency-check --project vertex --scan . --format JSON --out " + shellQuote(reportDir)
+	default:
+		command := "mvn"
+		if HasMavenWrapper(serviceDir) {
+			command = "./mvnw"
+		}
+		return command + " -B org.owasp:dependency-check-maven:check -Dformat=JSON -DfailBuildOnCVSS=11 -Dodc.outputDirectory=" + shellQuote(reportDir)
+	}
+}
+
+// waitForDependencyScan keeps the tail of the scanner output, then parses
+// and records the report
+func (sm *Manager) waitForDependencyScan(scan *models.DependencyScan, cmd *exec.Cmd, output io.Reader, report *bytes.Buffer, serviceDir, reportDir string) {
+	defer sm.finishActiveDependencyScan(scan.ServiceID)
+	defer os.RemoveAll(reportDir)
+
+	timer := time.AfterFunc(dependencyScanTimeout, func() {
+		log.Printf("[WARN] Dependency scan of service %s exceeded %s, stopping it", scan.ServiceName, dependencyScanTimeout)
+		if err := ForceKillProcessGroup(cmd.Process.Pid); err != nil {
+			log.Printf("[WARN] Failed to stop dependency scan of service %s: %v", scan.ServiceName, err)
+		}
+	})
+	defer timer.Stop()
+
+	var tail []string
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		tail = append(tail, scanner.Text())
+		if len(tail) > dependencyScanOutputLines {
+			tail = tail[1:]
+		}
+	}
+
+	waitErr := cmd.Wait()
+
+	finishedAt := time.Now()
+	scan.FinishedAt = &finishedAt
+	scan.DurationMs = finishedAt.Sub(scan.StartedAt).Milliseconds()
+	scan.Output = strings.Join(tail, "\n")
+
+	var parseErr error
+	switch scan.Scanner {
+	case models.ScannerOSV:
+		// osv-scanner exits with 1 when it finds vulnerabilities
+		var exitErr *exec.ExitError
+		if waitErr != nil && errors.As(waitErr, &exitErr) && exitErr.ExitCode() == 1 {
+			waitErr = nil
+		}
+		parseErr = parseOSVReport(report.Bytes(), scan)
+	default:
+		data, err := readDependencyCheckReport(serviceDir, reportDir)
+		if err == nil {
+			err = parseDependencyCheckReport(data, scan)
+		}
+		parseErr = err
+	}
+
+	switch {
+	case waitErr != nil:
+		scan.Status = models.DependencyScanError
+		scan.Error = fmt.Sprintf("Scanner failed: %v", waitErr)
+	case parseErr != nil:
+		scan.Status = models.DependencyScanError
+		scan.Error = fmt.Sprintf("Could not read the scan report: %v", parseErr)
+	default:
+		scan.Status = models.DependencyScanCompleted
+	}
+
+	if err := sm.db.UpdateDependencyScan(scan); err != nil {
+		log.Printf("[ERROR] Failed to store dependency scan of service %s: %v", scan.ServiceName, err)
+	}
+
+	log.Printf("[INFO] Dependency scan of service %s %s: %d dependencies, %d critical, %d high, %d medium, %d low, %d unknown in %s",
+		scan.ServiceName, scan.Status, scan.Dependencies, scan.Summary.Critical, scan.Summary.High, scan.Summary.Medium,
+		scan.Summary.Low, scan.Summary.Unknown, time.Duration(scan.DurationMs)*time.Millisecond)
+
+	broadcast := *scan
+	broadcast.Findings = nil
+	broadcast.Output = ""
+	sm.broadcastMessage(WebSocketMessage{Type: "dependency_scan", Payload: broadcast})
+}
+
+// failDependencyScan records a scan that could not be started
+func (sm *Manager) failDependencyScan(scan *models.DependencyScan, cause error) error {
+	finishedAt := time.Now()
+	scan.FinishedAt = &finishedAt
+	scan.Status = models.DependencyScanError
+	scan.Error = cause.Error()
+	if err := sm.db.UpdateDependencyScan(scan); err != nil {
+		log.Printf("[ERROR] Failed to store dependency scan of service %s: %v", scan.ServiceName, err)
+	}
+	return cause
+}
+
+func (sm *Manager) finishActiveDependencyScan(serviceUUID string) {
+	activeDependencyScansMutex.Lock()
+	delete(activeDependencyScans, serviceUUID)
+	activeDependencyScansMutex.Unlock()
+}
+
+// readDependencyCheckReport reads the JSON report from the report directory,
+// falling back to target/ for plugin versions that ignore the output setting
+func readDependencyCheckReport(serviceDir, reportDir string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(reportDir, dependencyCheckReportName))
+	if os.IsNotExist(err) {
+		data, err = os.ReadFile(filepath.Join(serviceDir, "target", dependencyCheckReportName))
+	}
+	return data, err
+}
+
+// addFinding counts a finding in the scan's severity summary
+func addFinding(scan *models.DependencyScan, finding models.DependencyFinding) {
+	switch finding.Severity {
+	case models.SeverityCritical:
+		scan.Summary.Critical++
+	case models.SeverityHigh:
+		scan.Summary.High++
+	case models.SeverityMedium:
+		scan.Summary.Medium++
+	case models.SeverityLow:
+		scan.Summary.Low++
+	default:
+		finding.Severity = models.SeverityUnknown
+		scan.Summary.Unknown++
+	}
+	scan.Summary.Total++
+	scan.Findings = append(scan.Findings, finding)
+}
+
+// normalizeSeverity maps a scanner's severity label, or failing that a CVSS
+// score, to one of the severities
+func normalizeSeverity(label string, score float64) string {
+	switch strings.ToUpper(strings.TrimSpace(label)) {
+	case "CRITICAL":
+		return models.SeverityCritical
+	case "HIGH":
+		return models.SeverityHigh
+	case "MEDIUM", "MODERATE":
+		return models.SeverityMedium
+	case "LOW":
+		return models.SeverityLow
+	}
+	switch {
+	case score >= 9:
+		return models.SeverityCritical
+	case score >= 7:
+		return models.SeverityHigh
+	case score >= 4:
+		return models.SeverityMedium
+	case score > 0:
+		return models.SeverityLow
+	}
+	return models.SeverityUnknown
+}
+
+type osvReport struct {
+	Results []struct {
+		Packages []struct {
+			Package struct {
+				Name      string `json:"name"`
+				Version   string `json:"version"`
+				Ecosystem string `json:"ecosystem"`
+			} `json:"package"`
+			Licenses        []string           `json:"licenses"`
+			Vulnerabilities []osvVulnerability `json:"vulnerabilities"`
+			Groups          []struct {
+				IDs         []string `json:"ids"`
+				MaxSeverity string   `json:"max_severity"` // CVSS score
+			} `json:"groups"`
+		} `json:"packages"`
+	} `json:"results"`
+}
+
+type osvVulnerability struct {
+	ID               string   `json:"id"`
+	Summary          string   `json:"summary"`
+	Aliases          []string `json:"aliases"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+	Affected []struct {
+		Ranges []struct {
+			Events []struct {
+				Fixed string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+	} `json:"affected"`
+}
+
+// parseOSVReport reads osv-scanner's JSON output. Vulnerabilities osv-scanner
+// groups as aliases of each other are reported once.
+func parseOSVReport(data []byte, scan *models.DependencyScan) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil // No lockfiles or manifests were found
+	}
+	var report osvReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return err
+	}
+
+	for _, result := range report.Results {
+		for _, pkg := range result.Packages {
+			scan.Dependencies++
+			for _, license := range pkg.Licenses {
+				scan.Licenses[license]++
+			}
+
+			reported := make(map[string]bool)
+			for _, vulnerability := range pkg.Vulnerabilities {
+				if reported[vulnerability.ID] {
+					continue
+				}
+				score := 0.0
+				for _, group := range pkg.Groups {
+					if !slices.Contains(group.IDs, vulnerability.ID) {
+						continue
+					}
+					score, _ = strconv.ParseFloat(group.MaxSeverity, 64)
+					for _, id := range group.IDs {
+						reported[id] = true
+					}
+				}
+				reported[vulnerability.ID] = true
+
+				// The CVSS score of the group beats the advisory's own label
+				label := vulnerability.DatabaseSpecific.Severity
+				if score > 0 {
+					label = ""
+				}
+				finding := models.DependencyFinding{
+					Package:         pkg.Package.Name,
+					Version:         pkg.Package.Version,
+					Ecosystem:       pkg.Package.Ecosystem,
+					VulnerabilityID: vulnerability.ID,
+					Aliases:         vulnerability.Aliases,
+					Severity:        normalizeSeverity(label, score),
+					Score:           score,
+					Summary:         vulnerability.Summary,
+				}
+			fixed:
+				for _, affected := range vulnerability.Affected {
+					for _, versionRange := range affected.Ranges {
+						for _, event := range versionRange.Events {
+							if event.Fixed != "" {
+								finding.FixedVersion = event.Fixed
+								break fixed
+							}
+						}
+					}
+				}
+				addFinding(scan, finding)
+			}
+		}
+	}
+	return nil
+}
+
+type dependencyCheckReport struct {
+	Dependencies []struct {
+		FileName string `json:"fileName"`
+		License  string `json:"license"`
+		Packages []struct {
+			ID string `json:"id"` // Package URL, e.g. pkg:maven/org.example/lib@1.0
+		} `json:"packages"`
+		Vulnerabilities []struct {
+			Name        string `json:"name"`
+			Severity    string `json:"severity"`
+			Description string `json:"description"`
+			CVSSv3      *struct {
+				BaseScore float64 `json:"baseScore"`
+			} `json:"cvssv3"`
+			CVSSv2 *struct {
+				Score float64 `json:"score"`
+			} `json:"cvssv2"`
+		} `json:"vulnerabilities"`
+	} `json:"dependencies"`
+}
+
+// parseDependencyCheckReport reads an OWASP Dependency-Check JSON report
+func parseDependencyCheckReport(data []byte, scan *models.DependencyScan) error {
+	var report dependencyCheckReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return err
+	}
+
+	for _, dependency := range report.Dependencies {
+		scan.Dependencies++
+		if license := strings.TrimSpace(dependency.License); license != "" {
+			scan.Licenses[license]++
+		}
+
+		name, version, ecosystem := dependency.FileName, "", ""
+		if len(dependency.Packages) > 0 {
+			name, version, ecosystem = parsePackageURL(dependency.Packages[0].ID, name)
+		}
+
+		for _, vulnerability := range dependency.Vulnerabilities {
+			score := 0.0
+			if vulnerability.CVSSv3 != nil {
+				score = vulnerability.CVSSv3.BaseScore
+			} else if vulnerability.CVSSv2 != nil {
+				score = vulnerability.CVSSv2.Score
+			}
+			summary := vulnerability.Description
+			if len(summary) > 500 {
+				summary = summary[:500] + "..."
+			}
+			addFinding(scan, models.DependencyFinding{
+				Package:         name,
+				Version:         version,
+				Ecosystem:       ecosystem,
+				VulnerabilityID: vulnerability.Name,
+				Severity:        normalizeSeverity(vulnerability.Severity, score),
+				Score:           score,
+				Summary:         summary,
+			})
+		}
+	}
+	return nil
+}
+
+// parsePackageURL splits pkg:maven/org.example/lib@1.0 into org.example:lib,
+// 1.0 and maven; anything else keeps the fallback name
+func parsePackageURL(purl, fallback string) (name, version, ecosystem string) {
+	rest, found := strings.CutPrefix(purl, "pkg:")
+	if !found {
+		return fallback, "", ""
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	ecosystem, rest, _ = strings.Cut(rest, "/")
+	rest, version, _ = strings.Cut(rest, "@")
+	name = strings.ReplaceAll(rest, "/", ":")
+	if ecosystem == "npm" {
+		name = rest
+	}
+	if name == "" {
+		name = fallback
+	}
+	return name, version, ecosystem
+}
@@ -0,0 +1,316 @@
+// Package services - Profile starts that wait for each service's dependencies
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// orderByDependencies sorts services so each one comes after the services it
+// depends on; ties and cycles fall back to the order field
+func orderByDependencies(services []*models.Service, dependencies map[string][]map[string]any) []*models.Service {
+	remaining := make([]*models.Service, len(services))
+	copy(remaining, services)
+	sort.SliceStable(remaining, func(i, j int) bool {
+		return remaining[i].Order < remaining[j].Order
+	})
+
+	pending := make(map[string]bool, len(remaining))
+	for _, service := range remaining {
+		pending[service.ID] = true
+	}
+
+	ordered := make([]*models.Service, 0, len(remaining))
+	for len(remaining) > 0 {
+		next := -1
+		for i, service := range remaining {
+			blocked := false
+			for _, dependency := range dependencies[service.ID] {
+				if dependencyID, _ := dependency["serviceId"].(string); dependencyID != service.ID && pending[dependencyID] {
+					blocked = true
+					break
+				}
+			}
+			if !blocked {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			log.Printf("[WARN] Dependency cycle around %s; starting it by order", remaining[0].Name)
+			next = 0
+		}
+
+		service := remaining[next]
+		ordered = append(ordered, service)
+		delete(pending, service.ID)
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return ordered
+}
+
+// dependencyWaitSettings returns the timeout and retry interval stored for a
+// dependency, or the defaults when they are not set
+func dependencyWaitSettings(dependency map[string]any) (time.Duration, time.Duration) {
+	timeout := defaultDependencyTimeout
+	if seconds, ok := dependency["timeoutSeconds"].(int); ok && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	interval := defaultDependencyRetryInterval
+	if seconds, ok := dependency["retryIntervalSeconds"].(int); ok && seconds > 0 {
+		interval = time.Duration(seconds) * time.Second
+	}
+	return timeout, interval
+}
+
+// waitForDependencyHealth polls a dependency until it runs and, with
+// healthCheck, passes its health check. It gives up at once when the
+// dependency is stopped and otherwise when the timeout expires.
+func (sm *Manager) waitForDependencyHealth(ctx context.Context, service, depService *models.Service, healthCheck bool, timeout, interval time.Duration) (int, string, error) {
+	deadline := time.Now().Add(timeout)
+	attempts := 0
+	logged := false
+	for {
+		attempts++
+		depService.Mutex.RLock()
+		status := depService.Status
+		depService.Mutex.RUnlock()
+
+		var err error
+		switch status {
+		case "stopped", "":
+			return attempts, models.DependencyNotRunning, fmt.Errorf("%s is not running", depService.Name)
+		case "running":
+			if !healthCheck {
+				return attempts, models.DependencyReady, nil
+			}
+			sm.checkServiceHealth(ctx, depService)
+			depService.Mutex.RLock()
+			health := depService.HealthStatus
+			depService.Mutex.RUnlock()
+			// "running" answers on its port but has no usable health endpoint
+			if health == "healthy" || health == "running" {
+				if logged {
+					sm.logHookOutput(service, "INFO", fmt.Sprintf("Dependency %s is healthy", depService.Name))
+				}
+				return attempts, models.DependencyReady, nil
+			}
+			err = fmt.Errorf("%s is %s", depService.Name, health)
+		default:
+			err = fmt.Errorf("%s is %s", depService.Name, status)
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return attempts, models.DependencyTimedOut, fmt.Errorf("gave up after %s: %v", timeout, err)
+		}
+		if !logged {
+			sm.logHookOutput(service, "INFO", fmt.Sprintf("Waiting for dependency %s: %v", depService.Name, err))
+			logged = true
+		}
+		// The last attempt runs at the deadline
+		if !sleepContext(ctx, min(interval, remaining)) {
+			return attempts, models.DependencyCancelled, ctx.Err()
+		}
+	}
+}
+
+// waitForStartupDependencies waits for each dependency of a service with the
+// dependency's own timeout and retry interval. failed holds the services
+// that did not start earlier in the same profile start; a required
+// dependency among them, or one that does not become ready, fails the wait.
+func (sm *Manager) waitForStartupDependencies(ctx context.Context, service *models.Service, dependencies []map[string]any, failed map[string]bool) ([]models.DependencyWaitResult, error) {
+	results := []models.DependencyWaitResult{}
+	for _, dependency := range dependencies {
+		dependencyID, _ := dependency["serviceId"].(string)
+		depService, exists := sm.GetServiceByUUID(dependencyID)
+		if !exists || dependencyID == service.ID {
+			continue
+		}
+		required, _ := dependency["required"].(bool)
+		healthCheck, _ := dependency["healthCheck"].(bool)
+		timeout, interval := dependencyWaitSettings(dependency)
+		result := models.DependencyWaitResult{
+			ServiceID:            dependencyID,
+			ServiceName:          depService.Name,
+			Required:             required,
+			TimeoutSeconds:       int(timeout / time.Second),
+			RetryIntervalSeconds: int(interval / time.Second),
+		}
+
+		start := time.Now()
+		var err error
+		switch {
+		case failed[dependencyID]:
+			result.Outcome = models.DependencyFailed
+			err = fmt.Errorf("%s did not start", depService.Name)
+		default:
+			probe, probeErr := parseDependencyReadiness(dependency["readiness"])
+			if probeErr != nil {
+				sm.logHookOutput(service, "WARN", fmt.Sprintf("Ignoring readiness probe of %s: %v", depService.Name, probeErr))
+			}
+			if probe != nil {
+				result.Attempts = 1
+				err = sm.waitForReadinessProbe(ctx, service, depService, probe, timeout, interval)
+				switch {
+				case err == nil:
+					result.Outcome = models.DependencyReady
+				case ctx.Err() != nil:
+					result.Outcome = models.DependencyCancelled
+				case !isServiceActive(depService):
+					result.Outcome = models.DependencyNotRunning
+				default:
+					result.Outcome = models.DependencyTimedOut
+				}
+			} else {
+				result.Attempts, result.Outcome, err = sm.waitForDependencyHealth(ctx, service, depService, healthCheck, timeout, interval)
+			}
+		}
+		result.WaitedMs = time.Since(start).Milliseconds()
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+
+		if ctx.Err() != nil {
+			return results, fmt.Errorf("start of %s cancelled: %w", service.Name, ctx.Err())
+		}
+		if err == nil {
+			continue
+		}
+		if required {
+			return results, fmt.Errorf("required dependency %s is not ready: %v", depService.Name, err)
+		}
+		sm.logHookOutput(service, "WARN", fmt.Sprintf("Starting without optional dependency %s: %v", depService.Name, err))
+	}
+	return results, nil
+}
+
+// isServiceActive reports whether a service is running or on its way there
+func isServiceActive(service *models.Service) bool {
+	service.Mutex.RLock()
+	defer service.Mutex.RUnlock()
+	return service.Status != "stopped" && service.Status != ""
+}
+
+// startServicesInDependencyOrder starts services one by one, each after its
+// dependencies are ready, and records the start in the startup report. A
+// service whose required dependency is not ready is not started.
+func (sm *Manager) startServicesInDependencyOrder(ctx context.Context, services []*models.Service, projectsDir string) *models.StartupReport {
+	dependencies := make(map[string][]map[string]any, len(services))
+	for _, service := range services {
+		serviceDependencies, err := sm.db.LoadServiceDependencies(service.ID)
+		if err != nil {
+			log.Printf("[WARN] Could not load dependencies of %s: %v", service.Name, err)
+			continue
+		}
+		dependencies[service.ID] = serviceDependencies
+	}
+	ordered := orderByDependencies(services, dependencies)
+
+	report := &models.StartupReport{StartedAt: time.Now(), Status: "running"}
+	for _, service := range ordered {
+		report.Services = append(report.Services, models.ServiceStartupResult{
+			ServiceID:    service.ID,
+			ServiceName:  service.Name,
+			Outcome:      "pending",
+			Dependencies: []models.DependencyWaitResult{},
+		})
+	}
+	sm.setStartupReport(report)
+
+	failed := make(map[string]bool)
+	for i, service := range ordered {
+		result := &report.Services[i]
+		if ctx.Err() != nil {
+			break
+		}
+
+		service.Mutex.RLock()
+		status := service.Status
+		isEnabled := service.IsEnabled
+		service.Mutex.RUnlock()
+
+		switch {
+		case status == "running":
+			log.Printf("[INFO] Service %s (order %d) is already running, skipping", service.Name, service.Order)
+			result.Outcome = "already-running"
+		case !isEnabled:
+			log.Printf("[INFO] Service %s (order %d) is disabled, skipping", service.Name, service.Order)
+			result.Outcome = "disabled"
+			failed[service.ID] = true
+		default:
+			waits, err := sm.waitForStartupDependencies(ctx, service, dependencies[service.ID], failed)
+			result.Dependencies = waits
+			if err != nil {
+				log.Printf("[ERROR] Not starting service %s (profile): %v", service.Name, err)
+				result.Outcome = "skipped"
+				result.Error = err.Error()
+				failed[service.ID] = true
+				break
+			}
+
+			log.Printf("[INFO] Starting service %s (order %d) in profile", service.Name, service.Order)
+			if err := sm.startServiceOp(ctx, service, projectsDir); err != nil {
+				log.Printf("[ERROR] Failed to start service %s (profile): %v", service.Name, err)
+				result.Outcome = "failed"
+				result.Error = err.Error()
+				failed[service.ID] = true
+				break
+			}
+			result.Outcome = "started"
+		}
+		sm.setStartupReport(report)
+	}
+
+	finished := time.Now()
+	report.FinishedAt = &finished
+	switch {
+	case ctx.Err() != nil:
+		report.Status = "cancelled"
+	case hasFailedStart(report):
+		report.Status = "failed"
+	default:
+		report.Status = "completed"
+	}
+	sm.setStartupReport(report)
+	log.Printf("[INFO] Profile start %s in %s", report.Status, finished.Sub(report.StartedAt).Round(time.Second))
+	return sm.GetStartupReport()
+}
+
+// hasFailedStart reports whether a service of a start failed or was skipped
+// for a dependency; disabled services do not count
+func hasFailedStart(report *models.StartupReport) bool {
+	for _, service := range report.Services {
+		if service.Outcome == "failed" || service.Outcome == "skipped" {
+			return true
+		}
+	}
+	return false
+}
+
+// setStartupReport publishes a copy of a startup report in progress
+func (sm *Manager) setStartupReport(report *models.StartupReport) {
+	clone := *report
+	clone.Services = make([]models.ServiceStartupResult, len(report.Services))
+	for i, service := range report.Services {
+		service.Dependencies = append([]models.DependencyWaitResult{}, service.Dependencies...)
+		clone.Services[i] = service
+	}
+
+	sm.startupReportMutex.Lock()
+	sm.startupReport = &clone
+	sm.startupReportMutex.Unlock()
+}
+
+// GetStartupReport returns the latest profile start, or nil before the first
+func (sm *Manager) GetStartupReport() *models.StartupReport {
+	sm.startupReportMutex.Lock()
+	defer sm.startupReportMutex.Unlock()
+	return sm.startupReport
+}
@@ -0,0 +1,154 @@
+// Package services - Service discovery variables for the other services of a profile
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// discoveryHost is the host other services are reachable on; Vertex runs
+// every service on this machine
+const discoveryHost = "localhost"
+
+var discoveryNameSeparators = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// DiscoveryEnv describes the service discovery variables a service receives
+type DiscoveryEnv struct {
+	Prefix    string            `json:"prefix"`    // Prefix the service itself is known by to others
+	Skipped   bool              `json:"skipped"`   // The service opted out
+	Variables map[string]string `json:"variables"` // Variables injected on start
+}
+
+// DiscoveryEnvPrefix returns the variable prefix of a service: its name in
+// upper case with every run of other characters replaced by an underscore,
+// e.g. "user-service" becomes USER_SERVICE
+func DiscoveryEnvPrefix(name string) string {
+	prefix := strings.Trim(discoveryNameSeparators.ReplaceAllString(strings.ToUpper(name), "_"), "_")
+	if prefix != "" && prefix[0] >= '0' && prefix[0] <= '9' {
+		prefix = "_" + prefix
+	}
+	return prefix
+}
+
+// profileServiceUUIDs returns the services of a profile
+func (sm *Manager) profileServiceUUIDs(profileID string) ([]string, error) {
+	var servicesJSON string
+	if err := sm.db.QueryRow(`SELECT services_json FROM service_profiles WHERE id = ?`, profileID).Scan(&servicesJSON); err != nil {
+		return nil, fmt.Errorf("failed to load profile %s: %w", profileID, err)
+	}
+
+	var serviceUUIDs []string
+	if err := json.Unmarshal([]byte(servicesJSON), &serviceUUIDs); err != nil {
+		return nil, fmt.Errorf("failed to parse services of profile %s: %w", profileID, err)
+	}
+	return serviceUUIDs, nil
+}
+
+// GetDiscoveryEnv returns the variables injected into a service on start:
+// <NAME>_HOST, <NAME>_PORT and <NAME>_URL for every other service with a port
+// in the profile it runs under. The URL of infrastructure services is their
+// connection URL, such as redis://localhost:6379. Services outside any
+// profile get none.
+func (sm *Manager) GetDiscoveryEnv(serviceUUID string) (*DiscoveryEnv, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	env := &DiscoveryEnv{Prefix: DiscoveryEnvPrefix(service.Name), Skipped: service.SkipDiscovery}
+	service.Mutex.RUnlock()
+
+	if env.Skipped {
+		env.Variables = map[string]string{}
+		return env, nil
+	}
+	env.Variables = sm.discoveryVariables(sm.getServiceProfileID(serviceUUID), serviceUUID)
+	return env, nil
+}
+
+// discoveryVariables builds the discovery variables of the profile's services
+// other than serviceUUID. When two services map to the same prefix, the one
+// started first by order keeps it and the other is left out.
+func (sm *Manager) discoveryVariables(profileID, serviceUUID string) map[string]string {
+	variables := make(map[string]string)
+	if profileID == "" {
+		return variables
+	}
+
+	serviceUUIDs, err := sm.profileServiceUUIDs(profileID)
+	if err != nil {
+		log.Printf("[WARN] Skipping service discovery variables: %v", err)
+		return variables
+	}
+
+	type peer struct {
+		name      string
+		port      int
+		order     int
+		infraType string
+	}
+	var peers []peer
+	for _, peerUUID := range serviceUUIDs {
+		if peerUUID == serviceUUID {
+			continue
+		}
+		other, exists := sm.GetServiceByUUID(peerUUID)
+		if !exists {
+			continue
+		}
+		other.Mutex.RLock()
+		if other.Port > 0 {
+			peers = append(peers, peer{name: other.Name, port: other.Port, order: other.Order, infraType: other.InfraType})
+		}
+		other.Mutex.RUnlock()
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].order != peers[j].order {
+			return peers[i].order < peers[j].order
+		}
+		return peers[i].name < peers[j].name
+	})
+
+	claimed := make(map[string]string)
+	for _, p := range peers {
+		prefix := DiscoveryEnvPrefix(p.name)
+		if prefix == "" {
+			continue
+		}
+		if owner, taken := claimed[prefix]; taken {
+			log.Printf("[WARN] Services %s and %s share the discovery prefix %s; only %s is exported", owner, p.name, prefix, owner)
+			continue
+		}
+		claimed[prefix] = p.name
+
+		port := strconv.Itoa(p.port)
+		variables[prefix+"_HOST"] = discoveryHost
+		variables[prefix+"_PORT"] = port
+		variables[prefix+"_URL"] = "http://" + discoveryHost + ":" + port
+		if p.infraType != "" {
+			// Infrastructure is reached through its own protocol
+			variables[prefix+"_URL"] = InfraConnectionURL(p.infraType, p.port)
+		}
+	}
+	return variables
+}
+
+// serviceDiscoveryEnv returns the discovery variables to inject when starting
+// a service, or none when it opted out
+func (sm *Manager) serviceDiscoveryEnv(service *models.Service) map[string]string {
+	service.Mutex.RLock()
+	skip := service.SkipDiscovery
+	service.Mutex.RUnlock()
+	if skip {
+		return nil
+	}
+	return sm.discoveryVariables(sm.getServiceProfileID(service.ID), service.ID)
+}
@@ -0,0 +1,353 @@
+// Package services - The environment a service process starts with
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// serviceEnvEntry is one KEY=VALUE of a service's environment with where it
+// came from
+type serviceEnvEntry struct {
+	name   string
+	value  string
+	source string
+	secret bool // The value may be a secret reference
+	hidden bool // The value is never shown, e.g. a repository password
+}
+
+// serviceEnv is the environment of a service process in the order it is
+// built. A name set more than once takes its last value, as with exec.Cmd.
+type serviceEnv struct {
+	entries []serviceEnvEntry
+}
+
+func (e *serviceEnv) set(name, value, source string) {
+	e.entries = append(e.entries, serviceEnvEntry{name: name, value: value, source: source})
+}
+
+// setSecret adds a variable whose value may be a secret reference
+func (e *serviceEnv) setSecret(name, value, source string) {
+	e.entries = append(e.entries, serviceEnvEntry{name: name, value: value, source: source, secret: true})
+}
+
+// setHidden adds a variable whose value is not shown by effective
+func (e *serviceEnv) setHidden(name, value, source string) {
+	e.entries = append(e.entries, serviceEnvEntry{name: name, value: value, source: source, hidden: true})
+}
+
+// lookup returns the final value of a variable, as stored
+func (e *serviceEnv) lookup(name string) (string, bool) {
+	for i := len(e.entries) - 1; i >= 0; i-- {
+		if e.entries[i].name == name {
+			return e.entries[i].value, true
+		}
+	}
+	return "", false
+}
+
+// environ returns the entries for exec.Cmd.Env with secret references
+// replaced by their resolved values
+func (e *serviceEnv) environ(secrets resolvedSecrets) []string {
+	env := make([]string, 0, len(e.entries))
+	for _, entry := range e.entries {
+		value := entry.value
+		if entry.secret {
+			value = secrets.value(value)
+		}
+		env = append(env, entry.name+"="+value)
+	}
+	return env
+}
+
+// effective returns the final value of each variable, sorted by name, with
+// the values it replaced. Secret references are shown as they are stored;
+// other hidden values are redacted.
+func (e *serviceEnv) effective() []models.EffectiveEnvVar {
+	shown := func(entry serviceEnvEntry) (string, bool) {
+		if entry.hidden {
+			return redactedSecretValue, true
+		}
+		return entry.value, entry.secret && isSecretReference(entry.value)
+	}
+
+	byName := make(map[string]*models.EffectiveEnvVar)
+	var names []string
+	for _, entry := range e.entries {
+		value, secret := shown(entry)
+		variable, exists := byName[entry.name]
+		if !exists {
+			byName[entry.name] = &models.EffectiveEnvVar{Name: entry.name, Value: value, Source: entry.source, Secret: secret}
+			names = append(names, entry.name)
+			continue
+		}
+		if variable.Value == value && variable.Source == entry.source {
+			continue
+		}
+		variable.Overridden = append(variable.Overridden, models.EnvVarOverride{Value: variable.Value, Source: variable.Source, Secret: variable.Secret})
+		variable.Value, variable.Source, variable.Secret = value, entry.source, secret
+	}
+
+	sort.Strings(names)
+	variables := make([]models.EffectiveEnvVar, 0, len(names))
+	for _, name := range names {
+		variables = append(variables, *byName[name])
+	}
+	return variables
+}
+
+// sortedEnvKeys returns the keys of a map of variables in a stable order
+func sortedEnvKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// secretEnvNameParts mark inherited variables whose values are masked when
+// the environment is shown, such as Vertex's own JWT_SECRET
+var secretEnvNameParts = []string{"SECRET", "PASSWORD", "PASSWD", "TOKEN", "CREDENTIAL", "PRIVATE_KEY", "API_KEY"}
+
+// isSecretEnvName reports whether a variable name looks like it holds a secret
+func isSecretEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, part := range secretEnvNameParts {
+		if strings.Contains(upper, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildServiceEnv assembles the environment of a service process. The caller
+// holds the service mutex. Precedence, lowest first: Vertex's own environment
+// without JAVA_HOME and PATH, the Java home, discovery variables, global
+// variables, the service's variables, its Eureka overrides and the
+// repository credentials. The Java home is the one the profile pins the
+// service to (profileJavaHome), else the service's JAVA_HOME, else the
+// profile's Java home override. A required service variable left empty is a
+// placeholder for a global or profile value.
+func (sm *Manager) buildServiceEnv(service *models.Service, profileJavaHome string, globalEnvVars, discoveryEnv, credentialEnv map[string]string) *serviceEnv {
+	env := &serviceEnv{}
+
+	// Start with current environment, but filter out JAVA_HOME and PATH to avoid conflicts.
+	// Values that look like secrets are passed on but masked when shown.
+	for _, entry := range os.Environ() {
+		if strings.HasPrefix(entry, "JAVA_HOME=") || strings.HasPrefix(entry, "PATH=") {
+			continue
+		}
+		name, value, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		if isSecretEnvName(name) {
+			env.setHidden(name, value, models.EnvSourceInherited)
+		} else {
+			env.set(name, value, models.EnvSourceInherited)
+		}
+	}
+
+	// Create a map to track which variables are set by service
+	serviceEnvKeys := make(map[string]bool)
+	for key, envVar := range service.EnvVars {
+		if isEnvVarPlaceholder(key, envVar, globalEnvVars) {
+			continue
+		}
+		serviceEnvKeys[key] = true
+	}
+
+	// Determine which JAVA_HOME to use and set PATH accordingly
+	var finalJavaHome string
+	javaHomeSource := models.EnvSourceJavaHome
+	if profileJavaHome != "" {
+		// The Java version the profile pins this service to
+		finalJavaHome = profileJavaHome
+		javaHomeSource = models.EnvSourceJavaVersion
+	} else if serviceEnvKeys["JAVA_HOME"] {
+		// Service-specific JAVA_HOME takes priority over the profile-wide override
+		finalJavaHome = service.EnvVars["JAVA_HOME"].Value
+		javaHomeSource = models.EnvSourceService
+	} else if sm.config.JavaHomeOverride != "" {
+		// Profile Java Home override
+		finalJavaHome = sm.config.JavaHomeOverride
+	}
+
+	// Set JAVA_HOME and PATH if we have a Java home
+	if finalJavaHome != "" {
+		env.set("JAVA_HOME", finalJavaHome, javaHomeSource)
+		env.set("PATH", fmt.Sprintf("%s/bin:%s", finalJavaHome, os.Getenv("PATH")), javaHomeSource)
+	} else {
+		// No Java home override, use system PATH
+		env.set("PATH", os.Getenv("PATH"), models.EnvSourceInherited)
+	}
+
+	// Add service discovery variables first so global and service variables override them
+	for _, key := range sortedEnvKeys(discoveryEnv) {
+		env.set(key, discoveryEnv[key], models.EnvSourceDiscovery)
+	}
+
+	// Add global environment variables (only if not overridden by service)
+	for _, key := range sortedEnvKeys(globalEnvVars) {
+		if !serviceEnvKeys[key] && key != "JAVA_HOME" { // Skip JAVA_HOME as we handled it above
+			env.setSecret(key, globalEnvVars[key], models.EnvSourceGlobal)
+		}
+	}
+
+	// Add service-specific environment variables (these take precedence)
+	for _, key := range sortedEnvKeys(service.EnvVars) {
+		envVar := service.EnvVars[key]
+		if !serviceEnvKeys[key] {
+			continue
+		}
+		// Skip JAVA_HOME as we already handled it above
+		if key != "JAVA_HOME" {
+			env.setSecret(key, envVar.Value, models.EnvSourceService)
+		}
+		// Also set SPRING_PROFILES_ACTIVE for Spring Boot if ACTIVE_PROFILE is set
+		if key == "ACTIVE_PROFILE" {
+			env.set("SPRING_PROFILES_ACTIVE", envVar.Value, models.EnvSourceService)
+		}
+	}
+
+	// Ensure ACTIVE_PROFILE and SPRING_PROFILES_ACTIVE are set if not already set by service
+	if activeProfile, exists := globalEnvVars["ACTIVE_PROFILE"]; exists {
+		if !serviceEnvKeys["ACTIVE_PROFILE"] {
+			env.set("ACTIVE_PROFILE", activeProfile, models.EnvSourceGlobal)
+			env.set("SPRING_PROFILES_ACTIVE", activeProfile, models.EnvSourceGlobal)
+		}
+	}
+
+	// Inject Eureka overrides as environment variables.
+	// Env vars are inherited by forked JVMs and would normally have higher priority than config-server.
+	// However, Spring Cloud Config defaults to override-system-properties=true, which places config-server
+	// properties ABOVE env vars. We counteract this by also setting
+	// SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES=false — a client-side bootstrap property read before
+	// the config server is consulted, so the config server cannot override our env vars.
+	if service.EurekaPreferIPAddress != nil || service.EurekaHostname != "" {
+		env.set("SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES", "false", models.EnvSourceEureka)
+	}
+	if service.EurekaPreferIPAddress != nil {
+		val := "false"
+		if *service.EurekaPreferIPAddress {
+			val = "true"
+		}
+		env.set("EUREKA_INSTANCE_PREFERIPADDRESS", val, models.EnvSourceEureka)
+	}
+	if service.EurekaHostname != "" {
+		env.set("EUREKA_INSTANCE_HOSTNAME", service.EurekaHostname, models.EnvSourceEureka)
+	}
+
+	for _, key := range sortedEnvKeys(credentialEnv) {
+		env.setHidden(key, credentialEnv[key], models.EnvSourceCredentials)
+	}
+
+	return env
+}
+
+// isEnvVarPlaceholder reports whether a service variable is required but left
+// empty for a global or profile variable of the same name to fill in
+func isEnvVarPlaceholder(name string, envVar models.EnvVar, globalEnvVars map[string]string) bool {
+	if !envVar.IsRequired || envVar.Value != "" {
+		return false
+	}
+	_, filled := globalEnvVars[name]
+	return filled
+}
+
+// assembleServiceEnv builds the environment a service would start with right
+// now, with secret references unresolved, and returns the global variables it
+// used. credentialEnv holds the repository credentials of the command it is
+// for, if any.
+func (sm *Manager) assembleServiceEnv(service *models.Service, credentialEnv map[string]string) (*serviceEnv, map[string]string, error) {
+	globalEnvVars, err := sm.GetGlobalEnvVars()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load global environment variables: %w", err)
+	}
+	discoveryEnv := sm.serviceDiscoveryEnv(service)
+	profileJavaHome := sm.profileJavaHome(sm.getServiceProfileID(service.ID), service.ID)
+
+	service.Mutex.RLock()
+	env := sm.buildServiceEnv(service, profileJavaHome, globalEnvVars, discoveryEnv, credentialEnv)
+	service.Mutex.RUnlock()
+	return env, globalEnvVars, nil
+}
+
+// GetEffectiveEnv returns the environment a service would start with right
+// now and where each value comes from, without starting it. Secret
+// references are not resolved and repository credentials are hidden.
+// Vertex's own environment is only listed with inherited, with values that
+// look like secrets masked.
+func (sm *Manager) GetEffectiveEnv(serviceUUID string, inherited bool) (*models.EffectiveEnv, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	profileID := sm.getServiceProfileID(service.ID)
+
+	service.Mutex.RLock()
+	infraType := service.InfraType
+	serviceDir := serviceWorkingDir(sm.config.ProjectsDir, service)
+	buildSystem := service.BuildSystem
+	service.Mutex.RUnlock()
+
+	credentialEnv := make(map[string]string)
+	if infraType == "" {
+		_, credentialEnv = sm.applyRepositoryCredentials("", GetEffectiveBuildSystem(serviceDir, buildSystem), profileID, service.Name)
+	}
+
+	env, _, err := sm.assembleServiceEnv(service, credentialEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	// Profile variables are stored as global ones when the profile is applied
+	profileEnvVars := sm.profileEnvVars(profileID)
+
+	result := &models.EffectiveEnv{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		ProfileID:   profileID,
+		Variables:   []models.EffectiveEnvVar{},
+	}
+	for _, variable := range env.effective() {
+		if !inherited {
+			if variable.Source == models.EnvSourceInherited {
+				continue
+			}
+			variable.Overridden = slices.DeleteFunc(variable.Overridden, func(override models.EnvVarOverride) bool {
+				return override.Source == models.EnvSourceInherited
+			})
+		}
+		if variable.Source == models.EnvSourceGlobal {
+			if value, exists := profileEnvVars[variable.Name]; exists && value == variable.Value {
+				variable.Source = models.EnvSourceProfile
+			}
+		}
+		result.Variables = append(result.Variables, variable)
+	}
+	return result, nil
+}
+
+// profileEnvVars returns the variables of a profile, which applying it
+// copies into the global ones
+func (sm *Manager) profileEnvVars(profileID string) map[string]string {
+	envVars := make(map[string]string)
+	if profileID == "" {
+		return envVars
+	}
+	var envVarsJSON string
+	if err := sm.db.QueryRow(`SELECT COALESCE(env_vars_json, '') FROM service_profiles WHERE id = ?`, profileID).Scan(&envVarsJSON); err != nil || envVarsJSON == "" {
+		return envVars
+	}
+	json.Unmarshal([]byte(envVarsJSON), &envVars)
+	return envVars
+}
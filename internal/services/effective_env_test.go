@@ -0,0 +1,88 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func TestBuildServiceEnv_InheritedSecretsMasked(t *testing.T) {
+	t.Setenv("JWT_SECRET", "vertex-signing-key")
+	t.Setenv("VERTEX_TEST_REGION", "eu-west-1")
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	env := sm.buildServiceEnv(service, "", nil, nil, nil)
+
+	// The child process gets every inherited value
+	environ := env.environ(nil)
+	for _, want := range []string{"JWT_SECRET=vertex-signing-key", "VERTEX_TEST_REGION=eu-west-1"} {
+		if !slices.Contains(environ, want) {
+			t.Errorf("Expected %s in the process environment", want)
+		}
+	}
+
+	// Only the secret is masked when the environment is shown
+	shown := make(map[string]models.EffectiveEnvVar)
+	for _, variable := range env.effective() {
+		shown[variable.Name] = variable
+	}
+	if got := shown["JWT_SECRET"].Value; got != redactedSecretValue {
+		t.Errorf("JWT_SECRET shown as %q want %q", got, redactedSecretValue)
+	}
+	if got := shown["VERTEX_TEST_REGION"]; got.Value != "eu-west-1" || got.Source != models.EnvSourceInherited {
+		t.Errorf("Expected the inherited value to be shown, got %+v", got)
+	}
+}
+
+func TestIsSecretEnvName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"JWT_SECRET", true},
+		{"DB_PASSWORD", true},
+		{"github_token", true},
+		{"AWS_CREDENTIALS_FILE", true},
+		{"STRIPE_API_KEY", true},
+		{"HOME", false},
+		{"SERVER_PORT", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSecretEnvName(tt.name); got != tt.want {
+			t.Errorf("isSecretEnvName(%q): got %v want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildServiceEnv_EurekaOverrides(t *testing.T) {
+	sm := newTestManager(t)
+	preferIP := false
+	service := &models.Service{
+		ID:                    "svc-1",
+		Name:                  "api",
+		EnvVars:               map[string]models.EnvVar{"EUREKA_INSTANCE_HOSTNAME": {Name: "EUREKA_INSTANCE_HOSTNAME", Value: "from-service"}},
+		EurekaHostname:        "api.local",
+		EurekaPreferIPAddress: &preferIP,
+	}
+
+	env := sm.buildServiceEnv(service, "", nil, nil, nil)
+
+	want := map[string]string{
+		"EUREKA_INSTANCE_HOSTNAME":                     "api.local",
+		"EUREKA_INSTANCE_PREFERIPADDRESS":              "false",
+		"SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES": "false",
+	}
+	for name, value := range want {
+		if got, _ := env.lookup(name); got != value {
+			t.Errorf("%s: got %q want %q", name, got, value)
+		}
+	}
+	for _, variable := range env.effective() {
+		if _, exists := want[variable.Name]; exists && variable.Source != models.EnvSourceEureka {
+			t.Errorf("%s: got source %q want %q", variable.Name, variable.Source, models.EnvSourceEureka)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+// Package services - Bulk environment variable updates
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+var envVarNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// BulkUpdateEnvVars applies env var changes to the selected services and profiles
+// in a single transaction. With DryRun set nothing is written and only the
+// before/after preview is returned.
+func (ps *ProfileService) BulkUpdateEnvVars(userID string, req *models.BulkEnvVarUpdateRequest) ([]models.EnvVarUpdatePreview, error) {
+	if len(req.ServiceIDs) == 0 && len(req.ProfileIDs) == 0 {
+		return nil, fmt.Errorf("at least one service or profile must be selected")
+	}
+	if len(req.Changes) == 0 {
+		return nil, fmt.Errorf("at least one change is required")
+	}
+	for i, change := range req.Changes {
+		if !envVarNameRegex.MatchString(change.Name) {
+			return nil, fmt.Errorf("change %d: invalid variable name '%s'", i+1, change.Name)
+		}
+		if change.Action != "set" && change.Action != "delete" {
+			return nil, fmt.Errorf("change %d: invalid action '%s'", i+1, change.Action)
+		}
+	}
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	previews := []models.EnvVarUpdatePreview{}
+	serviceEnvVars := make(map[string]map[string]models.EnvVar)
+
+	for _, serviceUUID := range req.ServiceIDs {
+		service, exists := ps.sm.GetServiceByUUID(serviceUUID)
+		if !exists {
+			return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+		}
+
+		envVars, err := ps.sm.GetServiceEnvVars(serviceUUID)
+		if err != nil {
+			return nil, err
+		}
+
+		before := make(map[string]string, len(envVars))
+		current := make(map[string]models.EnvVar, len(envVars))
+		for name, envVar := range envVars {
+			before[name] = envVar.Value
+			current[name] = envVar
+		}
+
+		for _, change := range req.Changes {
+			if change.Action == "delete" {
+				delete(envVars, change.Name)
+				continue
+			}
+			envVar := envVars[change.Name]
+			envVar.Name = change.Name
+			envVar.Value = change.Value
+			if change.Description != "" {
+				envVar.Description = change.Description
+			}
+			envVars[change.Name] = envVar
+		}
+
+		if err := validateEnvVarChanges(current, envVars); err != nil {
+			return nil, fmt.Errorf("service %s: %w", service.Name, err)
+		}
+
+		after := make(map[string]string, len(envVars))
+		for name, envVar := range envVars {
+			after[name] = envVar.Value
+		}
+
+		serviceEnvVars[serviceUUID] = envVars
+		previews = append(previews, newEnvVarPreview("service", serviceUUID, service.Name, before, after))
+	}
+
+	for _, profileID := range req.ProfileIDs {
+		profile, err := ps.getServiceProfileInternal(profileID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("profile validation failed: %w", err)
+		}
+
+		before, err := ps.db.GetProfileEnvVars(profileID)
+		if err != nil {
+			return nil, err
+		}
+
+		after := make(map[string]string, len(before))
+		for name, value := range before {
+			after[name] = value
+		}
+		for _, change := range req.Changes {
+			if change.Action == "delete" {
+				delete(after, change.Name)
+			} else {
+				after[change.Name] = change.Value
+			}
+		}
+
+		previews = append(previews, newEnvVarPreview("profile", profileID, profile.Name, before, after))
+	}
+
+	if req.DryRun {
+		return previews, nil
+	}
+
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for serviceUUID := range serviceEnvVars {
+		for _, change := range req.Changes {
+			if change.Action == "delete" {
+				_, err = tx.Exec("DELETE FROM service_env_vars WHERE service_id = ? AND var_name = ?", serviceUUID, change.Name)
+			} else {
+				_, err = tx.Exec(`
+					INSERT INTO service_env_vars (service_id, var_name, var_value, description, updated_at)
+					VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+					ON CONFLICT(service_id, var_name) DO UPDATE SET
+						var_value = excluded.var_value,
+						description = CASE WHEN excluded.description = '' THEN description ELSE excluded.description END,
+						updated_at = CURRENT_TIMESTAMP`,
+					serviceUUID, change.Name, change.Value, change.Description)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to update %s for service UUID %s: %w", change.Name, serviceUUID, err)
+			}
+		}
+	}
+
+	for _, profileID := range req.ProfileIDs {
+		for _, change := range req.Changes {
+			if change.Action == "delete" {
+				_, err = tx.Exec("DELETE FROM profile_env_vars WHERE profile_id = ? AND var_name = ?", profileID, change.Name)
+			} else {
+				_, err = tx.Exec(`
+					INSERT INTO profile_env_vars (profile_id, var_name, var_value, description, updated_at)
+					VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+					ON CONFLICT(profile_id, var_name) DO UPDATE SET
+						var_value = excluded.var_value,
+						description = CASE WHEN excluded.description = '' THEN description ELSE excluded.description END,
+						updated_at = CURRENT_TIMESTAMP`,
+					profileID, change.Name, change.Value, change.Description)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to update %s for profile %s: %w", change.Name, profileID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Keep the in-memory services in sync with what was committed
+	for serviceUUID, envVars := range serviceEnvVars {
+		if service, exists := ps.sm.GetServiceByUUID(serviceUUID); exists {
+			service.Mutex.Lock()
+			service.EnvVars = envVars
+			service.Mutex.Unlock()
+		}
+	}
+
+	return previews, nil
+}
+
+// newEnvVarPreview builds a preview entry listing the variables whose value changed
+func newEnvVarPreview(targetType, targetID, targetName string, before, after map[string]string) models.EnvVarUpdatePreview {
+	changed := []string{}
+	for name, value := range after {
+		if previous, ok := before[name]; !ok || previous != value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+
+	return models.EnvVarUpdatePreview{
+		TargetType: targetType,
+		TargetID:   targetID,
+		TargetName: targetName,
+		Before:     before,
+		After:      after,
+		Changed:    changed,
+	}
+}
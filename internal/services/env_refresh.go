@@ -0,0 +1,258 @@
+// Package services - Live environment refresh for running services
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Ways an environment change reaches a service
+const (
+	EnvRefreshActuator = "actuator" // Spring Cloud Context /actuator/refresh
+	EnvRefreshSignal   = "sighup"   // SIGHUP for services that opted in with reloadOnSighup
+	EnvRefreshNone     = "none"     // Applied on the next start
+)
+
+const envRefreshTimeout = 30 * time.Second
+
+// EnvRefreshResult describes how an environment change was applied
+type EnvRefreshResult struct {
+	Method          string   `json:"method"`
+	Applied         bool     `json:"applied"`
+	RestartRequired bool     `json:"restartRequired"`
+	ChangedVars     []string `json:"changedVars"`
+	RefreshedKeys   []string `json:"refreshedKeys,omitempty"` // Keys Spring reported as changed
+	Message         string   `json:"message"`
+}
+
+// Properties derived from environment variables refreshed into each running
+// service, and the services started with their overrides file as a Spring
+// config location, cleared when the service is started again
+var (
+	refreshedEnv         = make(map[string]map[string]string)
+	propertyOverridesSet = make(map[string]bool)
+	refreshedEnvMutex    sync.Mutex
+)
+
+func clearRefreshedEnv(serviceUUID string) {
+	refreshedEnvMutex.Lock()
+	delete(refreshedEnv, serviceUUID)
+	delete(propertyOverridesSet, serviceUUID)
+	refreshedEnvMutex.Unlock()
+}
+
+func markPropertyOverridesInUse(serviceUUID string) {
+	refreshedEnvMutex.Lock()
+	propertyOverridesSet[serviceUUID] = true
+	refreshedEnvMutex.Unlock()
+}
+
+// RefreshServiceEnv saves a service's environment variables and pushes the
+// change into the running process where possible. Spring services started
+// with a property overrides file get the new values through that file and
+// POST /actuator/refresh. The environment of any other process is fixed
+// once it runs, so the change is reported as requiring a restart; services
+// that opted in with reloadOnSighup are also sent SIGHUP.
+func (sm *Manager) RefreshServiceEnv(serviceUUID string, envVars map[string]models.EnvVar) (*EnvRefreshResult, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	before := make(map[string]models.EnvVar, len(service.EnvVars))
+	for name, envVar := range service.EnvVars {
+		before[name] = envVar
+	}
+	running := service.Status == "running"
+	pid := service.PID
+	profileID := service.ProfileID
+	serviceName := service.Name
+	reloadOnSighup := service.ReloadOnSIGHUP
+	service.Mutex.RUnlock()
+
+	if err := sm.UpdateServiceEnvVars(serviceUUID, envVars); err != nil {
+		return nil, err
+	}
+
+	changed, removed := diffEnvVars(before, envVars)
+	result := &EnvRefreshResult{Method: EnvRefreshNone, ChangedVars: append(changed, removed...)}
+	sort.Strings(result.ChangedVars)
+
+	if !running {
+		result.Message = "Environment saved; it will be applied when the service starts"
+		return result, nil
+	}
+	if len(result.ChangedVars) == 0 {
+		result.Applied = true
+		result.Message = "No environment changes to apply"
+		return result, nil
+	}
+
+	// The process environment cannot be changed from outside, so removed
+	// variables stay visible until the next restart
+	result.RestartRequired = len(removed) > 0
+
+	refreshedEnvMutex.Lock()
+	overridesInUse := propertyOverridesSet[serviceUUID]
+	refreshedEnvMutex.Unlock()
+
+	// Only Spring services with profile overrides are started with the file
+	if !overridesInUse {
+		result.RestartRequired = true
+		if !reloadOnSighup {
+			result.Message = "Environment saved; restart the service to apply it"
+			return result, nil
+		}
+		result.Method = EnvRefreshSignal
+		pgid, err := GetProcessGroup(pid)
+		if err == nil {
+			err = ReloadProcessGroup(pgid)
+		}
+		if err != nil {
+			result.Message = fmt.Sprintf("Environment saved but the reload signal failed: %v; restart the service to apply it", err)
+			return result, nil
+		}
+		result.Message = "Environment saved and SIGHUP sent; variables the service reads from its environment change after a restart"
+		sm.logHookOutput(service, "INFO", "Sent SIGHUP after environment change ("+strings.Join(result.ChangedVars, ", ")+")")
+		return result, nil
+	}
+
+	result.Method = EnvRefreshActuator
+
+	refreshedEnvMutex.Lock()
+	properties := refreshedEnv[serviceUUID]
+	if properties == nil {
+		properties = make(map[string]string)
+		refreshedEnv[serviceUUID] = properties
+	}
+	for _, name := range changed {
+		for _, key := range envVarPropertyKeys(name) {
+			properties[key] = envVars[name].Value
+		}
+	}
+	for _, name := range removed {
+		for _, key := range envVarPropertyKeys(name) {
+			delete(properties, key)
+		}
+	}
+	snapshot := make(map[string]string, len(properties))
+	for key, value := range properties {
+		snapshot[key] = value
+	}
+	refreshedEnvMutex.Unlock()
+
+	if _, err := sm.writePropertyOverrides(profileID, serviceUUID, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to write refreshed environment for %s: %w", serviceName, err)
+	}
+
+	keys, err := postActuatorRefresh(service)
+	if err != nil {
+		result.RestartRequired = true
+		result.Message = fmt.Sprintf("Environment saved but could not be refreshed live: %v", err)
+		return result, nil
+	}
+
+	result.Applied = true
+	result.RefreshedKeys = keys
+	result.Message = fmt.Sprintf("Refreshed %d properties via /actuator/refresh", len(keys))
+	if result.RestartRequired {
+		result.Message += "; removed variables stay set until the service restarts"
+	}
+	sm.logHookOutput(service, "INFO", "Environment refreshed via /actuator/refresh ("+strings.Join(result.ChangedVars, ", ")+")")
+	log.Printf("[INFO] Refreshed environment of service %s: %v", serviceName, result.ChangedVars)
+	return result, nil
+}
+
+// postActuatorRefresh triggers Spring Cloud Context's refresh endpoint and
+// returns the property keys it reports as changed
+func postActuatorRefresh(service *models.Service) ([]string, error) {
+	service.Mutex.RLock()
+	refreshURL := actuatorRefreshURL(service.HealthURL, service.Port)
+	service.Mutex.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), envRefreshTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, refreshURL, strings.NewReader("{}"))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not reachable", refreshURL)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s not found; add spring-cloud-context and expose the refresh endpoint", refreshURL)
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%s returned status %d", refreshURL, resp.StatusCode)
+	}
+
+	keys := []string{}
+	json.Unmarshal(body, &keys)
+	return keys, nil
+}
+
+// actuatorRefreshURL derives the refresh endpoint from the health URL so a
+// custom management port or base path is respected
+func actuatorRefreshURL(healthURL string, port int) string {
+	if idx := strings.Index(healthURL, "/actuator/health"); idx >= 0 {
+		return healthURL[:idx] + "/actuator/refresh"
+	}
+	return fmt.Sprintf("http://localhost:%d/actuator/refresh", port)
+}
+
+// envVarPropertyKeys returns the property names an environment variable is
+// written under: as-is for ${VAR} placeholders, and in Spring's dotted form
+// (SPRING_DATASOURCE_URL -> spring.datasource.url) for direct binding
+func envVarPropertyKeys(name string) []string {
+	dotted := strings.ToLower(strings.ReplaceAll(name, "_", "."))
+	if dotted == name {
+		return []string{name}
+	}
+	return []string{name, dotted}
+}
+
+// diffEnvVars lists variables whose value was added or changed, and those removed
+func diffEnvVars(before, after map[string]models.EnvVar) ([]string, []string) {
+	var changed, removed []string
+	for name, envVar := range after {
+		if previous, exists := before[name]; !exists || previous.Value != envVar.Value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+	return changed, removed
+}
+
+// isJVMProject reports whether a directory is a Maven or Gradle project
+func isJVMProject(serviceDir string) bool {
+	for _, file := range []string{"pom.xml", "build.gradle", "build.gradle.kts"} {
+		if _, err := os.Stat(filepath.Join(serviceDir, file)); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+// Package services - Validation schemas of service environment variables
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// envVarTypeNames describe each value type in validation messages
+var envVarTypeNames = map[string]string{
+	models.EnvVarTypeString:   "a string",
+	models.EnvVarTypeInt:      "an integer",
+	models.EnvVarTypeNumber:   "a number",
+	models.EnvVarTypeBool:     "true or false",
+	models.EnvVarTypePort:     "a port between 1 and 65535",
+	models.EnvVarTypeURL:      "a URL",
+	models.EnvVarTypeHTTPSURL: "an https URL",
+}
+
+// hasEnvVarSchema reports whether a variable declares anything about its value
+func hasEnvVarSchema(envVar models.EnvVar) bool {
+	return envVar.IsRequired || envVar.Type != "" || envVar.Pattern != ""
+}
+
+// compileEnvVarSchema checks the type and pattern a variable declares and
+// returns its pattern anchored to the whole value, or nil without one
+func compileEnvVarSchema(envVar models.EnvVar) (*regexp.Regexp, error) {
+	if _, known := envVarTypeNames[envVar.Type]; !known {
+		types := make([]string, 0, len(envVarTypeNames))
+		for name := range envVarTypeNames {
+			if name != "" {
+				types = append(types, name)
+			}
+		}
+		sort.Strings(types)
+		return nil, fmt.Errorf("%s has unknown type '%s'; use %s", envVar.Name, envVar.Type, strings.Join(types, ", "))
+	}
+	if envVar.Pattern == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile("^(?:" + envVar.Pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("%s has an invalid pattern '%s': %v", envVar.Name, envVar.Pattern, err)
+	}
+	return pattern, nil
+}
+
+// checkEnvVarValue validates a value against the schema of its variable. An
+// empty value only fails a required variable, and secret references are
+// resolved at start, so only their presence is checked.
+func checkEnvVarValue(envVar models.EnvVar, value string) error {
+	pattern, err := compileEnvVarSchema(envVar)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		if envVar.IsRequired {
+			return fmt.Errorf("%s is required but has no value", envVar.Name)
+		}
+		return nil
+	}
+	if isSecretReference(value) {
+		return nil
+	}
+
+	valid := true
+	switch envVar.Type {
+	case models.EnvVarTypeInt:
+		_, err := strconv.ParseInt(value, 10, 64)
+		valid = err == nil
+	case models.EnvVarTypeNumber:
+		_, err := strconv.ParseFloat(value, 64)
+		valid = err == nil
+	case models.EnvVarTypeBool:
+		_, err := strconv.ParseBool(value)
+		valid = err == nil
+	case models.EnvVarTypePort:
+		port, err := strconv.Atoi(value)
+		valid = err == nil && port >= 1 && port <= 65535
+	case models.EnvVarTypeURL, models.EnvVarTypeHTTPSURL:
+		parsed, err := url.Parse(value)
+		valid = err == nil && parsed.Scheme != "" && parsed.Host != ""
+		if envVar.Type == models.EnvVarTypeHTTPSURL {
+			valid = valid && parsed.Scheme == "https"
+		}
+	}
+	// The value is left out of the message, as it may be a credential
+	if !valid {
+		return fmt.Errorf("%s must be %s", envVar.Name, envVarTypeNames[envVar.Type])
+	}
+	if pattern != nil && !pattern.MatchString(value) {
+		return fmt.Errorf("%s must match %s", envVar.Name, envVar.Pattern)
+	}
+	return nil
+}
+
+// validateEnvVarChanges checks the variables of a service about to be saved.
+// Every schema must be valid; values are checked where they or their schema
+// changed, so an unrelated update is not refused over a value saved before
+// its schema was. A required variable may be saved empty, to be filled in
+// before the service starts.
+func validateEnvVarChanges(current, updated map[string]models.EnvVar) error {
+	var problems []string
+	for _, name := range sortedEnvKeys(updated) {
+		envVar := updated[name]
+		envVar.Name = name
+		if _, err := compileEnvVarSchema(envVar); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if previous, exists := current[name]; exists && previous.Value == envVar.Value &&
+			previous.Type == envVar.Type && previous.Pattern == envVar.Pattern {
+			continue
+		}
+		if envVar.Value == "" {
+			continue
+		}
+		if err := checkEnvVarValue(envVar, envVar.Value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid environment variables: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkPreflightEnvVars validates the values a service starts with, after
+// global, profile and discovery variables are applied, against the schemas
+// of its variables, so a missing or malformed value fails fast instead of
+// deep in the application's startup
+func checkPreflightEnvVars(result *PreflightResult, envVars map[string]models.EnvVar, env *serviceEnv) {
+	checked := 0
+	var problems []string
+	for _, name := range sortedEnvKeys(envVars) {
+		envVar := envVars[name]
+		if !hasEnvVarSchema(envVar) {
+			continue
+		}
+		envVar.Name = name
+		checked++
+		value, _ := env.lookup(name)
+		if err := checkEnvVarValue(envVar, value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	switch {
+	case checked == 0:
+		return
+	case len(problems) > 0:
+		result.add("envVars", PreflightFail, "env_var_invalid", strings.Join(problems, "; "))
+	default:
+		result.add("envVars", PreflightPass, "", fmt.Sprintf("%d environment variables match their schema", checked))
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func TestCheckEnvVarValue_Types(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		valid bool
+	}{
+		{models.EnvVarTypeString, "anything at all", true},
+		{models.EnvVarTypeInt, "42", true},
+		{models.EnvVarTypeInt, "-7", true},
+		{models.EnvVarTypeInt, "4.2", false},
+		{models.EnvVarTypeInt, "forty", false},
+		{models.EnvVarTypeNumber, "4.2", true},
+		{models.EnvVarTypeNumber, "1e3", true},
+		{models.EnvVarTypeNumber, "x", false},
+		{models.EnvVarTypeBool, "true", true},
+		{models.EnvVarTypeBool, "0", true},
+		{models.EnvVarTypeBool, "yes", false},
+		{models.EnvVarTypePort, "8080", true},
+		{models.EnvVarTypePort, "1", true},
+		{models.EnvVarTypePort, "65535", true},
+		{models.EnvVarTypePort, "0", false},
+		{models.EnvVarTypePort, "65536", false},
+		{models.EnvVarTypePort, "http", false},
+		{models.EnvVarTypeURL, "http://localhost:8761/eureka", true},
+		{models.EnvVarTypeURL, "jdbc:postgresql://db:5432/app", false},
+		{models.EnvVarTypeURL, "localhost:8080", false},
+		{models.EnvVarTypeURL, "/relative/path", false},
+		{models.EnvVarTypeHTTPSURL, "https://auth.example.com", true},
+		{models.EnvVarTypeHTTPSURL, "http://auth.example.com", false},
+	}
+
+	for _, tt := range tests {
+		envVar := models.EnvVar{Name: "VALUE", Type: tt.typ}
+		err := checkEnvVarValue(envVar, tt.value)
+		if tt.valid && err != nil {
+			t.Errorf("Type %q value %q: unexpected error %v", tt.typ, tt.value, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("Type %q value %q: expected an error", tt.typ, tt.value)
+		}
+	}
+}
+
+func TestCheckEnvVarValue_DoesNotEchoValue(t *testing.T) {
+	envVar := models.EnvVar{Name: "DB_PASSWORD", Type: models.EnvVarTypeInt}
+
+	err := checkEnvVarValue(envVar, "hunter2")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected the value to be left out of the error, got %q", err.Error())
+	}
+	if err.Error() != "DB_PASSWORD must be an integer" {
+		t.Errorf("Unexpected error: got %q", err.Error())
+	}
+}
+
+func TestCheckEnvVarValue_Pattern(t *testing.T) {
+	envVar := models.EnvVar{Name: "REGION", Pattern: "eu-[a-z]+-[0-9]"}
+
+	if err := checkEnvVarValue(envVar, "eu-west-1"); err != nil {
+		t.Errorf("Expected a matching value to pass, got %v", err)
+	}
+	// Create a value that only matches part of the pattern
+	if err := checkEnvVarValue(envVar, "us-east-1 eu-west-1"); err == nil || !strings.Contains(err.Error(), "must match") {
+		t.Errorf("Expected the pattern to be anchored to the whole value, got %v", err)
+	}
+
+	// Create a pattern with alternatives; anchoring applies to all of them
+	envVar.Pattern = "dev|prod"
+	if err := checkEnvVarValue(envVar, "production"); err == nil {
+		t.Error("Expected alternatives to be anchored too")
+	}
+
+	envVar = models.EnvVar{Name: "PORT", Type: models.EnvVarTypePort, Pattern: "80[0-9]{2}"}
+	if err := checkEnvVarValue(envVar, "9090"); err == nil || !strings.Contains(err.Error(), "must match") {
+		t.Errorf("Expected a valid port outside the pattern to fail, got %v", err)
+	}
+}
+
+func TestCheckEnvVarValue_RequiredAndEmpty(t *testing.T) {
+	if err := checkEnvVarValue(models.EnvVar{Name: "OPTIONAL", Type: models.EnvVarTypeInt}, ""); err != nil {
+		t.Errorf("Expected an empty optional value to pass, got %v", err)
+	}
+
+	err := checkEnvVarValue(models.EnvVar{Name: "API_KEY", IsRequired: true}, "")
+	if err == nil || err.Error() != "API_KEY is required but has no value" {
+		t.Errorf("Expected an empty required value to fail, got %v", err)
+	}
+}
+
+func TestCheckEnvVarValue_SecretReference(t *testing.T) {
+	envVar := models.EnvVar{Name: "DB_PORT", Type: models.EnvVarTypePort, IsRequired: true}
+
+	if err := checkEnvVarValue(envVar, "op://vault/db/port"); err != nil {
+		t.Errorf("Expected a secret reference to be accepted before it is resolved, got %v", err)
+	}
+}
+
+func TestCompileEnvVarSchema_InvalidSchema(t *testing.T) {
+	_, err := compileEnvVarSchema(models.EnvVar{Name: "X", Type: "uuid"})
+	if err == nil || !strings.Contains(err.Error(), "unknown type 'uuid'") || !strings.Contains(err.Error(), "bool, https-url, int, number, port, url") {
+		t.Errorf("Expected an unknown type to list the known ones, got %v", err)
+	}
+
+	_, err = compileEnvVarSchema(models.EnvVar{Name: "X", Pattern: "([a-z"})
+	if err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("Expected an invalid pattern to be refused, got %v", err)
+	}
+
+	pattern, err := compileEnvVarSchema(models.EnvVar{Name: "X"})
+	if err != nil || pattern != nil {
+		t.Errorf("Expected no pattern and no error without a schema, got %v %v", pattern, err)
+	}
+}
+
+func TestValidateEnvVarChanges(t *testing.T) {
+	current := map[string]models.EnvVar{
+		// Saved before its schema was added, and left alone
+		"LEGACY_PORT": {Value: "not-a-port", Type: models.EnvVarTypePort},
+	}
+
+	tests := []struct {
+		name    string
+		updated map[string]models.EnvVar
+		want    string // Empty when the change is valid
+	}{
+		{
+			"unchanged value keeps its schema",
+			map[string]models.EnvVar{"LEGACY_PORT": {Value: "not-a-port", Type: models.EnvVarTypePort}},
+			"",
+		},
+		{
+			"changed schema checks the value",
+			map[string]models.EnvVar{"LEGACY_PORT": {Value: "not-a-port", Type: models.EnvVarTypePort, Pattern: "[0-9]+"}},
+			"LEGACY_PORT must be a port",
+		},
+		{
+			"required saved empty",
+			map[string]models.EnvVar{"API_KEY": {IsRequired: true}},
+			"",
+		},
+		{
+			"new invalid value",
+			map[string]models.EnvVar{"DEBUG": {Value: "maybe", Type: models.EnvVarTypeBool}},
+			"DEBUG must be true or false",
+		},
+		{
+			"invalid schema without a value",
+			map[string]models.EnvVar{"ID": {Type: "uuid"}},
+			"ID has unknown type",
+		},
+		{
+			"problems are listed together",
+			map[string]models.EnvVar{
+				"A": {Value: "x", Type: models.EnvVarTypeInt},
+				"B": {Value: "y", Type: models.EnvVarTypeNumber},
+			},
+			"invalid environment variables: A must be an integer; B must be a number",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateEnvVarChanges(current, tt.updated)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Expected the change to be valid, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCheckPreflightEnvVars(t *testing.T) {
+	envVars := map[string]models.EnvVar{
+		"PLAIN":       {Value: "anything"},
+		"SERVER_PORT": {Value: "8080", Type: models.EnvVarTypePort},
+		"API_KEY":     {IsRequired: true},
+	}
+
+	// Create an environment where a global variable fills the placeholder
+	env := &serviceEnv{}
+	env.set("SERVER_PORT", "8080", models.EnvSourceService)
+	env.set("API_KEY", "from-global", models.EnvSourceGlobal)
+	result := &PreflightResult{Passed: true}
+	checkPreflightEnvVars(result, envVars, env)
+
+	if !result.Passed || len(result.Checks) != 1 || result.Checks[0].Status != PreflightPass {
+		t.Fatalf("Expected one passing check, got %+v", result.Checks)
+	}
+	if result.Checks[0].Message != "2 environment variables match their schema" {
+		t.Errorf("Unexpected message: got %q", result.Checks[0].Message)
+	}
+
+	// Create an environment that leaves the required variable empty
+	env = &serviceEnv{}
+	env.set("SERVER_PORT", "99999", models.EnvSourceService)
+	result = &PreflightResult{Passed: true}
+	checkPreflightEnvVars(result, envVars, env)
+
+	if result.Passed || len(result.Checks) != 1 || result.Checks[0].Code != "env_var_invalid" {
+		t.Fatalf("Expected one failing check, got %+v", result.Checks)
+	}
+	if got := result.Checks[0].Message; got != "API_KEY is required but has no value; SERVER_PORT must be a port between 1 and 65535" {
+		t.Errorf("Unexpected message: got %q", got)
+	}
+	if strings.Contains(result.Checks[0].Message, "99999") {
+		t.Errorf("Expected values to be left out of the message, got %q", result.Checks[0].Message)
+	}
+}
+
+func TestCheckPreflightEnvVars_NoSchemas(t *testing.T) {
+	result := &PreflightResult{Passed: true}
+	checkPreflightEnvVars(result, map[string]models.EnvVar{"PLAIN": {Value: "x"}}, &serviceEnv{})
+
+	if len(result.Checks) != 0 {
+		t.Errorf("Expected no check without schemas, got %+v", result.Checks)
+	}
+}
+
+func TestBuildServiceEnv_RequiredPlaceholder(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{
+		ID: "svc-1",
+		EnvVars: map[string]models.EnvVar{
+			"API_KEY":   {Name: "API_KEY", IsRequired: true},
+			"LOG_LEVEL": {Name: "LOG_LEVEL", IsRequired: true},
+			"REGION":    {Name: "REGION", Value: "eu-west-1"},
+		},
+	}
+	globalEnvVars := map[string]string{"API_KEY": "from-global", "REGION": "us-east-1"}
+
+	env := sm.buildServiceEnv(service, "", globalEnvVars, nil, nil)
+
+	if value, _ := env.lookup("API_KEY"); value != "from-global" {
+		t.Errorf("Expected the global value to fill the placeholder, got %q", value)
+	}
+	if value, _ := env.lookup("REGION"); value != "eu-west-1" {
+		t.Errorf("Expected the service value to override the global one, got %q", value)
+	}
+	if value, found := env.lookup("LOG_LEVEL"); !found || value != "" {
+		t.Errorf("Expected an unfilled required variable to stay empty, got %q %v", value, found)
+	}
+}
@@ -2,6 +2,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -77,7 +78,7 @@ type EurekaApplications struct {
 }
 
 // checkEurekaHealth checks service health via Eureka registry
-func (sm *Manager) checkEurekaHealth(service *models.Service) bool {
+func (sm *Manager) checkEurekaHealth(ctx context.Context, service *models.Service) bool {
 	// Only check Eureka for services that should be registered (not Eureka itself)
 	serviceName := strings.ToUpper(service.Name)
 	if serviceName == "EUREKA" {
@@ -93,7 +94,9 @@ func (sm *Manager) checkEurekaHealth(service *models.Service) bool {
 
 	// Add small random delay to stagger concurrent requests
 	delay := time.Duration(rand.Intn(500)) * time.Millisecond
-	time.Sleep(delay)
+	if !sleepContext(ctx, delay) {
+		return false
+	}
 
 	// Query Eureka for all applications
 	eurekaURL := fmt.Sprintf("http://localhost:%d/eureka/apps", eurekaPort)
@@ -107,7 +110,7 @@ func (sm *Manager) checkEurekaHealth(service *models.Service) bool {
 		},
 	}
 
-	req, err := http.NewRequest("GET", eurekaURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", eurekaURL, nil)
 	if err != nil {
 		log.Printf("[DEBUG] Failed to create Eureka request for %s: %v", service.Name, err)
 		return false
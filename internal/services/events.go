@@ -0,0 +1,136 @@
+// Package services - Persistent per-service event timeline
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const serviceEventRetention = 90 * 24 * time.Hour
+
+// recordServiceEvent persists a timeline event, pushes it to websocket clients
+// and alerts the owner and the email subscribers when the event means the
+// service is failing. It does
+// not take the service mutex so it can be called while held.
+func (sm *Manager) recordServiceEvent(service *models.Service, eventType, message string) {
+	event := models.ServiceEvent{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		Type:        eventType,
+		Message:     message,
+		Timestamp:   time.Now(),
+	}
+
+	sm.alertServiceOwner(service, event)
+	sm.notifySubscribers(service, event)
+
+	if err := sm.db.InsertServiceEvent(event); err != nil {
+		log.Printf("[WARN] Failed to record %s event for service %s: %v", eventType, service.Name, err)
+		return
+	}
+
+	sm.broadcastMessage(WebSocketMessage{Type: "service_event", Payload: event})
+}
+
+// GetServiceEvents returns the timeline of the given services since a point in time, newest first
+func (sm *Manager) GetServiceEvents(serviceUUIDs []string, since time.Time, limit int) ([]models.ServiceEvent, error) {
+	return sm.db.GetServiceEvents(serviceUUIDs, since, limit)
+}
+
+// cleanupServiceEvents drops timeline events past the retention period
+func (sm *Manager) cleanupServiceEvents() {
+	removed, err := sm.db.CleanupServiceEvents(time.Now().Add(-serviceEventRetention))
+	if err != nil {
+		log.Printf("[WARN] Service event cleanup failed: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("[INFO] Removed %d service events older than %s", removed, serviceEventRetention)
+	}
+}
+
+// describeServiceConfigChanges lists the service settings an update changes
+func describeServiceConfigChanges(service *models.Service, update *models.ServiceConfigRequest) string {
+	var changes []string
+	add := func(field string, before, after interface{}) {
+		if fmt.Sprint(before) != fmt.Sprint(after) {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", field, before, after))
+		}
+	}
+
+	add("name", service.Name, update.Name)
+	add("dir", service.Dir, update.Dir)
+	add("port", service.Port, update.Port)
+	add("healthUrl", service.HealthURL, update.HealthURL)
+	add("javaOpts", service.JavaOpts, update.JavaOpts)
+	add("javaOptsPreset", service.JavaOptsPreset, update.JavaOptsPreset)
+	add("buildSystem", service.BuildSystem, update.BuildSystem)
+	add("enabled", service.IsEnabled, update.IsEnabled)
+	add("verboseLogging", service.VerboseLogging, update.VerboseLogging)
+	add("idleMinutes", service.IdleMinutes, update.IdleMinutes)
+	add("healthInterval", service.HealthInterval, update.HealthInterval)
+	add("skipDiscovery", service.SkipDiscovery, update.SkipDiscovery)
+	add("owner", service.Owner, update.Owner)
+	add("runAsUser", service.RunAsUser, update.RunAsUser)
+	add("executionMode", service.ExecutionMode, update.ExecutionMode)
+	add("workingDir", service.WorkingDir, update.WorkingDir)
+	add("autoMigrate", service.AutoMigrate, update.AutoMigrate)
+	add("interactive", service.Interactive, update.Interactive)
+	add("reloadOnSighup", service.ReloadOnSIGHUP, update.ReloadOnSIGHUP)
+	add("infraType", service.InfraType, update.InfraType)
+	add("infraRuntime", service.InfraRuntime, update.InfraRuntime)
+	if service.StartCommand != update.StartCommand {
+		changes = append(changes, "startCommand updated")
+	}
+	if service.BuildCommand != update.BuildCommand {
+		changes = append(changes, "buildCommand updated")
+	}
+	if service.Description != update.Description {
+		changes = append(changes, "description updated")
+	}
+
+	if len(changes) == 0 {
+		return ""
+	}
+	return "Configuration changed (" + strings.Join(changes, ", ") + ")"
+}
+
+// describeEnvVarChanges summarises which variables were added, removed or
+// changed without exposing their values
+func describeEnvVarChanges(before, after map[string]models.EnvVar) string {
+	var added, removed, changed []string
+	for name, envVar := range after {
+		previous, exists := before[name]
+		if !exists {
+			added = append(added, name)
+		} else if previous.Value != envVar.Value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+
+	var parts []string
+	for _, group := range []struct {
+		label string
+		names []string
+	}{{"added", added}, {"changed", changed}, {"removed", removed}} {
+		if len(group.names) > 0 {
+			sort.Strings(group.names)
+			parts = append(parts, group.label+" "+strings.Join(group.names, ", "))
+		}
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Environment variables " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,167 @@
+// Package services - Running Spring Boot services from their packaged jar
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// How a service is run: through the build tool's run goal, which compiles
+// and runs in one JVM, or by packaging it once and running java -jar
+const (
+	ExecutionModeBuildTool = "build-tool"
+	ExecutionModeJar       = "jar"
+)
+
+// executableJar stands in for the build system when passing application
+// arguments to a java -jar command
+const executableJar BuildSystemType = "jar"
+
+// Jars the build writes next to the executable one
+var nonExecutableJarRegex = regexp.MustCompile(`-(sources|javadoc|tests|test-sources|plain)\.jar$`)
+
+// Directories that hold build output or tooling state rather than sources
+var jarSourceSkipDirs = map[string]bool{"target": true, "build": true, "out": true, "bin": true, "node_modules": true}
+
+// validateExecutionMode trims the execution mode of a service; empty runs it
+// through the build tool
+func validateExecutionMode(mode *string) error {
+	*mode = strings.TrimSpace(*mode)
+	switch *mode {
+	case "", ExecutionModeBuildTool, ExecutionModeJar:
+		return nil
+	}
+	return fmt.Errorf("invalid execution mode '%s': use %s or %s", *mode, ExecutionModeBuildTool, ExecutionModeJar)
+}
+
+// jarOutputDir is where the build system writes the packaged jar
+func jarOutputDir(buildSystem BuildSystemType) string {
+	if buildSystem == BuildSystemGradle {
+		return filepath.Join("build", "libs")
+	}
+	return "target"
+}
+
+// packagedJar returns the newest executable jar in the service's build
+// output, relative to the service directory, or "" when there is none
+func packagedJar(serviceDir string, buildSystem BuildSystemType) (string, time.Time) {
+	jars, _ := filepath.Glob(filepath.Join(serviceDir, jarOutputDir(buildSystem), "*.jar"))
+
+	newestPath, newest := "", time.Time{}
+	for _, jar := range jars {
+		if nonExecutableJarRegex.MatchString(jar) {
+			continue
+		}
+		info, err := os.Stat(jar)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if newestPath == "" || info.ModTime().After(newest) {
+			newestPath, newest = jar, info.ModTime()
+		}
+	}
+	if newestPath == "" {
+		return "", time.Time{}
+	}
+	rel, _ := filepath.Rel(serviceDir, newestPath)
+	return rel, newest
+}
+
+// newestSourceAfter returns a build input of the service, such as a source
+// file or pom.xml, modified after the given time, or "" when there is none.
+// Build output and hidden directories are skipped.
+func newestSourceAfter(serviceDir string, since time.Time) string {
+	found := ""
+	filepath.WalkDir(serviceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != serviceDir && (strings.HasPrefix(d.Name(), ".") || jarSourceSkipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(since) {
+			found, _ = filepath.Rel(serviceDir, path)
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// jarStartCommand returns the command that runs a service from its packaged
+// jar, packaging it first when there is no jar or a source is newer, and the
+// environment the build needs. It reports whether the command builds. The
+// service's JavaOpts go to the application's JVM only, not to the build. A
+// non-empty buildCommand replaces the build system's package command.
+func (sm *Manager) jarStartCommand(service *models.Service, serviceDir string, buildSystem BuildSystemType, javaOpts, extraEnv, buildCommand string, verboseLogging bool) (string, map[string]string, bool) {
+	profileID := sm.getServiceProfileID(service.ID)
+	credentialEnv := make(map[string]string)
+
+	var build string
+	jar, builtAt := packagedJar(serviceDir, buildSystem)
+	stale := ""
+	if jar != "" {
+		stale = newestSourceAfter(serviceDir, builtAt)
+	}
+	switch {
+	case jar == "":
+		log.Printf("[INFO] Service %s: no packaged jar in %s, building it", service.Name, jarOutputDir(buildSystem))
+	case stale != "":
+		log.Printf("[INFO] Service %s: %s changed since %s was built, rebuilding", service.Name, stale, jar)
+	default:
+		log.Printf("[INFO] Service %s: %s is up to date, skipping the build", service.Name, jar)
+	}
+
+	if jar == "" || stale != "" {
+		switch {
+		case buildCommand != "":
+			build = buildCommand
+		case buildSystem == BuildSystemGradle:
+			build = "./gradlew bootJar"
+			if verboseLogging {
+				build = "./gradlew -i bootJar"
+			}
+		default:
+			build = "./mvnw package -DskipTests"
+			if verboseLogging {
+				build = "./mvnw -X package -DskipTests"
+			}
+		}
+		build, credentialEnv = sm.applyRepositoryCredentials(build, buildSystem, profileID, service.Name)
+		build = sm.applyBuildSettings(build, buildSystem, profileID, service.Name)
+	}
+
+	outputDir := jarOutputDir(buildSystem)
+	run := "java"
+	if javaOpts != "" {
+		run += " " + javaOpts
+	}
+	run += ` -jar "$VERTEX_JAR"`
+	if extraEnv != "" {
+		run = extraEnv + " " + run
+	}
+	run = sm.applyPropertyOverrides(run, executableJar, serviceDir, profileID, service.ID, service.Name)
+
+	var command strings.Builder
+	if build != "" {
+		command.WriteString("( " + build + " ) && " + unsetCredentialEnv(credentialEnv))
+	}
+	command.WriteString(fmt.Sprintf(`VERTEX_JAR=$(ls -t %s/*.jar 2>/dev/null | grep -Ev -- %s | head -n 1) && `,
+		outputDir, shellQuote(nonExecutableJarRegex.String())))
+	command.WriteString(fmt.Sprintf(`{ test -n "$VERTEX_JAR" || { echo "%sNo executable jar found in %s" >&2; exit 1; }; } && `,
+		buildSummaryPrefix, outputDir))
+	command.WriteString(fmt.Sprintf(`echo "%sRunning $VERTEX_JAR" && `, buildSummaryPrefix))
+	command.WriteString(run)
+	return command.String(), credentialEnv, build != ""
+}
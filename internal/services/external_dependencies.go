@@ -0,0 +1,230 @@
+// Package services - External resource dependencies (databases, brokers, URLs)
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const defaultExternalDependencyTimeout = 5 * time.Second
+
+// Default ports for JDBC URLs that omit one
+var jdbcDefaultPorts = map[string]string{
+	"postgresql": "5432",
+	"mysql":      "3306",
+	"mariadb":    "3306",
+	"sqlserver":  "1433",
+	"oracle":     "1521",
+	"db2":        "50000",
+}
+
+// GetExternalDependencies returns the external dependencies configured for a service
+func (sm *Manager) GetExternalDependencies(serviceUUID string) ([]models.ExternalDependency, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	return sm.db.GetExternalDependencies(serviceUUID)
+}
+
+// UpdateExternalDependencies validates and replaces the external dependencies of a service
+func (sm *Manager) UpdateExternalDependencies(serviceUUID string, dependencies []models.ExternalDependency) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	for i := range dependencies {
+		dep := &dependencies[i]
+		dep.ServiceID = serviceUUID
+		dep.Target = strings.TrimSpace(dep.Target)
+
+		if dep.Target == "" {
+			return fmt.Errorf("dependency %d: target is required", i+1)
+		}
+
+		switch dep.Type {
+		case models.ExternalDependencyTCP:
+			if _, _, err := net.SplitHostPort(dep.Target); err != nil {
+				return fmt.Errorf("dependency %d: target must be host:port", i+1)
+			}
+		case models.ExternalDependencyHTTP:
+			if parsed, err := url.Parse(dep.Target); err != nil || parsed.Host == "" {
+				return fmt.Errorf("dependency %d: invalid URL '%s'", i+1, dep.Target)
+			}
+		case models.ExternalDependencyJDBC:
+			if _, _, err := parseJDBCAddress(dep.Target); err != nil {
+				return fmt.Errorf("dependency %d: %v", i+1, err)
+			}
+		default:
+			return fmt.Errorf("dependency %d: invalid type '%s'", i+1, dep.Type)
+		}
+
+		if strings.TrimSpace(dep.Name) == "" {
+			dep.Name = dep.Target
+		}
+		if dep.TimeoutSeconds <= 0 {
+			dep.TimeoutSeconds = int(defaultExternalDependencyTimeout / time.Second)
+		}
+	}
+
+	return sm.db.SaveExternalDependencies(serviceUUID, dependencies)
+}
+
+// CheckExternalDependencies checks every enabled external dependency of a service
+func (sm *Manager) CheckExternalDependencies(serviceUUID string) ([]models.ExternalDependencyStatus, error) {
+	dependencies, err := sm.GetExternalDependencies(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.ExternalDependencyStatus, 0, len(dependencies))
+	for _, dep := range dependencies {
+		if !dep.Enabled {
+			continue
+		}
+		start := time.Now()
+		err := checkExternalDependency(sm.ctx, dep)
+		status := models.ExternalDependencyStatus{
+			Dependency: dep,
+			Reachable:  err == nil,
+			LatencyMs:  time.Since(start).Milliseconds(),
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		results = append(results, status)
+	}
+
+	return results, nil
+}
+
+// verifyExternalDependencies runs before a service starts and fails with a
+// readable error when a required dependency is unreachable
+func (sm *Manager) verifyExternalDependencies(ctx context.Context, service *models.Service) error {
+	dependencies, err := sm.db.GetExternalDependencies(service.ID)
+	if err != nil {
+		sm.logHookOutput(service, "WARN", fmt.Sprintf("Could not load external dependencies: %v", err))
+		return nil
+	}
+
+	var failures []string
+	for _, dep := range dependencies {
+		if !dep.Enabled {
+			continue
+		}
+		if err := checkExternalDependency(ctx, dep); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("start of %s cancelled: %w", service.Name, ctx.Err())
+			}
+			if dep.Required {
+				sm.logHookOutput(service, "ERROR", err.Error())
+				failures = append(failures, err.Error())
+			} else {
+				sm.logHookOutput(service, "WARN", fmt.Sprintf("%v (optional, starting anyway)", err))
+			}
+			continue
+		}
+		sm.logHookOutput(service, "INFO", fmt.Sprintf("%s at %s is reachable", dep.Name, dep.Target))
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("cannot start %s: %s", service.Name, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// checkExternalDependency probes a single dependency
+func checkExternalDependency(ctx context.Context, dep models.ExternalDependency) error {
+	timeout := time.Duration(dep.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultExternalDependencyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch dep.Type {
+	case models.ExternalDependencyTCP:
+		return dialDependency(ctx, dep.Name, dep.Target)
+
+	case models.ExternalDependencyJDBC:
+		address, _, err := parseJDBCAddress(dep.Target)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dep.Name, err)
+		}
+		return dialDependency(ctx, dep.Name, address)
+
+	case models.ExternalDependencyHTTP:
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, dep.Target, nil)
+		if err != nil {
+			return fmt.Errorf("%s: invalid URL %s: %v", dep.Name, dep.Target, err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("%s at %s is not reachable", dep.Name, dep.Target)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("%s at %s is unhealthy (status %d)", dep.Name, dep.Target, resp.StatusCode)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%s: unsupported dependency type '%s'", dep.Name, dep.Type)
+}
+
+func dialDependency(ctx context.Context, name, address string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("%s at %s is not reachable", name, address)
+	}
+	conn.Close()
+	return nil
+}
+
+// parseJDBCAddress extracts host:port and the driver from a JDBC URL such as
+// jdbc:postgresql://localhost:5432/app or jdbc:oracle:thin:@db:1521/XE
+func parseJDBCAddress(jdbcURL string) (string, string, error) {
+	if !strings.HasPrefix(jdbcURL, "jdbc:") {
+		return "", "", fmt.Errorf("JDBC URL must start with 'jdbc:'")
+	}
+	rest := strings.TrimPrefix(jdbcURL, "jdbc:")
+	driver, _, _ := strings.Cut(rest, ":")
+
+	var hostPart string
+	if idx := strings.Index(rest, "//"); idx >= 0 {
+		hostPart = rest[idx+2:]
+	} else if idx := strings.Index(rest, "@"); idx >= 0 {
+		hostPart = strings.TrimPrefix(rest[idx+1:], "//")
+	} else {
+		return "", "", fmt.Errorf("cannot find host in JDBC URL '%s'", jdbcURL)
+	}
+
+	// Stop at the database path, parameters or a second host
+	if idx := strings.IndexAny(hostPart, "/;?,"); idx >= 0 {
+		hostPart = hostPart[:idx]
+	}
+	if at := strings.LastIndex(hostPart, "@"); at >= 0 {
+		hostPart = hostPart[at+1:]
+	}
+	if hostPart == "" {
+		return "", "", fmt.Errorf("cannot find host in JDBC URL '%s'", jdbcURL)
+	}
+
+	if _, _, err := net.SplitHostPort(hostPart); err != nil {
+		port, known := jdbcDefaultPorts[driver]
+		if !known {
+			return "", "", fmt.Errorf("JDBC URL '%s' has no port and driver '%s' has no known default", jdbcURL, driver)
+		}
+		hostPart = net.JoinHostPort(hostPart, port)
+	}
+
+	return hostPart, driver, nil
+}
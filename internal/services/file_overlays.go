@@ -0,0 +1,335 @@
+// Package services - Profile-scoped file overlays
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// fileOverlayMutex serializes writing and restoring overlays so a profile switch
+// never interleaves with an overlay being saved or deleted
+var fileOverlayMutex sync.Mutex
+
+var overlayEnvPattern = regexp.MustCompile(`\{env:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// FileOverlayMaterialization reports what switching a service directory to a
+// profile's overlays did
+type FileOverlayMaterialization struct {
+	ProfileID string   `json:"profileId"`
+	Written   []string `json:"written"`  // Files written from the profile's overlays
+	Restored  []string `json:"restored"` // Files put back as they were before another profile's overlay
+	Warnings  []string `json:"warnings"`
+}
+
+// validateOverlayPath cleans the path of an overlay and makes sure it stays
+// within the service directory
+func validateOverlayPath(p string) (string, error) {
+	p = strings.TrimSpace(filepath.ToSlash(p))
+	if p == "" {
+		return "", fmt.Errorf("invalid overlay path: path is required")
+	}
+	if path.IsAbs(p) || filepath.IsAbs(p) {
+		return "", fmt.Errorf("invalid overlay path %s: must be relative to the service directory", p)
+	}
+	cleaned := path.Clean(p)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid overlay path %s: must stay within the service directory", p)
+	}
+	return cleaned, nil
+}
+
+// GetProfileFileOverlays returns the file overlays of a profile, marking those
+// currently written into their service directory
+func (sm *Manager) GetProfileFileOverlays(profileID string) ([]models.ProfileFileOverlay, error) {
+	overlays, err := sm.db.GetProfileFileOverlays(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	materialized, err := sm.db.GetMaterializedFileOverlays()
+	if err != nil {
+		return nil, err
+	}
+	written := make(map[string]bool, len(materialized))
+	for _, m := range materialized {
+		written[m.ProfileID+"|"+m.ServiceID+"|"+m.Path] = true
+	}
+
+	for i := range overlays {
+		if service, exists := sm.GetServiceByUUID(overlays[i].ServiceID); exists {
+			overlays[i].ServiceName = service.Name
+		}
+		overlays[i].Materialized = written[overlays[i].ProfileID+"|"+overlays[i].ServiceID+"|"+overlays[i].Path]
+	}
+
+	return overlays, nil
+}
+
+// SaveProfileFileOverlay creates or replaces a profile's variant of a service
+// file. If the overlay is currently written into the service directory the
+// file is rewritten straight away.
+func (sm *Manager) SaveProfileFileOverlay(overlay *models.ProfileFileOverlay) error {
+	if _, exists := sm.GetServiceByUUID(overlay.ServiceID); !exists {
+		return fmt.Errorf("service UUID %s not found", overlay.ServiceID)
+	}
+
+	cleaned, err := validateOverlayPath(overlay.Path)
+	if err != nil {
+		return err
+	}
+	overlay.Path = cleaned
+
+	fileOverlayMutex.Lock()
+	defer fileOverlayMutex.Unlock()
+
+	if err := sm.db.SaveProfileFileOverlay(overlay); err != nil {
+		return err
+	}
+
+	m, err := sm.findMaterializedOverlay(overlay.ProfileID, overlay.ServiceID, overlay.Path)
+	if err != nil || m == nil {
+		return err
+	}
+	content, err := sm.renderFileOverlay(overlay)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.FilePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", m.FilePath, err)
+	}
+	m.WrittenContent = content
+	m.MaterializedAt = time.Now()
+	return sm.db.SaveMaterializedFileOverlay(m)
+}
+
+// DeleteProfileFileOverlay removes a profile's variant of a service file,
+// putting the original back if the overlay is currently written
+func (sm *Manager) DeleteProfileFileOverlay(profileID, serviceUUID, overlayPath string) error {
+	cleaned, err := validateOverlayPath(overlayPath)
+	if err != nil {
+		return err
+	}
+
+	fileOverlayMutex.Lock()
+	defer fileOverlayMutex.Unlock()
+
+	removed, err := sm.db.DeleteProfileFileOverlay(profileID, serviceUUID, cleaned)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("file overlay %s not found", cleaned)
+	}
+
+	m, err := sm.findMaterializedOverlay(profileID, serviceUUID, cleaned)
+	if err != nil || m == nil {
+		return err
+	}
+	if warning, err := sm.restoreFileOverlay(m); err != nil {
+		return err
+	} else if warning != "" {
+		log.Printf("[WARN] %s", warning)
+	}
+	return nil
+}
+
+// MaterializeProfileFileOverlays switches service directories to a profile:
+// files written for any other profile are restored, then the profile's
+// overlays are rendered and written under projectsDir. A file edited since
+// Vertex wrote it is backed up to the data directory before being replaced.
+func (sm *Manager) MaterializeProfileFileOverlays(profileID, projectsDir string) (*FileOverlayMaterialization, error) {
+	if projectsDir == "" {
+		projectsDir = sm.config.ProjectsDir
+	}
+
+	fileOverlayMutex.Lock()
+	defer fileOverlayMutex.Unlock()
+
+	result := &FileOverlayMaterialization{ProfileID: profileID, Written: []string{}, Restored: []string{}, Warnings: []string{}}
+
+	overlays, err := sm.db.GetProfileFileOverlays(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]models.ProfileFileOverlay, len(overlays))
+	for _, overlay := range overlays {
+		service, exists := sm.GetServiceByUUID(overlay.ServiceID)
+		if !exists {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("skipped %s: service UUID %s not found", overlay.Path, overlay.ServiceID))
+			continue
+		}
+		targets[filepath.Join(projectsDir, service.Dir, filepath.FromSlash(overlay.Path))] = overlay
+	}
+
+	materialized, err := sm.db.GetMaterializedFileOverlays()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]*models.MaterializedFileOverlay, len(materialized))
+	for i := range materialized {
+		m := &materialized[i]
+		if _, keep := targets[m.FilePath]; keep && m.ProfileID == profileID {
+			current[m.FilePath] = m
+			continue
+		}
+		warning, err := sm.restoreFileOverlay(m)
+		if err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+			continue
+		}
+		if warning != "" {
+			result.Warnings = append(result.Warnings, warning)
+		}
+		result.Restored = append(result.Restored, m.FilePath)
+	}
+
+	for filePath, overlay := range targets {
+		if err := sm.writeFileOverlay(filePath, &overlay, current[filePath], result); err != nil {
+			result.Warnings = append(result.Warnings, err.Error())
+			continue
+		}
+		result.Written = append(result.Written, filePath)
+	}
+
+	if len(result.Written) > 0 || len(result.Restored) > 0 {
+		log.Printf("[INFO] File overlays for profile %s: %d written, %d restored", profileID, len(result.Written), len(result.Restored))
+	}
+	return result, nil
+}
+
+// writeFileOverlay renders an overlay into filePath. The file it replaces is
+// recorded the first time so it can be restored later; previous is the record
+// when the overlay is already written.
+func (sm *Manager) writeFileOverlay(filePath string, overlay *models.ProfileFileOverlay, previous *models.MaterializedFileOverlay, result *FileOverlayMaterialization) error {
+	content, err := sm.renderFileOverlay(overlay)
+	if err != nil {
+		return err
+	}
+
+	record := previous
+	existing, readErr := os.ReadFile(filePath)
+	if record == nil {
+		record = &models.MaterializedFileOverlay{
+			FilePath:        filePath,
+			ProfileID:       overlay.ProfileID,
+			ServiceID:       overlay.ServiceID,
+			Path:            overlay.Path,
+			OriginalExisted: readErr == nil,
+			OriginalContent: string(existing),
+		}
+	} else if readErr == nil && string(existing) != record.WrittenContent && string(existing) != content {
+		if warning := backupEditedOverlay(filePath, existing); warning != "" {
+			result.Warnings = append(result.Warnings, warning)
+		}
+	}
+	record.WrittenContent = content
+	record.MaterializedAt = time.Now()
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", filePath, err)
+	}
+	// Record before writing so the original is never lost if the write succeeds
+	// and Vertex stops before it is remembered
+	if err := sm.db.SaveMaterializedFileOverlay(record); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// restoreFileOverlay puts back the file an overlay replaced, or removes the
+// file if the overlay created it. It returns a warning when the file had been
+// edited since it was written and was backed up first.
+func (sm *Manager) restoreFileOverlay(m *models.MaterializedFileOverlay) (string, error) {
+	warning := ""
+	if existing, err := os.ReadFile(m.FilePath); err == nil && string(existing) != m.WrittenContent {
+		warning = backupEditedOverlay(m.FilePath, existing)
+	}
+
+	if m.OriginalExisted {
+		if err := os.MkdirAll(filepath.Dir(m.FilePath), 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory for %s: %w", m.FilePath, err)
+		}
+		if err := os.WriteFile(m.FilePath, []byte(m.OriginalContent), 0644); err != nil {
+			return "", fmt.Errorf("failed to restore %s: %w", m.FilePath, err)
+		}
+	} else if err := os.Remove(m.FilePath); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove %s: %w", m.FilePath, err)
+	}
+
+	return warning, sm.db.DeleteMaterializedFileOverlay(m.FilePath)
+}
+
+// backupEditedOverlay keeps a copy of a written overlay that was edited by hand
+// in the data directory and returns a warning naming it
+func backupEditedOverlay(filePath string, content []byte) string {
+	dir := filepath.Join(database.GetDataDir(), "overlays", "backups")
+	backup := filepath.Join(dir, fmt.Sprintf("%s-%s", time.Now().Format("20060102-150405"), filepath.Base(filePath)))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Sprintf("%s was edited since its overlay was written and could not be backed up: %v", filePath, err)
+	}
+	if err := os.WriteFile(backup, content, 0644); err != nil {
+		return fmt.Sprintf("%s was edited since its overlay was written and could not be backed up: %v", filePath, err)
+	}
+	return fmt.Sprintf("%s was edited since its overlay was written; the edits were saved to %s", filePath, backup)
+}
+
+// findMaterializedOverlay returns the record of an overlay written into its
+// service directory, or nil when it is not
+func (sm *Manager) findMaterializedOverlay(profileID, serviceUUID, overlayPath string) (*models.MaterializedFileOverlay, error) {
+	materialized, err := sm.db.GetMaterializedFileOverlays()
+	if err != nil {
+		return nil, err
+	}
+	for i := range materialized {
+		m := &materialized[i]
+		if m.ProfileID == profileID && m.ServiceID == serviceUUID && m.Path == overlayPath {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// renderFileOverlay fills in the placeholders of an overlay: {profile},
+// {service}, {port} and {env:NAME} from the profile's environment variables.
+// Unknown environment variables are left as written.
+func (sm *Manager) renderFileOverlay(overlay *models.ProfileFileOverlay) (string, error) {
+	var profileName string
+	if err := sm.db.QueryRow("SELECT name FROM service_profiles WHERE id = ?", overlay.ProfileID).Scan(&profileName); err != nil {
+		return "", fmt.Errorf("failed to load profile %s: %w", overlay.ProfileID, err)
+	}
+
+	envVars, err := sm.db.GetProfileEnvVars(overlay.ProfileID)
+	if err != nil {
+		return "", err
+	}
+
+	serviceName, port := "", ""
+	if service, exists := sm.GetServiceByUUID(overlay.ServiceID); exists {
+		serviceName = service.Name
+		port = strconv.Itoa(service.Port)
+	}
+
+	content := overlayEnvPattern.ReplaceAllStringFunc(overlay.Content, func(match string) string {
+		if value, ok := envVars[overlayEnvPattern.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+
+	return strings.NewReplacer("{profile}", profileName, "{service}", serviceName, "{port}", port).Replace(content), nil
+}
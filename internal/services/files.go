@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/zechtz/vertex/internal/models"
 )
 
 type ServiceFile struct {
@@ -210,5 +212,84 @@ func (sm *Manager) UpdateServiceFileWithProjectsDir(serviceUUID, filename, conte
 	}
 
 	log.Printf("[INFO] Successfully updated file %s for service %s at %s", filename, serviceUUID, fullFilePath)
+	sm.recordServiceEvent(service, models.EventConfigFileSaved, fmt.Sprintf("Edited %s", filename))
 	return nil
 }
+
+// ConfigFileDiff describes how a service configuration file differs from the committed version
+type ConfigFileDiff struct {
+	ServiceID   string `json:"serviceId"`
+	ServiceName string `json:"serviceName"`
+	Name        string `json:"name"`
+	Path        string `json:"path"` // Relative to the service directory
+	Status      string `json:"status"`
+	Diff        string `json:"diff"`
+}
+
+// GetServiceFileDiffsWithProjectsDir compares each configuration file of a service
+// with the version committed in git. When modifiedOnly is set, clean files are omitted.
+func (sm *Manager) GetServiceFileDiffsWithProjectsDir(serviceUUID, projectsDir string, modifiedOnly bool) ([]ConfigFileDiff, error) {
+	sm.mutex.RLock()
+	service, exists := sm.services[serviceUUID]
+	sm.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("service with UUID %s not found", serviceUUID)
+	}
+
+	serviceDir := filepath.Join(projectsDir, service.Dir)
+	if !IsGitRepository(serviceDir) {
+		return nil, fmt.Errorf("service directory is not a git repository: %s", serviceDir)
+	}
+
+	searchPaths := []string{
+		"src/main/resources",
+		"src/main/resources/config",
+		"config",
+		".",
+	}
+
+	diffs := []ConfigFileDiff{}
+	seen := make(map[string]bool)
+
+	for _, searchPath := range searchPaths {
+		fullSearchPath := filepath.Join(serviceDir, searchPath)
+		if _, err := os.Stat(fullSearchPath); os.IsNotExist(err) {
+			continue
+		}
+
+		files, err := sm.findConfigFiles(fullSearchPath)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			relPath := filepath.ToSlash(filepath.Join(searchPath, file.Path))
+			if seen[relPath] {
+				continue
+			}
+			seen[relPath] = true
+
+			status, diff, err := GetFileDiff(serviceDir, relPath)
+			if err != nil {
+				log.Printf("[WARN] Failed to diff %s for service %s: %v", relPath, service.Name, err)
+				continue
+			}
+
+			if modifiedOnly && status == "clean" {
+				continue
+			}
+
+			diffs = append(diffs, ConfigFileDiff{
+				ServiceID:   service.ID,
+				ServiceName: service.Name,
+				Name:        file.Name,
+				Path:        relPath,
+				Status:      status,
+				Diff:        diff,
+			})
+		}
+	}
+
+	return diffs, nil
+}
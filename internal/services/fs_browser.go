@@ -0,0 +1,220 @@
+// Package services - Browsing the directories a projects directory can be picked from
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxDirectoryEntries caps a listing so a huge directory cannot stall the picker
+const maxDirectoryEntries = 1000
+
+// Files whose presence marks a directory as a project, in the order they are reported
+var projectBuildFiles = []string{
+	"pom.xml", "build.gradle", "build.gradle.kts", "package.json", "go.mod",
+	"Cargo.toml", "pyproject.toml", "requirements.txt", "Makefile", "docker-compose.yml",
+}
+
+// DirectoryEntry is a subdirectory in a directory listing
+type DirectoryEntry struct {
+	Name       string   `json:"name"`
+	Path       string   `json:"path"`
+	IsGitRepo  bool     `json:"isGitRepo"`
+	BuildFiles []string `json:"buildFiles"` // Build files found directly in the directory
+}
+
+// DirectoryListing is the subdirectories of a directory inside the browse
+// roots. Listing no path returns the roots themselves.
+type DirectoryListing struct {
+	Path      string           `json:"path"`
+	Parent    string           `json:"parent"` // Empty at a root
+	Roots     []string         `json:"roots"`
+	Entries   []DirectoryEntry `json:"entries"`
+	Truncated bool             `json:"truncated"`
+}
+
+// browseRoots returns the directories the browser may list: VERTEX_FS_ROOTS
+// (separated like PATH) when set, otherwise the home directory and the
+// global projects directory
+func (sm *Manager) browseRoots() []string {
+	var candidates []string
+	if configured := os.Getenv("VERTEX_FS_ROOTS"); configured != "" {
+		candidates = filepath.SplitList(configured)
+	} else {
+		if home, err := os.UserHomeDir(); err == nil {
+			candidates = append(candidates, home)
+		}
+		candidates = append(candidates, sm.config.ProjectsDir)
+	}
+
+	roots := []string{}
+	for _, candidate := range candidates {
+		if strings.TrimSpace(candidate) == "" {
+			continue
+		}
+		resolved, err := resolveBrowsePath(candidate)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(resolved); err != nil || !info.IsDir() {
+			continue
+		}
+		covered := false
+		for _, root := range roots {
+			if isWithinDirectory(root, resolved) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			roots = append(roots, resolved)
+		}
+	}
+	return roots
+}
+
+// resolveBrowsePath makes a path absolute and resolves its symlinks, so a
+// link cannot lead out of the roots
+func resolveBrowsePath(path string) (string, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(absolute)
+}
+
+// resolveMissingPath resolves the symlinks of the deepest existing ancestor of
+// a path that does not exist, to tell where the path would be
+func resolveMissingPath(path string) string {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	missing := ""
+	for dir := absolute; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, missing)
+		}
+		if dir == filepath.Dir(dir) {
+			return ""
+		}
+		missing = filepath.Join(filepath.Base(dir), missing)
+	}
+}
+
+// isWithinDirectory reports whether path is dir or below it
+func isWithinDirectory(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// browseRootOf returns the root path lies in, or "" when it is outside them all
+func browseRootOf(roots []string, path string) string {
+	for _, root := range roots {
+		if isWithinDirectory(root, path) {
+			return root
+		}
+	}
+	return ""
+}
+
+// BrowseDirectories lists the subdirectories of a directory inside the browse
+// roots, marking git repositories and projects with build files. Hidden
+// directories are left out.
+func (sm *Manager) BrowseDirectories(path string) (*DirectoryListing, error) {
+	roots := sm.browseRoots()
+	listing := &DirectoryListing{Roots: roots, Entries: []DirectoryEntry{}}
+
+	if strings.TrimSpace(path) == "" {
+		for _, root := range roots {
+			listing.Entries = append(listing.Entries, describeDirectory(root, root))
+		}
+		return listing, nil
+	}
+
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	resolved, err := resolveBrowsePath(path)
+	if os.IsNotExist(err) {
+		// Outside the roots a missing path fails like an existing one, so
+		// the browser cannot be used to probe for files elsewhere
+		if browseRootOf(roots, resolveMissingPath(path)) == "" {
+			return nil, fmt.Errorf("path %s is outside the allowed roots", path)
+		}
+		return nil, fmt.Errorf("directory %s not found", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %v", path, err)
+	}
+
+	root := browseRootOf(roots, resolved)
+	if root == "" {
+		return nil, fmt.Errorf("path %s is outside the allowed roots", path)
+	}
+
+	entries, err := os.ReadDir(resolved)
+	if err != nil {
+		if os.IsPermission(err) {
+			return nil, fmt.Errorf("permission denied reading %s", resolved)
+		}
+		if info, statErr := os.Stat(resolved); statErr == nil && !info.IsDir() {
+			return nil, fmt.Errorf("%s is not a directory", resolved)
+		}
+		return nil, fmt.Errorf("failed to read %s: %v", resolved, err)
+	}
+
+	listing.Path = resolved
+	if resolved != root {
+		listing.Parent = filepath.Dir(resolved)
+	}
+
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		entryPath := filepath.Join(resolved, entry.Name())
+		if entry.Type()&os.ModeSymlink != 0 {
+			// Only follow links to directories that stay inside the root
+			target, err := filepath.EvalSymlinks(entryPath)
+			if err != nil || !isWithinDirectory(root, target) {
+				continue
+			}
+			if info, err := os.Stat(target); err != nil || !info.IsDir() {
+				continue
+			}
+		} else if !entry.IsDir() {
+			continue
+		}
+
+		if len(listing.Entries) == maxDirectoryEntries {
+			listing.Truncated = true
+			break
+		}
+		listing.Entries = append(listing.Entries, describeDirectory(entry.Name(), entryPath))
+	}
+
+	sort.Slice(listing.Entries, func(i, j int) bool {
+		return strings.ToLower(listing.Entries[i].Name) < strings.ToLower(listing.Entries[j].Name)
+	})
+	return listing, nil
+}
+
+// describeDirectory checks a directory for a git repository and build files
+func describeDirectory(name, path string) DirectoryEntry {
+	entry := DirectoryEntry{Name: name, Path: path, BuildFiles: []string{}}
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		entry.IsGitRepo = true
+	}
+	for _, file := range projectBuildFiles {
+		if _, err := os.Stat(filepath.Join(path, file)); err == nil {
+			entry.BuildFiles = append(entry.BuildFiles, file)
+		}
+	}
+	return entry
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newBrowseTestRoot creates a browse root with a few projects and a
+// directory outside it, and points VERTEX_FS_ROOTS at the root
+func newBrowseTestRoot(t *testing.T) (string, string) {
+	t.Helper()
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(base, "projects")
+	outside := filepath.Join(base, "outside")
+
+	for _, dir := range []string{
+		filepath.Join(root, "api", ".git"),
+		filepath.Join(root, "Web"),
+		filepath.Join(root, "zeta"),
+		filepath.Join(root, ".hidden"),
+		filepath.Join(outside, "secrets"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{
+		filepath.Join(root, "api", "pom.xml"),
+		filepath.Join(root, "Web", "package.json"),
+		filepath.Join(root, "README.md"),
+	} {
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("VERTEX_FS_ROOTS", root)
+	return root, outside
+}
+
+func TestIsWithinDirectory(t *testing.T) {
+	tests := []struct {
+		dir  string
+		path string
+		want bool
+	}{
+		{"/home/dev", "/home/dev", true},
+		{"/home/dev", "/home/dev/projects/api", true},
+		{"/home/dev", "/home/dev/../other", false},
+		{"/home/dev", "/home", false},
+		{"/home/dev", "/home/developer", false},
+		{"/home/dev", "/home/dev/..hidden", true},
+	}
+
+	for _, tt := range tests {
+		if got := isWithinDirectory(tt.dir, tt.path); got != tt.want {
+			t.Errorf("isWithinDirectory(%q, %q): got %v want %v", tt.dir, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestBrowseDirectories_Roots(t *testing.T) {
+	root, _ := newBrowseTestRoot(t)
+	sm := newTestManager(t)
+
+	listing, err := sm.BrowseDirectories("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listing.Roots) != 1 || listing.Roots[0] != root {
+		t.Errorf("Expected the configured root, got %v", listing.Roots)
+	}
+	if len(listing.Entries) != 1 || listing.Entries[0].Path != root {
+		t.Errorf("Expected the root as the only entry, got %+v", listing.Entries)
+	}
+}
+
+func TestBrowseRoots_SkipsMissingAndNestedRoots(t *testing.T) {
+	root, _ := newBrowseTestRoot(t)
+	t.Setenv("VERTEX_FS_ROOTS", strings.Join([]string{root, filepath.Join(root, "api"), filepath.Join(root, "missing"), " "}, string(os.PathListSeparator)))
+	sm := newTestManager(t)
+
+	roots := sm.browseRoots()
+	if len(roots) != 1 || roots[0] != root {
+		t.Errorf("Expected only the outer root, got %v", roots)
+	}
+}
+
+func TestBrowseDirectories_ListsProjects(t *testing.T) {
+	root, _ := newBrowseTestRoot(t)
+	sm := newTestManager(t)
+
+	listing, err := sm.BrowseDirectories(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if listing.Path != root || listing.Parent != "" {
+		t.Errorf("Expected the root without a parent, got path %q parent %q", listing.Path, listing.Parent)
+	}
+
+	var names []string
+	for _, entry := range listing.Entries {
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, ",") != "api,Web,zeta" {
+		t.Errorf("Expected sorted visible directories only, got %v", names)
+	}
+
+	api := listing.Entries[0]
+	if !api.IsGitRepo || len(api.BuildFiles) != 1 || api.BuildFiles[0] != "pom.xml" {
+		t.Errorf("Expected api to be a git repository with a pom.xml, got %+v", api)
+	}
+	web := listing.Entries[1]
+	if web.IsGitRepo || len(web.BuildFiles) != 1 || web.BuildFiles[0] != "package.json" {
+		t.Errorf("Expected Web to have a package.json, got %+v", web)
+	}
+
+	sub, err := sm.BrowseDirectories(filepath.Join(root, "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Parent != root {
+		t.Errorf("Expected the parent to be the root, got %q", sub.Parent)
+	}
+}
+
+func TestBrowseDirectories_ConfinedToRoots(t *testing.T) {
+	root, outside := newBrowseTestRoot(t)
+	sm := newTestManager(t)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"outside directory", outside, "outside the allowed roots"},
+		{"dot-dot escape", filepath.Join(root, "..", "outside"), "outside the allowed roots"},
+		{"filesystem root", "/", "outside the allowed roots"},
+		{"missing directory", filepath.Join(root, "missing"), "not found"},
+		{"missing directory outside", filepath.Join(outside, "missing"), "outside the allowed roots"},
+		{"missing directory past a dot-dot escape", filepath.Join(root, "..", "outside", "missing"), "outside the allowed roots"},
+		{"file", filepath.Join(root, "README.md"), "is not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sm.BrowseDirectories(tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestBrowseDirectories_Symlinks(t *testing.T) {
+	root, outside := newBrowseTestRoot(t)
+	sm := newTestManager(t)
+
+	// Create a link leading out of the root and one staying inside it
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "api"), filepath.Join(root, "api-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	listing, err := sm.BrowseDirectories(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range listing.Entries {
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, ",") != "api,api-link,Web,zeta" {
+		t.Errorf("Expected only the link inside the root to be listed, got %v", names)
+	}
+
+	if _, err := sm.BrowseDirectories(filepath.Join(root, "escape")); err == nil || !strings.Contains(err.Error(), "outside the allowed roots") {
+		t.Errorf("Expected browsing through a link out of the root to be refused, got %v", err)
+	}
+	if _, err := sm.BrowseDirectories(filepath.Join(root, "escape", "secrets")); err == nil || !strings.Contains(err.Error(), "outside the allowed roots") {
+		t.Errorf("Expected browsing below a link out of the root to be refused, got %v", err)
+	}
+	if _, err := sm.BrowseDirectories(filepath.Join(root, "escape", "missing")); err == nil || !strings.Contains(err.Error(), "outside the allowed roots") {
+		t.Errorf("Expected a missing path below a link out of the root to be refused like an existing one, got %v", err)
+	}
+}
+
+func TestBrowseDirectories_Truncated(t *testing.T) {
+	root, _ := newBrowseTestRoot(t)
+	sm := newTestManager(t)
+
+	many := filepath.Join(root, "many")
+	for i := 0; i <= maxDirectoryEntries; i++ {
+		if err := os.MkdirAll(filepath.Join(many, fmt.Sprintf("d%04d", i)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	listing, err := sm.BrowseDirectories(many)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !listing.Truncated || len(listing.Entries) != maxDirectoryEntries {
+		t.Errorf("Expected %d entries and a truncated listing, got %d and %v", maxDirectoryEntries, len(listing.Entries), listing.Truncated)
+	}
+}
@@ -0,0 +1,549 @@
+// Package services - Gateway route inspection and test console
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	gatewayRequestTimeout  = 30 * time.Second
+	gatewayMaxResponseBody = 64 * 1024
+)
+
+var (
+	gatewayPathsRegex       = regexp.MustCompile(`Paths: \[([^\]]*)\]`)
+	gatewayStripPrefixRegex = regexp.MustCompile(`StripPrefix(?:\s+parts\s*=\s*|=)(\d+)`)
+	gatewayRouteKeyRegex    = regexp.MustCompile(`^spring\.cloud\.gateway(?:\.server\.webflux)?\.routes\[(\d+)\]\.(\w+)(?:\[(\d+)\])?$`)
+)
+
+// GatewayRoute is a route of a Spring Cloud Gateway service together with the
+// managed service it forwards to, when that can be resolved
+type GatewayRoute struct {
+	ID                string   `json:"id"`
+	URI               string   `json:"uri"`
+	Order             int      `json:"order"`
+	Predicates        []string `json:"predicates"`
+	Filters           []string `json:"filters"`
+	Paths             []string `json:"paths"`
+	StripPrefix       int      `json:"stripPrefix"`
+	TargetServiceID   string   `json:"targetServiceId,omitempty"`
+	TargetServiceName string   `json:"targetServiceName,omitempty"`
+	TargetPort        int      `json:"targetPort,omitempty"`
+}
+
+// GatewayRoutes lists a gateway's routes and where they were read from
+type GatewayRoutes struct {
+	GatewayID   string         `json:"gatewayId"`
+	GatewayName string         `json:"gatewayName"`
+	Source      string         `json:"source"` // "actuator" or "config"
+	Routes      []GatewayRoute `json:"routes"`
+}
+
+// GatewayTestRequest is a request sent through the gateway from the console
+type GatewayTestRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	// Also call the target service directly to separate gateway overhead from
+	// upstream time. Always done for GET and HEAD; opt-in for other methods
+	// since the request is then executed twice.
+	CompareUpstream bool `json:"compareUpstream"`
+}
+
+// RequestTimings are the phases of a single HTTP exchange, in milliseconds
+type RequestTimings struct {
+	ConnectMs   float64 `json:"connectMs"`
+	FirstByteMs float64 `json:"firstByteMs"`
+	TotalMs     float64 `json:"totalMs"`
+}
+
+// GatewayExchange is the outcome of one request
+type GatewayExchange struct {
+	URL        string            `json:"url"`
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	Truncated  bool              `json:"truncated,omitempty"`
+	Timings    RequestTimings    `json:"timings"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// GatewayTestResult reports the downstream (client to gateway) exchange, the
+// route that matched and, when compared, the direct upstream exchange
+type GatewayTestResult struct {
+	Route             *GatewayRoute    `json:"route,omitempty"`
+	Downstream        GatewayExchange  `json:"downstream"`
+	Upstream          *GatewayExchange `json:"upstream,omitempty"`
+	GatewayOverheadMs *float64         `json:"gatewayOverheadMs,omitempty"`
+}
+
+// GetGatewayRoutes reads a gateway's routes from the Spring Cloud Gateway
+// actuator endpoint, falling back to its configuration files
+func (sm *Manager) GetGatewayRoutes(serviceUUID, projectsDir string) (*GatewayRoutes, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	result := &GatewayRoutes{GatewayID: service.ID, GatewayName: service.Name}
+	running := service.Status == "running"
+	baseURL := serviceBaseURL(service)
+	service.Mutex.RUnlock()
+
+	var routes []GatewayRoute
+	var actuatorErr error
+	if running {
+		routes, actuatorErr = fetchActuatorGatewayRoutes(baseURL)
+		if actuatorErr == nil {
+			result.Source = "actuator"
+		}
+	}
+
+	if result.Source == "" {
+		files, err := sm.GetServiceFilesWithProjectsDir(serviceUUID, projectsDir)
+		if err != nil {
+			if actuatorErr != nil {
+				return nil, fmt.Errorf("could not read routes from the actuator (%v) or configuration files (%v)", actuatorErr, err)
+			}
+			return nil, err
+		}
+		routes = parseGatewayRouteConfig(files)
+		result.Source = "config"
+	}
+
+	for i := range routes {
+		sm.resolveRouteTarget(&routes[i])
+	}
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Order < routes[j].Order })
+
+	result.Routes = routes
+	return result, nil
+}
+
+// TestGatewayRoute sends a request through the gateway and times it
+func (sm *Manager) TestGatewayRoute(serviceUUID, projectsDir string, req GatewayTestRequest) (*GatewayTestResult, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	req.Method = strings.ToUpper(strings.TrimSpace(req.Method))
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if !strings.HasPrefix(req.Path, "/") {
+		req.Path = "/" + req.Path
+	}
+
+	service.Mutex.RLock()
+	baseURL := serviceBaseURL(service)
+	service.Mutex.RUnlock()
+
+	result := &GatewayTestResult{}
+	if routes, err := sm.GetGatewayRoutes(serviceUUID, projectsDir); err == nil {
+		requestPath, _, _ := strings.Cut(req.Path, "?")
+		for i := range routes.Routes {
+			if routeMatchesPath(routes.Routes[i], requestPath) {
+				result.Route = &routes.Routes[i]
+				break
+			}
+		}
+	}
+
+	result.Downstream = executeTimedRequest(req.Method, baseURL+req.Path, req.Headers, req.Body)
+
+	safeMethod := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if result.Route != nil && result.Route.TargetPort > 0 && (safeMethod || req.CompareUpstream) {
+		upstreamPath := stripPathSegments(req.Path, result.Route.StripPrefix)
+		upstream := executeTimedRequest(req.Method, fmt.Sprintf("http://localhost:%d%s", result.Route.TargetPort, upstreamPath), req.Headers, req.Body)
+		result.Upstream = &upstream
+		if upstream.Error == "" && result.Downstream.Error == "" {
+			overhead := result.Downstream.Timings.TotalMs - upstream.Timings.TotalMs
+			result.GatewayOverheadMs = &overhead
+		}
+	}
+
+	return result, nil
+}
+
+func serviceBaseURL(service *models.Service) string {
+	return fmt.Sprintf("http://localhost:%d", service.Port)
+}
+
+// fetchActuatorGatewayRoutes reads GET /actuator/gateway/routes
+func fetchActuatorGatewayRoutes(baseURL string) ([]GatewayRoute, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(baseURL + "/actuator/gateway/routes")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway actuator returned status %d", resp.StatusCode)
+	}
+
+	var entries []struct {
+		RouteID   string   `json:"route_id"`
+		URI       string   `json:"uri"`
+		Order     int      `json:"order"`
+		Predicate string   `json:"predicate"`
+		Filters   []string `json:"filters"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid gateway routes response: %w", err)
+	}
+
+	routes := make([]GatewayRoute, 0, len(entries))
+	for _, entry := range entries {
+		route := GatewayRoute{
+			ID:         entry.RouteID,
+			URI:        entry.URI,
+			Order:      entry.Order,
+			Predicates: []string{entry.Predicate},
+			Filters:    entry.Filters,
+		}
+		for _, match := range gatewayPathsRegex.FindAllStringSubmatch(entry.Predicate, -1) {
+			for _, path := range strings.Split(match[1], ",") {
+				if path = strings.TrimSpace(path); path != "" {
+					route.Paths = append(route.Paths, path)
+				}
+			}
+		}
+		route.StripPrefix = stripPrefixFromFilters(entry.Filters)
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// parseGatewayRouteConfig reads spring.cloud.gateway routes from YAML and
+// properties configuration files
+func parseGatewayRouteConfig(files []ServiceFile) []GatewayRoute {
+	var routes []GatewayRoute
+	for _, file := range files {
+		switch file.Type {
+		case "yml", "yaml":
+			routes = append(routes, parseGatewayYAMLRoutes(file.Content)...)
+		case "properties":
+			routes = append(routes, parseGatewayPropertiesRoutes(file.Content)...)
+		}
+	}
+
+	for i := range routes {
+		route := &routes[i]
+		for _, predicate := range route.Predicates {
+			name, args, found := strings.Cut(predicate, "=")
+			if !found || strings.TrimSpace(name) != "Path" {
+				continue
+			}
+			for _, path := range strings.Split(args, ",") {
+				if path = strings.TrimSpace(path); path != "" {
+					route.Paths = append(route.Paths, path)
+				}
+			}
+		}
+		route.StripPrefix = stripPrefixFromFilters(route.Filters)
+	}
+	return routes
+}
+
+type yamlGatewayRoute struct {
+	ID         string      `yaml:"id"`
+	URI        string      `yaml:"uri"`
+	Order      int         `yaml:"order"`
+	Predicates []yaml.Node `yaml:"predicates"`
+	Filters    []yaml.Node `yaml:"filters"`
+}
+
+func parseGatewayYAMLRoutes(content string) []GatewayRoute {
+	var routes []GatewayRoute
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var document struct {
+			Spring struct {
+				Cloud struct {
+					Gateway struct {
+						Routes []yamlGatewayRoute `yaml:"routes"`
+						Server struct {
+							Webflux struct {
+								Routes []yamlGatewayRoute `yaml:"routes"`
+							} `yaml:"webflux"`
+						} `yaml:"server"`
+					} `yaml:"gateway"`
+				} `yaml:"cloud"`
+			} `yaml:"spring"`
+		}
+		if err := decoder.Decode(&document); err != nil {
+			break
+		}
+
+		gateway := document.Spring.Cloud.Gateway
+		for _, entry := range append(gateway.Routes, gateway.Server.Webflux.Routes...) {
+			routes = append(routes, GatewayRoute{
+				ID:         entry.ID,
+				URI:        entry.URI,
+				Order:      entry.Order,
+				Predicates: yamlShortcuts(entry.Predicates),
+				Filters:    yamlShortcuts(entry.Filters),
+			})
+		}
+	}
+	return routes
+}
+
+// yamlShortcuts turns predicate/filter entries into the "Name=args" shortcut
+// form, whether written as shortcuts or as {name, args} maps
+func yamlShortcuts(nodes []yaml.Node) []string {
+	shortcuts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Kind == yaml.ScalarNode {
+			shortcuts = append(shortcuts, node.Value)
+			continue
+		}
+		var full struct {
+			Name string            `yaml:"name"`
+			Args map[string]string `yaml:"args"`
+		}
+		if err := node.Decode(&full); err != nil || full.Name == "" {
+			continue
+		}
+		keys := make([]string, 0, len(full.Args))
+		for key := range full.Args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, full.Args[key])
+		}
+		shortcuts = append(shortcuts, full.Name+"="+strings.Join(values, ","))
+	}
+	return shortcuts
+}
+
+func parseGatewayPropertiesRoutes(content string) []GatewayRoute {
+	byIndex := make(map[int]*GatewayRoute)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		match := gatewayRouteKeyRegex.FindStringSubmatch(strings.TrimSpace(key))
+		if match == nil {
+			continue
+		}
+
+		index, _ := strconv.Atoi(match[1])
+		route, exists := byIndex[index]
+		if !exists {
+			route = &GatewayRoute{}
+			byIndex[index] = route
+		}
+
+		value = strings.TrimSpace(value)
+		switch match[2] {
+		case "id":
+			route.ID = value
+		case "uri":
+			route.URI = value
+		case "order":
+			route.Order, _ = strconv.Atoi(value)
+		case "predicates":
+			route.Predicates = append(route.Predicates, value)
+		case "filters":
+			route.Filters = append(route.Filters, value)
+		}
+	}
+
+	indexes := make([]int, 0, len(byIndex))
+	for index := range byIndex {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	routes := make([]GatewayRoute, 0, len(indexes))
+	for _, index := range indexes {
+		routes = append(routes, *byIndex[index])
+	}
+	return routes
+}
+
+func stripPrefixFromFilters(filters []string) int {
+	for _, filter := range filters {
+		if match := gatewayStripPrefixRegex.FindStringSubmatch(filter); match != nil {
+			parts, _ := strconv.Atoi(match[1])
+			return parts
+		}
+	}
+	return 0
+}
+
+// resolveRouteTarget links a route to the managed service it forwards to:
+// lb://NAME by service name, http://host:port by port
+func (sm *Manager) resolveRouteTarget(route *GatewayRoute) {
+	parsed, err := url.Parse(route.URI)
+	if err != nil {
+		return
+	}
+
+	var target *models.Service
+	switch parsed.Scheme {
+	case "lb":
+		for _, service := range sm.GetServices() {
+			if strings.EqualFold(service.Name, parsed.Host) {
+				target, _ = sm.GetServiceByUUID(service.ID)
+				break
+			}
+		}
+	case "http", "https":
+		port, _ := strconv.Atoi(parsed.Port())
+		for _, service := range sm.GetServices() {
+			if port > 0 && service.Port == port {
+				target, _ = sm.GetServiceByUUID(service.ID)
+				break
+			}
+		}
+	}
+
+	if target == nil {
+		return
+	}
+	target.Mutex.RLock()
+	route.TargetServiceID = target.ID
+	route.TargetServiceName = target.Name
+	route.TargetPort = target.Port
+	target.Mutex.RUnlock()
+}
+
+// routeMatchesPath applies a route's Path predicates using Spring's pattern
+// syntax: * and {var} match one segment, ** matches the rest
+func routeMatchesPath(route GatewayRoute, requestPath string) bool {
+	for _, pattern := range route.Paths {
+		if pathPatternMatches(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(strings.Trim(requestPath, "/"), "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathPatternMatches(pattern, segments []string) bool {
+	for i, part := range pattern {
+		if part == "**" {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if part == "*" || (strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}")) {
+			continue
+		}
+		if part != segments[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(segments)
+}
+
+// stripPathSegments mirrors the StripPrefix filter
+func stripPathSegments(path string, parts int) string {
+	if parts <= 0 {
+		return path
+	}
+	pathOnly, query, hasQuery := strings.Cut(path, "?")
+	segments := strings.Split(strings.TrimPrefix(pathOnly, "/"), "/")
+	if parts >= len(segments) {
+		segments = nil
+	} else {
+		segments = segments[parts:]
+	}
+	stripped := "/" + strings.Join(segments, "/")
+	if hasQuery {
+		stripped += "?" + query
+	}
+	return stripped
+}
+
+// executeTimedRequest performs a request and records connect, first byte and total time
+func executeTimedRequest(method, targetURL string, headers map[string]string, body string) GatewayExchange {
+	exchange := GatewayExchange{URL: targetURL}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayRequestTimeout)
+	defer cancel()
+
+	var start, connectStart time.Time
+	trace := &httptrace.ClientTrace{
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(string, string, error) {
+			exchange.Timings.ConnectMs = elapsedMs(connectStart)
+		},
+		GotFirstResponseByte: func() { exchange.Timings.FirstByteMs = elapsedMs(start) },
+	}
+
+	var bodyReader io.Reader
+	if body != "" {
+		bodyReader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), method, targetURL, bodyReader)
+	if err != nil {
+		exchange.Error = err.Error()
+		return exchange
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	// A fresh transport so connection setup is measured for every test
+	client := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start = time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		exchange.Timings.TotalMs = elapsedMs(start)
+		exchange.Error = err.Error()
+		return exchange
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, gatewayMaxResponseBody+1))
+	exchange.Timings.TotalMs = elapsedMs(start)
+	exchange.StatusCode = resp.StatusCode
+	if len(data) > gatewayMaxResponseBody {
+		data = data[:gatewayMaxResponseBody]
+		exchange.Truncated = true
+	}
+	exchange.Body = string(data)
+	exchange.Headers = make(map[string]string, len(resp.Header))
+	for name := range resp.Header {
+		exchange.Headers[name] = resp.Header.Get(name)
+	}
+	return exchange
+}
+
+func elapsedMs(since time.Time) float64 {
+	return float64(time.Since(since).Microseconds()) / 1000
+}
@@ -301,3 +301,45 @@ func GetGitStatus(dir string) (*GitStatus, error) {
 
 	return status, nil
 }
+
+// GetFileDiff returns the git status code and unified diff of a file against HEAD.
+// Status is "modified", "untracked", "added", "deleted" or "clean".
+func GetFileDiff(dir, relPath string) (string, string, error) {
+	if !IsGitRepository(dir) {
+		return "", "", fmt.Errorf("not a git repository")
+	}
+
+	statusCmd := exec.Command("git", "status", "--porcelain", "--", relPath)
+	statusCmd.Dir = dir
+	statusOutput, err := statusCmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to check git status: %w", err)
+	}
+
+	line := strings.TrimRight(string(statusOutput), "\n")
+	if line == "" {
+		return "clean", "", nil
+	}
+
+	var status string
+	switch {
+	case strings.HasPrefix(line, "??"):
+		// Untracked files have nothing committed to compare against
+		return "untracked", "", nil
+	case strings.Contains(line[:2], "A"):
+		status = "added"
+	case strings.Contains(line[:2], "D"):
+		status = "deleted"
+	default:
+		status = "modified"
+	}
+
+	diffCmd := exec.Command("git", "diff", "HEAD", "--", relPath)
+	diffCmd.Dir = dir
+	diffOutput, err := diffCmd.Output()
+	if err != nil {
+		return status, "", fmt.Errorf("failed to get diff: %w", err)
+	}
+
+	return status, string(diffOutput), nil
+}
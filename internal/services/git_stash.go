@@ -0,0 +1,313 @@
+// Package services - Stashing uncommitted changes around branch switches
+package services
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// vertexStashPrefix starts the message of every stash Vertex creates,
+// followed by the branch the changes were made on
+const vertexStashPrefix = "vertex-autostash: "
+
+// VertexStash is a stash Vertex made of uncommitted changes before switching
+// branches. Commit identifies it; Ref changes as stashes are added and removed.
+type VertexStash struct {
+	Ref       string    `json:"ref"`
+	Commit    string    `json:"commit"`
+	Branch    string    `json:"branch"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BranchSwitchStash reports what a branch switch did with uncommitted
+// changes: the stash it made of those on the old branch, and the stash of the
+// new branch it put back
+type BranchSwitchStash struct {
+	Stashed      *VertexStash `json:"stashed,omitempty"`
+	Restored     *VertexStash `json:"restored,omitempty"`
+	RestoreError string       `json:"restoreError,omitempty"`
+}
+
+// ListVertexStashes returns the stashes Vertex made in a repository, newest first
+func ListVertexStashes(dir string) ([]VertexStash, error) {
+	if !IsGitRepository(dir) {
+		return nil, fmt.Errorf("not a git repository")
+	}
+
+	cmd := exec.Command("git", "stash", "list", "--format=%gd%x1f%H%x1f%ct%x1f%gs")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stashes: %w", err)
+	}
+
+	stashes := []VertexStash{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		_, branch, found := strings.Cut(fields[3], vertexStashPrefix)
+		if !found {
+			continue
+		}
+		created, _ := strconv.ParseInt(fields[2], 10, 64)
+		stashes = append(stashes, VertexStash{
+			Ref:       fields[0],
+			Commit:    fields[1],
+			Branch:    strings.TrimSpace(branch),
+			CreatedAt: time.Unix(created, 0),
+		})
+	}
+
+	return stashes, nil
+}
+
+// findVertexStash returns the Vertex stash with the given commit
+func findVertexStash(dir, commit string) (*VertexStash, error) {
+	stashes, err := ListVertexStashes(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, stash := range stashes {
+		if stash.Commit == commit || (len(commit) >= 7 && strings.HasPrefix(stash.Commit, commit)) {
+			return &stash, nil
+		}
+	}
+	return nil, fmt.Errorf("stash %s not found", commit)
+}
+
+// StashChanges stashes the uncommitted changes of a repository, untracked
+// files included, as changes made on the given branch
+func StashChanges(dir, branch string) (*VertexStash, error) {
+	cmd := exec.Command("git", "stash", "push", "--include-untracked", "-m", vertexStashPrefix+branch)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to stash changes: %s", strings.TrimSpace(string(output)))
+	}
+
+	stashes, err := ListVertexStashes(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(stashes) == 0 || stashes[0].Ref != "stash@{0}" {
+		return nil, fmt.Errorf("failed to stash changes: the stash was not created")
+	}
+	return &stashes[0], nil
+}
+
+// ApplyVertexStash puts a Vertex stash back into a clean working tree and
+// drops it. When it does not apply cleanly the working tree is reset and the
+// stash kept, so it can be applied by hand.
+func ApplyVertexStash(dir, commit string) (*VertexStash, error) {
+	stash, err := findVertexStash(dir, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	dirty, err := HasUncommittedChanges(dir)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("cannot apply stash: the working tree has uncommitted changes")
+	}
+
+	applyCmd := exec.Command("git", "stash", "apply", stash.Ref)
+	applyCmd.Dir = dir
+	if output, err := applyCmd.CombinedOutput(); err != nil {
+		// The tree was clean, so everything in it now came from the stash
+		for _, args := range [][]string{{"reset", "--hard", "-q"}, {"clean", "-fdq", "--", ":/"}} {
+			cleanupCmd := exec.Command("git", args...)
+			cleanupCmd.Dir = dir
+			cleanupCmd.Run()
+		}
+		return nil, fmt.Errorf("stash %s does not apply cleanly and was kept: %s", stash.Ref, gitFailureReason(string(output)))
+	}
+
+	if err := dropStash(dir, stash.Ref); err != nil {
+		return stash, err
+	}
+	return stash, nil
+}
+
+// gitFailureReason picks the lines of git's output that say why it failed,
+// such as the files with conflicts
+func gitFailureReason(output string) string {
+	reasons := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "CONFLICT") || strings.HasPrefix(line, "error:") {
+			reasons = append(reasons, strings.TrimSpace(line))
+		}
+	}
+	if len(reasons) == 0 {
+		return strings.TrimSpace(output)
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// DropVertexStash deletes a Vertex stash without applying it
+func DropVertexStash(dir, commit string) error {
+	stash, err := findVertexStash(dir, commit)
+	if err != nil {
+		return err
+	}
+	return dropStash(dir, stash.Ref)
+}
+
+func dropStash(dir, ref string) error {
+	cmd := exec.Command("git", "stash", "drop", "-q", ref)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to drop stash %s: %s", ref, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// SwitchBranchWithAutoStash switches branches like SwitchBranch, stashing
+// uncommitted changes first instead of refusing. After the switch the newest
+// Vertex stash of the new branch, left by an earlier switch away from it, is
+// put back. If the switch fails the stashed changes are restored.
+func SwitchBranchWithAutoStash(dir, branch string) (*BranchSwitchStash, error) {
+	if !IsGitRepository(dir) {
+		return nil, fmt.Errorf("not a git repository")
+	}
+
+	result := &BranchSwitchStash{}
+	dirty, err := HasUncommittedChanges(dir)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		current, err := GetCurrentBranch(dir)
+		if err != nil {
+			return nil, err
+		}
+		if result.Stashed, err = StashChanges(dir, current); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := SwitchBranch(dir, branch); err != nil {
+		if result.Stashed != nil {
+			if _, restoreErr := ApplyVertexStash(dir, result.Stashed.Commit); restoreErr != nil {
+				return nil, fmt.Errorf("%w; the uncommitted changes were kept in %s: %v", err, result.Stashed.Ref, restoreErr)
+			}
+		}
+		return nil, err
+	}
+
+	current, err := GetCurrentBranch(dir)
+	if err != nil {
+		return result, nil
+	}
+	stashes, err := ListVertexStashes(dir)
+	if err != nil {
+		result.RestoreError = err.Error()
+		return result, nil
+	}
+	for _, stash := range stashes {
+		if stash.Branch != current || (result.Stashed != nil && stash.Commit == result.Stashed.Commit) {
+			continue
+		}
+		if result.Restored, err = ApplyVertexStash(dir, stash.Commit); err != nil {
+			result.RestoreError = err.Error()
+		}
+		break
+	}
+
+	return result, nil
+}
+
+// serviceGitDirectory returns the directory of a service's repository
+func (sm *Manager) serviceGitDirectory(serviceUUID string) (*models.Service, string, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, "", fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	projectsDir := sm.getServiceProjectsDirectory(serviceUUID)
+	if projectsDir == "" {
+		projectsDir = sm.config.ProjectsDir
+	}
+
+	service.Mutex.RLock()
+	dir := filepath.Join(projectsDir, service.Dir)
+	service.Mutex.RUnlock()
+
+	if !IsGitRepository(dir) {
+		if root := gitRepositoryRoot(dir); root != "" {
+			return service, root, nil
+		}
+	}
+	return service, dir, nil
+}
+
+// GetServiceStashes lists the stashes Vertex made in a service's repository
+func (sm *Manager) GetServiceStashes(serviceUUID string) ([]VertexStash, error) {
+	_, dir, err := sm.serviceGitDirectory(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	return ListVertexStashes(dir)
+}
+
+// ApplyServiceStash puts a Vertex stash back into a service's repository
+func (sm *Manager) ApplyServiceStash(serviceUUID, commit string) (*VertexStash, error) {
+	service, dir, err := sm.serviceGitDirectory(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	stash, err := ApplyVertexStash(dir, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Applied stash %s of branch %s to service %s", stash.Commit, stash.Branch, service.Name)
+	return stash, nil
+}
+
+// DropServiceStash deletes a Vertex stash from a service's repository
+func (sm *Manager) DropServiceStash(serviceUUID, commit string) error {
+	service, dir, err := sm.serviceGitDirectory(serviceUUID)
+	if err != nil {
+		return err
+	}
+
+	if err := DropVertexStash(dir, commit); err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Dropped stash %s of service %s", commit, service.Name)
+	return nil
+}
+
+// describeStash summarizes what a branch switch did with uncommitted changes,
+// for the service's event log
+func describeStash(stash *BranchSwitchStash) string {
+	if stash == nil {
+		return ""
+	}
+	parts := []string{}
+	if stash.Stashed != nil {
+		parts = append(parts, fmt.Sprintf("stashed uncommitted changes of %s", stash.Stashed.Branch))
+	}
+	if stash.Restored != nil {
+		parts = append(parts, fmt.Sprintf("restored the changes stashed on %s", stash.Restored.Branch))
+	}
+	if stash.RestoreError != "" {
+		parts = append(parts, "could not restore stashed changes: "+stash.RestoreError)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "; " + strings.Join(parts, "; ")
+}
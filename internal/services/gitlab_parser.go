@@ -214,7 +214,7 @@ func (sm *Manager) InstallLibrariesWithProjectsDir(serviceUUID string, libraries
 		}
 
 		// Execute the Maven install command
-		if err := sm.executeMavenCommand(serviceDir, library.Command); err != nil {
+		if err := sm.executeMavenCommand(serviceDir, library.Command, sm.getServiceProfileID(serviceUUID)); err != nil {
 			return fmt.Errorf("failed to install library %s:%s:%s: %w",
 				library.GroupID, library.ArtifactID, library.Version, err)
 		}
@@ -227,8 +227,9 @@ func (sm *Manager) InstallLibrariesWithProjectsDir(serviceUUID string, libraries
 	return nil
 }
 
-// executeMavenCommand executes a Maven command in the specified directory
-func (sm *Manager) executeMavenCommand(workDir, command string) error {
+// executeMavenCommand executes a Maven command in the specified directory with
+// the repository credentials of the given profile
+func (sm *Manager) executeMavenCommand(workDir, command, profileID string) error {
 	// Use Maven wrapper if available, otherwise fall back to mvn
 	mvnCommand := "./mvnw"
 	if _, err := os.Stat(filepath.Join(workDir, "mvnw")); os.IsNotExist(err) {
@@ -242,6 +243,8 @@ func (sm *Manager) executeMavenCommand(workDir, command string) error {
 
 	// Use the existing Maven execution pattern from startService
 	cmd := fmt.Sprintf("cd %s && %s", workDir, fullCommand)
+	cmd, credentialEnv := sm.applyRepositoryCredentials(cmd, BuildSystemMaven, profileID, filepath.Base(workDir))
+	cmd = sm.applyBuildSettings(cmd, BuildSystemMaven, profileID, filepath.Base(workDir))
 
 	// Get global environment variables for Maven execution
 	globalEnvVars, err := sm.GetGlobalEnvVars()
@@ -249,6 +252,9 @@ func (sm *Manager) executeMavenCommand(workDir, command string) error {
 		log.Printf("[WARN] Failed to load global environment variables: %v", err)
 		globalEnvVars = make(map[string]string)
 	}
+	for key, value := range credentialEnv {
+		globalEnvVars[key] = value
+	}
 
 	// Execute the command using the same approach as service startup
 	return sm.executeCommand(cmd, globalEnvVars)
@@ -2,16 +2,35 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math/rand"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/zechtz/vertex/internal/models"
 )
 
+const (
+	defaultHealthInterval     = 30 * time.Second
+	minHealthInterval         = 5 * time.Second
+	maxConcurrentHealthChecks = 8
+	healthSchedulerTick       = time.Second
+)
+
+// Services with a health probe in flight, so slow endpoints don't pile up
+// overlapping checks
+var (
+	healthChecksInFlight      = make(map[string]bool)
+	healthChecksInFlightMutex sync.Mutex
+)
+
+// healthCheckSlots bounds how many scheduled health checks run at once
+var healthCheckSlots = make(chan struct{}, maxConcurrentHealthChecks)
+
 func (sm *Manager) CheckServiceHealth(serviceName string) error {
 	sm.mutex.RLock()
 	service, exists := sm.services[serviceName]
@@ -21,23 +40,31 @@ func (sm *Manager) CheckServiceHealth(serviceName string) error {
 		return fmt.Errorf("service %s not found", serviceName)
 	}
 
-	go sm.checkServiceHealth(service)
+	go sm.checkServiceHealth(sm.ctx, service)
 	return nil
 }
 
-func (sm *Manager) healthCheckRoutine() {
-	ticker := time.NewTicker(30 * time.Second) // Check every 30 seconds
+// healthCheckRoutine checks every service on its own interval. Each service
+// gets a random initial offset and a jittered next check, so a large profile
+// is spread over the interval instead of probed in one burst.
+func (sm *Manager) healthCheckRoutine(ctx context.Context) {
+	ticker := time.NewTicker(healthSchedulerTick)
 	defer ticker.Stop()
 
+	nextCheck := make(map[string]time.Time)
 	for {
 		select {
-		case <-ticker.C:
-			sm.performHealthChecks()
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sm.performHealthChecks(ctx, now, nextCheck)
 		}
 	}
 }
 
-func (sm *Manager) performHealthChecks() {
+// performHealthChecks dispatches the checks that are due and schedules their
+// next run
+func (sm *Manager) performHealthChecks(ctx context.Context, now time.Time, nextCheck map[string]time.Time) {
 	sm.mutex.RLock()
 	services := make([]*models.Service, 0, len(sm.services))
 	for _, service := range sm.services {
@@ -45,20 +72,99 @@ func (sm *Manager) performHealthChecks() {
 	}
 	sm.mutex.RUnlock()
 
+	seen := make(map[string]bool, len(services))
 	for _, service := range services {
-		go sm.checkServiceHealth(service)
+		seen[service.ID] = true
+
+		service.Mutex.RLock()
+		interval := healthCheckInterval(service.HealthInterval)
+		service.Mutex.RUnlock()
+
+		due, scheduled := nextCheck[service.ID]
+		if !scheduled {
+			// Spread the first checks over one interval
+			nextCheck[service.ID] = now.Add(time.Duration(rand.Int63n(int64(interval))))
+			continue
+		}
+		if now.Before(due) {
+			// Pick up a shortened interval without waiting out the old one
+			if due.Sub(now) > interval {
+				nextCheck[service.ID] = now.Add(interval)
+			}
+			continue
+		}
+
+		nextCheck[service.ID] = now.Add(jitterHealthInterval(interval))
+		go sm.runScheduledHealthCheck(ctx, service)
+	}
+
+	// Forget deleted services
+	for serviceID := range nextCheck {
+		if !seen[serviceID] {
+			delete(nextCheck, serviceID)
+		}
 	}
 }
 
-func (sm *Manager) checkServiceHealth(service *models.Service) {
+// runScheduledHealthCheck waits for a free slot and checks one service
+func (sm *Manager) runScheduledHealthCheck(ctx context.Context, service *models.Service) {
+	select {
+	case healthCheckSlots <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-healthCheckSlots }()
+
+	sm.checkServiceHealth(ctx, service)
+}
+
+// healthCheckInterval returns the effective interval for a configured number
+// of seconds
+func healthCheckInterval(seconds int) time.Duration {
+	if seconds <= 0 {
+		return defaultHealthInterval
+	}
+	interval := time.Duration(seconds) * time.Second
+	if interval < minHealthInterval {
+		return minHealthInterval
+	}
+	return interval
+}
+
+// jitterHealthInterval varies an interval by up to ±10% so services that share
+// one drift apart
+func jitterHealthInterval(interval time.Duration) time.Duration {
+	spread := int64(interval / 5)
+	return interval - interval/10 + time.Duration(rand.Int63n(spread+1))
+}
+
+// checkServiceHealth refreshes the liveness, uptime and health status of a
+// service. The HTTP probe runs on a detached copy without holding the service
+// mutex; its result is dropped if the service was stopped or restarted
+// meanwhile.
+func (sm *Manager) checkServiceHealth(ctx context.Context, service *models.Service) {
+	healthChecksInFlightMutex.Lock()
+	if healthChecksInFlight[service.ID] {
+		healthChecksInFlightMutex.Unlock()
+		return
+	}
+	healthChecksInFlight[service.ID] = true
+	healthChecksInFlightMutex.Unlock()
+
+	defer func() {
+		healthChecksInFlightMutex.Lock()
+		delete(healthChecksInFlight, service.ID)
+		healthChecksInFlightMutex.Unlock()
+	}()
+
 	service.Mutex.Lock()
-	defer service.Mutex.Unlock()
 
 	// Check if process is still running
 	if service.Status == "running" && service.PID > 0 {
 		// Check if process still exists
 		if !sm.isProcessRunning(service.PID) {
 			log.Printf("Process %d for service %s is no longer running", service.PID, service.Name)
+			sm.recordServiceEvent(service, models.EventCrashed, fmt.Sprintf("Process %d is no longer running", service.PID))
 			service.Status = "stopped"
 			service.HealthStatus = "unknown"
 			service.PID = 0
@@ -66,13 +172,31 @@ func (sm *Manager) checkServiceHealth(service *models.Service) {
 			service.Uptime = ""
 			sm.updateServiceInDB(service)
 			sm.broadcastUpdate(service)
+			service.Mutex.Unlock()
 			return
 		}
 	}
 
 	if service.Status != "running" {
 		service.HealthStatus = "unknown"
+		sm.resetHealthFlapping(service)
+		sm.updateServiceInDB(service)
+		service.Mutex.Unlock()
+		return
+	}
+
+	// A service that pushes its own health is not probed while its last
+	// report is fresh
+	if pushed, ok := freshPushedHealth(service); ok {
+		if !service.LastStarted.IsZero() {
+			service.Uptime = formatDuration(time.Since(service.LastStarted))
+		}
+		previousHealth := service.HealthStatus
+		service.HealthStatus = pushed
+		sm.recordHealthResult(service, previousHealth)
 		sm.updateServiceInDB(service)
+		sm.broadcastUpdate(service)
+		service.Mutex.Unlock()
 		return
 	}
 
@@ -86,6 +210,7 @@ func (sm *Manager) checkServiceHealth(service *models.Service) {
 				service.HealthStatus = "starting"
 				sm.updateServiceInDB(service)
 			}
+			service.Mutex.Unlock()
 			return
 		}
 	}
@@ -96,22 +221,64 @@ func (sm *Manager) checkServiceHealth(service *models.Service) {
 		service.Uptime = formatDuration(uptime)
 	}
 
+	pid := service.PID
+	probe := &models.Service{
+		ID:           service.ID,
+		Name:         service.Name,
+		Port:         service.Port,
+		HealthURL:    service.HealthURL,
+		InfraType:    service.InfraType,
+		LastStarted:  service.LastStarted,
+		HealthStatus: service.HealthStatus,
+	}
+	service.Mutex.Unlock()
+
+	sm.probeServiceHealth(ctx, probe)
+	if ctx.Err() != nil {
+		return
+	}
+
+	service.Mutex.Lock()
+	defer service.Mutex.Unlock()
+
+	if service.Status != "running" || service.PID != pid {
+		// Stopped or restarted while probing; the result is stale
+		return
+	}
+
+	// Record transitions into healthy/unhealthy on the service timeline,
+	// damped while the service is flapping
+	previousHealth := service.HealthStatus
+	service.HealthStatus = probe.HealthStatus
+	sm.recordHealthResult(service, previousHealth)
+
+	// Update database and broadcast
+	sm.updateServiceInDB(service)
+	sm.broadcastUpdate(service)
+}
+
+// probeServiceHealth sets the HealthStatus of a detached service copy from
+// Eureka or, failing that, the service's own health endpoint
+func (sm *Manager) probeServiceHealth(ctx context.Context, service *models.Service) {
+	// Infrastructure neither registers with Eureka nor serves a health endpoint
+	if service.InfraType != "" {
+		probeInfrastructureHealth(ctx, service)
+		return
+	}
+
 	// Try Eureka-based health check first (for microservices that register with Eureka)
-	if sm.checkEurekaHealth(service) {
+	if sm.checkEurekaHealth(ctx, service) {
 		log.Printf("[DEBUG] Health status for %s updated from Eureka: %s", service.Name, service.HealthStatus)
-		sm.updateServiceInDB(service)
-		sm.broadcastUpdate(service)
 		return
 	}
 
 	// Fall back to direct HTTP health check
 	log.Printf("[DEBUG] Using direct health check for %s (not found in Eureka or Eureka unavailable)", service.Name)
-	client := sm.createHealthCheckClient()
-	req, err := sm.createHealthCheckRequest(service.HealthURL)
+	config := sm.healthCheckConfig(service.ID)
+	client := sm.createHealthCheckClient(config)
+	req, err := sm.createHealthCheckRequest(ctx, service.HealthURL, config)
 	if err != nil {
 		service.HealthStatus = "unhealthy"
-		sm.updateServiceInDB(service)
-		sm.broadcastUpdate(service)
 		return
 	}
 
@@ -123,8 +290,6 @@ func (sm *Manager) checkServiceHealth(service *models.Service) {
 			if timeSinceStart < 2*time.Minute {
 				log.Printf("[DEBUG] Health check failed for %s (still initializing): %v", service.Name, err)
 				service.HealthStatus = "starting"
-				sm.updateServiceInDB(service)
-				sm.broadcastUpdate(service)
 				return
 			}
 		}
@@ -133,7 +298,7 @@ func (sm *Manager) checkServiceHealth(service *models.Service) {
 
 		// If health endpoint fails, try a simple connectivity test to the service port
 		simpleURL := fmt.Sprintf("http://localhost:%d/", service.Port)
-		simpleReq, err := http.NewRequest("GET", simpleURL, nil)
+		simpleReq, err := http.NewRequestWithContext(ctx, "GET", simpleURL, nil)
 		if err == nil {
 			simpleResp, err := client.Do(simpleReq)
 			if err == nil {
@@ -190,7 +355,7 @@ func (sm *Manager) checkServiceHealth(service *models.Service) {
 			// Unauthorized - auth issue, but service is running and responding
 			log.Printf("[DEBUG] Health check for %s returned 401 - service is running but requires different auth", service.Name)
 			// Try without auth for services that might not need it
-			reqNoAuth, err := http.NewRequest("GET", service.HealthURL, nil)
+			reqNoAuth, err := http.NewRequestWithContext(ctx, "GET", service.HealthURL, nil)
 			if err == nil {
 				respNoAuth, err := client.Do(reqNoAuth)
 				if err == nil {
@@ -214,10 +379,6 @@ func (sm *Manager) checkServiceHealth(service *models.Service) {
 			service.HealthStatus = "unhealthy"
 		}
 	}
-
-	// Update database and broadcast
-	sm.updateServiceInDB(service)
-	sm.broadcastUpdate(service)
 }
 
 func formatDuration(d time.Duration) string {
@@ -243,25 +404,27 @@ func (sm *Manager) isProcessRunning(pid int) bool {
 	return IsProcessRunning(pid)
 }
 
-// createHealthCheckClient creates an HTTP client for health checks
-func (sm *Manager) createHealthCheckClient() *http.Client {
-	return &http.Client{Timeout: 10 * time.Second} // Increased timeout for Spring Boot services
+// createHealthCheckClient creates an HTTP client for health checks with the
+// service's timeout and TLS settings
+func (sm *Manager) createHealthCheckClient(config *models.HealthCheckConfig) *http.Client {
+	client := &http.Client{Timeout: defaultHealthCheckTimeout, Transport: healthCheckTransport}
+	if config.TimeoutSeconds > 0 {
+		client.Timeout = time.Duration(config.TimeoutSeconds) * time.Second
+	}
+	if config.InsecureSkipVerify {
+		client.Transport = insecureHealthCheckTransport
+	}
+	return client
 }
 
-// createHealthCheckRequest creates an HTTP request for health checks with authentication
-func (sm *Manager) createHealthCheckRequest(healthURL string) (*http.Request, error) {
-	req, err := http.NewRequest("GET", healthURL, nil)
+// createHealthCheckRequest creates an HTTP request for health checks with the
+// service's headers and authentication
+func (sm *Manager) createHealthCheckRequest(ctx context.Context, healthURL string, config *models.HealthCheckConfig) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", healthURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// Add basic auth for Spring Boot services
-	if strings.Contains(healthURL, "actuator/health") {
-		// Get credentials from environment variables
-		username := os.Getenv("CONFIG_USERNAME")
-		password := os.Getenv("CONFIG_PASSWORD")
-		req.SetBasicAuth(username, password)
-	}
-
+	sm.applyHealthCheckConfig(req, config)
 	return req, nil
 }
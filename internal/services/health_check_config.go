@@ -0,0 +1,209 @@
+// Package services - Per-service health check client settings
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+	"golang.org/x/net/http/httpguts"
+)
+
+const (
+	defaultHealthCheckTimeout = 10 * time.Second // Increased timeout for Spring Boot services
+	maxHealthCheckTimeout     = 5 * time.Minute
+)
+
+// GetHealthCheckConfig returns the health check settings of a service, with
+// defaults when none were saved
+func (sm *Manager) GetHealthCheckConfig(serviceUUID string) (*models.HealthCheckConfig, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	config, err := sm.db.GetHealthCheckConfig(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &models.HealthCheckConfig{ServiceID: serviceUUID}
+	}
+	if config.Headers == nil {
+		config.Headers = map[string]string{}
+	}
+	return config, nil
+}
+
+// SetHealthCheckConfig validates and saves a service's health check settings;
+// the next check uses them
+func (sm *Manager) SetHealthCheckConfig(config models.HealthCheckConfig) error {
+	if _, exists := sm.GetServiceByUUID(config.ServiceID); !exists {
+		return fmt.Errorf("service UUID %s not found", config.ServiceID)
+	}
+
+	for name, value := range config.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid header name '%s'", name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("invalid value for header %s", name)
+		}
+		if strings.EqualFold(name, "Authorization") && config.AuthType != models.HealthAuthNone {
+			return fmt.Errorf("set authType to none to send your own Authorization header")
+		}
+	}
+
+	config.UsernameEnv = strings.TrimSpace(config.UsernameEnv)
+	config.PasswordEnv = strings.TrimSpace(config.PasswordEnv)
+	config.TokenEnv = strings.TrimSpace(config.TokenEnv)
+	for _, name := range []string{config.UsernameEnv, config.PasswordEnv, config.TokenEnv} {
+		if name != "" && !envVarNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name '%s'", name)
+		}
+	}
+
+	switch config.AuthType {
+	case models.HealthAuthDefault, models.HealthAuthNone:
+	case models.HealthAuthBasic:
+		if config.UsernameEnv == "" {
+			return fmt.Errorf("basic auth needs usernameEnv")
+		}
+	case models.HealthAuthBearer:
+		if config.TokenEnv == "" {
+			return fmt.Errorf("bearer auth needs tokenEnv")
+		}
+	default:
+		return fmt.Errorf("invalid authType '%s'; use none, basic or bearer", config.AuthType)
+	}
+
+	if config.TimeoutSeconds < 0 || time.Duration(config.TimeoutSeconds)*time.Second > maxHealthCheckTimeout {
+		return fmt.Errorf("timeoutSeconds must be between 0 and %d", int(maxHealthCheckTimeout.Seconds()))
+	}
+
+	return sm.db.SaveHealthCheckConfig(config)
+}
+
+// healthCheckConfig loads the settings a health probe of a service uses,
+// falling back to the defaults when they cannot be read
+func (sm *Manager) healthCheckConfig(serviceUUID string) *models.HealthCheckConfig {
+	config, err := sm.db.GetHealthCheckConfig(serviceUUID)
+	if err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+	if config == nil {
+		config = &models.HealthCheckConfig{ServiceID: serviceUUID}
+	}
+	return config
+}
+
+// healthCheckEnv returns the environment a service's health check credentials
+// and header values are resolved in
+func (sm *Manager) healthCheckEnv(serviceUUID string) map[string]string {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return map[string]string{}
+	}
+	return sm.serviceEnvSettings(service)
+}
+
+// applyHealthCheckConfig adds a service's headers and credentials to a
+// health check request
+func (sm *Manager) applyHealthCheckConfig(req *http.Request, config *models.HealthCheckConfig) {
+	if config.AuthType == models.HealthAuthDefault {
+		// Spring Boot actuator endpoints behind the config server's credentials
+		if strings.Contains(req.URL.Path, "actuator/health") {
+			req.SetBasicAuth(os.Getenv("CONFIG_USERNAME"), os.Getenv("CONFIG_PASSWORD"))
+		}
+	}
+	if len(config.Headers) == 0 && (config.AuthType == models.HealthAuthDefault || config.AuthType == models.HealthAuthNone) {
+		return
+	}
+
+	env := sm.healthCheckEnv(config.ServiceID)
+	lookup := func(name string) string {
+		value, exists := env[name]
+		if !exists {
+			log.Printf("[DEBUG] Health check of service %s references unset variable %s", config.ServiceID, name)
+		}
+		return value
+	}
+
+	for name, value := range config.Headers {
+		if strings.EqualFold(name, "Host") {
+			req.Host = os.Expand(value, lookup)
+			continue
+		}
+		req.Header.Set(name, os.Expand(value, lookup))
+	}
+
+	switch config.AuthType {
+	case models.HealthAuthBasic:
+		password := ""
+		if config.PasswordEnv != "" {
+			password = lookup(config.PasswordEnv)
+		}
+		req.SetBasicAuth(lookup(config.UsernameEnv), password)
+	case models.HealthAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+lookup(config.TokenEnv))
+	}
+}
+
+// The transports are shared by all probes, so they keep reusing connections
+var (
+	healthCheckTransport         = newHealthCheckTransport()
+	insecureHealthCheckTransport = newInsecureHealthCheckTransport()
+)
+
+// newHealthCheckTransport dials loopback addresses of either IP family
+func newHealthCheckTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialLoopbackEitherFamily
+	return transport
+}
+
+// newInsecureHealthCheckTransport accepts any certificate, for services with
+// self-signed ones
+func newInsecureHealthCheckTransport() http.RoundTripper {
+	transport := newHealthCheckTransport()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // #nosec G402 -- opted into per service
+	return transport
+}
+
+var healthCheckDialer = &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+// dialLoopbackEitherFamily dials an address and, when 127.0.0.1 or ::1 cannot
+// be reached, the loopback address of the other IP family: a service bound
+// to [::1] does not answer on 127.0.0.1 and the other way around
+func dialLoopbackEitherFamily(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := healthCheckDialer.DialContext(ctx, network, address)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+
+	host, port, splitErr := net.SplitHostPort(address)
+	if splitErr != nil {
+		return nil, err
+	}
+	var alternate string
+	switch ip := net.ParseIP(host); {
+	case ip == nil:
+		return nil, err
+	case ip.Equal(net.IPv4(127, 0, 0, 1)):
+		alternate = "::1"
+	case ip.Equal(net.IPv6loopback):
+		alternate = "127.0.0.1"
+	default:
+		return nil, err
+	}
+	if conn, alternateErr := healthCheckDialer.DialContext(ctx, "tcp", net.JoinHostPort(alternate, port)); alternateErr == nil {
+		return conn, nil
+	}
+	return nil, err
+}
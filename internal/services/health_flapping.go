@@ -0,0 +1,173 @@
+// Package services - Health flap detection and damping
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	flapWindow     = 10 * time.Minute // health changes older than this no longer count
+	flapStartScore = 5.0              // a service starts flapping at this score
+	flapStopScore  = 2.0              // and settles once its score drops below this
+)
+
+// HealthFlapState describes how often a service's health has changed lately
+type HealthFlapState struct {
+	ServiceUUID   string      `json:"serviceUUID"`
+	ServiceName   string      `json:"serviceName"`
+	HealthStatus  string      `json:"healthStatus"`
+	Flapping      bool        `json:"flapping"`
+	FlapScore     float64     `json:"flapScore"`
+	FlappingSince *time.Time  `json:"flappingSince,omitempty"`
+	Changes       []time.Time `json:"changes"` // Changes between healthy and unhealthy within the window, oldest first
+	WindowSeconds int         `json:"windowSeconds"`
+	StartScore    float64     `json:"startScore"`
+	StopScore     float64     `json:"stopScore"`
+}
+
+type healthFlapState struct {
+	changes       []time.Time
+	flappingSince time.Time
+}
+
+var (
+	healthFlapStates      = make(map[string]*healthFlapState)
+	healthFlapStatesMutex sync.Mutex
+)
+
+// flapScore weighs the health changes within the window: a change just now
+// counts as 1, one at the edge of the window as 0.5, so the score decays
+// while the health holds steady
+func flapScore(changes []time.Time, now time.Time) float64 {
+	score := 0.0
+	for _, changedAt := range changes {
+		age := now.Sub(changedAt)
+		if age > flapWindow {
+			continue
+		}
+		score += 1 - 0.5*float64(age)/float64(flapWindow)
+	}
+	return math.Round(score*10) / 10
+}
+
+// recordHealthResult updates the flap state of a service after a health check
+// changed its health from previousHealth, and records the change on its
+// timeline unless the service is flapping. Flapping starts and ends are
+// recorded instead, so a toggling service alerts once rather than on every
+// change. Must be called with the service mutex held.
+func (sm *Manager) recordHealthResult(service *models.Service, previousHealth string) {
+	now := time.Now()
+	changed := service.HealthStatus != previousHealth
+
+	healthFlapStatesMutex.Lock()
+	state, exists := healthFlapStates[service.ID]
+	if !exists {
+		state = &healthFlapState{}
+		healthFlapStates[service.ID] = state
+	}
+
+	// Only a toggle between healthy and unhealthy counts; coming up after a
+	// start does not
+	if changed && isSettledHealth(previousHealth) && isSettledHealth(service.HealthStatus) {
+		state.changes = append(state.changes, now)
+	}
+	for len(state.changes) > 0 && now.Sub(state.changes[0]) > flapWindow {
+		state.changes = state.changes[1:]
+	}
+
+	score := flapScore(state.changes, now)
+	wasFlapping := !state.flappingSince.IsZero()
+	flapping := wasFlapping
+	switch {
+	case !wasFlapping && score >= flapStartScore:
+		flapping = true
+		state.flappingSince = now
+	case wasFlapping && score < flapStopScore:
+		flapping = false
+		state.flappingSince = time.Time{}
+	}
+	changes := len(state.changes)
+	healthFlapStatesMutex.Unlock()
+
+	service.Flapping = flapping
+	service.FlapScore = score
+
+	switch {
+	case flapping && !wasFlapping:
+		log.Printf("[WARN] Service %s is flapping: its health changed %d times in the last %s", service.Name, changes, flapWindow)
+		sm.recordServiceEvent(service, models.EventHealthFlapping,
+			fmt.Sprintf("Health changed %d times in the last %d minutes; further changes are not alerted until it settles", changes, int(flapWindow.Minutes())))
+	case wasFlapping && !flapping:
+		log.Printf("[INFO] Service %s stopped flapping and is %s", service.Name, service.HealthStatus)
+		sm.recordServiceEvent(service, models.EventHealthChanged, fmt.Sprintf("Health settled as %s after flapping", service.HealthStatus))
+	case flapping:
+		// Damped
+	case changed && (service.HealthStatus == "healthy" || service.HealthStatus == "unhealthy"):
+		sm.recordServiceEvent(service, models.EventHealthChanged, fmt.Sprintf("Health changed from %s to %s", previousHealth, service.HealthStatus))
+	}
+}
+
+// isSettledHealth reports whether a health status came from a health check of
+// a service that is up, rather than from it starting or being stopped
+func isSettledHealth(healthStatus string) bool {
+	return healthStatus == "healthy" || healthStatus == "unhealthy"
+}
+
+// resetHealthFlapping forgets the health changes of a service that is no
+// longer running. Must be called with the service mutex held.
+func (sm *Manager) resetHealthFlapping(service *models.Service) {
+	healthFlapStatesMutex.Lock()
+	delete(healthFlapStates, service.ID)
+	healthFlapStatesMutex.Unlock()
+
+	service.Flapping = false
+	service.FlapScore = 0
+}
+
+// GetHealthFlapState returns the health and flap state of a service
+func (sm *Manager) GetHealthFlapState(serviceUUID string) (*HealthFlapState, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	report := &HealthFlapState{
+		ServiceUUID:   service.ID,
+		ServiceName:   service.Name,
+		HealthStatus:  service.HealthStatus,
+		Changes:       []time.Time{},
+		WindowSeconds: int(flapWindow / time.Second),
+		StartScore:    flapStartScore,
+		StopScore:     flapStopScore,
+	}
+	service.Mutex.RUnlock()
+
+	now := time.Now()
+	healthFlapStatesMutex.Lock()
+	defer healthFlapStatesMutex.Unlock()
+
+	state, exists := healthFlapStates[serviceUUID]
+	if !exists {
+		return report, nil
+	}
+	for _, changedAt := range state.changes {
+		if now.Sub(changedAt) <= flapWindow {
+			report.Changes = append(report.Changes, changedAt)
+		}
+	}
+	// Scored now rather than at the last check, so it shows the decay
+	report.FlapScore = flapScore(report.Changes, now)
+	if !state.flappingSince.IsZero() {
+		since := state.flappingSince
+		report.Flapping = true
+		report.FlappingSince = &since
+	}
+	return report, nil
+}
@@ -0,0 +1,208 @@
+// Package services - Health that services push to Vertex themselves
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// HealthPushPath is where services push their health, with their token as
+	// a bearer token
+	HealthPushPath = "/api/ingest/health"
+
+	// HealthPushTokenRoute takes the token in the path instead, for webhooks
+	// that cannot set headers. Request traces record it without the token.
+	HealthPushTokenRoute = HealthPushPath + "/{token}"
+
+	// A pushed report replaces this many health check intervals
+	healthPushFreshIntervals = 3
+)
+
+// pushedHealth is the last health a service pushed
+type pushedHealth struct {
+	status string
+	at     time.Time
+}
+
+// The last pushed health by service UUID, consulted by every health check
+var (
+	pushedHealths      = make(map[string]pushedHealth)
+	pushedHealthsMutex sync.RWMutex
+)
+
+// hashHealthPushToken is how health ingestion tokens are stored
+func hashHealthPushToken(token string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return hex.EncodeToString(sum[:])
+}
+
+// pushedHealthStatus maps the status of a pushed report to a health status
+func pushedHealthStatus(status string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case "UP", "HEALTHY":
+		return "healthy", nil
+	case "DOWN", "OUT_OF_SERVICE", "UNHEALTHY":
+		return "unhealthy", nil
+	case "UNKNOWN":
+		return "unknown", nil
+	case "":
+		return "", fmt.Errorf("status is required")
+	default:
+		return "", fmt.Errorf("unsupported status '%s'; use UP, DOWN, OUT_OF_SERVICE or UNKNOWN", status)
+	}
+}
+
+// healthPushFreshFor returns how long a pushed report replaces the health
+// checks of a service checked at the given interval
+func healthPushFreshFor(healthInterval int) time.Duration {
+	return healthPushFreshIntervals * healthCheckInterval(healthInterval)
+}
+
+// GetHealthPush returns whether a service has a health ingestion token and
+// the last report it pushed
+func (sm *Manager) GetHealthPush(serviceUUID string) (*models.HealthPush, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	push, err := sm.db.GetServiceHealthPush(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	if push == nil {
+		return &models.HealthPush{ServiceID: serviceUUID}, nil
+	}
+
+	service.Mutex.RLock()
+	freshFor := healthPushFreshFor(service.HealthInterval)
+	lastStarted := service.LastStarted
+	service.Mutex.RUnlock()
+	push.Fresh = push.LastPushedAt != nil && push.LastPushedAt.After(lastStarted) && time.Since(*push.LastPushedAt) < freshFor
+	return push, nil
+}
+
+// GenerateHealthPushToken gives a service a new health ingestion token,
+// replacing its previous one. The token is only returned here; Vertex keeps
+// a hash of it.
+func (sm *Manager) GenerateHealthPushToken(serviceUUID string) (*models.HealthPush, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate health push token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	now := time.Now()
+	if err := sm.db.SaveServiceHealthPushToken(serviceUUID, hashHealthPushToken(token), now); err != nil {
+		return nil, err
+	}
+	forgetPushedHealth(serviceUUID)
+
+	return &models.HealthPush{
+		ServiceID:  serviceUUID,
+		Enabled:    true,
+		Token:      token,
+		IngestPath: HealthPushPath + "/" + token,
+		CreatedAt:  &now,
+	}, nil
+}
+
+// DisableHealthPush drops the health ingestion token of a service, so its
+// health is checked by Vertex alone again
+func (sm *Manager) DisableHealthPush(serviceUUID string) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	if err := sm.db.DeleteServiceHealthPush(serviceUUID); err != nil {
+		return err
+	}
+	forgetPushedHealth(serviceUUID)
+	return nil
+}
+
+// IngestHealthPush stores a report a service pushed with its token. While
+// the service runs, the report sets its health right away and replaces the
+// health checks until it is three intervals old, so a service behind
+// authentication keeps a health even when Vertex cannot probe it.
+func (sm *Manager) IngestHealthPush(token string, report models.HealthPushReport) (*models.HealthPush, error) {
+	serviceUUID, err := sm.db.GetServiceByHealthPushToken(hashHealthPushToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if serviceUUID == "" {
+		return nil, fmt.Errorf("invalid health push token")
+	}
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("invalid health push token")
+	}
+
+	healthStatus, err := pushedHealthStatus(report.Status)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := sm.db.SaveServiceHealthPushReport(serviceUUID, healthStatus, report, now); err != nil {
+		return nil, err
+	}
+
+	pushedHealthsMutex.Lock()
+	pushedHealths[serviceUUID] = pushedHealth{status: healthStatus, at: now}
+	pushedHealthsMutex.Unlock()
+
+	service.Mutex.Lock()
+	fresh := service.Status == "running"
+	if fresh && service.HealthStatus != healthStatus {
+		previousHealth := service.HealthStatus
+		service.HealthStatus = healthStatus
+		sm.recordHealthResult(service, previousHealth)
+		sm.updateServiceInDB(service)
+		sm.broadcastUpdate(service)
+	}
+	service.Mutex.Unlock()
+
+	if !fresh {
+		log.Printf("[INFO] Stored health pushed by service %s, which Vertex does not show as running", service.Name)
+	}
+	return &models.HealthPush{
+		ServiceID:    serviceUUID,
+		Enabled:      true,
+		HealthStatus: healthStatus,
+		LastReport:   &report,
+		LastPushedAt: &now,
+		Fresh:        fresh,
+	}, nil
+}
+
+// freshPushedHealth returns the health a running service last pushed, unless
+// it is older than its start or has gone stale. Must be called with the
+// service mutex held.
+func freshPushedHealth(service *models.Service) (string, bool) {
+	pushedHealthsMutex.RLock()
+	pushed, exists := pushedHealths[service.ID]
+	pushedHealthsMutex.RUnlock()
+
+	if !exists || !pushed.at.After(service.LastStarted) || time.Since(pushed.at) >= healthPushFreshFor(service.HealthInterval) {
+		return "", false
+	}
+	return pushed.status, true
+}
+
+// forgetPushedHealth drops the pushed health of a service
+func forgetPushedHealth(serviceUUID string) {
+	pushedHealthsMutex.Lock()
+	delete(pushedHealths, serviceUUID)
+	pushedHealthsMutex.Unlock()
+}
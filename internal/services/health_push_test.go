@@ -0,0 +1,231 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// newHealthPushTestService returns a manager backed by a temporary database
+// and a running service registered with it
+func newHealthPushTestService(t *testing.T) (*Manager, *models.Service) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("VERTEX_DATA_DIR", dir)
+	db, err := database.NewDatabaseWithPath(filepath.Join(dir, "vertex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	sm := newTestManager(t)
+	sm.db = db
+	service := &models.Service{
+		ID:           "svc-push",
+		Name:         "api",
+		Status:       "running",
+		HealthStatus: "unknown",
+		LastStarted:  time.Now().Add(-time.Minute),
+		IsEnabled:    true,
+	}
+	if err := sm.insertServiceInDB(service); err != nil {
+		t.Fatal(err)
+	}
+	sm.registerService(service)
+	t.Cleanup(func() { forgetPushedHealth(service.ID) })
+	return sm, service
+}
+
+func TestPushedHealthStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"UP", "healthy"},
+		{" up ", "healthy"},
+		{"healthy", "healthy"},
+		{"DOWN", "unhealthy"},
+		{"OUT_OF_SERVICE", "unhealthy"},
+		{"unhealthy", "unhealthy"},
+		{"UNKNOWN", "unknown"},
+	}
+
+	for _, tt := range tests {
+		got, err := pushedHealthStatus(tt.status)
+		if err != nil {
+			t.Errorf("Status %q: unexpected error %v", tt.status, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Status %q: got %q want %q", tt.status, got, tt.want)
+		}
+	}
+
+	if _, err := pushedHealthStatus(""); err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("Expected an empty status to be refused, got %v", err)
+	}
+	if _, err := pushedHealthStatus("GREEN"); err == nil || !strings.Contains(err.Error(), "unsupported status") {
+		t.Errorf("Expected an unknown status to be refused, got %v", err)
+	}
+}
+
+func TestGenerateHealthPushToken_StoresHashOnly(t *testing.T) {
+	sm, service := newHealthPushTestService(t)
+
+	push, err := sm.GenerateHealthPushToken(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !push.Enabled || len(push.Token) != 48 || push.IngestPath != HealthPushPath+"/"+push.Token {
+		t.Errorf("Unexpected token response: got %+v", push)
+	}
+
+	var stored string
+	if err := sm.db.QueryRow("SELECT token_hash FROM service_health_push WHERE service_id = ?", service.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == push.Token || stored != hashHealthPushToken(push.Token) {
+		t.Errorf("Expected only the hash of the token to be stored, got %q", stored)
+	}
+
+	status, err := sm.GetHealthPush(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Enabled || status.Token != "" {
+		t.Errorf("Expected the status to leave the token out, got %+v", status)
+	}
+
+	if _, err := sm.GenerateHealthPushToken("missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected an unknown service to be refused, got %v", err)
+	}
+}
+
+func TestIngestHealthPush_SetsHealthOfRunningService(t *testing.T) {
+	sm, service := newHealthPushTestService(t)
+	push, err := sm.GenerateHealthPushToken(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := models.HealthPushReport{Status: "DOWN", Build: map[string]string{"version": "1.2.3"}}
+	result, err := sm.IngestHealthPush(" "+push.Token+" ", report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.HealthStatus != "unhealthy" || !result.Fresh {
+		t.Errorf("Unexpected ingestion result: got %+v", result)
+	}
+
+	service.Mutex.RLock()
+	health := service.HealthStatus
+	pushed, fresh := freshPushedHealth(service)
+	service.Mutex.RUnlock()
+	if health != "unhealthy" {
+		t.Errorf("Expected the pushed health to apply at once, got %q", health)
+	}
+	if !fresh || pushed != "unhealthy" {
+		t.Errorf("Expected the pushed health to replace health checks, got %q %v", pushed, fresh)
+	}
+
+	status, err := sm.GetHealthPush(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Fresh || status.HealthStatus != "unhealthy" || status.LastReport == nil || status.LastReport.Build["version"] != "1.2.3" {
+		t.Errorf("Expected the stored report, got %+v", status)
+	}
+}
+
+func TestIngestHealthPush_StoppedServiceKeepsHealth(t *testing.T) {
+	sm, service := newHealthPushTestService(t)
+	service.Status = "stopped"
+	push, err := sm.GenerateHealthPushToken(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := sm.IngestHealthPush(push.Token, models.HealthPushReport{Status: "UP"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Fresh {
+		t.Error("Expected a report from a stopped service not to be fresh")
+	}
+	if service.HealthStatus != "unknown" {
+		t.Errorf("Expected a stopped service to keep its health, got %q", service.HealthStatus)
+	}
+}
+
+func TestIngestHealthPush_RefusesBadTokensAndReports(t *testing.T) {
+	sm, service := newHealthPushTestService(t)
+	push, err := sm.GenerateHealthPushToken(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sm.IngestHealthPush("not-a-token", models.HealthPushReport{Status: "UP"}); err == nil || err.Error() != "invalid health push token" {
+		t.Errorf("Expected an unknown token to be refused, got %v", err)
+	}
+	if _, err := sm.IngestHealthPush(push.Token, models.HealthPushReport{Status: "GREEN"}); err == nil || !strings.Contains(err.Error(), "unsupported status") {
+		t.Errorf("Expected an unknown status to be refused, got %v", err)
+	}
+
+	// Create a new token; the old one stops working
+	renewed, err := sm.GenerateHealthPushToken(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.IngestHealthPush(push.Token, models.HealthPushReport{Status: "UP"}); err == nil {
+		t.Error("Expected a replaced token to be refused")
+	}
+	if _, err := sm.IngestHealthPush(renewed.Token, models.HealthPushReport{Status: "UP"}); err != nil {
+		t.Errorf("Expected the new token to work, got %v", err)
+	}
+
+	if err := sm.DisableHealthPush(service.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.IngestHealthPush(renewed.Token, models.HealthPushReport{Status: "UP"}); err == nil {
+		t.Error("Expected the token to be refused once health push is disabled")
+	}
+	service.Mutex.RLock()
+	_, fresh := freshPushedHealth(service)
+	service.Mutex.RUnlock()
+	if fresh {
+		t.Error("Expected disabling health push to drop the pushed health")
+	}
+}
+
+func TestFreshPushedHealth(t *testing.T) {
+	service := &models.Service{ID: "svc-fresh", LastStarted: time.Now().Add(-time.Hour)}
+	defer forgetPushedHealth(service.ID)
+
+	if _, fresh := freshPushedHealth(service); fresh {
+		t.Error("Expected no pushed health before a report")
+	}
+
+	// Create a report older than three health check intervals
+	pushedHealthsMutex.Lock()
+	pushedHealths[service.ID] = pushedHealth{status: "healthy", at: time.Now().Add(-healthPushFreshFor(service.HealthInterval))}
+	pushedHealthsMutex.Unlock()
+	if _, fresh := freshPushedHealth(service); fresh {
+		t.Error("Expected a stale report not to replace health checks")
+	}
+
+	pushedHealthsMutex.Lock()
+	pushedHealths[service.ID] = pushedHealth{status: "healthy", at: time.Now()}
+	pushedHealthsMutex.Unlock()
+	if status, fresh := freshPushedHealth(service); !fresh || status != "healthy" {
+		t.Errorf("Expected a recent report to be fresh, got %q %v", status, fresh)
+	}
+
+	service.LastStarted = time.Now().Add(time.Second)
+	if _, fresh := freshPushedHealth(service); fresh {
+		t.Error("Expected a report from before the last start not to be fresh")
+	}
+}
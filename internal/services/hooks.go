@@ -0,0 +1,239 @@
+// Package services - Service lifecycle hooks
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	defaultHookTimeout       = 60 * time.Second
+	postStartHookWaitTimeout = 5 * time.Minute
+)
+
+// GetServiceHooks returns the lifecycle hooks configured for a service
+func (sm *Manager) GetServiceHooks(serviceUUID string) ([]models.ServiceHook, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	return sm.db.GetServiceHooks(serviceUUID)
+}
+
+// UpdateServiceHooks validates and replaces the lifecycle hooks of a service
+func (sm *Manager) UpdateServiceHooks(serviceUUID string, hooks []models.ServiceHook) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	for i := range hooks {
+		hook := &hooks[i]
+		hook.ServiceID = serviceUUID
+
+		switch hook.Phase {
+		case models.HookPhasePreStart, models.HookPhasePostStart, models.HookPhasePostStop:
+		default:
+			return fmt.Errorf("hook %d: invalid phase '%s'", i+1, hook.Phase)
+		}
+
+		switch hook.Type {
+		case models.HookTypeCommand:
+			if strings.TrimSpace(hook.Command) == "" {
+				return fmt.Errorf("hook %d: command is required", i+1)
+			}
+		case models.HookTypeHTTP:
+			if hook.URL == "" {
+				return fmt.Errorf("hook %d: url is required", i+1)
+			}
+			if hook.Method == "" {
+				hook.Method = http.MethodPost
+			}
+		default:
+			return fmt.Errorf("hook %d: invalid type '%s'", i+1, hook.Type)
+		}
+
+		if hook.TimeoutSeconds <= 0 {
+			hook.TimeoutSeconds = int(defaultHookTimeout / time.Second)
+		}
+	}
+
+	return sm.db.SaveServiceHooks(serviceUUID, hooks)
+}
+
+// runServiceHooks runs the enabled hooks of a phase in order. It returns an
+// error only when a hook marked AbortOnFailure fails. Cancelling ctx kills the
+// running hook and skips the rest.
+func (sm *Manager) runServiceHooks(ctx context.Context, service *models.Service, phase, serviceDir string) error {
+	hooks, err := sm.db.GetServiceHooks(service.ID)
+	if err != nil {
+		log.Printf("[WARN] Failed to load %s hooks for service %s: %v", phase, service.Name, err)
+		return nil
+	}
+
+	for _, hook := range hooks {
+		if hook.Phase != phase || !hook.Enabled {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%s hooks cancelled: %w", phase, err)
+		}
+
+		sm.logHookOutput(service, "INFO", fmt.Sprintf("[hook:%s] Running %s hook", phase, hook.Type))
+
+		output, err := sm.executeHook(ctx, service, hook, serviceDir)
+		for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+			if line != "" {
+				sm.logHookOutput(service, "INFO", fmt.Sprintf("[hook:%s] %s", phase, line))
+			}
+		}
+
+		if err != nil {
+			sm.logHookOutput(service, "ERROR", fmt.Sprintf("[hook:%s] Hook failed: %v", phase, err))
+			if hook.AbortOnFailure {
+				return fmt.Errorf("%s hook failed: %w", phase, err)
+			}
+			continue
+		}
+
+		sm.logHookOutput(service, "INFO", fmt.Sprintf("[hook:%s] Hook completed", phase))
+	}
+
+	return nil
+}
+
+// runPostStartHooks waits for the service to become healthy and then runs its
+// post-start hooks, stopping the service if an aborting hook fails
+func (sm *Manager) runPostStartHooks(service *models.Service, serviceDir string, cmd *exec.Cmd) {
+	service.Mutex.RLock()
+	hasHealthURL := service.HealthURL != ""
+	service.Mutex.RUnlock()
+
+	if hasHealthURL {
+		deadline := time.Now().Add(postStartHookWaitTimeout)
+		for {
+			service.Mutex.RLock()
+			healthy := service.HealthStatus == "healthy"
+			current := service.Cmd == cmd
+			service.Mutex.RUnlock()
+
+			if !current {
+				// The process we were started for is gone; nothing to do
+				return
+			}
+			if healthy {
+				break
+			}
+			if time.Now().After(deadline) {
+				log.Printf("[WARN] Service %s did not become healthy in time, running post-start hooks anyway", service.Name)
+				break
+			}
+			if !sleepContext(sm.ctx, 2*time.Second) {
+				return
+			}
+		}
+	}
+
+	if err := sm.runServiceHooks(sm.ctx, service, models.HookPhasePostStart, serviceDir); err != nil {
+		if sm.ctx.Err() != nil {
+			return
+		}
+		log.Printf("[ERROR] Stopping service %s: %v", service.Name, err)
+		if stopErr := sm.stopServiceOp(sm.ctx, service); stopErr != nil {
+			log.Printf("[WARN] Failed to stop service %s after hook failure: %v", service.Name, stopErr)
+		}
+	}
+}
+
+// executeHook runs a single hook and returns its combined output
+func (sm *Manager) executeHook(ctx context.Context, service *models.Service, hook models.ServiceHook, serviceDir string) (string, error) {
+	timeout := time.Duration(hook.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch hook.Type {
+	case models.HookTypeCommand:
+		secrets, err := sm.resolveServiceSecrets(ctx, service, nil)
+		if err != nil {
+			return "", err
+		}
+
+		cmd := exec.CommandContext(ctx, "bash", "-c", hook.Command)
+		cmd.Dir = serviceDir
+		service.Mutex.RLock()
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("SERVICE_NAME=%s", service.Name),
+			fmt.Sprintf("SERVICE_PORT=%d", service.Port),
+			fmt.Sprintf("SERVICE_DIR=%s", serviceDir),
+		)
+		for key, envVar := range service.EnvVars {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, secrets.value(envVar.Value)))
+		}
+		service.Mutex.RUnlock()
+
+		output, err := cmd.CombinedOutput()
+		if ctx.Err() == context.DeadlineExceeded {
+			return string(output), fmt.Errorf("timed out after %s", timeout)
+		}
+		return string(output), err
+
+	case models.HookTypeHTTP:
+		method := hook.Method
+		if method == "" {
+			method = http.MethodPost
+		}
+
+		var body io.Reader
+		if hook.Body != "" {
+			body = strings.NewReader(hook.Body)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, hook.URL, body)
+		if err != nil {
+			return "", fmt.Errorf("invalid request: %w", err)
+		}
+		if hook.Body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		output := fmt.Sprintf("%s %s -> %d\n%s", method, hook.URL, resp.StatusCode, respBody)
+		if resp.StatusCode >= 400 {
+			return output, fmt.Errorf("request returned status %d", resp.StatusCode)
+		}
+		return output, nil
+	}
+
+	return "", fmt.Errorf("unsupported hook type '%s'", hook.Type)
+}
+
+// logHookOutput stores and broadcasts a hook log line without touching the
+// service mutex, so it is safe to call whether or not the caller holds it
+func (sm *Manager) logHookOutput(service *models.Service, level, message string) {
+	logEntry := models.LogEntry{
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Level:     level,
+		Message:   message,
+	}
+
+	log.Printf("[%s] Service %s %s", level, service.Name, message)
+
+	sm.storeLogEntry(service, logEntry)
+	sm.broadcastLogEntry(service.ID, logEntry)
+}
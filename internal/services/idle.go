@@ -0,0 +1,157 @@
+// Package services - Idle service auto-suspend
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	idleCheckInterval = time.Minute
+	idleCPUThreshold  = 2.0 // percent; anything below counts as negligible
+)
+
+// idleState tracks the last observed activity of a running service
+type idleState struct {
+	lastActivity time.Time
+	connections  map[string]bool
+}
+
+var (
+	idleStates      = make(map[string]*idleState)
+	idleStatesMutex sync.Mutex
+)
+
+// startIdleMonitor periodically suspends services that exceeded their idle timeout
+func (sm *Manager) startIdleMonitor(ctx context.Context) {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+
+	log.Printf("[INFO] Started idle service monitor (%s interval)", idleCheckInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sm.checkIdleServices()
+		}
+	}
+}
+
+// checkIdleServices records activity for running services and suspends the idle ones
+func (sm *Manager) checkIdleServices() {
+	sm.mutex.RLock()
+	candidates := make([]*models.Service, 0)
+	for _, service := range sm.services {
+		candidates = append(candidates, service)
+	}
+	sm.mutex.RUnlock()
+
+	var connections []net.ConnectionStat
+	connectionsLoaded := false
+	now := time.Now()
+
+	for _, service := range candidates {
+		service.Mutex.RLock()
+		running := service.Status == "running"
+		idleMinutes := service.IdleMinutes
+		port := service.Port
+		cpu := service.CPUPercent
+		lastStarted := service.LastStarted
+		service.Mutex.RUnlock()
+
+		if !running || idleMinutes <= 0 {
+			idleStatesMutex.Lock()
+			delete(idleStates, service.ID)
+			idleStatesMutex.Unlock()
+			continue
+		}
+
+		if !connectionsLoaded {
+			var err error
+			connections, err = net.Connections("tcp")
+			if err != nil {
+				log.Printf("[WARN] Idle monitor could not read TCP connections: %v", err)
+			}
+			connectionsLoaded = true
+		}
+
+		current := inboundConnections(connections, port)
+
+		idleStatesMutex.Lock()
+		state, exists := idleStates[service.ID]
+		if !exists || state.lastActivity.Before(lastStarted) {
+			// Treat (re)starts as activity so a fresh service gets a full idle window
+			state = &idleState{lastActivity: now, connections: current}
+			idleStates[service.ID] = state
+		}
+
+		// New connections or real CPU work both count as traffic
+		active := cpu >= idleCPUThreshold
+		for key := range current {
+			if !state.connections[key] {
+				active = true
+				break
+			}
+		}
+		state.connections = current
+		if active {
+			state.lastActivity = now
+		}
+		idleFor := now.Sub(state.lastActivity)
+		idleStatesMutex.Unlock()
+
+		if idleFor >= time.Duration(idleMinutes)*time.Minute {
+			log.Printf("[INFO] Service %s idle for %s, suspending", service.Name, idleFor.Round(time.Second))
+			if err := sm.suspendService(service); err != nil {
+				log.Printf("[WARN] Failed to suspend idle service %s: %v", service.Name, err)
+			}
+		}
+	}
+}
+
+// inboundConnections returns the remote endpoints currently connected to a local port
+func inboundConnections(connections []net.ConnectionStat, port int) map[string]bool {
+	current := make(map[string]bool)
+	if port <= 0 {
+		return current
+	}
+
+	for _, conn := range connections {
+		if conn.Status == "ESTABLISHED" && int(conn.Laddr.Port) == port {
+			current[fmt.Sprintf("%s:%d", conn.Raddr.IP, conn.Raddr.Port)] = true
+		}
+	}
+
+	return current
+}
+
+// suspendService stops a service and marks it "suspended" so it can be told
+// apart from a manual stop and restarted on demand
+func (sm *Manager) suspendService(service *models.Service) error {
+	return sm.runServiceOp(sm.ctx, service, "suspend", func(ctx context.Context) error {
+		if err := sm.stopService(service); err != nil {
+			return err
+		}
+
+		idleStatesMutex.Lock()
+		delete(idleStates, service.ID)
+		idleStatesMutex.Unlock()
+
+		service.Mutex.Lock()
+		defer service.Mutex.Unlock()
+
+		service.Status = "suspended"
+		sm.recordServiceEvent(service, models.EventSuspended, "Suspended after idle timeout")
+		sm.updateServiceInDB(service)
+		sm.broadcastUpdate(service)
+		return nil
+	})
+}
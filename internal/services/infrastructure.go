@@ -0,0 +1,518 @@
+// Package services - Redis, Kafka, RabbitMQ, MinIO and Postgres run by Vertex
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Infrastructure types Vertex can run next to the Java services
+const (
+	InfraRedis    = "redis"
+	InfraKafka    = "kafka"
+	InfraRabbitMQ = "rabbitmq"
+	InfraMinIO    = "minio"
+	InfraPostgres = "postgres"
+)
+
+// How infrastructure runs: as a container of the official image, or as a
+// local binary. Empty picks a container when Docker or Podman is installed.
+const (
+	InfraRuntimeContainer = "container"
+	InfraRuntimeBinary    = "binary"
+)
+
+// Kafka release downloaded for the binary runtime
+const (
+	kafkaVersion      = "3.7.2"
+	kafkaScalaVersion = "2.13"
+)
+
+// InfraExtraPort is a port an infrastructure service listens on besides its
+// main one, at a fixed offset from it so several instances do not collide
+type InfraExtraPort struct {
+	Name          string `json:"name"`
+	Offset        int    `json:"offset"`
+	containerPort int
+}
+
+// InfraKind describes an infrastructure type and its development defaults
+type InfraKind struct {
+	Type         string           `json:"type"`
+	Name         string           `json:"name"`
+	DefaultPort  int              `json:"defaultPort"`
+	Image        string           `json:"image"`
+	Username     string           `json:"username,omitempty"` // Development credentials; override them with env vars of the service
+	Password     string           `json:"password,omitempty"`
+	ExtraPorts   []InfraExtraPort `json:"extraPorts,omitempty"` // Published by the container runtime
+	Binary       string           `json:"binary"`               // Executable the binary runtime runs
+	Downloadable bool             `json:"downloadable"`         // Vertex downloads the binary when it is not installed
+
+	containerPort int
+	dataMount     string            // Where the image keeps its data, backed by a named volume
+	env           map[string]string // Image settings, overridable by env vars of the service
+	args          []string
+}
+
+var infraKinds = map[string]InfraKind{
+	InfraRedis: {
+		Type: InfraRedis, Name: "Redis", DefaultPort: 6379, Image: "redis:7-alpine", Binary: "redis-server",
+		containerPort: 6379, dataMount: "/data", args: []string{"redis-server", "--appendonly", "yes"},
+	},
+	InfraPostgres: {
+		Type: InfraPostgres, Name: "PostgreSQL", DefaultPort: 5432, Image: "postgres:16-alpine", Binary: "postgres",
+		Username: "vertex", Password: "vertex",
+		containerPort: 5432, dataMount: "/var/lib/postgresql/data",
+		env: map[string]string{"POSTGRES_USER": "vertex", "POSTGRES_PASSWORD": "vertex", "POSTGRES_DB": "vertex"},
+	},
+	InfraRabbitMQ: {
+		Type: InfraRabbitMQ, Name: "RabbitMQ", DefaultPort: 5672, Image: "rabbitmq:3-management-alpine", Binary: "rabbitmq-server",
+		Username: "guest", Password: "guest",
+		ExtraPorts:    []InfraExtraPort{{Name: "management", Offset: 10000, containerPort: 15672}},
+		containerPort: 5672, dataMount: "/var/lib/rabbitmq",
+	},
+	InfraMinIO: {
+		Type: InfraMinIO, Name: "MinIO", DefaultPort: 9000, Image: "minio/minio:latest", Binary: "minio", Downloadable: true,
+		Username: "vertex", Password: "vertex-secret",
+		ExtraPorts:    []InfraExtraPort{{Name: "console", Offset: 1, containerPort: 9001}},
+		containerPort: 9000, dataMount: "/data",
+		env:  map[string]string{"MINIO_ROOT_USER": "vertex", "MINIO_ROOT_PASSWORD": "vertex-secret"},
+		args: []string{"server", "/data", "--console-address", ":9001"},
+	},
+	InfraKafka: {
+		Type: InfraKafka, Name: "Kafka", DefaultPort: 9092, Image: "apache/kafka:" + kafkaVersion, Binary: "kafka-server-start.sh", Downloadable: true,
+		containerPort: 9092, dataMount: "/var/lib/kafka/data",
+		env: map[string]string{
+			"KAFKA_NODE_ID":                                  "1",
+			"KAFKA_PROCESS_ROLES":                            "broker,controller",
+			"KAFKA_LISTENERS":                                "PLAINTEXT://:9092,CONTROLLER://:9093",
+			"KAFKA_CONTROLLER_LISTENER_NAMES":                "CONTROLLER",
+			"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP":           "CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+			"KAFKA_CONTROLLER_QUORUM_VOTERS":                 "1@localhost:9093",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR":         "1",
+			"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR": "1",
+			"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR":            "1",
+			"KAFKA_LOG_DIRS":                                 "/var/lib/kafka/data",
+		},
+	},
+}
+
+// InfraKindStatus is an infrastructure type and whether it can run here
+type InfraKindStatus struct {
+	InfraKind
+	BinaryPath string `json:"binaryPath,omitempty"` // Installed or downloaded binary, empty when there is none yet
+}
+
+// InfrastructureStatus lists the infrastructure types and the container
+// engine found on this machine
+type InfrastructureStatus struct {
+	ContainerEngine string            `json:"containerEngine"` // "docker", "podman" or empty when neither is installed
+	DefaultRuntime  string            `json:"defaultRuntime"`  // Runtime of services that do not pick one
+	Kinds           []InfraKindStatus `json:"kinds"`
+}
+
+// validateInfrastructure trims the infrastructure type and runtime of a
+// service; an empty type makes it a Java service
+func validateInfrastructure(infraType, infraRuntime *string) error {
+	*infraType = strings.ToLower(strings.TrimSpace(*infraType))
+	*infraRuntime = strings.ToLower(strings.TrimSpace(*infraRuntime))
+
+	if *infraType == "" {
+		if *infraRuntime != "" {
+			return fmt.Errorf("invalid infrastructure runtime '%s': the service has no infrastructure type", *infraRuntime)
+		}
+		return nil
+	}
+	if _, exists := infraKinds[*infraType]; !exists {
+		return fmt.Errorf("invalid infrastructure type '%s': use %s", *infraType, strings.Join(infraTypeNames(), ", "))
+	}
+	switch *infraRuntime {
+	case "", InfraRuntimeContainer, InfraRuntimeBinary:
+		return nil
+	}
+	return fmt.Errorf("invalid infrastructure runtime '%s': use %s or %s", *infraRuntime, InfraRuntimeContainer, InfraRuntimeBinary)
+}
+
+func infraTypeNames() []string {
+	names := make([]string, 0, len(infraKinds))
+	for name := range infraKinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// containerEngine returns the container CLI installed, preferring Docker
+func containerEngine() string {
+	for _, engine := range []string{"docker", "podman"} {
+		if commandExists(engine) {
+			return engine
+		}
+	}
+	return ""
+}
+
+// resolveInfraRuntime returns how a service runs its infrastructure
+func resolveInfraRuntime(infraRuntime string) string {
+	if infraRuntime != "" {
+		return infraRuntime
+	}
+	if containerEngine() != "" {
+		return InfraRuntimeContainer
+	}
+	return InfraRuntimeBinary
+}
+
+// GetInfrastructureStatus lists the infrastructure types Vertex can run
+func GetInfrastructureStatus() *InfrastructureStatus {
+	status := &InfrastructureStatus{ContainerEngine: containerEngine(), DefaultRuntime: resolveInfraRuntime(""), Kinds: []InfraKindStatus{}}
+	for _, name := range infraTypeNames() {
+		kind := infraKinds[name]
+		status.Kinds = append(status.Kinds, InfraKindStatus{InfraKind: kind, BinaryPath: findInfraBinary(kind)})
+	}
+	return status
+}
+
+// infraDefaultPort returns the usual port of an infrastructure type, or 0
+// for services that are not infrastructure
+func infraDefaultPort(infraType string) int {
+	return infraKinds[infraType].DefaultPort
+}
+
+// InfraConnectionURL is how applications reach an infrastructure service,
+// in the form their client libraries expect
+func InfraConnectionURL(infraType string, port int) string {
+	address := fmt.Sprintf("%s:%d", discoveryHost, port)
+	switch infraType {
+	case InfraRedis:
+		return "redis://" + address
+	case InfraPostgres:
+		return "jdbc:postgresql://" + address + "/vertex"
+	case InfraRabbitMQ:
+		return "amqp://guest:guest@" + address
+	case InfraKafka:
+		return address
+	default:
+		return "http://" + address
+	}
+}
+
+// infraContainerName is the container of a service, stable across restarts
+// so its data volume is reused
+func infraContainerName(service *models.Service) string {
+	name := strings.Trim(discoveryNameSeparators.ReplaceAllString(strings.ToLower(service.Name), "-"), "-")
+	id := service.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return "vertex-" + name + "-" + id
+}
+
+// infraDataDir is where the binary runtime keeps a service's data
+func infraDataDir(serviceUUID string) string {
+	return filepath.Join(database.GetDataDir(), "infrastructure", "data", serviceUUID)
+}
+
+// infraDownloadDir is where downloaded infrastructure binaries are kept
+func infraDownloadDir() string {
+	return filepath.Join(database.GetDataDir(), "infrastructure", "bin")
+}
+
+// kafkaHome is where the downloaded Kafka release is extracted
+func kafkaHome() string {
+	return filepath.Join(infraDownloadDir(), fmt.Sprintf("kafka_%s-%s", kafkaScalaVersion, kafkaVersion))
+}
+
+// findInfraBinary returns the binary of an infrastructure type on the PATH,
+// in a common install location or downloaded by Vertex, or "" when there is none
+func findInfraBinary(kind InfraKind) string {
+	if kind.Type == InfraKafka {
+		// Kafka runs from its release directory, which has the storage tool next to it
+		path := filepath.Join(kafkaHome(), "bin", kind.Binary)
+		if isExecutable(path) {
+			return path
+		}
+		return ""
+	}
+	if path, err := exec.LookPath(kind.Binary); err == nil {
+		return path
+	}
+	var candidates []string
+	switch kind.Type {
+	case InfraPostgres:
+		// Debian and Homebrew keep the server out of the PATH
+		for _, pattern := range []string{"/usr/lib/postgresql/*/bin", "/opt/homebrew/opt/postgresql*/bin", "/usr/local/opt/postgresql*/bin"} {
+			dirs, _ := filepath.Glob(pattern)
+			sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+			candidates = append(candidates, dirs...)
+		}
+	case InfraRabbitMQ:
+		candidates = []string{"/usr/lib/rabbitmq/bin", "/opt/homebrew/sbin", "/usr/local/sbin"}
+	case InfraMinIO:
+		candidates = []string{infraDownloadDir()}
+	}
+	for _, dir := range candidates {
+		if path := filepath.Join(dir, kind.Binary); isExecutable(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// ensureInfraBinary returns the binary of an infrastructure type, downloading
+// MinIO or Kafka when they are missing
+func ensureInfraBinary(ctx context.Context, kind InfraKind) (string, error) {
+	if path := findInfraBinary(kind); path != "" {
+		return path, nil
+	}
+	if !kind.Downloadable {
+		return "", fmt.Errorf("%s is not installed: install %s or use the %s runtime", kind.Name, kind.Binary, InfraRuntimeContainer)
+	}
+	if err := os.MkdirAll(infraDownloadDir(), 0755); err != nil {
+		return "", fmt.Errorf("failed to create infrastructure directory: %w", err)
+	}
+
+	switch kind.Type {
+	case InfraMinIO:
+		platform := runtime.GOOS + "-" + runtime.GOARCH
+		if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+			return "", fmt.Errorf("MinIO has no download for %s", platform)
+		}
+		binary := kind.Binary
+		if runtime.GOOS == "windows" {
+			binary += ".exe"
+		}
+		target := filepath.Join(infraDownloadDir(), binary)
+		if err := downloadInfraFile(ctx, fmt.Sprintf("https://dl.min.io/server/minio/release/%s/%s", platform, binary), target); err != nil {
+			return "", err
+		}
+		if err := os.Chmod(target, 0755); err != nil {
+			return "", fmt.Errorf("failed to make MinIO executable: %w", err)
+		}
+	case InfraKafka:
+		release := filepath.Base(kafkaHome())
+		archive := kafkaHome() + ".tgz"
+		defer os.Remove(archive)
+		if err := downloadInfraFile(ctx, fmt.Sprintf("https://archive.apache.org/dist/kafka/%s/%s.tgz", kafkaVersion, release), archive); err != nil {
+			return "", err
+		}
+		// Extract into a staging directory so a failed install never leaves a half-populated release
+		stagingDir := kafkaHome() + ".partial"
+		os.RemoveAll(stagingDir)
+		defer os.RemoveAll(stagingDir)
+		if err := extractTarGz(archive, stagingDir); err != nil {
+			return "", fmt.Errorf("failed to extract Kafka: %w", err)
+		}
+		if err := os.Rename(filepath.Join(stagingDir, release), kafkaHome()); err != nil {
+			return "", fmt.Errorf("failed to move Kafka into place: %w", err)
+		}
+	}
+
+	path := findInfraBinary(kind)
+	if path == "" {
+		return "", fmt.Errorf("downloaded %s does not contain %s", kind.Name, kind.Binary)
+	}
+	log.Printf("[INFO] Installed %s at %s", kind.Name, path)
+	return path, nil
+}
+
+// downloadInfraFile downloads url to target, replacing it only once complete
+func downloadInfraFile(ctx context.Context, url, target string) error {
+	log.Printf("[INFO] Downloading %s", url)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	client := &http.Client{Timeout: 30 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download of %s failed with status %d", url, resp.StatusCode)
+	}
+
+	partial := target + ".partial"
+	out, err := os.Create(partial)
+	if err != nil {
+		return fmt.Errorf("failed to create download file: %w", err)
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		os.Remove(partial)
+		return fmt.Errorf("failed to save %s: %w", url, err)
+	}
+	out.Close()
+	return os.Rename(partial, target)
+}
+
+// shellDefault expands to an environment variable of the service, or to a
+// default when the service does not set it
+func shellDefault(name, value string) string {
+	return fmt.Sprintf(`%s="${%s:-%s}"`, name, name, strings.ReplaceAll(value, `"`, `\"`))
+}
+
+// infrastructureStartCommand returns the command that runs an infrastructure
+// service and the directory to run it from, downloading its binary first
+// when needed
+func (sm *Manager) infrastructureStartCommand(ctx context.Context, service *models.Service) (string, string, error) {
+	service.Mutex.RLock()
+	kind, exists := infraKinds[service.InfraType]
+	infraRuntime := resolveInfraRuntime(service.InfraRuntime)
+	port := service.Port
+	containerName := infraContainerName(service)
+	serviceUUID := service.ID
+	service.Mutex.RUnlock()
+
+	if !exists {
+		return "", "", fmt.Errorf("invalid infrastructure type '%s' of service %s", service.InfraType, service.Name)
+	}
+	if port <= 0 {
+		return "", "", fmt.Errorf("service %s has no port to run %s on", service.Name, kind.Name)
+	}
+
+	dataDir := infraDataDir(serviceUUID)
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create data directory for %s: %w", service.Name, err)
+	}
+
+	if infraRuntime == InfraRuntimeContainer {
+		engine := containerEngine()
+		if engine == "" {
+			return "", "", fmt.Errorf("cannot start %s: neither docker nor podman is installed; use the %s runtime", service.Name, InfraRuntimeBinary)
+		}
+		log.Printf("[INFO] Running %s as %s container %s", service.Name, engine, containerName)
+		return containerStartCommand(engine, kind, containerName, port), dataDir, nil
+	}
+
+	binary, err := ensureInfraBinary(ctx, kind)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot start %s: %w", service.Name, err)
+	}
+	log.Printf("[INFO] Running %s from %s", service.Name, binary)
+	return binaryStartCommand(kind, binary, dataDir, containerName, port), dataDir, nil
+}
+
+// containerStartCommand runs the official image in the foreground, so its
+// output becomes the service's log and stopping the service stops it
+func containerStartCommand(engine string, kind InfraKind, containerName string, port int) string {
+	args := []string{engine, "run", "--rm", "--name", containerName, "--hostname", containerName,
+		"-p", fmt.Sprintf("%d:%d", port, kind.containerPort)}
+	for _, extra := range kind.ExtraPorts {
+		args = append(args, "-p", fmt.Sprintf("%d:%d", port+extra.Offset, extra.containerPort))
+	}
+	args = append(args, "-v", containerName+"-data:"+kind.dataMount)
+
+	env := make(map[string]string, len(kind.env)+1)
+	for name, value := range kind.env {
+		env[name] = value
+	}
+	if kind.Type == InfraKafka {
+		// Clients connect through the published port
+		env["KAFKA_ADVERTISED_LISTENERS"] = fmt.Sprintf("PLAINTEXT://localhost:%d", port)
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "-e", shellDefault(name, env[name]))
+	}
+
+	args = append(args, kind.Image)
+	args = append(args, kind.args...)
+	// A container left behind by a killed Vertex would hold the name
+	return fmt.Sprintf("%s rm -f %s >/dev/null 2>&1; exec %s", engine, containerName, strings.Join(args, " "))
+}
+
+// binaryStartCommand runs a local binary with its data in dataDir,
+// initializing the data directory on first start
+func binaryStartCommand(kind InfraKind, binary, dataDir, containerName string, port int) string {
+	data := shellQuote(dataDir)
+	switch kind.Type {
+	case InfraRedis:
+		return fmt.Sprintf("exec %s --port %d --dir %s --appendonly yes", shellQuote(binary), port, data)
+	case InfraPostgres:
+		bin := filepath.Dir(binary)
+		initdb := shellQuote(filepath.Join(bin, "initdb"))
+		return fmt.Sprintf(`if [ ! -f %s/PG_VERSION ]; then %s -D %s -U vertex --auth=trust && echo 'CREATE DATABASE vertex;' | %s --single -D %s postgres >/dev/null; fi && exec %s -D %s -p %d -k %s -c listen_addresses=localhost`,
+			data, initdb, data, shellQuote(binary), data, shellQuote(binary), data, port, data)
+	case InfraRabbitMQ:
+		return fmt.Sprintf("RABBITMQ_NODE_PORT=%d RABBITMQ_DIST_PORT=%d RABBITMQ_NODENAME=%s@localhost RABBITMQ_MNESIA_BASE=%s/mnesia RABBITMQ_LOG_BASE=%s/log exec %s",
+			port, port+20000, containerName, data, data, shellQuote(binary))
+	case InfraMinIO:
+		return fmt.Sprintf("%s %s exec %s server %s --address :%d --console-address :%d",
+			shellDefault("MINIO_ROOT_USER", kind.env["MINIO_ROOT_USER"]), shellDefault("MINIO_ROOT_PASSWORD", kind.env["MINIO_ROOT_PASSWORD"]),
+			shellQuote(binary), data, port, port+1)
+	case InfraKafka:
+		bin := filepath.Dir(binary)
+		storage := shellQuote(filepath.Join(bin, "kafka-storage.sh"))
+		properties := strings.Join([]string{
+			"process.roles=broker,controller",
+			"node.id=1",
+			fmt.Sprintf("controller.quorum.voters=1@localhost:%d", port+1),
+			fmt.Sprintf("listeners=PLAINTEXT://localhost:%d,CONTROLLER://localhost:%d", port, port+1),
+			fmt.Sprintf("advertised.listeners=PLAINTEXT://localhost:%d", port),
+			"controller.listener.names=CONTROLLER",
+			"listener.security.protocol.map=CONTROLLER:PLAINTEXT,PLAINTEXT:PLAINTEXT",
+			"log.dirs=" + filepath.Join(dataDir, "logs"),
+			"offsets.topic.replication.factor=1",
+			"transaction.state.log.replication.factor=1",
+			"transaction.state.log.min.isr=1",
+		}, "\n")
+		config := shellQuote(filepath.Join(dataDir, "server.properties"))
+		return fmt.Sprintf(`printf '%%s\n' %s > %s && { test -f %s/logs/meta.properties || %s format -t "$(%s random-uuid)" -c %s; } && exec %s %s`,
+			shellQuote(properties), config, data, storage, storage, config, shellQuote(binary), config)
+	}
+	return ""
+}
+
+// removeInfraContainer removes the container of a stopped service in case
+// the engine did not stop it along with its client
+func removeInfraContainer(service *models.Service) {
+	if service.InfraType == "" || resolveInfraRuntime(service.InfraRuntime) != InfraRuntimeContainer {
+		return
+	}
+	engine := containerEngine()
+	if engine == "" {
+		return
+	}
+	containerName := infraContainerName(service)
+	go func() {
+		if output, err := exec.Command(engine, "rm", "-f", containerName).CombinedOutput(); err != nil {
+			log.Printf("[WARN] Failed to remove container %s: %v: %s", containerName, err, strings.TrimSpace(string(output)))
+		}
+	}()
+}
+
+// probeInfrastructureHealth sets the HealthStatus of a detached copy of an
+// infrastructure service from whether its port accepts connections, since it
+// has no HTTP health endpoint
+func probeInfrastructureHealth(ctx context.Context, service *models.Service) {
+	dialer := net.Dialer{Timeout: 3 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", discoveryHost, service.Port))
+	if err != nil {
+		log.Printf("[DEBUG] Health check failed for %s: %v", service.Name, err)
+		service.HealthStatus = "unhealthy"
+		return
+	}
+	conn.Close()
+	service.HealthStatus = "healthy"
+}
@@ -0,0 +1,171 @@
+// Package services - The Java version a service runs with within a profile
+package services
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// ConfigTypeJavaVersion marks profile service configs that pin a service
+	// to another Java version within that profile
+	ConfigTypeJavaVersion = "java-version"
+
+	javaVersionConfigKey = "javaVersion"
+)
+
+// GetJavaVersionOverrides returns the Java version each service of a profile
+// is pinned to and the JDK it resolves to, keyed by service UUID
+func (sm *Manager) GetJavaVersionOverrides(profileID string) (map[string]models.JavaVersionOverride, error) {
+	versions, err := sm.db.GetProfileConfigsByType(profileID, ConfigTypeJavaVersion, javaVersionConfigKey)
+	if err != nil {
+		return nil, err
+	}
+
+	jdks := ListInstalledJDKs()
+	overrides := make(map[string]models.JavaVersionOverride, len(versions))
+	for serviceUUID, version := range versions {
+		override := models.JavaVersionOverride{Version: version}
+		if javaHome, err := resolveJavaVersion(version, jdks); err != nil {
+			override.Problem = err.Error()
+		} else {
+			override.JavaHome = javaHome
+		}
+		overrides[serviceUUID] = override
+	}
+	return overrides, nil
+}
+
+// SetJavaVersionOverride pins a service to a Java version within a profile,
+// which must match an installed JDK. An empty version removes the override.
+// It takes effect on the next start.
+func (sm *Manager) SetJavaVersionOverride(profileID, serviceUUID, version string) (*models.JavaVersionOverride, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	version = strings.TrimSpace(version)
+	if version == "" {
+		if err := sm.db.DeleteProfileServiceConfig(profileID, serviceUUID, javaVersionConfigKey); err != nil {
+			return nil, err
+		}
+		return &models.JavaVersionOverride{}, nil
+	}
+
+	javaHome, err := resolveJavaVersion(version, ListInstalledJDKs())
+	if err != nil {
+		return nil, err
+	}
+	if err := sm.db.SetProfileServiceConfig(profileID, serviceUUID, javaVersionConfigKey, version, ConfigTypeJavaVersion, "Java version override"); err != nil {
+		return nil, err
+	}
+	return &models.JavaVersionOverride{Version: version, JavaHome: javaHome}, nil
+}
+
+// profileJavaHome returns the Java home a profile pins a service to, or ""
+// when it does not. A version no installed JDK matches is logged and ignored,
+// so the service falls back to its usual Java.
+func (sm *Manager) profileJavaHome(profileID, serviceUUID string) string {
+	if profileID == "" {
+		return ""
+	}
+	overrides, err := sm.db.GetProfileServiceConfigByType(profileID, serviceUUID, ConfigTypeJavaVersion)
+	if err != nil {
+		log.Printf("[WARN] Failed to load Java version override for service %s: %v", serviceUUID, err)
+		return ""
+	}
+	version := overrides[javaVersionConfigKey]
+	if version == "" {
+		return ""
+	}
+
+	javaHome, err := resolveJavaVersion(version, ListInstalledJDKs())
+	if err != nil {
+		log.Printf("[WARN] Ignoring Java version override of service %s: %v", serviceUUID, err)
+		return ""
+	}
+	return javaHome
+}
+
+// resolveJavaVersion finds the JDK of a Java version among the installed
+// ones: an absolute path is used as the Java home, a JDK name such as
+// "21.0.2-tem" or "temurin-17" picks that JDK, and a version such as "8",
+// "1.8" or "21.0.2" picks the newest JDK whose version starts with it
+func resolveJavaVersion(version string, jdks []JDKInstallation) (string, error) {
+	if filepath.IsAbs(version) {
+		if !isExecutable(filepath.Join(version, "bin", getJavaExecutable())) {
+			return "", fmt.Errorf("%s has no executable bin/%s", version, getJavaExecutable())
+		}
+		return version, nil
+	}
+
+	for _, jdk := range jdks {
+		if jdk.Name == version {
+			return jdk.JavaHome, nil
+		}
+	}
+
+	wanted := javaVersionParts(version)
+	if len(wanted) == 0 {
+		return "", fmt.Errorf("'%s' is not a Java version, JDK name or path", version)
+	}
+	var best *JDKInstallation
+	for i, jdk := range jdks {
+		if !javaVersionMatches(javaVersionParts(jdk.Version), wanted) {
+			continue
+		}
+		if best == nil || compareJavaVersions(jdk.Version, best.Version) > 0 {
+			best = &jdks[i]
+		}
+	}
+	if best == nil {
+		return "", fmt.Errorf("no installed JDK matches Java %s; install it with asdf, SDKMAN or Vertex first", version)
+	}
+	return best.JavaHome, nil
+}
+
+// javaVersionParts splits a version such as "21.0.2+13" into its numbers,
+// with the legacy "1.8.0_392" read as 8.0.392
+func javaVersionParts(version string) []int {
+	var parts []int
+	for _, field := range strings.FieldsFunc(version, func(r rune) bool { return r < '0' || r > '9' }) {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, number)
+	}
+	if len(parts) > 1 && parts[0] == 1 {
+		parts = parts[1:]
+	}
+	return parts
+}
+
+// javaVersionMatches reports whether a version starts with the wanted parts
+func javaVersionMatches(parts, wanted []int) bool {
+	if len(parts) < len(wanted) {
+		return false
+	}
+	for i := range wanted {
+		if parts[i] != wanted[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// compareJavaVersions orders two versions by their numbers
+func compareJavaVersions(a, b string) int {
+	aParts, bParts := javaVersionParts(a), javaVersionParts(b)
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if aParts[i] != bParts[i] {
+			return aParts[i] - bParts[i]
+		}
+	}
+	return len(aParts) - len(bParts)
+}
@@ -0,0 +1,572 @@
+// Package services - JDK discovery and Temurin provisioning
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const adoptiumAPIBase = "https://api.adoptium.net/v3"
+
+// JDKInstallation describes a JDK found on this machine
+type JDKInstallation struct {
+	Name     string `json:"name"`
+	JavaHome string `json:"javaHome"`
+	Version  string `json:"version"`
+	Source   string `json:"source"` // "vertex", "asdf", "sdkman", "system"
+}
+
+// TemurinReleases lists the Temurin feature releases offered by Adoptium
+type TemurinReleases struct {
+	AvailableReleases    []int `json:"available_releases"`
+	AvailableLTSReleases []int `json:"available_lts_releases"`
+	MostRecentLTS        int   `json:"most_recent_lts"`
+}
+
+// JDKInstallJob is a Temurin download and install running in the background
+type JDKInstallJob struct {
+	Version         int              `json:"version"`
+	Status          string           `json:"status"` // running, completed, failed
+	BytesDownloaded int64            `json:"bytesDownloaded"`
+	BytesTotal      int64            `json:"bytesTotal,omitempty"`
+	Installation    *JDKInstallation `json:"installation,omitempty"`
+	Error           string           `json:"error,omitempty"`
+	StartedAt       time.Time        `json:"startedAt"`
+	FinishedAt      *time.Time       `json:"finishedAt,omitempty"`
+}
+
+// temurinPackage is the archive Adoptium publishes for a release, with its SHA-256
+type temurinPackage struct {
+	Name     string `json:"name"`
+	Link     string `json:"link"`
+	Checksum string `json:"checksum"`
+	Size     int64  `json:"size"`
+}
+
+// The latest install job of each Java feature version
+var (
+	jdkInstallJobs      = make(map[int]*JDKInstallJob)
+	jdkInstallJobsMutex sync.Mutex
+)
+
+// jdkRoot is a directory whose children are individual JDK installations
+type jdkRoot struct {
+	dir    string
+	source string
+}
+
+// GetJDKInstallDir returns the directory Vertex installs managed JDKs into
+func GetJDKInstallDir() string {
+	return filepath.Join(database.GetDataDir(), "jdks")
+}
+
+// ListInstalledJDKs returns JDKs installed by Vertex, asdf, SDKMAN and common system locations
+func ListInstalledJDKs() []JDKInstallation {
+	roots := []jdkRoot{{GetJDKInstallDir(), "vertex"}}
+
+	if homeDir := os.Getenv("HOME"); homeDir != "" {
+		roots = append(roots,
+			jdkRoot{filepath.Join(homeDir, ".asdf", "installs", "java"), "asdf"},
+			jdkRoot{filepath.Join(homeDir, ".sdkman", "candidates", "java"), "sdkman"},
+		)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		roots = append(roots, jdkRoot{"/Library/Java/JavaVirtualMachines", "system"})
+	case "linux":
+		roots = append(roots, jdkRoot{"/usr/lib/jvm", "system"})
+	case "windows":
+		roots = append(roots, jdkRoot{`C:\Program Files\Java`, "system"})
+	}
+
+	var jdks []JDKInstallation
+	seen := make(map[string]bool)
+
+	for _, root := range roots {
+		entries, err := os.ReadDir(root.dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			// SDKMAN's "current" is a symlink to one of the other candidates
+			if entry.Name() == "current" {
+				continue
+			}
+
+			javaHome := resolveJDKHome(filepath.Join(root.dir, entry.Name()))
+			if javaHome == "" {
+				continue
+			}
+
+			realHome, err := filepath.EvalSymlinks(javaHome)
+			if err != nil {
+				realHome = javaHome
+			}
+			if seen[realHome] {
+				continue
+			}
+			seen[realHome] = true
+
+			jdks = append(jdks, JDKInstallation{
+				Name:     entry.Name(),
+				JavaHome: javaHome,
+				Version:  getJavaVersion(filepath.Join(javaHome, "bin", getJavaExecutable())),
+				Source:   root.source,
+			})
+		}
+	}
+
+	return jdks
+}
+
+// GetTemurinReleases fetches the list of Temurin versions available for download
+func GetTemurinReleases() (*TemurinReleases, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	resp, err := client.Get(adoptiumAPIBase + "/info/available_releases")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Adoptium releases: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Adoptium releases request failed with status %d", resp.StatusCode)
+	}
+
+	var releases TemurinReleases
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to decode Adoptium releases: %w", err)
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(releases.AvailableReleases)))
+	return &releases, nil
+}
+
+// StartTemurinInstall downloads and installs the latest GA Temurin build of
+// the given feature version in the background and returns the job; its
+// progress is broadcast as jdk_install messages and GetTemurinInstall reports
+// it. onInstalled runs once the JDK is in place, e.g. to assign it.
+func (sm *Manager) StartTemurinInstall(featureVersion int, onInstalled func(*JDKInstallation) error) (*JDKInstallJob, error) {
+	if featureVersion < 8 {
+		return nil, fmt.Errorf("invalid Java version: %d", featureVersion)
+	}
+	if _, _, _, err := adoptiumPlatform(); err != nil {
+		return nil, err
+	}
+
+	jdkInstallJobsMutex.Lock()
+	if job, exists := jdkInstallJobs[featureVersion]; exists && job.Status == "running" {
+		jdkInstallJobsMutex.Unlock()
+		return nil, fmt.Errorf("Temurin %d is already being installed", featureVersion)
+	}
+	job := &JDKInstallJob{Version: featureVersion, Status: "running", StartedAt: time.Now()}
+	jdkInstallJobs[featureVersion] = job
+	started := *job
+	jdkInstallJobsMutex.Unlock()
+
+	go sm.runTemurinInstall(job, onInstalled)
+	return &started, nil
+}
+
+// GetTemurinInstall returns the latest install job of a feature version
+func GetTemurinInstall(featureVersion int) (*JDKInstallJob, bool) {
+	jdkInstallJobsMutex.Lock()
+	defer jdkInstallJobsMutex.Unlock()
+
+	job, exists := jdkInstallJobs[featureVersion]
+	if !exists {
+		return nil, false
+	}
+	copied := *job
+	return &copied, true
+}
+
+// runTemurinInstall installs the JDK of a job and records how it ended
+func (sm *Manager) runTemurinInstall(job *JDKInstallJob, onInstalled func(*JDKInstallation) error) {
+	progress := func(downloaded, total int64) {
+		jdkInstallJobsMutex.Lock()
+		job.BytesDownloaded = downloaded
+		job.BytesTotal = total
+		update := *job
+		jdkInstallJobsMutex.Unlock()
+		sm.broadcastMessage(WebSocketMessage{Type: "jdk_install", Payload: update})
+	}
+
+	installation, err := installTemurinJDK(job.Version, progress)
+	if err == nil && onInstalled != nil {
+		err = onInstalled(installation)
+	}
+
+	jdkInstallJobsMutex.Lock()
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Installation = installation
+	if err != nil {
+		job.Status = "failed"
+		job.Error = err.Error()
+	} else {
+		job.Status = "completed"
+	}
+	done := *job
+	jdkInstallJobsMutex.Unlock()
+
+	if err != nil {
+		log.Printf("[ERROR] Failed to install Temurin %d: %v", job.Version, err)
+	}
+	sm.broadcastMessage(WebSocketMessage{Type: "jdk_install", Payload: done})
+}
+
+// installTemurinJDK downloads the latest GA Temurin build of the given feature
+// version into the Vertex data directory and returns the resulting
+// installation. The archive is checked against the SHA-256 Adoptium publishes.
+func installTemurinJDK(featureVersion int, progress func(downloaded, total int64)) (*JDKInstallation, error) {
+	adoptiumOS, adoptiumArch, archiveExt, err := adoptiumPlatform()
+	if err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("temurin-%d", featureVersion)
+	targetDir := filepath.Join(GetJDKInstallDir(), name)
+
+	if javaHome := resolveJDKHome(targetDir); javaHome != "" {
+		log.Printf("[INFO] Temurin %d is already installed at %s", featureVersion, javaHome)
+		return &JDKInstallation{
+			Name:     name,
+			JavaHome: javaHome,
+			Version:  getJavaVersion(filepath.Join(javaHome, "bin", getJavaExecutable())),
+			Source:   "vertex",
+		}, nil
+	}
+
+	if err := os.MkdirAll(GetJDKInstallDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create JDK directory: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Minute}
+	pkg, err := latestTemurinPackage(client, featureVersion, adoptiumOS, adoptiumArch)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Downloading Temurin %d from %s", featureVersion, pkg.Link)
+
+	archive, err := os.CreateTemp(GetJDKInstallDir(), name+"-*"+archiveExt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download file: %w", err)
+	}
+	defer os.Remove(archive.Name())
+
+	resp, err := client.Get(pkg.Link)
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("failed to download JDK: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		archive.Close()
+		return nil, fmt.Errorf("JDK download failed with status %d", resp.StatusCode)
+	}
+
+	total := pkg.Size
+	if total <= 0 {
+		total = resp.ContentLength
+	}
+	hash := sha256.New()
+	counter := &downloadCounter{total: total, progress: progress}
+	if _, err := io.Copy(io.MultiWriter(archive, hash, counter), resp.Body); err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("failed to save JDK archive: %w", err)
+	}
+	archive.Close()
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(actual, pkg.Checksum) {
+		return nil, fmt.Errorf("JDK archive checksum mismatch: expected %s, got %s", pkg.Checksum, actual)
+	}
+
+	// Extract into a staging directory so a failed install never leaves a half-populated target
+	stagingDir := targetDir + ".partial"
+	os.RemoveAll(stagingDir)
+	defer os.RemoveAll(stagingDir)
+
+	if archiveExt == ".zip" {
+		err = extractZip(archive.Name(), stagingDir)
+	} else {
+		err = extractTarGz(archive.Name(), stagingDir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract JDK archive: %w", err)
+	}
+
+	os.RemoveAll(targetDir)
+	if err := os.Rename(stagingDir, targetDir); err != nil {
+		return nil, fmt.Errorf("failed to move JDK into place: %w", err)
+	}
+
+	javaHome := resolveJDKHome(targetDir)
+	if javaHome == "" {
+		return nil, fmt.Errorf("downloaded archive does not contain a usable JDK")
+	}
+
+	installation := &JDKInstallation{
+		Name:     name,
+		JavaHome: javaHome,
+		Version:  getJavaVersion(filepath.Join(javaHome, "bin", getJavaExecutable())),
+		Source:   "vertex",
+	}
+	log.Printf("[INFO] Installed Temurin %s at %s", installation.Version, installation.JavaHome)
+
+	return installation, nil
+}
+
+// latestTemurinPackage looks up the archive of the latest GA Temurin JDK for a
+// platform, along with its published checksum
+func latestTemurinPackage(client *http.Client, featureVersion int, adoptiumOS, adoptiumArch string) (*temurinPackage, error) {
+	url := fmt.Sprintf("%s/assets/latest/%d/hotspot?architecture=%s&image_type=jdk&os=%s&vendor=eclipse",
+		adoptiumAPIBase, featureVersion, adoptiumArch, adoptiumOS)
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Temurin %d release: %w", featureVersion, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Temurin %d release request failed with status %d", featureVersion, resp.StatusCode)
+	}
+
+	var assets []struct {
+		Binary struct {
+			Package temurinPackage `json:"package"`
+		} `json:"binary"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&assets); err != nil {
+		return nil, fmt.Errorf("failed to decode Temurin %d release: %w", featureVersion, err)
+	}
+	if len(assets) == 0 || assets[0].Binary.Package.Link == "" {
+		return nil, fmt.Errorf("no Temurin %d JDK is published for %s/%s", featureVersion, adoptiumOS, adoptiumArch)
+	}
+
+	pkg := assets[0].Binary.Package
+	if pkg.Checksum == "" {
+		return nil, fmt.Errorf("Temurin %d release has no published checksum", featureVersion)
+	}
+	return &pkg, nil
+}
+
+// downloadCounter reports the bytes written through it, at most every 1%
+type downloadCounter struct {
+	written, reported, total int64
+	progress                 func(downloaded, total int64)
+}
+
+func (c *downloadCounter) Write(p []byte) (int, error) {
+	c.written += int64(len(p))
+	if c.progress != nil && (c.total <= 0 || c.written-c.reported >= c.total/100 || c.written >= c.total) {
+		c.reported = c.written
+		c.progress(c.written, c.total)
+	}
+	return len(p), nil
+}
+
+// resolveJDKHome finds the JAVA_HOME inside a JDK directory, descending into the
+// single top-level folder of an extracted archive and the macOS bundle layout
+func resolveJDKHome(dir string) string {
+	candidates := []string{dir, filepath.Join(dir, "Contents", "Home")}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		nested := filepath.Join(dir, entries[0].Name())
+		candidates = append(candidates, nested, filepath.Join(nested, "Contents", "Home"))
+	}
+
+	for _, candidate := range candidates {
+		if isExecutable(filepath.Join(candidate, "bin", getJavaExecutable())) {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// adoptiumPlatform maps the current platform to Adoptium's os/arch identifiers
+func adoptiumPlatform() (string, string, string, error) {
+	var osName, ext string
+	switch runtime.GOOS {
+	case "linux":
+		osName, ext = "linux", ".tar.gz"
+	case "darwin":
+		osName, ext = "mac", ".tar.gz"
+	case "windows":
+		osName, ext = "windows", ".zip"
+	default:
+		return "", "", "", fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+
+	var arch string
+	switch runtime.GOARCH {
+	case "amd64":
+		arch = "x64"
+	case "arm64":
+		arch = "aarch64"
+	default:
+		return "", "", "", fmt.Errorf("unsupported architecture: %s", runtime.GOARCH)
+	}
+
+	return osName, arch, ext, nil
+}
+
+// safeJoin joins an archive entry name onto dest, rejecting entries that escape it
+func safeJoin(dest, name string) (string, error) {
+	target := filepath.Join(dest, name)
+	if target != filepath.Clean(dest) && !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return target, nil
+}
+
+func extractTarGz(archivePath, dest string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// A link must point inside dest, like the entry names
+			if filepath.IsAbs(header.Linkname) {
+				return fmt.Errorf("illegal symlink in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			linkTarget, err := filepath.Rel(dest, filepath.Join(filepath.Dir(target), header.Linkname))
+			if err != nil {
+				return fmt.Errorf("illegal symlink in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			if _, err := safeJoin(dest, linkTarget); err != nil {
+				return fmt.Errorf("illegal symlink in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(archivePath, dest string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		target, err := safeJoin(dest, file.Name)
+		if err != nil {
+			return err
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = writeArchiveFile(target, rc, file.Mode())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeArchiveFile(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, r)
+	return err
+}
+
+// SetServiceJavaHome pins a service to a specific JDK by setting its JAVA_HOME env var
+func (sm *Manager) SetServiceJavaHome(serviceUUID, javaHome string) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	envVars, err := sm.GetServiceEnvVars(serviceUUID)
+	if err != nil {
+		return err
+	}
+
+	envVars["JAVA_HOME"] = models.EnvVar{
+		Name:        "JAVA_HOME",
+		Value:       javaHome,
+		Description: "JDK assigned by Vertex",
+	}
+
+	return sm.UpdateServiceEnvVars(serviceUUID, envVars)
+}
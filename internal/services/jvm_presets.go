@@ -0,0 +1,217 @@
+// Package services - Named JVM option presets and their per-profile overrides
+package services
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// ConfigTypeJVMPreset marks profile service configs that replace a
+	// service's JVM preset within that profile
+	ConfigTypeJVMPreset = "jvm-preset"
+
+	jvmPresetConfigKey = "javaOptsPreset"
+)
+
+var (
+	jvmPresetNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,49}$`)
+	heapSizeRegex      = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+)
+
+// Flags selecting the garbage collector of a preset
+var jvmPresetGCFlags = map[string]string{
+	"g1":         "-XX:+UseG1GC",
+	"parallel":   "-XX:+UseParallelGC",
+	"serial":     "-XX:+UseSerialGC",
+	"zgc":        "-XX:+UseZGC",
+	"shenandoah": "-XX:+UseShenandoahGC",
+}
+
+// jvmPresetOpts expands a preset into JVM options
+func jvmPresetOpts(preset models.JVMPreset) string {
+	var opts []string
+	if preset.Xms != "" {
+		opts = append(opts, "-Xms"+preset.Xms)
+	}
+	if preset.Xmx != "" {
+		opts = append(opts, "-Xmx"+preset.Xmx)
+	}
+	if flag := jvmPresetGCFlags[preset.GC]; flag != "" {
+		opts = append(opts, flag)
+	}
+	if extra := strings.TrimSpace(preset.ExtraOpts); extra != "" {
+		opts = append(opts, extra)
+	}
+	return strings.Join(opts, " ")
+}
+
+// GetJVMPresets returns all JVM presets with the options they expand to and
+// the services referencing them
+func (sm *Manager) GetJVMPresets() ([]models.JVMPreset, error) {
+	presets, err := sm.db.GetJVMPresets()
+	if err != nil {
+		return nil, err
+	}
+
+	usedBy := sm.jvmPresetUsage()
+	for i := range presets {
+		presets[i].JavaOpts = jvmPresetOpts(presets[i])
+		presets[i].UsedBy = usedBy[presets[i].Name]
+		if presets[i].UsedBy == nil {
+			presets[i].UsedBy = []string{}
+		}
+	}
+	return presets, nil
+}
+
+// getJVMPreset looks a preset up by name
+func (sm *Manager) getJVMPreset(name string) (*models.JVMPreset, error) {
+	presets, err := sm.db.GetJVMPresets()
+	if err != nil {
+		return nil, err
+	}
+	for i := range presets {
+		if presets[i].Name == name {
+			return &presets[i], nil
+		}
+	}
+	return nil, fmt.Errorf("JVM preset '%s' not found", name)
+}
+
+// jvmPresetUsage maps preset names to the sorted names of the services using them
+func (sm *Manager) jvmPresetUsage() map[string][]string {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	usage := make(map[string][]string)
+	for _, service := range sm.services {
+		service.Mutex.RLock()
+		if service.JavaOptsPreset != "" {
+			usage[service.JavaOptsPreset] = append(usage[service.JavaOptsPreset], service.Name)
+		}
+		service.Mutex.RUnlock()
+	}
+	for _, names := range usage {
+		sort.Strings(names)
+	}
+	return usage
+}
+
+// SaveJVMPreset validates and creates or replaces a JVM preset. Services using
+// it pick up the change the next time they start.
+func (sm *Manager) SaveJVMPreset(preset models.JVMPreset) (*models.JVMPreset, error) {
+	preset.Name = strings.TrimSpace(preset.Name)
+	preset.Xms = strings.TrimSpace(preset.Xms)
+	preset.Xmx = strings.TrimSpace(preset.Xmx)
+	preset.GC = strings.ToLower(strings.TrimSpace(preset.GC))
+	preset.ExtraOpts = strings.TrimSpace(preset.ExtraOpts)
+
+	if !jvmPresetNameRegex.MatchString(preset.Name) {
+		return nil, fmt.Errorf("invalid preset name '%s' (use letters, digits, '.', '_' or '-')", preset.Name)
+	}
+	if preset.Xms != "" && !heapSizeRegex.MatchString(preset.Xms) {
+		return nil, fmt.Errorf("invalid xms '%s' (use a size such as 256m or 1g)", preset.Xms)
+	}
+	if preset.Xmx != "" && !heapSizeRegex.MatchString(preset.Xmx) {
+		return nil, fmt.Errorf("invalid xmx '%s' (use a size such as 512m or 2g)", preset.Xmx)
+	}
+	if preset.Xms != "" && preset.Xmx != "" && parseXmxMB("-Xmx"+preset.Xms) > parseXmxMB("-Xmx"+preset.Xmx) {
+		return nil, fmt.Errorf("xms (%s) cannot be larger than xmx (%s)", preset.Xms, preset.Xmx)
+	}
+	if _, known := jvmPresetGCFlags[preset.GC]; preset.GC != "" && !known {
+		return nil, fmt.Errorf("unknown garbage collector '%s' (use g1, parallel, serial, zgc or shenandoah)", preset.GC)
+	}
+
+	if err := sm.db.SaveJVMPreset(preset); err != nil {
+		return nil, err
+	}
+
+	saved, err := sm.getJVMPreset(preset.Name)
+	if err != nil {
+		return nil, err
+	}
+	saved.JavaOpts = jvmPresetOpts(*saved)
+	saved.UsedBy = sm.jvmPresetUsage()[saved.Name]
+	if saved.UsedBy == nil {
+		saved.UsedBy = []string{}
+	}
+	return saved, nil
+}
+
+// DeleteJVMPreset deletes a preset that no service references
+func (sm *Manager) DeleteJVMPreset(name string) error {
+	if _, err := sm.getJVMPreset(name); err != nil {
+		return err
+	}
+	if usedBy := sm.jvmPresetUsage()[name]; len(usedBy) > 0 {
+		return fmt.Errorf("JVM preset '%s' is still used by %s", name, strings.Join(usedBy, ", "))
+	}
+	return sm.db.DeleteJVMPreset(name)
+}
+
+// validateJVMPresetReference checks that a service refers to an existing preset
+func (sm *Manager) validateJVMPresetReference(name string) error {
+	if name == "" {
+		return nil
+	}
+	_, err := sm.getJVMPreset(name)
+	return err
+}
+
+// GetJVMPresetOverrides returns the preset each service uses within a profile
+// instead of its own, keyed by service UUID
+func (sm *Manager) GetJVMPresetOverrides(profileID string) (map[string]string, error) {
+	return sm.db.GetProfileConfigsByType(profileID, ConfigTypeJVMPreset, jvmPresetConfigKey)
+}
+
+// SetJVMPresetOverride makes a service use another preset within a profile.
+// An empty preset removes the override. It takes effect on the next start.
+func (sm *Manager) SetJVMPresetOverride(profileID, serviceUUID, preset string) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	preset = strings.TrimSpace(preset)
+	if preset == "" {
+		return sm.db.DeleteProfileServiceConfig(profileID, serviceUUID, jvmPresetConfigKey)
+	}
+	if err := sm.validateJVMPresetReference(preset); err != nil {
+		return err
+	}
+	return sm.db.SetProfileServiceConfig(profileID, serviceUUID, jvmPresetConfigKey, preset, ConfigTypeJVMPreset, "JVM preset override")
+}
+
+// resolveJavaOpts returns the JVM options a service starts with: those of its
+// preset (or the profile's override of it) followed by its own JAVA_OPTS, so
+// flags set on the service win over the preset's. An override naming a
+// deleted preset falls back to the service's own preset.
+func (sm *Manager) resolveJavaOpts(profileID, serviceUUID, presetName, javaOpts string) string {
+	candidates := []string{presetName}
+	if profileID != "" {
+		overrides, err := sm.db.GetProfileServiceConfigByType(profileID, serviceUUID, ConfigTypeJVMPreset)
+		if err != nil {
+			log.Printf("[WARN] Failed to load JVM preset override for service %s: %v", serviceUUID, err)
+		} else if override := overrides[jvmPresetConfigKey]; override != "" {
+			candidates = []string{override, presetName}
+		}
+	}
+
+	for _, name := range candidates {
+		if name == "" {
+			continue
+		}
+		preset, err := sm.getJVMPreset(name)
+		if err != nil {
+			log.Printf("[WARN] Ignoring JVM preset of service %s: %v", serviceUUID, err)
+			continue
+		}
+		return strings.TrimSpace(jvmPresetOpts(*preset) + " " + javaOpts)
+	}
+	return javaOpts
+}
@@ -0,0 +1,194 @@
+// Package services - Stack trace grouping and error clustering for service logs
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// How long a log entry waits for stack trace lines before it is emitted
+	logGroupFlushDelay = 200 * time.Millisecond
+	// Frames of the root cause that identify where an exception was thrown
+	fingerprintFrames = 3
+	// Upper bound on distinct clusters kept per service
+	maxErrorClusters = 200
+	// Upper bound on lines folded into one log entry
+	maxGroupedLines = 500
+)
+
+var (
+	stackFrameRegex      = regexp.MustCompile(`^\s+at\s+(\S+?)(\(|$)`)
+	stackContinueRegex   = regexp.MustCompile(`^\s*(\.\.\. \d+ (more|common frames omitted)|Caused by: |Suppressed: )`)
+	exceptionHeaderRegex = regexp.MustCompile(`^(?:Exception in thread "[^"]*" )?((?:[a-zA-Z_$][\w$]*\.)+[\w$]*(?:Exception|Error|Throwable))(?::\s*(.*))?$`)
+	causedByRegex        = regexp.MustCompile(`(?m)^\s*Caused by: ((?:[a-zA-Z_$][\w$]*\.)+[\w$]*)(?::\s*(.*))?$`)
+	logVariableRegex     = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b|\b0x[0-9a-fA-F]+\b|\d+`)
+	logPrefixRegex       = regexp.MustCompile(`^.*?\b(ERROR|FATAL)\b[^:]*?:\s`)
+)
+
+// ErrorCluster groups recurring errors of a service that share a fingerprint
+type ErrorCluster struct {
+	Fingerprint    string `json:"fingerprint"`
+	ExceptionClass string `json:"exceptionClass,omitempty"`
+	Message        string `json:"message"`
+	Level          string `json:"level"`
+	Count          int    `json:"count"`
+	FirstSeen      string `json:"firstSeen"`
+	LastSeen       string `json:"lastSeen"`
+	Sample         string `json:"sample"` // Most recent full entry, including the stack trace
+}
+
+var (
+	errorClusters      = make(map[string]map[string]*ErrorCluster) // service UUID -> fingerprint -> cluster
+	errorClustersMutex sync.Mutex
+)
+
+// isLogContinuation reports whether a line belongs to the entry before it:
+// stack frames, "Caused by:" sections, and an exception header directly after
+// an ERROR or WARN line
+func isLogContinuation(line string, previous *models.LogEntry) bool {
+	if stackFrameRegex.MatchString(line) || stackContinueRegex.MatchString(line) {
+		return true
+	}
+	if previous.Level != "ERROR" && previous.Level != "WARN" {
+		return false
+	}
+	return !strings.Contains(previous.Message, "\n") && exceptionHeaderRegex.MatchString(strings.TrimSpace(line))
+}
+
+// newLogEntry parses a line that starts a new entry; a bare exception header
+// (as printed by printStackTrace) is an error
+func newLogEntry(serviceUUID, line string) models.LogEntry {
+	entry := parseLogLine(serviceUUID, line)
+	if exceptionHeaderRegex.MatchString(strings.TrimSpace(line)) {
+		entry.Level = "ERROR"
+	}
+	return entry
+}
+
+// recordErrorCluster counts an ERROR entry under its fingerprint
+func recordErrorCluster(serviceUUID string, entry models.LogEntry) {
+	if entry.Level != "ERROR" && entry.Level != "FATAL" {
+		return
+	}
+
+	fingerprint, exceptionClass, message := fingerprintLogEntry(entry.Message)
+
+	errorClustersMutex.Lock()
+	defer errorClustersMutex.Unlock()
+
+	clusters := errorClusters[serviceUUID]
+	if clusters == nil {
+		clusters = make(map[string]*ErrorCluster)
+		errorClusters[serviceUUID] = clusters
+	}
+
+	cluster, exists := clusters[fingerprint]
+	if !exists {
+		if len(clusters) >= maxErrorClusters {
+			evictOldestCluster(clusters)
+		}
+		cluster = &ErrorCluster{
+			Fingerprint:    fingerprint,
+			ExceptionClass: exceptionClass,
+			Message:        message,
+			Level:          entry.Level,
+			FirstSeen:      entry.Timestamp,
+		}
+		clusters[fingerprint] = cluster
+	}
+	cluster.Count++
+	cluster.LastSeen = entry.Timestamp
+	cluster.Sample = entry.Message
+}
+
+func evictOldestCluster(clusters map[string]*ErrorCluster) {
+	var oldest *ErrorCluster
+	for _, cluster := range clusters {
+		if oldest == nil || cluster.LastSeen < oldest.LastSeen {
+			oldest = cluster
+		}
+	}
+	if oldest != nil {
+		delete(clusters, oldest.Fingerprint)
+	}
+}
+
+// fingerprintLogEntry identifies an error by its root cause exception and the
+// top frames where it was thrown. Errors without a stack trace are identified
+// by their message with numbers, ids and the log prefix removed.
+func fingerprintLogEntry(message string) (fingerprint, exceptionClass, summary string) {
+	lines := strings.Split(message, "\n")
+	summary = strings.TrimSpace(logPrefixRegex.ReplaceAllString(lines[0], ""))
+
+	// The root cause is the last "Caused by:" section, or the first exception
+	rootStart := -1
+	for i, line := range lines {
+		if match := exceptionHeaderRegex.FindStringSubmatch(strings.TrimSpace(line)); match != nil && rootStart < 0 {
+			exceptionClass = match[1]
+			rootStart = i
+			if i == 0 {
+				summary = strings.TrimSpace(line)
+			}
+		}
+		if match := causedByRegex.FindStringSubmatch(line); match != nil {
+			exceptionClass = match[1]
+			rootStart = i
+		}
+	}
+
+	var key []string
+	if exceptionClass != "" {
+		key = append(key, exceptionClass)
+		for _, line := range lines[rootStart+1:] {
+			if len(key) > fingerprintFrames {
+				break
+			}
+			if match := stackFrameRegex.FindStringSubmatch(line); match != nil {
+				key = append(key, match[1])
+			} else if stackContinueRegex.MatchString(line) {
+				break
+			}
+		}
+	} else {
+		key = append(key, logVariableRegex.ReplaceAllString(summary, "#"))
+	}
+
+	sum := sha1.Sum([]byte(strings.Join(key, "|")))
+	return hex.EncodeToString(sum[:])[:12], exceptionClass, summary
+}
+
+// GetErrorClusters returns the deduplicated errors of a service, most frequent first
+func (sm *Manager) GetErrorClusters(serviceUUID string) ([]ErrorCluster, bool) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, false
+	}
+
+	errorClustersMutex.Lock()
+	clusters := make([]ErrorCluster, 0, len(errorClusters[serviceUUID]))
+	for _, cluster := range errorClusters[serviceUUID] {
+		clusters = append(clusters, *cluster)
+	}
+	errorClustersMutex.Unlock()
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Count != clusters[j].Count {
+			return clusters[i].Count > clusters[j].Count
+		}
+		return clusters[i].LastSeen > clusters[j].LastSeen
+	})
+	return clusters, true
+}
+
+func clearErrorClusters(serviceUUID string) {
+	errorClustersMutex.Lock()
+	delete(errorClusters, serviceUUID)
+	errorClustersMutex.Unlock()
+}
@@ -0,0 +1,420 @@
+// Package services - Rotating per-service log files
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	defaultLogFileMaxSizeMB = 10
+	defaultLogFileMaxFiles  = 5
+	maxLogFileMaxSizeMB     = 1024
+	maxLogFileMaxFiles      = 50
+	currentLogFileName      = "service.log"
+)
+
+// Log archive formats accepted by WriteLogArchive
+const (
+	LogArchiveGzip = "gz"
+	LogArchiveText = "text"
+)
+
+// rotatingLogFile appends log lines to service.log and shifts it to
+// service.log.1, service.log.2, ... once it reaches the size limit
+type rotatingLogFile struct {
+	mutex    sync.Mutex
+	dir      string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// Open log files by service UUID
+var (
+	logFiles      = make(map[string]*rotatingLogFile)
+	logFilesMutex sync.RWMutex
+)
+
+// logFileDir returns the directory holding a service's log files
+func logFileDir(serviceUUID string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, serviceUUID)
+	return filepath.Join(database.GetDataDir(), "logs", safe)
+}
+
+func openRotatingLogFile(dir string, maxSizeMB, maxFiles int) (*rotatingLogFile, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory %s: %w", dir, err)
+	}
+
+	lf := &rotatingLogFile{
+		dir:      dir,
+		maxBytes: int64(maxSizeMB) * 1024 * 1024,
+		maxFiles: maxFiles,
+	}
+	if err := lf.open(); err != nil {
+		return nil, err
+	}
+	return lf, nil
+}
+
+func (lf *rotatingLogFile) open() error {
+	file, err := os.OpenFile(filepath.Join(lf.dir, currentLogFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	lf.file = file
+	lf.size = info.Size()
+	return nil
+}
+
+func (lf *rotatingLogFile) write(line string) error {
+	lf.mutex.Lock()
+	defer lf.mutex.Unlock()
+
+	if lf.file == nil {
+		return fmt.Errorf("log file is closed")
+	}
+	if lf.size > 0 && lf.size+int64(len(line)) > lf.maxBytes {
+		if err := lf.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := lf.file.WriteString(line)
+	lf.size += int64(n)
+	return err
+}
+
+// rotate shifts every file one position up, dropping the oldest. Callers hold the mutex.
+func (lf *rotatingLogFile) rotate() error {
+	lf.file.Close()
+	lf.file = nil
+
+	base := filepath.Join(lf.dir, currentLogFileName)
+	os.Remove(fmt.Sprintf("%s.%d", base, lf.maxFiles))
+	for i := lf.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", base, i), fmt.Sprintf("%s.%d", base, i+1))
+	}
+	if err := os.Rename(base, base+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	return lf.open()
+}
+
+// truncate removes every file and starts a fresh one
+func (lf *rotatingLogFile) truncate() error {
+	lf.mutex.Lock()
+	defer lf.mutex.Unlock()
+
+	if lf.file != nil {
+		lf.file.Close()
+		lf.file = nil
+	}
+	removeLogFiles(lf.dir)
+	return lf.open()
+}
+
+func (lf *rotatingLogFile) close() {
+	lf.mutex.Lock()
+	defer lf.mutex.Unlock()
+
+	if lf.file != nil {
+		lf.file.Close()
+		lf.file = nil
+	}
+}
+
+// loadLogFiles opens the log files of every service with file logging enabled
+func (sm *Manager) loadLogFiles() error {
+	configs, err := sm.db.GetLogFileConfigs()
+	if err != nil {
+		return err
+	}
+
+	for _, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		if err := startLogFile(config); err != nil {
+			log.Printf("[WARN] Could not open log file for service UUID %s: %v", config.ServiceID, err)
+		}
+	}
+	return nil
+}
+
+// startLogFile replaces the open log file of a service
+func startLogFile(config models.LogFileConfig) error {
+	lf, err := openRotatingLogFile(logFileDir(config.ServiceID), config.MaxSizeMB, config.MaxFiles)
+	if err != nil {
+		return err
+	}
+
+	logFilesMutex.Lock()
+	previous := logFiles[config.ServiceID]
+	logFiles[config.ServiceID] = lf
+	logFilesMutex.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+	return nil
+}
+
+// stopLogFile closes the log file of a service, keeping what was written
+func stopLogFile(serviceUUID string) {
+	logFilesMutex.Lock()
+	lf := logFiles[serviceUUID]
+	delete(logFiles, serviceUUID)
+	logFilesMutex.Unlock()
+
+	if lf != nil {
+		lf.close()
+	}
+}
+
+// discardLogFiles closes and deletes the log files of a removed service
+func discardLogFiles(serviceUUID string) {
+	stopLogFile(serviceUUID)
+	os.RemoveAll(logFileDir(serviceUUID))
+}
+
+// writeLogFile appends a log line to the service's file when file logging is on.
+// A failing file is closed so a full disk doesn't produce an error per line.
+func writeLogFile(serviceUUID string, logEntry models.LogEntry) {
+	logFilesMutex.RLock()
+	lf := logFiles[serviceUUID]
+	logFilesMutex.RUnlock()
+
+	if lf == nil {
+		return
+	}
+
+	line := fmt.Sprintf("[%s] [%s] %s\n", logEntry.Timestamp, logEntry.Level, logEntry.Message)
+	if err := lf.write(line); err != nil {
+		log.Printf("[ERROR] Disabling log file for service UUID %s after write failure: %v", serviceUUID, err)
+		stopLogFile(serviceUUID)
+	}
+}
+
+// clearLogFiles empties the log files of a service
+func clearLogFiles(serviceUUID string) error {
+	logFilesMutex.RLock()
+	lf := logFiles[serviceUUID]
+	logFilesMutex.RUnlock()
+
+	if lf != nil {
+		return lf.truncate()
+	}
+	removeLogFiles(logFileDir(serviceUUID))
+	return nil
+}
+
+// removeLogFiles deletes service.log and its rotated files from a directory
+func removeLogFiles(dir string) {
+	for _, file := range listLogFiles(dir) {
+		os.Remove(filepath.Join(dir, file.Name))
+	}
+}
+
+// listLogFiles returns the log files in a directory, newest first
+func listLogFiles(dir string) []models.LogFileInfo {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []models.LogFileInfo{}
+	}
+
+	files := []models.LogFileInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || logFileIndex(entry.Name()) < 0 {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, models.LogFileInfo{
+			Name:       entry.Name(),
+			SizeBytes:  info.Size(),
+			ModifiedAt: info.ModTime(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return logFileIndex(files[i].Name) < logFileIndex(files[j].Name)
+	})
+	return files
+}
+
+// logFileIndex returns 0 for service.log, n for service.log.n and -1 for
+// anything else
+func logFileIndex(name string) int {
+	if name == currentLogFileName {
+		return 0
+	}
+	suffix, found := strings.CutPrefix(name, currentLogFileName+".")
+	if !found {
+		return -1
+	}
+	n, err := strconv.Atoi(suffix)
+	if err != nil || n < 1 {
+		return -1
+	}
+	return n
+}
+
+// GetLogFileConfig returns the log file settings of a service and the files on disk
+func (sm *Manager) GetLogFileConfig(serviceUUID string) (*models.LogFileConfig, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	configs, err := sm.db.GetLogFileConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	config := models.LogFileConfig{
+		ServiceID: serviceUUID,
+		MaxSizeMB: defaultLogFileMaxSizeMB,
+		MaxFiles:  defaultLogFileMaxFiles,
+	}
+	for _, stored := range configs {
+		if stored.ServiceID == serviceUUID {
+			config = stored
+			break
+		}
+	}
+	config.Files = listLogFiles(logFileDir(serviceUUID))
+	return &config, nil
+}
+
+// SetLogFileConfig validates and saves a service's log file settings and
+// opens or closes its file accordingly
+func (sm *Manager) SetLogFileConfig(config models.LogFileConfig) error {
+	if _, exists := sm.GetServiceByUUID(config.ServiceID); !exists {
+		return fmt.Errorf("service UUID %s not found", config.ServiceID)
+	}
+
+	if config.MaxSizeMB <= 0 {
+		config.MaxSizeMB = defaultLogFileMaxSizeMB
+	}
+	if config.MaxSizeMB > maxLogFileMaxSizeMB {
+		return fmt.Errorf("maxSizeMb cannot exceed %d", maxLogFileMaxSizeMB)
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = defaultLogFileMaxFiles
+	}
+	if config.MaxFiles > maxLogFileMaxFiles {
+		return fmt.Errorf("maxFiles cannot exceed %d", maxLogFileMaxFiles)
+	}
+
+	if err := sm.db.SaveLogFileConfig(config); err != nil {
+		return err
+	}
+
+	if !config.Enabled {
+		stopLogFile(config.ServiceID)
+		return nil
+	}
+	return startLogFile(config)
+}
+
+// WriteLogArchive writes a service's log files, oldest first, either as a
+// gzipped tarball or as one plain-text stream
+func (sm *Manager) WriteLogArchive(serviceUUID, format string, w io.Writer) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	dir := logFileDir(serviceUUID)
+
+	// Open every file while holding the writer's lock so a rotation can't
+	// move them in between; the open handles stay valid afterwards
+	logFilesMutex.RLock()
+	lf := logFiles[serviceUUID]
+	logFilesMutex.RUnlock()
+	if lf != nil {
+		lf.mutex.Lock()
+	}
+	files := listLogFiles(dir)
+	handles := make([]*os.File, 0, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		handle, err := os.Open(filepath.Join(dir, files[i].Name))
+		if err != nil {
+			continue
+		}
+		handles = append(handles, handle)
+	}
+	if lf != nil {
+		lf.mutex.Unlock()
+	}
+	defer func() {
+		for _, handle := range handles {
+			handle.Close()
+		}
+	}()
+
+	switch format {
+	case LogArchiveText:
+		for _, handle := range handles {
+			if _, err := io.Copy(w, handle); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case LogArchiveGzip:
+		gz := gzip.NewWriter(w)
+		tw := tar.NewWriter(gz)
+		for _, handle := range handles {
+			info, err := handle.Stat()
+			if err != nil {
+				return err
+			}
+			header := &tar.Header{
+				Name:    filepath.Base(handle.Name()),
+				Mode:    0644,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			// The current file may grow while it is copied; the header fixed its size
+			if _, err := io.Copy(tw, io.LimitReader(handle, info.Size())); err != nil {
+				return err
+			}
+		}
+		if err := tw.Close(); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+
+	return fmt.Errorf("unsupported log archive format '%s'", format)
+}
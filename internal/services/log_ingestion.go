@@ -0,0 +1,268 @@
+// Package services - Dropping, sampling and rate limiting captured log lines
+package services
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	maxLogDropPatterns   = 50
+	maxLogLinesPerSecond = 100000
+)
+
+// The Spring Boot banner is ASCII art made of these characters, followed by
+// the version line
+var (
+	bannerArtRegex     = regexp.MustCompile("^[ _./\\\\|()'`,=<>^*#+~:-]{10,}$")
+	bannerVersionRegex = regexp.MustCompile(`^\s*:: Spring Boot ::\s+\(v[\w.-]+\)\s*$`)
+)
+
+// logIngestionFilter applies a service's ingestion settings to its captured
+// lines and counts what it keeps and drops
+type logIngestionFilter struct {
+	mutex      sync.Mutex
+	config     models.LogIngestionConfig
+	patterns   []*regexp.Regexp
+	stats      models.LogIngestionStats
+	debugSeen  int64
+	tokens     float64
+	refilledAt time.Time
+	suppressed int64 // Lines dropped by the rate cap since the last notice
+}
+
+// Ingestion filters by service UUID; services without one keep every line
+var (
+	logIngestionFilters      = make(map[string]*logIngestionFilter)
+	logIngestionFiltersMutex sync.RWMutex
+)
+
+// isBannerLine reports whether a line belongs to the Spring Boot startup banner
+func isBannerLine(line string) bool {
+	if bannerVersionRegex.MatchString(line) {
+		return true
+	}
+	return bannerArtRegex.MatchString(line) && strings.ContainsAny(line, "_|/\\")
+}
+
+// compileLogDropPatterns compiles drop patterns, naming the one that is invalid
+func compileLogDropPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid drop pattern '%s': %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// logIngestionActive reports whether the settings drop anything at all
+func logIngestionActive(config models.LogIngestionConfig) bool {
+	return config.DropBanner || len(config.DropPatterns) > 0 || config.DebugSamplePercent > 0 || config.MaxLinesPerSecond > 0
+}
+
+// loadLogIngestion installs the ingestion filters of every service that has them
+func (sm *Manager) loadLogIngestion() error {
+	configs, err := sm.db.GetLogIngestionConfigs()
+	if err != nil {
+		return err
+	}
+
+	for _, config := range configs {
+		if err := startLogIngestion(config); err != nil {
+			log.Printf("[WARN] Keeping every log line of service UUID %s: %v", config.ServiceID, err)
+		}
+	}
+	return nil
+}
+
+// startLogIngestion replaces the ingestion filter of a service, starting its
+// counters over
+func startLogIngestion(config models.LogIngestionConfig) error {
+	if !logIngestionActive(config) {
+		stopLogIngestion(config.ServiceID)
+		return nil
+	}
+
+	patterns, err := compileLogDropPatterns(config.DropPatterns)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	filter := &logIngestionFilter{
+		config:     config,
+		patterns:   patterns,
+		stats:      models.LogIngestionStats{Since: now},
+		tokens:     float64(config.MaxLinesPerSecond),
+		refilledAt: now,
+	}
+
+	logIngestionFiltersMutex.Lock()
+	logIngestionFilters[config.ServiceID] = filter
+	logIngestionFiltersMutex.Unlock()
+	return nil
+}
+
+// stopLogIngestion removes the ingestion filter of a service
+func stopLogIngestion(serviceUUID string) {
+	logIngestionFiltersMutex.Lock()
+	delete(logIngestionFilters, serviceUUID)
+	logIngestionFiltersMutex.Unlock()
+}
+
+// admit decides whether to keep a log entry. Once lines are kept again after
+// the rate cap dropped some, it also returns how many were dropped.
+func (f *logIngestionFilter) admit(entry models.LogEntry, now time.Time) (bool, int64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.config.DropBanner && isBannerLine(entry.Message) {
+		f.stats.DroppedByPattern++
+		return false, 0
+	}
+	for _, pattern := range f.patterns {
+		if pattern.MatchString(entry.Message) {
+			f.stats.DroppedByPattern++
+			return false, 0
+		}
+	}
+
+	// Keep an even share of DEBUG and TRACE lines: the nth is kept when it
+	// raises the number kept so far
+	if f.config.DebugSamplePercent > 0 && (entry.Level == "DEBUG" || entry.Level == "TRACE") {
+		f.debugSeen++
+		percent := int64(f.config.DebugSamplePercent)
+		if f.debugSeen*percent/100 == (f.debugSeen-1)*percent/100 {
+			f.stats.DroppedBySampling++
+			return false, 0
+		}
+	}
+
+	if rate := float64(f.config.MaxLinesPerSecond); rate > 0 {
+		f.tokens = min(rate, f.tokens+now.Sub(f.refilledAt).Seconds()*rate)
+		f.refilledAt = now
+		if f.tokens < 1 {
+			f.stats.DroppedByRate++
+			f.suppressed++
+			return false, 0
+		}
+		f.tokens--
+	}
+
+	f.stats.Kept++
+	suppressed := f.suppressed
+	f.suppressed = 0
+	return true, suppressed
+}
+
+// ingestLogEntry applies the service's ingestion filter to a captured entry
+// and emits it when kept. Lines dropped by the rate cap are noted in the
+// service's log once it keeps lines again.
+func (sm *Manager) ingestLogEntry(service *models.Service, entry models.LogEntry) {
+	logIngestionFiltersMutex.RLock()
+	filter := logIngestionFilters[service.ID]
+	logIngestionFiltersMutex.RUnlock()
+
+	if filter == nil {
+		sm.emitLogEntry(service, entry)
+		return
+	}
+
+	keep, suppressed := filter.admit(entry, time.Now())
+	if !keep {
+		return
+	}
+	if suppressed > 0 {
+		sm.emitLogEntry(service, models.LogEntry{
+			Timestamp: time.Now().Format(time.RFC3339Nano),
+			Level:     "WARN",
+			Message:   fmt.Sprintf("[vertex] Dropped %d log lines over the limit of %d lines per second", suppressed, filter.config.MaxLinesPerSecond),
+		})
+	}
+	sm.emitLogEntry(service, entry)
+}
+
+// GetLogIngestionConfig returns a service's log ingestion settings and what
+// they kept and dropped
+func (sm *Manager) GetLogIngestionConfig(serviceUUID string) (*models.LogIngestionConfig, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	configs, err := sm.db.GetLogIngestionConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	config := models.LogIngestionConfig{ServiceID: serviceUUID, DropPatterns: []string{}}
+	for _, stored := range configs {
+		if stored.ServiceID == serviceUUID {
+			config = stored
+			break
+		}
+	}
+	if config.DropPatterns == nil {
+		config.DropPatterns = []string{}
+	}
+
+	logIngestionFiltersMutex.RLock()
+	filter := logIngestionFilters[serviceUUID]
+	logIngestionFiltersMutex.RUnlock()
+	if filter != nil {
+		filter.mutex.Lock()
+		stats := filter.stats
+		filter.mutex.Unlock()
+		config.Stats = &stats
+	}
+	return &config, nil
+}
+
+// SetLogIngestionConfig validates and saves a service's log ingestion
+// settings; they apply to the next line it logs
+func (sm *Manager) SetLogIngestionConfig(config models.LogIngestionConfig) error {
+	if _, exists := sm.GetServiceByUUID(config.ServiceID); !exists {
+		return fmt.Errorf("service UUID %s not found", config.ServiceID)
+	}
+
+	if err := normalizeLogIngestionConfig(&config); err != nil {
+		return err
+	}
+	if err := sm.db.SaveLogIngestionConfig(config); err != nil {
+		return err
+	}
+	return startLogIngestion(config)
+}
+
+// normalizeLogIngestionConfig trims and checks log ingestion settings
+func normalizeLogIngestionConfig(config *models.LogIngestionConfig) error {
+	patterns := []string{}
+	for _, pattern := range config.DropPatterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" && !slices.Contains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) > maxLogDropPatterns {
+		return fmt.Errorf("at most %d drop patterns are allowed", maxLogDropPatterns)
+	}
+	if _, err := compileLogDropPatterns(patterns); err != nil {
+		return err
+	}
+	config.DropPatterns = patterns
+
+	if config.DebugSamplePercent < 0 || config.DebugSamplePercent > 100 {
+		return fmt.Errorf("debugSamplePercent must be between 1 and 100, or 0 to keep every DEBUG line")
+	}
+	if config.MaxLinesPerSecond < 0 || config.MaxLinesPerSecond > maxLogLinesPerSecond {
+		return fmt.Errorf("maxLinesPerSecond must be between 1 and %d, or 0 for no cap", maxLogLinesPerSecond)
+	}
+	return nil
+}
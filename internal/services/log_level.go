@@ -0,0 +1,291 @@
+// Package services - Changing the log level of a service at runtime
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Ways a log level change reaches a service
+const (
+	LogLevelActuator = "actuator" // Spring Boot /actuator/loggers, applied live
+	LogLevelEnv      = "env"      // LOG_LEVEL environment variable, applied on start
+)
+
+const (
+	logLevelEnvVar  = "LOG_LEVEL"
+	logLevelTimeout = 10 * time.Second
+)
+
+var (
+	logLevels         = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "OFF"}
+	loggerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_$.-]+$`)
+)
+
+// LogLevelRequest changes the level of a logger. An empty level resets a
+// Spring logger to its configured level, or removes LOG_LEVEL.
+type LogLevelRequest struct {
+	Level   string `json:"level"`
+	Logger  string `json:"logger"`  // Spring logger name; defaults to ROOT
+	Restart bool   `json:"restart"` // Restart a running non-Spring service so LOG_LEVEL takes effect
+}
+
+// LogLevelResult describes a service's log level and how a change was applied
+type LogLevelResult struct {
+	Method          string `json:"method"`
+	Logger          string `json:"logger,omitempty"`
+	ConfiguredLevel string `json:"configuredLevel,omitempty"`
+	EffectiveLevel  string `json:"effectiveLevel,omitempty"`
+	Applied         bool   `json:"applied"`
+	Restarted       bool   `json:"restarted"`
+	RestartRequired bool   `json:"restartRequired"`
+	Message         string `json:"message,omitempty"`
+}
+
+// actuatorLogger is the body of GET /actuator/loggers/{name}
+type actuatorLogger struct {
+	ConfiguredLevel string `json:"configuredLevel"`
+	EffectiveLevel  string `json:"effectiveLevel"`
+}
+
+// normalizeLogLevel upper-cases a level and checks it is one Spring and
+// most logging libraries know
+func normalizeLogLevel(level string) (string, error) {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if level == "WARNING" {
+		level = "WARN"
+	}
+	if level == "" {
+		return "", nil
+	}
+	for _, known := range logLevels {
+		if level == known {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("invalid log level '%s' (use one of %s)", level, strings.Join(logLevels, ", "))
+}
+
+// logLevelTarget returns whether a service is a Spring service reachable
+// through its actuator, and whether it is running
+func (sm *Manager) logLevelTarget(service *models.Service) (spring, running bool) {
+	service.Mutex.RLock()
+	serviceDir := service.Dir
+	running = service.Status == "running"
+	service.Mutex.RUnlock()
+
+	projectsDir := sm.getServiceProjectsDirectory(service.ID)
+	if projectsDir == "" {
+		projectsDir = sm.config.ProjectsDir
+	}
+	return isJVMProject(filepath.Join(projectsDir, serviceDir)), running
+}
+
+// GetServiceLogLevel returns the level of a logger of a running Spring
+// service, or the LOG_LEVEL of any other service
+func (sm *Manager) GetServiceLogLevel(serviceUUID, logger string) (*LogLevelResult, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	spring, running := sm.logLevelTarget(service)
+	if !spring {
+		service.Mutex.RLock()
+		level := strings.ToUpper(service.EnvVars[logLevelEnvVar].Value)
+		service.Mutex.RUnlock()
+		return &LogLevelResult{Method: LogLevelEnv, ConfiguredLevel: level, Applied: true}, nil
+	}
+
+	if logger == "" {
+		logger = "ROOT"
+	}
+	result := &LogLevelResult{Method: LogLevelActuator, Logger: logger}
+	if !running {
+		result.Message = fmt.Sprintf("service %s is not running", service.Name)
+		return result, nil
+	}
+
+	current, err := getActuatorLogger(service, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log level of %s: %w", service.Name, err)
+	}
+	result.ConfiguredLevel = current.ConfiguredLevel
+	result.EffectiveLevel = current.EffectiveLevel
+	result.Applied = true
+	return result, nil
+}
+
+// SetServiceLogLevel changes the log level of a service. Running Spring
+// services get the level through /actuator/loggers, which lasts until the
+// next restart. Other services get LOG_LEVEL, which takes effect on their
+// next start, optionally restarting a running service right away.
+func (sm *Manager) SetServiceLogLevel(serviceUUID string, request LogLevelRequest) (*LogLevelResult, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	level, err := normalizeLogLevel(request.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	spring, running := sm.logLevelTarget(service)
+	if spring {
+		logger := strings.TrimSpace(request.Logger)
+		if logger == "" {
+			logger = "ROOT"
+		}
+		if !loggerNamePattern.MatchString(logger) {
+			return nil, fmt.Errorf("invalid logger name '%s'", logger)
+		}
+		if !running {
+			return nil, fmt.Errorf("service %s is not running; the actuator can only change the level of a running service", service.Name)
+		}
+
+		if err := postActuatorLogger(service, logger, level); err != nil {
+			return nil, fmt.Errorf("failed to set log level of %s: %w", service.Name, err)
+		}
+		result := &LogLevelResult{Method: LogLevelActuator, Logger: logger, Applied: true}
+		if current, err := getActuatorLogger(service, logger); err == nil {
+			result.ConfiguredLevel = current.ConfiguredLevel
+			result.EffectiveLevel = current.EffectiveLevel
+		}
+		result.Message = fmt.Sprintf("Logger %s set to %s via /actuator/loggers until the service restarts", logger, result.EffectiveLevel)
+		if level == "" {
+			result.Message = fmt.Sprintf("Logger %s reset to its configured level", logger)
+		}
+		sm.logHookOutput(service, "INFO", result.Message)
+		return result, nil
+	}
+
+	service.Mutex.RLock()
+	envVars := make(map[string]models.EnvVar, len(service.EnvVars)+1)
+	for name, envVar := range service.EnvVars {
+		envVars[name] = envVar
+	}
+	service.Mutex.RUnlock()
+
+	if level == "" {
+		delete(envVars, logLevelEnvVar)
+	} else {
+		// Most non-JVM logging libraries expect lower case levels
+		envVars[logLevelEnvVar] = models.EnvVar{
+			Name:        logLevelEnvVar,
+			Value:       strings.ToLower(level),
+			Description: "Log level set through Vertex",
+		}
+	}
+	if err := sm.UpdateServiceEnvVars(serviceUUID, envVars); err != nil {
+		return nil, err
+	}
+
+	result := &LogLevelResult{Method: LogLevelEnv, ConfiguredLevel: level}
+	switch {
+	case !running:
+		result.Applied = true
+		result.Message = "LOG_LEVEL saved; it applies when the service starts"
+	case request.Restart:
+		projectsDir := sm.getServiceProjectsDirectory(serviceUUID)
+		if projectsDir == "" {
+			projectsDir = sm.config.ProjectsDir
+		}
+		if err := sm.RestartServiceWithProjectsDir(serviceUUID, projectsDir); err != nil {
+			result.RestartRequired = true
+			result.Message = fmt.Sprintf("LOG_LEVEL saved but the restart failed: %v", err)
+			return result, nil
+		}
+		result.Applied = true
+		result.Restarted = true
+		result.Message = "LOG_LEVEL saved and the service restarted"
+	default:
+		result.RestartRequired = true
+		result.Message = "LOG_LEVEL saved; restart the service to apply it"
+	}
+	log.Printf("[INFO] Service %s: %s", service.Name, result.Message)
+	return result, nil
+}
+
+// actuatorLoggerURL derives the loggers endpoint from the health URL, like
+// the refresh endpoint
+func actuatorLoggerURL(service *models.Service, logger string) string {
+	service.Mutex.RLock()
+	defer service.Mutex.RUnlock()
+	return strings.TrimSuffix(actuatorRefreshURL(service.HealthURL, service.Port), "/refresh") + "/loggers/" + logger
+}
+
+// getActuatorLogger reads GET /actuator/loggers/{name}
+func getActuatorLogger(service *models.Service, logger string) (*actuatorLogger, error) {
+	loggerURL := actuatorLoggerURL(service, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), logLevelTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loggerURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not reachable", loggerURL)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s returned 404; expose the loggers endpoint (management.endpoints.web.exposure.include=loggers)", loggerURL)
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%s returned status %d", loggerURL, resp.StatusCode)
+	}
+
+	var current actuatorLogger
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&current); err != nil {
+		return nil, fmt.Errorf("unexpected response from %s: %v", loggerURL, err)
+	}
+	return &current, nil
+}
+
+// postActuatorLogger sets the configured level of a logger; an empty level
+// clears it
+func postActuatorLogger(service *models.Service, logger, level string) error {
+	loggerURL := actuatorLoggerURL(service, logger)
+
+	body := []byte(`{"configuredLevel":null}`)
+	if level != "" {
+		body, _ = json.Marshal(map[string]string{"configuredLevel": level})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), logLevelTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, loggerURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is not reachable", loggerURL)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s returned 404; expose the loggers endpoint (management.endpoints.web.exposure.include=loggers)", loggerURL)
+	case resp.StatusCode >= 300:
+		return fmt.Errorf("%s returned status %d", loggerURL, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package services - Rules that reclassify the level of service log lines
+package services
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Most rules a service may have
+const maxLogLevelRules = 50
+
+// Levels a rule may give a line
+var logLevelRuleLevels = map[string]bool{
+	"TRACE": true,
+	"DEBUG": true,
+	"INFO":  true,
+	"WARN":  true,
+	"ERROR": true,
+}
+
+// defaultLogLevelRules are tuned for Spring Boot output: the level keyword
+// finds nothing in the banner or in Hibernate's SQL, and a failed start
+// prints its reasons without one
+var defaultLogLevelRules = []models.LogLevelRule{
+	{Pattern: `^\s*(\.\s+____|/\\\\ /|\( \( \)|\\\\/\s|'\s+\|____|=+\|_\|=+|:: Spring Boot ::)`, Level: "DEBUG"},
+	{Pattern: `^Hibernate: `, Level: "DEBUG"},
+	{Pattern: `APPLICATION FAILED TO START|Application run failed|BUILD FAILURE`, Level: "ERROR"},
+	{Pattern: `^(Description|Action):\s*$`, Level: "ERROR"},
+	{Pattern: `^Exception in thread "`, Level: "ERROR"},
+}
+
+// compiledLogLevelRule is a rule ready to be matched
+type compiledLogLevelRule struct {
+	pattern *regexp.Regexp
+	level   string
+}
+
+// The defaults, compiled once
+var compiledDefaultLogLevelRules = mustCompileLogLevelRules(defaultLogLevelRules)
+
+// The compiled rules of each service with stored rules, by service UUID;
+// other services use the defaults
+var (
+	logLevelRules      = make(map[string][]compiledLogLevelRule)
+	logLevelRulesMutex sync.RWMutex
+)
+
+// compileLogLevelRules checks and compiles a list of rules
+func compileLogLevelRules(rules []models.LogLevelRule) ([]compiledLogLevelRule, error) {
+	if len(rules) > maxLogLevelRules {
+		return nil, fmt.Errorf("a service can have at most %d log level rules", maxLogLevelRules)
+	}
+
+	compiled := make([]compiledLogLevelRule, 0, len(rules))
+	for i, rule := range rules {
+		level := strings.ToUpper(strings.TrimSpace(rule.Level))
+		if !logLevelRuleLevels[level] {
+			return nil, fmt.Errorf("rule %d has unsupported level '%s'; use TRACE, DEBUG, INFO, WARN or ERROR", i+1, rule.Level)
+		}
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("rule %d has no pattern", i+1)
+		}
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d has an invalid pattern '%s': %v", i+1, rule.Pattern, err)
+		}
+		compiled = append(compiled, compiledLogLevelRule{pattern: pattern, level: level})
+	}
+	return compiled, nil
+}
+
+func mustCompileLogLevelRules(rules []models.LogLevelRule) []compiledLogLevelRule {
+	compiled, err := compileLogLevelRules(rules)
+	if err != nil {
+		panic(err)
+	}
+	return compiled
+}
+
+// setLogLevelRules replaces the rules applied to a service's log lines
+func setLogLevelRules(rules models.LogLevelRules) error {
+	compiled, err := compileLogLevelRules(rules.Rules)
+	if err != nil {
+		return err
+	}
+	if rules.UseDefaults {
+		compiled = append(compiled, compiledDefaultLogLevelRules...)
+	}
+
+	logLevelRulesMutex.Lock()
+	logLevelRules[rules.ServiceID] = compiled
+	logLevelRulesMutex.Unlock()
+	return nil
+}
+
+// forgetLogLevelRules drops the rules of a removed service
+func forgetLogLevelRules(serviceUUID string) {
+	logLevelRulesMutex.Lock()
+	delete(logLevelRules, serviceUUID)
+	logLevelRulesMutex.Unlock()
+}
+
+// classifyLogLine returns the level the first matching rule of a service
+// gives a line
+func classifyLogLine(serviceUUID, line string) (string, bool) {
+	logLevelRulesMutex.RLock()
+	rules, exists := logLevelRules[serviceUUID]
+	logLevelRulesMutex.RUnlock()
+	if !exists {
+		rules = compiledDefaultLogLevelRules
+	}
+
+	for _, rule := range rules {
+		if rule.pattern.MatchString(line) {
+			return rule.level, true
+		}
+	}
+	return "", false
+}
+
+// loadLogLevelRules applies the stored rules of every service
+func (sm *Manager) loadLogLevelRules() error {
+	ruleSets, err := sm.db.GetLogLevelRules()
+	if err != nil {
+		return err
+	}
+
+	for _, rules := range ruleSets {
+		if err := setLogLevelRules(rules); err != nil {
+			log.Printf("[WARN] Could not apply log level rules of service UUID %s: %v", rules.ServiceID, err)
+		}
+	}
+	return nil
+}
+
+// GetLogLevelRules returns the log level rules of a service, along with the
+// defaults
+func (sm *Manager) GetLogLevelRules(serviceUUID string) (*models.LogLevelRules, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	ruleSets, err := sm.db.GetLogLevelRules()
+	if err != nil {
+		return nil, err
+	}
+
+	rules := models.LogLevelRules{
+		ServiceID:   serviceUUID,
+		UseDefaults: true,
+		Rules:       []models.LogLevelRule{},
+	}
+	for _, stored := range ruleSets {
+		if stored.ServiceID == serviceUUID {
+			rules = stored
+			break
+		}
+	}
+	rules.Defaults = defaultLogLevelRules
+	return &rules, nil
+}
+
+// SetLogLevelRules validates and saves a service's log level rules, which
+// apply to the lines it logs from then on
+func (sm *Manager) SetLogLevelRules(rules models.LogLevelRules) error {
+	if _, exists := sm.GetServiceByUUID(rules.ServiceID); !exists {
+		return fmt.Errorf("service UUID %s not found", rules.ServiceID)
+	}
+
+	if rules.Rules == nil {
+		rules.Rules = []models.LogLevelRule{}
+	}
+	for i := range rules.Rules {
+		rules.Rules[i].Level = strings.ToUpper(strings.TrimSpace(rules.Rules[i].Level))
+	}
+	if _, err := compileLogLevelRules(rules.Rules); err != nil {
+		return err
+	}
+
+	if err := sm.db.SaveLogLevelRules(rules); err != nil {
+		return err
+	}
+	return setLogLevelRules(rules)
+}
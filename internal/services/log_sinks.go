@@ -0,0 +1,465 @@
+// Package services - External log sinks (Loki, Elasticsearch)
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	defaultLogSinkBatchSize     = 500
+	defaultLogSinkFlushInterval = 5 * time.Second
+	defaultLogSinkBufferSize    = 10000
+	defaultElasticsearchIndex   = "vertex-logs"
+	logSinkMaxAttempts          = 3
+	logSinkRequestTimeout       = 10 * time.Second
+)
+
+// LogRecord is a captured log line together with the service it came from
+type LogRecord struct {
+	ServiceID   string
+	ServiceName string
+	ProfileID   string
+	Entry       models.LogEntry
+}
+
+// LogSink delivers batches of log records to an external store
+type LogSink interface {
+	Ship(ctx context.Context, batch []LogRecord) error
+}
+
+// newLogSink builds the sink for a profile's configuration
+func newLogSink(config models.LogSinkConfig) (LogSink, error) {
+	client := &http.Client{Timeout: logSinkRequestTimeout}
+	switch config.Type {
+	case models.LogSinkLoki:
+		return &lokiSink{config: config, client: client}, nil
+	case models.LogSinkElasticsearch:
+		return &elasticsearchSink{config: config, client: client}, nil
+	}
+	return nil, fmt.Errorf("unsupported log sink type '%s'", config.Type)
+}
+
+// lokiSink pushes streams to Loki's HTTP push API, one stream per service and level
+type lokiSink struct {
+	config models.LogSinkConfig
+	client *http.Client
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (s *lokiSink) Ship(ctx context.Context, batch []LogRecord) error {
+	streams := make(map[string]*lokiStream)
+	var order []string
+	for _, record := range batch {
+		key := record.ServiceName + "\x00" + record.Entry.Level
+		stream, exists := streams[key]
+		if !exists {
+			labels := map[string]string{
+				"job":     "vertex",
+				"service": record.ServiceName,
+				"profile": record.ProfileID,
+				"level":   strings.ToLower(record.Entry.Level),
+			}
+			for name, value := range s.config.Labels {
+				labels[name] = value
+			}
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			order = append(order, key)
+		}
+		stream.Values = append(stream.Values, [2]string{
+			strconv.FormatInt(logRecordTime(record).UnixNano(), 10),
+			record.Entry.Message,
+		})
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode Loki payload: %w", err)
+	}
+
+	endpoint := strings.TrimRight(s.config.URL, "/") + "/loki/api/v1/push"
+	return postLogBatch(ctx, s.client, s.config, endpoint, "application/json", body)
+}
+
+// elasticsearchSink indexes records through the _bulk API
+type elasticsearchSink struct {
+	config models.LogSinkConfig
+	client *http.Client
+}
+
+func (s *elasticsearchSink) Ship(ctx context.Context, batch []LogRecord) error {
+	index := s.config.Index
+	if index == "" {
+		index = defaultElasticsearchIndex
+	}
+	action, err := json.Marshal(map[string]interface{}{"index": map[string]string{"_index": index}})
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	for _, record := range batch {
+		document := map[string]interface{}{
+			"@timestamp":  logRecordTime(record).Format(time.RFC3339Nano),
+			"level":       record.Entry.Level,
+			"message":     record.Entry.Message,
+			"serviceId":   record.ServiceID,
+			"serviceName": record.ServiceName,
+			"profileId":   record.ProfileID,
+		}
+		for name, value := range s.config.Labels {
+			if _, reserved := document[name]; !reserved {
+				document[name] = value
+			}
+		}
+		line, err := json.Marshal(document)
+		if err != nil {
+			return fmt.Errorf("failed to encode log document: %w", err)
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(line)
+		body.WriteByte('\n')
+	}
+
+	endpoint := strings.TrimRight(s.config.URL, "/") + "/_bulk"
+	return postLogBatch(ctx, s.client, s.config, endpoint, "application/x-ndjson", body.Bytes())
+}
+
+// postLogBatch sends a batch and treats any non-2xx response, or a bulk
+// response reporting item errors, as a failure
+func postLogBatch(ctx context.Context, client *http.Client, config models.LogSinkConfig, endpoint, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid log sink URL %s: %w", endpoint, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if config.Username != "" {
+		req.SetBasicAuth(config.Username, config.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s sink at %s is not reachable: %w", config.Type, config.URL, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s sink returned status %d: %s", config.Type, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if config.Type == models.LogSinkElasticsearch {
+		var bulk struct {
+			Errors bool `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &bulk) == nil && bulk.Errors {
+			return fmt.Errorf("elasticsearch rejected some documents in the batch")
+		}
+	}
+	return nil
+}
+
+func logRecordTime(record LogRecord) time.Time {
+	if parsed, err := time.Parse(time.RFC3339Nano, record.Entry.Timestamp); err == nil {
+		return parsed
+	}
+	return time.Now()
+}
+
+// logShipper batches records for one profile and ships them in the
+// background. When the sink falls behind and the buffer is full, new records
+// are dropped rather than blocking the service's log reader.
+type logShipper struct {
+	config models.LogSinkConfig
+	sink   LogSink
+	queue  chan LogRecord
+	stop   chan struct{}
+	done   chan struct{}
+
+	mutex  sync.Mutex
+	status models.LogSinkStatus
+}
+
+var (
+	logShippers      = make(map[string]*logShipper)
+	logShippersMutex sync.RWMutex
+)
+
+func newLogShipper(config models.LogSinkConfig) (*logShipper, error) {
+	sink, err := newLogSink(config)
+	if err != nil {
+		return nil, err
+	}
+
+	shipper := &logShipper{
+		config: config,
+		sink:   sink,
+		queue:  make(chan LogRecord, config.BufferSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		status: models.LogSinkStatus{ProfileID: config.ProfileID, Type: config.Type},
+	}
+	go shipper.run()
+	return shipper, nil
+}
+
+// enqueue never blocks; records that do not fit in the buffer are counted as dropped
+func (ls *logShipper) enqueue(record LogRecord) {
+	select {
+	case ls.queue <- record:
+	default:
+		ls.mutex.Lock()
+		ls.status.Dropped++
+		ls.mutex.Unlock()
+	}
+}
+
+func (ls *logShipper) run() {
+	defer close(ls.done)
+
+	ticker := time.NewTicker(time.Duration(ls.config.FlushIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	batch := make([]LogRecord, 0, ls.config.BatchSize)
+	for {
+		select {
+		case record := <-ls.queue:
+			batch = append(batch, record)
+			if len(batch) >= ls.config.BatchSize {
+				ls.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				ls.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ls.stop:
+			// Ship whatever is still buffered before shutting down
+			for {
+				select {
+				case record := <-ls.queue:
+					batch = append(batch, record)
+					if len(batch) >= ls.config.BatchSize {
+						ls.flush(batch)
+						batch = batch[:0]
+					}
+				default:
+					if len(batch) > 0 {
+						ls.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush ships a batch, retrying with backoff before giving up on it
+func (ls *logShipper) flush(batch []LogRecord) {
+	var err error
+	for attempt := 1; attempt <= logSinkMaxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), logSinkRequestTimeout)
+		err = ls.sink.Ship(ctx, batch)
+		cancel()
+		if err == nil {
+			break
+		}
+		if attempt < logSinkMaxAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+	if err != nil {
+		ls.status.Failed += int64(len(batch))
+		ls.status.LastError = err.Error()
+		log.Printf("[WARN] Failed to ship %d log entries for profile %s: %v", len(batch), ls.config.ProfileID, err)
+		return
+	}
+	ls.status.Shipped += int64(len(batch))
+	ls.status.LastShipped = time.Now()
+}
+
+// close stops the shipper after flushing buffered records
+func (ls *logShipper) close() {
+	close(ls.stop)
+	<-ls.done
+}
+
+func (ls *logShipper) snapshot() models.LogSinkStatus {
+	ls.mutex.Lock()
+	defer ls.mutex.Unlock()
+	status := ls.status
+	status.Queued = len(ls.queue)
+	return status
+}
+
+// loadLogSinks starts a shipper for every enabled profile log sink
+func (sm *Manager) loadLogSinks() error {
+	configs, err := sm.db.GetLogSinkConfigs()
+	if err != nil {
+		return err
+	}
+
+	for _, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		if err := startLogShipper(config); err != nil {
+			log.Printf("[WARN] Could not start %s log sink for profile %s: %v", config.Type, config.ProfileID, err)
+		}
+	}
+	return nil
+}
+
+// startLogShipper replaces the running shipper of a profile
+func startLogShipper(config models.LogSinkConfig) error {
+	shipper, err := newLogShipper(config)
+	if err != nil {
+		return err
+	}
+
+	logShippersMutex.Lock()
+	previous := logShippers[config.ProfileID]
+	logShippers[config.ProfileID] = shipper
+	logShippersMutex.Unlock()
+
+	if previous != nil {
+		previous.close()
+	}
+	log.Printf("[INFO] Shipping logs for profile %s to %s at %s", config.ProfileID, config.Type, config.URL)
+	return nil
+}
+
+func stopLogShipper(profileID string) {
+	logShippersMutex.Lock()
+	shipper := logShippers[profileID]
+	delete(logShippers, profileID)
+	logShippersMutex.Unlock()
+
+	if shipper != nil {
+		shipper.close()
+	}
+}
+
+// GetLogSinkConfig returns the log sink of a profile, or nil if none is configured
+func (sm *Manager) GetLogSinkConfig(profileID string) (*models.LogSinkConfig, error) {
+	configs, err := sm.db.GetLogSinkConfigs()
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		if config.ProfileID == profileID {
+			return &config, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetLogSinkConfig validates and saves a profile's log sink and restarts its shipper
+func (sm *Manager) SetLogSinkConfig(config models.LogSinkConfig) error {
+	config.URL = strings.TrimSpace(config.URL)
+	if parsed, err := url.Parse(config.URL); err != nil || parsed.Host == "" ||
+		(parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("invalid log sink URL '%s'", config.URL)
+	}
+	if config.Type != models.LogSinkLoki && config.Type != models.LogSinkElasticsearch {
+		return fmt.Errorf("invalid log sink type '%s'", config.Type)
+	}
+	if config.Type == models.LogSinkElasticsearch && config.Index == "" {
+		config.Index = defaultElasticsearchIndex
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultLogSinkBatchSize
+	}
+	if config.FlushIntervalSeconds <= 0 {
+		config.FlushIntervalSeconds = int(defaultLogSinkFlushInterval / time.Second)
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultLogSinkBufferSize
+	}
+	if config.BufferSize < config.BatchSize {
+		config.BufferSize = config.BatchSize
+	}
+
+	if err := sm.db.SaveLogSinkConfig(config); err != nil {
+		return err
+	}
+
+	if !config.Enabled {
+		stopLogShipper(config.ProfileID)
+		return nil
+	}
+	return startLogShipper(config)
+}
+
+// DeleteLogSinkConfig stops shipping a profile's logs and removes its sink
+func (sm *Manager) DeleteLogSinkConfig(profileID string) error {
+	stopLogShipper(profileID)
+	return sm.db.DeleteLogSinkConfig(profileID)
+}
+
+// GetLogSinkStatus returns delivery counters for a profile's running log sink
+func (sm *Manager) GetLogSinkStatus(profileID string) (*models.LogSinkStatus, bool) {
+	logShippersMutex.RLock()
+	shipper, exists := logShippers[profileID]
+	logShippersMutex.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+	status := shipper.snapshot()
+	return &status, true
+}
+
+// storeLogEntry persists a captured log line to the service's log file, if
+// enabled, and to SQLite and/or the external sink of the profile the service
+// runs under
+func (sm *Manager) storeLogEntry(service *models.Service, logEntry models.LogEntry) {
+	writeLogFile(service.ID, logEntry)
+
+	logShippersMutex.RLock()
+	shipper := logShippers[service.ProfileID]
+	logShippersMutex.RUnlock()
+
+	if shipper != nil {
+		shipper.enqueue(LogRecord{
+			ServiceID:   service.ID,
+			ServiceName: service.Name,
+			ProfileID:   service.ProfileID,
+			Entry:       logEntry,
+		})
+		if shipper.config.DisableSQLite {
+			return
+		}
+	}
+
+	sm.db.QueueLogEntry(service.ID, logEntry)
+}
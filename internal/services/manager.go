@@ -1,12 +1,14 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,35 +24,89 @@ type Manager struct {
 	activeConfigID    string
 	db                *database.Database
 	mutex             sync.RWMutex
-	clients           map[*websocket.Conn]bool
+	clients           map[*websocket.Conn]*wsClient
 	clientsMutex      sync.RWMutex
+	stream            wsStream // Guarded by clientsMutex
+	broadcaster       wsBroadcaster
 	dependencyManager *DependencyManager
 	Id                int64
+
+	// ctx is cancelled by GracefulShutdown and bounds every lifecycle
+	// operation and background routine
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	actors      map[string]*serviceActor
+	actorsMutex sync.Mutex
+	actorsWG    sync.WaitGroup
+
+	// replicas is the copy of the services that polling endpoints read
+	// without contending for locks with lifecycle operations
+	replicas serviceReplicaSet
+
+	// startupReport is the latest profile start, see GetStartupReport
+	startupReport      *models.StartupReport
+	startupReportMutex sync.Mutex
 }
 
 type WebSocketMessage struct {
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload"`
+	Seq     uint64      `json:"seq,omitempty"` // Position in the broadcast stream, for resuming after a reconnect
 }
 
 func NewManager(config models.Config, db *database.Database) (*Manager, error) {
+	ctx, cancel := context.WithCancel(context.Background())
 	sm := &Manager{
 		config:         config,
 		services:       make(map[string]*models.Service),
 		configurations: make(map[string]*models.Configuration),
 		activeConfigID: "default",
 		db:             db,
-		clients:        make(map[*websocket.Conn]bool),
+		clients:        make(map[*websocket.Conn]*wsClient),
+		stream:         wsStream{id: newStreamID()},
+		ctx:            ctx,
+		cancel:         cancel,
+		actors:         make(map[string]*serviceActor),
 	}
 
 	// Initialize dependency manager
 	sm.dependencyManager = NewDependencyManager(sm)
 
+	// Loading clears the PIDs a crash left behind; keep them to find the
+	// processes that outlived it
+	previousPIDs, err := db.GetRecordedServicePIDs()
+	if err != nil {
+		log.Printf("Warning: Could not read previous service PIDs: %v", err)
+	}
+
 	// Load or create services
 	if err := sm.loadServices(config); err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to load services: %w", err)
 	}
 
+	// Attach service tags
+	if err := sm.loadServiceTags(); err != nil {
+		log.Printf("Warning: Could not load service tags: %v", err)
+	}
+
+	if err := sm.loadLogSinks(); err != nil {
+		log.Printf("Warning: Could not load log sinks: %v", err)
+	}
+
+	if err := sm.loadLogFiles(); err != nil {
+		log.Printf("Warning: Could not open service log files: %v", err)
+	}
+
+	if err := sm.loadLogLevelRules(); err != nil {
+		log.Printf("Warning: Could not load log level rules: %v", err)
+	}
+
+	if err := sm.loadLogIngestion(); err != nil {
+		log.Printf("Warning: Could not load log ingestion settings: %v", err)
+	}
+
 	// Load global configuration from database (override defaults)
 	if err := sm.loadGlobalConfigFromDB(); err != nil {
 		log.Printf("Warning: Could not load global config from database: %v", err)
@@ -58,6 +114,7 @@ func NewManager(config models.Config, db *database.Database) (*Manager, error) {
 
 	// Load configurations from database
 	if err := sm.loadConfigurations(); err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to load configurations: %w", err)
 	}
 
@@ -76,21 +133,63 @@ func NewManager(config models.Config, db *database.Database) (*Manager, error) {
 		log.Printf("Warning: Dependency validation failed: %v", err)
 	}
 
+	// Test runs cannot survive a restart of their process
+	if err := sm.db.FailInterruptedTestRuns(); err != nil {
+		log.Printf("Warning: Could not close interrupted test runs: %v", err)
+	}
+	if err := sm.db.FailInterruptedBuilds(); err != nil {
+		log.Printf("Warning: Could not close interrupted builds: %v", err)
+	}
+	if err := sm.db.CloseInterruptedChaosFaults(); err != nil {
+		log.Printf("Warning: Could not close interrupted chaos faults: %v", err)
+	}
+	if err := sm.db.FailInterruptedDependencyScans(); err != nil {
+		log.Printf("Warning: Could not close interrupted dependency scans: %v", err)
+	}
+	if err := sm.db.FailInterruptedMigrationRuns(); err != nil {
+		log.Printf("Warning: Could not close interrupted migration runs: %v", err)
+	}
+
+	// Adopt or list the service processes that survived a crash of Vertex
+	sm.reapOrphanedProcesses(previousPIDs)
+
+	// Publish the services for lock-free reads and keep them in step
+	sm.syncServiceReplica()
+	go sm.startServiceReplicaSync(ctx)
+
 	// Start health check routine
-	go sm.healthCheckRoutine()
+	go sm.healthCheckRoutine(ctx)
 
 	// Start resource metrics collection
-	go sm.startMetricsCollection()
+	go sm.startMetricsCollection(ctx)
 
 	// Start periodic log cleanup (daily)
-	go sm.startLogCleanupRoutine()
+	go sm.startLogCleanupRoutine(ctx)
+
+	// Start idle service auto-suspend monitor
+	go sm.startIdleMonitor(ctx)
+
+	// Start hourly email notification digests
+	go sm.startNotificationDigest(ctx)
+
+	// Start synthetic request probes
+	go sm.startProbeMonitor(ctx)
+
+	// Start daily database backups
+	go sm.startBackupRoutine(ctx)
+
+	// Start scheduled database maintenance
+	go sm.startMaintenanceRoutine(ctx)
+
+	// Re-render vertex.conf when its template is edited
+	go sm.startNginxTemplateWatcher(ctx)
 
 	return sm, nil
 }
 
 func (sm *Manager) AddWebSocketClient(conn *websocket.Conn) {
 	sm.clientsMutex.Lock()
-	sm.clients[conn] = true
+	sm.clients[conn] = &wsClient{}
 	sm.clientsMutex.Unlock()
 }
 
@@ -100,12 +199,12 @@ func (sm *Manager) RemoveWebSocketClient(conn *websocket.Conn) {
 	sm.clientsMutex.Unlock()
 }
 
-func (sm *Manager) GetServices() []models.Service {
+func (sm *Manager) GetServices() []*models.Service {
 	sm.mutex.RLock()
-	services := make([]models.Service, 0, len(sm.services))
+	services := make([]*models.Service, 0, len(sm.services))
 	for _, service := range sm.services {
 		service.Mutex.RLock()
-		services = append(services, *service)
+		services = append(services, service.Clone())
 		service.Mutex.RUnlock()
 	}
 	sm.mutex.RUnlock()
@@ -176,6 +275,30 @@ func (sm *Manager) GetServiceByUUID(uuid string) (*models.Service, bool) {
 	return service, exists
 }
 
+// GetServiceByName looks a service up by name (case-insensitive). Names are only
+// unique within a profile, so a running match is preferred over a stopped one.
+func (sm *Manager) GetServiceByName(name string) (*models.Service, bool) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	var match *models.Service
+	for _, service := range sm.services {
+		if !strings.EqualFold(service.Name, name) {
+			continue
+		}
+		service.Mutex.RLock()
+		running := service.Status == "running"
+		service.Mutex.RUnlock()
+		if running {
+			return service, true
+		}
+		if match == nil {
+			match = service
+		}
+	}
+	return match, match != nil
+}
+
 func (sm *Manager) GetDatabase() *database.Database {
 	return sm.db
 }
@@ -194,70 +317,26 @@ func (sm *Manager) GetConfig() models.Config {
 	return sm.config
 }
 
-func (sm *Manager) broadcastUpdate(service *models.Service) {
-	sm.clientsMutex.Lock()
-	defer sm.clientsMutex.Unlock()
-
-	// Create a list of clients to remove (to avoid concurrent map modification)
-	var clientsToRemove []*websocket.Conn
-
-	for client := range sm.clients {
-		if err := client.WriteJSON(WebSocketMessage{Type: "service_update", Payload: service}); err != nil {
-			// Mark client for removal
-			clientsToRemove = append(clientsToRemove, client)
-		}
-	}
-
-	// Remove failed clients
-	for _, client := range clientsToRemove {
-		delete(sm.clients, client)
-		client.Close()
-	}
-}
-
-func (sm *Manager) broadcastLogEntry(serviceUUID string, logEntry models.LogEntry) {
-	sm.clientsMutex.Lock()
-	defer sm.clientsMutex.Unlock()
-
-	_, exists := sm.services[serviceUUID]
-	if !exists {
-		log.Printf("[WARN] Service UUID %s not found for log broadcast", serviceUUID)
-		return
-	}
-
-	message := WebSocketMessage{
-		Type: "log_entry",
-		Payload: struct {
-			ServiceUUID string          `json:"serviceUUID"`
-			LogEntry    models.LogEntry `json:"logEntry"`
-		}{
-			ServiceUUID: serviceUUID,
-			LogEntry:    logEntry,
-		},
-	}
+func (sm *Manager) GracefulShutdown() {
+	log.Printf("[INFO] %s - Stopping all running services...", time.Now().Format("2006-01-02 15:04:05"))
 
-	var clientsToRemove []*websocket.Conn
-	for client := range sm.clients {
-		if err := client.WriteJSON(message); err != nil {
-			clientsToRemove = append(clientsToRemove, client)
-		}
-	}
+	// Cancel in-flight starts, health checks and background routines, and
+	// refuse new lifecycle operations. Holding actorsMutex keeps new actors
+	// from being registered while the wait group is waited on.
+	sm.actorsMutex.Lock()
+	sm.cancel()
+	sm.actorsMutex.Unlock()
 
-	for _, client := range clientsToRemove {
-		delete(sm.clients, client)
-		client.Close()
+	if !sm.waitForServiceActors(10 * time.Second) {
+		log.Printf("[WARN] %s - Some service operations did not finish in time, stopping anyway", time.Now().Format("2006-01-02 15:04:05"))
 	}
-}
-
-func (sm *Manager) GracefulShutdown() {
-	log.Printf("[INFO] %s - Stopping all running services...", time.Now().Format("2006-01-02 15:04:05"))
 
 	// Get all running services
 	sm.mutex.RLock()
 	runningServices := make([]*models.Service, 0)
 	for _, service := range sm.services {
 		service.Mutex.RLock()
-		if service.Status == "running" {
+		if service.Status == "running" || service.Status == StatusPaused {
 			runningServices = append(runningServices, service)
 		}
 		service.Mutex.RUnlock()
@@ -274,10 +353,10 @@ func (sm *Manager) GracefulShutdown() {
 		return runningServices[i].Order > runningServices[j].Order
 	})
 
-	// Stop each service
+	// Stop each service directly; the actors have exited by now
 	for _, service := range runningServices {
 		log.Printf("[INFO] %s - Stopping service UUID: %s", time.Now().Format("2006-01-02 15:04:05"), service.ID)
-		if err := sm.StopService(service.ID); err != nil {
+		if err := sm.stopService(service); err != nil {
 			log.Printf("Failed to stop service UUID %s: %v", service.ID, err)
 		} else {
 			log.Printf("[INFO] %s - Successfully stopped service UUID: %s", time.Now().Format("2006-01-02 15:04:05"), service.ID)
@@ -423,17 +502,56 @@ func (sm *Manager) UpdateService(serviceConfig *models.ServiceConfigRequest) err
 		return fmt.Errorf("service UUID %s not found", serviceConfig.ID)
 	}
 
-	// Check for directory conflicts if directory is being changed
-	if service.Dir != serviceConfig.Dir {
-		if err := sm.ValidateServiceUniqueness(serviceConfig.ID, serviceConfig.Dir); err != nil {
+	// Check for directory conflicts if directory is being changed;
+	// infrastructure keeps its data in the Vertex data directory instead
+	if (service.Dir != serviceConfig.Dir || service.WorkingDir != serviceConfig.WorkingDir) && serviceConfig.InfraType == "" {
+		if err := sm.ValidateServiceUniqueness(serviceConfig.ID, serviceConfig.Dir, serviceConfig.WorkingDir); err != nil {
+			return err
+		}
+	}
+
+	// Check for port conflicts if the port is being changed
+	if service.Port != serviceConfig.Port {
+		if err := sm.validateServicePort(serviceConfig.ID, serviceConfig.Port); err != nil {
 			return err
 		}
 	}
 
+	if serviceConfig.HealthInterval < 0 {
+		return fmt.Errorf("health interval cannot be negative")
+	}
+
+	if err := sm.validateJVMPresetReference(serviceConfig.JavaOptsPreset); err != nil {
+		return err
+	}
+
+	if err := validateServiceOwner(&serviceConfig.Owner); err != nil {
+		return err
+	}
+
+	if err := validateRunAsUser(&serviceConfig.RunAsUser); err != nil {
+		return err
+	}
+
+	if err := validateExecutionMode(&serviceConfig.ExecutionMode); err != nil {
+		return err
+	}
+
+	if err := validateCommandOverrides(&serviceConfig.WorkingDir, &serviceConfig.StartCommand, &serviceConfig.BuildCommand); err != nil {
+		return err
+	}
+
+	if err := validateInfrastructure(&serviceConfig.InfraType, &serviceConfig.InfraRuntime); err != nil {
+		return err
+	}
+
+	changes := describeServiceConfigChanges(service, serviceConfig)
+
 	// Update service fields
 	service.Name = serviceConfig.Name
 	service.Dir = serviceConfig.Dir
 	service.JavaOpts = serviceConfig.JavaOpts
+	service.JavaOptsPreset = serviceConfig.JavaOptsPreset
 	service.HealthURL = serviceConfig.HealthURL
 	service.Port = serviceConfig.Port
 	service.Order = serviceConfig.Order
@@ -441,6 +559,20 @@ func (sm *Manager) UpdateService(serviceConfig *models.ServiceConfigRequest) err
 	service.IsEnabled = serviceConfig.IsEnabled
 	service.BuildSystem = serviceConfig.BuildSystem
 	service.VerboseLogging = serviceConfig.VerboseLogging
+	service.IdleMinutes = serviceConfig.IdleMinutes
+	service.HealthInterval = serviceConfig.HealthInterval
+	service.SkipDiscovery = serviceConfig.SkipDiscovery
+	service.Owner = serviceConfig.Owner
+	service.RunAsUser = serviceConfig.RunAsUser
+	service.ExecutionMode = serviceConfig.ExecutionMode
+	service.WorkingDir = serviceConfig.WorkingDir
+	service.StartCommand = serviceConfig.StartCommand
+	service.BuildCommand = serviceConfig.BuildCommand
+	service.AutoMigrate = serviceConfig.AutoMigrate
+	service.Interactive = serviceConfig.Interactive
+	service.ReloadOnSIGHUP = serviceConfig.ReloadOnSIGHUP
+	service.InfraType = serviceConfig.InfraType
+	service.InfraRuntime = serviceConfig.InfraRuntime
 	service.EnvVars = serviceConfig.EnvVars
 
 	// Save to database
@@ -448,6 +580,10 @@ func (sm *Manager) UpdateService(serviceConfig *models.ServiceConfigRequest) err
 		return fmt.Errorf("failed to update service in database: %w", err)
 	}
 
+	if changes != "" {
+		sm.recordServiceEvent(service, models.EventConfigEdited, changes)
+	}
+
 	// Broadcast update
 	sm.broadcastUpdate(service)
 
@@ -498,11 +634,6 @@ func (sm *Manager) GetSystemResourceSummary() map[string]interface{} {
 	return sm.getSystemResourceSummary()
 }
 
-// CleanupPort cleans up processes using the specified port
-func (sm *Manager) CleanupPort(port int) *PortCleanupResult {
-	return KillProcessesOnPort(port)
-}
-
 // ValidateServiceNameUniquenessInProfile checks if a service name is unique within the profiles it belongs to
 // This replaces the global service name uniqueness validation with profile-scoped validation
 func (sm *Manager) ValidateServiceNameUniquenessInProfile(serviceUUID, serviceName string) error {
@@ -552,9 +683,10 @@ func (sm *Manager) ValidateServiceNameUniquenessInProfile(serviceUUID, serviceNa
 }
 
 // ValidateServiceUniqueness checks if a service would conflict with existing services
-// based on the combination of profile root directory and service directory
+// based on the combination of profile root directory, service directory and
+// working directory, so modules of one repository can each be a service
 // Note: This method assumes the caller already holds the appropriate mutex lock
-func (sm *Manager) ValidateServiceUniqueness(serviceUUID, serviceDir string) error {
+func (sm *Manager) ValidateServiceUniqueness(serviceUUID, serviceDir, workingDir string) error {
 	// Get the default projects directory (global)
 	globalProjectsDir := sm.config.ProjectsDir
 
@@ -568,13 +700,13 @@ func (sm *Manager) ValidateServiceUniqueness(serviceUUID, serviceDir string) err
 	}
 
 	// Calculate the proposed service path using global projects directory
-	proposedPath := filepath.Join(globalProjectsDir, serviceDir)
+	proposedPath := filepath.Join(globalProjectsDir, serviceDir, filepath.FromSlash(workingDir))
 	proposedPath = filepath.Clean(proposedPath)
 
 	// Check against all existing services (using direct map access to avoid mutex deadlock)
 	for _, existing := range sm.services {
-		// Skip self when updating
-		if existing.ID == serviceUUID {
+		// Skip self when updating, and infrastructure, which has no directory
+		if existing.ID == serviceUUID || existing.InfraType != "" {
 			continue
 		}
 
@@ -585,7 +717,7 @@ func (sm *Manager) ValidateServiceUniqueness(serviceUUID, serviceDir string) err
 		}
 
 		// Calculate existing service path
-		existingPath := filepath.Join(existingProjectsDir, existing.Dir)
+		existingPath := filepath.Join(existingProjectsDir, existing.Dir, filepath.FromSlash(existing.WorkingDir))
 		existingPath = filepath.Clean(existingPath)
 
 		// Check if paths would conflict
@@ -620,12 +752,12 @@ func (sm *Manager) ValidateServiceUniqueness(serviceUUID, serviceDir string) err
 
 				// Find the actual service details
 				existingService, exists := sm.services[profileServiceUUID]
-				if !exists {
+				if !exists || existingService.InfraType != "" {
 					continue
 				}
 
 				// Calculate profile service path
-				profileServicePath := filepath.Join(profile.ProjectsDir, existingService.Dir)
+				profileServicePath := filepath.Join(profile.ProjectsDir, existingService.Dir, filepath.FromSlash(existingService.WorkingDir))
 				profileServicePath = filepath.Clean(profileServicePath)
 
 				if proposedPath == profileServicePath {
@@ -658,8 +790,33 @@ func (sm *Manager) getServiceProjectsDirectory(serviceUUID string) string {
 	return projectsDir
 }
 
-// AddService adds a new service to the manager
+// getServiceProfileID returns the profile a service runs under, using the same
+// precedence as getServiceProjectsDirectory (active, then default, then newest)
+func (sm *Manager) getServiceProfileID(serviceUUID string) string {
+	query := `SELECT id FROM service_profiles
+			  WHERE services_json LIKE ?
+			  ORDER BY is_active DESC, is_default DESC, created_at DESC
+			  LIMIT 1`
+
+	searchPattern := fmt.Sprintf("%%\"%s\"%%", serviceUUID)
+
+	var profileID string
+	if err := sm.db.QueryRow(query, searchPattern).Scan(&profileID); err != nil {
+		// Service is not part of any profile
+		return ""
+	}
+
+	return profileID
+}
+
+// AddService adds a new service to the manager. A service without a port
+// gets a free port of the default pool.
 func (sm *Manager) AddService(service *models.Service) error {
+	return sm.addService(service, defaultPortPool)
+}
+
+// addService adds a new service, assigning it a port of the pool if it has none
+func (sm *Manager) addService(service *models.Service, pool portPool) error {
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
@@ -669,31 +826,50 @@ func (sm *Manager) AddService(service *models.Service) error {
 	}
 
 	// Validate system-wide uniqueness based on directory path
-	if err := sm.ValidateServiceUniqueness(service.ID, service.Dir); err != nil {
+	if service.InfraType == "" {
+		if err := sm.ValidateServiceUniqueness(service.ID, service.Dir, service.WorkingDir); err != nil {
+			return err
+		}
+	}
+
+	if err := validateInfrastructure(&service.InfraType, &service.InfraRuntime); err != nil {
 		return err
 	}
 
-	// Initialize service fields if not set
-	if service.EnvVars == nil {
-		service.EnvVars = make(map[string]models.EnvVar)
+	if err := sm.assignServicePort(service, pool); err != nil {
+		return err
 	}
-	if service.Logs == nil {
-		service.Logs = []models.LogEntry{}
+	if err := sm.validateServicePort(service.ID, service.Port); err != nil {
+		return err
 	}
-	if service.Status == "" {
-		service.Status = "stopped"
+
+	if err := sm.validateJVMPresetReference(service.JavaOptsPreset); err != nil {
+		return err
 	}
-	if service.HealthStatus == "" {
-		service.HealthStatus = "unknown"
+
+	if err := validateServiceOwner(&service.Owner); err != nil {
+		return err
+	}
+
+	if err := validateRunAsUser(&service.RunAsUser); err != nil {
+		return err
+	}
+
+	if err := validateExecutionMode(&service.ExecutionMode); err != nil {
+		return err
+	}
+
+	if err := validateCommandOverrides(&service.WorkingDir, &service.StartCommand, &service.BuildCommand); err != nil {
+		return err
 	}
 
 	// Add service to memory
-	sm.services[service.ID] = service
+	sm.registerService(service)
 
 	// Save to database (insert or update)
 	if err := sm.upsertServiceInDB(service); err != nil {
 		// Remove from memory if database save fails
-		delete(sm.services, service.ID)
+		sm.unregisterService(service.ID)
 		return fmt.Errorf("failed to save service to database: %w", err)
 	}
 
@@ -712,18 +888,23 @@ func (sm *Manager) AddService(service *models.Service) error {
 	return nil
 }
 
-// DeleteService removes a service from the manager
-func (sm *Manager) deleteService(serviceUUID string) error {
+// DeleteService stops a service, deletes it from the database and drops
+// everything the manager keeps for it. It is the only way services are
+// removed.
+func (sm *Manager) DeleteService(serviceUUID string) error {
 	// First, check if service exists and get its status
 	sm.mutex.RLock()
 	service, exists := sm.services[serviceUUID]
-	isRunning := exists && service.Status == "running"
 	sm.mutex.RUnlock()
 
 	if !exists {
 		return fmt.Errorf("service UUID '%s' not found", serviceUUID)
 	}
 
+	service.Mutex.RLock()
+	isRunning := service.Status == "running" || service.Status == StatusPaused
+	service.Mutex.RUnlock()
+
 	// Stop the service if it's running (without holding the main lock)
 	if isRunning {
 		log.Printf("[INFO] Stopping service UUID %s before deletion", serviceUUID)
@@ -738,21 +919,16 @@ func (sm *Manager) deleteService(serviceUUID string) error {
 	defer sm.mutex.Unlock()
 
 	// Double-check the service still exists (in case it was deleted by another goroutine)
-	service, exists = sm.services[serviceUUID]
-	if !exists {
+	if _, exists = sm.services[serviceUUID]; !exists {
 		return fmt.Errorf("service UUID '%s' not found", serviceUUID)
 	}
 
-	// Remove from memory
-	delete(sm.services, serviceUUID)
-
-	// Remove from database
+	// Remove from database first, so a failure leaves the service untouched
 	if err := sm.db.DeleteService(serviceUUID); err != nil {
-		// Re-add to memory if database deletion fails
-		sm.services[serviceUUID] = service
 		return fmt.Errorf("failed to delete service from database: %w", err)
 	}
 
+	sm.unregisterService(serviceUUID)
 	log.Printf("[INFO] Successfully deleted service UUID: %s", serviceUUID)
 
 	// Normalize orders to ensure sequential ordering
@@ -765,6 +941,38 @@ func (sm *Manager) deleteService(serviceUUID string) error {
 	return nil
 }
 
+// registerService adds a service to memory, filling in the fields it is
+// missing. The caller holds sm.mutex.
+func (sm *Manager) registerService(service *models.Service) {
+	if service.EnvVars == nil {
+		service.EnvVars = make(map[string]models.EnvVar)
+	}
+	if service.Logs == nil {
+		service.Logs = []models.LogEntry{}
+	}
+	if service.Status == "" {
+		service.Status = "stopped"
+	}
+	if service.HealthStatus == "" {
+		service.HealthStatus = "unknown"
+	}
+	sm.services[service.ID] = service
+}
+
+// unregisterService drops a service from memory along with its actor, replica,
+// log files and the other state kept for it. The caller holds sm.mutex.
+func (sm *Manager) unregisterService(serviceUUID string) {
+	delete(sm.services, serviceUUID)
+	discardTrafficCapture(serviceUUID)
+	discardLogFiles(serviceUUID)
+	forgetLogLevelRules(serviceUUID)
+	forgetPushedHealth(serviceUUID)
+	stopLogIngestion(serviceUUID)
+	sm.removeServiceActor(serviceUUID)
+	sm.unpublishService(serviceUUID)
+	sm.forgetBroadcastState(serviceUUID)
+}
+
 // StartService starts a service by UUID
 func (sm *Manager) StartService(serviceUUID string) error {
 	sm.mutex.RLock()
@@ -786,7 +994,7 @@ func (sm *Manager) StartService(serviceUUID string) error {
 
 	log.Printf("[INFO] Starting service UUID: %s", serviceUUID)
 
-	return sm.startService(service)
+	return sm.startServiceOp(sm.ctx, service, sm.config.ProjectsDir)
 }
 
 // StopService stops a service by UUID
@@ -801,7 +1009,7 @@ func (sm *Manager) StopService(serviceUUID string) error {
 
 	log.Printf("[INFO] Stopping service UUID: %s", serviceUUID)
 
-	return sm.stopService(service)
+	return sm.stopServiceOp(sm.ctx, service)
 }
 
 // RestartService restarts a service by UUID
@@ -825,35 +1033,7 @@ func (sm *Manager) RestartService(serviceUUID string) error {
 
 	log.Printf("[INFO] Restarting service UUID: %s (port %d)", serviceUUID, service.Port)
 
-	// Stop the service first
-	if service.Status == "running" {
-		if err := sm.stopService(service); err != nil {
-			log.Printf("[WARN] Failed to stop service gracefully: %v", err)
-			// Continue anyway - we'll clean up the port
-		}
-		// Wait a moment for cleanup
-		time.Sleep(2 * time.Second)
-	}
-
-	// Clean up any processes still using the service's port
-	if service.Port > 0 {
-		log.Printf("[INFO] Cleaning up port %d before restarting service UUID %s", service.Port, serviceUUID)
-		if err := CleanupPortBeforeStart(service.Port); err != nil {
-			log.Printf("[WARN] Port cleanup failed: %v", err)
-			// Continue anyway - the port might be available by now
-		}
-	}
-
-	// Start the service
-	err := sm.startService(service)
-
-	// Record restart event if successful
-	if err == nil {
-		uptimeTracker := GetUptimeTracker()
-		uptimeTracker.RecordEvent(service.ID, "restart", "running")
-	}
-
-	return err
+	return sm.restartServiceOp(sm.ctx, service, sm.config.ProjectsDir)
 }
 
 // StartServiceWithProjectsDir starts a service using a specific projects directory
@@ -877,7 +1057,7 @@ func (sm *Manager) StartServiceWithProjectsDir(serviceUUID, projectsDir string)
 
 	log.Printf("[INFO] Starting service UUID %s from projects directory: %s", serviceUUID, projectsDir)
 
-	return sm.startServiceWithProjectsDir(service, projectsDir)
+	return sm.startServiceOp(sm.ctx, service, projectsDir)
 }
 
 // RestartServiceWithProjectsDir restarts a service using a specific projects directory
@@ -901,52 +1081,37 @@ func (sm *Manager) RestartServiceWithProjectsDir(serviceUUID, projectsDir string
 
 	log.Printf("[INFO] Restarting service UUID %s from projects directory: %s (port %d)", serviceUUID, projectsDir, service.Port)
 
-	// Stop the service first
-	if service.Status == "running" {
-		if err := sm.stopService(service); err != nil {
-			log.Printf("[WARN] Failed to stop service gracefully: %v", err)
-			// Continue anyway - we'll clean up the port
-		}
-		// Wait a moment for cleanup
-		time.Sleep(2 * time.Second)
-	}
-
-	// Clean up any processes still using the service's port
-	if service.Port > 0 {
-		log.Printf("[INFO] Cleaning up port %d before restarting service UUID %s", service.Port, serviceUUID)
-		if err := CleanupPortBeforeStart(service.Port); err != nil {
-			log.Printf("[WARN] Port cleanup failed: %v", err)
-			// Continue anyway - the port might be available by now
-		}
-	}
-
-	// Start the service with custom projects directory
-	return sm.startServiceWithProjectsDir(service, projectsDir)
+	return sm.restartServiceOp(sm.ctx, service, projectsDir)
 }
 
 // startLogCleanupRoutine starts a background routine that periodically cleans up old logs
-func (sm *Manager) startLogCleanupRoutine() {
+func (sm *Manager) startLogCleanupRoutine(ctx context.Context) {
 	// Run cleanup every 24 hours
 	ticker := time.NewTicker(24 * time.Hour)
 	defer ticker.Stop()
 
 	// Run initial cleanup after 1 hour of startup
 	initialDelay := time.NewTimer(1 * time.Hour)
+	defer initialDelay.Stop()
 
 	log.Printf("[INFO] Started periodic log cleanup routine (24-hour interval)")
 
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-initialDelay.C:
 			// Run initial cleanup
 			if err := sm.AutoCleanupLogs(); err != nil {
 				log.Printf("[ERROR] Initial log cleanup failed: %v", err)
 			}
+			sm.cleanupServiceEvents()
 		case <-ticker.C:
 			// Run periodic cleanup
 			if err := sm.AutoCleanupLogs(); err != nil {
 				log.Printf("[ERROR] Periodic log cleanup failed: %v", err)
 			}
+			sm.cleanupServiceEvents()
 		}
 	}
 }
@@ -1075,19 +1240,21 @@ func (sm *Manager) GetGitBranches(serviceUUID string) ([]string, error) {
 	return branches, nil
 }
 
-// SwitchGitBranch switches a service to a different git branch
-func (sm *Manager) SwitchGitBranch(serviceUUID, branch string) error {
+// SwitchGitBranch switches a service to a different git branch. With
+// autoStash, uncommitted changes are stashed instead of refusing the switch,
+// and changes stashed on the new branch are put back.
+func (sm *Manager) SwitchGitBranch(serviceUUID, branch string, autoStash bool) (*BranchSwitchStash, error) {
 	sm.mutex.RLock()
 	service, exists := sm.services[serviceUUID]
 	sm.mutex.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("service UUID %s not found", serviceUUID)
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
 	}
 
 	// Check if service is running
 	if service.Status == "running" {
-		return fmt.Errorf("cannot switch branches while service is running. Please stop the service first")
+		return nil, fmt.Errorf("cannot switch branches while service is running. Please stop the service first")
 	}
 
 	// Get the full service directory path
@@ -1099,8 +1266,15 @@ func (sm *Manager) SwitchGitBranch(serviceUUID, branch string) error {
 	fullPath := filepath.Join(projectsDir, service.Dir)
 
 	// Switch branch
-	if err := SwitchBranch(fullPath, branch); err != nil {
-		return err
+	var stash *BranchSwitchStash
+	var err error
+	if autoStash {
+		stash, err = SwitchBranchWithAutoStash(fullPath, branch)
+	} else {
+		err = SwitchBranch(fullPath, branch)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// Update the service's git branch info
@@ -1114,8 +1288,10 @@ func (sm *Manager) SwitchGitBranch(serviceUUID, branch string) error {
 		sm.broadcastUpdate(service)
 	}
 
+	sm.recordServiceEvent(service, models.EventBranchSwitched, fmt.Sprintf("Switched to branch %s%s", branch, describeStash(stash)))
+
 	log.Printf("[INFO] Successfully switched service %s (UUID: %s) to branch %s", service.Name, serviceUUID, branch)
-	return nil
+	return stash, nil
 }
 
 // UpdateServiceGitBranch updates the git branch information for a service
@@ -0,0 +1,726 @@
+// Package services - Markdown rendering for READMEs and notes
+package services
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The renderer covers the Markdown found in service READMEs: headings,
+// paragraphs, lists, block quotes, code, tables, links, images and emphasis.
+// It never passes raw HTML through; all text is escaped and only its own tags
+// are written, and links keep only http, https, mailto and relative URLs, so
+// the output is safe to embed in the dashboard.
+
+var (
+	mdHeadingRegex     = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdRuleRegex        = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdFenceRegex       = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*)$")
+	mdListItemRegex    = regexp.MustCompile(`^( {0,12})([-*+]|\d{1,9}[.)])(?:([ \t]+)(.*))?$`)
+	mdSetextRegex      = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdTableDelimRegex  = regexp.MustCompile(`^[ \t]*:?-+:?[ \t]*$`)
+	mdReferenceRegex   = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:[ \t]*<?([^\s>]+)>?(?:[ \t]+(?:"[^"]*"|'[^']*'|\([^)]*\)))?[ \t]*$`)
+	mdAutolinkRegex    = regexp.MustCompile(`^<((?:https?|mailto):[^\s<>]+)>`)
+	mdEmailLinkRegex   = regexp.MustCompile(`^<([^\s<>@]+@[^\s<>@]+\.[^\s<>@]+)>`)
+	mdBareURLRegex     = regexp.MustCompile(`^https?://[^\s<]+`)
+	mdLanguageRegex    = regexp.MustCompile(`^[A-Za-z0-9_+#.-]+$`)
+	mdTaskMarkerRegex  = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	mdWhitespaceRegex  = regexp.MustCompile(`\s+`)
+	mdURLTrailingChars = ".,:;!?'\"*_"
+)
+
+type markdownRenderer struct {
+	references map[string]string // Link reference definitions by lower-case label
+	inLink     bool              // Rendering a link's text, which cannot hold links
+}
+
+// renderMarkdown converts Markdown to sanitized HTML
+func renderMarkdown(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\t", "    ")
+
+	renderer := &markdownRenderer{references: make(map[string]string)}
+	var lines []string
+	inFence := false
+	for _, line := range strings.Split(source, "\n") {
+		if mdFenceRegex.MatchString(line) {
+			inFence = !inFence
+		}
+		if !inFence {
+			if match := mdReferenceRegex.FindStringSubmatch(line); match != nil {
+				label := strings.ToLower(mdWhitespaceRegex.ReplaceAllString(strings.TrimSpace(match[1]), " "))
+				if _, exists := renderer.references[label]; !exists {
+					renderer.references[label] = match[2]
+				}
+				continue
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	var out strings.Builder
+	renderer.blocks(&out, lines, false)
+	return out.String()
+}
+
+// renderPlainText shows text that is not Markdown as preformatted
+func renderPlainText(source string) string {
+	return "<pre>" + html.EscapeString(strings.ReplaceAll(source, "\r\n", "\n")) + "</pre>\n"
+}
+
+func mdIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func mdBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// mdStartsBlock reports whether a line interrupts a paragraph
+func mdStartsBlock(line string) bool {
+	if mdIndent(line) >= 4 {
+		return false
+	}
+	trimmed := strings.TrimSpace(line)
+	return mdHeadingRegex.MatchString(line) || mdRuleRegex.MatchString(line) ||
+		mdFenceRegex.MatchString(line) || strings.HasPrefix(trimmed, ">") || mdListItemRegex.MatchString(line)
+}
+
+// blocks renders block elements. In tight list items paragraphs are written
+// without <p>.
+func (mr *markdownRenderer) blocks(out *strings.Builder, lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case mdBlank(line):
+			i++
+		case mdIndent(line) >= 4:
+			i = mr.indentedCode(out, lines, i)
+		case mdFenceRegex.MatchString(line):
+			i = mr.fencedCode(out, lines, i)
+		case mdHeadingRegex.MatchString(line):
+			match := mdHeadingRegex.FindStringSubmatch(line)
+			level := strconv.Itoa(len(match[1]))
+			out.WriteString("<h" + level + ">" + mr.inline(match[2]) + "</h" + level + ">\n")
+			i++
+		case mdRuleRegex.MatchString(line):
+			out.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(strings.TrimSpace(line), ">"):
+			i = mr.blockquote(out, lines, i)
+		case mdListItemRegex.MatchString(line):
+			i = mr.list(out, lines, i)
+		case i+1 < len(lines) && strings.Contains(line, "|") && mr.tableDelimiter(lines[i+1], len(mdTableCells(line))):
+			i = mr.table(out, lines, i)
+		default:
+			i = mr.paragraph(out, lines, i, tight)
+		}
+	}
+}
+
+func (mr *markdownRenderer) indentedCode(out *strings.Builder, lines []string, i int) int {
+	var code []string
+	for ; i < len(lines) && (mdBlank(lines[i]) || mdIndent(lines[i]) >= 4); i++ {
+		if len(lines[i]) >= 4 {
+			code = append(code, lines[i][4:])
+		} else {
+			code = append(code, "")
+		}
+	}
+	for len(code) > 0 && mdBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+	out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "\n</code></pre>\n")
+	return i
+}
+
+func (mr *markdownRenderer) fencedCode(out *strings.Builder, lines []string, i int) int {
+	match := mdFenceRegex.FindStringSubmatch(lines[i])
+	indent, fence := len(match[1]), match[2]
+	language := strings.Fields(match[3])
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if mdIndent(lines[i]) < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		line := lines[i]
+		if strip := min(indent, mdIndent(line)); strip > 0 {
+			line = line[strip:]
+		}
+		code = append(code, line)
+	}
+
+	out.WriteString("<pre><code")
+	if len(language) > 0 && mdLanguageRegex.MatchString(language[0]) {
+		out.WriteString(` class="language-` + html.EscapeString(language[0]) + `"`)
+	}
+	out.WriteString(">")
+	if len(code) > 0 {
+		out.WriteString(html.EscapeString(strings.Join(code, "\n")) + "\n")
+	}
+	out.WriteString("</code></pre>\n")
+	return i
+}
+
+func (mr *markdownRenderer) blockquote(out *strings.Builder, lines []string, i int) int {
+	var quoted []string
+	for ; i < len(lines) && !mdBlank(lines[i]); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if !strings.HasPrefix(trimmed, ">") {
+			if mdStartsBlock(lines[i]) {
+				break
+			}
+			// Lazy continuation of a quoted paragraph
+			quoted = append(quoted, trimmed)
+			continue
+		}
+		trimmed = strings.TrimPrefix(trimmed, ">")
+		quoted = append(quoted, strings.TrimPrefix(trimmed, " "))
+	}
+	out.WriteString("<blockquote>\n")
+	mr.blocks(out, quoted, false)
+	out.WriteString("</blockquote>\n")
+	return i
+}
+
+// list renders a bullet or ordered list and the lists nested in its items
+func (mr *markdownRenderer) list(out *strings.Builder, lines []string, i int) int {
+	first := mdListItemRegex.FindStringSubmatch(lines[i])
+	ordered := !strings.ContainsAny(first[2][:1], "-*+")
+	delimiter := first[2][len(first[2])-1:]
+	markerIndent := len(first[1])
+
+	var items [][]string
+	loose, blankBefore := false, false
+	for i < len(lines) {
+		match := mdListItemRegex.FindStringSubmatch(lines[i])
+		if match == nil || len(match[1]) >= markerIndent+2 || len(match[1]) < markerIndent-1 ||
+			ordered == strings.ContainsAny(match[2][:1], "-*+") || match[2][len(match[2])-1:] != delimiter {
+			break
+		}
+		if blankBefore {
+			loose = true
+		}
+
+		contentIndent := len(match[1]) + len(match[2]) + 1
+		if spaces := len(match[3]); spaces > 0 && spaces <= 4 && match[4] != "" {
+			contentIndent = len(match[1]) + len(match[2]) + spaces
+		}
+		item := []string{match[4]}
+		sawBlank := false
+		j := i + 1
+		for ; j < len(lines); j++ {
+			line := lines[j]
+			if mdBlank(line) {
+				item = append(item, "")
+				sawBlank = true
+				continue
+			}
+			if mdIndent(line) >= contentIndent {
+				item = append(item, line[contentIndent:])
+				sawBlank = false
+				continue
+			}
+			if sawBlank || mdStartsBlock(line) {
+				break
+			}
+			// Lazy continuation of the item's paragraph
+			item = append(item, strings.TrimLeft(line, " "))
+		}
+
+		for len(item) > 0 && mdBlank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+		}
+		for _, line := range item {
+			if mdBlank(line) {
+				loose = true
+			}
+		}
+		items = append(items, item)
+
+		i = j
+		blankBefore = sawBlank
+	}
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	out.WriteString("<" + tag)
+	if ordered {
+		if start, err := strconv.Atoi(first[2][:len(first[2])-1]); err == nil && start != 1 {
+			out.WriteString(` start="` + strconv.Itoa(start) + `"`)
+		}
+	}
+	out.WriteString(">\n")
+	for _, item := range items {
+		out.WriteString("<li>")
+		if len(item) == 0 {
+			out.WriteString("</li>\n")
+			continue
+		}
+		if match := mdTaskMarkerRegex.FindStringSubmatch(item[0]); match != nil {
+			if match[1] == " " {
+				out.WriteString(`<input type="checkbox" disabled> `)
+			} else {
+				out.WriteString(`<input type="checkbox" checked disabled> `)
+			}
+			item[0] = item[0][len(match[0]):]
+		}
+		var content strings.Builder
+		mr.blocks(&content, item, !loose)
+		out.WriteString(strings.TrimSuffix(content.String(), "\n"))
+		out.WriteString("</li>\n")
+	}
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func (mr *markdownRenderer) paragraph(out *strings.Builder, lines []string, i int, tight bool) int {
+	var text []string
+	for ; i < len(lines) && !mdBlank(lines[i]); i++ {
+		if len(text) > 0 {
+			if match := mdSetextRegex.FindStringSubmatch(lines[i]); match != nil {
+				level := "2"
+				if strings.HasPrefix(match[1], "=") {
+					level = "1"
+				}
+				out.WriteString("<h" + level + ">" + mr.inline(strings.Join(text, "\n")) + "</h" + level + ">\n")
+				return i + 1
+			}
+			if mdStartsBlock(lines[i]) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(lines[i], " "))
+	}
+
+	content := mr.inline(strings.TrimRight(strings.Join(text, "\n"), " "))
+	if tight {
+		out.WriteString(content + "\n")
+	} else {
+		out.WriteString("<p>" + content + "</p>\n")
+	}
+	return i
+}
+
+// mdTableCells splits a table row on unescaped pipes
+func mdTableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func (mr *markdownRenderer) tableDelimiter(line string, columns int) bool {
+	if !strings.Contains(line, "-") {
+		return false
+	}
+	cells := mdTableCells(line)
+	if len(cells) != columns {
+		return false
+	}
+	for _, cell := range cells {
+		if !mdTableDelimRegex.MatchString(cell) {
+			return false
+		}
+	}
+	return true
+}
+
+func (mr *markdownRenderer) table(out *strings.Builder, lines []string, i int) int {
+	header := mdTableCells(lines[i])
+	var aligns []string
+	for _, cell := range mdTableCells(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "right")
+		case strings.HasPrefix(cell, ":"):
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	writeRow := func(cells []string, tag string) {
+		out.WriteString("<tr>")
+		for column := range header {
+			out.WriteString("<" + tag)
+			if aligns[column] != "" {
+				out.WriteString(` style="text-align: ` + aligns[column] + `"`)
+			}
+			out.WriteString(">")
+			if column < len(cells) {
+				out.WriteString(mr.inline(cells[column]))
+			}
+			out.WriteString("</" + tag + ">")
+		}
+		out.WriteString("</tr>\n")
+	}
+
+	out.WriteString("<table>\n<thead>\n")
+	writeRow(header, "th")
+	out.WriteString("</thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && !mdBlank(lines[i]) && strings.Contains(lines[i], "|") && !mdStartsBlock(lines[i]); i++ {
+		writeRow(mdTableCells(lines[i]), "td")
+	}
+	out.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// safeMarkdownURL returns a link target if its scheme is allowed
+func safeMarkdownURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return raw, true
+	}
+	return "", false
+}
+
+func mdWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
+
+func mdPunctuation(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// inline renders emphasis, code spans, links and images of a paragraph
+func (mr *markdownRenderer) inline(text string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && text[i+1] == '\n':
+			out.WriteString("<br>\n")
+			i += 2
+		case c == '\\' && i+1 < len(text) && mdPunctuation(text[i+1]):
+			out.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+		case c == '\n':
+			if strings.HasSuffix(text[:i], "  ") {
+				out.WriteString("<br>")
+			}
+			out.WriteString("\n")
+			i++
+		case c == '`':
+			i = mr.codeSpan(&out, text, i)
+		case c == '!' && i+1 < len(text) && text[i+1] == '[':
+			if next, ok := mr.link(&out, text, i+1, true); ok {
+				i = next
+			} else {
+				out.WriteString("!")
+				i++
+			}
+		case c == '[' && !mr.inLink:
+			if next, ok := mr.link(&out, text, i, false); ok {
+				i = next
+			} else {
+				out.WriteString("[")
+				i++
+			}
+		case c == '<' && !mr.inLink:
+			if match := mdAutolinkRegex.FindStringSubmatch(text[i:]); match != nil {
+				mr.anchor(&out, match[1], html.EscapeString(match[1]))
+				i += len(match[0])
+			} else if match := mdEmailLinkRegex.FindStringSubmatch(text[i:]); match != nil {
+				mr.anchor(&out, "mailto:"+match[1], html.EscapeString(match[1]))
+				i += len(match[0])
+			} else {
+				out.WriteString("&lt;")
+				i++
+			}
+		case c == 'h' && !mr.inLink && (i == 0 || !mdWordChar(text[i-1])) && mdBareURLRegex.MatchString(text[i:]):
+			link := strings.TrimRight(mdBareURLRegex.FindString(text[i:]), mdURLTrailingChars)
+			for strings.HasSuffix(link, ")") && strings.Count(link, ")") > strings.Count(link, "(") {
+				link = strings.TrimRight(link[:len(link)-1], mdURLTrailingChars)
+			}
+			mr.anchor(&out, link, html.EscapeString(link))
+			i += len(link)
+		case c == '*' || c == '_' || c == '~':
+			i = mr.emphasis(&out, text, i)
+		default:
+			start := i
+			for i < len(text) && strings.IndexByte("\\\n`![<h*_~", text[i]) < 0 {
+				i++
+			}
+			if i == start {
+				i++
+			}
+			out.WriteString(html.EscapeString(text[start:i]))
+		}
+	}
+	return out.String()
+}
+
+func (mr *markdownRenderer) codeSpan(out *strings.Builder, text string, i int) int {
+	run := 0
+	for i+run < len(text) && text[i+run] == '`' {
+		run++
+	}
+	fence := strings.Repeat("`", run)
+	for j := i + run; j < len(text); {
+		k := strings.Index(text[j:], fence)
+		if k < 0 {
+			break
+		}
+		end := j + k
+		if end+run < len(text) && text[end+run] == '`' {
+			// Part of a longer run of backticks
+			for j = end; j < len(text) && text[j] == '`'; j++ {
+			}
+			continue
+		}
+		code := strings.ReplaceAll(text[i+run:end], "\n", " ")
+		if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+			code = code[1 : len(code)-1]
+		}
+		out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+		return end + run
+	}
+	out.WriteString(fence)
+	return i + run
+}
+
+// link renders [text](url), [text][label] or [label] starting at the
+// bracket, as an image when asked. It reports false when there is no link.
+func (mr *markdownRenderer) link(out *strings.Builder, text string, i int, image bool) (int, bool) {
+	depth := 0
+	closing := -1
+	for j := i; j < len(text) && closing < 0; j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = j
+			}
+		}
+	}
+	if closing < 0 {
+		return i, false
+	}
+	label := text[i+1 : closing]
+	next := closing + 1
+
+	var target string
+	switch {
+	case next < len(text) && text[next] == '(':
+		end, destination, ok := mdLinkDestination(text, next)
+		if !ok {
+			return i, false
+		}
+		target, next = destination, end
+	case next < len(text) && text[next] == '[':
+		end := strings.IndexByte(text[next:], ']')
+		if end < 0 {
+			return i, false
+		}
+		reference := text[next+1 : next+end]
+		if reference == "" {
+			reference = label
+		}
+		destination, exists := mr.references[strings.ToLower(mdWhitespaceRegex.ReplaceAllString(strings.TrimSpace(reference), " "))]
+		if !exists {
+			return i, false
+		}
+		target, next = destination, next+end+1
+	default:
+		destination, exists := mr.references[strings.ToLower(mdWhitespaceRegex.ReplaceAllString(strings.TrimSpace(label), " "))]
+		if !exists {
+			return i, false
+		}
+		target = destination
+	}
+
+	if image {
+		alt := html.EscapeString(mdPlainText(label))
+		source, ok := safeMarkdownURL(target)
+		if ok && (strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")) {
+			out.WriteString(`<img src="` + html.EscapeString(source) + `" alt="` + alt + `">`)
+		} else {
+			// Images relative to the repository cannot be served from here
+			out.WriteString(alt)
+		}
+		return next, true
+	}
+	mr.inLink = true
+	content := mr.inline(label)
+	mr.inLink = false
+	mr.anchor(out, target, content)
+	return next, true
+}
+
+// mdLinkDestination parses (url "title") at an opening parenthesis and
+// returns the index after it and the url
+func mdLinkDestination(text string, i int) (int, string, bool) {
+	j := i + 1
+	for j < len(text) && (text[j] == ' ' || text[j] == '\n') {
+		j++
+	}
+
+	var destination string
+	if j < len(text) && text[j] == '<' {
+		end := strings.IndexByte(text[j:], '>')
+		if end < 0 {
+			return i, "", false
+		}
+		destination = text[j+1 : j+end]
+		j += end + 1
+	} else {
+		start, depth := j, 0
+		for ; j < len(text) && text[j] != ' ' && text[j] != '\n'; j++ {
+			if text[j] == '(' {
+				depth++
+			} else if text[j] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+		destination = text[start:j]
+	}
+
+	for j < len(text) && (text[j] == ' ' || text[j] == '\n') {
+		j++
+	}
+	if j < len(text) && (text[j] == '"' || text[j] == '\'') {
+		end := strings.IndexByte(text[j+1:], text[j])
+		if end < 0 {
+			return i, "", false
+		}
+		j += end + 2
+		for j < len(text) && (text[j] == ' ' || text[j] == '\n') {
+			j++
+		}
+	}
+	if j >= len(text) || text[j] != ')' {
+		return i, "", false
+	}
+	return j + 1, destination, true
+}
+
+// anchor writes a link, or only its content when the URL is not allowed
+func (mr *markdownRenderer) anchor(out *strings.Builder, target, content string) {
+	href, ok := safeMarkdownURL(target)
+	if !ok {
+		out.WriteString(content)
+		return
+	}
+	out.WriteString(`<a href="` + html.EscapeString(href) + `" rel="noopener noreferrer">` + content + `</a>`)
+}
+
+// emphasis renders *em*, **strong**, ***both*** and ~~strikethrough~~, or the
+// delimiters as text when they are not closed
+func (mr *markdownRenderer) emphasis(out *strings.Builder, text string, i int) int {
+	c := text[i]
+	run := 0
+	for i+run < len(text) && text[i+run] == c {
+		run++
+	}
+
+	opens := i+run < len(text) && text[i+run] != ' ' && text[i+run] != '\n'
+	if c == '_' && i > 0 && mdWordChar(text[i-1]) {
+		opens = false
+	}
+	if c == '~' && run != 2 || run > 3 {
+		opens = false
+	}
+	if opens {
+		if end := mdClosingDelimiter(text, i+run, c, run); end >= 0 {
+			inner := mr.inline(text[i+run : end])
+			switch {
+			case c == '~':
+				out.WriteString("<del>" + inner + "</del>")
+			case run == 1:
+				out.WriteString("<em>" + inner + "</em>")
+			case run == 2:
+				out.WriteString("<strong>" + inner + "</strong>")
+			default:
+				out.WriteString("<em><strong>" + inner + "</strong></em>")
+			}
+			return end + run
+		}
+	}
+
+	out.WriteString(html.EscapeString(text[i : i+run]))
+	return i + run
+}
+
+// mdClosingDelimiter finds a run of exactly run delimiters that can close
+// emphasis, skipping code spans
+func mdClosingDelimiter(text string, start int, c byte, run int) int {
+	for j := start; j < len(text); {
+		switch text[j] {
+		case '`':
+			ticks := 0
+			for j+ticks < len(text) && text[j+ticks] == '`' {
+				ticks++
+			}
+			if end := strings.Index(text[j+ticks:], strings.Repeat("`", ticks)); end >= 0 {
+				j += ticks + end + ticks
+			} else {
+				j += ticks
+			}
+			continue
+		case '\\':
+			j += 2
+			continue
+		case c:
+			length := 0
+			for j+length < len(text) && text[j+length] == c {
+				length++
+			}
+			closes := j > start && text[j-1] != ' ' && text[j-1] != '\n'
+			if c == '_' && j+length < len(text) && mdWordChar(text[j+length]) {
+				closes = false
+			}
+			if closes && length == run {
+				return j
+			}
+			j += length
+			continue
+		}
+		j++
+	}
+	return -1
+}
+
+// mdPlainText strips the Markdown from a label for alt text
+func mdPlainText(text string) string {
+	return strings.NewReplacer("*", "", "_", "", "`", "", "[", "", "]", "").Replace(text)
+}
@@ -0,0 +1,306 @@
+// Package services - Per-profile memory budget and admission control
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// What happens when starting a service would exceed its profile's memory budget
+const (
+	MemoryBudgetWarn    = "warn"    // Start anyway and log a warning
+	MemoryBudgetEnforce = "enforce" // Refuse to start
+)
+
+// Assumed footprint of a service with no -Xmx and no observed usage
+const defaultMemoryEstimateMB = 512
+
+var xmxRegex = regexp.MustCompile(`-Xmx(\d+)([kKmMgGtT]?)`)
+
+// ServiceMemoryEstimate is the memory a service uses, or is expected to use once started
+type ServiceMemoryEstimate struct {
+	ServiceID   string `json:"serviceId"`
+	ServiceName string `json:"serviceName"`
+	Running     bool   `json:"running"`
+	EstimatedMB int    `json:"estimatedMb"`
+	Source      string `json:"source"` // "observed", "peak", "xmx", "limit" or "default"
+}
+
+// MemoryBudgetStatus summarizes a profile's budget against its services
+type MemoryBudgetStatus struct {
+	ProfileID   string                  `json:"profileId"`
+	ProfileName string                  `json:"profileName"`
+	BudgetMB    int                     `json:"budgetMb"`
+	Mode        string                  `json:"mode"`
+	UsedMB      int                     `json:"usedMb"` // Running services only
+	Services    []ServiceMemoryEstimate `json:"services"`
+}
+
+// MemoryAdmission is the outcome of checking a service start against the budget
+type MemoryAdmission struct {
+	Allowed        bool                    `json:"allowed"`
+	ServiceName    string                  `json:"serviceName"`
+	ProfileName    string                  `json:"profileName,omitempty"`
+	Mode           string                  `json:"mode,omitempty"`
+	BudgetMB       int                     `json:"budgetMb"`
+	UsedMB         int                     `json:"usedMb"`
+	RequiredMB     int                     `json:"requiredMb"`
+	ExceededByMB   int                     `json:"exceededByMb"`
+	SuggestedStops []ServiceMemoryEstimate `json:"suggestedStops,omitempty"`
+	Message        string                  `json:"message"`
+}
+
+// MemoryBudgetError is returned when an enforced budget refuses a service start
+type MemoryBudgetError struct {
+	Admission *MemoryAdmission
+}
+
+func (e *MemoryBudgetError) Error() string {
+	return e.Admission.Message
+}
+
+// Highest resident memory observed for each service, used to estimate the
+// footprint of services that are not running
+var (
+	observedMemoryPeaks      = make(map[string]uint64)
+	observedMemoryPeaksMutex sync.Mutex
+)
+
+func recordObservedMemory(serviceUUID string, rss uint64) {
+	observedMemoryPeaksMutex.Lock()
+	if rss > observedMemoryPeaks[serviceUUID] {
+		observedMemoryPeaks[serviceUUID] = rss
+	}
+	observedMemoryPeaksMutex.Unlock()
+}
+
+// estimateServiceMemory uses current RSS for running services; otherwise the
+// larger of the -Xmx setting (including the JVM preset in effect in the
+// profile) and the highest RSS seen in earlier runs
+func (sm *Manager) estimateServiceMemory(service *models.Service, profileID string) ServiceMemoryEstimate {
+	service.Mutex.RLock()
+	estimate := ServiceMemoryEstimate{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		Running:     service.Status == "running" || service.Status == StatusPaused, // A paused JVM keeps its heap
+	}
+	rss := service.MemoryUsage
+	javaOpts := service.JavaOpts
+	javaOptsPreset := service.JavaOptsPreset
+	if envVar, exists := service.EnvVars["JAVA_OPTS"]; exists {
+		javaOpts += " " + envVar.Value
+	}
+	service.Mutex.RUnlock()
+
+	if estimate.Running && rss > 0 {
+		estimate.EstimatedMB = bytesToMB(rss)
+		estimate.Source = "observed"
+		return estimate
+	}
+
+	observedMemoryPeaksMutex.Lock()
+	peakMB := bytesToMB(observedMemoryPeaks[service.ID])
+	observedMemoryPeaksMutex.Unlock()
+
+	xmxMB := parseXmxMB(sm.resolveJavaOpts(profileID, service.ID, javaOptsPreset, javaOpts))
+	limits, _ := sm.db.GetServiceResourceLimits(service.ID)
+	switch {
+	case peakMB > 0 && peakMB >= xmxMB:
+		estimate.EstimatedMB = peakMB
+		estimate.Source = "peak"
+	case xmxMB > 0:
+		estimate.EstimatedMB = xmxMB
+		estimate.Source = "xmx"
+	case limits != nil && limits.MemoryLimitMB > 0:
+		estimate.EstimatedMB = limits.MemoryLimitMB
+		estimate.Source = "limit"
+	default:
+		estimate.EstimatedMB = defaultMemoryEstimateMB
+		estimate.Source = "default"
+	}
+	return estimate
+}
+
+// parseXmxMB returns the last -Xmx value in JVM options, in megabytes
+func parseXmxMB(javaOpts string) int {
+	matches := xmxRegex.FindAllStringSubmatch(javaOpts, -1)
+	if len(matches) == 0 {
+		return 0
+	}
+	match := matches[len(matches)-1]
+	value, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+	switch strings.ToLower(match[2]) {
+	case "k":
+		return value / 1024
+	case "m":
+		return value
+	case "g":
+		return value * 1024
+	case "t":
+		return value * 1024 * 1024
+	default: // bytes
+		return value / (1024 * 1024)
+	}
+}
+
+func bytesToMB(bytes uint64) int {
+	return int(bytes / (1024 * 1024))
+}
+
+// profileMemoryBudget loads the budget settings and service list of a profile
+func (sm *Manager) profileMemoryBudget(profileID string) (name string, budgetMB int, mode string, serviceUUIDs []string, err error) {
+	var servicesJSON string
+	var budgetMode sql.NullString
+	err = sm.db.QueryRow(`SELECT name, services_json, memory_budget_mb, memory_budget_mode FROM service_profiles WHERE id = ?`, profileID).
+		Scan(&name, &servicesJSON, &budgetMB, &budgetMode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", 0, "", nil, fmt.Errorf("profile %s not found", profileID)
+		}
+		return "", 0, "", nil, fmt.Errorf("failed to load memory budget: %w", err)
+	}
+	if err = json.Unmarshal([]byte(servicesJSON), &serviceUUIDs); err != nil {
+		return "", 0, "", nil, fmt.Errorf("failed to parse profile services: %w", err)
+	}
+	mode = budgetMode.String
+	if mode == "" {
+		mode = MemoryBudgetWarn
+	}
+	return name, budgetMB, mode, serviceUUIDs, nil
+}
+
+// GetMemoryBudgetStatus reports a profile's budget, current usage and the
+// estimated footprint of each of its services
+func (sm *Manager) GetMemoryBudgetStatus(profileID string) (*MemoryBudgetStatus, error) {
+	name, budgetMB, mode, serviceUUIDs, err := sm.profileMemoryBudget(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MemoryBudgetStatus{
+		ProfileID:   profileID,
+		ProfileName: name,
+		BudgetMB:    budgetMB,
+		Mode:        mode,
+		Services:    []ServiceMemoryEstimate{},
+	}
+	for _, serviceUUID := range serviceUUIDs {
+		service, exists := sm.GetServiceByUUID(serviceUUID)
+		if !exists {
+			continue
+		}
+		estimate := sm.estimateServiceMemory(service, profileID)
+		if estimate.Running {
+			status.UsedMB += estimate.EstimatedMB
+		}
+		status.Services = append(status.Services, estimate)
+	}
+	sort.Slice(status.Services, func(i, j int) bool {
+		return status.Services[i].EstimatedMB > status.Services[j].EstimatedMB
+	})
+	return status, nil
+}
+
+// CheckMemoryAdmission decides whether starting a service keeps its profile
+// within budget. Services outside a profile or in a profile without a budget
+// are always allowed.
+func (sm *Manager) CheckMemoryAdmission(serviceUUID string) (*MemoryAdmission, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	profileID := sm.getServiceProfileID(serviceUUID)
+	candidate := sm.estimateServiceMemory(service, profileID)
+	admission := &MemoryAdmission{Allowed: true, ServiceName: candidate.ServiceName, RequiredMB: candidate.EstimatedMB}
+
+	if profileID == "" {
+		return admission, nil
+	}
+	status, err := sm.GetMemoryBudgetStatus(profileID)
+	if err != nil {
+		return nil, err
+	}
+	admission.ProfileName = status.ProfileName
+	admission.Mode = status.Mode
+	admission.BudgetMB = status.BudgetMB
+	admission.UsedMB = status.UsedMB
+
+	if status.BudgetMB <= 0 || candidate.Running {
+		return admission, nil
+	}
+
+	admission.ExceededByMB = status.UsedMB + candidate.EstimatedMB - status.BudgetMB
+	if admission.ExceededByMB <= 0 {
+		admission.ExceededByMB = 0
+		return admission, nil
+	}
+
+	// Suggest the smallest single running service that frees enough memory,
+	// otherwise the largest ones until the overage is covered
+	var running []ServiceMemoryEstimate
+	for _, estimate := range status.Services {
+		if estimate.Running && estimate.ServiceID != serviceUUID {
+			running = append(running, estimate)
+		}
+	}
+	for i := len(running) - 1; i >= 0; i-- {
+		if running[i].EstimatedMB >= admission.ExceededByMB {
+			admission.SuggestedStops = []ServiceMemoryEstimate{running[i]}
+			break
+		}
+	}
+	if admission.SuggestedStops == nil {
+		freed := 0
+		for _, estimate := range running {
+			if freed >= admission.ExceededByMB {
+				break
+			}
+			admission.SuggestedStops = append(admission.SuggestedStops, estimate)
+			freed += estimate.EstimatedMB
+		}
+	}
+
+	admission.Allowed = status.Mode != MemoryBudgetEnforce
+	admission.Message = fmt.Sprintf("Starting %s (~%d MB) would use %d MB of the %d MB budget of profile %s",
+		candidate.ServiceName, candidate.EstimatedMB, status.UsedMB+candidate.EstimatedMB, status.BudgetMB, status.ProfileName)
+	if len(admission.SuggestedStops) > 0 {
+		names := make([]string, 0, len(admission.SuggestedStops))
+		for _, estimate := range admission.SuggestedStops {
+			names = append(names, estimate.ServiceName)
+		}
+		admission.Message += "; consider stopping " + strings.Join(names, ", ")
+	}
+	return admission, nil
+}
+
+// admitServiceStart applies the memory budget before a service is started.
+// It must be called without holding the service's mutex.
+func (sm *Manager) admitServiceStart(service *models.Service) error {
+	admission, err := sm.CheckMemoryAdmission(service.ID)
+	if err != nil {
+		log.Printf("[WARN] Memory budget check for service %s failed: %v", service.Name, err)
+		return nil
+	}
+	if admission.ExceededByMB == 0 {
+		return nil
+	}
+	if !admission.Allowed {
+		sm.logHookOutput(service, "ERROR", "Start refused: "+admission.Message)
+		return &MemoryBudgetError{Admission: admission}
+	}
+	sm.logHookOutput(service, "WARN", admission.Message)
+	return nil
+}
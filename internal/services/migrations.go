@@ -0,0 +1,830 @@
+// Package services - Flyway and Liquibase database migrations
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	migrationRunTimeout      = 15 * time.Minute
+	migrationRunHistoryLimit = 20  // Runs kept per service
+	migrationRunOutputLines  = 200 // Lines of tool output stored with a run
+
+	// The password reaches the Liquibase Maven plugin through this variable,
+	// so it stays out of the recorded command
+	migrationPasswordVar = "VERTEX_MIGRATION_PASSWORD"
+)
+
+// Where Spring Boot looks for migrations when the service doesn't say
+var (
+	springResourcesDir         = filepath.Join("src", "main", "resources")
+	defaultFlywayLocation      = "classpath:db/migration"
+	defaultLiquibaseChangeLogs = []string{
+		"db/changelog/db.changelog-master.yaml",
+		"db/changelog/db.changelog-master.yml",
+		"db/changelog/db.changelog-master.xml",
+		"db/changelog/db.changelog-master.json",
+		"db/changelog/db.changelog-master.sql",
+	}
+)
+
+var (
+	// ${NAME} or ${NAME:default} in a Spring property value
+	springPlaceholderRegex = regexp.MustCompile(`\$\{([^}:]+)(?::([^}]*))?\}`)
+	// The log level Maven and Gradle put in front of plugin output
+	buildLogPrefixRegex = regexp.MustCompile(`^\[(INFO|WARNING|WARN|ERROR|DEBUG)\]\s?`)
+	// path::id::author, as Liquibase lists changesets
+	liquibaseChangeSetRegex = regexp.MustCompile(`^\s*(\S+)::(\S+)::(\S+?)\s*$`)
+	// A changeset Liquibase applied during the run
+	liquibaseRanRegex = regexp.MustCompile(`(?i)(?:ChangeSet|Running Changeset:)\s*(\S+)::(\S+)::(\S+?)(?:\s+ran successfully|\s*$)`)
+)
+
+// Migration runs in progress, keyed by service UUID
+var (
+	activeMigrationRuns      = make(map[string]*exec.Cmd)
+	activeMigrationRunsMutex sync.Mutex
+)
+
+// migrationConfig is how a service's migrations are run, read from its build
+// file, its Spring configuration and its environment
+type migrationConfig struct {
+	tool        string
+	runner      string
+	runnerError string   // Why no runner could be picked
+	locations   []string // Flyway locations, or the Liquibase changelog relative to the resources
+	detectedBy  []string
+	url         string
+	user        string
+	password    string
+}
+
+// detectMigrations looks for Flyway or Liquibase in a service: a dependency
+// or plugin in its build file, spring.flyway or spring.liquibase settings,
+// or migrations where Spring Boot looks for them by default. It returns nil
+// when the service uses neither.
+func detectMigrations(serviceDir string, buildSystem BuildSystemType, env map[string]string) *migrationConfig {
+	var buildFile strings.Builder
+	for _, name := range []string{"pom.xml", "build.gradle", "build.gradle.kts"} {
+		if data, err := os.ReadFile(filepath.Join(serviceDir, name)); err == nil {
+			buildFile.Write(data)
+		}
+	}
+	build := buildFile.String()
+	properties := readSpringProperties(serviceDir)
+	setting := func(key string) string {
+		return springSetting(properties, env, key)
+	}
+
+	config := &migrationConfig{}
+	switch {
+	case setting("spring.flyway.enabled") != "false" && (strings.Contains(build, "org.flywaydb") || hasSpringSettings(properties, "spring.flyway.") ||
+		dirExists(filepath.Join(serviceDir, springResourcesDir, "db", "migration"))):
+		config.tool = models.MigrationToolFlyway
+		if strings.Contains(build, "org.flywaydb") {
+			config.detectedBy = append(config.detectedBy, "Flyway in the build file")
+		}
+		if hasSpringSettings(properties, "spring.flyway.") {
+			config.detectedBy = append(config.detectedBy, "spring.flyway settings")
+		}
+
+		locations := setting("spring.flyway.locations")
+		if locations == "" {
+			locations = defaultFlywayLocation
+		}
+		for _, location := range strings.Split(locations, ",") {
+			if location = strings.TrimSpace(location); location != "" {
+				config.locations = append(config.locations, flywayFilesystemLocation(location))
+			}
+		}
+		if dirExists(filepath.Join(serviceDir, springResourcesDir, "db", "migration")) && locations == defaultFlywayLocation {
+			config.detectedBy = append(config.detectedBy, filepath.Join(springResourcesDir, "db", "migration"))
+		}
+
+		config.url = firstNonEmpty(setting("spring.flyway.url"), setting("spring.datasource.url"))
+		config.user = firstNonEmpty(setting("spring.flyway.user"), setting("spring.datasource.username"))
+		config.password = firstNonEmpty(setting("spring.flyway.password"), setting("spring.datasource.password"))
+
+	case setting("spring.liquibase.enabled") != "false" && (strings.Contains(build, "org.liquibase") || hasSpringSettings(properties, "spring.liquibase.") ||
+		liquibaseChangeLog(serviceDir, "") != ""):
+		config.tool = models.MigrationToolLiquibase
+		if strings.Contains(build, "org.liquibase") {
+			config.detectedBy = append(config.detectedBy, "Liquibase in the build file")
+		}
+		if hasSpringSettings(properties, "spring.liquibase.") {
+			config.detectedBy = append(config.detectedBy, "spring.liquibase settings")
+		}
+
+		configured := setting("spring.liquibase.change-log")
+		changeLog := liquibaseChangeLog(serviceDir, configured)
+		if configured == "" && changeLog != "" {
+			config.detectedBy = append(config.detectedBy, filepath.Join(springResourcesDir, filepath.FromSlash(changeLog)))
+		}
+		if changeLog == "" {
+			changeLog = defaultLiquibaseChangeLogs[0]
+		}
+		config.locations = []string{changeLog}
+
+		config.url = firstNonEmpty(setting("spring.liquibase.url"), setting("spring.datasource.url"))
+		config.user = firstNonEmpty(setting("spring.liquibase.user"), setting("spring.datasource.username"))
+		config.password = firstNonEmpty(setting("spring.liquibase.password"), setting("spring.datasource.password"))
+
+	default:
+		return nil
+	}
+
+	// The tool's own command line works for every build system; the build
+	// plugins are the fallback
+	switch {
+	case commandExists(config.tool):
+		config.runner = models.MigrationRunnerCLI
+	case buildSystem == BuildSystemMaven:
+		config.runner = models.MigrationRunnerMaven
+	case buildSystem == BuildSystemGradle && config.tool == models.MigrationToolFlyway && strings.Contains(build, "org.flywaydb.flyway"):
+		config.runner = models.MigrationRunnerGradle
+	default:
+		config.runnerError = fmt.Sprintf("install the %s command line to run the migrations of this service", config.tool)
+		if config.tool == models.MigrationToolFlyway {
+			config.runnerError += ", or apply the org.flywaydb.flyway Gradle plugin"
+		}
+	}
+	return config
+}
+
+// commandExists reports whether a command is on the PATH
+func commandExists(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// flywayFilesystemLocation turns a classpath location into the source
+// directory it is packaged from, so migrations run without a build
+func flywayFilesystemLocation(location string) string {
+	if strings.HasPrefix(location, "filesystem:") {
+		return location
+	}
+	path := strings.TrimLeft(strings.TrimPrefix(location, "classpath:"), "/")
+	return "filesystem:" + filepath.ToSlash(filepath.Join(springResourcesDir, path))
+}
+
+// liquibaseChangeLog returns the changelog relative to the resources
+// directory: the configured one, or the first default that exists
+func liquibaseChangeLog(serviceDir, configured string) string {
+	if configured != "" {
+		return strings.TrimLeft(strings.TrimPrefix(configured, "classpath:"), "/")
+	}
+	for _, changeLog := range defaultLiquibaseChangeLogs {
+		if _, err := os.Stat(filepath.Join(serviceDir, springResourcesDir, filepath.FromSlash(changeLog))); err == nil {
+			return changeLog
+		}
+	}
+	return ""
+}
+
+// readSpringProperties flattens application.yml and application.properties
+// into dotted keys; the properties file wins, as in Spring Boot
+func readSpringProperties(serviceDir string) map[string]string {
+	properties := make(map[string]string)
+	for _, name := range []string{"application.yml", "application.yaml"} {
+		data, err := os.ReadFile(filepath.Join(serviceDir, springResourcesDir, name))
+		if err != nil {
+			continue
+		}
+		// Only the first document; profile-specific ones follow it
+		var document map[string]interface{}
+		if err := yaml.NewDecoder(strings.NewReader(string(data))).Decode(&document); err == nil {
+			flattenSpringYAML("", document, properties)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(serviceDir, springResourcesDir, "application.properties"))
+	if err != nil {
+		return properties
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		separator := strings.IndexAny(line, "=:")
+		if separator < 0 {
+			continue
+		}
+		properties[strings.TrimSpace(line[:separator])] = strings.TrimSpace(line[separator+1:])
+	}
+	return properties
+}
+
+func flattenSpringYAML(prefix string, value interface{}, properties map[string]string) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenSpringYAML(key, child, properties)
+		}
+	case []interface{}:
+		values := make([]string, 0, len(typed))
+		for _, item := range typed {
+			values = append(values, fmt.Sprint(item))
+		}
+		properties[prefix] = strings.Join(values, ",")
+	case nil:
+	default:
+		properties[prefix] = fmt.Sprint(typed)
+	}
+}
+
+func hasSpringSettings(properties map[string]string, prefix string) bool {
+	for key := range properties {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// springSetting reads a Spring property the way the service would see it:
+// from its environment (SPRING_DATASOURCE_URL for spring.datasource.url)
+// first, then its configuration with ${...} placeholders filled in
+func springSetting(properties, env map[string]string, key string) string {
+	envName := strings.ToUpper(strings.ReplaceAll(strings.ReplaceAll(key, "-", ""), ".", "_"))
+	if value, exists := env[envName]; exists {
+		return value
+	}
+	return springPlaceholderRegex.ReplaceAllStringFunc(properties[key], func(placeholder string) string {
+		match := springPlaceholderRegex.FindStringSubmatch(placeholder)
+		if value, exists := env[match[1]]; exists {
+			return value
+		}
+		if value, exists := properties[match[1]]; exists {
+			return value
+		}
+		return match[2]
+	})
+}
+
+// migrationCommand builds the command for an action and the environment
+// that carries the connection settings
+func migrationCommand(config *migrationConfig, action, serviceDir string) (string, map[string]string) {
+	env := make(map[string]string)
+	var command string
+
+	switch config.tool {
+	case models.MigrationToolFlyway:
+		env["FLYWAY_URL"] = config.url
+		env["FLYWAY_USER"] = config.user
+		env["FLYWAY_PASSWORD"] = config.password
+		locations := strings.Join(config.locations, ",")
+
+		switch config.runner {
+		case models.MigrationRunnerCLI:
+			command = "flyway -locations=" + shellQuote(locations)
+			if action == models.MigrationActionMigrate {
+				command += " migrate"
+			}
+			command += " info"
+		case models.MigrationRunnerGradle:
+			command = "gradle"
+			if HasGradleWrapper(serviceDir) {
+				command = "./gradlew"
+			}
+			command += " --console=plain -Pflyway.locations=" + shellQuote(locations)
+			if action == models.MigrationActionMigrate {
+				command += " flywayMigrate"
+			}
+			command += " flywayInfo"
+		default:
+			command = mavenCommand(serviceDir) + " -Dflyway.locations=" + shellQuote(locations)
+			if action == models.MigrationActionMigrate {
+				command += " org.flywaydb:flyway-maven-plugin:migrate"
+			}
+			command += " org.flywaydb:flyway-maven-plugin:info"
+		}
+
+	case models.MigrationToolLiquibase:
+		changeLog := config.locations[0]
+		switch config.runner {
+		case models.MigrationRunnerCLI:
+			env["LIQUIBASE_COMMAND_URL"] = config.url
+			env["LIQUIBASE_COMMAND_USERNAME"] = config.user
+			env["LIQUIBASE_COMMAND_PASSWORD"] = config.password
+			base := "liquibase --changelog-file=" + shellQuote(changeLog) + " --search-path=" + shellQuote(filepath.ToSlash(springResourcesDir))
+			if action == models.MigrationActionMigrate {
+				command = base + " update && "
+			}
+			command += base + " history && " + base + " status --verbose"
+		default:
+			env[migrationPasswordVar] = config.password
+			command = mavenCommand(serviceDir) +
+				" -Dliquibase.changeLogFile=" + shellQuote(changeLog) +
+				" -Dliquibase.searchPath=" + shellQuote(filepath.ToSlash(springResourcesDir)) +
+				" -Dliquibase.url=" + shellQuote(config.url) +
+				" -Dliquibase.username=" + shellQuote(config.user) +
+				` -Dliquibase.password="$` + migrationPasswordVar + `"` +
+				" -Dliquibase.verbose=true"
+			if action == models.MigrationActionMigrate {
+				command += " org.liquibase:liquibase-maven-plugin:update"
+			}
+			command += " org.liquibase:liquibase-maven-plugin:status"
+		}
+	}
+	return command, env
+}
+
+// mavenCommand prefers the project's wrapper, in batch mode
+func mavenCommand(serviceDir string) string {
+	if HasMavenWrapper(serviceDir) {
+		return "./mvnw -B"
+	}
+	return "mvn -B"
+}
+
+// RunServiceMigrations starts a Flyway or Liquibase run for a service in the
+// background and returns it. The finished run, with its applied and pending
+// migrations, is broadcast as a "migration_run" message.
+func (sm *Manager) RunServiceMigrations(serviceUUID, projectsDir string, req models.MigrationRunRequest) (*models.MigrationRun, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	action := req.Action
+	if action == "" {
+		action = models.MigrationActionMigrate
+	}
+	if action != models.MigrationActionMigrate && action != models.MigrationActionInfo {
+		return nil, fmt.Errorf("invalid action '%s'; use %s or %s", action, models.MigrationActionMigrate, models.MigrationActionInfo)
+	}
+
+	run, _, err := sm.startMigrationRun(context.Background(), service, projectsDir, action)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// migrateBeforeStart applies the pending migrations of a service that asks
+// for it, waiting for them; a failed migration fails the start
+func (sm *Manager) migrateBeforeStart(ctx context.Context, service *models.Service, projectsDir string) error {
+	started, done, err := sm.startMigrationRun(ctx, service, projectsDir, models.MigrationActionMigrate)
+	if err != nil {
+		if strings.Contains(err.Error(), "no Flyway or Liquibase") {
+			log.Printf("[INFO] Service %s has auto-migrate on but no Flyway or Liquibase migrations, skipping", service.Name)
+			return nil
+		}
+		return fmt.Errorf("failed to migrate the database of %s before start: %w", service.Name, err)
+	}
+
+	run, err := done()
+	if err != nil {
+		return fmt.Errorf("start of %s cancelled while migrating: %w", service.Name, err)
+	}
+	if run.Status != models.MigrationRunCompleted {
+		return fmt.Errorf("failed to migrate the database of %s before start (run %d): %s", service.Name, started.ID, run.Error)
+	}
+	log.Printf("[INFO] Migrated the database of service %s: %d applied, %d pending", service.Name, run.Applied, run.Pending)
+	return nil
+}
+
+// startMigrationRun starts the tool and returns a copy of the new run and a
+// function that waits for it to finish. Cancelling ctx stops the tool.
+func (sm *Manager) startMigrationRun(ctx context.Context, service *models.Service, projectsDir, action string) (*models.MigrationRun, func() (*models.MigrationRun, error), error) {
+	service.Mutex.RLock()
+	serviceUUID := service.ID
+	serviceName := service.Name
+	serviceDir := serviceWorkingDir(projectsDir, service)
+	buildSystem := service.BuildSystem
+	service.Mutex.RUnlock()
+
+	if _, err := os.Stat(serviceDir); os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("service directory does not exist: %s", serviceDir)
+	}
+
+	effectiveBuildSystem := GetEffectiveBuildSystem(serviceDir, buildSystem)
+	config := detectMigrations(serviceDir, effectiveBuildSystem, sm.serviceEnvSettings(service))
+	if config == nil {
+		return nil, nil, fmt.Errorf("service %s has no Flyway or Liquibase migrations", serviceName)
+	}
+	if config.runnerError != "" {
+		return nil, nil, fmt.Errorf("%s", config.runnerError)
+	}
+	if config.url == "" {
+		return nil, nil, fmt.Errorf("no database URL for service %s; set spring.datasource.url or SPRING_DATASOURCE_URL", serviceName)
+	}
+
+	// The connection settings may name secrets kept in a vault
+	secrets, err := resolveSecrets(ctx, map[string]string{"url": config.url, "user": config.user, "password": config.password})
+	if err != nil {
+		return nil, nil, err
+	}
+	config.url, config.user, config.password = secrets.value(config.url), secrets.value(config.user), secrets.value(config.password)
+
+	cmdString, toolEnv := migrationCommand(config, action, serviceDir)
+	credentialEnv := map[string]string{}
+	if config.runner != models.MigrationRunnerCLI {
+		cmdString, credentialEnv = sm.applyRepositoryCredentials(cmdString, effectiveBuildSystem, sm.getServiceProfileID(serviceUUID), serviceName)
+		cmdString = sm.applyBuildSettings(cmdString, effectiveBuildSystem, sm.getServiceProfileID(serviceUUID), serviceName)
+	}
+
+	runEnv, err := sm.testRunEnv(service)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd := exec.Command("bash", "-c", cmdString)
+	cmd.Dir = serviceDir
+	cmd.Env = runEnv
+	for key, value := range toolEnv {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+	for key, value := range credentialEnv {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+	SetProcessGroup(cmd)
+
+	activeMigrationRunsMutex.Lock()
+	if _, running := activeMigrationRuns[serviceUUID]; running {
+		activeMigrationRunsMutex.Unlock()
+		return nil, nil, fmt.Errorf("a migration run of service %s is already running", serviceName)
+	}
+	activeMigrationRuns[serviceUUID] = cmd
+	activeMigrationRunsMutex.Unlock()
+
+	run := &models.MigrationRun{
+		ServiceID:   serviceUUID,
+		ServiceName: serviceName,
+		Tool:        config.tool,
+		Runner:      config.runner,
+		Action:      action,
+		Status:      models.MigrationRunRunning,
+		Command:     cmdString,
+		StartedAt:   time.Now(),
+	}
+
+	id, err := sm.db.InsertMigrationRun(run)
+	if err != nil {
+		sm.finishActiveMigrationRun(serviceUUID)
+		return nil, nil, err
+	}
+	run.ID = id
+
+	if err := sm.db.PruneMigrationRuns(serviceUUID, migrationRunHistoryLimit); err != nil {
+		log.Printf("[WARN] Failed to prune migration runs of service %s: %v", serviceName, err)
+	}
+
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		sm.finishActiveMigrationRun(serviceUUID)
+		return nil, nil, sm.failMigrationRun(run, fmt.Errorf("failed to capture %s output: %w", config.tool, err))
+	}
+	cmd.Stderr = cmd.Stdout
+
+	log.Printf("[INFO] Running %s %s for service %s with command: %s", config.tool, action, serviceName, cmdString)
+	if err := cmd.Start(); err != nil {
+		sm.finishActiveMigrationRun(serviceUUID)
+		return nil, nil, sm.failMigrationRun(run, fmt.Errorf("failed to start %s: %w", config.tool, err))
+	}
+
+	finished := make(chan struct{})
+	started := *run
+	go func() {
+		defer close(finished)
+		sm.waitForMigrationRun(run, cmd, output)
+	}()
+
+	wait := func() (*models.MigrationRun, error) {
+		select {
+		case <-finished:
+			return run, nil
+		case <-ctx.Done():
+			if err := ForceKillProcessGroup(cmd.Process.Pid); err != nil {
+				log.Printf("[WARN] Failed to stop %s of service %s: %v", config.tool, serviceName, err)
+			}
+			<-finished
+			return nil, ctx.Err()
+		}
+	}
+	return &started, wait, nil
+}
+
+// envMap turns KEY=VALUE entries into a map
+func envMap(entries []string) map[string]string {
+	env := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if key, value, found := strings.Cut(entry, "="); found {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// CancelServiceMigrations stops the running migration run of a service
+func (sm *Manager) CancelServiceMigrations(serviceUUID string) error {
+	activeMigrationRunsMutex.Lock()
+	cmd, running := activeMigrationRuns[serviceUUID]
+	activeMigrationRunsMutex.Unlock()
+
+	if !running || cmd.Process == nil {
+		return fmt.Errorf("no migration run in progress for service UUID %s", serviceUUID)
+	}
+	return ForceKillProcessGroup(cmd.Process.Pid)
+}
+
+// GetServiceMigrations reports which migration tool a service uses, how
+// Vertex would run it, and the applied and pending migrations of its latest
+// completed run
+func (sm *Manager) GetServiceMigrations(serviceUUID, projectsDir string) (*models.MigrationSetup, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	setup := &models.MigrationSetup{ServiceID: serviceUUID, ServiceName: service.Name, AutoMigrate: service.AutoMigrate, Migrations: []models.Migration{}}
+	serviceDir := serviceWorkingDir(projectsDir, service)
+	buildSystem := service.BuildSystem
+	service.Mutex.RUnlock()
+
+	if config := detectMigrations(serviceDir, GetEffectiveBuildSystem(serviceDir, buildSystem), sm.serviceEnvSettings(service)); config != nil {
+		setup.Detected = true
+		setup.Tool = config.tool
+		setup.Runner = config.runner
+		setup.Locations = config.locations
+		setup.DetectedBy = config.detectedBy
+		setup.DatabaseURL = config.url
+		setup.Error = config.runnerError
+		if config.url == "" && setup.Error == "" {
+			setup.Error = "no database URL; set spring.datasource.url or SPRING_DATASOURCE_URL"
+		}
+	}
+
+	runs, err := sm.db.GetMigrationRuns(serviceUUID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) > 0 {
+		setup.LastRun = &runs[0]
+	}
+
+	latest, err := sm.db.GetLatestMigrationRun(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		setup.Applied = latest.Applied
+		setup.Pending = latest.Pending
+		if latest.Migrations != nil {
+			setup.Migrations = latest.Migrations
+		}
+	}
+	return setup, nil
+}
+
+// GetMigrationRuns returns the recent migration runs of a service, newest first
+func (sm *Manager) GetMigrationRuns(serviceUUID string, limit int) ([]models.MigrationRun, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	if limit <= 0 || limit > migrationRunHistoryLimit {
+		limit = migrationRunHistoryLimit
+	}
+	return sm.db.GetMigrationRuns(serviceUUID, limit)
+}
+
+// GetMigrationRun returns a migration run with its migrations and output
+func (sm *Manager) GetMigrationRun(serviceUUID string, runID int64) (*models.MigrationRun, error) {
+	return sm.db.GetMigrationRun(serviceUUID, runID)
+}
+
+// waitForMigrationRun keeps the tool output, then records the migrations it
+// listed
+func (sm *Manager) waitForMigrationRun(run *models.MigrationRun, cmd *exec.Cmd, output io.Reader) {
+	defer sm.finishActiveMigrationRun(run.ServiceID)
+
+	timer := time.AfterFunc(migrationRunTimeout, func() {
+		log.Printf("[WARN] %s run of service %s exceeded %s, stopping it", run.Tool, run.ServiceName, migrationRunTimeout)
+		if err := ForceKillProcessGroup(cmd.Process.Pid); err != nil {
+			log.Printf("[WARN] Failed to stop %s run of service %s: %v", run.Tool, run.ServiceName, err)
+		}
+	})
+	defer timer.Stop()
+
+	var lines []string
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	waitErr := cmd.Wait()
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	tail := lines
+	if len(tail) > migrationRunOutputLines {
+		tail = tail[len(tail)-migrationRunOutputLines:]
+	}
+	run.Output = strings.Join(tail, "\n")
+
+	if run.Tool == models.MigrationToolFlyway {
+		run.Migrations = parseFlywayInfo(lines)
+	} else {
+		run.Migrations = parseLiquibaseOutput(lines)
+	}
+	for _, migration := range run.Migrations {
+		switch migration.State {
+		case models.MigrationApplied:
+			run.Applied++
+		case models.MigrationPending:
+			run.Pending++
+		}
+	}
+
+	if waitErr != nil {
+		run.Status = models.MigrationRunError
+		run.Error = fmt.Sprintf("%s failed: %v", run.Tool, waitErr)
+	} else {
+		run.Status = models.MigrationRunCompleted
+	}
+
+	if err := sm.db.UpdateMigrationRun(run); err != nil {
+		log.Printf("[ERROR] Failed to store migration run of service %s: %v", run.ServiceName, err)
+	}
+
+	log.Printf("[INFO] %s %s of service %s %s: %d applied, %d pending in %s", run.Tool, run.Action, run.ServiceName, run.Status,
+		run.Applied, run.Pending, time.Duration(run.DurationMs)*time.Millisecond)
+
+	broadcast := *run
+	broadcast.Output = ""
+	sm.broadcastMessage(WebSocketMessage{Type: "migration_run", Payload: broadcast})
+}
+
+// failMigrationRun records a run that could not be started
+func (sm *Manager) failMigrationRun(run *models.MigrationRun, cause error) error {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = models.MigrationRunError
+	run.Error = cause.Error()
+	if err := sm.db.UpdateMigrationRun(run); err != nil {
+		log.Printf("[ERROR] Failed to store migration run of service %s: %v", run.ServiceName, err)
+	}
+	return cause
+}
+
+func (sm *Manager) finishActiveMigrationRun(serviceUUID string) {
+	activeMigrationRunsMutex.Lock()
+	delete(activeMigrationRuns, serviceUUID)
+	activeMigrationRunsMutex.Unlock()
+}
+
+// toolTables returns the rows of the ASCII tables Flyway and Liquibase
+// print, keyed by column header. Rows of a later table with the same
+// header replace those of an earlier one.
+func toolTables(lines []string, required ...string) []map[string]string {
+	var header []string
+	var rows []map[string]string
+	for _, line := range lines {
+		line = strings.TrimSpace(buildLogPrefixRegex.ReplaceAllString(strings.TrimSpace(line), ""))
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		cells := strings.Split(strings.Trim(line, "|"), "|")
+		for i := range cells {
+			cells[i] = strings.TrimSpace(cells[i])
+		}
+
+		isHeader := true
+		for _, column := range required {
+			found := false
+			for _, cell := range cells {
+				if strings.EqualFold(cell, column) {
+					found = true
+				}
+			}
+			isHeader = isHeader && found
+		}
+		if isHeader {
+			header, rows = cells, nil
+			continue
+		}
+		if header == nil {
+			continue
+		}
+
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(cells) {
+				row[strings.ToLower(column)] = cells[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// parseFlywayInfo reads the table flyway info prints
+func parseFlywayInfo(lines []string) []models.Migration {
+	migrations := []models.Migration{}
+	for _, row := range toolTables(lines, "Version", "Description", "State") {
+		migration := models.Migration{
+			Version:     row["version"],
+			Description: row["description"],
+			Script:      row["script"],
+			InstalledOn: row["installed on"],
+			State:       flywayState(row["state"]),
+		}
+		if migration.Version == "" && migration.Description == "" {
+			continue // "No migrations found"
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations
+}
+
+// flywayState maps Flyway's states onto Vertex's
+func flywayState(state string) string {
+	state = strings.ToLower(strings.TrimSpace(state))
+	switch {
+	case state == "success", state == "baseline", strings.HasPrefix(state, "out of order"):
+		return models.MigrationApplied
+	case state == "pending":
+		return models.MigrationPending
+	case strings.HasPrefix(state, "failed"):
+		return models.MigrationFailed
+	}
+	return state
+}
+
+// parseLiquibaseOutput reads the changesets of liquibase history, those it
+// ran, and those status --verbose lists as not applied
+func parseLiquibaseOutput(lines []string) []models.Migration {
+	migrations := []models.Migration{}
+	index := make(map[string]int)
+	add := func(path, id, author, state, installedOn string) {
+		key := path + "::" + id + "::" + author
+		if i, exists := index[key]; exists {
+			migrations[i].State = state
+			if installedOn != "" {
+				migrations[i].InstalledOn = installedOn
+			}
+			return
+		}
+		index[key] = len(migrations)
+		migrations = append(migrations, models.Migration{Version: id, Description: author, Script: path, State: state, InstalledOn: installedOn})
+	}
+
+	for _, row := range toolTables(lines, "Changeset ID") {
+		add(row["changelog path"], row["changeset id"], row["changeset author"], models.MigrationApplied, row["update date"])
+	}
+
+	pendingList := false
+	for _, line := range lines {
+		line = buildLogPrefixRegex.ReplaceAllString(strings.TrimSpace(line), "")
+		if match := liquibaseRanRegex.FindStringSubmatch(line); match != nil && !pendingList {
+			add(match[1], match[2], match[3], models.MigrationApplied, "")
+			continue
+		}
+		if strings.Contains(line, "not been applied") {
+			pendingList = true
+			continue
+		}
+		if pendingList {
+			if match := liquibaseChangeSetRegex.FindStringSubmatch(line); match != nil {
+				add(match[1], match[2], match[3], models.MigrationPending, "")
+				continue
+			}
+			pendingList = false
+		}
+	}
+	return migrations
+}
@@ -2,96 +2,253 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/shirou/gopsutil/v3/process"
 	"github.com/zechtz/vertex/internal/models"
 )
 
-// collectResourceMetrics collects CPU, memory, and network metrics for a service
-func (sm *Manager) collectResourceMetrics(service *models.Service) error {
-	if service.PID <= 0 {
-		// Reset metrics for stopped services
-		service.CPUPercent = 0
-		service.MemoryUsage = 0
-		service.MemoryPercent = 0
-		service.DiskUsage = 0
-		service.NetworkRx = 0
-		service.NetworkTx = 0
-		return nil
+// processUsage is the summed resource usage of a service's process tree: the
+// process Vertex started and everything it spawned, such as the JVM under a
+// Maven wrapper, plus what is left of its process group
+type processUsage struct {
+	alive         bool
+	processes     int
+	cpuPercent    float64
+	rss           uint64
+	memoryPercent float32
+	ioBytes       uint64
+	readCount     uint64
+	writeCount    uint64
+	breakdown     []models.ProcessUsage // Each process, the main one first
+}
+
+// processCommandMaxLength caps the command line kept for each process
+const processCommandMaxLength = 512
+
+// Per-process usage of each running service at the last collection, by service UUID
+var (
+	serviceProcesses      = make(map[string][]models.ProcessUsage)
+	serviceProcessesMutex sync.RWMutex
+)
+
+// cpuSample is the CPU time of a process at the previous collection, so CPU
+// usage is measured over the interval instead of the process's lifetime
+type cpuSample struct {
+	seconds float64
+	at      time.Time
+}
+
+// Previous CPU samples by PID, only touched by the metrics collector
+var cpuSamples = make(map[int32]cpuSample)
+
+// sampleProcessTrees measures the process trees rooted at the given PIDs in
+// one pass over the process table, reading each process's parent once and
+// the machine's memory once, through gopsutil's native APIs
+func sampleProcessTrees(ctx context.Context, roots []int32) (map[int32]processUsage, error) {
+	usage := make(map[int32]processUsage, len(roots))
+	if len(roots) == 0 {
+		clear(cpuSamples)
+		return usage, nil
 	}
 
-	// Get process handle
-	proc, err := process.NewProcess(int32(service.PID))
+	pids, err := process.PidsWithContext(ctx)
 	if err != nil {
-		log.Printf("[DEBUG] Failed to get process handle for %s (PID %d): %v", service.Name, service.PID, err)
-		return err
+		return nil, fmt.Errorf("failed to list processes: %w", err)
+	}
+	isRoot := make(map[int32]bool, len(roots))
+	for _, root := range roots {
+		isRoot[root] = true
 	}
 
-	// Check if process is still running
-	isRunning, err := proc.IsRunning()
-	if err != nil || !isRunning {
-		log.Printf("[DEBUG] Process %d for service %s is no longer running", service.PID, service.Name)
-		// Reset metrics for stopped processes
-		service.CPUPercent = 0
-		service.MemoryUsage = 0
-		service.MemoryPercent = 0
-		service.DiskUsage = 0
-		service.NetworkRx = 0
-		service.NetworkTx = 0
-		return fmt.Errorf("process no longer running")
+	children := make(map[int32][]int32)
+	parents := make(map[int32]int32, len(pids))
+	groups := make(map[int32][]int32) // Members of the process groups services lead
+	alive := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		alive[pid] = true
+		// Services lead their own process group, so forks that outlive their
+		// parent and are reparented can still be found
+		if pgid, err := GetProcessGroup(int(pid)); err == nil && isRoot[int32(pgid)] && int32(pgid) != pid {
+			groups[int32(pgid)] = append(groups[int32(pgid)], pid)
+		}
+		ppid, err := (&process.Process{Pid: pid}).PpidWithContext(ctx)
+		if err != nil || ppid == pid {
+			continue // Exited meanwhile, or the idle process on Windows
+		}
+		parents[pid] = ppid
+		children[ppid] = append(children[ppid], pid)
 	}
 
-	// Collect CPU usage
-	cpuPercent, err := proc.CPUPercent()
-	if err != nil {
-		log.Printf("[DEBUG] Failed to get CPU usage for %s: %v", service.Name, err)
-	} else {
-		service.CPUPercent = cpuPercent
+	var totalMemory uint64
+	if machineMemory, err := mem.VirtualMemoryWithContext(ctx); err == nil {
+		totalMemory = machineMemory.Total
 	}
 
-	// Collect memory usage
-	memInfo, err := proc.MemoryInfo()
-	if err != nil {
-		log.Printf("[DEBUG] Failed to get memory info for %s: %v", service.Name, err)
-	} else {
-		service.MemoryUsage = memInfo.RSS // Resident Set Size (physical memory)
+	now := time.Now()
+	sampled := make(map[int32]bool)
+	for _, root := range roots {
+		if !alive[root] {
+			continue
+		}
+		tree := processUsage{alive: true}
+		walk := func(start int32, role string) {
+			queue := []int32{start}
+			for len(queue) > 0 {
+				pid := queue[0]
+				queue = queue[1:]
+				if sampled[pid] {
+					continue // Already counted, e.g. for a service started by another
+				}
+				sampled[pid] = true
+				queue = append(queue, children[pid]...)
+
+				entry := sampleProcess(ctx, pid, now, totalMemory)
+				entry.PPID = parents[pid]
+				entry.Role = role
+				if pid == root {
+					entry.Role = models.ProcessRoleMain
+				}
+				tree.processes++
+				tree.cpuPercent += entry.CPUPercent
+				tree.rss += entry.MemoryUsage
+				tree.ioBytes += entry.DiskUsage
+				tree.readCount += entry.readCount
+				tree.writeCount += entry.writeCount
+				tree.breakdown = append(tree.breakdown, entry.ProcessUsage)
+			}
+		}
+		walk(root, models.ProcessRoleChild)
+		for _, member := range groups[root] {
+			walk(member, models.ProcessRoleGroup)
+		}
+		if totalMemory > 0 {
+			tree.memoryPercent = float32(100 * float64(tree.rss) / float64(totalMemory))
+		}
+		usage[root] = tree
 	}
 
-	// Collect memory percentage
-	memPercent, err := proc.MemoryPercent()
-	if err != nil {
-		log.Printf("[DEBUG] Failed to get memory percentage for %s: %v", service.Name, err)
-	} else {
-		service.MemoryPercent = memPercent
+	// Forget processes that are gone or no longer belong to a service
+	for pid := range cpuSamples {
+		if !sampled[pid] {
+			delete(cpuSamples, pid)
+		}
 	}
+	return usage, nil
+}
 
-	// Collect I/O statistics (disk usage) - Optional on some platforms
-	ioCounters, err := proc.IOCounters()
-	if err != nil {
-		// Only log this at TRACE level since it's expected on some platforms (like macOS)
-		// log.Printf("[TRACE] I/O counters not available for %s: %v", service.Name, err)
-		// Set default values for unsupported platforms
-		service.DiskUsage = 0
-		service.NetworkRx = 0
-		service.NetworkTx = 0
-	} else {
-		service.DiskUsage = ioCounters.ReadBytes + ioCounters.WriteBytes
-		// Collect network statistics using I/O counters as a proxy
-		service.NetworkRx = ioCounters.ReadCount
-		service.NetworkTx = ioCounters.WriteCount
+// sampledProcess is the usage of one process and the I/O operation counts
+// that are only kept summed per service
+type sampledProcess struct {
+	models.ProcessUsage
+	readCount  uint64
+	writeCount uint64
+}
+
+// sampleProcess measures one process, working out its CPU usage since the
+// previous collection
+func sampleProcess(ctx context.Context, pid int32, now time.Time, totalMemory uint64) sampledProcess {
+	proc := &process.Process{Pid: pid}
+	entry := sampledProcess{ProcessUsage: models.ProcessUsage{PID: pid}}
+
+	if name, err := proc.NameWithContext(ctx); err == nil {
+		entry.Name = name
+	}
+	if cmdline, err := proc.CmdlineWithContext(ctx); err == nil {
+		if len(cmdline) > processCommandMaxLength {
+			cmdline = cmdline[:processCommandMaxLength] + "..."
+		}
+		entry.Command = cmdline
+	}
+	if times, err := proc.TimesWithContext(ctx); err == nil {
+		seconds := times.User + times.System
+		if previous, exists := cpuSamples[pid]; exists && seconds >= previous.seconds {
+			if elapsed := now.Sub(previous.at).Seconds(); elapsed > 0 {
+				entry.CPUPercent = (seconds - previous.seconds) / elapsed * 100
+			}
+		}
+		cpuSamples[pid] = cpuSample{seconds: seconds, at: now}
+	}
+	if memInfo, err := proc.MemoryInfoWithContext(ctx); err == nil {
+		entry.MemoryUsage = memInfo.RSS
+		if totalMemory > 0 {
+			entry.MemoryPercent = float32(100 * float64(memInfo.RSS) / float64(totalMemory))
+		}
+	}
+	// I/O counters are not available on every platform (like macOS)
+	if ioCounters, err := proc.IOCountersWithContext(ctx); err == nil {
+		entry.DiskUsage = ioCounters.ReadBytes + ioCounters.WriteBytes
+		entry.readCount = ioCounters.ReadCount
+		entry.writeCount = ioCounters.WriteCount
+	}
+	return entry
+}
+
+// GetServiceProcesses returns the usage of each process of a running service
+// at the last metrics collection, the process Vertex started first
+func (sm *Manager) GetServiceProcesses(serviceUUID string) []models.ProcessUsage {
+	serviceProcessesMutex.RLock()
+	defer serviceProcessesMutex.RUnlock()
+	return append([]models.ProcessUsage{}, serviceProcesses[serviceUUID]...)
+}
+
+// resetResourceMetrics zeroes the resource metrics of a service
+func resetResourceMetrics(service *models.Service) {
+	serviceProcessesMutex.Lock()
+	delete(serviceProcesses, service.ID)
+	serviceProcessesMutex.Unlock()
+
+	service.CPUPercent = 0
+	service.MemoryUsage = 0
+	service.MemoryPercent = 0
+	service.DiskUsage = 0
+	service.NetworkRx = 0
+	service.NetworkTx = 0
+}
+
+// applyResourceMetrics sets a service's CPU, memory and I/O metrics from the
+// sampled usage of its process tree
+func (sm *Manager) applyResourceMetrics(service *models.Service, usage processUsage) error {
+	if service.PID <= 0 {
+		// Reset metrics for stopped services
+		resetResourceMetrics(service)
+		return nil
 	}
 
-	log.Printf("[DEBUG] Collected metrics for %s - CPU: %.2f%%, Memory: %d bytes (%.2f%%)",
-		service.Name, service.CPUPercent, service.MemoryUsage, service.MemoryPercent)
+	if !usage.alive {
+		log.Printf("[DEBUG] Process %d for service %s is no longer running", service.PID, service.Name)
+		// Reset metrics for stopped processes
+		resetResourceMetrics(service)
+		return fmt.Errorf("process no longer running")
+	}
+
+	service.CPUPercent = usage.cpuPercent
+	service.MemoryUsage = usage.rss // Resident Set Size (physical memory)
+	service.MemoryPercent = usage.memoryPercent
+	recordObservedMemory(service.ID, usage.rss)
+
+	service.DiskUsage = usage.ioBytes
+	// Collect network statistics using I/O counters as a proxy
+	service.NetworkRx = usage.readCount
+	service.NetworkTx = usage.writeCount
+
+	serviceProcessesMutex.Lock()
+	serviceProcesses[service.ID] = usage.breakdown
+	serviceProcessesMutex.Unlock()
+
+	log.Printf("[DEBUG] Collected metrics for %s - CPU: %.2f%%, Memory: %d bytes (%.2f%%) across %d processes",
+		service.Name, service.CPUPercent, service.MemoryUsage, service.MemoryPercent, usage.processes)
 
 	return nil
 }
 
 // startMetricsCollection starts periodic resource monitoring for all services
-func (sm *Manager) startMetricsCollection() {
+func (sm *Manager) startMetricsCollection(ctx context.Context) {
 	ticker := time.NewTicker(10 * time.Second) // Collect metrics every 10 seconds
 	defer ticker.Stop()
 
@@ -99,6 +256,8 @@ func (sm *Manager) startMetricsCollection() {
 
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-ticker.C:
 			sm.collectAllServiceMetrics()
 		}
@@ -114,10 +273,29 @@ func (sm *Manager) collectAllServiceMetrics() {
 	}
 	sm.mutex.RUnlock()
 
+	// Sample every running service's processes in one pass
+	var roots []int32
+	for _, service := range services {
+		service.Mutex.RLock()
+		if service.ArchivedAt == nil && service.Status == "running" && service.PID > 0 {
+			roots = append(roots, int32(service.PID))
+		}
+		service.Mutex.RUnlock()
+	}
+	usage, err := sampleProcessTrees(sm.ctx, roots)
+	if err != nil {
+		log.Printf("[WARN] Failed to collect resource metrics: %v", err)
+		return
+	}
+
 	for _, service := range services {
 		service.Mutex.Lock()
+		if service.ArchivedAt != nil {
+			service.Mutex.Unlock()
+			continue
+		}
 		if service.Status == "running" && service.PID > 0 {
-			if err := sm.collectResourceMetrics(service); err != nil {
+			if err := sm.applyResourceMetrics(service, usage[int32(service.PID)]); err != nil {
 				// If metrics collection fails, the process might have stopped
 				if !sm.isProcessRunning(service.PID) {
 					log.Printf("[INFO] Process %d for service %s stopped, updating status", service.PID, service.Name)
@@ -129,22 +307,17 @@ func (sm *Manager) collectAllServiceMetrics() {
 
 					// Record uptime event
 					uptimeTracker := GetUptimeTracker()
-					uptimeTracker.RecordEvent(service.ID, "stop", "stopped")
+					uptimeTracker.RecordEvent(service.ProfileID, service.ID, "stop", "stopped")
 
 					// Reset metrics
-					service.CPUPercent = 0
-					service.MemoryUsage = 0
-					service.MemoryPercent = 0
-					service.DiskUsage = 0
-					service.NetworkRx = 0
-					service.NetworkTx = 0
+					resetResourceMetrics(service)
 					sm.updateServiceInDB(service)
 					sm.broadcastUpdate(service)
 				}
 			} else {
 				// Successful metrics collection, update uptime stats and broadcast update
 				uptimeTracker := GetUptimeTracker()
-				service.Metrics.UptimeStats = uptimeTracker.CalculateUptimeStats(service.ID, service)
+				service.Metrics.UptimeStats = uptimeTracker.CalculateUptimeStats(service.ProfileID, service.ID)
 				sm.broadcastUpdate(service)
 			}
 		}
@@ -161,8 +334,9 @@ func (sm *Manager) collectPerformanceMetrics(service *models.Service) error {
 	start := time.Now()
 
 	// Perform HTTP request to health endpoint
-	client := sm.createHealthCheckClient()
-	req, err := sm.createHealthCheckRequest(service.HealthURL)
+	config := sm.healthCheckConfig(service.ID)
+	client := sm.createHealthCheckClient(config)
+	req, err := sm.createHealthCheckRequest(sm.ctx, service.HealthURL, config)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,218 @@
+// Package services - Runnable modules of Maven and Gradle multi-module builds
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	// An include of settings.gradle(.kts), with its project paths
+	gradleIncludeRegex = regexp.MustCompile(`(?m)^\s*include\b\s*\(?((?:[^\n]*,[ \t]*\n)*[^\n]*)`)
+	gradleProjectRegex = regexp.MustCompile(`['"]([^'"]+)['"]`)
+
+	// The Spring Boot plugin applied in a build.gradle(.kts)
+	gradleSpringBootPluginRegex = regexp.MustCompile(`id\s*\(?\s*['"]org\.springframework\.boot['"]|apply\s+plugin:\s*['"]org\.springframework\.boot['"]`)
+)
+
+// discoverModules returns the runnable modules of a multi-module Maven or
+// Gradle build rooted at dir, and whether dir is the root of one. The modules
+// are discovered as services of the repository, built and run from it, so
+// the scan does not descend into them again.
+func (ads *AutoDiscoveryService) discoverModules(dir, scanDir string) ([]DiscoveredService, bool) {
+	relativePath, err := filepath.Rel(scanDir, dir)
+	if err != nil {
+		return nil, false
+	}
+	// Handle the case where the repository is the scan directory itself
+	if relativePath == "." {
+		relativePath = filepath.Base(dir)
+	}
+
+	if pom, err := readMavenPOM(filepath.Join(dir, "pom.xml")); err == nil && len(pom.Modules.Module) > 0 {
+		modules := ads.discoverMavenModules(dir, relativePath, "", pom)
+		log.Printf("[INFO] Discovered Maven multi-module build at %s with %d runnable modules", relativePath, len(modules))
+		return modules, true
+	}
+
+	for _, settings := range []string{"settings.gradle", "settings.gradle.kts"} {
+		content, err := os.ReadFile(filepath.Join(dir, settings))
+		if err != nil {
+			continue
+		}
+		projects := parseGradleIncludes(string(content))
+		if len(projects) == 0 {
+			continue
+		}
+		modules := ads.discoverGradleModules(dir, scanDir, relativePath, projects)
+		log.Printf("[INFO] Discovered Gradle multi-module build at %s with %d runnable modules", relativePath, len(modules))
+		return modules, true
+	}
+
+	return nil, false
+}
+
+// discoverMavenModules walks the modules of an aggregator pom, including the
+// modules of nested aggregators. A module is runnable when it applies the
+// spring-boot-maven-plugin.
+func (ads *AutoDiscoveryService) discoverMavenModules(repoDir, relativePath, parentModule string, parent MavenPOM) []DiscoveredService {
+	mvn := "mvn"
+	if fileExists(filepath.Join(repoDir, "mvnw")) {
+		mvn = "./mvnw"
+	}
+
+	var modules []DiscoveredService
+	for _, entry := range parent.Modules.Module {
+		module := path.Join(parentModule, strings.TrimSpace(entry))
+		pomPath := filepath.Join(repoDir, filepath.FromSlash(module), "pom.xml")
+		// A module may also name its pom file instead of its directory
+		if strings.HasSuffix(module, ".xml") {
+			pomPath = filepath.Join(repoDir, filepath.FromSlash(module))
+			module = path.Dir(module)
+		}
+
+		pom, err := readMavenPOM(pomPath)
+		if err != nil {
+			log.Printf("[WARN] Skipping Maven module %s of %s: %v", module, relativePath, err)
+			continue
+		}
+		if len(pom.Modules.Module) > 0 {
+			modules = append(modules, ads.discoverMavenModules(repoDir, relativePath, module, pom)...)
+			continue
+		}
+		if pom.Packaging == "pom" || !hasSpringBootMavenPlugin(pom) {
+			continue
+		}
+
+		service := ads.newModuleService(repoDir, relativePath, module, ads.generateServiceName(pom.ArtifactID, module), "Spring Boot")
+		service.Description = pom.Description
+		service.Properties["groupId"] = pom.GroupID
+		service.Properties["artifactId"] = pom.ArtifactID
+		service.Properties["version"] = pom.Version
+		service.Properties["name"] = pom.Name
+		service.Type = ads.determineServiceType(pom, service.Name)
+		// Siblings the module depends on are installed first, since Maven
+		// resolves them from the local repository when running one module
+		service.BuildCommand = fmt.Sprintf("cd {serviceDir} && %s -pl {module} -am install -DskipTests", mvn)
+		service.StartCommand = fmt.Sprintf(`cd {serviceDir} && %s -pl {module} spring-boot:run -Dspring-boot.run.jvmArguments="{javaOpts}"`, mvn)
+		modules = append(modules, *service)
+	}
+	return modules
+}
+
+// discoverGradleModules checks the included projects of a Gradle build. A
+// project is runnable when it applies the org.springframework.boot plugin;
+// so is the root project, which is then discovered as a service of its own.
+func (ads *AutoDiscoveryService) discoverGradleModules(repoDir, scanDir, relativePath string, projects []string) []DiscoveredService {
+	gradle := "gradle"
+	if fileExists(filepath.Join(repoDir, "gradlew")) {
+		gradle = "./gradlew"
+	}
+
+	var modules []DiscoveredService
+	for _, buildFile := range []string{"build.gradle", "build.gradle.kts"} {
+		if buildPath := filepath.Join(repoDir, buildFile); appliesSpringBootGradlePlugin(buildPath) {
+			if service, err := ads.analyzeGradleProjectWithScanDir(buildPath, scanDir); err == nil && service != nil {
+				modules = append(modules, *service)
+			}
+			break
+		}
+	}
+
+	for _, project := range projects {
+		module := strings.ReplaceAll(strings.Trim(project, ":"), ":", "/")
+		moduleDir := filepath.Join(repoDir, filepath.FromSlash(module))
+		if !appliesSpringBootGradlePlugin(filepath.Join(moduleDir, "build.gradle")) &&
+			!appliesSpringBootGradlePlugin(filepath.Join(moduleDir, "build.gradle.kts")) {
+			continue
+		}
+
+		task := ":" + strings.Trim(project, ":")
+		service := ads.newModuleService(repoDir, relativePath, module, path.Base(module), "Spring Boot (Gradle)")
+		service.Properties["projectName"] = path.Base(module)
+		service.Properties["gradleProject"] = task
+		service.Type = ads.determineServiceType(MavenPOM{ArtifactID: service.Name}, service.Name)
+		// Gradle builds the projects a module depends on by itself
+		service.BuildCommand = fmt.Sprintf("cd {serviceDir} && %s %s:bootJar", gradle, task)
+		service.StartCommand = fmt.Sprintf(`cd {serviceDir} && %s %s:bootRun --args="{javaOpts}"`, gradle, task)
+		modules = append(modules, *service)
+	}
+	return modules
+}
+
+// newModuleService describes a runnable module: the service's directory is
+// the repository, and the module its working directory
+func (ads *AutoDiscoveryService) newModuleService(repoDir, relativePath, module, name, framework string) *DiscoveredService {
+	service := &DiscoveredService{
+		Name:       name,
+		Path:       relativePath,
+		Module:     module,
+		Type:       "microservice",
+		Framework:  framework,
+		Properties: make(map[string]string),
+		IsValid:    true,
+	}
+
+	var err error
+	moduleDir := filepath.Join(repoDir, filepath.FromSlash(module))
+	service.Port, err = ads.extractPortFromProject(moduleDir)
+	if err != nil {
+		log.Printf("[DEBUG] No port found for module %s of %s, using default: %v", module, relativePath, err)
+		service.Port = 8080 // Fallback to default
+	}
+
+	log.Printf("[INFO] Discovered %s module: %s at %s/%s (port: %d)", framework, service.Name, relativePath, module, service.Port)
+	return service
+}
+
+// readMavenPOM reads and parses a pom.xml
+func readMavenPOM(pomPath string) (MavenPOM, error) {
+	var pom MavenPOM
+	content, err := os.ReadFile(pomPath)
+	if err != nil {
+		return pom, fmt.Errorf("failed to read %s: %w", filepath.Base(pomPath), err)
+	}
+	if err := xml.Unmarshal(content, &pom); err != nil {
+		return pom, fmt.Errorf("failed to parse %s: %w", filepath.Base(pomPath), err)
+	}
+	return pom, nil
+}
+
+// hasSpringBootMavenPlugin reports whether a pom applies the Spring Boot
+// plugin, which makes its module runnable
+func hasSpringBootMavenPlugin(pom MavenPOM) bool {
+	for _, plugin := range pom.Build.Plugins.Plugin {
+		if plugin.ArtifactID == "spring-boot-maven-plugin" {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesSpringBootGradlePlugin reports whether a Gradle build file applies
+// the Spring Boot plugin
+func appliesSpringBootGradlePlugin(buildPath string) bool {
+	content, err := os.ReadFile(buildPath)
+	if err != nil {
+		return false
+	}
+	return gradleSpringBootPluginRegex.Match(content)
+}
+
+// parseGradleIncludes returns the project paths included by a settings.gradle
+// or settings.gradle.kts, such as ":payments:api"
+func parseGradleIncludes(content string) []string {
+	var projects []string
+	for _, include := range gradleIncludeRegex.FindAllStringSubmatch(content, -1) {
+		for _, project := range gradleProjectRegex.FindAllStringSubmatch(include[1], -1) {
+			projects = append(projects, project[1])
+		}
+	}
+	return projects
+}
@@ -0,0 +1,158 @@
+// Package services - Exposing services under their own nginx location
+package services
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/installer"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+var (
+	nginxLocationPathPattern = regexp.MustCompile(`^/[A-Za-z0-9._~/-]*/$`)
+	nginxLocationNameChars   = regexp.MustCompile(`[^a-z0-9._-]+`)
+)
+
+// Path prefixes the Vertex UI and API are served under
+var reservedNginxLocationPrefixes = []string{"/api/", "/ws/"}
+
+// defaultNginxLocationPath returns /svc/<name>/ for a service
+func defaultNginxLocationPath(serviceName string) string {
+	name := strings.Trim(nginxLocationNameChars.ReplaceAllString(strings.ToLower(serviceName), "-"), "-")
+	return "/svc/" + name + "/"
+}
+
+// nginxOutputDir is where the service locations are saved for the installer
+func nginxOutputDir() string {
+	return filepath.Join(database.GetDataDir(), "nginx")
+}
+
+// GetNginxLocationConfig returns a service's nginx location settings,
+// defaulting to a disabled /svc/<name>/ prefix
+func (sm *Manager) GetNginxLocationConfig(serviceUUID string) (*models.NginxLocationConfig, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	configs, err := sm.db.GetNginxLocationConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	service.Mutex.RLock()
+	config := models.NginxLocationConfig{
+		ServiceID: serviceUUID,
+		Path:      defaultNginxLocationPath(service.Name),
+		Port:      service.Port,
+	}
+	service.Mutex.RUnlock()
+
+	for _, stored := range configs {
+		if stored.ServiceID == serviceUUID {
+			config.Enabled = stored.Enabled
+			config.Path = stored.Path
+			break
+		}
+	}
+	return &config, nil
+}
+
+// SetNginxLocationConfig validates and saves a service's nginx location
+// settings, then regenerates the service locations in vertex.conf
+func (sm *Manager) SetNginxLocationConfig(config models.NginxLocationConfig) (*installer.NginxLocationsResult, error) {
+	service, exists := sm.GetServiceByUUID(config.ServiceID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", config.ServiceID)
+	}
+
+	config.Path = strings.TrimSpace(config.Path)
+	if config.Path == "" {
+		service.Mutex.RLock()
+		config.Path = defaultNginxLocationPath(service.Name)
+		service.Mutex.RUnlock()
+	}
+	if !strings.HasSuffix(config.Path, "/") {
+		config.Path += "/"
+	}
+	if !nginxLocationPathPattern.MatchString(config.Path) || strings.Contains(config.Path, "//") || config.Path == "/" {
+		return nil, fmt.Errorf("invalid path '%s' (use a prefix such as /svc/my-service/)", config.Path)
+	}
+	for _, reserved := range reservedNginxLocationPrefixes {
+		if strings.HasPrefix(config.Path, reserved) {
+			return nil, fmt.Errorf("path '%s' is reserved for Vertex itself", config.Path)
+		}
+	}
+
+	if config.Enabled {
+		service.Mutex.RLock()
+		port := service.Port
+		service.Mutex.RUnlock()
+		if port == 0 {
+			return nil, fmt.Errorf("service %s has no port to expose", service.Name)
+		}
+
+		configs, err := sm.db.GetNginxLocationConfigs()
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range configs {
+			if other.Enabled && other.ServiceID != config.ServiceID && other.Path == config.Path {
+				if _, exists := sm.GetServiceByUUID(other.ServiceID); exists {
+					return nil, fmt.Errorf("path '%s' is already used by another service", config.Path)
+				}
+			}
+		}
+	}
+
+	if err := sm.db.SaveNginxLocationConfig(config); err != nil {
+		return nil, err
+	}
+	return sm.ApplyNginxLocations()
+}
+
+// ApplyNginxLocations rewrites the enabled service locations, with the
+// services' current ports, into vertex.conf
+func (sm *Manager) ApplyNginxLocations() (*installer.NginxLocationsResult, error) {
+	configs, err := sm.db.GetNginxLocationConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []installer.NginxLocation{}
+	for _, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		service, exists := sm.GetServiceByUUID(config.ServiceID)
+		if !exists {
+			continue
+		}
+		service.Mutex.RLock()
+		location := installer.NginxLocation{Path: config.Path, Port: service.Port, ServiceName: service.Name}
+		service.Mutex.RUnlock()
+		if location.Port == 0 {
+			log.Printf("[WARN] Skipping nginx location %s: service %s has no port", location.Path, location.ServiceName)
+			continue
+		}
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Path < locations[j].Path })
+
+	result, err := installer.ApplyNginxLocations(nginxOutputDir(), locations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update nginx service locations: %w", err)
+	}
+	if result.Reloaded {
+		log.Printf("[INFO] Updated %d nginx service location(s) in %s and reloaded nginx", len(locations), result.ConfigFile)
+	} else {
+		log.Printf("[INFO] Updated %d nginx service location(s); to finish run: %s", len(locations), result.Instructions)
+	}
+	return result, nil
+}
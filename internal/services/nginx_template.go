@@ -0,0 +1,132 @@
+// Package services - The template vertex.conf is rendered from
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/installer"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const nginxTemplateCheckInterval = 10 * time.Second // How often edits of the template files are looked for
+
+var (
+	nginxTemplateMutex   sync.Mutex
+	appliedNginxTemplate *installer.NginxTemplate // The template vertex.conf was last rendered from
+	seenNginxTemplate    string                   // Content of the template files last looked at
+)
+
+// nginxTemplateContent identifies the content of a template and its
+// overrides, to tell when either file was edited
+func nginxTemplateContent(tmpl *installer.NginxTemplate) string {
+	overrides, _ := json.Marshal(tmpl.Overrides)
+	return tmpl.Template + "\x00" + string(overrides)
+}
+
+// GetNginxTemplate returns the template vertex.conf is rendered from and its
+// overrides
+func (sm *Manager) GetNginxTemplate() (*installer.NginxTemplate, error) {
+	return installer.LoadNginxTemplate(nginxOutputDir())
+}
+
+// SetNginxTemplate renders vertex.conf from a new template and overrides,
+// checks it with nginx -t and only then saves them and reloads nginx. An
+// empty template restores the built-in one. Only admins may change it.
+func (sm *Manager) SetNginxTemplate(tmpl installer.NginxTemplate, claims *models.JWTClaims) (*installer.NginxTemplateResult, error) {
+	if claims.Role != "admin" {
+		return nil, fmt.Errorf("only admins can change the nginx template")
+	}
+	if tmpl.Template == "" {
+		builtIn, err := installer.LoadNginxTemplate("")
+		if err != nil {
+			return nil, err
+		}
+		tmpl.Template = builtIn.Template
+	}
+
+	nginxTemplateMutex.Lock()
+	defer nginxTemplateMutex.Unlock()
+
+	result, err := installer.ApplyNginxTemplate(nginxOutputDir(), &tmpl, appliedNginxTemplate)
+	if err != nil {
+		return nil, err
+	}
+	appliedNginxTemplate = &tmpl
+	seenNginxTemplate = nginxTemplateContent(&tmpl)
+	logNginxTemplateResult(result, claims.Username)
+	return result, nil
+}
+
+func logNginxTemplateResult(result *installer.NginxTemplateResult, changedBy string) {
+	if result.Backup != "" {
+		log.Printf("[WARN] vertex.conf had edits the template does not make; kept them in %s", result.Backup)
+	}
+	switch {
+	case result.Reloaded:
+		log.Printf("[INFO] nginx template changed by %s; rendered %s and reloaded nginx", changedBy, result.ConfigFile)
+	case result.Instructions != "":
+		log.Printf("[INFO] nginx template changed by %s; to finish run: %s", changedBy, result.Instructions)
+	default:
+		log.Printf("[INFO] nginx template changed by %s", changedBy)
+	}
+}
+
+// startNginxTemplateWatcher re-renders vertex.conf when the template or
+// overrides file is edited by hand. An edit nginx rejects is logged and
+// leaves the installed configuration alone.
+func (sm *Manager) startNginxTemplateWatcher(ctx context.Context) {
+	if tmpl, err := installer.LoadNginxTemplate(nginxOutputDir()); err == nil {
+		nginxTemplateMutex.Lock()
+		appliedNginxTemplate = tmpl
+		seenNginxTemplate = nginxTemplateContent(tmpl)
+		nginxTemplateMutex.Unlock()
+	}
+
+	ticker := time.NewTicker(nginxTemplateCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sm.applyEditedNginxTemplate()
+		}
+	}
+}
+
+// applyEditedNginxTemplate renders vertex.conf if the template files changed
+// since they were last rendered
+func (sm *Manager) applyEditedNginxTemplate() {
+	outputDir := nginxOutputDir()
+	if site, err := installer.LoadNginxSite(outputDir); err != nil || site == nil {
+		return
+	}
+	tmpl, err := installer.LoadNginxTemplate(outputDir)
+	if err != nil {
+		log.Printf("[WARN] %v", err)
+		return
+	}
+
+	nginxTemplateMutex.Lock()
+	defer nginxTemplateMutex.Unlock()
+	content := nginxTemplateContent(tmpl)
+	if content == seenNginxTemplate {
+		return
+	}
+	// Not retried until the files change again
+	seenNginxTemplate = content
+
+	result, err := installer.ApplyNginxTemplate(outputDir, tmpl, appliedNginxTemplate)
+	if err != nil {
+		log.Printf("[ERROR] Not applying the edited nginx template: %v", err)
+		return
+	}
+	appliedNginxTemplate = tmpl
+	logNginxTemplateResult(result, "an edit of "+tmpl.Path)
+}
@@ -0,0 +1,486 @@
+// Package services - Email notifications users subscribe to
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Notification channel types
+const (
+	NotificationChannelEmail = "email"
+)
+
+// When a subscription's notifications are sent
+const (
+	NotificationModeImmediate = "immediate"
+	NotificationModeDigest    = "digest" // Collected and sent once an hour
+)
+
+// Events users can subscribe to; unhealthy is sent when a service stays
+// unhealthy for prolongedUnhealthyAfter
+const (
+	NotificationEventCrashed      = models.EventCrashed
+	NotificationEventCrashLooping = models.EventCrashLooping
+	NotificationEventUnhealthy    = "unhealthy"
+)
+
+const (
+	prolongedUnhealthyAfter    = 5 * time.Minute
+	notificationDigestInterval = time.Hour
+	smtpTimeout                = 15 * time.Second
+)
+
+var notificationEvents = []string{NotificationEventCrashed, NotificationEventCrashLooping, NotificationEventUnhealthy}
+
+// When each unhealthy service became unhealthy, for the prolonged-unhealthy
+// notification
+var (
+	unhealthySince      = make(map[string]time.Time)
+	unhealthySinceMutex sync.Mutex
+)
+
+// GetNotificationChannels returns the notification channels without their
+// SMTP passwords, each with the user's subscription to it
+func (sm *Manager) GetNotificationChannels(userID string) ([]models.NotificationChannel, error) {
+	channels, err := sm.db.GetNotificationChannels()
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := sm.db.GetNotificationSubscriptions(0, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range channels {
+		channels[i].HasSMTPPassword = channels[i].SMTPPassword != ""
+		channels[i].SMTPPassword = ""
+		for j := range subscriptions {
+			if subscriptions[j].ChannelID == channels[i].ID {
+				channels[i].Subscription = &subscriptions[j]
+			}
+		}
+	}
+	return channels, nil
+}
+
+// CreateNotificationChannel validates and stores a new notification channel
+func (sm *Manager) CreateNotificationChannel(channel models.NotificationChannel) (int64, error) {
+	if err := validateNotificationChannel(&channel, nil); err != nil {
+		return 0, err
+	}
+	return sm.db.CreateNotificationChannel(channel)
+}
+
+// UpdateNotificationChannel validates and replaces the settings of a
+// notification channel. An empty SMTP password keeps the stored one unless
+// hasSmtpPassword is false, which removes it.
+func (sm *Manager) UpdateNotificationChannel(channel models.NotificationChannel) error {
+	stored, err := sm.db.GetNotificationChannel(channel.ID)
+	if err != nil {
+		return err
+	}
+	if err := validateNotificationChannel(&channel, stored); err != nil {
+		return err
+	}
+	return sm.db.UpdateNotificationChannel(channel)
+}
+
+// DeleteNotificationChannel removes a notification channel and every
+// subscription to it
+func (sm *Manager) DeleteNotificationChannel(channelID int64) error {
+	return sm.db.DeleteNotificationChannel(channelID)
+}
+
+// validateNotificationChannel trims and checks a channel, filling in the
+// defaults and encrypting a new SMTP password
+func validateNotificationChannel(channel *models.NotificationChannel, stored *models.NotificationChannel) error {
+	channel.Name = strings.TrimSpace(channel.Name)
+	channel.Type = strings.ToLower(strings.TrimSpace(channel.Type))
+	channel.SMTPHost = strings.TrimSpace(channel.SMTPHost)
+	channel.SMTPUsername = strings.TrimSpace(channel.SMTPUsername)
+	channel.FromAddress = strings.TrimSpace(channel.FromAddress)
+
+	if channel.Name == "" {
+		return fmt.Errorf("channel name is required")
+	}
+	if channel.Type == "" {
+		channel.Type = NotificationChannelEmail
+	}
+	if channel.Type != NotificationChannelEmail {
+		return fmt.Errorf("unsupported channel type '%s' (only email is supported)", channel.Type)
+	}
+	if channel.SMTPHost == "" || strings.ContainsAny(channel.SMTPHost, " /:") {
+		return fmt.Errorf("invalid SMTP host '%s'", channel.SMTPHost)
+	}
+	if channel.SMTPPort == 0 {
+		channel.SMTPPort = 587
+		if channel.ImplicitTLS {
+			channel.SMTPPort = 465
+		}
+	}
+	if channel.SMTPPort < 1 || channel.SMTPPort > 65535 {
+		return fmt.Errorf("invalid SMTP port %d", channel.SMTPPort)
+	}
+	if _, err := mail.ParseAddress(channel.FromAddress); err != nil {
+		return fmt.Errorf("invalid from address '%s'", channel.FromAddress)
+	}
+
+	if channel.SMTPPassword == "" {
+		if stored != nil && channel.HasSMTPPassword {
+			channel.SMTPPassword = stored.SMTPPassword
+		}
+	} else {
+		encrypted, err := encryptRepositorySecret(channel.SMTPPassword)
+		if err != nil {
+			return err
+		}
+		channel.SMTPPassword = encrypted
+	}
+	return nil
+}
+
+// SetNotificationSubscription validates and saves a user's subscription to a
+// channel. The address defaults to the user's own email.
+func (sm *Manager) SetNotificationSubscription(subscription models.NotificationSubscription, userEmail string) (*models.NotificationSubscription, error) {
+	if _, err := sm.db.GetNotificationChannel(subscription.ChannelID); err != nil {
+		return nil, err
+	}
+
+	subscription.Email = strings.TrimSpace(subscription.Email)
+	if subscription.Email == "" {
+		subscription.Email = userEmail
+	}
+	if address, err := mail.ParseAddress(subscription.Email); err != nil || address.Address != subscription.Email {
+		return nil, fmt.Errorf("invalid email address '%s'", subscription.Email)
+	}
+
+	subscription.Mode = strings.ToLower(strings.TrimSpace(subscription.Mode))
+	if subscription.Mode == "" {
+		subscription.Mode = NotificationModeImmediate
+	}
+	if subscription.Mode != NotificationModeImmediate && subscription.Mode != NotificationModeDigest {
+		return nil, fmt.Errorf("invalid mode '%s' (use immediate or digest)", subscription.Mode)
+	}
+
+	events := []string{}
+	for _, event := range subscription.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !slices.Contains(notificationEvents, event) {
+			return nil, fmt.Errorf("invalid event '%s' (use one of %s)", event, strings.Join(notificationEvents, ", "))
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("subscribe to at least one event (%s)", strings.Join(notificationEvents, ", "))
+	}
+	subscription.Events = events
+
+	if err := sm.db.SaveNotificationSubscription(subscription); err != nil {
+		return nil, err
+	}
+	subscription.UpdatedAt = time.Now()
+	return &subscription, nil
+}
+
+// DeleteNotificationSubscription unsubscribes a user from a channel
+func (sm *Manager) DeleteNotificationSubscription(channelID int64, userID string) error {
+	removed, err := sm.db.DeleteNotificationSubscription(channelID, userID)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("subscription to notification channel %d not found", channelID)
+	}
+	return nil
+}
+
+// SendTestNotification sends a test email through a channel
+func (sm *Manager) SendTestNotification(channelID int64, to string) error {
+	channel, err := sm.db.GetNotificationChannel(channelID)
+	if err != nil {
+		return err
+	}
+	if address, err := mail.ParseAddress(to); err != nil || address.Address != to {
+		return fmt.Errorf("invalid email address '%s'", to)
+	}
+
+	body := fmt.Sprintf("This is a test notification from Vertex sent through the %s channel.\n", channel.Name)
+	return sendNotificationEmail(sm.ctx, channel, to, "[Vertex] Test notification", body)
+}
+
+// notifySubscribers emails the subscribers of a failing service's event in
+// the background. Unhealthy services are only reported once they stay
+// unhealthy for prolongedUnhealthyAfter. Like alertServiceOwner it reads the
+// service, so the caller must hold its mutex or otherwise own it.
+func (sm *Manager) notifySubscribers(service *models.Service, event models.ServiceEvent) {
+	notification := models.PendingNotification{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		Event:       event.Type,
+		Message:     event.Message,
+		Timestamp:   event.Timestamp,
+	}
+
+	switch event.Type {
+	case models.EventCrashed, models.EventCrashLooping, models.EventSLOViolated:
+		go sm.deliverNotification(notification)
+	case models.EventHealthFlapping:
+		// Its unhealthy spells are no longer continuous; the settling health
+		// change starts a new one
+		unhealthySinceMutex.Lock()
+		delete(unhealthySince, service.ID)
+		unhealthySinceMutex.Unlock()
+	case models.EventHealthChanged:
+		unhealthySinceMutex.Lock()
+		defer unhealthySinceMutex.Unlock()
+		if service.HealthStatus != "unhealthy" {
+			delete(unhealthySince, service.ID)
+			return
+		}
+		unhealthySince[service.ID] = event.Timestamp
+		time.AfterFunc(prolongedUnhealthyAfter, func() {
+			sm.notifyProlongedUnhealthy(service, event.Timestamp, notification)
+		})
+	}
+}
+
+// notifyProlongedUnhealthy sends the unhealthy notification when the service
+// is still in the unhealthy spell that started at since
+func (sm *Manager) notifyProlongedUnhealthy(service *models.Service, since time.Time, notification models.PendingNotification) {
+	unhealthySinceMutex.Lock()
+	current, exists := unhealthySince[service.ID]
+	if !exists || !current.Equal(since) {
+		unhealthySinceMutex.Unlock()
+		return
+	}
+	delete(unhealthySince, service.ID)
+	unhealthySinceMutex.Unlock()
+
+	service.Mutex.RLock()
+	stillUnhealthy := service.HealthStatus == "unhealthy" && service.Status == "running"
+	service.Mutex.RUnlock()
+	if !stillUnhealthy || sm.ctx.Err() != nil {
+		return
+	}
+
+	notification.Event = NotificationEventUnhealthy
+	notification.Message = fmt.Sprintf("Unhealthy for more than %d minutes, since %s", int(prolongedUnhealthyAfter.Minutes()), since.UTC().Format("15:04 UTC"))
+	notification.Timestamp = time.Now()
+	sm.deliverNotification(notification)
+}
+
+// deliverNotification emails the notification to the immediate subscribers
+// of its event and queues it for the digest subscribers
+func (sm *Manager) deliverNotification(notification models.PendingNotification) {
+	settings, err := sm.db.GetAlertSettings()
+	if err != nil {
+		log.Printf("[WARN] Failed to load alert settings: %v", err)
+		return
+	}
+	if !claimAlertSlot("notify|"+notification.ServiceID+"|"+notification.Event, time.Duration(settings.Cooldown)*time.Second) {
+		return
+	}
+
+	channels, err := sm.db.GetNotificationChannels()
+	if err != nil {
+		log.Printf("[WARN] Failed to load notification channels: %v", err)
+		return
+	}
+	for i := range channels {
+		channel := &channels[i]
+		if !channel.Enabled {
+			continue
+		}
+		subscriptions, err := sm.db.GetNotificationSubscriptions(channel.ID, "")
+		if err != nil {
+			log.Printf("[WARN] Failed to load subscriptions of notification channel %s: %v", channel.Name, err)
+			continue
+		}
+
+		for _, subscription := range subscriptions {
+			if !slices.Contains(subscription.Events, notification.Event) {
+				continue
+			}
+			if subscription.Mode == NotificationModeDigest {
+				pending := notification
+				pending.ChannelID = channel.ID
+				pending.UserID = subscription.UserID
+				if err := sm.db.InsertPendingNotification(pending); err != nil {
+					log.Printf("[WARN] %v", err)
+				}
+				continue
+			}
+
+			subject := fmt.Sprintf("[Vertex] %s %s", notification.ServiceName, notification.Event)
+			body := formatNotificationLine(notification) + "\n\n" + notificationFooter(channel)
+			if err := sendNotificationEmail(sm.ctx, channel, subscription.Email, subject, body); err != nil {
+				log.Printf("[WARN] Failed to email %s notification for service %s to %s: %v", notification.Event, notification.ServiceName, subscription.Email, err)
+				continue
+			}
+			log.Printf("[INFO] Emailed %s notification for service %s to %s", notification.Event, notification.ServiceName, subscription.Email)
+		}
+	}
+}
+
+// startNotificationDigest sends the hourly digests of the subscriptions in
+// digest mode
+func (sm *Manager) startNotificationDigest(ctx context.Context) {
+	ticker := time.NewTicker(notificationDigestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sm.sendNotificationDigests()
+		}
+	}
+}
+
+// sendNotificationDigests emails every subscription its pending
+// notifications in one message. Notifications whose digest cannot be sent
+// stay queued for the next hour.
+func (sm *Manager) sendNotificationDigests() {
+	pending, err := sm.db.GetPendingNotifications()
+	if err != nil {
+		log.Printf("[WARN] Failed to load pending notifications: %v", err)
+		return
+	}
+
+	for start := 0; start < len(pending); {
+		end := start
+		for end < len(pending) && pending[end].ChannelID == pending[start].ChannelID && pending[end].UserID == pending[start].UserID {
+			end++
+		}
+		batch := pending[start:end]
+		start = end
+
+		channelID, userID := batch[0].ChannelID, batch[0].UserID
+		channel, err := sm.db.GetNotificationChannel(channelID)
+		if err != nil {
+			log.Printf("[WARN] Dropping digest for a missing notification channel: %v", err)
+			sm.db.DeletePendingNotifications(channelID, userID, batch[len(batch)-1].ID)
+			continue
+		}
+		if !channel.Enabled {
+			continue
+		}
+		subscriptions, err := sm.db.GetNotificationSubscriptions(channelID, userID)
+		if err != nil || len(subscriptions) == 0 {
+			continue
+		}
+
+		var body strings.Builder
+		for _, notification := range batch {
+			body.WriteString(formatNotificationLine(notification) + "\n")
+		}
+		body.WriteString("\n" + notificationFooter(channel))
+
+		subject := fmt.Sprintf("[Vertex] %d notification(s) in the last hour", len(batch))
+		if err := sendNotificationEmail(sm.ctx, channel, subscriptions[0].Email, subject, body.String()); err != nil {
+			log.Printf("[WARN] Failed to email notification digest to %s: %v", subscriptions[0].Email, err)
+			continue
+		}
+		if err := sm.db.DeletePendingNotifications(channelID, userID, batch[len(batch)-1].ID); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+		log.Printf("[INFO] Emailed a digest of %d notification(s) to %s", len(batch), subscriptions[0].Email)
+	}
+}
+
+func formatNotificationLine(notification models.PendingNotification) string {
+	return fmt.Sprintf("%s  %s %s: %s", notification.Timestamp.UTC().Format("2006-01-02 15:04 UTC"), notification.ServiceName, notification.Event, notification.Message)
+}
+
+func notificationFooter(channel *models.NotificationChannel) string {
+	return fmt.Sprintf("You receive this because you subscribed to the %s notifications of Vertex.\n", channel.Name)
+}
+
+// sendNotificationEmail sends a plain text email through a channel's SMTP
+// server, upgrading to TLS with STARTTLS when the server offers it
+func sendNotificationEmail(ctx context.Context, channel *models.NotificationChannel, to, subject, body string) error {
+	password := ""
+	if channel.SMTPPassword != "" {
+		decrypted, err := decryptRepositorySecret(channel.SMTPPassword)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt SMTP password: %w", err)
+		}
+		password = decrypted
+	}
+	from, err := mail.ParseAddress(channel.FromAddress)
+	if err != nil {
+		return fmt.Errorf("invalid from address '%s'", channel.FromAddress)
+	}
+
+	address := net.JoinHostPort(channel.SMTPHost, strconv.Itoa(channel.SMTPPort))
+	tlsConfig := &tls.Config{ServerName: channel.SMTPHost}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	if channel.ImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, channel.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet %s: %w", address, err)
+	}
+	defer client.Close()
+
+	if !channel.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("STARTTLS failed: %w", err)
+			}
+		}
+	}
+	if channel.SMTPUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", channel.SMTPUsername, password, channel.SMTPHost)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	subject = strings.Join(strings.Fields(subject), " ")
+	message := fmt.Sprintf("From: %s\nTo: %s\nSubject: %s\nDate: %s\nMIME-Version: 1.0\nContent-Type: text/plain; charset=UTF-8\n\n%s",
+		from.String(), to, mime.QEncoding.Encode("utf-8", subject), time.Now().Format(time.RFC1123Z), body)
+	if _, err := writer.Write([]byte(message)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
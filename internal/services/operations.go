@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
@@ -23,7 +22,7 @@ var logLevelRegex = regexp.MustCompile(`(?i)(INFO|WARN|ERROR|DEBUG|TRACE)`)
 
 // WaitForServiceReady waits for a service to be fully running and healthy
 func (sm *Manager) WaitForServiceReady(serviceName string, timeout time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(sm.ctx, timeout)
 	defer cancel()
 
 	ticker := time.NewTicker(1 * time.Second) // Check every second for faster detection
@@ -32,7 +31,9 @@ func (sm *Manager) WaitForServiceReady(serviceName string, timeout time.Duration
 	log.Printf("[INFO] Waiting for service %s to be ready...", serviceName)
 
 	// Add a small initial delay to let the service start up
-	time.Sleep(2 * time.Second)
+	if !sleepContext(ctx, 2*time.Second) {
+		return fmt.Errorf("stopped waiting for service %s: %w", serviceName, ctx.Err())
+	}
 
 	for {
 		sm.mutex.RLock()
@@ -107,6 +108,10 @@ func (sm *Manager) StartAllServices() error {
 				log.Printf("[WARN] Service %s not found, skipping", serviceName)
 				continue
 			}
+			if isArchived(service) {
+				log.Printf("[INFO] Service %s is archived, skipping", serviceName)
+				continue
+			}
 
 			service.Mutex.RLock()
 			status := service.Status
@@ -119,6 +124,9 @@ func (sm *Manager) StartAllServices() error {
 				// Start the service
 				if err := sm.StartService(serviceName); err != nil {
 					log.Printf("[ERROR] Failed to start service %s: %v", serviceName, err)
+					if sm.ctx.Err() != nil {
+						return
+					}
 					continue
 				}
 
@@ -138,6 +146,9 @@ func (sm *Manager) StartAllServices() error {
 					timeout = 2 * time.Minute // Default for other services
 				}
 				if err := sm.WaitForServiceReady(serviceName, timeout); err != nil {
+					if sm.ctx.Err() != nil {
+						return
+					}
 					log.Printf("[ERROR] Service %s did not become ready within timeout: %v", serviceName, err)
 					log.Printf("[WARN] Continuing with next service despite %s not being ready", serviceName)
 					continue
@@ -175,12 +186,17 @@ func (sm *Manager) StopAllServices() error {
 			status := service.Status
 			service.Mutex.RUnlock()
 
-			if status == "running" {
-				if err := sm.stopService(service); err != nil {
+			if status == "running" || status == StatusPaused {
+				if err := sm.stopServiceOp(sm.ctx, service); err != nil {
 					log.Printf("Failed to stop service %s: %v", service.Name, err)
+					if sm.ctx.Err() != nil {
+						return
+					}
 					continue
 				}
-				time.Sleep(1 * time.Second) // Brief wait between stops
+				if !sleepContext(sm.ctx, 1*time.Second) { // Brief wait between stops
+					return
+				}
 			}
 		}
 	}()
@@ -223,16 +239,25 @@ func (sm *Manager) StopAllServicesForProfile(profileServicesJson string) error {
 
 	go func() {
 		for _, service := range profileServices {
+			if isArchived(service) {
+				log.Printf("[INFO] Service %s (order %d) is archived, skipping", service.Name, service.Order)
+				continue
+			}
 			service.Mutex.RLock()
 			status := service.Status
 			service.Mutex.RUnlock()
 
-			if status == "running" {
-				if err := sm.stopService(service); err != nil {
+			if status == "running" || status == StatusPaused {
+				if err := sm.stopServiceOp(sm.ctx, service); err != nil {
 					log.Printf("Failed to stop service %s (profile): %v", service.Name, err)
+					if sm.ctx.Err() != nil {
+						return
+					}
 					continue
 				}
-				time.Sleep(1 * time.Second) // Brief wait between stops
+				if !sleepContext(sm.ctx, 1*time.Second) { // Brief wait between stops
+					return
+				}
 			}
 		}
 	}()
@@ -268,75 +293,50 @@ func (sm *Manager) StartAllServicesForProfile(profileServicesJSON string, projec
 
 	log.Printf("[INFO] Found %d services in profile to start", len(profileServices))
 
-	// Sort by order (start in dependency order)
-	sort.Slice(profileServices, func(i, j int) bool {
-		return profileServices[i].Order < profileServices[j].Order
-	})
-
-	go func() {
-		for _, service := range profileServices {
-			service.Mutex.RLock()
-			status := service.Status
-			isEnabled := service.IsEnabled
-			service.Mutex.RUnlock()
-
-			if status != "running" && isEnabled {
-				log.Printf("[INFO] Starting service %s (order %d) in profile", service.Name, service.Order)
-				// Use profile-aware starting if projectsDir is different from global
-				globalConfig := sm.GetConfig()
-				if projectsDir != globalConfig.ProjectsDir {
-					if err := sm.startServiceWithProjectsDir(service, projectsDir); err != nil {
-						log.Printf("Failed to start service %s (profile): %v", service.Name, err)
-						continue
-					}
-				} else {
-					if err := sm.startService(service); err != nil {
-						log.Printf("Failed to start service %s (profile): %v", service.Name, err)
-						continue
-					}
-				}
-				time.Sleep(2 * time.Second) // Brief wait between starts
-			} else if status == "running" {
-				log.Printf("[INFO] Service %s (order %d) is already running, skipping", service.Name, service.Order)
-			} else {
-				log.Printf("[INFO] Service %s (order %d) is disabled, skipping", service.Name, service.Order)
-			}
-		}
-	}()
+	// Each service starts once its dependencies are ready, waiting for each
+	// with the dependency's own timeout and retry interval
+	go sm.startServicesInDependencyOrder(sm.ctx, profileServices, projectsDir)
 
 	return nil
 }
 
-func (sm *Manager) startServiceWithProjectsDir(service *models.Service, projectsDir string) error {
-	service.Mutex.Lock()
-	defer service.Mutex.Unlock()
-
-	if service.Status == "running" {
+// startServiceWithProjectsDir starts a service from the given projects
+// directory. It runs on the service's actor, so nothing else starts or stops
+// the service meanwhile; the slow preparation (wrapper generation, port
+// cleanup, dependency checks, pre-start hooks) therefore runs without holding
+// the service mutex and is abandoned when ctx is cancelled.
+func (sm *Manager) startServiceWithProjectsDir(ctx context.Context, service *models.Service, projectsDir string) error {
+	if isArchived(service) {
+		return fmt.Errorf("service %s is archived; unarchive it to start it", service.Name)
+	}
+
+	if err := sm.admitServiceStart(service); err != nil {
+		return err
+	}
+
+	service.Mutex.RLock()
+	status := service.Status
+	serviceDir := serviceWorkingDir(projectsDir, service)
+	overrides := serviceCommandOverrides(projectsDir, service)
+	buildSystem := service.BuildSystem
+	javaOpts := service.JavaOpts
+	javaOptsPreset := service.JavaOptsPreset
+	extraEnv := service.ExtraEnv
+	verboseLogging := service.VerboseLogging
+	executionMode := service.ExecutionMode
+	autoMigrate := service.AutoMigrate
+	port := service.Port
+	infraType := service.InfraType
+	service.Mutex.RUnlock()
+
+	if status == "running" {
 		return fmt.Errorf("service %s is already running", service.Name)
 	}
-
-	serviceDir := filepath.Join(projectsDir, service.Dir)
-	if _, err := os.Stat(serviceDir); os.IsNotExist(err) {
-		return fmt.Errorf("service directory does not exist: %s", serviceDir)
-	}
-
-	log.Printf("[INFO] Starting service %s from directory: %s", service.Name, serviceDir)
-
-	// Ensure vertex user has access to the profile's project directory
-	if err := EnsureVertexUserProjectAccess(projectsDir); err != nil {
-		log.Printf("[WARN] Failed to setup project directory access for service %s: %v", service.Name, err)
-		// Continue with startup - this shouldn't block service startup
+	if status == StatusPaused {
+		return fmt.Errorf("service %s is paused; resume it instead", service.Name)
 	}
-
-	// Also ensure the specific service's build directory exists with proper permissions
-	if err := ensureServiceBuildDirectory(serviceDir); err != nil {
-		log.Printf("[WARN] Failed to setup build directory for service %s: %v", service.Name, err)
-	}
-
-	// Check and fix Lombok compatibility before starting the service
-	if err := sm.checkAndFixLombokCompatibility(serviceDir, service.Name); err != nil {
-		log.Printf("[WARN] Lombok compatibility check failed for service %s: %v", service.Name, err)
-		// Continue with startup
+	if status == StatusCrashLooping {
+		return fmt.Errorf("service %s is crash-looping; reset it before starting again", service.Name)
 	}
 
 	// Get global environment variables
@@ -346,418 +346,283 @@ func (sm *Manager) startServiceWithProjectsDir(service *models.Service, projects
 		globalEnvVars = make(map[string]string)
 	}
 
-	// Auto-detect build system
-	effectiveBuildSystem := GetEffectiveBuildSystem(serviceDir, service.BuildSystem)
-	log.Printf("[INFO] Using build system '%s' for service %s", effectiveBuildSystem, service.Name)
-
-	// Regenerate Maven wrapper only when ./mvnw and local mvn report different versions.
-	// If they already match the wrapper is correct and should not be touched.
-	if effectiveBuildSystem == BuildSystemMaven && !MavenVersionsMatch(serviceDir) {
-		if err := GenerateMavenWrapper(serviceDir); err != nil {
-			log.Printf("[WARN] Failed to update Maven wrapper for service %s: %v", service.Name, err)
-			// Continue with startup - this is not a critical failure
+	var cmdString string
+	var credentialEnv map[string]string
+	var effectiveBuildSystem BuildSystemType
+	builds := true
+	if infraType != "" {
+		// Infrastructure runs its image or binary with development defaults;
+		// there is nothing to build
+		cmdString, serviceDir, err = sm.infrastructureStartCommand(ctx, service)
+		if err != nil {
+			return err
 		}
-	}
-
-	// Get start command
-	cmdString, err := GetStartCommand(serviceDir, string(effectiveBuildSystem), service.JavaOpts, service.ExtraEnv, service.VerboseLogging)
-	if err != nil {
-		return fmt.Errorf("failed to construct start command: %w", err)
-	}
-
-	// Clean up port
-	if service.Port > 0 {
-		log.Printf("[INFO] Checking port %d for conflicts before starting service %s", service.Port, service.Name)
-		if err := CleanupPortBeforeStart(service.Port); err != nil {
-			log.Printf("[WARN] Port cleanup failed for service %s: %v", service.Name, err)
+		credentialEnv = make(map[string]string)
+		builds = false
+	} else {
+		// Fail with readable reasons instead of a cryptic build log
+		if err := sm.preflightServiceStart(service, projectsDir); err != nil {
+			return err
 		}
-	}
 
-	cmd := exec.Command("bash", "-c", cmdString)
-	cmd.Dir = serviceDir
-	SetProcessGroup(cmd)
+		log.Printf("[INFO] Starting service %s from directory: %s", service.Name, serviceDir)
 
-	// Start with current environment, but filter out JAVA_HOME and PATH to avoid conflicts
-	baseEnv := []string{}
-	for _, env := range os.Environ() {
-		// Skip JAVA_HOME and PATH from base environment - we'll set them explicitly
-		if !strings.HasPrefix(env, "JAVA_HOME=") && !strings.HasPrefix(env, "PATH=") {
-			baseEnv = append(baseEnv, env)
+		// Ensure vertex user has access to the profile's project directory
+		if err := EnsureVertexUserProjectAccess(projectsDir); err != nil {
+			log.Printf("[WARN] Failed to setup project directory access for service %s: %v", service.Name, err)
+			// Continue with startup - this shouldn't block service startup
 		}
-	}
-	cmd.Env = baseEnv
 
-	// Build environment variables with proper precedence
-	// Priority: Service-specific env vars > Profile Java Home override > Global env vars
-
-	// Create a map to track which variables are set by service
-	serviceEnvKeys := make(map[string]bool)
-	for key := range service.EnvVars {
-		serviceEnvKeys[key] = true
-	}
-
-	// Determine which JAVA_HOME to use and set PATH accordingly
-	var finalJavaHome string
-	if serviceEnvKeys["JAVA_HOME"] {
-		// Service-specific JAVA_HOME takes highest priority
-		finalJavaHome = service.EnvVars["JAVA_HOME"].Value
-	} else if sm.config.JavaHomeOverride != "" {
-		// Profile Java Home override
-		finalJavaHome = sm.config.JavaHomeOverride
-	}
-
-	// Set JAVA_HOME and PATH if we have a Java home
-	if finalJavaHome != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("JAVA_HOME=%s", finalJavaHome))
-		cmd.Env = append(cmd.Env, fmt.Sprintf("PATH=%s/bin:%s", finalJavaHome, os.Getenv("PATH")))
-	} else {
-		// No Java home override, use system PATH
-		cmd.Env = append(cmd.Env, fmt.Sprintf("PATH=%s", os.Getenv("PATH")))
-	}
-
-	// Add global environment variables (only if not overridden by service)
-	for key, value := range globalEnvVars {
-		if !serviceEnvKeys[key] && key != "JAVA_HOME" { // Skip JAVA_HOME as we handled it above
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+		// Also ensure the specific service's build directory exists with proper permissions
+		if err := ensureServiceBuildDirectory(serviceDir); err != nil {
+			log.Printf("[WARN] Failed to setup build directory for service %s: %v", service.Name, err)
 		}
-	}
 
-	// Add service-specific environment variables (these take precedence)
-	for key, envVar := range service.EnvVars {
-		// Skip JAVA_HOME as we already handled it above
-		if key != "JAVA_HOME" {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, envVar.Value))
-		}
-		// Also set SPRING_PROFILES_ACTIVE for Spring Boot if ACTIVE_PROFILE is set
-		if key == "ACTIVE_PROFILE" {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("SPRING_PROFILES_ACTIVE=%s", envVar.Value))
+		// Check and fix Lombok compatibility before starting the service
+		if err := sm.checkAndFixLombokCompatibility(serviceDir, service.Name); err != nil {
+			log.Printf("[WARN] Lombok compatibility check failed for service %s: %v", service.Name, err)
+			// Continue with startup
 		}
-	}
 
-	// Ensure ACTIVE_PROFILE and SPRING_PROFILES_ACTIVE are set if not already set by service
-	if activeProfile, exists := globalEnvVars["ACTIVE_PROFILE"]; exists {
-		if !serviceEnvKeys["ACTIVE_PROFILE"] {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("ACTIVE_PROFILE=%s", activeProfile))
-			cmd.Env = append(cmd.Env, fmt.Sprintf("SPRING_PROFILES_ACTIVE=%s", activeProfile))
-		}
-	}
+		// Auto-detect build system
+		effectiveBuildSystem = GetEffectiveBuildSystem(serviceDir, buildSystem)
+		log.Printf("[INFO] Using build system '%s' for service %s", effectiveBuildSystem, service.Name)
 
-	// Inject Eureka overrides as environment variables.
-	// Env vars are inherited by forked JVMs and would normally have higher priority than config-server.
-	// However, Spring Cloud Config defaults to override-system-properties=true, which places config-server
-	// properties ABOVE env vars. We counteract this by also setting
-	// SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES=false — a client-side bootstrap property read before
-	// the config server is consulted, so the config server cannot override our env vars.
-	if service.EurekaPreferIPAddress != nil || service.EurekaHostname != "" {
-		cmd.Env = append(cmd.Env, "SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES=false")
-		log.Printf("[INFO] Service %s: disabling config-server env var override (SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES=false)", service.Name)
-	}
-	if service.EurekaPreferIPAddress != nil {
-		val := "false"
-		if *service.EurekaPreferIPAddress {
-			val = "true"
+		// Regenerate Maven wrapper only when ./mvnw and local mvn report different versions.
+		// If they already match the wrapper is correct and should not be touched.
+		// A service whose commands are overridden manages its own wrapper.
+		if effectiveBuildSystem == BuildSystemMaven && !overrides.replacesBuildTool(executionMode) && !MavenVersionsMatch(serviceDir) {
+			if err := GenerateMavenWrapper(serviceDir); err != nil {
+				log.Printf("[WARN] Failed to update Maven wrapper for service %s: %v", service.Name, err)
+				// Continue with startup - this is not a critical failure
+			}
 		}
-		cmd.Env = append(cmd.Env, "EUREKA_INSTANCE_PREFERIPADDRESS="+val)
-		log.Printf("[INFO] Service %s: injecting EUREKA_INSTANCE_PREFERIPADDRESS=%s", service.Name, val)
-	}
-	if service.EurekaHostname != "" {
-		cmd.Env = append(cmd.Env, "EUREKA_INSTANCE_HOSTNAME="+service.EurekaHostname)
-		log.Printf("[INFO] Service %s: injecting EUREKA_INSTANCE_HOSTNAME=%s", service.Name, service.EurekaHostname)
-	}
 
-	// Detect and log Java version being used
-	logJavaVersion(cmd.Env, service.Name)
+		// Expand the service's JVM preset, or the profile's override of it
+		javaOpts = sm.resolveJavaOpts(sm.getServiceProfileID(service.ID), service.ID, javaOptsPreset, javaOpts)
 
-	// Log the final command and environment variables for profile services
-	log.Printf("[DEBUG] Starting profile service %s with command: %s", service.Name, cmdString)
-	log.Printf("[DEBUG] Working directory: %s", serviceDir)
-	log.Printf("[DEBUG] Environment variables for %s:", service.Name)
-	for _, env := range cmd.Env {
-		if strings.Contains(env, "ACTIVE_PROFILE") || strings.Contains(env, "SPRING_PROFILES") || strings.Contains(env, "SERVICE_PORT") || strings.Contains(env, "CONFIG_") || strings.Contains(env, "JAVA_HOME") {
-			log.Printf("[DEBUG]   %s", env)
+		// Size the JVM for the service's memory and processor hints
+		limits := sm.serviceResourceLimits(service.ID, service.Name)
+		if hints := jvmMemoryHints(limits); hints != "" {
+			javaOpts = strings.TrimSpace(hints + " " + javaOpts)
+			log.Printf("[INFO] Service %s: JVM memory hints %s", service.Name, hints)
 		}
-	}
-
-	// Create stdout and stderr pipes
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start service: %w", err)
-	}
 
-	service.Status = "running"
-	service.HealthStatus = "starting"
-	service.LastStarted = time.Now()
-	service.PID = cmd.Process.Pid
-	service.Cmd = cmd
-	service.Uptime = ""
-	service.Logs = []models.LogEntry{}
+		// Get start command
+		if executionMode == ExecutionModeJar {
+			// Package once and run java -jar, skipping the build while the jar is current
+			var build string
+			if overrides.Build != "" {
+				build = overrides.expand(overrides.Build, javaOpts, port)
+			}
+			cmdString, credentialEnv, builds = sm.jarStartCommand(service, serviceDir, effectiveBuildSystem, javaOpts, extraEnv, build, verboseLogging)
+		} else {
+			if overrides.Start != "" {
+				// The service's own run command takes the place of the build system's
+				cmdString = overrides.expand(overrides.Start, javaOpts, port)
+				if extraEnv != "" {
+					cmdString = extraEnv + " " + cmdString
+				}
+			} else {
+				cmdString, err = GetStartCommand(serviceDir, string(effectiveBuildSystem), javaOpts, extraEnv, verboseLogging)
+				if err != nil {
+					return fmt.Errorf("failed to construct start command: %w", err)
+				}
+			}
 
-	// Save and broadcast
-	sm.updateServiceInDB(service)
-	sm.broadcastUpdate(service)
+			// Point Spring at the profile's property overrides, if any
+			cmdString = sm.applyPropertyOverrides(cmdString, effectiveBuildSystem, serviceDir, sm.getServiceProfileID(service.ID), service.ID, service.Name)
 
-	go sm.readLogs(service, stdout)
-	go sm.readLogs(service, stderr)
+			// Let the build authenticate against the profile's private repositories
+			cmdString, credentialEnv = sm.applyRepositoryCredentials(cmdString, effectiveBuildSystem, sm.getServiceProfileID(service.ID), service.Name)
 
-	go func() {
-		err := cmd.Wait()
-		service.Mutex.Lock()
-		defer service.Mutex.Unlock()
+			// Apply the profile's offline, mirror and build option settings
+			cmdString = sm.applyBuildSettings(cmdString, effectiveBuildSystem, sm.getServiceProfileID(service.ID), service.Name)
 
-		if err != nil {
-			log.Printf("Service %s exited with error: %v", service.Name, err)
-			if strings.Contains(err.Error(), "compilation") || strings.Contains(err.Error(), "cannot find symbol") {
-				log.Printf("[INFO] Compilation error detected for service %s, attempting pom.xml backup restoration", service.Name)
-				pomPath := filepath.Join(serviceDir, "pom.xml")
-				if restoreErr := sm.restorePomBackup(pomPath, service.Name); restoreErr != nil {
-					log.Printf("[WARN] Failed to restore backup for service %s: %v", service.Name, restoreErr)
+			// Build first when the service names a build command, such as
+			// installing the modules a monorepo module depends on. Otherwise
+			// a service with repository credentials is compiled first, so the
+			// dependencies are resolved while the credentials are set and the
+			// application runs without them.
+			build := overrides.expand(overrides.Build, javaOpts, port)
+			if build == "" && len(credentialEnv) > 0 {
+				build = credentialResolveCommand(serviceDir, effectiveBuildSystem)
+			}
+			if build != "" {
+				build, buildEnv := sm.applyRepositoryCredentials(build, effectiveBuildSystem, sm.getServiceProfileID(service.ID), service.Name)
+				build = sm.applyBuildSettings(build, effectiveBuildSystem, sm.getServiceProfileID(service.ID), service.Name)
+				for key, value := range buildEnv {
+					credentialEnv[key] = value
 				}
+				// Braces keep an export of the build settings inside the && chain
+				cmdString = "( " + build + " ) && " + unsetCredentialEnv(credentialEnv) + "{ " + cmdString + "; }"
 			}
-		} else {
-			log.Printf("Service %s exited successfully", service.Name)
 		}
 
-		service.Status = "stopped"
-		service.HealthStatus = "unknown"
-		service.PID = 0
-		service.Cmd = nil
-		service.Uptime = ""
-		sm.updateServiceInDB(service)
-		sm.broadcastUpdate(service)
-	}()
-
-	log.Printf("[INFO] Service %s started successfully with PID %d", service.Name, service.PID)
-	return nil
-}
-
-func (sm *Manager) startService(service *models.Service) error {
-	service.Mutex.Lock()
-	defer service.Mutex.Unlock()
-
-	if service.Status == "running" {
-		return fmt.Errorf("service %s is already running", service.Name)
-	}
-
-	serviceDir := filepath.Join(sm.config.ProjectsDir, service.Dir)
-	if _, err := os.Stat(serviceDir); os.IsNotExist(err) {
-		return fmt.Errorf("service directory does not exist: %s", serviceDir)
-	}
-
-	// Ensure vertex user has access to the profile's project directory
-	if err := EnsureVertexUserProjectAccess(sm.config.ProjectsDir); err != nil {
-		log.Printf("[WARN] Failed to setup project directory access for service %s: %v", service.Name, err)
-		// Continue with startup - this shouldn't block service startup
+		// Raise the open file and process limits before anything starts
+		if prefix := ulimitPrefix(limits); prefix != "" {
+			cmdString = prefix + cmdString
+			log.Printf("[INFO] Service %s: ulimits nofile=%d nproc=%d", service.Name, limits.NoFile, limits.NProc)
+		}
 	}
 
-	// Also ensure the specific service's build directory exists with proper permissions
-	if err := ensureServiceBuildDirectory(serviceDir); err != nil {
-		log.Printf("[WARN] Failed to setup build directory for service %s: %v", service.Name, err)
-	}
+	// Tell the service where the other services of its profile listen
+	discoveryEnv := sm.serviceDiscoveryEnv(service)
 
-	// Check and fix Lombok compatibility before starting the service
-	if err := sm.checkAndFixLombokCompatibility(serviceDir, service.Name); err != nil {
-		log.Printf("[WARN] Lombok compatibility check failed for service %s: %v", service.Name, err)
-		// Continue with startup - the error might not be critical
-	}
+	// Look up the JDK of the Java version the profile pins the service to
+	profileJavaHome := sm.profileJavaHome(sm.getServiceProfileID(service.ID), service.ID)
 
-	// Get global environment variables
-	globalEnvVars, err := sm.GetGlobalEnvVars()
+	// Look up env var values kept in Vault, SSM or 1Password; they only
+	// exist in the process's environment
+	secrets, err := sm.resolveServiceSecrets(ctx, service, globalEnvVars)
 	if err != nil {
-		log.Printf("Warning: Failed to load global environment variables for service %s: %v", service.Name, err)
-		globalEnvVars = make(map[string]string)
+		return err
 	}
 
-	// Auto-detect build system if needed and get appropriate command
-	effectiveBuildSystem := GetEffectiveBuildSystem(serviceDir, service.BuildSystem)
-	log.Printf("[INFO] Using build system '%s' for service %s", effectiveBuildSystem, service.Name)
-
-	// Regenerate Maven wrapper only when ./mvnw and local mvn report different versions.
-	// If they already match the wrapper is correct and should not be touched.
-	if effectiveBuildSystem == BuildSystemMaven && !MavenVersionsMatch(serviceDir) {
-		if err := GenerateMavenWrapper(serviceDir); err != nil {
-			log.Printf("[WARN] Failed to update Maven wrapper for service %s: %v", service.Name, err)
-			// Continue with startup - this is not a critical failure
+	// Clean up port
+	if port > 0 {
+		log.Printf("[INFO] Checking port %d for conflicts before starting service %s", port, service.Name)
+		if err := sm.CleanupPortBeforeStart(port); err != nil {
+			log.Printf("[WARN] Port cleanup failed for service %s: %v", service.Name, err)
 		}
 	}
 
-	// Get the start command for the detected build system
-	cmdString, err := GetStartCommand(serviceDir, string(effectiveBuildSystem), service.JavaOpts, service.ExtraEnv, service.VerboseLogging)
-	if err != nil {
-		return fmt.Errorf("failed to construct start command: %w", err)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("start of %s cancelled: %w", service.Name, err)
 	}
 
-	// Clean up any processes using the service's port before starting
-	if service.Port > 0 {
-		log.Printf("[INFO] Checking port %d for conflicts before starting service %s", service.Port, service.Name)
-		if err := CleanupPortBeforeStart(service.Port); err != nil {
-			log.Printf("[WARN] Port cleanup failed for service %s: %v", service.Name, err)
-			// Continue anyway - the service might still be able to start
-		}
+	// Wait for dependencies whose readiness probe must pass first
+	if err := sm.waitForDependencyReadiness(ctx, service); err != nil {
+		return err
 	}
 
-	log.Printf("[INFO] Starting service %s with command: %s", service.Name, cmdString)
-	cmd := exec.Command("bash", "-c", cmdString)
-
-	// log the cmd
-	// fmt.Printf("The command to run is: %s", cmd)
-
-	// Set process group for proper cleanup
-	SetProcessGroup(cmd)
+	// Fail fast with a clear message when a required database, broker or URL is down
+	if err := sm.verifyExternalDependencies(ctx, service); err != nil {
+		return err
+	}
 
-	// Set environment variables for the process
-	// Start with current environment, but filter out JAVA_HOME and PATH to avoid conflicts
-	baseEnv := []string{}
-	for _, env := range os.Environ() {
-		// Skip JAVA_HOME and PATH from base environment - we'll set them explicitly
-		if !strings.HasPrefix(env, "JAVA_HOME=") && !strings.HasPrefix(env, "PATH=") {
-			baseEnv = append(baseEnv, env)
+	// Bring the database schema up to date while the database is known to be up
+	if autoMigrate {
+		if err := sm.migrateBeforeStart(ctx, service, projectsDir); err != nil {
+			return err
 		}
 	}
-	cmd.Env = baseEnv
-
-	// Build environment variables with proper precedence
-	// Priority: Service-specific env vars > Profile Java Home override > Global env vars
 
-	// Create a map to track which variables are set by service
-	serviceEnvKeys := make(map[string]bool)
-	for key := range service.EnvVars {
-		serviceEnvKeys[key] = true
+	// Run pre-start hooks; an aborting hook failure prevents startup
+	if err := sm.runServiceHooks(ctx, service, models.HookPhasePreStart, serviceDir); err != nil {
+		return err
 	}
 
-	// Determine which JAVA_HOME to use and set PATH accordingly
-	var finalJavaHome string
-	if serviceEnvKeys["JAVA_HOME"] {
-		// Service-specific JAVA_HOME takes highest priority
-		finalJavaHome = service.EnvVars["JAVA_HOME"].Value
-	} else if sm.config.JavaHomeOverride != "" {
-		// Profile Java Home override
-		finalJavaHome = sm.config.JavaHomeOverride
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("start of %s cancelled: %w", service.Name, err)
 	}
 
-	// Set JAVA_HOME and PATH if we have a Java home
-	if finalJavaHome != "" {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("JAVA_HOME=%s", finalJavaHome))
-		cmd.Env = append(cmd.Env, fmt.Sprintf("PATH=%s/bin:%s", finalJavaHome, os.Getenv("PATH")))
-	} else {
-		// No Java home override, use system PATH
-		cmd.Env = append(cmd.Env, fmt.Sprintf("PATH=%s", os.Getenv("PATH")))
-	}
+	service.Mutex.Lock()
+	defer service.Mutex.Unlock()
 
-	// Add global environment variables (only if not overridden by service)
-	for key, value := range globalEnvVars {
-		if !serviceEnvKeys[key] && key != "JAVA_HOME" { // Skip JAVA_HOME as we handled it above
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
-		}
+	if service.Status == "running" || service.Status == StatusPaused {
+		return fmt.Errorf("service %s is already running", service.Name)
 	}
 
-	// Add service-specific environment variables (these take precedence)
-	for key, envVar := range service.EnvVars {
-		// Skip JAVA_HOME as we already handled it above
-		if key != "JAVA_HOME" {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, envVar.Value))
-		}
-		// Also set SPRING_PROFILES_ACTIVE for Spring Boot if ACTIVE_PROFILE is set
-		if key == "ACTIVE_PROFILE" {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("SPRING_PROFILES_ACTIVE=%s", envVar.Value))
-		}
-	}
+	cmd := exec.Command("bash", "-c", cmdString)
+	cmd.Dir = serviceDir
+	SetProcessGroup(cmd)
 
-	// Ensure ACTIVE_PROFILE and SPRING_PROFILES_ACTIVE are set if not already set by service
-	if activeProfile, exists := globalEnvVars["ACTIVE_PROFILE"]; exists {
-		if !serviceEnvKeys["ACTIVE_PROFILE"] {
-			cmd.Env = append(cmd.Env, fmt.Sprintf("ACTIVE_PROFILE=%s", activeProfile))
-			cmd.Env = append(cmd.Env, fmt.Sprintf("SPRING_PROFILES_ACTIVE=%s", activeProfile))
-		}
-	}
+	// Build environment variables with proper precedence
+	// Priority: Service-specific env vars > Profile Java Home override > Global env vars
+	cmd.Env = sm.buildServiceEnv(service, profileJavaHome, globalEnvVars, discoveryEnv, credentialEnv).environ(secrets)
 
-	// Inject Eureka overrides as environment variables.
-	// Env vars are inherited by forked JVMs and would normally have higher priority than config-server.
-	// However, Spring Cloud Config defaults to override-system-properties=true, which places config-server
-	// properties ABOVE env vars. We counteract this by also setting
-	// SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES=false — a client-side bootstrap property read before
-	// the config server is consulted, so the config server cannot override our env vars.
 	if service.EurekaPreferIPAddress != nil || service.EurekaHostname != "" {
-		cmd.Env = append(cmd.Env, "SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES=false")
 		log.Printf("[INFO] Service %s: disabling config-server env var override (SPRING_CLOUD_CONFIG_OVERRIDESYSTEMPROPERTIES=false)", service.Name)
 	}
 	if service.EurekaPreferIPAddress != nil {
-		val := "false"
-		if *service.EurekaPreferIPAddress {
-			val = "true"
-		}
-		cmd.Env = append(cmd.Env, "EUREKA_INSTANCE_PREFERIPADDRESS="+val)
-		log.Printf("[INFO] Service %s: injecting EUREKA_INSTANCE_PREFERIPADDRESS=%s", service.Name, val)
+		log.Printf("[INFO] Service %s: injecting EUREKA_INSTANCE_PREFERIPADDRESS=%t", service.Name, *service.EurekaPreferIPAddress)
 	}
 	if service.EurekaHostname != "" {
-		cmd.Env = append(cmd.Env, "EUREKA_INSTANCE_HOSTNAME="+service.EurekaHostname)
 		log.Printf("[INFO] Service %s: injecting EUREKA_INSTANCE_HOSTNAME=%s", service.Name, service.EurekaHostname)
 	}
 
 	// Detect and log Java version being used
-	logJavaVersion(cmd.Env, service.Name)
+	if infraType == "" {
+		logJavaVersion(cmd.Env, service.Name)
+	}
 
-	// Log the final command and environment variables
-	// log.Printf("[DEBUG] Starting service %s with command: %s", service.Name, cmdString)
-	// log.Printf("[DEBUG] Working directory: %s", serviceDir)
-	// log.Printf("[DEBUG] Environment variables for %s:", service.Name)
+	// Log the final command and environment variables for profile services
+	log.Printf("[DEBUG] Starting profile service %s with command: %s", service.Name, cmdString)
+	log.Printf("[DEBUG] Working directory: %s", serviceDir)
+	log.Printf("[DEBUG] Environment variables for %s:", service.Name)
 	for _, env := range cmd.Env {
 		if strings.Contains(env, "ACTIVE_PROFILE") || strings.Contains(env, "SPRING_PROFILES") || strings.Contains(env, "SERVICE_PORT") || strings.Contains(env, "CONFIG_") || strings.Contains(env, "JAVA_HOME") {
-			log.Printf("[DEBUG]   %s", env)
+			log.Printf("[DEBUG]   %s", secrets.redact(env))
 		}
 	}
 
-	// Create pipes for stdout and stderr
+	// Run the service as its dedicated OS user, if it has one
+	if err := applyRunAsUser(cmd, service); err != nil {
+		return fmt.Errorf("failed to start %s as user %s: %w", service.Name, service.RunAsUser, err)
+	}
+
+	// Create stdout and stderr pipes
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
-
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
 
-	// Start the command
+	// Interactive services keep stdin open for input sent through the API
+	var stdin io.WriteCloser
+	if service.Interactive {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return fmt.Errorf("failed to create stdin pipe: %w", err)
+		}
+	}
+
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start service: %w", err)
 	}
 
-	// fmt.Printf("RUNNING THE COMMAND:\n%s\n", cmd)
-	// fmt.Printf("THE_CURRENT_CMD_WITH_OPTS:\n%s\n", cmd)
-
 	service.Status = "running"
 	service.HealthStatus = "starting"
+	service.LastStarted = time.Now()
 	service.PID = cmd.Process.Pid
 	service.Cmd = cmd
-	service.LastStarted = time.Now()
+	if stdin != nil {
+		service.Stdin = newServiceStdin(stdin)
+	}
+	service.Uptime = ""
 	service.Logs = []models.LogEntry{}
+	service.ProfileID = sm.getServiceProfileID(service.ID)
 
 	// Record uptime event
 	uptimeTracker := GetUptimeTracker()
-	uptimeTracker.RecordEvent(service.ID, "start", "running")
+	uptimeTracker.RecordEvent(service.ProfileID, service.ID, "start", "running")
+	sm.recordServiceEvent(service, models.EventStarted, fmt.Sprintf("Started with PID %d", service.PID))
 
-	// Start reading logs
-	go sm.readLogs(service, stdout)
-	go sm.readLogs(service, stderr)
+	// Save and broadcast
+	sm.updateServiceInDB(service)
+	sm.broadcastUpdate(service)
+
+	// Compile output goes to the service's build history, not the runtime log
+	var build *buildPhase
+	if builds {
+		build = sm.beginBuild(service, serviceDir, effectiveBuildSystem)
+	}
+
+	go sm.readLogs(service, stdout, build)
+	go sm.readLogs(service, stderr, build)
 
-	// Monitor process completion
 	go func() {
 		err := cmd.Wait()
+		sm.endBuild(service, build, err)
+
 		service.Mutex.Lock()
 		defer service.Mutex.Unlock()
 
 		if err != nil {
 			log.Printf("Service %s exited with error: %v", service.Name, err)
-
-			// Check if it's a compilation error that might be related to Lombok
 			if strings.Contains(err.Error(), "compilation") || strings.Contains(err.Error(), "cannot find symbol") {
 				log.Printf("[INFO] Compilation error detected for service %s, attempting pom.xml backup restoration", service.Name)
 				pomPath := filepath.Join(serviceDir, "pom.xml")
@@ -769,25 +634,50 @@ func (sm *Manager) startService(service *models.Service) error {
 			log.Printf("Service %s exited successfully", service.Name)
 		}
 
-		service.Status = "stopped"
+		// Manual stops already changed the status, so a running service here exited on its own
+		exitedOnItsOwn := service.Status == "running" || service.Status == StatusPaused
+
+		if exitedOnItsOwn && err != nil {
+			sm.recordServiceRestart(service, models.RestartCrash)
+		}
+
+		// Quarantine services that keep dying right after start, and keep
+		// the "suspended" marker set by the idle monitor
+		if sm.recordServiceExit(service, err) {
+			service.Status = StatusCrashLooping
+			sm.recordServiceEvent(service, models.EventCrashLooping, "Quarantined after repeated crashes shortly after start")
+		} else {
+			if exitedOnItsOwn && err != nil {
+				sm.recordServiceEvent(service, models.EventCrashed, fmt.Sprintf("Process exited unexpectedly: %v", err))
+			} else if exitedOnItsOwn {
+				sm.recordServiceEvent(service, models.EventStopped, "Process exited")
+			}
+			if service.Status != "suspended" {
+				service.Status = "stopped"
+			}
+		}
 		service.HealthStatus = "unknown"
 		service.PID = 0
 		service.Cmd = nil
+		if service.Stdin != nil {
+			service.Stdin.Close()
+		}
+		service.Stdin = nil
 		service.Uptime = ""
 
 		// Record uptime event
 		uptimeTracker := GetUptimeTracker()
-		uptimeTracker.RecordEvent(service.ID, "stop", "stopped")
+		uptimeTracker.RecordEvent(service.ProfileID, service.ID, "stop", "stopped")
 
 		sm.updateServiceInDB(service)
 		sm.broadcastUpdate(service)
+
+		go sm.runServiceHooks(sm.ctx, service, models.HookPhasePostStop, serviceDir)
 	}()
 
-	// Update database and broadcast
-	sm.updateServiceInDB(service)
-	sm.broadcastUpdate(service)
+	go sm.runPostStartHooks(service, serviceDir, cmd)
 
-	log.Printf("Started service %s with PID %d", service.Name, service.PID)
+	log.Printf("[INFO] Service %s started successfully with PID %d", service.Name, service.PID)
 	return nil
 }
 
@@ -795,12 +685,19 @@ func (sm *Manager) stopService(service *models.Service) error {
 	service.Mutex.Lock()
 	defer service.Mutex.Unlock()
 
-	if service.Status != "running" || service.Cmd == nil {
+	if (service.Status != "running" && service.Status != StatusPaused) || service.Cmd == nil {
 		return fmt.Errorf("service %s is not running", service.Name)
 	}
 
 	log.Printf("Stopping service %s (PID: %d)", service.Name, service.PID)
 
+	// A frozen process only acts on SIGTERM once it is continued
+	if service.Status == StatusPaused {
+		if err := resumeServiceProcesses(service); err != nil {
+			log.Printf("[WARN] %v", err)
+		}
+	}
+
 	// Get the process group ID and kill the entire group
 	if pgid, err := GetProcessGroup(service.Cmd.Process.Pid); err != nil {
 		log.Printf("Failed to get process group for %s: %v", service.Name, err)
@@ -823,48 +720,102 @@ func (sm *Manager) stopService(service *models.Service) error {
 		}
 	}
 
+	// The container may outlive its killed client
+	removeInfraContainer(service)
+
 	service.Status = "stopped"
 	service.HealthStatus = "unknown"
 	service.PID = 0
 	service.Cmd = nil
 	service.Uptime = ""
 
+	sm.recordServiceEvent(service, models.EventStopped, "Stopped")
+
 	// Update database
 	sm.updateServiceInDB(service)
 	sm.broadcastUpdate(service)
 	return nil
 }
 
-func (sm *Manager) readLogs(service *models.Service, pipe io.Reader) {
-	scanner := bufio.NewScanner(pipe)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		logEntry := parseLogLine(line)
+func (sm *Manager) readLogs(service *models.Service, pipe io.Reader, build *buildPhase) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(pipe)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
 
-		service.Mutex.Lock()
-		// Keep in-memory logs for immediate access (last 1000 entries)
-		service.Logs = append(service.Logs, logEntry)
-		if len(service.Logs) > 1000 {
-			service.Logs = service.Logs[len(service.Logs)-1000:]
+	// Lines are held briefly so a stack trace is stored as one entry together
+	// with the log line that introduced it
+	var pending *models.LogEntry
+	groupedLines := 0
+	for {
+		var flush <-chan time.Time
+		if pending != nil {
+			flush = time.After(logGroupFlushDelay)
 		}
-		service.Mutex.Unlock()
 
-		// Store log entry in database for persistent storage
-		if err := sm.db.StoreLogEntry(service.ID, logEntry); err != nil {
-			log.Printf("Failed to store log entry for service %s: %v", service.ID, err)
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if pending != nil {
+					sm.ingestLogEntry(service, *pending)
+				}
+				build.readerDone()
+				return
+			}
+			if sm.captureBuildLine(service, build, line) {
+				continue
+			}
+			if pending != nil && groupedLines < maxGroupedLines && isLogContinuation(line, pending) {
+				pending.Message += "\n" + line
+				groupedLines++
+				continue
+			}
+			if pending != nil {
+				sm.ingestLogEntry(service, *pending)
+			}
+			entry := newLogEntry(service.ID, line)
+			pending = &entry
+			groupedLines = 1
+		case <-flush:
+			sm.ingestLogEntry(service, *pending)
+			pending = nil
 		}
+	}
+}
 
-		// Broadcast the new log entry
-		sm.broadcastLogEntry(service.ID, logEntry)
+// emitLogEntry keeps, stores and broadcasts a complete log entry
+func (sm *Manager) emitLogEntry(service *models.Service, logEntry models.LogEntry) {
+	service.Mutex.Lock()
+	// Keep in-memory logs for immediate access (last 1000 entries)
+	service.Logs = append(service.Logs, logEntry)
+	if len(service.Logs) > 1000 {
+		service.Logs = service.Logs[len(service.Logs)-1000:]
 	}
+	sm.publishServiceLogs(service)
+	service.Mutex.Unlock()
+
+	recordErrorCluster(service.ID, logEntry)
+
+	// Store log entry in database and/or the profile's external log sink
+	sm.storeLogEntry(service, logEntry)
+
+	// Broadcast the new log entry
+	sm.broadcastLogEntry(service.ID, logEntry)
 }
 
-func parseLogLine(line string) models.LogEntry {
-	match := logLevelRegex.FindStringSubmatch(line)
-	level := "INFO" // Default level
-	if len(match) > 1 {
-		level = strings.ToUpper(match[1])
+// parseLogLine gives a line the level of the first of the service's log level
+// rules it matches, or else of the first level keyword in it
+func parseLogLine(serviceUUID, line string) models.LogEntry {
+	level, classified := classifyLogLine(serviceUUID, line)
+	if !classified {
+		level = "INFO" // Default level
+		if match := logLevelRegex.FindStringSubmatch(line); len(match) > 1 {
+			level = strings.ToUpper(match[1])
+		}
 	}
 
 	return models.LogEntry{
@@ -884,11 +835,15 @@ func (sm *Manager) ClearLogs(serviceID string) error {
 	if err := sm.db.ClearServiceLogs(serviceID); err != nil {
 		return fmt.Errorf("failed to clear logs from database: %w", err)
 	}
+	if err := clearLogFiles(serviceID); err != nil {
+		log.Printf("[WARN] Failed to clear log files for service %s: %v", service.Name, err)
+	}
 
 	// Clear in-memory logs
 	service.Mutex.Lock()
 	service.Logs = []models.LogEntry{}
 	service.Mutex.Unlock()
+	clearErrorClusters(serviceID)
 
 	sm.broadcastUpdate(service)
 	return nil
@@ -913,6 +868,7 @@ func (sm *Manager) ClearAllLogs(serviceNames []string) map[string]string {
 			service.Mutex.Lock()
 			service.Logs = []models.LogEntry{}
 			service.Mutex.Unlock()
+			clearErrorClusters(service.ID)
 
 			sm.broadcastUpdate(service)
 			results[service.Name] = "Success"
@@ -965,6 +921,7 @@ func (sm *Manager) ClearAllLogs(serviceNames []string) map[string]string {
 					service.Mutex.Lock()
 					service.Logs = []models.LogEntry{}
 					service.Mutex.Unlock()
+					clearErrorClusters(service.ID)
 
 					sm.broadcastUpdate(service)
 					results[serviceName] = "Success"
@@ -0,0 +1,382 @@
+// Package services - Adoption and cleanup of processes left by a crashed Vertex
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// orphanKillTimeout is how long a killed orphan gets to exit before SIGKILL
+const orphanKillTimeout = 10 * time.Second
+
+// orphanedProcesses holds the orphans that were neither adopted nor killed,
+// keyed by PID
+var (
+	orphanedProcesses = make(map[int]models.OrphanedProcess)
+	orphanMutex       sync.Mutex
+)
+
+// orphanAdoptionEnabled reports whether orphans found on startup are adopted
+// automatically; VERTEX_ADOPT_ORPHANS=false only lists them
+func orphanAdoptionEnabled() bool {
+	value := strings.ToLower(os.Getenv("VERTEX_ADOPT_ORPHANS"))
+	return value != "false" && value != "0" && value != "no"
+}
+
+// reapOrphanedProcesses runs on startup: services whose processes survived a
+// crash of Vertex are adopted, and anything else holding their PID or port is
+// kept for the user to adopt or kill
+func (sm *Manager) reapOrphanedProcesses(previousPIDs map[string]int) {
+	orphans := sm.findOrphanedProcesses(previousPIDs)
+	if len(orphans) == 0 {
+		return
+	}
+
+	adopt := orphanAdoptionEnabled()
+	for _, orphan := range orphans {
+		if adopt && orphan.Adoptable {
+			err := sm.adoptOrphanedProcess(orphan)
+			if err == nil {
+				log.Printf("[INFO] Adopted orphaned process %d of service %s", orphan.PID, orphan.ServiceName)
+				continue
+			}
+			log.Printf("[WARN] Failed to adopt orphaned process %d of service %s: %v", orphan.PID, orphan.ServiceName, err)
+		}
+		log.Printf("[WARN] Process %d (%s) holds the %s of stopped service %s; adopt or kill it from the orphans API",
+			orphan.PID, orphan.Command, orphan.Match, orphan.ServiceName)
+		orphanMutex.Lock()
+		orphanedProcesses[orphan.PID] = orphan
+		orphanMutex.Unlock()
+	}
+}
+
+// ScanOrphanedProcesses looks again for processes holding the port of a
+// stopped service and returns every known orphan
+func (sm *Manager) ScanOrphanedProcesses() ([]models.OrphanedProcess, error) {
+	previousPIDs, err := sm.db.GetRecordedServicePIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := sm.findOrphanedProcesses(previousPIDs)
+	orphanMutex.Lock()
+	for _, orphan := range orphans {
+		orphanedProcesses[orphan.PID] = orphan
+	}
+	orphanMutex.Unlock()
+
+	return sm.GetOrphanedProcesses(), nil
+}
+
+// GetOrphanedProcesses returns the orphans waiting to be adopted or killed,
+// dropping those that exited since
+func (sm *Manager) GetOrphanedProcesses() []models.OrphanedProcess {
+	orphanMutex.Lock()
+	defer orphanMutex.Unlock()
+
+	orphans := make([]models.OrphanedProcess, 0, len(orphanedProcesses))
+	for pid, orphan := range orphanedProcesses {
+		if !orphanStillRunning(sm.ctx, orphan) {
+			delete(orphanedProcesses, pid)
+			continue
+		}
+		orphans = append(orphans, orphan)
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		if orphans[i].ServiceName != orphans[j].ServiceName {
+			return orphans[i].ServiceName < orphans[j].ServiceName
+		}
+		return orphans[i].PID < orphans[j].PID
+	})
+	return orphans
+}
+
+// AdoptOrphanedProcess reattaches an orphan to its service, which then shows
+// as running and can be stopped as usual
+func (sm *Manager) AdoptOrphanedProcess(pid int) error {
+	orphan, err := takeOrphanedProcess(sm.ctx, pid)
+	if err != nil {
+		return err
+	}
+	if !orphan.Adoptable {
+		sm.keepOrphanedProcess(orphan)
+		return fmt.Errorf("process %d does not run in the directory of service %s; kill it instead", pid, orphan.ServiceName)
+	}
+
+	if err := sm.adoptOrphanedProcess(orphan); err != nil {
+		sm.keepOrphanedProcess(orphan)
+		return err
+	}
+	log.Printf("[INFO] Adopted orphaned process %d of service %s", orphan.PID, orphan.ServiceName)
+	return nil
+}
+
+// KillOrphanedProcess terminates an orphan and its process group, freeing the
+// service's port
+func (sm *Manager) KillOrphanedProcess(pid int) error {
+	orphan, err := takeOrphanedProcess(sm.ctx, pid)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Killing orphaned process %d of service %s", orphan.PID, orphan.ServiceName)
+	if err := terminateOrphan(sm.ctx, orphan.PID); err != nil {
+		sm.keepOrphanedProcess(orphan)
+		return err
+	}
+	return nil
+}
+
+// takeOrphanedProcess removes a known orphan from the list, checking that its
+// PID was not reused by another process
+func takeOrphanedProcess(ctx context.Context, pid int) (models.OrphanedProcess, error) {
+	orphanMutex.Lock()
+	defer orphanMutex.Unlock()
+
+	orphan, exists := orphanedProcesses[pid]
+	if !exists {
+		return orphan, fmt.Errorf("orphaned process %d not found", pid)
+	}
+	delete(orphanedProcesses, pid)
+	if !orphanStillRunning(ctx, orphan) {
+		return orphan, fmt.Errorf("orphaned process %d not found; it already exited", pid)
+	}
+	return orphan, nil
+}
+
+// keepOrphanedProcess puts an orphan back after a failed adoption or kill
+func (sm *Manager) keepOrphanedProcess(orphan models.OrphanedProcess) {
+	orphanMutex.Lock()
+	orphanedProcesses[orphan.PID] = orphan
+	orphanMutex.Unlock()
+}
+
+// adoptOrphanedProcess marks a service running with the orphan as its process
+func (sm *Manager) adoptOrphanedProcess(orphan models.OrphanedProcess) error {
+	service, exists := sm.GetServiceByUUID(orphan.ServiceID)
+	if !exists {
+		return fmt.Errorf("service UUID %s not found", orphan.ServiceID)
+	}
+	proc, err := os.FindProcess(orphan.PID)
+	if err != nil {
+		return fmt.Errorf("failed to find process %d: %w", orphan.PID, err)
+	}
+
+	service.Mutex.Lock()
+	defer service.Mutex.Unlock()
+
+	if service.Cmd != nil || service.Status == "running" || service.Status == StatusPaused {
+		return fmt.Errorf("service %s is already running", service.Name)
+	}
+
+	// Stopping, pausing and chaos faults act on Cmd.Process. Vertex is not the
+	// parent, so nothing waits on it: the health and metrics checks notice
+	// when it exits. Its output went to the crashed Vertex and is not logged.
+	service.Cmd = &exec.Cmd{Process: proc}
+	service.Status = "running"
+	service.HealthStatus = "starting"
+	service.PID = orphan.PID
+	service.Uptime = ""
+	service.LastStarted = orphan.StartedAt
+	if service.LastStarted.IsZero() {
+		service.LastStarted = time.Now()
+	}
+	service.ProfileID = sm.getServiceProfileID(service.ID)
+
+	uptimeTracker := GetUptimeTracker()
+	uptimeTracker.RecordEvent(service.ProfileID, service.ID, "start", "running")
+	sm.recordServiceEvent(service, models.EventAdopted, fmt.Sprintf("Adopted orphaned process %d (matched by %s)", orphan.PID, orphan.Match))
+
+	if err := sm.updateServiceInDB(service); err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+	sm.broadcastUpdate(service)
+	return nil
+}
+
+// findOrphanedProcesses matches running processes to stopped services, by the
+// PID each service had before the restart or by a listener on its port
+func (sm *Manager) findOrphanedProcesses(previousPIDs map[string]int) []models.OrphanedProcess {
+	listeners := make(map[int]int)
+	connections, err := net.ConnectionsWithContext(sm.ctx, "tcp")
+	if err != nil {
+		log.Printf("[WARN] Orphan scan could not read TCP listeners: %v", err)
+	}
+	for _, conn := range connections {
+		if conn.Status == "LISTEN" && conn.Pid > 0 {
+			listeners[int(conn.Laddr.Port)] = int(conn.Pid)
+		}
+	}
+
+	self := os.Getpid()
+	now := time.Now()
+	seen := make(map[int]bool)
+	var orphans []models.OrphanedProcess
+
+	for _, service := range sm.snapshotServices() {
+		service.Mutex.RLock()
+		skip := service.Cmd != nil || service.ArchivedAt != nil || service.Status == "running" || service.Status == StatusPaused
+		serviceID, name, dir, port := service.ID, service.Name, service.Dir, service.Port
+		service.Mutex.RUnlock()
+		if skip {
+			continue
+		}
+
+		type candidate struct {
+			pid   int
+			match string
+		}
+		var candidates []candidate
+		if pid := previousPIDs[serviceID]; pid > 0 {
+			candidates = append(candidates, candidate{pid, models.OrphanMatchPID})
+		}
+		if pid := listeners[port]; port > 0 && pid > 0 {
+			candidates = append(candidates, candidate{pid, models.OrphanMatchPort})
+		}
+
+		for _, c := range candidates {
+			orphan, ok := inspectOrphan(sm.ctx, c.pid, dir)
+			if !ok || orphan.PID == self || seen[orphan.PID] {
+				continue
+			}
+			// A reused PID is only an orphan when it runs in the service's directory
+			if c.match == models.OrphanMatchPID && !orphan.Adoptable {
+				continue
+			}
+			seen[orphan.PID] = true
+			orphan.ServiceID = serviceID
+			orphan.ServiceName = name
+			orphan.Port = port
+			orphan.Match = c.match
+			orphan.DetectedAt = now
+			orphans = append(orphans, orphan)
+			break
+		}
+	}
+
+	return orphans
+}
+
+// inspectOrphan describes a process, moving up to its process group leader
+// when that also runs in the service's directory: services are started in a
+// group of their own, and the build tool leads it
+func inspectOrphan(ctx context.Context, pid int, serviceDir string) (models.OrphanedProcess, bool) {
+	orphan, ok := describeProcess(ctx, pid)
+	if !ok {
+		return orphan, false
+	}
+	orphan.Adoptable = processInServiceDir(orphan.Cwd, serviceDir)
+
+	if pgid, err := GetProcessGroup(pid); err == nil && pgid > 0 && pgid != pid {
+		if leader, ok := describeProcess(ctx, pgid); ok && processInServiceDir(leader.Cwd, serviceDir) {
+			leader.Adoptable = true
+			return leader, true
+		}
+	}
+	return orphan, true
+}
+
+// describeProcess reads the command, directory and start time of a process
+func describeProcess(ctx context.Context, pid int) (models.OrphanedProcess, bool) {
+	orphan := models.OrphanedProcess{PID: pid}
+	proc, err := process.NewProcessWithContext(ctx, int32(pid))
+	if err != nil {
+		return orphan, false
+	}
+
+	if cmdline, err := proc.CmdlineWithContext(ctx); err == nil {
+		orphan.Command = cmdline
+	}
+	if cwd, err := proc.CwdWithContext(ctx); err == nil {
+		orphan.Cwd = cwd
+	}
+	if created, err := proc.CreateTimeWithContext(ctx); err == nil {
+		orphan.StartedAt = time.UnixMilli(created)
+	}
+	return orphan, true
+}
+
+// orphanStillRunning reports whether an orphan's PID still belongs to the
+// process that was found
+func orphanStillRunning(ctx context.Context, orphan models.OrphanedProcess) bool {
+	current, ok := describeProcess(ctx, orphan.PID)
+	if !ok {
+		return false
+	}
+	return orphan.StartedAt.IsZero() || current.StartedAt.Equal(orphan.StartedAt)
+}
+
+// processInServiceDir reports whether a working directory is a service's
+// directory or inside it. Relative service directories are matched as a path
+// suffix, since the projects directory depends on the profile.
+func processInServiceDir(cwd, serviceDir string) bool {
+	if cwd == "" || serviceDir == "" {
+		return false
+	}
+	cwd = filepath.Clean(cwd)
+	dir := filepath.Clean(serviceDir)
+	separator := string(filepath.Separator)
+
+	if filepath.IsAbs(dir) {
+		return cwd == dir || strings.HasPrefix(cwd, dir+separator)
+	}
+	if dir == "." || strings.HasPrefix(dir, "..") {
+		return false
+	}
+	suffix := separator + dir
+	return strings.HasSuffix(cwd, suffix) || strings.Contains(cwd, suffix+separator)
+}
+
+// terminateOrphan stops a process and its group, as stopService does, and
+// force kills what is left after orphanKillTimeout
+func terminateOrphan(ctx context.Context, pid int) error {
+	pgid, err := GetProcessGroup(pid)
+	if err != nil {
+		return fmt.Errorf("failed to get process group of %d: %w", pid, err)
+	}
+
+	// Only signal the group the orphan leads, never one it merely belongs to
+	if pgid == pid {
+		err = KillProcessGroup(pgid)
+	} else {
+		err = KillProcess(pid)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to terminate process %d: %w", pid, err)
+	}
+
+	deadline := time.Now().Add(orphanKillTimeout)
+	for IsProcessRunning(pid) && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	if !IsProcessRunning(pid) {
+		return nil
+	}
+
+	log.Printf("[WARN] Orphaned process %d ignored SIGTERM, force killing it", pid)
+	if pgid == pid {
+		err = ForceKillProcessGroup(pgid)
+	} else {
+		err = ForceKillProcess(pid)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to force kill process %d: %w", pid, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package services - Pausing services in place with SIGSTOP/SIGCONT
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// StatusPaused marks a service whose processes are frozen with SIGSTOP. They
+// keep their memory, ports and JVM warmup but get no CPU until resumed.
+const StatusPaused = "paused"
+
+// PauseService freezes a running service's process group
+func (sm *Manager) PauseService(serviceUUID string) error {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	return sm.runServiceOp(sm.ctx, service, "pause", func(ctx context.Context) error {
+		service.Mutex.Lock()
+		defer service.Mutex.Unlock()
+
+		if service.Status != "running" || service.Cmd == nil {
+			return fmt.Errorf("service %s is not running", service.Name)
+		}
+		if runsThroughSudo(service) {
+			return fmt.Errorf("service %s runs as %s through sudo, which cannot pause it", service.Name, service.RunAsUser)
+		}
+
+		pgid, err := GetProcessGroup(service.Cmd.Process.Pid)
+		if err != nil {
+			return fmt.Errorf("failed to get process group of %s: %w", service.Name, err)
+		}
+		if err := PauseProcessGroup(pgid); err != nil {
+			return fmt.Errorf("failed to pause %s: %w", service.Name, err)
+		}
+
+		log.Printf("[INFO] Paused service %s (PID: %d)", service.Name, service.PID)
+		service.Status = StatusPaused
+		sm.recordServiceEvent(service, models.EventPaused, "Paused (SIGSTOP)")
+		sm.updateServiceInDB(service)
+		sm.broadcastUpdate(service)
+		return nil
+	})
+}
+
+// ResumeService continues a paused service's process group
+func (sm *Manager) ResumeService(serviceUUID string) error {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	return sm.runServiceOp(sm.ctx, service, "resume", func(ctx context.Context) error {
+		service.Mutex.Lock()
+		defer service.Mutex.Unlock()
+
+		if service.Status != StatusPaused || service.Cmd == nil {
+			return fmt.Errorf("service %s is not paused", service.Name)
+		}
+
+		if err := resumeServiceProcesses(service); err != nil {
+			return err
+		}
+
+		log.Printf("[INFO] Resumed service %s (PID: %d)", service.Name, service.PID)
+		service.Status = "running"
+		sm.recordServiceEvent(service, models.EventResumed, "Resumed (SIGCONT)")
+		sm.updateServiceInDB(service)
+		sm.broadcastUpdate(service)
+		return nil
+	})
+}
+
+// resumeServiceProcesses sends SIGCONT to a service's process group; the
+// service's mutex must be held
+func resumeServiceProcesses(service *models.Service) error {
+	pgid, err := GetProcessGroup(service.Cmd.Process.Pid)
+	if err != nil {
+		return fmt.Errorf("failed to get process group of %s: %w", service.Name, err)
+	}
+	if err := ResumeProcessGroup(pgid); err != nil {
+		return fmt.Errorf("failed to resume %s: %w", service.Name, err)
+	}
+	return nil
+}
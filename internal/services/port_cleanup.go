@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -9,15 +10,26 @@ import (
 	"strings"
 	"syscall"
 	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/zechtz/vertex/internal/models"
 )
 
+// portHolderAncestorDepth bounds the walk up the parents of a port holder
+// looking for Vertex
+const portHolderAncestorDepth = 32
+
 // PortCleanupResult represents the result of port cleanup operation
 type PortCleanupResult struct {
-	Port            int      `json:"port"`
-	ProcessesFound  int      `json:"processesFound"`
-	ProcessesKilled int      `json:"processesKilled"`
-	PIDs            []int    `json:"pids"`
-	Errors          []string `json:"errors"`
+	Port                 int                 `json:"port"`
+	ProcessesFound       int                 `json:"processesFound"`
+	ProcessesKilled      int                 `json:"processesKilled"`
+	PIDs                 []int               `json:"pids"`
+	Holders              []models.PortHolder `json:"holders"`
+	Skipped              []int               `json:"skipped"`              // PIDs left running because Vertex did not start them
+	ConfirmationRequired bool                `json:"confirmationRequired"` // Clean up again with force to kill the skipped processes
+	Errors               []string            `json:"errors"`
 }
 
 // KillProcessesOnPort finds and kills all processes using the specified port
@@ -27,6 +39,8 @@ func KillProcessesOnPort(port int) *PortCleanupResult {
 		ProcessesFound:  0,
 		ProcessesKilled: 0,
 		PIDs:            []int{},
+		Holders:         []models.PortHolder{},
+		Skipped:         []int{},
 		Errors:          []string{},
 	}
 
@@ -83,9 +97,16 @@ func KillProcessesOnPort(port int) *PortCleanupResult {
 func findProcessesOnPort(port int) []int {
 	var pids []int
 
-	// Try lsof first (most reliable)
-	if lsofPids := findProcessesWithLsof(port); len(lsofPids) > 0 {
-		pids = append(pids, lsofPids...)
+	// Read the socket tables first; they list IPv4 and IPv6 listeners alike
+	if listenerPids := findProcessesWithConnections(port); len(listenerPids) > 0 {
+		pids = append(pids, listenerPids...)
+	}
+
+	// Try lsof next
+	if len(pids) == 0 {
+		if lsofPids := findProcessesWithLsof(port); len(lsofPids) > 0 {
+			pids = append(pids, lsofPids...)
+		}
 	}
 
 	// Try netstat as fallback
@@ -106,9 +127,27 @@ func findProcessesOnPort(port int) []int {
 	return deduplicateAndValidatePids(pids)
 }
 
-// findProcessesWithLsof uses lsof to find processes using the port
+// findProcessesWithConnections finds the processes listening on the port on
+// any address, IPv4 or IPv6
+func findProcessesWithConnections(port int) []int {
+	connections, err := net.Connections("tcp")
+	if err != nil {
+		return []int{}
+	}
+
+	var pids []int
+	for _, conn := range connections {
+		if conn.Status == "LISTEN" && int(conn.Laddr.Port) == port && conn.Pid > 0 {
+			pids = append(pids, int(conn.Pid))
+		}
+	}
+	return pids
+}
+
+// findProcessesWithLsof uses lsof to find processes listening on the port
+// over IPv4 or IPv6; clients connected to it are left alone
 func findProcessesWithLsof(port int) []int {
-	cmd := exec.Command("lsof", "-t", "-i", fmt.Sprintf(":%d", port))
+	cmd := exec.Command("lsof", "-t", fmt.Sprintf("-iTCP:%d", port), "-sTCP:LISTEN")
 	output, err := cmd.Output()
 	if err != nil {
 		return []int{}
@@ -156,11 +195,13 @@ func findProcessesWithFuser(port int) []int {
 func parseNetstatOutput(output string, port int) []int {
 	var pids []int
 	lines := strings.Split(output, "\n")
-	portStr := fmt.Sprintf(":%d", port)
 
 	for _, line := range lines {
-		if strings.Contains(line, portStr) && strings.Contains(line, "LISTEN") {
-			fields := strings.Fields(line)
+		if !strings.Contains(line, "LISTEN") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if netstatLocalPort(fields) == port {
 			// Look for PID in the last field (format: PID/program)
 			for _, field := range fields {
 				if strings.Contains(field, "/") {
@@ -176,6 +217,24 @@ func parseNetstatOutput(output string, port int) []int {
 	return pids
 }
 
+// netstatLocalPort returns the port of the local address of a netstat line,
+// the first address column, in any of its forms: 0.0.0.0:8080, :::8080,
+// [::1]:8080, or *.8080 and ::1.8080 on BSD and macOS
+func netstatLocalPort(fields []string) int {
+	for _, field := range fields {
+		separator := strings.LastIndexAny(field, ":.")
+		if separator < 0 || strings.Contains(field, "/") {
+			continue
+		}
+		port, err := strconv.Atoi(field[separator+1:])
+		if err != nil {
+			continue
+		}
+		return port
+	}
+	return 0
+}
+
 // parsePidsFromOutput parses space or newline separated PIDs from command output
 func parsePidsFromOutput(output string) []int {
 	var pids []int
@@ -209,7 +268,8 @@ func deduplicateAndValidatePids(pids []int) []int {
 	var result []int
 
 	for _, pid := range pids {
-		if seen[pid] || pid <= 0 {
+		// Never take Vertex down with the port's other users
+		if seen[pid] || pid <= 0 || pid == os.Getpid() {
 			continue
 		}
 
@@ -275,14 +335,176 @@ func killProcessForcefully(pid int) error {
 	return nil
 }
 
-// CleanupPortBeforeStart ensures a port is available before starting a service
-func CleanupPortBeforeStart(port int) error {
-	result := KillProcessesOnPort(port)
+// safePortCleanupEnabled reports whether port cleanup leaves processes
+// Vertex did not start alone until the cleanup is confirmed;
+// VERTEX_SAFE_PORT_CLEANUP=false kills whatever holds the port
+func safePortCleanupEnabled() bool {
+	value := strings.ToLower(os.Getenv("VERTEX_SAFE_PORT_CLEANUP"))
+	return value != "false" && value != "0" && value != "no"
+}
+
+// FindPortHolders describes the processes listening on a port and whether
+// Vertex started them
+func (sm *Manager) FindPortHolders(port int) []models.PortHolder {
+	recorded, err := sm.db.GetRecordedServicePIDs()
+	if err != nil {
+		log.Printf("[WARN] Failed to read recorded service PIDs: %v", err)
+	}
+
+	pids := findProcessesOnPort(port)
+	holders := make([]models.PortHolder, 0, len(pids))
+	for _, pid := range pids {
+		holders = append(holders, sm.describePortHolder(pid, recorded))
+	}
+	return holders
+}
+
+// describePortHolder reads the name, command, user and start time of a
+// process. It was spawned by Vertex when it is a service's process or in its
+// process group, when it still has the PID a service had before Vertex
+// restarted and runs in that service's directory, or when Vertex is among
+// its ancestors.
+func (sm *Manager) describePortHolder(pid int, recorded map[string]int) models.PortHolder {
+	holder := models.PortHolder{PID: pid}
+	proc, err := process.NewProcessWithContext(sm.ctx, int32(pid))
+	if err != nil {
+		return holder
+	}
+	if name, err := proc.NameWithContext(sm.ctx); err == nil {
+		holder.Name = name
+	}
+	if cmdline, err := proc.CmdlineWithContext(sm.ctx); err == nil {
+		holder.Command = cmdline
+	}
+	if username, err := proc.UsernameWithContext(sm.ctx); err == nil {
+		holder.User = username
+	}
+	if cwd, err := proc.CwdWithContext(sm.ctx); err == nil {
+		holder.Cwd = cwd
+	}
+	if created, err := proc.CreateTimeWithContext(sm.ctx); err == nil {
+		holder.StartedAt = time.UnixMilli(created)
+	}
+
+	pgid, _ := GetProcessGroup(pid)
+	for _, service := range sm.snapshotServices() {
+		service.Mutex.RLock()
+		servicePID := 0
+		if service.Cmd != nil {
+			servicePID = service.PID
+		}
+		serviceID, name, dir := service.ID, service.Name, service.Dir
+		service.Mutex.RUnlock()
+
+		previous := recorded[serviceID]
+		running := servicePID > 0 && (pid == servicePID || pgid == servicePID)
+		leftOver := previous > 0 && (pid == previous || pgid == previous) && processInServiceDir(holder.Cwd, dir)
+		if running || leftOver {
+			holder.SpawnedByVertex = true
+			holder.ServiceID = serviceID
+			holder.ServiceName = name
+			return holder
+		}
+	}
+
+	holder.SpawnedByVertex = descendsFromVertex(sm.ctx, proc)
+	return holder
+}
+
+// descendsFromVertex reports whether Vertex is the parent of a process or
+// one of its ancestors
+func descendsFromVertex(ctx context.Context, proc *process.Process) bool {
+	self := int32(os.Getpid())
+	for i := 0; i < portHolderAncestorDepth; i++ {
+		ppid, err := proc.PpidWithContext(ctx)
+		if err != nil || ppid <= 1 {
+			return false
+		}
+		if ppid == self {
+			return true
+		}
+		if proc, err = process.NewProcessWithContext(ctx, ppid); err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// describeHolder names a port holder for log and error messages
+func describeHolder(holder models.PortHolder) string {
+	description := fmt.Sprintf("PID %d", holder.PID)
+	if holder.Name != "" {
+		description += " (" + holder.Name + ")"
+	}
+	if holder.User != "" {
+		description += " of user " + holder.User
+	}
+	if !holder.StartedAt.IsZero() {
+		description += ", started " + holder.StartedAt.Format("2006-01-02 15:04")
+	}
+	return description
+}
+
+// CleanupPort frees a port. Unless force is set, only processes Vertex
+// started are killed: the others are reported in Skipped and the result asks
+// for confirmation. With safe cleanup turned off every holder is killed.
+func (sm *Manager) CleanupPort(port int, force bool) *PortCleanupResult {
+	holders := sm.FindPortHolders(port)
+	if force || !safePortCleanupEnabled() {
+		result := KillProcessesOnPort(port)
+		result.Holders = holders
+		return result
+	}
+
+	result := &PortCleanupResult{
+		Port:           port,
+		ProcessesFound: len(holders),
+		PIDs:           []int{},
+		Holders:        holders,
+		Skipped:        []int{},
+		Errors:         []string{},
+	}
+	for _, holder := range holders {
+		result.PIDs = append(result.PIDs, holder.PID)
+		if !holder.SpawnedByVertex {
+			result.Skipped = append(result.Skipped, holder.PID)
+			log.Printf("[WARN] Not killing %s on port %d: Vertex did not start it (command: %s)", describeHolder(holder), port, holder.Command)
+			continue
+		}
+		if err := killProcessGracefully(holder.PID); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to kill PID %d: %v", holder.PID, err))
+			log.Printf("[WARN] Failed to kill process %d: %v", holder.PID, err)
+			continue
+		}
+		result.ProcessesKilled++
+		log.Printf("[INFO] Killed %s on port %d", describeHolder(holder), port)
+	}
+	result.ConfirmationRequired = len(result.Skipped) > 0
+	return result
+}
+
+// CleanupPortBeforeStart ensures a port is available before starting a
+// service. A process Vertex did not start is left running and named in the
+// error.
+func (sm *Manager) CleanupPortBeforeStart(port int) error {
+	result := sm.CleanupPort(port, false)
 
 	if len(result.Errors) > 0 {
 		log.Printf("[WARN] Port cleanup had %d error(s): %v", len(result.Errors), result.Errors)
 	}
 
+	if result.ConfirmationRequired {
+		skipped := []string{}
+		for _, holder := range result.Holders {
+			if !holder.SpawnedByVertex && IsProcessRunning(holder.PID) {
+				skipped = append(skipped, describeHolder(holder))
+			}
+		}
+		if len(skipped) > 0 {
+			return fmt.Errorf("port %d is held by %s, which Vertex did not start; stop it or confirm the port cleanup with force", port, strings.Join(skipped, ", "))
+		}
+	}
+
 	// Final verification that port is available
 	finalCheck := findProcessesOnPort(port)
 	if len(finalCheck) > 0 {
@@ -0,0 +1,240 @@
+// Package services - Per-profile port pools for new services
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Ports new services get when their profile has no pool of its own
+const (
+	defaultPortPoolStart = 8100
+	defaultPortPoolEnd   = 8199
+)
+
+// portPool is a range of ports, both ends included
+type portPool struct {
+	start int
+	end   int
+}
+
+var defaultPortPool = portPool{start: defaultPortPoolStart, end: defaultPortPoolEnd}
+
+// PortAllocation is a service holding a port of a profile's pool, or a
+// service of the profile
+type PortAllocation struct {
+	Port        int      `json:"port"`
+	ServiceID   string   `json:"serviceId"`
+	ServiceName string   `json:"serviceName"`
+	InProfile   bool     `json:"inProfile"`            // False for services of other profiles on a port of the pool
+	InPool      bool     `json:"inPool"`               // False for services of the profile with a port outside the pool
+	SharedWith  []string `json:"sharedWith,omitempty"` // Other services set to the same port
+}
+
+// PortPoolStatus shows a profile's port pool and who holds its ports
+type PortPoolStatus struct {
+	ProfileID   string           `json:"profileId"`
+	ProfileName string           `json:"profileName"`
+	Start       int              `json:"start"`
+	End         int              `json:"end"`
+	IsDefault   bool             `json:"isDefault"` // The profile has no pool of its own
+	Size        int              `json:"size"`
+	Used        int              `json:"used"`     // Ports of the pool held by a service
+	NextFree    int              `json:"nextFree"` // The port a new service would get, 0 when the pool is exhausted
+	Allocations []PortAllocation `json:"allocations"`
+}
+
+// validatePortPool checks a pool's range; 0-0 selects the default pool
+func validatePortPool(start, end int) error {
+	if start == 0 && end == 0 {
+		return nil
+	}
+	if start < 1024 || end > 65535 {
+		return fmt.Errorf("invalid port pool %d-%d: ports must be between 1024 and 65535", start, end)
+	}
+	if start > end {
+		return fmt.Errorf("invalid port pool %d-%d: the first port is above the last", start, end)
+	}
+	return nil
+}
+
+// profilePortPool loads the port pool and service list of a profile
+func (sm *Manager) profilePortPool(profileID string) (name string, pool portPool, isDefault bool, serviceUUIDs []string, err error) {
+	var servicesJSON string
+	err = sm.db.QueryRow(`SELECT name, services_json, port_pool_start, port_pool_end FROM service_profiles WHERE id = ?`, profileID).
+		Scan(&name, &servicesJSON, &pool.start, &pool.end)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", portPool{}, false, nil, fmt.Errorf("profile %s not found", profileID)
+		}
+		return "", portPool{}, false, nil, fmt.Errorf("failed to load port pool: %w", err)
+	}
+	if err = json.Unmarshal([]byte(servicesJSON), &serviceUUIDs); err != nil {
+		return "", portPool{}, false, nil, fmt.Errorf("failed to parse profile services: %w", err)
+	}
+	if pool.start == 0 && pool.end == 0 {
+		return name, defaultPortPool, true, serviceUUIDs, nil
+	}
+	return name, pool, false, serviceUUIDs, nil
+}
+
+// servicesByPort groups the services by the port they are set to. The caller
+// holds the manager mutex.
+func (sm *Manager) servicesByPort() map[int][]*models.Service {
+	ports := make(map[int][]*models.Service)
+	for _, service := range sm.services {
+		if service.Port > 0 {
+			ports[service.Port] = append(ports[service.Port], service)
+		}
+	}
+	return ports
+}
+
+// freePoolPort returns the first port of a pool that no service is set to
+// and nothing listens on, or 0 when there is none
+func freePoolPort(pool portPool, taken map[int][]*models.Service) int {
+	for port := pool.start; port <= pool.end; port++ {
+		if len(taken[port]) == 0 && !portListening(port) {
+			return port
+		}
+	}
+	return 0
+}
+
+// portListening reports whether a process outside Vertex's services already
+// holds a port
+func portListening(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return true
+	}
+	listener.Close()
+	return false
+}
+
+// assignServicePort sets a service without a port to a free port of the pool,
+// or infrastructure to its usual port while that is free. The caller holds
+// the manager mutex.
+func (sm *Manager) assignServicePort(service *models.Service, pool portPool) error {
+	if service.Port != 0 {
+		return nil
+	}
+	taken := sm.servicesByPort()
+	if port := infraDefaultPort(service.InfraType); port > 0 && len(taken[port]) == 0 && !portListening(port) {
+		service.Port = port
+		log.Printf("[INFO] Assigned the default %s port %d to service %s", service.InfraType, port, service.Name)
+		return nil
+	}
+	port := freePoolPort(pool, taken)
+	if port == 0 {
+		return fmt.Errorf("port pool %d-%d is exhausted: set a port or widen the pool", pool.start, pool.end)
+	}
+	service.Port = port
+	if service.HealthURL == "" && service.InfraType == "" {
+		service.HealthURL = fmt.Sprintf("http://localhost:%d/actuator/health", port)
+	}
+	log.Printf("[INFO] Assigned port %d from pool %d-%d to service %s", port, pool.start, pool.end, service.Name)
+	return nil
+}
+
+// validateServicePort checks that no other service is set to a port. The
+// caller holds the manager mutex.
+func (sm *Manager) validateServicePort(serviceUUID string, port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %d: must be between 1 and 65535", port)
+	}
+	for _, other := range sm.servicesByPort()[port] {
+		if other.ID != serviceUUID {
+			return fmt.Errorf("invalid port %d: already used by service %s", port, other.Name)
+		}
+	}
+	return nil
+}
+
+// AddServiceInProfile adds a service like AddService, assigning a service
+// without a port a free port of the profile's pool
+func (sm *Manager) AddServiceInProfile(service *models.Service, profileID string) error {
+	pool := defaultPortPool
+	if profileID != "" {
+		_, profilePool, _, _, err := sm.profilePortPool(profileID)
+		if err != nil {
+			return err
+		}
+		pool = profilePool
+	}
+	return sm.addService(service, pool)
+}
+
+// servicePortTaken reports whether a service is already set to a port
+func (sm *Manager) servicePortTaken(port int) bool {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return len(sm.servicesByPort()[port]) > 0
+}
+
+// GetPortPoolStatus reports a profile's port pool, the services holding its
+// ports and the services of the profile outside it
+func (sm *Manager) GetPortPoolStatus(profileID string) (*PortPoolStatus, error) {
+	name, pool, isDefault, serviceUUIDs, err := sm.profilePortPool(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	inProfile := make(map[string]bool, len(serviceUUIDs))
+	for _, serviceUUID := range serviceUUIDs {
+		inProfile[serviceUUID] = true
+	}
+
+	status := &PortPoolStatus{
+		ProfileID:   profileID,
+		ProfileName: name,
+		Start:       pool.start,
+		End:         pool.end,
+		IsDefault:   isDefault,
+		Size:        pool.end - pool.start + 1,
+		Allocations: []PortAllocation{},
+	}
+
+	sm.mutex.RLock()
+	taken := sm.servicesByPort()
+	for port, services := range taken {
+		inPool := port >= pool.start && port <= pool.end
+		if inPool {
+			status.Used++
+		}
+		for _, service := range services {
+			if !inPool && !inProfile[service.ID] {
+				continue
+			}
+			allocation := PortAllocation{
+				Port:        port,
+				ServiceID:   service.ID,
+				ServiceName: service.Name,
+				InProfile:   inProfile[service.ID],
+				InPool:      inPool,
+			}
+			for _, other := range services {
+				if other.ID != service.ID {
+					allocation.SharedWith = append(allocation.SharedWith, other.Name)
+				}
+			}
+			status.Allocations = append(status.Allocations, allocation)
+		}
+	}
+	sm.mutex.RUnlock()
+
+	status.NextFree = freePoolPort(pool, taken)
+	sort.Slice(status.Allocations, func(i, j int) bool {
+		if status.Allocations[i].Port != status.Allocations[j].Port {
+			return status.Allocations[i].Port < status.Allocations[j].Port
+		}
+		return status.Allocations[i].ServiceName < status.Allocations[j].ServiceName
+	})
+	return status, nil
+}
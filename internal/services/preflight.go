@@ -0,0 +1,263 @@
+// Package services - Pre-start checks for tooling, Java and disk space
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// preflightMinFreeDiskMB is the free space below which a build is not started
+	preflightMinFreeDiskMB = 1024
+	// preflightLowFreeDiskMB is the free space below which a start is warned about
+	preflightLowFreeDiskMB = 4096
+)
+
+// Outcomes of a preflight check
+const (
+	PreflightPass = "pass"
+	PreflightWarn = "warn"
+	PreflightFail = "fail"
+)
+
+// PreflightCheck is the outcome of one pre-start check
+type PreflightCheck struct {
+	Name    string `json:"name"`           // "serviceDir", "projectsDir", "java", "buildTool", "runAsUser", "envVars", "node" or "disk"
+	Status  string `json:"status"`         // "pass", "warn" or "fail"
+	Code    string `json:"code,omitempty"` // Machine-readable reason for warn and fail, e.g. "java_home_invalid"
+	Message string `json:"message"`
+}
+
+// PreflightResult lists the checks run before starting a service
+type PreflightResult struct {
+	ServiceID   string           `json:"serviceId"`
+	ServiceName string           `json:"serviceName"`
+	Passed      bool             `json:"passed"` // No check failed
+	Checks      []PreflightCheck `json:"checks"`
+}
+
+// Failures returns the checks that failed
+func (r *PreflightResult) Failures() []PreflightCheck {
+	failures := []PreflightCheck{}
+	for _, check := range r.Checks {
+		if check.Status == PreflightFail {
+			failures = append(failures, check)
+		}
+	}
+	return failures
+}
+
+func (r *PreflightResult) add(name, status, code, message string) {
+	r.Checks = append(r.Checks, PreflightCheck{Name: name, Status: status, Code: code, Message: message})
+	if status == PreflightFail {
+		r.Passed = false
+	}
+}
+
+// PreflightError is returned when a failed preflight check prevents a start
+type PreflightError struct {
+	Result *PreflightResult
+}
+
+func (e *PreflightError) Error() string {
+	messages := []string{}
+	for _, check := range e.Result.Failures() {
+		messages = append(messages, check.Message)
+	}
+	return fmt.Sprintf("preflight checks failed for %s: %s", e.Result.ServiceName, strings.Join(messages, "; "))
+}
+
+// RunPreflight runs the pre-start checks of a service without starting it
+func (sm *Manager) RunPreflight(serviceUUID string) (*PreflightResult, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	return sm.runPreflight(service, sm.getServiceProjectsDirectory(serviceUUID)), nil
+}
+
+// preflightServiceStart runs the pre-start checks and refuses the start when
+// one fails. It must be called without holding the service's mutex.
+func (sm *Manager) preflightServiceStart(service *models.Service, projectsDir string) error {
+	result := sm.runPreflight(service, projectsDir)
+	for _, check := range result.Checks {
+		switch check.Status {
+		case PreflightWarn:
+			sm.logHookOutput(service, "WARN", "Preflight: "+check.Message)
+		case PreflightFail:
+			sm.logHookOutput(service, "ERROR", "Preflight: "+check.Message)
+		}
+	}
+	if !result.Passed {
+		return &PreflightError{Result: result}
+	}
+	return nil
+}
+
+// runPreflight checks everything a build needs before it is started: the
+// service and projects directories, Java, the build tool, the run-as user,
+// the environment variables' schemas, Node for services with a package.json,
+// and free disk space
+func (sm *Manager) runPreflight(service *models.Service, projectsDir string) *PreflightResult {
+	service.Mutex.RLock()
+	result := &PreflightResult{ServiceID: service.ID, ServiceName: service.Name, Passed: true}
+	serviceDir := serviceWorkingDir(projectsDir, service)
+	overrides := serviceCommandOverrides(projectsDir, service)
+	buildSystem := service.BuildSystem
+	executionMode := service.ExecutionMode
+	runAsUser := service.RunAsUser
+	javaHome := ""
+	if envVar, exists := service.EnvVars["JAVA_HOME"]; exists {
+		javaHome = envVar.Value
+	}
+	envVars := make(map[string]models.EnvVar, len(service.EnvVars))
+	for name, envVar := range service.EnvVars {
+		envVars[name] = envVar
+	}
+	service.Mutex.RUnlock()
+
+	if info, err := os.Stat(serviceDir); err != nil || !info.IsDir() {
+		result.add("serviceDir", PreflightFail, "service_dir_missing", fmt.Sprintf("service directory %s does not exist", serviceDir))
+		return result
+	}
+	result.add("serviceDir", PreflightPass, "", serviceDir)
+
+	if err := checkDirWritable(projectsDir); err != nil {
+		result.add("projectsDir", PreflightFail, "projects_dir_not_writable", fmt.Sprintf("projects directory %s is not writable: %v", projectsDir, err))
+	} else {
+		result.add("projectsDir", PreflightPass, "", projectsDir+" is writable")
+	}
+
+	sm.checkPreflightJava(result, sm.profileJavaHome(sm.getServiceProfileID(service.ID), service.ID), javaHome)
+	if overrides.replacesBuildTool(executionMode) {
+		result.add("buildTool", PreflightPass, "", "the service's own command runs the build")
+	} else {
+		checkPreflightBuildTool(result, serviceDir, GetEffectiveBuildSystem(serviceDir, buildSystem))
+	}
+	checkPreflightRunAsUser(result, runAsUser, serviceDir)
+	if env, _, err := sm.assembleServiceEnv(service, nil); err != nil {
+		result.add("envVars", PreflightWarn, "env_vars_unchecked", fmt.Sprintf("could not check environment variables: %v", err))
+	} else {
+		checkPreflightEnvVars(result, envVars, env)
+	}
+
+	if _, err := os.Stat(filepath.Join(serviceDir, "package.json")); err == nil {
+		if nodePath, err := exec.LookPath("node"); err != nil {
+			result.add("node", PreflightFail, "node_missing", "the service has a package.json but node is not in PATH")
+		} else {
+			result.add("node", PreflightPass, "", nodePath)
+		}
+	}
+
+	if usage, err := disk.Usage(serviceDir); err != nil {
+		result.add("disk", PreflightWarn, "disk_usage_unknown", fmt.Sprintf("could not determine free disk space: %v", err))
+	} else {
+		freeMB := int(usage.Free / (1024 * 1024))
+		switch {
+		case freeMB < preflightMinFreeDiskMB:
+			result.add("disk", PreflightFail, "disk_space_low", fmt.Sprintf("only %d MB free on %s; at least %d MB is needed to build", freeMB, usage.Path, preflightMinFreeDiskMB))
+		case freeMB < preflightLowFreeDiskMB:
+			result.add("disk", PreflightWarn, "disk_space_low", fmt.Sprintf("only %d MB free on %s", freeMB, usage.Path))
+		default:
+			result.add("disk", PreflightPass, "", fmt.Sprintf("%d MB free", freeMB))
+		}
+	}
+
+	return result
+}
+
+// checkPreflightJava verifies the JAVA_HOME the service will run with, in the
+// same order of precedence as the start itself, or java in PATH when none is set
+func (sm *Manager) checkPreflightJava(result *PreflightResult, profileJavaHome, serviceJavaHome string) {
+	javaHome, source := profileJavaHome, "profile Java version"
+	if javaHome == "" {
+		javaHome, source = serviceJavaHome, "service JAVA_HOME"
+	}
+	if javaHome == "" && sm.config.JavaHomeOverride != "" {
+		javaHome, source = sm.config.JavaHomeOverride, "Java home override"
+	}
+	if javaHome == "" {
+		if globalEnvVars, err := sm.GetGlobalEnvVars(); err == nil && globalEnvVars["JAVA_HOME"] != "" {
+			javaHome, source = globalEnvVars["JAVA_HOME"], "global JAVA_HOME"
+		}
+	}
+	if javaHome == "" && os.Getenv("JAVA_HOME") != "" {
+		javaHome, source = os.Getenv("JAVA_HOME"), "JAVA_HOME"
+	}
+
+	if javaHome != "" {
+		javaPath := filepath.Join(javaHome, "bin", getJavaExecutable())
+		if !isExecutable(javaPath) {
+			result.add("java", PreflightFail, "java_home_invalid", fmt.Sprintf("%s %s has no executable bin/%s", source, javaHome, getJavaExecutable()))
+			return
+		}
+		result.add("java", PreflightPass, "", javaPath)
+		return
+	}
+
+	javaPath, err := exec.LookPath("java")
+	if err != nil {
+		result.add("java", PreflightFail, "java_missing", "java is not in PATH and no JAVA_HOME is configured")
+		return
+	}
+	result.add("java", PreflightPass, "", javaPath)
+}
+
+// checkPreflightBuildTool verifies the build file and a runnable wrapper, or
+// for Maven an mvn in PATH that Vertex can generate the wrapper with
+func checkPreflightBuildTool(result *PreflightResult, serviceDir string, buildSystem BuildSystemType) {
+	wrapper, buildFiles := "mvnw", []string{"pom.xml"}
+	if buildSystem == BuildSystemGradle {
+		wrapper, buildFiles = "gradlew", []string{"build.gradle", "build.gradle.kts"}
+	}
+
+	hasBuildFile := false
+	for _, file := range buildFiles {
+		if _, err := os.Stat(filepath.Join(serviceDir, file)); err == nil {
+			hasBuildFile = true
+		}
+	}
+	if !hasBuildFile {
+		result.add("buildTool", PreflightFail, "build_file_missing", fmt.Sprintf("no %s in %s", strings.Join(buildFiles, " or "), serviceDir))
+		return
+	}
+
+	wrapperPath := filepath.Join(serviceDir, wrapper)
+	if _, err := os.Stat(wrapperPath); err == nil {
+		if !isExecutable(wrapperPath) {
+			result.add("buildTool", PreflightFail, "wrapper_not_executable", fmt.Sprintf("%s is not executable (chmod +x %s)", wrapper, wrapper))
+			return
+		}
+		result.add("buildTool", PreflightPass, "", wrapperPath)
+		return
+	}
+
+	// Only the Maven wrapper is generated on start; a Gradle wrapper has to
+	// be generated first (POST /api/services/{id}/wrapper/generate)
+	if buildSystem == BuildSystemGradle {
+		result.add("buildTool", PreflightFail, "wrapper_missing", fmt.Sprintf("no ./%s in %s; generate the Gradle wrapper first", wrapper, serviceDir))
+		return
+	}
+	mvnPath, err := exec.LookPath("mvn")
+	if err != nil {
+		result.add("buildTool", PreflightFail, "build_tool_missing", "no ./mvnw in the service and mvn is not in PATH")
+		return
+	}
+	result.add("buildTool", PreflightWarn, "wrapper_missing", "no ./mvnw; it will be generated with "+mvnPath)
+}
+
+// checkDirWritable creates and removes a temporary file in dir
+func checkDirWritable(dir string) error {
+	file, err := os.CreateTemp(dir, ".vertex-preflight-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,428 @@
+// Package services - Synthetic request probes with latency and success-rate objectives
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	probeTickInterval        = 5 * time.Second
+	defaultProbeInterval     = 30
+	minProbeInterval         = 5
+	maxProbeInterval         = 3600
+	defaultProbeSuccessRate  = 99.0
+	defaultProbeWindow       = 60
+	maxProbeWindow           = 7 * 24 * 60
+	minProbeSamples          = 5 // objectives are not judged on fewer results
+	probeResultRetention     = 30 * 24 * time.Hour
+	probeCleanupInterval     = time.Hour
+	maxProbeErrorMessageSize = 500
+	maxProbeBodySize         = 1 << 20 // bytes read of a response, so the latency covers the body
+)
+
+// probeState is what the probe monitor remembers of a probe between requests
+type probeState struct {
+	lastRun       time.Time
+	running       bool
+	violatedSince time.Time
+}
+
+var (
+	probeStates      = make(map[int64]*probeState)
+	probeStatesMutex sync.Mutex
+)
+
+// validateServiceProbe trims a probe and fills in its defaults
+func validateServiceProbe(probe *models.ServiceProbe) error {
+	probe.Name = strings.TrimSpace(probe.Name)
+	probe.URL = strings.TrimSpace(probe.URL)
+	probe.Method = strings.ToUpper(strings.TrimSpace(probe.Method))
+
+	if probe.Name == "" {
+		return fmt.Errorf("probe name is required")
+	}
+	if probe.URL == "" {
+		return fmt.Errorf("probe url is required")
+	}
+	if !strings.HasPrefix(probe.URL, "/") {
+		parsed, err := url.Parse(probe.URL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return fmt.Errorf("invalid probe url '%s' (use a path such as /api/orders or an http(s) URL)", probe.URL)
+		}
+	}
+
+	switch probe.Method {
+	case "":
+		probe.Method = http.MethodGet
+	case http.MethodGet, http.MethodHead:
+	default:
+		return fmt.Errorf("invalid probe method '%s'; use GET or HEAD", probe.Method)
+	}
+
+	if probe.IntervalSeconds == 0 {
+		probe.IntervalSeconds = defaultProbeInterval
+	}
+	if probe.IntervalSeconds < minProbeInterval || probe.IntervalSeconds > maxProbeInterval {
+		return fmt.Errorf("intervalSeconds must be between %d and %d", minProbeInterval, maxProbeInterval)
+	}
+	if probe.ExpectedStatus != 0 && (probe.ExpectedStatus < 100 || probe.ExpectedStatus > 599) {
+		return fmt.Errorf("invalid expectedStatus %d", probe.ExpectedStatus)
+	}
+	if probe.MaxLatencyMs < 0 {
+		return fmt.Errorf("maxLatencyMs cannot be negative")
+	}
+	if probe.TargetSuccessRate == 0 {
+		probe.TargetSuccessRate = defaultProbeSuccessRate
+	}
+	if probe.TargetSuccessRate < 0 || probe.TargetSuccessRate > 100 {
+		return fmt.Errorf("targetSuccessRate must be a percentage between 0 and 100")
+	}
+	if probe.WindowMinutes == 0 {
+		probe.WindowMinutes = defaultProbeWindow
+	}
+	if probe.WindowMinutes < 1 || probe.WindowMinutes > maxProbeWindow {
+		return fmt.Errorf("windowMinutes must be between 1 and %d", maxProbeWindow)
+	}
+	return nil
+}
+
+// GetServiceProbes returns the probes of a service with their rolling success
+// rate, p95 latency and objective status
+func (sm *Manager) GetServiceProbes(serviceUUID string) ([]models.ProbeStatus, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	probes, err := sm.db.GetServiceProbes(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]models.ProbeStatus, 0, len(probes))
+	for _, probe := range probes {
+		status, err := sm.probeStatus(probe)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// SaveServiceProbe validates and creates or updates a probe; the probe
+// monitor picks it up on its next tick
+func (sm *Manager) SaveServiceProbe(probe *models.ServiceProbe) error {
+	if _, exists := sm.GetServiceByUUID(probe.ServiceID); !exists {
+		return fmt.Errorf("service UUID %s not found", probe.ServiceID)
+	}
+	if probe.ID != 0 {
+		existing, err := sm.db.GetServiceProbe(probe.ServiceID, probe.ID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return fmt.Errorf("probe %d not found", probe.ID)
+		}
+	}
+	if err := validateServiceProbe(probe); err != nil {
+		return err
+	}
+
+	others, err := sm.db.GetServiceProbes(probe.ServiceID)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if other.ID != probe.ID && strings.EqualFold(other.Name, probe.Name) {
+			return fmt.Errorf("probe name '%s' already exists for this service", probe.Name)
+		}
+	}
+
+	if err := sm.db.SaveServiceProbe(probe); err != nil {
+		return err
+	}
+
+	// Send a changed probe straight away rather than after its old interval
+	probeStatesMutex.Lock()
+	if state, exists := probeStates[probe.ID]; exists {
+		state.lastRun = time.Time{}
+	}
+	probeStatesMutex.Unlock()
+	return nil
+}
+
+// DeleteServiceProbe removes a probe and its results
+func (sm *Manager) DeleteServiceProbe(serviceUUID string, probeID int64) error {
+	removed, err := sm.db.DeleteServiceProbe(serviceUUID, probeID)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("probe %d not found", probeID)
+	}
+
+	probeStatesMutex.Lock()
+	delete(probeStates, probeID)
+	probeStatesMutex.Unlock()
+	return nil
+}
+
+// GetProbeResults returns the results of a service's probe newer than since,
+// oldest first, keeping the newest limit
+func (sm *Manager) GetProbeResults(serviceUUID string, probeID int64, since time.Time, limit int) ([]models.ProbeResult, error) {
+	probe, err := sm.db.GetServiceProbe(serviceUUID, probeID)
+	if err != nil {
+		return nil, err
+	}
+	if probe == nil {
+		return nil, fmt.Errorf("probe %d not found", probeID)
+	}
+	return sm.db.GetProbeResults(probeID, since, limit)
+}
+
+// probeStatus measures a probe's results over its window against its objective
+func (sm *Manager) probeStatus(probe models.ServiceProbe) (*models.ProbeStatus, error) {
+	results, err := sm.db.GetProbeResults(probe.ID, time.Now().Add(-time.Duration(probe.WindowMinutes)*time.Minute), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.ProbeStatus{Probe: probe, Samples: len(results), SuccessRate: 100, Violations: []string{}}
+	if len(results) == 0 {
+		return status, nil
+	}
+
+	successes := 0
+	latencies := make([]int64, 0, len(results))
+	for _, result := range results {
+		if result.Success {
+			successes++
+		}
+		latencies = append(latencies, result.LatencyMs)
+	}
+	status.SuccessRate = math.Round(float64(successes)/float64(len(results))*10000) / 100
+	status.P95LatencyMs = percentileLatency(latencies, 95)
+	last := results[len(results)-1]
+	status.LastResult = &last
+
+	if len(results) >= minProbeSamples {
+		if status.SuccessRate < probe.TargetSuccessRate {
+			status.Violations = append(status.Violations,
+				fmt.Sprintf("success rate %.2f%% is below the %.2f%% objective", status.SuccessRate, probe.TargetSuccessRate))
+		}
+		if probe.MaxLatencyMs > 0 && status.P95LatencyMs > int64(probe.MaxLatencyMs) {
+			status.Violations = append(status.Violations,
+				fmt.Sprintf("p95 latency %dms is above the %dms objective", status.P95LatencyMs, probe.MaxLatencyMs))
+		}
+	}
+	status.Violated = len(status.Violations) > 0
+
+	probeStatesMutex.Lock()
+	if state, exists := probeStates[probe.ID]; exists && !state.violatedSince.IsZero() {
+		since := state.violatedSince
+		status.ViolatedSince = &since
+	}
+	probeStatesMutex.Unlock()
+
+	return status, nil
+}
+
+// percentileLatency returns the nearest-rank percentile of the latencies
+func percentileLatency(latencies []int64, percentile float64) int64 {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// startProbeMonitor sends the probes of running services when they are due
+// and prunes old results
+func (sm *Manager) startProbeMonitor(ctx context.Context) {
+	ticker := time.NewTicker(probeTickInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Now()
+
+	log.Printf("[INFO] Started service probe monitor (%s tick)", probeTickInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sm.runDueProbes(ctx)
+			if time.Since(lastCleanup) >= probeCleanupInterval {
+				lastCleanup = time.Now()
+				if removed, err := sm.db.CleanupProbeResults(time.Now().Add(-probeResultRetention)); err != nil {
+					log.Printf("[WARN] Probe result cleanup failed: %v", err)
+				} else if removed > 0 {
+					log.Printf("[INFO] Removed %d probe results older than %s", removed, probeResultRetention)
+				}
+			}
+		}
+	}
+}
+
+// runDueProbes starts every enabled probe whose interval has passed and whose
+// service is up. Stopped and starting services are not probed, so their
+// downtime does not count against the objective.
+func (sm *Manager) runDueProbes(ctx context.Context) {
+	probes, err := sm.db.GetServiceProbes("")
+	if err != nil {
+		log.Printf("[WARN] Probe monitor could not load probes: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, probe := range probes {
+		if !probe.Enabled {
+			continue
+		}
+		service, exists := sm.GetServiceByUUID(probe.ServiceID)
+		if !exists {
+			continue
+		}
+		service.Mutex.RLock()
+		up := service.Status == "running" && service.HealthStatus != "starting"
+		port := service.Port
+		service.Mutex.RUnlock()
+		if !up {
+			continue
+		}
+
+		probeStatesMutex.Lock()
+		state, exists := probeStates[probe.ID]
+		if !exists {
+			state = &probeState{}
+			probeStates[probe.ID] = state
+		}
+		due := !state.running && now.Sub(state.lastRun) >= time.Duration(probe.IntervalSeconds)*time.Second
+		if due {
+			state.running = true
+			state.lastRun = now
+		}
+		probeStatesMutex.Unlock()
+
+		if due {
+			go sm.runProbe(ctx, service, probe, port)
+		}
+	}
+}
+
+// runProbe sends one request of a probe, records the result and alerts when
+// the probe starts or stops meeting its objective
+func (sm *Manager) runProbe(ctx context.Context, service *models.Service, probe models.ServiceProbe, port int) {
+	defer func() {
+		probeStatesMutex.Lock()
+		if state, exists := probeStates[probe.ID]; exists {
+			state.running = false
+		}
+		probeStatesMutex.Unlock()
+	}()
+
+	result := sm.sendProbeRequest(ctx, probe, port)
+	if err := sm.db.InsertProbeResult(result); err != nil {
+		log.Printf("[WARN] %v", err)
+		return
+	}
+
+	status, err := sm.probeStatus(probe)
+	if err != nil {
+		log.Printf("[WARN] %v", err)
+		return
+	}
+
+	probeStatesMutex.Lock()
+	state, exists := probeStates[probe.ID]
+	if !exists {
+		probeStatesMutex.Unlock()
+		return
+	}
+	wasViolated := !state.violatedSince.IsZero()
+	switch {
+	case status.Violated && !wasViolated:
+		state.violatedSince = result.Timestamp
+	case !status.Violated && wasViolated:
+		state.violatedSince = time.Time{}
+	}
+	probeStatesMutex.Unlock()
+
+	service.Mutex.RLock()
+	defer service.Mutex.RUnlock()
+	switch {
+	case status.Violated && !wasViolated:
+		message := fmt.Sprintf("Probe %s missed its objective over the last %d minutes: %s",
+			probe.Name, probe.WindowMinutes, strings.Join(status.Violations, "; "))
+		log.Printf("[WARN] Service %s: %s", service.Name, message)
+		sm.recordServiceEvent(service, models.EventSLOViolated, message)
+	case !status.Violated && wasViolated:
+		log.Printf("[INFO] Service %s: probe %s meets its objective again", service.Name, probe.Name)
+		sm.recordServiceEvent(service, models.EventSLORecovered,
+			fmt.Sprintf("Probe %s meets its objective again (success rate %.2f%%, p95 latency %dms)", probe.Name, status.SuccessRate, status.P95LatencyMs))
+	}
+}
+
+// sendProbeRequest sends a probe's request with the service's health check
+// headers, authentication, timeout and TLS settings
+func (sm *Manager) sendProbeRequest(ctx context.Context, probe models.ServiceProbe, port int) models.ProbeResult {
+	result := models.ProbeResult{ProbeID: probe.ID, Timestamp: time.Now()}
+
+	target := probe.URL
+	if strings.HasPrefix(target, "/") {
+		target = fmt.Sprintf("http://localhost:%d%s", port, target)
+	}
+
+	config := sm.healthCheckConfig(probe.ServiceID)
+	req, err := http.NewRequestWithContext(ctx, probe.Method, target, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	sm.applyHealthCheckConfig(req, config)
+
+	started := time.Now()
+	resp, err := sm.createHealthCheckClient(config).Do(req)
+	if err != nil {
+		result.LatencyMs = time.Since(started).Milliseconds()
+		result.Error = truncateProbeError(err.Error())
+		return result
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxProbeBodySize))
+	resp.Body.Close()
+	result.LatencyMs = time.Since(started).Milliseconds()
+	result.StatusCode = resp.StatusCode
+
+	if probe.ExpectedStatus != 0 {
+		result.Success = resp.StatusCode == probe.ExpectedStatus
+	} else {
+		result.Success = resp.StatusCode >= 200 && resp.StatusCode < 400
+	}
+	if !result.Success {
+		result.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return result
+}
+
+func truncateProbeError(message string) string {
+	if len(message) > maxProbeErrorMessageSize {
+		return message[:maxProbeErrorMessageSize]
+	}
+	return message
+}
@@ -3,7 +3,11 @@
 package services
 
 import (
+	"fmt"
+	"os"
 	"os/exec"
+	"os/user"
+	"strconv"
 	"syscall"
 )
 
@@ -43,3 +47,57 @@ func IsProcessRunning(pid int) bool {
 	err := syscall.Kill(pid, 0)
 	return err == nil
 }
+
+// ReloadProcessGroup asks a process group to reload its configuration (SIGHUP)
+func ReloadProcessGroup(pgid int) error {
+	return syscall.Kill(-pgid, syscall.SIGHUP)
+}
+
+// PauseProcessGroup freezes a process group in place (SIGSTOP)
+func PauseProcessGroup(pgid int) error {
+	return syscall.Kill(-pgid, syscall.SIGSTOP)
+}
+
+// ResumeProcessGroup continues a process group frozen by PauseProcessGroup (SIGCONT)
+func ResumeProcessGroup(pgid int) error {
+	return syscall.Kill(-pgid, syscall.SIGCONT)
+}
+
+// RunAsUser makes cmd run as another OS user. Running as root, the process
+// switches user itself; otherwise cmd is wrapped in non-interactive sudo,
+// which needs a sudoers rule for the Vertex user. It reports whether sudo
+// is used.
+func RunAsUser(cmd *exec.Cmd, account *user.User) (bool, error) {
+	if os.Geteuid() != 0 {
+		sudoPath, err := exec.LookPath("sudo")
+		if err != nil {
+			return false, fmt.Errorf("sudo is required to run services as %s", account.Username)
+		}
+		cmd.Args = append([]string{"sudo", "-n", "--preserve-env", "-u", account.Username, "--"}, cmd.Args...)
+		cmd.Path = sudoPath
+		return true, nil
+	}
+
+	uid, err := strconv.ParseUint(account.Uid, 10, 32)
+	if err != nil {
+		return false, fmt.Errorf("invalid uid %s of user %s", account.Uid, account.Username)
+	}
+	gid, err := strconv.ParseUint(account.Gid, 10, 32)
+	if err != nil {
+		return false, fmt.Errorf("invalid gid %s of user %s", account.Gid, account.Username)
+	}
+	credential := &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)}
+	if groupIDs, err := account.GroupIds(); err == nil {
+		for _, groupID := range groupIDs {
+			if id, err := strconv.ParseUint(groupID, 10, 32); err == nil {
+				credential.Groups = append(credential.Groups, uint32(id))
+			}
+		}
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = credential
+	return false, nil
+}
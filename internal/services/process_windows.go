@@ -3,7 +3,9 @@
 package services
 
 import (
+	"fmt"
 	"os/exec"
+	"os/user"
 	"syscall"
 )
 
@@ -88,3 +90,23 @@ func IsProcessRunning(pid int) bool {
 
 	return true
 }
+
+// ReloadProcessGroup is not supported on Windows, which has no SIGHUP
+func ReloadProcessGroup(pgid int) error {
+	return fmt.Errorf("configuration reload signals are not supported on Windows")
+}
+
+// PauseProcessGroup is not supported on Windows, which has no SIGSTOP
+func PauseProcessGroup(pgid int) error {
+	return fmt.Errorf("pausing services is not supported on Windows")
+}
+
+// ResumeProcessGroup is not supported on Windows, which has no SIGCONT
+func ResumeProcessGroup(pgid int) error {
+	return fmt.Errorf("resuming services is not supported on Windows")
+}
+
+// RunAsUser is not supported on Windows
+func RunAsUser(cmd *exec.Cmd, account *user.User) (bool, error) {
+	return false, fmt.Errorf("running services as another user is not supported on Windows")
+}
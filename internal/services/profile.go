@@ -7,7 +7,6 @@ import (
 	"log"
 	"slices"
 	"sync"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/zechtz/vertex/internal/database"
@@ -128,7 +127,7 @@ func (ps *ProfileService) GetServiceProfiles(userID string) ([]models.ServicePro
 	ps.mutex.RLock()
 	defer ps.mutex.RUnlock()
 
-	query := `SELECT id, user_id, name, description, services_json, env_vars_json, projects_dir, java_home_override, is_default, is_active, created_at, updated_at 
+	query := `SELECT id, user_id, name, description, services_json, env_vars_json, projects_dir, java_home_override, is_default, is_active, memory_budget_mb, memory_budget_mode, port_pool_start, port_pool_end, created_at, updated_at 
 			  FROM service_profiles WHERE user_id = ? ORDER BY is_active DESC, is_default DESC, created_at DESC`
 
 	rows, err := ps.db.Query(query, userID)
@@ -153,6 +152,10 @@ func (ps *ProfileService) GetServiceProfiles(userID string) ([]models.ServicePro
 			&profile.JavaHomeOverride,
 			&profile.IsDefault,
 			&profile.IsActive,
+			&profile.MemoryBudgetMB,
+			&profile.MemoryBudgetMode,
+			&profile.PortPoolStart,
+			&profile.PortPoolEnd,
 			&profile.CreatedAt,
 			&profile.UpdatedAt,
 		)
@@ -187,7 +190,7 @@ func (ps *ProfileService) getServiceProfileInternal(profileID, userID string) (*
 	var profile models.ServiceProfile
 	var servicesJSON, envVarsJSON string
 
-	query := `SELECT id, user_id, name, description, services_json, env_vars_json, projects_dir, java_home_override, is_default, is_active, created_at, updated_at 
+	query := `SELECT id, user_id, name, description, services_json, env_vars_json, projects_dir, java_home_override, is_default, is_active, memory_budget_mb, memory_budget_mode, port_pool_start, port_pool_end, created_at, updated_at 
 			  FROM service_profiles WHERE id = ? AND user_id = ?`
 
 	err := ps.db.QueryRow(query, profileID, userID).Scan(
@@ -201,6 +204,10 @@ func (ps *ProfileService) getServiceProfileInternal(profileID, userID string) (*
 		&profile.JavaHomeOverride,
 		&profile.IsDefault,
 		&profile.IsActive,
+		&profile.MemoryBudgetMB,
+		&profile.MemoryBudgetMode,
+		&profile.PortPoolStart,
+		&profile.PortPoolEnd,
 		&profile.CreatedAt,
 		&profile.UpdatedAt,
 	)
@@ -276,6 +283,7 @@ func (ps *ProfileService) CreateServiceProfile(userID string, req *models.Create
 		return nil, fmt.Errorf("failed to create service profile: %w", err)
 	}
 
+	ps.broadcastProfileUpdate(profileID, userID, "created")
 	return ps.getServiceProfileInternal(profileID, userID)
 }
 
@@ -346,6 +354,7 @@ func (ps *ProfileService) UpdateServiceProfile(profileID, userID string, req *mo
 		return nil, fmt.Errorf("failed to update service profile: %w", err)
 	}
 	log.Printf("[DEBUG] Database update successful")
+	ps.broadcastProfileUpdate(profileID, userID, "updated")
 
 	log.Printf("[DEBUG] Fetching updated profile...")
 	result, err := ps.getServiceProfileInternal(profileID, userID)
@@ -363,7 +372,7 @@ func (ps *ProfileService) DeleteServiceProfile(profileID, userID string) error {
 	defer ps.mutex.Unlock()
 
 	// Check if profile exists and belongs to user
-	if _, err := ps.GetServiceProfile(profileID, userID); err != nil {
+	if _, err := ps.getServiceProfileInternal(profileID, userID); err != nil {
 		return err
 	}
 
@@ -382,6 +391,14 @@ func (ps *ProfileService) DeleteServiceProfile(profileID, userID string) error {
 		return fmt.Errorf("no profile deleted")
 	}
 
+	// Take the profile's hostnames out of the hosts file and nginx
+	if ps.sm != nil {
+		if err := ps.sm.RemoveProfileHostnames(profileID); err != nil {
+			log.Printf("[WARN] Failed to remove hostnames of deleted profile %s: %v", profileID, err)
+		}
+	}
+
+	ps.broadcastProfileUpdate(profileID, userID, "deleted")
 	return nil
 }
 
@@ -443,6 +460,9 @@ func (ps *ProfileService) ApplyProfile(profileID, userID string) error {
 		}
 	}
 
+	// Swap in the profile's file overlays while everything is stopped
+	ps.materializeFileOverlays(profile)
+
 	// Start services specified in profile with dependency ordering
 	if ps.sm != nil && len(profile.Services) > 0 {
 		log.Printf("[INFO] Starting %d services from profile", len(profile.Services))
@@ -478,7 +498,7 @@ func (ps *ProfileService) SetActiveProfile(userID, profileID string) error {
 	log.Printf("[INFO] Setting active profile %s for user %s", profileID, userID)
 
 	// Verify the profile exists and belongs to the user
-	_, err := ps.getServiceProfileInternal(profileID, userID)
+	profile, err := ps.getServiceProfileInternal(profileID, userID)
 	if err != nil {
 		return fmt.Errorf("profile validation failed: %w", err)
 	}
@@ -488,7 +508,101 @@ func (ps *ProfileService) SetActiveProfile(userID, profileID string) error {
 		return fmt.Errorf("failed to set active profile: %w", err)
 	}
 
+	ps.materializeFileOverlays(profile)
+
 	log.Printf("[INFO] Active profile set successfully")
+	ps.broadcastProfileUpdate(profileID, userID, "activated")
+	return nil
+}
+
+// ProfileUpdate is the payload of profile_update messages; clients refetch
+// the profile it names
+type ProfileUpdate struct {
+	ProfileID string `json:"profileId"`
+	UserID    string `json:"userId"`
+	Action    string `json:"action"` // "created", "updated", "deleted" or "activated"
+}
+
+// broadcastProfileUpdate tells websocket clients a profile changed
+func (ps *ProfileService) broadcastProfileUpdate(profileID, userID, action string) {
+	if ps.sm == nil {
+		return
+	}
+	ps.sm.broadcastMessage(WebSocketMessage{
+		Type:    "profile_update",
+		Payload: ProfileUpdate{ProfileID: profileID, UserID: userID, Action: action},
+	})
+}
+
+// SetProfileJavaHomeOverride updates only the Java home override of a profile
+func (ps *ProfileService) SetProfileJavaHomeOverride(profileID, userID, javaHome string) error {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if _, err := ps.getServiceProfileInternal(profileID, userID); err != nil {
+		return fmt.Errorf("profile validation failed: %w", err)
+	}
+
+	_, err := ps.db.Exec(`UPDATE service_profiles SET java_home_override = ?, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = ? AND user_id = ?`, javaHome, profileID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update java home override: %w", err)
+	}
+
+	log.Printf("[INFO] Java home override for profile %s set to: %s", profileID, javaHome)
+	return nil
+}
+
+// SetProfileMemoryBudget updates the memory budget of a profile; a budget of 0 disables admission control
+func (ps *ProfileService) SetProfileMemoryBudget(profileID, userID string, budgetMB int, mode string) error {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if budgetMB < 0 {
+		return fmt.Errorf("memory budget cannot be negative")
+	}
+	if mode == "" {
+		mode = MemoryBudgetWarn
+	}
+	if mode != MemoryBudgetWarn && mode != MemoryBudgetEnforce {
+		return fmt.Errorf("invalid memory budget mode %q (expected %q or %q)", mode, MemoryBudgetWarn, MemoryBudgetEnforce)
+	}
+
+	if _, err := ps.getServiceProfileInternal(profileID, userID); err != nil {
+		return fmt.Errorf("profile validation failed: %w", err)
+	}
+
+	_, err := ps.db.Exec(`UPDATE service_profiles SET memory_budget_mb = ?, memory_budget_mode = ?, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = ? AND user_id = ?`, budgetMB, mode, profileID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update memory budget: %w", err)
+	}
+
+	log.Printf("[INFO] Memory budget for profile %s set to %d MB (%s)", profileID, budgetMB, mode)
+	return nil
+}
+
+// SetProfilePortPool sets the ports new services of a profile are assigned
+// from; 0-0 returns the profile to the default pool
+func (ps *ProfileService) SetProfilePortPool(profileID, userID string, start, end int) error {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	if err := validatePortPool(start, end); err != nil {
+		return err
+	}
+
+	if _, err := ps.getServiceProfileInternal(profileID, userID); err != nil {
+		return fmt.Errorf("profile validation failed: %w", err)
+	}
+
+	_, err := ps.db.Exec(`UPDATE service_profiles SET port_pool_start = ?, port_pool_end = ?, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = ? AND user_id = ?`, start, end, profileID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update port pool: %w", err)
+	}
+
+	log.Printf("[INFO] Port pool for profile %s set to %d-%d", profileID, start, end)
 	return nil
 }
 
@@ -703,20 +817,7 @@ func (ps *ProfileService) RemoveServiceFromProfile(userID, profileID, serviceNam
 // Helper methods
 
 func (ps *ProfileService) createDefaultUserProfile(userID string) (*models.UserProfile, error) {
-	defaultPreferences := models.UserPreferences{
-		Theme:    "light",
-		Language: "en",
-		NotificationSettings: map[string]bool{
-			"serviceStatus": true,
-			"errors":        true,
-			"deployments":   true,
-		},
-		DashboardLayout: "grid",
-		AutoRefresh:     true,
-		RefreshInterval: 30,
-	}
-
-	preferencesJSON, err := json.Marshal(defaultPreferences)
+	preferencesJSON, err := json.Marshal(defaultUserPreferences())
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal default preferences: %w", err)
 	}
@@ -815,6 +916,40 @@ func (ps *ProfileService) validateServicesForProfile(profile *models.ServiceProf
 	return nil
 }
 
+// MaterializeFileOverlays writes a profile's file overlays into its service
+// directories, restoring files written for any other profile
+func (ps *ProfileService) MaterializeFileOverlays(profileID, userID string) (*FileOverlayMaterialization, error) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	profile, err := ps.getServiceProfileInternal(profileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("profile not found: %w", err)
+	}
+	if ps.sm == nil {
+		return nil, fmt.Errorf("service manager not available")
+	}
+
+	return ps.sm.MaterializeProfileFileOverlays(profile.ID, profile.ProjectsDir)
+}
+
+// materializeFileOverlays switches service files to the profile's overlays,
+// logging rather than failing so a bad overlay never blocks a profile switch
+func (ps *ProfileService) materializeFileOverlays(profile *models.ServiceProfile) {
+	if ps.sm == nil {
+		return
+	}
+
+	result, err := ps.sm.MaterializeProfileFileOverlays(profile.ID, profile.ProjectsDir)
+	if err != nil {
+		log.Printf("[WARN] Failed to apply file overlays for profile '%s': %v", profile.Name, err)
+		return
+	}
+	for _, warning := range result.Warnings {
+		log.Printf("[WARN] File overlays for profile '%s': %s", profile.Name, warning)
+	}
+}
+
 // applyProjectsDirectory sets the projects directory for service operations
 func (ps *ProfileService) applyProjectsDirectory(projectsDir string) error {
 	// Set the PROJECTS_DIR environment variable
@@ -854,17 +989,14 @@ func (ps *ProfileService) startServicesWithDependencies(serviceNames []string) e
 		return fmt.Errorf("service manager not available")
 	}
 
-	// Get all available services with their dependency information
-	allServices := ps.sm.GetServices()
-	serviceMap := make(map[string]*models.Service)
-	for _, service := range allServices {
-		serviceMap[service.Name] = &service
-	}
-
-	// Filter to only the services we want to start
+	// Profiles list services by UUID; older ones by name
 	var servicesToStart []*models.Service
 	for _, name := range serviceNames {
-		if service, exists := serviceMap[name]; exists {
+		service, exists := ps.sm.GetServiceByUUID(name)
+		if !exists {
+			service, exists = ps.sm.GetServiceByName(name)
+		}
+		if exists {
 			servicesToStart = append(servicesToStart, service)
 		} else {
 			log.Printf("[WARN] Service '%s' not found, skipping", name)
@@ -875,32 +1007,10 @@ func (ps *ProfileService) startServicesWithDependencies(serviceNames []string) e
 		return fmt.Errorf("no valid services to start")
 	}
 
-	// Sort services by their order field (this provides basic dependency ordering)
-	// For more sophisticated dependency management, we'd use the dependency graph
-	sortedServices := make([]*models.Service, len(servicesToStart))
-	copy(sortedServices, servicesToStart)
-
-	// Simple bubble sort by order field
-	for i := 0; i < len(sortedServices)-1; i++ {
-		for j := 0; j < len(sortedServices)-i-1; j++ {
-			if sortedServices[j].Order > sortedServices[j+1].Order {
-				sortedServices[j], sortedServices[j+1] = sortedServices[j+1], sortedServices[j]
-			}
-		}
-	}
-
-	// Start services in dependency order
-	for _, service := range sortedServices {
-		log.Printf("[INFO] Starting service: %s (order: %d)", service.Name, service.Order)
-
-		if err := ps.sm.StartService(service.Name); err != nil {
-			log.Printf("[ERROR] Failed to start service %s: %v", service.Name, err)
-			// Continue starting other services rather than failing completely
-			continue
-		}
-
-		// Brief delay between service starts to allow proper initialization
-		time.Sleep(2 * time.Second)
+	// Failed services are in the startup report; the others keep running
+	report := ps.sm.startServicesInDependencyOrder(ps.sm.ctx, servicesToStart, ps.sm.GetConfig().ProjectsDir)
+	if report.Status == "failed" {
+		log.Printf("[WARN] Some services of the profile did not start; see /api/services/start-all/report")
 	}
 
 	return nil
@@ -0,0 +1,329 @@
+// Package services - Exporting profiles to bundles and importing them for other users
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// ExportProfile bundles a profile with its environment variables, service
+// configuration, file overlays and build settings. Services that no longer
+// exist are left out, along with the configuration and overlays of services
+// outside the profile.
+func (ps *ProfileService) ExportProfile(profileID, userID string) (*models.ProfileBundle, error) {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	profile, err := ps.getServiceProfileInternal(profileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &models.ProfileBundle{
+		FormatVersion:    models.ProfileBundleFormatVersion,
+		ExportedAt:       time.Now(),
+		Name:             profile.Name,
+		Description:      profile.Description,
+		ProjectsDir:      profile.ProjectsDir,
+		JavaHomeOverride: profile.JavaHomeOverride,
+		EnvVars:          profile.EnvVars,
+		MemoryBudgetMB:   profile.MemoryBudgetMB,
+		MemoryBudgetMode: profile.MemoryBudgetMode,
+		PortPoolStart:    profile.PortPoolStart,
+		PortPoolEnd:      profile.PortPoolEnd,
+		Services:         []models.ProfileBundleService{},
+		ProfileEnvVars:   []models.ProfileBundleEnvVar{},
+		ServiceConfigs:   []models.ProfileBundleServiceConfig{},
+		FileOverlays:     []models.ProfileBundleFileOverlay{},
+	}
+
+	serviceNames := make(map[string]string)
+	for _, serviceUUID := range profile.Services {
+		if ps.sm == nil {
+			break
+		}
+		service, exists := ps.sm.GetServiceByUUID(serviceUUID)
+		if !exists {
+			continue
+		}
+		service.Mutex.RLock()
+		entry := models.ProfileBundleService{ID: service.ID, Name: service.Name, Dir: service.Dir}
+		service.Mutex.RUnlock()
+		serviceNames[serviceUUID] = entry.Name
+		bundle.Services = append(bundle.Services, entry)
+	}
+
+	rows, err := ps.db.Query(`SELECT var_name, var_value, COALESCE(description, ''), is_required
+			  FROM profile_env_vars WHERE profile_id = ? ORDER BY var_name`, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile env vars: %w", err)
+	}
+	for rows.Next() {
+		var envVar models.ProfileBundleEnvVar
+		if err := rows.Scan(&envVar.Name, &envVar.Value, &envVar.Description, &envVar.IsRequired); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan profile env var: %w", err)
+		}
+		bundle.ProfileEnvVars = append(bundle.ProfileEnvVars, envVar)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read profile env vars: %w", err)
+	}
+
+	rows, err = ps.db.Query(`SELECT service_id, config_key, config_value, COALESCE(config_type, 'string'), COALESCE(description, '')
+			  FROM profile_service_configs WHERE profile_id = ? ORDER BY service_id, config_key`, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile service configs: %w", err)
+	}
+	for rows.Next() {
+		var serviceUUID string
+		var config models.ProfileBundleServiceConfig
+		if err := rows.Scan(&serviceUUID, &config.Key, &config.Value, &config.Type, &config.Description); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan profile service config: %w", err)
+		}
+		name, inProfile := serviceNames[serviceUUID]
+		if !inProfile {
+			continue
+		}
+		config.Service = name
+		bundle.ServiceConfigs = append(bundle.ServiceConfigs, config)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read profile service configs: %w", err)
+	}
+
+	overlays, err := ps.db.GetProfileFileOverlays(profileID)
+	if err != nil {
+		return nil, err
+	}
+	for _, overlay := range overlays {
+		name, inProfile := serviceNames[overlay.ServiceID]
+		if !inProfile {
+			continue
+		}
+		bundle.FileOverlays = append(bundle.FileOverlays, models.ProfileBundleFileOverlay{
+			Service: name,
+			Path:    overlay.Path,
+			Content: overlay.Content,
+		})
+	}
+
+	if bundle.BuildSettings, err = ps.db.GetProfileBuildSettings(profileID); err != nil {
+		return nil, err
+	}
+	if bundle.BuildSettings != nil {
+		bundle.BuildSettings.ProfileID = ""
+	}
+
+	return bundle, nil
+}
+
+// ImportProfile creates a profile for the user from a bundle. The profile is
+// a copy: later changes to it or to the exported profile do not affect each
+// other. Bundle services are mapped to local services by name, then by
+// directory; services that match neither are dropped with their configuration
+// and overlays, and reported in the result.
+func (ps *ProfileService) ImportProfile(userID string, req *models.ProfileImportRequest) (*models.ProfileImportResult, error) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	bundle := req.Bundle
+	if bundle.FormatVersion < 1 {
+		return nil, fmt.Errorf("invalid profile bundle: formatVersion is required")
+	}
+	if bundle.FormatVersion > models.ProfileBundleFormatVersion {
+		return nil, fmt.Errorf("unsupported profile bundle version %d (expected at most %d)", bundle.FormatVersion, models.ProfileBundleFormatVersion)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = strings.TrimSpace(bundle.Name)
+	}
+	if len(name) < 3 || len(name) > 100 {
+		return nil, fmt.Errorf("invalid profile name %q: must be between 3 and 100 characters", name)
+	}
+	var existing int
+	if err := ps.db.QueryRow(`SELECT COUNT(*) FROM service_profiles WHERE user_id = ? AND name = ?`, userID, name).Scan(&existing); err != nil {
+		return nil, fmt.Errorf("failed to check profile name: %w", err)
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("profile %q already exists; import it under another name", name)
+	}
+
+	result := &models.ProfileImportResult{Services: []models.ProfileImportMapping{}, Warnings: []string{}}
+	var localServices []*models.Service
+	if ps.sm != nil {
+		localServices = ps.sm.GetServices()
+	}
+
+	serviceIDs := []string{}
+	mapped := make(map[string]string)
+	for _, bundleService := range bundle.Services {
+		mapping := models.ProfileImportMapping{Name: bundleService.Name, Dir: bundleService.Dir, SourceID: bundleService.ID}
+		mapping.ServiceID, mapping.MatchedBy = matchBundleService(bundleService, localServices)
+		result.Services = append(result.Services, mapping)
+		if mapping.ServiceID == "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("No service named %s or in directory %s; it was left out of the profile", bundleService.Name, bundleService.Dir))
+			continue
+		}
+		if _, seen := mapped[bundleService.Name]; seen {
+			continue
+		}
+		mapped[bundleService.Name] = mapping.ServiceID
+		serviceIDs = append(serviceIDs, mapping.ServiceID)
+	}
+
+	memoryBudgetMode := bundle.MemoryBudgetMode
+	if memoryBudgetMode == "" {
+		memoryBudgetMode = MemoryBudgetWarn
+	}
+	if bundle.MemoryBudgetMB < 0 || (memoryBudgetMode != MemoryBudgetWarn && memoryBudgetMode != MemoryBudgetEnforce) {
+		result.Warnings = append(result.Warnings, "The memory budget is invalid and was not imported")
+		bundle.MemoryBudgetMB, memoryBudgetMode = 0, MemoryBudgetWarn
+	}
+	if err := validatePortPool(bundle.PortPoolStart, bundle.PortPoolEnd); err != nil {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("The port pool was not imported: %v", err))
+		bundle.PortPoolStart, bundle.PortPoolEnd = 0, 0
+	}
+	if bundle.ProjectsDir != "" {
+		if _, err := os.Stat(bundle.ProjectsDir); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Projects directory %s does not exist on this machine", bundle.ProjectsDir))
+		}
+	}
+
+	envVars := bundle.EnvVars
+	if envVars == nil {
+		envVars = make(map[string]string)
+	}
+	servicesJSON, err := json.Marshal(serviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal services: %w", err)
+	}
+	envVarsJSON, err := json.Marshal(envVars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal env vars: %w", err)
+	}
+
+	profileID := uuid.New().String()
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO service_profiles (id, user_id, name, description, services_json, env_vars_json, projects_dir, java_home_override,
+			  is_default, is_active, memory_budget_mb, memory_budget_mode, port_pool_start, port_pool_end, created_at, updated_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, FALSE, FALSE, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		profileID, userID, name, bundle.Description, string(servicesJSON), string(envVarsJSON), bundle.ProjectsDir, bundle.JavaHomeOverride,
+		bundle.MemoryBudgetMB, memoryBudgetMode, bundle.PortPoolStart, bundle.PortPoolEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service profile: %w", err)
+	}
+
+	for _, envVar := range bundle.ProfileEnvVars {
+		if envVar.Name == "" {
+			continue
+		}
+		_, err := tx.Exec(`INSERT OR REPLACE INTO profile_env_vars (profile_id, var_name, var_value, description, is_required, updated_at)
+			  VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`, profileID, envVar.Name, envVar.Value, envVar.Description, envVar.IsRequired)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import profile env var %s: %w", envVar.Name, err)
+		}
+	}
+
+	skipped := make(map[string]int)
+	for _, config := range bundle.ServiceConfigs {
+		serviceUUID, ok := mapped[config.Service]
+		if !ok || config.Key == "" {
+			skipped[config.Service]++
+			continue
+		}
+		if config.Type == "" {
+			config.Type = "string"
+		}
+		_, err := tx.Exec(`INSERT OR REPLACE INTO profile_service_configs
+			  (profile_id, service_id, config_key, config_value, config_type, description, updated_at)
+			  VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`, profileID, serviceUUID, config.Key, config.Value, config.Type, config.Description)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import service config %s.%s: %w", config.Service, config.Key, err)
+		}
+	}
+
+	for _, overlay := range bundle.FileOverlays {
+		serviceUUID, ok := mapped[overlay.Service]
+		if !ok {
+			skipped[overlay.Service]++
+			continue
+		}
+		overlayPath, err := validateOverlayPath(overlay.Path)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("File overlay of %s was not imported: %v", overlay.Service, err))
+			continue
+		}
+		_, err = tx.Exec(`INSERT INTO profile_file_overlays (profile_id, service_id, path, content, updated_at)
+			  VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+			  ON CONFLICT(profile_id, service_id, path) DO UPDATE SET content = excluded.content, updated_at = CURRENT_TIMESTAMP`,
+			profileID, serviceUUID, overlayPath, overlay.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import file overlay %s: %w", overlayPath, err)
+		}
+	}
+	for serviceName, count := range skipped {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d configuration value(s) and overlay(s) of unmatched service %s were not imported", count, serviceName))
+	}
+
+	if settings := bundle.BuildSettings; settings != nil {
+		_, err := tx.Exec(`INSERT INTO profile_build_settings (profile_id, offline, mirror_url, mirror_of, maven_opts, gradle_opts)
+			  VALUES (?, ?, ?, ?, ?, ?)`,
+			profileID, settings.Offline, settings.MirrorURL, settings.MirrorOf, settings.MavenOpts, settings.GradleOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import build settings: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit imported profile: %w", err)
+	}
+
+	log.Printf("[INFO] Imported profile %s (%s) for user %s with %d of %d services", name, profileID, userID, len(serviceIDs), len(bundle.Services))
+	ps.broadcastProfileUpdate(profileID, userID, "created")
+
+	if result.Profile, err = ps.getServiceProfileInternal(profileID, userID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// matchBundleService finds the local service a bundle service stands for:
+// the one with the same name, ignoring case when nothing matches exactly,
+// otherwise the one in the same directory
+func matchBundleService(bundleService models.ProfileBundleService, localServices []*models.Service) (string, string) {
+	for _, service := range localServices {
+		if service.Name == bundleService.Name {
+			return service.ID, "name"
+		}
+	}
+	for _, service := range localServices {
+		if strings.EqualFold(service.Name, bundleService.Name) {
+			return service.ID, "name"
+		}
+	}
+	if bundleService.Dir != "" {
+		for _, service := range localServices {
+			if service.Dir == bundleService.Dir {
+				return service.ID, "dir"
+			}
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,307 @@
+// Package services - Switching the git branch of every repository in a profile
+package services
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Outcomes of switching one repository of a profile
+const (
+	BranchSwitchSwitched      = "switched"
+	BranchSwitchAlreadyOn     = "already-on-branch"
+	BranchSwitchReady         = "ready"
+	BranchSwitchDirty         = "dirty"
+	BranchSwitchRunning       = "running"
+	BranchSwitchNotFound      = "branch-not-found"
+	BranchSwitchNotRepository = "not-a-repository"
+	BranchSwitchSkipped       = "skipped"
+	BranchSwitchFailed        = "failed"
+)
+
+// ProfileBranchSwitchRequest switches the repositories of a profile's
+// services. Branches maps service UUIDs or names to the branch of that
+// service, and Branch is used for the rest; services with neither are left
+// alone. Uncommitted changes are stashed unless AutoStash is false. Unless
+// Partial is set, a running repository, or a dirty one when changes are not
+// stashed, stops every repository from being switched.
+type ProfileBranchSwitchRequest struct {
+	Branch    string            `json:"branch"`
+	Branches  map[string]string `json:"branches"`
+	Fetch     bool              `json:"fetch"` // Fetch from the remotes first, so new remote branches are found
+	AutoStash *bool             `json:"autoStash"`
+	Partial   bool              `json:"partial"`
+	DryRun    bool              `json:"dryRun"`
+}
+
+// RepositoryBranchSwitch reports what switching one repository did. Services
+// that share a repository share one entry.
+type RepositoryBranchSwitch struct {
+	Dir            string             `json:"dir"`
+	ServiceIDs     []string           `json:"serviceIds"`
+	ServiceNames   []string           `json:"serviceNames"`
+	Branch         string             `json:"branch"`
+	PreviousBranch string             `json:"previousBranch,omitempty"`
+	Status         string             `json:"status"`
+	Message        string             `json:"message,omitempty"`
+	Stash          *BranchSwitchStash `json:"stash,omitempty"`
+}
+
+// ProfileBranchSwitchResult reports the switch of every repository in a profile
+type ProfileBranchSwitchResult struct {
+	ProfileID    string                   `json:"profileId"`
+	Switched     int                      `json:"switched"`
+	Conflicts    int                      `json:"conflicts"`
+	Aborted      bool                     `json:"aborted"` // Nothing was switched because of conflicts
+	Repositories []RepositoryBranchSwitch `json:"repositories"`
+}
+
+// repositorySwitch is a repository to switch and the services in it
+type repositorySwitch struct {
+	report   RepositoryBranchSwitch
+	services []*models.Service
+	checkout string // What to check out: the branch, or origin/<branch> for a branch only the remote has
+	running  string // Why the repository cannot be switched while its services run
+}
+
+// SwitchProfileBranches checks out a branch in the repository of every
+// service of a profile, for testing a feature that spans several services.
+// Every repository is checked first; a conflict in one leaves all of them
+// alone unless the request allows a partial switch.
+func (sm *Manager) SwitchProfileBranches(profile *models.ServiceProfile, req *ProfileBranchSwitchRequest) (*ProfileBranchSwitchResult, error) {
+	req.Branch = strings.TrimSpace(req.Branch)
+	if req.Branch == "" && len(req.Branches) == 0 {
+		return nil, fmt.Errorf("a branch or a branch per service is required")
+	}
+	for _, branch := range append([]string{req.Branch}, mapValues(req.Branches)...) {
+		if strings.HasPrefix(branch, "-") || strings.ContainsAny(branch, " \t\n~^:?*[\\") {
+			return nil, fmt.Errorf("invalid branch name %q", branch)
+		}
+	}
+
+	result := &ProfileBranchSwitchResult{ProfileID: profile.ID, Repositories: []RepositoryBranchSwitch{}}
+	repositories := []*repositorySwitch{}
+	byDir := make(map[string]*repositorySwitch)
+
+	for _, serviceUUID := range profile.Services {
+		service, exists := sm.GetServiceByUUID(serviceUUID)
+		if !exists {
+			continue
+		}
+		service.Mutex.RLock()
+		name, dir, status := service.Name, service.Dir, service.Status
+		service.Mutex.RUnlock()
+
+		branch := req.Branch
+		if mapped, ok := req.Branches[serviceUUID]; ok {
+			branch = strings.TrimSpace(mapped)
+		} else if mapped, ok := req.Branches[name]; ok {
+			branch = strings.TrimSpace(mapped)
+		}
+
+		projectsDir := profile.ProjectsDir
+		if projectsDir == "" {
+			projectsDir = sm.getServiceProjectsDirectory(serviceUUID)
+		}
+		if projectsDir == "" {
+			projectsDir = sm.config.ProjectsDir
+		}
+		fullPath := filepath.Join(projectsDir, dir)
+		if root := gitRepositoryRoot(fullPath); root != "" {
+			fullPath = root
+		}
+
+		repository, seen := byDir[fullPath]
+		if !seen {
+			repository = &repositorySwitch{report: RepositoryBranchSwitch{
+				Dir:          fullPath,
+				ServiceIDs:   []string{},
+				ServiceNames: []string{},
+				Branch:       branch,
+			}}
+			byDir[fullPath] = repository
+			repositories = append(repositories, repository)
+		} else if repository.report.Branch == "" {
+			repository.report.Branch = branch
+		} else if branch != "" && branch != repository.report.Branch {
+			repository.report.Status = BranchSwitchFailed
+			repository.report.Message = fmt.Sprintf("Services sharing this repository were given different branches (%s and %s)", repository.report.Branch, branch)
+		}
+		repository.report.ServiceIDs = append(repository.report.ServiceIDs, serviceUUID)
+		repository.report.ServiceNames = append(repository.report.ServiceNames, name)
+		repository.services = append(repository.services, service)
+
+		if (status == "running" || status == "starting" || status == StatusPaused) && repository.running == "" {
+			repository.running = fmt.Sprintf("%s is %s; stop it before switching branches", name, status)
+		}
+	}
+
+	for _, repository := range repositories {
+		sm.checkRepositorySwitch(repository, req.Fetch, req.AutoStash == nil || *req.AutoStash)
+		switch repository.report.Status {
+		case BranchSwitchDirty, BranchSwitchRunning, BranchSwitchFailed:
+			result.Conflicts++
+		}
+	}
+
+	if req.DryRun || (result.Conflicts > 0 && !req.Partial) {
+		result.Aborted = !req.DryRun
+		for _, repository := range repositories {
+			result.Repositories = append(result.Repositories, repository.report)
+		}
+		return result, nil
+	}
+
+	for _, repository := range repositories {
+		if repository.report.Status == BranchSwitchReady {
+			sm.switchRepository(repository)
+			if repository.report.Status == BranchSwitchSwitched {
+				result.Switched++
+			} else {
+				result.Conflicts++
+			}
+		}
+		result.Repositories = append(result.Repositories, repository.report)
+	}
+
+	log.Printf("[INFO] Switched %d repositories of profile %s (%d conflicts)", result.Switched, profile.Name, result.Conflicts)
+	return result, nil
+}
+
+// checkRepositorySwitch works out whether a repository can be switched,
+// setting its status to ready when it can
+func (sm *Manager) checkRepositorySwitch(repository *repositorySwitch, fetch, autoStash bool) {
+	report := &repository.report
+	if report.Status != "" {
+		return
+	}
+	if report.Branch == "" {
+		report.Status = BranchSwitchSkipped
+		report.Message = "No branch was given for these services"
+		return
+	}
+	if !IsGitRepository(report.Dir) {
+		report.Status = BranchSwitchNotRepository
+		report.Message = "The service directory is not a git repository"
+		return
+	}
+
+	current, err := GetCurrentBranch(report.Dir)
+	if err != nil {
+		report.Status = BranchSwitchFailed
+		report.Message = err.Error()
+		return
+	}
+	report.PreviousBranch = current
+	if current == report.Branch {
+		report.Status = BranchSwitchAlreadyOn
+		return
+	}
+	if repository.running != "" {
+		report.Status = BranchSwitchRunning
+		report.Message = repository.running
+		return
+	}
+
+	dirty, err := HasUncommittedChanges(report.Dir)
+	if err != nil {
+		report.Status = BranchSwitchFailed
+		report.Message = err.Error()
+		return
+	}
+	if dirty && !autoStash {
+		report.Status = BranchSwitchDirty
+		report.Message = "The working tree has uncommitted changes; commit or stash them first"
+		return
+	}
+	if dirty {
+		report.Message = "Uncommitted changes will be stashed"
+	}
+
+	if fetch {
+		fetchCmd := exec.Command("git", "fetch", "--all")
+		fetchCmd.Dir = report.Dir
+		if output, err := fetchCmd.CombinedOutput(); err != nil {
+			log.Printf("[WARN] Failed to fetch %s: %s", report.Dir, strings.TrimSpace(string(output)))
+		}
+	}
+
+	localBranches, err := GetBranches(report.Dir)
+	if err != nil {
+		report.Status = BranchSwitchFailed
+		report.Message = err.Error()
+		return
+	}
+	if slices.Contains(localBranches, report.Branch) {
+		repository.checkout = report.Branch
+		report.Status = BranchSwitchReady
+		return
+	}
+
+	remoteCmd := exec.Command("git", "branch", "-r", "--format=%(refname:short)")
+	remoteCmd.Dir = report.Dir
+	output, err := remoteCmd.Output()
+	if err == nil && slices.Contains(strings.Fields(string(output)), "origin/"+report.Branch) {
+		repository.checkout = "origin/" + report.Branch
+		report.Status = BranchSwitchReady
+		return
+	}
+
+	report.Status = BranchSwitchNotFound
+	report.Message = fmt.Sprintf("Branch %s exists neither locally nor on origin", report.Branch)
+}
+
+// switchRepository checks out the branch of a checked repository and records
+// the switch on each of its services
+func (sm *Manager) switchRepository(repository *repositorySwitch) {
+	report := &repository.report
+	stash, err := SwitchBranchWithAutoStash(report.Dir, repository.checkout)
+	if err != nil {
+		report.Status = BranchSwitchFailed
+		report.Message = err.Error()
+		return
+	}
+	report.Status = BranchSwitchSwitched
+	report.Message = strings.TrimPrefix(describeStash(stash), "; ")
+	if stash.Stashed != nil || stash.Restored != nil || stash.RestoreError != "" {
+		report.Stash = stash
+	}
+
+	for _, service := range repository.services {
+		service.Mutex.Lock()
+		service.GitBranch = report.Branch
+		service.Mutex.Unlock()
+		sm.broadcastUpdate(service)
+
+		service.Mutex.RLock()
+		sm.recordServiceEvent(service, models.EventBranchSwitched, fmt.Sprintf("Switched to branch %s with its profile%s", report.Branch, describeStash(stash)))
+		service.Mutex.RUnlock()
+	}
+}
+
+// gitRepositoryRoot returns the top of the git repository a directory is in,
+// or "" when it is in none
+func gitRepositoryRoot(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.Clean(strings.TrimSpace(string(output)))
+}
+
+// mapValues returns the values of a map in no particular order
+func mapValues(values map[string]string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
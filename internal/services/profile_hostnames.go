@@ -0,0 +1,153 @@
+// Package services - Local hostnames declared by profiles
+package services
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/installer"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// A dotted hostname of RFC 1123 labels, e.g. api.test
+var profileHostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)+$`)
+
+// HostnamesResult reports the outcome of regenerating the nginx servers and
+// hosts file entries of the profile hostnames
+type HostnamesResult struct {
+	Nginx *installer.NginxLocationsResult `json:"nginx"`
+	Hosts *installer.HostsFileResult      `json:"hosts"`
+}
+
+// GetProfileHostnames returns the local hostnames of a profile with the
+// names and current ports of their services
+func (sm *Manager) GetProfileHostnames(profileID string) ([]models.ProfileHostname, error) {
+	hostnames, err := sm.db.GetProfileHostnames(profileID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hostnames {
+		if service, exists := sm.GetServiceByUUID(hostnames[i].ServiceID); exists {
+			service.Mutex.RLock()
+			hostnames[i].ServiceName = service.Name
+			hostnames[i].Port = service.Port
+			service.Mutex.RUnlock()
+		}
+	}
+	return hostnames, nil
+}
+
+// SetProfileHostnames validates and replaces the local hostnames of a
+// profile, then regenerates the hosts file entries and nginx servers.
+// profileServices are the UUIDs of the services in the profile.
+func (sm *Manager) SetProfileHostnames(profileID string, profileServices []string, hostnames []models.ProfileHostname) (*HostnamesResult, error) {
+	existing, err := sm.db.GetProfileHostnames("")
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string]string, len(existing))
+	for _, hostname := range existing {
+		owners[hostname.Hostname] = hostname.ProfileID
+	}
+
+	domains, err := sm.enabledServiceDomains(vertexSite())
+	if err != nil {
+		return nil, err
+	}
+	serviceDomains := make(map[string]string, len(domains))
+	for _, domain := range domains {
+		serviceDomains[domain.Domain] = domain.ServiceName
+	}
+
+	seen := make(map[string]bool, len(hostnames))
+	for i := range hostnames {
+		hostname := &hostnames[i]
+		hostname.Hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname.Hostname)), ".")
+		hostname.ProfileID = profileID
+
+		if !profileHostnamePattern.MatchString(hostname.Hostname) || len(hostname.Hostname) > 253 {
+			return nil, fmt.Errorf("invalid hostname '%s' (use a dotted name such as api.test)", hostname.Hostname)
+		}
+		if seen[hostname.Hostname] {
+			return nil, fmt.Errorf("hostname '%s' is listed twice", hostname.Hostname)
+		}
+		seen[hostname.Hostname] = true
+		if owner, exists := owners[hostname.Hostname]; exists && owner != profileID {
+			return nil, fmt.Errorf("hostname '%s' is already used by another profile", hostname.Hostname)
+		}
+		if serviceName, exists := serviceDomains[hostname.Hostname]; exists {
+			return nil, fmt.Errorf("hostname '%s' is already the domain of service %s", hostname.Hostname, serviceName)
+		}
+
+		if !slices.Contains(profileServices, hostname.ServiceID) {
+			return nil, fmt.Errorf("service %s is not part of the profile", hostname.ServiceID)
+		}
+		service, exists := sm.GetServiceByUUID(hostname.ServiceID)
+		if !exists {
+			return nil, fmt.Errorf("service UUID %s not found", hostname.ServiceID)
+		}
+		service.Mutex.RLock()
+		port := service.Port
+		service.Mutex.RUnlock()
+		if port == 0 {
+			return nil, fmt.Errorf("service %s has no port to proxy %s to", service.Name, hostname.Hostname)
+		}
+	}
+
+	if err := sm.db.ReplaceProfileHostnames(profileID, hostnames); err != nil {
+		return nil, err
+	}
+	return sm.ApplyProfileHostnames()
+}
+
+// RemoveProfileHostnames drops the local hostnames of a deleted profile and
+// takes them out of the hosts file and nginx
+func (sm *Manager) RemoveProfileHostnames(profileID string) error {
+	removed, err := sm.db.DeleteProfileHostnames(profileID)
+	if err != nil || removed == 0 {
+		return err
+	}
+	_, err = sm.ApplyProfileHostnames()
+	return err
+}
+
+// ApplyProfileHostnames points every profile hostname at 127.0.0.1 in the
+// hosts file, alongside the service domains, and rewrites their nginx
+// servers with the services' current ports
+func (sm *Manager) ApplyProfileHostnames() (*HostnamesResult, error) {
+	hostnames, err := sm.GetProfileHostnames("")
+	if err != nil {
+		return nil, err
+	}
+
+	servers := []installer.NginxHostname{}
+	for _, hostname := range hostnames {
+		if hostname.ServiceName == "" {
+			continue // The service was deleted
+		}
+		if hostname.Port == 0 {
+			log.Printf("[WARN] Skipping nginx server for %s: service %s has no port", hostname.Hostname, hostname.ServiceName)
+			continue
+		}
+		servers = append(servers, installer.NginxHostname{Hostname: hostname.Hostname, Port: hostname.Port, ServiceName: hostname.ServiceName})
+	}
+
+	hosts, err := sm.applyHostsEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	nginx, err := installer.ApplyNginxHostnames(nginxOutputDir(), servers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update nginx profile hostnames: %w", err)
+	}
+	if nginx.Reloaded {
+		log.Printf("[INFO] Updated %d profile hostname(s) in %s and reloaded nginx", len(servers), nginx.ConfigFile)
+	} else {
+		log.Printf("[INFO] Updated %d profile hostname(s); to finish run: %s", len(servers), nginx.Instructions)
+	}
+	return &HostnamesResult{Nginx: nginx, Hosts: hosts}, nil
+}
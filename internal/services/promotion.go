@@ -0,0 +1,255 @@
+// Package services - Promoting environment variables and service configuration between profiles
+package services
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	promotionKindEnvVar        = "envVar"
+	promotionKindServiceConfig = "serviceConfig"
+)
+
+// promotionSource is a value of the source profile that can be promoted
+type promotionSource struct {
+	change      models.PromotionChange
+	description string
+	isRequired  bool
+}
+
+// PromoteProfile copies the selected profile environment variables and
+// service configuration values of a profile into another profile of the same
+// user. Values the target does not have are added and differing ones
+// replaced; values only the target has are left alone. A dry run returns the
+// diff without writing it, and every applied promotion is kept as an audit
+// entry.
+func (ps *ProfileService) PromoteProfile(sourceProfileID, userID string, req *models.ProfilePromotionRequest) (*models.ProfilePromotion, error) {
+	if req.TargetProfileID == "" {
+		return nil, fmt.Errorf("targetProfileId is required")
+	}
+	if req.TargetProfileID == sourceProfileID {
+		return nil, fmt.Errorf("cannot promote a profile to itself")
+	}
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	source, err := ps.getServiceProfileInternal(sourceProfileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := ps.getServiceProfileInternal(req.TargetProfileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("target %w", err)
+	}
+
+	candidates, err := ps.promotionCandidates(source, target)
+	if err != nil {
+		return nil, err
+	}
+
+	promotion := &models.ProfilePromotion{
+		UserID:            userID,
+		SourceProfileID:   source.ID,
+		SourceProfileName: source.Name,
+		TargetProfileID:   target.ID,
+		TargetProfileName: target.Name,
+		Note:              req.Note,
+		Changes:           []models.PromotionChange{},
+		DryRun:            req.DryRun,
+		CreatedAt:         time.Now(),
+	}
+
+	selected := []promotionSource{}
+	if len(req.EnvVars) == 0 && len(req.ServiceConfigs) == 0 {
+		if !req.DryRun {
+			return nil, fmt.Errorf("select at least one environment variable or service configuration value to promote")
+		}
+		selected = candidates
+	} else {
+		for _, name := range req.EnvVars {
+			index := slices.IndexFunc(candidates, func(candidate promotionSource) bool {
+				return candidate.change.Kind == promotionKindEnvVar && candidate.change.Key == name
+			})
+			if index < 0 {
+				return nil, fmt.Errorf("environment variable %s is not set in profile %s", name, source.Name)
+			}
+			selected = append(selected, candidates[index])
+		}
+		for _, key := range req.ServiceConfigs {
+			if !slices.Contains(target.Services, key.ServiceID) {
+				return nil, fmt.Errorf("service %s is not part of profile %s", key.ServiceID, target.Name)
+			}
+			index := slices.IndexFunc(candidates, func(candidate promotionSource) bool {
+				return candidate.change.Kind == promotionKindServiceConfig && candidate.change.ServiceID == key.ServiceID && candidate.change.Key == key.Key
+			})
+			if index < 0 {
+				return nil, fmt.Errorf("service configuration %s of service %s is not set in profile %s", key.Key, key.ServiceID, source.Name)
+			}
+			selected = append(selected, candidates[index])
+		}
+	}
+
+	for _, value := range selected {
+		promotion.Changes = append(promotion.Changes, value.change)
+	}
+	if req.DryRun {
+		return promotion, nil
+	}
+
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, value := range selected {
+		change := value.change
+		if change.Action == "unchanged" {
+			continue
+		}
+		if change.Kind == promotionKindEnvVar {
+			_, err = tx.Exec(`
+				INSERT INTO profile_env_vars (profile_id, var_name, var_value, description, is_required, updated_at)
+				VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+				ON CONFLICT(profile_id, var_name) DO UPDATE SET
+					var_value = excluded.var_value,
+					description = CASE WHEN excluded.description = '' THEN description ELSE excluded.description END,
+					is_required = excluded.is_required,
+					updated_at = CURRENT_TIMESTAMP`,
+				target.ID, change.Key, change.After, value.description, value.isRequired)
+		} else {
+			_, err = tx.Exec(`INSERT OR REPLACE INTO profile_service_configs
+				(profile_id, service_id, config_key, config_value, config_type, description, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+				target.ID, change.ServiceID, change.Key, change.After, change.ConfigType, value.description)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to promote %s to profile %s: %w", change.Key, target.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit promotion: %w", err)
+	}
+
+	if promotion.ID, err = ps.db.InsertProfilePromotion(*promotion); err != nil {
+		log.Printf("[ERROR] Promotion from profile %s to %s was applied but not recorded: %v", source.Name, target.Name, err)
+	}
+	log.Printf("[INFO] Promoted %d value(s) from profile %s to %s", len(promotion.Changes), source.Name, target.Name)
+	ps.broadcastProfileUpdate(target.ID, userID, "updated")
+
+	return promotion, nil
+}
+
+// GetProfilePromotions returns the newest promotions from or to a profile first
+func (ps *ProfileService) GetProfilePromotions(profileID, userID string, limit int) ([]models.ProfilePromotion, error) {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	if _, err := ps.getServiceProfileInternal(profileID, userID); err != nil {
+		return nil, err
+	}
+	return ps.db.GetProfilePromotions(profileID, limit)
+}
+
+// promotionCandidates lists the values of the source profile that can be
+// promoted to the target, compared with the target's values: every profile
+// environment variable, and the service configuration of services that are in
+// both profiles
+func (ps *ProfileService) promotionCandidates(source, target *models.ServiceProfile) ([]promotionSource, error) {
+	candidates := []promotionSource{}
+
+	targetEnvVars, err := ps.db.GetProfileEnvVars(target.ID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := ps.db.Query(`SELECT var_name, var_value, COALESCE(description, ''), is_required
+			  FROM profile_env_vars WHERE profile_id = ? ORDER BY var_name`, source.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query profile env vars: %w", err)
+	}
+	for rows.Next() {
+		var value promotionSource
+		value.change.Kind = promotionKindEnvVar
+		if err := rows.Scan(&value.change.Key, &value.change.After, &value.description, &value.isRequired); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan profile env var: %w", err)
+		}
+		before, exists := targetEnvVars[value.change.Key]
+		value.change.Before = before
+		value.change.Action = promotionAction(exists, before, value.change.After)
+		candidates = append(candidates, value)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read profile env vars: %w", err)
+	}
+
+	configs := []promotionSource{}
+	for _, serviceUUID := range source.Services {
+		if !slices.Contains(target.Services, serviceUUID) {
+			continue
+		}
+		serviceName := serviceUUID
+		if ps.sm != nil {
+			if service, exists := ps.sm.GetServiceByUUID(serviceUUID); exists {
+				service.Mutex.RLock()
+				serviceName = service.Name
+				service.Mutex.RUnlock()
+			}
+		}
+
+		targetConfig, err := ps.db.GetProfileServiceConfig(target.ID, serviceUUID)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := ps.db.Query(`SELECT config_key, config_value, COALESCE(config_type, 'string'), COALESCE(description, '')
+				  FROM profile_service_configs WHERE profile_id = ? AND service_id = ? ORDER BY config_key`, source.ID, serviceUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query profile service config for UUID %s: %w", serviceUUID, err)
+		}
+		for rows.Next() {
+			value := promotionSource{change: models.PromotionChange{
+				Kind:        promotionKindServiceConfig,
+				ServiceID:   serviceUUID,
+				ServiceName: serviceName,
+			}}
+			if err := rows.Scan(&value.change.Key, &value.change.After, &value.change.ConfigType, &value.description); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan profile service config: %w", err)
+			}
+			before, exists := targetConfig[value.change.Key]
+			value.change.Before = before
+			value.change.Action = promotionAction(exists, before, value.change.After)
+			configs = append(configs, value)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read profile service config: %w", err)
+		}
+	}
+	sort.SliceStable(configs, func(i, j int) bool {
+		return configs[i].change.ServiceName < configs[j].change.ServiceName
+	})
+
+	return append(candidates, configs...), nil
+}
+
+// promotionAction tells what promoting a value does to the target
+func promotionAction(exists bool, before, after string) string {
+	switch {
+	case !exists:
+		return "add"
+	case before != after:
+		return "change"
+	default:
+		return "unchanged"
+	}
+}
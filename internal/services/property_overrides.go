@@ -0,0 +1,190 @@
+// Package services - Profile-scoped Spring property overrides
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/database"
+)
+
+const (
+	// ConfigTypeSpringProperty marks profile service configs that are written to
+	// application-override.properties instead of being used by Vertex itself
+	ConfigTypeSpringProperty = "spring-property"
+
+	propertyOverridesFile = "application-override.properties"
+)
+
+// GetPropertyOverrides returns the Spring property overrides of a service within a profile
+func (sm *Manager) GetPropertyOverrides(profileID, serviceUUID string) (map[string]string, error) {
+	return sm.db.GetProfileServiceConfigByType(profileID, serviceUUID, ConfigTypeSpringProperty)
+}
+
+// SetPropertyOverrides replaces the Spring property overrides of a service within a profile.
+// They take effect the next time the service starts.
+func (sm *Manager) SetPropertyOverrides(profileID, serviceUUID string, overrides map[string]string) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	cleaned := make(map[string]string, len(overrides))
+	for key, value := range overrides {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return fmt.Errorf("property names cannot be empty")
+		}
+		cleaned[key] = value
+	}
+
+	return sm.db.ReplaceProfileServiceConfigsByType(profileID, serviceUUID, ConfigTypeSpringProperty, cleaned)
+}
+
+// propertyOverridesPath is where the overrides file of a service lives. It is kept
+// in the data directory so the service's working tree stays clean. Services
+// outside any profile use overrides/<service>.
+func propertyOverridesPath(profileID, serviceUUID string) string {
+	return filepath.Join(database.GetDataDir(), "overrides", profileID, serviceUUID, propertyOverridesFile)
+}
+
+// writePropertyOverrides writes the profile's overrides for a service, followed
+// by any environment variables refreshed into the running service, to
+// application-override.properties and returns its path. Callers only write
+// it for services started with the file as an additional config location.
+func (sm *Manager) writePropertyOverrides(profileID, serviceUUID string, refreshed map[string]string) (string, error) {
+	overrides := map[string]string{}
+	if profileID != "" {
+		var err error
+		if overrides, err = sm.GetPropertyOverrides(profileID, serviceUUID); err != nil {
+			return "", err
+		}
+	}
+
+	var content strings.Builder
+	content.WriteString("# Generated by Vertex from profile property overrides - do not edit\n")
+	writeSortedProperties(&content, overrides)
+	if len(refreshed) > 0 {
+		content.WriteString("\n# Environment variables refreshed since the service started\n")
+		writeSortedProperties(&content, refreshed)
+	}
+
+	path := propertyOverridesPath(profileID, serviceUUID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create overrides directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", propertyOverridesFile, err)
+	}
+
+	return path, nil
+}
+
+func writeSortedProperties(content *strings.Builder, properties map[string]string) {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		content.WriteString(escapeProperty(key, true))
+		content.WriteString("=")
+		content.WriteString(escapeProperty(properties[key], false))
+		content.WriteString("\n")
+	}
+}
+
+// escapeProperty escapes a key or value for the .properties format
+func escapeProperty(value string, isKey bool) string {
+	var escaped strings.Builder
+	for i, r := range value {
+		switch r {
+		case '\\':
+			escaped.WriteString(`\\`)
+		case '\n':
+			escaped.WriteString(`\n`)
+		case '\r':
+			escaped.WriteString(`\r`)
+		case '\t':
+			escaped.WriteString(`\t`)
+		case '=', ':', '#', '!', ' ':
+			if isKey || i == 0 {
+				escaped.WriteRune('\\')
+			}
+			escaped.WriteRune(r)
+		default:
+			escaped.WriteRune(r)
+		}
+	}
+	return escaped.String()
+}
+
+// applyPropertyOverrides writes the overrides file and appends
+// --spring.config.additional-location to the start command of a Spring Boot
+// service whose profile has overrides; other commands are returned as they
+// are. Environment variables refreshed into a previous run are dropped since
+// the new process receives them directly.
+func (sm *Manager) applyPropertyOverrides(cmdString string, buildSystem BuildSystemType, serviceDir, profileID, serviceUUID, serviceName string) string {
+	clearRefreshedEnv(serviceUUID)
+
+	if profileID == "" || !isSpringService(serviceDir) {
+		return cmdString
+	}
+	overrides, err := sm.GetPropertyOverrides(profileID, serviceUUID)
+	if err != nil {
+		log.Printf("[WARN] Failed to load property overrides for service %s: %v", serviceName, err)
+		return cmdString
+	}
+	if len(overrides) == 0 {
+		// Drop a file left by an earlier start so it is not mistaken for current overrides
+		os.Remove(propertyOverridesPath(profileID, serviceUUID))
+		return cmdString
+	}
+
+	path, err := sm.writePropertyOverrides(profileID, serviceUUID, nil)
+	if err != nil {
+		log.Printf("[WARN] Failed to prepare property overrides for service %s: %v", serviceName, err)
+		return cmdString
+	}
+
+	markPropertyOverridesInUse(serviceUUID)
+	log.Printf("[INFO] Service %s: applying property overrides from %s", serviceName, path)
+	return appendSpringArgument(cmdString, buildSystem, "--spring.config.additional-location=file:"+path)
+}
+
+// isSpringService reports whether a Maven or Gradle project is a Spring Boot
+// application, the only kind that reads --spring.config.additional-location
+func isSpringService(serviceDir string) bool {
+	if pom, err := readMavenPOM(filepath.Join(serviceDir, "pom.xml")); err == nil {
+		return isSpringBootPOM(pom) || hasSpringBootMavenPlugin(pom)
+	}
+	for _, buildFile := range []string{"build.gradle", "build.gradle.kts"} {
+		if appliesSpringBootGradlePlugin(filepath.Join(serviceDir, buildFile)) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendSpringArgument passes an application argument through the build tool's run goal
+func appendSpringArgument(cmdString string, buildSystem BuildSystemType, arg string) string {
+	switch buildSystem {
+	case BuildSystemMaven:
+		return cmdString + fmt.Sprintf(" -Dspring-boot.run.arguments=%q", arg)
+	case BuildSystemGradle:
+		// Join an existing --args="..." rather than passing it twice
+		if idx := strings.Index(cmdString, `--args="`); idx >= 0 {
+			insertAt := idx + len(`--args="`)
+			return cmdString[:insertAt] + arg + " " + cmdString[insertAt:]
+		}
+		return cmdString + fmt.Sprintf(" --args=%q", arg)
+	case executableJar:
+		return cmdString + " " + shellQuote(arg)
+	default:
+		return cmdString
+	}
+}
@@ -0,0 +1,463 @@
+// Package services - Artifact repository credentials injected into builds
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// Key that encrypts repository secrets at rest, created on first use
+	repositoryKeyFile = "repository-credentials.key"
+	// Prefix of encrypted secrets so the format can change later
+	repositorySecretPrefix = "enc:v1:"
+	// GitLab's header for personal access tokens
+	gitLabTokenHeader = "Private-Token"
+)
+
+var (
+	repositoryServerIDRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	httpHeaderNameRegex     = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	envNameInvalidRegex     = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+var (
+	repositoryKey      []byte
+	repositoryKeyMutex sync.Mutex
+)
+
+// GetRepositoryCredentials returns the repository credentials of a profile
+// without their secrets
+func (sm *Manager) GetRepositoryCredentials(profileID string) ([]models.RepositoryCredential, error) {
+	credentials, err := sm.db.GetRepositoryCredentials(profileID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range credentials {
+		credentials[i].Secret = ""
+	}
+	return credentials, nil
+}
+
+// SetRepositoryCredential validates, encrypts and saves a repository credential.
+// An empty secret keeps the stored one so clients can edit other fields.
+func (sm *Manager) SetRepositoryCredential(credential models.RepositoryCredential) error {
+	credential.ServerID = strings.TrimSpace(credential.ServerID)
+	credential.URL = strings.TrimSpace(credential.URL)
+	credential.Username = strings.TrimSpace(credential.Username)
+	credential.TokenHeader = strings.TrimSpace(credential.TokenHeader)
+
+	if !repositoryServerIDRegex.MatchString(credential.ServerID) {
+		return fmt.Errorf("invalid server id '%s'", credential.ServerID)
+	}
+	switch credential.Kind {
+	case models.RepositoryNexus, models.RepositoryArtifactory:
+	case models.RepositoryGitLab:
+		if credential.TokenHeader == "" && credential.Username == "" {
+			credential.TokenHeader = gitLabTokenHeader
+		}
+	default:
+		return fmt.Errorf("invalid repository kind '%s'", credential.Kind)
+	}
+	if credential.URL != "" {
+		if parsed, err := url.Parse(credential.URL); err != nil || parsed.Host == "" ||
+			(parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return fmt.Errorf("invalid repository URL '%s'", credential.URL)
+		}
+	}
+	if credential.TokenHeader != "" && !httpHeaderNameRegex.MatchString(credential.TokenHeader) {
+		return fmt.Errorf("invalid token header '%s'", credential.TokenHeader)
+	}
+	if credential.TokenHeader == "" && credential.Username == "" {
+		return fmt.Errorf("a username is required unless the secret is sent as a token header")
+	}
+
+	if credential.Secret == "" {
+		existing, err := sm.db.GetRepositoryCredentials(credential.ProfileID)
+		if err != nil {
+			return err
+		}
+		for _, stored := range existing {
+			if stored.ServerID == credential.ServerID {
+				credential.Secret = stored.Secret
+			}
+		}
+		if credential.Secret == "" {
+			return fmt.Errorf("a password or token is required for %s", credential.ServerID)
+		}
+	} else {
+		encrypted, err := encryptRepositorySecret(credential.Secret)
+		if err != nil {
+			return err
+		}
+		credential.Secret = encrypted
+	}
+
+	return sm.db.SaveRepositoryCredential(credential)
+}
+
+// DeleteRepositoryCredential removes a repository credential of a profile
+func (sm *Manager) DeleteRepositoryCredential(profileID, serverID string) error {
+	return sm.db.DeleteRepositoryCredential(profileID, serverID)
+}
+
+// applyRepositoryCredentials makes the profile's repository credentials
+// available to a build. Maven gets a generated user settings.xml (-s), a copy
+// of ~/.m2/settings.xml with a server per credential added, whose servers
+// read their secrets from environment variables, so no secret is written to
+// disk; servers with the same id in ~/.m2/settings.xml still take precedence.
+// Gradle gets <name>Username, <name>Password and <name>Token project
+// properties through ORG_GRADLE_PROJECT_ variables, the same as entries in
+// gradle.properties. The Maven settings also carry the mirror of the
+// profile's build settings. Returns the command and the variables to set;
+// commands that go on to run the application unset them first with
+// unsetCredentialEnv.
+func (sm *Manager) applyRepositoryCredentials(cmdString string, buildSystem BuildSystemType, profileID, serviceName string) (string, map[string]string) {
+	env := make(map[string]string)
+	if profileID == "" {
+		return cmdString, env
+	}
+
+	credentials, err := sm.db.GetRepositoryCredentials(profileID)
+	if err != nil {
+		log.Printf("[WARN] Failed to load repository credentials for service %s: %v", serviceName, err)
+		return cmdString, env
+	}
+	var mirror *models.ProfileBuildSettings
+	if buildSystem == BuildSystemMaven {
+		if settings := sm.profileBuildSettings(profileID, serviceName); settings != nil && settings.MirrorURL != "" {
+			mirror = settings
+		}
+	}
+	if len(credentials) == 0 && mirror == nil {
+		return cmdString, env
+	}
+
+	for i := range credentials {
+		secret, err := decryptRepositorySecret(credentials[i].Secret)
+		if err != nil {
+			log.Printf("[WARN] Skipping repository credential %s for service %s: %v", credentials[i].ServerID, serviceName, err)
+		}
+		credentials[i].Secret = secret
+	}
+
+	switch buildSystem {
+	case BuildSystemMaven:
+		path := filepath.Join(database.GetDataDir(), "credentials", profileID, "settings.xml")
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			log.Printf("[WARN] Failed to create repository settings directory for service %s: %v", serviceName, err)
+			return cmdString, env
+		}
+		if err := os.WriteFile(path, []byte(renderRepositorySettings(credentials, mirror, readMavenUserSettings(serviceName))), 0600); err != nil {
+			log.Printf("[WARN] Failed to write repository settings for service %s: %v", serviceName, err)
+			return cmdString, env
+		}
+		for _, credential := range credentials {
+			if credential.Secret != "" {
+				env[repositoryEnvPrefix(credential.ServerID)+"_SECRET"] = credential.Secret
+			}
+		}
+		cmdString += " -s " + shellQuote(path)
+	case BuildSystemGradle:
+		for _, credential := range credentials {
+			if credential.Secret == "" {
+				continue
+			}
+			prefix := "ORG_GRADLE_PROJECT_" + gradlePropertyPrefix(credential.ServerID)
+			if credential.TokenHeader != "" {
+				env[prefix+"Token"] = credential.Secret
+				continue
+			}
+			env[prefix+"Username"] = credential.Username
+			env[prefix+"Password"] = credential.Secret
+		}
+	default:
+		return cmdString, env
+	}
+
+	if len(credentials) > 0 {
+		log.Printf("[INFO] Service %s: providing %d repository credentials from its profile", serviceName, len(credentials))
+	}
+	return cmdString, env
+}
+
+// unsetCredentialEnv returns the prefix that removes the variables of
+// applyRepositoryCredentials from a shell before it runs the application,
+// so only the build and dependency resolution see the secrets
+func unsetCredentialEnv(env map[string]string) string {
+	if len(env) == 0 {
+		return ""
+	}
+	return "unset " + strings.Join(sortedEnvKeys(env), " ") + " && "
+}
+
+// credentialResolveCommand compiles a service with the build system, which
+// resolves the dependencies its run goal needs while the repository
+// credentials are set; the run that follows finds them in the local cache
+func credentialResolveCommand(serviceDir string, buildSystem BuildSystemType) string {
+	switch buildSystem {
+	case BuildSystemMaven:
+		return "cd " + shellQuote(serviceDir) + " && ./mvnw test-compile"
+	case BuildSystemGradle:
+		return "cd " + shellQuote(serviceDir) + " && ./gradlew classes"
+	default:
+		return ""
+	}
+}
+
+// mavenUserSettings holds the parts of ~/.m2/settings.xml the generated
+// settings are merged with
+type mavenUserSettings struct {
+	Servers []struct {
+		ID string `xml:"id"`
+	} `xml:"servers>server"`
+}
+
+// readMavenUserSettings returns ~/.m2/settings.xml, or an empty string when
+// there is none or it is not valid XML
+func readMavenUserSettings(serviceName string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	content, err := os.ReadFile(filepath.Join(home, ".m2", "settings.xml"))
+	if err != nil {
+		return ""
+	}
+	var parsed mavenUserSettings
+	if err := xml.Unmarshal(content, &parsed); err != nil || !strings.Contains(string(content), "</settings>") {
+		log.Printf("[WARN] Ignoring ~/.m2/settings.xml for the repository credentials of service %s: not a valid settings file", serviceName)
+		return ""
+	}
+	return string(content)
+}
+
+// renderRepositorySettings writes a settings.xml with one server per
+// credential and the profile's mirror, if any. With the user's own
+// settings.xml, they are added to a copy of it, since -s replaces it; its
+// servers and mirrors come first so they still take precedence.
+func renderRepositorySettings(credentials []models.RepositoryCredential, mirror *models.ProfileBuildSettings, userSettings string) string {
+	userServers := map[string]bool{}
+	if userSettings != "" {
+		var parsed mavenUserSettings
+		xml.Unmarshal([]byte(userSettings), &parsed)
+		for _, server := range parsed.Servers {
+			userServers[strings.TrimSpace(server.ID)] = true
+		}
+	}
+
+	var servers strings.Builder
+	for _, credential := range credentials {
+		if credential.Secret == "" || userServers[credential.ServerID] {
+			continue
+		}
+		secretRef := "${env." + repositoryEnvPrefix(credential.ServerID) + "_SECRET}"
+		servers.WriteString("    <server>\n")
+		servers.WriteString("      <id>" + xmlEscape(credential.ServerID) + "</id>\n")
+		if credential.TokenHeader != "" {
+			servers.WriteString("      <configuration>\n")
+			servers.WriteString("        <httpHeaders>\n")
+			servers.WriteString("          <property>\n")
+			servers.WriteString("            <name>" + xmlEscape(credential.TokenHeader) + "</name>\n")
+			servers.WriteString("            <value>" + secretRef + "</value>\n")
+			servers.WriteString("          </property>\n")
+			servers.WriteString("        </httpHeaders>\n")
+			servers.WriteString("      </configuration>\n")
+		} else {
+			servers.WriteString("      <username>" + xmlEscape(credential.Username) + "</username>\n")
+			servers.WriteString("      <password>" + secretRef + "</password>\n")
+		}
+		servers.WriteString("    </server>\n")
+	}
+
+	if userSettings != "" {
+		settings := strings.NewReplacer("<servers/>", "<servers></servers>", "<mirrors/>", "<mirrors></mirrors>").Replace(userSettings)
+		settings = insertSettingsSection(settings, "servers", servers.String())
+		if mirror != nil {
+			entry := strings.TrimSuffix(strings.TrimPrefix(renderMavenMirror(mirror), "  <mirrors>\n"), "  </mirrors>\n")
+			settings = insertSettingsSection(settings, "mirrors", entry)
+		}
+		return "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n" +
+			"<!-- Generated by Vertex from ~/.m2/settings.xml, profile repository credentials and build settings - do not edit.\n" +
+			"     Secrets are read from environment variables set for the build. -->\n" + stripXMLDeclaration(settings)
+	}
+
+	var settings strings.Builder
+	settings.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	settings.WriteString("<!-- Generated by Vertex from profile repository credentials and build settings - do not edit.\n")
+	settings.WriteString("     Secrets are read from environment variables set for the build. -->\n")
+	settings.WriteString("<settings xmlns=\"http://maven.apache.org/SETTINGS/1.0.0\">\n")
+	settings.WriteString("  <servers>\n")
+	settings.WriteString(servers.String())
+	settings.WriteString("  </servers>\n")
+	if mirror != nil {
+		settings.WriteString(renderMavenMirror(mirror))
+	}
+	settings.WriteString("</settings>\n")
+	return settings.String()
+}
+
+// insertSettingsSection appends entries to the end of a section of a
+// settings.xml, adding the section when the file has none
+func insertSettingsSection(settings, section, entries string) string {
+	if entries == "" {
+		return settings
+	}
+	closing := strings.LastIndex(settings, "</"+section+">")
+	if closing < 0 {
+		closing = strings.LastIndex(settings, "</settings>")
+		entries = "  <" + section + ">\n" + entries + "  </" + section + ">\n"
+	}
+	// Insert on a line of its own when the closing tag starts one
+	lineStart := strings.LastIndex(settings[:closing], "\n") + 1
+	if strings.TrimSpace(settings[lineStart:closing]) == "" {
+		return settings[:lineStart] + entries + settings[lineStart:]
+	}
+	return settings[:closing] + "\n" + entries + settings[closing:]
+}
+
+// stripXMLDeclaration removes the XML declaration of a document, which must
+// come first, so the generated one can precede the comment added to it
+func stripXMLDeclaration(document string) string {
+	trimmed := strings.TrimLeft(document, " \t\r\n\ufeff")
+	if !strings.HasPrefix(trimmed, "<?xml") {
+		return document
+	}
+	if end := strings.Index(trimmed, "?>"); end >= 0 {
+		return strings.TrimLeft(trimmed[end+2:], "\r\n")
+	}
+	return document
+}
+
+func xmlEscape(value string) string {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
+
+// repositoryEnvPrefix turns a server id such as nexus-releases into VERTEX_REPO_NEXUS_RELEASES
+func repositoryEnvPrefix(serverID string) string {
+	return "VERTEX_REPO_" + strings.Trim(envNameInvalidRegex.ReplaceAllString(strings.ToUpper(serverID), "_"), "_")
+}
+
+// gradlePropertyPrefix turns a server id such as gitlab-maven into gitlabMaven,
+// the name Gradle's credentials(PasswordCredentials) looks up
+func gradlePropertyPrefix(serverID string) string {
+	var prefix strings.Builder
+	upperNext := false
+	for _, r := range serverID {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upperNext = prefix.Len() > 0
+			continue
+		}
+		if upperNext {
+			r = unicode.ToUpper(r)
+			upperNext = false
+		}
+		prefix.WriteRune(r)
+	}
+	return prefix.String()
+}
+
+// repositoryCredentialKey loads the encryption key, creating it readable only
+// by the current user on first use
+func repositoryCredentialKey() ([]byte, error) {
+	repositoryKeyMutex.Lock()
+	defer repositoryKeyMutex.Unlock()
+
+	if repositoryKey != nil {
+		return repositoryKey, nil
+	}
+
+	path := filepath.Join(database.GetDataDir(), repositoryKeyFile)
+	key, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if len(key) != 32 {
+			return nil, fmt.Errorf("repository credential key %s is corrupt", path)
+		}
+	case os.IsNotExist(err):
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate repository credential key: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
+		}
+		if err := os.WriteFile(path, key, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write repository credential key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to read repository credential key: %w", err)
+	}
+
+	repositoryKey = key
+	return key, nil
+}
+
+func repositoryCipher() (cipher.AEAD, error) {
+	key, err := repositoryCredentialKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptRepositorySecret seals a secret with AES-GCM
+func encryptRepositorySecret(secret string) (string, error) {
+	gcm, err := repositoryCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return repositorySecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptRepositorySecret(stored string) (string, error) {
+	if stored == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(stored, repositorySecretPrefix) {
+		return "", fmt.Errorf("secret is not in a known format")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, repositorySecretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	gcm, err := repositoryCipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("secret is truncated")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret; was %s replaced?", repositoryKeyFile)
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,134 @@
+// Package services - Per-service ulimits and JVM memory hints
+package services
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	minNoFileLimit   = 64
+	minNProcLimit    = 16
+	maxProcessLimit  = 1048576
+	minMemoryLimitMB = 64
+	maxCPUs          = 1024
+)
+
+// GetServiceResourceLimits returns the ulimits and memory hints of a service,
+// all zero when none were saved
+func (sm *Manager) GetServiceResourceLimits(serviceUUID string) (*models.ServiceResourceLimits, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	limits, err := sm.db.GetServiceResourceLimits(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	if limits == nil {
+		limits = &models.ServiceResourceLimits{ServiceID: serviceUUID}
+	}
+	return limits, nil
+}
+
+// SetServiceResourceLimits validates and saves a service's ulimits and memory
+// hints; they apply from its next start
+func (sm *Manager) SetServiceResourceLimits(limits models.ServiceResourceLimits) error {
+	if _, exists := sm.GetServiceByUUID(limits.ServiceID); !exists {
+		return fmt.Errorf("service UUID %s not found", limits.ServiceID)
+	}
+
+	if (limits.NoFile != 0 || limits.NProc != 0) && runtime.GOOS == "windows" {
+		return fmt.Errorf("ulimits are not supported on Windows")
+	}
+	if limits.NoFile != 0 && (limits.NoFile < minNoFileLimit || limits.NoFile > maxProcessLimit) {
+		return fmt.Errorf("nofile must be between %d and %d, or 0 to keep the inherited limit", minNoFileLimit, maxProcessLimit)
+	}
+	if limits.NProc != 0 && (limits.NProc < minNProcLimit || limits.NProc > maxProcessLimit) {
+		return fmt.Errorf("nproc must be between %d and %d, or 0 to keep the inherited limit", minNProcLimit, maxProcessLimit)
+	}
+	if limits.MemoryLimitMB != 0 && limits.MemoryLimitMB < minMemoryLimitMB {
+		return fmt.Errorf("memoryLimitMb must be at least %d, or 0 for no limit", minMemoryLimitMB)
+	}
+	if limits.HeapPercent < 0 || limits.HeapPercent > 100 {
+		return fmt.Errorf("heapPercent must be between 1 and 100, or 0 for the JVM default")
+	}
+	if limits.CPUs < 0 || limits.CPUs > maxCPUs {
+		return fmt.Errorf("cpus must be between 1 and %d, or 0 for all processors", maxCPUs)
+	}
+
+	return sm.db.SaveServiceResourceLimits(limits)
+}
+
+// RemoveServiceResourceLimits drops a service's ulimits and memory hints; it
+// starts with the limits Vertex runs with from its next start
+func (sm *Manager) RemoveServiceResourceLimits(serviceUUID string) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	return sm.db.DeleteServiceResourceLimits(serviceUUID)
+}
+
+// serviceResourceLimits returns the limits a service starts with, or nil when
+// it has none
+func (sm *Manager) serviceResourceLimits(serviceUUID, serviceName string) *models.ServiceResourceLimits {
+	limits, err := sm.db.GetServiceResourceLimits(serviceUUID)
+	if err != nil {
+		log.Printf("[WARN] Starting %s without its resource limits: %v", serviceName, err)
+		return nil
+	}
+	return limits
+}
+
+// ulimitPrefix returns shell commands raising the soft ulimits before a start
+// command runs. A limit above the hard limit falls back to the hard limit and
+// says so in the service's log rather than failing the start; macOS caps open
+// files well below what is often asked for.
+func ulimitPrefix(limits *models.ServiceResourceLimits) string {
+	if limits == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, limit := range []struct {
+		flag  string
+		name  string
+		value int
+	}{
+		{"-n", "open files", limits.NoFile},
+		{"-u", "processes", limits.NProc},
+	} {
+		if limit.value == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, `{ ulimit -S %s %d 2>/dev/null || { ulimit -S %s hard; echo "Vertex: cannot raise the %s limit to %d; using the hard limit of $(ulimit -H %s)" >&2; }; }; `,
+			limit.flag, limit.value, limit.flag, limit.name, limit.value, limit.flag)
+	}
+	return b.String()
+}
+
+// jvmMemoryHints returns the JVM options that make a service's JVM size its
+// heap and thread pools as it would inside a container with these limits.
+// They go in front of the service's own options, so an explicit -Xmx or
+// -XX:MaxRAMPercentage still wins.
+func jvmMemoryHints(limits *models.ServiceResourceLimits) string {
+	if limits == nil {
+		return ""
+	}
+
+	hints := []string{}
+	if limits.MemoryLimitMB > 0 {
+		hints = append(hints, fmt.Sprintf("-XX:MaxRAM=%dm", limits.MemoryLimitMB))
+	}
+	if limits.HeapPercent > 0 {
+		hints = append(hints, fmt.Sprintf("-XX:MaxRAMPercentage=%d.0", limits.HeapPercent))
+	}
+	if limits.CPUs > 0 {
+		hints = append(hints, fmt.Sprintf("-XX:ActiveProcessorCount=%d", limits.CPUs))
+	}
+	return strings.Join(hints, " ")
+}
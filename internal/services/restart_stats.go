@@ -0,0 +1,157 @@
+// Package services - How often each service restarts and crashes
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	restartStatsRetention     = 8 * 24 * time.Hour // A day more than the longest window
+	defaultRestartBoardWindow = "24h"
+	defaultRestartBoardLimit  = 10
+)
+
+// restartWindows are the windows restarts are counted in
+var restartWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// recordServiceRestart counts a restart or crash of a service. It does not
+// take the service mutex so it can be called while held.
+func (sm *Manager) recordServiceRestart(service *models.Service, reason string) {
+	now := time.Now()
+	if err := sm.db.InsertServiceRestart(service.ID, reason, now, now.Add(-restartStatsRetention)); err != nil {
+		log.Printf("[WARN] Failed to record %s restart of service %s: %v", reason, service.Name, err)
+	}
+}
+
+// GetRestartStats returns how often each service restarted in the last
+// hour, day and week; services without restarts are left out
+func (sm *Manager) GetRestartStats() (map[string]*models.RestartStats, error) {
+	now := time.Now()
+	restarts, err := sm.db.GetServiceRestarts(now.Add(-restartWindows["7d"]))
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*models.RestartStats)
+	for _, restart := range restarts {
+		serviceStats, exists := stats[restart.ServiceID]
+		if !exists {
+			serviceStats = &models.RestartStats{}
+			stats[restart.ServiceID] = serviceStats
+		}
+
+		age := now.Sub(restart.Timestamp)
+		countRestart(&serviceStats.LastWeek, restart.Reason)
+		if age <= restartWindows["24h"] {
+			countRestart(&serviceStats.LastDay, restart.Reason)
+		}
+		if age <= restartWindows["1h"] {
+			countRestart(&serviceStats.LastHour, restart.Reason)
+		}
+		timestamp := restart.Timestamp
+		serviceStats.LastRestartAt = &timestamp
+	}
+	return stats, nil
+}
+
+func countRestart(counts *models.RestartCounts, reason string) {
+	counts.Total++
+	if reason == models.RestartCrash {
+		counts.Crashes++
+	} else {
+		counts.Manual++
+	}
+}
+
+// restartCountsIn returns the counts of a window of restart statistics
+func restartCountsIn(stats models.RestartStats, window string) models.RestartCounts {
+	switch window {
+	case "1h":
+		return stats.LastHour
+	case "7d":
+		return stats.LastWeek
+	default:
+		return stats.LastDay
+	}
+}
+
+// withRestartStats sets the restart statistics of listed services; a
+// service without restarts gets zero counts
+func (sm *Manager) withRestartStats(services []*models.Service) {
+	stats, err := sm.GetRestartStats()
+	if err != nil {
+		log.Printf("[WARN] Listing services without restart statistics: %v", err)
+		return
+	}
+	for i := range services {
+		if serviceStats, exists := stats[services[i].ID]; exists {
+			services[i].Restarts = serviceStats
+		} else {
+			services[i].Restarts = &models.RestartStats{}
+		}
+	}
+}
+
+// GetRestartLeaderboard ranks the services of a profile by their restarts
+// within a window (1h, 24h or 7d), most restarts first. Services that did not
+// restart in the window are left out.
+func (sm *Manager) GetRestartLeaderboard(profile *models.ServiceProfile, window string, limit int) (*models.RestartLeaderboard, error) {
+	if window == "" {
+		window = defaultRestartBoardWindow
+	}
+	if _, valid := restartWindows[window]; !valid {
+		return nil, fmt.Errorf("unsupported window '%s' (use 1h, 24h or 7d)", window)
+	}
+	if limit <= 0 {
+		limit = defaultRestartBoardLimit
+	}
+
+	stats, err := sm.GetRestartStats()
+	if err != nil {
+		return nil, err
+	}
+
+	board := &models.RestartLeaderboard{ProfileID: profile.ID, Window: window, Services: []models.RestartLeaderboardEntry{}}
+	for _, serviceUUID := range profile.Services {
+		serviceStats, exists := stats[serviceUUID]
+		if !exists || restartCountsIn(*serviceStats, window).Total == 0 {
+			continue
+		}
+		service, exists := sm.GetServiceSnapshot(serviceUUID)
+		if !exists {
+			continue
+		}
+		board.Services = append(board.Services, models.RestartLeaderboardEntry{
+			ServiceID:   service.ID,
+			ServiceName: service.Name,
+			Status:      service.Status,
+			Restarts:    *serviceStats,
+		})
+	}
+
+	sort.SliceStable(board.Services, func(i, j int) bool {
+		a := restartCountsIn(board.Services[i].Restarts, window)
+		b := restartCountsIn(board.Services[j].Restarts, window)
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		if a.Crashes != b.Crashes {
+			return a.Crashes > b.Crashes
+		}
+		return strings.ToLower(board.Services[i].ServiceName) < strings.ToLower(board.Services[j].ServiceName)
+	})
+	if len(board.Services) > limit {
+		board.Services = board.Services[:limit]
+	}
+	return board, nil
+}
@@ -0,0 +1,137 @@
+// Package services - Starting services as a dedicated OS user
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+var runAsUserNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,31}$`)
+
+// validateRunAsUser trims the run-as user of a service and checks that the
+// account exists; an empty user runs the service as the Vertex user
+func validateRunAsUser(username *string) error {
+	*username = strings.TrimSpace(*username)
+	if *username == "" {
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("invalid run-as user: running services as another user is not supported on Windows")
+	}
+	if !runAsUserNameRegex.MatchString(*username) {
+		return fmt.Errorf("invalid run-as user '%s'", *username)
+	}
+
+	account, err := user.Lookup(*username)
+	if err != nil {
+		return fmt.Errorf("invalid run-as user '%s': no such user", *username)
+	}
+	if account.Uid == "0" {
+		return fmt.Errorf("invalid run-as user '%s': services may not run as root", *username)
+	}
+	return nil
+}
+
+// lookupRunAsUser returns the account a service runs as, or nil when it runs
+// as the Vertex user
+func lookupRunAsUser(username string) (*user.User, error) {
+	if username == "" {
+		return nil, nil
+	}
+	account, err := user.Lookup(username)
+	if err != nil {
+		return nil, fmt.Errorf("run-as user %s: %w", username, err)
+	}
+	if account.Uid == strconv.Itoa(os.Getuid()) {
+		return nil, nil
+	}
+	return account, nil
+}
+
+// applyRunAsUser makes a service's start command run as its run-as user,
+// with that user's home directory. The service's mutex must be held.
+func applyRunAsUser(cmd *exec.Cmd, service *models.Service) error {
+	account, err := lookupRunAsUser(service.RunAsUser)
+	if err != nil || account == nil {
+		return err
+	}
+
+	viaSudo, err := RunAsUser(cmd, account)
+	if err != nil {
+		return err
+	}
+
+	// Maven and Gradle keep their caches in the home of the user they run as
+	cmd.Env = append(cmd.Env, "HOME="+account.HomeDir, "USER="+account.Username, "LOGNAME="+account.Username)
+
+	if viaSudo {
+		log.Printf("[INFO] Service %s: running as user %s through sudo", service.Name, account.Username)
+	} else {
+		log.Printf("[INFO] Service %s: running as user %s (uid %s)", service.Name, account.Username, account.Uid)
+	}
+	return nil
+}
+
+// runsThroughSudo reports whether a service's processes are started through
+// sudo, which does not forward SIGSTOP and SIGKILL to them
+func runsThroughSudo(service *models.Service) bool {
+	if os.Geteuid() == 0 {
+		return false
+	}
+	account, err := lookupRunAsUser(service.RunAsUser)
+	return err == nil && account != nil
+}
+
+// checkPreflightRunAsUser verifies that Vertex can start processes as the
+// service's run-as user and that the user can write the service directory
+func checkPreflightRunAsUser(result *PreflightResult, username, serviceDir string) {
+	if username == "" {
+		return
+	}
+
+	account, err := lookupRunAsUser(username)
+	if err != nil {
+		result.add("runAsUser", PreflightFail, "run_as_user_missing", fmt.Sprintf("run-as user %s does not exist", username))
+		return
+	}
+	if account == nil {
+		result.add("runAsUser", PreflightPass, "", "Vertex already runs as "+username)
+		return
+	}
+
+	probe := exec.Command("true")
+	viaSudo, err := RunAsUser(probe, account)
+	if err != nil {
+		result.add("runAsUser", PreflightFail, "run_as_user_unsupported", err.Error())
+		return
+	}
+	if err := probe.Run(); err != nil {
+		if viaSudo {
+			vertexUser := "<vertex-user>"
+			if current, err := user.Current(); err == nil {
+				vertexUser = current.Username
+			}
+			result.add("runAsUser", PreflightFail, "run_as_user_sudo_denied",
+				fmt.Sprintf("cannot run commands as %s without a password; add a sudoers rule such as '%s ALL=(%s) NOPASSWD:SETENV: ALL'", username, vertexUser, username))
+			return
+		}
+		result.add("runAsUser", PreflightFail, "run_as_user_failed", fmt.Sprintf("cannot start processes as %s: %v", username, err))
+		return
+	}
+
+	probe = exec.Command("test", "-w", serviceDir)
+	if _, err := RunAsUser(probe, account); err == nil && probe.Run() != nil {
+		result.add("runAsUser", PreflightFail, "run_as_user_no_access", fmt.Sprintf("user %s cannot write to %s", username, serviceDir))
+		return
+	}
+	result.add("runAsUser", PreflightPass, "", "runs as "+username)
+}
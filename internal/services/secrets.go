@@ -0,0 +1,253 @@
+// Package services - Env var values resolved from external secret stores
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// secretResolveTimeout bounds the lookup of one secret reference
+const secretResolveTimeout = 30 * time.Second
+
+// Prefixes of env var values that refer to a secret instead of holding it:
+//
+//	vault:secret/data/dev#DB_PASSWORD   HashiCorp Vault, KV version 1 or 2
+//	ssm:/dev/db/password                AWS SSM Parameter Store, decrypted
+//	op://dev/database/password          1Password secret reference
+const (
+	secretPrefixVault     = "vault:"
+	secretPrefixSSM       = "ssm:"
+	secretPrefix1Password = "op://"
+)
+
+// redactedSecretValue replaces resolved secrets in Vertex's own log
+const redactedSecretValue = "<secret>"
+
+// isSecretReference reports whether an env var value names a secret
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, secretPrefixVault) ||
+		strings.HasPrefix(value, secretPrefixSSM) ||
+		strings.HasPrefix(value, secretPrefix1Password)
+}
+
+// resolvedSecrets maps secret references to their values. It only lives
+// for one process start; the values are never stored.
+type resolvedSecrets map[string]string
+
+// value returns the secret a reference resolved to, or the value itself
+func (s resolvedSecrets) value(value string) string {
+	if secret, exists := s[value]; exists {
+		return secret
+	}
+	return value
+}
+
+// redact hides the value of a KEY=VALUE entry that holds a resolved secret
+func (s resolvedSecrets) redact(entry string) string {
+	key, value, found := strings.Cut(entry, "=")
+	if !found {
+		return entry
+	}
+	for _, secret := range s {
+		if value == secret {
+			return key + "=" + redactedSecretValue
+		}
+	}
+	return entry
+}
+
+// resolveServiceSecrets resolves the secret references among the global and
+// service env vars of a service. The plain values are visible to the
+// providers, so VAULT_ADDR, AWS_PROFILE or OP_ACCOUNT can be set there. A
+// reference that cannot be resolved fails the start rather than passing the
+// reference to the service.
+func (sm *Manager) resolveServiceSecrets(ctx context.Context, service *models.Service, globalEnvVars map[string]string) (resolvedSecrets, error) {
+	values := make(map[string]string, len(globalEnvVars))
+	for key, value := range globalEnvVars {
+		values[key] = value
+	}
+	service.Mutex.RLock()
+	for key, envVar := range service.EnvVars {
+		values[key] = envVar.Value
+	}
+	service.Mutex.RUnlock()
+
+	return resolveSecrets(ctx, values)
+}
+
+// resolveSecrets looks up every secret reference among values, once each
+func resolveSecrets(ctx context.Context, values map[string]string) (resolvedSecrets, error) {
+	env := make(map[string]string)
+	for _, entry := range os.Environ() {
+		if key, value, found := strings.Cut(entry, "="); found && !isSecretReference(value) {
+			env[key] = value
+		}
+	}
+	var keys []string
+	for key, value := range values {
+		if isSecretReference(value) {
+			keys = append(keys, key)
+		} else {
+			env[key] = value
+		}
+	}
+	sort.Strings(keys)
+
+	secrets := make(resolvedSecrets)
+	for _, key := range keys {
+		reference := values[key]
+		if _, done := secrets[reference]; done {
+			continue
+		}
+		secret, err := resolveSecret(ctx, reference, env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve secret for %s: %w", key, err)
+		}
+		secrets[reference] = secret
+	}
+	return secrets, nil
+}
+
+// resolveSecret looks up one reference with the provider its prefix names
+func resolveSecret(ctx context.Context, reference string, env map[string]string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, secretResolveTimeout)
+	defer cancel()
+
+	switch {
+	case strings.HasPrefix(reference, secretPrefixVault):
+		return resolveVaultSecret(ctx, strings.TrimPrefix(reference, secretPrefixVault), env)
+	case strings.HasPrefix(reference, secretPrefixSSM):
+		name := strings.TrimPrefix(reference, secretPrefixSSM)
+		if name == "" {
+			return "", fmt.Errorf("ssm reference has no parameter name")
+		}
+		output, err := runSecretCLI(ctx, env, "aws", "ssm", "get-parameter", "--name", name,
+			"--with-decryption", "--query", "Parameter.Value", "--output", "text")
+		return strings.TrimRight(output, "\r\n"), err
+	case strings.HasPrefix(reference, secretPrefix1Password):
+		return runSecretCLI(ctx, env, "op", "read", "--no-newline", reference)
+	}
+	return "", fmt.Errorf("unknown secret reference")
+}
+
+// runSecretCLI runs a provider's command line tool and returns its output
+func runSecretCLI(ctx context.Context, env map[string]string, name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", fmt.Errorf("%s CLI not found in PATH", name)
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("%s timed out after %s", name, secretResolveTimeout)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s: %s", name, message)
+		}
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return stdout.String(), nil
+}
+
+// resolveVaultSecret reads a field of a Vault secret over the HTTP API. The
+// path is the API path, such as secret/data/dev for KV version 2. The field
+// may be left out when the secret holds a single one.
+func resolveVaultSecret(ctx context.Context, reference string, env map[string]string) (string, error) {
+	path, field, _ := strings.Cut(reference, "#")
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", fmt.Errorf("vault reference has no path")
+	}
+
+	address := strings.TrimRight(env["VAULT_ADDR"], "/")
+	if address == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+	token := env["VAULT_TOKEN"]
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is not set and ~/.vault-token is missing")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address+"/v1/"+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid VAULT_ADDR: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := env["VAULT_NAMESPACE"]; namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s for %s", resp.Status, path)
+	}
+
+	var secret struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+	fields := secret.Data
+	// KV version 2 nests the fields under data.data, next to data.metadata
+	if nested, exists := fields["data"]; exists {
+		if _, versioned := fields["metadata"]; versioned {
+			fields = nil
+			if err := json.Unmarshal(nested, &fields); err != nil {
+				return "", fmt.Errorf("invalid vault response: %w", err)
+			}
+		}
+	}
+
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("vault secret %s has %d fields; name one after #", path, len(fields))
+		}
+		for name := range fields {
+			field = name
+		}
+	}
+	raw, exists := fields[field]
+	if !exists {
+		return "", fmt.Errorf("vault secret %s has no field %s", path, field)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		// Numbers and booleans are passed on as written
+		value = string(raw)
+	}
+	return value, nil
+}
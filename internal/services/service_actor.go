@@ -0,0 +1,304 @@
+// Package services - Per-service actors that serialize lifecycle operations
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// errShuttingDown is returned for lifecycle operations that arrive after
+// GracefulShutdown began
+var errShuttingDown = errors.New("service manager is shutting down")
+
+// OperationConflictError is returned when the same operation is already
+// queued or running for a service, e.g. a second click on restart
+type OperationConflictError struct {
+	Operation models.ServiceOperation // The operation in progress
+}
+
+func (e *OperationConflictError) Error() string {
+	return fmt.Sprintf("%s of %s is already %s", e.Operation.Name, e.Operation.ServiceName, e.Operation.State)
+}
+
+// operationIDs numbers the accepted operations of every service
+var operationIDs atomic.Int64
+
+// serviceOp is one lifecycle operation (start, stop, restart, suspend) queued
+// on a service's actor
+type serviceOp struct {
+	run       func(ctx context.Context) error
+	done      chan error
+	operation *models.ServiceOperation
+}
+
+// serviceActor owns the lifecycle of one service. Operations run one at a
+// time on its goroutine, so a start never overlaps a stop of the same service
+// and the long parts of a start do not need the service mutex. The mutex only
+// guards field reads and writes.
+type serviceActor struct {
+	ops  chan serviceOp
+	quit chan struct{}
+
+	// pending lists the accepted operations in arrival order; different
+	// operations queue, a duplicate of a pending one is refused
+	pending      []*models.ServiceOperation
+	pendingMutex sync.Mutex
+}
+
+// accept records an operation as queued, or returns the pending operation
+// of the same name
+func (a *serviceActor) accept(operation *models.ServiceOperation) error {
+	a.pendingMutex.Lock()
+	defer a.pendingMutex.Unlock()
+
+	for _, pending := range a.pending {
+		if pending.Name == operation.Name {
+			return &OperationConflictError{Operation: *pending}
+		}
+	}
+	a.pending = append(a.pending, operation)
+	return nil
+}
+
+// markRunning records that the actor began an operation
+func (a *serviceActor) markRunning(operation *models.ServiceOperation) {
+	a.pendingMutex.Lock()
+	defer a.pendingMutex.Unlock()
+
+	now := time.Now()
+	operation.State = models.OperationRunning
+	operation.StartedAt = &now
+}
+
+// finish forgets an operation that completed or was never handed over
+func (a *serviceActor) finish(operation *models.ServiceOperation) {
+	a.pendingMutex.Lock()
+	defer a.pendingMutex.Unlock()
+
+	for i, pending := range a.pending {
+		if pending == operation {
+			a.pending = append(a.pending[:i], a.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// operations returns copies of the pending operations
+func (a *serviceActor) operations() []models.ServiceOperation {
+	a.pendingMutex.Lock()
+	defer a.pendingMutex.Unlock()
+
+	operations := make([]models.ServiceOperation, 0, len(a.pending))
+	for _, pending := range a.pending {
+		operations = append(operations, *pending)
+	}
+	return operations
+}
+
+// serviceActorFor returns the actor of a service, starting it on first use.
+// It returns nil once the manager is shutting down.
+func (sm *Manager) serviceActorFor(serviceUUID string) *serviceActor {
+	sm.actorsMutex.Lock()
+	defer sm.actorsMutex.Unlock()
+
+	if sm.ctx.Err() != nil {
+		return nil
+	}
+
+	actor, exists := sm.actors[serviceUUID]
+	if !exists {
+		actor = &serviceActor{
+			ops:  make(chan serviceOp),
+			quit: make(chan struct{}),
+		}
+		sm.actors[serviceUUID] = actor
+		sm.actorsWG.Add(1)
+		go sm.runServiceActor(actor)
+	}
+	return actor
+}
+
+// runServiceActor executes queued operations until the service is removed or
+// the manager shuts down
+func (sm *Manager) runServiceActor(actor *serviceActor) {
+	defer sm.actorsWG.Done()
+
+	for {
+		select {
+		case <-sm.ctx.Done():
+			return
+		case <-actor.quit:
+			return
+		case op := <-actor.ops:
+			actor.markRunning(op.operation)
+			err := op.run(sm.ctx)
+			actor.finish(op.operation)
+			op.done <- err
+		}
+	}
+}
+
+// runServiceOp hands an operation to the service's actor and waits for its
+// result. ctx only bounds the wait: an operation that was already accepted
+// runs to completion under the manager's context. The same operation already
+// queued or running is refused with an OperationConflictError.
+func (sm *Manager) runServiceOp(ctx context.Context, service *models.Service, name string, run func(ctx context.Context) error) error {
+	actor := sm.serviceActorFor(service.ID)
+	if actor == nil {
+		return errShuttingDown
+	}
+
+	operation := &models.ServiceOperation{
+		ID:          operationIDs.Add(1),
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		Name:        name,
+		State:       models.OperationQueued,
+		RequestedAt: time.Now(),
+	}
+	if err := actor.accept(operation); err != nil {
+		return err
+	}
+
+	op := serviceOp{run: run, done: make(chan error, 1), operation: operation}
+	select {
+	case actor.ops <- op:
+	case <-actor.quit:
+		actor.finish(operation)
+		return fmt.Errorf("service %s was removed", service.Name)
+	case <-sm.ctx.Done():
+		actor.finish(operation)
+		return errShuttingDown
+	case <-ctx.Done():
+		actor.finish(operation)
+		return fmt.Errorf("%s of %s not started: %w", name, service.Name, ctx.Err())
+	}
+
+	select {
+	case err := <-op.done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("stopped waiting for %s of %s: %w", name, service.Name, ctx.Err())
+	}
+}
+
+// startServiceOp starts a service on its actor
+func (sm *Manager) startServiceOp(ctx context.Context, service *models.Service, projectsDir string) error {
+	return sm.runServiceOp(ctx, service, "start", func(ctx context.Context) error {
+		return sm.startServiceWithProjectsDir(ctx, service, projectsDir)
+	})
+}
+
+// stopServiceOp stops a service on its actor
+func (sm *Manager) stopServiceOp(ctx context.Context, service *models.Service) error {
+	return sm.runServiceOp(ctx, service, "stop", func(ctx context.Context) error {
+		return sm.stopService(service)
+	})
+}
+
+// restartServiceOp stops a running service, frees its port and starts it
+// again as a single operation so nothing can slip in between
+func (sm *Manager) restartServiceOp(ctx context.Context, service *models.Service, projectsDir string) error {
+	return sm.runServiceOp(ctx, service, "restart", func(ctx context.Context) error {
+		service.Mutex.RLock()
+		running := service.Status == "running" || service.Status == StatusPaused
+		port := service.Port
+		service.Mutex.RUnlock()
+
+		// Stop the service first
+		if running {
+			if err := sm.stopService(service); err != nil {
+				log.Printf("[WARN] Failed to stop service gracefully: %v", err)
+				// Continue anyway - we'll clean up the port
+			}
+			// Wait a moment for cleanup
+			if !sleepContext(ctx, 2*time.Second) {
+				return fmt.Errorf("restart of %s cancelled: %w", service.Name, ctx.Err())
+			}
+		}
+
+		// Clean up any processes still using the service's port
+		if port > 0 {
+			log.Printf("[INFO] Cleaning up port %d before restarting service UUID %s", port, service.ID)
+			if err := sm.CleanupPortBeforeStart(port); err != nil {
+				log.Printf("[WARN] Port cleanup failed: %v", err)
+				// Continue anyway - the port might be available by now
+			}
+		}
+
+		if err := sm.startServiceWithProjectsDir(ctx, service, projectsDir); err != nil {
+			return err
+		}
+
+		// Record restart event
+		uptimeTracker := GetUptimeTracker()
+		uptimeTracker.RecordEvent(service.ProfileID, service.ID, "restart", "running")
+		sm.recordServiceRestart(service, models.RestartManual)
+		return nil
+	})
+}
+
+// GetServiceOperations returns the lifecycle operations queued or running for
+// a service, oldest first
+func (sm *Manager) GetServiceOperations(serviceUUID string) ([]models.ServiceOperation, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	sm.actorsMutex.Lock()
+	actor, exists := sm.actors[serviceUUID]
+	sm.actorsMutex.Unlock()
+	if !exists {
+		return []models.ServiceOperation{}, nil
+	}
+	return actor.operations(), nil
+}
+
+// removeServiceActor stops the actor of a deleted service
+func (sm *Manager) removeServiceActor(serviceUUID string) {
+	sm.actorsMutex.Lock()
+	defer sm.actorsMutex.Unlock()
+
+	if actor, exists := sm.actors[serviceUUID]; exists {
+		close(actor.quit)
+		delete(sm.actors, serviceUUID)
+	}
+}
+
+// waitForServiceActors waits for the actors to finish their current
+// operation after the manager's context was cancelled. It reports false when
+// some were still busy at the deadline.
+func (sm *Manager) waitForServiceActors(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		sm.actorsWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// sleepContext sleeps for d and reports false if ctx was cancelled first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,375 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// newTestManager returns a manager without a database, enough to run
+// service actors and replicas
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	sm := &Manager{
+		services:       make(map[string]*models.Service),
+		configurations: make(map[string]*models.Configuration),
+		ctx:            ctx,
+		cancel:         cancel,
+		actors:         make(map[string]*serviceActor),
+	}
+	t.Cleanup(func() {
+		cancel()
+		sm.waitForServiceActors(time.Second)
+	})
+	return sm
+}
+
+func TestRunServiceOp_RunsOperationsOneAtATime(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	// Create operations that record how many run at once
+	var running, maxRunning atomic.Int32
+	run := func(ctx context.Context) error {
+		n := running.Add(1)
+		for {
+			current := maxRunning.Load()
+			if n <= current || maxRunning.CompareAndSwap(current, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		running.Add(-1)
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"start", "stop", "restart", "suspend"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if err := sm.runServiceOp(context.Background(), service, name, run); err != nil {
+				t.Errorf("Operation %s failed: %v", name, err)
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if got := maxRunning.Load(); got != 1 {
+		t.Errorf("Expected operations to run one at a time, got %d at once", got)
+	}
+}
+
+func TestRunServiceOp_ReturnsOperationError(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+	want := errors.New("port in use")
+
+	err := sm.runServiceOp(context.Background(), service, "start", func(ctx context.Context) error {
+		return want
+	})
+	if !errors.Is(err, want) {
+		t.Errorf("Expected the operation's error, got %v want %v", err, want)
+	}
+}
+
+func TestRunServiceOp_SeparateActorsPerService(t *testing.T) {
+	sm := newTestManager(t)
+	first := &models.Service{ID: "svc-1", Name: "api"}
+	second := &models.Service{ID: "svc-2", Name: "worker"}
+
+	// Create a long operation on the first service
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go sm.runServiceOp(context.Background(), first, "start", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := sm.runServiceOp(ctx, second, "start", func(ctx context.Context) error { return nil })
+	if err != nil {
+		t.Errorf("Expected the second service not to wait for the first, got %v", err)
+	}
+}
+
+func TestRunServiceOp_WaitCancelledOperationStillRuns(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := sm.runServiceOp(ctx, service, "start", func(ctx context.Context) error {
+		<-release
+		close(finished)
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stopped waiting") {
+		t.Errorf("Expected the wait to time out, got %v", err)
+	}
+
+	// Create the release after the caller gave up; the operation completes
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Error("Expected the accepted operation to run to completion")
+	}
+}
+
+func TestRunServiceOp_QueuedOperationNotStartedWhenCancelled(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go sm.runServiceOp(context.Background(), service, "start", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var ran atomic.Bool
+	err := sm.runServiceOp(ctx, service, "stop", func(ctx context.Context) error {
+		ran.Store(true)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "not started") {
+		t.Errorf("Expected the queued operation not to start, got %v", err)
+	}
+	if ran.Load() {
+		t.Error("Expected the cancelled operation never to run")
+	}
+}
+
+func TestRunServiceOp_RemovedService(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go sm.runServiceOp(context.Background(), service, "start", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	// Create a queued operation, then remove the service under it
+	result := make(chan error, 1)
+	go func() {
+		result <- sm.runServiceOp(context.Background(), service, "stop", func(ctx context.Context) error { return nil })
+	}()
+	time.Sleep(20 * time.Millisecond)
+	sm.removeServiceActor(service.ID)
+
+	select {
+	case err := <-result:
+		if err == nil || !strings.Contains(err.Error(), "was removed") {
+			t.Errorf("Expected the queued operation to fail for a removed service, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the queued operation to return after the service was removed")
+	}
+}
+
+func TestRunServiceOp_ShuttingDown(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	sm.cancel()
+	if !sm.waitForServiceActors(time.Second) {
+		t.Fatal("Expected idle actors to stop on shutdown")
+	}
+
+	err := sm.runServiceOp(context.Background(), service, "start", func(ctx context.Context) error { return nil })
+	if !errors.Is(err, errShuttingDown) {
+		t.Errorf("Expected operations to be refused after shutdown, got %v want %v", err, errShuttingDown)
+	}
+}
+
+func TestWaitForServiceActors_BusyActor(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go sm.runServiceOp(context.Background(), service, "stop", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	sm.cancel()
+	if sm.waitForServiceActors(20 * time.Millisecond) {
+		t.Error("Expected the wait to report an actor still busy")
+	}
+	close(release)
+	if !sm.waitForServiceActors(time.Second) {
+		t.Error("Expected the actor to stop after its operation finished")
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	if !sleepContext(context.Background(), time.Millisecond) {
+		t.Error("Expected an uncancelled sleep to report true")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if sleepContext(ctx, time.Minute) {
+		t.Error("Expected a cancelled sleep to report false")
+	}
+}
+
+func TestRunServiceOp_RefusesDuplicateOperation(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go sm.runServiceOp(context.Background(), service, "restart", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	err := sm.runServiceOp(context.Background(), service, "restart", func(ctx context.Context) error {
+		t.Error("Expected the duplicate restart never to run")
+		return nil
+	})
+	var conflictErr *OperationConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Expected an OperationConflictError, got %v", err)
+	}
+	if conflictErr.Operation.Name != "restart" || conflictErr.Operation.State != models.OperationRunning {
+		t.Errorf("Expected the running restart in the conflict, got %+v", conflictErr.Operation)
+	}
+	if got := conflictErr.Error(); got != "restart of api is already "+models.OperationRunning {
+		t.Errorf("Unexpected conflict message: got %q", got)
+	}
+}
+
+func TestRunServiceOp_QueuesDifferentOperations(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+	sm.services[service.ID] = service
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go sm.runServiceOp(context.Background(), service, "start", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// Create a stop that queues behind the running start
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- sm.runServiceOp(context.Background(), service, "stop", func(ctx context.Context) error { return nil })
+	}()
+	deadline := time.Now().Add(time.Second)
+	var operations []models.ServiceOperation
+	for time.Now().Before(deadline) {
+		var err error
+		if operations, err = sm.GetServiceOperations(service.ID); err != nil {
+			t.Fatal(err)
+		}
+		if len(operations) == 2 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if len(operations) != 2 {
+		t.Fatalf("Expected 2 pending operations, got %+v", operations)
+	}
+	if operations[0].Name != "start" || operations[0].State != models.OperationRunning || operations[0].StartedAt == nil {
+		t.Errorf("Expected the start to be running first, got %+v", operations[0])
+	}
+	if operations[1].Name != "stop" || operations[1].State != models.OperationQueued || operations[1].StartedAt != nil {
+		t.Errorf("Expected the stop to be queued second, got %+v", operations[1])
+	}
+	if operations[0].ID >= operations[1].ID {
+		t.Errorf("Expected operation IDs to increase, got %d and %d", operations[0].ID, operations[1].ID)
+	}
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Errorf("Expected the queued stop to succeed, got %v", err)
+	}
+	operations, err := sm.GetServiceOperations(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operations) != 0 {
+		t.Errorf("Expected no pending operations after both finished, got %+v", operations)
+	}
+}
+
+func TestRunServiceOp_AcceptsOperationAgainAfterCancelledWait(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go sm.runServiceOp(context.Background(), service, "start", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	// Create a queued stop whose caller gives up before it is handed over
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	sm.runServiceOp(ctx, service, "stop", func(ctx context.Context) error { return nil })
+
+	operations := sm.actors[service.ID].operations()
+	if len(operations) != 1 || operations[0].Name != "start" {
+		t.Errorf("Expected the abandoned stop to be forgotten, got %+v", operations)
+	}
+}
+
+func TestGetServiceOperations(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+	sm.services[service.ID] = service
+
+	// Create a lookup of a service that never ran an operation
+	operations, err := sm.GetServiceOperations(service.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operations == nil || len(operations) != 0 {
+		t.Errorf("Expected an empty list, got %#v", operations)
+	}
+
+	if _, err := sm.GetServiceOperations("missing"); err == nil {
+		t.Error("Expected an error for an unknown service")
+	}
+}
@@ -0,0 +1,278 @@
+// Package services - Serving services on their own subdomain through nginx
+package services
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/installer"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// defaultVertexDomain is the domain service domains go under when nginx was
+// installed before the site was recorded
+const defaultVertexDomain = "vertex.dev"
+
+// A single RFC 1123 label, e.g. payments
+var serviceSubdomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ServiceDomainsResult reports the outcome of regenerating the nginx servers,
+// certificate and hosts file entries of the service domains
+type ServiceDomainsResult struct {
+	Nginx   *installer.NginxLocationsResult `json:"nginx"`
+	Hosts   *installer.HostsFileResult      `json:"hosts"`
+	HTTPS   bool                            `json:"https"`             // The domains are served with a certificate
+	Warning string                          `json:"warning,omitempty"` // Why they are not, when the site uses HTTPS
+}
+
+// defaultServiceSubdomain returns a subdomain label made from a service name
+func defaultServiceSubdomain(serviceName string) string {
+	label := strings.Trim(nginxLocationNameChars.ReplaceAllString(strings.ToLower(serviceName), "-"), "-")
+	label = strings.Trim(strings.NewReplacer(".", "-", "_", "-").Replace(label), "-")
+	if len(label) > 63 {
+		label = strings.TrimRight(label[:63], "-")
+	}
+	return label
+}
+
+// vertexSite returns the domain nginx serves Vertex under and whether it
+// uses HTTPS; .dev domains always do
+func vertexSite() installer.NginxSite {
+	site, err := installer.LoadNginxSite(nginxOutputDir())
+	if err != nil {
+		log.Printf("[WARN] %v; assuming %s", err, defaultVertexDomain)
+	}
+	if site == nil || site.Domain == "" {
+		return installer.NginxSite{Domain: defaultVertexDomain, HTTPS: true}
+	}
+	site.HTTPS = site.HTTPS || strings.HasSuffix(site.Domain, ".dev")
+	return *site
+}
+
+// fillServiceDomain sets the full domain and URL of a subdomain setting
+func fillServiceDomain(config *models.ServiceDomainConfig, site installer.NginxSite) {
+	config.Domain = config.Subdomain + "." + site.Domain
+	scheme := "http"
+	if site.HTTPS {
+		scheme = "https"
+	}
+	config.URL = scheme + "://" + config.Domain
+}
+
+// GetServiceDomainConfig returns a service's subdomain settings, defaulting
+// to a disabled subdomain named after the service
+func (sm *Manager) GetServiceDomainConfig(serviceUUID string) (*models.ServiceDomainConfig, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	configs, err := sm.db.GetServiceDomainConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	service.Mutex.RLock()
+	config := models.ServiceDomainConfig{
+		ServiceID: serviceUUID,
+		Subdomain: defaultServiceSubdomain(service.Name),
+		Port:      service.Port,
+	}
+	service.Mutex.RUnlock()
+
+	for _, stored := range configs {
+		if stored.ServiceID == serviceUUID {
+			config.Enabled = stored.Enabled
+			config.Subdomain = stored.Subdomain
+			break
+		}
+	}
+	fillServiceDomain(&config, vertexSite())
+	return &config, nil
+}
+
+// SetServiceDomainConfig validates and saves a service's subdomain settings,
+// then regenerates the service domains
+func (sm *Manager) SetServiceDomainConfig(config models.ServiceDomainConfig) (*ServiceDomainsResult, error) {
+	service, exists := sm.GetServiceByUUID(config.ServiceID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", config.ServiceID)
+	}
+
+	config.Subdomain = strings.ToLower(strings.TrimSpace(config.Subdomain))
+	if config.Subdomain == "" {
+		service.Mutex.RLock()
+		config.Subdomain = defaultServiceSubdomain(service.Name)
+		service.Mutex.RUnlock()
+	}
+	if !serviceSubdomainPattern.MatchString(config.Subdomain) {
+		return nil, fmt.Errorf("invalid subdomain '%s' (use a single label such as payments)", config.Subdomain)
+	}
+
+	if config.Enabled {
+		service.Mutex.RLock()
+		port := service.Port
+		service.Mutex.RUnlock()
+		if port == 0 {
+			return nil, fmt.Errorf("service %s has no port to proxy to", service.Name)
+		}
+
+		configs, err := sm.db.GetServiceDomainConfigs()
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range configs {
+			if other.Enabled && other.ServiceID != config.ServiceID && other.Subdomain == config.Subdomain {
+				if _, exists := sm.GetServiceByUUID(other.ServiceID); exists {
+					return nil, fmt.Errorf("subdomain '%s' is already used by another service", config.Subdomain)
+				}
+			}
+		}
+
+		domain := config.Subdomain + "." + vertexSite().Domain
+		hostnames, err := sm.db.GetProfileHostnames("")
+		if err != nil {
+			return nil, err
+		}
+		for _, hostname := range hostnames {
+			if hostname.Hostname == domain {
+				return nil, fmt.Errorf("%s is already a hostname of a profile", domain)
+			}
+		}
+	}
+
+	if err := sm.db.SaveServiceDomainConfig(config); err != nil {
+		return nil, err
+	}
+	return sm.ApplyServiceDomains()
+}
+
+// RemoveServiceDomainConfig drops a service's subdomain and takes it out of
+// nginx and the hosts file
+func (sm *Manager) RemoveServiceDomainConfig(serviceUUID string) (*ServiceDomainsResult, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	if err := sm.db.DeleteServiceDomainConfig(serviceUUID); err != nil {
+		return nil, err
+	}
+	return sm.ApplyServiceDomains()
+}
+
+// enabledServiceDomains returns the enabled subdomains of existing services
+// with their full domains and current ports
+func (sm *Manager) enabledServiceDomains(site installer.NginxSite) ([]installer.NginxServiceDomain, error) {
+	configs, err := sm.db.GetServiceDomainConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	domains := []installer.NginxServiceDomain{}
+	for _, config := range configs {
+		if !config.Enabled {
+			continue
+		}
+		service, exists := sm.GetServiceByUUID(config.ServiceID)
+		if !exists {
+			continue
+		}
+		service.Mutex.RLock()
+		domain := installer.NginxServiceDomain{Domain: config.Subdomain + "." + site.Domain, Port: service.Port, ServiceName: service.Name}
+		service.Mutex.RUnlock()
+		domains = append(domains, domain)
+	}
+	return domains, nil
+}
+
+// ApplyServiceDomains points every enabled service domain at 127.0.0.1 in
+// the hosts file and rewrites their nginx servers with the services' current
+// ports. When the site uses HTTPS they share one mkcert certificate naming
+// all of them; without mkcert they are served over plain HTTP.
+func (sm *Manager) ApplyServiceDomains() (*ServiceDomainsResult, error) {
+	site := vertexSite()
+	domains, err := sm.enabledServiceDomains(site)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ServiceDomainsResult{}
+	servers := []installer.NginxServiceDomain{}
+	for _, domain := range domains {
+		if domain.Port == 0 {
+			log.Printf("[WARN] Skipping nginx server for %s: service %s has no port", domain.Domain, domain.ServiceName)
+			continue
+		}
+		servers = append(servers, domain)
+	}
+
+	if site.HTTPS && len(servers) > 0 {
+		names := make([]string, 0, len(servers))
+		for _, server := range servers {
+			names = append(names, server.Domain)
+		}
+		certFile, keyFile, err := installer.ServiceDomainsCertificate(nginxOutputDir(), names)
+		if err != nil {
+			result.Warning = fmt.Sprintf("Serving service domains over HTTP: %v", err)
+			log.Printf("[WARN] %s", result.Warning)
+		} else {
+			result.HTTPS = true
+			for i := range servers {
+				servers[i].CertFile = certFile
+				servers[i].KeyFile = keyFile
+			}
+		}
+	}
+
+	hosts, err := sm.applyHostsEntries()
+	if err != nil {
+		return nil, err
+	}
+	result.Hosts = hosts
+
+	nginx, err := installer.ApplyNginxServiceDomains(nginxOutputDir(), servers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update nginx service domains: %w", err)
+	}
+	if nginx.Reloaded {
+		log.Printf("[INFO] Updated %d service domain(s) in %s and reloaded nginx", len(servers), nginx.ConfigFile)
+	} else {
+		log.Printf("[INFO] Updated %d service domain(s); to finish run: %s", len(servers), nginx.Instructions)
+	}
+	result.Nginx = nginx
+	return result, nil
+}
+
+// applyHostsEntries points every profile hostname and enabled service domain
+// at 127.0.0.1. They share one block of the hosts file, so both are written
+// whenever either changes.
+func (sm *Manager) applyHostsEntries() (*installer.HostsFileResult, error) {
+	hostnames, err := sm.GetProfileHostnames("")
+	if err != nil {
+		return nil, err
+	}
+	domains, err := sm.enabledServiceDomains(vertexSite())
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, hostname := range hostnames {
+		if hostname.ServiceName != "" { // Skip those of deleted services
+			names = append(names, hostname.Hostname)
+		}
+	}
+	for _, domain := range domains {
+		names = append(names, domain.Domain)
+	}
+
+	hosts, err := installer.ApplyHostsEntries(nginxOutputDir(), names)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update hosts file: %w", err)
+	}
+	if hosts.Instructions != "" {
+		log.Printf("[INFO] Local hostnames need a hosts file update; to finish run: %s", hosts.Instructions)
+	}
+	return hosts, nil
+}
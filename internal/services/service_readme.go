@@ -0,0 +1,155 @@
+// Package services - Service READMEs and notes
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// Larger READMEs are cut off before rendering
+	maxReadmeBytes       = 512 * 1024
+	maxServiceNotesBytes = 64 * 1024
+	// How many directories above the service to look for the repository's README
+	readmeSearchDepth = 4
+)
+
+// README file names in order of preference, compared case-insensitively
+var readmeNames = []string{"readme.md", "readme.markdown", "readme", "readme.txt", "readme.rst", "readme.adoc"}
+
+// GetServiceReadmeWithProjectsDir renders the README of a service's
+// repository and returns it with the service's notes. A service without a
+// README gets an empty one.
+func (sm *Manager) GetServiceReadmeWithProjectsDir(serviceUUID, projectsDir string) (*models.ServiceReadme, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	notes, err := sm.GetServiceNotes(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	readme := &models.ServiceReadme{ServiceID: serviceUUID, Notes: *notes}
+
+	service.Mutex.RLock()
+	serviceDir := filepath.Join(projectsDir, service.Dir)
+	service.Mutex.RUnlock()
+
+	path := findServiceReadme(serviceDir, projectsDir)
+	if path == "" {
+		return readme, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open README %s: %w", path, err)
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, maxReadmeBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read README %s: %w", path, err)
+	}
+	if len(content) > maxReadmeBytes {
+		content = content[:maxReadmeBytes]
+		readme.Truncated = true
+	}
+	source := strings.ToValidUTF8(string(content), "�")
+
+	if relative, err := filepath.Rel(serviceDir, path); err == nil {
+		readme.Path = relative
+	} else {
+		readme.Path = path
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		readme.Format = "markdown"
+		readme.HTML = renderMarkdown(source)
+	default:
+		readme.Format = "text"
+		readme.HTML = renderPlainText(source)
+	}
+	return readme, nil
+}
+
+// findServiceReadme looks for a README in the service directory, then in its
+// parents up to the repository root, so modules of a multi-module repository
+// show the repository's README. It stays below the projects directory.
+func findServiceReadme(serviceDir, projectsDir string) string {
+	dir := serviceDir
+	for depth := 0; depth <= readmeSearchDepth; depth++ {
+		if path := readmeInDir(dir); path != "" {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		if projectsDir != "" {
+			relative, err := filepath.Rel(projectsDir, parent)
+			if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+				return ""
+			}
+		}
+		dir = parent
+	}
+	return ""
+}
+
+// readmeInDir returns the preferred README file of a directory, if any
+func readmeInDir(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	files := make(map[string]string)
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			files[strings.ToLower(entry.Name())] = entry.Name()
+		}
+	}
+	for _, name := range readmeNames {
+		if file, exists := files[name]; exists {
+			return filepath.Join(dir, file)
+		}
+	}
+	return ""
+}
+
+// GetServiceNotes returns a service's notes with their rendered HTML; empty
+// when none were saved
+func (sm *Manager) GetServiceNotes(serviceUUID string) (*models.ServiceNotes, error) {
+	notes, err := sm.db.GetServiceNotes(serviceUUID)
+	if err != nil {
+		return nil, err
+	}
+	if notes == nil {
+		notes = &models.ServiceNotes{ServiceID: serviceUUID}
+	}
+	notes.HTML = renderMarkdown(notes.Notes)
+	return notes, nil
+}
+
+// SetServiceNotes replaces a service's notes
+func (sm *Manager) SetServiceNotes(serviceUUID, notes string) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	if len(notes) > maxServiceNotesBytes {
+		return fmt.Errorf("notes are larger than %d KB", maxServiceNotesBytes/1024)
+	}
+	if !utf8.ValidString(notes) {
+		return fmt.Errorf("notes must be UTF-8 text")
+	}
+	return sm.db.SaveServiceNotes(serviceUUID, notes)
+}
@@ -0,0 +1,199 @@
+// Package services - Lock-free read replica of service state for polling
+package services
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// serviceReplicaSyncInterval bounds how stale the replica gets when a change
+// was not broadcast
+const serviceReplicaSyncInterval = 5 * time.Second
+
+// serviceReplica is the last published state of one service. Both pointers
+// are only ever swapped, never written through, so readers need no lock.
+type serviceReplica struct {
+	service atomic.Pointer[models.Service] // Without logs, command or locks
+	logs    atomic.Pointer[[]models.LogEntry]
+}
+
+// serviceReplicaSet maps service UUIDs to replicas. Adding or removing a
+// service replaces the whole map.
+type serviceReplicaSet struct {
+	pointer atomic.Pointer[map[string]*serviceReplica]
+	mutex   sync.Mutex // Serializes replacing the map
+}
+
+// load returns the current map; nil before the first publish
+func (rs *serviceReplicaSet) load() map[string]*serviceReplica {
+	if replicas := rs.pointer.Load(); replicas != nil {
+		return *replicas
+	}
+	return nil
+}
+
+// replicaFor returns the replica of a service, adding one if needed
+func (rs *serviceReplicaSet) replicaFor(serviceUUID string) *serviceReplica {
+	if replica, exists := rs.load()[serviceUUID]; exists {
+		return replica
+	}
+
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	current := rs.load()
+	if replica, exists := current[serviceUUID]; exists {
+		return replica
+	}
+	next := make(map[string]*serviceReplica, len(current)+1)
+	maps.Copy(next, current)
+	replica := &serviceReplica{}
+	next[serviceUUID] = replica
+	rs.pointer.Store(&next)
+	return replica
+}
+
+// retain drops the replicas of services that no longer exist
+func (rs *serviceReplicaSet) retain(keep func(serviceUUID string) bool) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	current := rs.load()
+	next := make(map[string]*serviceReplica, len(current))
+	for serviceUUID, replica := range current {
+		if keep(serviceUUID) {
+			next[serviceUUID] = replica
+		}
+	}
+	if len(next) != len(current) {
+		rs.pointer.Store(&next)
+	}
+}
+
+// publishService copies a service into the replica. It reads the service
+// without locking it, so the caller holds its lock or owns it, as for
+// broadcastUpdate.
+func (sm *Manager) publishService(service *models.Service) {
+	replica := sm.replicas.replicaFor(service.ID)
+
+	copied := service.Clone()
+	copied.Cmd = nil
+	copied.Stdin = nil
+	copied.Logs = nil
+	copied.EnvVars = maps.Clone(service.EnvVars)
+	copied.Tags = maps.Clone(service.Tags)
+	copied.Dependencies = append([]models.ServiceDependency(nil), service.Dependencies...)
+	copied.DependentOn = append([]string(nil), service.DependentOn...)
+	copied.Metrics.ResponseTimes = append([]models.ResponseTime(nil), service.Metrics.ResponseTimes...)
+	replica.service.Store(copied)
+	publishLogs(replica, service.Logs)
+}
+
+// publishServiceLogs makes a service's in-memory logs visible to readers; the
+// caller holds the service's lock
+func (sm *Manager) publishServiceLogs(service *models.Service) {
+	publishLogs(sm.replicas.replicaFor(service.ID), service.Logs)
+}
+
+// publishLogs shares a log slice without copying it: entries are only
+// appended past its end or dropped by reslicing, never overwritten
+func publishLogs(replica *serviceReplica, logs []models.LogEntry) {
+	if logs == nil {
+		logs = []models.LogEntry{}
+	}
+	replica.logs.Store(&logs)
+}
+
+// unpublishService removes a deleted service from the replica
+func (sm *Manager) unpublishService(serviceUUID string) {
+	sm.replicas.retain(func(id string) bool { return id != serviceUUID })
+}
+
+// syncServiceReplica republishes every service, catching changes that were
+// not broadcast and services that were added or removed without it
+func (sm *Manager) syncServiceReplica() {
+	services := sm.snapshotServices()
+	present := make(map[string]bool, len(services))
+	for _, service := range services {
+		present[service.ID] = true
+		service.Mutex.RLock()
+		sm.publishService(service)
+		service.Mutex.RUnlock()
+	}
+	sm.replicas.retain(func(id string) bool { return present[id] })
+}
+
+// startServiceReplicaSync keeps the replica in step with the services
+func (sm *Manager) startServiceReplicaSync(ctx context.Context) {
+	ticker := time.NewTicker(serviceReplicaSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sm.syncServiceReplica()
+		}
+	}
+}
+
+// readReplica returns a copy of a published service with its logs, or nil
+func readReplica(replica *serviceReplica) *models.Service {
+	published := replica.service.Load()
+	if published == nil {
+		return nil
+	}
+	service := published.Clone()
+	if logs := replica.logs.Load(); logs != nil {
+		service.Logs = *logs
+	}
+	return service
+}
+
+// GetServiceSnapshots returns the published state of every service sorted by
+// order, without taking the manager's or the services' locks. It trails
+// changes by at most serviceReplicaSyncInterval.
+func (sm *Manager) GetServiceSnapshots() []*models.Service {
+	replicas := sm.replicas.load()
+	services := make([]*models.Service, 0, len(replicas))
+	for _, replica := range replicas {
+		if service := readReplica(replica); service != nil {
+			services = append(services, service)
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Order < services[j].Order
+	})
+	return services
+}
+
+// GetServiceSnapshot returns the published state of a service, as
+// GetServiceSnapshots does
+func (sm *Manager) GetServiceSnapshot(serviceUUID string) (*models.Service, bool) {
+	replica, exists := sm.replicas.load()[serviceUUID]
+	if !exists {
+		return nil, false
+	}
+	service := readReplica(replica)
+	return service, service != nil
+}
+
+// snapshotServices returns the services without holding the manager's mutex
+func (sm *Manager) snapshotServices() []*models.Service {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	services := make([]*models.Service, 0, len(sm.services))
+	for _, service := range sm.services {
+		services = append(services, service)
+	}
+	return services
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+func TestGetServiceSnapshot_NotPublished(t *testing.T) {
+	sm := newTestManager(t)
+
+	if _, exists := sm.GetServiceSnapshot("svc-1"); exists {
+		t.Error("Expected no snapshot before the service is published")
+	}
+	if services := sm.GetServiceSnapshots(); len(services) != 0 {
+		t.Errorf("Expected no snapshots, got %d", len(services))
+	}
+}
+
+func TestGetServiceSnapshot_ReturnsPublishedState(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{
+		ID:             "svc-1",
+		Name:           "api",
+		Status:         "running",
+		Port:           8080,
+		ReloadOnSIGHUP: true,
+		EnvVars:        map[string]models.EnvVar{"PORT": {Name: "PORT", Value: "8080"}},
+		Tags:           map[string]string{"team": "core"},
+		DependentOn:    []string{"db"},
+		Logs:           []models.LogEntry{{Message: "started"}},
+	}
+	service.Metrics.ResponseTimes = []models.ResponseTime{{Duration: 5}}
+	sm.publishService(service)
+
+	snapshot, exists := sm.GetServiceSnapshot("svc-1")
+	if !exists {
+		t.Fatal("Expected the published service to have a snapshot")
+	}
+	if snapshot.Name != "api" || snapshot.Status != "running" || snapshot.Port != 8080 || !snapshot.ReloadOnSIGHUP {
+		t.Errorf("Unexpected snapshot: got %+v", snapshot)
+	}
+	if snapshot.EnvVars["PORT"].Value != "8080" || snapshot.Tags["team"] != "core" {
+		t.Errorf("Expected env vars and tags in the snapshot, got %v and %v", snapshot.EnvVars, snapshot.Tags)
+	}
+	if len(snapshot.Logs) != 1 || snapshot.Logs[0].Message != "started" {
+		t.Errorf("Expected the published logs, got %+v", snapshot.Logs)
+	}
+	if snapshot.Cmd != nil || snapshot.Stdin != nil {
+		t.Error("Expected no process handles in the snapshot")
+	}
+}
+
+func TestGetServiceSnapshot_IsolatedFromLaterChanges(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{
+		ID:          "svc-1",
+		Name:        "api",
+		Status:      "running",
+		Tags:        map[string]string{"team": "core"},
+		DependentOn: []string{"db"},
+	}
+	service.Metrics.ResponseTimes = []models.ResponseTime{{Duration: 5}}
+	sm.publishService(service)
+
+	// Create changes to the live service that were not published
+	service.Status = "stopped"
+	service.Tags["team"] = "edge"
+	service.DependentOn[0] = "cache"
+	service.Metrics.ResponseTimes[0].Duration = 9
+
+	snapshot, _ := sm.GetServiceSnapshot("svc-1")
+	if snapshot.Status != "running" {
+		t.Errorf("Expected the published status, got %q want %q", snapshot.Status, "running")
+	}
+	if snapshot.Tags["team"] != "core" {
+		t.Errorf("Expected the published tags, got %v", snapshot.Tags)
+	}
+	if snapshot.DependentOn[0] != "db" {
+		t.Errorf("Expected the published dependents, got %v", snapshot.DependentOn)
+	}
+	if snapshot.Metrics.ResponseTimes[0].Duration != 5 {
+		t.Errorf("Expected the published response times, got %v", snapshot.Metrics.ResponseTimes)
+	}
+
+	sm.publishService(service)
+	snapshot, _ = sm.GetServiceSnapshot("svc-1")
+	if snapshot.Status != "stopped" {
+		t.Errorf("Expected the republished status, got %q want %q", snapshot.Status, "stopped")
+	}
+}
+
+func TestGetServiceSnapshots_SortedByOrder(t *testing.T) {
+	sm := newTestManager(t)
+	sm.publishService(&models.Service{ID: "svc-3", Name: "gateway", Order: 3})
+	sm.publishService(&models.Service{ID: "svc-1", Name: "db", Order: 1})
+	sm.publishService(&models.Service{ID: "svc-2", Name: "api", Order: 2})
+
+	services := sm.GetServiceSnapshots()
+	if len(services) != 3 {
+		t.Fatalf("Expected 3 snapshots, got %d", len(services))
+	}
+	for i, want := range []string{"db", "api", "gateway"} {
+		if services[i].Name != want {
+			t.Errorf("Snapshot %d: got %q want %q", i, services[i].Name, want)
+		}
+	}
+}
+
+func TestPublishServiceLogs(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api"}
+	sm.publishService(service)
+
+	snapshot, _ := sm.GetServiceSnapshot("svc-1")
+	if snapshot.Logs == nil || len(snapshot.Logs) != 0 {
+		t.Errorf("Expected empty logs rather than nil, got %#v", snapshot.Logs)
+	}
+
+	service.Logs = append(service.Logs, models.LogEntry{Message: "ready"})
+	sm.publishServiceLogs(service)
+
+	snapshot, _ = sm.GetServiceSnapshot("svc-1")
+	if len(snapshot.Logs) != 1 || snapshot.Logs[0].Message != "ready" {
+		t.Errorf("Expected the new log entry, got %+v", snapshot.Logs)
+	}
+}
+
+func TestUnpublishService(t *testing.T) {
+	sm := newTestManager(t)
+	sm.publishService(&models.Service{ID: "svc-1", Name: "api"})
+	sm.publishService(&models.Service{ID: "svc-2", Name: "worker"})
+
+	sm.unpublishService("svc-1")
+
+	if _, exists := sm.GetServiceSnapshot("svc-1"); exists {
+		t.Error("Expected the unpublished service to have no snapshot")
+	}
+	if _, exists := sm.GetServiceSnapshot("svc-2"); !exists {
+		t.Error("Expected other services to keep their snapshot")
+	}
+}
+
+func TestSyncServiceReplica(t *testing.T) {
+	sm := newTestManager(t)
+	kept := &models.Service{ID: "svc-1", Name: "api", Status: "running"}
+	sm.services[kept.ID] = kept
+	sm.publishService(kept)
+	sm.publishService(&models.Service{ID: "svc-2", Name: "removed"})
+
+	// Create a change that was not broadcast and a service that was not published
+	kept.Status = "stopped"
+	added := &models.Service{ID: "svc-3", Name: "added"}
+	sm.services[added.ID] = added
+
+	sm.syncServiceReplica()
+
+	if snapshot, _ := sm.GetServiceSnapshot("svc-1"); snapshot == nil || snapshot.Status != "stopped" {
+		t.Errorf("Expected the sync to republish the change, got %+v", snapshot)
+	}
+	if _, exists := sm.GetServiceSnapshot("svc-2"); exists {
+		t.Error("Expected the sync to drop a removed service")
+	}
+	if _, exists := sm.GetServiceSnapshot("svc-3"); !exists {
+		t.Error("Expected the sync to publish an added service")
+	}
+}
+
+func TestGetServiceSnapshots_ConcurrentPublish(t *testing.T) {
+	sm := newTestManager(t)
+	service := &models.Service{ID: "svc-1", Name: "api", Tags: map[string]string{}}
+	sm.publishService(service)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			service.Mutex.Lock()
+			service.Port = i
+			service.Tags["n"] = "x"
+			service.Logs = append(service.Logs, models.LogEntry{Timestamp: time.Now().String()})
+			sm.publishService(service)
+			service.Mutex.Unlock()
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		for _, snapshot := range sm.GetServiceSnapshots() {
+			_ = snapshot.Tags["n"]
+			_ = len(snapshot.Logs)
+		}
+	}
+	wg.Wait()
+}
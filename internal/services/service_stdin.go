@@ -0,0 +1,156 @@
+// Package services - Input sent to the stdin of interactive services
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// Longest input accepted at once, well above any typed command
+const maxServiceInputBytes = 64 * 1024
+
+const (
+	// Inputs that may wait for a process to read them before more are refused
+	serviceInputQueueSize = 16
+	// How long a caller waits for its input to reach the process
+	serviceInputTimeout = 5 * time.Second
+)
+
+var (
+	errServiceInputQueueFull = errors.New("input queue is full; the process is not reading its input")
+	errServiceInputClosed    = errors.New("stdin is closed")
+)
+
+// serviceStdin writes to the stdin pipe of a process from its own
+// goroutine, so a process that stops reading its input fills a bounded
+// queue instead of blocking every caller on a full pipe
+type serviceStdin struct {
+	pipe      io.WriteCloser
+	queue     chan serviceInput
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type serviceInput struct {
+	data   []byte
+	result chan error
+}
+
+// newServiceStdin starts the writer for a process's stdin pipe. It runs
+// until Close, which the exit handler calls once the process is gone
+func newServiceStdin(pipe io.WriteCloser) *serviceStdin {
+	s := &serviceStdin{
+		pipe:  pipe,
+		queue: make(chan serviceInput, serviceInputQueueSize),
+		done:  make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *serviceStdin) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case input := <-s.queue:
+			_, err := s.pipe.Write(input.data)
+			input.result <- err
+		}
+	}
+}
+
+// Write queues p and waits up to serviceInputTimeout for it to reach the
+// pipe. Input that times out stays queued and is written if the process
+// starts reading again
+func (s *serviceStdin) Write(p []byte) (int, error) {
+	input := serviceInput{data: append([]byte(nil), p...), result: make(chan error, 1)}
+	select {
+	case <-s.done:
+		return 0, errServiceInputClosed
+	default:
+	}
+	select {
+	case s.queue <- input:
+	default:
+		return 0, errServiceInputQueueFull
+	}
+
+	timer := time.NewTimer(serviceInputTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-input.result:
+		if err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	case <-timer.C:
+		return 0, fmt.Errorf("the process has not read its input within %s; it stays queued", serviceInputTimeout)
+	case <-s.done:
+		return 0, errServiceInputClosed
+	}
+}
+
+// Close stops the writer and closes the pipe, which also unblocks a write
+// stuck on a full pipe
+func (s *serviceStdin) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.pipe.Close()
+	})
+	return err
+}
+
+// SendServiceInput writes input to the stdin of a running interactive
+// service, ending it with a newline if it has none. The input is shown on
+// live consoles but never stored in the service's logs, since what is typed
+// into a process is often a password
+func (sm *Manager) SendServiceInput(serviceUUID, input string) error {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	if len(input) > maxServiceInputBytes {
+		return fmt.Errorf("input is longer than %d bytes", maxServiceInputBytes)
+	}
+
+	service.Mutex.RLock()
+	name := service.Name
+	interactive := service.Interactive
+	status := service.Status
+	stdin := service.Stdin
+	service.Mutex.RUnlock()
+
+	switch {
+	case !interactive:
+		return fmt.Errorf("service %s is not interactive; turn on interactive mode and restart it", name)
+	case status == StatusPaused:
+		// A stopped process cannot read, so the input would only pile up
+		return fmt.Errorf("service %s is paused; resume it before sending input", name)
+	case status != "running":
+		return fmt.Errorf("service %s is not running", name)
+	case stdin == nil:
+		return fmt.Errorf("service %s was started before interactive mode was turned on; restart it", name)
+	}
+
+	if !strings.HasSuffix(input, "\n") {
+		input += "\n"
+	}
+	if _, err := stdin.Write([]byte(input)); err != nil {
+		return fmt.Errorf("failed to write to stdin of %s: %w", name, err)
+	}
+
+	sm.broadcastLogEntry(serviceUUID, models.LogEntry{
+		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Level:     "INFO",
+		Message:   "> " + strings.TrimSuffix(input, "\n"),
+	})
+	return nil
+}
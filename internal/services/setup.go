@@ -0,0 +1,276 @@
+// Package services - First-run setup wizard
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const setupSyncType = "setup_wizard"
+
+// ErrSetupCompleted is returned when the setup wizard is run on an initialized installation
+var ErrSetupCompleted = errors.New("setup has already been completed")
+
+// SetupStep is one stage of the first-run wizard
+type SetupStep struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+// SetupStatus tells the UI whether to show the setup wizard and which steps
+// remain. It is served without a login, so it carries no paths.
+type SetupStatus struct {
+	Required         bool        `json:"required"`
+	Steps            []SetupStep `json:"steps"`
+	JavaAvailable    bool        `json:"javaAvailable"`
+	ServiceCount     int         `json:"serviceCount"`
+	ExistingProfiles int         `json:"existingProfiles"`
+}
+
+// SetupRequest carries everything the wizard collects before completing setup
+type SetupRequest struct {
+	Admin              models.UserRegistration `json:"admin"`
+	ProjectsDir        string                  `json:"projectsDir"`
+	JavaHomeOverride   string                  `json:"javaHomeOverride"`
+	DiscoverServices   bool                    `json:"discoverServices"`
+	ProfileName        string                  `json:"profileName"`
+	ProfileDescription string                  `json:"profileDescription"`
+	EnvVars            map[string]string       `json:"envVars"` // Global environment variables
+}
+
+// SetupResult is returned once setup completes; it logs the new admin in
+type SetupResult struct {
+	Auth               *models.AuthResponse   `json:"auth"`
+	Profile            *models.ServiceProfile `json:"profile"`
+	DiscoveredServices []string               `json:"discoveredServices"`
+	Warnings           []string               `json:"warnings,omitempty"`
+}
+
+// SetupService walks a fresh installation through creating the admin user,
+// choosing the projects directory, discovering services and creating the first profile
+type SetupService struct {
+	manager   *Manager
+	auth      *AuthService
+	profiles  *ProfileService
+	discovery *AutoDiscoveryService
+	mutex     sync.Mutex
+}
+
+func NewSetupService(manager *Manager, auth *AuthService, profiles *ProfileService, discovery *AutoDiscoveryService) *SetupService {
+	return &SetupService{
+		manager:   manager,
+		auth:      auth,
+		profiles:  profiles,
+		discovery: discovery,
+	}
+}
+
+// IsCompleted reports whether the installation is already initialized. Installs
+// that predate the wizard count as completed once they have a user.
+func (ss *SetupService) IsCompleted() (bool, error) {
+	completed, err := ss.manager.db.IsSyncCompleted(setupSyncType)
+	if err != nil || completed {
+		return completed, err
+	}
+	return ss.auth.HasUsers()
+}
+
+// Status returns the progress of the setup wizard
+func (ss *SetupService) Status() (*SetupStatus, error) {
+	completed, err := ss.IsCompleted()
+	if err != nil {
+		return nil, err
+	}
+	hasUsers, err := ss.auth.HasUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	var profileCount int
+	if err := ss.manager.db.QueryRow("SELECT COUNT(*) FROM service_profiles").Scan(&profileCount); err != nil {
+		return nil, fmt.Errorf("failed to count profiles: %w", err)
+	}
+
+	projectsDir := ss.manager.GetConfig().ProjectsDir
+	projectsDirSet := projectsDir != ""
+	if projectsDirSet {
+		if info, err := os.Stat(projectsDir); err != nil || !info.IsDir() {
+			projectsDirSet = false
+		}
+	}
+
+	serviceCount := len(ss.manager.GetServices())
+
+	return &SetupStatus{
+		Required: !completed,
+		Steps: []SetupStep{
+			{ID: "admin", Title: "Create the admin user", Done: hasUsers},
+			{ID: "projects-dir", Title: "Choose the projects directory", Done: projectsDirSet},
+			{ID: "discovery", Title: "Discover services", Done: serviceCount > 0},
+			{ID: "profile", Title: "Create the first profile", Done: profileCount > 0},
+		},
+		JavaAvailable:    DetectJavaEnvironment().Available,
+		ServiceCount:     serviceCount,
+		ExistingProfiles: profileCount,
+	}, nil
+}
+
+// Complete runs all wizard steps in one go. The whole request is validated
+// first and the admin user is created last, and removed again if the profile
+// cannot be created, so a failed setup can be retried. Discovery problems are
+// reported as warnings rather than failing setup.
+func (ss *SetupService) Complete(req *SetupRequest) (*SetupResult, error) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	completed, err := ss.IsCompleted()
+	if err != nil {
+		return nil, err
+	}
+	if completed {
+		return nil, ErrSetupCompleted
+	}
+
+	if err := req.Admin.Validate(); err != nil {
+		return nil, err
+	}
+
+	projectsDir := strings.TrimSpace(req.ProjectsDir)
+	if projectsDir == "" {
+		return nil, fmt.Errorf("projects directory is required")
+	}
+	projectsDir, err = filepath.Abs(projectsDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid projects directory: %w", err)
+	}
+	if info, err := os.Stat(projectsDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("projects directory does not exist: %s", projectsDir)
+	}
+
+	profileName := strings.TrimSpace(req.ProfileName)
+	if profileName == "" {
+		profileName = "Default"
+	}
+
+	for name := range req.EnvVars {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return nil, fmt.Errorf("invalid environment variable name '%s'", name)
+		}
+	}
+
+	result := &SetupResult{DiscoveredServices: []string{}}
+
+	// Step 1: projects directory and global environment
+	if _, err := ss.manager.UpdateGlobalConfig(projectsDir, req.JavaHomeOverride); err != nil {
+		return nil, fmt.Errorf("failed to save projects directory: %w", err)
+	}
+	for name, value := range req.EnvVars {
+		if err := ss.manager.db.SetGlobalEnvVar(name, value); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to save environment variable %s: %v", name, err))
+			continue
+		}
+		os.Setenv(name, value)
+	}
+
+	// Step 2: auto-discovery
+	var serviceUUIDs []string
+	if req.DiscoverServices {
+		discovered, err := ss.discovery.ScanDirectory(projectsDir)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Service discovery failed: %v", err))
+		}
+		for _, candidate := range discovered {
+			if !candidate.IsValid {
+				continue
+			}
+			if candidate.Exists {
+				if existing, found := ss.manager.GetServiceByName(candidate.Name); found {
+					serviceUUIDs = append(serviceUUIDs, existing.ID)
+				}
+				continue
+			}
+			service, err := ss.discovery.CreateServiceFromDiscovered(candidate)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to import %s: %v", candidate.Name, err))
+				continue
+			}
+			serviceUUIDs = append(serviceUUIDs, service.ID)
+			result.DiscoveredServices = append(result.DiscoveredServices, service.Name)
+		}
+		log.Printf("[INFO] Setup: imported %d discovered services from %s", len(result.DiscoveredServices), projectsDir)
+	}
+
+	// Step 3: admin user, which ends the wizard's public access
+	user, err := ss.auth.RegisterAdmin(&req.Admin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin user: %w", err)
+	}
+	log.Printf("[INFO] Setup: created admin user %s", user.Username)
+
+	// Step 4: first profile, active and default
+	if serviceUUIDs == nil {
+		serviceUUIDs = []string{}
+	}
+	profile, err := ss.profiles.CreateServiceProfile(user.ID, &models.CreateProfileRequest{
+		Name:             profileName,
+		Description:      req.ProfileDescription,
+		Services:         serviceUUIDs,
+		ProjectsDir:      projectsDir,
+		JavaHomeOverride: req.JavaHomeOverride,
+		IsDefault:        true,
+		IsActive:         true,
+	})
+	if err != nil {
+		ss.discardAdmin(user)
+		return nil, fmt.Errorf("failed to create profile: %w", err)
+	}
+	result.Profile = profile
+
+	if err := ss.manager.db.MarkSyncCompleted(setupSyncType); err != nil {
+		ss.profiles.DeleteServiceProfile(profile.ID, user.ID)
+		ss.discardAdmin(user)
+		return nil, err
+	}
+
+	result.Auth, err = ss.auth.Login(&models.UserLogin{Email: req.Admin.Email, Password: req.Admin.Password})
+	if err != nil {
+		return nil, fmt.Errorf("setup completed but login failed: %w", err)
+	}
+
+	log.Printf("[INFO] Setup completed: profile %s with %d services", profile.Name, len(serviceUUIDs))
+	return result, nil
+}
+
+// discardAdmin removes the admin user of a setup that failed after creating
+// it, so the wizard stays open
+func (ss *SetupService) discardAdmin(user *models.User) {
+	if err := ss.auth.deleteUser(user.ID); err != nil {
+		log.Printf("[ERROR] Setup failed and its admin user %s could not be removed: %v", user.Username, err)
+		return
+	}
+	log.Printf("[WARN] Setup failed; removed its admin user %s", user.Username)
+}
+
+// LoadGlobalEnvironment exports the global environment variables stored in
+// the database into the Vertex process so services inherit them
+func LoadGlobalEnvironment(db *database.Database) (int, error) {
+	envVars, err := db.GetGlobalEnvVars()
+	if err != nil {
+		return 0, err
+	}
+	for name, value := range envVars {
+		if err := os.Setenv(name, value); err != nil {
+			log.Printf("[WARN] Failed to set environment variable %s: %v", name, err)
+		}
+	}
+	return len(envVars), nil
+}
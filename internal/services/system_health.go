@@ -0,0 +1,276 @@
+// Package services - Health of the Vertex process itself
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	daemonErrorHistory   = 20   // Errors kept for the health report
+	daemonErrorMaxLength = 1024 // Longer log lines are truncated
+
+	// A subsystem is reported degraded past these limits
+	degradedDatabaseLatency  = 500 * time.Millisecond
+	degradedQueueFill        = 0.8 // Fraction of a bounded queue in use
+	degradedBroadcastBacklog = 1000
+	degradedOperationAge     = 15 * time.Minute
+	degradedGoroutines       = 10000
+	degradedHeapBytes        = 1 << 30
+	degradedErrorWindow      = 5 * time.Minute // How long a failed log write keeps the database degraded
+)
+
+// daemonStartedAt is when the Vertex process started
+var daemonStartedAt = time.Now()
+
+// daemonErrorLog passes log output through, keeping the lines logged as
+// errors so they can be read back without the log file
+type daemonErrorLog struct {
+	mutex  sync.Mutex
+	out    io.Writer
+	errors []models.DaemonError // Oldest first
+	count  int64
+}
+
+var daemonErrors = &daemonErrorLog{out: os.Stderr}
+
+// RecordDaemonErrors returns a writer for the standard logger that writes to
+// out and keeps the recent [ERROR] and [FATAL] lines for GetSystemHealth
+func RecordDaemonErrors(out io.Writer) io.Writer {
+	daemonErrors.mutex.Lock()
+	daemonErrors.out = out
+	daemonErrors.mutex.Unlock()
+	return daemonErrors
+}
+
+// Write receives one log entry per call from the standard logger
+func (d *daemonErrorLog) Write(p []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if bytes.Contains(p, []byte("[ERROR]")) || bytes.Contains(p, []byte("[FATAL]")) {
+		message := strings.TrimSpace(string(p))
+		if len(message) > daemonErrorMaxLength {
+			message = message[:daemonErrorMaxLength] + "…"
+		}
+		d.errors = append(d.errors, models.DaemonError{Time: time.Now(), Message: message})
+		if len(d.errors) > daemonErrorHistory {
+			d.errors = d.errors[len(d.errors)-daemonErrorHistory:]
+		}
+		d.count++
+	}
+	return d.out.Write(p)
+}
+
+// recent returns the kept errors, most recent first, and the total logged
+func (d *daemonErrorLog) recent() ([]models.DaemonError, int64) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	errors := make([]models.DaemonError, 0, len(d.errors))
+	for i := len(d.errors) - 1; i >= 0; i-- {
+		errors = append(errors, d.errors[i])
+	}
+	return errors, d.count
+}
+
+// GetSystemHealth reports the health of Vertex itself: its goroutines and
+// memory, how quickly the database answers, its WebSocket clients, the depth
+// of its internal queues, its backups and its recent errors. Subsystems past their limits
+// are listed as degraded, and so is the whole report.
+func (sm *Manager) GetSystemHealth(ctx context.Context) models.SystemHealth {
+	health := models.SystemHealth{
+		StartedAt:     daemonStartedAt,
+		UptimeSeconds: int64(time.Since(daemonStartedAt).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		Memory:        daemonMemory(),
+	}
+	health.LastErrors, health.ErrorCount = daemonErrors.recent()
+
+	sm.clientsMutex.RLock()
+	health.WebSocketClients = len(sm.clients)
+	sm.clientsMutex.RUnlock()
+
+	sm.broadcaster.mutex.Lock()
+	health.Queues.Broadcast = len(sm.broadcaster.queue)
+	health.Subscribers = len(sm.broadcaster.subscribers)
+	sm.broadcaster.mutex.Unlock()
+
+	health.Subsystems = []models.SubsystemHealth{
+		sm.databaseHealth(ctx, &health),
+		subsystemHealth("process", processDegradation(health)),
+		subsystemHealth("websocket", broadcastDegradation(health.Queues.Broadcast)),
+		sm.serviceOperationsHealth(&health.Queues),
+		healthChecksHealth(&health.Queues),
+		logSinksHealth(&health.Queues),
+		sm.backupsHealth(&health.Backups),
+	}
+
+	health.Status = models.SystemHealthy
+	for _, subsystem := range health.Subsystems {
+		if subsystem.Status == models.SystemDegraded {
+			health.Status = models.SystemDegraded
+		}
+	}
+	return health
+}
+
+// subsystemHealth is healthy without a reason and degraded with one
+func subsystemHealth(name, reason string) models.SubsystemHealth {
+	if reason == "" {
+		return models.SubsystemHealth{Name: name, Status: models.SystemHealthy}
+	}
+	return models.SubsystemHealth{Name: name, Status: models.SystemDegraded, Reason: reason}
+}
+
+// daemonMemory reads the Go runtime's memory statistics and, where the
+// platform reports them, the process's resident memory and open files
+func daemonMemory() models.DaemonMemory {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	memory := models.DaemonMemory{
+		HeapAllocBytes: stats.HeapAlloc,
+		HeapInuseBytes: stats.HeapInuse,
+		SysBytes:       stats.Sys,
+		NumGC:          stats.NumGC,
+	}
+	if stats.NumGC > 0 {
+		memory.LastGCPauseMs = float64(stats.PauseNs[(stats.NumGC+255)%256]) / 1e6
+	}
+
+	if proc, err := process.NewProcess(int32(os.Getpid())); err == nil {
+		if info, err := proc.MemoryInfo(); err == nil {
+			memory.RSSBytes = info.RSS
+		}
+		if files, err := proc.NumFDs(); err == nil {
+			memory.OpenFiles = files
+		}
+	}
+	return memory
+}
+
+// processDegradation flags a goroutine leak or runaway heap
+func processDegradation(health models.SystemHealth) string {
+	var reasons []string
+	if health.Goroutines > degradedGoroutines {
+		reasons = append(reasons, fmt.Sprintf("%d goroutines are running", health.Goroutines))
+	}
+	if health.Memory.HeapAllocBytes > degradedHeapBytes {
+		reasons = append(reasons, fmt.Sprintf("the heap holds %d MB", health.Memory.HeapAllocBytes>>20))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// broadcastDegradation flags WebSocket messages piling up between batches
+func broadcastDegradation(backlog int) string {
+	if backlog > degradedBroadcastBacklog {
+		return fmt.Sprintf("%d WebSocket messages are waiting to be sent", backlog)
+	}
+	return ""
+}
+
+// databaseHealth times a query and checks the log write queue
+func (sm *Manager) databaseHealth(ctx context.Context, health *models.SystemHealth) models.SubsystemHealth {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var reasons []string
+	latency, err := sm.db.Probe(ctx)
+	health.Database.LatencyMs = float64(latency.Microseconds()) / 1000
+	if err != nil {
+		health.Database.Error = err.Error()
+		reasons = append(reasons, err.Error())
+	} else if latency > degradedDatabaseLatency {
+		reasons = append(reasons, fmt.Sprintf("a trivial query took %s", latency.Round(time.Millisecond)))
+	}
+
+	logQueue := sm.db.LogQueueStatus()
+	health.Queues.LogWrites = logQueue.Pending
+	health.Queues.LogWritesCapacity = logQueue.Capacity
+	if logQueue.Capacity > 0 && float64(logQueue.Pending) >= degradedQueueFill*float64(logQueue.Capacity) {
+		reasons = append(reasons, fmt.Sprintf("the log write queue is %d/%d full", logQueue.Pending, logQueue.Capacity))
+	}
+	if logQueue.LastErrorAt != nil && time.Since(*logQueue.LastErrorAt) < degradedErrorWindow {
+		reasons = append(reasons, "writing logs failed: "+logQueue.LastError)
+	}
+	return subsystemHealth("database", strings.Join(reasons, "; "))
+}
+
+// serviceOperationsHealth counts the lifecycle operations and flags one that
+// has been running for too long
+func (sm *Manager) serviceOperationsHealth(queues *models.DaemonQueues) models.SubsystemHealth {
+	sm.actorsMutex.Lock()
+	actors := make([]*serviceActor, 0, len(sm.actors))
+	for _, actor := range sm.actors {
+		actors = append(actors, actor)
+	}
+	sm.actorsMutex.Unlock()
+
+	var oldest *models.ServiceOperation
+	for _, actor := range actors {
+		for _, operation := range actor.operations() {
+			queues.ServiceOperations++
+			if operation.StartedAt != nil && (oldest == nil || operation.StartedAt.Before(*oldest.StartedAt)) {
+				oldest = &operation
+			}
+		}
+	}
+
+	reason := ""
+	if oldest != nil && time.Since(*oldest.StartedAt) > degradedOperationAge {
+		reason = fmt.Sprintf("%s of service %s has been running for %s", oldest.Name, oldest.ServiceName,
+			time.Since(*oldest.StartedAt).Round(time.Second))
+	}
+	return subsystemHealth("serviceOperations", reason)
+}
+
+// healthChecksHealth counts the health checks in flight and flags the
+// scheduler running out of slots
+func healthChecksHealth(queues *models.DaemonQueues) models.SubsystemHealth {
+	healthChecksInFlightMutex.Lock()
+	queues.HealthChecks = len(healthChecksInFlight)
+	healthChecksInFlightMutex.Unlock()
+
+	reason := ""
+	if len(healthCheckSlots) >= maxConcurrentHealthChecks {
+		reason = fmt.Sprintf("all %d health check slots are busy", maxConcurrentHealthChecks)
+	}
+	return subsystemHealth("healthChecks", reason)
+}
+
+// logSinksHealth counts the records waiting to be shipped and flags sinks
+// whose buffer is nearly full
+func logSinksHealth(queues *models.DaemonQueues) models.SubsystemHealth {
+	logShippersMutex.RLock()
+	shippers := make([]*logShipper, 0, len(logShippers))
+	for _, shipper := range logShippers {
+		shippers = append(shippers, shipper)
+	}
+	logShippersMutex.RUnlock()
+
+	var reasons []string
+	for _, shipper := range shippers {
+		status := shipper.snapshot()
+		queues.LogSinks += status.Queued
+		capacity := cap(shipper.queue)
+		if capacity > 0 && float64(status.Queued) >= degradedQueueFill*float64(capacity) {
+			reason := fmt.Sprintf("the %s sink of profile %s is %d/%d full", status.Type, status.ProfileID, status.Queued, capacity)
+			if status.LastError != "" {
+				reason += ": " + status.LastError
+			}
+			reasons = append(reasons, reason)
+		}
+	}
+	return subsystemHealth("logSinks", strings.Join(reasons, "; "))
+}
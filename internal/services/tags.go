@@ -0,0 +1,202 @@
+// Package services - Service tags and list filtering
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const maxTagLength = 64
+
+// ServiceQuery describes the filters and ordering applied to a service listing.
+// Zero values mean "no filter".
+type ServiceQuery struct {
+	Tags         map[string]string // key -> value; an empty value matches any value of the key
+	Statuses     []string
+	BuildSystems []string
+	MinPort      int
+	MaxPort      int
+	Archived     string // "" hides archived services, ArchivedInclude or ArchivedOnly
+	SortBy       string // "order" (default), "name", "status", "port", "buildSystem" or "tag:<key>"
+	Descending   bool
+}
+
+// loadServiceTags attaches persisted tags to the loaded services
+func (sm *Manager) loadServiceTags() error {
+	tags, err := sm.db.GetAllServiceTags()
+	if err != nil {
+		return err
+	}
+
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	for id, service := range sm.services {
+		service.Mutex.Lock()
+		service.Tags = tags[id]
+		if service.Tags == nil {
+			service.Tags = make(map[string]string)
+		}
+		service.Mutex.Unlock()
+	}
+
+	return nil
+}
+
+// UpdateServiceTags validates and replaces the tags of a service
+func (sm *Manager) UpdateServiceTags(serviceUUID string, tags map[string]string) error {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	normalized := make(map[string]string, len(tags))
+	for key, value := range tags {
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if key == "" {
+			return fmt.Errorf("tag keys cannot be empty")
+		}
+		if len(key) > maxTagLength || len(value) > maxTagLength {
+			return fmt.Errorf("tag '%s' exceeds %d characters", key, maxTagLength)
+		}
+		if strings.ContainsAny(key, ":,") {
+			return fmt.Errorf("tag key '%s' cannot contain ':' or ','", key)
+		}
+		normalized[key] = value
+	}
+
+	if err := sm.db.SaveServiceTags(serviceUUID, normalized); err != nil {
+		return err
+	}
+
+	service.Mutex.Lock()
+	service.Tags = normalized
+	service.Mutex.Unlock()
+
+	sm.broadcastUpdate(service)
+	return nil
+}
+
+// GetTagIndex returns every tag key in use with its distinct values
+func (sm *Manager) GetTagIndex() map[string][]string {
+	seen := make(map[string]map[string]bool)
+	for _, service := range sm.GetServices() {
+		for key, value := range service.Tags {
+			if seen[key] == nil {
+				seen[key] = make(map[string]bool)
+			}
+			seen[key][value] = true
+		}
+	}
+
+	index := make(map[string][]string, len(seen))
+	for key, values := range seen {
+		list := make([]string, 0, len(values))
+		for value := range values {
+			list = append(list, value)
+		}
+		sort.Strings(list)
+		index[key] = list
+	}
+	return index
+}
+
+// QueryServices returns the services matching the query in the requested
+// order, read from the replica so listing never waits on a locked service
+func (sm *Manager) QueryServices(query ServiceQuery) ([]*models.Service, error) {
+	switch query.Archived {
+	case "", ArchivedInclude, ArchivedOnly:
+	default:
+		return nil, fmt.Errorf("unsupported archived filter '%s'", query.Archived)
+	}
+
+	switch {
+	case query.SortBy == "", query.SortBy == "order", query.SortBy == "name", query.SortBy == "status",
+		query.SortBy == "port", query.SortBy == "buildSystem", strings.HasPrefix(query.SortBy, "tag:"):
+	default:
+		return nil, fmt.Errorf("unsupported sort field '%s'", query.SortBy)
+	}
+
+	filtered := make([]*models.Service, 0)
+	for _, service := range sm.GetServiceSnapshots() {
+		if matchesServiceQuery(service, query) {
+			filtered = append(filtered, service)
+		}
+	}
+
+	tagKey := strings.TrimPrefix(query.SortBy, "tag:")
+	less := func(a, b *models.Service) bool {
+		switch query.SortBy {
+		case "name":
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		case "status":
+			return a.Status < b.Status
+		case "port":
+			return a.Port < b.Port
+		case "buildSystem":
+			return a.BuildSystem < b.BuildSystem
+		case "", "order":
+			return a.Order < b.Order
+		default:
+			return a.Tags[tagKey] < b.Tags[tagKey]
+		}
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if query.Descending {
+			return less(filtered[j], filtered[i])
+		}
+		return less(filtered[i], filtered[j])
+	})
+
+	sm.withRestartStats(filtered)
+	return filtered, nil
+}
+
+func matchesServiceQuery(service *models.Service, query ServiceQuery) bool {
+	switch archived := service.ArchivedAt != nil; query.Archived {
+	case "":
+		if archived {
+			return false
+		}
+	case ArchivedOnly:
+		if !archived {
+			return false
+		}
+	}
+
+	for key, value := range query.Tags {
+		actual, ok := service.Tags[key]
+		if !ok || (value != "" && !strings.EqualFold(actual, value)) {
+			return false
+		}
+	}
+
+	if len(query.Statuses) > 0 && !containsFold(query.Statuses, service.Status) {
+		return false
+	}
+	if len(query.BuildSystems) > 0 && !containsFold(query.BuildSystems, service.BuildSystem) {
+		return false
+	}
+	if query.MinPort > 0 && service.Port < query.MinPort {
+		return false
+	}
+	if query.MaxPort > 0 && service.Port > query.MaxPort {
+		return false
+	}
+
+	return true
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,471 @@
+// Package services - Per-service test runs with JUnit report parsing
+package services
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	testRunTimeout      = 30 * time.Minute
+	testRunHistoryLimit = 50  // Runs kept per service
+	testRunOutputLines  = 200 // Lines of build output stored with a run
+)
+
+// Maven prints one of these per test class; Gradle only with test logging enabled
+var (
+	surefireClassRegex = regexp.MustCompile(`Tests run: (\d+), Failures: (\d+), Errors: (\d+), Skipped: (\d+).*? - in (\S+)`)
+	gradleTestRegex    = regexp.MustCompile(`^(\S+) > (.+) (PASSED|FAILED|SKIPPED)\s*$`)
+)
+
+// Test runs in progress, keyed by service UUID
+var (
+	activeTestRuns      = make(map[string]*exec.Cmd)
+	activeTestRunsMutex sync.Mutex
+)
+
+// TestProgress is streamed to websocket clients while a test run executes
+type TestProgress struct {
+	RunID     int64  `json:"runId"`
+	ServiceID string `json:"serviceId"`
+	Line      string `json:"line"`
+	Completed int    `json:"completed"` // Tests reported so far
+	Failed    int    `json:"failed"`
+}
+
+// RunServiceTests starts the test suite of a service in the background and
+// returns the new run. Progress is broadcast as "test_progress" messages and
+// the finished run as a "test_run" message.
+func (sm *Manager) RunServiceTests(serviceUUID, projectsDir string, req models.TestRunRequest) (*models.TestRun, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	serviceName := service.Name
+	serviceDir := serviceWorkingDir(projectsDir, service)
+	buildSystem := service.BuildSystem
+	service.Mutex.RUnlock()
+
+	if _, err := os.Stat(serviceDir); os.IsNotExist(err) {
+		return nil, fmt.Errorf("service directory does not exist: %s", serviceDir)
+	}
+
+	effectiveBuildSystem := GetEffectiveBuildSystem(serviceDir, buildSystem)
+	cmdString := testCommand(serviceDir, effectiveBuildSystem, req.Filter)
+	cmdString, credentialEnv := sm.applyRepositoryCredentials(cmdString, effectiveBuildSystem, sm.getServiceProfileID(serviceUUID), serviceName)
+	cmdString = sm.applyBuildSettings(cmdString, effectiveBuildSystem, sm.getServiceProfileID(serviceUUID), serviceName)
+
+	runEnv, err := sm.testRunEnv(service)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("bash", "-c", cmdString)
+	cmd.Dir = serviceDir
+	cmd.Env = runEnv
+	for key, value := range credentialEnv {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+	SetProcessGroup(cmd)
+
+	activeTestRunsMutex.Lock()
+	if _, running := activeTestRuns[serviceUUID]; running {
+		activeTestRunsMutex.Unlock()
+		return nil, fmt.Errorf("tests of service %s are already running", serviceName)
+	}
+	activeTestRuns[serviceUUID] = cmd
+	activeTestRunsMutex.Unlock()
+
+	run := &models.TestRun{
+		ServiceID:   serviceUUID,
+		ServiceName: serviceName,
+		Status:      models.TestRunRunning,
+		Command:     cmdString,
+		Filter:      req.Filter,
+		StartedAt:   time.Now(),
+	}
+
+	id, err := sm.db.InsertTestRun(run)
+	if err != nil {
+		sm.finishActiveTestRun(serviceUUID)
+		return nil, err
+	}
+	run.ID = id
+
+	if err := sm.db.PruneTestRuns(serviceUUID, testRunHistoryLimit); err != nil {
+		log.Printf("[WARN] Failed to prune test runs of service %s: %v", serviceName, err)
+	}
+
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		sm.finishActiveTestRun(serviceUUID)
+		return nil, sm.failTestRun(run, fmt.Errorf("failed to capture test output: %w", err))
+	}
+	cmd.Stderr = cmd.Stdout
+
+	log.Printf("[INFO] Running tests of service %s with command: %s", serviceName, cmdString)
+	if err := cmd.Start(); err != nil {
+		sm.finishActiveTestRun(serviceUUID)
+		return nil, sm.failTestRun(run, fmt.Errorf("failed to start tests: %w", err))
+	}
+
+	started := *run
+	go sm.waitForTestRun(run, cmd, output, serviceDir, effectiveBuildSystem)
+	return &started, nil
+}
+
+// CancelServiceTests stops the running test suite of a service
+func (sm *Manager) CancelServiceTests(serviceUUID string) error {
+	activeTestRunsMutex.Lock()
+	cmd, running := activeTestRuns[serviceUUID]
+	activeTestRunsMutex.Unlock()
+
+	if !running || cmd.Process == nil {
+		return fmt.Errorf("no test run in progress for service UUID %s", serviceUUID)
+	}
+	return ForceKillProcessGroup(cmd.Process.Pid)
+}
+
+// GetTestRuns returns the recent test runs of a service, newest first
+func (sm *Manager) GetTestRuns(serviceUUID string, limit int) ([]models.TestRun, error) {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+	if limit <= 0 || limit > testRunHistoryLimit {
+		limit = testRunHistoryLimit
+	}
+	return sm.db.GetTestRuns(serviceUUID, limit)
+}
+
+// GetTestRun returns a test run with its suites and output
+func (sm *Manager) GetTestRun(serviceUUID string, runID int64) (*models.TestRun, error) {
+	return sm.db.GetTestRun(serviceUUID, runID)
+}
+
+// testCommand builds the test command, preferring the project's wrapper
+func testCommand(serviceDir string, buildSystem BuildSystemType, filter string) string {
+	if buildSystem == BuildSystemGradle {
+		command := "gradle"
+		if HasGradleWrapper(serviceDir) {
+			command = "./gradlew"
+		}
+		command += " test --console=plain"
+		if filter != "" {
+			command += " --tests " + shellQuote(filter)
+		}
+		return command
+	}
+
+	command := "mvn"
+	if HasMavenWrapper(serviceDir) {
+		command = "./mvnw"
+	}
+	command += " -B test"
+	if filter != "" {
+		command += " -Dtest=" + shellQuote(filter) + " -Dsurefire.failIfNoSpecifiedTests=false"
+	}
+	return command
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// testRunEnv returns the environment tests, scans and migrations of a service
+// run with: the one it starts with, secrets resolved and the Java version its
+// profile pins it to applied
+func (sm *Manager) testRunEnv(service *models.Service) ([]string, error) {
+	env, globalEnvVars, err := sm.assembleServiceEnv(service, nil)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := sm.resolveServiceSecrets(sm.ctx, service, globalEnvVars)
+	if err != nil {
+		return nil, err
+	}
+	return env.environ(secrets), nil
+}
+
+// serviceEnvSettings returns the environment a service starts with, secret
+// references left unresolved, for reading settings such as a database URL
+func (sm *Manager) serviceEnvSettings(service *models.Service) map[string]string {
+	env, _, err := sm.assembleServiceEnv(service, nil)
+	if err != nil {
+		log.Printf("[WARN] Failed to assemble the environment of service %s: %v", service.ID, err)
+		return map[string]string{}
+	}
+	return envMap(env.environ(nil))
+}
+
+// waitForTestRun streams the build output, then records the parsed reports
+func (sm *Manager) waitForTestRun(run *models.TestRun, cmd *exec.Cmd, output io.Reader, serviceDir string, buildSystem BuildSystemType) {
+	defer sm.finishActiveTestRun(run.ServiceID)
+
+	timer := time.AfterFunc(testRunTimeout, func() {
+		log.Printf("[WARN] Tests of service %s exceeded %s, stopping them", run.ServiceName, testRunTimeout)
+		if err := ForceKillProcessGroup(cmd.Process.Pid); err != nil {
+			log.Printf("[WARN] Failed to stop tests of service %s: %v", run.ServiceName, err)
+		}
+	})
+	defer timer.Stop()
+
+	progress := TestProgress{RunID: run.ID, ServiceID: run.ServiceID}
+	var tail []string
+
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		tail = append(tail, line)
+		if len(tail) > testRunOutputLines {
+			tail = tail[1:]
+		}
+
+		if match := surefireClassRegex.FindStringSubmatch(line); match != nil {
+			tests, _ := strconv.Atoi(match[1])
+			failures, _ := strconv.Atoi(match[2])
+			errors, _ := strconv.Atoi(match[3])
+			progress.Completed += tests
+			progress.Failed += failures + errors
+		} else if match := gradleTestRegex.FindStringSubmatch(line); match != nil {
+			progress.Completed++
+			if match[3] == "FAILED" {
+				progress.Failed++
+			}
+		}
+
+		progress.Line = line
+		sm.broadcastMessage(WebSocketMessage{Type: "test_progress", Payload: progress})
+	}
+
+	waitErr := cmd.Wait()
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.DurationMs = finishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Output = strings.Join(tail, "\n")
+
+	suites, err := readTestReports(serviceDir, buildSystem, run.StartedAt)
+	if err != nil {
+		log.Printf("[WARN] Failed to read test reports of service %s: %v", run.ServiceName, err)
+	}
+	run.Suites = suites
+	for _, suite := range suites {
+		run.Total += suite.Tests
+		run.Failures += suite.Failures
+		run.Errors += suite.Errors
+		run.Skipped += suite.Skipped
+	}
+
+	switch {
+	case waitErr == nil && run.Failures+run.Errors == 0:
+		run.Status = models.TestRunPassed
+	case run.Failures+run.Errors > 0:
+		run.Status = models.TestRunFailed
+	default:
+		run.Status = models.TestRunError
+		run.Error = fmt.Sprintf("Test command failed: %v", waitErr)
+	}
+
+	if err := sm.db.UpdateTestRun(run); err != nil {
+		log.Printf("[ERROR] Failed to store test run of service %s: %v", run.ServiceName, err)
+	}
+
+	log.Printf("[INFO] Tests of service %s %s: %d run, %d failed, %d errors, %d skipped in %s",
+		run.ServiceName, run.Status, run.Total, run.Failures, run.Errors, run.Skipped, time.Duration(run.DurationMs)*time.Millisecond)
+	sm.broadcastMessage(WebSocketMessage{Type: "test_run", Payload: run})
+}
+
+// failTestRun records a run that could not be started
+func (sm *Manager) failTestRun(run *models.TestRun, cause error) error {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = models.TestRunError
+	run.Error = cause.Error()
+	if err := sm.db.UpdateTestRun(run); err != nil {
+		log.Printf("[ERROR] Failed to store test run of service %s: %v", run.ServiceName, err)
+	}
+	return cause
+}
+
+func (sm *Manager) finishActiveTestRun(serviceUUID string) {
+	activeTestRunsMutex.Lock()
+	delete(activeTestRuns, serviceUUID)
+	activeTestRunsMutex.Unlock()
+}
+
+// readTestReports parses the surefire or Gradle XML reports written since
+// the run started, including those of direct submodules
+func readTestReports(serviceDir string, buildSystem BuildSystemType, since time.Time) ([]models.TestSuiteResult, error) {
+	reportDir := filepath.Join("target", "surefire-reports")
+	if buildSystem == BuildSystemGradle {
+		reportDir = filepath.Join("build", "test-results", "test")
+	}
+
+	var files []string
+	for _, pattern := range []string{
+		filepath.Join(serviceDir, reportDir, "TEST-*.xml"),
+		filepath.Join(serviceDir, "*", reportDir, "TEST-*.xml"),
+	} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	suites := []models.TestSuiteResult{}
+	var firstErr error
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || info.ModTime().Before(since.Add(-time.Second)) {
+			continue // Left over from an earlier run
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		parsed, err := parseJUnitXML(data)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", filepath.Base(file), err)
+			}
+			continue
+		}
+		suites = append(suites, parsed...)
+	}
+
+	sort.Slice(suites, func(i, j int) bool { return suites[i].Name < suites[j].Name })
+	return suites, firstErr
+}
+
+type junitSuites struct {
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:""`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure"`
+	Error     *junitProblem `xml:"error"`
+	Skipped   *junitProblem `xml:"skipped"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// parseJUnitXML reads a report with a <testsuite> or <testsuites> root
+func parseJUnitXML(data []byte) ([]models.TestSuiteResult, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	raw := []junitSuite{root}
+	if root.XMLName.Local == "testsuites" {
+		var wrapper junitSuites
+		if err := xml.Unmarshal(data, &wrapper); err != nil {
+			return nil, err
+		}
+		raw = wrapper.Suites
+	}
+
+	suites := make([]models.TestSuiteResult, 0, len(raw))
+	for _, suite := range raw {
+		result := models.TestSuiteResult{
+			Name:     suite.Name,
+			Tests:    suite.Tests,
+			Failures: suite.Failures,
+			Errors:   suite.Errors,
+			Skipped:  suite.Skipped,
+			Time:     parseReportSeconds(suite.Time),
+			Cases:    make([]models.TestCaseResult, 0, len(suite.Cases)),
+		}
+		for _, testCase := range suite.Cases {
+			caseResult := models.TestCaseResult{
+				Name:      testCase.Name,
+				ClassName: testCase.ClassName,
+				Status:    models.TestCasePassed,
+				Time:      parseReportSeconds(testCase.Time),
+			}
+			switch {
+			case testCase.Failure != nil:
+				caseResult.Status = models.TestCaseFailed
+				caseResult.Message = problemMessage(testCase.Failure)
+				caseResult.Details = strings.TrimSpace(testCase.Failure.Body)
+			case testCase.Error != nil:
+				caseResult.Status = models.TestCaseError
+				caseResult.Message = problemMessage(testCase.Error)
+				caseResult.Details = strings.TrimSpace(testCase.Error.Body)
+			case testCase.Skipped != nil:
+				caseResult.Status = models.TestCaseSkipped
+				caseResult.Message = testCase.Skipped.Message
+			}
+			result.Cases = append(result.Cases, caseResult)
+		}
+		// Some writers leave out the counts; derive them from the cases
+		if result.Tests == 0 && len(result.Cases) > 0 {
+			for _, caseResult := range result.Cases {
+				result.Tests++
+				switch caseResult.Status {
+				case models.TestCaseFailed:
+					result.Failures++
+				case models.TestCaseError:
+					result.Errors++
+				case models.TestCaseSkipped:
+					result.Skipped++
+				}
+			}
+		}
+		suites = append(suites, result)
+	}
+	return suites, nil
+}
+
+func problemMessage(problem *junitProblem) string {
+	if problem.Message != "" {
+		return problem.Message
+	}
+	return problem.Type
+}
+
+// parseReportSeconds parses report durations, which surefire may write with
+// thousands separators ("1,234.5")
+func parseReportSeconds(value string) float64 {
+	seconds, _ := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	return seconds
+}
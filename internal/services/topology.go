@@ -25,12 +25,7 @@ func NewTopologyService(serviceManager *Manager) *TopologyService {
 
 // GenerateTopology analyzes services and generates topology visualization data
 func (ts *TopologyService) GenerateTopology() (*models.ServiceTopology, error) {
-	servicesSlice := ts.serviceManager.GetServices()
-	services := make([]*models.Service, 0, len(servicesSlice))
-	for i := range servicesSlice {
-		services = append(services, &servicesSlice[i])
-	}
-	return ts.generateTopologyForServices(services)
+	return ts.generateTopologyForServices(ts.serviceManager.GetServices())
 }
 
 // GenerateTopologyForProfile generates topology for services in a specific profile
@@ -52,7 +47,7 @@ func (ts *TopologyService) GenerateTopologyForProfile(profileServicesJson string
 	var profileServices []*models.Service
 	for i := range allServices {
 		if profileServicesMap[allServices[i].ID] {
-			profileServices = append(profileServices, &allServices[i])
+			profileServices = append(profileServices, allServices[i])
 		}
 	}
 
@@ -0,0 +1,378 @@
+// Package services - Debugging proxy that records HTTP traffic sent through it
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	// Latency samples kept per endpoint for percentiles
+	trafficLatencySamples = 1000
+	// Upper bound on distinct endpoints tracked per service
+	maxTrafficEndpoints = 200
+	// Path that collects requests once the endpoint limit is reached
+	trafficOverflowPath = "{other}"
+)
+
+var (
+	trafficNumberRegex = regexp.MustCompile(`^\d+$`)
+	trafficUUIDRegex   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	trafficTokenRegex  = regexp.MustCompile(`^[A-Za-z0-9_-]{16,}$`)
+)
+
+// trafficEndpoint accumulates requests for one method and path template
+type trafficEndpoint struct {
+	stats     models.TrafficEndpointStats
+	latencies []float64 // Ring buffer of recent durations in milliseconds
+	next      int
+}
+
+// trafficCapture is the debugging proxy of a service and what it has seen
+type trafficCapture struct {
+	mutex      sync.Mutex
+	server     *http.Server
+	listenPort int
+	startedAt  time.Time
+	total      int64
+	endpoints  map[string]*trafficEndpoint
+}
+
+var (
+	trafficCaptures      = make(map[string]*trafficCapture) // service UUID -> capture
+	trafficCapturesMutex sync.Mutex
+)
+
+// StartTrafficCapture starts a debugging proxy on a local port that forwards
+// to the service and records every request sent through it. The service keeps
+// listening on its own port, so only clients pointed at the listen port are
+// recorded; traffic to the service's port bypasses the proxy. Starting a
+// capture clears earlier samples.
+func (sm *Manager) StartTrafficCapture(serviceUUID string, req models.TrafficCaptureRequest) (*models.TrafficSummary, error) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	service.Mutex.RLock()
+	serviceName := service.Name
+	servicePort := service.Port
+	service.Mutex.RUnlock()
+
+	if servicePort <= 0 {
+		return nil, fmt.Errorf("service %s has no port configured", serviceName)
+	}
+	if req.ListenPort < 0 || req.ListenPort > 65535 {
+		return nil, fmt.Errorf("invalid listen port %d", req.ListenPort)
+	}
+	if req.ListenPort == servicePort {
+		return nil, fmt.Errorf("listen port %d is the port of %s itself", req.ListenPort, serviceName)
+	}
+
+	trafficCapturesMutex.Lock()
+	if existing, ok := trafficCaptures[serviceUUID]; ok && existing.server != nil {
+		trafficCapturesMutex.Unlock()
+		return nil, fmt.Errorf("traffic capture already running for %s on port %d", serviceName, existing.listenPort)
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", req.ListenPort))
+	if err != nil {
+		trafficCapturesMutex.Unlock()
+		return nil, fmt.Errorf("failed to listen on port %d: %w", req.ListenPort, err)
+	}
+
+	capture := &trafficCapture{
+		listenPort: listener.Addr().(*net.TCPAddr).Port,
+		startedAt:  time.Now(),
+		endpoints:  make(map[string]*trafficEndpoint),
+	}
+	capture.server = &http.Server{
+		Handler:           trafficProxyHandler(service, capture),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	trafficCaptures[serviceUUID] = capture
+	trafficCapturesMutex.Unlock()
+
+	go func() {
+		if err := capture.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[ERROR] Traffic capture for %s stopped: %v", serviceName, err)
+		}
+	}()
+
+	log.Printf("[INFO] Debugging proxy for %s: requests to 127.0.0.1:%d are recorded and forwarded to localhost:%d", serviceName, capture.listenPort, servicePort)
+
+	return capture.summary(service), nil
+}
+
+// StopTrafficCapture shuts the debugging proxy down; the collected samples stay
+// available until the next capture starts or they are reset
+func (sm *Manager) StopTrafficCapture(serviceUUID string) error {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	trafficCapturesMutex.Lock()
+	capture, ok := trafficCaptures[serviceUUID]
+	var server *http.Server
+	if ok {
+		server = capture.server
+		capture.server = nil
+	}
+	trafficCapturesMutex.Unlock()
+
+	if server == nil {
+		return fmt.Errorf("no traffic capture running for %s", service.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+	}
+
+	log.Printf("[INFO] Stopped traffic capture for %s on port %d", service.Name, capture.listenPort)
+	return nil
+}
+
+// GetTrafficSummary returns the requests recorded by a service's debugging
+// proxy, busiest endpoints first
+func (sm *Manager) GetTrafficSummary(serviceUUID string) (*models.TrafficSummary, bool) {
+	service, exists := sm.GetServiceByUUID(serviceUUID)
+	if !exists {
+		return nil, false
+	}
+
+	trafficCapturesMutex.Lock()
+	capture, ok := trafficCaptures[serviceUUID]
+	trafficCapturesMutex.Unlock()
+
+	if !ok {
+		service.Mutex.RLock()
+		summary := &models.TrafficSummary{
+			ServiceID:   service.ID,
+			ServiceName: service.Name,
+			TargetPort:  service.Port,
+			Endpoints:   []models.TrafficEndpointStats{},
+		}
+		service.Mutex.RUnlock()
+		return summary, true
+	}
+	return capture.summary(service), true
+}
+
+// ResetTrafficStats clears the recorded requests without stopping the capture
+func (sm *Manager) ResetTrafficStats(serviceUUID string) error {
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("service UUID %s not found", serviceUUID)
+	}
+
+	trafficCapturesMutex.Lock()
+	defer trafficCapturesMutex.Unlock()
+
+	capture, ok := trafficCaptures[serviceUUID]
+	if !ok {
+		return nil
+	}
+	if capture.server == nil {
+		delete(trafficCaptures, serviceUUID)
+		return nil
+	}
+
+	capture.mutex.Lock()
+	capture.total = 0
+	capture.startedAt = time.Now()
+	capture.endpoints = make(map[string]*trafficEndpoint)
+	capture.mutex.Unlock()
+	return nil
+}
+
+// discardTrafficCapture closes the proxy of a deleted service and drops its samples
+func discardTrafficCapture(serviceUUID string) {
+	trafficCapturesMutex.Lock()
+	capture, ok := trafficCaptures[serviceUUID]
+	delete(trafficCaptures, serviceUUID)
+	trafficCapturesMutex.Unlock()
+
+	if ok && capture.server != nil {
+		capture.server.Close()
+	}
+}
+
+// trafficProxyHandler forwards to the service's current port and records the outcome
+func trafficProxyHandler(service *models.Service, capture *trafficCapture) http.Handler {
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			service.Mutex.RLock()
+			port := service.Port
+			service.Mutex.RUnlock()
+
+			req.URL.Scheme = "http"
+			req.URL.Host = fmt.Sprintf("localhost:%d", port)
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			http.Error(w, fmt.Sprintf("Service '%s' is not reachable: %v", service.Name, err), http.StatusBadGateway)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &trafficRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+
+		proxy.ServeHTTP(recorder, r)
+
+		capture.record(r.Method, trafficPathTemplate(r.URL.Path), recorder.status, time.Since(start))
+	})
+}
+
+// trafficRecorder captures the response status; Unwrap keeps flushing and
+// websocket upgrades working through http.ResponseController
+type trafficRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (tr *trafficRecorder) WriteHeader(status int) {
+	tr.status = status
+	tr.ResponseWriter.WriteHeader(status)
+}
+
+func (tr *trafficRecorder) Unwrap() http.ResponseWriter {
+	return tr.ResponseWriter
+}
+
+// trafficPathTemplate replaces path segments that look like identifiers with
+// placeholders so /users/42 and /users/43 count as the same endpoint
+func trafficPathTemplate(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "":
+			continue
+		case trafficNumberRegex.MatchString(segment):
+			segments[i] = "{id}"
+		case trafficUUIDRegex.MatchString(segment):
+			segments[i] = "{uuid}"
+		case trafficTokenRegex.MatchString(segment) && strings.ContainsAny(segment, "0123456789"):
+			segments[i] = "{token}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (tc *trafficCapture) record(method, path string, status int, duration time.Duration) {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+
+	tc.total++
+
+	key := method + " " + path
+	endpoint, ok := tc.endpoints[key]
+	if !ok && len(tc.endpoints) >= maxTrafficEndpoints {
+		path = trafficOverflowPath
+		key = method + " " + path
+		endpoint, ok = tc.endpoints[key]
+	}
+	if !ok {
+		endpoint = &trafficEndpoint{
+			stats: models.TrafficEndpointStats{
+				Method:   method,
+				Path:     path,
+				Statuses: make(map[string]int64),
+			},
+		}
+		tc.endpoints[key] = endpoint
+	}
+
+	ms := float64(duration.Microseconds()) / 1000
+	endpoint.stats.Count++
+	endpoint.stats.Statuses[strconv.Itoa(status)]++
+	if status >= 500 {
+		endpoint.stats.Errors++
+	}
+	if ms > endpoint.stats.MaxMs {
+		endpoint.stats.MaxMs = ms
+	}
+	endpoint.stats.LastSeen = time.Now()
+
+	if len(endpoint.latencies) < trafficLatencySamples {
+		endpoint.latencies = append(endpoint.latencies, ms)
+	} else {
+		endpoint.latencies[endpoint.next] = ms
+		endpoint.next = (endpoint.next + 1) % trafficLatencySamples
+	}
+}
+
+func (tc *trafficCapture) summary(service *models.Service) *models.TrafficSummary {
+	service.Mutex.RLock()
+	summary := &models.TrafficSummary{
+		ServiceID:   service.ID,
+		ServiceName: service.Name,
+		TargetPort:  service.Port,
+	}
+	service.Mutex.RUnlock()
+
+	trafficCapturesMutex.Lock()
+	summary.Capturing = tc.server != nil
+	trafficCapturesMutex.Unlock()
+
+	tc.mutex.Lock()
+	startedAt := tc.startedAt
+	summary.StartedAt = &startedAt
+	summary.ListenPort = tc.listenPort
+	summary.TotalRequests = tc.total
+	summary.Endpoints = make([]models.TrafficEndpointStats, 0, len(tc.endpoints))
+	for _, endpoint := range tc.endpoints {
+		stats := endpoint.stats
+		stats.Statuses = make(map[string]int64, len(endpoint.stats.Statuses))
+		for status, count := range endpoint.stats.Statuses {
+			stats.Statuses[status] = count
+		}
+
+		latencies := append([]float64(nil), endpoint.latencies...)
+		sort.Float64s(latencies)
+		stats.P50Ms = latencyPercentile(latencies, 50)
+		stats.P90Ms = latencyPercentile(latencies, 90)
+		stats.P99Ms = latencyPercentile(latencies, 99)
+		summary.Endpoints = append(summary.Endpoints, stats)
+	}
+	tc.mutex.Unlock()
+
+	sort.Slice(summary.Endpoints, func(i, j int) bool {
+		if summary.Endpoints[i].Count != summary.Endpoints[j].Count {
+			return summary.Endpoints[i].Count > summary.Endpoints[j].Count
+		}
+		return summary.Endpoints[i].Method+" "+summary.Endpoints[i].Path < summary.Endpoints[j].Method+" "+summary.Endpoints[j].Path
+	})
+	return summary
+}
+
+// latencyPercentile returns the nearest-rank percentile of sorted samples
+func latencyPercentile(sorted []float64, percentile float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
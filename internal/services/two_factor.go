@@ -0,0 +1,413 @@
+// Package services - Two-factor authentication with one-time codes (TOTP)
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+const (
+	totpPeriod           = 30 // Seconds per code, as authenticator apps expect
+	totpDigits           = 6
+	totpSkew             = 1 // Codes from one step before or after are accepted, for clock drift
+	totpIssuer           = "Vertex"
+	recoveryCodeCount    = 10
+	maxTwoFactorFailures = 5
+	twoFactorLockout     = 5 * time.Minute
+)
+
+// ErrTwoFactorRequired means the password was right and the account also
+// needs a one-time code
+var ErrTwoFactorRequired = errors.New("two-factor code required")
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// twoFactorFailures counts the wrong codes given for an account since the
+// first one
+type twoFactorFailures struct {
+	count int
+	since time.Time
+}
+
+// generateTOTPSecret returns a new 160-bit secret, base32 encoded
+func generateTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate two-factor secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(key), nil
+}
+
+// totpCode computes the code of a time step (RFC 6238 with HMAC-SHA1)
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// matchTOTP returns the time step a code belongs to. Steps up to
+// lastUsedStep are refused, so a code cannot be used twice.
+func matchTOTP(secret, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step > lastUsedStep && hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// normalizeTwoFactorCode drops the spaces and dashes people type or paste
+func normalizeTwoFactorCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+// hashRecoveryCode is how recovery codes are stored
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeTwoFactorCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes returns new recovery codes and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		code := hex.EncodeToString(raw)
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// loadTwoFactor reads a user's two-factor settings with the TOTP secret
+// decrypted. A secret stored in plain text by an earlier version is
+// encrypted on the way.
+func (as *AuthService) loadTwoFactor(userID string) (*models.UserTwoFactor, error) {
+	tf, err := as.db.GetUserTwoFactor(userID)
+	if err != nil || tf == nil {
+		return tf, err
+	}
+	if !strings.HasPrefix(tf.Secret, repositorySecretPrefix) {
+		if err := as.storeTwoFactor(*tf); err != nil {
+			log.Printf("[WARN] Failed to encrypt the two-factor secret of user %s: %v", userID, err)
+		}
+		return tf, nil
+	}
+	secret, err := decryptRepositorySecret(tf.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read two-factor secret: %w", err)
+	}
+	tf.Secret = secret
+	return tf, nil
+}
+
+// storeTwoFactor saves a user's two-factor settings with the TOTP secret
+// encrypted by the same key as repository credentials
+func (as *AuthService) storeTwoFactor(tf models.UserTwoFactor) error {
+	secret, err := encryptRepositorySecret(tf.Secret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt two-factor secret: %w", err)
+	}
+	tf.Secret = secret
+	return as.db.SaveUserTwoFactor(tf)
+}
+
+// verifyTwoFactorCode checks a one-time or recovery code for a user with
+// two-factor authentication and saves that it was used. Accounts are locked
+// for a while after too many wrong codes.
+func (as *AuthService) verifyTwoFactorCode(userID, code string) (*models.UserTwoFactor, error) {
+	// Read under the lock, so a code cannot be used twice by requests at once
+	as.twoFactorMutex.Lock()
+	defer as.twoFactorMutex.Unlock()
+
+	tf, err := as.loadTwoFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil || !tf.Enabled {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	now := time.Now()
+	failures := as.twoFactorFailures[userID]
+	if failures != nil && now.Sub(failures.since) >= twoFactorLockout {
+		delete(as.twoFactorFailures, userID)
+		failures = nil
+	}
+	if failures != nil && failures.count >= maxTwoFactorFailures {
+		wait := failures.since.Add(twoFactorLockout).Sub(now).Round(time.Second)
+		return nil, fmt.Errorf("too many invalid two-factor codes; try again in %s", wait)
+	}
+
+	code = normalizeTwoFactorCode(code)
+	if step, ok := matchTOTP(tf.Secret, code, now, tf.LastUsedStep); ok {
+		tf.LastUsedStep = step
+	} else if i := slices.Index(tf.RecoveryCodes, hashRecoveryCode(code)); i >= 0 && len(code) > totpDigits {
+		tf.RecoveryCodes = slices.Delete(tf.RecoveryCodes, i, i+1)
+		log.Printf("[INFO] Recovery code used for user %s; %d left", userID, len(tf.RecoveryCodes))
+	} else {
+		if failures == nil {
+			failures = &twoFactorFailures{since: now}
+			as.twoFactorFailures[userID] = failures
+		}
+		failures.count++
+		log.Printf("[WARN] Invalid two-factor code for user %s (%d of %d)", userID, failures.count, maxTwoFactorFailures)
+		return nil, fmt.Errorf("invalid two-factor code")
+	}
+
+	delete(as.twoFactorFailures, userID)
+	if err := as.storeTwoFactor(*tf); err != nil {
+		return nil, err
+	}
+	return tf, nil
+}
+
+// twoFactorEnabled reports whether a user signs in with a one-time code.
+// It is cached, since every API request of a signed-in user may ask.
+func (as *AuthService) twoFactorEnabled(userID string) (bool, error) {
+	as.twoFactorMutex.Lock()
+	enabled, cached := as.twoFactorEnabledUsers[userID]
+	as.twoFactorMutex.Unlock()
+	if cached {
+		return enabled, nil
+	}
+
+	tf, err := as.loadTwoFactor(userID)
+	if err != nil {
+		return false, err
+	}
+	enabled = tf != nil && tf.Enabled
+	as.twoFactorMutex.Lock()
+	as.twoFactorEnabledUsers[userID] = enabled
+	as.twoFactorMutex.Unlock()
+	return enabled, nil
+}
+
+func (as *AuthService) forgetTwoFactor(userID string) {
+	as.twoFactorMutex.Lock()
+	delete(as.twoFactorEnabledUsers, userID)
+	as.twoFactorMutex.Unlock()
+}
+
+// TwoFactorSetupRequired reports whether admins require two-factor
+// authentication and a user has not set it up. It is required when it
+// cannot be checked.
+func (as *AuthService) TwoFactorSetupRequired(userID string) bool {
+	settings, err := as.GetAccessSettings()
+	if err != nil {
+		log.Printf("[WARN] Failed to read access settings, requiring two-factor authentication: %v", err)
+		return true
+	}
+	if !settings.RequireTwoFactor {
+		return false
+	}
+	enabled, err := as.twoFactorEnabled(userID)
+	if err != nil {
+		log.Printf("[WARN] Failed to check two-factor authentication of user %s: %v", userID, err)
+		return true
+	}
+	return !enabled
+}
+
+// setUserTwoFactor fills in the two-factor state of a user sent to clients
+func (as *AuthService) setUserTwoFactor(user *models.User) {
+	enabled, err := as.twoFactorEnabled(user.ID)
+	if err != nil {
+		log.Printf("[WARN] Failed to check two-factor authentication of user %s: %v", user.ID, err)
+	}
+	user.TwoFactorEnabled = enabled
+	user.TwoFactorSetupRequired = as.TwoFactorSetupRequired(user.ID)
+}
+
+// GetTwoFactorStatus returns whether the signed-in user has two-factor
+// authentication and how many recovery codes are left
+func (as *AuthService) GetTwoFactorStatus(claims *models.JWTClaims) (*models.TwoFactorStatus, error) {
+	tf, err := as.loadTwoFactor(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := as.GetAccessSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.TwoFactorStatus{Required: settings.RequireTwoFactor}
+	if tf != nil {
+		status.Enabled = tf.Enabled
+		status.Pending = !tf.Enabled
+		status.EnabledAt = tf.EnabledAt
+		if tf.Enabled {
+			status.RecoveryCodesLeft = len(tf.RecoveryCodes)
+		}
+	}
+	return status, nil
+}
+
+// EnrollTwoFactor creates a new secret for the signed-in user to add to an
+// authenticator app. Two-factor authentication is on once a code from the
+// app is confirmed; enrolling again before that replaces the secret.
+func (as *AuthService) EnrollTwoFactor(claims *models.JWTClaims) (*models.TwoFactorEnrollment, error) {
+	tf, err := as.loadTwoFactor(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if tf != nil && tf.Enabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled; turn it off before enrolling again")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := as.storeTwoFactor(models.UserTwoFactor{UserID: claims.UserID, Secret: secret}); err != nil {
+		return nil, err
+	}
+
+	account := claims.Email
+	if account == "" {
+		account = claims.Username
+	}
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	otpauthURL := (&url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + totpIssuer + ":" + account, RawQuery: query.Encode()}).String()
+
+	log.Printf("[INFO] Two-factor enrollment started by %s", claims.Username)
+	return &models.TwoFactorEnrollment{Secret: secret, OTPAuthURL: otpauthURL}, nil
+}
+
+// ConfirmTwoFactor turns two-factor authentication on once the first code
+// from the authenticator app checks out, and returns the recovery codes
+func (as *AuthService) ConfirmTwoFactor(claims *models.JWTClaims, code string) (*models.TwoFactorRecoveryCodes, error) {
+	tf, err := as.loadTwoFactor(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if tf == nil {
+		return nil, fmt.Errorf("no two-factor enrollment is pending; enroll first")
+	}
+	if tf.Enabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	step, ok := matchTOTP(tf.Secret, normalizeTwoFactorCode(code), time.Now(), 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid two-factor code; check the time on the device running the authenticator app")
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tf.Enabled = true
+	tf.EnabledAt = &now
+	tf.LastUsedStep = step
+	tf.RecoveryCodes = hashes
+	if err := as.storeTwoFactor(*tf); err != nil {
+		return nil, err
+	}
+	as.forgetTwoFactor(claims.UserID)
+
+	log.Printf("[INFO] Two-factor authentication enabled by %s", claims.Username)
+	return &models.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// RegenerateRecoveryCodes replaces the signed-in user's recovery codes; the
+// old ones stop working
+func (as *AuthService) RegenerateRecoveryCodes(claims *models.JWTClaims, code string) (*models.TwoFactorRecoveryCodes, error) {
+	tf, err := as.verifyTwoFactorCode(claims.UserID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	tf.RecoveryCodes = hashes
+	if err := as.storeTwoFactor(*tf); err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] Recovery codes regenerated by %s", claims.Username)
+	return &models.TwoFactorRecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off for the signed-in
+// user, who proves it is them with a current or recovery code. It cannot be
+// turned off while admins require it.
+func (as *AuthService) DisableTwoFactor(claims *models.JWTClaims, code string) error {
+	if settings, err := as.GetAccessSettings(); err != nil {
+		return err
+	} else if settings.RequireTwoFactor {
+		return fmt.Errorf("two-factor authentication is required for every account and cannot be turned off")
+	}
+	if _, err := as.verifyTwoFactorCode(claims.UserID, code); err != nil {
+		return err
+	}
+
+	if err := as.db.DeleteUserTwoFactor(claims.UserID); err != nil {
+		return err
+	}
+	as.forgetTwoFactor(claims.UserID)
+
+	log.Printf("[INFO] Two-factor authentication disabled by %s", claims.Username)
+	return nil
+}
+
+// ResetTwoFactor turns two-factor authentication off for a user who lost
+// their authenticator and recovery codes. Only admins may reset it.
+func (as *AuthService) ResetTwoFactor(userID string, claims *models.JWTClaims) error {
+	if claims.Role != "admin" {
+		return fmt.Errorf("only admins can reset two-factor authentication")
+	}
+	user, err := as.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("user %s not found", userID)
+	}
+
+	if err := as.db.DeleteUserTwoFactor(userID); err != nil {
+		return err
+	}
+	as.forgetTwoFactor(userID)
+	as.twoFactorMutex.Lock()
+	delete(as.twoFactorFailures, userID)
+	as.twoFactorMutex.Unlock()
+
+	log.Printf("[INFO] Two-factor authentication of %s reset by %s", user.Username, claims.Username)
+	return nil
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zechtz/vertex/internal/database"
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// newTwoFactorTestUser returns an auth service backed by a temporary
+// database and a user with two-factor authentication enabled
+func newTwoFactorTestUser(t *testing.T) (*AuthService, *models.JWTClaims, string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("VERTEX_DATA_DIR", dir)
+	db, err := database.NewDatabaseWithPath(filepath.Join(dir, "vertex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	as := NewAuthService(db)
+	user, err := as.Register(&models.UserRegistration{Username: "alice", Email: "alice@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := &models.JWTClaims{UserID: user.ID, Username: user.Username, Email: user.Email, Role: user.Role}
+
+	enrollment, err := as.EnrollTwoFactor(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := as.ConfirmTwoFactor(claims, totpAt(t, enrollment.Secret, time.Now(), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return as, claims, enrollment.Secret, recovery.RecoveryCodes
+}
+
+// totpAt returns the code offset steps after the one current at now
+func totpAt(t *testing.T, secret string, now time.Time, offset int64) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totpCode(key, now.Unix()/totpPeriod+offset)
+}
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("Code at %d: got %s want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	current := now.Unix() / totpPeriod
+
+	for _, offset := range []int64{-1, 0, 1} {
+		step, ok := matchTOTP(secret, totpAt(t, secret, now, offset), now, 0)
+		if !ok || step != current+offset {
+			t.Errorf("Expected the code %d steps away to match step %d, got %d %v", offset, current+offset, step, ok)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		if _, ok := matchTOTP(secret, totpAt(t, secret, now, offset), now, 0); ok {
+			t.Errorf("Expected the code %d steps away to be refused", offset)
+		}
+	}
+
+	if _, ok := matchTOTP(secret, totpAt(t, secret, now, 0), now, current); ok {
+		t.Error("Expected a code of an already used step to be refused")
+	}
+	if _, ok := matchTOTP(secret, "12345", now, 0); ok {
+		t.Error("Expected a code of the wrong length to be refused")
+	}
+	if _, ok := matchTOTP("not base32!", "123456", now, 0); ok {
+		t.Error("Expected an invalid secret to match nothing")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("Expected %d codes and hashes, got %d and %d", recoveryCodeCount, len(codes), len(hashes))
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("Expected a code of the form xxxxx-xxxxx, got %q", code)
+		}
+		if seen[code] {
+			t.Errorf("Expected unique codes, got %q twice", code)
+		}
+		seen[code] = true
+		if hashes[i] != hashRecoveryCode(code) {
+			t.Errorf("Expected hash %d to be the hash of its code", i)
+		}
+		if hashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", " "))+" ") != hashes[i] {
+			t.Errorf("Expected %q typed with spaces and capitals to match", code)
+		}
+	}
+}
+
+func TestConfirmTwoFactor_StoresSecretEncrypted(t *testing.T) {
+	as, claims, secret, recovery := newTwoFactorTestUser(t)
+
+	if len(recovery) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %d", recoveryCodeCount, len(recovery))
+	}
+
+	stored, err := as.db.GetUserTwoFactor(claims.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.Secret, repositorySecretPrefix) || strings.Contains(stored.Secret, secret) {
+		t.Errorf("Expected the secret to be encrypted at rest, got %q", stored.Secret)
+	}
+	for _, code := range recovery {
+		for _, hash := range stored.RecoveryCodes {
+			if hash == code {
+				t.Errorf("Expected recovery codes to be stored hashed, found %q", code)
+			}
+		}
+	}
+
+	status, err := as.GetTwoFactorStatus(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Enabled || status.Pending || status.RecoveryCodesLeft != recoveryCodeCount {
+		t.Errorf("Unexpected status: got %+v", status)
+	}
+}
+
+func TestConfirmTwoFactor_InvalidCode(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("VERTEX_DATA_DIR", dir)
+	db, err := database.NewDatabaseWithPath(filepath.Join(dir, "vertex.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	as := NewAuthService(db)
+	user, err := as.Register(&models.UserRegistration{Username: "bob", Email: "bob@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := &models.JWTClaims{UserID: user.ID, Username: user.Username, Email: user.Email}
+
+	if _, err := as.ConfirmTwoFactor(claims, "123456"); err == nil || !strings.Contains(err.Error(), "enroll first") {
+		t.Errorf("Expected confirming without enrolling to fail, got %v", err)
+	}
+	if _, err := as.EnrollTwoFactor(claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := as.ConfirmTwoFactor(claims, "000000x"); err == nil {
+		t.Error("Expected an invalid code not to enable two-factor authentication")
+	}
+	if enabled, _ := as.twoFactorEnabled(user.ID); enabled {
+		t.Error("Expected two-factor authentication to stay off")
+	}
+}
+
+func TestLogin_RequiresTwoFactorCode(t *testing.T) {
+	as, claims, secret, _ := newTwoFactorTestUser(t)
+	login := &models.UserLogin{Email: "alice@example.com", Password: "secret123"}
+
+	if _, err := as.Login(login); !errors.Is(err, ErrTwoFactorRequired) {
+		t.Errorf("Expected a code to be required, got %v", err)
+	}
+
+	// Create a login with the code the enrollment was confirmed with
+	tf, err := as.loadTwoFactor(claims.UserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	login.Code = totpCode(key, tf.LastUsedStep)
+	if _, err := as.Login(login); err == nil {
+		t.Error("Expected a code that was already used to be refused")
+	}
+
+	login.Code = totpAt(t, secret, time.Now(), 1)
+	response, err := as.Login(login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.User.TwoFactorEnabled || response.Token == "" {
+		t.Errorf("Unexpected login response: got %+v", response.User)
+	}
+}
+
+func TestVerifyTwoFactorCode_RecoveryCodeWorksOnce(t *testing.T) {
+	as, claims, _, recovery := newTwoFactorTestUser(t)
+
+	tf, err := as.verifyTwoFactorCode(claims.UserID, strings.ToUpper(recovery[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tf.RecoveryCodes) != recoveryCodeCount-1 {
+		t.Errorf("Expected the used recovery code to be removed, got %d left", len(tf.RecoveryCodes))
+	}
+
+	if _, err := as.verifyTwoFactorCode(claims.UserID, recovery[0]); err == nil {
+		t.Error("Expected a used recovery code to be refused")
+	}
+}
+
+func TestVerifyTwoFactorCode_LocksOutAfterFailures(t *testing.T) {
+	as, claims, secret, _ := newTwoFactorTestUser(t)
+
+	for i := 0; i < maxTwoFactorFailures; i++ {
+		if _, err := as.verifyTwoFactorCode(claims.UserID, "000000"); err == nil || !strings.Contains(err.Error(), "invalid two-factor code") {
+			t.Fatalf("Expected attempt %d to be refused as invalid, got %v", i+1, err)
+		}
+	}
+
+	// Create an attempt with a valid code while the account is locked
+	code := totpAt(t, secret, time.Now(), 1)
+	if _, err := as.verifyTwoFactorCode(claims.UserID, code); err == nil || !strings.Contains(err.Error(), "too many invalid two-factor codes") {
+		t.Fatalf("Expected the account to be locked, got %v", err)
+	}
+
+	as.twoFactorMutex.Lock()
+	as.twoFactorFailures[claims.UserID].since = time.Now().Add(-twoFactorLockout)
+	as.twoFactorMutex.Unlock()
+
+	if _, err := as.verifyTwoFactorCode(claims.UserID, code); err != nil {
+		t.Errorf("Expected the lockout to end after %s, got %v", twoFactorLockout, err)
+	}
+	as.twoFactorMutex.Lock()
+	_, tracked := as.twoFactorFailures[claims.UserID]
+	as.twoFactorMutex.Unlock()
+	if tracked {
+		t.Error("Expected a valid code to clear the failures")
+	}
+}
+
+func TestResetTwoFactor(t *testing.T) {
+	as, claims, _, _ := newTwoFactorTestUser(t)
+
+	if err := as.ResetTwoFactor(claims.UserID, claims); err == nil {
+		t.Error("Expected a non-admin reset to be refused")
+	}
+
+	admin := &models.JWTClaims{UserID: "admin-1", Username: "admin", Role: "admin"}
+	if err := as.ResetTwoFactor(claims.UserID, admin); err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := as.twoFactorEnabled(claims.UserID); enabled {
+		t.Error("Expected two-factor authentication to be off after the reset")
+	}
+}
@@ -11,13 +11,21 @@ import (
 
 type UptimeEvent struct {
 	ServiceID string    `json:"serviceId"`
-	EventType string    `json:"eventType"` // "start", "stop", "restart"
+	ProfileID string    `json:"profileId,omitempty"` // Profile the service was running under
+	EventType string    `json:"eventType"`           // "start", "stop", "restart"
 	Timestamp time.Time `json:"timestamp"`
 	Status    string    `json:"status"` // "running", "stopped", "unhealthy"
 }
 
+// uptimeKey scopes uptime events to the profile a service was running under,
+// so the same service UUID used by several profiles keeps separate statistics
+type uptimeKey struct {
+	profileID string
+	serviceID string
+}
+
 type UptimeTracker struct {
-	events map[string][]UptimeEvent // serviceID -> events
+	events map[uptimeKey][]UptimeEvent // (profileID, serviceID) -> events
 	mutex  sync.RWMutex
 }
 
@@ -28,44 +36,51 @@ var once sync.Once
 func GetUptimeTracker() *UptimeTracker {
 	once.Do(func() {
 		uptimeTracker = &UptimeTracker{
-			events: make(map[string][]UptimeEvent),
+			events: make(map[uptimeKey][]UptimeEvent),
 		}
 	})
 	return uptimeTracker
 }
 
-// RecordEvent records a service state change event
-func (ut *UptimeTracker) RecordEvent(serviceID, eventType, status string) {
+// RecordEvent records a service state change event for the profile the service is running under.
+// An empty profileID records the event outside of any profile.
+func (ut *UptimeTracker) RecordEvent(profileID, serviceID, eventType, status string) {
 	ut.mutex.Lock()
 	defer ut.mutex.Unlock()
 
+	key := uptimeKey{profileID: profileID, serviceID: serviceID}
 	event := UptimeEvent{
 		ServiceID: serviceID,
+		ProfileID: profileID,
 		EventType: eventType,
 		Timestamp: time.Now(),
 		Status:    status,
 	}
 
-	if ut.events[serviceID] == nil {
-		ut.events[serviceID] = make([]UptimeEvent, 0)
+	if ut.events[key] == nil {
+		ut.events[key] = make([]UptimeEvent, 0)
 	}
 
-	ut.events[serviceID] = append(ut.events[serviceID], event)
+	ut.events[key] = append(ut.events[key], event)
 
 	// Keep only last 1000 events per service to prevent memory issues
-	if len(ut.events[serviceID]) > 1000 {
-		ut.events[serviceID] = ut.events[serviceID][len(ut.events[serviceID])-1000:]
+	if len(ut.events[key]) > 1000 {
+		ut.events[key] = ut.events[key][len(ut.events[key])-1000:]
 	}
 
-	log.Printf("[DEBUG] Recorded uptime event for %s: %s -> %s", serviceID, eventType, status)
+	log.Printf("[DEBUG] Recorded uptime event for %s (profile %q): %s -> %s", serviceID, profileID, eventType, status)
 }
 
-// CalculateUptimeStats calculates uptime statistics for a service
-func (ut *UptimeTracker) CalculateUptimeStats(serviceID string, service *models.Service) models.UptimeStatistics {
+// CalculateUptimeStats calculates uptime statistics for a service within a profile
+func (ut *UptimeTracker) CalculateUptimeStats(profileID, serviceID string) models.UptimeStatistics {
 	ut.mutex.RLock()
 	defer ut.mutex.RUnlock()
 
-	events := ut.events[serviceID]
+	return ut.calculateUptimeStats(ut.events[uptimeKey{profileID: profileID, serviceID: serviceID}])
+}
+
+// calculateUptimeStats calculates uptime statistics from a list of events (caller must hold the lock)
+func (ut *UptimeTracker) calculateUptimeStats(events []UptimeEvent) models.UptimeStatistics {
 	if len(events) == 0 {
 		return models.UptimeStatistics{
 			UptimePercentage24h: 100.0,
@@ -237,14 +252,16 @@ func (ut *UptimeTracker) calculateDowntime(events []UptimeEvent, start, end time
 	return downTime
 }
 
-// GetAllUptimeStats returns uptime statistics for all services
-func (ut *UptimeTracker) GetAllUptimeStats() map[string]models.UptimeStatistics {
+// GetProfileUptimeStats returns uptime statistics for all services recorded under a profile, keyed by service ID
+func (ut *UptimeTracker) GetProfileUptimeStats(profileID string) map[string]models.UptimeStatistics {
 	ut.mutex.RLock()
 	defer ut.mutex.RUnlock()
 
 	stats := make(map[string]models.UptimeStatistics)
-	for serviceID := range ut.events {
-		stats[serviceID] = ut.CalculateUptimeStats(serviceID, nil)
+	for key, events := range ut.events {
+		if key.profileID == profileID {
+			stats[key.serviceID] = ut.calculateUptimeStats(events)
+		}
 	}
 
 	return stats
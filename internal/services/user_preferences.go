@@ -0,0 +1,272 @@
+// Package services - Per-user UI preferences with ETag concurrency control
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zechtz/vertex/internal/models"
+)
+
+// ErrPreferencesConflict is returned when a preferences patch was based on an
+// ETag that is no longer current, e.g. because another browser tab saved first
+var ErrPreferencesConflict = errors.New("preferences were changed by another client")
+
+const (
+	maxPinnedServices   = 100
+	maxFavoriteProfiles = 50
+	maxColumnLayouts    = 50
+)
+
+// defaultUserPreferences returns the preferences of a new user
+func defaultUserPreferences() models.UserPreferences {
+	return normalizeUserPreferences(models.UserPreferences{
+		Theme:    "light",
+		Language: "en",
+		NotificationSettings: map[string]bool{
+			"serviceStatus": true,
+			"errors":        true,
+			"deployments":   true,
+		},
+		DashboardLayout: "grid",
+		AutoRefresh:     true,
+		RefreshInterval: 30,
+	})
+}
+
+// normalizeUserPreferences replaces nil maps and slices with empty ones so
+// preferences saved before a field existed encode (and hash) the same as new ones
+func normalizeUserPreferences(prefs models.UserPreferences) models.UserPreferences {
+	if prefs.NotificationSettings == nil {
+		prefs.NotificationSettings = map[string]bool{}
+	}
+	if prefs.ColumnLayouts == nil {
+		prefs.ColumnLayouts = map[string][]string{}
+	}
+	if prefs.PinnedServices == nil {
+		prefs.PinnedServices = []string{}
+	}
+	if prefs.FavoriteProfiles == nil {
+		prefs.FavoriteProfiles = []string{}
+	}
+	if prefs.DefaultLogFilters.Levels == nil {
+		prefs.DefaultLogFilters.Levels = []string{}
+	}
+	return prefs
+}
+
+// preferencesETag returns a strong ETag derived from the preferences content
+func preferencesETag(prefs models.UserPreferences) (string, error) {
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`, nil
+}
+
+// ETagMatches reports whether an If-Match or If-None-Match header value
+// names the given ETag. "*" matches any ETag and weak validators compare equal
+// to their strong form.
+func ETagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// loadUserPreferences reads a user's preferences, creating the default
+// profile row when the user has none yet. Callers hold the mutex.
+func (ps *ProfileService) loadUserPreferences(userID string) (models.UserPreferences, error) {
+	var preferencesJSON string
+	err := ps.db.QueryRow(`SELECT preferences_json FROM user_profiles WHERE user_id = ?`, userID).Scan(&preferencesJSON)
+	if err == sql.ErrNoRows {
+		prefs := defaultUserPreferences()
+		data, err := json.Marshal(prefs)
+		if err != nil {
+			return prefs, fmt.Errorf("failed to marshal default preferences: %w", err)
+		}
+		query := `INSERT INTO user_profiles (user_id, display_name, avatar, preferences_json, created_at, updated_at)
+			  VALUES (?, '', '', ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+		if _, err := ps.db.Exec(query, userID, string(data)); err != nil {
+			return prefs, fmt.Errorf("failed to create default user profile: %w", err)
+		}
+		return prefs, nil
+	}
+	if err != nil {
+		return models.UserPreferences{}, fmt.Errorf("failed to get user preferences: %w", err)
+	}
+
+	var prefs models.UserPreferences
+	if err := json.Unmarshal([]byte(preferencesJSON), &prefs); err != nil {
+		return prefs, fmt.Errorf("failed to parse preferences: %w", err)
+	}
+	return normalizeUserPreferences(prefs), nil
+}
+
+// GetUserPreferences returns a user's preferences and their current ETag
+func (ps *ProfileService) GetUserPreferences(userID string) (*models.UserPreferences, string, error) {
+	// Exclusive lock: the first read may insert the default profile
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	prefs, err := ps.loadUserPreferences(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	etag, err := preferencesETag(prefs)
+	if err != nil {
+		return nil, "", err
+	}
+	return &prefs, etag, nil
+}
+
+// PatchUserPreferences applies the fields present in patch to a user's
+// preferences. When ifMatch is non-empty the patch is rejected with
+// ErrPreferencesConflict unless it names the current ETag.
+func (ps *ProfileService) PatchUserPreferences(userID string, patch *models.UserPreferencesPatch, ifMatch string) (*models.UserPreferences, string, error) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	prefs, err := ps.loadUserPreferences(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	currentETag, err := preferencesETag(prefs)
+	if err != nil {
+		return nil, "", err
+	}
+	if ifMatch != "" && !ETagMatches(ifMatch, currentETag) {
+		return &prefs, currentETag, ErrPreferencesConflict
+	}
+
+	if err := applyPreferencesPatch(&prefs, patch); err != nil {
+		return nil, "", err
+	}
+	prefs = normalizeUserPreferences(prefs)
+
+	etag, err := preferencesETag(prefs)
+	if err != nil {
+		return nil, "", err
+	}
+	if etag == currentETag {
+		return &prefs, etag, nil
+	}
+
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal preferences: %w", err)
+	}
+	query := `UPDATE user_profiles SET preferences_json = ?, updated_at = CURRENT_TIMESTAMP WHERE user_id = ?`
+	if _, err := ps.db.Exec(query, string(data), userID); err != nil {
+		return nil, "", fmt.Errorf("failed to update user preferences: %w", err)
+	}
+
+	return &prefs, etag, nil
+}
+
+// applyPreferencesPatch validates patch and merges it into prefs
+func applyPreferencesPatch(prefs *models.UserPreferences, patch *models.UserPreferencesPatch) error {
+	if patch.Theme != nil {
+		prefs.Theme = *patch.Theme
+	}
+	if patch.Language != nil {
+		prefs.Language = *patch.Language
+	}
+	if patch.DashboardLayout != nil {
+		prefs.DashboardLayout = *patch.DashboardLayout
+	}
+	if patch.AutoRefresh != nil {
+		prefs.AutoRefresh = *patch.AutoRefresh
+	}
+	if patch.RefreshInterval != nil {
+		if *patch.RefreshInterval < 0 {
+			return fmt.Errorf("refreshInterval cannot be negative")
+		}
+		prefs.RefreshInterval = *patch.RefreshInterval
+	}
+
+	if len(patch.NotificationSettings) > 0 && prefs.NotificationSettings == nil {
+		prefs.NotificationSettings = map[string]bool{}
+	}
+	for key, enabled := range patch.NotificationSettings {
+		prefs.NotificationSettings[key] = enabled
+	}
+
+	if len(patch.ColumnLayouts) > 0 && prefs.ColumnLayouts == nil {
+		prefs.ColumnLayouts = map[string][]string{}
+	}
+	for view, columns := range patch.ColumnLayouts {
+		if strings.TrimSpace(view) == "" {
+			return fmt.Errorf("column layout view name cannot be empty")
+		}
+		if columns == nil {
+			delete(prefs.ColumnLayouts, view)
+			continue
+		}
+		prefs.ColumnLayouts[view] = uniqueNonEmpty(columns)
+	}
+	if len(prefs.ColumnLayouts) > maxColumnLayouts {
+		return fmt.Errorf("cannot store more than %d column layouts", maxColumnLayouts)
+	}
+
+	if patch.PinnedServices != nil {
+		pinned := uniqueNonEmpty(*patch.PinnedServices)
+		if len(pinned) > maxPinnedServices {
+			return fmt.Errorf("cannot pin more than %d services", maxPinnedServices)
+		}
+		prefs.PinnedServices = pinned
+	}
+
+	if patch.FavoriteProfiles != nil {
+		favorites := uniqueNonEmpty(*patch.FavoriteProfiles)
+		if len(favorites) > maxFavoriteProfiles {
+			return fmt.Errorf("cannot mark more than %d profiles as favorite", maxFavoriteProfiles)
+		}
+		prefs.FavoriteProfiles = favorites
+	}
+
+	if patch.DefaultLogFilters != nil {
+		levels := []string{}
+		for _, level := range patch.DefaultLogFilters.Levels {
+			levels = append(levels, strings.ToUpper(level))
+		}
+		levels = uniqueNonEmpty(levels)
+		for _, level := range levels {
+			switch level {
+			case "TRACE", "DEBUG", "INFO", "WARN", "ERROR":
+			default:
+				return fmt.Errorf("unknown log level '%s' (use TRACE, DEBUG, INFO, WARN or ERROR)", level)
+			}
+		}
+		prefs.DefaultLogFilters = models.LogFilterPreferences{
+			Levels: levels,
+			Search: patch.DefaultLogFilters.Search,
+		}
+	}
+
+	return nil
+}
+
+// uniqueNonEmpty trims values and drops blanks and duplicates, keeping order
+func uniqueNonEmpty(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
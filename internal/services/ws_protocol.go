@@ -0,0 +1,264 @@
+// Package services - WebSocket topic subscriptions and resumable streams
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Broadcast messages kept for clients resuming after a reconnect
+const wsReplayBufferSize = 5000
+
+// How long a write to a client may take before the client is dropped
+const wsWriteTimeout = 5 * time.Second
+
+// Topics clients can subscribe to. "logs" covers the logs of every service,
+// "logs:<service-id>" those of one service, and "*" every message.
+var wsTopicTypes = map[string][]string{
+	"services":   {"service_update"},
+	"logs":       {"log_entry"},
+	"events":     {"service_event", "crash_loop_alert"},
+	"builds":     {"build", "build_output"},
+	"tests":      {"test_run", "test_progress"},
+	"scans":      {"dependency_scan"},
+	"migrations": {"migration_run"},
+	"profiles":   {"profile_update"},
+}
+
+// wsClient is a connected websocket client. Clients that never subscribe
+// receive every message, as before topics existed.
+type wsClient struct {
+	topics     map[string]bool // nil until the first subscribe; guarded by clientsMutex
+	writeMutex sync.Mutex      // Connections allow only one writer
+}
+
+// wsSequenced is a broadcast message as sent, kept for replay
+type wsSequenced struct {
+	seq   uint64
+	topic string
+	data  json.RawMessage
+}
+
+// wsStream numbers broadcast messages and keeps the latest for replay. It is
+// guarded by the manager's clientsMutex, so messages are numbered in the
+// order clients receive them.
+type wsStream struct {
+	id     string // Changes with every Vertex run, so a client can tell its sequence numbers are void
+	seq    uint64
+	replay []wsSequenced
+}
+
+// WSClientMessage is a request sent by a websocket client:
+//
+//	{"type": "subscribe", "id": "1", "topics": ["services", "logs:<id>"], "stream": "...", "resumeFrom": 41}
+//	{"type": "unsubscribe", "id": "2", "topics": ["logs:<id>"]}
+type WSClientMessage struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id,omitempty"` // Echoed in the acknowledgement
+	Topics     []string `json:"topics"`
+	Stream     string   `json:"stream,omitempty"`     // Stream of the last message received
+	ResumeFrom uint64   `json:"resumeFrom,omitempty"` // Sequence number of the last message received
+}
+
+// WSSubscriptionAck acknowledges a subscribe or unsubscribe request
+type WSSubscriptionAck struct {
+	ID       string   `json:"id,omitempty"`
+	Topics   []string `json:"topics"` // All topics the client is now subscribed to
+	Stream   string   `json:"stream"`
+	Seq      uint64   `json:"seq"`      // Latest sequence number; messages after it follow live
+	Replayed int      `json:"replayed"` // Missed messages sent ahead of this acknowledgement
+	Gap      bool     `json:"gap"`      // Some missed messages are gone; reload to resync
+}
+
+// wsTopic is the topic of a broadcast message
+func wsTopic(message WebSocketMessage) string {
+	if message.Type == "log_entry" {
+		if entry, ok := message.Payload.(LogEntryMessage); ok {
+			return "logs:" + entry.ServiceUUID
+		}
+	}
+	for topic, types := range wsTopicTypes {
+		for _, messageType := range types {
+			if messageType == message.Type {
+				return topic
+			}
+		}
+	}
+	return message.Type
+}
+
+// wants reports whether the client receives messages of a topic
+func (c *wsClient) wants(topic string) bool {
+	if c.topics == nil || c.topics["*"] || c.topics[topic] {
+		return true
+	}
+	family, _, found := strings.Cut(topic, ":")
+	return found && c.topics[family]
+}
+
+// validateWSTopic checks that a client asked for a topic that exists
+func (sm *Manager) validateWSTopic(topic string) error {
+	if topic == "*" {
+		return nil
+	}
+	family, serviceUUID, scoped := strings.Cut(topic, ":")
+	if _, exists := wsTopicTypes[family]; !exists {
+		return fmt.Errorf("unknown topic '%s'", topic)
+	}
+	if !scoped {
+		return nil
+	}
+	if family != "logs" {
+		return fmt.Errorf("unknown topic '%s': only logs can be scoped to a service", topic)
+	}
+	if _, exists := sm.GetServiceByUUID(serviceUUID); !exists {
+		return fmt.Errorf("unknown topic '%s': service not found", topic)
+	}
+	return nil
+}
+
+// sequenceBroadcast numbers a message and keeps it for replay. The
+// clientsMutex must be held.
+func (sm *Manager) sequenceBroadcast(message *WebSocketMessage) (wsSequenced, error) {
+	stream := &sm.stream
+	stream.seq++
+	message.Seq = stream.seq
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return wsSequenced{}, err
+	}
+	sequenced := wsSequenced{seq: message.Seq, topic: wsTopic(*message), data: data}
+
+	// Drop the oldest tenth at once rather than shifting on every message
+	if len(stream.replay) >= wsReplayBufferSize {
+		stream.replay = append(stream.replay[:0], stream.replay[wsReplayBufferSize/10:]...)
+	}
+	stream.replay = append(stream.replay, sequenced)
+	return sequenced, nil
+}
+
+// WSError reports a client request that could not be applied
+type WSError struct {
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// HandleWebSocketMessage applies a subscribe or unsubscribe request of a
+// client and acknowledges it. On subscribe with resumeFrom, the buffered
+// messages after it on the client's topics are sent first. Invalid requests
+// are answered with an error message; the returned error means the client
+// could not be written to.
+func (sm *Manager) HandleWebSocketMessage(conn *websocket.Conn, data []byte) error {
+	var request WSClientMessage
+	err := json.Unmarshal(data, &request)
+	if err != nil {
+		err = fmt.Errorf("invalid message: %v", err)
+	} else if request.Type != "subscribe" && request.Type != "unsubscribe" {
+		err = fmt.Errorf("unknown message type '%s'", request.Type)
+	}
+	for _, topic := range request.Topics {
+		if err == nil {
+			err = sm.validateWSTopic(topic)
+		}
+	}
+
+	sm.clientsMutex.Lock()
+	defer sm.clientsMutex.Unlock()
+
+	client, exists := sm.clients[conn]
+	if !exists {
+		return fmt.Errorf("websocket client is not connected")
+	}
+	if err != nil {
+		return client.writeMessage(conn, WebSocketMessage{Type: "error", Payload: WSError{ID: request.ID, Message: err.Error()}})
+	}
+
+	if client.topics == nil {
+		client.topics = make(map[string]bool)
+	}
+	for _, topic := range request.Topics {
+		if request.Type == "subscribe" {
+			client.topics[topic] = true
+		} else {
+			delete(client.topics, topic)
+		}
+	}
+
+	ack := WSSubscriptionAck{ID: request.ID, Topics: []string{}, Stream: sm.stream.id, Seq: sm.stream.seq}
+	for topic := range client.topics {
+		ack.Topics = append(ack.Topics, topic)
+	}
+	sort.Strings(ack.Topics)
+
+	if request.Type == "unsubscribe" {
+		return client.writeMessage(conn, WebSocketMessage{Type: "unsubscribed", Payload: ack})
+	}
+
+	if request.ResumeFrom > 0 || request.Stream != "" {
+		var missed []json.RawMessage
+		missed, ack.Gap = sm.missedBroadcasts(client, request.Stream, request.ResumeFrom)
+		ack.Replayed = len(missed)
+		if len(missed) > 0 {
+			if err := client.writeMessage(conn, WebSocketMessage{Type: "batch", Payload: missed}); err != nil {
+				return err
+			}
+		}
+	}
+	return client.writeMessage(conn, WebSocketMessage{Type: "subscribed", Payload: ack})
+}
+
+// writeMessage encodes and sends one message to the client
+func (c *wsClient) writeMessage(conn *websocket.Conn, message WebSocketMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return c.write(conn, data)
+}
+
+// write sends data to the client, giving up after wsWriteTimeout so a stalled
+// client cannot hold up the others
+func (c *wsClient) write(conn *websocket.Conn, data []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// missedBroadcasts returns the buffered messages after resumeFrom on the
+// client's topics, and whether some are no longer buffered. The clientsMutex
+// must be held.
+func (sm *Manager) missedBroadcasts(client *wsClient, stream string, resumeFrom uint64) ([]json.RawMessage, bool) {
+	// Sequence numbers of an earlier Vertex run mean nothing now
+	if stream != sm.stream.id || resumeFrom > sm.stream.seq {
+		return nil, true
+	}
+
+	replay := sm.stream.replay
+	gap := len(replay) > 0 && replay[0].seq > resumeFrom+1
+	missed := []json.RawMessage{}
+	start := sort.Search(len(replay), func(i int) bool { return replay[i].seq > resumeFrom })
+	for _, message := range replay[start:] {
+		if client.wants(message.topic) {
+			missed = append(missed, message.data)
+		}
+	}
+	return missed, gap
+}
+
+// newStreamID returns a random identifier for this run's broadcast stream
+func newStreamID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
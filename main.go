@@ -4,8 +4,10 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -20,6 +22,8 @@ import (
 	"github.com/zechtz/vertex/internal/installer"
 	"github.com/zechtz/vertex/internal/services"
 	"github.com/zechtz/vertex/web"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // Version information (set by build flags)
@@ -56,9 +60,13 @@ func parseSubcommands() {
 		"version":   "--version",
 		"domain":    "--domain",
 		"port":      "--port",
+		"bind":      "--bind",
+		"base-path": "--base-path",
 		"data-dir":  "--data-dir",
 		"nginx":     "--nginx",
 		"https":     "--https",
+		"apply":     "--apply",
+		"verify-proxy": "--verify-proxy",
 	}
 
 	// Check if the subcommand is valid
@@ -86,6 +94,9 @@ func main() {
 	var install bool
 	var uninstall bool
 	var update bool
+	var updateFile string
+	var updateChecksum string
+	var updateInsecure bool
 	var start bool
 	var stop bool
 	var restart bool
@@ -93,14 +104,31 @@ func main() {
 	var logs bool
 	var follow bool
 	var port string
+	var bind string
+	var basePath string
 	var dataDir string
 	var enableNginx bool
 	var enableHTTPS bool
 	var domain string
+	var readTimeout time.Duration
+	var writeTimeout time.Duration
+	var idleTimeout time.Duration
+	var apply bool
+	var dryRun bool
+	var prune bool
+	var noSudo bool
+	var verifyProxy bool
+	var proxy string
+	var proxyPort int
+	var output string
+	var quiet bool
 	flag.BoolVar(&showVersion, "version", false, "Show version information")
 	flag.BoolVar(&install, "install", false, "Install Vertex as a user service")
 	flag.BoolVar(&uninstall, "uninstall", false, "Uninstall Vertex service")
 	flag.BoolVar(&update, "update", false, "Update the Vertex service")
+	flag.StringVar(&updateFile, "file", "", "Update from a local release bundle or binary (use with --update), or the configuration to apply (use with --apply)")
+	flag.StringVar(&updateChecksum, "checksum", "", "Expected SHA-256 of the update bundle (use with --file)")
+	flag.BoolVar(&updateInsecure, "insecure", false, "Install an update bundle checked by its checksum alone when this build has no signing key (use with --file)")
 	flag.BoolVar(&start, "start", false, "Start the Vertex service")
 	flag.BoolVar(&stop, "stop", false, "Stop the Vertex service")
 	flag.BoolVar(&restart, "restart", false, "Restart the Vertex service")
@@ -111,6 +139,20 @@ func main() {
 	flag.BoolVar(&enableHTTPS, "https", false, "Enable HTTPS with locally-trusted certificates (automatically enabled for .dev domains)")
 	flag.StringVar(&domain, "domain", "vertex.dev", "Domain name for nginx proxy (automatically installs with nginx when specified)")
 	flag.StringVar(&port, "port", "54321", "Port to run the server on (default: 54321)")
+	flag.StringVar(&bind, "bind", "127.0.0.1", "Address to listen on: 127.0.0.1 or ::1 for this machine only, 0.0.0.0 or :: for every network interface")
+	flag.StringVar(&basePath, "base-path", os.Getenv("VERTEX_BASE_PATH"), "Path prefix Vertex is served under behind a reverse proxy, e.g. /vertex (default from VERTEX_BASE_PATH)")
+	flag.DurationVar(&readTimeout, "read-timeout", 30*time.Second, "Maximum duration for reading an HTTP request, including the body")
+	flag.DurationVar(&writeTimeout, "write-timeout", 0, "Maximum duration for writing an HTTP response (0 disables it, needed for long-running log streams)")
+	flag.DurationVar(&idleTimeout, "idle-timeout", 120*time.Second, "How long idle keep-alive connections are kept open")
+	flag.BoolVar(&apply, "apply", false, "Apply a declarative vertex.yaml to the database")
+	flag.BoolVar(&dryRun, "dry-run", false, "Show the changes --apply would make without applying them")
+	flag.BoolVar(&prune, "prune", false, "Delete services, profiles and global env vars missing from the file (use with --apply)")
+	flag.BoolVar(&noSudo, "no-sudo", false, "Generate proxy configs into the data directory and print the privileged commands instead of running sudo (use with --install)")
+	flag.BoolVar(&verifyProxy, "verify-proxy", false, "Check the proxy setup for --domain without elevated privileges")
+	flag.StringVar(&proxy, "proxy", "nginx", "Reverse proxy for domain access: nginx or caddy (use with --install)")
+	flag.IntVar(&proxyPort, "proxy-port", 0, "Port caddy listens on instead of 80/443, so no root is needed (use with --proxy caddy)")
+	flag.StringVar(&output, "output", outputTable, "Output format of status, apply and version: table, json or yaml")
+	flag.BoolVar(&quiet, "quiet", false, "Print nothing and report the result in the exit code (use with status or apply)")
 	flag.StringVar(&dataDir, "data-dir", "", "Directory to store application data (database, logs, etc.). If not set, uses VERTEX_DATA_DIR environment variable or current directory")
 	
 	// Custom usage function to show both flag and subcommand syntax
@@ -126,30 +168,70 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  vertex install      Install Vertex as a user service\n")
 		fmt.Fprintf(os.Stderr, "  vertex uninstall    Uninstall Vertex service\n")
 		fmt.Fprintf(os.Stderr, "  vertex update       Update the Vertex service\n")
+		fmt.Fprintf(os.Stderr, "  vertex update --file <bundle>  Update from a local bundle (offline)\n")
 		fmt.Fprintf(os.Stderr, "  vertex version      Show version information\n")
+		fmt.Fprintf(os.Stderr, "  vertex apply        Apply vertex.yaml from the projects directory\n")
+		fmt.Fprintf(os.Stderr, "  vertex apply --file <path> [--dry-run] [--prune]  Apply a configuration file\n")
+		fmt.Fprintf(os.Stderr, "  vertex install --nginx --no-sudo  Install, leaving privileged proxy steps to a generated script\n")
+		fmt.Fprintf(os.Stderr, "  vertex install --proxy caddy --domain <name>  Install with a Caddy proxy instead of nginx\n")
+		fmt.Fprintf(os.Stderr, "  vertex verify-proxy        Check the proxy setup for the domain\n")
+		fmt.Fprintf(os.Stderr, "  vertex status --output json  Print the status for scripts (also apply and version; json or yaml)\n")
+		fmt.Fprintf(os.Stderr, "  vertex status --quiet       Exit 0 when Vertex answers, 1 when it does not\n")
 		fmt.Fprintf(os.Stderr, "\nSubcommands with arguments:\n")
 		fmt.Fprintf(os.Stderr, "  vertex domain <name>        Set domain and auto-install with nginx\n")
 		fmt.Fprintf(os.Stderr, "  vertex port <number>        Set port number\n")
+		fmt.Fprintf(os.Stderr, "  vertex bind <address>       Set the address to listen on\n")
+		fmt.Fprintf(os.Stderr, "  vertex base-path <path>     Serve under a path prefix behind a reverse proxy\n")
 		fmt.Fprintf(os.Stderr, "  vertex data-dir <path>      Set data directory\n")
 		fmt.Fprintf(os.Stderr, "  vertex nginx                Enable nginx proxy\n")
 		fmt.Fprintf(os.Stderr, "  vertex https                Enable HTTPS\n")
 		fmt.Fprintf(os.Stderr, "\nFlags (alternative syntax):\n")
+		fmt.Fprintf(os.Stderr, "  --apply\n")
+		fmt.Fprintf(os.Stderr, "    \tApply a declarative vertex.yaml to the database\n")
+		fmt.Fprintf(os.Stderr, "  --base-path string\n")
+		fmt.Fprintf(os.Stderr, "    \tPath prefix Vertex is served under behind a reverse proxy, e.g. /vertex (default from VERTEX_BASE_PATH)\n")
+		fmt.Fprintf(os.Stderr, "  --bind string\n")
+		fmt.Fprintf(os.Stderr, "    \tAddress to listen on: 127.0.0.1 or ::1 for this machine only, 0.0.0.0 or :: for every network interface (default \"127.0.0.1\")\n")
+		fmt.Fprintf(os.Stderr, "  --checksum string\n")
+		fmt.Fprintf(os.Stderr, "    \tExpected SHA-256 of the update bundle (use with --file)\n")
 		fmt.Fprintf(os.Stderr, "  --data-dir string\n")
 		fmt.Fprintf(os.Stderr, "    \tDirectory to store application data (database, logs, etc.). If not set, uses VERTEX_DATA_DIR environment variable or current directory\n")
 		fmt.Fprintf(os.Stderr, "  --domain string\n")
 		fmt.Fprintf(os.Stderr, "    \tDomain name for nginx proxy (automatically installs with nginx when specified) (default \"vertex.dev\")\n")
+		fmt.Fprintf(os.Stderr, "  --dry-run\n")
+		fmt.Fprintf(os.Stderr, "    \tShow the changes --apply would make without applying them\n")
+		fmt.Fprintf(os.Stderr, "  --file string\n")
+		fmt.Fprintf(os.Stderr, "    \tUpdate from a local release bundle or binary (use with --update), or the configuration to apply (use with --apply)\n")
 		fmt.Fprintf(os.Stderr, "  --follow\n")
 		fmt.Fprintf(os.Stderr, "    \tFollow log output (use with --logs)\n")
 		fmt.Fprintf(os.Stderr, "  --https\n")
 		fmt.Fprintf(os.Stderr, "    \tEnable HTTPS with locally-trusted certificates (automatically enabled for .dev domains)\n")
+		fmt.Fprintf(os.Stderr, "  --idle-timeout duration\n")
+		fmt.Fprintf(os.Stderr, "    \tHow long idle keep-alive connections are kept open (default 2m0s)\n")
+		fmt.Fprintf(os.Stderr, "  --insecure\n")
+		fmt.Fprintf(os.Stderr, "    \tInstall an update bundle checked by its checksum alone when this build has no signing key (use with --file)\n")
 		fmt.Fprintf(os.Stderr, "  --install\n")
 		fmt.Fprintf(os.Stderr, "    \tInstall Vertex as a user service\n")
 		fmt.Fprintf(os.Stderr, "  --logs\n")
 		fmt.Fprintf(os.Stderr, "    \tShow service logs\n")
 		fmt.Fprintf(os.Stderr, "  --nginx\n")
 		fmt.Fprintf(os.Stderr, "    \tConfigure nginx proxy for domain access (requires nginx to be installed)\n")
+		fmt.Fprintf(os.Stderr, "  --no-sudo\n")
+		fmt.Fprintf(os.Stderr, "    \tGenerate proxy configs into the data directory and print the privileged commands instead of running sudo (use with --install)\n")
+		fmt.Fprintf(os.Stderr, "  --output string\n")
+		fmt.Fprintf(os.Stderr, "    \tOutput format of status, apply and version: table, json or yaml (default \"table\")\n")
 		fmt.Fprintf(os.Stderr, "  --port string\n")
 		fmt.Fprintf(os.Stderr, "    \tPort to run the server on (default: 54321) (default \"54321\")\n")
+		fmt.Fprintf(os.Stderr, "  --proxy string\n")
+		fmt.Fprintf(os.Stderr, "    \tReverse proxy for domain access: nginx or caddy (use with --install) (default \"nginx\")\n")
+		fmt.Fprintf(os.Stderr, "  --proxy-port int\n")
+		fmt.Fprintf(os.Stderr, "    \tPort caddy listens on instead of 80/443, so no root is needed (use with --proxy caddy)\n")
+		fmt.Fprintf(os.Stderr, "  --prune\n")
+		fmt.Fprintf(os.Stderr, "    \tDelete services, profiles and global env vars missing from the file (use with --apply)\n")
+		fmt.Fprintf(os.Stderr, "  --quiet\n")
+		fmt.Fprintf(os.Stderr, "    \tPrint nothing and report the result in the exit code (use with status or apply)\n")
+		fmt.Fprintf(os.Stderr, "  --read-timeout duration\n")
+		fmt.Fprintf(os.Stderr, "    \tMaximum duration for reading an HTTP request, including the body (default 30s)\n")
 		fmt.Fprintf(os.Stderr, "  --restart\n")
 		fmt.Fprintf(os.Stderr, "    \tRestart the Vertex service\n")
 		fmt.Fprintf(os.Stderr, "  --start\n")
@@ -162,13 +244,43 @@ func main() {
 		fmt.Fprintf(os.Stderr, "    \tUninstall Vertex service\n")
 		fmt.Fprintf(os.Stderr, "  --update\n")
 		fmt.Fprintf(os.Stderr, "    \tUpdate the Vertex service\n")
+		fmt.Fprintf(os.Stderr, "  --verify-proxy\n")
+		fmt.Fprintf(os.Stderr, "    \tCheck the proxy setup for --domain without elevated privileges\n")
 		fmt.Fprintf(os.Stderr, "  --version\n")
 		fmt.Fprintf(os.Stderr, "    \tShow version information\n")
+		fmt.Fprintf(os.Stderr, "  --write-timeout duration\n")
+		fmt.Fprintf(os.Stderr, "    \tMaximum duration for writing an HTTP response (0 disables it, needed for long-running log streams)\n")
 	}
 	
 	flag.Parse()
 
+	bind, err := parseBindAddress(bind)
+	if err != nil {
+		log.Fatalf("Invalid --bind: %v", err)
+	}
+	basePath, err = handlers.NormalizeBasePath(basePath)
+	if err != nil {
+		log.Fatalf("Invalid --base-path: %v", err)
+	}
+	output, err = parseOutputFormat(output)
+	if err != nil {
+		log.Fatalf("Invalid --output: %v", err)
+	}
+	if quiet {
+		// Only the exit code reports the result
+		log.SetOutput(io.Discard)
+	}
+
 	if showVersion {
+		if quiet {
+			os.Exit(0)
+		}
+		if output != outputTable {
+			if err := writeOutput(output, map[string]string{"version": version, "commit": commit, "built": date}); err != nil {
+				log.Fatalf("Failed to show version: %v", err)
+			}
+			os.Exit(0)
+		}
 		fmt.Printf("Vertex %s\n", version)
 		fmt.Printf("Commit: %s\n", commit)
 		fmt.Printf("Built: %s\n", date)
@@ -176,6 +288,12 @@ func main() {
 	}
 
 	if update {
+		if updateFile != "" {
+			if err := installer.UpdateFromFile(updateFile, updateChecksum, updateInsecure); err != nil {
+				log.Fatalf("Failed to update service from %s: %v", updateFile, err)
+			}
+			os.Exit(0)
+		}
 		if err := installer.UpdateService(); err != nil {
 			log.Fatalf("Failed to update service: %v", err)
 		}
@@ -207,9 +325,16 @@ func main() {
 	}
 
 	if status {
-		if err := showStatus(); err != nil {
+		running, err := showStatus(output, quiet)
+		if err != nil {
+			if quiet {
+				os.Exit(2)
+			}
 			log.Fatalf("Failed to show status: %v", err)
 		}
+		if quiet && !running {
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
@@ -220,12 +345,39 @@ func main() {
 		os.Exit(0)
 	}
 
+	if apply {
+		if dataDir != "" {
+			os.Setenv("VERTEX_DATA_DIR", dataDir)
+		}
+		if err := applyDeclarativeConfig(updateFile, dryRun, prune, localAddress(bind, port), output, quiet); err != nil {
+			if quiet {
+				os.Exit(1)
+			}
+			log.Fatalf("Failed to apply configuration: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	if verifyProxy {
+		if dataDir != "" {
+			os.Setenv("VERTEX_DATA_DIR", dataDir)
+		}
+		if err := verifyProxySetup(domain, port, enableHTTPS || strings.HasSuffix(domain, ".dev"), proxy, proxyPort); err != nil {
+			log.Fatalf("Proxy verification failed: %v", err)
+		}
+		os.Exit(0)
+	}
+
 	// Check if domain flag was explicitly specified (smart auto-install)
 	domainWasExplicitlySet := false
+	proxyWasExplicitlySet := false
 	flag.Visit(func(f *flag.Flag) {
 		if f.Name == "domain" {
 			domainWasExplicitlySet = true
 		}
+		if f.Name == "proxy" {
+			proxyWasExplicitlySet = true
+		}
 	})
 	
 	// Auto-enable HTTPS for .dev domains (Google-owned TLD requires HTTPS)
@@ -234,27 +386,30 @@ func main() {
 		fmt.Printf("🔒 .dev domain detected (%s), automatically enabling HTTPS\n", domain)
 	}
 	
-	// Auto-install with nginx if domain is specified
+	// Auto-install with the proxy if domain is specified
 	if domainWasExplicitlySet && !install && !uninstall {
 		install = true
 		enableNginx = true
-		fmt.Printf("🌐 Domain specified (%s), automatically installing with nginx proxy\n", domain)
+		fmt.Printf("🌐 Domain specified (%s), automatically installing with %s proxy\n", domain, proxy)
 	}
 	
-	// Auto-enable nginx if HTTPS is requested
+	// Auto-enable the proxy if HTTPS is requested or a proxy is chosen
 	if enableHTTPS && !enableNginx {
 		enableNginx = true
-		fmt.Printf("🔒 HTTPS enabled, automatically configuring nginx proxy\n")
+		fmt.Printf("🔒 HTTPS enabled, automatically configuring %s proxy\n", proxy)
+	}
+	if proxyWasExplicitlySet {
+		enableNginx = true
 	}
 
 	if install {
 		// Auto-enable nginx if domain flag was explicitly specified (smart UX)
 		if domainWasExplicitlySet && !enableNginx {
 			enableNginx = true
-			fmt.Printf("🌐 Domain specified (%s), automatically enabling nginx proxy\n", domain)
+			fmt.Printf("🌐 Domain specified (%s), automatically enabling %s proxy\n", domain, proxy)
 		}
 		
-		if err := installService(enableNginx, enableHTTPS, domain); err != nil {
+		if err := installService(enableNginx, enableHTTPS, domain, noSudo, proxy, proxyPort, bind, basePath); err != nil {
 			log.Fatalf("Installation failed: %v", err)
 		}
 		fmt.Println("✅ Vertex installed successfully as a user service!")
@@ -264,7 +419,11 @@ func main() {
 			if enableHTTPS {
 				protocol = "https"
 			}
-			fmt.Printf("🌐 Access the web interface at: %s://%s\n", protocol, domain)
+			address := domain
+			if proxy == "caddy" && proxyPort > 0 {
+				address = fmt.Sprintf("%s:%d", domain, proxyPort)
+			}
+			fmt.Printf("🌐 Access the web interface at: %s://%s\n", protocol, address)
 			fmt.Printf("   Also available at: http://localhost:%s\n", port)
 		} else {
 			fmt.Printf("🌐 Access the web interface at: http://localhost:%s\n", port)
@@ -287,6 +446,9 @@ func main() {
 		os.Setenv("VERTEX_DATA_DIR", dataDir)
 	}
 
+	// Keep Vertex's own errors for /api/system/health
+	log.SetOutput(services.RecordDaemonErrors(os.Stderr))
+
 	// Display startup information
 	logMessage(fmt.Sprintf("Starting Vertex %s", version))
 	if dataDir := os.Getenv("VERTEX_DATA_DIR"); dataDir != "" {
@@ -311,8 +473,8 @@ func main() {
 		log.Printf("[INFO] Services requiring Java may fail to start")
 	}
 
-	// Automatic environment setup check with database
-	checkAndSetupEnvironment(db)
+	// Export stored global environment variables for managed services
+	loadGlobalEnvironment(db)
 
 	// Load environment variables
 	if err := config.LoadEnvironmentVariables(); err != nil {
@@ -340,29 +502,56 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to access embedded UI:", err)
 	}
-	r.PathPrefix("/").Handler(http.FileServer(http.FS(uiFS)))
+	r.PathPrefix("/").Handler(handlers.UIHandler(uiFS, basePath))
 
-	// Create HTTP server
-	serverAddr := ":" + port
+	// Create HTTP server with compression and cleartext HTTP/2 (h2c) support;
+	// TLS and h2 are terminated by nginx when the domain proxy is enabled
+	serverAddr := net.JoinHostPort(bind, port)
 	server := &http.Server{
-		Addr:    serverAddr,
-		Handler: r,
+		Addr:              serverAddr,
+		Handler:           h2c.NewHandler(handlers.CompressionMiddleware(handlers.BasePathMiddleware(basePath, r)), &http2.Server{IdleTimeout: idleTimeout}),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
 	}
 
 	// Setup graceful shutdown
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
+	logMessage(fmt.Sprintf("Starting Vertex on %s", serverAddr))
+	if basePath != "" {
+		logMessage(fmt.Sprintf("Serving Vertex under %s/", basePath))
+	}
+	if !isLoopbackBind(bind) {
+		log.Printf("[WARN] Vertex is listening on %s, so other machines on the network can reach its API and start processes as %s. Keep authentication enabled and restrict the port with a firewall.", serverAddr, os.Getenv("USER"))
+	}
+	listener, err := net.Listen("tcp", serverAddr)
+	if err != nil {
+		log.Fatal("Server failed to start:", err)
+	}
 	go func() {
-		logMessage(fmt.Sprintf("Starting Vertex on %s", serverAddr))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Fatal("Server failed to start:", err)
 		}
 	}()
 
+	// Tell systemd (Type=notify units) that we are up and keep its watchdog
+	// fed for as long as the API answers
+	if _, err := installer.SystemdNotify("READY=1"); err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+	watchdogCtx, stopWatchdog := context.WithCancel(context.Background())
+	go installer.RunSystemdWatchdog(watchdogCtx, func(ctx context.Context) error {
+		return checkSelf(ctx, localAddress(bind, port))
+	})
+
 	// Wait for interrupt signal
 	<-c
 	logMessage("Shutdown signal received, stopping all services...")
+	stopWatchdog()
+	installer.SystemdNotify("STOPPING=1")
 
 	// Stop all running services
 	sm.GracefulShutdown()
@@ -378,65 +567,201 @@ func main() {
 	}
 }
 
+// checkSelf requests a public API endpoint that touches the database, so a
+// deadlocked handler or database stops the watchdog pings
+func checkSelf(ctx context.Context, address string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", "http://"+address+"/api/setup/status", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", handlers.WatchdogUserAgent)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("self-check returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// parseBindAddress checks a --bind value, an IP address or localhost,
+// and drops the brackets of an IPv6 address
+func parseBindAddress(bind string) (string, error) {
+	bind = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(bind), "["), "]")
+	if bind == "localhost" {
+		return bind, nil
+	}
+	if net.ParseIP(bind) == nil {
+		return "", fmt.Errorf("'%s' is not an IP address; use 127.0.0.1, ::1, 0.0.0.0 or :: (or an address of this machine)", bind)
+	}
+	return bind, nil
+}
+
+// isLoopbackBind reports whether only this machine can connect
+func isLoopbackBind(bind string) bool {
+	if bind == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(bind)
+	return ip != nil && ip.IsLoopback()
+}
+
+// localAddress is the address Vertex itself connects to when listening on
+// bind: the loopback address of the same IP family for every interface
+func localAddress(bind, port string) string {
+	switch ip := net.ParseIP(bind); {
+	case ip == nil:
+		return net.JoinHostPort(bind, port)
+	case ip.Equal(net.IPv4zero):
+		return net.JoinHostPort("127.0.0.1", port)
+	case ip.Equal(net.IPv6unspecified):
+		return net.JoinHostPort("::1", port)
+	default:
+		return net.JoinHostPort(bind, port)
+	}
+}
+
 func logMessage(message string) {
 	fmt.Printf("[INFO] %s - %s\n", time.Now().Format("2006-01-02 15:04:05"), message)
 }
 
-func checkAndSetupEnvironment(db *database.Database) {
-	logMessage("Checking environment setup...")
-
-	// Get working directory
-	workingDir, err := os.Getwd()
+// loadGlobalEnvironment exports the global environment variables stored in
+// the database. First-run configuration (admin user, projects directory,
+// discovery, first profile) is done through the setup wizard at /api/setup.
+func loadGlobalEnvironment(db *database.Database) {
+	count, err := services.LoadGlobalEnvironment(db)
 	if err != nil {
-		log.Printf("Warning: Could not get working directory: %v", err)
+		log.Printf("Warning: Could not load global environment variables: %v", err)
 		return
 	}
+	logMessage(fmt.Sprintf("Loaded %d global environment variables", count))
+}
 
-	// Create environment setup instance with database
-	envSetup := services.NewEnvironmentSetup(workingDir, db)
+// applyDeclarativeConfig handles the --apply flag: it reconciles the database
+// with a vertex.yaml (by default the one in the projects directory) and
+// prints the resulting creates, updates and deletes
+func applyDeclarativeConfig(path string, dryRun, prune bool, address string, output string, quiet bool) error {
+	db, err := database.NewDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
 
-	// Initialize default environment variables in database
-	logMessage("Initializing default environment variables in database...")
-	if err := envSetup.InitializeDefaultEnvironmentVariables(); err != nil {
-		log.Printf("Warning: Failed to initialize database environment variables: %v", err)
+	sm, err := services.NewManager(config.LoadDefaultConfig(), db)
+	if err != nil {
+		return err
 	}
+	applier := services.NewConfigApplier(sm, services.NewProfileService(db, sm))
 
-	// Check environment status
-	status := envSetup.CheckEnvironmentStatus()
-	missingCount := status["missing"].(int)
-	totalCount := status["total"].(int)
+	if path == "" {
+		path = applier.DefaultConfigPath()
+	}
+	declared, err := services.LoadDeclarativeConfig(path)
+	if err != nil {
+		return err
+	}
 
-	if missingCount > 0 {
-		logMessage(fmt.Sprintf("Environment setup needed: %d/%d variables missing", missingCount, totalCount))
+	var owner string
+	if len(declared.Profiles) > 0 || prune {
+		if owner, err = applier.DefaultOwner(); err != nil {
+			return err
+		}
+	}
 
-		// Setup environment variables (now loads from database)
-		logMessage("Setting up environment variables...")
-		result := envSetup.SetupEnvironment()
-		if result.Success {
-			logMessage(fmt.Sprintf("Successfully set up %d environment variables", result.VariablesSet))
-		} else {
-			log.Printf("Warning: Environment setup failed: %s", result.Message)
+	result, err := applier.Apply(declared, owner, services.ConfigApplyOptions{DryRun: dryRun, Prune: prune})
+	if err != nil {
+		return err
+	}
+	restartRequired := false
+	if !dryRun {
+		if conn, err := net.DialTimeout("tcp", address, time.Second); err == nil {
+			conn.Close()
+			restartRequired = true
 		}
+	}
 
-		if len(result.Errors) > 0 {
-			log.Printf("Environment setup warnings: %v", result.Errors)
+	switch {
+	case quiet:
+		if result.Errors > 0 {
+			return fmt.Errorf("%d changes failed", result.Errors)
 		}
-	} else {
-		logMessage("Environment already configured")
+		return nil
+	case output != outputTable:
+		report := struct {
+			*services.ConfigApplyResult
+			RestartRequired bool `json:"restartRequired"` // Vertex is running and has not loaded the changes
+		}{result, restartRequired}
+		if err := writeOutput(output, report); err != nil {
+			return err
+		}
+		if result.Errors > 0 {
+			return fmt.Errorf("%d changes failed", result.Errors)
+		}
+		return nil
+	}
+
+	symbols := map[string]string{services.ConfigActionCreate: "+", services.ConfigActionUpdate: "~", services.ConfigActionDelete: "-"}
+	fmt.Printf("Applying %s\n\n", path)
+	for _, change := range result.Changes {
+		line := fmt.Sprintf("  %s %s %s", symbols[change.Action], change.Kind, change.Name)
+		if len(change.Fields) > 0 {
+			line += " (" + strings.Join(change.Fields, ", ") + ")"
+		}
+		switch {
+		case change.Error != "":
+			line += "  ❌ " + change.Error
+		case change.Skipped != "":
+			line += "  ⏭️  " + change.Skipped
+		}
+		fmt.Println(line)
+	}
+	if len(result.Changes) == 0 {
+		fmt.Println("  Nothing to change")
+	}
+	fmt.Printf("\n%d to create, %d to update, %d to delete, %d errors\n", result.Creates, result.Updates, result.Deletes, result.Errors)
+
+	if dryRun {
+		fmt.Println("Dry run: no changes were made")
+	} else if restartRequired {
+		fmt.Println("⚠️  Vertex is running; restart it to load the applied configuration")
 	}
+	if result.Errors > 0 {
+		return fmt.Errorf("%d changes failed", result.Errors)
+	}
+	return nil
 }
 
 // installService handles the --install flag
-func installService(enableNginx bool, enableHTTPS bool, domain string) error {
+func installService(enableNginx bool, enableHTTPS bool, domain string, noSudo bool, proxy string, proxyPort int, bind string, basePath string) error {
 	installer := installer.NewServiceInstaller()
+	installer.Bind = bind
+	installer.BasePath = basePath
 	if enableNginx {
+		if err := installer.SetProxy(proxy, proxyPort); err != nil {
+			return err
+		}
 		installer.SetDomain(domain)
 		installer.EnableNginxProxy(true)
 		installer.EnableHTTPS(enableHTTPS)
+		installer.SetNoSudo(noSudo)
 	}
 	return installer.Install()
 }
 
+// verifyProxySetup handles the --verify-proxy flag
+func verifyProxySetup(domain, port string, enableHTTPS bool, proxy string, proxyPort int) error {
+	installer := installer.NewServiceInstaller()
+	if err := installer.SetProxy(proxy, proxyPort); err != nil {
+		return err
+	}
+	installer.SetDomain(domain)
+	installer.Port = port
+	installer.EnableHTTPS(enableHTTPS)
+	return installer.VerifyProxy()
+}
+
 // uninstallService handles the --uninstall flag
 func uninstallService() error {
 	installer := installer.NewServiceInstaller()
@@ -461,10 +786,21 @@ func restartService() error {
 	return serviceManager.Restart()
 }
 
-// showStatus handles the --status flag
-func showStatus() error {
+// showStatus handles the --status flag and reports whether Vertex answers
+func showStatus(output string, quiet bool) (bool, error) {
 	serviceManager := installer.NewServiceManager()
-	return serviceManager.ShowStatus()
+	if output == outputTable && !quiet {
+		return true, serviceManager.ShowStatus()
+	}
+
+	status, err := serviceManager.Status()
+	if err != nil {
+		return false, err
+	}
+	if quiet {
+		return status.Running, nil
+	}
+	return status.Running, writeOutput(output, status)
 }
 
 // showLogs handles the --logs flag
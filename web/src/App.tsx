@@ -1,4 +1,6 @@
+import { useState } from "react";
 import { AuthContainer } from "@/components/Auth/AuthContainer";
+import { TwoFactorSetupRequired } from "@/components/Auth/TwoFactorSetupRequired";
 import { AuthProvider, useAuth } from "@/contexts/AuthContext";
 import { ThemeProvider } from "@/contexts/ThemeContext";
 import { ProfileProvider } from "@/contexts/ProfileContext";
@@ -6,9 +8,19 @@ import { ToastProvider, ToastContainer } from "@/components/ui/toast";
 import { ConfirmDialogProvider } from "@/components/ui/confirm-dialog";
 import { ErrorBoundary } from "@/components/ui/error-boundary";
 import { AuthenticatedApp } from "@/containers/AuthenticatedApp";
+import { ReadOnlyDashboard } from "@/components/ReadOnlyDashboard/ReadOnlyDashboard";
 
 function AppContent() {
-  const { isAuthenticated, isLoading, login } = useAuth();
+  const {
+    user,
+    isAuthenticated,
+    isLoading,
+    anonymousReadOnly,
+    login,
+    logout,
+    checkAuth,
+  } = useAuth();
+  const [showLogin, setShowLogin] = useState(false);
 
   if (isLoading) {
     return (
@@ -21,6 +33,10 @@ function AppContent() {
     );
   }
 
+  if (!isAuthenticated && anonymousReadOnly && !showLogin) {
+    return <ReadOnlyDashboard onSignIn={() => setShowLogin(true)} />;
+  }
+
   if (!isAuthenticated) {
     return (
       <AuthContainer
@@ -31,6 +47,17 @@ function AppContent() {
     );
   }
 
+  if (user?.twoFactorSetupRequired) {
+    return (
+      <TwoFactorSetupRequired
+        onEnabled={() => {
+          checkAuth();
+        }}
+        onSignOut={logout}
+      />
+    );
+  }
+
   return <AuthenticatedApp />;
 }
 
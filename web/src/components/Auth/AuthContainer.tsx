@@ -9,6 +9,8 @@ interface User {
   role: string;
   createdAt: string;
   lastLogin: string;
+  twoFactorEnabled?: boolean;
+  twoFactorSetupRequired?: boolean;
 }
 
 interface AuthResponse {
@@ -24,8 +26,9 @@ export function AuthContainer({ onAuthSuccess }: AuthContainerProps) {
   const [mode, setMode] = useState<'login' | 'register'>('login');
   const [isLoading, setIsLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  const [twoFactorRequired, setTwoFactorRequired] = useState(false);
 
-  const handleLogin = async (email: string, password: string) => {
+  const handleLogin = async (email: string, password: string, code?: string) => {
     setIsLoading(true);
     setError(null);
 
@@ -35,11 +38,22 @@ export function AuthContainer({ onAuthSuccess }: AuthContainerProps) {
         headers: {
           'Content-Type': 'application/json',
         },
-        body: JSON.stringify({ email, password }),
+        body: JSON.stringify({ email, password, code }),
       });
 
       if (!response.ok) {
         const errorData = await response.text();
+        // The password was right; ask for the one-time code and send both again
+        let challenge: { twoFactorRequired?: boolean } | null = null;
+        try {
+          challenge = JSON.parse(errorData);
+        } catch {
+          challenge = null;
+        }
+        if (challenge?.twoFactorRequired) {
+          setTwoFactorRequired(true);
+          return;
+        }
         throw new Error(errorData || 'Login failed');
       }
 
@@ -100,9 +114,11 @@ export function AuthContainer({ onAuthSuccess }: AuthContainerProps) {
         onSwitchToRegister={() => {
           setMode('register');
           setError(null);
+          setTwoFactorRequired(false);
         }}
         isLoading={isLoading}
         error={error}
+        twoFactorRequired={twoFactorRequired}
       />
     );
   }
@@ -1,15 +1,25 @@
 import { useState } from "react";
-import { Lock, Mail, Eye, EyeOff, LogIn, Activity } from "lucide-react";
+import {
+  Lock,
+  Mail,
+  Eye,
+  EyeOff,
+  LogIn,
+  Activity,
+  ShieldCheck,
+} from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Input } from "@/components/ui/input";
 import { Label } from "@/components/ui/label";
 
 interface LoginFormProps {
-  onLogin: (email: string, password: string) => Promise<void>;
+  onLogin: (email: string, password: string, code?: string) => Promise<void>;
   onSwitchToRegister: () => void;
   isLoading: boolean;
   error: string | null;
+  // The password was right and the account also needs a one-time code
+  twoFactorRequired?: boolean;
 }
 
 export function LoginForm({
@@ -17,15 +27,17 @@ export function LoginForm({
   onSwitchToRegister,
   isLoading,
   error,
+  twoFactorRequired = false,
 }: LoginFormProps) {
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
+  const [code, setCode] = useState("");
   const [showPassword, setShowPassword] = useState(false);
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     if (email && password) {
-      await onLogin(email, password);
+      await onLogin(email, password, twoFactorRequired ? code : undefined);
     }
   };
 
@@ -108,9 +120,36 @@ export function LoginForm({
               </div>
             </div>
 
+            {twoFactorRequired && (
+              <div className="space-y-2">
+                <Label
+                  htmlFor="code"
+                  className="text-sm font-medium text-gray-700"
+                >
+                  Two-factor Code
+                </Label>
+                <div className="relative">
+                  <ShieldCheck className="absolute left-3 top-1/2 transform -translate-y-1/2 text-gray-400 h-4 w-4" />
+                  <Input
+                    id="code"
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                    placeholder="Code from your authenticator app or a recovery code"
+                    className="pl-10 font-mono"
+                    autoComplete="one-time-code"
+                    autoFocus
+                    required
+                    disabled={isLoading}
+                  />
+                </div>
+              </div>
+            )}
+
             <Button
               type="submit"
-              disabled={isLoading || !email || !password}
+              disabled={
+                isLoading || !email || !password || (twoFactorRequired && !code)
+              }
               className="w-full bg-gradient-to-r from-blue-500 to-purple-600 hover:from-blue-600 hover:to-purple-700"
             >
               {isLoading ? (
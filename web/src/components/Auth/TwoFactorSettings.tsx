@@ -0,0 +1,234 @@
+import { useState, useEffect } from "react";
+import { ShieldCheck } from "lucide-react";
+import { Button } from "@/components/ui/button";
+import { Input } from "@/components/ui/input";
+import { Label } from "@/components/ui/label";
+import { CopyButton } from "@/components/ui/copy-button";
+
+interface TwoFactorStatus {
+  enabled: boolean;
+  pending: boolean;
+  enabledAt?: string;
+  recoveryCodesLeft: number;
+  required: boolean;
+}
+
+interface TwoFactorEnrollment {
+  secret: string;
+  otpauthUrl: string;
+}
+
+interface TwoFactorSettingsProps {
+  // Called once a code is confirmed and two-factor authentication is on
+  onEnabled?: () => void;
+}
+
+export function TwoFactorSettings({ onEnabled }: TwoFactorSettingsProps) {
+  const [status, setStatus] = useState<TwoFactorStatus | null>(null);
+  const [enrollment, setEnrollment] = useState<TwoFactorEnrollment | null>(
+    null,
+  );
+  const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null);
+  const [code, setCode] = useState("");
+  const [isBusy, setIsBusy] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  const fetchStatus = async () => {
+    const response = await fetch("/api/auth/2fa");
+    if (response.ok) {
+      setStatus(await response.json());
+    }
+  };
+
+  useEffect(() => {
+    fetchStatus();
+  }, []);
+
+  // Posts to a two-factor endpoint and returns its JSON, if any
+  const post = async (path: string, body?: object) => {
+    setIsBusy(true);
+    setError(null);
+    try {
+      const response = await fetch(path, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: body ? JSON.stringify(body) : undefined,
+      });
+      if (!response.ok) {
+        throw new Error(await response.text());
+      }
+      return response.status === 204 ? null : await response.json();
+    } finally {
+      setIsBusy(false);
+    }
+  };
+
+  const run = async (action: () => Promise<void>) => {
+    try {
+      await action();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Request failed");
+    }
+  };
+
+  const handleEnroll = () =>
+    run(async () => {
+      setEnrollment(await post("/api/auth/2fa/enroll"));
+      setCode("");
+    });
+
+  const handleConfirm = () =>
+    run(async () => {
+      const result = await post("/api/auth/2fa/confirm", { code });
+      setRecoveryCodes(result.recoveryCodes);
+      setEnrollment(null);
+      setCode("");
+      await fetchStatus();
+    });
+
+  const handleRegenerate = () =>
+    run(async () => {
+      const result = await post("/api/auth/2fa/recovery-codes", { code });
+      setRecoveryCodes(result.recoveryCodes);
+      setCode("");
+      await fetchStatus();
+    });
+
+  const handleDisable = () =>
+    run(async () => {
+      await post("/api/auth/2fa/disable", { code });
+      setCode("");
+      await fetchStatus();
+    });
+
+  if (!status) {
+    return null;
+  }
+
+  if (recoveryCodes) {
+    return (
+      <div className="space-y-3">
+        <p className="text-sm text-muted-foreground">
+          Keep these recovery codes somewhere safe. Each one signs you in once
+          if you lose your authenticator app. They are not shown again.
+        </p>
+        <div className="grid grid-cols-2 gap-1 p-3 bg-muted rounded-md font-mono text-sm">
+          {recoveryCodes.map((recoveryCode) => (
+            <span key={recoveryCode}>{recoveryCode}</span>
+          ))}
+        </div>
+        <div className="flex gap-2">
+          <CopyButton text={recoveryCodes.join("\n")} label="Copy codes" />
+          <Button
+            size="sm"
+            onClick={() => {
+              setRecoveryCodes(null);
+              if (status.enabled) {
+                onEnabled?.();
+              }
+            }}
+          >
+            Done
+          </Button>
+        </div>
+      </div>
+    );
+  }
+
+  const codeInput = (
+    <div className="space-y-1">
+      <Label htmlFor="twoFactorCode" className="text-sm">
+        {enrollment
+          ? "Code from the authenticator app"
+          : "Current code or a recovery code"}
+      </Label>
+      <Input
+        id="twoFactorCode"
+        value={code}
+        onChange={(e) => setCode(e.target.value)}
+        placeholder="123456"
+        autoComplete="one-time-code"
+        className="font-mono"
+        disabled={isBusy}
+      />
+    </div>
+  );
+
+  return (
+    <div className="space-y-3">
+      {error && (
+        <div className="p-2 bg-red-50 border border-red-200 rounded-md">
+          <p className="text-red-700 text-sm">{error}</p>
+        </div>
+      )}
+
+      {status.enabled ? (
+        <>
+          <p className="text-sm text-muted-foreground flex items-center gap-2">
+            <ShieldCheck className="h-4 w-4 text-green-600" />
+            On; you sign in with a one-time code.{" "}
+            {status.recoveryCodesLeft} recovery codes left.
+          </p>
+          {codeInput}
+          <div className="flex gap-2">
+            <Button
+              variant="outline"
+              size="sm"
+              onClick={handleRegenerate}
+              disabled={isBusy || !code}
+            >
+              New recovery codes
+            </Button>
+            <Button
+              variant="outline"
+              size="sm"
+              onClick={handleDisable}
+              disabled={isBusy || !code || status.required}
+              title={
+                status.required
+                  ? "An admin requires two-factor authentication"
+                  : undefined
+              }
+            >
+              Turn off
+            </Button>
+          </div>
+        </>
+      ) : enrollment ? (
+        <>
+          <p className="text-sm text-muted-foreground">
+            Add this account to an authenticator app: open the link on your
+            phone, or type in the secret. Then enter the code the app shows.
+          </p>
+          <div className="flex items-center gap-2">
+            <code className="flex-1 bg-muted px-2 py-1 rounded text-sm break-all">
+              {enrollment.secret}
+            </code>
+            <CopyButton text={enrollment.secret} />
+          </div>
+          <a
+            href={enrollment.otpauthUrl}
+            className="text-sm text-blue-600 hover:underline break-all"
+          >
+            {enrollment.otpauthUrl}
+          </a>
+          {codeInput}
+          <Button size="sm" onClick={handleConfirm} disabled={isBusy || !code}>
+            Turn on
+          </Button>
+        </>
+      ) : (
+        <>
+          <p className="text-sm text-muted-foreground">
+            Sign in with a one-time code from an authenticator app as well as
+            your password.
+            {status.required && " An admin requires it for every account."}
+          </p>
+          <Button size="sm" onClick={handleEnroll} disabled={isBusy}>
+            Set up two-factor authentication
+          </Button>
+        </>
+      )}
+    </div>
+  );
+}
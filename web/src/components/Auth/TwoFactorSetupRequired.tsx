@@ -0,0 +1,45 @@
+import { ShieldCheck } from "lucide-react";
+import { Button } from "@/components/ui/button";
+import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
+import { TwoFactorSettings } from "./TwoFactorSettings";
+
+interface TwoFactorSetupRequiredProps {
+  onEnabled: () => void;
+  onSignOut: () => void;
+}
+
+// Shown after signing in while admins require two-factor authentication and
+// the account has not set it up; the API answers nothing else until it does
+export function TwoFactorSetupRequired({
+  onEnabled,
+  onSignOut,
+}: TwoFactorSetupRequiredProps) {
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gradient-to-br from-blue-50 to-purple-50 p-4">
+      <Card className="w-full max-w-md shadow-xl">
+        <CardHeader className="text-center pb-4">
+          <div className="flex justify-center mb-4">
+            <div className="p-3 bg-gradient-to-br from-blue-500 to-purple-600 rounded-full">
+              <ShieldCheck className="h-8 w-8 text-white" />
+            </div>
+          </div>
+          <CardTitle className="text-2xl font-bold bg-gradient-to-r from-blue-600 to-purple-600 bg-clip-text text-transparent">
+            Set Up Two-factor Authentication
+          </CardTitle>
+          <p className="text-gray-600 text-sm">
+            An admin requires a one-time code for every account
+          </p>
+        </CardHeader>
+
+        <CardContent className="space-y-4">
+          <TwoFactorSettings onEnabled={onEnabled} />
+          <div className="text-center">
+            <Button variant="ghost" size="sm" onClick={onSignOut}>
+              Sign out
+            </Button>
+          </div>
+        </CardContent>
+      </Card>
+    </div>
+  );
+}
@@ -1,3 +1,4 @@
 export { AuthContainer } from './AuthContainer';
 export { LoginForm } from './LoginForm';
-export { RegisterForm } from './RegisterForm';
\ No newline at end of file
+export { RegisterForm } from './RegisterForm';
+export { TwoFactorSettings } from './TwoFactorSettings';
\ No newline at end of file
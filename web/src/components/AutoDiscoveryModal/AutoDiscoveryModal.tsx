@@ -29,6 +29,10 @@ interface DiscoveredService {
   properties: Record<string, string>;
   isValid: boolean;
   exists: boolean;
+  // Set for a module of a multi-module build, run from the repository at path
+  module?: string;
+  buildCommand?: string;
+  startCommand?: string;
 }
 
 interface AutoDiscoveryModalProps {
@@ -607,6 +611,14 @@ export function AutoDiscoveryModal({
                                 {service.path}
                               </span>
                             </div>
+                            {service.module && (
+                              <div className="flex items-center gap-2">
+                                <span className="font-medium">Module:</span>
+                                <span className="text-gray-600 dark:text-gray-400 font-mono text-xs">
+                                  {service.module}
+                                </span>
+                              </div>
+                            )}
                             <div className="flex items-center gap-2">
                               <span className="font-medium">Port:</span>
                               <Badge variant="outline">{service.port}</Badge>
@@ -0,0 +1,168 @@
+import { useState, useEffect } from "react";
+import { Folder, GitBranch, ArrowUp, Home } from "lucide-react";
+import { Button } from "@/components/ui/button";
+import Modal from "@/components/ui/Modal";
+
+interface DirectoryEntry {
+  name: string;
+  path: string;
+  isGitRepo: boolean;
+  buildFiles: string[];
+}
+
+interface DirectoryListing {
+  path: string;
+  parent: string; // Empty at a root
+  roots: string[];
+  entries: DirectoryEntry[];
+  truncated: boolean;
+}
+
+interface FolderPickerProps {
+  isOpen: boolean;
+  onClose: () => void;
+  onSelect: (path: string) => void;
+  initialPath?: string;
+}
+
+// FolderPicker browses the directories allowed by /api/fs/list and returns
+// the one the user picks
+export function FolderPicker({
+  isOpen,
+  onClose,
+  onSelect,
+  initialPath = "",
+}: FolderPickerProps) {
+  const [listing, setListing] = useState<DirectoryListing | null>(null);
+  const [error, setError] = useState("");
+  const [isLoading, setIsLoading] = useState(false);
+
+  const browse = async (path: string, fallbackToRoots = false) => {
+    setIsLoading(true);
+    setError("");
+    try {
+      const token = localStorage.getItem("authToken");
+      const headers: Record<string, string> = {};
+      if (token) {
+        headers["Authorization"] = `Bearer ${token}`;
+      }
+
+      const response = await fetch(
+        `/api/fs/list?path=${encodeURIComponent(path)}`,
+        { headers },
+      );
+      if (!response.ok) {
+        if (fallbackToRoots && path !== "") {
+          await browse("");
+          return;
+        }
+        setError((await response.text()).trim() || "Failed to list directory");
+        return;
+      }
+      setListing(await response.json());
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to list directory");
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    if (isOpen) {
+      browse(initialPath, true);
+    }
+  }, [isOpen]);
+
+  return (
+    <Modal isOpen={isOpen} onClose={onClose} title="Choose a Directory" size="lg">
+      <div className="space-y-3">
+        <div className="flex items-center gap-2">
+          <Button
+            type="button"
+            variant="outline"
+            size="sm"
+            onClick={() => browse("")}
+            title="Allowed roots"
+          >
+            <Home className="w-4 h-4" />
+          </Button>
+          <Button
+            type="button"
+            variant="outline"
+            size="sm"
+            disabled={!listing?.parent}
+            onClick={() => listing?.parent && browse(listing.parent)}
+            title="Parent directory"
+          >
+            <ArrowUp className="w-4 h-4" />
+          </Button>
+          <span className="text-sm font-mono text-gray-700 dark:text-gray-300 truncate">
+            {listing?.path || "Allowed roots"}
+          </span>
+        </div>
+
+        {error && (
+          <p className="text-sm text-red-600 dark:text-red-400">{error}</p>
+        )}
+
+        <div className="border border-gray-200 dark:border-gray-700 rounded-md max-h-80 overflow-y-auto divide-y divide-gray-100 dark:divide-gray-700">
+          {isLoading && (
+            <p className="p-3 text-sm text-gray-500">Loading...</p>
+          )}
+          {!isLoading && listing?.entries.length === 0 && (
+            <p className="p-3 text-sm text-gray-500">No subdirectories</p>
+          )}
+          {!isLoading &&
+            listing?.entries.map((entry) => (
+              <button
+                key={entry.path}
+                type="button"
+                onClick={() => browse(entry.path)}
+                className="w-full flex items-center gap-2 px-3 py-2 text-left text-sm hover:bg-gray-50 dark:hover:bg-gray-700"
+              >
+                <Folder className="w-4 h-4 text-blue-500 flex-shrink-0" />
+                <span className="truncate text-gray-900 dark:text-gray-100">
+                  {entry.name}
+                </span>
+                {entry.isGitRepo && (
+                  <GitBranch
+                    className="w-3 h-3 text-orange-500 flex-shrink-0"
+                    aria-label="Git repository"
+                  />
+                )}
+                {entry.buildFiles.length > 0 && (
+                  <span className="ml-auto text-xs text-gray-500 truncate">
+                    {entry.buildFiles.join(", ")}
+                  </span>
+                )}
+              </button>
+            ))}
+        </div>
+
+        {listing?.truncated && (
+          <p className="text-xs text-gray-500">
+            Only the first entries are shown.
+          </p>
+        )}
+
+        <div className="flex justify-end gap-2">
+          <Button type="button" variant="outline" onClick={onClose}>
+            Cancel
+          </Button>
+          <Button
+            type="button"
+            disabled={!listing?.path}
+            onClick={() => {
+              if (listing?.path) {
+                onSelect(listing.path);
+                onClose();
+              }
+            }}
+          >
+            Select This Directory
+          </Button>
+        </div>
+      </div>
+    </Modal>
+  );
+}
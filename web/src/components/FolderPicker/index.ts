@@ -0,0 +1 @@
+export { FolderPicker } from './FolderPicker';
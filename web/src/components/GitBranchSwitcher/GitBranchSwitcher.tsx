@@ -99,13 +99,32 @@ export function GitBranchSwitcher({
         throw new Error(errorText || `Failed to switch branch`);
       }
 
-      await response.json();
+      const data = await response.json();
+      const stashNotes: string[] = [];
+      if (data.stash?.stashed) {
+        stashNotes.push(
+          `Uncommitted changes on '${data.stash.stashed.branch}' were stashed`,
+        );
+      }
+      if (data.stash?.restored) {
+        stashNotes.push(`Changes stashed on '${branch}' were restored`);
+      }
       addToast(
         toast.success(
           "Branch switched",
-          `${serviceName} is now on branch '${branch}'`,
+          [`${serviceName} is now on branch '${branch}'`, ...stashNotes].join(
+            ". ",
+          ),
         ),
       );
+      if (data.stash?.restoreError) {
+        addToast(
+          toast.error(
+            "Stashed changes not restored",
+            data.stash.restoreError,
+          ),
+        );
+      }
 
       setIsModalOpen(false);
       // Refresh the page to update the service info
@@ -4,9 +4,12 @@ import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { Label } from "@/components/ui/label";
 import { Badge } from "@/components/ui/badge";
+import { Switch } from "@/components/ui/switch";
+import { useAuth } from "@/contexts/AuthContext";
 import { useToast, toast } from "@/components/ui/toast";
 import { ButtonSpinner } from "@/components/ui/spinner";
 import { ErrorBoundarySection } from "@/components/ui/error-boundary";
+import { TwoFactorSettings } from "@/components/Auth/TwoFactorSettings";
 
 interface GlobalConfig {
   projectsDir: string;
@@ -40,13 +43,111 @@ export function GlobalConfigModal({
   const [hasChanges, setHasChanges] = useState(false);
 
   const { addToast } = useToast();
+  const { user, anonymousReadOnly, setAnonymousReadOnly } = useAuth();
+  const [isSavingAccess, setIsSavingAccess] = useState(false);
+  const [requireTwoFactor, setRequireTwoFactor] = useState(false);
 
   useEffect(() => {
     if (isOpen) {
       fetchGlobalConfig();
+      fetchAccessSettings();
     }
   }, [isOpen]);
 
+  const fetchAccessSettings = async () => {
+    try {
+      const response = await fetch("/api/auth/access");
+      if (response.ok) {
+        const settings = await response.json();
+        setRequireTwoFactor(!!settings.requireTwoFactor);
+      }
+    } catch (error) {
+      console.error("Failed to fetch access settings:", error);
+    }
+  };
+
+  const handleRequireTwoFactorChange = async (required: boolean) => {
+    try {
+      setIsSavingAccess(true);
+      const response = await fetch("/api/auth/access", {
+        method: "PUT",
+        headers: {
+          "Content-Type": "application/json",
+        },
+        body: JSON.stringify({ requireTwoFactor: required }),
+      });
+
+      if (!response.ok) {
+        throw new Error(await response.text());
+      }
+
+      const settings = await response.json();
+      setRequireTwoFactor(settings.requireTwoFactor);
+      addToast(
+        toast.success(
+          required
+            ? "Two-factor authentication required"
+            : "Two-factor authentication optional",
+          required
+            ? "Accounts without it must set it up before they can do anything else"
+            : "Each user decides whether to sign in with a one-time code",
+        ),
+      );
+    } catch (error) {
+      addToast(
+        toast.error(
+          "Failed to change the two-factor policy",
+          error instanceof Error
+            ? error.message
+            : "An unexpected error occurred",
+        ),
+      );
+    } finally {
+      setIsSavingAccess(false);
+    }
+  };
+
+  const handleAnonymousAccessChange = async (enabled: boolean) => {
+    try {
+      setIsSavingAccess(true);
+      const response = await fetch("/api/auth/access", {
+        method: "PUT",
+        headers: {
+          "Content-Type": "application/json",
+        },
+        body: JSON.stringify({ anonymousReadOnly: enabled }),
+      });
+
+      if (!response.ok) {
+        throw new Error(await response.text());
+      }
+
+      const settings = await response.json();
+      setAnonymousReadOnly(settings.anonymousReadOnly);
+      addToast(
+        toast.success(
+          enabled
+            ? "Anonymous dashboard enabled"
+            : "Anonymous dashboard disabled",
+          enabled
+            ? "Anyone who opens Vertex can now see services, statuses and logs without signing in"
+            : "Visitors must sign in again to see anything",
+        ),
+      );
+    } catch (error) {
+      addToast(
+        toast.error(
+          "Failed to change anonymous access",
+          error instanceof Error
+            ? error.message
+            : "An unexpected error occurred",
+        ),
+      );
+    } finally {
+      setIsSavingAccess(false);
+    }
+  };
+
   useEffect(() => {
     const changed =
       config.projectsDir !== originalConfig.projectsDir ||
@@ -248,6 +349,57 @@ export function GlobalConfigModal({
                   </div>
                 </div>
 
+                {/* Anonymous Read-only Access */}
+                {user?.role === "admin" && (
+                  <div className="space-y-2">
+                    <div className="flex items-center justify-between">
+                      <Label className="text-base font-medium">
+                        Anonymous Read-only Dashboard
+                      </Label>
+                      <Switch
+                        checked={anonymousReadOnly}
+                        onCheckedChange={handleAnonymousAccessChange}
+                        disabled={isSavingAccess}
+                      />
+                    </div>
+                    <p className="text-sm text-muted-foreground">
+                      Let visitors who have not signed in see services, their
+                      status, metrics and logs, e.g. on a team screen.
+                      Environment variables stay hidden and every change still
+                      requires signing in.
+                    </p>
+                  </div>
+                )}
+
+                {/* Required Two-factor Authentication */}
+                {user?.role === "admin" && (
+                  <div className="space-y-2">
+                    <div className="flex items-center justify-between">
+                      <Label className="text-base font-medium">
+                        Require Two-factor Authentication
+                      </Label>
+                      <Switch
+                        checked={requireTwoFactor}
+                        onCheckedChange={handleRequireTwoFactorChange}
+                        disabled={isSavingAccess}
+                      />
+                    </div>
+                    <p className="text-sm text-muted-foreground">
+                      Every account signs in with a one-time code. Accounts
+                      without one must set it up before they can do anything
+                      else. Set it up for your own account first.
+                    </p>
+                  </div>
+                )}
+
+                {/* Two-factor Authentication of the signed-in user */}
+                <div className="space-y-2">
+                  <Label className="text-base font-medium">
+                    Two-factor Authentication
+                  </Label>
+                  <TwoFactorSettings />
+                </div>
+
                 {/* Onboarding Section */}
                 {onboarding && (
                   <div className="space-y-2">
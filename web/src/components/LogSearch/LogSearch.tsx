@@ -1,5 +1,12 @@
 import { useState, useEffect } from "react";
-import { Search, Download, Calendar, Trash2 } from "lucide-react";
+import {
+  Search,
+  Download,
+  Calendar,
+  Trash2,
+  ChevronDown,
+  ChevronUp,
+} from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Badge } from "@/components/ui/badge";
@@ -11,6 +18,7 @@ import { ButtonSpinner } from "@/components/ui/spinner";
 
 interface LogSearchResult {
   id: number;
+  serviceId: string;
   serviceName: string;
   timestamp: string;
   level: string;
@@ -25,6 +33,14 @@ interface LogSearchResponse {
   offset: number;
 }
 
+interface LogContext {
+  entry: LogSearchResult;
+  before: LogSearchResult[];
+  after: LogSearchResult[];
+  hasMoreBefore: boolean;
+  hasMoreAfter: boolean;
+}
+
 interface LogSearchProps {
   services: Service[];
   className?: string;
@@ -50,6 +66,9 @@ export function LogSearch({ services = [], className = "" }: LogSearchProps) {
   const [isExporting, setIsExporting] = useState(false);
   const [isClearingLogs, setIsClearingLogs] = useState(false);
   const [error, setError] = useState<string | null>(null);
+  const [contextEntryId, setContextEntryId] = useState<number | null>(null);
+  const [logContext, setLogContext] = useState<LogContext | null>(null);
+  const [isLoadingContext, setIsLoadingContext] = useState(false);
 
   const logLevels = ["INFO", "WARN", "ERROR", "DEBUG", "TRACE"];
   const resultsPerPage = 50;
@@ -191,6 +210,74 @@ export function LogSearch({ services = [], className = "" }: LogSearchProps) {
     }
   };
 
+  const contextLines = 50;
+
+  const toggleContext = async (result: LogSearchResult) => {
+    if (contextEntryId === result.id) {
+      setContextEntryId(null);
+      setLogContext(null);
+      return;
+    }
+
+    try {
+      setContextEntryId(result.id);
+      setLogContext(null);
+      setIsLoadingContext(true);
+
+      const token = localStorage.getItem("authToken");
+      if (!token) {
+        throw new Error("No authentication token");
+      }
+
+      const params = new URLSearchParams({
+        serviceId: result.serviceId,
+        id: String(result.id),
+        timestamp: result.timestamp,
+        before: String(contextLines),
+        after: String(contextLines),
+      });
+      const response = await fetch(`/api/logs/context?${params}`, {
+        headers: { Authorization: `Bearer ${token}` },
+      });
+
+      if (!response.ok) {
+        throw new Error(
+          `Loading context failed: ${response.status} ${response.statusText}`,
+        );
+      }
+
+      setLogContext(await response.json());
+    } catch (error) {
+      console.error("Log context fetch failed:", error);
+      setContextEntryId(null);
+      addToast(
+        toast.error(
+          "Failed to load context",
+          error instanceof Error ? error.message : "Unknown error",
+        ),
+      );
+    } finally {
+      setIsLoadingContext(false);
+    }
+  };
+
+  const renderContextLine = (entry: LogSearchResult, isHit = false) => (
+    <div
+      key={entry.id}
+      className={`flex items-start space-x-3 px-2 py-0.5 text-xs font-mono ${
+        isHit
+          ? "bg-yellow-100 dark:bg-yellow-900/40"
+          : "text-gray-700 dark:text-gray-300"
+      }`}
+    >
+      <span className="flex-shrink-0 w-24 text-gray-500 dark:text-gray-400">
+        {new Date(entry.timestamp).toLocaleTimeString()}
+      </span>
+      <span className="flex-shrink-0 w-12">{entry.level}</span>
+      <span className="flex-1 break-all">{entry.message}</span>
+    </div>
+  );
+
   const clearLogs = async () => {
     const servicesToClear = selectedServices.length > 0 
       ? selectedServices 
@@ -511,24 +598,64 @@ export function LogSearch({ services = [], className = "" }: LogSearchProps) {
               {/* Results List */}
               <div className="space-y-2">
                 {searchResults.map((result) => (
-                  <div
-                    key={result.id}
-                    className="flex items-start space-x-3 p-3 bg-gray-50 dark:bg-gray-700 rounded-lg hover:bg-gray-100 dark:hover:bg-gray-600 transition-colors"
-                  >
-                    <div className="flex-shrink-0 text-xs text-gray-500 dark:text-gray-400 w-32">
-                      {new Date(result.timestamp).toLocaleString()}
-                    </div>
-                    <Badge variant="outline" className="flex-shrink-0">
-                      {result.serviceName}
-                    </Badge>
-                    <Badge
-                      className={`flex-shrink-0 text-xs ${getLevelColor(result.level)}`}
+                  <div key={result.id}>
+                    <div
+                      className="flex items-start space-x-3 p-3 bg-gray-50 dark:bg-gray-700 rounded-lg hover:bg-gray-100 dark:hover:bg-gray-600 transition-colors"
                     >
-                      {result.level}
-                    </Badge>
-                    <div className="flex-1 text-sm font-mono text-gray-800 dark:text-gray-200 break-all">
-                      {highlightSearchTerm(result.message, searchText)}
+                      <div className="flex-shrink-0 text-xs text-gray-500 dark:text-gray-400 w-32">
+                        {new Date(result.timestamp).toLocaleString()}
+                      </div>
+                      <Badge variant="outline" className="flex-shrink-0">
+                        {result.serviceName}
+                      </Badge>
+                      <Badge
+                        className={`flex-shrink-0 text-xs ${getLevelColor(result.level)}`}
+                      >
+                        {result.level}
+                      </Badge>
+                      <div className="flex-1 text-sm font-mono text-gray-800 dark:text-gray-200 break-all">
+                        {highlightSearchTerm(result.message, searchText)}
+                      </div>
+                      <Button
+                        variant="ghost"
+                        size="sm"
+                        className="flex-shrink-0 h-6 px-2 text-xs"
+                        onClick={() => toggleContext(result)}
+                        title="Show surrounding log lines"
+                      >
+                        {contextEntryId === result.id ? (
+                          <ChevronUp className="h-3 w-3 mr-1" />
+                        ) : (
+                          <ChevronDown className="h-3 w-3 mr-1" />
+                        )}
+                        Context
+                      </Button>
                     </div>
+                    {contextEntryId === result.id && (
+                      <div className="mt-1 ml-4 border-l-2 border-blue-200 dark:border-blue-800 max-h-96 overflow-y-auto">
+                        {isLoadingContext || !logContext ? (
+                          <div className="px-2 py-1 text-xs text-gray-500">
+                            Loading context...
+                          </div>
+                        ) : (
+                          <>
+                            {logContext.hasMoreBefore && (
+                              <div className="px-2 text-xs text-gray-400">…</div>
+                            )}
+                            {logContext.before.map((entry) =>
+                              renderContextLine(entry),
+                            )}
+                            {renderContextLine(logContext.entry, true)}
+                            {logContext.after.map((entry) =>
+                              renderContextLine(entry),
+                            )}
+                            {logContext.hasMoreAfter && (
+                              <div className="px-2 text-xs text-gray-400">…</div>
+                            )}
+                          </>
+                        )}
+                      </div>
+                    )}
                   </div>
                 ))}
               </div>
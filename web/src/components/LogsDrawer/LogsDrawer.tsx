@@ -19,6 +19,7 @@ import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { Service } from "@/types";
 import { ButtonSpinner } from "@/components/ui/spinner";
+import { ServiceInput } from "./ServiceInput";
 
 interface LogsDrawerProps {
   selectedService: Service | null;
@@ -324,6 +325,13 @@ export function LogsDrawer({
               </div>
             </div>
           )}
+
+          {/* Input for interactive services while they run */}
+          {isExpanded &&
+            selectedService.interactive &&
+            selectedService.status === "running" && (
+              <ServiceInput service={selectedService} />
+            )}
         </div>
       </div>
     </>
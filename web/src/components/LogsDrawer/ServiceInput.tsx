@@ -0,0 +1,64 @@
+import { useState } from "react";
+import { ChevronRight } from "lucide-react";
+import { Input } from "@/components/ui/input";
+import { Service } from "@/types";
+
+interface ServiceInputProps {
+  service: Service;
+}
+
+// ServiceInput sends lines typed by the user to the stdin of an interactive
+// service; what it prints in response shows up in the logs above
+export function ServiceInput({ service }: ServiceInputProps) {
+  const [input, setInput] = useState("");
+  const [isSending, setIsSending] = useState(false);
+  const [error, setError] = useState<string | null>(null);
+
+  const sendInput = async () => {
+    try {
+      setIsSending(true);
+      setError(null);
+      const token = localStorage.getItem("authToken");
+      const response = await fetch(`/api/services/${service.id}/stdin`, {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          ...(token ? { Authorization: `Bearer ${token}` } : {}),
+        },
+        body: JSON.stringify({ input }),
+      });
+      if (!response.ok) {
+        throw new Error((await response.text()).trim() || response.statusText);
+      }
+      setInput("");
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to send input");
+    } finally {
+      setIsSending(false);
+    }
+  };
+
+  return (
+    <div className="border-t border-gray-200 dark:border-gray-700 px-6 py-3">
+      <form
+        className="flex items-center gap-2"
+        onSubmit={(e) => {
+          e.preventDefault();
+          if (!isSending) {
+            sendInput();
+          }
+        }}
+      >
+        <ChevronRight className="h-4 w-4 text-gray-400" />
+        <Input
+          value={input}
+          onChange={(e) => setInput(e.target.value)}
+          placeholder={`Send input to ${service.name} (Enter to send)`}
+          className="h-8 font-mono text-xs"
+          disabled={isSending}
+        />
+      </form>
+      {error && <p className="mt-1 text-xs text-red-500">{error}</p>}
+    </div>
+  );
+}
@@ -24,6 +24,10 @@ interface DiscoveredService {
   properties: Record<string, string>;
   isValid: boolean;
   exists: boolean;
+  // Set for a module of a multi-module build, run from the repository at path
+  module?: string;
+  buildCommand?: string;
+  startCommand?: string;
 }
 
 interface OnboardingDiscoveryStepProps {
@@ -148,6 +152,9 @@ export function OnboardingDiscoveryStep({
           framework: service.framework,
           description: service.description,
           properties: service.properties,
+          module: service.module,
+          buildCommand: service.buildCommand,
+          startCommand: service.startCommand,
           profileId: profile.id,
         }));
 
@@ -317,6 +324,7 @@ export function OnboardingDiscoveryStep({
                         <span>Port: {service.port}</span>
                         <span>Type: {service.type}</span>
                         <span>Path: {service.path}</span>
+                        {service.module && <span>Module: {service.module}</span>}
                       </div>
                     </div>
                   </div>
@@ -7,6 +7,7 @@ import {
   Star,
   Search,
   Upload,
+  FolderOpen,
 } from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
@@ -15,6 +16,7 @@ import { CreateProfileRequest, Service } from "@/types";
 import { useToast, toast } from "@/components/ui/toast";
 import Modal from "@/components/ui/Modal";
 import { BulkImportModal } from "@/components/EnvironmentVariables/BulkImportModal";
+import { FolderPicker } from "@/components/FolderPicker";
 
 interface CreateProfileModalProps {
   isOpen: boolean;
@@ -40,6 +42,7 @@ export function CreateProfileModal({
   const [envVarKey, setEnvVarKey] = useState("");
   const [envVarValue, setEnvVarValue] = useState("");
   const [isBulkImportOpen, setIsBulkImportOpen] = useState(false);
+  const [isFolderPickerOpen, setIsFolderPickerOpen] = useState(false);
 
   // Fetch available services
   useEffect(() => {
@@ -234,18 +237,28 @@ export function CreateProfileModal({
                 <label className="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-2">
                   Projects Directory
                 </label>
-                <Input
-                  type="text"
-                  value={formData.projectsDir}
-                  onChange={(e) =>
-                    setFormData((prev) => ({
-                      ...prev,
-                      projectsDir: e.target.value,
-                    }))
-                  }
-                  placeholder="/path/to/your/projects"
-                  className="w-full"
-                />
+                <div className="flex gap-2">
+                  <Input
+                    type="text"
+                    value={formData.projectsDir}
+                    onChange={(e) =>
+                      setFormData((prev) => ({
+                        ...prev,
+                        projectsDir: e.target.value,
+                      }))
+                    }
+                    placeholder="/path/to/your/projects"
+                    className="w-full"
+                  />
+                  <Button
+                    type="button"
+                    variant="outline"
+                    onClick={() => setIsFolderPickerOpen(true)}
+                    title="Browse directories"
+                  >
+                    <FolderOpen className="w-4 h-4" />
+                  </Button>
+                </div>
                 <p className="text-xs text-gray-500 mt-1">
                   Directory where your project files are located
                 </p>
@@ -515,6 +528,15 @@ export function CreateProfileModal({
         onClose={() => setIsBulkImportOpen(false)}
         onImport={handleBulkImport}
       />
+
+      <FolderPicker
+        isOpen={isFolderPickerOpen}
+        onClose={() => setIsFolderPickerOpen(false)}
+        onSelect={(path) =>
+          setFormData((prev) => ({ ...prev, projectsDir: path }))
+        }
+        initialPath={formData.projectsDir}
+      />
     </Modal>
   );
 }
@@ -1,11 +1,12 @@
 import { useState, useEffect } from "react";
-import { X, Plus, Trash2, Server, Settings, Star, Save, Upload } from "lucide-react";
+import { X, Plus, Trash2, Server, Settings, Star, Save, Upload, FolderOpen } from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { useProfile } from "@/contexts/ProfileContext";
 import { UpdateProfileRequest, Service, ServiceProfile } from "@/types";
 import { useToast, toast } from "@/components/ui/toast";
 import { BulkImportModal } from "@/components/EnvironmentVariables/BulkImportModal";
+import { FolderPicker } from "@/components/FolderPicker";
 
 interface EditProfileModalProps {
   isOpen: boolean;
@@ -33,6 +34,7 @@ export function EditProfileModal({
   const [envVarKey, setEnvVarKey] = useState("");
   const [envVarValue, setEnvVarValue] = useState("");
   const [isBulkImportOpen, setIsBulkImportOpen] = useState(false);
+  const [isFolderPickerOpen, setIsFolderPickerOpen] = useState(false);
 
   // Initialize form data when profile changes
   useEffect(() => {
@@ -263,18 +265,28 @@ export function EditProfileModal({
                   <label className="block text-sm font-medium text-gray-700 dark:text-gray-300 mb-2">
                     Projects Directory
                   </label>
-                  <Input
-                    type="text"
-                    value={formData.projectsDir}
-                    onChange={(e) =>
-                      setFormData((prev) => ({
-                        ...prev,
-                        projectsDir: e.target.value,
-                      }))
-                    }
-                    placeholder="/path/to/your/projects"
-                    className="w-full"
-                  />
+                  <div className="flex gap-2">
+                    <Input
+                      type="text"
+                      value={formData.projectsDir}
+                      onChange={(e) =>
+                        setFormData((prev) => ({
+                          ...prev,
+                          projectsDir: e.target.value,
+                        }))
+                      }
+                      placeholder="/path/to/your/projects"
+                      className="w-full"
+                    />
+                    <Button
+                      type="button"
+                      variant="outline"
+                      onClick={() => setIsFolderPickerOpen(true)}
+                      title="Browse directories"
+                    >
+                      <FolderOpen className="w-4 h-4" />
+                    </Button>
+                  </div>
                   <p className="text-xs text-gray-500 mt-1">
                     Directory where your project files are located
                   </p>
@@ -466,6 +478,15 @@ export function EditProfileModal({
         onClose={() => setIsBulkImportOpen(false)}
         onImport={handleBulkImport}
       />
+
+      <FolderPicker
+        isOpen={isFolderPickerOpen}
+        onClose={() => setIsFolderPickerOpen(false)}
+        onSelect={(path) =>
+          setFormData((prev) => ({ ...prev, projectsDir: path }))
+        }
+        initialPath={formData.projectsDir}
+      />
     </div>
   );
 }
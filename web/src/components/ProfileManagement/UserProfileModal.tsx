@@ -32,6 +32,10 @@ export function UserProfileModal({ isOpen, onClose }: UserProfileModalProps) {
       dashboardLayout: 'grid',
       autoRefresh: true,
       refreshInterval: 30,
+      columnLayouts: {},
+      pinnedServices: [],
+      defaultLogFilters: { levels: [], search: '' },
+      favoriteProfiles: [],
     },
   });
 
@@ -70,6 +74,10 @@ export function UserProfileModal({ isOpen, onClose }: UserProfileModalProps) {
           dashboardLayout: 'grid',
           autoRefresh: true,
           refreshInterval: 30,
+          columnLayouts: {},
+          pinnedServices: [],
+          defaultLogFilters: { levels: [], search: '' },
+          favoriteProfiles: [],
         },
       });
     }
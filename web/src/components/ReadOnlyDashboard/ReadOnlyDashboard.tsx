@@ -0,0 +1,223 @@
+import { useEffect, useState } from "react";
+import { Eye, LogIn, RefreshCw, X } from "lucide-react";
+import { Button } from "@/components/ui/button";
+import {
+  Card,
+  CardContent,
+  CardHeader,
+  CardTitle,
+} from "@/components/ui/card";
+import {
+  formatBytes,
+  formatPercentage,
+  formatUptime,
+  getHealthStatusColor,
+  getServiceStatusColor,
+} from "@/utils/formatters";
+
+interface DashboardService {
+  id: string;
+  name: string;
+  port: number;
+  status: string;
+  healthStatus: string;
+  uptime: string;
+  cpuPercent: number;
+  memoryUsage: number;
+  gitBranch?: string;
+}
+
+interface DashboardLogEntry {
+  timestamp: string;
+  level: string;
+  message: string;
+}
+
+interface ReadOnlyDashboardProps {
+  onSignIn: () => void;
+}
+
+const REFRESH_INTERVAL_MS = 5000;
+
+// ReadOnlyDashboard shows every service, its status and its logs to visitors
+// who have not signed in, for team screens. It only reads; changes need a login.
+export function ReadOnlyDashboard({ onSignIn }: ReadOnlyDashboardProps) {
+  const [services, setServices] = useState<DashboardService[]>([]);
+  const [error, setError] = useState<string | null>(null);
+  const [lastUpdated, setLastUpdated] = useState<Date | null>(null);
+  const [selected, setSelected] = useState<DashboardService | null>(null);
+  const [logs, setLogs] = useState<DashboardLogEntry[]>([]);
+
+  useEffect(() => {
+    const fetchServices = async () => {
+      try {
+        const response = await fetch("/api/services");
+        if (!response.ok) {
+          throw new Error(
+            `Failed to fetch services: ${response.status} ${response.statusText}`,
+          );
+        }
+        setServices(await response.json());
+        setLastUpdated(new Date());
+        setError(null);
+      } catch (err) {
+        setError(err instanceof Error ? err.message : "Failed to fetch services");
+      }
+    };
+
+    fetchServices();
+    const interval = setInterval(fetchServices, REFRESH_INTERVAL_MS);
+    return () => clearInterval(interval);
+  }, []);
+
+  useEffect(() => {
+    if (!selected) {
+      setLogs([]);
+      return;
+    }
+
+    const fetchLogs = async () => {
+      try {
+        const response = await fetch(`/api/services/${selected.id}/logs`);
+        if (response.ok) {
+          const data = await response.json();
+          setLogs((data.logs || []).slice(-200));
+        }
+      } catch (err) {
+        console.error("Failed to fetch logs:", err);
+      }
+    };
+
+    fetchLogs();
+    const interval = setInterval(fetchLogs, REFRESH_INTERVAL_MS);
+    return () => clearInterval(interval);
+  }, [selected?.id]);
+
+  const running = services.filter((s) => s.status === "running").length;
+  const unhealthy = services.filter(
+    (s) => s.status === "running" && s.healthStatus === "unhealthy",
+  ).length;
+
+  return (
+    <div className="min-h-screen bg-gray-50 dark:bg-gray-900">
+      <header className="flex items-center justify-between px-6 py-4 bg-white dark:bg-gray-800 border-b">
+        <div>
+          <h1 className="text-xl font-semibold flex items-center gap-2">
+            <Eye className="h-5 w-5 text-blue-600" />
+            Vertex Dashboard
+          </h1>
+          <p className="text-sm text-muted-foreground">
+            Read-only view · {running} of {services.length} running
+            {unhealthy > 0 && ` · ${unhealthy} unhealthy`}
+            {lastUpdated && ` · updated ${lastUpdated.toLocaleTimeString()}`}
+          </p>
+        </div>
+        <Button onClick={onSignIn} className="flex items-center gap-2">
+          <LogIn className="h-4 w-4" />
+          Sign in to manage
+        </Button>
+      </header>
+
+      <main className="p-6">
+        {error && (
+          <div className="mb-4 p-3 rounded-lg bg-red-50 text-red-700 text-sm flex items-center gap-2">
+            <RefreshCw className="h-4 w-4" />
+            {error}
+          </div>
+        )}
+
+        <div className="grid gap-4 sm:grid-cols-2 lg:grid-cols-3 xl:grid-cols-4">
+          {services.map((service) => (
+            <Card
+              key={service.id}
+              className="cursor-pointer hover:shadow-md transition-shadow"
+              onClick={() => setSelected(service)}
+            >
+              <CardHeader className="pb-2">
+                <CardTitle className="text-base flex items-center justify-between gap-2">
+                  <span className="truncate">{service.name}</span>
+                  <span
+                    className={`text-xs px-2 py-0.5 rounded-full ${getServiceStatusColor(service.status)}`}
+                  >
+                    {service.status}
+                  </span>
+                </CardTitle>
+              </CardHeader>
+              <CardContent className="text-sm space-y-1">
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Health</span>
+                  <span
+                    className={`text-xs px-2 py-0.5 rounded-full ${getHealthStatusColor(service.healthStatus || "unknown")}`}
+                  >
+                    {service.healthStatus || "unknown"}
+                  </span>
+                </div>
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Port</span>
+                  <span>{service.port}</span>
+                </div>
+                {service.status === "running" && (
+                  <>
+                    <div className="flex justify-between">
+                      <span className="text-muted-foreground">Uptime</span>
+                      <span>{formatUptime(service.uptime)}</span>
+                    </div>
+                    <div className="flex justify-between">
+                      <span className="text-muted-foreground">CPU / Memory</span>
+                      <span>
+                        {formatPercentage(service.cpuPercent)} ·{" "}
+                        {formatBytes(service.memoryUsage)}
+                      </span>
+                    </div>
+                  </>
+                )}
+                {service.gitBranch && (
+                  <div className="flex justify-between">
+                    <span className="text-muted-foreground">Branch</span>
+                    <span className="truncate">{service.gitBranch}</span>
+                  </div>
+                )}
+              </CardContent>
+            </Card>
+          ))}
+        </div>
+      </main>
+
+      {selected && (
+        <div className="fixed inset-0 bg-black bg-opacity-50 flex items-center justify-center z-50">
+          <div className="bg-white dark:bg-gray-800 rounded-lg shadow-xl w-full max-w-4xl max-h-[85vh] flex flex-col">
+            <div className="flex items-center justify-between p-4 border-b">
+              <h2 className="text-lg font-semibold">{selected.name} logs</h2>
+              <Button variant="ghost" size="sm" onClick={() => setSelected(null)}>
+                <X className="h-4 w-4" />
+              </Button>
+            </div>
+            <div className="flex-1 overflow-y-auto p-4 bg-gray-900 text-gray-100 font-mono text-xs">
+              {logs.length === 0 ? (
+                <p className="text-gray-400">No logs yet</p>
+              ) : (
+                logs.map((entry, index) => (
+                  <div key={index} className="whitespace-pre-wrap break-all">
+                    <span className="text-gray-500">{entry.timestamp}</span>{" "}
+                    <span
+                      className={
+                        entry.level === "ERROR"
+                          ? "text-red-400"
+                          : entry.level === "WARN"
+                            ? "text-yellow-400"
+                            : "text-blue-300"
+                      }
+                    >
+                      {entry.level}
+                    </span>{" "}
+                    {entry.message}
+                  </div>
+                ))
+              )}
+            </div>
+          </div>
+        </div>
+      )}
+    </div>
+  );
+}
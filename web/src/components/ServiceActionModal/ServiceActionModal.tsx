@@ -1,6 +1,8 @@
+import { useEffect, useState } from "react";
 import { AlertTriangle, X, Trash2, UserMinus, Info } from "lucide-react";
 import { Button } from "@/components/ui/button";
-import { Service, ServiceProfile } from "@/types";
+import { Service, ServiceDeletionImpact, ServiceProfile } from "@/types";
+import { ServiceOperations } from "@/services/serviceOperations";
 
 interface ServiceActionModalProps {
   isOpen: boolean;
@@ -8,7 +10,10 @@ interface ServiceActionModalProps {
   service: Service | null;
   activeProfile: ServiceProfile | null;
   onRemoveFromProfile: (serviceName: string) => Promise<void>;
-  onDeleteGlobally: (serviceName: string) => Promise<void>;
+  onDeleteGlobally: (
+    serviceId: string,
+    confirmationToken?: string,
+  ) => Promise<void>;
 }
 
 export function ServiceActionModal({
@@ -19,6 +24,31 @@ export function ServiceActionModal({
   onRemoveFromProfile,
   onDeleteGlobally,
 }: ServiceActionModalProps) {
+  const [impact, setImpact] = useState<ServiceDeletionImpact | null>(null);
+  const [impactError, setImpactError] = useState<string | null>(null);
+  const serviceId = service?.id;
+
+  // Load what deleting the service would affect each time the modal opens
+  useEffect(() => {
+    if (!isOpen || !serviceId) return;
+    let cancelled = false;
+    setImpact(null);
+    setImpactError(null);
+    ServiceOperations.getDeletionImpact(serviceId)
+      .then((result) => {
+        if (!cancelled) setImpact(result);
+      })
+      .catch((error) => {
+        if (!cancelled)
+          setImpactError(
+            error instanceof Error ? error.message : "Failed to load impact",
+          );
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [isOpen, serviceId]);
+
   if (!isOpen || !service) return null;
 
   const handleRemoveFromProfile = async () => {
@@ -27,7 +57,7 @@ export function ServiceActionModal({
   };
 
   const handleDeleteGlobally = async () => {
-    await onDeleteGlobally(service.id);
+    await onDeleteGlobally(service.id, impact?.confirmationToken);
     onClose();
   };
 
@@ -99,8 +129,47 @@ export function ServiceActionModal({
                     Permanently delete "{service.name}" from the entire system.
                     This will remove it from all profiles and cannot be undone.
                   </p>
+                  {impactError && (
+                    <p className="text-xs text-red-700 dark:text-red-300 mt-2">
+                      {impactError}
+                    </p>
+                  )}
+                  {!impact && !impactError && (
+                    <p className="text-xs text-red-700 dark:text-red-300 mt-2">
+                      Checking what this affects...
+                    </p>
+                  )}
+                  {impact && !impact.empty && (
+                    <ul className="text-xs text-red-800 dark:text-red-200 mt-2 space-y-1 list-disc pl-4">
+                      {impact.running && (
+                        <li>It is running and will be stopped</li>
+                      )}
+                      {impact.profiles.length > 0 && (
+                        <li>
+                          Listed in{" "}
+                          {impact.profiles.length === 1 ? "profile" : "profiles"}{" "}
+                          {impact.profiles.map((p) => p.name).join(", ")}
+                        </li>
+                      )}
+                      {impact.dependents.map((d) => (
+                        <li key={`${d.profileId ?? ""}/${d.serviceId}`}>
+                          <strong>{d.serviceName || d.serviceId}</strong>:{" "}
+                          {d.behavior}
+                        </li>
+                      ))}
+                      {impact.storedData.length > 0 && (
+                        <li>
+                          Loses{" "}
+                          {impact.storedData
+                            .map((d) => `${d.count} ${d.label.toLowerCase()}`)
+                            .join(", ")}
+                        </li>
+                      )}
+                    </ul>
+                  )}
                   <Button
                     onClick={handleDeleteGlobally}
+                    disabled={!impact}
                     variant="outline"
                     className="mt-3 border-red-300 text-red-700 hover:bg-red-100 dark:border-red-600 dark:text-red-400 dark:hover:bg-red-900/30"
                     size="sm"
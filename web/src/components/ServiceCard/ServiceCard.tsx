@@ -20,6 +20,7 @@ import {
   Package,
   MoreVertical,
   Wrench,
+  Pause,
 } from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Card, CardContent } from "@/components/ui/card";
@@ -35,6 +36,8 @@ interface ServiceCardProps {
     starting?: boolean;
     stopping?: boolean;
     restarting?: boolean;
+    pausing?: boolean;
+    resuming?: boolean;
     checkingHealth?: boolean;
     installingLibraries?: boolean;
     validatingWrapper?: boolean;
@@ -44,6 +47,8 @@ interface ServiceCardProps {
   onStart: () => void;
   onStop: () => void;
   onRestart: () => void;
+  onPause: () => void;
+  onResume: () => void;
   onCheckHealth: () => void;
   onViewLogs: () => void;
   onEdit: () => void;
@@ -60,6 +65,8 @@ export function ServiceCard({
   onStart,
   onStop,
   onRestart,
+  onPause,
+  onResume,
   onCheckHealth,
   onViewLogs,
   onEdit,
@@ -91,6 +98,9 @@ export function ServiceCard({
 
   const getStatusColor = () => {
     if (service.status === "running") {
+      if (service.flapping) {
+        return "bg-orange-500";
+      }
       switch (service.healthStatus) {
         case "healthy":
           return "bg-green-500";
@@ -102,11 +112,17 @@ export function ServiceCard({
           return "bg-blue-500";
       }
     }
+    if (service.status === "paused") {
+      return "bg-amber-500";
+    }
     return "bg-gray-400";
   };
 
   const getStatusText = () => {
     if (service.status === "running") {
+      if (service.flapping) {
+        return "Flapping";
+      }
       switch (service.healthStatus) {
         case "healthy":
           return "Healthy";
@@ -118,6 +134,9 @@ export function ServiceCard({
           return "Running";
       }
     }
+    if (service.status === "paused") {
+      return "Paused";
+    }
     return "Stopped";
   };
 
@@ -149,6 +168,9 @@ export function ServiceCard({
           return <Activity className="w-4 h-4 text-blue-500" />;
       }
     }
+    if (service.status === "paused") {
+      return <Pause className="w-4 h-4 text-amber-500" />;
+    }
     return <Square className="w-4 h-4 text-gray-400" />;
   };
 
@@ -165,6 +187,9 @@ export function ServiceCard({
           return "border-l-blue-500";
       }
     }
+    if (service.status === "paused") {
+      return "border-l-amber-500";
+    }
     return "border-l-gray-300";
   };
 
@@ -222,7 +247,9 @@ export function ServiceCard({
                             : service.healthStatus === "starting"
                               ? "text-yellow-600"
                               : "text-blue-600"
-                        : "text-gray-500"
+                        : service.status === "paused"
+                          ? "text-amber-600"
+                          : "text-gray-500"
                     }`}
                   >
                     {getStatusText()}
@@ -315,6 +342,20 @@ export function ServiceCard({
                     Manage Wrappers
                   </button>
 
+                  {service.status === "running" && (
+                    <button
+                      onClick={() => {
+                        onPause();
+                        setShowDropdown(false);
+                      }}
+                      disabled={isLoading}
+                      className="w-full px-3 py-2 text-left text-xs text-gray-700 dark:text-gray-300 hover:bg-gray-50 dark:hover:bg-gray-700 flex items-center gap-2"
+                    >
+                      <Pause className="w-3 h-3" />
+                      Pause Service
+                    </button>
+                  )}
+
                   <hr className="my-1 border-gray-100 dark:border-gray-700" />
 
                   <button
@@ -415,7 +456,8 @@ export function ServiceCard({
           <div className="px-5 py-3 border-b border-gray-100 dark:border-gray-700">
             <div className="text-center text-sm text-gray-500 dark:text-gray-400">
               <Clock className="w-4 h-4 mx-auto mb-1 text-gray-400" />
-              Service stopped • No metrics available
+              {service.status === "paused" ? "Service paused" : "Service stopped"} •
+              No metrics available
             </div>
           </div>
         )}
@@ -457,6 +499,38 @@ export function ServiceCard({
                   </span>
                 </Button>
               </>
+            ) : service.status === "paused" ? (
+              <>
+                <Button
+                  onClick={onResume}
+                  disabled={isLoading}
+                  className="flex-1 h-10 bg-amber-500 hover:bg-amber-600 font-medium"
+                >
+                  {loadingStates.resuming ? (
+                    <Loader className="w-4 h-4 animate-spin text-white" />
+                  ) : (
+                    <Play className="w-4 h-4" />
+                  )}
+                  <span className="ml-2">
+                    {loadingStates.resuming ? "Resuming..." : "Resume"}
+                  </span>
+                </Button>
+                <Button
+                  onClick={onStop}
+                  disabled={isLoading}
+                  variant="outline"
+                  className="flex-1 h-10 border-red-200 text-red-600 hover:bg-red-50 hover:border-red-300"
+                >
+                  {loadingStates.stopping ? (
+                    <Loader className="w-4 h-4 animate-spin" />
+                  ) : (
+                    <Square className="w-4 h-4" />
+                  )}
+                  <span className="ml-2">
+                    {loadingStates.stopping ? "Stopping..." : "Stop"}
+                  </span>
+                </Button>
+              </>
             ) : (
               <Button
                 onClick={onStart}
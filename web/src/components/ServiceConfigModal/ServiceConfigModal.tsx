@@ -12,7 +12,7 @@ import {
   SelectTrigger,
   SelectValue,
 } from "@/components/ui/select";
-import { Service, EnvVar } from "@/types";
+import { Service, ServiceOwner, EnvVar, JVMPreset } from "@/types";
 import { useProfile } from "@/contexts/ProfileContext";
 import { ButtonSpinner } from "@/components/ui/spinner";
 import { ErrorBoundarySection } from "@/components/ui/error-boundary";
@@ -26,6 +26,10 @@ interface ServiceConfigModalProps {
   isCreateMode?: boolean; // Explicitly pass create mode
 }
 
+// Services saved before owners existed have no owner object
+const ownerOf = (service: Service): ServiceOwner =>
+  service.owner || { team: "", slackChannel: "", email: "" };
+
 export function ServiceConfigModal({
   service,
   isOpen,
@@ -36,8 +40,17 @@ export function ServiceConfigModal({
 }: ServiceConfigModalProps) {
   const [editingService, setEditingService] = useState<Service | null>(service);
   const [selectedProfileId, setSelectedProfileId] = useState<string>("");
+  const [jvmPresets, setJvmPresets] = useState<JVMPreset[]>([]);
   const { serviceProfiles, activeProfile } = useProfile();
 
+  React.useEffect(() => {
+    if (!isOpen) return;
+    fetch("/api/jvm-presets")
+      .then((response) => (response.ok ? response.json() : []))
+      .then((presets: JVMPreset[]) => setJvmPresets(presets || []))
+      .catch((error) => console.error("Failed to load JVM presets:", error));
+  }, [isOpen]);
+
   React.useEffect(() => {
     setEditingService(service);
     // Set default profile to active profile when creating new service
@@ -170,14 +183,18 @@ export function ServiceConfigModal({
                   type="number"
                   min="1"
                   max="65535"
-                  value={editingService.port}
+                  value={editingService.port || ""}
                   onChange={(e) =>
                     setEditingService({
                       ...editingService,
-                      port: parseInt(e.target.value) || 8080,
+                      port: parseInt(e.target.value) || 0,
                     })
                   }
-                  placeholder="Port number (1-65535)"
+                  placeholder={
+                    isCreateMode
+                      ? "Auto: next free port of the profile's pool"
+                      : "Port number (1-65535)"
+                  }
                 />
               </div>
               <div>
@@ -224,6 +241,60 @@ export function ServiceConfigModal({
               </div>
             )}
 
+            {/* Infrastructure run by Vertex instead of a Java service */}
+            <div className="grid grid-cols-2 gap-4">
+              <div>
+                <Label htmlFor="infraType">Infrastructure</Label>
+                <Select
+                  value={editingService.infraType || "none"}
+                  onValueChange={(value) =>
+                    setEditingService({
+                      ...editingService,
+                      infraType: value === "none" ? "" : value,
+                      infraRuntime:
+                        value === "none" ? "" : editingService.infraRuntime,
+                    })
+                  }
+                >
+                  <SelectTrigger>
+                    <SelectValue placeholder="Select infrastructure type" />
+                  </SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="none">None (Java service)</SelectItem>
+                    <SelectItem value="postgres">PostgreSQL</SelectItem>
+                    <SelectItem value="redis">Redis</SelectItem>
+                    <SelectItem value="kafka">Kafka</SelectItem>
+                    <SelectItem value="rabbitmq">RabbitMQ</SelectItem>
+                    <SelectItem value="minio">MinIO (S3)</SelectItem>
+                  </SelectContent>
+                </Select>
+              </div>
+              <div>
+                <Label htmlFor="infraRuntime">Runs As</Label>
+                <Select
+                  value={editingService.infraRuntime || "auto"}
+                  onValueChange={(value) =>
+                    setEditingService({
+                      ...editingService,
+                      infraRuntime: value === "auto" ? "" : value,
+                    })
+                  }
+                  disabled={!editingService.infraType}
+                >
+                  <SelectTrigger>
+                    <SelectValue placeholder="Select runtime" />
+                  </SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="auto">
+                      Auto (container when Docker or Podman is installed)
+                    </SelectItem>
+                    <SelectItem value="container">Container</SelectItem>
+                    <SelectItem value="binary">Local binary</SelectItem>
+                  </SelectContent>
+                </Select>
+              </div>
+            </div>
+
             <div className="grid grid-cols-2 gap-4">
               <div>
                 <Label htmlFor="buildSystem">Build System</Label>
@@ -254,6 +325,37 @@ export function ServiceConfigModal({
               </div>
             </div>
 
+            <div className="grid grid-cols-2 gap-4">
+              <div>
+                <Label htmlFor="executionMode">Execution Mode</Label>
+                <Select
+                  value={editingService.executionMode || "build-tool"}
+                  onValueChange={(value) =>
+                    setEditingService({
+                      ...editingService,
+                      executionMode: value,
+                    })
+                  }
+                >
+                  <SelectTrigger>
+                    <SelectValue placeholder="Select execution mode" />
+                  </SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="build-tool">
+                      Build tool (spring-boot:run / bootRun)
+                    </SelectItem>
+                    <SelectItem value="jar">Packaged jar (java -jar)</SelectItem>
+                  </SelectContent>
+                </Select>
+              </div>
+              <div>
+                <Label className="text-sm text-gray-500">
+                  Packaged jar builds only when sources changed since the last
+                  jar, then runs it like production
+                </Label>
+              </div>
+            </div>
+
             <div>
               <Label htmlFor="description">Description</Label>
               <Textarea
@@ -285,6 +387,56 @@ export function ServiceConfigModal({
               />
             </div>
 
+            <div>
+              <Label htmlFor="healthInterval">
+                Health Check Interval (seconds)
+              </Label>
+              <Input
+                id="healthInterval"
+                type="number"
+                min={0}
+                value={editingService.healthInterval || 0}
+                onChange={(e) =>
+                  setEditingService({
+                    ...editingService,
+                    healthInterval: parseInt(e.target.value) || 0,
+                  })
+                }
+                placeholder="0"
+              />
+              <p className="text-xs text-gray-500 mt-1">
+                0 uses the default of 30 seconds; the minimum is 5 seconds
+              </p>
+            </div>
+
+            <div>
+              <Label htmlFor="javaOptsPreset">JVM Preset</Label>
+              <Select
+                value={editingService.javaOptsPreset || "none"}
+                onValueChange={(value) =>
+                  setEditingService({
+                    ...editingService,
+                    javaOptsPreset: value === "none" ? "" : value,
+                  })
+                }
+              >
+                <SelectTrigger id="javaOptsPreset">
+                  <SelectValue placeholder="Select a JVM preset" />
+                </SelectTrigger>
+                <SelectContent>
+                  <SelectItem value="none">None</SelectItem>
+                  {jvmPresets.map((preset) => (
+                    <SelectItem key={preset.name} value={preset.name}>
+                      {preset.name} ({preset.javaOpts || "JVM defaults"})
+                    </SelectItem>
+                  ))}
+                </SelectContent>
+              </Select>
+              <p className="text-xs text-gray-500 mt-1">
+                Applied before the Java options below, which take precedence
+              </p>
+            </div>
+
             <div>
               <Label htmlFor="javaOpts">Java Options</Label>
               <Textarea
@@ -301,6 +453,62 @@ export function ServiceConfigModal({
               />
             </div>
 
+            <div>
+              <Label htmlFor="workingDir">Working Directory</Label>
+              <Input
+                id="workingDir"
+                value={editingService.workingDir || ""}
+                onChange={(e) =>
+                  setEditingService({
+                    ...editingService,
+                    workingDir: e.target.value,
+                  })
+                }
+                placeholder="e.g. payments-api (empty = the service directory)"
+              />
+              <p className="text-sm text-gray-500 mt-1">
+                Subdirectory the service is built, run and tested in, such as a
+                module of a monorepo
+              </p>
+            </div>
+
+            <div>
+              <Label htmlFor="buildCommand">Build Command</Label>
+              <Textarea
+                id="buildCommand"
+                value={editingService.buildCommand || ""}
+                onChange={(e) =>
+                  setEditingService({
+                    ...editingService,
+                    buildCommand: e.target.value,
+                  })
+                }
+                placeholder="e.g. cd {serviceDir} && ./mvnw -pl {module} -am install -DskipTests"
+                rows={2}
+              />
+            </div>
+
+            <div>
+              <Label htmlFor="startCommand">Start Command</Label>
+              <Textarea
+                id="startCommand"
+                value={editingService.startCommand || ""}
+                onChange={(e) =>
+                  setEditingService({
+                    ...editingService,
+                    startCommand: e.target.value,
+                  })
+                }
+                placeholder="e.g. cd {serviceDir} && ./mvnw -pl {module} spring-boot:run"
+                rows={2}
+              />
+              <p className="text-sm text-gray-500 mt-1">
+                Empty uses the build system's commands. Placeholders:{" "}
+                {"{javaOpts}"}, {"{serviceDir}"}, {"{workingDir}"},{" "}
+                {"{module}"}, {"{port}"}
+              </p>
+            </div>
+
             <div className="flex items-center space-x-2">
               <Checkbox
                 id="isEnabled"
@@ -331,6 +539,147 @@ export function ServiceConfigModal({
               </Label>
             </div>
 
+            <div className="flex items-center space-x-2">
+              <Checkbox
+                id="skipDiscovery"
+                checked={editingService.skipDiscovery || false}
+                onCheckedChange={(checked) =>
+                  setEditingService({
+                    ...editingService,
+                    skipDiscovery: checked === true,
+                  })
+                }
+              />
+              <Label htmlFor="skipDiscovery" className="text-sm">
+                Don't inject other services' addresses (NAME_HOST, NAME_PORT,
+                NAME_URL)
+              </Label>
+            </div>
+
+            <div className="flex items-center space-x-2">
+              <Checkbox
+                id="autoMigrate"
+                checked={editingService.autoMigrate || false}
+                onCheckedChange={(checked) =>
+                  setEditingService({
+                    ...editingService,
+                    autoMigrate: checked === true,
+                  })
+                }
+              />
+              <Label htmlFor="autoMigrate" className="text-sm">
+                Apply pending Flyway/Liquibase migrations before each start
+              </Label>
+            </div>
+
+            <div className="flex items-center space-x-2">
+              <Checkbox
+                id="interactive"
+                checked={editingService.interactive || false}
+                onCheckedChange={(checked) =>
+                  setEditingService({
+                    ...editingService,
+                    interactive: checked === true,
+                  })
+                }
+              />
+              <Label htmlFor="interactive" className="text-sm">
+                Interactive: keep stdin open to type into the running process
+                (takes effect on the next start)
+              </Label>
+            </div>
+
+            <div className="flex items-center space-x-2">
+              <Checkbox
+                id="reloadOnSighup"
+                checked={editingService.reloadOnSighup || false}
+                onCheckedChange={(checked) =>
+                  setEditingService({
+                    ...editingService,
+                    reloadOnSighup: checked === true,
+                  })
+                }
+              />
+              <Label htmlFor="reloadOnSighup" className="text-sm">
+                Re-reads its configuration on SIGHUP: send it SIGHUP when its
+                environment changes instead of asking for a restart
+              </Label>
+            </div>
+
+            {/* Owner */}
+            <div>
+              <Label>Owner</Label>
+              <div className="grid grid-cols-3 gap-4 mt-1">
+                <Input
+                  id="ownerTeam"
+                  value={editingService.owner?.team || ""}
+                  onChange={(e) =>
+                    setEditingService({
+                      ...editingService,
+                      owner: {
+                        ...ownerOf(editingService),
+                        team: e.target.value,
+                      },
+                    })
+                  }
+                  placeholder="Team"
+                />
+                <Input
+                  id="ownerSlackChannel"
+                  value={editingService.owner?.slackChannel || ""}
+                  onChange={(e) =>
+                    setEditingService({
+                      ...editingService,
+                      owner: {
+                        ...ownerOf(editingService),
+                        slackChannel: e.target.value,
+                      },
+                    })
+                  }
+                  placeholder="#slack-channel"
+                />
+                <Input
+                  id="ownerEmail"
+                  type="email"
+                  value={editingService.owner?.email || ""}
+                  onChange={(e) =>
+                    setEditingService({
+                      ...editingService,
+                      owner: {
+                        ...ownerOf(editingService),
+                        email: e.target.value,
+                      },
+                    })
+                  }
+                  placeholder="owner@example.com"
+                />
+              </div>
+              <p className="text-xs text-gray-500 mt-1">
+                Crash and health alerts go to this Slack channel, else the
+                team's webhook, else the global alert webhook
+              </p>
+            </div>
+
+            {/* Run as user */}
+            <div>
+              <Label htmlFor="runAsUser">Run As User</Label>
+              <Input
+                id="runAsUser"
+                value={editingService.runAsUser || ""}
+                onChange={(e) =>
+                  setEditingService({
+                    ...editingService,
+                    runAsUser: e.target.value,
+                  })
+                }
+                placeholder="Leave empty to run as the Vertex user"
+              />
+              <p className="text-xs text-gray-500 mt-1">
+                Dedicated OS user for the service's JVM; needs Vertex to run as
+                root or a sudoers rule for this user
+              </p>
+            </div>
+
             {/* Environment Variables */}
             <div>
               <div className="flex items-center justify-between mb-3">
@@ -24,8 +24,20 @@ interface ServiceEnvVar {
   value: string;
   description?: string;
   isRequired?: boolean;
+  type?: string;
+  pattern?: string;
 }
 
+const envVarTypeOptions = [
+  { value: "", label: "Any string" },
+  { value: "int", label: "Integer" },
+  { value: "number", label: "Number" },
+  { value: "bool", label: "Boolean" },
+  { value: "port", label: "Port" },
+  { value: "url", label: "URL" },
+  { value: "https-url", label: "HTTPS URL" },
+];
+
 interface ServiceEnvModalProps {
   isOpen: boolean;
   onClose: () => void;
@@ -71,6 +83,8 @@ export function ServiceEnvModal({
           value: envVar.value || "",
           description: envVar.description || "",
           isRequired: envVar.isRequired || false,
+          type: envVar.type || "",
+          pattern: envVar.pattern || "",
         }),
       );
       setEnvVars(vars);
@@ -100,6 +114,8 @@ export function ServiceEnvModal({
               value: envVar.value,
               description: envVar.description || "",
               isRequired: envVar.isRequired || false,
+              type: envVar.type || "",
+              pattern: envVar.pattern || "",
             };
           }
           return acc;
@@ -116,8 +132,11 @@ export function ServiceEnvModal({
       });
 
       if (!response.ok) {
+        // Values that break their schema come back with the reason
+        const errorText = (await response.text()).trim();
         throw new Error(
-          `Failed to save environment variables: ${response.status} ${response.statusText}`,
+          errorText ||
+            `Failed to save environment variables: ${response.status} ${response.statusText}`,
         );
       }
 
@@ -556,6 +575,42 @@ export function ServiceEnvModal({
                           </Label>
                         </div>
                       </div>
+                      <div className="flex items-center gap-3">
+                        <div className="flex-1">
+                          <Label htmlFor={`type-${actualIndex}`}>Type</Label>
+                          <select
+                            id={`type-${actualIndex}`}
+                            value={envVar.type || ""}
+                            onChange={(e) =>
+                              updateEnvVar(actualIndex, "type", e.target.value)
+                            }
+                            className="w-full h-10 px-3 text-sm border border-gray-300 dark:border-gray-600 rounded-md bg-white dark:bg-gray-800 text-gray-900 dark:text-gray-100"
+                          >
+                            {envVarTypeOptions.map((option) => (
+                              <option key={option.value} value={option.value}>
+                                {option.label}
+                              </option>
+                            ))}
+                          </select>
+                        </div>
+                        <div className="flex-1">
+                          <Label htmlFor={`pattern-${actualIndex}`}>
+                            Pattern
+                          </Label>
+                          <Input
+                            id={`pattern-${actualIndex}`}
+                            value={envVar.pattern || ""}
+                            onChange={(e) =>
+                              updateEnvVar(
+                                actualIndex,
+                                "pattern",
+                                e.target.value,
+                              )
+                            }
+                            placeholder="Regular expression the whole value must match"
+                          />
+                        </div>
+                      </div>
                     </div>
                   );
                 })}
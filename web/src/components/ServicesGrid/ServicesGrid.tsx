@@ -45,6 +45,8 @@ interface ServicesGridProps {
   onStartService: (service: Service) => void;
   onStopService: (service: Service) => void;
   onRestartService: (service: Service) => void;
+  onPauseService: (service: Service) => void;
+  onResumeService: (service: Service) => void;
   onCheckHealth: (service: Service) => void;
   onViewLogs: (service: Service) => void;
   onEditService: (service: Service) => void;
@@ -71,6 +73,8 @@ export function ServicesGrid({
   onStartService,
   onStopService,
   onRestartService,
+  onPauseService,
+  onResumeService,
   onCheckHealth,
   onViewLogs,
   onEditService,
@@ -392,6 +396,8 @@ export function ServicesGrid({
                 onStart={() => onStartService(service)}
                 onStop={() => onStopService(service)}
                 onRestart={() => onRestartService(service)}
+                onPause={() => onPauseService(service)}
+                onResume={() => onResumeService(service)}
                 onCheckHealth={() => onCheckHealth(service)}
                 onViewLogs={() => onViewLogs(service)}
                 onEdit={() => onEditService(service)}
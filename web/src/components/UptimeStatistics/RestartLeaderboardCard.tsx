@@ -0,0 +1,143 @@
+import { useState, useEffect } from "react";
+import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
+import { Badge } from "@/components/ui/badge";
+import { Button } from "@/components/ui/button";
+import { RotateCcw } from "lucide-react";
+import { useProfile } from "@/contexts/ProfileContext";
+import { RestartLeaderboard, RestartStats } from "@/types";
+
+type RestartWindow = RestartLeaderboard["window"];
+
+const timeWindows: { value: RestartWindow; label: string }[] = [
+  { value: "1h", label: "1h" },
+  { value: "24h", label: "24h" },
+  { value: "7d", label: "7d" },
+];
+
+const countsIn = (restarts: RestartStats, timeWindow: RestartWindow) => {
+  switch (timeWindow) {
+    case "1h":
+      return restarts.lastHour;
+    case "7d":
+      return restarts.lastWeek;
+    default:
+      return restarts.lastDay;
+  }
+};
+
+export function RestartLeaderboardCard() {
+  const { activeProfile } = useProfile();
+  const [timeWindow, setTimeWindow] = useState<RestartWindow>("24h");
+  const [leaderboard, setLeaderboard] = useState<RestartLeaderboard | null>(
+    null,
+  );
+  const [error, setError] = useState<string | null>(null);
+
+  const fetchLeaderboard = async () => {
+    if (!activeProfile) return;
+    try {
+      setError(null);
+      const token = localStorage.getItem("authToken");
+      if (!token) {
+        throw new Error("No authentication token");
+      }
+
+      const response = await fetch(
+        `/api/profiles/${activeProfile.id}/restart-leaderboard?window=${timeWindow}`,
+        {
+          headers: {
+            "Content-Type": "application/json",
+            Authorization: `Bearer ${token}`,
+          },
+        },
+      );
+      if (!response.ok) {
+        throw new Error(
+          `Failed to fetch restart leaderboard: ${response.statusText}`,
+        );
+      }
+      setLeaderboard(await response.json());
+    } catch (err) {
+      setError(
+        err instanceof Error ? err.message : "Failed to fetch restart leaderboard",
+      );
+      console.error("Error fetching restart leaderboard:", err);
+    }
+  };
+
+  useEffect(() => {
+    fetchLeaderboard();
+    // Refresh every 30 seconds
+    const interval = setInterval(fetchLeaderboard, 30000);
+    return () => clearInterval(interval);
+  }, [activeProfile?.id, timeWindow]);
+
+  if (!activeProfile) {
+    return null;
+  }
+
+  return (
+    <Card>
+      <CardHeader className="flex flex-row items-center justify-between space-y-0">
+        <CardTitle className="flex items-center">
+          <RotateCcw className="w-5 h-5 mr-2" />
+          Most Restarted in {activeProfile.name}
+        </CardTitle>
+        <div className="flex items-center space-x-1 bg-gray-100 dark:bg-gray-700 rounded-lg p-1">
+          {timeWindows.map((option) => (
+            <Button
+              key={option.value}
+              onClick={() => setTimeWindow(option.value)}
+              variant={timeWindow === option.value ? "default" : "ghost"}
+              size="sm"
+              className="h-7 px-2"
+            >
+              {option.label}
+            </Button>
+          ))}
+        </div>
+      </CardHeader>
+      <CardContent>
+        {error ? (
+          <p className="text-sm text-red-600">{error}</p>
+        ) : !leaderboard || leaderboard.services.length === 0 ? (
+          <p className="text-sm text-gray-500">
+            No service restarted in the last {timeWindow}.
+          </p>
+        ) : (
+          <ol className="space-y-2">
+            {leaderboard.services.map((entry, index) => {
+              const counts = countsIn(entry.restarts, timeWindow);
+              return (
+                <li
+                  key={entry.serviceId}
+                  className="flex items-center justify-between"
+                >
+                  <div className="flex items-center space-x-3">
+                    <span className="w-5 text-right text-sm text-gray-500">
+                      {index + 1}.
+                    </span>
+                    <span className="font-medium">{entry.serviceName}</span>
+                    <Badge variant="outline" className="text-xs">
+                      {entry.status}
+                    </Badge>
+                  </div>
+                  <div className="flex items-center space-x-3 text-sm">
+                    {counts.crashes > 0 && (
+                      <span className="text-red-600">
+                        {counts.crashes} crash{counts.crashes === 1 ? "" : "es"}
+                      </span>
+                    )}
+                    <span className="font-semibold">
+                      {counts.total} restart{counts.total === 1 ? "" : "s"}
+                    </span>
+                  </div>
+                </li>
+              );
+            })}
+          </ol>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
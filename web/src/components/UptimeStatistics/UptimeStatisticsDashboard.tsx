@@ -16,6 +16,7 @@ import { UptimeProgressBar } from "./UptimeProgressBar";
 import { ServiceDetailModal } from "./ServiceDetailModal";
 import { ServiceUptimeCard } from "./ServiceUptimeCard";
 import { UptimeFiltersComponent, UptimeFilters } from "./UptimeFilters";
+import { RestartLeaderboardCard } from "./RestartLeaderboardCard";
 
 interface ServiceUptimeStats {
   serviceName: string;
@@ -357,6 +358,9 @@ export function UptimeStatisticsDashboard() {
         </Card>
       </div>
 
+      {/* Most restarted services of the active profile */}
+      <RestartLeaderboardCard />
+
       {/* Service Statistics - Table View */}
       {viewMode === 'table' && (
         <Card>
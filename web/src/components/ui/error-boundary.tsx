@@ -1,6 +1,7 @@
 import * as React from "react"
 import { AlertTriangle, RefreshCcw, Home } from "lucide-react"
 import { Button } from "./button"
+import { withBasePath } from "@/lib/basePath"
 
 interface ErrorInfo {
   componentStack: string
@@ -124,7 +125,7 @@ export class ErrorBoundary extends React.Component<ErrorBoundaryProps, ErrorBoun
   }
 
   handleHome = () => {
-    window.location.href = '/'
+    window.location.href = withBasePath('/')
   }
 
   render() {
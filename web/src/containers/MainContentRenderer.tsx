@@ -52,6 +52,8 @@ export function MainContentRenderer({
             onStartService={serviceOps.startService}
             onStopService={serviceOps.stopService}
             onRestartService={serviceOps.restartService}
+            onPauseService={serviceOps.pauseService}
+            onResumeService={serviceOps.resumeService}
             onCheckHealth={serviceOps.checkServiceHealth}
             onViewLogs={servicesData.setSelectedService}
             onEditService={serviceManagement.openEditService}
@@ -13,6 +13,9 @@ interface User {
   role: string;
   createdAt: string;
   lastLogin: string;
+  twoFactorEnabled?: boolean;
+  // Admins require two-factor authentication and it is not set up yet
+  twoFactorSetupRequired?: boolean;
 }
 
 interface AuthContextType {
@@ -20,6 +23,9 @@ interface AuthContextType {
   token: string | null;
   isAuthenticated: boolean;
   isLoading: boolean;
+  // Visitors who have not signed in get a read-only dashboard
+  anonymousReadOnly: boolean;
+  setAnonymousReadOnly: (enabled: boolean) => void;
   login: (user: User, token: string) => void;
   logout: () => void;
   checkAuth: () => Promise<boolean>;
@@ -43,6 +49,7 @@ export function AuthProvider({ children }: AuthProviderProps) {
   const [user, setUser] = useState<User | null>(null);
   const [token, setToken] = useState<string | null>(null);
   const [isLoading, setIsLoading] = useState(true);
+  const [anonymousReadOnly, setAnonymousReadOnly] = useState(false);
 
   const isAuthenticated = !!user && !!token;
 
@@ -98,8 +105,20 @@ export function AuthProvider({ children }: AuthProviderProps) {
     }
   };
 
+  const checkAccess = async () => {
+    try {
+      const response = await fetch("/api/auth/access");
+      if (response.ok) {
+        const settings = await response.json();
+        setAnonymousReadOnly(!!settings.anonymousReadOnly);
+      }
+    } catch (error) {
+      console.error("Access settings check failed:", error);
+    }
+  };
+
   useEffect(() => {
-    checkAuth();
+    checkAccess().finally(() => checkAuth());
   }, []);
 
   const value: AuthContextType = {
@@ -107,6 +126,8 @@ export function AuthProvider({ children }: AuthProviderProps) {
     token,
     isAuthenticated,
     isLoading,
+    anonymousReadOnly,
+    setAnonymousReadOnly,
     login,
     logout,
     checkAuth,
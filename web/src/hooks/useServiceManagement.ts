@@ -31,10 +31,13 @@ export function useServiceManagement(onServiceUpdated: () => void) {
       dir: "",
       extraEnv: "",
       javaOpts: "",
+      javaOptsPreset: "",
       status: "stopped",
       healthStatus: "unknown",
+      flapping: false,
+      flapScore: 0,
       healthUrl: "",
-      port: 8080,
+      port: 0, // Assigned from the profile's port pool on save
       pid: 0,
       order: 0, // Will be set when we have all services
       lastStarted: new Date().toISOString(),
@@ -42,6 +45,19 @@ export function useServiceManagement(onServiceUpdated: () => void) {
       isEnabled: true,
       buildSystem: "auto",
       verboseLogging: false,
+      healthInterval: 0,
+      skipDiscovery: false,
+      owner: { team: "", slackChannel: "", email: "" },
+      runAsUser: "",
+      executionMode: "",
+      workingDir: "",
+      startCommand: "",
+      buildCommand: "",
+      autoMigrate: false,
+      interactive: false,
+      reloadOnSighup: false,
+      infraType: "",
+      infraRuntime: "",
       gitBranch: "",
       gitHasUncommitted: false,
       gitCommitsAhead: 0,
@@ -162,8 +178,11 @@ export function useServiceManagement(onServiceUpdated: () => void) {
   );
 
   const handleDeleteGlobally = useCallback(
-    async (service: Service) => {
-      const result = await ServiceOperations.deleteService(service.id);
+    async (serviceId: string, confirmationToken?: string) => {
+      const result = await ServiceOperations.deleteService(
+        serviceId,
+        confirmationToken,
+      );
 
       if (result.success) {
         addToast(toast.success("Service deleted", result.message!));
@@ -182,8 +201,11 @@ export function useServiceManagement(onServiceUpdated: () => void) {
         setIsSavingService(true);
         const isCreate = isCreatingService;
 
-        // For updates, use the service UUID. For creation, POST to /api/services
-        const url = isCreate ? "/api/services" : `/api/services/${service.id}`;
+        // For updates, use the service UUID. For creation, POST to /api/services,
+        // naming the profile whose port pool a service without a port draws from
+        const url = isCreate
+          ? `/api/services${profileId ? `?profileId=${encodeURIComponent(profileId)}` : ""}`
+          : `/api/services/${service.id}`;
         const method = isCreate ? "POST" : "PUT";
 
         console.log("Saving service:", {
@@ -199,15 +221,33 @@ export function useServiceManagement(onServiceUpdated: () => void) {
           dir: service.dir || "",
           extraEnv: service.extraEnv || "",
           javaOpts: service.javaOpts || "",
+          javaOptsPreset: service.javaOptsPreset || "",
+          // Left empty, the backend fills in the health URL of an assigned
+          // port; infrastructure is checked by connecting to its port instead
           healthUrl:
             service.healthUrl ||
-            `http://localhost:${service.port || 8080}/actuator/health`,
-          port: service.port || 8080,
+            (service.port && !service.infraType
+              ? `http://localhost:${service.port}/actuator/health`
+              : ""),
+          port: service.port || 0,
           order: service.order || 0,
           description: service.description || "",
           isEnabled: service.isEnabled,
           buildSystem: service.buildSystem || "auto",
           verboseLogging: service.verboseLogging || false,
+          healthInterval: service.healthInterval || 0,
+          skipDiscovery: service.skipDiscovery || false,
+          owner: service.owner || { team: "", slackChannel: "", email: "" },
+          runAsUser: service.runAsUser || "",
+          executionMode: service.executionMode || "",
+          workingDir: service.workingDir || "",
+          startCommand: service.startCommand || "",
+          buildCommand: service.buildCommand || "",
+          autoMigrate: service.autoMigrate || false,
+          interactive: service.interactive || false,
+          reloadOnSighup: service.reloadOnSighup || false,
+          infraType: service.infraType || "",
+          infraRuntime: service.infraRuntime || "",
           envVars: service.envVars || {},
           startupDelay: service.startupDelay || 0,
         };
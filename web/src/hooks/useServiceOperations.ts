@@ -114,6 +114,50 @@ export function useServiceOperations() {
     [addToast],
   );
 
+  const pauseService = useCallback(
+    async (service: Service) => {
+      setServiceLoadingStates((prev) => ({
+        ...prev,
+        [service.id]: { ...prev[service.id], pausing: true },
+      }));
+
+      const result = await ServiceOperations.pauseService(service.id);
+      if (result.success) {
+        addToast(toast.success(`${service.name} paused`, result.message!));
+      } else {
+        addToast(toast.error("Failed to pause service", result.error!));
+      }
+
+      setServiceLoadingStates((prev) => ({
+        ...prev,
+        [service.id]: { ...prev[service.id], pausing: false },
+      }));
+    },
+    [addToast],
+  );
+
+  const resumeService = useCallback(
+    async (service: Service) => {
+      setServiceLoadingStates((prev) => ({
+        ...prev,
+        [service.id]: { ...prev[service.id], resuming: true },
+      }));
+
+      const result = await ServiceOperations.resumeService(service.id);
+      if (result.success) {
+        addToast(toast.success(`${service.name} resumed`, result.message!));
+      } else {
+        addToast(toast.error("Failed to resume service", result.error!));
+      }
+
+      setServiceLoadingStates((prev) => ({
+        ...prev,
+        [service.id]: { ...prev[service.id], resuming: false },
+      }));
+    },
+    [addToast],
+  );
+
   const checkServiceHealth = useCallback(
     async (service: Service) => {
       setServiceLoadingStates((prev) => ({
@@ -369,6 +413,8 @@ export function useServiceOperations() {
     startService,
     stopService,
     restartService,
+    pauseService,
+    resumeService,
     checkServiceHealth,
     installLibraries,
     startAllServices,
@@ -1,8 +1,22 @@
-import { useState, useEffect, useCallback } from "react";
+import { useState, useEffect, useCallback, useRef } from "react";
 import { Service, Configuration } from "@/types";
 import { ServiceOperations } from "@/services/serviceOperations";
 import { useProfile } from "@/contexts/ProfileContext";
 import { useToast, toast } from "@/components/ui/toast";
+import { withBasePath } from "@/lib/basePath";
+
+// Matches the number of log entries the server keeps in memory per service
+const MAX_LIVE_LOGS = 1000;
+
+// Topics of the websocket stream this hook follows
+const WS_TOPICS = ["services", "logs"];
+const WS_RECONNECT_DELAY_MS = 2000;
+
+interface WebSocketMessage {
+  type: string;
+  payload: any;
+  seq?: number;
+}
 
 export function useServices() {
   const { activeProfile } = useProfile();
@@ -129,47 +143,107 @@ export function useServices() {
     }
   }, [activeProfile, allConfigurations, filterConfigurationsByProfile]);
 
+  // Position in the websocket stream, kept across reconnects so missed
+  // messages are replayed instead of lost
+  const streamRef = useRef<{ id: string; seq: number } | null>(null);
+
   // WebSocket handling
   useEffect(() => {
     fetchServices();
     fetchConfigurations();
 
-    // WebSocket connection for real-time updates
     const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
-    const ws = new WebSocket(`${protocol}//${window.location.host}/ws`);
+    let ws: WebSocket;
+    let reconnectTimer: ReturnType<typeof setTimeout> | undefined;
+    let closed = false;
 
-    ws.onmessage = (event) => {
-      const message = JSON.parse(event.data);
+    const handleMessage = (message: WebSocketMessage) => {
+      if (message.seq !== undefined && streamRef.current) {
+        // Replayed messages may overlap those already received
+        if (message.seq <= streamRef.current.seq) {
+          return;
+        }
+        streamRef.current.seq = message.seq;
+      }
 
-      if (message.type === "service_update") {
-        const updatedService: Service = message.payload;
+      if (message.type === "batch") {
+        message.payload.forEach(handleMessage);
+      } else if (message.type === "subscribed") {
+        const { stream, seq, gap } = message.payload;
+        if (gap || !streamRef.current || streamRef.current.id !== stream) {
+          // Missed messages are gone; start over from the current state
+          if (streamRef.current) {
+            fetchServices();
+          }
+          streamRef.current = { id: stream, seq };
+        }
+      } else if (message.type === "service_update") {
+        // Updates carry only the fields that changed, and never logs
+        const delta: Partial<Service> & { id: string } = message.payload;
         setServices((prev) =>
           prev.map((service) =>
-            service.id === updatedService.id ? updatedService : service,
+            service.id === delta.id ? { ...service, ...delta } : service,
           ),
         );
 
-        if (selectedService && selectedService.id === updatedService.id) {
-          setSelectedService(updatedService);
+        if (selectedService && selectedService.id === delta.id) {
+          setSelectedService((prev) => (prev ? { ...prev, ...delta } : null));
         }
       } else if (message.type === "log_entry") {
-        const { serviceId, logEntry } = message.payload;
+        const { serviceUUID, logEntry } = message.payload;
+        const appendLog = (logs: Service["logs"]) =>
+          [...(logs || []), logEntry].slice(-MAX_LIVE_LOGS);
         setServices((prev) =>
           prev.map((service) =>
-            service.id === serviceId
-              ? { ...service, logs: [...service.logs, logEntry] }
+            service.id === serviceUUID
+              ? { ...service, logs: appendLog(service.logs) }
               : service,
           ),
         );
-        if (selectedService && selectedService.id === serviceId) {
+        if (selectedService && selectedService.id === serviceUUID) {
           setSelectedService((prev) =>
-            prev ? { ...prev, logs: [...prev.logs, logEntry] } : null,
+            prev ? { ...prev, logs: appendLog(prev.logs) } : null,
           );
         }
       }
     };
 
-    return () => ws.close();
+    const connect = () => {
+      // Browsers cannot set headers on a WebSocket, so the login goes in the URL
+      const token = localStorage.getItem("authToken");
+      const query = token ? `?token=${encodeURIComponent(token)}` : "";
+      ws = new WebSocket(
+        `${protocol}//${window.location.host}${withBasePath("/ws")}${query}`,
+      );
+
+      ws.onopen = () => {
+        ws.send(
+          JSON.stringify({
+            type: "subscribe",
+            topics: WS_TOPICS,
+            stream: streamRef.current?.id,
+            resumeFrom: streamRef.current?.seq,
+          }),
+        );
+      };
+
+      ws.onmessage = (event) => {
+        handleMessage(JSON.parse(event.data));
+      };
+
+      ws.onclose = () => {
+        if (!closed) {
+          reconnectTimer = setTimeout(connect, WS_RECONNECT_DELAY_MS);
+        }
+      };
+    };
+    connect();
+
+    return () => {
+      closed = true;
+      clearTimeout(reconnectTimer);
+      ws.close();
+    };
   }, [selectedService, fetchServices, fetchConfigurations]);
   return {
     // State
@@ -0,0 +1,38 @@
+import { withBasePath } from "./basePath";
+
+// Sends the signed-in user's token with every API call that does not set its
+// own Authorization header, so calls keep working when anonymous read-only
+// access makes the server require a login for everything beyond the dashboard.
+// API paths are put under the base path Vertex is served under.
+export function installAuthFetch() {
+  const originalFetch = window.fetch.bind(window);
+
+  window.fetch = (input: RequestInfo | URL, init?: RequestInit) => {
+    const url =
+      typeof input === "string"
+        ? input
+        : input instanceof URL
+          ? input.pathname
+          : input.url;
+    const token = localStorage.getItem("authToken");
+    const isApiCall =
+      url.startsWith("/api/") ||
+      url.startsWith(`${window.location.origin}/api/`);
+
+    if (typeof input === "string" && input.startsWith("/api/")) {
+      input = withBasePath(input);
+    }
+
+    if (!token || !isApiCall) {
+      return originalFetch(input, init);
+    }
+
+    const headers = new Headers(
+      init?.headers ?? (input instanceof Request ? input.headers : undefined),
+    );
+    if (!headers.has("Authorization")) {
+      headers.set("Authorization", `Bearer ${token}`);
+    }
+    return originalFetch(input, { ...init, headers });
+  };
+}
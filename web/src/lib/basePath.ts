@@ -0,0 +1,8 @@
+// The path Vertex is served under behind a reverse proxy, e.g. "/vertex", or
+// "" at the root. The server writes it into index.html (--base-path).
+export const basePath: string = window.__VERTEX_BASE_PATH__ ?? "";
+
+// Puts a root-relative path such as "/api/services" under the base path
+export function withBasePath(path: string): string {
+  return path.startsWith("/") ? `${basePath}${path}` : path;
+}
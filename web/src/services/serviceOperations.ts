@@ -1,10 +1,12 @@
-import { Service } from "@/types";
+import { Service, ServiceDeletionImpact } from "@/types";
 
 export interface ServiceLoadingStates {
   [serviceName: string]: {
     starting?: boolean;
     stopping?: boolean;
     restarting?: boolean;
+    pausing?: boolean;
+    resuming?: boolean;
     checkingHealth?: boolean;
     installingLibraries?: boolean;
     validatingWrapper?: boolean;
@@ -27,6 +29,18 @@ export class ServiceOperations {
       const response = await fetch(`/api/services/${serviceId}/start`, {
         method: "POST",
       });
+      if (response.status === 412) {
+        // Preflight checks failed; report what is missing instead of the status
+        const result = await response.json().catch(() => null);
+        const failures = (result?.checks || [])
+          .filter((check: { status: string }) => check.status === "fail")
+          .map((check: { message: string }) => check.message);
+        throw new Error(
+          failures.length > 0
+            ? `Cannot start service: ${failures.join("; ")}`
+            : `Failed to start service: ${response.status} ${response.statusText}`,
+        );
+      }
       if (!response.ok) {
         throw new Error(
           `Failed to start service: ${response.status} ${response.statusText}`,
@@ -105,6 +119,64 @@ export class ServiceOperations {
     }
   }
 
+  static async pauseService(
+    serviceId: string,
+  ): Promise<ServiceOperationResult> {
+    try {
+      const response = await fetch(`/api/services/${serviceId}/pause`, {
+        method: "POST",
+      });
+      if (!response.ok) {
+        const message = (await response.text()).trim();
+        throw new Error(
+          message ||
+            `Failed to pause service: ${response.status} ${response.statusText}`,
+        );
+      }
+      return {
+        success: true,
+        message: "Service paused; its processes get no CPU until resumed",
+      };
+    } catch (error) {
+      return {
+        success: false,
+        error:
+          error instanceof Error
+            ? error.message
+            : "An unexpected error occurred",
+      };
+    }
+  }
+
+  static async resumeService(
+    serviceId: string,
+  ): Promise<ServiceOperationResult> {
+    try {
+      const response = await fetch(`/api/services/${serviceId}/resume`, {
+        method: "POST",
+      });
+      if (!response.ok) {
+        const message = (await response.text()).trim();
+        throw new Error(
+          message ||
+            `Failed to resume service: ${response.status} ${response.statusText}`,
+        );
+      }
+      return {
+        success: true,
+        message: "Service resumed",
+      };
+    } catch (error) {
+      return {
+        success: false,
+        error:
+          error instanceof Error
+            ? error.message
+            : "An unexpected error occurred",
+      };
+    }
+  }
+
   static async checkServiceHealth(
     serviceId: string,
   ): Promise<ServiceOperationResult> {
@@ -304,13 +376,33 @@ export class ServiceOperations {
     }
   }
 
+  static async getDeletionImpact(
+    serviceId: string,
+  ): Promise<ServiceDeletionImpact> {
+    const response = await fetch(`/api/services/${serviceId}/delete-impact`);
+    if (!response.ok) {
+      throw new Error(
+        `Failed to load deletion impact: ${response.status} ${response.statusText}`,
+      );
+    }
+    return response.json();
+  }
+
   static async deleteService(
     serviceId: string,
+    confirmationToken?: string,
   ): Promise<ServiceOperationResult> {
     try {
-      const response = await fetch(`/api/services/${serviceId}`, {
+      const query = confirmationToken
+        ? `?confirm=${encodeURIComponent(confirmationToken)}`
+        : "";
+      const response = await fetch(`/api/services/${serviceId}${query}`, {
         method: "DELETE",
       });
+      if (response.status === 409) {
+        const result = await response.json();
+        throw new Error(result.error);
+      }
       if (!response.ok) {
         throw new Error(
           `Failed to delete service: ${response.status} ${response.statusText}`,
@@ -3,6 +3,8 @@ export interface EnvVar {
   value: string;
   description: string;
   isRequired: boolean;
+  type?: string;
+  pattern?: string;
 }
 
 export interface ResponseTime {
@@ -29,6 +31,30 @@ export interface ServiceMetrics {
   uptimeStats: UptimeStatistics;
 }
 
+export interface RestartCounts {
+  total: number;
+  manual: number; // Restarts asked for through the UI, API or CLI
+  crashes: number; // Exits with an error the service had to be started again after
+}
+
+export interface RestartStats {
+  lastHour: RestartCounts;
+  lastDay: RestartCounts;
+  lastWeek: RestartCounts;
+  lastRestartAt?: string;
+}
+
+export interface RestartLeaderboard {
+  profileId: string;
+  window: "1h" | "24h" | "7d";
+  services: {
+    serviceId: string;
+    serviceName: string;
+    status: string;
+    restarts: RestartStats;
+  }[];
+}
+
 export interface ServiceDependency {
   serviceName: string;
   type: string; // "hard", "soft", "optional"
@@ -45,8 +71,11 @@ export interface Service {
   dir: string;
   extraEnv: string;
   javaOpts: string;
+  javaOptsPreset: string; // Name of the JVM preset applied before javaOpts ("" = none)
   status: string;
   healthStatus: string;
+  flapping: boolean; // Health toggles too often; its changes are not alerted
+  flapScore: number;
   healthUrl: string;
   port: number;
   pid: number;
@@ -57,6 +86,20 @@ export interface Service {
   isEnabled: boolean;
   buildSystem: string; // "maven", "gradle", or "auto"
   verboseLogging: boolean; // Enable verbose/debug logging for build tools
+  healthInterval: number; // Seconds between health checks (0 = default)
+  skipDiscovery: boolean; // Don't inject <SERVICE>_HOST/_PORT/_URL of the profile's other services
+  owner: ServiceOwner; // Team and contacts its alerts are routed to
+  runAsUser: string; // OS user the service process runs as (empty = the Vertex user)
+  executionMode: string; // "build-tool" (default) or "jar" (java -jar of the packaged jar)
+  workingDir: string; // Subdirectory of dir the service is built and run from
+  startCommand: string; // Replaces the build system's run command (empty = default)
+  buildCommand: string; // Replaces the jar mode's package command, or runs before the start command
+  autoMigrate: boolean; // Apply pending Flyway/Liquibase migrations before each start
+  interactive: boolean; // Keep stdin open so input can be sent to the running process
+  reloadOnSighup: boolean; // Re-reads its configuration on SIGHUP, so environment changes are signalled live
+  infraType: string; // "postgres", "redis", "kafka", "rabbitmq" or "minio" run by Vertex (empty = a Java service)
+  infraRuntime: string; // "container", "binary" or empty to pick one
+  archivedAt?: string; // Set while the service is archived
   gitBranch: string; // Current git branch (if service is a git repo)
   gitHasUncommitted: boolean; // Has uncommitted changes
   gitCommitsAhead: number; // Commits ahead of remote
@@ -72,6 +115,7 @@ export interface Service {
   networkRx: number; // bytes received
   networkTx: number; // bytes transmitted
   metrics: ServiceMetrics;
+  restarts?: RestartStats; // Restarts and crashes in the last hour, day and week
   // Service dependencies
   dependencies: ServiceDependency[] | null;
   dependentOn: string[] | null;
@@ -88,6 +132,7 @@ export interface ServiceConfigRequest {
   name: string;
   dir: string;
   javaOpts: string;
+  javaOptsPreset: string;
   healthUrl: string;
   port: number;
   order: number;
@@ -95,9 +140,59 @@ export interface ServiceConfigRequest {
   isEnabled: boolean;
   buildSystem: string;
   verboseLogging: boolean;
+  healthInterval: number;
+  skipDiscovery: boolean;
+  owner: ServiceOwner;
+  runAsUser: string;
+  executionMode: string;
+  workingDir: string;
+  startCommand: string;
+  buildCommand: string;
+  autoMigrate: boolean;
+  interactive: boolean;
+  reloadOnSighup: boolean;
+  infraType: string;
+  infraRuntime: string;
   envVars: Record<string, EnvVar>;
 }
 
+export interface ServiceOwner {
+  team: string;
+  slackChannel: string; // e.g. #payments-alerts, or a channel ID
+  email: string;
+}
+
+// What deleting a service affects; deleting needs confirmationToken unless empty
+export interface ServiceDeletionImpact {
+  serviceId: string;
+  serviceName: string;
+  running: boolean;
+  profiles: { id: string; name: string; userId: string }[];
+  dependents: {
+    serviceId: string;
+    serviceName: string;
+    profileId?: string; // Set for dependencies scoped to a profile
+    type: string;
+    required: boolean;
+    behavior: string;
+  }[];
+  storedData: { kind: string; label: string; count: number }[];
+  empty: boolean;
+  confirmationToken?: string;
+}
+
+export interface JVMPreset {
+  name: string;
+  description: string;
+  xms: string; // Initial heap, e.g. "256m"
+  xmx: string; // Maximum heap, e.g. "512m"
+  gc: string; // "g1", "parallel", "serial", "zgc", "shenandoah" or "" for the JVM default
+  extraOpts: string;
+  javaOpts: string; // Options the preset expands to
+  usedBy: string[]; // Names of services referencing the preset
+  updatedAt: string;
+}
+
 export interface Configuration {
   id: string;
   name: string;
@@ -117,8 +212,25 @@ export interface UserPreferences {
   dashboardLayout: string;
   autoRefresh: boolean;
   refreshInterval: number; // seconds
+  columnLayouts: Record<string, string[]>; // view name -> visible columns
+  pinnedServices: string[]; // service UUIDs
+  defaultLogFilters: LogFilterPreferences;
+  favoriteProfiles: string[]; // service profile IDs
+}
+
+export interface LogFilterPreferences {
+  levels: string[];
+  search: string;
 }
 
+// PATCH /api/user/preferences body; a null column layout removes that view
+export type UserPreferencesPatch = Partial<
+  Omit<UserPreferences, "columnLayouts" | "defaultLogFilters">
+> & {
+  columnLayouts?: Record<string, string[] | null>;
+  defaultLogFilters?: LogFilterPreferences;
+};
+
 // Profile-scoped configuration types
 
 export interface ProfileEnvVar {
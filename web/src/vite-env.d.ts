@@ -8,4 +8,7 @@ interface ImportMetaEnv {
 
 interface ImportMeta {
   readonly env: ImportMetaEnv
-}
\ No newline at end of file
+}
+interface Window {
+  __VERTEX_BASE_PATH__?: string
+}
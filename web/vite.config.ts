@@ -3,6 +3,8 @@ import react from "@vitejs/plugin-react";
 import path from "path";
 
 export default defineConfig({
+  // Relative asset URLs, so the UI also loads under a --base-path
+  base: "./",
   plugins: [react()],
   resolve: {
     alias: {